@@ -10,27 +10,34 @@ import (
 
 	"zerotrace/agent/internal/communicator"
 	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/metrics"
+	"zerotrace/agent/internal/models"
 	"zerotrace/agent/internal/processor"
 	"zerotrace/agent/internal/scanner"
-
-	"github.com/joho/godotenv"
 )
 
 func main() {
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
+	if err := config.LoadFile(".env"); err != nil {
 		log.Println("No .env file found, using system environment variables")
 	}
 
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Configuration validation failed: %v", err)
+	}
 
 	// Initialize components
 	softwareScanner := scanner.NewSoftwareScanner(cfg)
 	configScanner := scanner.NewConfigScanner(cfg)
 	systemScanner := scanner.NewSystemScanner(cfg)
 	processor := processor.NewProcessor(cfg, cfg.EnrichmentURL)
-	communicator := communicator.NewCommunicator(cfg)
+	communicator, err := communicator.NewCommunicator(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up API communication: %v", err)
+	}
+	var scope *scanner.ScanScope // nil until fetched, which runs the default scanners
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -106,12 +113,29 @@ func main() {
 
 				log.Printf("Found %d installed applications", len(softwareResults.Dependencies))
 
-				// Scan for configuration vulnerabilities
-				configResults, err := configScanner.Scan()
-				if err != nil {
-					log.Printf("Configuration scan error: %v", err)
+				// Refresh the scan scope, keeping the last one if the API cannot be reached
+				if fetched, err := communicator.GetScanScope(); err != nil {
+					log.Printf("Failed to fetch scan scope: %v", err)
 				} else {
-					log.Printf("Found %d configuration vulnerabilities", len(configResults.Vulnerabilities))
+					scope = fetched
+				}
+
+				var configResults *models.ScanResult
+				if scope.Enabled(scanner.ScannerConfig) {
+					// Refresh the approved baseline so drift is reported against the current version
+					if baseline, err := communicator.GetConfigBaseline(); err != nil {
+						log.Printf("Failed to fetch config baseline: %v", err)
+					} else {
+						configScanner.SetBaseline(baseline)
+					}
+
+					// Scan for configuration vulnerabilities
+					configResults, err = configScanner.Scan(ctx)
+					if err != nil {
+						log.Printf("Configuration scan error: %v", err)
+					} else {
+						log.Printf("Found %d configuration vulnerabilities", len(configResults.Vulnerabilities))
+					}
 				}
 
 				// Process software results
@@ -121,20 +145,15 @@ func main() {
 					continue
 				}
 
-				// Send software results to API
-				if err := communicator.SendResults(processedResults); err != nil {
-					log.Printf("Failed to send software results: %v", err)
-				} else {
-					log.Printf("Software results sent successfully")
-				}
-
-				// Send configuration results to API
+				// Send software and configuration results to API in one request
+				batch := []*models.ScanResult{processedResults}
 				if configResults != nil {
-					if err := communicator.SendResults(configResults); err != nil {
-						log.Printf("Failed to send configuration results: %v", err)
-					} else {
-						log.Printf("Configuration results sent successfully")
-					}
+					batch = append(batch, configResults)
+				}
+				if err := communicator.SendBatch(batch...); err != nil {
+					log.Printf("Failed to send scan results: %v", err)
+				} else {
+					log.Printf("Scan results sent successfully")
 				}
 
 				// Wait for next scan interval
@@ -147,6 +166,7 @@ func main() {
 	go func() {
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
+		sampler := metrics.NewSampler()
 
 		for {
 			select {
@@ -154,12 +174,12 @@ func main() {
 				return
 			case <-ticker.C:
 				var err error
-				// Simple metrics for MDM mode
-				cpuUsage := 0.0
-				memoryUsage := 0.0
+				usage := sampler.Sample(ctx)
+				cpuUsage, memoryUsage := usage.ProcessCPUPercent, usage.ProcessMemoryPercent
 				metadata := map[string]any{
-					"mode": "mdm",
-					"os":   cfg.OS,
+					"mode":           "mdm",
+					"os":             cfg.OS,
+					"resource_usage": usage.Metadata(),
 				}
 
 				if cfg.IsEnrolled() {
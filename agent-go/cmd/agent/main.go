@@ -2,31 +2,100 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"zerotrace/agent/internal/collector"
 	"zerotrace/agent/internal/communicator"
 	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/ipc"
+	"zerotrace/agent/internal/metrics"
+	"zerotrace/agent/internal/models"
 	"zerotrace/agent/internal/processor"
 	"zerotrace/agent/internal/scanner"
+	"zerotrace/agent/internal/scheduler"
 	"zerotrace/agent/internal/tray"
+	"zerotrace/agent/internal/updater"
 
-	"github.com/joho/godotenv"
 	"fyne.io/systray"
 )
 
+// version is the agent's version, set at build time with
+// -ldflags "-X main.version=1.4.0". Releases newer than it are installed when
+// AUTO_UPDATE is on.
+var version = "1.0.0"
+
 // noOpTrayManager is a no-op implementation for when tray is disabled
 type noOpTrayManager struct{}
 
 func (n *noOpTrayManager) Start() {}
 func (n *noOpTrayManager) Stop()  {}
 
+// agentScanners are the scanners this agent can run: the built-in ones and
+// those registered with scanner.Register. The agent's configuration and the
+// scan scope the API sets for the agent decide which of them do, and on
+// which paths.
+type agentScanners struct {
+	cfg        *config.Config
+	software   *scanner.SoftwareScanner
+	system     *scanner.SystemScanner
+	network    *scanner.NetworkScanner
+	config     *scanner.ConfigScanner // Re-runs single checks to verify findings
+	aiml       *scanner.AIMLScanner
+	container  *scanner.ContainerScanner
+	registered []scanner.Scanner                 // Run on the scan interval after the built-in scanners, in name order
+	scope      atomic.Pointer[scanner.ScanScope] // nil until fetched, which runs the defaults
+}
+
+// refreshScope fetches the agent's scan scope, keeping the current one if the API cannot be reached
+func (s *agentScanners) refreshScope(communicator *communicator.Communicator) {
+	scope, err := communicator.GetScanScope()
+	if err != nil {
+		log.Printf("Failed to fetch scan scope, keeping the current one: %v", err)
+		return
+	}
+	if previous := s.scope.Swap(scope); previous == nil || previous.Version != scope.Version || previous.Default != scope.Default {
+		log.Printf("Scan scope: %s", strings.Join(scope.Scanners, ", "))
+	}
+}
+
+// enabled reports whether the agent's configuration and current scan scope
+// run a scanner
+func (s *agentScanners) enabled(name string) bool {
+	return s.cfg.ScannerEnabled(name) && s.scope.Load().Enabled(name)
+}
+
+// intervalScans are the scans run one after another on the scan interval
+func (s *agentScanners) intervalScans() []string {
+	scans := []string{scanner.ScannerSoftware, scanner.ScannerAIML, scanner.ScannerContainer}
+	for _, registered := range s.registered {
+		scans = append(scans, registered.Name())
+	}
+	return scans
+}
+
+// lookup finds a registered scanner by name
+func (s *agentScanners) lookup(name string) scanner.Scanner {
+	for _, registered := range s.registered {
+		if registered.Name() == name {
+			return registered
+		}
+	}
+	return nil
+}
+
 func main() {
 	// Check if running as .app bundle (macOS)
 	// If so, redirect logs to file to avoid showing terminal
@@ -48,32 +117,133 @@ func main() {
 		}
 	}
 
+	// Parse flags
+	disableTray := flag.Bool("no-tray", false, "Disable system tray UI")
+	testTray := flag.Bool("test-tray", false, "Run in tray test mode")
+	envFile := flag.String("env-file", ".env", "Env file to load variables from; the environment takes precedence")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration with secrets redacted, validate it and exit")
+	selfCheck := flag.Bool(strings.TrimPrefix(updater.SelfCheckFlag, "-"), false, "Check the configuration is valid and the API reachable, then exit; updates must pass it")
+	flag.Parse()
+
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	if err := config.LoadFile(*envFile); err != nil {
+		log.Printf("No %s file found, using system environment variables", *envFile)
 	}
 
 	// Load configuration
 	cfg := config.Load()
 
+	if *printConfig {
+		cfg.PrintSettings(os.Stdout)
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "\nConfiguration is invalid:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *selfCheck {
+		if err := runSelfCheck(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Self-check failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Agent %s passed its self-check\n", version)
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Configuration validation failed: %v", err)
+	}
+
 	// Initialize components
-	softwareScanner := scanner.NewSoftwareScanner(cfg)
-	systemScanner := scanner.NewSystemScanner(cfg)
-	networkScanner := scanner.NewNetworkScanner(cfg)
+	osqueryScanner, err := scanner.NewOsqueryScanner(cfg)
+	if err != nil {
+		log.Fatalf("Invalid OSQUERY_QUERIES_PATH: %v", err)
+	}
+	if osqueryScanner.Available() {
+		log.Println("osquery found; software inventory will come from osquery")
+	}
+	scanners := &agentScanners{
+		cfg:       cfg,
+		software:  scanner.NewSoftwareScanner(cfg),
+		system:    scanner.NewSystemScanner(cfg),
+		network:   scanner.NewNetworkScanner(cfg),
+		config:    scanner.NewConfigScanner(cfg),
+		aiml:      scanner.NewAIMLScanner(cfg, nil),
+		container: scanner.NewContainerScanner(cfg),
+	}
+	scanners.software.SetOsquery(osqueryScanner)
+	scanners.registered, err = scanner.NewRegistered(cfg)
+	if err != nil {
+		log.Printf("Some registered scanners are unavailable: %v", err)
+	}
+	if len(scanners.registered) > 0 {
+		names := make([]string, len(scanners.registered))
+		for i, registered := range scanners.registered {
+			names[i] = registered.Name()
+		}
+		log.Printf("Registered scanners: %s", strings.Join(names, ", "))
+	}
+
+	// Image scans' NVD lookups go through a local cache, kept current from
+	// NVD's modified feed, so they don't hit NVD's rate limit again
+	var cveCache *scanner.CVECache
+	if cfg.ContainerImageScan && cfg.CVECachePath != "" {
+		cveCache, err = scanner.OpenCVECache(cfg.CVECachePath, cfg.CVECacheTTL)
+		if err != nil {
+			log.Printf("CVE cache unavailable, NVD lookups will not be cached: %v", err)
+			cveCache = nil
+		} else {
+			defer cveCache.Close()
+			scanners.container.SetCVECache(cveCache)
+		}
+	}
 	processor := processor.NewProcessor(cfg)
-	communicator := communicator.NewCommunicator(cfg)
+	communicator, err := communicator.NewCommunicator(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up API communication: %v", err)
+	}
 
-	// Parse flags
-	disableTray := flag.Bool("no-tray", false, "Disable system tray UI")
-	testTray := flag.Bool("test-tray", false, "Run in tray test mode")
-	flag.Parse()
+	// Releases the API offers are only installed when auto-update is on
+	var agentUpdater *updater.Updater
+	if cfg.AutoUpdate {
+		agentUpdater, err = updater.New(cfg, version)
+		if err != nil {
+			log.Fatalf("Failed to set up auto-update: %v", err)
+		}
+	}
+
+	// All scanners share one resource budget so they don't overwhelm the host together
+	budget := scheduler.NewBudget(cfg)
+
+	// Periodic scans only start within their maintenance windows, if any
+	scanWindow, err := scheduler.ParseWindow(cfg.ScanSchedule)
+	if err != nil {
+		log.Fatalf("Invalid SCAN_SCHEDULE: %v", err)
+	}
+	networkScanWindow, err := scheduler.ParseWindow(cfg.NetworkScanSchedule)
+	if err != nil {
+		log.Fatalf("Invalid NETWORK_SCAN_SCHEDULE: %v", err)
+	}
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Scans run on their own context, so a scan in flight at shutdown can
+	// finish and upload its results instead of being abandoned
+	scanCtx, cancelScans := context.WithCancel(context.Background())
+	defer cancelScans()
+	var scans sync.WaitGroup
+
+	// Signals stop the agent, as does installing an update, after which it
+	// restarts into the new release
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	var restart atomic.Bool
+
 	// Start agent initialization
-	log.Println("Starting ZeroTrace Software Vulnerability Agent...")
+	log.Printf("Starting ZeroTrace Software Vulnerability Agent %s...", version)
 	log.Printf("Agent ID: %s", cfg.AgentID)
 	log.Printf("API Endpoint: %s", cfg.APIEndpoint)
 	log.Printf("Organization ID: %s", cfg.OrganizationID)
@@ -105,49 +275,55 @@ func main() {
 		}
 	}
 
+	// Run only the scanners the API has scoped this agent to
+	scanners.refreshScope(communicator)
+
 	// Function to start all background agent work
 	startAgentWork := func() {
-		// Start software scanning in a goroutine
+		// Start software scanning in a goroutine. Once ctx is done no further
+		// scan starts, but the one running finishes on scanCtx.
+		scans.Add(1)
 		go func() {
+			defer scans.Done()
 			for {
 				select {
 				case <-ctx.Done():
 					return
 				default:
-					// Perform scan
-					results, err := softwareScanner.Scan()
-					if err != nil {
-						log.Printf("Scan error: %v", err)
-						time.Sleep(cfg.ScanInterval)
-						continue
-					}
-
-					// Process results
-					processedResults, err := processor.Process(results)
-					if err != nil {
-						log.Printf("Processing error: %v", err)
-						time.Sleep(cfg.ScanInterval)
-						continue
+					// Intervals outside the window are skipped, not made up
+					if !scanWindow.Wait(ctx, "software scan") {
+						return
 					}
-
-					// Send results to API
-					if err := communicator.SendResults(processedResults); err != nil {
-						log.Printf("Communication error: %v", err)
-					} else {
-						log.Printf("Successfully sent software scan results to API")
+					scanners.refreshScope(communicator)
+					for _, scanType := range scanners.intervalScans() {
+						if !scanners.enabled(scanType) || ctx.Err() != nil {
+							continue
+						}
+						if _, err := runScan(scanCtx, scanType, cfg, budget, scanners, processor, communicator); err != nil {
+							if scanCtx.Err() != nil {
+								return
+							}
+							log.Printf("%s scan error: %v", scanType, err)
+						}
 					}
 
 					// Wait before next scan
 					log.Printf("Next scan in %v", cfg.ScanInterval)
-					time.Sleep(cfg.ScanInterval)
+					if !pause(ctx, cfg.ScanInterval) {
+						return
+					}
 				}
 			}
 		}()
 
 		// Start system info scanning in a goroutine
+		scans.Add(1)
 		go func() {
+			defer scans.Done()
 			// Perform an initial scan right away
-			sendSystemInfo(ctx, systemScanner, communicator)
+			if scanners.enabled(scanner.ScannerSystem) {
+				sendSystemInfo(scanCtx, budget, scanners.system, communicator)
+			}
 
 			// Then scan on a longer interval
 			ticker := time.NewTicker(1 * time.Hour)
@@ -158,53 +334,137 @@ func main() {
 				case <-ctx.Done():
 					return
 				case <-ticker.C:
-					sendSystemInfo(ctx, systemScanner, communicator)
+					if scanners.enabled(scanner.ScannerSystem) {
+						sendSystemInfo(scanCtx, budget, scanners.system, communicator)
+					}
 				}
 			}
 		}()
 
 		// Start network scanning in a goroutine (if enabled)
 		if cfg.NetworkScanEnabled {
+			scans.Add(1)
 			go func() {
-				// Perform an initial scan after a short delay
-				time.Sleep(30 * time.Second)
-				sendNetworkScan(ctx, networkScanner, communicator)
+				defer scans.Done()
+				// Scan after a short delay and then on the configured
+				// interval, each time within the network scan window
+				if !pause(ctx, 30*time.Second) {
+					return
+				}
+				for networkScanWindow.Wait(ctx, "network scan") {
+					if scanners.enabled(scanner.ScannerNetwork) {
+						sendNetworkScan(scanCtx, budget, scanners.network, communicator)
+					}
+					if !pause(ctx, cfg.NetworkScanInterval) {
+						return
+					}
+				}
+			}()
+			log.Printf("Network scanning enabled (interval: %v, window: %s)", cfg.NetworkScanInterval, networkScanWindow)
+		} else {
+			log.Println("Network scanning disabled")
+		}
 
-				// Then scan on configured interval
-				ticker := time.NewTicker(cfg.NetworkScanInterval)
-				defer ticker.Stop()
+		// Retry results the API could not take when they were scanned
+		go communicator.RunSpoolFlusher(ctx)
 
+		// Install releases the API offers with heartbeats, then shut down and
+		// restart into them
+		if agentUpdater != nil {
+			go func() {
 				for {
 					select {
 					case <-ctx.Done():
 						return
-					case <-ticker.C:
-						sendNetworkScan(ctx, networkScanner, communicator)
+					case update := <-communicator.Updates():
+						if err := agentUpdater.Install(ctx, update); err != nil {
+							if !errors.Is(err, updater.ErrNotNewer) {
+								log.Printf("Failed to update to agent %s: %v", update.Version, err)
+							}
+							continue
+						}
+						log.Printf("Installed agent %s, restarting", update.Version)
+						restart.Store(true)
+						select {
+						case quit <- syscall.SIGTERM:
+						default:
+						}
+						return
 					}
 				}
 			}()
-			log.Printf("Network scanning enabled (interval: %v)", cfg.NetworkScanInterval)
+			log.Printf("Auto-update enabled (%s)", updater.Platform())
+		}
+
+		// Run commands the API delivers with heartbeats, such as an org-wide re-scan
+		scans.Add(1)
+		go func() {
+			defer scans.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case cmd := <-communicator.Commands():
+					handleCommand(scanCtx, cmd, cfg, budget, scanners, processor, communicator)
+				}
+			}
+		}()
+
+		// Run scans the tray asks for over the local control socket, alongside
+		// the regular scan interval
+		if listener, err := ipc.Listen(cfg.IPCAddress); err != nil {
+			log.Printf("Failed to listen on %s, Scan Now is unavailable: %v", cfg.IPCAddress, err)
 		} else {
-			log.Println("Network scanning disabled")
+			go ipc.Serve(ctx, listener, func(ctx context.Context, req ipc.Request) ipc.Response {
+				return handleIPCRequest(ctx, req, cfg, budget, scanners, processor, communicator)
+			})
+			log.Printf("Listening for local commands on %s", cfg.IPCAddress)
+		}
+
+		// Load NVD's modified feed into the CVE cache now and then on the
+		// sync interval
+		if cveCache != nil && cfg.NVDFeedSyncInterval > 0 {
+			go func() {
+				client := &http.Client{Timeout: 10 * time.Minute}
+				ticker := time.NewTicker(cfg.NVDFeedSyncInterval)
+				defer ticker.Stop()
+				for {
+					if loaded, err := cveCache.SyncNVDFeed(ctx, client, cfg.NVDFeedURL); err != nil {
+						log.Printf("NVD feed sync failed: %v", err)
+					} else if loaded > 0 {
+						log.Printf("Loaded %d changed CVE records from the NVD feed", loaded)
+					}
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
 		}
 
 		// Start heartbeat in a goroutine
 		go func() {
 			ticker := time.NewTicker(30 * time.Second)
 			defer ticker.Stop()
+			sampler := metrics.NewSampler()
 
 			for {
 				select {
 				case <-ctx.Done():
 					return
 				case <-ticker.C:
-					cpuUsage := 2.5
-					memoryUsage := 45.2
+					usage := sampler.Sample(ctx)
+					cpuUsage, memoryUsage := usage.ProcessCPUPercent, usage.ProcessMemoryPercent
 
 					metadata := map[string]any{
-						"scan_interval": cfg.ScanInterval.String(),
-						"scan_depth":    cfg.ScanDepth,
-						"version":       "1.0.0",
+						"scan_interval":  cfg.ScanInterval.String(),
+						"scan_depth":     cfg.ScanDepth,
+						"version":        version,
+						"platform":       updater.Platform(),
+						"tool_versions":  scanner.AllToolVersions(),
+						"missing_tools":  scanner.MissingTools(),
+						"resource_usage": usage.Metadata(),
 					}
 
 					if cfg.IsEnrolled() {
@@ -237,7 +497,7 @@ func main() {
 		runtime.LockOSThread()
 		
 		// Create tray manager
-		trayMgr := tray.NewSimpleTrayManager()
+		trayMgr := tray.NewSimpleTrayManager(cfg.IPCAddress)
 		trayManager = trayMgr
 		
 		// Define onReady callback that starts agent work
@@ -255,6 +515,12 @@ func main() {
 			cancel()
 		}
 		
+		// Signals and updates stop the agent through the tray
+		go func() {
+			<-quit
+			systray.Quit()
+		}()
+
 		// Run systray on main thread - this blocks until systray.Quit() is called
 		log.Println("Starting systray on main thread (macOS)...")
 		systray.Run(onReady, onExit)
@@ -266,7 +532,7 @@ func main() {
 		
 	} else if !*disableTray {
 		// Non-macOS: can run systray in goroutine
-		trayManager = tray.NewSimpleTrayManager()
+		trayManager = tray.NewSimpleTrayManager(cfg.IPCAddress)
 		go func() {
 			defer func() {
 				if r := recover(); r != nil {
@@ -281,8 +547,6 @@ func main() {
 		startAgentWork()
 		
 		// Wait for interrupt signal
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 		
 		log.Println("Shutting down agent...")
@@ -298,8 +562,6 @@ func main() {
 		startAgentWork()
 		
 		// Wait for interrupt signal
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 		
 		log.Println("Shutting down agent...")
@@ -310,31 +572,228 @@ func main() {
 		select {}
 	}
 
-	// Graceful shutdown
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer shutdownCancel()
+	// Graceful shutdown: no new scans start, but those in flight get until the
+	// drain timeout to finish and upload their results
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.ShutdownDrainTimeout)
+	defer cancelDrain()
 
-	select {
-	case <-shutdownCtx.Done():
-		log.Println("Forced shutdown")
-	case <-time.After(5 * time.Second):
+	drained := waitForScans(drainCtx, &scans)
+	if !drained {
+		cancelScans()
+	}
+
+	// Send results queued while the API was unreachable, including any the
+	// drained scans could not upload
+	communicator.FlushSpool(drainCtx)
+
+	if drained {
 		log.Println("Graceful shutdown completed")
+	} else {
+		log.Printf("Forced shutdown: scans still running after %v were abandoned", cfg.ShutdownDrainTimeout)
+	}
+
+	if restart.Load() {
+		if err := agentUpdater.Relaunch(); err != nil {
+			log.Fatalf("Failed to restart into the updated agent: %v", err)
+		}
+	}
+}
+
+// runSelfCheck checks that the agent can run with its configuration: that the
+// configuration is valid and the API reachable
+func runSelfCheck(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	communicator, err := communicator.NewCommunicator(cfg)
+	if err != nil {
+		return err
+	}
+	return communicator.CheckAPIStatus()
+}
+
+// waitForScans waits for the scan goroutines to return, reporting false if
+// ctx is done first
+func waitForScans(ctx context.Context, scans *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		scans.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pause waits for d, returning false if ctx is done first
+func pause(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// runSoftwareScan scans installed software, processes the results and sends
+// them to the API, returning the number of findings
+func runSoftwareScan(ctx context.Context, budget *scheduler.Budget, softwareScanner *scanner.SoftwareScanner, processor *processor.Processor, communicator *communicator.Communicator) (int, error) {
+	var results *models.ScanResult
+	var err error
+	if budgetErr := budget.Run(ctx, "software scan", scheduler.Heavy, func() {
+		results, err = softwareScanner.Scan()
+	}); budgetErr != nil {
+		return 0, budgetErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("scan failed: %w", err)
+	}
+
+	processedResults, err := processor.Process(results)
+	if err != nil {
+		return 0, fmt.Errorf("processing failed: %w", err)
+	}
+
+	if err := communicator.SendResults(processedResults); err != nil {
+		return 0, fmt.Errorf("failed to send results: %w", err)
+	}
+	log.Printf("Successfully sent software scan results to API")
+	return len(processedResults.Vulnerabilities), nil
+}
+
+// runAIMLScan scans the paths the scan scope gives the AI/ML scanner, the
+// user's home directory by default, and sends the findings to the API
+func runAIMLScan(ctx context.Context, budget *scheduler.Budget, scanners *agentScanners, processor *processor.Processor, communicator *communicator.Communicator) (int, error) {
+	var roots []string
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, home)
+	}
+	roots = scanners.scope.Load().PathsFor(scanner.ScannerAIML, roots)
+
+	var results *models.ScanResult
+	var err error
+	if budgetErr := budget.Run(ctx, "AI/ML scan", scheduler.Heavy, func() {
+		results, err = scanners.aiml.ScanPaths(ctx, roots)
+	}); budgetErr != nil {
+		return 0, budgetErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("scan failed: %w", err)
+	}
+
+	findings := len(results.Vulnerabilities)
+	commit := processor.ApplyDelta(results)
+	if err := communicator.SendResults(results); err != nil {
+		return 0, fmt.Errorf("failed to send results: %w", err)
 	}
+	commit()
+	log.Printf("Successfully sent AI/ML scan results to API (%d findings)", findings)
+	return findings, nil
 }
 
-func sendSystemInfo(ctx context.Context, systemScanner *scanner.SystemScanner, communicator *communicator.Communicator) {
+// runContainerScan scans containers, Kubernetes and the IaC files under the
+// paths the scan scope gives the container scanner, the user's home directory
+// by default, suppressing the organization's allowlisted findings, and sends
+// the findings to the API
+func runContainerScan(ctx context.Context, budget *scheduler.Budget, scanners *agentScanners, processor *processor.Processor, communicator *communicator.Communicator) (int, error) {
+	containerScanner := scanners.container
+	var roots []string
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, home)
+	}
+	containerScanner.SetIaCRoots(scanners.scope.Load().PathsFor(scanner.ScannerContainer, roots))
+
+	if rules, err := communicator.GetContainerAllowlist(); err != nil {
+		log.Printf("Failed to fetch container allowlist, keeping the current one: %v", err)
+	} else {
+		containerScanner.SetAllowlist(rules)
+	}
+
+	var results *models.ScanResult
+	var err error
+	if budgetErr := budget.Run(ctx, "container scan", scheduler.Heavy, func() {
+		results, err = containerScanner.ScanResult()
+	}); budgetErr != nil {
+		return 0, budgetErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("scan failed: %w", err)
+	}
+
+	findings := len(results.Vulnerabilities)
+	commit := processor.ApplyDelta(results)
+	if err := communicator.SendResults(results); err != nil {
+		return 0, fmt.Errorf("failed to send results: %w", err)
+	}
+	commit()
+	log.Printf("Successfully sent container scan results to API (%d findings)", findings)
+	return findings, nil
+}
+
+// runRegisteredScan runs a registered scanner, such as the configuration
+// scanner or a custom one, and sends its findings to the API
+func runRegisteredScan(ctx context.Context, budget *scheduler.Budget, registered scanner.Scanner, processor *processor.Processor, communicator *communicator.Communicator) (int, error) {
+	// Refresh the approved baseline so drift is reported against the current version
+	if configScanner, ok := registered.(*scanner.ConfigScanner); ok {
+		if baseline, err := communicator.GetConfigBaseline(); err != nil {
+			log.Printf("Failed to fetch config baseline: %v", err)
+		} else {
+			configScanner.SetBaseline(baseline)
+		}
+	}
+
+	class := scheduler.Light
+	if scanner.IsHeavy(registered) {
+		class = scheduler.Heavy
+	}
+	var results *models.ScanResult
+	var err error
+	if budgetErr := budget.Run(ctx, registered.Name()+" scan", class, func() {
+		results, err = registered.Scan(ctx)
+	}); budgetErr != nil {
+		return 0, budgetErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("scan failed: %w", err)
+	}
+
+	findings := len(results.Vulnerabilities)
+	commit := processor.ApplyDelta(results)
+	if err := communicator.SendResults(results); err != nil {
+		return 0, fmt.Errorf("failed to send results: %w", err)
+	}
+	commit()
+	log.Printf("Successfully sent %s scan results to API (%d findings)", registered.Name(), findings)
+	return findings, nil
+}
+
+func sendSystemInfo(ctx context.Context, budget *scheduler.Budget, systemScanner *scanner.SystemScanner, communicator *communicator.Communicator) error {
 	log.Println("Scanning for system information...")
-	sysInfo, err := systemScanner.Scan()
+	var sysInfo *scanner.SystemInfo
+	var err error
+	if budgetErr := budget.Run(ctx, "system info scan", scheduler.Light, func() {
+		sysInfo, err = systemScanner.Scan()
+	}); budgetErr != nil {
+		return budgetErr
+	}
 	if err != nil {
 		log.Printf("System info scan error: %v", err)
-		return
+		return err
 	}
 
 	if err := communicator.SendSystemInfo(sysInfo); err != nil {
 		log.Printf("Failed to send system info: %v", err)
-	} else {
-		log.Println("Successfully sent system information to API.")
+		return err
 	}
+	log.Println("Successfully sent system information to API.")
+	return nil
 }
 
 // sendNetworkScan performs agentless network scanning
@@ -342,12 +801,18 @@ func sendSystemInfo(ctx context.Context, systemScanner *scanner.SystemScanner, c
 // not something installed on target devices. It scans other devices on the network
 // using network protocols (Nmap, Nuclei) without requiring any agent installation
 // on the target devices. Similar to how Tenable sensors work.
-func sendNetworkScan(ctx context.Context, networkScanner *scanner.NetworkScanner, communicator *communicator.Communicator) {
+func sendNetworkScan(ctx context.Context, budget *scheduler.Budget, networkScanner *scanner.NetworkScanner, communicator *communicator.Communicator) (int, error) {
 	log.Println("Starting agentless network scan...")
-	scanResult, err := networkScanner.ScanLocalNetwork()
+	var scanResult *scanner.NetworkScanResult
+	var err error
+	if budgetErr := budget.Run(ctx, "network scan", scheduler.Heavy, func() {
+		scanResult, err = networkScanner.ScanLocalNetwork()
+	}); budgetErr != nil {
+		return 0, budgetErr
+	}
 	if err != nil {
 		log.Printf("Network scan error: %v", err)
-		return
+		return 0, err
 	}
 
 	totalHosts := 0
@@ -362,7 +827,311 @@ func sendNetworkScan(ctx context.Context, networkScanner *scanner.NetworkScanner
 
 	if err := communicator.SendNetworkScanResults(scanResult); err != nil {
 		log.Printf("Failed to send network scan results: %v", err)
-	} else {
-		log.Println("Successfully sent network scan results to API.")
+		return 0, err
+	}
+	log.Println("Successfully sent network scan results to API.")
+	return len(scanResult.NetworkFindings), nil
+}
+
+// handleCommand runs a command from the API and reports its progress back
+func handleCommand(ctx context.Context, cmd models.AgentCommand, cfg *config.Config, budget *scheduler.Budget, scanners *agentScanners, processor *processor.Processor, communicator *communicator.Communicator) {
+	if cmd.Type == models.CommandVerifyFinding {
+		verifyFinding(ctx, cmd, budget, scanners, communicator)
+		return
+	}
+	if cmd.Type == models.CommandCollect {
+		// A run can take hours; other commands are not held up behind it
+		go runCollection(ctx, cmd, cfg, communicator)
+		return
+	}
+	if cmd.Type != models.CommandScanNow {
+		log.Printf("Ignoring unsupported command %s (%s)", cmd.ID, cmd.Type)
+		if err := communicator.ReportCommandStatus(cmd.ID, models.CommandFailed, "unsupported command type "+cmd.Type); err != nil {
+			log.Printf("Failed to report command status: %v", err)
+		}
+		return
+	}
+
+	log.Printf("Received scan_now command %s", cmd.ID)
+	if err := communicator.ReportCommandStatus(cmd.ID, models.CommandAcked, ""); err != nil {
+		log.Printf("Failed to acknowledge command %s: %v", cmd.ID, err)
+	}
+
+	// Without explicit scan types, run every scanner in the agent's scope
+	var scanTypes []string
+	if requested, ok := cmd.Payload["scan_types"].([]any); ok {
+		for _, t := range requested {
+			if name, ok := t.(string); ok {
+				scanTypes = append(scanTypes, name)
+			}
+		}
+	}
+
+	_, failures := runScans(ctx, scanTypes, cfg, budget, scanners, processor, communicator)
+	status, errMsg := models.CommandCompleted, ""
+	if len(failures) > 0 {
+		status, errMsg = models.CommandFailed, strings.Join(failures, "; ")
+	}
+	if err := communicator.ReportCommandStatus(cmd.ID, status, errMsg); err != nil {
+		log.Printf("Failed to report command %s as %s: %v", cmd.ID, status, err)
+	}
+}
+
+// ipcScanRunning is set while a scan the tray asked for is running
+var ipcScanRunning atomic.Bool
+
+// handleIPCRequest runs a command from the local control socket. Scan Now
+// runs every scanner in the agent's scope once, turning away a second request
+// while one is running.
+func handleIPCRequest(ctx context.Context, req ipc.Request, cfg *config.Config, budget *scheduler.Budget, scanners *agentScanners, processor *processor.Processor, communicator *communicator.Communicator) ipc.Response {
+	if req.Command != ipc.CommandScanNow {
+		return ipc.Response{Error: fmt.Sprintf("unsupported command %q", req.Command)}
+	}
+	if !ipcScanRunning.CompareAndSwap(false, true) {
+		return ipc.Response{Error: "a scan is already running"}
+	}
+	defer ipcScanRunning.Store(false)
+
+	log.Println("Running scan requested over the local control socket")
+	findings, failures := runScans(ctx, nil, cfg, budget, scanners, processor, communicator)
+	log.Printf("Requested scan finished: %d findings", findings)
+	if len(failures) > 0 {
+		return ipc.Response{Findings: findings, Error: strings.Join(failures, "; ")}
+	}
+	return ipc.Response{OK: true, Findings: findings}
+}
+
+// runScans runs the given scan types once, or every scanner in the agent's
+// scope when none are given, returning the number of findings they sent and
+// a description of each that failed
+func runScans(ctx context.Context, scanTypes []string, cfg *config.Config, budget *scheduler.Budget, scanners *agentScanners, processor *processor.Processor, communicator *communicator.Communicator) (int, []string) {
+	if len(scanTypes) == 0 {
+		for _, scanType := range append([]string{scanner.ScannerSystem, scanner.ScannerNetwork}, scanners.intervalScans()...) {
+			if scanners.enabled(scanType) {
+				scanTypes = append(scanTypes, scanType)
+			}
+		}
+	}
+
+	findings := 0
+	var failures []string
+	for _, scanType := range scanTypes {
+		if !scanners.enabled(scanType) {
+			log.Printf("Skipping requested %s scan: not in this agent's scan scope", scanType)
+			continue
+		}
+
+		if scanType == scanner.ScannerNetwork && !cfg.NetworkScanEnabled {
+			log.Println("Skipping requested network scan: network scanning is disabled")
+			continue
+		}
+		count, err := runScan(ctx, scanType, cfg, budget, scanners, processor, communicator)
+		findings += count
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", scanType, err))
+		}
+	}
+	return findings, failures
+}
+
+// runScan runs one scan of the given type, a built-in scanner or a
+// registered one, returning the number of findings it sent
+func runScan(ctx context.Context, scanType string, cfg *config.Config, budget *scheduler.Budget, scanners *agentScanners, processor *processor.Processor, communicator *communicator.Communicator) (int, error) {
+	switch scanType {
+	case scanner.ScannerSoftware:
+		return runSoftwareScan(ctx, budget, scanners.software, processor, communicator)
+	case scanner.ScannerSystem:
+		return 0, sendSystemInfo(ctx, budget, scanners.system, communicator)
+	case scanner.ScannerNetwork:
+		return sendNetworkScan(ctx, budget, scanners.network, communicator)
+	case scanner.ScannerAIML:
+		return runAIMLScan(ctx, budget, scanners, processor, communicator)
+	case scanner.ScannerContainer:
+		return runContainerScan(ctx, budget, scanners, processor, communicator)
+	}
+	if registered := scanners.lookup(scanType); registered != nil {
+		return runRegisteredScan(ctx, budget, registered, processor, communicator)
+	}
+	return 0, fmt.Errorf("unsupported scan type")
+}
+
+// collectionProgressInterval is the most often a collection run reports progress
+const collectionProgressInterval = 10 * time.Second
+
+// runCollection collects from the hosts a collect command lists over SSH or
+// WinRM, with the credentials this agent is configured with, within the
+// concurrency, per-host timeout and auth failure budget the API sends.
+// Per-host results are reported as progress while the run goes, and in full
+// when it finishes.
+func runCollection(ctx context.Context, cmd models.AgentCommand, cfg *config.Config, communicator *communicator.Communicator) {
+	log.Printf("Received collect command %s", cmd.ID)
+	fail := func(err error) {
+		log.Printf("Collection %s failed: %v", cmd.ID, err)
+		if reportErr := communicator.ReportCommandStatus(cmd.ID, models.CommandFailed, err.Error()); reportErr != nil {
+			log.Printf("Failed to report command %s as failed: %v", cmd.ID, reportErr)
+		}
+	}
+
+	protocol, _ := cmd.Payload["protocol"].(string)
+	var c collector.Collector
+	var err error
+	switch protocol {
+	case "ssh":
+		c, err = collector.NewSSHCollector(cfg.CollectorSSHUsername, cfg.CollectorSSHPassword, cfg.CollectorSSHKeyPath, cfg.CollectorSSHKnownHosts)
+	case "winrm":
+		c, err = collector.NewWinRMCollector(cfg.CollectorWinRMUsername, cfg.CollectorWinRMPassword, cfg.CollectorWinRMHTTPS)
+	default:
+		err = fmt.Errorf("unsupported collection protocol %q", protocol)
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	var targets []string
+	if requested, ok := cmd.Payload["targets"].([]any); ok {
+		for _, t := range requested {
+			if host, ok := t.(string); ok {
+				targets = append(targets, host)
+			}
+		}
+	}
+	number := func(key string) int {
+		n, _ := cmd.Payload[key].(float64)
+		return int(n)
+	}
+	opts := collector.Options{
+		Concurrency:       number("max_concurrency"),
+		HostTimeout:       time.Duration(number("host_timeout_seconds")) * time.Second,
+		AuthFailureBudget: number("auth_failure_budget"),
+	}
+
+	if err := communicator.ReportCommandStatus(cmd.ID, models.CommandAcked, ""); err != nil {
+		log.Printf("Failed to acknowledge command %s: %v", cmd.ID, err)
+	}
+
+	var lastProgress time.Time
+	report := collector.Run(ctx, c, targets, opts, func(progress collector.Report) {
+		if time.Since(lastProgress) < collectionProgressInterval {
+			return
+		}
+		lastProgress = time.Now()
+		if err := communicator.ReportCommandProgress(cmd.ID, progress); err != nil {
+			log.Printf("Failed to report progress of command %s: %v", cmd.ID, err)
+		}
+	})
+
+	if report.Paused {
+		log.Printf("Collection %s paused after %d authentication failures", cmd.ID, report.AuthFailures)
+	}
+	log.Printf("Collection %s over %s finished: %d hosts", cmd.ID, protocol, len(report.Hosts))
+	if err := communicator.ReportCommandResult(cmd.ID, report); err != nil {
+		log.Printf("Failed to report result of command %s: %v", cmd.ID, err)
+	}
+}
+
+// verifyFinding re-runs the check behind one finding and reports whether the
+// finding still holds. Configuration findings re-run their check; software
+// findings re-inventory their package, and the API matches the installed
+// version against the finding's CVE. Other findings cannot be re-checked on
+// their own and are reported unverifiable.
+func verifyFinding(ctx context.Context, cmd models.AgentCommand, budget *scheduler.Budget, scanners *agentScanners, communicator *communicator.Communicator) {
+	log.Printf("Received verify_finding command %s", cmd.ID)
+	if err := communicator.ReportCommandStatus(cmd.ID, models.CommandAcked, ""); err != nil {
+		log.Printf("Failed to acknowledge command %s: %v", cmd.ID, err)
+	}
+
+	scope, _ := cmd.Payload["scope"].(string)
+	title, _ := cmd.Payload["title"].(string)
+	packageName, _ := cmd.Payload["package_name"].(string)
+
+	var report models.FindingVerification
+	var err error
+	weight := scheduler.Light
+	if scope == "software" {
+		weight = scheduler.Heavy
+	}
+	if budgetErr := budget.Run(ctx, "finding verification", weight, func() {
+		switch scope {
+		case "configuration":
+			report = verifyConfigFinding(scanners.config, title)
+		case "software":
+			report, err = verifySoftwareFinding(scanners.software, packageName)
+		default:
+			report = models.FindingVerification{
+				Status:  models.VerificationUnverifiable,
+				Details: fmt.Sprintf("Re-checking %s findings on their own is not supported", scope),
+			}
+		}
+	}); budgetErr != nil {
+		err = budgetErr
+	}
+	if err != nil {
+		if reportErr := communicator.ReportCommandStatus(cmd.ID, models.CommandFailed, err.Error()); reportErr != nil {
+			log.Printf("Failed to report command %s as failed: %v", cmd.ID, reportErr)
+		}
+		return
+	}
+
+	log.Printf("Verified finding %q: %s", title, report.Status)
+	if err := communicator.ReportCommandResult(cmd.ID, report); err != nil {
+		log.Printf("Failed to report verification result of command %s: %v", cmd.ID, err)
+	}
+}
+
+// verifyConfigFinding re-runs the configuration check a finding is named after
+func verifyConfigFinding(configScanner *scanner.ConfigScanner, check string) models.FindingVerification {
+	result, evidence, found := configScanner.RunCheck(check)
+	if !found {
+		return models.FindingVerification{
+			Status:  models.VerificationUnverifiable,
+			Details: fmt.Sprintf("No %q check on %s", check, runtime.GOOS),
+		}
+	}
+
+	report := models.FindingVerification{Details: result.Details, CheckResult: result, Evidence: evidence}
+	switch {
+	case result.Errored:
+		report.Status = models.VerificationUnverifiable
+	case result.Passed:
+		report.Status = models.VerificationResolved
+	default:
+		report.Status = models.VerificationConfirmed
+	}
+	return report
+}
+
+// verifySoftwareFinding checks whether a finding's package is still installed
+func verifySoftwareFinding(softwareScanner *scanner.SoftwareScanner, packageName string) (models.FindingVerification, error) {
+	if packageName == "" {
+		return models.FindingVerification{
+			Status:  models.VerificationUnverifiable,
+			Details: "Finding names no package to look for",
+		}, nil
+	}
+
+	results, err := softwareScanner.Scan()
+	if err != nil {
+		return models.FindingVerification{}, fmt.Errorf("software inventory failed: %w", err)
+	}
+	for _, dep := range results.Dependencies {
+		if strings.EqualFold(dep.Name, packageName) {
+			return models.FindingVerification{
+				Status:  models.VerificationConfirmed,
+				Details: fmt.Sprintf("%s %s is still installed", dep.Name, dep.Version),
+				CheckResult: map[string]any{
+					"observed": dep.Version,
+					"expected": "not installed, or a version the finding does not affect",
+				},
+			}, nil
+		}
 	}
+	return models.FindingVerification{
+		Status:  models.VerificationResolved,
+		Details: fmt.Sprintf("%s is no longer installed", packageName),
+		CheckResult: map[string]any{
+			"passed":   true,
+			"expected": "not installed, or a version the finding does not affect",
+		},
+	}, nil
 }
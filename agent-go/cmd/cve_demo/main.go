@@ -14,6 +14,7 @@ func main() {
 	// Initialize CVE sources
 	nvdSource := scanner.NewNVDSource("") // No API key for demo (rate limited)
 	githubSource := scanner.NewGitHubAdvisorySource()
+	osvSource := scanner.NewOSVSource()
 
 	fmt.Println("\n Fetching real CVE data from NIST NVD...")
 
@@ -47,6 +48,19 @@ func main() {
 		}
 	}
 
+	// Match an installed package version against OSV.dev
+	fmt.Println("\n Matching jinja2 2.11.2 against OSV.dev...")
+	advisories, err := osvSource.PackageAdvisories([]scanner.PackageQuery{{Ecosystem: "PyPI", Name: "jinja2", Version: "2.11.2"}})
+	if err != nil {
+		log.Printf("Error matching package: %v", err)
+	} else {
+		fmt.Printf(" Found %d advisories:\n", len(advisories))
+		for i, vuln := range advisories {
+			fmt.Printf("   %d. %s (%s) - %s\n",
+				i+1, vuln.ID, vuln.Severity, truncateString(vuln.Title, 50))
+		}
+	}
+
 	fmt.Println("\n How to integrate real CVE data:")
 	fmt.Println("1. Get NVD API key from: https://nvd.nist.gov/developers/request-an-api-key")
 	fmt.Println("2. Set environment variable: NVD_API_KEY=your_key_here")
@@ -57,6 +71,8 @@ func main() {
 	fmt.Println("\n Real CVE Sources Available:")
 	fmt.Println("• NIST National Vulnerability Database (NVD)")
 	fmt.Println("• GitHub Security Advisories")
+	fmt.Println("• OSV.dev, matched by package version")
+	fmt.Println("• GitLab Advisory Database")
 	fmt.Println("• MITRE CVE Database")
 	fmt.Println("• Vendor Security Advisories")
 	fmt.Println("• Custom vulnerability feeds")
@@ -1,25 +1,39 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"os/exec"
 	"runtime"
+	"time"
+
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/ipc"
 
 	"github.com/getlantern/systray"
 )
 
+// scanNowTimeout is the longest the tray waits for a requested scan to finish
+const scanNowTimeout = time.Hour
+
 type TrayApp struct {
 	apiURL       string
 	dashboardURL string
+	agentSocket  string
 }
 
 func main() {
 	app := &TrayApp{
 		apiURL:       "http://localhost:8080",
 		dashboardURL: "http://localhost:5173",
+		agentSocket:  config.DefaultIPCAddress(),
+	}
+	if socket := os.Getenv("AGENT_SOCKET"); socket != "" {
+		app.agentSocket = socket
 	}
 
 	systray.Run(app.onReady, app.onExit)
@@ -34,6 +48,7 @@ func (app *TrayApp) onReady() {
 	// Create menu items
 	mDashboard := systray.AddMenuItem("Open Dashboard", "Open ZeroTrace Dashboard")
 	mStatus := systray.AddMenuItem("Check Status", "Check Agent Status")
+	mScanNow := systray.AddMenuItem("Scan Now", "Run a scan on this machine now")
 	mVulns := systray.AddMenuItem("View Vulnerabilities", "View Security Vulnerabilities")
 	systray.AddSeparator()
 	mAbout := systray.AddMenuItem("About", "About ZeroTrace")
@@ -48,6 +63,8 @@ func (app *TrayApp) onReady() {
 				app.openDashboard()
 			case <-mStatus.ClickedCh:
 				app.checkStatus()
+			case <-mScanNow.ClickedCh:
+				go app.scanNow(mScanNow)
 			case <-mVulns.ClickedCh:
 				app.viewVulnerabilities()
 			case <-mAbout.ClickedCh:
@@ -112,6 +129,22 @@ func (app *TrayApp) checkStatus() {
 	}
 }
 
+// scanNow asks the local agent to scan, and shows how many findings it sent
+func (app *TrayApp) scanNow(item *systray.MenuItem) {
+	item.Disable()
+	defer item.Enable()
+	app.showNotification("ZeroTrace Scan", "Scan started", "Scanning this machine")
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanNowTimeout)
+	defer cancel()
+	findings, err := ipc.ScanNow(ctx, app.agentSocket)
+	if err != nil {
+		app.showNotification("ZeroTrace Scan", "Scan failed", err.Error())
+		return
+	}
+	app.showNotification("ZeroTrace Scan", "Scan complete", fmt.Sprintf("%d findings", findings))
+}
+
 func (app *TrayApp) viewVulnerabilities() {
 	resp, err := http.Get(app.apiURL + "/api/vulnerabilities/")
 	if err != nil {
@@ -0,0 +1,150 @@
+// Package collector collects from other hosts on the agent's network over
+// SSH or WinRM, with credentials the agent holds, without installing an
+// agent on them.
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrAuthFailed marks a host that rejected the collector's credentials.
+// Collectors wrap it so runs can count failures against their budget.
+var ErrAuthFailed = errors.New("authentication failed")
+
+// Per-host outcomes, as the API records them
+const (
+	StatusSucceeded  = "succeeded"
+	StatusFailed     = "failed"
+	StatusAuthFailed = "auth_failed"
+	StatusSkipped    = "skipped"
+)
+
+// Collector collects from one host
+type Collector interface {
+	Collect(ctx context.Context, host string) (map[string]any, error)
+}
+
+// HostResult is the outcome of collecting from one host
+type HostResult struct {
+	Host       string         `json:"host"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// Report is a run's progress, and once it returns, its outcome
+type Report struct {
+	Hosts        []HostResult `json:"hosts"`
+	AuthFailures int          `json:"auth_failures"`
+	Paused       bool         `json:"paused"` // The auth failure budget ran out; hosts not yet started were skipped
+}
+
+// Options bound a run
+type Options struct {
+	Concurrency       int           // Hosts collected from at once
+	HostTimeout       time.Duration // Longest one host's connection and collection may take (0 = no limit)
+	AuthFailureBudget int           // Authentication failures after which the run pauses (0 = never)
+}
+
+// Run collects from every target with at most opts.Concurrency hosts at once.
+// Once opts.AuthFailureBudget hosts have rejected the credentials, the run
+// pauses: hosts already started finish, and the rest are skipped, so a wrong
+// credential does not go on to lock accounts out across the fleet. progress,
+// when set, is called with a snapshot after every host; calls never overlap.
+func Run(ctx context.Context, c Collector, targets []string, opts Options, progress func(Report)) Report {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mu         sync.Mutex
+		report     = Report{Hosts: make([]HostResult, 0, len(targets))}
+		progressMu sync.Mutex
+	)
+	record := func(result HostResult) {
+		mu.Lock()
+		report.Hosts = append(report.Hosts, result)
+		if result.Status == StatusAuthFailed {
+			report.AuthFailures++
+			if opts.AuthFailureBudget > 0 && report.AuthFailures >= opts.AuthFailureBudget {
+				report.Paused = true
+			}
+		}
+		snapshot := report
+		snapshot.Hosts = append([]HostResult(nil), report.Hosts...)
+		mu.Unlock()
+
+		if progress != nil {
+			progressMu.Lock()
+			progress(snapshot)
+			progressMu.Unlock()
+		}
+	}
+	stopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return report.Paused || ctx.Err() != nil
+	}
+
+	// A slot frees only once its host is recorded, so no host starts after the budget runs out
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	next := 0
+	for ; next < len(targets); next++ {
+		slots <- struct{}{}
+		if stopped() {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			record(collectHost(ctx, c, host, opts.HostTimeout))
+			<-slots
+		}(targets[next])
+	}
+	wg.Wait()
+
+	reason := "run paused: auth failure budget exhausted"
+	if !report.Paused {
+		reason = "run stopped: agent shutting down"
+	}
+	for _, host := range targets[next:] {
+		report.Hosts = append(report.Hosts, HostResult{Host: host, Status: StatusSkipped, Error: reason})
+	}
+	return report
+}
+
+// collectHost collects from one host within its timeout
+func collectHost(ctx context.Context, c Collector, host string, timeout time.Duration) HostResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	data, err := c.Collect(ctx, host)
+	result := HostResult{Host: host, DurationMS: time.Since(start).Milliseconds()}
+	switch {
+	case err == nil:
+		result.Status = StatusSucceeded
+		result.Data = data
+	case errors.Is(err, ErrAuthFailed):
+		result.Status = StatusAuthFailed
+		result.Error = err.Error()
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Status = StatusFailed
+		result.Error = fmt.Sprintf("timed out after %s", timeout)
+	default:
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCollector answers from a fixed outcome per host and tracks how many
+// hosts it is collecting from at once
+type fakeCollector struct {
+	mu       sync.Mutex
+	active   int
+	peak     int
+	outcomes map[string]error
+	delay    time.Duration
+}
+
+func (f *fakeCollector) Collect(ctx context.Context, host string) (map[string]any, error) {
+	f.mu.Lock()
+	f.active++
+	if f.active > f.peak {
+		f.peak = f.active
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := f.outcomes[host]; err != nil {
+		return nil, err
+	}
+	return map[string]any{"host": host}, nil
+}
+
+func countStatuses(report Report) map[string]int {
+	counts := make(map[string]int)
+	for _, host := range report.Hosts {
+		counts[host.Status]++
+	}
+	return counts
+}
+
+func TestRunBoundsConcurrency(t *testing.T) {
+	var targets []string
+	for i := 0; i < 20; i++ {
+		targets = append(targets, fmt.Sprintf("10.0.0.%d", i))
+	}
+	c := &fakeCollector{delay: 5 * time.Millisecond}
+
+	progressCalls := 0
+	report := Run(context.Background(), c, targets, Options{Concurrency: 3}, func(Report) { progressCalls++ })
+
+	if c.peak > 3 {
+		t.Errorf("collected from %d hosts at once, want at most 3", c.peak)
+	}
+	if got := countStatuses(report)[StatusSucceeded]; got != 20 {
+		t.Errorf("%d hosts succeeded, want 20", got)
+	}
+	if progressCalls != 20 {
+		t.Errorf("progress reported %d times, want once per host", progressCalls)
+	}
+}
+
+func TestRunPausesWhenAuthFailureBudgetRunsOut(t *testing.T) {
+	targets := []string{"a", "b", "c", "d", "e", "f"}
+	c := &fakeCollector{outcomes: map[string]error{
+		"a": fmt.Errorf("%w: permission denied", ErrAuthFailed),
+		"b": fmt.Errorf("%w: permission denied", ErrAuthFailed),
+	}}
+
+	report := Run(context.Background(), c, targets, Options{Concurrency: 1, AuthFailureBudget: 2}, nil)
+
+	if !report.Paused {
+		t.Fatal("run did not pause after two authentication failures")
+	}
+	counts := countStatuses(report)
+	if counts[StatusAuthFailed] != 2 || counts[StatusSkipped] != 4 {
+		t.Errorf("got %v, want 2 auth_failed and 4 skipped", counts)
+	}
+	if len(report.Hosts) != len(targets) {
+		t.Errorf("reported %d hosts, want all %d", len(report.Hosts), len(targets))
+	}
+}
+
+func TestRunTimesOutSlowHosts(t *testing.T) {
+	c := &fakeCollector{delay: time.Second}
+
+	report := Run(context.Background(), c, []string{"slow"}, Options{Concurrency: 1, HostTimeout: 10 * time.Millisecond}, nil)
+
+	host := report.Hosts[0]
+	if host.Status != StatusFailed || host.Error != "timed out after 10ms" {
+		t.Errorf("got %s %q, want a failed host that timed out", host.Status, host.Error)
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	release := parseOSRelease("NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nID=ubuntu\nPRETTY_NAME=\"Ubuntu 22.04.4 LTS\"\n")
+	if release["os_id"] != "ubuntu" || release["os_version"] != "22.04" || release["os_name"] != "Ubuntu 22.04.4 LTS" {
+		t.Errorf("parseOSRelease() = %v", release)
+	}
+}
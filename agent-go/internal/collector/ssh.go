@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHCollector collects a host's kernel and OS release over SSH. Host keys
+// are verified against a known_hosts file; unknown hosts are not trusted.
+type SSHCollector struct {
+	config *ssh.ClientConfig
+}
+
+// NewSSHCollector creates an SSH collector that authenticates as username
+// with a private key, a password, or both
+func NewSSHCollector(username, password, keyPath, knownHostsPath string) (*SSHCollector, error) {
+	if username == "" {
+		return nil, fmt.Errorf("no SSH username configured")
+	}
+	if knownHostsPath == "" {
+		return nil, fmt.Errorf("no SSH known_hosts file configured")
+	}
+	hostKeys, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+
+	var auth []ssh.AuthMethod
+	if keyPath != "" {
+		pemData, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("no SSH key or password configured")
+	}
+
+	return &SSHCollector{config: &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+	}}, nil
+}
+
+// Collect connects to host, on port 22 unless it names one, and runs uname
+// and reads /etc/os-release
+func (s *SSHCollector) Collect(ctx context.Context, host string) (map[string]any, error) {
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, "22")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// Closing the connection unblocks the handshake and commands when the context ends
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, s.config)
+	if err != nil {
+		conn.Close()
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+		}
+		return nil, err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	kernel, err := runSSH(client, "uname -srm")
+	if err != nil {
+		return nil, fmt.Errorf("uname: %w", err)
+	}
+	data := map[string]any{"kernel": strings.TrimSpace(kernel)}
+
+	// Not every system has os-release; the kernel alone still identifies the host
+	if osRelease, err := runSSH(client, "cat /etc/os-release"); err == nil {
+		for key, value := range parseOSRelease(osRelease) {
+			data[key] = value
+		}
+	}
+	return data, nil
+}
+
+// runSSH runs one command in its own session and returns its output
+func runSSH(client *ssh.Client, command string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	out, err := session.Output(command)
+	return string(out), err
+}
+
+// parseOSRelease picks the distribution fields out of an os-release file
+func parseOSRelease(content string) map[string]string {
+	fields := map[string]string{"ID": "os_id", "VERSION_ID": "os_version", "PRETTY_NAME": "os_name"}
+	release := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if name, wanted := fields[key]; ok && wanted {
+			release[name] = strings.Trim(value, `"'`)
+		}
+	}
+	return release
+}
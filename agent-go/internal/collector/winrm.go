@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// identifyRequest is a WS-Management Identify request, answered by every
+// WinRM listener with the service's vendor and version
+const identifyRequest = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:wsmid="http://schemas.dmtf.org/wbem/wsman/identity/1/wsmanidentity.xsd"><s:Header/><s:Body><wsmid:Identify/></s:Body></s:Envelope>`
+
+// WinRMCollector identifies a host's WinRM service with basic
+// authentication, over HTTPS on port 5986 or HTTP on port 5985
+type WinRMCollector struct {
+	username string
+	password string
+	https    bool
+	client   *http.Client
+}
+
+// NewWinRMCollector creates a WinRM collector that authenticates as username
+func NewWinRMCollector(username, password string, https bool) (*WinRMCollector, error) {
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("no WinRM username and password configured")
+	}
+	return &WinRMCollector{
+		username: username,
+		password: password,
+		https:    https,
+		client:   &http.Client{},
+	}, nil
+}
+
+// identifyResponse holds the fields of an Identify response
+type identifyResponse struct {
+	ProtocolVersion string `xml:"Body>IdentifyResponse>ProtocolVersion"`
+	ProductVendor   string `xml:"Body>IdentifyResponse>ProductVendor"`
+	ProductVersion  string `xml:"Body>IdentifyResponse>ProductVersion"`
+}
+
+// Collect sends host's WinRM listener an authenticated Identify request
+func (w *WinRMCollector) Collect(ctx context.Context, host string) (map[string]any, error) {
+	scheme, port := "http", "5985"
+	if w.https {
+		scheme, port = "https", "5986"
+	}
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, port)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, scheme+"://"+addr+"/wsman", bytes.NewBufferString(identifyRequest))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	req.SetBasicAuth(w.username, w.password)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w: WinRM returned 401", ErrAuthFailed)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("WinRM returned status %d", resp.StatusCode)
+	}
+
+	var identity identifyResponse
+	if err := xml.Unmarshal(body, &identity); err != nil {
+		return nil, fmt.Errorf("invalid Identify response: %w", err)
+	}
+	return map[string]any{
+		"protocol_version": strings.TrimSpace(identity.ProtocolVersion),
+		"product_vendor":   strings.TrimSpace(identity.ProductVendor),
+		"product_version":  strings.TrimSpace(identity.ProductVersion),
+	}, nil
+}
@@ -2,48 +2,174 @@ package communicator
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"zerotrace/agent/internal/config"
 	"zerotrace/agent/internal/models"
 	"zerotrace/agent/internal/scanner"
+
+	"github.com/google/uuid"
 )
 
 const (
-	registerEndpoint    = "/api/agents/register"
-	heartbeatEndpoint   = "/api/agents/heartbeat"
-	resultsEndpoint     = "/api/agents/results"
-	systemInfoEndpoint  = "/api/agents/system-info"
-	enrollEndpoint      = "/api/enrollment/enroll"
-	healthCheckEndpoint = "/health" // Health check endpoint
+	registerEndpoint           = "/api/agents/register"
+	heartbeatEndpoint          = "/api/agents/heartbeat"
+	resultsEndpoint            = "/api/agents/results"
+	resultsBatchEndpoint       = "/api/agents/results/batch"
+	systemInfoEndpoint         = "/api/agents/system-info"
+	enrollEndpoint             = "/api/enrollment/enroll"
+	commandStatusFormat        = "/api/agents/commands/%s/status"
+	configBaselineEndpoint     = "/api/agents/config-baseline"
+	containerAllowlistEndpoint = "/api/agents/container-allowlist"
+	scanScopeEndpoint          = "/api/agents/scan-scope"
+	healthCheckEndpoint        = "/health" // Health check endpoint
 )
 
+// maxPayloadHeader carries the largest body a route accepts on a 413 response
+const maxPayloadHeader = "X-Max-Payload-Bytes"
+
+// PayloadTooLargeError is returned when the API rejects a submission as too
+// large. The results should be split into smaller submissions that each fit
+// within Limit.
+type PayloadTooLargeError struct {
+	Size  int   // Bytes the agent tried to send
+	Limit int64 // Largest body the API accepts; 0 if it did not say
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	if e.Limit > 0 {
+		return fmt.Sprintf("payload of %d bytes exceeds API limit of %d bytes", e.Size, e.Limit)
+	}
+	return fmt.Sprintf("payload of %d bytes is too large for the API", e.Size)
+}
+
+// payloadTooLarge builds the error for a 413 response to a body of size bytes
+func payloadTooLarge(resp *http.Response, size int) error {
+	limit, _ := strconv.ParseInt(resp.Header.Get(maxPayloadHeader), 10, 64)
+	return &PayloadTooLargeError{Size: size, Limit: limit}
+}
+
+// ResultRejectedError is returned when the API refuses results outright, so
+// sending them again would not help
+type ResultRejectedError struct {
+	Status int
+}
+
+func (e *ResultRejectedError) Error() string {
+	return fmt.Sprintf("API rejected results with status %d", e.Status)
+}
+
 // Communicator handles communication with the API
 type Communicator struct {
-	config *config.Config
-	client *http.Client
+	config       *config.Config
+	client       *http.Client
+	commands     chan models.AgentCommand
+	updates      chan models.AgentUpdate
+	payloadLimit atomic.Int64  // Result size limit learned from the API's last 413, 0 until then
+	queue        *resultQueue  // Encrypted on-disk queue of unsent results; nil if it could not be opened
+	flush        chan struct{} // Asks the spool flusher to retry queued results now
+	flushMu      sync.Mutex    // Held while sending queued results, so no result is sent twice
 }
 
-// NewCommunicator creates a new communicator instance
-func NewCommunicator(cfg *config.Config) *Communicator {
+// NewCommunicator creates a new communicator instance. It fails if mutual TLS
+// is enabled and the client certificate cannot be loaded, rather than talk to
+// the API without it.
+func NewCommunicator(cfg *config.Config) (*Communicator, error) {
+	tlsConfig, err := clientTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout: time.Duration(cfg.APITimeout) * time.Second,
+	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+	if cfg.MTLSEnabled {
+		// The API matches the client certificate against the agent ID of every request
+		client.Transport = &agentIDTransport{base: client.Transport, agentID: cfg.AgentID}
+	}
+
+	queue, err := newResultQueue(cfg.ResultQueueDir, queueSecret(cfg), int64(cfg.ResultQueueMaxBytes))
+	if err != nil {
+		log.Printf("Result queue disabled: %v", err)
+	}
+
 	return &Communicator{
-		config: cfg,
-		client: &http.Client{
-			Timeout: time.Duration(cfg.APITimeout) * time.Second,
-		},
+		config:   cfg,
+		client:   client,
+		commands: make(chan models.AgentCommand, 16),
+		updates:  make(chan models.AgentUpdate, 1),
+		queue:    queue,
+		flush:    make(chan struct{}, 1),
+	}, nil
+}
+
+// queueSecret is the credential the result queue key is derived from: the
+// enrollment credential, or the API key of a legacy registered agent
+func queueSecret(cfg *config.Config) string {
+	if cfg.AgentCredential != "" {
+		return cfg.AgentCredential
 	}
+	return cfg.APIKey
 }
 
-// SendResults sends scan results to the API
+// Commands returns the commands the API has delivered in heartbeat responses
+func (c *Communicator) Commands() <-chan models.AgentCommand {
+	return c.commands
+}
+
+// Updates returns the agent releases the API has offered in heartbeat responses
+func (c *Communicator) Updates() <-chan models.AgentUpdate {
+	return c.updates
+}
+
+// SendResults sends scan results to the API. Results larger than the API
+// accepts are split into chunks sent as one batch. Results the API could not
+// take are queued on disk and retried by RunSpoolFlusher, as are results sent
+// while earlier ones are still queued, so they reach the API in order.
 func (c *Communicator) SendResults(result *models.ScanResult) error {
 	log.Printf("[SendResults] Starting to send results for agent %s", c.config.AgentID)
 	log.Printf("[SendResults] Result contains %d dependencies and %d vulnerabilities", len(result.Dependencies), len(result.Vulnerabilities))
 
+	if c.queue != nil {
+		if queued, err := c.queue.Entries(); err == nil && len(queued) > 0 {
+			if err := c.enqueueFailed(result); err != nil {
+				return err
+			}
+			log.Printf("[SendResults] Queued results behind %d unsent results", len(queued))
+			c.signalFlush()
+			return nil
+		}
+	}
+
+	err := c.sendResult(result)
+	var rejected *ResultRejectedError
+	var tooLarge *PayloadTooLargeError
+	if err != nil && !errors.As(err, &rejected) && !errors.As(err, &tooLarge) {
+		if queueErr := c.enqueueFailed(result); queueErr != nil {
+			return fmt.Errorf("%w (and could not be queued: %v)", err, queueErr)
+		}
+		return fmt.Errorf("%w (queued for retry)", err)
+	}
+	return err
+}
+
+// sendResult sends one scan result to the API, in chunks if it is too large
+func (c *Communicator) sendResult(result *models.ScanResult) error {
 	// Prepare request payload
 	payload := map[string]any{
 		"agent_id": c.config.AgentID,
@@ -59,8 +185,39 @@ func (c *Communicator) SendResults(result *models.ScanResult) error {
 		return fmt.Errorf("failed to marshal scan results: %w", err)
 	}
 
+	// Skip a request the API is known to reject
+	if limit := c.payloadLimit.Load(); limit > 0 && int64(len(jsonData)) > limit {
+		return c.sendResultsInChunks(result, len(jsonData), limit)
+	}
+
+	err = c.postResults(jsonData)
+	var tooLarge *PayloadTooLargeError
+	if errors.As(err, &tooLarge) {
+		limit := tooLarge.Limit
+		if limit <= 0 {
+			limit = int64(len(jsonData)) / 2
+		}
+		c.payloadLimit.Store(limit)
+		return c.sendResultsInChunks(result, len(jsonData), limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[SendResults] Results sent successfully")
+	return nil
+}
+
+// postResults posts a results payload, whole or one chunk of a batch
+func (c *Communicator) postResults(jsonData []byte) error {
+	return c.postResultsTo(resultsEndpoint, jsonData, nil)
+}
+
+// postResultsTo posts a results payload to endpoint, decoding the API
+// response into response if it is not nil
+func (c *Communicator) postResultsTo(endpoint string, jsonData []byte, response any) error {
 	// Create request
-	url := fmt.Sprintf("%s/api/agents/results", c.config.APIEndpoint)
+	url := fmt.Sprintf("%s%s", c.config.APIEndpoint, endpoint)
 	log.Printf("[SendResults] Sending request to: %s", url)
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
@@ -82,16 +239,353 @@ func (c *Communicator) SendResults(result *models.ScanResult) error {
 
 	log.Printf("[SendResults] Received response with status: %d", resp.StatusCode)
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+	// Check response status; chunks of an unfinished batch are accepted rather than processed
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		log.Printf("[SendResults] API rejected %d byte results as too large", len(jsonData))
+		return payloadTooLarge(resp, len(jsonData))
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusMultiStatus {
 		log.Printf("[SendResults] API returned status %d for results", resp.StatusCode)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return &ResultRejectedError{Status: resp.StatusCode}
+		}
 		return fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
-	log.Printf("[SendResults] Results sent successfully")
+	if response != nil {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
 	return nil
 }
 
+// batchItemStatus is the API's outcome for one result of a batch sent with
+// SendBatch
+type batchItemStatus struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"` // processed or failed
+	Error  string `json:"error"`
+}
+
+// SendBatch sends several scan results to the API in one request, rather than
+// one request each with SendResults. The API processes each result on its
+// own; those it fails to process are queued on disk and retried by
+// RunSpoolFlusher, as are all of them if the request fails. A batch too large
+// for the API is sent one result at a time with SendResults.
+func (c *Communicator) SendBatch(results ...*models.ScanResult) error {
+	switch len(results) {
+	case 0:
+		return nil
+	case 1:
+		return c.SendResults(results[0])
+	}
+	log.Printf("[SendResults] Sending %d results in one batch for agent %s", len(results), c.config.AgentID)
+
+	// Results sent while earlier ones are still queued are queued behind them
+	if c.queue != nil {
+		if queued, err := c.queue.Entries(); err == nil && len(queued) > 0 {
+			for _, result := range results {
+				if err := c.enqueueFailed(result); err != nil {
+					return err
+				}
+			}
+			log.Printf("[SendResults] Queued %d batched results behind %d unsent results", len(results), len(queued))
+			c.signalFlush()
+			return nil
+		}
+	}
+
+	batch := make([]models.ScanResult, len(results))
+	for i, result := range results {
+		batch[i] = *result
+	}
+	jsonData, err := json.Marshal(map[string]any{
+		"agent_id": c.config.AgentID,
+		"results":  batch,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan results: %w", err)
+	}
+	if limit := c.payloadLimit.Load(); limit > 0 && int64(len(jsonData)) > limit {
+		return c.sendEach(results)
+	}
+
+	var response struct {
+		Data []batchItemStatus `json:"data"`
+	}
+	err = c.postResultsTo(resultsBatchEndpoint, jsonData, &response)
+	var rejected *ResultRejectedError
+	var tooLarge *PayloadTooLargeError
+	switch {
+	case errors.As(err, &tooLarge):
+		return c.sendEach(results)
+	case errors.As(err, &rejected):
+		return err
+	case err != nil:
+		var queueErrs []error
+		for _, result := range results {
+			if queueErr := c.enqueueFailed(result); queueErr != nil {
+				queueErrs = append(queueErrs, queueErr)
+			}
+		}
+		if len(queueErrs) > 0 {
+			return fmt.Errorf("%w (and could not be queued: %v)", err, errors.Join(queueErrs...))
+		}
+		return fmt.Errorf("%w (queued for retry)", err)
+	}
+
+	// Queue the results the API failed to process, to be resent on their own
+	var failed []error
+	for _, status := range response.Data {
+		if status.Status != "failed" || status.Index < 0 || status.Index >= len(results) {
+			continue
+		}
+		failure := fmt.Errorf("result %d: %s", status.Index, status.Error)
+		if queueErr := c.enqueueFailed(results[status.Index]); queueErr != nil {
+			failure = fmt.Errorf("%w (and could not be queued: %v)", failure, queueErr)
+		} else {
+			failure = fmt.Errorf("%w (queued for retry)", failure)
+		}
+		failed = append(failed, failure)
+	}
+	if len(failed) > 0 {
+		c.signalFlush()
+		return fmt.Errorf("API failed to process %d of %d batched results: %w", len(failed), len(results), errors.Join(failed...))
+	}
+
+	log.Printf("[SendResults] Batch of %d results sent successfully", len(results))
+	return nil
+}
+
+// sendEach sends results one at a time, for a batch too large to send whole
+func (c *Communicator) sendEach(results []*models.ScanResult) error {
+	log.Printf("[SendResults] Batch of %d results is too large for the API, sending them one at a time", len(results))
+	var errs []error
+	for _, result := range results {
+		if err := c.SendResults(result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enqueueFailed queues a result the API could not take, to be retried by
+// RunSpoolFlusher
+func (c *Communicator) enqueueFailed(result *models.ScanResult) error {
+	if c.queue == nil {
+		return errors.New("result queue is disabled")
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queued results: %w", err)
+	}
+	name, dropped, err := c.queue.Enqueue(data)
+	if err != nil {
+		return fmt.Errorf("failed to queue results: %w", err)
+	}
+	if dropped > 0 {
+		log.Printf("[ResultQueue] Dropped the %d oldest unsent results to stay within %d bytes", dropped, c.config.ResultQueueMaxBytes)
+	}
+	log.Printf("[ResultQueue] Queued unsent results as %s", name)
+	return nil
+}
+
+// RunSpoolFlusher retries queued results every flush interval, and as soon as
+// a heartbeat gets through, until ctx is done
+func (c *Communicator) RunSpoolFlusher(ctx context.Context) {
+	if c.queue == nil {
+		return
+	}
+	ticker := time.NewTicker(c.config.ResultQueueFlushInterval)
+	defer ticker.Stop()
+
+	c.FlushSpool(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.flush:
+		}
+		c.FlushSpool(ctx)
+	}
+}
+
+// signalFlush asks the spool flusher to retry queued results now
+func (c *Communicator) signalFlush() {
+	select {
+	case c.flush <- struct{}{}:
+	default:
+	}
+}
+
+// FlushSpool sends queued results oldest first, deleting each once the API
+// accepts it. It stops at the first the API still cannot take, so results
+// keep their order, or once ctx is done. Entries that cannot be read, such as
+// those queued under a credential the agent no longer holds, are skipped; they
+// go once the queue outgrows its limit.
+func (c *Communicator) FlushSpool(ctx context.Context) {
+	if c.queue == nil {
+		return
+	}
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	names, err := c.queue.Entries()
+	if err != nil {
+		log.Printf("[ResultQueue] %v", err)
+		return
+	}
+
+	sent := 0
+	for _, name := range names {
+		if ctx.Err() != nil {
+			log.Printf("[ResultQueue] Stopped sending, %d queued results left", len(names)-sent)
+			break
+		}
+		data, err := c.queue.Read(name)
+		if err != nil {
+			log.Printf("[ResultQueue] Skipping unreadable entry: %v", err)
+			continue
+		}
+		var result models.ScanResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			log.Printf("[ResultQueue] Dropping corrupt entry %s: %v", name, err)
+			c.removeQueued(name)
+			continue
+		}
+
+		err = c.sendResult(&result)
+		var rejected *ResultRejectedError
+		var tooLarge *PayloadTooLargeError
+		if errors.As(err, &rejected) || errors.As(err, &tooLarge) {
+			log.Printf("[ResultQueue] Dropping queued results %s the API will not take: %v", name, err)
+			c.removeQueued(name)
+			continue
+		}
+		if err != nil {
+			log.Printf("[ResultQueue] API still unavailable, %d queued results left: %v", len(names)-sent, err)
+			return
+		}
+		c.removeQueued(name)
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("[ResultQueue] Sent %d queued results", sent)
+	}
+}
+
+// removeQueued deletes a queued entry, logging a failure
+func (c *Communicator) removeQueued(name string) {
+	if err := c.queue.Remove(name); err != nil {
+		log.Printf("[ResultQueue] %v", err)
+	}
+}
+
+const (
+	maxResultChunks    = 1000 // The API's default RESULT_BATCH_MAX_CHUNKS
+	resultChunkRetries = 3    // Attempts per chunk; resending a received chunk is harmless
+)
+
+// sendResultsInChunks splits a result that is size bytes as one payload into
+// chunks under limit bytes and sends them as one batch, which the API
+// reassembles into a single scan
+func (c *Communicator) sendResultsInChunks(result *models.ScanResult, size int, limit int64) error {
+	batchID := uuid.New().String()
+	items := len(result.Dependencies) + len(result.Vulnerabilities)
+
+	// Start from an even split and halve the chunks until each one fits
+	parts := int(int64(size)/limit) + 1
+	var chunks [][]byte
+	for {
+		if parts > maxResultChunks || parts > items {
+			return &PayloadTooLargeError{Size: size, Limit: limit}
+		}
+
+		var err error
+		chunks, err = c.marshalChunks(result, batchID, parts)
+		if err != nil {
+			return err
+		}
+		if chunksFit(chunks, limit) {
+			break
+		}
+		parts *= 2
+	}
+
+	log.Printf("[SendResults] Sending %d byte results as %d chunks (limit %d bytes, batch %s)", size, len(chunks), limit, batchID)
+	for i, chunk := range chunks {
+		var err error
+		for attempt := 1; attempt <= resultChunkRetries; attempt++ {
+			if err = c.postResults(chunk); err == nil {
+				break
+			}
+			var tooLarge *PayloadTooLargeError
+			if errors.As(err, &tooLarge) {
+				return err
+			}
+			log.Printf("[SendResults] Chunk %d/%d of batch %s failed (attempt %d): %v", i+1, len(chunks), batchID, attempt, err)
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to send chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+	}
+
+	log.Printf("[SendResults] Results sent successfully in %d chunks", len(chunks))
+	return nil
+}
+
+// marshalChunks splits a result's dependencies and vulnerabilities evenly into
+// parts chunk payloads. Every chunk carries the result's ID so the API merges
+// them back into one result; only the first carries its metadata and delta.
+func (c *Communicator) marshalChunks(result *models.ScanResult, batchID string, parts int) ([][]byte, error) {
+	chunks := make([][]byte, 0, parts)
+	for i := 0; i < parts; i++ {
+		part := *result
+		part.Dependencies = splitPart(result.Dependencies, i, parts)
+		part.Vulnerabilities = splitPart(result.Vulnerabilities, i, parts)
+		if i > 0 {
+			part.Metadata = nil
+			part.Delta = nil
+		}
+
+		jsonData, err := json.Marshal(map[string]any{
+			"agent_id": c.config.AgentID,
+			"results":  []models.ScanResult{part},
+			"metadata": map[string]interface{}{
+				"status": result.Status,
+			},
+			"batch": map[string]any{
+				"batch_id": batchID,
+				"sequence": i,
+				"total":    parts,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal result chunk: %w", err)
+		}
+		chunks = append(chunks, jsonData)
+	}
+	return chunks, nil
+}
+
+// splitPart returns the i-th of parts roughly equal slices of items
+func splitPart[T any](items []T, i, parts int) []T {
+	return items[i*len(items)/parts : (i+1)*len(items)/parts]
+}
+
+// chunksFit reports whether every chunk is within limit bytes
+func chunksFit(chunks [][]byte, limit int64) bool {
+	for _, chunk := range chunks {
+		if int64(len(chunk)) > limit {
+			return false
+		}
+	}
+	return true
+}
+
 // SendStatus sends agent status to the API
 func (c *Communicator) SendStatus(status *models.AgentStatus) error {
 	// Prepare request payload
@@ -174,19 +668,19 @@ func (c *Communicator) GetScanTasks() ([]map[string]any, error) {
 	return []map[string]any{}, nil
 }
 
-// SendHeartbeat sends agent heartbeat to the API
-func (c *Communicator) SendHeartbeat(cpuUsage, memoryUsage float64, metadata map[string]any) error {
+// SendHeartbeat sends agent heartbeat to the API. Usage the agent couldn't
+// measure is nil and left out of the heartbeat.
+func (c *Communicator) SendHeartbeat(cpuUsage, memoryUsage *float64, metadata map[string]any) error {
 	// Prepare heartbeat payload
 	heartbeat := map[string]any{
 		"agent_id":        c.config.AgentID,
 		"organization_id": c.config.OrganizationID,
 		"agent_name":      c.config.AgentName,
 		"status":          "online",
-		"cpu_usage":       cpuUsage,
-		"memory_usage":    memoryUsage,
 		"metadata":        metadata,
 		"timestamp":       time.Now(),
 	}
+	addUsage(heartbeat, cpuUsage, memoryUsage)
 
 	// Marshal to JSON
 	jsonData, err := json.Marshal(heartbeat)
@@ -218,9 +712,22 @@ func (c *Communicator) SendHeartbeat(cpuUsage, memoryUsage float64, metadata map
 		return fmt.Errorf("API returned status %d for heartbeat", resp.StatusCode)
 	}
 
+	c.queueCommands(resp.Body)
+	c.signalFlush()
 	return nil
 }
 
+// addUsage adds the CPU and memory usage percentages that were measured to a
+// heartbeat payload
+func addUsage(heartbeat map[string]any, cpuUsage, memoryUsage *float64) {
+	if cpuUsage != nil {
+		heartbeat["cpu_usage"] = *cpuUsage
+	}
+	if memoryUsage != nil {
+		heartbeat["memory_usage"] = *memoryUsage
+	}
+}
+
 // RegisterAgent registers the agent with the API
 func (c *Communicator) RegisterAgent() error {
 	// Prepare registration payload
@@ -345,11 +852,18 @@ func (c *Communicator) EnrollAgent() error {
 	// Clear the enrollment token since it's been used
 	c.config.EnrollmentToken = ""
 
+	// Queued results must stay readable under the new credential
+	if c.queue != nil {
+		if err := c.queue.Rekey(c.config.AgentCredential); err != nil {
+			log.Printf("Failed to re-encrypt result queue after enrollment: %v", err)
+		}
+	}
+
 	return nil
 }
 
 // SendHeartbeatWithCredential sends heartbeat using agent credential
-func (c *Communicator) SendHeartbeatWithCredential(cpuUsage, memoryUsage float64, metadata map[string]any) error {
+func (c *Communicator) SendHeartbeatWithCredential(cpuUsage, memoryUsage *float64, metadata map[string]any) error {
 	// Check if we have a credential
 	if !c.config.IsEnrolled() {
 		return fmt.Errorf("agent not enrolled")
@@ -360,11 +874,10 @@ func (c *Communicator) SendHeartbeatWithCredential(cpuUsage, memoryUsage float64
 		"agent_id":        c.config.AgentID,
 		"organization_id": c.config.OrganizationID,
 		"status":          "online",
-		"cpu_usage":       cpuUsage,
-		"memory_usage":    memoryUsage,
 		"metadata":        metadata,
 		"timestamp":       time.Now(),
 	}
+	addUsage(heartbeat, cpuUsage, memoryUsage)
 
 	// Marshal to JSON
 	jsonData, err := json.Marshal(heartbeat)
@@ -396,6 +909,8 @@ func (c *Communicator) SendHeartbeatWithCredential(cpuUsage, memoryUsage float64
 		return fmt.Errorf("API returned status %d for heartbeat", resp.StatusCode)
 	}
 
+	c.queueCommands(resp.Body)
+	c.signalFlush()
 	return nil
 }
 
@@ -496,6 +1011,9 @@ func (c *Communicator) SendNetworkScanResults(scanResult *scanner.NetworkScanRes
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return payloadTooLarge(resp, len(jsonData))
+	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("API returned status %d for network scan results: %s", resp.StatusCode, string(body))
@@ -504,6 +1022,202 @@ func (c *Communicator) SendNetworkScanResults(scanResult *scanner.NetworkScanRes
 	return nil
 }
 
+// queueCommands reads any commands from a heartbeat response and queues them
+// for the agent, along with any update the API offers
+func (c *Communicator) queueCommands(body io.Reader) {
+	var response struct {
+		Data struct {
+			Commands []models.AgentCommand `json:"commands"`
+			Update   *models.AgentUpdate   `json:"update"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return
+	}
+
+	if response.Data.Update != nil {
+		select {
+		case c.updates <- *response.Data.Update:
+		default:
+			// An update is already pending; the API offers it again next heartbeat
+		}
+	}
+
+	for _, cmd := range response.Data.Commands {
+		select {
+		case c.commands <- cmd:
+		default:
+			// The API fails commands that are never acknowledged
+			log.Printf("Command queue full, dropping %s command %s", cmd.Type, cmd.ID)
+		}
+	}
+}
+
+// ReportCommandStatus tells the API how a command is progressing
+func (c *Communicator) ReportCommandStatus(commandID, status, errMsg string) error {
+	payload := map[string]any{
+		"agent_id": c.config.AgentID,
+		"status":   status,
+	}
+	if errMsg != "" {
+		payload["error"] = errMsg
+	}
+	return c.postCommandStatus(commandID, payload)
+}
+
+// ReportCommandResult completes a command with the result its type reports,
+// such as the outcome of a finding's verification
+func (c *Communicator) ReportCommandResult(commandID string, result any) error {
+	return c.postCommandStatus(commandID, map[string]any{
+		"agent_id": c.config.AgentID,
+		"status":   "completed",
+		"result":   result,
+	})
+}
+
+// ReportCommandProgress reports what a long-running command has done so far,
+// such as the hosts a collection run has finished
+func (c *Communicator) ReportCommandProgress(commandID string, result any) error {
+	return c.postCommandStatus(commandID, map[string]any{
+		"agent_id": c.config.AgentID,
+		"status":   "acked",
+		"result":   result,
+	})
+}
+
+func (c *Communicator) postCommandStatus(commandID string, payload map[string]any) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal command status: %w", err)
+	}
+
+	url := c.config.APIEndpoint + fmt.Sprintf(commandStatusFormat, commandID)
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create command status request: %w", err)
+	}
+
+	token := c.config.APIKey
+	if c.config.IsEnrolled() {
+		token = c.config.AgentCredential
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("User-Agent", "ZeroTrace-Agent/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send command status: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d for command status: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// GetConfigBaseline fetches the approved configuration baseline for the agent's
+// host group. It returns nil when no baseline has been captured for the group.
+func (c *Communicator) GetConfigBaseline() (*scanner.ConfigBaseline, error) {
+	params := url.Values{}
+	params.Set("agent_id", c.config.AgentID)
+	params.Set("host_group", c.config.HostGroup)
+
+	req, err := http.NewRequest("GET", c.config.APIEndpoint+configBaselineEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create baseline request: %w", err)
+	}
+	c.setAuthHeaders(req)
+	req.Header.Set("User-Agent", "ZeroTrace-Agent/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config baseline: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d for config baseline", resp.StatusCode)
+	}
+
+	var response struct {
+		Data scanner.ConfigBaseline `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode config baseline: %w", err)
+	}
+	return &response.Data, nil
+}
+
+// GetContainerAllowlist fetches the organization's allowlist of expected
+// container findings
+func (c *Communicator) GetContainerAllowlist() ([]scanner.ContainerAllowlistRule, error) {
+	params := url.Values{}
+	params.Set("agent_id", c.config.AgentID)
+
+	req, err := http.NewRequest("GET", c.config.APIEndpoint+containerAllowlistEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create allowlist request: %w", err)
+	}
+	c.setAuthHeaders(req)
+	req.Header.Set("User-Agent", "ZeroTrace-Agent/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch container allowlist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d for container allowlist", resp.StatusCode)
+	}
+
+	var response struct {
+		Data []scanner.ContainerAllowlistRule `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode container allowlist: %w", err)
+	}
+	return response.Data, nil
+}
+
+// GetScanScope fetches the scanners and paths the API has set for this agent
+func (c *Communicator) GetScanScope() (*scanner.ScanScope, error) {
+	params := url.Values{}
+	params.Set("agent_id", c.config.AgentID)
+
+	req, err := http.NewRequest("GET", c.config.APIEndpoint+scanScopeEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scan scope request: %w", err)
+	}
+	c.setAuthHeaders(req)
+	req.Header.Set("User-Agent", "ZeroTrace-Agent/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch scan scope: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d for scan scope", resp.StatusCode)
+	}
+
+	var response struct {
+		Data scanner.ScanScope `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode scan scope: %w", err)
+	}
+	return &response.Data, nil
+}
+
 // setAuthHeaders sets authentication headers for requests
 func (c *Communicator) setAuthHeaders(req *http.Request) {
 	if c.config.APIKey != "" {
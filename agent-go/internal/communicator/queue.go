@@ -0,0 +1,279 @@
+package communicator
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// queueKeyInfo binds keys derived from the agent credential to the result queue
+const queueKeyInfo = "zerotrace agent result queue v1"
+
+var (
+	// ErrQueueLocked is returned when the queue has no key, because the agent has no credential
+	ErrQueueLocked = errors.New("result queue has no key")
+	// ErrQueueKeyMismatch is returned for an entry encrypted under a credential the agent no longer holds
+	ErrQueueKeyMismatch = errors.New("queued entry was encrypted with another credential")
+	// ErrQueueEntryTooLarge is returned for a payload larger than the whole queue may be
+	ErrQueueEntryTooLarge = errors.New("payload is larger than the result queue")
+)
+
+// queueEntry is a queued payload as stored on disk. Only the ciphertext is
+// written, so a copied disk does not reveal queued findings.
+type queueEntry struct {
+	KeyID      string `json:"key_id"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// resultQueue stores result payloads on disk, one file per payload, encrypted
+// with AES-GCM under a key derived from the agent's credential. Payloads are
+// only decrypted when read back to be sent. When the entries outgrow the
+// queue's size limit, the oldest are dropped.
+type resultQueue struct {
+	dir      string
+	maxBytes int64 // Most bytes the entries may take up; 0 for no limit
+
+	mu    sync.Mutex
+	aead  cipher.AEAD // nil until the agent has a credential
+	keyID string
+}
+
+// newResultQueue opens the queue in dir, keyed by secret and holding at most
+// maxBytes of entries
+func newResultQueue(dir, secret string, maxBytes int64) (*resultQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create result queue directory: %w", err)
+	}
+	q := &resultQueue{dir: dir, maxBytes: maxBytes}
+	if secret != "" {
+		aead, keyID, err := queueKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		q.aead, q.keyID = aead, keyID
+	}
+	return q, nil
+}
+
+// queueKey derives the queue key and its identifier from the agent credential.
+// The agent ID is not mixed in, because enrollment can change it.
+func queueKey(secret string) (cipher.AEAD, string, error) {
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, queueKeyInfo, 32)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to derive result queue key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, "", err
+	}
+	// The identifier lets entries under an old key be told apart without
+	// revealing anything about the key itself
+	sum := sha256.Sum256(append([]byte(queueKeyInfo+" id\x00"), key...))
+	return aead, hex.EncodeToString(sum[:8]), nil
+}
+
+// Enqueue encrypts a payload and writes it to the queue, returning its entry
+// name and how many older entries were dropped to make room for it
+func (q *resultQueue) Enqueue(payload []byte) (string, int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.aead == nil {
+		return "", 0, ErrQueueLocked
+	}
+	if q.maxBytes > 0 && int64(len(payload)) > q.maxBytes {
+		return "", 0, fmt.Errorf("%w: %d bytes, limit %d", ErrQueueEntryTooLarge, len(payload), q.maxBytes)
+	}
+	// Names sort in the order entries were queued
+	name := fmt.Sprintf("%020d-%s.json", time.Now().UnixNano(), uuid.New())
+	if err := q.write(name, q.aead, q.keyID, payload); err != nil {
+		return "", 0, err
+	}
+	dropped, err := q.trim(name)
+	return name, dropped, err
+}
+
+// trim drops the oldest entries until the queue is within its size limit,
+// keeping the newest entry, and returns how many it dropped
+func (q *resultQueue) trim(newest string) (int, error) {
+	if q.maxBytes <= 0 {
+		return 0, nil
+	}
+	names, err := q.Entries()
+	if err != nil {
+		return 0, err
+	}
+	sizes := make([]int64, len(names))
+	var total int64
+	for i, name := range names {
+		if info, err := os.Stat(filepath.Join(q.dir, name)); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+
+	dropped := 0
+	for i, name := range names {
+		if total <= q.maxBytes || name == newest {
+			break
+		}
+		if err := q.Remove(name); err != nil {
+			return dropped, err
+		}
+		total -= sizes[i]
+		dropped++
+	}
+	return dropped, nil
+}
+
+// Entries lists the queued entry names, oldest first
+func (q *resultQueue) Entries() ([]string, error) {
+	files, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result queue: %w", err)
+	}
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Read decrypts a queued entry for sending
+func (q *resultQueue) Read(name string) ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.aead == nil {
+		return nil, ErrQueueLocked
+	}
+	return q.read(name, q.aead, q.keyID)
+}
+
+// Remove deletes a queued entry once it has been sent
+func (q *resultQueue) Remove(name string) error {
+	if err := os.Remove(filepath.Join(q.dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove queued entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// Rekey switches the queue to a key derived from a refreshed credential and
+// re-encrypts every queued entry under it. Entries the old key cannot decrypt
+// are left as they are and reported in the returned error.
+func (q *resultQueue) Rekey(secret string) error {
+	aead, keyID, err := queueKey(secret)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	oldAEAD, oldKeyID := q.aead, q.keyID
+	q.aead, q.keyID = aead, keyID
+	if oldAEAD == nil || oldKeyID == keyID {
+		return nil
+	}
+
+	names, err := q.Entries()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range names {
+		payload, err := q.read(name, oldAEAD, oldKeyID)
+		if errors.Is(err, ErrQueueKeyMismatch) {
+			// Already under the new key, from a rekey interrupted part way
+			if _, err := q.read(name, aead, keyID); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := q.write(name, aead, keyID, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// write encrypts payload to the named entry, replacing it atomically
+func (q *resultQueue) write(name string, aead cipher.AEAD, keyID string, payload []byte) error {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	data, err := json.Marshal(queueEntry{
+		KeyID:      keyID,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, payload, []byte(name)),
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(q.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write queued entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write queued entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write queued entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(q.dir, name)); err != nil {
+		return fmt.Errorf("failed to write queued entry: %w", err)
+	}
+	return nil
+}
+
+// read decrypts the named entry with the given key
+func (q *resultQueue) read(name string, aead cipher.AEAD, keyID string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(q.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queued entry %s: %w", name, err)
+	}
+	var entry queueEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("queued entry %s is corrupt: %w", name, err)
+	}
+	if entry.KeyID != keyID {
+		return nil, fmt.Errorf("%s: %w", name, ErrQueueKeyMismatch)
+	}
+	if len(entry.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("queued entry %s is corrupt: bad nonce", name)
+	}
+	payload, err := aead.Open(nil, entry.Nonce, entry.Ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("queued entry %s failed to decrypt: %w", name, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,252 @@
+package communicator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
+)
+
+func TestResultQueueEncryptsAtRest(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newResultQueue(dir, "credential-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{"vulnerabilities":[{"cve":"CVE-2024-0001"}]}`)
+	name, _, err := q.Enqueue(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("CVE-2024-0001")) {
+		t.Fatalf("queued entry is stored in plaintext: %s", raw)
+	}
+
+	got, err := q.Read(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("Read() = %s, want %s", got, payload)
+	}
+
+	// Another credential cannot read the queue
+	other, err := newResultQueue(dir, "credential-2", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Read(name); !errors.Is(err, ErrQueueKeyMismatch) {
+		t.Fatalf("Read() with another credential: err = %v, want ErrQueueKeyMismatch", err)
+	}
+}
+
+func TestResultQueueRekey(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newResultQueue(dir, "old-credential", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ := q.Enqueue([]byte("first"))
+	second, _, _ := q.Enqueue([]byte("second"))
+
+	if err := q.Rekey("new-credential"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted agent holding only the new credential reads every entry, in order
+	reopened, err := newResultQueue(dir, "new-credential", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := reopened.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != first || names[1] != second {
+		t.Fatalf("Entries() = %v, want [%s %s]", names, first, second)
+	}
+	for i, want := range []string{"first", "second"} {
+		got, err := reopened.Read(names[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("Read(%s) = %q, want %q", names[i], got, want)
+		}
+	}
+
+	stale, err := newResultQueue(dir, "old-credential", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stale.Read(first); !errors.Is(err, ErrQueueKeyMismatch) {
+		t.Fatalf("Read() with the old credential: err = %v, want ErrQueueKeyMismatch", err)
+	}
+}
+
+func TestResultQueueWithoutCredential(t *testing.T) {
+	q, err := newResultQueue(t.TempDir(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := q.Enqueue([]byte("results")); !errors.Is(err, ErrQueueLocked) {
+		t.Fatalf("Enqueue() without a credential: err = %v, want ErrQueueLocked", err)
+	}
+}
+
+func TestResultQueueDropsOldestOverLimit(t *testing.T) {
+	dir := t.TempDir()
+	probe, err := newResultQueue(t.TempDir(), "credential", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, _, err := probe.Enqueue(bytes.Repeat([]byte("x"), 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(probe.dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Room for two entries of this size, but not three
+	q, err := newResultQueue(dir, "credential", 2*info.Size()+info.Size()/2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for i := 0; i < 3; i++ {
+		name, dropped, err := q.Enqueue(bytes.Repeat([]byte{byte('a' + i)}, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := max(i-1, 0); dropped != want {
+			t.Errorf("Enqueue() #%d dropped %d entries, want %d", i, dropped, want)
+		}
+		names = append(names, name)
+	}
+
+	remaining, err := q.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 2 || remaining[0] != names[1] || remaining[1] != names[2] {
+		t.Fatalf("Entries() = %v, want the newest two %v", remaining, names[1:])
+	}
+
+	if _, _, err := q.Enqueue(bytes.Repeat([]byte("x"), int(3*info.Size()))); !errors.Is(err, ErrQueueEntryTooLarge) {
+		t.Fatalf("Enqueue() of a payload larger than the queue: err = %v, want ErrQueueEntryTooLarge", err)
+	}
+}
+
+func TestSendResultsQueuesUntilAPIAccepts(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	var received atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(status.Load()) == http.StatusOK {
+			received.Add(1)
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer api.Close()
+
+	c, err := NewCommunicator(&config.Config{
+		APIEndpoint:              api.URL,
+		APITimeout:               5,
+		AgentCredential:          "credential",
+		ResultQueueDir:           t.TempDir(),
+		ResultQueueMaxBytes:      1 << 20,
+		ResultQueueFlushInterval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewCommunicator() error = %v", err)
+	}
+
+	if err := c.SendResults(&models.ScanResult{Status: "completed"}); err == nil {
+		t.Fatal("SendResults() to an unavailable API succeeded")
+	}
+	// Queued behind the first, without trying the API
+	if err := c.SendResults(&models.ScanResult{Status: "completed"}); err != nil {
+		t.Fatalf("SendResults() behind queued results: %v", err)
+	}
+	c.FlushSpool(context.Background())
+	if names, _ := c.queue.Entries(); len(names) != 2 {
+		t.Fatalf("%d queued results while the API is unavailable, want 2", len(names))
+	}
+
+	status.Store(http.StatusOK)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.FlushSpool(ctx)
+	if received.Load() != 0 {
+		t.Fatalf("FlushSpool() sent %d results after its context was done, want 0", received.Load())
+	}
+	c.FlushSpool(context.Background())
+	if names, _ := c.queue.Entries(); len(names) != 0 {
+		t.Fatalf("%d queued results left once the API accepts them, want 0", len(names))
+	}
+	if received.Load() != 2 {
+		t.Fatalf("API received %d results, want 2", received.Load())
+	}
+}
+
+func TestSendBatchQueuesFailedResults(t *testing.T) {
+	var batches, singles atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != resultsBatchEndpoint {
+			singles.Add(1)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		batches.Add(1)
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`{"success":false,"data":[{"index":0,"status":"processed"},{"index":1,"status":"failed","error":"database unavailable"}]}`))
+	}))
+	defer api.Close()
+
+	c, err := NewCommunicator(&config.Config{
+		APIEndpoint:              api.URL,
+		APITimeout:               5,
+		AgentCredential:          "credential",
+		ResultQueueDir:           t.TempDir(),
+		ResultQueueMaxBytes:      1 << 20,
+		ResultQueueFlushInterval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewCommunicator() error = %v", err)
+	}
+
+	software := &models.ScanResult{Status: "completed", Metadata: map[string]any{"scan_type": "software"}}
+	configuration := &models.ScanResult{Status: "completed", Metadata: map[string]any{"scan_type": "config"}}
+	if err := c.SendBatch(software, configuration); err == nil {
+		t.Fatal("SendBatch() with a failed result succeeded")
+	}
+	if batches.Load() != 1 || singles.Load() != 0 {
+		t.Fatalf("API received %d batches and %d single results, want one batch", batches.Load(), singles.Load())
+	}
+
+	names, _ := c.queue.Entries()
+	if len(names) != 1 {
+		t.Fatalf("%d queued results, want only the failed one", len(names))
+	}
+	c.FlushSpool(context.Background())
+	if singles.Load() != 1 {
+		t.Fatalf("failed result resent %d times on its own, want 1", singles.Load())
+	}
+}
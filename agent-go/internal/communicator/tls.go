@@ -0,0 +1,60 @@
+package communicator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"zerotrace/agent/internal/config"
+)
+
+// clientTLSConfig builds the TLS configuration for requests to the API: the
+// agent's client certificate when mutual TLS is enabled, and the CAs the
+// API's certificate is verified against when configured. It returns nil when
+// neither is, leaving Go's defaults in place.
+func clientTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if !cfg.MTLSEnabled && cfg.CACertPath == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.MTLSEnabled {
+		if cfg.ClientCertPath == "" || cfg.ClientKeyPath == "" {
+			return nil, fmt.Errorf("mutual TLS is enabled but no client certificate is configured")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertPath, cfg.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.CACertPath != "" {
+		pemData, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no PEM certificates in %s", cfg.CACertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// agentIDTransport sends the agent ID with every request that does not
+// already carry it, so the API can match it to the client certificate
+type agentIDTransport struct {
+	base    http.RoundTripper
+	agentID string
+}
+
+func (t *agentIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("X-Agent-ID") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Agent-ID", t.agentID)
+	}
+	return t.base.RoundTrip(req)
+}
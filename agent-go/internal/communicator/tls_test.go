@@ -0,0 +1,119 @@
+package communicator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerotrace/agent/internal/config"
+)
+
+// writeClientCert issues a client certificate for commonName from a new CA
+// and writes both as PEM files, returning the CA's pool and the file paths
+func writeClientCert(t *testing.T, commonName string) (cas *x509.CertPool, certPath, keyPath string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ZeroTrace Agent CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certPath = filepath.Join(dir, "agent.crt")
+	keyPath = filepath.Join(dir, "agent.key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	cas = x509.NewCertPool()
+	cas.AddCert(ca)
+	return cas, certPath, keyPath
+}
+
+func TestCommunicatorPresentsClientCertificate(t *testing.T) {
+	cas, certPath, keyPath := writeClientCert(t, "agent-1")
+
+	var commonName, agentID string
+	api := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commonName = r.TLS.PeerCertificates[0].Subject.CommonName
+		agentID = r.Header.Get("X-Agent-ID")
+	}))
+	api.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: cas}
+	api.StartTLS()
+	defer api.Close()
+
+	// Trust the test server's own certificate
+	serverCA := filepath.Join(t.TempDir(), "ca.crt")
+	os.WriteFile(serverCA, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: api.Certificate().Raw}), 0600)
+
+	cfg := &config.Config{
+		AgentID:        "agent-1",
+		APIEndpoint:    api.URL,
+		APITimeout:     5,
+		MTLSEnabled:    true,
+		ClientCertPath: certPath,
+		ClientKeyPath:  keyPath,
+		CACertPath:     serverCA,
+	}
+	c, err := NewCommunicator(cfg)
+	if err != nil {
+		t.Fatalf("NewCommunicator() error = %v", err)
+	}
+	if err := c.CheckAPIStatus(); err != nil {
+		t.Fatalf("CheckAPIStatus() over mutual TLS: %v", err)
+	}
+	if commonName != "agent-1" {
+		t.Errorf("API saw client certificate for %q, want agent-1", commonName)
+	}
+	if agentID != "agent-1" {
+		t.Errorf("API saw X-Agent-ID %q, want agent-1", agentID)
+	}
+}
+
+func TestNewCommunicatorFailsWithoutClientCertificate(t *testing.T) {
+	cfg := &config.Config{
+		APITimeout:     5,
+		MTLSEnabled:    true,
+		ClientCertPath: filepath.Join(t.TempDir(), "missing.crt"),
+		ClientKeyPath:  filepath.Join(t.TempDir(), "missing.key"),
+	}
+	if _, err := NewCommunicator(cfg); err == nil {
+		t.Fatal("NewCommunicator() with a missing client certificate succeeded")
+	}
+}
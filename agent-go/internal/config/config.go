@@ -4,7 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,6 +27,23 @@ type Config struct {
 	AgentCredential string `json:"agent_credential"`
 	OrganizationID  string `json:"organization_id"`
 	AgentName       string `json:"agent_name"`
+	HostGroup       string `json:"host_group"` // Group whose approved config baseline this host is compared against
+
+	// Mutual TLS Configuration
+	MTLSEnabled    bool   `json:"mtls_enabled"`     // Authenticate to the API with a client certificate
+	ClientCertPath string `json:"client_cert_path"` // PEM client certificate, issued with the agent ID as its common name
+	ClientKeyPath  string `json:"client_key_path"`  // PEM private key of the client certificate
+	CACertPath     string `json:"ca_cert_path"`     // PEM CA certificates the API's certificate is verified against; system roots when empty
+
+	// Result Queue Configuration
+	ResultQueueDir           string        `json:"result_queue_dir"`            // Unsent results, encrypted with a key derived from the agent credential
+	ResultQueueMaxBytes      int           `json:"result_queue_max_bytes"`      // Most bytes of unsent results kept; the oldest are dropped beyond it
+	ResultQueueFlushInterval time.Duration `json:"result_queue_flush_interval"` // How often unsent results are retried
+
+	// Delta Reporting Configuration
+	ResultDelta             bool          `json:"result_delta"`               // Send only the findings changed since the last result of a scan type
+	ResultDeltaStatePath    string        `json:"result_delta_state_path"`    // Fingerprints of the findings last sent, kept across restarts
+	ResultDeltaFullInterval time.Duration `json:"result_delta_full_interval"` // How often each scan type's findings are sent in full anyway
 
 	// Company-specific Configuration (legacy - will be replaced by enrollment)
 	CompanyID   string `json:"company_id"`
@@ -40,6 +57,9 @@ type Config struct {
 	// API Configuration
 	APIPort int `json:"api_port"`
 
+	// Local Control Configuration
+	IPCAddress string `json:"ipc_address"` // Unix socket or named pipe the tray triggers scans through
+
 	// Enrichment Configuration
 	EnrichmentURL string `json:"enrichment_url"`
 
@@ -52,16 +72,98 @@ type Config struct {
 	ScanTimeout     time.Duration `json:"scan_timeout"`
 	ExcludePatterns []string      `json:"exclude_patterns"`
 	IncludePatterns []string      `json:"include_patterns"`
+	IncrementalScan bool          `json:"incremental_scan"` // Only enrich software packages added or changed since the previous scan
+	ScanSchedule    string        `json:"scan_schedule"`    // Cron expression of the minutes periodic scans may start in; any time when empty
+
+	// Scanner Selection
+	EnabledScanners  []string `json:"enabled_scanners"`  // Scanners, built-in or registered, this agent may run; all when empty
+	DisabledScanners []string `json:"disabled_scanners"` // Scanners this agent never runs, whatever its scan scope
+
+	// osquery Configuration
+	OsqueryEnabled     bool   `json:"osquery_enabled"`      // Inventory the host through osquery when it is installed
+	OsqueryBinary      string `json:"osquery_binary"`       // osqueryi binary queries run through
+	OsquerySocket      string `json:"osquery_socket"`       // osqueryd extensions socket queried when present; osqueryi runs standalone otherwise
+	OsqueryQueriesPath string `json:"osquery_queries_path"` // JSON file of queries run in addition to the curated ones
+
+	// Container Image Scanning
+	ContainerImageScan bool   `json:"container_image_scan"` // Match the OS packages in container images against NVD
+	NVDAPIKey          string `json:"nvd_api_key"`          // Raises NVD's rate limit from 5 to 50 requests per 30 seconds
+
+	// CVE Cache
+	CVECachePath        string        `json:"cve_cache_path"`         // SQLite database NVD lookups are cached in; no cache when empty
+	CVECacheTTL         time.Duration `json:"cve_cache_ttl"`          // How long a cached record or package match is used before it is looked up again
+	NVDFeedSyncInterval time.Duration `json:"nvd_feed_sync_interval"` // How often NVD's modified feed is loaded into the cache (0 = never)
+	NVDFeedURL          string        `json:"nvd_feed_url"`           // NVD's modified feed, the CVEs changed in the last eight days
+
+	// Advisory Sources
+	CVESources  []string `json:"cve_sources"`  // Advisory databases dependencies are matched against on the agent: osv, gitlab, github; none when empty
+	GitLabToken string   `json:"gitlab_token"` // GitLab token, only needed to look GitLab advisories up by ID
+
+	// Scan Resource Budget
+	ScanMaxConcurrent   int           `json:"scan_max_concurrent"`    // Scanners allowed to run at once
+	ScanMaxHeavy        int           `json:"scan_max_heavy"`         // Filesystem-heavy scanners allowed at once (1 = serialized)
+	ScanCPUThreshold    float64       `json:"scan_cpu_threshold"`     // Defer heavy scanners while host CPU % is above this (0 = never)
+	ScanThrottleMaxWait time.Duration `json:"scan_throttle_max_wait"` // Longest a heavy scanner is deferred for CPU (0 = no limit)
+	ScanMaxProcs        int           `json:"scan_max_procs"`         // CPUs the agent may use (0 = all)
+
+	// Configuration Checks
+	ConfigCheckWorkers   int           `json:"config_check_workers"`   // Configuration checks run at once
+	ConfigCommandTimeout time.Duration `json:"config_command_timeout"` // Longest a check's command may run before the check is unable to determine its setting
+	ConfigScanTimeout    time.Duration `json:"config_scan_timeout"`    // Longest a configuration scan's checks may take altogether
+
+	// Shutdown Configuration
+	ShutdownDrainTimeout time.Duration `json:"shutdown_drain_timeout"` // How long shutdown waits for in-flight scans and their uploads
+
+	// Self-Update Configuration (opt-in)
+	AutoUpdate          bool          `json:"auto_update"`           // Install newer agent releases the API offers on heartbeat
+	UpdatePublicKey     string        `json:"update_public_key"`     // Base64 ed25519 public key releases must be signed with
+	UpdateHealthTimeout time.Duration `json:"update_health_timeout"` // How long an installed release has to pass its self-check before it is rolled back
 
 	// Network Scan Configuration
-	NetworkScanInterval time.Duration `json:"network_scan_interval"`
-	NetworkScanEnabled  bool         `json:"network_scan_enabled"`
+	NetworkScanInterval           time.Duration `json:"network_scan_interval"`
+	NetworkScanEnabled            bool          `json:"network_scan_enabled"`
+	NetworkScanSchedule           string        `json:"network_scan_schedule"`             // Same as ScanSchedule, for network scans
+	NetworkScanCIDRs              []string      `json:"network_scan_cidrs"`                // Subnets network scans may cover; the first interface's subnet when empty
+	NetworkScanExcludeCIDRs       []string      `json:"network_scan_exclude_cidrs"`        // Subnets network scans never cover, even inside NetworkScanCIDRs
+	NetworkScanMaxConcurrentHosts int           `json:"network_scan_max_concurrent_hosts"` // Hosts scanned at once
+	NetworkScanMaxPPS             int           `json:"network_scan_max_pps"`              // Packets (Nuclei: requests) sent per second
+	TLSCertExpiryDays             int           `json:"tls_cert_expiry_days"`              // Flag TLS certificates expiring within this many days
+	WeakTLSProtocols              []string      `json:"weak_tls_protocols"`                // TLS protocol versions reported when a service accepts them
+	WeakTLSCiphers                []string      `json:"weak_tls_ciphers"`                  // Cipher suite name components, such as RC4, that make a suite weak
+	WeakSSHAlgorithms             []string      `json:"weak_ssh_algorithms"`               // SSH key exchange, host key, cipher and MAC algorithms reported when offered
+	TopologyMaxNodes              int           `json:"topology_max_nodes"`                // Largest network graph analyzed whole; larger ones are partitioned by subnet
+	TopologyMaxEdges              int           `json:"topology_max_edges"`                // Same, in connections
+	TopologyRiskDecay             float64       `json:"topology_risk_decay"`               // Share of an asset's risk passed on per hop to the assets it can reach
+
+	// Nuclei Template Selection
+	NucleiProfile          string   `json:"nuclei_profile"`           // safe leaves out intrusive and DoS templates; full runs them
+	NucleiTags             []string `json:"nuclei_tags"`              // Only run templates with one of these tags
+	NucleiExcludeTags      []string `json:"nuclei_exclude_tags"`      // Never run templates with one of these tags
+	NucleiTemplates        []string `json:"nuclei_templates"`         // Only run these templates or template directories
+	NucleiExcludeTemplates []string `json:"nuclei_exclude_templates"` // Never run these templates or template directories
+	NucleiTemplatesDir     string   `json:"nuclei_templates_dir"`     // The customer's own templates, run alongside NucleiTemplates
+	NucleiMinSeverity      string   `json:"nuclei_min_severity"`      // Only run templates of at least this severity
+
+	// Agentless Collection Configuration; credentials stay on the agent
+	CollectorSSHUsername   string `json:"collector_ssh_username"`
+	CollectorSSHPassword   string `json:"collector_ssh_password"`
+	CollectorSSHKeyPath    string `json:"collector_ssh_key_path"`    // PEM private key, tried before the password
+	CollectorSSHKnownHosts string `json:"collector_ssh_known_hosts"` // known_hosts file host keys are verified against
+	CollectorWinRMUsername string `json:"collector_winrm_username"`
+	CollectorWinRMPassword string `json:"collector_winrm_password"`
+	CollectorWinRMHTTPS    bool   `json:"collector_winrm_https"` // Connect on 5986 over HTTPS rather than 5985
 
 	// AI/ML Configuration
 	FairnessThreshold    float64 `json:"fairness_threshold"`
 	DataQualityThreshold float64 `json:"data_quality_threshold"`
 	RiskThreshold        float64 `json:"risk_threshold"`
 
+	// AI/ML group-fairness metrics over labeled datasets (opt-in)
+	FairnessMetrics     bool     `json:"fairness_metrics"`
+	ProtectedAttributes []string `json:"protected_attributes"` // Column names treated as protected attributes
+	LabelColumns        []string `json:"label_columns"`        // Column names treated as the outcome label
+	FairnessSampleRows  int      `json:"fairness_sample_rows"` // Most rows of a dataset read to compute metrics
+
 	// Database Configuration
 	DBHost     string `json:"db_host"`
 	DBPort     int    `json:"db_port"`
@@ -69,47 +171,76 @@ type Config struct {
 	DBUser     string `json:"db_user"`
 	DBPassword string `json:"db_password"`
 	DBSSLMode  string `json:"db_ssl_mode"`
+
+	settings   []Setting // Every value with its source, for --print-config
+	loadErrors []error   // Values that failed to parse and fell back to their defaults
 }
 
-// Load loads configuration from environment variables
+// Load loads configuration from environment variables. Values that fail to
+// parse fall back to their defaults and are reported by Validate.
 func Load() *Config {
-	apiPort, _ := strconv.Atoi(getEnv("API_PORT", "8080"))
-	dbPort, _ := strconv.Atoi(getEnv("DB_PORT", "5432"))
-	debug, _ := strconv.ParseBool(getEnv("DEBUG", "false"))
+	l := &loader{}
 
 	// Get or generate agent ID (persist to disk)
 	agentID := getOrGenerateAgentID()
+	agentIDSource := SourceDefault
+	if os.Getenv("AGENT_ID") == agentID {
+		agentIDSource = SourceEnv
+	}
+	l.record("AGENT_ID", agentID, agentIDSource, "Agent UUID; generated and persisted when unset", false)
+
+	hostname := getHostname()
 
-	return &Config{
+	cfg := &Config{
 		// Agent Configuration
 		AgentID:     agentID,
-		APIURL:      getEnv("API_URL", "http://localhost:8080"),
-		APIEndpoint: getEnv("API_ENDPOINT", "http://localhost:8080"),
-		APIKey:      getEnv("API_KEY", ""),
+		APIURL:      l.String("API_URL", "http://localhost:8080", "ZeroTrace API base URL"),
+		APIEndpoint: l.String("API_ENDPOINT", "http://localhost:8080", "ZeroTrace API endpoint results are sent to"),
+		APIKey:      l.Secret("API_KEY", "", "API key for legacy registration"),
 		APITimeout:  30, // 30 seconds default
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
-		Debug:       debug,
+		LogLevel:    l.String("LOG_LEVEL", "info", "debug, info, warn or error"),
+		Debug:       l.Bool("DEBUG", false, "Enable debug logging"),
 
 		// Enrollment Configuration
-		EnrollmentToken: getEnv("ENROLLMENT_TOKEN", ""),
-		AgentCredential: getEnv("AGENT_CREDENTIAL", ""),
-		OrganizationID:  getEnv("ZEROTRACE_ORGANIZATION_ID", ""),
-		AgentName:       getEnv("AGENT_NAME", getHostname()),
+		EnrollmentToken: l.Secret("ENROLLMENT_TOKEN", "", "One-time token used to enroll with an organization"),
+		AgentCredential: l.Secret("AGENT_CREDENTIAL", "", "Credential issued at enrollment"),
+		OrganizationID:  l.String("ZEROTRACE_ORGANIZATION_ID", "", "Organization the agent is enrolled in"),
+		AgentName:       l.String("AGENT_NAME", hostname, "Display name; defaults to the hostname"),
+		HostGroup:       l.String("HOST_GROUP", "default", "Host group whose config baseline this host is compared against"),
+
+		// Mutual TLS Configuration
+		MTLSEnabled:    l.Bool("MTLS_ENABLED", false, "Authenticate to the API with a TLS client certificate"),
+		ClientCertPath: l.String("CLIENT_CERT_PATH", "", "PEM client certificate whose common name is the agent ID"),
+		ClientKeyPath:  l.String("CLIENT_KEY_PATH", "", "PEM private key of CLIENT_CERT_PATH"),
+		CACertPath:     l.String("CA_CERT_PATH", "", "PEM CA certificates the API certificate is verified against; system roots when empty"),
+
+		// Result Queue Configuration
+		ResultQueueDir:           l.String("RESULT_QUEUE_DIR", filepath.Join(filepath.Dir(getAgentIDFilePath()), "queue"), "Directory unsent results are queued in, encrypted"),
+		ResultQueueMaxBytes:      l.Int("RESULT_QUEUE_MAX_BYTES", 100*1024*1024, "Most bytes of unsent results kept; the oldest are dropped beyond it"),
+		ResultQueueFlushInterval: l.Duration("RESULT_QUEUE_FLUSH_INTERVAL", time.Minute, "How often unsent results are retried"),
+
+		// Delta Reporting Configuration
+		ResultDelta:             l.Bool("RESULT_DELTA", true, "Send only the findings changed since the last result of a scan type"),
+		ResultDeltaStatePath:    l.String("RESULT_DELTA_STATE_PATH", filepath.Join(filepath.Dir(getAgentIDFilePath()), "finding_fingerprints.json"), "File the fingerprints of the findings last sent are kept in"),
+		ResultDeltaFullInterval: l.Duration("RESULT_DELTA_FULL_INTERVAL", 24*time.Hour, "How often each scan type's findings are sent in full to correct drift"),
 
 		// Company-specific Configuration (legacy)
-		CompanyID:   getEnv("COMPANY_ID", ""),
-		CompanyName: getEnv("COMPANY_NAME", ""),
-		CompanySlug: getEnv("COMPANY_SLUG", ""),
+		CompanyID:   l.String("COMPANY_ID", "", "Legacy company ID"),
+		CompanyName: l.String("COMPANY_NAME", "", "Legacy company name"),
+		CompanySlug: l.String("COMPANY_SLUG", "", "Legacy company slug"),
 
 		// System Information
-		Hostname: getEnv("HOSTNAME", getHostname()),
-		OS:       getEnv("OS", getOS()),
+		Hostname: l.String("HOSTNAME", hostname, "Hostname reported to the API"),
+		OS:       l.String("OS", getOS(), "Operating system reported to the API"),
 
 		// API Configuration
-		APIPort: apiPort,
+		APIPort: l.Int("API_PORT", 8080, "Local API port"),
+
+		// Local Control Configuration
+		IPCAddress: l.String("AGENT_SOCKET", DefaultIPCAddress(), "Unix socket or Windows named pipe the tray sends Scan Now through"),
 
 		// Enrichment Configuration
-		EnrichmentURL: getEnv("ZEROTRACE_ENRICHMENT_URL", "http://localhost:8000"),
+		EnrichmentURL: l.String("ZEROTRACE_ENRICHMENT_URL", "http://localhost:8000", "Enrichment service base URL"),
 
 		// Scan Configuration
 		ScanInterval:    5 * time.Minute,  // Default 5 minutes
@@ -118,34 +249,114 @@ func Load() *Config {
 		MaxFileSizeMB:   10,               // 10MB default
 		MaxWorkers:      4,                // Default 4 workers
 		ScanTimeout:     30 * time.Minute, // Default 30 minutes
-		ExcludePatterns: []string{".git", "node_modules", ".DS_Store", "*.log"},
+		ExcludePatterns: l.List("EXCLUDE_PATTERNS", ".git,node_modules,.DS_Store,*.log", "File and directory names skipped when walking the filesystem"),
 		IncludePatterns: []string{".go", ".py", ".js", ".ts", ".java", ".php", ".rb", ".rs", ".cpp", ".c", ".cs"},
+		IncrementalScan: l.Bool("INCREMENTAL_SCAN", false, "Only enrich software packages added or changed since the previous scan"),
+		ScanSchedule:    l.String("SCAN_SCHEDULE", "", "Cron expression of the minutes periodic scans may start in, such as \"* 1-5 * * *\"; any time when empty"),
+
+		// Scanner Selection
+		EnabledScanners:  l.List("ENABLED_SCANNERS", "", "Scanners, built-in or registered, this agent may run; all when empty"),
+		DisabledScanners: l.List("DISABLED_SCANNERS", "", "Scanners this agent never runs, whatever its scan scope"),
+
+		// osquery Configuration
+		OsqueryEnabled:     l.Bool("OSQUERY_ENABLED", true, "Inventory the host through osquery when it is installed, rather than the native commands"),
+		OsqueryBinary:      l.String("OSQUERY_BINARY", "osqueryi", "osqueryi binary queries run through"),
+		OsquerySocket:      l.String("OSQUERY_SOCKET", defaultOsquerySocket(), "osqueryd extensions socket queried when present; osqueryi runs standalone otherwise"),
+		OsqueryQueriesPath: l.String("OSQUERY_QUERIES_PATH", "", "JSON file of osquery queries run in addition to the curated ones"),
+
+		// Container Image Scanning
+		ContainerImageScan: l.Bool("CONTAINER_IMAGE_SCAN", false, "Match the OS packages in running containers' images against NVD"),
+		NVDAPIKey:          l.Secret("NVD_API_KEY", "", "NVD API key, for a higher rate limit"),
+
+		// CVE Cache
+		CVECachePath:        l.String("CVE_CACHE_PATH", filepath.Join(filepath.Dir(getAgentIDFilePath()), "cve_cache.db"), "SQLite database NVD lookups are cached in; no cache when empty"),
+		CVECacheTTL:         l.Duration("CVE_CACHE_TTL", 48*time.Hour, "How long a cached CVE record or package match is used before it is looked up again"),
+		NVDFeedSyncInterval: l.Duration("NVD_FEED_SYNC_INTERVAL", 24*time.Hour, "How often NVD's modified feed is loaded into the CVE cache (0 = never)"),
+		NVDFeedURL:          l.String("NVD_FEED_URL", "https://nvd.nist.gov/feeds/json/cve/2.0/nvdcve-2.0-modified.json.gz", "NVD's modified CVE feed"),
+
+		// Advisory Sources
+		CVESources:  l.List("CVE_SOURCES", "", "Advisory databases dependencies are matched against on the agent (osv, gitlab, github); matching is left to the API when empty"),
+		GitLabToken: l.Secret("GITLAB_TOKEN", "", "GitLab token, only needed to look GitLab advisories up by ID"),
+
+		// Scan Resource Budget
+		ScanMaxConcurrent:   l.Int("SCAN_MAX_CONCURRENT", 2, "Scanners allowed to run at once"),
+		ScanMaxHeavy:        l.Int("SCAN_MAX_HEAVY", 1, "Filesystem-heavy scanners allowed at once"),
+		ScanCPUThreshold:    l.Float("SCAN_CPU_THRESHOLD", 80, "Defer heavy scanners while host CPU % is above this (0 = never)"),
+		ScanThrottleMaxWait: l.Duration("SCAN_THROTTLE_MAX_WAIT", 10*time.Minute, "Longest a heavy scanner is deferred for CPU (0 = no limit)"),
+		ScanMaxProcs:        l.Int("SCAN_MAX_PROCS", 0, "CPUs the agent may use (0 = all)"),
+
+		// Configuration Checks
+		ConfigCheckWorkers:   l.Int("CONFIG_CHECK_WORKERS", 4, "Configuration checks run at once"),
+		ConfigCommandTimeout: l.Duration("CONFIG_COMMAND_TIMEOUT", 15*time.Second, "Longest a configuration check's command may run before the check is reported unable to determine its setting"),
+		ConfigScanTimeout:    l.Duration("CONFIG_SCAN_TIMEOUT", 2*time.Minute, "Longest a configuration scan's checks may take altogether; checks still running are reported unable to determine"),
+
+		// Shutdown Configuration
+		ShutdownDrainTimeout: l.Duration("SHUTDOWN_DRAIN_TIMEOUT", 2*time.Minute, "How long shutdown waits for in-flight scans and their uploads (0 = don't wait)"),
+
+		// Self-Update Configuration
+		AutoUpdate:          l.Bool("AUTO_UPDATE", false, "Install newer agent releases the API offers on heartbeat"),
+		UpdatePublicKey:     l.String("UPDATE_PUBLIC_KEY", "", "Base64 ed25519 public key agent releases must be signed with"),
+		UpdateHealthTimeout: l.Duration("UPDATE_HEALTH_TIMEOUT", 30*time.Second, "How long an installed release has to pass its self-check before it is rolled back"),
 
 		// Network Scan Configuration
-		NetworkScanInterval: 6 * time.Hour, // Default 6 hours
-		NetworkScanEnabled:  getEnv("NETWORK_SCAN_ENABLED", "true") == "true",
+		NetworkScanInterval:           6 * time.Hour, // Default 6 hours
+		NetworkScanEnabled:            l.Bool("NETWORK_SCAN_ENABLED", true, "Run network scans"),
+		NetworkScanSchedule:           l.String("NETWORK_SCAN_SCHEDULE", "", "Cron expression of the minutes network scans may start in; any time when empty"),
+		NetworkScanCIDRs:              l.List("NETWORK_SCAN_CIDRS", "", "Subnets network scans may cover; the first interface's subnet when empty"),
+		NetworkScanExcludeCIDRs:       l.List("NETWORK_SCAN_EXCLUDE_CIDRS", "", "Subnets network scans never cover"),
+		NetworkScanMaxConcurrentHosts: l.Int("NETWORK_SCAN_MAX_CONCURRENT_HOSTS", 16, "Hosts a network scan probes at once"),
+		NetworkScanMaxPPS:             l.Int("NETWORK_SCAN_MAX_PPS", 300, "Packets per second a network scan sends at most"),
+		TLSCertExpiryDays:             l.Int("TLS_CERT_EXPIRY_DAYS", 30, "Flag TLS certificates expiring within this many days"),
+		WeakTLSProtocols:              l.List("WEAK_TLS_PROTOCOLS", "SSLv3,TLS1.0,TLS1.1", "TLS protocol versions reported when a service accepts them"),
+		WeakTLSCiphers:                l.List("WEAK_TLS_CIPHERS", "NULL,EXPORT,anon,RC4,DES,3DES,MD5", "Cipher suite name components that make a suite weak"),
+		WeakSSHAlgorithms: l.List("WEAK_SSH_ALGORITHMS",
+			"diffie-hellman-group1-sha1,diffie-hellman-group-exchange-sha1,ssh-dss,arcfour,arcfour128,arcfour256,3des-cbc,blowfish-cbc,cast128-cbc,hmac-md5,hmac-md5-96,hmac-sha1-96",
+			"SSH algorithms reported when a service offers them"),
+		TopologyMaxNodes:  l.Int("TOPOLOGY_MAX_NODES", 5000, "Largest network graph analyzed whole; larger ones are partitioned by subnet"),
+		TopologyMaxEdges:  l.Int("TOPOLOGY_MAX_EDGES", 50000, "Most connections in a network graph analyzed whole"),
+		TopologyRiskDecay: l.Float("TOPOLOGY_RISK_DECAY", 0.7, "Share of an asset's risk passed on per hop to the assets it can reach (0 = off)"),
+
+		// Nuclei Template Selection
+		NucleiProfile:          l.String("NUCLEI_PROFILE", "safe", "Nuclei template profile: safe leaves out intrusive, DoS, fuzzing and brute-force templates; full runs them"),
+		NucleiTags:             l.List("NUCLEI_TAGS", "", "Only run Nuclei templates with one of these tags; all when empty"),
+		NucleiExcludeTags:      l.List("NUCLEI_EXCLUDE_TAGS", "", "Never run Nuclei templates with one of these tags, besides those the profile leaves out"),
+		NucleiTemplates:        l.List("NUCLEI_TEMPLATES", "", "Only run these Nuclei templates or template directories; Nuclei's own templates when empty"),
+		NucleiExcludeTemplates: l.List("NUCLEI_EXCLUDE_TEMPLATES", "", "Never run these Nuclei templates or template directories"),
+		NucleiTemplatesDir:     l.String("NUCLEI_TEMPLATES_DIR", "", "Directory of custom Nuclei templates, run alongside NUCLEI_TEMPLATES"),
+		NucleiMinSeverity:      l.String("NUCLEI_MIN_SEVERITY", "info", "Only run Nuclei templates of at least this severity (info, low, medium, high, critical)"),
+
+		// Agentless Collection Configuration
+		CollectorSSHUsername:   l.String("COLLECTOR_SSH_USERNAME", "", "User SSH collection logs in as"),
+		CollectorSSHPassword:   l.Secret("COLLECTOR_SSH_PASSWORD", "", "Password SSH collection logs in with"),
+		CollectorSSHKeyPath:    l.String("COLLECTOR_SSH_KEY_PATH", "", "PEM private key SSH collection logs in with"),
+		CollectorSSHKnownHosts: l.String("COLLECTOR_SSH_KNOWN_HOSTS", "", "known_hosts file collected hosts' keys are verified against"),
+		CollectorWinRMUsername: l.String("COLLECTOR_WINRM_USERNAME", "", "User WinRM collection authenticates as"),
+		CollectorWinRMPassword: l.Secret("COLLECTOR_WINRM_PASSWORD", "", "Password WinRM collection authenticates with"),
+		CollectorWinRMHTTPS:    l.Bool("COLLECTOR_WINRM_HTTPS", true, "Collect over WinRM HTTPS (5986) rather than HTTP (5985)"),
 
 		// AI/ML Configuration
 		FairnessThreshold:    0.8, // Default 80% fairness threshold
 		DataQualityThreshold: 0.7, // Default 70% data quality threshold
 		RiskThreshold:        0.6, // Default 60% risk threshold
 
+		// AI/ML group-fairness metrics
+		FairnessMetrics:     l.Bool("AIML_FAIRNESS_METRICS", false, "Compute group-fairness metrics for labeled CSV datasets"),
+		ProtectedAttributes: l.List("AIML_PROTECTED_ATTRIBUTES", "gender,sex,race,ethnicity,religion,disability,nationality,marital_status,age_group", "Dataset columns treated as protected attributes"),
+		LabelColumns:        l.List("AIML_LABEL_COLUMNS", "label,target,outcome,approved,y", "Dataset columns treated as the binary outcome label"),
+		FairnessSampleRows:  l.Int("AIML_FAIRNESS_SAMPLE_ROWS", 10000, "Most rows of a dataset read to compute fairness metrics"),
+
 		// Database Configuration
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     dbPort,
-		DBName:     getEnv("DB_NAME", "zerotrace"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", ""),
-		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
+		DBHost:     l.String("DB_HOST", "localhost", "PostgreSQL host"),
+		DBPort:     l.Int("DB_PORT", 5432, "PostgreSQL port"),
+		DBName:     l.String("DB_NAME", "zerotrace", "PostgreSQL database name"),
+		DBUser:     l.String("DB_USER", "postgres", "PostgreSQL user"),
+		DBPassword: l.Secret("DB_PASSWORD", "", "PostgreSQL password"),
+		DBSSLMode:  l.String("DB_SSL_MODE", "disable", "PostgreSQL sslmode"),
 	}
-}
 
-// getEnv gets environment variable with fallback
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return fallback
+	cfg.settings = l.settings
+	cfg.loadErrors = l.errs
+	return cfg
 }
 
 // IsEnrolled checks if the agent is enrolled with an organization
@@ -171,6 +382,16 @@ func (c *Config) GetCompanyIdentifier() string {
 	return c.CompanyID
 }
 
+// ScannerEnabled reports whether this agent may run a scanner, as named in
+// its scan scope or when registered. The scan scope still decides whether
+// an enabled scanner runs.
+func (c *Config) ScannerEnabled(name string) bool {
+	if slices.Contains(c.DisabledScanners, name) {
+		return false
+	}
+	return len(c.EnabledScanners) == 0 || slices.Contains(c.EnabledScanners, name)
+}
+
 // GetOrganizationIdentifier returns the organization identifier
 func (c *Config) GetOrganizationIdentifier() string {
 	return c.OrganizationID
@@ -230,6 +451,23 @@ func getOrGenerateAgentID() string {
 }
 
 // getAgentIDFilePath returns the path to the agent ID file
+// DefaultIPCAddress is the agent's control socket when AGENT_SOCKET is unset:
+// a named pipe on Windows, and a socket next to the agent ID elsewhere
+func DefaultIPCAddress() string {
+	if runtime.GOOS == "windows" {
+		return `\\.\pipe\zerotrace-agent`
+	}
+	return filepath.Join(filepath.Dir(getAgentIDFilePath()), "agent.sock")
+}
+
+// defaultOsquerySocket is where osqueryd puts its extensions socket by default
+func defaultOsquerySocket() string {
+	if runtime.GOOS == "windows" {
+		return `\\.\pipe\osquery.em`
+	}
+	return "/var/osquery/osquery.em"
+}
+
 func getAgentIDFilePath() string {
 	// Use different paths based on OS
 	switch runtime.GOOS {
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Source says where a configuration value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+)
+
+// Setting describes one configuration value as it was resolved
+type Setting struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"` // Redacted for secrets
+	Source      Source `json:"source"`
+	Description string `json:"description"`
+	Secret      bool   `json:"secret,omitempty"`
+}
+
+// fileKeys holds the variables LoadFile read from an env file
+var fileKeys = map[string]bool{}
+
+// LoadFile loads variables from an env file into the environment without
+// overriding variables that are already set
+func LoadFile(path string) error {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		fileKeys[key] = true
+	}
+	return nil
+}
+
+// loader reads typed values from the environment, recording each value's
+// source and any value that failed to parse
+type loader struct {
+	settings []Setting
+	errs     []error
+}
+
+func (l *loader) lookup(key string) (string, Source) {
+	value := os.Getenv(key)
+	if value == "" {
+		return "", SourceDefault
+	}
+	if fileKeys[key] {
+		return value, SourceFile
+	}
+	return value, SourceEnv
+}
+
+func (l *loader) record(key string, value interface{}, source Source, description string, secret bool) {
+	display := fmt.Sprint(value)
+	if secret && display != "" {
+		display = "********"
+	}
+	l.settings = append(l.settings, Setting{
+		Key:         key,
+		Value:       display,
+		Source:      source,
+		Description: description,
+		Secret:      secret,
+	})
+}
+
+// parse converts a variable with fn, falling back to the default when it is
+// unset or invalid
+func parse[T any](l *loader, key string, defaultValue T, kind, description string, fn func(string) (T, error)) T {
+	raw, source := l.lookup(key)
+	value := defaultValue
+	if source != SourceDefault {
+		parsed, err := fn(raw)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("%s: %q is not a valid %s", key, raw, kind))
+			source = SourceDefault
+		} else {
+			value = parsed
+		}
+	}
+	l.record(key, value, source, description, false)
+	return value
+}
+
+func (l *loader) String(key, defaultValue, description string) string {
+	raw, source := l.lookup(key)
+	if source == SourceDefault {
+		raw = defaultValue
+	}
+	l.record(key, raw, source, description, false)
+	return raw
+}
+
+// Secret is like String but the value is redacted when printed
+func (l *loader) Secret(key, defaultValue, description string) string {
+	raw, source := l.lookup(key)
+	if source == SourceDefault {
+		raw = defaultValue
+	}
+	l.record(key, raw, source, description, true)
+	return raw
+}
+
+// List reads a comma-separated list, dropping empty items
+func (l *loader) List(key, defaultValue, description string) []string {
+	var items []string
+	for _, item := range strings.Split(l.String(key, defaultValue, description), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (l *loader) Int(key string, defaultValue int, description string) int {
+	return parse(l, key, defaultValue, "integer", description, strconv.Atoi)
+}
+
+func (l *loader) Float(key string, defaultValue float64, description string) float64 {
+	return parse(l, key, defaultValue, "number", description, func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+func (l *loader) Bool(key string, defaultValue bool, description string) bool {
+	return parse(l, key, defaultValue, "boolean", description, strconv.ParseBool)
+}
+
+func (l *loader) Duration(key string, defaultValue time.Duration, description string) time.Duration {
+	return parse(l, key, defaultValue, "duration", description, time.ParseDuration)
+}
+
+// Settings returns every configuration value with its source, in load order
+func (c *Config) Settings() []Setting {
+	return c.settings
+}
+
+// PrintSettings writes the effective configuration as a table, secrets redacted
+func (c *Config) PrintSettings(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE\tDESCRIPTION")
+	for _, s := range c.settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Key, s.Value, s.Source, s.Description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+)
+
+// NucleiSeverities are Nuclei's template severities, least severe first
+var NucleiSeverities = []string{"info", "low", "medium", "high", "critical"}
+
+// Validate checks every configuration value and reports all problems at once
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.loadErrors...)
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validURL(c.APIURL), "API_URL must be an http(s) URL, got %q", c.APIURL)
+	check(validURL(c.APIEndpoint), "API_ENDPOINT must be an http(s) URL, got %q", c.APIEndpoint)
+	check(validURL(c.EnrichmentURL), "ZEROTRACE_ENRICHMENT_URL must be an http(s) URL, got %q", c.EnrichmentURL)
+	check(c.LogLevel == "debug" || c.LogLevel == "info" || c.LogLevel == "warn" || c.LogLevel == "error",
+		"LOG_LEVEL must be one of debug, info, warn or error, got %q", c.LogLevel)
+
+	// An enrolled agent needs both halves of its identity
+	check(c.AgentCredential == "" || c.OrganizationID != "", "ZEROTRACE_ORGANIZATION_ID is required when AGENT_CREDENTIAL is set")
+	check(c.HostGroup != "", "HOST_GROUP must not be empty")
+	check(c.ResultQueueDir != "", "RESULT_QUEUE_DIR must not be empty")
+	check(c.ResultQueueMaxBytes > 0, "RESULT_QUEUE_MAX_BYTES must be positive, got %d", c.ResultQueueMaxBytes)
+	check(c.ResultQueueFlushInterval > 0, "RESULT_QUEUE_FLUSH_INTERVAL must be positive")
+	check(!c.ResultDelta || c.ResultDeltaStatePath != "", "RESULT_DELTA_STATE_PATH must not be empty when RESULT_DELTA is set")
+	check(c.ResultDeltaFullInterval > 0, "RESULT_DELTA_FULL_INTERVAL must be positive")
+
+	// Mutual TLS; a missing certificate must stop the agent rather than fall back to plain TLS
+	if c.MTLSEnabled {
+		check(c.ClientCertPath != "" && c.ClientKeyPath != "", "CLIENT_CERT_PATH and CLIENT_KEY_PATH are required when MTLS_ENABLED is set")
+		check(c.ClientCertPath == "" || fileExists(c.ClientCertPath), "CLIENT_CERT_PATH %q does not exist", c.ClientCertPath)
+		check(c.ClientKeyPath == "" || fileExists(c.ClientKeyPath), "CLIENT_KEY_PATH %q does not exist", c.ClientKeyPath)
+	}
+	check(c.CACertPath == "" || fileExists(c.CACertPath), "CA_CERT_PATH %q does not exist", c.CACertPath)
+
+	check(c.APIPort > 0 && c.APIPort <= 65535, "API_PORT must be between 1 and 65535, got %d", c.APIPort)
+	check(c.DBPort > 0 && c.DBPort <= 65535, "DB_PORT must be between 1 and 65535, got %d", c.DBPort)
+
+	// Scan resource budget
+	check(c.ScanMaxConcurrent > 0, "SCAN_MAX_CONCURRENT must be positive, got %d", c.ScanMaxConcurrent)
+	check(c.ScanMaxHeavy > 0 && c.ScanMaxHeavy <= c.ScanMaxConcurrent,
+		"SCAN_MAX_HEAVY must be between 1 and SCAN_MAX_CONCURRENT (%d), got %d", c.ScanMaxConcurrent, c.ScanMaxHeavy)
+	check(c.ScanCPUThreshold >= 0 && c.ScanCPUThreshold <= 100, "SCAN_CPU_THRESHOLD must be between 0 and 100, got %g", c.ScanCPUThreshold)
+	check(c.ScanThrottleMaxWait >= 0, "SCAN_THROTTLE_MAX_WAIT must not be negative")
+	check(c.ScanMaxProcs >= 0, "SCAN_MAX_PROCS must not be negative, got %d", c.ScanMaxProcs)
+	check(c.ConfigCheckWorkers > 0, "CONFIG_CHECK_WORKERS must be positive, got %d", c.ConfigCheckWorkers)
+	check(c.ConfigCommandTimeout > 0, "CONFIG_COMMAND_TIMEOUT must be positive")
+	check(c.ConfigScanTimeout > 0, "CONFIG_SCAN_TIMEOUT must be positive")
+	check(c.ShutdownDrainTimeout >= 0, "SHUTDOWN_DRAIN_TIMEOUT must not be negative")
+
+	// Self-update; without the pinned key no release could be verified
+	if c.AutoUpdate {
+		key, err := base64.StdEncoding.DecodeString(c.UpdatePublicKey)
+		check(err == nil && len(key) == ed25519.PublicKeySize, "UPDATE_PUBLIC_KEY must be a base64 ed25519 public key when AUTO_UPDATE is set")
+	}
+	check(c.UpdateHealthTimeout > 0, "UPDATE_HEALTH_TIMEOUT must be positive")
+
+	// CVE cache
+	check(c.CVECacheTTL > 0, "CVE_CACHE_TTL must be positive")
+	check(c.NVDFeedSyncInterval >= 0, "NVD_FEED_SYNC_INTERVAL must not be negative")
+	check(c.NVDFeedSyncInterval == 0 || validURL(c.NVDFeedURL), "NVD_FEED_URL must be an http(s) URL, got %q", c.NVDFeedURL)
+
+	// Advisory sources
+	for _, source := range c.CVESources {
+		check(source == "osv" || source == "gitlab" || source == "github", "CVE_SOURCES entries must be osv, gitlab or github, got %q", source)
+	}
+
+	// Network scans
+	for _, cidr := range c.NetworkScanCIDRs {
+		_, _, err := net.ParseCIDR(cidr)
+		check(err == nil, "NETWORK_SCAN_CIDRS entries must be CIDRs such as 10.0.0.0/24, got %q", cidr)
+	}
+	for _, cidr := range c.NetworkScanExcludeCIDRs {
+		_, _, err := net.ParseCIDR(cidr)
+		check(err == nil, "NETWORK_SCAN_EXCLUDE_CIDRS entries must be CIDRs such as 10.0.0.0/24, got %q", cidr)
+	}
+	check(c.NetworkScanMaxConcurrentHosts > 0, "NETWORK_SCAN_MAX_CONCURRENT_HOSTS must be positive, got %d", c.NetworkScanMaxConcurrentHosts)
+	check(c.NetworkScanMaxPPS > 0, "NETWORK_SCAN_MAX_PPS must be positive, got %d", c.NetworkScanMaxPPS)
+	check(c.TLSCertExpiryDays > 0, "TLS_CERT_EXPIRY_DAYS must be positive, got %d", c.TLSCertExpiryDays)
+	for _, protocol := range c.WeakTLSProtocols {
+		check(protocol == "SSLv3" || protocol == "TLS1.0" || protocol == "TLS1.1" || protocol == "TLS1.2",
+			"WEAK_TLS_PROTOCOLS entries must be SSLv3, TLS1.0, TLS1.1 or TLS1.2, got %q", protocol)
+	}
+	check(c.TopologyMaxNodes > 0, "TOPOLOGY_MAX_NODES must be positive, got %d", c.TopologyMaxNodes)
+	check(c.TopologyMaxEdges > 0, "TOPOLOGY_MAX_EDGES must be positive, got %d", c.TopologyMaxEdges)
+	check(c.TopologyRiskDecay >= 0 && c.TopologyRiskDecay < 1, "TOPOLOGY_RISK_DECAY must be at least 0 and below 1, got %g", c.TopologyRiskDecay)
+	check(c.NucleiProfile == "safe" || c.NucleiProfile == "full", "NUCLEI_PROFILE must be safe or full, got %q", c.NucleiProfile)
+	check(slices.Contains(NucleiSeverities, c.NucleiMinSeverity),
+		"NUCLEI_MIN_SEVERITY must be one of info, low, medium, high or critical, got %q", c.NucleiMinSeverity)
+	check(c.NucleiTemplatesDir == "" || dirExists(c.NucleiTemplatesDir), "NUCLEI_TEMPLATES_DIR %q is not a directory", c.NucleiTemplatesDir)
+
+	// Agentless collection
+	check(c.CollectorSSHKeyPath == "" || fileExists(c.CollectorSSHKeyPath), "COLLECTOR_SSH_KEY_PATH %q does not exist", c.CollectorSSHKeyPath)
+	check(c.CollectorSSHKnownHosts == "" || fileExists(c.CollectorSSHKnownHosts), "COLLECTOR_SSH_KNOWN_HOSTS %q does not exist", c.CollectorSSHKnownHosts)
+
+	// AI/ML fairness metrics
+	check(c.FairnessSampleRows > 0, "AIML_FAIRNESS_SAMPLE_ROWS must be positive, got %d", c.FairnessSampleRows)
+
+	return errors.Join(errs...)
+}
+
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// dirExists reports whether path names a directory
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// fileExists reports whether path names a regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -15,8 +15,10 @@ import (
 
 // NetworkDiscovery handles network asset discovery
 type NetworkDiscovery struct {
-	agentID   string
-	companyID string
+	agentID     string
+	companyID   string
+	graphLimits GraphLimits
+	riskDecay   float64
 }
 
 // NewNetworkDiscovery creates a new network discovery instance
@@ -24,9 +26,22 @@ func NewNetworkDiscovery(agentID, companyID string) *NetworkDiscovery {
 	return &NetworkDiscovery{
 		agentID:   agentID,
 		companyID: companyID,
+		riskDecay: DefaultRiskDecay,
 	}
 }
 
+// SetGraphLimits sets the size above which topology analysis partitions the
+// network graph by subnet instead of analyzing it whole
+func (nd *NetworkDiscovery) SetGraphLimits(limits GraphLimits) {
+	nd.graphLimits = limits
+}
+
+// SetRiskDecay sets the share of an asset's risk topology analysis passes on
+// to each asset it can reach, per hop
+func (nd *NetworkDiscovery) SetRiskDecay(decay float64) {
+	nd.riskDecay = decay
+}
+
 // DiscoverLocalNetwork discovers assets on the local network
 func (nd *NetworkDiscovery) DiscoverLocalNetwork(ctx context.Context) ([]models.NetworkAsset, error) {
 	var assets []models.NetworkAsset
@@ -61,15 +76,20 @@ func (nd *NetworkDiscovery) DiscoverLocalNetwork(ctx context.Context) ([]models.
 		}
 	}
 
-	// Perform network topology analysis using Fast SSSP
+	// Perform network topology analysis
 	if len(assets) > 0 {
-		analyzer := NewNetworkPathAnalyzer()
+		analyzer := NewNetworkPathAnalyzerWithLimits(nd.graphLimits)
+		analyzer.SetRiskDecay(nd.riskDecay)
 		topology, err := analyzer.AnalyzeNetworkTopology(ctx, assets)
 		if err != nil {
 			log.Printf("Warning: Failed to analyze network topology: %v", err)
 		} else {
 			log.Printf("Network topology analysis complete: %d nodes, %d connections, %d critical paths found", 
 				topology.TotalAssets, topology.TotalConnections, len(topology.CriticalPaths))
+			if topology.Sampled {
+				log.Printf("Network graph exceeded the analysis limits: critical paths were found across %d subnets, leaving out %d nodes",
+					topology.Partitions, topology.DroppedNodes)
+			}
 		}
 	}
 
@@ -0,0 +1,382 @@
+package discovery
+
+import (
+	"container/heap"
+	"context"
+	"net"
+	"sort"
+
+	"zerotrace/agent/internal/models"
+)
+
+// Default limits on the graph NetworkPathAnalyzer analyzes as a whole
+const (
+	DefaultMaxGraphNodes = 5000
+	DefaultMaxGraphEdges = 50000
+)
+
+// SamplingSubnetPartition names the strategy used on graphs over the limits,
+// reported in NetworkTopology.SamplingStrategy
+const SamplingSubnetPartition = "subnet_partition"
+
+// GraphLimits bound the graph NetworkPathAnalyzer analyzes as a whole. Zero
+// values mean the defaults. On a graph over either limit, critical paths are
+// found by partitioning it by subnet instead:
+//
+//  1. Nodes are grouped by subnet: the asset's Subnet, else its /24 (IPv4) or
+//     /64 (IPv6).
+//  2. Each subnet is analyzed on its own. A subnet that is itself over the
+//     limits keeps only its riskiest nodes, and the cheapest edges among them.
+//  3. Paths between subnets are stitched through their border nodes, the
+//     endpoints of inter-subnet edges. A border graph holds the inter-subnet
+//     edges and, per subnet, an edge from each node entering it to each node
+//     leaving it, weighted by the shortest path between them inside the
+//     subnet. A high-risk source reaches its subnet's exits inside the
+//     subnet, crosses the border graph, and reaches servers from the entries
+//     of their subnets.
+//
+// A partitioned result is flagged as sampled. It may miss or overestimate
+// paths that run through nodes sampling dropped, or that leave a subnet and
+// come back into it.
+type GraphLimits struct {
+	MaxNodes int
+	MaxEdges int
+}
+
+func (l GraphLimits) withDefaults() GraphLimits {
+	if l.MaxNodes <= 0 {
+		l.MaxNodes = DefaultMaxGraphNodes
+	}
+	if l.MaxEdges <= 0 {
+		l.MaxEdges = DefaultMaxGraphEdges
+	}
+	return l
+}
+
+// allows reports whether a graph is small enough to analyze as a whole
+func (l GraphLimits) allows(graph *NetworkGraph) bool {
+	return len(graph.Nodes) <= l.MaxNodes && graph.edgeCount() <= l.MaxEdges
+}
+
+// graphSample describes how a graph over the limits was analyzed
+type graphSample struct {
+	partitions   int // Subnets analyzed separately
+	droppedNodes int // Nodes sampling left out of the analysis
+}
+
+type graphEdge struct {
+	source, dest string
+	weight       float64
+}
+
+func newNetworkGraph() *NetworkGraph {
+	return &NetworkGraph{
+		Nodes: make(map[string]*models.NetworkAsset),
+		Edges: make(map[string]map[string]float64),
+	}
+}
+
+func (g *NetworkGraph) addEdge(source, dest string, weight float64) {
+	if g.Edges[source] == nil {
+		g.Edges[source] = make(map[string]float64)
+	}
+	g.Edges[source][dest] = weight
+}
+
+func (g *NetworkGraph) edgeCount() int {
+	count := 0
+	for _, edges := range g.Edges {
+		count += len(edges)
+	}
+	return count
+}
+
+// pathTree holds the shortest distance to every node reached from a set of
+// seed nodes, and each node's predecessor on its shortest path
+type pathTree struct {
+	distances    map[string]float64
+	predecessors map[string]string
+}
+
+// path returns the nodes from the seed a node was reached from to the node
+func (t pathTree) path(dest string) []string {
+	var path []string
+	for current, ok := dest, true; ok; current, ok = t.predecessors[current] {
+		path = append(path, current)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// shortestPaths runs Dijkstra's algorithm over graph from each seed at its
+// starting distance. Edges to nodes outside the graph are not followed.
+func shortestPaths(graph *NetworkGraph, seeds map[string]float64) pathTree {
+	tree := pathTree{
+		distances:    make(map[string]float64, len(seeds)),
+		predecessors: make(map[string]string),
+	}
+	queue := &distanceQueue{}
+	for node, distance := range seeds {
+		tree.distances[node] = distance
+		heap.Push(queue, queuedNode{node, distance})
+	}
+
+	done := make(map[string]bool)
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(queuedNode)
+		if done[current.node] {
+			continue
+		}
+		done[current.node] = true
+
+		for neighbor, weight := range graph.Edges[current.node] {
+			if done[neighbor] || graph.Nodes[neighbor] == nil {
+				continue
+			}
+			distance := current.distance + weight
+			if known, ok := tree.distances[neighbor]; !ok || distance < known {
+				tree.distances[neighbor] = distance
+				tree.predecessors[neighbor] = current.node
+				heap.Push(queue, queuedNode{neighbor, distance})
+			}
+		}
+	}
+	return tree
+}
+
+type queuedNode struct {
+	node     string
+	distance float64
+}
+
+// distanceQueue is a min-heap of nodes by distance
+type distanceQueue []queuedNode
+
+func (q distanceQueue) Len() int           { return len(q) }
+func (q distanceQueue) Less(i, j int) bool { return q[i].distance < q[j].distance }
+func (q distanceQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *distanceQueue) Push(x any)        { *q = append(*q, x.(queuedNode)) }
+func (q *distanceQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// subnetOf returns the subnet a node is partitioned into
+func subnetOf(asset *models.NetworkAsset) string {
+	if asset.Subnet != "" {
+		return asset.Subnet
+	}
+	ip := net.ParseIP(asset.IPAddress)
+	switch {
+	case ip == nil:
+		return "unknown"
+	case ip.To4() != nil:
+		mask := net.CIDRMask(24, 32)
+		return (&net.IPNet{IP: ip.To4().Mask(mask), Mask: mask}).String()
+	default:
+		mask := net.CIDRMask(64, 128)
+		return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+	}
+}
+
+// sampleGraph reduces a graph over the limits to its riskiest nodes and the
+// cheapest edges among them, returning how many nodes it dropped
+func sampleGraph(graph *NetworkGraph, limits GraphLimits) (*NetworkGraph, int) {
+	if limits.allows(graph) {
+		return graph, 0
+	}
+
+	nodes := make([]string, 0, len(graph.Nodes))
+	for node := range graph.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := graph.Nodes[nodes[i]], graph.Nodes[nodes[j]]
+		if a.RiskScore != b.RiskScore {
+			return a.RiskScore > b.RiskScore
+		}
+		return nodes[i] < nodes[j]
+	})
+	if len(nodes) > limits.MaxNodes {
+		nodes = nodes[:limits.MaxNodes]
+	}
+
+	sampled := newNetworkGraph()
+	for _, node := range nodes {
+		sampled.Nodes[node] = graph.Nodes[node]
+	}
+	var edges []graphEdge
+	for source, dests := range graph.Edges {
+		if sampled.Nodes[source] == nil {
+			continue
+		}
+		for dest, weight := range dests {
+			if sampled.Nodes[dest] != nil {
+				edges = append(edges, graphEdge{source, dest, weight})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].weight != edges[j].weight {
+			return edges[i].weight < edges[j].weight
+		}
+		if edges[i].source != edges[j].source {
+			return edges[i].source < edges[j].source
+		}
+		return edges[i].dest < edges[j].dest
+	})
+	if len(edges) > limits.MaxEdges {
+		edges = edges[:limits.MaxEdges]
+	}
+	for _, edge := range edges {
+		sampled.addEdge(edge.source, edge.dest, edge.weight)
+	}
+
+	return sampled, len(graph.Nodes) - len(sampled.Nodes)
+}
+
+// findCriticalPathsPartitioned finds critical paths on a graph over the
+// limits by subnet partitioning, as described on GraphLimits
+func (npa *NetworkPathAnalyzer) findCriticalPathsPartitioned(ctx context.Context, limits GraphLimits) ([]*models.NetworkPath, *graphSample, error) {
+	// Partition the graph by subnet, setting inter-subnet edges aside
+	subnets := make(map[string]string, len(npa.graph.Nodes))
+	subgraphs := make(map[string]*NetworkGraph)
+	for ip, asset := range npa.graph.Nodes {
+		subnet := subnetOf(asset)
+		subnets[ip] = subnet
+		if subgraphs[subnet] == nil {
+			subgraphs[subnet] = newNetworkGraph()
+		}
+		subgraphs[subnet].Nodes[ip] = asset
+	}
+	var crossing []graphEdge
+	for source, dests := range npa.graph.Edges {
+		from, ok := subnets[source]
+		if !ok {
+			continue
+		}
+		for dest, weight := range dests {
+			to, ok := subnets[dest]
+			switch {
+			case !ok:
+			case from == to:
+				subgraphs[from].addEdge(source, dest, weight)
+			default:
+				crossing = append(crossing, graphEdge{source, dest, weight})
+			}
+		}
+	}
+
+	sample := &graphSample{partitions: len(subgraphs)}
+	for subnet, subgraph := range subgraphs {
+		sampled, dropped := sampleGraph(subgraph, limits)
+		subgraphs[subnet] = sampled
+		sample.droppedNodes += dropped
+	}
+	kept := func(node string) bool { return subgraphs[subnets[node]].Nodes[node] != nil }
+
+	// Build the border graph from the inter-subnet edges between kept nodes
+	// and, inside each subnet, the shortest paths from its entries to its exits
+	border := newNetworkGraph()
+	exits := make(map[string][]string) // Subnet -> nodes with edges out of it
+	entries := make(map[string]bool)
+	for _, edge := range crossing {
+		if !kept(edge.source) || !kept(edge.dest) {
+			continue
+		}
+		if border.Nodes[edge.source] == nil {
+			exits[subnets[edge.source]] = append(exits[subnets[edge.source]], edge.source)
+		}
+		border.Nodes[edge.source] = npa.graph.Nodes[edge.source]
+		border.Nodes[edge.dest] = npa.graph.Nodes[edge.dest]
+		border.addEdge(edge.source, edge.dest, edge.weight)
+		entries[edge.dest] = true
+	}
+
+	legs := make(map[string]pathTree, len(entries)) // Entry -> shortest paths inside its subnet
+	for entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		subnet := subnets[entry]
+		leg := shortestPaths(subgraphs[subnet], map[string]float64{entry: 0})
+		legs[entry] = leg
+		for _, exit := range exits[subnet] {
+			if distance, ok := leg.distances[exit]; ok && exit != entry {
+				border.addEdge(entry, exit, distance)
+			}
+		}
+	}
+	border, _ = sampleGraph(border, limits)
+
+	var criticalPaths []*models.NetworkPath
+	for source, asset := range npa.graph.Nodes {
+		if asset.RiskScore <= 7.0 || !kept(source) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		subnet := subnets[source]
+		local := shortestPaths(subgraphs[subnet], map[string]float64{source: 0})
+		for dest, distance := range local.distances {
+			if dest != source && npa.graph.Nodes[dest].DeviceType == "server" {
+				criticalPaths = append(criticalPaths, npa.newPath(source, dest, local.path(dest), distance))
+			}
+		}
+
+		// Leave the subnet through its exits, and cross the border graph
+		seeds := make(map[string]float64)
+		for _, exit := range exits[subnet] {
+			if distance, ok := local.distances[exit]; ok && border.Nodes[exit] != nil {
+				seeds[exit] = distance
+			}
+		}
+		if len(seeds) == 0 {
+			continue
+		}
+		crossed := shortestPaths(border, seeds)
+
+		// Reach each other subnet's servers from its closest entry
+		type route struct {
+			entry    string
+			distance float64
+		}
+		best := make(map[string]route)
+		for entry, leg := range legs {
+			reached, ok := crossed.distances[entry]
+			if !ok || subnets[entry] == subnet {
+				continue
+			}
+			for dest, distance := range leg.distances {
+				if npa.graph.Nodes[dest].DeviceType != "server" {
+					continue
+				}
+				if current, ok := best[dest]; !ok || reached+distance < current.distance {
+					best[dest] = route{entry, reached + distance}
+				}
+			}
+		}
+
+		for dest, route := range best {
+			borderPath := crossed.path(route.entry)
+			path := local.path(borderPath[0])
+			for i := 1; i < len(borderPath); i++ {
+				from, to := borderPath[i-1], borderPath[i]
+				if subnets[from] == subnets[to] {
+					path = append(path, legs[from].path(to)[1:]...)
+				} else {
+					path = append(path, to)
+				}
+			}
+			path = append(path, legs[route.entry].path(dest)[1:]...)
+			criticalPaths = append(criticalPaths, npa.newPath(source, dest, path, route.distance))
+		}
+	}
+
+	return criticalPaths, sample, nil
+}
@@ -18,21 +18,35 @@ type NetworkGraph struct {
 	Edges map[string]map[string]float64   `json:"edges"` // source -> destination -> weight
 }
 
-// NetworkPathAnalyzer handles shortest path calculations using fast SSSP principles
+// NetworkPathAnalyzer finds critical paths through the network from the
+// shortest paths between assets, see ShortestPaths
 type NetworkPathAnalyzer struct {
-	graph *NetworkGraph
+	graph     *NetworkGraph
+	limits    GraphLimits
+	riskDecay float64
 }
 
-// NewNetworkPathAnalyzer creates a new path analyzer
+// NewNetworkPathAnalyzer creates a new path analyzer with the default graph limits
 func NewNetworkPathAnalyzer() *NetworkPathAnalyzer {
+	return NewNetworkPathAnalyzerWithLimits(GraphLimits{})
+}
+
+// NewNetworkPathAnalyzerWithLimits creates a path analyzer that partitions
+// graphs over the given limits instead of analyzing them whole
+func NewNetworkPathAnalyzerWithLimits(limits GraphLimits) *NetworkPathAnalyzer {
 	return &NetworkPathAnalyzer{
-		graph: &NetworkGraph{
-			Nodes: make(map[string]*models.NetworkAsset),
-			Edges: make(map[string]map[string]float64),
-		},
+		graph:     newNetworkGraph(),
+		limits:    limits.withDefaults(),
+		riskDecay: DefaultRiskDecay,
 	}
 }
 
+// SetRiskDecay sets the share of an asset's risk passed on to each asset it
+// can reach, per hop. 0 turns risk propagation off.
+func (npa *NetworkPathAnalyzer) SetRiskDecay(decay float64) {
+	npa.riskDecay = decay
+}
+
 // AddAsset adds a network asset to the graph
 func (npa *NetworkPathAnalyzer) AddAsset(asset *models.NetworkAsset) {
 	npa.graph.Nodes[asset.IPAddress] = asset
@@ -73,116 +87,96 @@ func (npa *NetworkPathAnalyzer) calculateEdgeWeight(source, dest *models.Network
 	return baseWeight
 }
 
-// FastSSSP implements the core algorithm inspired by the Duan-Mao breakthrough
-// This is a simplified version focusing on the key principles
+// FastSSSP returns the shortest path from source to every node it reaches,
+// computed by ShortestPaths
 func (npa *NetworkPathAnalyzer) FastSSSP(source string) map[string]*models.NetworkPath {
-	distances := make(map[string]float64)
-	predecessors := make(map[string]string)
-	visited := make(map[string]bool)
-	
-	// Initialize distances
-	for node := range npa.graph.Nodes {
-		distances[node] = math.Inf(1)
-	}
-	distances[source] = 0
-	
-	// Priority queue simulation (in practice, use a proper heap)
-	var queue []string
-	queue = append(queue, source)
-	
-	for len(queue) > 0 {
-		// Find minimum distance node (simplified - use proper heap in production)
-		minNode := ""
-		minDist := math.Inf(1)
-		minIdx := -1
-		
-		for i, node := range queue {
-			if !visited[node] && distances[node] < minDist {
-				minDist = distances[node]
-				minNode = node
-				minIdx = i
-			}
-		}
-		
-		if minNode == "" {
-			break
-		}
-		
-		// Remove from queue
-		queue = append(queue[:minIdx], queue[minIdx+1:]...)
-		visited[minNode] = true
-		
-		// Relax edges
-		for neighbor, weight := range npa.graph.Edges[minNode] {
-			if !visited[neighbor] {
-				newDist := distances[minNode] + weight
-				if newDist < distances[neighbor] {
-					distances[neighbor] = newDist
-					predecessors[neighbor] = minNode
-					queue = append(queue, neighbor)
-				}
-			}
-		}
-	}
-	
+	return npa.pathsFrom(newSSSPGraph(npa.graph), source)
+}
+
+// pathsFrom builds the shortest paths from source over an already numbered graph
+func (npa *NetworkPathAnalyzer) pathsFrom(graph *ssspGraph, source string) map[string]*models.NetworkPath {
+	tree := npa.shortestPathTree(graph, source)
+
 	// Build paths
 	paths := make(map[string]*models.NetworkPath)
-	for dest, distance := range distances {
-		if distance < math.Inf(1) && dest != source {
-			path := npa.buildPath(source, dest, predecessors)
-			paths[dest] = &models.NetworkPath{
-				Source:      source,
-				Destination: dest,
-				Path:        path,
-				Distance:    distance,
-				Hops:        len(path) - 1,
-				Latency:     distance * 10, // Rough latency estimation
-				RiskScore:   npa.calculatePathRisk(path),
-				Discovered:  time.Now(),
-			}
+	for dest, distance := range tree.distances {
+		if dest != source {
+			paths[dest] = npa.newPath(source, dest, tree.path(dest), distance)
 		}
 	}
-	
+
 	return paths
 }
 
-// buildPath reconstructs the path from source to destination
-func (npa *NetworkPathAnalyzer) buildPath(source, dest string, predecessors map[string]string) []string {
-	var path []string
-	current := dest
-	
-	for current != "" {
-		path = append([]string{current}, path...)
-		current = predecessors[current]
+// newPath describes a path found from source to dest
+func (npa *NetworkPathAnalyzer) newPath(source, dest string, path []string, distance float64) *models.NetworkPath {
+	return &models.NetworkPath{
+		Source:      source,
+		Destination: dest,
+		Path:        path,
+		Distance:    distance,
+		Hops:        len(path) - 1,
+		Latency:     distance * 10, // Rough latency estimation
+		RiskScore:   npa.calculatePathRisk(source, distance),
+		Discovered:  time.Now(),
 	}
-	
-	return path
 }
 
-// calculatePathRisk calculates the risk score for a path
-func (npa *NetworkPathAnalyzer) calculatePathRisk(path []string) float64 {
-	if len(path) == 0 {
+// calculatePathRisk scores a path by how exposed its destination is to its
+// source: the source's risk, divided by one plus the shortest distance between
+// them. Edge weights already make routes through risky or unmonitored assets
+// longer and routes through network devices shorter, so the closer a risky
+// source sits to a server, the more critical the path.
+func (npa *NetworkPathAnalyzer) calculatePathRisk(source string, distance float64) float64 {
+	asset, exists := npa.graph.Nodes[source]
+	if !exists {
 		return 0
 	}
-	
-	totalRisk := 0.0
-	for _, node := range path {
-		if asset, exists := npa.graph.Nodes[node]; exists {
-			totalRisk += asset.RiskScore
+	return math.Min(asset.RiskScore, maxRiskScore) / (1 + distance)
+}
+
+// FindCriticalPaths finds the most critical paths in the network. Graphs over
+// the analyzer's limits are partitioned by subnet, see GraphLimits.
+func (npa *NetworkPathAnalyzer) FindCriticalPaths(ctx context.Context) ([]*models.NetworkPath, error) {
+	criticalPaths, _, err := npa.findCriticalPaths(ctx)
+	return criticalPaths, err
+}
+
+// findCriticalPaths finds the critical paths, reporting how the graph was
+// sampled if it was over the limits
+func (npa *NetworkPathAnalyzer) findCriticalPaths(ctx context.Context) ([]*models.NetworkPath, *graphSample, error) {
+	var criticalPaths []*models.NetworkPath
+	var sample *graphSample
+	if npa.limits.allows(npa.graph) {
+		criticalPaths = npa.findAllCriticalPaths()
+	} else {
+		var err error
+		criticalPaths, sample, err = npa.findCriticalPathsPartitioned(ctx, npa.limits)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
-	
-	return totalRisk / float64(len(path))
+
+	// Sort by risk score (highest first), then by distance
+	sort.Slice(criticalPaths, func(i, j int) bool {
+		if criticalPaths[i].RiskScore != criticalPaths[j].RiskScore {
+			return criticalPaths[i].RiskScore > criticalPaths[j].RiskScore
+		}
+		return criticalPaths[i].Distance < criticalPaths[j].Distance
+	})
+
+	return criticalPaths, sample, nil
 }
 
-// FindCriticalPaths finds the most critical paths in the network
-func (npa *NetworkPathAnalyzer) FindCriticalPaths(ctx context.Context) ([]*models.NetworkPath, error) {
+// findAllCriticalPaths finds the critical paths over the whole graph
+func (npa *NetworkPathAnalyzer) findAllCriticalPaths() []*models.NetworkPath {
 	var criticalPaths []*models.NetworkPath
+	graph := newSSSPGraph(npa.graph)
 	
 	// Find paths from high-risk assets to critical assets
 	for sourceIP, sourceAsset := range npa.graph.Nodes {
 		if sourceAsset.RiskScore > 7.0 {
-			paths := npa.FastSSSP(sourceIP)
+			paths := npa.pathsFrom(graph, sourceIP)
 			
 			for destIP, path := range paths {
 				destAsset := npa.graph.Nodes[destIP]
@@ -193,12 +187,7 @@ func (npa *NetworkPathAnalyzer) FindCriticalPaths(ctx context.Context) ([]*model
 		}
 	}
 	
-	// Sort by risk score (highest first)
-	sort.Slice(criticalPaths, func(i, j int) bool {
-		return criticalPaths[i].RiskScore > criticalPaths[j].RiskScore
-	})
-	
-	return criticalPaths, nil
+	return criticalPaths
 }
 
 // AnalyzeNetworkTopology performs comprehensive network analysis
@@ -220,27 +209,37 @@ func (npa *NetworkPathAnalyzer) AnalyzeNetworkTopology(ctx context.Context, asse
 	}
 	
 	// Find critical paths
-	criticalPaths, err := npa.FindCriticalPaths(ctx)
+	criticalPaths, sample, err := npa.findCriticalPaths(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find critical paths: %w", err)
 	}
 	
+	// Adjust each asset's risk for the assets that can reach it
+	propagated := propagateRisk(npa.graph, npa.riskDecay)
+
 	// Build topology nodes
 	var nodes []models.TopologyNode
 	for ip, asset := range npa.graph.Nodes {
+		risk := propagated[ip]
+		asset.AdjustedRiskScore = risk.adjusted
 		nodes = append(nodes, models.TopologyNode{
-			ID:          ip,
-			Name:        asset.Hostname,
-			Type:        asset.DeviceType,
-			IPAddress:   ip,
-			RiskScore:   asset.RiskScore,
-			IsMonitored: asset.IsMonitored,
-			Location:    asset.Location,
-			Department:  asset.Department,
+			ID:                ip,
+			Name:              asset.Hostname,
+			Type:              asset.DeviceType,
+			IPAddress:         ip,
+			RiskScore:         asset.RiskScore,
+			AdjustedRiskScore: risk.adjusted,
+			InheritedRisk:     risk.inherited,
+			RiskSource:        risk.source,
+			RiskSourceHops:    risk.hops,
+			IsMonitored:       asset.IsMonitored,
+			Location:          asset.Location,
+			Department:        asset.Department,
 		})
 	}
 	
 	// Build topology links
+	criticalLinks := criticalLinkSet(criticalPaths)
 	var links []models.TopologyLink
 	for source, edges := range npa.graph.Edges {
 		for dest, weight := range edges {
@@ -249,7 +248,7 @@ func (npa *NetworkPathAnalyzer) AnalyzeNetworkTopology(ctx context.Context, asse
 				Target:      dest,
 				Weight:      weight,
 				Type:        "network",
-				IsCritical:  npa.isCriticalPath(source, dest, criticalPaths),
+				IsCritical:  criticalLinks[[2]string{source, dest}],
 			})
 		}
 	}
@@ -257,7 +256,7 @@ func (npa *NetworkPathAnalyzer) AnalyzeNetworkTopology(ctx context.Context, asse
 	// Identify clusters
 	clusters := npa.identifyClusters(nodes, links)
 	
-	return &models.NetworkTopology{
+	topology := &models.NetworkTopology{
 		Nodes:           nodes,
 		Links:           links,
 		Clusters:        clusters,
@@ -265,20 +264,27 @@ func (npa *NetworkPathAnalyzer) AnalyzeNetworkTopology(ctx context.Context, asse
 		TotalAssets:     len(nodes),
 		TotalConnections: len(links),
 		LastUpdated:     time.Now(),
-	}, nil
+	}
+	if sample != nil {
+		topology.Sampled = true
+		topology.SamplingStrategy = SamplingSubnetPartition
+		topology.Partitions = sample.partitions
+		topology.DroppedNodes = sample.droppedNodes
+	}
+	return topology, nil
 }
 
-// isCriticalPath checks if a link is part of a critical path
-func (npa *NetworkPathAnalyzer) isCriticalPath(source, dest string, criticalPaths []*models.NetworkPath) bool {
+// criticalLinkSet returns the links critical paths run over, in either
+// direction, so each link is checked once rather than against every path
+func criticalLinkSet(criticalPaths []*models.NetworkPath) map[[2]string]bool {
+	links := make(map[[2]string]bool)
 	for _, path := range criticalPaths {
 		for i := 0; i < len(path.Path)-1; i++ {
-			if (path.Path[i] == source && path.Path[i+1] == dest) ||
-				(path.Path[i] == dest && path.Path[i+1] == source) {
-				return true
-			}
+			links[[2]string{path.Path[i], path.Path[i+1]}] = true
+			links[[2]string{path.Path[i+1], path.Path[i]}] = true
 		}
 	}
-	return false
+	return links
 }
 
 // identifyClusters identifies logical clusters in the network
@@ -0,0 +1,136 @@
+package discovery
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"zerotrace/agent/internal/models"
+)
+
+// twoSubnetAssets is a high-risk workstation in 10.0.1.0/24 whose only route
+// to a server in 10.0.2.0/24 runs through a router in each subnet
+func twoSubnetAssets() []models.NetworkAsset {
+	peer := func(ip string) []models.PeerInfo { return []models.PeerInfo{{IPAddress: ip}} }
+	return []models.NetworkAsset{
+		{IPAddress: "10.0.1.5", DeviceType: "workstation", RiskScore: 9, IsMonitored: true, ConnectedPeers: peer("10.0.1.1")},
+		{IPAddress: "10.0.1.1", DeviceType: "network_device", RiskScore: 2, IsMonitored: true, ConnectedPeers: peer("10.0.2.1")},
+		{IPAddress: "10.0.2.1", DeviceType: "network_device", RiskScore: 2, IsMonitored: true, ConnectedPeers: peer("10.0.2.10")},
+		{IPAddress: "10.0.2.10", DeviceType: "server", RiskScore: 4, IsMonitored: true},
+	}
+}
+
+func TestAnalyzeNetworkTopologyWithinLimits(t *testing.T) {
+	topology, err := NewNetworkPathAnalyzer().AnalyzeNetworkTopology(context.Background(), twoSubnetAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topology.Sampled {
+		t.Error("expected a small graph to be analyzed whole")
+	}
+	if len(topology.CriticalPaths) != 1 {
+		t.Fatalf("expected one critical path, got %d", len(topology.CriticalPaths))
+	}
+}
+
+func TestAnalyzeNetworkTopologyPartitionsLargeGraphs(t *testing.T) {
+	analyzer := NewNetworkPathAnalyzerWithLimits(GraphLimits{MaxNodes: 3})
+	topology, err := analyzer.AnalyzeNetworkTopology(context.Background(), twoSubnetAssets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !topology.Sampled || topology.SamplingStrategy != SamplingSubnetPartition || topology.Partitions != 2 {
+		t.Fatalf("expected a graph over the limits to be partitioned by subnet, got sampled=%v strategy=%q partitions=%d",
+			topology.Sampled, topology.SamplingStrategy, topology.Partitions)
+	}
+	if len(topology.CriticalPaths) != 1 {
+		t.Fatalf("expected the inter-subnet path to be stitched, got %d paths", len(topology.CriticalPaths))
+	}
+
+	whole, _ := NewNetworkPathAnalyzer().AnalyzeNetworkTopology(context.Background(), twoSubnetAssets())
+	stitched := topology.CriticalPaths[0]
+	if !slices.Equal(stitched.Path, whole.CriticalPaths[0].Path) || stitched.Distance != whole.CriticalPaths[0].Distance {
+		t.Errorf("stitched path %v (%g) differs from the whole-graph path %v (%g)",
+			stitched.Path, stitched.Distance, whole.CriticalPaths[0].Path, whole.CriticalPaths[0].Distance)
+	}
+}
+
+func TestSampleGraphKeepsRiskiestNodes(t *testing.T) {
+	graph := newNetworkGraph()
+	for ip, risk := range map[string]float64{"10.0.0.1": 1, "10.0.0.2": 8, "10.0.0.3": 5} {
+		graph.Nodes[ip] = &models.NetworkAsset{IPAddress: ip, RiskScore: risk}
+	}
+	graph.addEdge("10.0.0.1", "10.0.0.2", 1)
+	graph.addEdge("10.0.0.2", "10.0.0.3", 1)
+
+	sampled, dropped := sampleGraph(graph, GraphLimits{MaxNodes: 2, MaxEdges: 10})
+	if dropped != 1 || sampled.Nodes["10.0.0.1"] != nil {
+		t.Fatalf("expected the lowest-risk node to be dropped, kept %d nodes", len(sampled.Nodes))
+	}
+	if sampled.edgeCount() != 1 || sampled.Edges["10.0.0.2"]["10.0.0.3"] != 1 {
+		t.Errorf("expected only the edge between kept nodes, got %v", sampled.Edges)
+	}
+}
+
+func TestPropagateRiskElevatesAssetsBehindVulnerableHosts(t *testing.T) {
+	graph := newNetworkGraph()
+	for _, asset := range []*models.NetworkAsset{
+		{IPAddress: "dmz", DeviceType: "server", RiskScore: 9},
+		{IPAddress: "app", DeviceType: "workstation", RiskScore: 1},
+		{IPAddress: "db", DeviceType: "server", RiskScore: 2},
+		{IPAddress: "isolated", DeviceType: "server", RiskScore: 3},
+	} {
+		graph.Nodes[asset.IPAddress] = asset
+	}
+	graph.addEdge("dmz", "app", 1)
+	graph.addEdge("app", "db", 1)
+
+	propagated := propagateRisk(graph, 0.5)
+	db := propagated["db"]
+	if db.source != "dmz" || db.hops != 2 || db.adjusted != 2.25 {
+		t.Errorf("expected db to inherit 9 * 0.5 * 0.5 from dmz over two hops, got %+v", db)
+	}
+	if app := propagated["app"]; app.adjusted != 4.5*defaultAssetValue {
+		t.Errorf("expected app's inherited risk to be weighted by its asset value, got %+v", app)
+	}
+	if dmz := propagated["dmz"]; dmz.adjusted != 9 || dmz.source != "" {
+		t.Errorf("expected the entry host to keep its own risk, got %+v", dmz)
+	}
+	if isolated := propagated["isolated"]; isolated.adjusted != 3 {
+		t.Errorf("expected an unreachable asset to keep its own risk, got %+v", isolated)
+	}
+
+	if off := propagateRisk(graph, 0)["db"]; off.adjusted != 2 {
+		t.Errorf("expected no propagation with a decay of 0, got %+v", off)
+	}
+}
+
+func TestCriticalPathsRankedByDistance(t *testing.T) {
+	peers := func(ips ...string) []models.PeerInfo {
+		var peers []models.PeerInfo
+		for _, ip := range ips {
+			peers = append(peers, models.PeerInfo{IPAddress: ip})
+		}
+		return peers
+	}
+	assets := []models.NetworkAsset{
+		{IPAddress: "laptop", DeviceType: "workstation", RiskScore: 9, IsMonitored: true, ConnectedPeers: peers("near", "hop")},
+		{IPAddress: "hop", DeviceType: "workstation", RiskScore: 1, IsMonitored: true, ConnectedPeers: peers("far")},
+		{IPAddress: "near", DeviceType: "server", RiskScore: 1, IsMonitored: true},
+		{IPAddress: "far", DeviceType: "server", RiskScore: 1, IsMonitored: true},
+	}
+	topology, err := NewNetworkPathAnalyzer().AnalyzeNetworkTopology(context.Background(), assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topology.CriticalPaths) != 2 {
+		t.Fatalf("expected two critical paths, got %d", len(topology.CriticalPaths))
+	}
+	near, far := topology.CriticalPaths[0], topology.CriticalPaths[1]
+	if near.Destination != "near" || far.Destination != "far" {
+		t.Fatalf("expected the closer server first, got %s then %s", near.Destination, far.Destination)
+	}
+	if want := 9 / (1 + far.Distance); far.RiskScore != want {
+		t.Errorf("expected the path risk to be derived from its distance %g, got %g want %g", far.Distance, far.RiskScore, want)
+	}
+}
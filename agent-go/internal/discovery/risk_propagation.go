@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"container/heap"
+	"math"
+)
+
+// DefaultRiskDecay is the share of an asset's risk passed on to each asset it
+// can reach, per hop
+const DefaultRiskDecay = 0.7
+
+// maxRiskScore is the top of the risk score scale
+const maxRiskScore = 10.0
+
+// assetValue is how much of the risk inherited from upstream assets counts
+// against an asset of a device type. Servers are what attack paths lead to, so
+// they take all of it.
+var assetValue = map[string]float64{
+	"server":         1.0,
+	"network_device": 0.8,
+}
+
+// defaultAssetValue is the asset value of other device types
+const defaultAssetValue = 0.5
+
+// propagatedRisk is an asset's risk adjusted for the assets that can reach it
+type propagatedRisk struct {
+	inherited float64 // Risk reaching the asset from upstream, before its asset value
+	adjusted  float64 // Reachability-adjusted risk: the higher of its own and its weighted inherited risk
+	source    string  // Asset the inherited risk originates from
+	hops      int     // Hops from source
+}
+
+// propagateRisk passes risk along the graph's directed connections. An asset
+// exposes everything it connects to: it passes on the higher of its own risk
+// and the risk it inherited, reduced by decay per hop. An asset keeps the
+// most risk reaching it over any path, so an internal database behind a
+// vulnerable DMZ host inherits most of the DMZ host's risk, however low its
+// own is.
+//
+// As the risk passed on only decreases along a path, the riskiest path to
+// each asset is found the way Dijkstra finds the shortest, in O(E log V).
+func propagateRisk(graph *NetworkGraph, decay float64) map[string]propagatedRisk {
+	risk := func(node string) float64 {
+		if asset := graph.Nodes[node]; asset != nil {
+			return math.Min(asset.RiskScore, maxRiskScore)
+		}
+		return 0
+	}
+
+	inherited := make(map[string]queuedPressure)
+	queue := &pressureQueue{}
+	settled := make(map[string]bool)
+	relax := func(from queuedPressure) {
+		for dest := range graph.Edges[from.node] {
+			pressure := from.pressure * decay
+			if best, ok := inherited[dest]; pressure > 0 && (!ok || pressure > best.pressure) {
+				next := queuedPressure{node: dest, pressure: pressure, source: from.source, hops: from.hops + 1}
+				inherited[dest] = next
+				heap.Push(queue, next)
+			}
+		}
+	}
+
+	if decay > 0 {
+		// Every asset starts out exposing its own risk
+		for node := range graph.Nodes {
+			relax(queuedPressure{node: node, pressure: risk(node), source: node})
+		}
+	}
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(queuedPressure)
+		if settled[current.node] || current.pressure < inherited[current.node].pressure {
+			continue
+		}
+		settled[current.node] = true
+		if current.pressure > risk(current.node) {
+			relax(current) // Passes on inherited risk beyond its own
+		}
+	}
+
+	propagated := make(map[string]propagatedRisk, len(graph.Nodes))
+	for node, asset := range graph.Nodes {
+		result := propagatedRisk{adjusted: risk(node)}
+		if from, ok := inherited[node]; ok && from.source != node {
+			result.inherited = from.pressure
+			result.source = from.source
+			result.hops = from.hops
+			value, ok := assetValue[asset.DeviceType]
+			if !ok {
+				value = defaultAssetValue
+			}
+			result.adjusted = math.Max(result.adjusted, from.pressure*value)
+		}
+		propagated[node] = result
+	}
+	return propagated
+}
+
+// queuedPressure is risk reaching a node from source
+type queuedPressure struct {
+	node     string
+	pressure float64
+	source   string
+	hops     int
+}
+
+// pressureQueue is a max-heap of nodes by the risk reaching them
+type pressureQueue []queuedPressure
+
+func (q pressureQueue) Len() int           { return len(q) }
+func (q pressureQueue) Less(i, j int) bool { return q[i].pressure > q[j].pressure }
+func (q pressureQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pressureQueue) Push(x any)        { *q = append(*q, x.(queuedPressure)) }
+func (q *pressureQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,471 @@
+package discovery
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+	"sort"
+)
+
+// fastSSSPMinNodes is the graph size from which ShortestPaths uses bounded
+// multi-source shortest paths (BMSSP) instead of Dijkstra's algorithm.
+//
+// BMSSP beats Dijkstra asymptotically, O(m log^(2/3) n) against
+// O(m + n log n), but its recursion does more work per edge.
+// BenchmarkShortestPaths on random graphs with an average out-degree of 5
+// measured Dijkstra faster at every size it ran:
+//
+//	nodes      dijkstra   bmssp     ratio
+//	1,000      0.34ms     0.83ms    2.5x
+//	10,000     5.2ms      9.8ms     1.9x
+//	100,000    90ms       142ms     1.6x
+//	1,000,000  1.47s      2.38s     1.6x
+//
+// The gap narrows up to 100k nodes, then stops narrowing as both algorithms
+// become bound by cache misses, so there is no measured crossover. The
+// threshold sits well past the largest graph measured and any network an
+// agent discovers.
+const fastSSSPMinNodes = 1 << 24
+
+// ShortestPaths returns the shortest distance from source to every node it
+// reaches, and each reached node's predecessor on its shortest path. Edges to
+// nodes outside the graph are not followed. Graphs of fastSSSPMinNodes or
+// more run BMSSP, smaller ones Dijkstra's algorithm.
+func (npa *NetworkPathAnalyzer) ShortestPaths(source string) (distances map[string]float64, predecessors map[string]string) {
+	tree := npa.shortestPathTree(newSSSPGraph(npa.graph), source)
+	return tree.distances, tree.predecessors
+}
+
+// DijkstraShortestPaths returns the same as ShortestPaths, always computed
+// with Dijkstra's algorithm, to cross-validate it
+func (npa *NetworkPathAnalyzer) DijkstraShortestPaths(source string) (distances map[string]float64, predecessors map[string]string) {
+	graph := newSSSPGraph(npa.graph)
+	node, ok := graph.index[source]
+	if !ok {
+		return map[string]float64{}, map[string]string{}
+	}
+	tree := graph.tree(dijkstra(graph, node))
+	return tree.distances, tree.predecessors
+}
+
+// shortestPathTree runs the algorithm suited to the graph's size from source
+func (npa *NetworkPathAnalyzer) shortestPathTree(graph *ssspGraph, source string) pathTree {
+	node, ok := graph.index[source]
+	if !ok {
+		return pathTree{distances: map[string]float64{}, predecessors: map[string]string{}}
+	}
+	if len(graph.ids) < fastSSSPMinNodes {
+		return graph.tree(dijkstra(graph, node))
+	}
+	return graph.tree(bmssp(graph, node))
+}
+
+// ssspGraph is a NetworkGraph with its nodes numbered, so the shortest path
+// algorithms work on slices rather than maps
+type ssspGraph struct {
+	ids   []string
+	index map[string]int
+	edges [][]ssspEdge
+}
+
+type ssspEdge struct {
+	to     int
+	weight float64
+}
+
+func newSSSPGraph(g *NetworkGraph) *ssspGraph {
+	graph := &ssspGraph{
+		ids:   make([]string, 0, len(g.Nodes)),
+		index: make(map[string]int, len(g.Nodes)),
+	}
+	for id := range g.Nodes {
+		graph.ids = append(graph.ids, id)
+	}
+	sort.Strings(graph.ids)
+	for i, id := range graph.ids {
+		graph.index[id] = i
+	}
+
+	graph.edges = make([][]ssspEdge, len(graph.ids))
+	for i, id := range graph.ids {
+		for dest, weight := range g.Edges[id] {
+			if to, ok := graph.index[dest]; ok {
+				graph.edges[i] = append(graph.edges[i], ssspEdge{to, weight})
+			}
+		}
+	}
+	return graph
+}
+
+// ssspResult holds the distance to and predecessor of every node, +Inf and -1
+// for nodes not reached
+type ssspResult struct {
+	distances    []float64
+	predecessors []int
+}
+
+func newSSSPResult(n, source int) ssspResult {
+	result := ssspResult{
+		distances:    make([]float64, n),
+		predecessors: make([]int, n),
+	}
+	for i := range result.distances {
+		result.distances[i] = math.Inf(1)
+		result.predecessors[i] = -1
+	}
+	result.distances[source] = 0
+	return result
+}
+
+// tree converts a result back to node IDs
+func (g *ssspGraph) tree(result ssspResult) pathTree {
+	tree := pathTree{
+		distances:    make(map[string]float64),
+		predecessors: make(map[string]string),
+	}
+	for node, distance := range result.distances {
+		if math.IsInf(distance, 1) {
+			continue
+		}
+		tree.distances[g.ids[node]] = distance
+		if pred := result.predecessors[node]; pred >= 0 {
+			tree.predecessors[g.ids[node]] = g.ids[pred]
+		}
+	}
+	return tree
+}
+
+// dijkstra computes shortest paths from source with a binary heap
+func dijkstra(g *ssspGraph, source int) ssspResult {
+	result := newSSSPResult(len(g.ids), source)
+	done := make([]bool, len(g.ids))
+	queue := &nodeHeap{{source, 0}}
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(heapNode)
+		if done[current.node] {
+			continue
+		}
+		done[current.node] = true
+		for _, edge := range g.edges[current.node] {
+			distance := current.distance + edge.weight
+			if !done[edge.to] && distance < result.distances[edge.to] {
+				result.distances[edge.to] = distance
+				result.predecessors[edge.to] = current.node
+				heap.Push(queue, heapNode{edge.to, distance})
+			}
+		}
+	}
+	return result
+}
+
+// bmssp computes shortest paths from source with the bounded multi-source
+// shortest path recursion of Duan, Mao, Mao, Shu and Yin, "Breaking the
+// Sorting Barrier for Directed Single-Source Shortest Paths" (2025).
+//
+// BMSSP(l, B, S) completes every node whose shortest path below the bound B
+// runs through a complete node of S. Rather than keep a frontier of all
+// incomplete nodes sorted, as Dijkstra does, it:
+//
+//  1. Runs k rounds of Bellman-Ford relaxation from S. Nodes settled within k
+//     hops need no more work; of S, only the roots of trees of at least k
+//     nodes, the pivots, lead anywhere further, leaving at most |S|/k of them.
+//  2. Repeatedly pulls the 2^((l-1)t) closest pivots and frontier nodes and
+//     recurses on them one level down with the bound just above them, adding
+//     what the recursion completes and the edges it relaxes back to the
+//     frontier.
+//
+// With k = log^(1/3) n and t = log^(2/3) n, the recursion is log n / t levels
+// deep. Level 0 is Dijkstra from a single node, stopped after k+1 nodes.
+//
+// The paper's frontier is a block linked list that inserts in O(1) amortized
+// and prepends batches cheaply. This uses a binary heap with lazy deletion,
+// which gives up the last log factor the paper shaves off but keeps every
+// step of the recursion as described. The paper also assumes distinct path
+// lengths; relaxing on ties as well (d[u]+w <= d[v]) and pulling equal
+// distances together keeps it correct without them.
+func bmssp(g *ssspGraph, source int) ssspResult {
+	n := len(g.ids)
+	logN := math.Log2(float64(max(n, 2)))
+	s := &bmsspSolver{
+		graph:  g,
+		result: newSSSPResult(n, source),
+		done:   make([]bool, n),
+		inSet:  make([]int, n),
+		k:      max(1, int(math.Cbrt(logN))),
+		t:      max(1, int(math.Pow(logN, 2.0/3.0))),
+	}
+	levels := int(math.Ceil(logN / float64(s.t)))
+	s.run(levels, math.Inf(1), []int{source})
+	return s.result
+}
+
+type bmsspSolver struct {
+	graph  *ssspGraph
+	result ssspResult
+	done   []bool // Nodes whose distance is final
+	inSet  []int  // Scratch membership marks, valid when equal to stamp
+	stamp  int
+	k, t   int
+}
+
+// mark starts a new scratch set
+func (s *bmsspSolver) mark() int {
+	s.stamp++
+	return s.stamp
+}
+
+// relax lowers the distance to edge.to through from, reporting the distance
+// through from and whether it is as short as the best known
+func (s *bmsspSolver) relax(from int, edge ssspEdge) (float64, bool) {
+	distance := s.result.distances[from] + edge.weight
+	if s.done[edge.to] || distance > s.result.distances[edge.to] {
+		return distance, false
+	}
+	s.result.distances[edge.to] = distance
+	s.result.predecessors[edge.to] = from
+	return distance, true
+}
+
+// run is BMSSP(level, bound, frontier). It returns the bound it got to, the
+// bound itself unless it stopped early for having completed enough nodes, and
+// the nodes it completed below it.
+func (s *bmsspSolver) run(level int, bound float64, frontier []int) (float64, []int) {
+	if len(frontier) == 0 {
+		return bound, nil
+	}
+	if level == 0 {
+		return s.baseCase(bound, frontier)
+	}
+
+	pivots, reached := s.findPivots(bound, frontier)
+
+	queue := newPullQueue(1<<((level-1)*s.t), bound)
+	reachedBound := bound
+	for _, pivot := range pivots {
+		queue.insert(pivot, s.result.distances[pivot])
+		reachedBound = math.Min(reachedBound, s.result.distances[pivot])
+	}
+
+	var completed []int
+	limit := s.k << (level * s.t)
+	for len(completed) < limit && queue.Len() > 0 {
+		pullBound, pulled := queue.pull()
+		pulled = slices.DeleteFunc(pulled, func(node int) bool { return s.done[node] })
+		var subCompleted []int
+		reachedBound, subCompleted = s.run(level-1, pullBound, pulled)
+		completed = append(completed, subCompleted...)
+
+		var prepend []heapNode
+		for _, node := range subCompleted {
+			for _, edge := range s.graph.edges[node] {
+				distance, ok := s.relax(node, edge)
+				if !ok {
+					continue
+				}
+				if distance >= pullBound && distance < bound {
+					queue.insert(edge.to, distance)
+				} else if distance >= reachedBound && distance < pullBound {
+					prepend = append(prepend, heapNode{edge.to, distance})
+				}
+			}
+		}
+		for _, node := range pulled {
+			if distance := s.result.distances[node]; !s.done[node] && distance >= reachedBound && distance < pullBound {
+				prepend = append(prepend, heapNode{node, distance})
+			}
+		}
+		queue.batchPrepend(prepend)
+	}
+	reachedBound = math.Min(reachedBound, bound)
+
+	for _, node := range reached {
+		if !s.done[node] && s.result.distances[node] < reachedBound {
+			s.done[node] = true
+			completed = append(completed, node)
+		}
+	}
+	return reachedBound, completed
+}
+
+// findPivots relaxes k rounds from frontier below bound. It returns the nodes
+// reached, and the frontier nodes that root a tree of at least k of them.
+// Everything else reached is complete, or within k hops of a pivot.
+func (s *bmsspSolver) findPivots(bound float64, frontier []int) (pivots, reached []int) {
+	inReached := s.mark()
+	for _, node := range frontier {
+		s.inSet[node] = inReached
+	}
+	reached = append(reached, frontier...)
+
+	layer := frontier
+	for i := 0; i < s.k; i++ {
+		var next []int
+		for _, node := range layer {
+			for _, edge := range s.graph.edges[node] {
+				if distance, ok := s.relax(node, edge); ok && distance < bound {
+					if s.inSet[edge.to] != inReached {
+						s.inSet[edge.to] = inReached
+						reached = append(reached, edge.to)
+					}
+					next = append(next, edge.to)
+				}
+			}
+		}
+		if len(reached) > s.k*len(frontier) {
+			return frontier, reached
+		}
+		layer = next
+	}
+
+	// Count the reached nodes under each frontier node along predecessors
+	roots := make(map[int]int, len(reached))
+	for _, node := range frontier {
+		roots[node] = node
+	}
+	var root func(node int) int
+	root = func(node int) int {
+		if r, ok := roots[node]; ok {
+			return r
+		}
+		roots[node] = -1 // Guards against cycles left by ties
+		pred := s.result.predecessors[node]
+		r := -1
+		if pred >= 0 && s.inSet[pred] == inReached {
+			r = root(pred)
+		}
+		roots[node] = r
+		return r
+	}
+	sizes := make(map[int]int, len(frontier))
+	for _, node := range reached {
+		if r := root(node); r >= 0 {
+			sizes[r]++
+		}
+	}
+	for _, node := range frontier {
+		if sizes[node] >= s.k {
+			pivots = append(pivots, node)
+		}
+	}
+	return pivots, reached
+}
+
+// baseCase runs Dijkstra from frontier, complete nodes at equal distances,
+// below bound. Once it has completed more than k nodes it stops at the next
+// distance, and returns that as the bound it got to.
+func (s *bmsspSolver) baseCase(bound float64, frontier []int) (float64, []int) {
+	visited := s.mark()
+	var completed []int
+	queue := &nodeHeap{}
+	for _, node := range frontier {
+		heap.Push(queue, heapNode{node, s.result.distances[node]})
+	}
+	last := math.Inf(-1)
+	for queue.Len() > 0 {
+		current := (*queue)[0]
+		if s.inSet[current.node] == visited || current.distance > s.result.distances[current.node] {
+			heap.Pop(queue)
+			continue
+		}
+		if len(completed) > s.k && current.distance > last {
+			bound = current.distance
+			break
+		}
+		heap.Pop(queue)
+		s.inSet[current.node] = visited
+		completed = append(completed, current.node)
+		last = current.distance
+		for _, edge := range s.graph.edges[current.node] {
+			if distance, ok := s.relax(current.node, edge); ok && distance < bound && s.inSet[edge.to] != visited {
+				heap.Push(queue, heapNode{edge.to, distance})
+			}
+		}
+	}
+
+	for _, node := range completed {
+		s.done[node] = true
+	}
+	return bound, completed
+}
+
+// pullQueue is the frontier of a BMSSP call: nodes keyed by distance below
+// its bound, each at the lowest distance inserted for it
+type pullQueue struct {
+	size  int
+	bound float64
+	best  map[int]float64
+	nodes nodeHeap
+}
+
+func newPullQueue(size int, bound float64) *pullQueue {
+	return &pullQueue{size: size, bound: bound, best: make(map[int]float64)}
+}
+
+func (q *pullQueue) Len() int { return len(q.best) }
+
+func (q *pullQueue) insert(node int, distance float64) {
+	if best, ok := q.best[node]; ok && best <= distance {
+		return
+	}
+	q.best[node] = distance
+	heap.Push(&q.nodes, heapNode{node, distance})
+}
+
+// batchPrepend inserts nodes closer than any already queued
+func (q *pullQueue) batchPrepend(nodes []heapNode) {
+	for _, node := range nodes {
+		q.insert(node.node, node.distance)
+	}
+}
+
+// pull removes the size closest nodes, and any at the same distance as the
+// last of them. It returns them with a bound above them and no higher than
+// any node left, the queue's own bound once it is empty.
+func (q *pullQueue) pull() (float64, []int) {
+	var pulled []int
+	last := math.Inf(-1)
+	for {
+		q.dropStale()
+		if q.nodes.Len() == 0 {
+			return q.bound, pulled
+		}
+		next := q.nodes[0]
+		if len(pulled) >= q.size && next.distance > last {
+			return next.distance, pulled
+		}
+		heap.Pop(&q.nodes)
+		delete(q.best, next.node)
+		pulled = append(pulled, next.node)
+		last = next.distance
+	}
+}
+
+// dropStale pops entries superseded by a lower distance for their node
+func (q *pullQueue) dropStale() {
+	for q.nodes.Len() > 0 {
+		top := q.nodes[0]
+		if best, ok := q.best[top.node]; ok && best == top.distance {
+			return
+		}
+		heap.Pop(&q.nodes)
+	}
+}
+
+type heapNode struct {
+	node     int
+	distance float64
+}
+
+// nodeHeap is a min-heap of numbered nodes by distance
+type nodeHeap []heapNode
+
+func (h nodeHeap) Len() int           { return len(h) }
+func (h nodeHeap) Less(i, j int) bool { return h[i].distance < h[j].distance }
+func (h nodeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nodeHeap) Push(x any)        { *h = append(*h, x.(heapNode)) }
+func (h *nodeHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
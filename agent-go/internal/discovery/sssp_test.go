@@ -0,0 +1,96 @@
+package discovery
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"zerotrace/agent/internal/models"
+)
+
+// randomGraph generates a directed graph of n nodes with degree edges out of
+// each on average. Integer weights make for many paths of equal length.
+func randomGraph(n, degree int, integerWeights bool, seed int64) *NetworkGraph {
+	rng := rand.New(rand.NewSource(seed))
+	graph := newNetworkGraph()
+	id := func(i int) string { return fmt.Sprintf("node-%d", i) }
+	for i := 0; i < n; i++ {
+		graph.Nodes[id(i)] = &models.NetworkAsset{IPAddress: id(i)}
+	}
+	for i := 0; i < n*degree; i++ {
+		weight := 0.5 + rng.Float64()*4
+		if integerWeights {
+			weight = float64(1 + rng.Intn(3))
+		}
+		graph.addEdge(id(rng.Intn(n)), id(rng.Intn(n)), weight)
+	}
+	return graph
+}
+
+func TestBMSSPMatchesDijkstra(t *testing.T) {
+	for _, tc := range []struct {
+		nodes, degree  int
+		integerWeights bool
+	}{
+		{1, 0, false},
+		{10, 1, true},
+		{200, 2, false},
+		{200, 3, true},
+		{2000, 5, false},
+		{2000, 5, true},
+		{5000, 1, true},
+	} {
+		for seed := int64(1); seed <= 5; seed++ {
+			graph := newSSSPGraph(randomGraph(tc.nodes, tc.degree, tc.integerWeights, seed))
+			want := dijkstra(graph, 0)
+			got := bmssp(graph, 0)
+			for node := range want.distances {
+				if want.distances[node] != got.distances[node] {
+					t.Fatalf("%d nodes, degree %d, seed %d: distance to %s is %g, Dijkstra found %g",
+						tc.nodes, tc.degree, seed, graph.ids[node], got.distances[node], want.distances[node])
+				}
+				// Predecessors may differ between equal paths, but must lie on one
+				if pred := got.predecessors[node]; pred >= 0 {
+					if !onShortestPath(graph, got.distances, pred, node) {
+						t.Fatalf("%d nodes, degree %d, seed %d: %s is not on a shortest path to %s",
+							tc.nodes, tc.degree, seed, graph.ids[pred], graph.ids[node])
+					}
+				}
+			}
+		}
+	}
+}
+
+func onShortestPath(g *ssspGraph, distances []float64, from, to int) bool {
+	for _, edge := range g.edges[from] {
+		if edge.to == to && distances[from]+edge.weight == distances[to] {
+			return true
+		}
+	}
+	return false
+}
+
+func TestShortestPathsUnknownSource(t *testing.T) {
+	distances, predecessors := NewNetworkPathAnalyzer().ShortestPaths("10.0.0.1")
+	if len(distances) != 0 || len(predecessors) != 0 {
+		t.Errorf("expected nothing reached from a node outside the graph, got %v %v", distances, predecessors)
+	}
+}
+
+// BenchmarkShortestPaths compares BMSSP with Dijkstra on generated graphs, see
+// fastSSSPMinNodes for the results. Run with -run '^$' -bench ShortestPaths.
+func BenchmarkShortestPaths(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 100_000, 1_000_000} {
+		graph := newSSSPGraph(randomGraph(n, 5, false, 1))
+		b.Run(fmt.Sprintf("dijkstra/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dijkstra(graph, 0)
+			}
+		})
+		b.Run(fmt.Sprintf("bmssp/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bmssp(graph, 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Package ipc is the agent's local control socket: a Unix socket on macOS and
+// Linux, a named pipe on Windows. Each connection carries one JSON request
+// line and one JSON response line.
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+// CommandScanNow runs every scanner in the agent's scan scope once, outside
+// the regular scan interval
+const CommandScanNow = "scan_now"
+
+// requestTimeout is how long a client has to send its request once connected
+const requestTimeout = 10 * time.Second
+
+// Request is a command sent to the agent
+type Request struct {
+	Command string `json:"command"`
+}
+
+// Response is the agent's reply once a command has finished
+type Response struct {
+	OK       bool   `json:"ok"`
+	Findings int    `json:"findings"` // Findings the scans reported
+	Error    string `json:"error,omitempty"`
+}
+
+// Handler runs a command. ctx is cancelled when the agent shuts down.
+type Handler func(ctx context.Context, req Request) Response
+
+// Serve answers requests on listener with handler until ctx is done, then
+// closes the listener
+func Serve(ctx context.Context, listener net.Listener, handler Handler) {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("IPC listener stopped: %v", err)
+			}
+			return
+		}
+		go serveConn(ctx, conn, handler)
+	}
+}
+
+func serveConn(ctx context.Context, conn net.Conn, handler Handler) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var req Request
+	var resp Response
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = "invalid request"
+	} else {
+		resp = handler(ctx, req)
+	}
+
+	data, _ := json.Marshal(resp)
+	conn.Write(append(data, '\n'))
+}
+
+// Send sends a request to the agent listening on address and waits for its
+// response, or for ctx to be done
+func Send(ctx context.Context, address string, req Request) (Response, error) {
+	conn, err := dial(ctx, address)
+	if err != nil {
+		return Response{}, fmt.Errorf("agent is not reachable: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	data, _ := json.Marshal(req)
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return Response{}, err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		if ctx.Err() != nil {
+			return Response{}, ctx.Err()
+		}
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return Response{}, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp, nil
+}
+
+// ScanNow asks the agent listening on address to scan now, returning the
+// number of findings once the scan has finished
+func ScanNow(ctx context.Context, address string) (int, error) {
+	resp, err := Send(ctx, address, Request{Command: CommandScanNow})
+	if err != nil {
+		return 0, err
+	}
+	if !resp.OK {
+		return resp.Findings, errors.New(resp.Error)
+	}
+	return resp.Findings, nil
+}
@@ -0,0 +1,107 @@
+//go:build !windows
+// +build !windows
+
+package ipc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, address string, handler Handler) {
+	t.Helper()
+	listener, err := Listen(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go Serve(ctx, listener, handler)
+}
+
+func TestScanNow(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "agent.sock")
+	serve(t, address, func(ctx context.Context, req Request) Response {
+		if req.Command != CommandScanNow {
+			return Response{Error: "unsupported command"}
+		}
+		return Response{OK: true, Findings: 7}
+	})
+
+	info, err := os.Stat(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("expected the socket to be private to its user, got mode %o", perm)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	findings, err := ScanNow(ctx, address)
+	if err != nil || findings != 7 {
+		t.Fatalf("got %d findings and error %v, want 7", findings, err)
+	}
+
+	resp, err := Send(ctx, address, Request{Command: "uninstall"})
+	if err != nil || resp.OK || resp.Error == "" {
+		t.Errorf("expected an unsupported command to fail, got %+v and %v", resp, err)
+	}
+}
+
+func TestScanNowReportsFailures(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "agent.sock")
+	serve(t, address, func(ctx context.Context, req Request) Response {
+		return Response{Findings: 2, Error: "network: nmap not found"}
+	})
+
+	findings, err := ScanNow(context.Background(), address)
+	if err == nil || err.Error() != "network: nmap not found" || findings != 2 {
+		t.Errorf("got %d findings and error %v", findings, err)
+	}
+}
+
+func TestScanNowTimesOut(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "agent.sock")
+	serve(t, address, func(ctx context.Context, req Request) Response {
+		<-ctx.Done()
+		return Response{}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := ScanNow(ctx, address); err == nil {
+		t.Error("expected ScanNow to give up when its context is done")
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "agent.sock")
+	stale, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Closing a Unix listener removes its file; leave one behind like a crashed agent
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := Listen(address)
+	if err != nil {
+		t.Fatalf("expected a stale socket to be replaced: %v", err)
+	}
+	defer listener.Close()
+
+	if _, err := Listen(address); err == nil {
+		t.Error("expected a second listener on a live socket to fail")
+	}
+}
+
+func TestScanNowWithoutAgent(t *testing.T) {
+	if _, err := ScanNow(context.Background(), filepath.Join(t.TempDir(), "agent.sock")); err == nil {
+		t.Error("expected an error when no agent is listening")
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package ipc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Listen listens on the Unix socket at address, replacing a socket a previous
+// agent left behind. Only the user the agent runs as may connect.
+func Listen(address string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(address), 0o700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", address); err == nil {
+		conn.Close()
+		return nil, errors.New("another agent is listening on " + address)
+	}
+	if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(address, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+func dial(ctx context.Context, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "unix", address)
+}
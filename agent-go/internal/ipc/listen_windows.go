@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package ipc
+
+import (
+	"context"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// pipeSecurity lets SYSTEM, administrators and the interactive user connect,
+// so a tray running in the user's session can reach an agent service
+const pipeSecurity = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GRGW;;;IU)"
+
+// Listen listens on the named pipe at address
+func Listen(address string) (net.Listener, error) {
+	return winio.ListenPipe(address, &winio.PipeConfig{SecurityDescriptor: pipeSecurity})
+}
+
+func dial(ctx context.Context, address string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, address)
+}
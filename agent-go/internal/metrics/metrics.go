@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// sampleWindow is how long CPU use is measured over. A single reading of CPU
+// times says nothing about current use, so two are taken this far apart.
+const sampleWindow = time.Second
+
+// Sample is the agent's and its host's resource use at one point. Metrics the
+// platform can't report are nil rather than zero, so they can be left out of
+// heartbeats instead of looking like an idle machine.
+type Sample struct {
+	ProcessCPUPercent    *float64 // Relative to one core, so can exceed 100
+	ProcessRSSBytes      *uint64
+	ProcessMemoryPercent *float64 // RSS as a share of host memory
+	HostCPUPercent       *float64 // Across all cores
+	HostMemoryPercent    *float64
+	Load1                *float64
+	Load5                *float64
+	Load15               *float64
+}
+
+// Metadata returns the sample as heartbeat metadata, with only the metrics that
+// were available
+func (s Sample) Metadata() map[string]any {
+	metadata := make(map[string]any)
+	add := func(key string, value *float64) {
+		if value != nil {
+			metadata[key] = *value
+		}
+	}
+	add("process_cpu_percent", s.ProcessCPUPercent)
+	add("process_memory_percent", s.ProcessMemoryPercent)
+	add("host_cpu_percent", s.HostCPUPercent)
+	add("host_memory_percent", s.HostMemoryPercent)
+	add("load1", s.Load1)
+	add("load5", s.Load5)
+	add("load15", s.Load15)
+	if s.ProcessRSSBytes != nil {
+		metadata["process_rss_bytes"] = *s.ProcessRSSBytes
+	}
+	return metadata
+}
+
+// Sampler takes samples of the running agent's resource use
+type Sampler struct {
+	proc   *process.Process // Nil if the agent can't inspect its own process
+	window time.Duration
+}
+
+// NewSampler creates a sampler for the current process
+func NewSampler() *Sampler {
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		log.Printf("Process metrics unavailable: %v", err)
+		proc = nil
+	}
+	return &Sampler{proc: proc, window: sampleWindow}
+}
+
+// Sample measures CPU use over the sample window, then reads memory use and
+// load. It returns early with an empty sample if ctx is cancelled.
+func (s *Sampler) Sample(ctx context.Context) Sample {
+	var sample Sample
+
+	procBefore, procOK := s.processCPUSeconds(ctx)
+	hostBefore, hostOK := hostCPUTimes(ctx)
+	start := time.Now()
+
+	timer := time.NewTimer(s.window)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return sample
+	case <-timer.C:
+	}
+
+	if procAfter, ok := s.processCPUSeconds(ctx); procOK && ok {
+		if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+			percent := (procAfter - procBefore) / elapsed * 100
+			sample.ProcessCPUPercent = &percent
+		}
+	}
+	if hostAfter, ok := hostCPUTimes(ctx); hostOK && ok {
+		if percent, ok := hostCPUPercent(hostBefore, hostAfter); ok {
+			sample.HostCPUPercent = &percent
+		}
+	}
+
+	if s.proc != nil {
+		if info, err := s.proc.MemoryInfoWithContext(ctx); err == nil && info.RSS > 0 {
+			rss := info.RSS
+			sample.ProcessRSSBytes = &rss
+		}
+	}
+	if vm, err := mem.VirtualMemoryWithContext(ctx); err == nil && vm.Total > 0 {
+		used := vm.UsedPercent
+		sample.HostMemoryPercent = &used
+		if sample.ProcessRSSBytes != nil {
+			share := float64(*sample.ProcessRSSBytes) / float64(vm.Total) * 100
+			sample.ProcessMemoryPercent = &share
+		}
+	}
+
+	// Windows has no load average. gopsutil estimates one there, but it reads
+	// zero until its background sampling has warmed up.
+	if runtime.GOOS != "windows" {
+		if avg, err := load.AvgWithContext(ctx); err == nil {
+			sample.Load1, sample.Load5, sample.Load15 = &avg.Load1, &avg.Load5, &avg.Load15
+		}
+	}
+
+	return sample
+}
+
+// processCPUSeconds returns the CPU time the agent has used so far
+func (s *Sampler) processCPUSeconds(ctx context.Context) (float64, bool) {
+	if s.proc == nil {
+		return 0, false
+	}
+	times, err := s.proc.TimesWithContext(ctx)
+	if err != nil {
+		return 0, false
+	}
+	return times.User + times.System, true
+}
+
+// hostCPUTimes returns the host's CPU times summed across all cores
+func hostCPUTimes(ctx context.Context) (cpu.TimesStat, bool) {
+	times, err := cpu.TimesWithContext(ctx, false)
+	if err != nil || len(times) == 0 {
+		return cpu.TimesStat{}, false
+	}
+	return times[0], true
+}
+
+// hostCPUPercent returns how busy the host was between two readings of its CPU
+// times. It reports false if no time passed between them.
+func hostCPUPercent(before, after cpu.TimesStat) (float64, bool) {
+	idle := func(t cpu.TimesStat) float64 { return t.Idle + t.Iowait }
+	total := func(t cpu.TimesStat) float64 {
+		return t.User + t.Nice + t.System + t.Idle + t.Iowait + t.Irq + t.Softirq + t.Steal
+	}
+
+	totalDelta := total(after) - total(before)
+	if totalDelta <= 0 {
+		return 0, false
+	}
+	busy := (totalDelta - (idle(after) - idle(before))) / totalDelta * 100
+	return min(max(busy, 0), 100), true
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+func TestHostCPUPercent(t *testing.T) {
+	before := cpu.TimesStat{User: 100, System: 50, Idle: 800, Iowait: 50}
+	after := cpu.TimesStat{User: 130, System: 60, Idle: 850, Iowait: 60}
+
+	if got, ok := hostCPUPercent(before, after); !ok || got != 40 {
+		t.Errorf("hostCPUPercent = %v, %v, want 40, true", got, ok)
+	}
+	if _, ok := hostCPUPercent(after, after); ok {
+		t.Error("hostCPUPercent reported a percentage with no time passed")
+	}
+}
+
+func TestSampleMetadataOmitsUnavailable(t *testing.T) {
+	cpuPercent, rss := 12.5, uint64(64<<20)
+	metadata := Sample{ProcessCPUPercent: &cpuPercent, ProcessRSSBytes: &rss}.Metadata()
+
+	if len(metadata) != 2 || metadata["process_cpu_percent"] != cpuPercent || metadata["process_rss_bytes"] != rss {
+		t.Errorf("Metadata() = %v", metadata)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	s := NewSampler()
+	s.window = 50 * time.Millisecond
+
+	sample := s.Sample(context.Background())
+	if sample.ProcessCPUPercent == nil || *sample.ProcessCPUPercent < 0 {
+		t.Errorf("ProcessCPUPercent = %v", sample.ProcessCPUPercent)
+	}
+	if sample.ProcessRSSBytes == nil || *sample.ProcessRSSBytes == 0 {
+		t.Errorf("ProcessRSSBytes = %v", sample.ProcessRSSBytes)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if metadata := s.Sample(ctx).Metadata(); len(metadata) != 0 {
+		t.Errorf("cancelled Sample() = %v, want nothing", metadata)
+	}
+}
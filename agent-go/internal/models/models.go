@@ -20,6 +20,15 @@ type ScanResult struct {
 	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
 	Dependencies    []Dependency    `json:"dependencies"`
 	Metadata        map[string]any  `json:"metadata"`
+	Delta           *FindingDelta   `json:"delta,omitempty"` // Set when Vulnerabilities are only the findings changed since the last result of the same scan type
+}
+
+// FindingDelta refers, by fingerprint, to the findings of a scan that were
+// sent before: those no longer found and those found unchanged. The result's
+// vulnerabilities are only its new and changed findings.
+type FindingDelta struct {
+	Resolved  []string `json:"resolved"`
+	Unchanged []string `json:"unchanged"`
 }
 
 // Vulnerability represents a detected vulnerability
@@ -42,12 +51,22 @@ type Vulnerability struct {
 	ExploitAvailable bool           `json:"exploit_available"`
 	ExploitCount     int            `json:"exploit_count"`
 	Status           string         `json:"status"`
-	Priority         string         `json:"priority"`
+	Priority         string         `json:"priority"` // Derived by the API at ingestion
 	Notes            string         `json:"notes,omitempty"`
 	EnrichmentData   map[string]any `json:"enrichment_data"`
+	Evidence         []Evidence     `json:"evidence,omitempty"` // Raw output that triggered the finding
 	CreatedAt        time.Time      `json:"created_at"`
 }
 
+// Evidence is raw scanner output attached to a finding, such as the command
+// output behind a configuration finding. The API stores it and replaces it
+// with a reference.
+type Evidence struct {
+	Label       string `json:"label"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
 // Dependency represents a detected dependency
 type Dependency struct {
 	ID              string          `json:"id"`
@@ -60,6 +79,7 @@ type Dependency struct {
 	Size            int64           `json:"size,omitempty"`
 	Vendor          string          `json:"vendor,omitempty"`
 	Description     string          `json:"description,omitempty"`
+	License         string          `json:"license,omitempty"` // SPDX expression, when the package metadata declares one
 	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
 	Metadata        map[string]any  `json:"metadata"`
 	CreatedAt       time.Time       `json:"created_at"`
@@ -109,6 +129,52 @@ type AgentStatus struct {
 	PerformanceMetrics   map[string]any `json:"performance_metrics"`
 }
 
+// Command types the API can queue for the agent
+const (
+	CommandScanNow       = "scan_now"
+	CommandVerifyFinding = "verify_finding" // Re-run the check behind one finding
+	CommandCollect       = "collect"        // Collect from other hosts over SSH or WinRM
+)
+
+// Command statuses the agent reports back
+const (
+	CommandAcked     = "acked"
+	CommandCompleted = "completed"
+	CommandFailed    = "failed"
+)
+
+// Outcomes of a verify_finding command
+const (
+	VerificationConfirmed    = "confirmed"    // The check still reports the finding
+	VerificationResolved     = "resolved"     // The check no longer reports the finding
+	VerificationUnverifiable = "unverifiable" // The check could not be re-run or could not tell
+)
+
+// FindingVerification is the result a verify_finding command completes with
+type FindingVerification struct {
+	Status      string     `json:"status"`
+	Details     string     `json:"details"`
+	CheckResult any        `json:"check_result,omitempty"`
+	Evidence    []Evidence `json:"evidence,omitempty"`
+}
+
+// AgentCommand is an instruction from the API, delivered in heartbeat responses
+type AgentCommand struct {
+	ID      string         `json:"id"`
+	Type    string         `json:"type"`
+	Payload map[string]any `json:"payload,omitempty"`
+}
+
+// AgentUpdate is an agent release the API offers in a heartbeat response, for
+// agents running an older version to install
+type AgentUpdate struct {
+	Version   string `json:"version"`
+	Platform  string `json:"platform"` // GOOS/GOARCH
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"` // Base64 ed25519 signature of the release manifest, see updater.releaseManifest
+}
+
 // APIResponse represents API response structure
 type APIResponse struct {
 	Success   bool      `json:"success"`
@@ -148,6 +214,9 @@ type NetworkAsset struct {
 	LastSeen        time.Time              `json:"last_seen"`
 	IsMonitored     bool                   `json:"is_monitored"`
 	Metadata        map[string]interface{} `json:"metadata"`
+
+	// Risk adjusted for the assets that can reach this one, set by topology analysis
+	AdjustedRiskScore float64 `json:"adjusted_risk_score,omitempty"`
 }
 
 // PortInfo represents information about an open port
@@ -209,6 +278,13 @@ type NetworkTopology struct {
 	TotalAssets      int            `json:"total_assets"`
 	TotalConnections int            `json:"total_connections"`
 	LastUpdated      time.Time      `json:"last_updated"`
+
+	// Set when the graph exceeded the analyzer's size limits and critical
+	// paths were found by sampling rather than over the whole graph
+	Sampled          bool   `json:"sampled"`
+	SamplingStrategy string `json:"sampling_strategy,omitempty"`
+	Partitions       int    `json:"partitions,omitempty"`    // Subnets analyzed separately
+	DroppedNodes     int    `json:"dropped_nodes,omitempty"` // Nodes left out of path analysis
 }
 
 // TopologyNode represents a node in the topology map
@@ -226,6 +302,14 @@ type TopologyNode struct {
 	IsMonitored bool                   `json:"is_monitored"`
 	Status      string                 `json:"status"` // active, inactive, discovered
 	Metadata    map[string]interface{} `json:"metadata"`
+
+	// Reachability-adjusted risk: the node's own risk, raised by the risk of
+	// the assets that can reach it, decayed per hop and weighted by the
+	// node's value as a target
+	AdjustedRiskScore float64 `json:"adjusted_risk_score"`
+	InheritedRisk     float64 `json:"inherited_risk,omitempty"`   // Risk reaching the node, before weighting
+	RiskSource        string  `json:"risk_source,omitempty"`      // Node the inherited risk originates from
+	RiskSourceHops    int     `json:"risk_source_hops,omitempty"` // Hops from RiskSource
 }
 
 // TopologyLink represents a connection between nodes
@@ -297,6 +381,7 @@ type InstalledApp struct {
 	Size        int64     `json:"size"`
 	Vendor      string    `json:"vendor"`
 	Description string    `json:"description"`
+	License     string    `json:"license,omitempty"` // As the package manager reports it
 }
 
 // Asset represents a scanned asset
@@ -0,0 +1,177 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/agent/internal/models"
+)
+
+// FindingFingerprint identifies a finding across scans by what it is and
+// where it was found, ignoring details such as severity that may change. It
+// matches the key the API tracks finding states by.
+func FindingFingerprint(v *models.Vulnerability) string {
+	id := v.CVEID
+	if id == "" {
+		id = v.Title
+	}
+	parts := []string{v.Type, id, v.PackageName, v.Location}
+	for i := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(parts[i]))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// findingDigest summarizes the details of a finding the API stores, so a
+// finding whose details changed is sent again
+func findingDigest(v *models.Vulnerability) string {
+	var score string
+	if v.CVSSScore != nil {
+		score = fmt.Sprintf("%g", *v.CVSSScore)
+	}
+	parts := []string{v.Severity, v.Title, v.Description, score, v.CVSSVector, v.PackageVersion, v.Remediation, v.Status, fmt.Sprint(v.ExploitAvailable)}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// sentFindings is what was last sent for one scan type
+type sentFindings struct {
+	Digests  map[string]string `json:"digests"`   // Finding digests by fingerprint
+	LastFull time.Time         `json:"last_full"` // When the findings were last sent in full
+}
+
+// deltaTracker remembers the findings last sent for each scan type, kept in
+// a file so deltas survive restarts
+type deltaTracker struct {
+	path         string
+	fullInterval time.Duration
+
+	mu     sync.Mutex
+	scopes map[string]*sentFindings
+}
+
+// newDeltaTracker loads the fingerprints last sent from path. A missing or
+// unreadable file starts over, sending every scan type in full.
+func newDeltaTracker(path string, fullInterval time.Duration) *deltaTracker {
+	t := &deltaTracker{path: path, fullInterval: fullInterval, scopes: make(map[string]*sentFindings)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[Processor] Failed to read finding fingerprints, sending findings in full: %v", err)
+		}
+		return t
+	}
+	if err := json.Unmarshal(data, &t.scopes); err != nil {
+		log.Printf("[Processor] Ignoring corrupt finding fingerprints in %s: %v", path, err)
+		t.scopes = make(map[string]*sentFindings)
+	}
+	return t
+}
+
+// save writes the fingerprints to the state file, replacing it atomically
+func (t *deltaTracker) save() error {
+	data, err := json.Marshal(t.scopes)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(t.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create finding fingerprint directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write finding fingerprints: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write finding fingerprints: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write finding fingerprints: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), t.path); err != nil {
+		return fmt.Errorf("failed to write finding fingerprints: %w", err)
+	}
+	return nil
+}
+
+// ApplyDelta trims a result down to the findings added or changed since the
+// last result of its scan type was sent, naming the rest in its delta as
+// unchanged or resolved. Each scan type is sent in full when nothing was sent
+// for it before and once every full-sync interval, so the API corrects any
+// drift. Results carrying dependencies are left whole, as the API derives
+// their findings itself.
+//
+// The returned commit records the findings as sent; call it once the result
+// was delivered or queued, so a lost result is diffed against again.
+func (p *Processor) ApplyDelta(result *models.ScanResult) (commit func()) {
+	scanType, _ := result.Metadata["scan_type"].(string)
+	if p.deltas == nil || scanType == "" || len(result.Dependencies) > 0 {
+		return func() {}
+	}
+
+	current := make(map[string]string, len(result.Vulnerabilities))
+	for i := range result.Vulnerabilities {
+		v := &result.Vulnerabilities[i]
+		current[FindingFingerprint(v)] = findingDigest(v)
+	}
+
+	t := p.deltas
+	now := time.Now()
+	t.mu.Lock()
+	last := t.scopes[scanType]
+	full := last == nil || now.Sub(last.LastFull) >= t.fullInterval
+	if !full {
+		delta := &models.FindingDelta{Resolved: []string{}, Unchanged: []string{}}
+		var changed []models.Vulnerability
+		for i := range result.Vulnerabilities {
+			v := result.Vulnerabilities[i]
+			fingerprint := FindingFingerprint(&v)
+			if last.Digests[fingerprint] == current[fingerprint] {
+				delta.Unchanged = append(delta.Unchanged, fingerprint)
+			} else {
+				changed = append(changed, v)
+			}
+		}
+		for fingerprint := range last.Digests {
+			if _, ok := current[fingerprint]; !ok {
+				delta.Resolved = append(delta.Resolved, fingerprint)
+			}
+		}
+		sort.Strings(delta.Resolved)
+		sort.Strings(delta.Unchanged)
+
+		log.Printf("[Processor] Sending %s findings as a delta: %d added or changed, %d unchanged, %d resolved",
+			scanType, len(changed), len(delta.Unchanged), len(delta.Resolved))
+		result.Vulnerabilities = changed
+		result.Delta = delta
+	}
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		sent := &sentFindings{Digests: current}
+		if full {
+			sent.LastFull = now
+		} else if prev := t.scopes[scanType]; prev != nil {
+			sent.LastFull = prev.LastFull
+		}
+		t.scopes[scanType] = sent
+		if err := t.save(); err != nil {
+			log.Printf("[Processor] Failed to save finding fingerprints: %v", err)
+		}
+	}
+}
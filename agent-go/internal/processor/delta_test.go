@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
+)
+
+func newDeltaProcessor(t *testing.T, path string) *Processor {
+	t.Helper()
+	return NewProcessor(&config.Config{ResultDelta: true, ResultDeltaStatePath: path, ResultDeltaFullInterval: time.Hour})
+}
+
+func containerResult(findings ...models.Vulnerability) *models.ScanResult {
+	return &models.ScanResult{
+		Metadata:        map[string]any{"scan_type": "container"},
+		Vulnerabilities: findings,
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "finding_fingerprints.json")
+	finding := func(title, severity string) models.Vulnerability {
+		return models.Vulnerability{Type: "container", Title: title, Severity: severity}
+	}
+	privileged, rootUser, latestTag := finding("Privileged container", "high"), finding("Runs as root", "medium"), finding("Uses latest tag", "low")
+
+	// The first result of a scan type is sent in full
+	p := newDeltaProcessor(t, path)
+	result := containerResult(privileged, rootUser, latestTag)
+	p.ApplyDelta(result)()
+	if result.Delta != nil || len(result.Vulnerabilities) != 3 {
+		t.Fatalf("first result should be sent in full, got %d findings and delta %v", len(result.Vulnerabilities), result.Delta)
+	}
+
+	// A restarted agent sends only what changed since
+	p = newDeltaProcessor(t, path)
+	privileged.Severity = "critical"
+	tlsDisabled := finding("TLS verification disabled", "high")
+	result = containerResult(privileged, rootUser, tlsDisabled)
+	commit := p.ApplyDelta(result)
+
+	if result.Delta == nil {
+		t.Fatal("second result should be sent as a delta")
+	}
+	var sent []string
+	for _, v := range result.Vulnerabilities {
+		sent = append(sent, v.Title)
+	}
+	if want := []string{"Privileged container", "TLS verification disabled"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent findings = %v, want %v", sent, want)
+	}
+	if want := []string{FindingFingerprint(&rootUser)}; !reflect.DeepEqual(result.Delta.Unchanged, want) {
+		t.Errorf("Unchanged = %v, want %v", result.Delta.Unchanged, want)
+	}
+	if want := []string{FindingFingerprint(&latestTag)}; !reflect.DeepEqual(result.Delta.Resolved, want) {
+		t.Errorf("Resolved = %v, want %v", result.Delta.Resolved, want)
+	}
+
+	// Until a result is committed the next one is diffed against the last sent
+	retry := containerResult(privileged, rootUser, tlsDisabled)
+	p.ApplyDelta(retry)
+	if len(retry.Vulnerabilities) != 2 {
+		t.Errorf("uncommitted delta: sent %d findings, want 2", len(retry.Vulnerabilities))
+	}
+	commit()
+
+	same := containerResult(privileged, rootUser, tlsDisabled)
+	p.ApplyDelta(same)
+	if len(same.Vulnerabilities) != 0 || len(same.Delta.Unchanged) != 3 || len(same.Delta.Resolved) != 0 {
+		t.Errorf("unchanged findings: sent %d findings and delta %+v", len(same.Vulnerabilities), same.Delta)
+	}
+
+	// Once the full-sync interval passes the findings are sent in full again
+	p.deltas.scopes["container"].LastFull = time.Now().Add(-2 * time.Hour)
+	full := containerResult(privileged, rootUser, tlsDisabled)
+	p.ApplyDelta(full)
+	if full.Delta != nil || len(full.Vulnerabilities) != 3 {
+		t.Errorf("expired full sync: sent %d findings and delta %v", len(full.Vulnerabilities), full.Delta)
+	}
+}
+
+func TestApplyDeltaSkipsDependencyResults(t *testing.T) {
+	p := newDeltaProcessor(t, filepath.Join(t.TempDir(), "finding_fingerprints.json"))
+	result := &models.ScanResult{
+		Metadata:     map[string]any{"scan_type": "vulnerability_scan"},
+		Dependencies: []models.Dependency{{Name: "lodash", Version: "4.17.20"}},
+	}
+	p.ApplyDelta(result)()
+	p.ApplyDelta(result)
+	if result.Delta != nil {
+		t.Error("results with dependencies should always be sent whole")
+	}
+
+	p = NewProcessor(&config.Config{})
+	result = containerResult(models.Vulnerability{Title: "Runs as root"})
+	p.ApplyDelta(result)()
+	p.ApplyDelta(result)
+	if result.Delta != nil {
+		t.Error("results should be sent whole when deltas are disabled")
+	}
+}
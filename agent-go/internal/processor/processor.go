@@ -1,22 +1,39 @@
 package processor
 
 import (
+	"log"
+	"os"
+	"runtime"
+	"strings"
 	"time"
 
 	"zerotrace/agent/internal/config"
 	"zerotrace/agent/internal/models"
+	"zerotrace/agent/internal/scanner"
 )
 
 // Processor handles scan result processing
 type Processor struct {
 	config *config.Config
+	deltas *deltaTracker // Findings last sent per scan type; nil when deltas are off
+
+	advisories *scanner.MultiSource // Advisory databases dependencies are matched against; nil to leave matching to the API
+	osID       string               // Distribution ID from /etc/os-release, which OS packages' advisories are for
 }
 
 // NewProcessor creates a new processor instance
 func NewProcessor(cfg *config.Config) *Processor {
-	return &Processor{
+	p := &Processor{
 		config: cfg,
 	}
+	if cfg.ResultDelta {
+		p.deltas = newDeltaTracker(cfg.ResultDeltaStatePath, cfg.ResultDeltaFullInterval)
+	}
+	if advisories := scanner.NewCVESources(cfg); advisories != nil {
+		p.advisories = advisories
+		p.osID = hostOSID()
+	}
+	return p
 }
 
 // Process processes scan results
@@ -28,7 +45,10 @@ func (p *Processor) Process(result *models.ScanResult) (*models.ScanResult, erro
 
 	// Agent sends raw dependencies to API
 	// API handles enrichment asynchronously via Python enrichment service
-	// No local enrichment processing needed
+	// Dependencies are only matched locally when CVE_SOURCES names databases
+	if p.advisories != nil {
+		p.matchAdvisories(result)
+	}
 
 	// Process vulnerabilities (if any from local scanning)
 	for i := range result.Vulnerabilities {
@@ -69,3 +89,60 @@ func (p *Processor) processDependency(dep *models.Dependency) {
 	// Dependencies are enriched by enrichment service
 	// Additional processing can be added here
 }
+
+// matchAdvisories adds the advisories affecting the result's dependencies,
+// found in the configured advisory databases, to its vulnerabilities.
+// Dependencies of ecosystems no database covers are left to the API.
+func (p *Processor) matchAdvisories(result *models.ScanResult) {
+	var packages []scanner.PackageQuery
+	seen := make(map[scanner.PackageQuery]bool)
+	for _, dep := range result.Dependencies {
+		query := scanner.PackageQuery{
+			Ecosystem: scanner.PackageEcosystem(dep.Type, p.osID),
+			Name:      dep.Name,
+			Version:   dep.Version,
+		}
+		if query.Ecosystem == "" || query.Version == "" || seen[query] {
+			continue
+		}
+		seen[query] = true
+		packages = append(packages, query)
+	}
+	if len(packages) == 0 {
+		return
+	}
+
+	// A failing database doesn't hold back what the others found
+	advisories, err := p.advisories.PackageAdvisories(packages)
+	if err != nil {
+		log.Printf("Advisory lookup failed for some sources: %v", err)
+	}
+	reported := make(map[string]bool)
+	for _, vuln := range result.Vulnerabilities {
+		reported[vuln.PackageName+"@"+vuln.PackageVersion+" "+vuln.ID] = true
+	}
+	for _, vuln := range advisories {
+		if key := vuln.PackageName + "@" + vuln.PackageVersion + " " + vuln.ID; !reported[key] {
+			reported[key] = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+		}
+	}
+}
+
+// hostOSID is the distribution's ID from /etc/os-release, such as debian or
+// alpine, or "" off Linux
+func hostOSID() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	data, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if id, ok := strings.CutPrefix(line, "ID="); ok {
+			return strings.Trim(strings.TrimSpace(id), `"'`)
+		}
+	}
+	return ""
+}
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
 
 	"github.com/google/uuid"
 )
@@ -93,6 +94,7 @@ type ModelInfo struct {
 	FairnessScore   float64                `json:"fairness_score,omitempty"`
 	PrivacyScore    float64                `json:"privacy_score,omitempty"`
 	SecurityScore   float64                `json:"security_score,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"` // Read from the model file's header, such as an ONNX producer and opset
 }
 
 // Vulnerability represents a specific vulnerability
@@ -106,21 +108,22 @@ type AIMLVulnerability struct {
 
 // TrainingDataInfo represents training data information
 type TrainingDataInfo struct {
-	DatasetName     string    `json:"dataset_name"`
-	Path            string    `json:"path"`
-	Size            int64     `json:"size"`
-	Hash            string    `json:"hash"`
-	Records         int64     `json:"records"`
-	Columns         []string  `json:"columns"`
-	SensitiveFields []string  `json:"sensitive_fields"`
-	HasPII          bool      `json:"has_pii"`
-	HasBias         bool      `json:"has_bias"`
-	DataQuality     float64   `json:"data_quality"`
-	LastUpdated     time.Time `json:"last_updated"`
-	Source          string    `json:"source"`
-	License         string    `json:"license"`
-	RetentionPolicy string    `json:"retention_policy"`
-	Permissions     string    `json:"permissions"`
+	DatasetName     string             `json:"dataset_name"`
+	Path            string             `json:"path"`
+	Size            int64              `json:"size"`
+	Hash            string             `json:"hash"`
+	Records         int64              `json:"records"`
+	Columns         []string           `json:"columns"`
+	SensitiveFields []string           `json:"sensitive_fields"`
+	HasPII          bool               `json:"has_pii"`
+	HasBias         bool               `json:"has_bias"`
+	BiasMetrics     map[string]float64 `json:"bias_metrics,omitempty"` // Group-fairness metrics when AIML_FAIRNESS_METRICS is on
+	DataQuality     float64            `json:"data_quality"`
+	LastUpdated     time.Time          `json:"last_updated"`
+	Source          string             `json:"source"`
+	License         string             `json:"license"`
+	RetentionPolicy string             `json:"retention_policy"`
+	Permissions     string             `json:"permissions"`
 }
 
 // SupplyChainInfo represents AI/ML supply chain information
@@ -184,7 +187,7 @@ var modelPatterns = map[string][]string{
 	"TensorFlow":   {".pb", ".h5", ".tflite", ".keras"},
 	"PyTorch":      {".pth", ".pt"},
 	"ONNX":         {".onnx"},
-	"HuggingFace":  {"config.json", "pytorch_model.bin", "model.safetensors"},
+	"HuggingFace":  {"config.json", "pytorch_model.bin", ".safetensors"},
 	"scikit-learn": {".joblib"},
 	"JAX":          {".msgpack", ".flax"},
 	"MXNet":        {".params"},
@@ -269,16 +272,17 @@ func (as *AIMLScanner) ScanWithContext(ctx context.Context, rootPath string) (*S
 		"model_files", len(modelFiles),
 		"data_files", len(dataFiles))
 
-	// Process models concurrently
-	models, modelFindings := as.processModelsParallel(ctx, modelFiles)
-	result.Models = models
-	result.Findings = append(result.Findings, modelFindings...)
-
-	// Process training data concurrently
+	// Process training data concurrently. Datasets go first so models can be
+	// judged on the fairness measured in the data they sit beside.
 	trainingData, dataFindings := as.processTrainingDataParallel(ctx, dataFiles)
 	result.TrainingData = trainingData
 	result.Findings = append(result.Findings, dataFindings...)
 
+	// Process models concurrently
+	models, modelFindings := as.processModelsParallel(ctx, modelFiles, datasetBiasByDir(trainingData))
+	result.Models = models
+	result.Findings = append(result.Findings, modelFindings...)
+
 	// Analyze supply chain
 	result.SupplyChain = as.analyzeSupplyChain(models, trainingData)
 	supplyChainFindings := as.scanSupplyChainSecurity(result.SupplyChain)
@@ -429,7 +433,7 @@ func (as *AIMLScanner) discoverFilesParallel(ctx context.Context, rootPath strin
 }
 
 // processModelsParallel processes model files concurrently
-func (as *AIMLScanner) processModelsParallel(ctx context.Context, modelFiles map[string][]string) ([]ModelInfo, []AIMLFinding) {
+func (as *AIMLScanner) processModelsParallel(ctx context.Context, modelFiles map[string][]string, biasByDir map[string]map[string]float64) ([]ModelInfo, []AIMLFinding) {
 	var models []ModelInfo
 	var findings []AIMLFinding
 	var mu sync.Mutex
@@ -460,6 +464,13 @@ func (as *AIMLScanner) processModelsParallel(ctx context.Context, modelFiles map
 					as.logger.Error("Failed to analyze model", "path", item.path, "error", err)
 					continue
 				}
+				if metrics, ok := biasByDir[filepath.Dir(item.path)]; ok {
+					// Copy, as the analyzed model is cached across scans
+					measured := *model
+					measured.BiasMetrics = metrics
+					measured.FairnessScore = as.calculateFairnessScore(&measured)
+					model = &measured
+				}
 
 				modelFindings := as.scanModel(*model)
 
@@ -636,11 +647,28 @@ func (as *AIMLScanner) analyzeModelContent(model *ModelInfo) {
 	// Check for known vulnerabilities based on framework
 	model.Vulnerabilities = as.checkKnownVulnerabilities(model.Framework, model.Name)
 
+	// Pickled models run whatever callables their pickles import when loaded
+	if pickledModelExtensions[strings.ToLower(filepath.Ext(model.Path))] {
+		vulns, err := pickleVulnerabilities(model.Path)
+		if err != nil {
+			as.logger.Warn("Failed to scan pickled model", "path", model.Path, "error", err)
+		}
+		model.Vulnerabilities = append(model.Vulnerabilities, vulns...)
+	}
+
 	// Analyze file header for additional metadata
 	if metadata := as.extractModelMetadata(model.Path); metadata != nil {
+		model.Metadata = metadata
 		if version, ok := metadata["version"].(string); ok {
 			model.Version = version
 		}
+		if framework, ok := metadata["framework"].(string); ok {
+			model.Framework = framework
+		}
+		if producer, ok := metadata["producer_name"].(string); ok {
+			version, _ := metadata["producer_version"].(string)
+			model.Vulnerabilities = append(model.Vulnerabilities, producerVulnerabilities(producer, version)...)
+		}
 	}
 }
 
@@ -667,15 +695,35 @@ func (as *AIMLScanner) checkKnownVulnerabilities(framework, modelName string) []
 	return vulns
 }
 
-// extractModelMetadata extracts metadata from model file
+// extractModelMetadata reads the metadata a model file's header records, for
+// the safetensors and ONNX formats. It returns nil for other formats, and for
+// files whose header cannot be read.
 func (as *AIMLScanner) extractModelMetadata(path string) map[string]interface{} {
-	// In production, would parse actual model format
-	// For now, return nil
-	return nil
+	var read func(string) (map[string]interface{}, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".safetensors":
+		read = readSafetensorsMetadata
+	case ".onnx":
+		read = readONNXMetadata
+	default:
+		return nil
+	}
+
+	metadata, err := read(path)
+	if err != nil {
+		as.logger.Warn("Failed to read model metadata", "path", path, "error", err)
+		return nil
+	}
+	return metadata
 }
 
-// calculateFairnessScore calculates model fairness score
+// calculateFairnessScore calculates model fairness score: the worst disparate
+// impact ratio measured in its training data, or a heuristic without one
 func (as *AIMLScanner) calculateFairnessScore(model *ModelInfo) float64 {
+	if ratio, _, ok := worstDisparateImpact(model.BiasMetrics); ok {
+		return ratio
+	}
+
 	score := 0.85 // Default reasonable score
 
 	// Reduce score based on risk factors
@@ -872,6 +920,57 @@ func (as *AIMLScanner) analyzeCSV(data *TrainingDataInfo) {
 	}
 
 	data.DataQuality = as.calculateDataQuality(data)
+	as.measureDatasetFairness(data)
+}
+
+// measureDatasetFairness computes group-fairness metrics for a labeled
+// dataset with protected attribute columns, when enabled. The dataset is
+// biased if any protected column's disparate impact ratio is below the
+// fairness threshold.
+func (as *AIMLScanner) measureDatasetFairness(data *TrainingDataInfo) {
+	if as.config == nil || !as.config.FairnessMetrics {
+		return
+	}
+
+	metrics, err := measureFairness(data.Path, as.config.ProtectedAttributes, as.config.LabelColumns, as.config.FairnessSampleRows)
+	if err != nil {
+		as.logger.Warn("Failed to measure dataset fairness", "path", data.Path, "error", err)
+		return
+	}
+	ratio, _, ok := worstDisparateImpact(metrics)
+	if !ok {
+		return
+	}
+	data.BiasMetrics = metrics
+	data.HasBias = ratio < as.fairnessThreshold()
+}
+
+// datasetBiasByDir maps each directory to the fairness metrics of its most
+// biased measured dataset
+func datasetBiasByDir(datasets []TrainingDataInfo) map[string]map[string]float64 {
+	byDir := make(map[string]map[string]float64)
+	for _, data := range datasets {
+		ratio, _, ok := worstDisparateImpact(data.BiasMetrics)
+		if !ok {
+			continue
+		}
+		dir := filepath.Dir(data.Path)
+		if current, exists := byDir[dir]; exists {
+			if currentRatio, _, _ := worstDisparateImpact(current); currentRatio <= ratio {
+				continue
+			}
+		}
+		byDir[dir] = data.BiasMetrics
+	}
+	return byDir
+}
+
+// fairnessThreshold is the lowest acceptable fairness score, and disparate impact ratio
+func (as *AIMLScanner) fairnessThreshold() float64 {
+	if as.config != nil && as.config.FairnessThreshold > 0 {
+		return as.config.FairnessThreshold
+	}
+	return 0.7
 }
 
 // analyzeJSON analyzes JSON files
@@ -897,10 +996,25 @@ func (as *AIMLScanner) analyzeJSON(data *TrainingDataInfo) {
 	data.DataQuality = as.calculateDataQuality(data)
 }
 
-// analyzeParquet analyzes Parquet files
+// parquetUnreadableQuality is the data quality of a Parquet file whose schema
+// could not be read: its columns, and any PII in them, are unknown
+const parquetUnreadableQuality = 0.3
+
+// analyzeParquet analyzes Parquet files from the schema and row count in
+// their footer
 func (as *AIMLScanner) analyzeParquet(data *TrainingDataInfo) {
-	// Would need parquet library in production
-	data.DataQuality = 0.7 // Default for binary format
+	columns, records, err := readParquetSchema(data.Path, as.maxFileSize)
+	if err != nil {
+		as.logger.Warn("Failed to read Parquet schema", "path", data.Path, "error", err)
+		data.DataQuality = parquetUnreadableQuality
+		return
+	}
+
+	data.Columns = columns
+	data.Records = records
+	data.SensitiveFields = as.detectSensitiveFields(data.Columns)
+	data.HasPII = len(data.SensitiveFields) > 0
+	data.DataQuality = as.calculateDataQuality(data)
 }
 
 // extractJSONFields extracts field names from JSON structure
@@ -984,14 +1098,16 @@ func (as *AIMLScanner) scanModel(model ModelInfo) []AIMLFinding {
 				"model_hash":       model.Hash,
 			},
 		}
+		for _, key := range []string{"producer_name", "producer_version", "opset"} {
+			if value, ok := model.Metadata[key]; ok {
+				finding.Metadata[key] = value
+			}
+		}
 		findings = append(findings, finding)
 	}
 
 	// Check fairness score
-	threshold := 0.7
-	if as.config != nil && as.config.FairnessThreshold > 0 {
-		threshold = as.config.FairnessThreshold
-	}
+	threshold := as.fairnessThreshold()
 
 	if model.FairnessScore < threshold {
 		finding := AIMLFinding{
@@ -1013,6 +1129,9 @@ func (as *AIMLScanner) scanModel(model ModelInfo) []AIMLFinding {
 				"threshold":      threshold,
 			},
 		}
+		if len(model.BiasMetrics) > 0 {
+			finding.Metadata["bias_metrics"] = model.BiasMetrics
+		}
 		findings = append(findings, finding)
 	}
 
@@ -1153,6 +1272,13 @@ func (as *AIMLScanner) scanTrainingData(data TrainingDataInfo) []AIMLFinding {
 				"dataset_name": data.DatasetName,
 			},
 		}
+		if ratio, column, ok := worstDisparateImpact(data.BiasMetrics); ok {
+			finding.Description = fmt.Sprintf("Dataset %s has disparate impact ratio %.2f across %s (sampled %.0f rows)",
+				data.DatasetName, ratio, column, data.BiasMetrics[fairnessSampleRowsMetric])
+			finding.CurrentValue = fmt.Sprintf("%.2f", ratio)
+			finding.RequiredValue = fmt.Sprintf("%.2f+", as.fairnessThreshold())
+			finding.Metadata["bias_metrics"] = data.BiasMetrics
+		}
 		findings = append(findings, finding)
 	}
 
@@ -1462,6 +1588,67 @@ func min(a, b float64) float64 {
 	return b
 }
 
+// ScanPaths scans each root path and reports the findings as a scan result
+// for the API. Roots that cannot be scanned are skipped and listed in the
+// result's metadata; it fails only if none could be.
+func (as *AIMLScanner) ScanPaths(ctx context.Context, roots []string) (*models.ScanResult, error) {
+	startTime := time.Now()
+	result := &models.ScanResult{
+		ID:              uuid.New(),
+		AgentID:         as.config.AgentID,
+		CompanyID:       as.config.CompanyID,
+		Status:          "completed",
+		StartTime:       startTime,
+		Vulnerabilities: []models.Vulnerability{},
+		Dependencies:    []models.Dependency{},
+		Metadata:        map[string]interface{}{"scan_type": "aiml", "paths": roots},
+	}
+
+	modelsFound, datasetsFound := 0, 0
+	skipped := make(map[string]string)
+	for _, root := range roots {
+		scan, err := as.ScanWithContext(ctx, root)
+		if err != nil {
+			skipped[root] = err.Error()
+			continue
+		}
+		modelsFound += len(scan.Models)
+		datasetsFound += len(scan.TrainingData)
+		for _, finding := range scan.Findings {
+			result.Vulnerabilities = append(result.Vulnerabilities, models.Vulnerability{
+				ID:          finding.ID,
+				Type:        "aiml",
+				Severity:    finding.Severity,
+				Title:       finding.Title,
+				Description: finding.Description,
+				Location:    finding.FilePath,
+				Remediation: finding.Remediation,
+				Status:      "open",
+				EnrichmentData: map[string]any{
+					"category":       finding.Type,
+					"model_name":     finding.ModelName,
+					"framework":      finding.Framework,
+					"current_value":  finding.CurrentValue,
+					"required_value": finding.RequiredValue,
+				},
+				CreatedAt: finding.DiscoveredAt,
+			})
+		}
+	}
+	if len(roots) > 0 && len(skipped) == len(roots) {
+		return nil, fmt.Errorf("no AI/ML scan path could be scanned: %v", skipped)
+	}
+
+	result.EndTime = time.Now()
+	result.Metadata["models_found"] = modelsFound
+	result.Metadata["datasets_found"] = datasetsFound
+	result.Metadata["total_vulnerabilities"] = len(result.Vulnerabilities)
+	if len(skipped) > 0 {
+		result.Metadata["skipped_paths"] = skipped
+	}
+	return result, nil
+}
+
 // noOpLogger is a no-op logger implementation
 type noOpLogger struct{}
 
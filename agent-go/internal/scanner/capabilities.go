@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"os/exec"
+	"sort"
+)
+
+// OptionalTools are the external tools scanners use when they are installed.
+// A scanner whose tool is missing skips that part of the scan rather than failing.
+var OptionalTools = []string{"nmap", "nuclei", "docker", "podman", "ctr", "kubectl", "trivy", "syft", "grype", "bluetoothctl", "lsusb"}
+
+// CapabilityReport records which parts of a scan ran and which were skipped
+// because the tool they need is not installed, so that an empty result can be
+// told apart from a scan that could not look
+type CapabilityReport struct {
+	Available []string            `json:"available"`
+	Skipped   []SkippedCapability `json:"skipped"`
+}
+
+// SkippedCapability is a part of a scan that did not run
+type SkippedCapability struct {
+	Capability  string `json:"capability"`
+	MissingTool string `json:"missing_tool"`
+}
+
+// newCapabilityReport creates an empty report that serializes as empty lists
+func newCapabilityReport() *CapabilityReport {
+	return &CapabilityReport{
+		Available: []string{},
+		Skipped:   []SkippedCapability{},
+	}
+}
+
+// require reports whether tool is installed, recording capability as available or skipped
+func (r *CapabilityReport) require(capability, tool string) bool {
+	if toolInstalled(tool) {
+		r.available(capability)
+		return true
+	}
+	r.skip(capability, tool)
+	return false
+}
+
+func (r *CapabilityReport) available(capability string) {
+	r.Available = append(r.Available, capability)
+}
+
+func (r *CapabilityReport) skip(capability, tool string) {
+	r.Skipped = append(r.Skipped, SkippedCapability{Capability: capability, MissingTool: tool})
+}
+
+// Complete reports whether every capability of the scan ran
+func (r *CapabilityReport) Complete() bool {
+	return len(r.Skipped) == 0
+}
+
+// MissingTools returns the optional tools that are not installed on this host
+func MissingTools() []string {
+	missing := []string{}
+	for _, tool := range OptionalTools {
+		if !toolInstalled(tool) {
+			missing = append(missing, tool)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// toolInstalled reports whether an external tool is on the PATH
+func toolInstalled(tool string) bool {
+	_, err := exec.LookPath(tool)
+	return err == nil
+}
@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"zerotrace/agent/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ConfigBaseline is the approved configuration for a host group, captured
+// from a known-good host. Settings map each check name to the state the
+// check reported on that host.
+type ConfigBaseline struct {
+	HostGroup string            `json:"host_group"`
+	Version   int               `json:"version"`
+	Settings  map[string]string `json:"settings"`
+}
+
+// configSetting is the state of one configuration check on this host
+type configSetting struct {
+	severity   string
+	value      string
+	unassessed bool // The check could not determine the setting
+}
+
+// SetBaseline sets the baseline that subsequent scans report drift against.
+// A nil baseline disables drift detection.
+func (cs *ConfigScanner) SetBaseline(baseline *ConfigBaseline) {
+	cs.baseline = baseline
+}
+
+// observe records the state a configuration check reported during the current scan
+func (cs *ConfigScanner) observe(name, severity, value string) {
+	if cs.settings == nil {
+		cs.settings = make(map[string]configSetting)
+	}
+	cs.settings[name] = configSetting{severity: severity, value: value}
+}
+
+// settingValues returns the observed settings in the form stored in a baseline
+func (cs *ConfigScanner) settingValues() map[string]string {
+	values := make(map[string]string, len(cs.settings))
+	for name, setting := range cs.settings {
+		if !setting.unassessed {
+			values[name] = setting.value
+		}
+	}
+	return values
+}
+
+// compareToBaseline reports every setting that differs from the approved
+// baseline, whether or not the new state still passes its security check.
+// Settings whose check errored are not compared.
+func compareToBaseline(baseline *ConfigBaseline, current map[string]configSetting, osName string) []models.Vulnerability {
+	if baseline == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(baseline.Settings))
+	for name := range baseline.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var drift []models.Vulnerability
+	for _, name := range names {
+		expected := baseline.Settings[name]
+		setting, ok := current[name]
+		if ok && (setting.value == expected || setting.unassessed) {
+			continue
+		}
+
+		severity := "medium"
+		actual := "setting not reported"
+		if ok {
+			severity = setting.severity
+			actual = setting.value
+		}
+
+		drift = append(drift, models.Vulnerability{
+			ID:          uuid.New().String(),
+			Type:        "configuration_drift",
+			Title:       fmt.Sprintf("%s changed from approved baseline", name),
+			Description: fmt.Sprintf("%s no longer matches the approved baseline for host group %q", name, baseline.HostGroup),
+			Severity:    severity,
+			Status:      "open",
+			Location:    name,
+			Remediation: "Restore the approved setting, or update the host group baseline if the change was authorized",
+			EnrichmentData: map[string]interface{}{
+				"expected":         expected,
+				"actual":           actual,
+				"host_group":       baseline.HostGroup,
+				"baseline_version": baseline.Version,
+				"os":               osName,
+				"category":         "configuration_drift",
+			},
+			CreatedAt: time.Now(),
+		})
+	}
+
+	return drift
+}
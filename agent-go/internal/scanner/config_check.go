@@ -0,0 +1,290 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"zerotrace/agent/internal/models"
+)
+
+// maxObservedLength truncates the raw value a check result records
+const maxObservedLength = 256
+
+// Defaults for a ConfigScanner without configuration, as in tests
+const (
+	defaultConfigCheckWorkers   = 4
+	defaultConfigCommandTimeout = 15 * time.Second
+	defaultConfigScanTimeout    = 2 * time.Minute
+)
+
+// CheckResult is the outcome of one configuration check: whether the setting
+// is secure, what the check saw and expected, and how sure it is
+type CheckResult struct {
+	Passed     bool    `json:"passed"`
+	Errored    bool    `json:"errored"` // The check could not determine the setting, so Passed is not a verdict
+	Details    string  `json:"details"`
+	Command    string  `json:"command,omitempty"`     // Command line the check examined
+	Observed   string  `json:"observed,omitempty"`    // Raw value observed
+	Expected   string  `json:"expected,omitempty"`    // Value the check passes with
+	Confidence float64 `json:"confidence"`            // 0 to 1: how certain Passed is
+	Severity   string  `json:"severity,omitempty"`    // Severity of the finding when it differs from the check's
+	EnforcedBy string  `json:"enforced_by,omitempty"` // UUID of the MDM profile enforcing the setting, when one does
+}
+
+// securityCheck is one configuration check a scan runs. It runs on a scanner
+// of its own, which captures its commands' evidence apart from the checks
+// running alongside it.
+type securityCheck struct {
+	name        string
+	description string
+	severity    string
+	check       func(cs *ConfigScanner) CheckResult
+}
+
+// checkOutcome is what running a check yielded: its result and the evidence
+// its commands left
+type checkOutcome struct {
+	result   CheckResult
+	evidence []models.Evidence
+}
+
+// severityOf returns the severity of the finding a failed result raises
+func (c securityCheck) severityOf(result CheckResult) string {
+	if result.Severity != "" {
+		return result.Severity
+	}
+	return c.severity
+}
+
+// RunCheck runs one security check of this OS by name, as verifying a single
+// finding does, and returns its result with the evidence it captured. It
+// reports false when the OS has no check of that name.
+func (cs *ConfigScanner) RunCheck(name string) (CheckResult, []models.Evidence, bool) {
+	var checks []securityCheck
+	switch runtime.GOOS {
+	case "darwin":
+		checks = cs.macOSChecks()
+	case "linux":
+		checks = cs.linuxChecks()
+	case "windows":
+		checks = cs.windowsChecks()
+	}
+
+	for _, check := range checks {
+		if strings.EqualFold(check.name, name) {
+			cs.mdm = nil // List the profiles afresh
+			outcome := cs.runCheck(context.Background(), check)
+			return outcome.result, outcome.evidence, true
+		}
+	}
+	return CheckResult{}, nil, false
+}
+
+// forCheck returns a scanner to run one check on. It shares this scanner's
+// configuration and MDM profiles, bounds the check's commands by ctx, and
+// keeps the check's evidence to itself.
+func (cs *ConfigScanner) forCheck(ctx context.Context) *ConfigScanner {
+	return &ConfigScanner{config: cs.config, mdm: cs.mdm, ctx: ctx}
+}
+
+// runCheck runs a check on a scanner of its own. A check whose command timed
+// out is unable to determine its setting, even one that takes a failing
+// command to mean the setting is off.
+func (cs *ConfigScanner) runCheck(ctx context.Context, check securityCheck) checkOutcome {
+	run := cs.forCheck(ctx)
+	result := check.check(run)
+	if run.timedOut != nil {
+		result = run.checkError(run.timedOut, fmt.Sprintf("Unable to determine %s: its command timed out", check.name))
+	}
+	return checkOutcome{result: result, evidence: run.takeEvidence()}
+}
+
+// runChecks runs checks in a pool of ConfigCheckWorkers workers and returns
+// their outcomes in the checks' order, whichever order they complete in.
+// Once ctx is done the pool is not waited on: checks still running, or not
+// yet started, are unable to determine their settings.
+func (cs *ConfigScanner) runChecks(ctx context.Context, checks []securityCheck) []checkOutcome {
+	type completed struct {
+		index   int
+		outcome checkOutcome
+	}
+	// Buffered so that workers abandoned at the deadline can still finish
+	results := make(chan completed, len(checks))
+	indexes := make(chan int)
+	base := cs.forCheck(ctx)
+
+	workers := cs.checkWorkers()
+	if workers > len(checks) {
+		workers = len(checks)
+	}
+	for range workers {
+		go func() {
+			for i := range indexes {
+				results <- completed{index: i, outcome: base.runCheck(ctx, checks[i])}
+			}
+		}()
+	}
+	go func() {
+		defer close(indexes)
+		for i := range checks {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	outcomes := make([]checkOutcome, len(checks))
+	done := make([]bool, len(checks))
+	collect := func(c completed) {
+		outcomes[c.index] = c.outcome
+		done[c.index] = true
+	}
+	for pending := len(checks); pending > 0; pending-- {
+		select {
+		case c := <-results:
+			collect(c)
+			continue
+		case <-ctx.Done():
+		}
+		// Take the outcomes already in, and give up on the rest
+		for drained := false; !drained; {
+			select {
+			case c := <-results:
+				collect(c)
+			default:
+				drained = true
+			}
+		}
+		break
+	}
+
+	for i, check := range checks {
+		if !done[i] {
+			outcomes[i].result = CheckResult{
+				Errored:  true,
+				Details:  fmt.Sprintf("Unable to determine %s: the configuration scan's deadline passed first", check.name),
+				Observed: truncateObserved(ctx.Err().Error()),
+			}
+		}
+	}
+	return outcomes
+}
+
+// checkWorkers is how many checks a scan runs at once
+func (cs *ConfigScanner) checkWorkers() int {
+	if cs.config != nil && cs.config.ConfigCheckWorkers > 0 {
+		return cs.config.ConfigCheckWorkers
+	}
+	return defaultConfigCheckWorkers
+}
+
+// commandTimeout is how long a check's command may run
+func (cs *ConfigScanner) commandTimeout() time.Duration {
+	if cs.config != nil && cs.config.ConfigCommandTimeout > 0 {
+		return cs.config.ConfigCommandTimeout
+	}
+	return defaultConfigCommandTimeout
+}
+
+// scanTimeout is how long a scan's checks may take altogether
+func (cs *ConfigScanner) scanTimeout() time.Duration {
+	if cs.config != nil && cs.config.ConfigScanTimeout > 0 {
+		return cs.config.ConfigScanTimeout
+	}
+	return defaultConfigScanTimeout
+}
+
+// UnassessedCheck is a check that could not determine its setting. It is
+// reported as an informational item rather than a vulnerability, as the
+// setting may well be secure.
+type UnassessedCheck struct {
+	Name     string      `json:"name"`
+	Severity string      `json:"severity"` // Severity of a failure, had the check been able to tell
+	OS       string      `json:"os"`
+	Result   CheckResult `json:"result"`
+}
+
+// recordUnassessed records a check that errored. Its setting is left out of
+// baseline comparison, so the error does not show up as drift either.
+func (cs *ConfigScanner) recordUnassessed(name, severity, osName string, result CheckResult) {
+	cs.unassessed = append(cs.unassessed, UnassessedCheck{Name: name, Severity: severity, OS: osName, Result: result})
+	if cs.settings == nil {
+		cs.settings = make(map[string]configSetting)
+	}
+	cs.settings[name] = configSetting{severity: severity, value: result.Details, unassessed: true}
+}
+
+// verdict builds the result of a check that determined the setting's state
+// from the output of the last command it ran
+func (cs *ConfigScanner) verdict(passed bool, observed, expected, details string) CheckResult {
+	return CheckResult{
+		Passed:     passed,
+		Details:    details,
+		Command:    cs.lastCommand,
+		Observed:   truncateObserved(observed),
+		Expected:   expected,
+		Confidence: 1,
+	}
+}
+
+// checkError builds the result of a check that could not determine the
+// setting's state, such as when its command is missing or failed
+func (cs *ConfigScanner) checkError(err error, details string) CheckResult {
+	result := CheckResult{
+		Errored: true,
+		Details: details,
+		Command: cs.lastCommand,
+	}
+	if err != nil {
+		result.Observed = truncateObserved(err.Error())
+	}
+	return result
+}
+
+// withConfidence lowers a result's confidence, for checks that infer the
+// setting indirectly
+func (r CheckResult) withConfidence(confidence float64) CheckResult {
+	r.Confidence = confidence
+	return r
+}
+
+// withSeverity overrides the severity of the finding a failed result raises,
+// for failures less pressing than the check usually finds
+func (r CheckResult) withSeverity(severity string) CheckResult {
+	r.Severity = severity
+	return r
+}
+
+// enrichment describes the result for a finding's enrichment data
+func (r CheckResult) enrichment() map[string]interface{} {
+	enrichment := map[string]interface{}{
+		"passed":     r.Passed,
+		"errored":    r.Errored,
+		"command":    r.Command,
+		"observed":   r.Observed,
+		"expected":   r.Expected,
+		"confidence": r.Confidence,
+	}
+	if r.EnforcedBy != "" {
+		enrichment["enforced_by"] = r.EnforcedBy
+	}
+	return enrichment
+}
+
+func truncateObserved(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > maxObservedLength {
+		return value[:maxObservedLength] + "..."
+	}
+	return value
+}
+
+// commandLine formats a command for a check result
+func commandLine(name string, args []string) string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s", name, strings.Join(args, " ")))
+}
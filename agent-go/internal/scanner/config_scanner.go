@@ -1,6 +1,10 @@
 package scanner
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"runtime"
@@ -15,7 +19,15 @@ import (
 
 // ConfigScanner scans for configuration vulnerabilities
 type ConfigScanner struct {
-	config *config.Config
+	config      *config.Config
+	baseline    *ConfigBaseline
+	settings    map[string]configSetting // Check states observed during the current scan
+	evidence    []models.Evidence        // Command output captured by the check currently running
+	lastCommand string                   // Command line last run by the check currently running
+	unassessed  []UnassessedCheck        // Checks that could not determine their setting during the current scan
+	mdm         *mdmState                // MDM profiles installed, listed before the checks consulting them
+	ctx         context.Context          // Bounds the commands of the check running on this scanner; none when nil
+	timedOut    error                    // Why the first of the check's commands to time out was stopped
 }
 
 // ComplianceCheck represents a compliance framework check
@@ -36,8 +48,13 @@ func NewConfigScanner(cfg *config.Config) *ConfigScanner {
 	}
 }
 
+// Name names the configuration scanner as scan scopes do
+func (cs *ConfigScanner) Name() string {
+	return ScannerConfig
+}
+
 // Scan performs configuration vulnerability scanning
-func (cs *ConfigScanner) Scan() (*models.ScanResult, error) {
+func (cs *ConfigScanner) Scan(ctx context.Context) (*models.ScanResult, error) {
 	startTime := time.Now()
 
 	// Create scan result
@@ -67,13 +84,20 @@ func (cs *ConfigScanner) Scan() (*models.ScanResult, error) {
 	var complianceChecks []ComplianceCheck
 	var err error
 
+	// Checks still running at the deadline are reported rather than waited on
+	ctx, cancel := context.WithTimeout(ctx, cs.scanTimeout())
+	defer cancel()
+
+	cs.settings = make(map[string]configSetting)
+	cs.unassessed = nil
+	cs.mdm = nil
 	switch runtime.GOOS {
 	case "darwin":
-		vulnerabilities, assets, complianceChecks, err = cs.scanMacOS()
+		vulnerabilities, assets, complianceChecks, err = cs.scanMacOS(ctx)
 	case "linux":
-		vulnerabilities, assets, complianceChecks, err = cs.scanLinux()
+		vulnerabilities, assets, complianceChecks, err = cs.scanLinux(ctx)
 	case "windows":
-		vulnerabilities, assets, complianceChecks, err = cs.scanWindows()
+		vulnerabilities, assets, complianceChecks, err = cs.scanWindows(ctx)
 	default:
 		return result, fmt.Errorf("unsupported OS: %s", runtime.GOOS)
 	}
@@ -88,6 +112,14 @@ func (cs *ConfigScanner) Scan() (*models.ScanResult, error) {
 	testVulns := cs.generateTestVulnerabilities()
 	vulnerabilities = append(vulnerabilities, testVulns...)
 
+	// Report drift from the host group's approved baseline
+	if cs.baseline != nil {
+		drift := compareToBaseline(cs.baseline, cs.settings, runtime.GOOS)
+		vulnerabilities = append(vulnerabilities, drift...)
+		result.Metadata["baseline_version"] = cs.baseline.Version
+		result.Metadata["baseline_drift"] = len(drift)
+	}
+
 	// Set results
 	result.Vulnerabilities = vulnerabilities
 	result.Metadata["total_vulnerabilities"] = len(vulnerabilities)
@@ -100,6 +132,13 @@ func (cs *ConfigScanner) Scan() (*models.ScanResult, error) {
 	result.Metadata["compliance_frameworks"] = cs.getComplianceFrameworks(complianceChecks)
 	result.Metadata["scan_duration"] = time.Since(startTime).Seconds()
 
+	result.Metadata["config_settings"] = cs.settingValues()
+	result.Metadata["unassessed_checks"] = cs.unassessed
+	result.Metadata["unassessed_count"] = len(cs.unassessed)
+	if cs.mdm != nil && len(cs.mdm.enforced) > 0 {
+		result.Metadata["mdm_enforced_controls"] = cs.mdm.enforced
+	}
+	result.Metadata["host_group"] = cs.config.HostGroup
 	result.Metadata["os"] = runtime.GOOS
 	result.Metadata["scan_type"] = "configuration"
 	result.Metadata["timestamp"] = time.Now().Format(time.RFC3339)
@@ -108,597 +147,770 @@ func (cs *ConfigScanner) Scan() (*models.ScanResult, error) {
 }
 
 // scanMacOS performs macOS-specific configuration scanning
-func (cs *ConfigScanner) scanMacOS() ([]models.Vulnerability, []models.Asset, []ComplianceCheck, error) {
+func (cs *ConfigScanner) scanMacOS(ctx context.Context) ([]models.Vulnerability, []models.Asset, []ComplianceCheck, error) {
 	var vulnerabilities []models.Vulnerability
 	var assets []models.Asset
 	var complianceChecks []ComplianceCheck
 
-	// Check system security settings
-	securityChecks := []struct {
-		name        string
-		description string
-		severity    string
-		check       func() (bool, string)
-	}{
+	// List the MDM profiles once, before the checks consulting them run at once
+	lister := cs.forCheck(ctx)
+	lister.installedProfiles()
+	cs.mdm = lister.mdm
+	cs.mdm.evidence = lister.takeEvidence()
+
+	vulnerabilities = cs.assessChecks(ctx, cs.macOSChecks(), "macOS")
+
+	// Create system asset
+	systemAsset := models.Asset{
+		ID:     "macos-system",
+		Name:   "macOS System",
+		Type:   "operating_system",
+		Status: "active",
+		Metadata: map[string]interface{}{
+			"os":           runtime.GOOS,
+			"version":      cs.getMacOSVersion(),
+			"architecture": runtime.GOARCH,
+		},
+	}
+	assets = append(assets, systemAsset)
+
+	// Perform compliance framework checks
+	complianceChecks = cs.performComplianceChecks()
+
+	return vulnerabilities, assets, complianceChecks, nil
+}
+
+// scanLinux performs Linux-specific configuration scanning
+func (cs *ConfigScanner) scanLinux(ctx context.Context) ([]models.Vulnerability, []models.Asset, []ComplianceCheck, error) {
+	var vulnerabilities []models.Vulnerability
+	var assets []models.Asset
+	var complianceChecks []ComplianceCheck
+
+	vulnerabilities = cs.assessChecks(ctx, cs.linuxChecks(), "Linux")
+
+	// Create system asset
+	systemAsset := models.Asset{
+		ID:     "linux-system",
+		Name:   "Linux System",
+		Type:   "operating_system",
+		Status: "active",
+		Metadata: map[string]interface{}{
+			"os":           runtime.GOOS,
+			"architecture": runtime.GOARCH,
+		},
+	}
+	assets = append(assets, systemAsset)
+
+	// Perform compliance framework checks
+	complianceChecks = cs.performComplianceChecks()
+
+	return vulnerabilities, assets, complianceChecks, nil
+}
+
+// scanWindows performs Windows-specific configuration scanning
+func (cs *ConfigScanner) scanWindows(ctx context.Context) ([]models.Vulnerability, []models.Asset, []ComplianceCheck, error) {
+	var vulnerabilities []models.Vulnerability
+	var assets []models.Asset
+	var complianceChecks []ComplianceCheck
+
+	vulnerabilities = cs.assessChecks(ctx, cs.windowsChecks(), "Windows")
+
+	// Create system asset
+	systemAsset := models.Asset{
+		ID:     "windows-system",
+		Name:   "Windows System",
+		Type:   "operating_system",
+		Status: "active",
+		Metadata: map[string]interface{}{
+			"os":           runtime.GOOS,
+			"architecture": runtime.GOARCH,
+		},
+	}
+	assets = append(assets, systemAsset)
+
+	// Perform compliance framework checks
+	complianceChecks = cs.performComplianceChecks()
+
+	return vulnerabilities, assets, complianceChecks, nil
+}
+
+// assessChecks runs an OS's security checks and raises a vulnerability for
+// each that fails. Checks that could not determine their setting are recorded
+// as unassessed instead.
+func (cs *ConfigScanner) assessChecks(ctx context.Context, checks []securityCheck, osName string) []models.Vulnerability {
+	var vulnerabilities []models.Vulnerability
+	for i, outcome := range cs.runChecks(ctx, checks) {
+		check, result := checks[i], outcome.result
+		if result.Errored {
+			cs.recordUnassessed(check.name, check.severity, osName, result)
+			continue
+		}
+		cs.observe(check.name, check.severity, result.Details)
+		if result.EnforcedBy != "" && cs.mdm != nil {
+			cs.mdm.enforced[check.name] = result.EnforcedBy
+		}
+		if !result.Passed {
+			vulnerability := models.Vulnerability{
+				ID:          uuid.New().String(),
+				Type:        "configuration",
+				Title:       check.name,
+				Description: check.description,
+				Severity:    check.severityOf(result),
+				Status:      "open",
+				EnrichmentData: map[string]interface{}{
+					"details":      result.Details,
+					"check_result": result.enrichment(),
+					"os":           osName,
+					"category":     "configuration",
+				},
+				Evidence:  outcome.evidence,
+				CreatedAt: time.Now(),
+			}
+			vulnerabilities = append(vulnerabilities, vulnerability)
+		}
+	}
+	return vulnerabilities
+}
+
+// macOSChecks are the macOS security checks
+func (cs *ConfigScanner) macOSChecks() []securityCheck {
+	return []securityCheck{
 		{
 			name:        "Gatekeeper Status",
 			description: "Check if Gatekeeper is enabled for malware protection",
 			severity:    "high",
-			check:       cs.checkGatekeeper,
+			check:       profileEnforced(mdmGatekeeper, (*ConfigScanner).checkGatekeeper),
 		},
 		{
 			name:        "System Integrity Protection",
 			description: "Check if System Integrity Protection (SIP) is enabled",
 			severity:    "critical",
-			check:       cs.checkSIP,
+			check:       (*ConfigScanner).checkSIP,
 		},
 		{
 			name:        "Firewall Status",
 			description: "Check if firewall is enabled",
 			severity:    "high",
-			check:       cs.checkFirewall,
+			check:       profileEnforced(mdmFirewall, (*ConfigScanner).checkFirewall),
 		},
 		{
 			name:        "Automatic Updates",
 			description: "Check if automatic security updates are enabled",
 			severity:    "medium",
-			check:       cs.checkAutoUpdates,
+			check:       (*ConfigScanner).checkAutoUpdates,
 		},
 		{
 			name:        "FileVault Encryption",
 			description: "Check if FileVault disk encryption is enabled",
 			severity:    "high",
-			check:       cs.checkFileVault,
+			check:       profileEnforced(mdmFileVault, (*ConfigScanner).checkFileVault),
 		},
 		{
 			name:        "Screen Lock",
 			description: "Check if screen lock is configured",
 			severity:    "medium",
-			check:       cs.checkScreenLock,
+			check:       profileEnforced(mdmScreenLock, (*ConfigScanner).checkScreenLock),
 		},
 		{
 			name:        "Remote Login (SSH)",
 			description: "Check if SSH remote login is disabled",
 			severity:    "high",
-			check:       cs.checkSSH,
+			check:       (*ConfigScanner).checkSSH,
 		},
 		{
 			name:        "Remote Management (ARD)",
 			description: "Check if Apple Remote Desktop is disabled",
 			severity:    "medium",
-			check:       cs.checkARD,
+			check:       (*ConfigScanner).checkARD,
 		},
 		{
 			name:        "Guest Account",
 			description: "Check if guest account is disabled",
 			severity:    "medium",
-			check:       cs.checkGuestAccount,
+			check:       (*ConfigScanner).checkGuestAccount,
 		},
 		{
 			name:        "Automatic Login",
 			description: "Check if automatic login is disabled",
 			severity:    "medium",
-			check:       cs.checkAutoLogin,
+			check:       (*ConfigScanner).checkAutoLogin,
 		},
 		{
 			name:        "Password Policy",
 			description: "Check if strong password policy is enforced",
 			severity:    "high",
-			check:       cs.checkPasswordPolicy,
+			check:       profileEnforced(mdmPasswordPolicy, (*ConfigScanner).checkPasswordPolicy),
 		},
 		{
 			name:        "Bluetooth Security",
 			description: "Check if Bluetooth is configured securely",
 			severity:    "low",
-			check:       cs.checkBluetoothSecurity,
+			check:       (*ConfigScanner).checkBluetoothSecurity,
 		},
 		{
 			name:        "Location Services",
 			description: "Check if location services are properly configured",
 			severity:    "low",
-			check:       cs.checkLocationServices,
+			check:       (*ConfigScanner).checkLocationServices,
 		},
 		{
 			name:        "System Time Sync",
 			description: "Check if system time is synchronized",
 			severity:    "medium",
-			check:       cs.checkTimeSync,
+			check:       (*ConfigScanner).checkTimeSync,
 		},
 		{
 			name:        "Secure Boot",
 			description: "Check if secure boot is enabled",
 			severity:    "high",
-			check:       cs.checkSecureBoot,
+			check:       (*ConfigScanner).checkSecureBoot,
 		},
 	}
-
-	for _, check := range securityChecks {
-		isSecure, details := check.check()
-		if !isSecure {
-			vulnerability := models.Vulnerability{
-				ID:          uuid.New().String(),
-				Type:        "configuration",
-				Title:       check.name,
-				Description: check.description,
-				Severity:    check.severity,
-				Status:      "open",
-				EnrichmentData: map[string]interface{}{
-					"details":  details,
-					"os":       "macOS",
-					"category": "configuration",
-				},
-				CreatedAt: time.Now(),
-			}
-			vulnerabilities = append(vulnerabilities, vulnerability)
-		}
-	}
-
-	// Create system asset
-	systemAsset := models.Asset{
-		ID:     "macos-system",
-		Name:   "macOS System",
-		Type:   "operating_system",
-		Status: "active",
-		Metadata: map[string]interface{}{
-			"os":           runtime.GOOS,
-			"version":      cs.getMacOSVersion(),
-			"architecture": runtime.GOARCH,
-		},
-	}
-	assets = append(assets, systemAsset)
-
-	// Perform compliance framework checks
-	complianceChecks = cs.performComplianceChecks()
-
-	return vulnerabilities, assets, complianceChecks, nil
 }
 
-// scanLinux performs Linux-specific configuration scanning
-func (cs *ConfigScanner) scanLinux() ([]models.Vulnerability, []models.Asset, []ComplianceCheck, error) {
-	var vulnerabilities []models.Vulnerability
-	var assets []models.Asset
-	var complianceChecks []ComplianceCheck
-
-	// Basic Linux security checks
-	securityChecks := []struct {
-		name        string
-		description string
-		severity    string
-		check       func() (bool, string)
-	}{
+// linuxChecks are the Linux security checks
+func (cs *ConfigScanner) linuxChecks() []securityCheck {
+	return []securityCheck{
 		{
 			name:        "SELinux Status",
 			description: "Check if SELinux is enabled and enforcing",
 			severity:    "high",
-			check:       cs.checkSELinux,
+			check:       (*ConfigScanner).checkSELinux,
 		},
 		{
 			name:        "AppArmor Status",
 			description: "Check if AppArmor is enabled",
 			severity:    "high",
-			check:       cs.checkAppArmor,
+			check:       (*ConfigScanner).checkAppArmor,
 		},
 		{
 			name:        "UFW Firewall",
 			description: "Check if UFW firewall is enabled",
 			severity:    "high",
-			check:       cs.checkUFW,
+			check:       (*ConfigScanner).checkUFW,
 		},
 		{
 			name:        "Automatic Updates",
 			description: "Check if automatic security updates are enabled",
 			severity:    "medium",
-			check:       cs.checkLinuxAutoUpdates,
-		},
-	}
-
-	for _, check := range securityChecks {
-		isSecure, details := check.check()
-		if !isSecure {
-			vulnerability := models.Vulnerability{
-				ID:          uuid.New().String(),
-				Type:        "configuration",
-				Title:       check.name,
-				Description: check.description,
-				Severity:    check.severity,
-				Status:      "open",
-				EnrichmentData: map[string]interface{}{
-					"details":  details,
-					"os":       "Linux",
-					"category": "configuration",
-				},
-				CreatedAt: time.Now(),
-			}
-			vulnerabilities = append(vulnerabilities, vulnerability)
-		}
-	}
-
-	// Create system asset
-	systemAsset := models.Asset{
-		ID:     "linux-system",
-		Name:   "Linux System",
-		Type:   "operating_system",
-		Status: "active",
-		Metadata: map[string]interface{}{
-			"os":           runtime.GOOS,
-			"architecture": runtime.GOARCH,
+			check:       (*ConfigScanner).checkLinuxAutoUpdates,
 		},
 	}
-	assets = append(assets, systemAsset)
-
-	// Perform compliance framework checks
-	complianceChecks = cs.performComplianceChecks()
-
-	return vulnerabilities, assets, complianceChecks, nil
 }
 
-// scanWindows performs Windows-specific configuration scanning
-func (cs *ConfigScanner) scanWindows() ([]models.Vulnerability, []models.Asset, []ComplianceCheck, error) {
-	var vulnerabilities []models.Vulnerability
-	var assets []models.Asset
-	var complianceChecks []ComplianceCheck
-
-	// Basic Windows security checks
-	securityChecks := []struct {
-		name        string
-		description string
-		severity    string
-		check       func() (bool, string)
-	}{
+// windowsChecks are the Windows security checks
+func (cs *ConfigScanner) windowsChecks() []securityCheck {
+	return []securityCheck{
 		{
 			name:        "Windows Defender",
 			description: "Check if Windows Defender is enabled",
 			severity:    "critical",
-			check:       cs.checkWindowsDefender,
+			check:       (*ConfigScanner).checkWindowsDefender,
 		},
 		{
 			name:        "Windows Firewall",
 			description: "Check if Windows Firewall is enabled",
 			severity:    "high",
-			check:       cs.checkWindowsFirewall,
+			check:       (*ConfigScanner).checkWindowsFirewall,
 		},
 		{
 			name:        "Automatic Updates",
 			description: "Check if Windows Update is configured",
 			severity:    "medium",
-			check:       cs.checkWindowsUpdates,
+			check:       (*ConfigScanner).checkWindowsUpdates,
 		},
 	}
-
-	for _, check := range securityChecks {
-		isSecure, details := check.check()
-		if !isSecure {
-			vulnerability := models.Vulnerability{
-				ID:          uuid.New().String(),
-				Type:        "configuration",
-				Title:       check.name,
-				Description: check.description,
-				Severity:    check.severity,
-				Status:      "open",
-				EnrichmentData: map[string]interface{}{
-					"details":  details,
-					"os":       "Windows",
-					"category": "configuration",
-				},
-				CreatedAt: time.Now(),
-			}
-			vulnerabilities = append(vulnerabilities, vulnerability)
-		}
-	}
-
-	// Create system asset
-	systemAsset := models.Asset{
-		ID:     "windows-system",
-		Name:   "Windows System",
-		Type:   "operating_system",
-		Status: "active",
-		Metadata: map[string]interface{}{
-			"os":           runtime.GOOS,
-			"architecture": runtime.GOARCH,
-		},
-	}
-	assets = append(assets, systemAsset)
-
-	// Perform compliance framework checks
-	complianceChecks = cs.performComplianceChecks()
-
-	return vulnerabilities, assets, complianceChecks, nil
 }
 
 // macOS Security Checks
 
-func (cs *ConfigScanner) checkGatekeeper() (bool, string) {
-	cmd := exec.Command("spctl", "--status")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkGatekeeper() CheckResult {
+	output, err := cs.command("spctl", "--status")
 	if err != nil {
-		return false, "Unable to check Gatekeeper status"
+		return cs.checkError(err, "Unable to check Gatekeeper status")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if strings.Contains(status, "enabled") {
-		return true, "Gatekeeper is enabled"
+		return cs.verdict(true, status, "assessments enabled", "Gatekeeper is enabled")
 	}
-	return false, "Gatekeeper is disabled - malware protection is reduced"
+	return cs.verdict(false, status, "assessments enabled", "Gatekeeper is disabled - malware protection is reduced")
 }
 
-func (cs *ConfigScanner) checkSIP() (bool, string) {
-	cmd := exec.Command("csrutil", "status")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkSIP() CheckResult {
+	output, err := cs.command("csrutil", "status")
 	if err != nil {
-		return false, "Unable to check SIP status"
+		return cs.checkError(err, "Unable to check SIP status")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if strings.Contains(status, "enabled") {
-		return true, "System Integrity Protection is enabled"
+		return cs.verdict(true, status, "enabled", "System Integrity Protection is enabled")
 	}
-	return false, "System Integrity Protection is disabled - system security is compromised"
+	return cs.verdict(false, status, "enabled", "System Integrity Protection is disabled - system security is compromised")
 }
 
-func (cs *ConfigScanner) checkFirewall() (bool, string) {
-	cmd := exec.Command("defaults", "read", "/Library/Preferences/com.apple.alf", "globalstate")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkFirewall() CheckResult {
+	output, err := cs.command("defaults", "read", "/Library/Preferences/com.apple.alf", "globalstate")
 	if err != nil {
-		return false, "Unable to check firewall status"
+		return cs.checkError(err, "Unable to check firewall status")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if status == "1" {
-		return true, "Firewall is enabled"
+		return cs.verdict(true, status, "1", "Firewall is enabled")
 	}
-	return false, "Firewall is disabled - network security is reduced"
+	return cs.verdict(false, status, "1", "Firewall is disabled - network security is reduced")
 }
 
-func (cs *ConfigScanner) checkAutoUpdates() (bool, string) {
-	cmd := exec.Command("defaults", "read", "/Library/Preferences/com.apple.SoftwareUpdate", "AutomaticCheckEnabled")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkAutoUpdates() CheckResult {
+	output, err := cs.command("defaults", "read", "/Library/Preferences/com.apple.SoftwareUpdate", "AutomaticCheckEnabled")
 	if err != nil {
-		return false, "Unable to check auto-update status"
+		return cs.checkError(err, "Unable to check auto-update status")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if status == "1" {
-		return true, "Automatic updates are enabled"
+		return cs.verdict(true, status, "1", "Automatic updates are enabled")
 	}
-	return false, "Automatic updates are disabled - system may be vulnerable to known exploits"
+	return cs.verdict(false, status, "1", "Automatic updates are disabled - system may be vulnerable to known exploits")
 }
 
-func (cs *ConfigScanner) checkFileVault() (bool, string) {
-	cmd := exec.Command("fdesetup", "status")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkFileVault() CheckResult {
+	output, err := cs.command("fdesetup", "status")
 	if err != nil {
-		return false, "Unable to check FileVault status"
+		return cs.checkError(err, "Unable to check FileVault status")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if strings.Contains(status, "FileVault is On") {
-		return true, "FileVault encryption is enabled"
+		return cs.verdict(true, status, "FileVault is On", "FileVault encryption is enabled")
 	}
-	return false, "FileVault encryption is disabled - disk data is not encrypted"
+	return cs.verdict(false, status, "FileVault is On", "FileVault encryption is disabled - disk data is not encrypted")
 }
 
-func (cs *ConfigScanner) checkScreenLock() (bool, string) {
-	cmd := exec.Command("defaults", "read", "com.apple.screensaver", "askForPassword")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkScreenLock() CheckResult {
+	output, err := cs.command("defaults", "read", "com.apple.screensaver", "askForPassword")
 	if err != nil {
-		return false, "Unable to check screen lock status"
+		return cs.checkError(err, "Unable to check screen lock status")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if status == "1" {
-		return true, "Screen lock is configured"
+		return cs.verdict(true, status, "1", "Screen lock is configured")
 	}
-	return false, "Screen lock is not configured - physical access is not protected"
+	return cs.verdict(false, status, "1", "Screen lock is not configured - physical access is not protected")
 }
 
 // Additional macOS Security Checks
 
-func (cs *ConfigScanner) checkSSH() (bool, string) {
-	cmd := exec.Command("systemsetup", "-getremotelogin")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkSSH() CheckResult {
+	output, err := cs.command("systemsetup", "-getremotelogin")
 	if err != nil {
-		return false, "Unable to check SSH status"
+		return cs.checkError(err, "Unable to check SSH status")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if strings.Contains(status, "Remote Login: Off") {
-		return true, "SSH remote login is disabled"
+		return cs.verdict(true, status, "Remote Login: Off", "SSH remote login is disabled")
 	}
-	return false, "SSH remote login is enabled - potential security risk"
+	return cs.verdict(false, status, "Remote Login: Off", "SSH remote login is enabled - potential security risk")
 }
 
-func (cs *ConfigScanner) checkARD() (bool, string) {
-	cmd := exec.Command("launchctl", "list", "com.apple.RemoteDesktop")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkARD() CheckResult {
+	output, err := cs.command("launchctl", "list", "com.apple.RemoteDesktop")
 	if err != nil {
-		return true, "Apple Remote Desktop is not running"
+		// launchctl fails for services that are not loaded
+		return cs.verdict(true, string(output), "not loaded", "Apple Remote Desktop is not running").withConfidence(0.8)
 	}
 
 	if strings.Contains(string(output), "com.apple.RemoteDesktop") {
-		return false, "Apple Remote Desktop is enabled - potential security risk"
+		return cs.verdict(false, string(output), "not loaded", "Apple Remote Desktop is enabled - potential security risk")
 	}
-	return true, "Apple Remote Desktop is disabled"
+	return cs.verdict(true, string(output), "not loaded", "Apple Remote Desktop is disabled")
 }
 
-func (cs *ConfigScanner) checkGuestAccount() (bool, string) {
-	cmd := exec.Command("dscl", ".", "-read", "/Users/Guest", "AuthenticationAuthority")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkGuestAccount() CheckResult {
+	output, err := cs.command("dscl", ".", "-read", "/Users/Guest", "AuthenticationAuthority")
 	if err != nil {
-		return true, "Guest account is disabled"
+		// dscl fails for a missing user or attribute, but also for other reasons
+		return cs.verdict(true, string(output), "no AuthenticationAuthority", "Guest account is disabled").withConfidence(0.7)
 	}
 
 	if strings.Contains(string(output), "No such key") {
-		return true, "Guest account is disabled"
+		return cs.verdict(true, string(output), "no AuthenticationAuthority", "Guest account is disabled")
 	}
-	return false, "Guest account is enabled - potential security risk"
+	return cs.verdict(false, string(output), "no AuthenticationAuthority", "Guest account is enabled - potential security risk")
 }
 
-func (cs *ConfigScanner) checkAutoLogin() (bool, string) {
-	cmd := exec.Command("defaults", "read", "/Library/Preferences/com.apple.loginwindow", "autoLoginUser")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkAutoLogin() CheckResult {
+	output, err := cs.command("defaults", "read", "/Library/Preferences/com.apple.loginwindow", "autoLoginUser")
 	if err != nil {
-		return true, "Automatic login is disabled"
+		// defaults fails when the key is not set
+		return cs.verdict(true, "", "unset", "Automatic login is disabled").withConfidence(0.9)
 	}
 
 	status := strings.TrimSpace(string(output))
 	if status == "" || status == "0" {
-		return true, "Automatic login is disabled"
+		return cs.verdict(true, status, "unset", "Automatic login is disabled")
 	}
-	return false, "Automatic login is enabled - potential security risk"
+	return cs.verdict(false, status, "unset", "Automatic login is enabled - potential security risk")
 }
 
-func (cs *ConfigScanner) checkPasswordPolicy() (bool, string) {
-	cmd := exec.Command("pwpolicy", "-getaccountpolicies")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkPasswordPolicy() CheckResult {
+	output, err := cs.command("pwpolicy", "-getaccountpolicies")
 	if err != nil {
-		return false, "Unable to check password policy"
+		return cs.checkError(err, "Unable to check password policy")
 	}
 
-	// Check for common password policy requirements
+	// Check for common password policy requirements. The policy is a plist
+	// searched for key names rather than parsed, so the result is a heuristic.
 	policy := string(output)
 	hasMinLength := strings.Contains(policy, "minChars")
 	hasComplexity := strings.Contains(policy, "requireMixedCase") || strings.Contains(policy, "requireNumeric")
 
+	observed := fmt.Sprintf("minChars=%t complexity=%t", hasMinLength, hasComplexity)
 	if hasMinLength && hasComplexity {
-		return true, "Strong password policy is enforced"
+		return cs.verdict(true, observed, "minChars=true complexity=true", "Strong password policy is enforced").withConfidence(0.8)
 	}
-	return false, "Weak or no password policy - security risk"
+	return cs.verdict(false, observed, "minChars=true complexity=true", "Weak or no password policy - security risk").withConfidence(0.8)
 }
 
-func (cs *ConfigScanner) checkBluetoothSecurity() (bool, string) {
-	cmd := exec.Command("defaults", "read", "/Library/Preferences/com.apple.Bluetooth", "ControllerPowerState")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkBluetoothSecurity() CheckResult {
+	output, err := cs.command("defaults", "read", "/Library/Preferences/com.apple.Bluetooth", "ControllerPowerState")
 	if err != nil {
-		return false, "Unable to check Bluetooth status"
+		return cs.checkError(err, "Unable to check Bluetooth status")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if status == "0" {
-		return true, "Bluetooth is disabled - most secure"
+		return cs.verdict(true, status, "0", "Bluetooth is disabled - most secure")
 	}
-	return false, "Bluetooth is enabled - ensure discoverable mode is off"
+	// Bluetooth being on is only a risk if the host is discoverable, which this does not check
+	return cs.verdict(false, status, "0", "Bluetooth is enabled - ensure discoverable mode is off").withConfidence(0.5)
 }
 
-func (cs *ConfigScanner) checkLocationServices() (bool, string) {
-	cmd := exec.Command("defaults", "read", "/var/db/locationd/Library/Preferences/ByHost/com.apple.locationd", "LocationServicesEnabled")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkLocationServices() CheckResult {
+	output, err := cs.command("defaults", "read", "/var/db/locationd/Library/Preferences/ByHost/com.apple.locationd", "LocationServicesEnabled")
 	if err != nil {
-		return false, "Unable to check location services"
+		return cs.checkError(err, "Unable to check location services")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if status == "0" {
-		return true, "Location services are disabled - privacy protected"
+		return cs.verdict(true, status, "0", "Location services are disabled - privacy protected")
 	}
-	return false, "Location services are enabled - privacy consideration"
+	return cs.verdict(false, status, "0", "Location services are enabled - privacy consideration")
 }
 
-func (cs *ConfigScanner) checkTimeSync() (bool, string) {
-	cmd := exec.Command("sntp", "-sS", "time.apple.com")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkTimeSync() CheckResult {
+	output, err := cs.command("sntp", "-sS", "time.apple.com")
 	if err != nil {
-		return false, "Unable to check time synchronization"
+		return cs.checkError(err, "Unable to check time synchronization")
 	}
 
 	if strings.Contains(string(output), "synchronized") {
-		return true, "System time is synchronized"
+		return cs.verdict(true, string(output), "synchronized", "System time is synchronized")
 	}
-	return false, "System time may not be synchronized - security risk"
+	return cs.verdict(false, string(output), "synchronized", "System time may not be synchronized - security risk").withConfidence(0.6)
 }
 
-func (cs *ConfigScanner) checkSecureBoot() (bool, string) {
-	cmd := exec.Command("bputil", "-d")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkSecureBoot() CheckResult {
+	output, err := cs.command("bputil", "-d")
 	if err != nil {
-		return false, "Unable to check secure boot status"
+		return cs.checkError(err, "Unable to check secure boot status")
 	}
 
 	if strings.Contains(string(output), "Secure Boot: Full Security") {
-		return true, "Secure boot is enabled with full security"
+		return cs.verdict(true, string(output), "Secure Boot: Full Security", "Secure boot is enabled with full security")
 	}
 	if strings.Contains(string(output), "Secure Boot: Medium Security") {
-		return false, "Secure boot is enabled with medium security - consider full security"
+		return cs.verdict(false, string(output), "Secure Boot: Full Security", "Secure boot is enabled with medium security - consider full security")
 	}
-	return false, "Secure boot is disabled or not available - security risk"
+	return cs.verdict(false, string(output), "Secure Boot: Full Security", "Secure boot is disabled or not available - security risk")
 }
 
 // Linux Security Checks
 
-func (cs *ConfigScanner) checkSELinux() (bool, string) {
-	cmd := exec.Command("getenforce")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkSELinux() CheckResult {
+	output, err := cs.command("getenforce")
 	if err != nil {
-		return false, "SELinux not available or not installed"
+		return cs.checkError(err, "SELinux not available or not installed")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if status == "Enforcing" {
-		return true, "SELinux is enforcing"
+		return cs.verdict(true, status, "Enforcing", "SELinux is enforcing")
 	}
-	return false, fmt.Sprintf("SELinux is %s - mandatory access control is not enforced", status)
+	return cs.verdict(false, status, "Enforcing", fmt.Sprintf("SELinux is %s - mandatory access control is not enforced", status))
 }
 
-func (cs *ConfigScanner) checkAppArmor() (bool, string) {
-	cmd := exec.Command("aa-status")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkAppArmor() CheckResult {
+	output, err := cs.command("aa-status")
 	if err != nil {
-		return false, "AppArmor not available or not installed"
+		return cs.checkError(err, "AppArmor not available or not installed")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if strings.Contains(status, "enforce") {
-		return true, "AppArmor is enforcing"
+		return cs.verdict(true, status, "profiles in enforce mode", "AppArmor is enforcing")
 	}
-	return false, "AppArmor is not enforcing - mandatory access control is not active"
+	return cs.verdict(false, status, "profiles in enforce mode", "AppArmor is not enforcing - mandatory access control is not active")
 }
 
-func (cs *ConfigScanner) checkUFW() (bool, string) {
-	cmd := exec.Command("ufw", "status")
-	output, err := cmd.Output()
+func (cs *ConfigScanner) checkUFW() CheckResult {
+	output, err := cs.command("ufw", "status")
 	if err != nil {
-		return false, "UFW not available or not installed"
+		return cs.checkError(err, "UFW not available or not installed")
 	}
 
 	status := strings.TrimSpace(string(output))
 	if strings.Contains(status, "Status: active") {
-		return true, "UFW firewall is active"
+		return cs.verdict(true, status, "Status: active", "UFW firewall is active")
 	}
-	return false, "UFW firewall is not active - network security is reduced"
+	return cs.verdict(false, status, "Status: active", "UFW firewall is not active - network security is reduced")
 }
 
-func (cs *ConfigScanner) checkLinuxAutoUpdates() (bool, string) {
-	// Check for unattended-upgrades
-	cmd := exec.Command("systemctl", "is-enabled", "unattended-upgrades")
-	output, err := cmd.Output()
-	if err != nil {
-		return false, "Automatic updates not configured"
+func (cs *ConfigScanner) checkLinuxAutoUpdates() CheckResult {
+	// Check for unattended-upgrades. systemctl exits non-zero for disabled
+	// units too, so only a unit it cannot report on is an error.
+	output, err := cs.command("systemctl", "is-enabled", "unattended-upgrades")
+	status := strings.TrimSpace(string(output))
+	if err != nil && status == "" {
+		return cs.checkError(err, "Automatic updates not configured")
 	}
 
-	status := strings.TrimSpace(string(output))
 	if status == "enabled" {
-		return true, "Automatic updates are enabled"
+		return cs.verdict(true, status, "enabled", "Automatic updates are enabled")
 	}
-	return false, "Automatic updates are disabled - system may be vulnerable to known exploits"
+	return cs.verdict(false, status, "enabled", "Automatic updates are disabled - system may be vulnerable to known exploits")
 }
 
 // Windows Security Checks
 
-func (cs *ConfigScanner) checkWindowsDefender() (bool, string) {
-	// This would require Windows-specific implementation
-	// For now, return a placeholder
-	return false, "Windows Defender status check not implemented"
+// powershell runs a PowerShell command and returns its output. Execution
+// policy does not apply to -Command, but Group Policy can still block
+// PowerShell or restrict it to constrained language; see powershellBlocked.
+func (cs *ConfigScanner) powershell(command string) ([]byte, error) {
+	return cs.command("powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-Command", command)
 }
 
-func (cs *ConfigScanner) checkWindowsFirewall() (bool, string) {
-	// This would require Windows-specific implementation
-	// For now, return a placeholder
-	return false, "Windows Firewall status check not implemented"
+// powershellBlocked reports whether a PowerShell command failed because
+// policy does not allow it to run, rather than because of what it queried
+func powershellBlocked(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	stderr := strings.ToLower(string(exitErr.Stderr))
+	for _, marker := range []string{"execution policy", "running scripts is disabled", "not digitally signed", "constrainedlanguage", "language mode"} {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
 }
 
-func (cs *ConfigScanner) checkWindowsUpdates() (bool, string) {
-	// This would require Windows-specific implementation
-	// For now, return a placeholder
-	return false, "Windows Update status check not implemented"
+// powershellError builds the result of a Windows check whose PowerShell
+// command failed, telling a policy block apart from other failures
+func (cs *ConfigScanner) powershellError(err error, subject string) CheckResult {
+	if powershellBlocked(err) {
+		return cs.checkError(err, fmt.Sprintf("PowerShell is blocked by policy on this host; unable to check %s", subject))
+	}
+	return cs.checkError(err, fmt.Sprintf("Unable to check %s", subject))
+}
+
+// defenderStatus is the part of Get-MpComputerStatus the Defender check reads
+type defenderStatus struct {
+	AMServiceEnabled          bool   `json:"AMServiceEnabled"`
+	AntivirusEnabled          bool   `json:"AntivirusEnabled"`
+	RealTimeProtectionEnabled bool   `json:"RealTimeProtectionEnabled"`
+	AMRunningMode             string `json:"AMRunningMode"` // Normal, Passive Mode, SxS Passive Mode or EDR Block Mode
+}
+
+// protecting reports whether Defender is actively protecting the host
+func (d defenderStatus) protecting() bool {
+	running := d.AMRunningMode == "" || strings.EqualFold(d.AMRunningMode, "Normal")
+	return d.AMServiceEnabled && d.AntivirusEnabled && d.RealTimeProtectionEnabled && running
+}
+
+// antivirusProduct is an antivirus registered with Windows Security Center
+type antivirusProduct struct {
+	DisplayName  string `json:"displayName"`
+	ProductState int    `json:"productState"`
+}
+
+// enabled decodes the product's real-time protection state from its Security
+// Center product state, whose second byte is 0x10 or 0x11 while it is on
+func (p antivirusProduct) enabled() bool {
+	return (p.ProductState>>12)&0x1 == 1
+}
+
+// thirdParty reports whether the product is another vendor's than Microsoft's
+func (p antivirusProduct) thirdParty() bool {
+	name := strings.ToLower(p.DisplayName)
+	return !strings.Contains(name, "windows defender") && !strings.Contains(name, "microsoft defender")
+}
+
+func (cs *ConfigScanner) checkWindowsDefender() CheckResult {
+	output, err := cs.powershell("Get-MpComputerStatus | Select-Object AMServiceEnabled,AntivirusEnabled,RealTimeProtectionEnabled,AMRunningMode | ConvertTo-Json")
+	if powershellBlocked(err) {
+		return cs.powershellError(err, "Windows Defender status")
+	}
+	var status defenderStatus
+	if err == nil {
+		if err := json.Unmarshal(output, &status); err != nil {
+			return cs.checkError(err, "Unable to parse Windows Defender status")
+		}
+		if status.protecting() {
+			return cs.verdict(true, string(output), "real-time protection enabled", "Windows Defender real-time protection is enabled")
+		}
+	}
+	// Get-MpComputerStatus also fails when Defender was removed or its
+	// service stopped for another antivirus, so ask Security Center which
+	// antivirus protects the host
+	defender := "Windows Defender is disabled"
+	if err != nil {
+		defender = "Windows Defender is not running"
+	}
+
+	products, avErr := cs.antivirusProducts()
+	if avErr != nil {
+		if err != nil {
+			return cs.powershellError(err, "Windows Defender status")
+		}
+		return cs.verdict(false, string(output), "real-time protection enabled", defender+" - host has no real-time malware protection")
+	}
+	var replacements []antivirusProduct
+	for _, product := range products {
+		if product.thirdParty() {
+			replacements = append(replacements, product)
+		}
+	}
+	observed := fmt.Sprintf("%s; Security Center: %s", defender, describeAntivirus(products))
+	for _, product := range replacements {
+		if product.enabled() {
+			return cs.verdict(true, observed, "real-time protection enabled", fmt.Sprintf("%s; %s provides real-time protection", defender, product.DisplayName))
+		}
+	}
+	if len(replacements) > 0 {
+		// Replaced rather than missing; the third-party antivirus owns its own state
+		return cs.verdict(false, observed, "real-time protection enabled",
+			fmt.Sprintf("%s and %s is installed but reports protection off", defender, replacements[0].DisplayName)).withSeverity("info")
+	}
+	return cs.verdict(false, observed, "real-time protection enabled", defender+" - host has no real-time malware protection")
+}
+
+// antivirusProducts lists the antivirus products registered with Windows
+// Security Center, which Windows Server does not have
+func (cs *ConfigScanner) antivirusProducts() ([]antivirusProduct, error) {
+	output, err := cs.powershell("@(Get-CimInstance -Namespace root/SecurityCenter2 -ClassName AntiVirusProduct | Select-Object displayName,productState) | ConvertTo-Json")
+	if err != nil {
+		return nil, err
+	}
+	return parseJSONList[antivirusProduct](output)
+}
+
+// describeAntivirus summarizes antivirus products for a check result
+func describeAntivirus(products []antivirusProduct) string {
+	if len(products) == 0 {
+		return "no antivirus registered"
+	}
+	descriptions := make([]string, 0, len(products))
+	for _, product := range products {
+		state := "off"
+		if product.enabled() {
+			state = "on"
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", product.DisplayName, state))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// firewallProfile is a Windows Firewall profile's state from Get-NetFirewallProfile
+type firewallProfile struct {
+	Name    string          `json:"Name"`
+	Enabled json.RawMessage `json:"Enabled"` // true, or 1, depending on the PowerShell version
+}
+
+// enabled reports whether the profile is on
+func (p firewallProfile) enabled() bool {
+	value := strings.TrimSpace(string(p.Enabled))
+	return value == "true" || value == "1" || strings.EqualFold(value, `"True"`)
+}
+
+func (cs *ConfigScanner) checkWindowsFirewall() CheckResult {
+	output, err := cs.powershell("@(Get-NetFirewallProfile | Select-Object Name,Enabled) | ConvertTo-Json")
+	if err != nil {
+		return cs.powershellError(err, "Windows Firewall status")
+	}
+	profiles, err := parseJSONList[firewallProfile](output)
+	if err != nil || len(profiles) == 0 {
+		return cs.checkError(err, "Unable to parse Windows Firewall profiles")
+	}
+
+	var disabled []string
+	for _, profile := range profiles {
+		if !profile.enabled() {
+			disabled = append(disabled, profile.Name)
+		}
+	}
+	if len(disabled) == 0 {
+		return cs.verdict(true, string(output), "all profiles enabled", "Windows Firewall is enabled for all profiles")
+	}
+	return cs.verdict(false, string(output), "all profiles enabled",
+		fmt.Sprintf("Windows Firewall is disabled for the %s profile(s) - network security is reduced", strings.Join(disabled, ", ")))
+}
+
+// windowsUpdateSettings is the Windows Update Agent's automatic update state
+type windowsUpdateSettings struct {
+	ServiceEnabled    bool `json:"ServiceEnabled"`
+	NotificationLevel int  `json:"NotificationLevel"` // 0 not configured, 1 disabled, 2 notify before download, 3 notify before install, 4 scheduled install
+}
+
+func (cs *ConfigScanner) checkWindowsUpdates() CheckResult {
+	output, err := cs.powershell("$au = New-Object -ComObject Microsoft.Update.AutoUpdate; " +
+		"[pscustomobject]@{ServiceEnabled = $au.ServiceEnabled; NotificationLevel = $au.Settings.NotificationLevel} | ConvertTo-Json")
+	if err != nil {
+		return cs.powershellError(err, "Windows Update settings")
+	}
+	var settings windowsUpdateSettings
+	if err := json.Unmarshal(output, &settings); err != nil {
+		return cs.checkError(err, "Unable to parse Windows Update settings")
+	}
+
+	observed := fmt.Sprintf("ServiceEnabled=%t NotificationLevel=%d", settings.ServiceEnabled, settings.NotificationLevel)
+	switch {
+	case !settings.ServiceEnabled || settings.NotificationLevel == 1:
+		return cs.verdict(false, observed, "NotificationLevel=4", "Automatic updates are disabled - system may be vulnerable to known exploits")
+	case settings.NotificationLevel == 0:
+		// Not configured by policy: current Windows then installs updates
+		// automatically, older versions may not
+		return cs.verdict(true, observed, "NotificationLevel=4", "Automatic updates are not configured by policy and use the Windows default").withConfidence(0.7)
+	case settings.NotificationLevel == 2:
+		return cs.verdict(false, observed, "NotificationLevel=4", "Updates are only announced, not downloaded or installed automatically")
+	default:
+		return cs.verdict(true, observed, "NotificationLevel=4", "Automatic updates are enabled")
+	}
+}
+
+// parseJSONList decodes ConvertTo-Json output, which is a single object
+// rather than an array when there is one item
+func parseJSONList[T any](output []byte) ([]T, error) {
+	trimmed := bytes.TrimSpace(output)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if trimmed[0] == '[' {
+		var items []T
+		err := json.Unmarshal(trimmed, &items)
+		return items, err
+	}
+	var item T
+	if err := json.Unmarshal(trimmed, &item); err != nil {
+		return nil, err
+	}
+	return []T{item}, nil
 }
 
 // Utility functions
@@ -734,7 +946,6 @@ func (cs *ConfigScanner) generateTestVulnerabilities() []models.Vulnerability {
 			Title:            "Outdated System Components",
 			Description:      "System contains outdated components with known security vulnerabilities",
 			Status:           "open",
-			Priority:         "urgent",
 			ExploitAvailable: true,
 			ExploitCount:     3,
 			CreatedAt:        now.Add(-24 * time.Hour),
@@ -752,7 +963,6 @@ func (cs *ConfigScanner) generateTestVulnerabilities() []models.Vulnerability {
 			Title:            "Vulnerable Browser Extensions",
 			Description:      "Browser extensions with known security vulnerabilities detected",
 			Status:           "open",
-			Priority:         "high",
 			ExploitAvailable: true,
 			ExploitCount:     1,
 			CreatedAt:        now.Add(-12 * time.Hour),
@@ -770,7 +980,6 @@ func (cs *ConfigScanner) generateTestVulnerabilities() []models.Vulnerability {
 			Title:            "Weak Password Policy",
 			Description:      "System password policy does not meet security requirements",
 			Status:           "open",
-			Priority:         "medium",
 			ExploitAvailable: false,
 			ExploitCount:     0,
 			CreatedAt:        now.Add(-6 * time.Hour),
@@ -788,7 +997,6 @@ func (cs *ConfigScanner) generateTestVulnerabilities() []models.Vulnerability {
 			Title:            "Unpatched Development Tools",
 			Description:      "Development tools contain unpatched security vulnerabilities",
 			Status:           "open",
-			Priority:         "high",
 			ExploitAvailable: true,
 			ExploitCount:     2,
 			CreatedAt:        now.Add(-3 * time.Hour),
@@ -806,7 +1014,6 @@ func (cs *ConfigScanner) generateTestVulnerabilities() []models.Vulnerability {
 			Title:            "Verbose Logging Enabled",
 			Description:      "System logging is set to verbose mode, potentially exposing sensitive information",
 			Status:           "open",
-			Priority:         "low",
 			ExploitAvailable: false,
 			ExploitCount:     0,
 			CreatedAt:        now.Add(-1 * time.Hour),
@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"path"
+	"strings"
+)
+
+// ContainerAllowlistRule marks container findings as expected for the
+// organization, such as a web server exposing 443. A finding matches when
+// every matcher the rule sets matches it; image and container name are glob
+// patterns, and an image pattern without a tag matches every tag.
+type ContainerAllowlistRule struct {
+	ID            string `json:"id"`
+	Image         string `json:"image,omitempty"`
+	ContainerName string `json:"container_name,omitempty"`
+	FindingType   string `json:"finding_type,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Reason        string `json:"reason"`
+}
+
+// SetAllowlist sets the rules subsequent scans suppress findings with. A nil
+// allowlist suppresses nothing.
+func (cs *ContainerScanner) SetAllowlist(rules []ContainerAllowlistRule) {
+	cs.allowlist = rules
+}
+
+// applyAllowlist marks the findings of one container that an allowlist rule
+// matches as suppressed by that rule. Kubernetes findings have no container,
+// and are matched on their pod name instead.
+func (cs *ContainerScanner) applyAllowlist(findings []ContainerFinding, containerName string) {
+	for i := range findings {
+		finding := &findings[i]
+		name := strings.TrimPrefix(containerName, "/")
+		if name == "" {
+			name = finding.PodName
+		}
+		for _, rule := range cs.allowlist {
+			if !rule.matches(finding, name) {
+				continue
+			}
+			finding.Suppressed = true
+			finding.SuppressedBy = rule.ID
+			if finding.Metadata == nil {
+				finding.Metadata = make(map[string]interface{})
+			}
+			finding.Metadata["suppressed_reason"] = rule.Reason
+			break
+		}
+	}
+}
+
+func (r ContainerAllowlistRule) matches(finding *ContainerFinding, containerName string) bool {
+	if r.Image == "" && r.ContainerName == "" && r.FindingType == "" && r.Title == "" {
+		return false
+	}
+	if r.Image != "" && !matchImage(r.Image, finding.ImageName) {
+		return false
+	}
+	if r.ContainerName != "" && !globMatch(r.ContainerName, containerName) {
+		return false
+	}
+	if r.FindingType != "" && !strings.EqualFold(r.FindingType, finding.Type) {
+		return false
+	}
+	if r.Title != "" && !strings.EqualFold(r.Title, finding.Title) {
+		return false
+	}
+	return true
+}
+
+// matchImage matches an image reference against a pattern, ignoring the
+// image's tag or digest when the pattern has none
+func matchImage(pattern, image string) bool {
+	if globMatch(pattern, image) {
+		return true
+	}
+	if strings.ContainsAny(pattern, ":@") {
+		return false
+	}
+	repository, _, _ := strings.Cut(image, "@")
+	if slash := strings.LastIndex(repository, "/"); strings.LastIndex(repository, ":") > slash {
+		repository = repository[:strings.LastIndex(repository, ":")]
+	}
+	return globMatch(pattern, repository)
+}
+
+func globMatch(pattern, value string) bool {
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
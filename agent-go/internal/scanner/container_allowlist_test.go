@@ -0,0 +1,40 @@
+package scanner
+
+import "testing"
+
+func TestContainerAllowlistSuppressesMatchingFindings(t *testing.T) {
+	cs := NewContainerScanner(setupTestConfig())
+	cs.SetAllowlist([]ContainerAllowlistRule{
+		{ID: "web-ports", Image: "nginx", FindingType: "network", Reason: "Web servers expose 443"},
+		{ID: "vault-secrets", ContainerName: "vault-*", Title: "Container Has Secrets", Reason: "Vault agent mounts secrets"},
+	})
+
+	findings := []ContainerFinding{
+		{Type: "network", Title: "Exposed Container Ports", ImageName: "registry.local:5000/nginx:1.25"},
+		{Type: "network", Title: "Exposed Container Ports", ImageName: "redis:7"},
+		{Type: "config", Title: "Privileged Container", ImageName: "nginx:1.25"},
+	}
+	cs.applyAllowlist(findings, "/web-1")
+	vault := []ContainerFinding{{Type: "config", Title: "Container Has Secrets", ImageName: "hashicorp/vault:1.15"}}
+	cs.applyAllowlist(vault, "/vault-agent")
+
+	// The first rule's image has no registry, so only the Docker Hub image matches
+	if findings[0].Suppressed {
+		t.Errorf("finding for another registry's nginx suppressed by %q", findings[0].SuppressedBy)
+	}
+	if findings[1].Suppressed || findings[2].Suppressed {
+		t.Errorf("non-matching findings suppressed: %+v", findings[1:])
+	}
+	if !vault[0].Suppressed || vault[0].SuppressedBy != "vault-secrets" {
+		t.Fatalf("secret mount finding: suppressed=%v by %q, want vault-secrets", vault[0].Suppressed, vault[0].SuppressedBy)
+	}
+	if vault[0].Metadata["suppressed_reason"] != "Vault agent mounts secrets" {
+		t.Errorf("suppressed_reason = %v", vault[0].Metadata["suppressed_reason"])
+	}
+
+	hub := []ContainerFinding{{Type: "network", Title: "Exposed Container Ports", ImageName: "nginx:1.25"}}
+	cs.applyAllowlist(hub, "/web-1")
+	if hub[0].SuppressedBy != "web-ports" {
+		t.Errorf("nginx:1.25 SuppressedBy = %q, want web-ports", hub[0].SuppressedBy)
+	}
+}
@@ -0,0 +1,395 @@
+package scanner
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/agent/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Package databases read from an image: dpkg's status file, the per-package
+// status files distroless images have instead, and Alpine's apk database
+const (
+	dpkgStatusPath    = "/var/lib/dpkg/status"
+	dpkgStatusDirPath = "/var/lib/dpkg/status.d"
+	apkInstalledPath  = "/lib/apk/db/installed"
+)
+
+var imagePackageDatabases = []string{dpkgStatusPath, dpkgStatusDirPath, apkInstalledPath}
+
+const (
+	maxImagePackageDBSize = 64 * 1024 * 1024 // Most bytes of package database read from one image
+
+	// NVD allows 5 requests per 30 seconds without an API key, and 50 with one
+	nvdLookupInterval        = 6 * time.Second
+	nvdLookupIntervalWithKey = 600 * time.Millisecond
+)
+
+var errNoPackageDatabase = errors.New("no dpkg or apk package database found")
+
+// severityRank orders severities, so an image finding takes its worst CVE's
+var severityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// imagePackage is an OS package installed in a container image
+type imagePackage struct {
+	Name    string // Binary package, such as libssl3
+	Source  string // Source package CVEs are filed against, such as openssl
+	Version string // Distribution version, such as 3.0.11-1~deb12u2
+}
+
+// imageVulnerability is a source package in an image and the CVEs matched to it
+type imageVulnerability struct {
+	Source   string
+	Version  string
+	Packages []string // Binary packages built from the source package
+	CVEs     []models.Vulnerability
+}
+
+// packageVulnerabilitySource finds the CVEs affecting a version of a product
+type packageVulnerabilitySource interface {
+	PackageCVEs(product, version string) ([]models.Vulnerability, error)
+}
+
+// cachedVulnerabilitySource is a source that can answer lookups from a cache
+// without a request
+type cachedVulnerabilitySource interface {
+	cachedPackageCVEs(product, version string) ([]models.Vulnerability, bool)
+}
+
+// imageScanner matches the OS packages installed in container images against
+// a vulnerability source. Results are cached by image ID, and lookups by
+// package version across images, for as long as the agent runs.
+type imageScanner struct {
+	source    packageVulnerabilitySource
+	readFiles func(runtime, image string, paths []string) (map[string][][]byte, error)
+	interval  time.Duration // Least time between lookups, for the source's rate limit
+
+	mu         sync.Mutex // Held for a whole image, so lookups stay within the rate limit
+	images     map[string][]imageVulnerability
+	lookups    map[string][]models.Vulnerability // By source package and upstream version
+	lastLookup time.Time
+}
+
+func newImageScanner(source packageVulnerabilitySource, interval time.Duration) *imageScanner {
+	return &imageScanner{
+		source:    source,
+		readFiles: readImageFiles,
+		interval:  interval,
+		images:    make(map[string][]imageVulnerability),
+		lookups:   make(map[string][]models.Vulnerability),
+	}
+}
+
+// scan returns the vulnerable source packages installed in an image. A scan
+// that fails part way is not cached, and is retried on the next scan.
+func (s *imageScanner) scan(runtime, image string) ([]imageVulnerability, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.images[image]; ok {
+		return cached, nil
+	}
+
+	files, err := s.readFiles(runtime, image, imagePackageDatabases)
+	if err != nil {
+		return nil, err
+	}
+	var packages []imagePackage
+	for _, path := range imagePackageDatabases {
+		for _, data := range files[path] {
+			if path == apkInstalledPath {
+				packages = append(packages, parseAPKInstalled(data)...)
+			} else {
+				packages = append(packages, parseDpkgStatus(data)...)
+			}
+		}
+	}
+	if len(packages) == 0 {
+		return nil, errNoPackageDatabase
+	}
+
+	var found []imageVulnerability
+	for _, group := range groupBySource(packages) {
+		cves, err := s.lookup(group.Source, upstreamVersion(group.Version))
+		if err != nil {
+			return nil, err
+		}
+		if len(cves) > 0 {
+			group.CVEs = cves
+			found = append(found, group)
+		}
+	}
+	s.images[image] = found
+	return found, nil
+}
+
+// lookup finds the CVEs of a product version, waiting out the rate limit
+func (s *imageScanner) lookup(product, version string) ([]models.Vulnerability, error) {
+	key := product + "\x00" + version
+	if cves, ok := s.lookups[key]; ok {
+		return cves, nil
+	}
+	// Answers from the source's cache don't count against the rate limit
+	if cached, ok := s.source.(cachedVulnerabilitySource); ok {
+		if cves, ok := cached.cachedPackageCVEs(product, version); ok {
+			s.lookups[key] = cves
+			return cves, nil
+		}
+	}
+	if wait := s.interval - time.Since(s.lastLookup); wait > 0 {
+		time.Sleep(wait)
+	}
+	s.lastLookup = time.Now()
+
+	cves, err := s.source.PackageCVEs(product, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s %s: %w", product, version, err)
+	}
+	s.lookups[key] = cves
+	return cves, nil
+}
+
+// groupBySource groups binary packages by the source package and version
+// they were built from, in source package order
+func groupBySource(packages []imagePackage) []imageVulnerability {
+	groups := make(map[string]*imageVulnerability)
+	var keys []string
+	for _, pkg := range packages {
+		key := pkg.Source + "\x00" + pkg.Version
+		group, ok := groups[key]
+		if !ok {
+			group = &imageVulnerability{Source: pkg.Source, Version: pkg.Version}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.Packages = append(group.Packages, pkg.Name)
+	}
+	sort.Strings(keys)
+
+	result := make([]imageVulnerability, len(keys))
+	for i, key := range keys {
+		result[i] = *groups[key]
+	}
+	return result
+}
+
+// parseDpkgStatus reads the installed packages from a dpkg status file, or
+// one of distroless's per-package status files
+func parseDpkgStatus(data []byte) []imagePackage {
+	var packages []imagePackage
+	var pkg imagePackage
+	installed := true
+	flush := func() {
+		if pkg.Name != "" && pkg.Version != "" && installed {
+			if pkg.Source == "" {
+				pkg.Source = pkg.Name
+			}
+			packages = append(packages, pkg)
+		}
+		pkg, installed = imagePackage{}, true
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") {
+			continue // Continuation of a multi-line field
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Package":
+			pkg.Name = value
+		case "Version":
+			pkg.Version = value
+		case "Source":
+			// "openssl", or "openssl (3.0.11-1)" for a rebuild of the source version
+			pkg.Source, _, _ = strings.Cut(value, " ")
+		case "Status":
+			installed = strings.HasSuffix(value, " installed")
+		}
+	}
+	flush()
+	return packages
+}
+
+// parseAPKInstalled reads the installed packages from Alpine's apk database
+func parseAPKInstalled(data []byte) []imagePackage {
+	var packages []imagePackage
+	var pkg imagePackage
+	flush := func() {
+		if pkg.Name != "" && pkg.Version != "" {
+			if pkg.Source == "" {
+				pkg.Source = pkg.Name
+			}
+			packages = append(packages, pkg)
+		}
+		pkg = imagePackage{}
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "P":
+			pkg.Name = value
+		case "V":
+			pkg.Version = value
+		case "o":
+			pkg.Source = value // Origin, the package's source package
+		}
+	}
+	flush()
+	return packages
+}
+
+// upstreamVersion strips a distribution version down to the upstream version
+// NVD knows: "1:3.0.11-1~deb12u2" is "3.0.11", and apk's "1.2.13-r1" is "1.2.13"
+func upstreamVersion(version string) string {
+	if epoch, rest, ok := strings.Cut(version, ":"); ok && epoch != "" && strings.Trim(epoch, "0123456789") == "" {
+		version = rest
+	}
+	if i := strings.LastIndex(version, "-"); i > 0 {
+		version = version[:i]
+	}
+	if i := strings.IndexAny(version, "+~"); i > 0 {
+		version = version[:i]
+	}
+	return version
+}
+
+// readImageFiles copies files and directories out of an image without running
+// it: it creates a container that is never started, copies each path out of it
+// as a tar stream, and removes it. Paths the image does not have are left out.
+func readImageFiles(runtime, image string, paths []string) (map[string][][]byte, error) {
+	output, err := exec.Command(runtime, "create", image, "true").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a container from %s: %w", image, err)
+	}
+	id := strings.TrimSpace(string(output))
+	defer exec.Command(runtime, "rm", id).Run()
+
+	files := make(map[string][][]byte)
+	remaining := int64(maxImagePackageDBSize)
+	for _, path := range paths {
+		archive, err := exec.Command(runtime, "cp", id+":"+path, "-").Output()
+		if err != nil {
+			continue
+		}
+		contents, err := readTarFiles(bytes.NewReader(archive), &remaining)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", path, image, err)
+		}
+		files[path] = contents
+	}
+	return files, nil
+}
+
+// readTarFiles returns the regular files in a tar stream, other than
+// checksum files, within a budget of remaining bytes
+func readTarFiles(r io.Reader, remaining *int64) ([][]byte, error) {
+	var files [][]byte
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || strings.HasSuffix(header.Name, ".md5sums") {
+			continue
+		}
+		if header.Size > *remaining {
+			return nil, fmt.Errorf("package database over %d bytes", maxImagePackageDBSize)
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, header.Size))
+		if err != nil {
+			return nil, err
+		}
+		*remaining -= header.Size
+		files = append(files, data)
+	}
+}
+
+// scanImageLayers reports the OS packages in a container's image with known
+// CVEs, one finding per source package. Images are scanned once, however many
+// containers run them. containerd images cannot be copied from, and are skipped.
+func (cs *ContainerScanner) scanImageLayers(container ContainerInfo) []ContainerFinding {
+	if cs.images == nil || (container.Runtime != "docker" && container.Runtime != "podman") {
+		return nil
+	}
+	image := container.ImageID
+	if image == "" {
+		image = container.Image
+	}
+
+	vulnerable, err := cs.images.scan(container.Runtime, image)
+	if err != nil {
+		log.Printf("[ContainerScanner] Failed to scan image %s: %v", container.Image, err)
+		return nil
+	}
+
+	var findings []ContainerFinding
+	for _, group := range vulnerable {
+		severity := "low"
+		cveIDs := make([]string, len(group.CVEs))
+		severities := make(map[string]string, len(group.CVEs))
+		for i, cve := range group.CVEs {
+			cveIDs[i] = cve.CVEID
+			severities[cve.CVEID] = cve.Severity
+			if severityRank[cve.Severity] > severityRank[severity] {
+				severity = cve.Severity
+			}
+		}
+
+		listed := cveIDs
+		if len(listed) > 10 {
+			listed = append(listed[:10:10], fmt.Sprintf("and %d more", len(cveIDs)-10))
+		}
+		findings = append(findings, ContainerFinding{
+			ID:           uuid.New().String(),
+			Type:         "image",
+			Severity:     severity,
+			Title:        fmt.Sprintf("Vulnerable Package in Image: %s %s", group.Source, group.Version),
+			Description:  fmt.Sprintf("Image %s has %s %s installed, affected by %s", container.Image, group.Source, group.Version, strings.Join(listed, ", ")),
+			ContainerID:  container.ID,
+			ImageName:    container.Image,
+			CurrentValue: group.Version,
+			Remediation:  fmt.Sprintf("Rebuild the image on an updated base image, or upgrade %s", strings.Join(group.Packages, ", ")),
+			DiscoveredAt: time.Now(),
+			Metadata: map[string]interface{}{
+				"image_id":        container.ImageID,
+				"package":         group.Source,
+				"version":         group.Version,
+				"binary_packages": group.Packages,
+				"cve_ids":         cveIDs,
+				"severities":      severities,
+			},
+		})
+	}
+	return findings
+}
@@ -0,0 +1,193 @@
+package scanner
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"zerotrace/agent/internal/models"
+)
+
+const dpkgStatus = `Package: libssl3
+Status: install ok installed
+Source: openssl
+Version: 3.0.11-1~deb12u2
+Description: Secure Sockets Layer toolkit - shared libraries
+ This package is part of the OpenSSL project's implementation.
+
+Package: openssl
+Status: install ok installed
+Version: 3.0.11-1~deb12u2
+
+Package: zlib1g
+Status: install ok installed
+Source: zlib (1:1.2.13.dfsg-1)
+Version: 1:1.2.13.dfsg-1+b1
+
+Package: removed-tool
+Status: deinstall ok config-files
+Version: 1.0-1
+`
+
+const apkInstalled = `C:Q1abc=
+P:musl
+V:1.2.4-r2
+o:musl
+
+P:libcrypto3
+V:3.1.4-r5
+o:openssl
+`
+
+func TestParsePackageDatabases(t *testing.T) {
+	got := parseDpkgStatus([]byte(dpkgStatus))
+	want := []imagePackage{
+		{Name: "libssl3", Source: "openssl", Version: "3.0.11-1~deb12u2"},
+		{Name: "openssl", Source: "openssl", Version: "3.0.11-1~deb12u2"},
+		{Name: "zlib1g", Source: "zlib", Version: "1:1.2.13.dfsg-1+b1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dpkg: got %+v, want %+v", got, want)
+	}
+
+	got = parseAPKInstalled([]byte(apkInstalled))
+	want = []imagePackage{
+		{Name: "musl", Source: "musl", Version: "1.2.4-r2"},
+		{Name: "libcrypto3", Source: "openssl", Version: "3.1.4-r5"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apk: got %+v, want %+v", got, want)
+	}
+}
+
+func TestUpstreamVersion(t *testing.T) {
+	for version, want := range map[string]string{
+		"3.0.11-1~deb12u2":   "3.0.11",
+		"1:1.2.13.dfsg-1+b1": "1.2.13.dfsg",
+		"2.36-9+deb12u4":     "2.36",
+		"1.2.4-r2":           "1.2.4",
+		"7.88.1":             "7.88.1",
+		"2.9.14+dfsg-1.3":    "2.9.14",
+	} {
+		if got := upstreamVersion(version); got != want {
+			t.Errorf("upstreamVersion(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+// fakeVulnerabilitySource has CVEs for openssl 3.0.11, and counts lookups
+type fakeVulnerabilitySource struct {
+	lookups []string
+	err     error
+}
+
+func (f *fakeVulnerabilitySource) PackageCVEs(product, version string) ([]models.Vulnerability, error) {
+	f.lookups = append(f.lookups, product+" "+version)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if product == "openssl" && version == "3.0.11" {
+		return []models.Vulnerability{
+			{CVEID: "CVE-2023-5678", Severity: "medium"},
+			{CVEID: "CVE-2024-0727", Severity: "high"},
+		}, nil
+	}
+	return nil, nil
+}
+
+func TestScanImageLayersCachesByImage(t *testing.T) {
+	source := &fakeVulnerabilitySource{}
+	cs := NewContainerScanner(setupTestConfig())
+	cs.images = newImageScanner(source, 0)
+	reads := 0
+	cs.images.readFiles = func(runtime, image string, paths []string) (map[string][][]byte, error) {
+		reads++
+		return map[string][][]byte{dpkgStatusPath: {[]byte(dpkgStatus)}}, nil
+	}
+
+	web := ContainerInfo{ID: "c1", Name: "web", Image: "nginx:1.25", ImageID: "sha256:aaa", Runtime: "docker"}
+	findings := cs.scanImageLayers(web)
+	if len(findings) != 1 {
+		t.Fatalf("expected one finding for openssl, got %+v", findings)
+	}
+	finding := findings[0]
+	if finding.Type != "image" || finding.Severity != "high" || finding.ContainerID != "c1" {
+		t.Errorf("unexpected finding %+v", finding)
+	}
+	if ids := finding.Metadata["cve_ids"].([]string); len(ids) != 2 || ids[1] != "CVE-2024-0727" {
+		t.Errorf("expected both CVEs, got %v", ids)
+	}
+	if packages := finding.Metadata["binary_packages"].([]string); len(packages) != 2 {
+		t.Errorf("expected libssl3 and openssl, got %v", packages)
+	}
+
+	// A second container from the same image is not scanned again
+	worker := web
+	worker.ID, worker.Name = "c2", "worker"
+	if findings := cs.scanImageLayers(worker); len(findings) != 1 || findings[0].ContainerID != "c2" {
+		t.Errorf("expected the cached finding for the second container, got %+v", findings)
+	}
+	if reads != 1 || len(source.lookups) != 2 {
+		t.Errorf("expected one image read and two lookups, got %d and %v", reads, source.lookups)
+	}
+
+	// containerd images cannot be read
+	web.Runtime = "containerd"
+	if findings := cs.scanImageLayers(web); findings != nil {
+		t.Errorf("expected containerd containers to be skipped, got %+v", findings)
+	}
+}
+
+func TestScanImageLayersRetriesFailedImages(t *testing.T) {
+	source := &fakeVulnerabilitySource{err: errors.New("rate limited")}
+	cs := NewContainerScanner(setupTestConfig())
+	cs.images = newImageScanner(source, 0)
+	cs.images.readFiles = func(runtime, image string, paths []string) (map[string][][]byte, error) {
+		return map[string][][]byte{apkInstalledPath: {[]byte(apkInstalled)}}, nil
+	}
+
+	container := ContainerInfo{ID: "c1", Image: "alpine:3.19", ImageID: "sha256:bbb", Runtime: "podman"}
+	if findings := cs.scanImageLayers(container); findings != nil {
+		t.Fatalf("expected no findings while lookups fail, got %+v", findings)
+	}
+	source.err = nil
+	if findings := cs.scanImageLayers(container); len(findings) != 0 {
+		t.Errorf("expected no vulnerable packages in the alpine image, got %+v", findings)
+	}
+	if len(source.lookups) != 3 {
+		t.Errorf("expected the failed image to be looked up again, got %v", source.lookups)
+	}
+}
+
+func TestNVDPackageCVEs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("virtualMatchString"); got != "cpe:2.3:a:*:openssl:3.0.11" {
+			t.Errorf("unexpected CPE %q", got)
+		}
+		w.Write([]byte(`{"vulnerabilities":[
+			{"cve":{"id":"CVE-2024-0727","descriptions":[{"lang":"es","value":"..."},{"lang":"en","value":"PKCS12 NULL dereference"}],
+			 "metrics":{"cvssMetricV31":[{"cvssData":{"baseScore":5.5,"vectorString":"CVSS:3.1/AV:L"}}]}}},
+			{"cve":{"id":"CVE-2009-0001","descriptions":[],"metrics":{"cvssMetricV2":[{"cvssData":{"baseScore":9.3}}]}}},
+			{"cve":{"id":"CVE-2025-0001","descriptions":[],"metrics":{}}}
+		]}`))
+	}))
+	defer server.Close()
+
+	nvd := NewNVDSource("")
+	nvd.baseURL = server.URL
+	cves, err := nvd.PackageCVEs("openssl", "3.0.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cves) != 3 {
+		t.Fatalf("expected 3 CVEs, got %+v", cves)
+	}
+	if cves[0].Severity != "medium" || cves[0].Description != "PKCS12 NULL dereference" || cves[0].PackageName != "openssl" {
+		t.Errorf("unexpected CVE %+v", cves[0])
+	}
+	if cves[1].Severity != "critical" || cves[2].Severity != "unknown" || cves[2].CVSSScore != nil {
+		t.Errorf("expected CVSS v2 and unscored fallbacks, got %+v and %+v", cves[1], cves[2])
+	}
+}
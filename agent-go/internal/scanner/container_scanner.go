@@ -8,13 +8,18 @@ import (
 	"time"
 
 	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
 
 	"github.com/google/uuid"
 )
 
 // ContainerScanner handles container and Kubernetes security scanning
 type ContainerScanner struct {
-	config *config.Config
+	config       *config.Config
+	capabilities *CapabilityReport        // What the last scan could and couldn't check
+	allowlist    []ContainerAllowlistRule // Expected findings, marked suppressed rather than reported
+	images       *imageScanner            // Matches image packages against NVD; nil unless CONTAINER_IMAGE_SCAN is set
+	iacRoots     []string                 // Directories IaC files are looked for in
 }
 
 // ContainerFinding represents a container security finding
@@ -32,6 +37,8 @@ type ContainerFinding struct {
 	RequiredValue string                 `json:"required_value,omitempty"`
 	Remediation   string                 `json:"remediation"`
 	DiscoveredAt  time.Time              `json:"discovered_at"`
+	Suppressed    bool                   `json:"suppressed,omitempty"`
+	SuppressedBy  string                 `json:"suppressed_by,omitempty"` // ID of the allowlist rule that matched
 	Metadata      map[string]interface{} `json:"metadata"`
 }
 
@@ -41,6 +48,7 @@ type ContainerInfo struct {
 	Name            string                 `json:"name"`
 	Image           string                 `json:"image"`
 	ImageID         string                 `json:"image_id"`
+	Runtime         string                 `json:"runtime"` // docker, podman or containerd
 	Status          string                 `json:"status"`
 	Created         time.Time              `json:"created"`
 	Ports           []string               `json:"ports"`
@@ -91,8 +99,27 @@ type IaCFinding struct {
 
 // NewContainerScanner creates a new container security scanner
 func NewContainerScanner(cfg *config.Config) *ContainerScanner {
-	return &ContainerScanner{
-		config: cfg,
+	cs := &ContainerScanner{
+		config:       cfg,
+		capabilities: newCapabilityReport(),
+	}
+	if cfg.ContainerImageScan {
+		interval := nvdLookupInterval
+		if cfg.NVDAPIKey != "" {
+			interval = nvdLookupIntervalWithKey
+		}
+		cs.images = newImageScanner(NewNVDSource(cfg.NVDAPIKey), interval)
+	}
+	return cs
+}
+
+// SetCVECache makes image scans look packages up in cache before NVD
+func (cs *ContainerScanner) SetCVECache(cache *CVECache) {
+	if cs.images == nil {
+		return
+	}
+	if nvd, ok := cs.images.source.(*NVDSource); ok {
+		nvd.SetCache(cache)
 	}
 }
 
@@ -103,6 +130,8 @@ func (cs *ContainerScanner) Scan() ([]ContainerFinding, []ContainerInfo, Kuberne
 	var k8sInfo KubernetesInfo
 	var iacFindings []IaCFinding
 
+	cs.capabilities = newCapabilityReport()
+
 	// Discover containers
 	discoveredContainers := cs.discoverContainers()
 	containers = append(containers, discoveredContainers...)
@@ -110,12 +139,15 @@ func (cs *ContainerScanner) Scan() ([]ContainerFinding, []ContainerInfo, Kuberne
 	// Scan each container
 	for _, container := range discoveredContainers {
 		containerFindings := cs.scanContainer(container)
+		containerFindings = append(containerFindings, cs.scanImageLayers(container)...)
+		cs.applyAllowlist(containerFindings, container.Name)
 		findings = append(findings, containerFindings...)
 	}
 
 	// Scan Kubernetes cluster
 	k8sInfo = cs.scanKubernetesCluster()
 	k8sFindings := cs.scanKubernetesSecurity(k8sInfo)
+	cs.applyAllowlist(k8sFindings, "")
 	findings = append(findings, k8sFindings...)
 
 	// Scan Infrastructure as Code
@@ -124,6 +156,86 @@ func (cs *ContainerScanner) Scan() ([]ContainerFinding, []ContainerInfo, Kuberne
 	return findings, containers, k8sInfo, iacFindings, nil
 }
 
+// ScanResult scans containers, Kubernetes and IaC files and reports the
+// findings as a scan result for the API. Allowlisted findings are kept, with
+// the status suppressed.
+func (cs *ContainerScanner) ScanResult() (*models.ScanResult, error) {
+	startTime := time.Now()
+	findings, containers, k8sInfo, iacFindings, err := cs.Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ScanResult{
+		ID:              uuid.New(),
+		AgentID:         cs.config.AgentID,
+		CompanyID:       cs.config.CompanyID,
+		Status:          "completed",
+		StartTime:       startTime,
+		EndTime:         time.Now(),
+		Vulnerabilities: []models.Vulnerability{},
+		Dependencies:    []models.Dependency{},
+		Metadata: map[string]interface{}{
+			"scan_type":          "container",
+			"containers":         len(containers),
+			"kubernetes_cluster": k8sInfo.ClusterName,
+			"tool_versions":      DetectToolVersions(ContainerScanTools...),
+		},
+	}
+	for _, finding := range findings {
+		status := "open"
+		if finding.Suppressed {
+			status = "suppressed"
+		}
+		vuln := models.Vulnerability{
+			ID:          finding.ID,
+			Type:        "container",
+			Severity:    finding.Severity,
+			Title:       finding.Title,
+			Description: finding.Description,
+			Location:    finding.ImageName,
+			Remediation: finding.Remediation,
+			Status:      status,
+			EnrichmentData: map[string]any{
+				"category":      finding.Type,
+				"container_id":  finding.ContainerID,
+				"namespace":     finding.Namespace,
+				"pod_name":      finding.PodName,
+				"suppressed_by": finding.SuppressedBy,
+			},
+			CreatedAt: finding.DiscoveredAt,
+		}
+		if finding.Type == "image" {
+			vuln.PackageName, _ = finding.Metadata["package"].(string)
+			vuln.PackageVersion, _ = finding.Metadata["version"].(string)
+			vuln.EnrichmentData["image_id"] = finding.Metadata["image_id"]
+			vuln.EnrichmentData["cve_ids"] = finding.Metadata["cve_ids"]
+			vuln.EnrichmentData["severities"] = finding.Metadata["severities"]
+		}
+		result.Vulnerabilities = append(result.Vulnerabilities, vuln)
+	}
+	for _, finding := range iacFindings {
+		result.Vulnerabilities = append(result.Vulnerabilities, models.Vulnerability{
+			ID:          finding.ID,
+			Type:        "iac",
+			Severity:    finding.Severity,
+			Title:       finding.Title,
+			Description: finding.Description,
+			Location:    finding.FilePath,
+			Remediation: finding.Remediation,
+			Status:      "open",
+			EnrichmentData: map[string]any{
+				"category":      finding.Type,
+				"resource_name": finding.ResourceName,
+				"line_number":   finding.LineNumber,
+			},
+			CreatedAt: finding.DiscoveredAt,
+		})
+	}
+	result.Metadata["total_vulnerabilities"] = len(result.Vulnerabilities)
+	return result, nil
+}
+
 // discoverContainers discovers running containers
 func (cs *ContainerScanner) discoverContainers() []ContainerInfo {
 	var containers []ContainerInfo
@@ -148,7 +260,7 @@ func (cs *ContainerScanner) discoverDockerContainers() []ContainerInfo {
 	var containers []ContainerInfo
 
 	// Check if Docker is available
-	if !cs.isCommandAvailable("docker") {
+	if !cs.capabilities.require("docker_containers", "docker") {
 		return containers
 	}
 
@@ -192,7 +304,7 @@ func (cs *ContainerScanner) discoverPodmanContainers() []ContainerInfo {
 	var containers []ContainerInfo
 
 	// Check if Podman is available
-	if !cs.isCommandAvailable("podman") {
+	if !cs.capabilities.require("podman_containers", "podman") {
 		return containers
 	}
 
@@ -236,7 +348,7 @@ func (cs *ContainerScanner) discoverContainerdContainers() []ContainerInfo {
 	var containers []ContainerInfo
 
 	// Check if containerd is available
-	if !cs.isCommandAvailable("ctr") {
+	if !cs.capabilities.require("containerd_containers", "ctr") {
 		return containers
 	}
 
@@ -276,6 +388,8 @@ func (cs *ContainerScanner) discoverContainerdContainers() []ContainerInfo {
 
 // enrichContainerInfo enriches container information
 func (cs *ContainerScanner) enrichContainerInfo(container *ContainerInfo, runtime string) {
+	container.Runtime = runtime
+
 	// Get container details
 	cmd := exec.Command(runtime, "inspect", container.ID)
 	output, err := cmd.Output()
@@ -515,7 +629,7 @@ func (cs *ContainerScanner) scanKubernetesCluster() KubernetesInfo {
 	info := KubernetesInfo{}
 
 	// Check if kubectl is available
-	if !cs.isCommandAvailable("kubectl") {
+	if !cs.capabilities.require("kubernetes_cluster", "kubectl") {
 		return info
 	}
 
@@ -825,32 +939,8 @@ func (cs *ContainerScanner) scanKubernetesManifests() []IaCFinding {
 	return findings
 }
 
-// scanDockerfiles scans Dockerfiles
-func (cs *ContainerScanner) scanDockerfiles() []IaCFinding {
-	var findings []IaCFinding
-
-	// This would require parsing Dockerfile files
-	// For now, return a placeholder finding
-	finding := IaCFinding{
-		ID:           uuid.New().String(),
-		Type:         "dockerfile",
-		Severity:     "medium",
-		Title:        "Dockerfile Security Scan",
-		Description:  "Dockerfiles should be scanned for security issues",
-		Remediation:  "Use tools like hadolint or dockerfilelint to scan Dockerfiles",
-		DiscoveredAt: time.Now(),
-		Metadata: map[string]interface{}{
-			"tool": "dockerfile",
-		},
-	}
-	findings = append(findings, finding)
-
-	return findings
-}
-
-// isCommandAvailable checks if a command is available
-func (cs *ContainerScanner) isCommandAvailable(command string) bool {
-	cmd := exec.Command("which", command)
-	err := cmd.Run()
-	return err == nil
+// Capabilities reports which parts of the last scan ran and which were
+// skipped because docker, podman, ctr or kubectl is not installed
+func (cs *ContainerScanner) Capabilities() CapabilityReport {
+	return *cs.capabilities
 }
@@ -0,0 +1,302 @@
+package scanner
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"zerotrace/agent/internal/models"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+// nvdFeedWindow is how far back NVD's modified feed reaches. The feed holds
+// every CVE changed in the last eight days; a day's margin is kept.
+const nvdFeedWindow = 7 * 24 * time.Hour
+
+// CVECache keeps the CVE records and CPE matches fetched from NVD in a SQLite
+// database, so repeated lookups and restarts don't spend NVD's rate limit.
+// Records are fresh for the cache's TTL after they were last validated,
+// either by a lookup or by a sync of NVD's modified feed finding them
+// unchanged; CPE matches are only validated by looking them up again.
+type CVECache struct {
+	db  *sql.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+// cachedCVE is a CVE record in the cache
+type cachedCVE struct {
+	vuln         models.Vulnerability
+	lastModified string // NVD's lastModified for the record
+	etag         string // ETag of the response it came in, for revalidation
+	fresh        bool
+}
+
+// OpenCVECache opens the cache at path, creating it if needed
+func OpenCVECache(path string, ttl time.Duration) (*CVECache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create CVE cache directory: %w", err)
+	}
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CVE cache: %w", err)
+	}
+	// SQLite takes one writer at a time
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS cves (
+			id            TEXT PRIMARY KEY,
+			record        TEXT NOT NULL,
+			last_modified TEXT NOT NULL DEFAULT '',
+			etag          TEXT NOT NULL DEFAULT '',
+			validated_at  INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_cves_validated_at ON cves (validated_at);
+		CREATE TABLE IF NOT EXISTS cpe_matches (
+			cpe          TEXT PRIMARY KEY,
+			cve_ids      TEXT NOT NULL,
+			validated_at INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS feeds (
+			url           TEXT PRIMARY KEY,
+			etag          TEXT NOT NULL DEFAULT '',
+			last_modified TEXT NOT NULL DEFAULT '',
+			synced_at     INTEGER NOT NULL
+		);`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create CVE cache tables: %w", err)
+	}
+	return &CVECache{db: db, ttl: ttl, now: time.Now}, nil
+}
+
+// Close closes the cache's database
+func (c *CVECache) Close() error {
+	return c.db.Close()
+}
+
+// cve reads a cached CVE record. Errors reading the cache count as misses.
+func (c *CVECache) cve(id string) (cachedCVE, bool) {
+	var record, lastModified, etag string
+	var validatedAt int64
+	err := c.db.QueryRow(`SELECT record, last_modified, etag, validated_at FROM cves WHERE id = ?`, id).
+		Scan(&record, &lastModified, &etag, &validatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("CVE cache: failed to read %s: %v", id, err)
+		}
+		return cachedCVE{}, false
+	}
+	cached := cachedCVE{lastModified: lastModified, etag: etag, fresh: c.isFresh(validatedAt)}
+	if err := json.Unmarshal([]byte(record), &cached.vuln); err != nil {
+		return cachedCVE{}, false
+	}
+	return cached, true
+}
+
+// put stores CVE records as validated now
+func (c *CVECache) put(records []cachedCVE) {
+	if err := c.store(records, c.now()); err != nil {
+		log.Printf("CVE cache: %v", err)
+	}
+}
+
+// store upserts CVE records in one transaction, validated at a given time
+func (c *CVECache) store(records []cachedCVE, validatedAt time.Time) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to store CVE records: %w", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`
+		INSERT INTO cves (id, record, last_modified, etag, validated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET record = excluded.record, last_modified = excluded.last_modified,
+			etag = excluded.etag, validated_at = excluded.validated_at`)
+	if err != nil {
+		return fmt.Errorf("failed to store CVE records: %w", err)
+	}
+	defer stmt.Close()
+	for _, record := range records {
+		// Package fields belong to a lookup, not the record
+		vuln := record.vuln
+		vuln.PackageName, vuln.PackageVersion = "", ""
+		data, err := json.Marshal(vuln)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", vuln.ID, err)
+		}
+		if _, err := stmt.Exec(vuln.ID, string(data), record.lastModified, record.etag, validatedAt.Unix()); err != nil {
+			return fmt.Errorf("failed to store %s: %w", vuln.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// revalidate marks a cached record as checked unchanged now
+func (c *CVECache) revalidate(id string) {
+	if _, err := c.db.Exec(`UPDATE cves SET validated_at = ? WHERE id = ?`, c.now().Unix(), id); err != nil {
+		log.Printf("CVE cache: failed to revalidate %s: %v", id, err)
+	}
+}
+
+// cpeMatches reads the CVEs a CPE last matched, reporting whether they are
+// fresh. Matches whose records are no longer all cached count as a miss.
+func (c *CVECache) cpeMatches(cpe string) ([]models.Vulnerability, bool, bool) {
+	var ids string
+	var validatedAt int64
+	err := c.db.QueryRow(`SELECT cve_ids, validated_at FROM cpe_matches WHERE cpe = ?`, cpe).Scan(&ids, &validatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("CVE cache: failed to read matches of %s: %v", cpe, err)
+		}
+		return nil, false, false
+	}
+	var cveIDs []string
+	if err := json.Unmarshal([]byte(ids), &cveIDs); err != nil {
+		return nil, false, false
+	}
+	cves := make([]models.Vulnerability, 0, len(cveIDs))
+	for _, id := range cveIDs {
+		cached, ok := c.cve(id)
+		if !ok {
+			return nil, false, false
+		}
+		cves = append(cves, cached.vuln)
+	}
+	return cves, c.isFresh(validatedAt), true
+}
+
+// putCPEMatches stores the CVEs a CPE matched, and their records
+func (c *CVECache) putCPEMatches(cpe string, records []cachedCVE) {
+	c.put(records)
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.vuln.ID
+	}
+	data, _ := json.Marshal(ids)
+	_, err := c.db.Exec(`
+		INSERT INTO cpe_matches (cpe, cve_ids, validated_at) VALUES (?, ?, ?)
+		ON CONFLICT (cpe) DO UPDATE SET cve_ids = excluded.cve_ids, validated_at = excluded.validated_at`,
+		cpe, string(data), c.now().Unix())
+	if err != nil {
+		log.Printf("CVE cache: failed to store matches of %s: %v", cpe, err)
+	}
+}
+
+func (c *CVECache) isFresh(validatedAt int64) bool {
+	return c.now().Sub(time.Unix(validatedAt, 0)) < c.ttl
+}
+
+// SyncNVDFeed loads NVD's modified feed, the gzipped JSON of every CVE
+// changed in the last eight days, into the cache. Changed records are
+// replaced, new ones warm the cache, and cached records the feed's window
+// covers but doesn't list are known unchanged, so they are revalidated
+// without a lookup. The feed is only downloaded when it changed since the
+// last sync. It returns the number of records loaded.
+func (c *CVECache) SyncNVDFeed(ctx context.Context, client *http.Client, feedURL string) (int, error) {
+	var etag, lastModified string
+	c.db.QueryRow(`SELECT etag, last_modified FROM feeds WHERE url = ?`, feedURL).Scan(&etag, &lastModified)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create feed request: %w", err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download NVD feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var records []cachedCVE
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		// Nothing changed since the feed was last loaded
+	case http.StatusOK:
+		etag, lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		if records, err = readNVDFeed(resp); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("NVD feed returned status %d", resp.StatusCode)
+	}
+
+	// The feed is as of when it was generated, not downloaded
+	generated := c.now()
+	if t, err := http.ParseTime(lastModified); err == nil && t.Before(generated) {
+		generated = t
+	}
+	if err := c.store(records, generated); err != nil {
+		return 0, err
+	}
+	_, err = c.db.Exec(`UPDATE cves SET validated_at = ? WHERE validated_at >= ? AND validated_at < ?`,
+		generated.Unix(), generated.Add(-nvdFeedWindow).Unix(), generated.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to revalidate unchanged CVE records: %w", err)
+	}
+	_, err = c.db.Exec(`
+		INSERT INTO feeds (url, etag, last_modified, synced_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET etag = excluded.etag, last_modified = excluded.last_modified, synced_at = excluded.synced_at`,
+		feedURL, etag, lastModified, c.now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to record NVD feed sync: %w", err)
+	}
+	return len(records), nil
+}
+
+// readNVDFeed decodes a gzipped NVD 2.0 feed one record at a time, as the
+// modified feed runs to tens of megabytes
+func readNVDFeed(resp *http.Response) ([]cachedCVE, error) {
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NVD feed: %w", err)
+	}
+	defer reader.Close()
+	decoder := json.NewDecoder(reader)
+
+	// Skip to the vulnerabilities array
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to parse NVD feed: %w", err)
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse NVD feed: %w", err)
+		}
+		if key != "vulnerabilities" {
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return nil, fmt.Errorf("failed to parse NVD feed: %w", err)
+			}
+			continue
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("failed to parse NVD feed: %w", err)
+		}
+		var records []cachedCVE
+		for decoder.More() {
+			var item struct {
+				CVE nvdCVE `json:"cve"`
+			}
+			if err := decoder.Decode(&item); err != nil {
+				return nil, fmt.Errorf("failed to parse NVD feed: %w", err)
+			}
+			records = append(records, cachedCVE{vuln: item.CVE.toVulnerability(), lastModified: item.CVE.LastModified})
+		}
+		return records, nil
+	}
+	return nil, fmt.Errorf("NVD feed has no vulnerabilities")
+}
@@ -0,0 +1,154 @@
+package scanner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerotrace/agent/internal/models"
+)
+
+func openTestCVECache(t *testing.T) *CVECache {
+	t.Helper()
+	cache, err := OpenCVECache(filepath.Join(t.TempDir(), "cve_cache.db"), 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+// testCVE is a cached CVE record
+func testCVE(id, description string) models.Vulnerability {
+	return models.Vulnerability{ID: id, CVEID: id, Type: "cve", Title: id, Description: description}
+}
+
+const nvdLog4ShellResponse = `{"vulnerabilities":[{"cve":{"id":"CVE-2021-44228","lastModified":"2024-01-01T00:00:00.000",
+	"descriptions":[{"lang":"en","value":"Log4Shell"}],
+	"metrics":{"cvssMetricV31":[{"cvssData":{"baseScore":10.0,"vectorString":"CVSS:3.1/AV:N"}}]}}}]}`
+
+func TestNVDGetCVEUsesCache(t *testing.T) {
+	requests, status := 0, http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+		w.Write([]byte(nvdLog4ShellResponse))
+	}))
+	defer server.Close()
+
+	cache := openTestCVECache(t)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	nvd := NewNVDSource("")
+	nvd.baseURL = server.URL
+	nvd.SetCache(cache)
+
+	for range 2 {
+		vuln, err := nvd.GetCVE("CVE-2021-44228")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vuln.Severity != "critical" || vuln.Description != "Log4Shell" {
+			t.Errorf("unexpected CVE %+v", vuln)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("a fresh cached CVE should not be fetched again, got %d requests", requests)
+	}
+
+	// Stale records are revalidated, and still served when NVD rate limits
+	now = now.Add(72 * time.Hour)
+	if _, err := nvd.GetCVE("CVE-2021-44228"); err != nil || requests != 2 {
+		t.Errorf("expected a stale CVE to be revalidated, got %v after %d requests", err, requests)
+	}
+	now = now.Add(72 * time.Hour)
+	status = http.StatusForbidden
+	if vuln, err := nvd.GetCVE("CVE-2021-44228"); err != nil || vuln.CVEID != "CVE-2021-44228" {
+		t.Errorf("expected the stale CVE when NVD rate limits, got %+v, %v", vuln, err)
+	}
+	if _, err := nvd.GetCVE("CVE-2024-0001"); err == nil {
+		t.Error("expected an uncached CVE to fail when NVD rate limits")
+	}
+}
+
+func TestNVDPackageCVEsUsesCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(nvdLog4ShellResponse))
+	}))
+	defer server.Close()
+
+	nvd := NewNVDSource("")
+	nvd.baseURL = server.URL
+	if _, ok := nvd.cachedPackageCVEs("log4j", "2.14.1"); ok {
+		t.Error("a source without a cache should have nothing cached")
+	}
+	nvd.SetCache(openTestCVECache(t))
+
+	if _, err := nvd.PackageCVEs("log4j", "2.14.1"); err != nil {
+		t.Fatal(err)
+	}
+	cves, ok := nvd.cachedPackageCVEs("log4j", "2.14.1")
+	if !ok || len(cves) != 1 || cves[0].PackageName != "log4j" || cves[0].PackageVersion != "2.14.1" {
+		t.Fatalf("expected the cached match for the package, got %+v", cves)
+	}
+	if cves, err := nvd.PackageCVEs("log4j", "2.14.1"); err != nil || len(cves) != 1 || requests != 1 {
+		t.Errorf("expected the match from the cache, got %+v, %v after %d requests", cves, err, requests)
+	}
+	if _, ok := nvd.cachedPackageCVEs("log4j", "2.17.0"); ok {
+		t.Error("another version should not be cached")
+	}
+}
+
+func TestSyncNVDFeed(t *testing.T) {
+	var feed bytes.Buffer
+	gz := gzip.NewWriter(&feed)
+	gz.Write([]byte(`{"resultsPerPage":1,"format":"NVD_CVE","vulnerabilities":[
+		{"cve":{"id":"CVE-2024-0002","lastModified":"2024-06-02T00:00:00.000","descriptions":[{"lang":"en","value":"changed"}],"metrics":{}}}
+	],"timestamp":"2024-06-02T00:00:00.000"}`))
+	gz.Close()
+
+	generated := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"feed-1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"feed-1"`)
+		w.Header().Set("Last-Modified", generated.Format(http.TimeFormat))
+		w.Write(feed.Bytes())
+	}))
+	defer server.Close()
+
+	cache := openTestCVECache(t)
+	// Cached three days ago, so stale; one is unchanged and one changed since
+	old := time.Now().Add(-72 * time.Hour)
+	cache.store([]cachedCVE{
+		{vuln: testCVE("CVE-2024-0001", "unchanged"), lastModified: "2024-01-01T00:00:00.000"},
+		{vuln: testCVE("CVE-2024-0002", "original"), lastModified: "2024-01-01T00:00:00.000"},
+	}, old)
+
+	loaded, err := cache.SyncNVDFeed(context.Background(), server.Client(), server.URL)
+	if err != nil || loaded != 1 {
+		t.Fatalf("SyncNVDFeed() = %d, %v", loaded, err)
+	}
+	changed, ok := cache.cve("CVE-2024-0002")
+	if !ok || !changed.fresh || changed.vuln.Description != "changed" || changed.lastModified != "2024-06-02T00:00:00.000" {
+		t.Errorf("expected the changed record replaced, got %+v", changed)
+	}
+	if unchanged, ok := cache.cve("CVE-2024-0001"); !ok || !unchanged.fresh || unchanged.vuln.Description != "unchanged" {
+		t.Errorf("expected the unchanged record revalidated, got %+v", unchanged)
+	}
+
+	if loaded, err := cache.SyncNVDFeed(context.Background(), server.Client(), server.URL); err != nil || loaded != 0 || downloads != 1 {
+		t.Errorf("an unchanged feed should not be downloaded again, got %d, %v after %d downloads", loaded, err, downloads)
+	}
+}
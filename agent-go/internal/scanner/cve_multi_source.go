@@ -0,0 +1,218 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
+)
+
+// MultiSource fans lookups out across CVE sources at once and merges what
+// they return, so an advisory several sources know of, under its CVE, GHSA or
+// source ID, is reported once with the sources that had it
+type MultiSource struct {
+	sources []CVESource
+}
+
+// NewMultiSource creates a CVE source querying all of sources
+func NewMultiSource(sources ...CVESource) *MultiSource {
+	return &MultiSource{sources: sources}
+}
+
+// NewCVESources creates a source for the advisory databases CVE_SOURCES
+// names, or nil when it names none
+func NewCVESources(cfg *config.Config) *MultiSource {
+	var sources []CVESource
+	for _, name := range cfg.CVESources {
+		switch name {
+		case "osv":
+			sources = append(sources, NewOSVSource())
+		case "gitlab":
+			sources = append(sources, NewGitLabAdvisorySource(cfg.GitLabToken))
+		case "github":
+			sources = append(sources, NewGitHubAdvisorySource())
+		}
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	return NewMultiSource(sources...)
+}
+
+// GetCVE retrieves a CVE from every source that has it
+func (m *MultiSource) GetCVE(cveID string) (*models.Vulnerability, error) {
+	vulnerabilities, err := m.fanOut(func(source CVESource) ([]models.Vulnerability, error) {
+		vuln, err := source.GetCVE(cveID)
+		if err != nil {
+			return nil, err
+		}
+		return []models.Vulnerability{*vuln}, nil
+	})
+	if len(vulnerabilities) == 0 {
+		if err == nil {
+			err = fmt.Errorf("CVE %s not found", cveID)
+		}
+		return nil, err
+	}
+	return &vulnerabilities[0], nil
+}
+
+// SearchCVEs searches every source
+func (m *MultiSource) SearchCVEs(query string) ([]models.Vulnerability, error) {
+	return m.fanOut(func(source CVESource) ([]models.Vulnerability, error) {
+		return source.SearchCVEs(query)
+	})
+}
+
+// GetRecentCVEs gets recent CVEs from every source
+func (m *MultiSource) GetRecentCVEs(limit int) ([]models.Vulnerability, error) {
+	return m.fanOut(func(source CVESource) ([]models.Vulnerability, error) {
+		return source.GetRecentCVEs(limit)
+	})
+}
+
+// PackageAdvisories matches packages against every source that matches
+// package versions. Sources that only look CVEs up by ID, like NVD, are
+// skipped.
+func (m *MultiSource) PackageAdvisories(packages []PackageQuery) ([]models.Vulnerability, error) {
+	return m.fanOut(func(source CVESource) ([]models.Vulnerability, error) {
+		if packageSource, ok := source.(PackageAdvisorySource); ok {
+			return packageSource.PackageAdvisories(packages)
+		}
+		return nil, nil
+	})
+}
+
+// fanOut runs query against every source at once and merges the results.
+// When some sources fail, what the others found is returned along with an
+// error naming the failures.
+func (m *MultiSource) fanOut(query func(CVESource) ([]models.Vulnerability, error)) ([]models.Vulnerability, error) {
+	results := make([][]models.Vulnerability, len(m.sources))
+	errs := make([]error, len(m.sources))
+	var wg sync.WaitGroup
+	for i, source := range m.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = query(source)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %w", sourceName(source), errs[i])
+			}
+			for j := range results[i] {
+				tagSource(&results[i][j], sourceName(source))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var merged []models.Vulnerability
+	for _, result := range results {
+		merged = append(merged, result...)
+	}
+	return mergeAdvisories(merged), errors.Join(errs...)
+}
+
+// sourceName names a source in a vulnerability's sources
+func sourceName(source CVESource) string {
+	switch source.(type) {
+	case *NVDSource:
+		return "nvd"
+	case *GitHubAdvisorySource:
+		return "github"
+	case *OSVSource:
+		return "osv"
+	case *GitLabAdvisorySource:
+		return "gitlab"
+	default:
+		return fmt.Sprintf("%T", source)
+	}
+}
+
+// tagSource records that a source reported the vulnerability
+func tagSource(vuln *models.Vulnerability, source string) {
+	if vuln.EnrichmentData == nil {
+		vuln.EnrichmentData = make(map[string]any)
+	}
+	sources, _ := vuln.EnrichmentData["sources"].([]string)
+	vuln.EnrichmentData["sources"] = appendUnique(sources, source)
+}
+
+// advisoryIDs are the IDs a vulnerability is known by: its own, its CVE and
+// the aliases sources recorded, such as GHSA IDs
+func advisoryIDs(vuln models.Vulnerability) []string {
+	ids := []string{vuln.ID}
+	if vuln.CVEID != "" {
+		ids = append(ids, vuln.CVEID)
+	}
+	aliases, _ := vuln.EnrichmentData["aliases"].([]string)
+	return appendUnique(ids, aliases...)
+}
+
+// mergeAdvisories merges the vulnerabilities that share any ID and affect the
+// same package version, keeping the order they were first seen in
+func mergeAdvisories(vulnerabilities []models.Vulnerability) []models.Vulnerability {
+	var merged []models.Vulnerability
+	index := make(map[string]int) // Package and ID to the merged vulnerability
+	for _, vuln := range vulnerabilities {
+		pkg := vuln.PackageName + "@" + vuln.PackageVersion + " "
+		ids := advisoryIDs(vuln)
+		target := -1
+		for _, id := range ids {
+			if i, ok := index[pkg+id]; ok {
+				target = i
+				break
+			}
+		}
+		if target < 0 {
+			target = len(merged)
+			merged = append(merged, vuln)
+		} else {
+			mergeAdvisory(&merged[target], vuln)
+		}
+		for _, id := range advisoryIDs(merged[target]) {
+			index[pkg+id] = target
+		}
+	}
+	return merged
+}
+
+// mergeAdvisory fills in what into is missing from another source's report
+// of the same advisory, preferring a CVE ID for the merged one
+func mergeAdvisory(into *models.Vulnerability, from models.Vulnerability) {
+	if into.CVEID == "" && from.CVEID != "" {
+		into.CVEID = from.CVEID
+		into.ID = from.CVEID
+	}
+	if into.Severity == "unknown" || into.Severity == "" {
+		into.Severity = from.Severity
+	}
+	if into.CVSSScore == nil {
+		into.CVSSScore = from.CVSSScore
+	}
+	if into.CVSSVector == "" {
+		into.CVSSVector = from.CVSSVector
+	}
+	if into.Title == "" || into.Title == into.ID {
+		into.Title = from.Title
+	}
+	if into.Description == "" {
+		into.Description = from.Description
+	}
+	if into.Remediation == "" {
+		into.Remediation = from.Remediation
+	}
+	into.References = appendUnique(into.References, from.References...)
+	into.AffectedVersions = appendUnique(into.AffectedVersions, from.AffectedVersions...)
+	into.PatchedVersions = appendUnique(into.PatchedVersions, from.PatchedVersions...)
+
+	if into.EnrichmentData == nil {
+		into.EnrichmentData = make(map[string]any)
+	}
+	for _, key := range []string{"aliases", "sources"} {
+		existing, _ := into.EnrichmentData[key].([]string)
+		other, _ := from.EnrichmentData[key].([]string)
+		into.EnrichmentData[key] = appendUnique(existing, other...)
+	}
+}
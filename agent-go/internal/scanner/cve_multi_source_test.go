@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+
+	"zerotrace/agent/internal/models"
+)
+
+// fakePackageSource returns fixed advisories for any packages
+type fakePackageSource struct {
+	vulns []models.Vulnerability
+	err   error
+}
+
+func (f *fakePackageSource) GetCVE(cveID string) (*models.Vulnerability, error) {
+	for _, v := range f.vulns {
+		if v.ID == cveID {
+			return &v, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakePackageSource) SearchCVEs(query string) ([]models.Vulnerability, error) {
+	return nil, nil
+}
+
+func (f *fakePackageSource) GetRecentCVEs(limit int) ([]models.Vulnerability, error) {
+	return nil, nil
+}
+
+func (f *fakePackageSource) PackageAdvisories(packages []PackageQuery) ([]models.Vulnerability, error) {
+	return f.vulns, f.err
+}
+
+func TestMultiSourceMergesAdvisories(t *testing.T) {
+	score := 9.1
+	osv := &fakePackageSource{vulns: []models.Vulnerability{{
+		ID: "CVE-2021-23337", CVEID: "CVE-2021-23337", Severity: "unknown", Title: "Command Injection",
+		PackageName: "lodash", PackageVersion: "4.17.20", PatchedVersions: []string{"4.17.21"},
+		EnrichmentData: map[string]any{"aliases": []string{"GHSA-35jh-r3h4-6jhm", "CVE-2021-23337"}},
+	}}}
+	github := &fakePackageSource{vulns: []models.Vulnerability{
+		// Known to GitHub by its GHSA ID only
+		{ID: "GHSA-35jh-r3h4-6jhm", Severity: "high", CVSSScore: &score, PackageName: "lodash", PackageVersion: "4.17.20",
+			EnrichmentData: map[string]any{"aliases": []string{"GHSA-35jh-r3h4-6jhm"}}},
+		// The same advisory for another version is reported separately
+		{ID: "GHSA-35jh-r3h4-6jhm", Severity: "high", PackageName: "lodash", PackageVersion: "4.17.19",
+			EnrichmentData: map[string]any{"aliases": []string{"GHSA-35jh-r3h4-6jhm"}}},
+	}}
+	failing := &fakePackageSource{err: errors.New("rate limited")}
+
+	vulns, err := NewMultiSource(osv, github, failing).PackageAdvisories(nil)
+	if err == nil {
+		t.Error("expected the failing source's error")
+	}
+	if len(vulns) != 2 {
+		t.Fatalf("expected the advisory merged per package version, got %+v", vulns)
+	}
+	v := vulns[0]
+	if v.ID != "CVE-2021-23337" || v.Severity != "high" || v.CVSSScore == nil || len(v.PatchedVersions) != 1 {
+		t.Errorf("expected the sources' details merged, got %+v", v)
+	}
+	if sources, _ := v.EnrichmentData["sources"].([]string); len(sources) != 1 || sources[0] != "*scanner.fakePackageSource" {
+		t.Errorf("expected the fakes named by their type, got %v", sources)
+	}
+	if vulns[1].PackageVersion != "4.17.19" {
+		t.Errorf("unexpected second advisory %+v", vulns[1])
+	}
+}
+
+func TestMultiSourceGetCVE(t *testing.T) {
+	source := NewMultiSource(
+		&fakePackageSource{err: errors.New("down")},
+		&fakePackageSource{vulns: []models.Vulnerability{{ID: "CVE-2024-0001", Title: "found"}}},
+	)
+	vuln, err := source.GetCVE("CVE-2024-0001")
+	if vuln == nil || vuln.Title != "found" {
+		t.Fatalf("expected the CVE from the source that has it, got %+v (%v)", vuln, err)
+	}
+	if _, err := source.GetCVE("CVE-2024-9999"); err == nil {
+		t.Error("expected an unknown CVE to fail")
+	}
+}
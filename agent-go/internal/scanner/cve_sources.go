@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -18,11 +19,28 @@ type CVESource interface {
 	GetRecentCVEs(limit int) ([]models.Vulnerability, error)
 }
 
+// PackageQuery is an installed package looked up in an advisory database
+type PackageQuery struct {
+	Ecosystem string // OSV ecosystem name, such as PyPI, npm or Debian
+	Name      string
+	Version   string
+}
+
+// PackageAdvisorySource is a CVE source that matches installed package
+// versions directly, without knowing the CVE IDs up front
+type PackageAdvisorySource interface {
+	CVESource
+	// PackageAdvisories returns the advisories affecting any of the packages,
+	// each with PackageName and PackageVersion set to the package it affects
+	PackageAdvisories(packages []PackageQuery) ([]models.Vulnerability, error)
+}
+
 // NVDSource implements CVE data from NIST National Vulnerability Database
 type NVDSource struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+	cache      *CVECache // Consulted before NVD when set
 }
 
 // NewNVDSource creates a new NVD CVE source
@@ -36,82 +54,237 @@ func NewNVDSource(apiKey string) *NVDSource {
 	}
 }
 
-// GetCVE retrieves a specific CVE from NVD
+// SetCache makes lookups consult cache before NVD
+func (n *NVDSource) SetCache(cache *CVECache) {
+	n.cache = cache
+}
+
+// GetCVE retrieves a specific CVE from NVD. With a cache, a fresh cached
+// record is returned without a request, a stale one is revalidated, and a
+// stale one is still returned when NVD can't be reached or rate limits.
 func (n *NVDSource) GetCVE(cveID string) (*models.Vulnerability, error) {
-	url := fmt.Sprintf("%s?cveId=%s", n.baseURL, cveID)
+	var cached cachedCVE
+	var isCached bool
+	if n.cache != nil {
+		cached, isCached = n.cache.cve(cveID)
+		if isCached && cached.fresh {
+			return &cached.vuln, nil
+		}
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	vuln, lastModified, etag, notModified, err := n.fetchCVE(cveID, cached.etag)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		if isCached {
+			return &cached.vuln, nil
+		}
+		return nil, err
+	}
+	if notModified || (isCached && lastModified != "" && lastModified == cached.lastModified) {
+		n.cache.revalidate(cveID)
+		return &cached.vuln, nil
+	}
+	if n.cache != nil {
+		n.cache.put([]cachedCVE{{vuln: *vuln, lastModified: lastModified, etag: etag}})
+	}
+	return vuln, nil
+}
+
+// fetchCVE requests a CVE from NVD, sending the ETag of a cached copy
+func (n *NVDSource) fetchCVE(cveID, etag string) (vuln *models.Vulnerability, lastModified, newETag string, notModified bool, err error) {
+	req, err := http.NewRequest("GET", n.baseURL+"?cveId="+url.QueryEscape(cveID), nil)
+	if err != nil {
+		return nil, "", "", false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add API key if available
 	if n.apiKey != "" {
 		req.Header.Set("apiKey", n.apiKey)
 	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 
 	resp, err := n.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch CVE: %w", err)
+		return nil, "", "", false, fmt.Errorf("failed to fetch CVE: %w", err)
 	}
 	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		return nil, "", "", true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", false, fmt.Errorf("NVD returned status %d for %s", resp.StatusCode, cveID)
 	}
 
 	var nvdResponse struct {
 		Vulnerabilities []struct {
-			CVE struct {
-				ID          string `json:"id"`
-				Description struct {
-					DescriptionData []struct {
-						Lang  string `json:"lang"`
-						Value string `json:"value"`
-					} `json:"description_data"`
-				} `json:"description"`
-				Metrics struct {
-					CvssMetricV31 []struct {
-						CvssData struct {
-							BaseScore    float64 `json:"baseScore"`
-							BaseSeverity string  `json:"baseSeverity"`
-							VectorString string  `json:"vectorString"`
-						} `json:"cvssData"`
-					} `json:"cvssMetricV31"`
-				} `json:"metrics"`
-				Published    string `json:"published"`
-				LastModified string `json:"lastModifiedDate"`
-			} `json:"cve"`
+			CVE nvdCVE `json:"cve"`
 		} `json:"vulnerabilities"`
 	}
-
-	if err := json.Unmarshal(body, &nvdResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse NVD response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(&nvdResponse); err != nil {
+		return nil, "", "", false, fmt.Errorf("failed to parse NVD response: %w", err)
 	}
 
 	if len(nvdResponse.Vulnerabilities) == 0 {
-		return nil, fmt.Errorf("CVE %s not found", cveID)
+		return nil, "", "", false, fmt.Errorf("CVE %s not found", cveID)
 	}
 
 	nvdCVE := nvdResponse.Vulnerabilities[0].CVE
+	converted := nvdCVE.toVulnerability()
+	if converted.CVSSScore != nil {
+		converted.Priority = getPriorityFromCVSS(*converted.CVSSScore)
+	}
+	return &converted, nvdCVE.LastModified, resp.Header.Get("ETag"), false, nil
+}
 
-	// Convert to our vulnerability model
-	vuln := &models.Vulnerability{
-		ID:          nvdCVE.ID,
-		Type:        "cve",
-		Severity:    strings.ToLower(nvdCVE.Metrics.CvssMetricV31[0].CvssData.BaseSeverity),
-		Title:       nvdCVE.ID,
-		Description: nvdCVE.Description.DescriptionData[0].Value,
-		CVEID:       nvdCVE.ID,
-		CVSSScore:   &nvdCVE.Metrics.CvssMetricV31[0].CvssData.BaseScore,
-		CVSSVector:  nvdCVE.Metrics.CvssMetricV31[0].CvssData.VectorString,
-		Status:      "open",
-		Priority:    getPriorityFromCVSS(nvdCVE.Metrics.CvssMetricV31[0].CvssData.BaseScore),
-		CreatedAt:   time.Now(),
+// PackageCVEs finds the CVEs whose affected configurations match a product at
+// an exact version, with NVD's CPE matching, from any vendor. With a cache,
+// matches are looked up again once they're stale, and stale matches are
+// returned when NVD can't be reached or rate limits.
+func (n *NVDSource) PackageCVEs(product, version string) ([]models.Vulnerability, error) {
+	cpe := fmt.Sprintf("cpe:2.3:a:*:%s:%s", product, version)
+	var stale []models.Vulnerability
+	if n.cache != nil {
+		if cves, fresh, ok := n.cache.cpeMatches(cpe); ok {
+			if fresh {
+				return withPackage(cves, product, version), nil
+			}
+			stale = cves
+		}
 	}
 
-	return vuln, nil
+	records, err := n.matchCPE(cpe)
+	if err != nil {
+		if stale != nil {
+			return withPackage(stale, product, version), nil
+		}
+		return nil, err
+	}
+	vulnerabilities := make([]models.Vulnerability, len(records))
+	for i, record := range records {
+		vulnerabilities[i] = record.vuln
+	}
+	if n.cache != nil {
+		n.cache.putCPEMatches(cpe, records)
+	}
+	return withPackage(vulnerabilities, product, version), nil
+}
+
+// cachedPackageCVEs returns the cached CVEs of a product version while they
+// are fresh, so callers can skip waiting out NVD's rate limit
+func (n *NVDSource) cachedPackageCVEs(product, version string) ([]models.Vulnerability, bool) {
+	if n.cache == nil {
+		return nil, false
+	}
+	cves, fresh, ok := n.cache.cpeMatches(fmt.Sprintf("cpe:2.3:a:*:%s:%s", product, version))
+	if !ok || !fresh {
+		return nil, false
+	}
+	return withPackage(cves, product, version), true
+}
+
+// matchCPE requests the CVEs matching a CPE from NVD
+func (n *NVDSource) matchCPE(cpe string) ([]cachedCVE, error) {
+	req, err := http.NewRequest("GET", n.baseURL+"?virtualMatchString="+url.QueryEscape(cpe), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if n.apiKey != "" {
+		req.Header.Set("apiKey", n.apiKey)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search CVEs: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("NVD returned status %d for %s", resp.StatusCode, cpe)
+	}
+
+	var nvdResponse struct {
+		Vulnerabilities []struct {
+			CVE nvdCVE `json:"cve"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&nvdResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse NVD response: %w", err)
+	}
+
+	records := make([]cachedCVE, 0, len(nvdResponse.Vulnerabilities))
+	for _, item := range nvdResponse.Vulnerabilities {
+		records = append(records, cachedCVE{vuln: item.CVE.toVulnerability(), lastModified: item.CVE.LastModified})
+	}
+	return records, nil
+}
+
+// withPackage sets the package CVEs were matched to
+func withPackage(cves []models.Vulnerability, product, version string) []models.Vulnerability {
+	for i := range cves {
+		cves[i].PackageName = product
+		cves[i].PackageVersion = version
+	}
+	return cves
+}
+
+// nvdCVE is a CVE record in NVD's 2.0 API responses and data feeds
+type nvdCVE struct {
+	ID           string `json:"id"`
+	LastModified string `json:"lastModified"`
+	Descriptions []struct {
+		Lang  string `json:"lang"`
+		Value string `json:"value"`
+	} `json:"descriptions"`
+	Metrics struct {
+		CvssMetricV31 []nvdCVSSMetric `json:"cvssMetricV31"`
+		CvssMetricV30 []nvdCVSSMetric `json:"cvssMetricV30"`
+		CvssMetricV2  []nvdCVSSMetric `json:"cvssMetricV2"`
+	} `json:"metrics"`
+	References []struct {
+		URL string `json:"url"`
+	} `json:"references"`
+}
+
+// nvdCVSSMetric is a CVSS score in an NVD 2.0 API response
+type nvdCVSSMetric struct {
+	CvssData struct {
+		BaseScore    float64 `json:"baseScore"`
+		VectorString string  `json:"vectorString"`
+	} `json:"cvssData"`
+}
+
+// toVulnerability converts an NVD CVE record, at the severity of the newest
+// CVSS version NVD scored it with
+func (c nvdCVE) toVulnerability() models.Vulnerability {
+	vuln := models.Vulnerability{
+		ID:        c.ID,
+		Type:      "cve",
+		Severity:  "unknown",
+		Title:     c.ID,
+		CVEID:     c.ID,
+		Status:    "open",
+		CreatedAt: time.Now(),
+	}
+	for _, description := range c.Descriptions {
+		if description.Lang == "en" {
+			vuln.Description = description.Value
+			break
+		}
+	}
+	for _, metrics := range [][]nvdCVSSMetric{c.Metrics.CvssMetricV31, c.Metrics.CvssMetricV30, c.Metrics.CvssMetricV2} {
+		if len(metrics) == 0 {
+			continue
+		}
+		score := metrics[0].CvssData.BaseScore
+		vuln.CVSSScore = &score
+		vuln.CVSSVector = metrics[0].CvssData.VectorString
+		vuln.Severity = getPriorityFromCVSS(score)
+		break
+	}
+	for _, reference := range c.References {
+		vuln.References = append(vuln.References, reference.URL)
+	}
+	return vuln
 }
 
 // SearchCVEs searches for CVEs by keyword
@@ -419,6 +592,116 @@ func (g *GitHubAdvisorySource) GetRecentCVEs(limit int) ([]models.Vulnerability,
 	return vulnerabilities, nil
 }
 
+// githubEcosystems are GitHub's advisory ecosystem names, by OSV ecosystem
+var githubEcosystems = map[string]string{
+	"Go":        "go",
+	"npm":       "npm",
+	"PyPI":      "pip",
+	"RubyGems":  "rubygems",
+	"crates.io": "rust",
+	"Maven":     "maven",
+	"NuGet":     "nuget",
+	"Packagist": "composer",
+}
+
+// PackageAdvisories finds the reviewed GitHub advisories affecting each
+// package, one request per package. Unauthenticated requests are limited to
+// 60 an hour. Packages of ecosystems GitHub doesn't cover are skipped.
+func (g *GitHubAdvisorySource) PackageAdvisories(packages []PackageQuery) ([]models.Vulnerability, error) {
+	var vulnerabilities []models.Vulnerability
+	for _, pkg := range packages {
+		ecosystem, ok := githubEcosystems[pkg.Ecosystem]
+		if !ok {
+			continue
+		}
+		query := url.Values{
+			"ecosystem": {ecosystem},
+			"affects":   {pkg.Name + "@" + pkg.Version},
+			"per_page":  {"100"},
+		}
+		req, err := http.NewRequest("GET", g.baseURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+		resp, err := g.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch advisories: %w", err)
+		}
+		var advisories []struct {
+			GHSAID      string `json:"ghsa_id"`
+			CVEID       string `json:"cve_id"`
+			HTMLURL     string `json:"html_url"`
+			Summary     string `json:"summary"`
+			Description string `json:"description"`
+			Severity    string `json:"severity"`
+			CVSS        struct {
+				Score        *float64 `json:"score"`
+				VectorString string   `json:"vector_string"`
+			} `json:"cvss"`
+			Vulnerabilities []struct {
+				Package struct {
+					Name string `json:"name"`
+				} `json:"package"`
+				VulnerableVersionRange string `json:"vulnerable_version_range"`
+				FirstPatchedVersion    string `json:"first_patched_version"`
+			} `json:"vulnerabilities"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("GitHub returned status %d for %s", resp.StatusCode, pkg.Name)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&advisories)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GitHub response: %w", err)
+		}
+
+		for _, advisory := range advisories {
+			vuln := models.Vulnerability{
+				ID:             advisory.GHSAID,
+				Type:           "cve",
+				Severity:       advisorySeverity(advisory.Severity),
+				Title:          advisory.Summary,
+				Description:    advisory.Description,
+				CVEID:          advisory.CVEID,
+				CVSSVector:     advisory.CVSS.VectorString,
+				PackageName:    pkg.Name,
+				PackageVersion: pkg.Version,
+				Status:         "open",
+				CreatedAt:      time.Now(),
+				EnrichmentData: map[string]any{
+					"aliases": []string{advisory.GHSAID},
+					"sources": []string{"github"},
+				},
+			}
+			if advisory.CVEID != "" {
+				vuln.ID = advisory.CVEID
+			}
+			if advisory.CVSS.Score != nil && *advisory.CVSS.Score > 0 {
+				vuln.CVSSScore = advisory.CVSS.Score
+			}
+			if advisory.HTMLURL != "" {
+				vuln.References = []string{advisory.HTMLURL}
+			}
+			for _, affected := range advisory.Vulnerabilities {
+				if affected.Package.Name != pkg.Name {
+					continue
+				}
+				if affected.VulnerableVersionRange != "" {
+					vuln.AffectedVersions = appendUnique(vuln.AffectedVersions, affected.VulnerableVersionRange)
+				}
+				if affected.FirstPatchedVersion != "" {
+					vuln.PatchedVersions = appendUnique(vuln.PatchedVersions, affected.FirstPatchedVersion)
+				}
+			}
+			vulnerabilities = append(vulnerabilities, vuln)
+		}
+	}
+	return vulnerabilities, nil
+}
+
 // Helper functions
 func getPriorityFromCVSS(score float64) string {
 	switch {
@@ -0,0 +1,292 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// dockerfileInstruction is one instruction of a Dockerfile, with its
+// continuation lines joined
+type dockerfileInstruction struct {
+	Command string // Upper-cased, such as FROM
+	Args    string
+	Line    int // Line the instruction starts on
+}
+
+var (
+	// curl or wget piped into a shell, such as "curl -fsSL https://x | sudo bash"
+	pipeToShellPattern = regexp.MustCompile(`\b(curl|wget)\b[^|;&]*\|\s*(sudo\s+)?(\S*/)?(ba|z|da|k)?sh\b`)
+	// ENV names that hold credentials
+	secretEnvPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|access_?key|private_?key|credential)`)
+)
+
+// SetIaCRoots sets the directories subsequent scans look for IaC files in.
+// Without roots, no IaC files are scanned.
+func (cs *ContainerScanner) SetIaCRoots(roots []string) {
+	cs.iacRoots = roots
+}
+
+// findDockerfiles walks the IaC roots for files named Dockerfile*, skipping
+// directories and files the agent's exclude patterns match
+func (cs *ContainerScanner) findDockerfiles() []string {
+	var paths []string
+	for _, root := range cs.iacRoots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Keep walking past unreadable directories
+			}
+			if cs.excluded(d.Name()) && path != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() && strings.HasPrefix(d.Name(), "Dockerfile") {
+				if info, err := d.Info(); err == nil && info.Size() <= cs.config.MaxFileSize {
+					paths = append(paths, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("[ContainerScanner] Failed to walk %s: %v", root, err)
+		}
+	}
+	return paths
+}
+
+// excluded reports whether a file or directory name matches one of the
+// agent's exclude patterns, such as node_modules, node_modules/ or *.log
+func (cs *ContainerScanner) excluded(name string) bool {
+	for _, pattern := range cs.config.ExcludePatterns {
+		if matched, _ := filepath.Match(strings.TrimSuffix(pattern, "/"), name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDockerfile splits a Dockerfile into instructions, joining lines ending
+// in a backslash and dropping comments and blank lines
+func parseDockerfile(data string) []dockerfileInstruction {
+	var instructions []dockerfileInstruction
+	var current strings.Builder
+	start := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue // Comments may sit between continuation lines too
+		}
+		if current.Len() == 0 {
+			start = line
+		}
+		continued := strings.HasSuffix(text, "\\")
+		current.WriteString(strings.TrimSuffix(text, "\\"))
+		if continued {
+			current.WriteString(" ")
+			continue
+		}
+		instructions = append(instructions, newDockerfileInstruction(current.String(), start))
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		instructions = append(instructions, newDockerfileInstruction(current.String(), start))
+	}
+	return instructions
+}
+
+func newDockerfileInstruction(text string, line int) dockerfileInstruction {
+	command, args, _ := strings.Cut(text, " ")
+	return dockerfileInstruction{Command: strings.ToUpper(command), Args: strings.TrimSpace(args), Line: line}
+}
+
+// scanDockerfiles parses the Dockerfiles under the IaC roots and reports
+// unpinned and latest base images, images that run as root, ADD from URLs,
+// credentials in ENV and scripts piped from curl or wget into a shell
+func (cs *ContainerScanner) scanDockerfiles() []IaCFinding {
+	var findings []IaCFinding
+	for _, path := range cs.findDockerfiles() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		findings = append(findings, checkDockerfile(path, parseDockerfile(string(data)))...)
+	}
+	return findings
+}
+
+// checkDockerfile checks a parsed Dockerfile's instructions
+func checkDockerfile(path string, instructions []dockerfileInstruction) []IaCFinding {
+	var findings []IaCFinding
+	add := func(instruction dockerfileInstruction, severity, rule, title, description, current, remediation, resource string) {
+		findings = append(findings, IaCFinding{
+			ID:           uuid.New().String(),
+			Type:         "dockerfile",
+			Severity:     severity,
+			Title:        title,
+			Description:  description,
+			FilePath:     path,
+			LineNumber:   instruction.Line,
+			ResourceName: resource,
+			CurrentValue: current,
+			Remediation:  remediation,
+			DiscoveredAt: time.Now(),
+			Metadata: map[string]interface{}{
+				"rule":        rule,
+				"instruction": instruction.Command,
+			},
+		})
+	}
+
+	stages := make(map[string]bool) // Earlier stages' names, which FROM may build on
+	var finalFrom, lastUser dockerfileInstruction
+	for _, instruction := range instructions {
+		switch instruction.Command {
+		case "FROM":
+			image, stage := parseFrom(instruction.Args)
+			finalFrom, lastUser = instruction, dockerfileInstruction{}
+			if stage != "" {
+				stages[strings.ToLower(stage)] = true
+			}
+			if image == "scratch" || stages[strings.ToLower(image)] || strings.Contains(image, "$") {
+				continue
+			}
+			if strings.Contains(image, "@sha256:") {
+				continue
+			}
+			if tag := imageTag(image); tag == "" || tag == "latest" {
+				add(instruction, "medium", "dockerfile-latest-tag", "Base Image Uses Latest Tag",
+					fmt.Sprintf("FROM %s resolves to whatever image is tagged latest when the image is built", image),
+					image, "Use a specific version tag and pin it to its digest (image:tag@sha256:...)", image)
+			} else {
+				add(instruction, "low", "dockerfile-unpinned-image", "Base Image Not Pinned to a Digest",
+					fmt.Sprintf("FROM %s can resolve to a different image if the tag is moved", image),
+					image, "Pin the base image to its digest (image:tag@sha256:...)", image)
+			}
+		case "USER":
+			lastUser = instruction
+		case "ADD":
+			for _, source := range addSources(instruction.Args) {
+				if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+					add(instruction, "medium", "dockerfile-add-url", "ADD Downloads from a URL",
+						fmt.Sprintf("ADD %s downloads a file without verifying it", source),
+						source, "Download with RUN curl and verify its checksum, or use ADD --checksum", "")
+				}
+			}
+		case "ENV":
+			for _, name := range envSecrets(instruction.Args) {
+				add(instruction, "high", "dockerfile-env-secret", "Secret in ENV",
+					fmt.Sprintf("ENV %s stores a credential in the image, readable by anyone who can pull it", name),
+					name, "Pass secrets at runtime, or with RUN --mount=type=secret at build time", "")
+			}
+		case "RUN":
+			if match := pipeToShellPattern.FindString(instruction.Args); match != "" {
+				add(instruction, "high", "dockerfile-pipe-to-shell", "Remote Script Piped to Shell",
+					"RUN pipes a downloaded script straight into a shell, running whatever the server returns",
+					match, "Download the script, verify its checksum, then run it", "")
+			}
+		}
+	}
+
+	if finalFrom.Command == "" {
+		return findings
+	}
+	if lastUser.Command == "" {
+		add(finalFrom, "high", "dockerfile-root-user", "Container Runs as Root",
+			"The final stage sets no USER, so the container runs as root",
+			"root", "Add a USER instruction with a non-root user to the final stage", "")
+	} else if user, _, _ := strings.Cut(lastUser.Args, ":"); user == "root" || user == "0" {
+		add(lastUser, "high", "dockerfile-root-user", "Container Runs as Root",
+			fmt.Sprintf("USER %s makes the container run as root", lastUser.Args),
+			lastUser.Args, "Switch to a non-root user at the end of the final stage", "")
+	}
+	return findings
+}
+
+// parseFrom returns the image and stage name of a FROM instruction's arguments,
+// such as "--platform=$BUILDPLATFORM golang:1.22 AS build"
+func parseFrom(args string) (image, stage string) {
+	var fields []string
+	for _, field := range strings.Fields(args) {
+		if !strings.HasPrefix(field, "--") {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return "", ""
+	}
+	if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+		stage = fields[2]
+	}
+	return fields[0], stage
+}
+
+// imageTag returns an image reference's tag, or "" if it has none. A colon
+// before the last slash is a registry port, not a tag.
+func imageTag(image string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if _, tag, ok := strings.Cut(name, ":"); ok {
+		return tag
+	}
+	return ""
+}
+
+// addSources returns an ADD instruction's sources: every argument but the
+// destination, in either the shell or the JSON form, and not counting flags
+func addSources(args string) []string {
+	var fields []string
+	if strings.HasPrefix(args, "[") {
+		for _, field := range strings.Split(strings.Trim(args, "[]"), ",") {
+			fields = append(fields, strings.Trim(strings.TrimSpace(field), `"`))
+		}
+	} else {
+		for _, field := range strings.Fields(args) {
+			if !strings.HasPrefix(field, "--") {
+				fields = append(fields, field)
+			}
+		}
+	}
+	if len(fields) < 2 {
+		return nil
+	}
+	return fields[:len(fields)-1]
+}
+
+// envSecrets returns the names an ENV instruction sets to a literal value that
+// look like credentials. Values taken from build arguments ($VAR) are not
+// reported, since the secret is not in the Dockerfile.
+func envSecrets(args string) []string {
+	values := make(map[string]string)
+	var names []string
+	if name, value, ok := strings.Cut(args, " "); ok && !strings.Contains(name, "=") {
+		// Legacy form: ENV NAME value with spaces
+		names, values[name] = []string{name}, strings.TrimSpace(value)
+	} else {
+		for _, pair := range strings.Fields(args) {
+			name, value, _ := strings.Cut(pair, "=")
+			names = append(names, name)
+			values[name] = value
+		}
+	}
+
+	var secrets []string
+	for _, name := range names {
+		value := strings.Trim(values[name], `"'`)
+		if secretEnvPattern.MatchString(name) && value != "" && !strings.HasPrefix(value, "$") {
+			secrets = append(secrets, name)
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const insecureDockerfile = `# syntax=docker/dockerfile:1
+FROM --platform=$BUILDPLATFORM golang:1.22 AS build
+RUN curl -fsSL https://example.com/install.sh \
+    | sudo bash
+ENV GOFLAGS=-mod=vendor \
+    API_TOKEN=abc123 DB_PASSWORD=$DB_PASSWORD
+
+FROM build AS test
+RUN go test ./...
+
+FROM ubuntu
+ADD --chown=app https://example.com/tool.tar.gz /opt/
+COPY --from=build /out/app /app
+USER root
+`
+
+func TestCheckDockerfile(t *testing.T) {
+	findings := checkDockerfile("Dockerfile", parseDockerfile(insecureDockerfile))
+
+	// Rule -> line it should be reported on
+	want := map[string]int{
+		"dockerfile-unpinned-image": 2,
+		"dockerfile-pipe-to-shell":  3,
+		"dockerfile-env-secret":     5,
+		"dockerfile-latest-tag":     11,
+		"dockerfile-add-url":        12,
+		"dockerfile-root-user":      14,
+	}
+	got := make(map[string]int)
+	for _, finding := range findings {
+		if finding.FilePath != "Dockerfile" || finding.Type != "dockerfile" {
+			t.Errorf("unexpected finding %+v", finding)
+		}
+		got[finding.Metadata["rule"].(string)] = finding.LineNumber
+		if finding.Metadata["rule"] == "dockerfile-env-secret" && finding.CurrentValue != "API_TOKEN" {
+			t.Errorf("expected only API_TOKEN to be reported, got %q", finding.CurrentValue)
+		}
+	}
+	if len(findings) != len(want) {
+		t.Errorf("expected %d findings, got %d: %v", len(want), len(findings), got)
+	}
+	for rule, line := range want {
+		if got[rule] != line {
+			t.Errorf("%s: expected line %d, got %d", rule, line, got[rule])
+		}
+	}
+}
+
+func TestCheckDockerfileSecure(t *testing.T) {
+	dockerfile := `FROM registry.local:5000/base:1.4@sha256:0123456789abcdef
+ENV PATH=/app/bin:$PATH LOG_LEVEL=info
+RUN useradd app
+USER app:app
+`
+	if findings := checkDockerfile("Dockerfile", parseDockerfile(dockerfile)); len(findings) != 0 {
+		t.Errorf("expected no findings, got %+v", findings)
+	}
+
+	// A missing USER is reported on the final stage's FROM
+	findings := checkDockerfile("Dockerfile", parseDockerfile("FROM scratch\nCOPY app /app\n"))
+	if len(findings) != 1 || findings[0].Metadata["rule"] != "dockerfile-root-user" || findings[0].LineNumber != 1 {
+		t.Errorf("expected a root user finding on line 1, got %+v", findings)
+	}
+}
+
+func TestScanDockerfilesRespectsExcludePatterns(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{
+		"service/Dockerfile",
+		"service/Dockerfile.dev",
+		"node_modules/pkg/Dockerfile",
+		".git/Dockerfile",
+		"docs/Dockerfile.md.log",
+	} {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("FROM alpine:3.19\nUSER nobody\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cs := NewContainerScanner(setupTestConfig())
+	cs.config.ExcludePatterns = []string{".git/", "node_modules", "*.log"}
+	cs.SetIaCRoots([]string{root})
+	findings := cs.scanDockerfiles()
+	if len(findings) != 2 {
+		t.Fatalf("expected unpinned image findings for the two service Dockerfiles, got %+v", findings)
+	}
+	for _, finding := range findings {
+		if filepath.Dir(finding.FilePath) != filepath.Join(root, "service") || finding.LineNumber != 1 {
+			t.Errorf("unexpected finding at %s:%d", finding.FilePath, finding.LineNumber)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"zerotrace/agent/internal/models"
+)
+
+// maxEvidenceBytes keeps each piece of evidence well under the API's default
+// EVIDENCE_MAX_SIZE; longer output is truncated
+const maxEvidenceBytes = 64 * 1024
+
+// textEvidence builds plain text evidence, truncated to maxEvidenceBytes
+func textEvidence(label, text string) models.Evidence {
+	if len(text) > maxEvidenceBytes {
+		text = text[:maxEvidenceBytes] + "\n[truncated]\n"
+	}
+	return models.Evidence{
+		Label:       label,
+		ContentType: "text/plain; charset=utf-8",
+		Data:        []byte(strings.ToValidUTF8(text, "�")),
+	}
+}
+
+// commandEvidence records a command line together with its output and error
+func commandEvidence(name string, args []string, output []byte, err error) models.Evidence {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ %s\n", strings.Join(append([]string{name}, args...), " "))
+	b.Write(output)
+	if err != nil {
+		fmt.Fprintf(&b, "\n[%v]\n", err)
+	}
+	return textEvidence("Output of "+name, b.String())
+}
+
+// command runs a check's command, keeping its output as evidence for the
+// finding the check may raise. The command is killed once it has run for
+// ConfigCommandTimeout or the scan's deadline passes, and the check is then
+// reported as unable to determine its setting, whatever it makes of the error.
+func (cs *ConfigScanner) command(name string, args ...string) ([]byte, error) {
+	parent := cs.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	timeout := cs.commandTimeout()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = time.Second // Don't wait on output pipes the killed command's children hold open
+	output, err := cmd.Output()
+	cs.lastCommand = commandLine(name, args)
+	if ctx.Err() != nil {
+		if parent.Err() != nil {
+			err = fmt.Errorf("%s was stopped at the configuration scan's deadline: %w", name, parent.Err())
+		} else {
+			err = fmt.Errorf("%s did not finish within %s: %w", name, timeout, ctx.Err())
+		}
+		if cs.timedOut == nil {
+			cs.timedOut = err
+		}
+	}
+	cs.evidence = append(cs.evidence, commandEvidence(name, args, output, err))
+	return output, err
+}
+
+// takeEvidence returns the evidence captured since the last call
+func (cs *ConfigScanner) takeEvidence() []models.Evidence {
+	evidence := cs.evidence
+	cs.evidence = nil
+	cs.lastCommand = ""
+	return evidence
+}
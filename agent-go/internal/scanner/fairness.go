@@ -0,0 +1,173 @@
+package scanner
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// fairnessMinGroupRows is the fewest rows a group needs for its positive
+	// rate to be compared; smaller groups are too noisy to judge
+	fairnessMinGroupRows = 10
+	// fairnessMaxGroups skips protected columns with more distinct values,
+	// which are identifiers or continuous values rather than groups
+	fairnessMaxGroups = 20
+)
+
+// BiasMetrics keys, prefixed with the protected column they describe
+const (
+	demographicParityDifference = "demographic_parity_difference" // Highest minus lowest group positive rate; 0 is parity
+	disparateImpactRatio        = "disparate_impact_ratio"        // Lowest over highest group positive rate; 1 is parity
+	fairnessSampleRowsMetric    = "sample_rows"
+)
+
+// positiveLabels are the label values counted as the favorable outcome, besides positive numbers
+var positiveLabels = map[string]bool{
+	"true": true, "yes": true, "y": true, "positive": true,
+	"approved": true, "accepted": true, "granted": true, "pass": true,
+}
+
+// groupOutcomes counts rows and favorable outcomes per group of one protected column
+type groupOutcomes map[string]*struct{ rows, positive int }
+
+// measureFairness computes group-fairness metrics over a sample of a labeled
+// CSV dataset: for each protected column, the demographic parity difference
+// and disparate impact ratio of the positive label rate across its groups. It
+// reads at most maxRows rows, and returns nil when the dataset has no label
+// column or no protected column.
+func measureFairness(path string, protected, labels []string, maxRows int) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	labelIndex := -1
+	protectedIndex := make(map[int]string)
+	for i, column := range header {
+		name := strings.ToLower(strings.TrimSpace(column))
+		if labelIndex < 0 && containsFold(labels, name) {
+			labelIndex = i
+		} else if containsFold(protected, name) {
+			protectedIndex[i] = strings.TrimSpace(column)
+		}
+	}
+	if labelIndex < 0 || len(protectedIndex) == 0 {
+		return nil, nil
+	}
+
+	outcomes := make(map[int]groupOutcomes, len(protectedIndex))
+	for i := range protectedIndex {
+		outcomes[i] = make(groupOutcomes)
+	}
+	rows := 0
+	for rows < maxRows {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", rows+1, err)
+		}
+		if labelIndex >= len(record) {
+			continue
+		}
+		rows++
+		positive := isPositiveLabel(record[labelIndex])
+
+		for i, groups := range outcomes {
+			if groups == nil || i >= len(record) {
+				continue
+			}
+			group := strings.TrimSpace(record[i])
+			counts, ok := groups[group]
+			if !ok {
+				if len(groups) == fairnessMaxGroups {
+					outcomes[i] = nil
+					continue
+				}
+				counts = &struct{ rows, positive int }{}
+				groups[group] = counts
+			}
+			counts.rows++
+			if positive {
+				counts.positive++
+			}
+		}
+	}
+
+	metrics := map[string]float64{fairnessSampleRowsMetric: float64(rows)}
+	for i, groups := range outcomes {
+		lowest, highest, compared := 1.0, 0.0, 0
+		for _, counts := range groups {
+			if counts.rows < fairnessMinGroupRows {
+				continue
+			}
+			rate := float64(counts.positive) / float64(counts.rows)
+			lowest = math.Min(lowest, rate)
+			highest = math.Max(highest, rate)
+			compared++
+		}
+		if compared < 2 {
+			continue
+		}
+
+		column := protectedIndex[i]
+		metrics[column+"."+demographicParityDifference] = highest - lowest
+		ratio := 1.0
+		if highest > 0 {
+			ratio = lowest / highest
+		}
+		metrics[column+"."+disparateImpactRatio] = ratio
+	}
+	return metrics, nil
+}
+
+// worstDisparateImpact returns the lowest disparate impact ratio among the
+// protected columns in metrics, and whether any was measured
+func worstDisparateImpact(metrics map[string]float64) (float64, string, bool) {
+	worst, worstColumn, found := 1.0, "", false
+	for key, value := range metrics {
+		column, ok := strings.CutSuffix(key, "."+disparateImpactRatio)
+		if !ok {
+			continue
+		}
+		if !found || value < worst || (value == worst && column < worstColumn) {
+			worst, worstColumn, found = value, column, true
+		}
+	}
+	return worst, worstColumn, found
+}
+
+func isPositiveLabel(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if positiveLabels[value] {
+		return true
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	return err == nil && n > 0
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDataset(t *testing.T, rows map[string][2]int) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("id,gender,income,approved\n")
+	id := 0
+	for group, counts := range rows {
+		total, positive := counts[0], counts[1]
+		for i := 0; i < total; i++ {
+			id++
+			fmt.Fprintf(&b, "%d,%s,50000,%t\n", id, group, i < positive)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "loans.csv")
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMeasureFairness(t *testing.T) {
+	// 60% of men approved, 30% of women; 3 unknowns are too few to compare
+	path := writeDataset(t, map[string][2]int{"M": {100, 60}, "F": {100, 30}, "X": {3, 3}})
+
+	metrics, err := measureFairness(path, []string{"gender"}, []string{"approved"}, 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := metrics["gender."+demographicParityDifference]; math.Abs(got-0.3) > 1e-9 {
+		t.Errorf("demographic parity difference = %v, want 0.3", got)
+	}
+	if got := metrics["gender."+disparateImpactRatio]; math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("disparate impact ratio = %v, want 0.5", got)
+	}
+	if got := metrics[fairnessSampleRowsMetric]; got != 203 {
+		t.Errorf("sample rows = %v, want 203", got)
+	}
+
+	ratio, column, ok := worstDisparateImpact(metrics)
+	if !ok || column != "gender" || math.Abs(ratio-0.5) > 1e-9 {
+		t.Errorf("worstDisparateImpact() = %v, %q, %v", ratio, column, ok)
+	}
+}
+
+func TestMeasureFairnessIsBoundedAndOptIn(t *testing.T) {
+	path := writeDataset(t, map[string][2]int{"M": {100, 50}, "F": {100, 50}})
+
+	metrics, err := measureFairness(path, []string{"gender"}, []string{"approved"}, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := metrics[fairnessSampleRowsMetric]; got != 50 {
+		t.Errorf("sample rows = %v, want 50", got)
+	}
+
+	// Without a label column there is nothing to measure
+	metrics, err = measureFairness(path, []string{"gender"}, []string{"outcome"}, 10000)
+	if err != nil || metrics != nil {
+		t.Errorf("measureFairness() without a label = %v, %v; want nil, nil", metrics, err)
+	}
+
+	// Disabled unless configured
+	data := &TrainingDataInfo{Path: path}
+	NewAIMLScanner(setupTestConfig(), nil).measureDatasetFairness(data)
+	if data.BiasMetrics != nil {
+		t.Errorf("fairness measured while AIML_FAIRNESS_METRICS is off: %v", data.BiasMetrics)
+	}
+}
@@ -0,0 +1,588 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"zerotrace/agent/internal/models"
+)
+
+// gitlabAdvisoryProject is the GitLab Advisory Database's public mirror
+const gitlabAdvisoryProject = "gitlab-org/advisories-community"
+
+// maxGitLabAdvisories is the most advisories read for one package
+const maxGitLabAdvisories = 100
+
+// gitlabEcosystems are the advisory database's directories, by OSV ecosystem
+var gitlabEcosystems = map[string]string{
+	"Go":        "go",
+	"npm":       "npm",
+	"PyPI":      "pypi",
+	"RubyGems":  "gem",
+	"Maven":     "maven",
+	"NuGet":     "nuget",
+	"Packagist": "packagist",
+}
+
+// GitLabAdvisorySource implements CVE data from the GitLab Advisory Database,
+// read through the GitLab API from the repository it is published in. Each
+// package's advisories are a directory of YAML files with the affected
+// version range, which installed versions are matched against.
+type GitLabAdvisorySource struct {
+	baseURL    string
+	project    string
+	token      string // Needed to look advisories up by ID, which uses GitLab's search
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string][]gitlabAdvisory // Advisories by package directory, for as long as the agent runs
+}
+
+// NewGitLabAdvisorySource creates a new GitLab advisory source. The token is
+// optional, and only needed for GetCVE.
+func NewGitLabAdvisorySource(token string) *GitLabAdvisorySource {
+	return &GitLabAdvisorySource{
+		baseURL: "https://gitlab.com/api/v4",
+		project: gitlabAdvisoryProject,
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		cache: make(map[string][]gitlabAdvisory),
+	}
+}
+
+// gitlabAdvisory is an advisory file of the GitLab Advisory Database
+type gitlabAdvisory struct {
+	Identifier    string
+	Identifiers   []string
+	Title         string
+	Description   string
+	AffectedRange string
+	FixedVersions []string
+	Solution      string
+	URLs          []string
+	CVSSv3        string
+}
+
+// GetCVE retrieves an advisory by its CVE or GHSA ID with GitLab's search,
+// which requires a token
+func (g *GitLabAdvisorySource) GetCVE(cveID string) (*models.Vulnerability, error) {
+	if g.token == "" {
+		return nil, fmt.Errorf("looking up %s in the GitLab Advisory Database requires a GitLab token", cveID)
+	}
+	var matches []struct {
+		Path string `json:"path"`
+	}
+	query := url.Values{"scope": {"blobs"}, "search": {cveID}}
+	if err := g.get(g.projectURL("/search?"+query.Encode()), &matches); err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		if !strings.HasSuffix(match.Path, ".yml") {
+			continue
+		}
+		advisory, err := g.advisory(match.Path)
+		if err != nil {
+			return nil, err
+		}
+		if advisory.hasID(cveID) {
+			vuln := advisory.toVulnerability(nil)
+			return &vuln, nil
+		}
+	}
+	return nil, fmt.Errorf("CVE %s not found", cveID)
+}
+
+// SearchCVEs searches GitLab advisories
+func (g *GitLabAdvisorySource) SearchCVEs(query string) ([]models.Vulnerability, error) {
+	// The database is a repository of files; advisories are found by package
+	return []models.Vulnerability{}, nil
+}
+
+// GetRecentCVEs gets recent GitLab advisories
+func (g *GitLabAdvisorySource) GetRecentCVEs(limit int) ([]models.Vulnerability, error) {
+	// The database is a repository of files; advisories are found by package
+	return []models.Vulnerability{}, nil
+}
+
+// PackageAdvisories matches packages against the advisories in their
+// directories. Packages of ecosystems the database doesn't cover are skipped.
+func (g *GitLabAdvisorySource) PackageAdvisories(packages []PackageQuery) ([]models.Vulnerability, error) {
+	var vulnerabilities []models.Vulnerability
+	for i, pkg := range packages {
+		directory, ok := gitlabEcosystems[pkg.Ecosystem]
+		if !ok {
+			continue
+		}
+		name := pkg.Name
+		switch pkg.Ecosystem {
+		case "Maven":
+			name = strings.ReplaceAll(name, ":", "/")
+		case "PyPI":
+			name = strings.ToLower(name)
+		}
+		advisories, err := g.packageAdvisories(directory + "/" + name)
+		if err != nil {
+			return nil, err
+		}
+		for _, advisory := range advisories {
+			if versionInRange(pkg.Version, advisory.AffectedRange) {
+				vulnerabilities = append(vulnerabilities, advisory.toVulnerability(&packages[i]))
+			}
+		}
+	}
+	return vulnerabilities, nil
+}
+
+// packageAdvisories reads the advisories in a package's directory
+func (g *GitLabAdvisorySource) packageAdvisories(path string) ([]gitlabAdvisory, error) {
+	g.mu.Lock()
+	cached, ok := g.cache[path]
+	g.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var files []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	query := url.Values{"path": {path}, "per_page": {strconv.Itoa(maxGitLabAdvisories)}}
+	err := g.get(g.projectURL("/repository/tree?"+query.Encode()), &files)
+	if err != nil && err != errGitLabNotFound {
+		return nil, err
+	}
+	// A package with no directory has no advisories
+	advisories := []gitlabAdvisory{}
+	for _, file := range files {
+		if file.Type != "blob" || !strings.HasSuffix(file.Path, ".yml") {
+			continue
+		}
+		advisory, err := g.advisory(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, advisory)
+	}
+
+	g.mu.Lock()
+	g.cache[path] = advisories
+	g.mu.Unlock()
+	return advisories, nil
+}
+
+// advisory reads and parses an advisory file
+func (g *GitLabAdvisorySource) advisory(path string) (gitlabAdvisory, error) {
+	resp, err := g.request(g.projectURL("/repository/files/" + url.PathEscape(path) + "/raw?ref=main"))
+	if err != nil {
+		return gitlabAdvisory{}, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return gitlabAdvisory{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return parseGitLabAdvisory(data), nil
+}
+
+// errGitLabNotFound is returned for a path the database has no file at
+var errGitLabNotFound = errors.New("not found in the GitLab Advisory Database")
+
+// projectURL is the API URL of a path under the advisory database's project
+func (g *GitLabAdvisorySource) projectURL(path string) string {
+	return g.baseURL + "/projects/" + url.PathEscape(g.project) + path
+}
+
+// get fetches a GitLab API URL and decodes its JSON response into v
+func (g *GitLabAdvisorySource) get(url string, v any) error {
+	resp, err := g.request(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse GitLab response: %w", err)
+	}
+	return nil
+}
+
+// request sends a GET request to the GitLab API, failing unless it succeeds
+func (g *GitLabAdvisorySource) request(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if g.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", g.token)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query GitLab advisories: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, errGitLabNotFound
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("GitLab returned status %d", resp.StatusCode)
+	}
+}
+
+// hasID reports whether the advisory is known by id
+func (a gitlabAdvisory) hasID(id string) bool {
+	for _, identifier := range append([]string{a.Identifier}, a.Identifiers...) {
+		if strings.EqualFold(identifier, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// toVulnerability converts a GitLab advisory, as it affects pkg when one is
+// given. Its CVE identifier, if it has one, is the vulnerability's ID.
+func (a gitlabAdvisory) toVulnerability(pkg *PackageQuery) models.Vulnerability {
+	ids := appendUnique([]string{a.Identifier}, a.Identifiers...)
+	vuln := models.Vulnerability{
+		ID:              a.Identifier,
+		Type:            "cve",
+		Severity:        "unknown",
+		Title:           a.Title,
+		Description:     a.Description,
+		CVSSVector:      a.CVSSv3,
+		Remediation:     a.Solution,
+		References:      a.URLs,
+		PatchedVersions: a.FixedVersions,
+		Status:          "open",
+		CreatedAt:       time.Now(),
+		EnrichmentData: map[string]any{
+			"aliases": ids,
+			"sources": []string{"gitlab"},
+		},
+	}
+	for _, id := range ids {
+		if strings.HasPrefix(id, "CVE-") {
+			vuln.ID = id
+			vuln.CVEID = id
+			break
+		}
+	}
+	if a.AffectedRange != "" {
+		vuln.AffectedVersions = []string{a.AffectedRange}
+	}
+	if vuln.Title == "" {
+		vuln.Title = vuln.ID
+	}
+	if pkg != nil {
+		vuln.PackageName = pkg.Name
+		vuln.PackageVersion = pkg.Version
+	}
+	return vuln
+}
+
+// parseGitLabAdvisory reads an advisory file. The files only use top-level
+// keys with plain, quoted or block scalars and lists of scalars, so that
+// subset of YAML is all that is parsed.
+func parseGitLabAdvisory(data []byte) gitlabAdvisory {
+	scalars, lists := parseYAMLSubset(string(data))
+	return gitlabAdvisory{
+		Identifier:    scalars["identifier"],
+		Identifiers:   lists["identifiers"],
+		Title:         scalars["title"],
+		Description:   scalars["description"],
+		AffectedRange: scalars["affected_range"],
+		FixedVersions: lists["fixed_versions"],
+		Solution:      scalars["solution"],
+		URLs:          lists["urls"],
+		CVSSv3:        scalars["cvss_v3"],
+	}
+}
+
+// parseYAMLSubset reads the top-level keys of a YAML mapping whose values are
+// scalars or lists of scalars. Nested mappings are read as text and anchors,
+// tags and flow mappings aren't supported.
+func parseYAMLSubset(text string) (map[string]string, map[string][]string) {
+	scalars := make(map[string]string)
+	lists := make(map[string][]string)
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" || line[0] == ' ' || line[0] == '#' || line[0] == '-' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		// The indented lines that follow belong to the value, as do list
+		// items, which needn't be indented
+		var block []string
+		for i+1 < len(lines) && (lines[i+1] == "" || lines[i+1][0] == ' ' || strings.HasPrefix(lines[i+1], "- ")) {
+			i++
+			block = append(block, lines[i])
+		}
+		for len(block) > 0 && strings.TrimSpace(block[len(block)-1]) == "" {
+			block = block[:len(block)-1]
+		}
+
+		switch {
+		case value == "" && len(block) > 0 && strings.HasPrefix(strings.TrimSpace(block[0]), "- "):
+			for _, item := range block {
+				if item = strings.TrimSpace(item); strings.HasPrefix(item, "- ") {
+					lists[key] = append(lists[key], yamlScalar(strings.TrimSpace(item[2:])))
+				}
+			}
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					lists[key] = append(lists[key], yamlScalar(item))
+				}
+			}
+		case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+			scalars[key] = yamlBlockScalar(block, value[0] == '|')
+		default:
+			// Plain and quoted scalars may continue on indented lines,
+			// which fold into one line
+			parts := []string{value}
+			for _, continuation := range block {
+				parts = append(parts, strings.TrimSpace(continuation))
+			}
+			scalars[key] = yamlScalar(strings.TrimSpace(strings.Join(parts, " ")))
+		}
+	}
+	return scalars, lists
+}
+
+// yamlScalar unquotes a plain, single-quoted or double-quoted scalar
+func yamlScalar(value string) string {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '"' && value[len(value)-1] == '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+			return value[1 : len(value)-1]
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// yamlBlockScalar joins a literal (|) or folded (>) block scalar's lines,
+// removing their common indentation
+func yamlBlockScalar(lines []string, literal bool) string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " ")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if indent >= 0 && len(line) >= indent {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimSpace(line)
+		}
+	}
+	if literal {
+		return strings.Join(lines, "\n")
+	}
+	// Folded lines join with spaces, and blank lines separate paragraphs
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.Join(lines, "\n"), "\n\n") {
+		paragraphs = append(paragraphs, strings.Join(strings.Fields(paragraph), " "))
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+// mavenInterval matches one interval of a Maven version range, such as
+// [1.0,2.0) or [1.2]
+var mavenInterval = regexp.MustCompile(`[\[(]([^\])]*)[\])]`)
+
+// versionInRange reports whether version is in a GitLab affected_range.
+// Ranges are alternatives separated by ||, each either comparisons that must
+// all hold (">=1.0 <1.2.3", ">=1.0,<1.2.3") or Maven and NuGet intervals
+// ("[1.0,2.0),[3.0,3.1)").
+func versionInRange(version, affectedRange string) bool {
+	for _, alternative := range strings.Split(affectedRange, "||") {
+		alternative = strings.TrimSpace(alternative)
+		if alternative == "" {
+			continue
+		}
+		if alternative == "*" {
+			return true
+		}
+		if alternative[0] == '[' || alternative[0] == '(' {
+			for _, interval := range mavenInterval.FindAllStringSubmatch(alternative, -1) {
+				if versionInInterval(version, interval[0], interval[1]) {
+					return true
+				}
+			}
+			continue
+		}
+		if versionMatchesAll(version, alternative) {
+			return true
+		}
+	}
+	return false
+}
+
+// versionInInterval checks a Maven interval, whose bounds are inclusive with
+// square brackets and either may be empty for no bound
+func versionInInterval(version, interval, bounds string) bool {
+	lower, upper, isRange := strings.Cut(bounds, ",")
+	lower, upper = strings.TrimSpace(lower), strings.TrimSpace(upper)
+	if !isRange {
+		return compareAdvisoryVersions(version, lower) == 0
+	}
+	if lower != "" {
+		c := compareAdvisoryVersions(version, lower)
+		if c < 0 || (c == 0 && interval[0] == '(') {
+			return false
+		}
+	}
+	if upper != "" {
+		c := compareAdvisoryVersions(version, upper)
+		if c > 0 || (c == 0 && interval[len(interval)-1] == ')') {
+			return false
+		}
+	}
+	return true
+}
+
+// versionMatchesAll checks comparisons separated by commas or spaces, such as
+// ">=1.0 <1.2.3"; a version without an operator must match exactly
+func versionMatchesAll(version, comparisons string) bool {
+	// Operators may be separated from their version, as in ">= 1.0"
+	for _, op := range []string{">= ", "<= ", "> ", "< ", "== ", "!= ", "= "} {
+		comparisons = strings.ReplaceAll(comparisons, op, strings.TrimSpace(op))
+	}
+	fields := strings.FieldsFunc(comparisons, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	if len(fields) == 0 {
+		return false
+	}
+	for _, field := range fields {
+		op := field[:len(field)-len(strings.TrimLeft(field, "<>=!"))]
+		c := compareAdvisoryVersions(version, field[len(op):])
+		var ok bool
+		switch op {
+		case ">=":
+			ok = c >= 0
+		case ">":
+			ok = c > 0
+		case "<=":
+			ok = c <= 0
+		case "<":
+			ok = c < 0
+		case "!=":
+			ok = c != 0
+		default:
+			ok = c == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compareAdvisoryVersions compares versions by their runs of digits and of
+// letters, numerically and alphabetically. A version that continues with
+// letters where the other ends, as 1.0.0-rc1 does 1.0.0, is a pre-release
+// and older; one that continues with digits is newer.
+func compareAdvisoryVersions(a, b string) int {
+	as, bs := versionTokens(a), versionTokens(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		if i >= len(as) {
+			return -trailingOrder(bs[i:])
+		}
+		if i >= len(bs) {
+			return trailingOrder(as[i:])
+		}
+		x, y := as[i], bs[i]
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		switch {
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case xErr == nil:
+			return 1 // A release number outranks a pre-release label
+		case yErr == nil:
+			return -1
+		default:
+			if c := strings.Compare(strings.ToLower(x), strings.ToLower(y)); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// trailingOrder is how a version compares with one it extends by tokens:
+// older when they start with a pre-release label, newer when they carry a
+// non-zero number, and equal for trailing zeros
+func trailingOrder(tokens []string) int {
+	for _, token := range tokens {
+		n, err := strconv.Atoi(token)
+		if err != nil {
+			return -1
+		}
+		if n != 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionTokens splits a version into its runs of digits and of letters
+func versionTokens(version string) []string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	var tokens []string
+	start := -1
+	for i, r := range version + "." {
+		isDigit := unicode.IsDigit(r)
+		isLetter := unicode.IsLetter(r)
+		if start >= 0 {
+			prevDigit := unicode.IsDigit(rune(version[start]))
+			if (isDigit && prevDigit) || (isLetter && !prevDigit) {
+				continue
+			}
+			tokens = append(tokens, version[start:i])
+			start = -1
+		}
+		if isDigit || isLetter {
+			start = i
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const gitlabAdvisoryYAML = `---
+identifier: "CVE-2021-23337"
+identifiers:
+- "GHSA-35jh-r3h4-6jhm"
+- "CVE-2021-23337"
+package_slug: "npm/lodash"
+title: "Command Injection"
+description: "Lodash versions prior to 4.17.21 are vulnerable to Command Injection
+  via the template function."
+date: "2021-05-06"
+affected_range: "<4.17.21"
+fixed_versions:
+- "4.17.21"
+solution: 'Upgrade to version 4.17.21 or above.'
+urls:
+- "https://nvd.nist.gov/vuln/detail/CVE-2021-23337"
+cvss_v3: "CVSS:3.1/AV:N/AC:L/PR:H/UI:N/S:U/C:H/I:H/A:H"
+notes: |
+  First line
+  second line
+`
+
+func TestGitLabPackageAdvisories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.EscapedPath()
+		switch {
+		case strings.HasSuffix(path, "/repository/tree") && r.URL.Query().Get("path") == "npm/lodash":
+			w.Write([]byte(`[{"path":"npm/lodash/CVE-2021-23337.yml","type":"blob"},{"path":"npm/lodash/README","type":"blob"}]`))
+		case strings.HasSuffix(path, "/repository/files/npm%2Flodash%2FCVE-2021-23337.yml/raw"):
+			w.Write([]byte(gitlabAdvisoryYAML))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	gitlab := NewGitLabAdvisorySource("")
+	gitlab.baseURL = server.URL
+	vulns, err := gitlab.PackageAdvisories([]PackageQuery{
+		{"npm", "lodash", "4.17.20"},
+		{"npm", "lodash", "4.17.21"},
+		{"npm", "express", "4.0.0"},
+		{"Debian", "openssl", "3.0.11"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vulns) != 1 {
+		t.Fatalf("expected only the vulnerable lodash version, got %+v", vulns)
+	}
+	v := vulns[0]
+	if v.ID != "CVE-2021-23337" || v.PackageVersion != "4.17.20" || v.Remediation != "Upgrade to version 4.17.21 or above." {
+		t.Errorf("unexpected advisory %+v", v)
+	}
+	if v.Description != "Lodash versions prior to 4.17.21 are vulnerable to Command Injection via the template function." {
+		t.Errorf("expected the continued description folded, got %q", v.Description)
+	}
+	if aliases, _ := v.EnrichmentData["aliases"].([]string); len(aliases) != 2 {
+		t.Errorf("expected the CVE and GHSA aliases, got %v", aliases)
+	}
+
+	if _, err := gitlab.GetCVE("CVE-2021-23337"); err == nil {
+		t.Error("expected lookup by ID without a token to fail")
+	}
+}
+
+func TestParseYAMLSubset(t *testing.T) {
+	scalars, lists := parseYAMLSubset(gitlabAdvisoryYAML + "tags: [a, 'b c']\nfolded: >\n  one\n  two\n\n  three\n")
+	if scalars["notes"] != "First line\nsecond line" {
+		t.Errorf("literal block = %q", scalars["notes"])
+	}
+	if scalars["folded"] != "one two\nthree" {
+		t.Errorf("folded block = %q", scalars["folded"])
+	}
+	if len(lists["tags"]) != 2 || lists["tags"][1] != "b c" {
+		t.Errorf("flow list = %q", lists["tags"])
+	}
+	if scalars["date"] != "2021-05-06" || len(lists["urls"]) != 1 {
+		t.Errorf("unexpected scalars %v and lists %v", scalars, lists)
+	}
+}
+
+func TestVersionInRange(t *testing.T) {
+	for _, tc := range []struct {
+		version, affectedRange string
+		want                   bool
+	}{
+		{"4.17.20", "<4.17.21", true},
+		{"4.17.21", "<4.17.21", false},
+		{"1.5.0", ">=1.0 <1.2.3||>=1.5,<1.6", true},
+		{"1.3.0", ">=1.0 <1.2.3||>=1.5,<1.6", false},
+		{"2.0.0", ">= 2.0.0, < 2.1", true},
+		{"2.0.0-rc1", ">=2.0.0", false},
+		{"1.10", ">1.9", true},
+		{"1.0", "==1.0.0", true},
+		{"2.5", "[1.0,2.0),[2.5]", true},
+		{"2.0", "[1.0,2.0)", false},
+		{"3.0", "(,3.0]", true},
+		{"v1.2.3", "<v1.2.4", true},
+		{"1.0", "*", true},
+		{"1.0", "", false},
+	} {
+		if got := versionInRange(tc.version, tc.affectedRange); got != tc.want {
+			t.Errorf("versionInRange(%q, %q) = %v, want %v", tc.version, tc.affectedRange, got, tc.want)
+		}
+	}
+}
@@ -14,7 +14,8 @@ import (
 
 // IoTOTScanner handles IoT and OT security scanning
 type IoTOTScanner struct {
-	config *config.Config
+	config       *config.Config
+	capabilities *CapabilityReport // What the last scan could and couldn't check
 }
 
 // IoTOTFinding represents an IoT/OT security finding
@@ -88,7 +89,8 @@ type FirmwareInfo struct {
 // NewIoTOTScanner creates a new IoT/OT security scanner
 func NewIoTOTScanner(cfg *config.Config) *IoTOTScanner {
 	return &IoTOTScanner{
-		config: cfg,
+		config:       cfg,
+		capabilities: newCapabilityReport(),
 	}
 }
 
@@ -99,6 +101,8 @@ func (ios *IoTOTScanner) Scan() ([]IoTOTFinding, []DeviceInfo, []ProtocolInfo, [
 	var protocols []ProtocolInfo
 	var firmwares []FirmwareInfo
 
+	ios.capabilities = newCapabilityReport()
+
 	// Discover IoT/OT devices
 	discoveredDevices := ios.discoverDevices()
 	devices = append(devices, discoveredDevices...)
@@ -188,7 +192,7 @@ func (ios *IoTOTScanner) discoverBluetoothDevices() []DeviceInfo {
 	var devices []DeviceInfo
 
 	// Check if Bluetooth is available
-	if !ios.isCommandAvailable("bluetoothctl") {
+	if !ios.capabilities.require("bluetooth_devices", "bluetoothctl") {
 		return devices
 	}
 
@@ -228,7 +232,7 @@ func (ios *IoTOTScanner) discoverUSBDevices() []DeviceInfo {
 	var devices []DeviceInfo
 
 	// Check if lsusb is available
-	if !ios.isCommandAvailable("lsusb") {
+	if !ios.capabilities.require("usb_devices", "lsusb") {
 		return devices
 	}
 
@@ -728,9 +732,8 @@ func (ios *IoTOTScanner) scanFirmware(firmware FirmwareInfo) []IoTOTFinding {
 	return findings
 }
 
-// isCommandAvailable checks if a command is available
-func (ios *IoTOTScanner) isCommandAvailable(command string) bool {
-	cmd := exec.Command("which", command)
-	err := cmd.Run()
-	return err == nil
+// Capabilities reports which parts of the last scan ran and which were
+// skipped because bluetoothctl or lsusb is not installed
+func (ios *IoTOTScanner) Capabilities() CapabilityReport {
+	return *ios.capabilities
 }
@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"zerotrace/agent/internal/models"
+)
+
+// debianDocDir holds each installed Debian package's copyright file
+var debianDocDir = "/usr/share/doc"
+
+// spdxIdentifiers are the SPDX license identifiers package metadata most
+// often declares, keyed by lower case so they can be written canonically
+var spdxIdentifiers = func() map[string]string {
+	ids := make(map[string]string)
+	for _, id := range []string{
+		"0BSD", "AGPL-3.0-only", "AGPL-3.0-or-later", "Apache-1.1", "Apache-2.0",
+		"Artistic-1.0", "Artistic-2.0", "BSD-2-Clause", "BSD-3-Clause", "BSL-1.0",
+		"BUSL-1.1", "CC-BY-4.0", "CC-BY-SA-4.0", "CC0-1.0", "CDDL-1.0", "curl",
+		"Elastic-2.0", "EPL-1.0", "EPL-2.0", "GPL-2.0-only", "GPL-2.0-or-later",
+		"GPL-3.0-only", "GPL-3.0-or-later", "ISC", "LGPL-2.0-only",
+		"LGPL-2.0-or-later", "LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0-only",
+		"LGPL-3.0-or-later", "MIT", "MPL-1.1", "MPL-2.0", "OpenSSL", "PSF-2.0",
+		"Python-2.0", "SSPL-1.0", "Unlicense", "Vim", "WTFPL", "X11", "Zlib",
+	} {
+		ids[strings.ToLower(id)] = id
+	}
+	return ids
+}()
+
+// licenseAliases maps the names Debian and RPM packages use for a license
+// to its SPDX identifier
+var licenseAliases = map[string]string{
+	"asl 2.0":              "Apache-2.0",
+	"apache":               "Apache-2.0",
+	"apache-2":             "Apache-2.0",
+	"apache 2.0":           "Apache-2.0",
+	"apache license 2.0":   "Apache-2.0",
+	"expat":                "MIT",
+	"bsd-2-clause-freebsd": "BSD-2-Clause",
+	"mplv1.1":              "MPL-1.1",
+	"mplv2.0":              "MPL-2.0",
+	"psf":                  "PSF-2.0",
+	"boost":                "BSL-1.0",
+	"zlib/libpng":          "Zlib",
+}
+
+// gnuLicense matches GNU license names in the Debian (GPL-2+) and RPM
+// (GPLv2+) styles, whose SPDX identifier says whether later versions apply
+var gnuLicense = regexp.MustCompile(`(?i)^(A?GPL|LGPL)[-v]?(\d)(?:\.(\d))?(\+)?$`)
+
+// normalizeLicense rewrites a license as package metadata declares it into
+// an SPDX expression, leaving names it doesn't recognize as they are.
+// "GPLv2+ and LGPLv2+" becomes "GPL-2.0-or-later AND LGPL-2.0-or-later".
+// Metadata that declares no license normalizes to "".
+func normalizeLicense(raw string) string {
+	raw = strings.TrimSpace(raw)
+	switch strings.ToLower(raw) {
+	case "", "unknown", "none", "noassertion", "(none)":
+		return ""
+	}
+
+	fields := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(raw))
+	var expr, name []string
+	flush := func() {
+		if len(name) > 0 {
+			expr = append(expr, spdxLicense(strings.Join(name, " ")))
+			name = name[:0]
+		}
+	}
+	for _, field := range fields {
+		switch op := strings.ToUpper(field); op {
+		case "AND", "OR", "WITH", "(", ")":
+			flush()
+			expr = append(expr, op)
+		default:
+			name = append(name, field)
+		}
+	}
+	flush()
+	return strings.NewReplacer("( ", "(", " )", ")").Replace(strings.Join(expr, " "))
+}
+
+// spdxLicense returns the SPDX identifier for a single license name
+func spdxLicense(name string) string {
+	lower := strings.ToLower(name)
+	if id, ok := spdxIdentifiers[lower]; ok {
+		return id
+	}
+	if id, ok := licenseAliases[lower]; ok {
+		return id
+	}
+	if m := gnuLicense.FindStringSubmatch(name); m != nil {
+		minor := m[3]
+		if minor == "" {
+			minor = "0"
+		}
+		suffix := "-only"
+		if m[4] != "" {
+			suffix = "-or-later"
+		}
+		return strings.ToUpper(m[1]) + "-" + m[2] + "." + minor + suffix
+	}
+	return name
+}
+
+// packageLicense detects an installed package's license: the license the
+// package manager reported, or for Debian packages the license their
+// machine-readable copyright file declares for the package as a whole
+func packageLicense(app models.InstalledApp) string {
+	if app.License != "" {
+		return normalizeLicense(app.License)
+	}
+	if app.Type == "apt" {
+		return debianCopyrightLicense(filepath.Join(debianDocDir, app.Name, "copyright"))
+	}
+	return ""
+}
+
+// debianCopyrightLicense reads the license from a Debian copyright file in
+// the machine-readable (DEP-5) format: the one covering "Files: *", or
+// else the first one it declares. Free-form copyright files have none.
+func debianCopyrightLicense(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "Format:") {
+		return ""
+	}
+
+	var first string
+	allFiles := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			allFiles = false
+		case strings.HasPrefix(line, "Files:"):
+			allFiles = strings.TrimSpace(strings.TrimPrefix(line, "Files:")) == "*"
+		case strings.HasPrefix(line, "License:"):
+			license := strings.TrimSpace(strings.TrimPrefix(line, "License:"))
+			if allFiles {
+				return normalizeLicense(license)
+			}
+			if first == "" {
+				first = license
+			}
+		}
+	}
+	return normalizeLicense(first)
+}
+
+// nodeModuleLicense reads the license an installed npm package declares,
+// from its package.json under the node_modules beside the manifest that
+// depends on it. Older packages declare it as an object, or a list of them.
+func nodeModuleLicense(manifestPath, name string) string {
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(manifestPath), "node_modules", name, "package.json"))
+	if err != nil {
+		return ""
+	}
+
+	type license struct {
+		Type string `json:"type"`
+	}
+	var pkg struct {
+		License  json.RawMessage `json:"license"`
+		Licenses []license       `json:"licenses"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return ""
+	}
+
+	var spdx string
+	var object license
+	switch {
+	case json.Unmarshal(pkg.License, &spdx) == nil:
+		return normalizeLicense(spdx)
+	case json.Unmarshal(pkg.License, &object) == nil:
+		return normalizeLicense(object.Type)
+	}
+	var types []string
+	for _, l := range pkg.Licenses {
+		if l.Type != "" {
+			types = append(types, l.Type)
+		}
+	}
+	return normalizeLicense(strings.Join(types, " OR "))
+}
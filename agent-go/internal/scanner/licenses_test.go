@@ -0,0 +1,88 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"zerotrace/agent/internal/models"
+)
+
+func TestNormalizeLicense(t *testing.T) {
+	cases := map[string]string{
+		"mit":                                  "MIT",
+		"ASL 2.0":                              "Apache-2.0",
+		"GPLv2+ and LGPLv2+":                   "GPL-2.0-or-later AND LGPL-2.0-or-later",
+		"GPL-2+ or Artistic-1.0":               "GPL-2.0-or-later OR Artistic-1.0",
+		"LGPL-2.1":                             "LGPL-2.1-only",
+		"(MIT OR apache-2.0)":                  "(MIT OR Apache-2.0)",
+		"GPL-2.0 with Classpath-exception-2.0": "GPL-2.0-only WITH Classpath-exception-2.0",
+		"Public Domain":                        "Public Domain",
+		"UNKNOWN":                              "",
+	}
+	for raw, want := range cases {
+		if got := normalizeLicense(raw); got != want {
+			t.Errorf("normalizeLicense(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestPackageLicenseReadsDebianCopyright(t *testing.T) {
+	dir := t.TempDir()
+	previous := debianDocDir
+	debianDocDir = dir
+	t.Cleanup(func() { debianDocDir = previous })
+
+	write := func(pkg, content string) {
+		if err := os.MkdirAll(filepath.Join(dir, pkg), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, pkg, "copyright"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("readline", `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+
+Files: examples/*
+License: BSD-3-clause
+
+Files: *
+Copyright: Free Software Foundation
+License: GPL-3+
+ This program is free software...
+`)
+	write("legacy", "This package was debianized by someone.\nLicense: MIT\n")
+
+	if got := packageLicense(models.InstalledApp{Name: "readline", Type: "apt"}); got != "GPL-3.0-or-later" {
+		t.Errorf("expected the license covering every file, got %q", got)
+	}
+	if got := packageLicense(models.InstalledApp{Name: "legacy", Type: "apt"}); got != "" {
+		t.Errorf("expected no license from a free-form copyright file, got %q", got)
+	}
+	if got := packageLicense(models.InstalledApp{Name: "bash", Type: "yum", License: "GPLv3+"}); got != "GPL-3.0-or-later" {
+		t.Errorf("expected the license the package manager reported, got %q", got)
+	}
+}
+
+func TestNodeModuleLicense(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "package.json")
+	for name, content := range map[string]string{
+		"left-pad": `{"name": "left-pad", "license": "WTFPL"}`,
+		"old":      `{"name": "old", "license": {"type": "MIT"}}`,
+		"older":    `{"name": "older", "licenses": [{"type": "MIT"}, {"type": "Apache-2.0"}]}`,
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, "node_modules", name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "node_modules", name, "package.json"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string]string{"left-pad": "WTFPL", "old": "MIT", "older": "MIT OR Apache-2.0", "missing": ""} {
+		if got := nodeModuleLicense(manifest, name); got != want {
+			t.Errorf("nodeModuleLicense(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
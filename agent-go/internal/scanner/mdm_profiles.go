@@ -0,0 +1,312 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"zerotrace/agent/internal/models"
+)
+
+// mdmProfile is a configuration profile installed on a Mac, as listed by
+// `profiles -P -o stdout-xml`
+type mdmProfile struct {
+	Identifier  string
+	UUID        string
+	DisplayName string
+	Payloads    []mdmPayload
+}
+
+// mdmPayload is one payload of a configuration profile: the settings it
+// manages for one preference domain, such as com.apple.security.firewall
+type mdmPayload struct {
+	Type    string
+	Content map[string]interface{}
+}
+
+// mdmControl is a security control an MDM profile can enforce, identified by
+// the payload type managing it and whether a payload's settings enforce it
+type mdmControl struct {
+	payloadType string
+	expected    string // Settings the control is enforced with, for check results
+	enforces    func(content map[string]interface{}) bool
+}
+
+// The controls ConfigScanner accepts as enforced by a profile, whatever the
+// local setting says
+var (
+	mdmFileVault = mdmControl{
+		payloadType: "com.apple.MCX.FileVault2",
+		expected:    "Enable=On",
+		enforces: func(content map[string]interface{}) bool {
+			return strings.EqualFold(plistString(content["Enable"]), "On")
+		},
+	}
+	mdmFirewall = mdmControl{
+		payloadType: "com.apple.security.firewall",
+		expected:    "EnableFirewall=true",
+		enforces: func(content map[string]interface{}) bool {
+			return plistBool(content["EnableFirewall"])
+		},
+	}
+	mdmGatekeeper = mdmControl{
+		payloadType: "com.apple.systempolicy.control",
+		expected:    "EnableAssessment=true",
+		enforces: func(content map[string]interface{}) bool {
+			return plistBool(content["EnableAssessment"])
+		},
+	}
+	mdmScreenLock = mdmControl{
+		payloadType: "com.apple.screensaver",
+		expected:    "askForPassword=true",
+		enforces: func(content map[string]interface{}) bool {
+			return plistBool(content["askForPassword"])
+		},
+	}
+	mdmPasswordPolicy = mdmControl{
+		payloadType: "com.apple.mobiledevice.passwordpolicy",
+		expected:    "minLength>0 and requireAlphanumeric or minComplexChars>0",
+		enforces: func(content map[string]interface{}) bool {
+			return plistInt(content["minLength"]) > 0 &&
+				(plistBool(content["requireAlphanumeric"]) || plistInt(content["minComplexChars"]) > 0)
+		},
+	}
+)
+
+// enforcingProfile returns the first profile with a payload enforcing the
+// control
+func enforcingProfile(profiles []mdmProfile, control mdmControl) (mdmProfile, bool) {
+	for _, profile := range profiles {
+		for _, payload := range profile.Payloads {
+			if payload.Type == control.payloadType && control.enforces(payload.Content) {
+				return profile, true
+			}
+		}
+	}
+	return mdmProfile{}, false
+}
+
+// parseMDMProfiles parses the output of `profiles -P -o stdout-xml`, a
+// dictionary of each user's profiles, with the device's under
+// _computerlevel. A Mac without profiles lists none.
+func parseMDMProfiles(output []byte) ([]mdmProfile, error) {
+	root, err := decodePlist(output)
+	if err != nil {
+		return nil, err
+	}
+	owners, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("profile list is not a dictionary")
+	}
+
+	var profiles []mdmProfile
+	for _, list := range owners {
+		entries, _ := list.([]interface{})
+		for _, entry := range entries {
+			fields, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			profile := mdmProfile{
+				Identifier:  plistString(fields["ProfileIdentifier"]),
+				UUID:        plistString(fields["ProfileUUID"]),
+				DisplayName: plistString(fields["ProfileDisplayName"]),
+			}
+			items, _ := fields["ProfileItems"].([]interface{})
+			for _, item := range items {
+				itemFields, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				content, _ := itemFields["PayloadContent"].(map[string]interface{})
+				profile.Payloads = append(profile.Payloads, mdmPayload{
+					Type:    plistString(itemFields["PayloadType"]),
+					Content: content,
+				})
+			}
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+// decodePlist decodes an XML property list into maps, slices, strings,
+// int64s, float64s and bools. Dates and data are kept as their text.
+func decodePlist(data []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, errors.New("empty property list")
+			}
+			return nil, fmt.Errorf("invalid property list: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return decodePlistValue(decoder, start)
+		}
+	}
+}
+
+// decodePlistValue decodes the value an element started
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		var key string
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("invalid property list: %w", err)
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &t); err != nil {
+						return nil, fmt.Errorf("invalid property list: %w", err)
+					}
+					continue
+				}
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		array := []interface{}{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("invalid property list: %w", err)
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, fmt.Errorf("invalid property list: %w", err)
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := decoder.DecodeElement(&text, &start); err != nil {
+		return nil, fmt.Errorf("invalid property list: %w", err)
+	}
+	text = strings.TrimSpace(text)
+	switch start.Name.Local {
+	case "integer":
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid property list integer %q", text)
+		}
+		return value, nil
+	case "real":
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid property list real %q", text)
+		}
+		return value, nil
+	}
+	return text, nil
+}
+
+// plistString returns a property list string, empty for other values
+func plistString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// plistBool reads a property list boolean. Profiles sometimes carry booleans
+// as the integer 1 or the string "true".
+func plistBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case string:
+		return strings.EqualFold(v, "true") || v == "1"
+	}
+	return false
+}
+
+// plistInt reads a property list integer, 0 for other values
+func plistInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// mdmProfilesCommand lists the installed configuration profiles
+var mdmProfilesCommand = []string{"profiles", "-P", "-o", "stdout-xml"}
+
+// mdmState is what a scan learned about the Mac's MDM profiles
+type mdmState struct {
+	profiles []mdmProfile      // None when the Mac isn't enrolled or they couldn't be listed
+	enforced map[string]string // Check name -> UUID of the profile enforcing its setting
+	evidence []models.Evidence // Output of the listing, when it was made before the checks consulting it
+}
+
+// installedProfiles lists the installed configuration profiles once per
+// scan, or per check run on its own. A Mac that isn't enrolled in MDM, or
+// whose profiles can't be listed, has none, leaving checks to the local
+// settings.
+func (cs *ConfigScanner) installedProfiles() []mdmProfile {
+	if cs.mdm == nil {
+		cs.mdm = &mdmState{enforced: make(map[string]string)}
+		output, err := cs.command(mdmProfilesCommand[0], mdmProfilesCommand[1:]...)
+		if err == nil {
+			cs.mdm.profiles, _ = parseMDMProfiles(output)
+		}
+	}
+	return cs.mdm.profiles
+}
+
+// profileEnforced wraps a macOS check so that a setting an MDM profile
+// enforces passes whatever its local default says. Without such a profile
+// the check runs as usual.
+func profileEnforced(control mdmControl, check func(cs *ConfigScanner) CheckResult) func(cs *ConfigScanner) CheckResult {
+	return func(cs *ConfigScanner) CheckResult {
+		profiles := cs.installedProfiles()
+		cs.evidence = append(cs.evidence, cs.mdm.evidence...)
+		profile, ok := enforcingProfile(profiles, control)
+		if !ok {
+			return check(cs)
+		}
+		name := profile.DisplayName
+		if name == "" {
+			name = profile.Identifier
+		}
+		return CheckResult{
+			Passed:     true,
+			Details:    fmt.Sprintf("Enforced by MDM profile %s (%s)", name, profile.UUID),
+			Command:    commandLine(mdmProfilesCommand[0], mdmProfilesCommand[1:]),
+			Observed:   truncateObserved(control.payloadType + " in " + profile.Identifier),
+			Expected:   control.expected,
+			Confidence: 1,
+			EnforcedBy: profile.UUID,
+		}
+	}
+}
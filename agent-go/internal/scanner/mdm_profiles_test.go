@@ -0,0 +1,108 @@
+package scanner
+
+import (
+	"testing"
+)
+
+const testProfilesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>_computerlevel</key>
+	<array>
+		<dict>
+			<key>ProfileDisplayName</key>
+			<string>Security Baseline</string>
+			<key>ProfileIdentifier</key>
+			<string>com.example.security</string>
+			<key>ProfileUUID</key>
+			<string>0D7A6F4E-1C2B-4E5F-9A8B-7C6D5E4F3A2B</string>
+			<key>ProfileItems</key>
+			<array>
+				<dict>
+					<key>PayloadType</key>
+					<string>com.apple.MCX.FileVault2</string>
+					<key>PayloadContent</key>
+					<dict>
+						<key>Enable</key>
+						<string>On</string>
+					</dict>
+				</dict>
+				<dict>
+					<key>PayloadType</key>
+					<string>com.apple.security.firewall</string>
+					<key>PayloadContent</key>
+					<dict>
+						<key>EnableFirewall</key>
+						<false/>
+					</dict>
+				</dict>
+				<dict>
+					<key>PayloadType</key>
+					<string>com.apple.mobiledevice.passwordpolicy</string>
+					<key>PayloadContent</key>
+					<dict>
+						<key>minLength</key>
+						<integer>12</integer>
+						<key>requireAlphanumeric</key>
+						<true/>
+					</dict>
+				</dict>
+			</array>
+		</dict>
+	</array>
+</dict>
+</plist>`
+
+func TestParseMDMProfiles(t *testing.T) {
+	profiles, err := parseMDMProfiles([]byte(testProfilesXML))
+	if err != nil {
+		t.Fatalf("parseMDMProfiles() = %v", err)
+	}
+	if len(profiles) != 1 || len(profiles[0].Payloads) != 3 {
+		t.Fatalf("parseMDMProfiles() = %+v, want one profile with 3 payloads", profiles)
+	}
+
+	profile, ok := enforcingProfile(profiles, mdmFileVault)
+	if !ok || profile.UUID != "0D7A6F4E-1C2B-4E5F-9A8B-7C6D5E4F3A2B" {
+		t.Errorf("FileVault should be enforced by the profile, got %+v, %v", profile, ok)
+	}
+	if _, ok := enforcingProfile(profiles, mdmPasswordPolicy); !ok {
+		t.Error("password policy should be enforced")
+	}
+	// A payload turning the firewall off doesn't enforce it
+	if _, ok := enforcingProfile(profiles, mdmFirewall); ok {
+		t.Error("firewall should not be enforced")
+	}
+	if _, ok := enforcingProfile(profiles, mdmGatekeeper); ok {
+		t.Error("Gatekeeper has no payload and should not be enforced")
+	}
+
+	// Macs without MDM enrollment list no profiles
+	empty, err := parseMDMProfiles([]byte(`<?xml version="1.0"?><plist version="1.0"><dict/></plist>`))
+	if err != nil || len(empty) != 0 {
+		t.Errorf("parseMDMProfiles(empty) = %v, %v", empty, err)
+	}
+	if _, err := parseMDMProfiles([]byte("There are no configuration profiles installed")); err == nil {
+		t.Error("text output should not parse")
+	}
+}
+
+func TestProfileEnforcedFallsBackToLocalCheck(t *testing.T) {
+	local := func(*ConfigScanner) CheckResult { return CheckResult{Passed: false, Details: "local"} }
+
+	cs := &ConfigScanner{mdm: &mdmState{enforced: map[string]string{}}}
+	if result := profileEnforced(mdmFirewall, local)(cs); result.Passed || result.Details != "local" {
+		t.Errorf("without profiles the local check should decide, got %+v", result)
+	}
+
+	profiles, _ := parseMDMProfiles([]byte(testProfilesXML))
+	cs.mdm.profiles = profiles
+	result := profileEnforced(mdmFileVault, local)(cs)
+	if !result.Passed || result.EnforcedBy != "0D7A6F4E-1C2B-4E5F-9A8B-7C6D5E4F3A2B" {
+		t.Errorf("a profile-enforced setting should pass whatever the local setting, got %+v", result)
+	}
+	if result.enrichment()["enforced_by"] != result.EnforcedBy {
+		t.Error("the enforcing profile should be in the finding's check result")
+	}
+}
@@ -0,0 +1,452 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxSafetensorsHeaderSize is the largest JSON header the safetensors format allows
+const maxSafetensorsHeaderSize = 100 * 1024 * 1024
+
+// safetensorsFrameworks maps the "format" a safetensors file's metadata
+// records to the framework that saved it
+var safetensorsFrameworks = map[string]string{
+	"pt":   "PyTorch",
+	"tf":   "TensorFlow",
+	"flax": "JAX",
+	"np":   "NumPy",
+	"mlx":  "MLX",
+}
+
+// readSafetensorsMetadata reads a safetensors file's header: an 8-byte
+// little-endian length, then a JSON object describing each tensor, with
+// free-form string metadata under "__metadata__". Tensor data is not read.
+func readSafetensorsMetadata(path string) (map[string]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix [8]byte
+	if _, err := io.ReadFull(file, prefix[:]); err != nil {
+		return nil, fmt.Errorf("failed to read header length: %w", err)
+	}
+	size := binary.LittleEndian.Uint64(prefix[:])
+	if size == 0 || size > maxSafetensorsHeaderSize || size > uint64(info.Size()-8) {
+		return nil, fmt.Errorf("invalid header length %d", size)
+	}
+	header := make([]byte, size)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(header, &entries); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	metadata := map[string]interface{}{"tensors": len(entries)}
+	raw, ok := entries["__metadata__"]
+	if !ok {
+		return metadata, nil
+	}
+	metadata["tensors"] = len(entries) - 1
+
+	var fields map[string]string
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("invalid __metadata__: %w", err)
+	}
+	metadata["safetensors_metadata"] = fields
+	if framework, ok := safetensorsFrameworks[fields["format"]]; ok {
+		metadata["framework"] = framework
+	}
+	for _, key := range []string{"version", "model_version"} {
+		if version := fields[key]; version != "" {
+			metadata["version"] = version
+			break
+		}
+	}
+	return metadata, nil
+}
+
+// ModelProto field numbers, from onnx.proto
+const (
+	onnxIRVersion       protowire.Number = 1
+	onnxProducerName    protowire.Number = 2
+	onnxProducerVersion protowire.Number = 3
+	onnxDomain          protowire.Number = 4
+	onnxModelVersion    protowire.Number = 5
+	onnxOpsetImport     protowire.Number = 8
+	onnxMetadataProps   protowire.Number = 14
+)
+
+const (
+	maxONNXFieldSize = 64 * 1024 // Larger fields than the ones read, such as the graph, are skipped
+	maxONNXFields    = 10000     // More top-level fields than any model has; beyond it the file is not ONNX
+)
+
+// readONNXMetadata reads the producer, versions and opsets from an ONNX
+// file's ModelProto. It walks the top-level protobuf fields, skipping the
+// graph and anything else large without reading it, so the weights are never
+// loaded. A model's version is the model_version its exporter recorded, or
+// failing that the producer and its version, such as "pytorch 2.1.0".
+func readONNXMetadata(path string) (map[string]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &protoFieldReader{r: file, end: info.Size()}
+	var producer, producerVersion, domain string
+	var irVersion, modelVersion uint64
+	opsets := make(map[string]int64)
+	props := make(map[string]string)
+	for fields := 0; reader.off < reader.end; fields++ {
+		if fields >= maxONNXFields {
+			return nil, errors.New("too many fields for an ONNX model")
+		}
+		num, typ, err := reader.tag()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case (num == onnxIRVersion || num == onnxModelVersion) && typ == protowire.VarintType:
+			value, err := reader.varint()
+			if err != nil {
+				return nil, err
+			}
+			if num == onnxIRVersion {
+				irVersion = value
+			} else {
+				modelVersion = value
+			}
+		case (num == onnxProducerName || num == onnxProducerVersion || num == onnxDomain ||
+			num == onnxOpsetImport || num == onnxMetadataProps) && typ == protowire.BytesType:
+			value, err := reader.bytes()
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				continue // Too large to be what it claims
+			}
+			switch num {
+			case onnxProducerName:
+				producer = string(value)
+			case onnxProducerVersion:
+				producerVersion = string(value)
+			case onnxDomain:
+				domain = string(value)
+			case onnxOpsetImport:
+				opsetDomain, version := parseOpsetImport(value)
+				opsets[opsetDomain] = version
+			case onnxMetadataProps:
+				key, value := parseStringEntry(value)
+				props[key] = value
+			}
+		default:
+			if err := reader.skip(typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if irVersion == 0 && producer == "" && len(opsets) == 0 {
+		return nil, errors.New("no ModelProto fields found")
+	}
+
+	metadata := map[string]interface{}{
+		"ir_version":    irVersion,
+		"opset_imports": opsets,
+	}
+	// The default operator set is named "" or "ai.onnx"
+	if version, ok := opsets[""]; ok {
+		metadata["opset"] = version
+	} else if version, ok := opsets["ai.onnx"]; ok {
+		metadata["opset"] = version
+	}
+	if producer != "" {
+		metadata["producer_name"] = producer
+	}
+	if producerVersion != "" {
+		metadata["producer_version"] = producerVersion
+	}
+	if domain != "" {
+		metadata["domain"] = domain
+	}
+	if len(props) > 0 {
+		metadata["metadata_props"] = props
+	}
+	switch {
+	case modelVersion > 0:
+		metadata["version"] = strconv.FormatUint(modelVersion, 10)
+	case producer != "" && producerVersion != "":
+		metadata["version"] = producer + " " + producerVersion
+	}
+	return metadata, nil
+}
+
+// parseOpsetImport decodes an OperatorSetIdProto: domain (1) and version (2)
+func parseOpsetImport(b []byte) (domain string, version int64) {
+	forEachField(b, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			domain = string(value)
+		case num == 2 && typ == protowire.VarintType:
+			version = int64(varint)
+		}
+	})
+	return domain, version
+}
+
+// parseStringEntry decodes a StringStringEntryProto: key (1) and value (2)
+func parseStringEntry(b []byte) (key, value string) {
+	forEachField(b, func(num protowire.Number, typ protowire.Type, field []byte, _ uint64) {
+		if typ != protowire.BytesType {
+			return
+		}
+		switch num {
+		case 1:
+			key = string(field)
+		case 2:
+			value = string(field)
+		}
+	})
+	return key, value
+}
+
+// forEachField calls fn with each varint or length-delimited field of an
+// encoded message held in memory, stopping at the first malformed one
+func forEachField(b []byte, fn func(num protowire.Number, typ protowire.Type, bytes []byte, varint uint64)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return
+			}
+			fn(num, typ, nil, v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return
+			}
+			fn(num, typ, v, 0)
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return
+			}
+			b = b[n:]
+		}
+	}
+}
+
+// protoFieldReader reads the top-level fields of a protobuf message from a
+// file, seeking past field values rather than reading them
+type protoFieldReader struct {
+	r        io.ReaderAt
+	off, end int64
+}
+
+func (p *protoFieldReader) varint() (uint64, error) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	if remaining := p.end - p.off; remaining < int64(len(buf)) {
+		buf = buf[:remaining]
+	}
+	n, err := p.r.ReadAt(buf, p.off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	value, length := protowire.ConsumeVarint(buf[:n])
+	if length < 0 {
+		return 0, fmt.Errorf("malformed varint at offset %d", p.off)
+	}
+	p.off += int64(length)
+	return value, nil
+}
+
+func (p *protoFieldReader) tag() (protowire.Number, protowire.Type, error) {
+	tag, err := p.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	num, typ := protowire.DecodeTag(tag)
+	if !num.IsValid() {
+		return 0, 0, fmt.Errorf("invalid field number %d", num)
+	}
+	return num, typ, nil
+}
+
+// length reads a length prefix, checking the value fits in the file
+func (p *protoFieldReader) length() (int64, error) {
+	length, err := p.varint()
+	if err != nil {
+		return 0, err
+	}
+	if length > uint64(p.end-p.off) {
+		return 0, fmt.Errorf("field of %d bytes runs past the end of the file", length)
+	}
+	return int64(length), nil
+}
+
+// bytes reads a length-delimited value, or skips it and returns nil if it is
+// over maxONNXFieldSize
+func (p *protoFieldReader) bytes() ([]byte, error) {
+	length, err := p.length()
+	if err != nil {
+		return nil, err
+	}
+	if length > maxONNXFieldSize {
+		p.off += length
+		return nil, nil
+	}
+	value := make([]byte, length)
+	if _, err := p.r.ReadAt(value, p.off); err != nil {
+		return nil, err
+	}
+	p.off += length
+	return value, nil
+}
+
+func (p *protoFieldReader) skip(typ protowire.Type) error {
+	switch typ {
+	case protowire.VarintType:
+		_, err := p.varint()
+		return err
+	case protowire.Fixed32Type:
+		p.off += 4
+	case protowire.Fixed64Type:
+		p.off += 8
+	case protowire.BytesType:
+		length, err := p.length()
+		if err != nil {
+			return err
+		}
+		p.off += length
+	default:
+		return fmt.Errorf("unsupported wire type %d", typ)
+	}
+	if p.off > p.end {
+		return errors.New("field runs past the end of the file")
+	}
+	return nil
+}
+
+// vulnerableModelProducer is a version range of a tool that writes model
+// files with a known vulnerability
+type vulnerableModelProducer struct {
+	producer    string // ONNX producer_name
+	fixedIn     string // First version without the vulnerability
+	severity    string
+	cve         string
+	description string
+}
+
+// vulnerableModelProducers are the model producers flagged by scanModel
+var vulnerableModelProducers = []vulnerableModelProducer{
+	{
+		producer:    "onnx",
+		fixedIn:     "1.13.0",
+		severity:    "high",
+		cve:         "CVE-2022-25882",
+		description: "Model was saved with onnx %s, whose external data loading allows directory traversal outside the model directory",
+	},
+	{
+		producer:    "onnx",
+		fixedIn:     "1.16.0",
+		severity:    "high",
+		cve:         "CVE-2024-27318",
+		description: "Model was saved with onnx %s, whose external data path check can be bypassed to read files outside the model directory",
+	},
+}
+
+// producerVulnerabilities returns the known vulnerabilities of the tool and
+// version that produced a model
+func producerVulnerabilities(producer, version string) []AIMLVulnerability {
+	var vulns []AIMLVulnerability
+	for _, known := range vulnerableModelProducers {
+		if !strings.EqualFold(producer, known.producer) || !versionBefore(version, known.fixedIn) {
+			continue
+		}
+		vulns = append(vulns, AIMLVulnerability{
+			ID:          uuid.New().String(),
+			Severity:    known.severity,
+			Description: fmt.Sprintf(known.description, version),
+			CVE:         known.cve,
+			FoundAt:     time.Now(),
+		})
+	}
+	return vulns
+}
+
+// versionBefore reports whether dotted numeric version a is older than b.
+// Pre-release and build suffixes are ignored; a version that does not start
+// with a number is never older.
+func versionBefore(a, b string) bool {
+	parse := func(v string) ([]int, bool) {
+		v = strings.TrimPrefix(v, "v")
+		var parts []int
+		for _, part := range strings.Split(v, ".") {
+			end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' })
+			if end == 0 {
+				break
+			}
+			if end > 0 {
+				part = part[:end]
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				break
+			}
+			parts = append(parts, n)
+			if end > 0 {
+				break
+			}
+		}
+		return parts, len(parts) > 0
+	}
+	va, ok := parse(a)
+	if !ok {
+		return false
+	}
+	vb, _ := parse(b)
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
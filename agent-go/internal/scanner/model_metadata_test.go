@@ -0,0 +1,126 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestExtractSafetensorsMetadata(t *testing.T) {
+	header := []byte(`{"__metadata__":{"format":"pt","version":"2.1"},"weight":{"dtype":"F32","shape":[1],"data_offsets":[0,4]}}`)
+	content := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	content = append(append(content, header...), 0, 0, 0, 0)
+	path := filepath.Join(t.TempDir(), "model.safetensors")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	as := NewAIMLScanner(setupTestConfig(), nil)
+	model := &ModelInfo{Name: "model.safetensors", Path: path, Framework: "HuggingFace"}
+	as.analyzeModelContent(model)
+
+	if model.Version != "2.1" || model.Framework != "PyTorch" || model.Metadata["tensors"] != 1 {
+		t.Errorf("got version %q, framework %q and metadata %v", model.Version, model.Framework, model.Metadata)
+	}
+}
+
+// onnxModel encodes a ModelProto with a graph standing in for the weights
+func onnxModel(producer, version string, opset int64) []byte {
+	var opsetImport []byte
+	opsetImport = protowire.AppendTag(opsetImport, 2, protowire.VarintType)
+	opsetImport = protowire.AppendVarint(opsetImport, uint64(opset))
+
+	var b []byte
+	b = protowire.AppendTag(b, onnxIRVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, 8)
+	b = protowire.AppendTag(b, onnxProducerName, protowire.BytesType)
+	b = protowire.AppendString(b, producer)
+	b = protowire.AppendTag(b, onnxProducerVersion, protowire.BytesType)
+	b = protowire.AppendString(b, version)
+	b = protowire.AppendTag(b, 7, protowire.BytesType)
+	b = protowire.AppendBytes(b, make([]byte, 2*maxONNXFieldSize))
+	b = protowire.AppendTag(b, onnxOpsetImport, protowire.BytesType)
+	return protowire.AppendBytes(b, opsetImport)
+}
+
+func TestExtractONNXMetadataFlagsVulnerableProducers(t *testing.T) {
+	as := NewAIMLScanner(setupTestConfig(), nil)
+	for _, tc := range []struct {
+		producer, version string
+		vulnerabilities   int
+	}{
+		{"onnx", "1.12.0", 2},
+		{"onnx", "1.15.0", 1},
+		{"onnx", "1.16.1", 0},
+		{"pytorch", "1.12.0", 0},
+	} {
+		path := filepath.Join(t.TempDir(), "model.onnx")
+		if err := os.WriteFile(path, onnxModel(tc.producer, tc.version, 17), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		model := ModelInfo{Name: "model.onnx", Path: path, Framework: "ONNX"}
+		as.analyzeModelContent(&model)
+
+		if model.Metadata["opset"] != int64(17) || model.Metadata["producer_name"] != tc.producer {
+			t.Fatalf("%s %s: got metadata %v", tc.producer, tc.version, model.Metadata)
+		}
+		if want := tc.producer + " " + tc.version; model.Version != want {
+			t.Errorf("got version %q, want %q", model.Version, want)
+		}
+		findings := as.scanModel(model)
+		flagged := 0
+		for _, finding := range findings {
+			if finding.Title == "Model Vulnerability Detected" {
+				flagged++
+				if finding.Metadata["producer_version"] != tc.version {
+					t.Errorf("finding is missing the producer version: %v", finding.Metadata)
+				}
+			}
+		}
+		if flagged != tc.vulnerabilities {
+			t.Errorf("%s %s: got %d vulnerabilities, want %d", tc.producer, tc.version, flagged, tc.vulnerabilities)
+		}
+	}
+}
+
+func TestExtractModelMetadataHandlesCorruptFiles(t *testing.T) {
+	as := NewAIMLScanner(setupTestConfig(), nil)
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"short.safetensors":   {1, 2, 3},
+		"huge.safetensors":    binary.LittleEndian.AppendUint64(nil, 1<<40),
+		"garbage.safetensors": append(binary.LittleEndian.AppendUint64(nil, 3), "{{{"...),
+		"empty.onnx":          {},
+		"text.onnx":           []byte("not a model at all"),
+		"truncated.onnx":      onnxModel("onnx", "1.12.0", 17)[:20],
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if metadata := as.extractModelMetadata(path); metadata != nil {
+			t.Errorf("%s: expected no metadata, got %v", name, metadata)
+		}
+	}
+}
+
+func TestVersionBefore(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"1.12.0", "1.13.0", true},
+		{"1.13", "1.13.0", false},
+		{"1.9.1", "1.13.0", true},
+		{"1.16.0rc1", "1.16.0", false},
+		{"v1.2", "1.10", true},
+		{"unknown", "1.13.0", false},
+	} {
+		if got := versionBefore(tc.a, tc.b); got != tc.want {
+			t.Errorf("versionBefore(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package scanner
 import (
 	"time"
 
+	"zerotrace/agent/internal/models"
+
 	"github.com/google/uuid"
 )
 
@@ -39,6 +41,7 @@ type NetworkFinding struct {
 	OS             string                 `json:"os,omitempty"`
 	OSVersion      string                 `json:"os_version,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	Evidence       []models.Evidence      `json:"evidence,omitempty"` // Raw output that triggered the finding
 }
 
 // SBOMFinding represents the result of an SBOM scan.
@@ -3,6 +3,7 @@ package scanner
 import (
 	"context"
 	"fmt"
+	"log"
 	"net"
 	"strings"
 	"time"
@@ -29,24 +30,65 @@ func NewNetworkScanner(cfg *config.Config) *NetworkScanner {
 		config:           cfg,
 		deviceClassifier: NewDeviceClassifier(),
 		configAuditor:    NewConfigAuditor(),
-		nucleiScanner:    NewNucleiScanner(),
+		nucleiScanner: &NucleiScanner{
+			rateLimit: cfg.NetworkScanMaxPPS,
+			bulkSize:  cfg.NetworkScanMaxConcurrentHosts,
+			templates: newNucleiTemplateSelection(cfg),
+		},
 	}
 }
 
 // Scan performs a comprehensive network scan using Nmap for device discovery,
-// device classification, configuration auditing, and Nuclei for vulnerability scanning
+// device classification, configuration auditing, and Nuclei for vulnerability scanning.
+// The target, an IP address or CIDR, is narrowed to the allowed scope first.
 func (ns *NetworkScanner) Scan(target string) (*NetworkScanResult, error) {
+	subnets, _ := localSubnets()
+	return ns.scan(ns.resolveScope([]string{target}, subnets))
+}
+
+// resolveScope narrows targets to NETWORK_SCAN_CIDRS or, when none are
+// configured, to the first of the host's own subnets, logging each refusal
+func (ns *NetworkScanner) resolveScope(targets, localSubnets []string) NetworkScanScope {
+	allowed := ns.config.NetworkScanCIDRs
+	if len(allowed) == 0 && len(localSubnets) > 0 {
+		allowed = localSubnets[:1]
+	}
+
+	scope := resolveScanScope(targets, allowed, ns.config.NetworkScanExcludeCIDRs)
+	scope.MaxConcurrentHosts = ns.config.NetworkScanMaxConcurrentHosts
+	scope.MaxPacketsPerSecond = ns.config.NetworkScanMaxPPS
+	for _, target := range scope.Refused {
+		log.Printf("[NetworkScanner] Refusing to scan %s: outside the allowed scope %s", target, strings.Join(allowed, ", "))
+	}
+	return scope
+}
+
+// scan runs the network scan over the scope's targets
+func (ns *NetworkScanner) scan(scope NetworkScanScope) (*NetworkScanResult, error) {
+	if len(scope.Targets) == 0 {
+		return nil, fmt.Errorf("%w: refused %s", ErrNetworkScanOutOfScope, strings.Join(scope.Refused, ", "))
+	}
+
 	scanID := uuid.New()
 	startTime := time.Now()
 
 	var allFindings []NetworkFinding
 	var hostsWithOpenPorts []string
 
+	// Nmap and Nuclei are external binaries; without them the scan degrades to
+	// Naabu port discovery and says so in its metadata
+	capabilities := newCapabilityReport()
+	hasNmap := capabilities.require("service_detection", "nmap")
+	hasNuclei := capabilities.require("vulnerability_templates", "nuclei")
+	if !hasNmap {
+		return ns.scanWithNaabu(scope, scanID, startTime, capabilities, hasNuclei)
+	}
+
 	// Step 1: Use Nmap for comprehensive device discovery and fingerprinting
-	nmapResults, err := ns.scanWithNmap(target)
+	nmapResults, err := ns.scanWithNmap(scope)
 	if err != nil {
 		// Fallback to Naabu if Nmap fails
-		return ns.scanWithNaabu(target, scanID, startTime)
+		return ns.scanWithNaabu(scope, scanID, startTime, capabilities, hasNuclei)
 	}
 
 	// Step 2: Process Nmap results and classify devices
@@ -92,6 +134,18 @@ func (ns *NetworkScanner) Scan(target string) (*NetworkScanResult, error) {
 			}
 		}
 
+		// Record the MAC address and hostname so the API can deduplicate hosts
+		// that several agents discover on overlapping subnets
+		var macAddress, hostname string
+		for _, addr := range host.Addresses {
+			if addr.AddrType == "mac" {
+				macAddress = addr.Addr
+			}
+		}
+		if len(host.Hostnames) > 0 {
+			hostname = host.Hostnames[0].Name
+		}
+
 		// Classify device type
 		deviceType := ns.deviceClassifier.ClassifyDevice(host.Addresses[0].Addr, ports, services, osInfo, banners)
 
@@ -122,7 +176,9 @@ func (ns *NetworkScanner) Scan(target string) (*NetworkScanResult, error) {
 				OS:             osInfo,
 				OSVersion:      osVersion,
 				Metadata: map[string]interface{}{
-					"confidence": ns.deviceClassifier.GetDeviceConfidence(deviceType, ports, services, osInfo),
+					"confidence":  ns.deviceClassifier.GetDeviceConfidence(deviceType, ports, services, osInfo),
+					"mac_address": macAddress,
+					"hostname":    hostname,
 				},
 			}
 
@@ -130,6 +186,10 @@ func (ns *NetworkScanner) Scan(target string) (*NetworkScanResult, error) {
 			hostsWithOpenPorts = append(hostsWithOpenPorts, fmt.Sprintf("%s:%d", host.Addresses[0].Addr, port))
 		}
 
+		// Check the certificates, protocols and ciphers of TLS and SSH services
+		allFindings = append(allFindings, ns.inspectTLSCertificates(host.Addresses[0].Addr, hostname, ports, services)...)
+		allFindings = append(allFindings, ns.inspectWeakCrypto(host.Addresses[0].Addr, hostname, ports, services)...)
+
 		// Step 3: Perform configuration auditing
 		credentials := make(map[string]string) // Would be populated from config if available
 		configFindings := ns.configAuditor.AuditConfiguration(
@@ -156,7 +216,7 @@ func (ns *NetworkScanner) Scan(target string) (*NetworkScanResult, error) {
 
 	// Step 4: Run Nuclei vulnerability scanning on discovered hosts
 	var vulnFindings []NetworkFinding
-	if len(hostsWithOpenPorts) > 0 {
+	if hasNuclei && len(hostsWithOpenPorts) > 0 {
 		// Prepare targets for Nuclei (unique hosts)
 		uniqueHosts := make(map[string]bool)
 		targets := []string{}
@@ -200,27 +260,38 @@ func (ns *NetworkScanner) Scan(target string) (*NetworkScanResult, error) {
 			"port_findings":  len(allFindings) - len(vulnFindings),
 			"vuln_findings":  len(vulnFindings),
 			"scan_method":    "nmap+nuclei",
+			"scan_scope":     scope,
+			"tool_versions":  DetectToolVersions(NetworkScanTools...),
+			"capabilities":   capabilities,
 		},
 	}
+	if hasNuclei {
+		result.Metadata["nuclei_templates"] = ns.nucleiScanner.templates
+	}
 
 	return result, nil
 }
 
 // scanWithNmap performs network scanning using Nmap
-func (ns *NetworkScanner) scanWithNmap(target string) ([]nmap.Host, error) {
+func (ns *NetworkScanner) scanWithNmap(scope NetworkScanScope) ([]nmap.Host, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	// Determine if target is a single IP, IP range, or CIDR
-	scanner, err := nmap.NewScanner(
-		nmap.WithTargets(target),
+	options := []func(*nmap.Scanner){
+		nmap.WithTargets(scope.Targets...),
 		nmap.WithContext(ctx),
-		nmap.WithTimingTemplate(nmap.TimingAggressive), // Faster scanning
-		nmap.WithOSDetection(),                         // OS detection
-		nmap.WithServiceInfo(),                         // Service version detection
-		nmap.WithScripts("default,safe"),               // Safe scripts
-		nmap.WithSkipHostDiscovery(),                   // Skip ping scan if target is specific
-	)
+		nmap.WithTimingTemplate(nmap.TimingAggressive),  // Faster scanning
+		nmap.WithMaxRate(scope.MaxPacketsPerSecond),     // Caps the aggressive timing
+		nmap.WithMaxHostgroup(scope.MaxConcurrentHosts), // Hosts scanned in parallel
+		nmap.WithOSDetection(),                          // OS detection
+		nmap.WithServiceInfo(),                          // Service version detection
+		nmap.WithScripts("default,safe"),                // Safe scripts
+		nmap.WithSkipHostDiscovery(),                    // Skip ping scan if target is specific
+	}
+	if len(scope.Excluded) > 0 {
+		options = append(options, nmap.WithTargetExclusion(strings.Join(scope.Excluded, ",")))
+	}
+	scanner, err := nmap.NewScanner(options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Nmap scanner: %w", err)
 	}
@@ -241,15 +312,22 @@ func (ns *NetworkScanner) scanWithNmap(target string) ([]nmap.Host, error) {
 	return result.Hosts, nil
 }
 
-// scanWithNaabu is a fallback method using Naabu (original implementation)
-func (ns *NetworkScanner) scanWithNaabu(target string, scanID uuid.UUID, startTime time.Time) (*NetworkScanResult, error) {
+// scanWithNaabu is a fallback method using Naabu (original implementation).
+// Naabu is linked into the agent, so port discovery is always available.
+func (ns *NetworkScanner) scanWithNaabu(scope NetworkScanScope, scanID uuid.UUID, startTime time.Time, capabilities *CapabilityReport, hasNuclei bool) (*NetworkScanResult, error) {
+	capabilities.available("port_discovery")
+
 	var portFindings []NetworkFinding
 	var hostsWithOpenPorts []string
 
 	// Run Naabu to discover open ports
+	// Naabu has no per-host limit, so its workers are capped instead
 	naabuOptions := &runner.Options{
-		Host:   []string{target},
-		Silent: true,
+		Host:       scope.Targets,
+		ExcludeIps: strings.Join(scope.Excluded, ","),
+		Rate:       scope.MaxPacketsPerSecond,
+		Threads:    scope.MaxConcurrentHosts,
+		Silent:     true,
 	}
 
 	var naabuResults []*result.HostResult
@@ -268,7 +346,9 @@ func (ns *NetworkScanner) scanWithNaabu(target string, scanID uuid.UUID, startTi
 	}
 
 	for _, result := range naabuResults {
+		var ports []int
 		for _, port := range result.Ports {
+			ports = append(ports, port.Port)
 			portFindings = append(portFindings, NetworkFinding{
 				ID:           uuid.New(),
 				FindingType:  "port",
@@ -283,11 +363,17 @@ func (ns *NetworkScanner) scanWithNaabu(target string, scanID uuid.UUID, startTi
 			})
 			hostsWithOpenPorts = append(hostsWithOpenPorts, fmt.Sprintf("%s:%d", result.IP, port.Port))
 		}
+		hostname := result.Host
+		if hostname == result.IP {
+			hostname = ""
+		}
+		portFindings = append(portFindings, ns.inspectTLSCertificates(result.IP, hostname, ports, nil)...)
+		portFindings = append(portFindings, ns.inspectWeakCrypto(result.IP, hostname, ports, nil)...)
 	}
 
 	// Run Nuclei on discovered hosts
 	var vulnFindings []NetworkFinding
-	if len(hostsWithOpenPorts) > 0 {
+	if hasNuclei && len(hostsWithOpenPorts) > 0 {
 		uniqueHosts := make(map[string]bool)
 		targets := []string{}
 		for _, hostPort := range hostsWithOpenPorts {
@@ -313,7 +399,7 @@ func (ns *NetworkScanner) scanWithNaabu(target string, scanID uuid.UUID, startTi
 
 	allFindings := append(portFindings, vulnFindings...)
 
-	return &NetworkScanResult{
+	result := &NetworkScanResult{
 		ID:              scanID,
 		AgentID:         uuid.MustParse(ns.config.AgentID),
 		CompanyID:       safeParse(ns.config.CompanyID),
@@ -322,20 +408,39 @@ func (ns *NetworkScanner) scanWithNaabu(target string, scanID uuid.UUID, startTi
 		Status:          "completed",
 		NetworkFindings: allFindings,
 		Metadata: map[string]interface{}{
-			"scan_method": "naabu+nuclei",
+			"scan_method":   "naabu+nuclei",
+			"scan_scope":    scope,
+			"tool_versions": DetectToolVersions(NetworkScanTools...),
+			"capabilities":  capabilities,
 		},
-	}, nil
+	}
+	if hasNuclei {
+		result.Metadata["nuclei_templates"] = ns.nucleiScanner.templates
+	}
+	return result, nil
 }
 
-// ScanLocalNetwork scans the local network for devices
+// ScanLocalNetwork scans the local network for devices, covering each of the
+// host's subnets that lies within the allowed scope
 func (ns *NetworkScanner) ScanLocalNetwork() (*NetworkScanResult, error) {
-	// Get local network interfaces
+	subnets, err := localSubnets()
+	if err != nil {
+		return nil, err
+	}
+	if len(subnets) == 0 {
+		return nil, fmt.Errorf("no network interfaces found for scanning")
+	}
+	return ns.scan(ns.resolveScope(subnets, subnets))
+}
+
+// localSubnets returns the IPv4 subnet of each up, non-loopback interface
+func localSubnets() ([]string, error) {
 	interfaces, err := net.Interfaces()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get network interfaces: %w", err)
 	}
 
-	var targets []string
+	var subnets []string
 	for _, iface := range interfaces {
 		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
 			continue
@@ -348,24 +453,17 @@ func (ns *NetworkScanner) ScanLocalNetwork() (*NetworkScanResult, error) {
 
 		for _, addr := range addrs {
 			if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-				if ipnet.IP.To4() != nil {
-					// Convert to CIDR notation for scanning
+				if ip := ipnet.IP.To4(); ip != nil {
 					ones, _ := ipnet.Mask.Size()
-					cidr := fmt.Sprintf("%s/%d", ipnet.IP.String(), ones)
-					targets = append(targets, cidr)
+					subnets = append(subnets, fmt.Sprintf("%s/%d", ip.Mask(ipnet.Mask), ones))
 					break // Only scan one network per interface
 				}
 			}
 		}
 	}
-
-	if len(targets) == 0 {
-		return nil, fmt.Errorf("no network interfaces found for scanning")
-	}
-
-	// Scan the first network (can be extended to scan all)
-	return ns.Scan(targets[0])
+	return subnets, nil
 }
+
 func safeParse(s string) uuid.UUID {
 	if s == "" {
 		return uuid.Nil
@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// ErrNetworkScanOutOfScope is returned when none of a network scan's targets
+// lie within the subnets it may cover
+var ErrNetworkScanOutOfScope = errors.New("network scan targets are outside the allowed scope")
+
+// NetworkScanScope is what a network scan was allowed to cover and how hard
+// it could probe it, reported in the scan's scan_scope metadata
+type NetworkScanScope struct {
+	Allowed             []string `json:"allowed"`            // Subnets scans may cover
+	Excluded            []string `json:"excluded,omitempty"` // Subnets scans never cover, even inside allowed ones
+	Targets             []string `json:"targets"`            // Hosts and subnets scanned
+	Refused             []string `json:"refused,omitempty"`  // Requested targets left out as outside the allowed subnets
+	MaxConcurrentHosts  int      `json:"max_concurrent_hosts"`
+	MaxPacketsPerSecond int      `json:"max_packets_per_second"`
+}
+
+// resolveScanScope narrows the requested targets, IP addresses or CIDRs, to
+// the allowed subnets. A target within an allowed subnet is scanned whole,
+// and an allowed subnet within a target is scanned in its place. Targets
+// overlapping no allowed subnet, lying wholly within an excluded one or that
+// aren't IP addresses are refused. Excluded subnets that only overlap a
+// target are left for the scanners to skip.
+func resolveScanScope(requested, allowed, excluded []string) NetworkScanScope {
+	scope := NetworkScanScope{Allowed: allowed, Excluded: excluded, Targets: []string{}}
+	allowedNets := parseScanNetworks(allowed)
+	excludedNets := parseScanNetworks(excluded)
+
+	seen := make(map[string]bool)
+	for _, target := range requested {
+		targetNet := parseScanNetwork(target)
+		if targetNet == nil {
+			scope.Refused = append(scope.Refused, target)
+			continue
+		}
+
+		var covered []string
+		for _, allowedNet := range allowedNets {
+			overlap := intersectNetworks(targetNet, allowedNet)
+			if overlap == nil || excludedBy(overlap, excludedNets) {
+				continue
+			}
+			covered = append(covered, formatScanNetwork(overlap))
+		}
+		if len(covered) == 0 {
+			scope.Refused = append(scope.Refused, target)
+			continue
+		}
+		for _, network := range covered {
+			if !seen[network] {
+				seen[network] = true
+				scope.Targets = append(scope.Targets, network)
+			}
+		}
+	}
+	return scope
+}
+
+// parseScanNetwork parses a CIDR, or an IP address as the network of just
+// that host. It returns nil for anything else, such as hostnames, whose
+// addresses can't be checked against the scope before scanning.
+func parseScanNetwork(target string) *net.IPNet {
+	target = strings.TrimSpace(target)
+	if strings.Contains(target, "/") {
+		_, network, err := net.ParseCIDR(target)
+		if err != nil {
+			return nil
+		}
+		return network
+	}
+
+	ip := net.ParseIP(target)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+func parseScanNetworks(targets []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, target := range targets {
+		if network := parseScanNetwork(target); network != nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// intersectNetworks returns the smaller of two networks when one contains
+// the other, and nil when they don't overlap; CIDRs never overlap partially
+func intersectNetworks(a, b *net.IPNet) *net.IPNet {
+	switch {
+	case containsNetwork(a, b):
+		return b
+	case containsNetwork(b, a):
+		return a
+	default:
+		return nil
+	}
+}
+
+// containsNetwork reports whether inner lies wholly within outer
+func containsNetwork(outer, inner *net.IPNet) bool {
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+func excludedBy(network *net.IPNet, excluded []*net.IPNet) bool {
+	for _, exclusion := range excluded {
+		if containsNetwork(exclusion, network) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatScanNetwork writes single hosts as a bare address and subnets as CIDRs
+func formatScanNetwork(network *net.IPNet) string {
+	if ones, bits := network.Mask.Size(); ones == bits {
+		return network.IP.String()
+	}
+	return network.String()
+}
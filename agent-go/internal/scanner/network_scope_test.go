@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveScanScope(t *testing.T) {
+	allowed := []string{"10.0.0.0/16", "192.168.1.0/24"}
+	excluded := []string{"10.0.8.0/24", "192.168.1.128/25"}
+
+	scope := resolveScanScope([]string{
+		"10.0.5.0/24",      // Within an allowed subnet: scanned whole
+		"192.168.0.0/16",   // Contains an allowed subnet: narrowed to it
+		"10.0.8.0/24",      // Excluded
+		"10.0.5.7",         // Already covered
+		"10.0.6.9",         // A single host
+		"172.16.0.0/12",    // The VPN range: outside the allowlist
+		"intranet.example", // Not an address
+	}, allowed, excluded)
+
+	if want := []string{"10.0.5.0/24", "192.168.1.0/24", "10.0.5.7", "10.0.6.9"}; !reflect.DeepEqual(scope.Targets, want) {
+		t.Errorf("Targets = %v, want %v", scope.Targets, want)
+	}
+	if want := []string{"10.0.8.0/24", "172.16.0.0/12", "intranet.example"}; !reflect.DeepEqual(scope.Refused, want) {
+		t.Errorf("Refused = %v, want %v", scope.Refused, want)
+	}
+	// Exclusions within a scanned subnet are left to the scanners to skip
+	if !reflect.DeepEqual(scope.Excluded, excluded) {
+		t.Errorf("Excluded = %v, want %v", scope.Excluded, excluded)
+	}
+}
+
+func TestNetworkScannerRefusesOutOfScopeTargets(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.NetworkScanCIDRs = []string{"10.0.0.0/24"}
+	ns := NewNetworkScanner(cfg)
+
+	_, err := ns.Scan("172.16.4.0/24")
+	if !errors.Is(err, ErrNetworkScanOutOfScope) {
+		t.Fatalf("Scan() error = %v, want ErrNetworkScanOutOfScope", err)
+	}
+
+	scope := ns.resolveScope([]string{"10.0.0.0/8"}, []string{"172.16.4.0/24"})
+	if want := []string{"10.0.0.0/24"}; !reflect.DeepEqual(scope.Targets, want) {
+		t.Errorf("Targets = %v, want %v", scope.Targets, want)
+	}
+	if scope.MaxConcurrentHosts != cfg.NetworkScanMaxConcurrentHosts || scope.MaxPacketsPerSecond != cfg.NetworkScanMaxPPS {
+		t.Errorf("scope limits = %d hosts, %d pps, want the configured ones", scope.MaxConcurrentHosts, scope.MaxPacketsPerSecond)
+	}
+
+	// Without an allowlist only the host's first subnet may be scanned
+	cfg.NetworkScanCIDRs = nil
+	scope = ns.resolveScope([]string{"192.168.1.0/24", "10.8.0.0/24"}, []string{"192.168.1.0/24", "10.8.0.0/24"})
+	if want := []string{"192.168.1.0/24"}; !reflect.DeepEqual(scope.Targets, want) {
+		t.Errorf("Targets = %v, want %v", scope.Targets, want)
+	}
+	if want := []string{"10.8.0.0/24"}; !reflect.DeepEqual(scope.Refused, want) {
+		t.Errorf("Refused = %v, want %v", scope.Refused, want)
+	}
+}
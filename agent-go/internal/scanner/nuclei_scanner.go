@@ -4,14 +4,90 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
+
 	"github.com/google/uuid"
 )
 
 // NucleiScanner handles vulnerability scanning using Nuclei
-type NucleiScanner struct{}
+type NucleiScanner struct {
+	rateLimit int                     // Requests per second; 150 when unset
+	bulkSize  int                     // Hosts scanned in parallel; Nuclei's default when unset
+	templates NucleiTemplateSelection // Templates run; all of Nuclei's own when unset
+}
+
+// nucleiSafeExcludedTags are the tags of the templates the safe profile
+// leaves out: those that can take a service down, change its state, or
+// flood it with requests
+var nucleiSafeExcludedTags = []string{"dos", "intrusive", "fuzz", "bruteforce"}
+
+// NucleiTemplateSelection is the set of templates a network scan runs with
+// Nuclei, reported in its "nuclei_templates" metadata
+type NucleiTemplateSelection struct {
+	Profile            string   `json:"profile"`
+	Tags               []string `json:"tags,omitempty"`
+	ExcludeTags        []string `json:"exclude_tags,omitempty"` // Including those the profile leaves out
+	Templates          []string `json:"templates,omitempty"`
+	ExcludeTemplates   []string `json:"exclude_templates,omitempty"`
+	CustomTemplatesDir string   `json:"custom_templates_dir,omitempty"`
+	Severities         []string `json:"severities,omitempty"` // Empty runs every severity
+}
+
+// newNucleiTemplateSelection selects the templates the configuration asks for
+func newNucleiTemplateSelection(cfg *config.Config) NucleiTemplateSelection {
+	selection := NucleiTemplateSelection{
+		Profile:            cfg.NucleiProfile,
+		Tags:               cfg.NucleiTags,
+		Templates:          cfg.NucleiTemplates,
+		ExcludeTemplates:   cfg.NucleiExcludeTemplates,
+		CustomTemplatesDir: cfg.NucleiTemplatesDir,
+	}
+	if selection.Profile != "full" {
+		selection.Profile = "safe"
+		selection.ExcludeTags = append(selection.ExcludeTags, nucleiSafeExcludedTags...)
+	}
+	for _, tag := range cfg.NucleiExcludeTags {
+		if !slices.Contains(selection.ExcludeTags, tag) {
+			selection.ExcludeTags = append(selection.ExcludeTags, tag)
+		}
+	}
+	if i := slices.Index(config.NucleiSeverities, strings.ToLower(cfg.NucleiMinSeverity)); i > 0 {
+		selection.Severities = config.NucleiSeverities[i:]
+	}
+	return selection
+}
+
+// args returns the Nuclei flags selecting the templates. Nuclei runs its
+// own templates only when none are named, so naming the custom directory
+// alone runs just the customer's templates.
+func (s NucleiTemplateSelection) args() []string {
+	var args []string
+	for _, template := range s.Templates {
+		args = append(args, "-t", template)
+	}
+	if s.CustomTemplatesDir != "" {
+		args = append(args, "-t", s.CustomTemplatesDir)
+	}
+	for _, template := range s.ExcludeTemplates {
+		args = append(args, "-exclude-templates", template)
+	}
+	if len(s.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(s.Tags, ","))
+	}
+	if len(s.ExcludeTags) > 0 {
+		args = append(args, "-exclude-tags", strings.Join(s.ExcludeTags, ","))
+	}
+	if len(s.Severities) > 0 {
+		args = append(args, "-severity", strings.Join(s.Severities, ","))
+	}
+	return args
+}
 
 // NewNucleiScanner creates a new Nuclei scanner
 func NewNucleiScanner() *NucleiScanner {
@@ -40,14 +116,23 @@ func (ns *NucleiScanner) ScanUsingCLI(targets []string) ([]NetworkFinding, error
 
 	var findings []NetworkFinding
 
+	rateLimit := ns.rateLimit
+	if rateLimit <= 0 {
+		rateLimit = 150
+	}
+
 	// Build Nuclei command
 	args := []string{
 		"-json",
 		"-silent",
 		"-no-color",
-		"-rate-limit", "150",
+		"-rate-limit", strconv.Itoa(rateLimit),
 		"-timeout", "10",
 	}
+	if ns.bulkSize > 0 {
+		args = append(args, "-bulk-size", strconv.Itoa(ns.bulkSize))
+	}
+	args = append(args, ns.templates.args()...)
 
 	// Add targets
 	for _, target := range targets {
@@ -117,7 +202,20 @@ func (ns *NucleiScanner) convertNucleiResult(result nucleiResult) *NetworkFindin
 			"extracted_results": result.ExtractedResults,
 			"curl_command":      result.CurlCommand,
 		},
+		Evidence: nucleiEvidence(result),
+	}
+}
+
+// nucleiEvidence keeps the request Nuclei sent and the response it matched
+func nucleiEvidence(result nucleiResult) []models.Evidence {
+	var evidence []models.Evidence
+	if result.Request != "" {
+		evidence = append(evidence, textEvidence("Nuclei request", result.Request))
+	}
+	if result.Response != "" {
+		evidence = append(evidence, textEvidence("Nuclei matched response", result.Response))
 	}
+	return evidence
 }
 
 // mapNucleiSeverity maps Nuclei severity levels to our system
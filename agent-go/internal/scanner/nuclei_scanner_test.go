@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNucleiSafeProfileIsTheDefault(t *testing.T) {
+	cfg := setupTestConfig()
+	selection := NewNetworkScanner(cfg).nucleiScanner.templates
+
+	if selection.Profile != "safe" || !reflect.DeepEqual(selection.ExcludeTags, nucleiSafeExcludedTags) {
+		t.Errorf("expected the safe profile to leave out intrusive templates, got %+v", selection)
+	}
+	want := []string{"-exclude-tags", "dos,intrusive,fuzz,bruteforce"}
+	if args := selection.args(); !reflect.DeepEqual(args, want) {
+		t.Errorf("args() = %v, want %v", args, want)
+	}
+}
+
+func TestNucleiTemplateSelectionArgs(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.NucleiProfile = "full"
+	cfg.NucleiTags = []string{"cve", "misconfig"}
+	cfg.NucleiExcludeTags = []string{"wordpress"}
+	cfg.NucleiTemplates = []string{"http/cves/"}
+	cfg.NucleiExcludeTemplates = []string{"http/cves/2018/"}
+	cfg.NucleiTemplatesDir = "/etc/zerotrace/nuclei"
+	cfg.NucleiMinSeverity = "medium"
+
+	selection := newNucleiTemplateSelection(cfg)
+	want := []string{
+		"-t", "http/cves/",
+		"-t", "/etc/zerotrace/nuclei",
+		"-exclude-templates", "http/cves/2018/",
+		"-tags", "cve,misconfig",
+		"-exclude-tags", "wordpress",
+		"-severity", "medium,high,critical",
+	}
+	if args := selection.args(); !reflect.DeepEqual(args, want) {
+		t.Errorf("args() = %v, want %v", args, want)
+	}
+
+	// The safe profile's exclusions are kept alongside the configured ones
+	cfg.NucleiProfile = "safe"
+	cfg.NucleiExcludeTags = []string{"dos", "wordpress"}
+	if got := newNucleiTemplateSelection(cfg).ExcludeTags; !reflect.DeepEqual(got, []string{"dos", "intrusive", "fuzz", "bruteforce", "wordpress"}) {
+		t.Errorf("ExcludeTags = %v", got)
+	}
+}
@@ -0,0 +1,297 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// What an osquery query's rows become
+const (
+	OsqueryProducesApps            = "apps"            // Installed software, reported as dependencies
+	OsqueryProducesAssets          = "assets"          // Assets of the query's asset type
+	OsqueryProducesVulnerabilities = "vulnerabilities" // One finding per row
+)
+
+// ErrOsqueryUnavailable is returned when osquery isn't installed or is turned off
+var ErrOsqueryUnavailable = errors.New("osquery is not available")
+
+// OsqueryQuery is a query the osquery scanner runs. Rows of apps queries need
+// name and version columns, and may have type, path and vendor; rows of
+// assets queries need a name column; rows of vulnerabilities queries may
+// have title, description and severity columns. Every column is kept with
+// the asset or finding. Rows of queries producing nothing are reported as
+// they are.
+type OsqueryQuery struct {
+	Name      string   `json:"name"`
+	SQL       string   `json:"sql"`
+	Platforms []string `json:"platforms,omitempty"` // GOOS values the query runs on; all when empty
+	Produces  string   `json:"produces,omitempty"`
+	AssetType string   `json:"asset_type,omitempty"` // Type of the assets an assets query produces
+	Severity  string   `json:"severity,omitempty"`   // Of findings whose row has no severity; medium when empty
+}
+
+// runsOn reports whether the query runs on an OS
+func (q OsqueryQuery) runsOn(goos string) bool {
+	if len(q.Platforms) == 0 {
+		return true
+	}
+	for _, platform := range q.Platforms {
+		if platform == goos {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultOsqueryQueries are the curated queries every osquery scan runs
+func defaultOsqueryQueries() []OsqueryQuery {
+	return []OsqueryQuery{
+		{Name: "deb_packages", SQL: "SELECT name, version, 'apt' AS type FROM deb_packages", Platforms: []string{"linux"}, Produces: OsqueryProducesApps},
+		{Name: "rpm_packages", SQL: "SELECT name, version || '-' || release AS version, 'yum' AS type, vendor FROM rpm_packages", Platforms: []string{"linux"}, Produces: OsqueryProducesApps},
+		{Name: "apps", SQL: "SELECT name, bundle_short_version AS version, 'macos_app' AS type, path FROM apps", Platforms: []string{"darwin"}, Produces: OsqueryProducesApps},
+		{Name: "homebrew_packages", SQL: "SELECT name, version, 'homebrew' AS type, path FROM homebrew_packages", Platforms: []string{"darwin"}, Produces: OsqueryProducesApps},
+		{Name: "programs", SQL: "SELECT name, version, 'windows_app' AS type, install_location AS path, publisher AS vendor FROM programs", Platforms: []string{"windows"}, Produces: OsqueryProducesApps},
+		{Name: "listening_ports", SQL: "SELECT DISTINCT COALESCE(p.name, 'pid ' || l.pid) || ':' || l.port AS name, p.name AS process, l.port, l.protocol, l.address FROM listening_ports l LEFT JOIN processes p USING (pid) WHERE l.port != 0", Produces: OsqueryProducesAssets, AssetType: "listening_port"},
+		{Name: "users", SQL: "SELECT username AS name, uid, gid, shell, directory FROM users", Produces: OsqueryProducesAssets, AssetType: "user_account"},
+		{Name: "kernel_info", SQL: "SELECT 'kernel' AS name, version, arguments, path FROM kernel_info", Produces: OsqueryProducesAssets, AssetType: "kernel"},
+		{Name: "startup_items", SQL: "SELECT name, path, source, status, type FROM startup_items", Produces: OsqueryProducesAssets, AssetType: "startup_item"},
+	}
+}
+
+// loadOsqueryQueries reads operators' own queries from a JSON array
+func loadOsqueryQueries(path string) ([]OsqueryQuery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read osquery queries: %w", err)
+	}
+	var queries []OsqueryQuery
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("failed to parse osquery queries: %w", err)
+	}
+	for _, query := range queries {
+		switch {
+		case query.Name == "" || strings.TrimSpace(query.SQL) == "":
+			return nil, fmt.Errorf("osquery query %q needs a name and SQL", query.Name)
+		case query.Produces != "" && query.Produces != OsqueryProducesApps && query.Produces != OsqueryProducesAssets && query.Produces != OsqueryProducesVulnerabilities:
+			return nil, fmt.Errorf("osquery query %q produces %q, not apps, assets or vulnerabilities", query.Name, query.Produces)
+		case query.Produces == OsqueryProducesAssets && query.AssetType == "":
+			return nil, fmt.Errorf("osquery query %q produces assets but has no asset_type", query.Name)
+		}
+	}
+	return queries, nil
+}
+
+// OsqueryScanner inventories the host through osquery, for hosts that run
+// it, rather than through each platform's own commands. Queries run through
+// osqueryi, against the running osqueryd when its extensions socket is
+// present.
+type OsqueryScanner struct {
+	config  *config.Config
+	queries []OsqueryQuery
+	binary  string                           // osqueryi's path; empty when osquery isn't available
+	run     func(sql string) ([]byte, error) // Runs a query, returning osqueryi's JSON output
+}
+
+// NewOsqueryScanner creates an osquery scanner running the curated queries
+// and those in OsqueryQueriesPath. It fails only when those can't be loaded;
+// without osquery the scanner is simply unavailable.
+func NewOsqueryScanner(cfg *config.Config) (*OsqueryScanner, error) {
+	queries := defaultOsqueryQueries()
+	if cfg.OsqueryQueriesPath != "" {
+		extra, err := loadOsqueryQueries(cfg.OsqueryQueriesPath)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, extra...)
+	}
+
+	s := &OsqueryScanner{config: cfg, queries: queries}
+	if cfg.OsqueryEnabled {
+		if path, err := exec.LookPath(cfg.OsqueryBinary); err == nil {
+			s.binary = path
+		}
+	}
+	s.run = s.osqueryi
+	return s, nil
+}
+
+// Available reports whether osquery is installed and turned on
+func (s *OsqueryScanner) Available() bool {
+	return s != nil && s.binary != ""
+}
+
+// osqueryi runs a query with osqueryi, connecting to osqueryd's extensions
+// socket when it is present
+func (s *OsqueryScanner) osqueryi(sql string) ([]byte, error) {
+	args := []string{"--json"}
+	if socket := s.config.OsquerySocket; socket != "" {
+		if _, err := os.Stat(socket); err == nil {
+			args = append(args, "--connect", socket)
+		}
+	}
+	return exec.Command(s.binary, append(args, sql)...).Output()
+}
+
+// OsqueryInventory is what an osquery scan found
+type OsqueryInventory struct {
+	Apps            []models.InstalledApp
+	Assets          []models.Asset
+	Vulnerabilities []models.Vulnerability
+	Rows            map[string][]map[string]string // Query name -> rows of queries producing nothing
+	Errors          map[string]string              // Query name -> why it failed
+}
+
+// Inventory runs the queries for this OS. Queries that fail are reported in
+// the inventory's errors; it fails as a whole when osquery isn't available
+// or no software query succeeded, as the software inventory would then be
+// missing.
+func (s *OsqueryScanner) Inventory() (*OsqueryInventory, error) {
+	if !s.Available() {
+		return nil, ErrOsqueryUnavailable
+	}
+
+	inventory := &OsqueryInventory{
+		Rows:   make(map[string][]map[string]string),
+		Errors: make(map[string]string),
+	}
+	appQueries := 0
+	for _, query := range s.queries {
+		if !query.runsOn(runtime.GOOS) {
+			continue
+		}
+		output, err := s.run(query.SQL)
+		var rows []map[string]string
+		if err == nil {
+			rows, err = parseOsqueryRows(output)
+		}
+		if err != nil {
+			inventory.Errors[query.Name] = err.Error()
+			continue
+		}
+
+		switch query.Produces {
+		case OsqueryProducesApps:
+			appQueries++
+			inventory.Apps = append(inventory.Apps, osqueryApps(rows)...)
+		case OsqueryProducesAssets:
+			inventory.Assets = append(inventory.Assets, osqueryAssets(query, rows)...)
+		case OsqueryProducesVulnerabilities:
+			inventory.Vulnerabilities = append(inventory.Vulnerabilities, osqueryVulnerabilities(query, rows)...)
+		default:
+			inventory.Rows[query.Name] = rows
+		}
+	}
+	if appQueries == 0 {
+		return nil, fmt.Errorf("no osquery software query succeeded: %v", inventory.Errors)
+	}
+	return inventory, nil
+}
+
+// parseOsqueryRows parses osqueryi's JSON output, an array of rows whose
+// columns are all strings
+func parseOsqueryRows(output []byte) ([]map[string]string, error) {
+	var raw []map[string]any
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("invalid osquery output: %w", err)
+	}
+	rows := make([]map[string]string, 0, len(raw))
+	for _, r := range raw {
+		row := make(map[string]string, len(r))
+		for column, value := range r {
+			if value != nil {
+				row[column] = fmt.Sprint(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// osqueryApps maps software rows to installed apps, skipping unnamed ones
+func osqueryApps(rows []map[string]string) []models.InstalledApp {
+	var apps []models.InstalledApp
+	for _, row := range rows {
+		if row["name"] == "" {
+			continue
+		}
+		apps = append(apps, models.InstalledApp{
+			Name:    row["name"],
+			Version: row["version"],
+			Type:    row["type"],
+			Path:    row["path"],
+			Vendor:  row["vendor"],
+			License: row["license"],
+		})
+	}
+	return apps
+}
+
+// osqueryAssets maps rows to assets of the query's type
+func osqueryAssets(query OsqueryQuery, rows []map[string]string) []models.Asset {
+	now := time.Now()
+	var assets []models.Asset
+	for _, row := range rows {
+		if row["name"] == "" {
+			continue
+		}
+		assets = append(assets, models.Asset{
+			ID:        fmt.Sprintf("osquery-%s-%s", query.AssetType, row["name"]),
+			Name:      row["name"],
+			Type:      query.AssetType,
+			Status:    "active",
+			Metadata:  osqueryMetadata(query, row),
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	return assets
+}
+
+// osqueryVulnerabilities raises a finding for each row
+func osqueryVulnerabilities(query OsqueryQuery, rows []map[string]string) []models.Vulnerability {
+	var vulnerabilities []models.Vulnerability
+	for _, row := range rows {
+		title := row["title"]
+		if title == "" {
+			title = query.Name
+		}
+		severity := strings.ToLower(row["severity"])
+		if severity == "" {
+			severity = strings.ToLower(query.Severity)
+		}
+		if severity == "" {
+			severity = "medium"
+		}
+		vulnerabilities = append(vulnerabilities, models.Vulnerability{
+			ID:             uuid.New().String(),
+			Type:           "osquery",
+			Title:          title,
+			Description:    row["description"],
+			Severity:       severity,
+			Status:         "open",
+			EnrichmentData: osqueryMetadata(query, row),
+			CreatedAt:      time.Now(),
+		})
+	}
+	return vulnerabilities
+}
+
+// osqueryMetadata keeps a row's columns along with the query it came from
+func osqueryMetadata(query OsqueryQuery, row map[string]string) map[string]interface{} {
+	metadata := map[string]interface{}{"source": "osquery", "query": query.Name}
+	for column, value := range row {
+		metadata[column] = value
+	}
+	return metadata
+}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"zerotrace/agent/internal/config"
+)
+
+// fakeOsquery returns an osquery scanner answering queries from canned
+// output keyed by the table they select from
+func fakeOsquery(queries []OsqueryQuery, outputs map[string]string) *OsqueryScanner {
+	return &OsqueryScanner{
+		config:  &config.Config{},
+		queries: queries,
+		binary:  "osqueryi",
+		run: func(sql string) ([]byte, error) {
+			for table, output := range outputs {
+				if strings.Contains(sql, "FROM "+table) {
+					return []byte(output), nil
+				}
+			}
+			return nil, errors.New("no such table")
+		},
+	}
+}
+
+func TestOsqueryInventory(t *testing.T) {
+	queries := []OsqueryQuery{
+		{Name: "packages", SQL: "SELECT name, version FROM packages", Produces: OsqueryProducesApps},
+		{Name: "ports", SQL: "SELECT name, port FROM listening_ports", Produces: OsqueryProducesAssets, AssetType: "listening_port"},
+		{Name: "suid", SQL: "SELECT path AS title FROM suid_bin", Produces: OsqueryProducesVulnerabilities, Severity: "High"},
+		{Name: "uptime", SQL: "SELECT total_seconds FROM uptime"},
+		{Name: "broken", SQL: "SELECT * FROM missing", Produces: OsqueryProducesAssets, AssetType: "thing"},
+		{Name: "elsewhere", SQL: "SELECT * FROM packages", Platforms: []string{"plan9"}, Produces: OsqueryProducesApps},
+	}
+	s := fakeOsquery(queries, map[string]string{
+		"packages":        `[{"name": "openssl", "version": "3.0.2"}, {"name": "", "version": "1"}]`,
+		"listening_ports": `[{"name": "sshd:22", "port": "22"}]`,
+		"suid_bin":        `[{"title": "/usr/bin/passwd"}]`,
+		"uptime":          `[{"total_seconds": "3600"}]`,
+	})
+
+	inventory, err := s.Inventory()
+	if err != nil {
+		t.Fatalf("Inventory() = %v", err)
+	}
+	if len(inventory.Apps) != 1 || inventory.Apps[0].Name != "openssl" || inventory.Apps[0].Version != "3.0.2" {
+		t.Errorf("apps = %+v, want openssl 3.0.2 only", inventory.Apps)
+	}
+	if len(inventory.Assets) != 1 || inventory.Assets[0].Type != "listening_port" || inventory.Assets[0].Metadata["port"] != "22" {
+		t.Errorf("assets = %+v", inventory.Assets)
+	}
+	if len(inventory.Vulnerabilities) != 1 || inventory.Vulnerabilities[0].Title != "/usr/bin/passwd" || inventory.Vulnerabilities[0].Severity != "high" {
+		t.Errorf("vulnerabilities = %+v", inventory.Vulnerabilities)
+	}
+	if inventory.Rows["uptime"][0]["total_seconds"] != "3600" {
+		t.Errorf("rows = %+v", inventory.Rows)
+	}
+	if _, ok := inventory.Errors["broken"]; !ok || len(inventory.Errors) != 1 {
+		t.Errorf("errors = %+v, want only broken", inventory.Errors)
+	}
+}
+
+func TestOsqueryInventoryNeedsSoftware(t *testing.T) {
+	s := fakeOsquery([]OsqueryQuery{{Name: "packages", SQL: "SELECT * FROM packages", Produces: OsqueryProducesApps}}, nil)
+	if _, err := s.Inventory(); err == nil {
+		t.Error("an inventory without software should fail, so scans fall back to native commands")
+	}
+
+	var missing *OsqueryScanner
+	if _, err := missing.Inventory(); !errors.Is(err, ErrOsqueryUnavailable) {
+		t.Errorf("Inventory() without osquery = %v, want ErrOsqueryUnavailable", err)
+	}
+}
+
+func TestSoftwareScannerUsesOsquery(t *testing.T) {
+	software := NewSoftwareScanner(&config.Config{})
+	software.SetOsquery(fakeOsquery([]OsqueryQuery{
+		{Name: "packages", SQL: "SELECT name, version FROM packages", Platforms: []string{runtime.GOOS}, Produces: OsqueryProducesApps},
+	}, map[string]string{"packages": `[{"name": "curl", "version": "8.0.0", "type": "apt"}]`}))
+
+	result, err := software.Scan()
+	if err != nil {
+		t.Fatalf("Scan() = %v", err)
+	}
+	if result.Metadata["inventory_source"] != "osquery" || len(result.Dependencies) != 1 || result.Dependencies[0].Name != "curl" {
+		t.Errorf("Scan() = %+v, %+v, want curl from osquery", result.Metadata, result.Dependencies)
+	}
+}
+
+func TestLoadOsqueryQueries(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "queries.json")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	queries, err := loadOsqueryQueries(write(`[{"name": "crontab", "sql": "SELECT command AS title FROM crontab", "produces": "vulnerabilities", "severity": "low"}]`))
+	if err != nil || len(queries) != 1 || queries[0].Severity != "low" {
+		t.Errorf("loadOsqueryQueries() = %+v, %v", queries, err)
+	}
+
+	for _, invalid := range []string{
+		`{"name": "not an array"}`,
+		`[{"name": "no sql"}]`,
+		`[{"name": "bad", "sql": "SELECT 1", "produces": "tickets"}]`,
+		`[{"name": "untyped", "sql": "SELECT 1", "produces": "assets"}]`,
+	} {
+		if _, err := loadOsqueryQueries(write(invalid)); err == nil {
+			t.Errorf("loadOsqueryQueries(%s) should fail", invalid)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/agent/internal/models"
+)
+
+// osvBatchSize is the most queries OSV takes in one querybatch request
+const osvBatchSize = 1000
+
+// OSVSource implements CVE data from OSV.dev, which aggregates GitHub, PyPA,
+// Go, RustSec and distribution advisories and matches them against package
+// versions, so installed packages can be checked without a CVE lookup
+type OSVSource struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]osvVulnerability // Advisories fetched, by ID, for as long as the agent runs
+}
+
+// NewOSVSource creates a new OSV.dev source
+func NewOSVSource() *OSVSource {
+	return &OSVSource{
+		baseURL: "https://api.osv.dev/v1",
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		cache: make(map[string]osvVulnerability),
+	}
+}
+
+// osvVulnerability is an advisory in the OSV schema
+type osvVulnerability struct {
+	ID       string   `json:"id"`
+	Modified string   `json:"modified"`
+	Summary  string   `json:"summary"`
+	Details  string   `json:"details"`
+	Aliases  []string `json:"aliases"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Events []map[string]string `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+	References []struct {
+		URL string `json:"url"`
+	} `json:"references"`
+	DatabaseSpecific map[string]any `json:"database_specific"`
+}
+
+// GetCVE retrieves an advisory from OSV by its CVE, GHSA or OSV ID
+func (o *OSVSource) GetCVE(cveID string) (*models.Vulnerability, error) {
+	advisory, err := o.vulnerability(cveID, "")
+	if err != nil {
+		return nil, err
+	}
+	vuln := advisory.toVulnerability(nil)
+	return &vuln, nil
+}
+
+// SearchCVEs searches OSV advisories
+func (o *OSVSource) SearchCVEs(query string) ([]models.Vulnerability, error) {
+	// OSV has no keyword search; advisories are found by package or ID
+	return []models.Vulnerability{}, nil
+}
+
+// GetRecentCVEs gets recent OSV advisories
+func (o *OSVSource) GetRecentCVEs(limit int) ([]models.Vulnerability, error) {
+	// OSV's API has no feed of recent advisories, only per-ecosystem exports
+	return []models.Vulnerability{}, nil
+}
+
+// PackageAdvisories matches packages against OSV with its batched query API,
+// then fetches the details of each advisory found
+func (o *OSVSource) PackageAdvisories(packages []PackageQuery) ([]models.Vulnerability, error) {
+	var vulnerabilities []models.Vulnerability
+	for start := 0; start < len(packages); start += osvBatchSize {
+		end := start + osvBatchSize
+		if end > len(packages) {
+			end = len(packages)
+		}
+		batch := packages[start:end]
+		results, err := o.queryBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+		for i, result := range results {
+			for _, match := range result.Vulns {
+				advisory, err := o.vulnerability(match.ID, match.Modified)
+				if err != nil {
+					return nil, err
+				}
+				vulnerabilities = append(vulnerabilities, advisory.toVulnerability(&batch[i]))
+			}
+		}
+	}
+	return vulnerabilities, nil
+}
+
+// osvBatchResult is the advisories matching one query of a batch. OSV only
+// returns their IDs and when they were last modified.
+type osvBatchResult struct {
+	Vulns []struct {
+		ID       string `json:"id"`
+		Modified string `json:"modified"`
+	} `json:"vulns"`
+}
+
+// queryBatch runs one querybatch request, returning a result per package
+func (o *OSVSource) queryBatch(packages []PackageQuery) ([]osvBatchResult, error) {
+	type osvQuery struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Version string `json:"version"`
+	}
+	queries := make([]osvQuery, len(packages))
+	for i, pkg := range packages {
+		queries[i].Package.Ecosystem = pkg.Ecosystem
+		queries[i].Package.Name = pkg.Name
+		queries[i].Version = pkg.Version
+	}
+	body, err := json.Marshal(map[string]any{"queries": queries})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OSV query: %w", err)
+	}
+
+	resp, err := o.httpClient.Post(o.baseURL+"/querybatch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query OSV: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OSV returned status %d for a batch of %d packages", resp.StatusCode, len(packages))
+	}
+
+	var response struct {
+		Results []osvBatchResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse OSV response: %w", err)
+	}
+	if len(response.Results) != len(packages) {
+		return nil, fmt.Errorf("OSV returned %d results for %d packages", len(response.Results), len(packages))
+	}
+	return response.Results, nil
+}
+
+// vulnerability fetches an advisory, from the cache unless it was modified
+// since. An empty modified time accepts any cached copy.
+func (o *OSVSource) vulnerability(id, modified string) (osvVulnerability, error) {
+	o.mu.Lock()
+	cached, ok := o.cache[id]
+	o.mu.Unlock()
+	if ok && (modified == "" || cached.Modified == modified) {
+		return cached, nil
+	}
+
+	resp, err := o.httpClient.Get(o.baseURL + "/vulns/" + url.PathEscape(id))
+	if err != nil {
+		return osvVulnerability{}, fmt.Errorf("failed to fetch %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return osvVulnerability{}, fmt.Errorf("CVE %s not found", id)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return osvVulnerability{}, fmt.Errorf("OSV returned status %d for %s", resp.StatusCode, id)
+	}
+
+	var advisory osvVulnerability
+	if err := json.NewDecoder(resp.Body).Decode(&advisory); err != nil {
+		return osvVulnerability{}, fmt.Errorf("failed to parse OSV response: %w", err)
+	}
+	o.mu.Lock()
+	o.cache[id] = advisory
+	o.mu.Unlock()
+	return advisory, nil
+}
+
+// toVulnerability converts an OSV advisory, as it affects pkg when one is
+// given. Its CVE alias, if it has one, is the vulnerability's ID.
+func (a osvVulnerability) toVulnerability(pkg *PackageQuery) models.Vulnerability {
+	ids := append([]string{a.ID}, a.Aliases...)
+	vuln := models.Vulnerability{
+		ID:          a.ID,
+		Type:        "cve",
+		Severity:    "unknown",
+		Title:       a.Summary,
+		Description: a.Details,
+		Status:      "open",
+		CreatedAt:   time.Now(),
+		EnrichmentData: map[string]any{
+			"aliases": ids,
+			"sources": []string{"osv"},
+		},
+	}
+	for _, id := range ids {
+		if strings.HasPrefix(id, "CVE-") {
+			vuln.ID = id
+			vuln.CVEID = id
+			break
+		}
+	}
+	if vuln.Title == "" {
+		vuln.Title = vuln.ID
+	}
+	if vuln.Description == "" {
+		vuln.Description = a.Summary
+	}
+
+	// GitHub advisories carry their severity rating; others only a vector
+	if severity, ok := a.DatabaseSpecific["severity"].(string); ok && severity != "" {
+		vuln.Severity = advisorySeverity(severity)
+	}
+	for _, severity := range a.Severity {
+		if severity.Type == "CVSS_V3" {
+			vuln.CVSSVector = severity.Score
+			break
+		}
+	}
+	for _, reference := range a.References {
+		vuln.References = append(vuln.References, reference.URL)
+	}
+
+	for _, affected := range a.Affected {
+		if pkg != nil && (affected.Package.Name != pkg.Name || !strings.EqualFold(affected.Package.Ecosystem, pkg.Ecosystem)) {
+			continue
+		}
+		for _, r := range affected.Ranges {
+			for _, event := range r.Events {
+				if fixed := event["fixed"]; fixed != "" {
+					vuln.PatchedVersions = appendUnique(vuln.PatchedVersions, fixed)
+				}
+			}
+		}
+	}
+	if pkg != nil {
+		vuln.PackageName = pkg.Name
+		vuln.PackageVersion = pkg.Version
+		if len(vuln.PatchedVersions) > 0 {
+			vuln.Remediation = fmt.Sprintf("Upgrade %s to %s or later", pkg.Name, strings.Join(vuln.PatchedVersions, " or "))
+		}
+	}
+	return vuln
+}
+
+// advisorySeverity normalizes an advisory database's severity rating
+func advisorySeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high", "medium", "low":
+		return strings.ToLower(severity)
+	case "moderate":
+		return "medium"
+	default:
+		return "unknown"
+	}
+}
+
+// PackageEcosystem is the OSV ecosystem of a dependency of the given type, or
+// "" when there's no advisory database for it. OS packages belong to the
+// distribution's ecosystem, named by osID, the ID field of /etc/os-release.
+func PackageEcosystem(depType, osID string) string {
+	switch depType {
+	case "go":
+		return "Go"
+	case "javascript", "npm":
+		return "npm"
+	case "python", "pip":
+		return "PyPI"
+	case "gem":
+		return "RubyGems"
+	case "cargo":
+		return "crates.io"
+	case "maven":
+		return "Maven"
+	case "nuget":
+		return "NuGet"
+	case "composer":
+		return "Packagist"
+	case "apt", "dpkg":
+		switch osID {
+		case "debian":
+			return "Debian"
+		case "ubuntu":
+			return "Ubuntu"
+		}
+	case "apk":
+		if osID == "alpine" {
+			return "Alpine"
+		}
+	case "yum", "rpm":
+		switch osID {
+		case "rocky":
+			return "Rocky Linux"
+		case "almalinux":
+			return "AlmaLinux"
+		}
+	}
+	return ""
+}
+
+// appendUnique appends the values not already in list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
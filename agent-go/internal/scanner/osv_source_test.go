@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOSVPackageAdvisories(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/querybatch":
+			var body struct {
+				Queries []struct {
+					Package struct{ Ecosystem, Name string } `json:"package"`
+					Version string                           `json:"version"`
+				} `json:"queries"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Queries) != 2 {
+				t.Fatalf("unexpected batch %+v: %v", body, err)
+			}
+			if q := body.Queries[0]; q.Package.Ecosystem != "PyPI" || q.Package.Name != "jinja2" || q.Version != "2.11.2" {
+				t.Errorf("unexpected query %+v", q)
+			}
+			w.Write([]byte(`{"results":[{"vulns":[{"id":"GHSA-g3rq-g295-4j3m","modified":"2024-01-01T00:00:00Z"}]},{}]}`))
+		case "/vulns/GHSA-g3rq-g295-4j3m":
+			fetches++
+			w.Write([]byte(`{"id":"GHSA-g3rq-g295-4j3m","modified":"2024-01-01T00:00:00Z","summary":"Jinja2 ReDoS",
+				"aliases":["CVE-2020-28493"],"database_specific":{"severity":"MODERATE"},
+				"severity":[{"type":"CVSS_V3","score":"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:L"}],
+				"affected":[{"package":{"ecosystem":"PyPI","name":"jinja2"},"ranges":[{"type":"ECOSYSTEM","events":[{"introduced":"0"},{"fixed":"2.11.3"}]}]},
+				            {"package":{"ecosystem":"PyPI","name":"other"},"ranges":[{"events":[{"fixed":"9.9"}]}]}],
+				"references":[{"type":"ADVISORY","url":"https://nvd.nist.gov/vuln/detail/CVE-2020-28493"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	osv := NewOSVSource()
+	osv.baseURL = server.URL
+	packages := []PackageQuery{{"PyPI", "jinja2", "2.11.2"}, {"npm", "left-pad", "1.3.0"}}
+	for range 2 {
+		vulns, err := osv.PackageAdvisories(packages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vulns) != 1 {
+			t.Fatalf("expected 1 advisory, got %+v", vulns)
+		}
+		v := vulns[0]
+		if v.ID != "CVE-2020-28493" || v.CVEID != "CVE-2020-28493" || v.Severity != "medium" || v.PackageName != "jinja2" || v.PackageVersion != "2.11.2" {
+			t.Errorf("unexpected advisory %+v", v)
+		}
+		if len(v.PatchedVersions) != 1 || v.PatchedVersions[0] != "2.11.3" || v.CVSSVector == "" || len(v.References) != 1 {
+			t.Errorf("expected the package's fix, vector and references, got %+v", v)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("an unmodified advisory should be fetched once, got %d fetches", fetches)
+	}
+
+	if _, err := osv.GetCVE("CVE-0000-0000"); err == nil {
+		t.Error("expected an unknown advisory to fail")
+	}
+}
+
+func TestPackageEcosystem(t *testing.T) {
+	for _, tc := range []struct {
+		depType, osID, want string
+	}{
+		{"python", "", "PyPI"},
+		{"javascript", "debian", "npm"},
+		{"go", "", "Go"},
+		{"apt", "ubuntu", "Ubuntu"},
+		{"apt", "", ""},
+		{"apk", "alpine", "Alpine"},
+		{"homebrew", "", ""},
+	} {
+		if got := PackageEcosystem(tc.depType, tc.osID); got != tc.want {
+			t.Errorf("PackageEcosystem(%q, %q) = %q, want %q", tc.depType, tc.osID, got, tc.want)
+		}
+	}
+}
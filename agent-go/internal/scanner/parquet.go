@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// maxParquetFooterSize bounds the footer (schema and row group metadata) read
+// from a Parquet file. Real footers are kilobytes; a corrupt length could
+// otherwise claim most of the file.
+const maxParquetFooterSize = 16 * 1024 * 1024
+
+// parquetMagic opens and closes every Parquet file
+var parquetMagic = []byte("PAR1")
+
+// readParquetSchema reads the column names and row count from a Parquet
+// file's footer, without reading any row data. Nested columns are named by
+// their path, such as "customer.email". Files over maxSize bytes, and
+// truncated or corrupt files, return an error rather than panic.
+func readParquetSchema(path string, maxSize int64) (columns []string, rows int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			columns, rows, err = nil, 0, fmt.Errorf("corrupt Parquet file: %v", r)
+		}
+	}()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+	if size > maxSize {
+		return nil, 0, fmt.Errorf("file of %d bytes exceeds the %d byte limit", size, maxSize)
+	}
+
+	// The file starts with the magic bytes and ends with the footer, its
+	// length and the magic bytes again
+	trailer := make([]byte, 8)
+	overhead := int64(len(parquetMagic) + len(trailer))
+	if size < overhead {
+		return nil, 0, fmt.Errorf("file of %d bytes is too small to be Parquet", size)
+	}
+	if _, err := file.ReadAt(trailer, size-int64(len(trailer))); err != nil {
+		return nil, 0, fmt.Errorf("failed to read footer: %w", err)
+	}
+	if !bytes.Equal(trailer[4:], parquetMagic) {
+		return nil, 0, fmt.Errorf("missing Parquet magic bytes")
+	}
+	footerSize := int64(binary.LittleEndian.Uint32(trailer[:4]))
+	if footerSize > maxParquetFooterSize || footerSize > size-overhead {
+		return nil, 0, fmt.Errorf("corrupt footer length %d", footerSize)
+	}
+
+	pf, err := parquet.OpenFile(file, size, parquet.SkipPageIndex(true), parquet.SkipBloomFilters(true))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid Parquet footer: %w", err)
+	}
+	for _, column := range pf.Schema().Columns() {
+		columns = append(columns, strings.Join(column, "."))
+	}
+	return columns, pf.NumRows(), nil
+}
@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type customerRow struct {
+	CustomerID int64   `parquet:"customer_id"`
+	Email      string  `parquet:"email"`
+	Score      float64 `parquet:"score"`
+}
+
+func TestAnalyzeParquetReadsColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "customers.parquet")
+	rows := []customerRow{{1, "a@example.com", 0.4}, {2, "b@example.com", 0.9}, {3, "c@example.com", 0.1}}
+	if err := parquet.WriteFile(path, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	as := NewAIMLScanner(setupTestConfig(), nil)
+	data := &TrainingDataInfo{Path: path, License: "unknown"}
+	as.analyzeParquet(data)
+
+	if len(data.Columns) != 3 || data.Records != 3 {
+		t.Fatalf("got columns %v and %d records, want 3 of each", data.Columns, data.Records)
+	}
+	if !data.HasPII || len(data.SensitiveFields) != 1 || data.SensitiveFields[0] != "email" {
+		t.Errorf("expected the email column to be flagged as PII, got %v", data.SensitiveFields)
+	}
+}
+
+func TestAnalyzeParquetHandlesCorruptFiles(t *testing.T) {
+	as := NewAIMLScanner(setupTestConfig(), nil)
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"truncated.parquet":   []byte("PAR1"),
+		"no-magic.parquet":    []byte("id,email\n1,a@example.com\n"),
+		"huge-footer.parquet": append([]byte("PAR1garbage"), 0xff, 0xff, 0xff, 0x7f, 'P', 'A', 'R', '1'),
+		"bad-footer.parquet":  append([]byte("PAR1garbage-footer"), 12, 0, 0, 0, 'P', 'A', 'R', '1'),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		data := &TrainingDataInfo{Path: path}
+		as.analyzeParquet(data)
+		if data.DataQuality != parquetUnreadableQuality || len(data.Columns) != 0 {
+			t.Errorf("%s: expected a low-quality result with no columns, got quality %v and columns %v", name, data.DataQuality, data.Columns)
+		}
+	}
+}
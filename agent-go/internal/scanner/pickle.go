@@ -0,0 +1,358 @@
+package scanner
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pickledModelExtensions are model files that hold pickled data: PyTorch
+// checkpoints, which are ZIP archives of pickles or legacy plain pickles, and
+// plain pickles
+var pickledModelExtensions = map[string]bool{
+	".pt":     true,
+	".pth":    true,
+	".bin":    true, // pytorch_model.bin
+	".pkl":    true,
+	".pickle": true,
+}
+
+// dangerousPickleModules are modules any of whose callables let a pickle run
+// commands or reach the host when it is loaded, with the severity of importing one
+var dangerousPickleModules = map[string]string{
+	"os":         "critical",
+	"posix":      "critical",
+	"nt":         "critical",
+	"subprocess": "critical",
+	"pty":        "critical",
+	"commands":   "critical",
+	"runpy":      "critical",
+	"socket":     "high",
+	"shutil":     "high",
+	"webbrowser": "high",
+	"importlib":  "high",
+}
+
+// dangerousPickleBuiltins are the builtins that let a pickle run code
+var dangerousPickleBuiltins = map[string]string{
+	"eval":       "critical",
+	"exec":       "critical",
+	"execfile":   "critical",
+	"compile":    "critical",
+	"apply":      "critical",
+	"__import__": "critical",
+	"getattr":    "high",
+	"open":       "high",
+	"breakpoint": "high",
+}
+
+const (
+	maxPickleStringSize = 256 // Longer strings are never module or callable names, and are skipped
+	maxPicklesPerStream = 8   // Legacy PyTorch files hold five pickles, then raw tensor data
+)
+
+// pickleGlobal is a callable a pickle imports with GLOBAL, INST or
+// STACK_GLOBAL, and whether a later REDUCE, INST, OBJ or NEWOBJ may call it
+type pickleGlobal struct {
+	module, name string
+	called       bool
+}
+
+// pickleRisk returns the severity of a pickle importing module.name
+func pickleRisk(module, name string) (string, bool) {
+	if module == "builtins" || module == "__builtin__" {
+		severity, ok := dangerousPickleBuiltins[name]
+		return severity, ok
+	}
+	root, _, _ := strings.Cut(module, ".")
+	severity, ok := dangerousPickleModules[root]
+	return severity, ok
+}
+
+// pickleVulnerabilities scans the pickled data of a model file for imports
+// of dangerous callables: each pickle in a PyTorch ZIP archive, or the file
+// itself if it is a plain pickle. A dangerous callable the pickle calls is
+// reported at its severity; one it only imports is reported as high.
+func pickleVulnerabilities(path string) ([]AIMLVulnerability, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return nil, fmt.Errorf("failed to read file header: %w", err)
+	}
+
+	var vulns []AIMLVulnerability
+	if !bytes.Equal(magic, []byte("PK\x03\x04")) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		globals, err := scanPickles(bufio.NewReader(file))
+		if err != nil {
+			return nil, err
+		}
+		return appendPickleVulnerabilities(vulns, filepath.Base(path), globals), nil
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	for _, entry := range archive.File {
+		if !strings.HasSuffix(entry.Name, ".pkl") {
+			continue
+		}
+		reader, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		globals, err := scanPickles(bufio.NewReader(reader))
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		vulns = appendPickleVulnerabilities(vulns, entry.Name, globals)
+	}
+	return vulns, nil
+}
+
+func appendPickleVulnerabilities(vulns []AIMLVulnerability, source string, globals []pickleGlobal) []AIMLVulnerability {
+	seen := make(map[string]bool)
+	for _, global := range globals {
+		severity, dangerous := pickleRisk(global.module, global.name)
+		callable := global.module + "." + global.name
+		if !dangerous || seen[callable] {
+			continue
+		}
+		seen[callable] = true
+
+		description := fmt.Sprintf("Pickled data in %s calls %s, which runs when the model is loaded", source, callable)
+		if !global.called {
+			severity = "high"
+			description = fmt.Sprintf("Pickled data in %s imports %s, which can run code when the model is loaded", source, callable)
+		}
+		vulns = append(vulns, AIMLVulnerability{
+			ID:          uuid.New().String(),
+			Severity:    severity,
+			Description: description,
+			CVE:         "CWE-502",
+			FoundAt:     time.Now(),
+		})
+	}
+	return vulns
+}
+
+// scanPickles scans the consecutive pickles at the start of r. Anything after
+// the first pickle that does not parse is taken to be data, not a pickle.
+func scanPickles(r *bufio.Reader) ([]pickleGlobal, error) {
+	var globals []pickleGlobal
+	for i := 0; i < maxPicklesPerStream; i++ {
+		if _, err := r.Peek(1); err == io.EOF && i > 0 {
+			break
+		}
+		found, err := scanPickle(r)
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("not a pickle: %w", err)
+			}
+			break
+		}
+		globals = append(globals, found...)
+	}
+	return globals, nil
+}
+
+// scanPickle walks the opcodes of one pickle up to its STOP, without
+// executing it, and returns the globals it imports. STACK_GLOBAL takes its
+// module and name from the stack; they are taken to be the last two strings
+// pushed, directly or from the memo.
+func scanPickle(r *bufio.Reader) ([]pickleGlobal, error) {
+	var globals []pickleGlobal
+	called := 0 // globals[:called] are imported before a call opcode
+
+	var recent [2]string // The last two strings pushed
+	top := ""            // The value on top of the stack, if a string
+	memo := make(map[uint64]string)
+	pushString := func(s string) {
+		recent[0], recent[1] = recent[1], s
+		top = s
+	}
+
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		pushed := top
+		top = ""
+
+		switch op {
+		case '.': // STOP
+			for i := range globals[:called] {
+				globals[i].called = true
+			}
+			return globals, nil
+
+		// No argument
+		case '(', '0', '1', '2', 'N', 'Q', 'a', 'b', 'd', '}', 'e', 'l', ']', 's', 't', ')', 'u',
+			0x85, 0x86, 0x87, 0x88, 0x89, 0x8f, 0x90, 0x91, 0x97, 0x98:
+		case 'R', 'o', 0x81, 0x92: // REDUCE, OBJ, NEWOBJ, NEWOBJ_EX
+			called = len(globals)
+		case 0x94: // MEMOIZE
+			memo[uint64(len(memo))] = pushed
+			top = pushed
+
+		// Fixed-size arguments
+		case 0x80: // PROTO
+			err = skipPickleBytes(r, 1)
+			top = pushed
+		case 0x95: // FRAME
+			err = skipPickleBytes(r, 8)
+			top = pushed
+		case 'K', 0x82: // BININT1, EXT1
+			err = skipPickleBytes(r, 1)
+		case 'M', 0x83: // BININT2, EXT2
+			err = skipPickleBytes(r, 2)
+		case 'J', 0x84: // BININT, EXT4
+			err = skipPickleBytes(r, 4)
+		case 'G': // BINFLOAT
+			err = skipPickleBytes(r, 8)
+		case 'h', 'j': // BINGET, LONG_BINGET
+			var index uint64
+			if index, err = readPickleUint(r, pickleIndexSize(op)); err == nil {
+				if s, ok := memo[index]; ok && s != "" {
+					pushString(s)
+				}
+			}
+		case 'q', 'r': // BINPUT, LONG_BINPUT
+			var index uint64
+			if index, err = readPickleUint(r, pickleIndexSize(op)); err == nil {
+				memo[index] = pushed
+				top = pushed
+			}
+
+		// Newline-terminated arguments
+		case 'F', 'I', 'L', 'P':
+			_, err = readPickleLine(r)
+		case 'g': // GET
+			var line string
+			if line, err = readPickleLine(r); err == nil {
+				index, perr := strconv.ParseUint(line, 10, 64)
+				if s, ok := memo[index]; perr == nil && ok && s != "" {
+					pushString(s)
+				}
+			}
+		case 'p': // PUT
+			var line string
+			if line, err = readPickleLine(r); err == nil {
+				if index, perr := strconv.ParseUint(line, 10, 64); perr == nil {
+					memo[index] = pushed
+				}
+				top = pushed
+			}
+		case 'S', 'V': // STRING, UNICODE
+			var line string
+			if line, err = readPickleLine(r); err == nil {
+				pushString(strings.Trim(line, `'"`))
+			}
+		case 'c', 'i': // GLOBAL, INST
+			var module, name string
+			if module, err = readPickleLine(r); err == nil {
+				name, err = readPickleLine(r)
+			}
+			globals = append(globals, pickleGlobal{module: module, name: name})
+			if op == 'i' {
+				called = len(globals)
+			}
+		case 0x93: // STACK_GLOBAL
+			globals = append(globals, pickleGlobal{module: recent[0], name: recent[1]})
+
+		// Length-prefixed arguments
+		case 'U', 'C', 0x8c: // SHORT_BINSTRING, SHORT_BINBYTES, SHORT_BINUNICODE
+			err = readPickleString(r, 1, op != 'C', pushString)
+		case 'T', 'X', 'B': // BINSTRING, BINUNICODE, BINBYTES
+			err = readPickleString(r, 4, op != 'B', pushString)
+		case 0x8d, 0x8e, 0x96: // BINUNICODE8, BINBYTES8, BYTEARRAY8
+			err = readPickleString(r, 8, op == 0x8d, pushString)
+		case 0x8a: // LONG1
+			err = readPickleString(r, 1, false, nil)
+		case 0x8b: // LONG4
+			err = readPickleString(r, 4, false, nil)
+
+		default:
+			return nil, fmt.Errorf("unknown opcode 0x%02x", op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// pickleIndexSize is the size of the memo index of BINGET and BINPUT (one
+// byte) or LONG_BINGET and LONG_BINPUT (four)
+func pickleIndexSize(op byte) int {
+	if op == 'h' || op == 'q' {
+		return 1
+	}
+	return 4
+}
+
+func skipPickleBytes(r *bufio.Reader, n int64) error {
+	if _, err := io.CopyN(io.Discard, r, n); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// readPickleUint reads a little-endian unsigned integer of size bytes
+func readPickleUint(r *bufio.Reader, size int) (uint64, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf[:size]); err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+func readPickleLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", io.ErrUnexpectedEOF
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// readPickleString reads a value prefixed by its length in size bytes,
+// passing it to push if it is text short enough to be a name
+func readPickleString(r *bufio.Reader, size int, text bool, push func(string)) error {
+	length, err := readPickleUint(r, size)
+	if err != nil {
+		return err
+	}
+	if !text || length > maxPickleStringSize {
+		if length > 1<<62 {
+			return errors.New("invalid length")
+		}
+		return skipPickleBytes(r, int64(length))
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	push(string(value))
+	return nil
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	// torch.save of an OrderedDict, with protocol 2
+	benignPickle = []byte("\x80\x02ccollections\nOrderedDict\nq\x00)Rq\x01.")
+	// os.system("id"), with protocol 0
+	systemPickle = []byte("cos\nsystem\n(S'id'\ntR.")
+	// eval("print(1)"), with protocol 4 and the callable memoized before STACK_GLOBAL
+	evalPickle = []byte("\x80\x04\x95\x1f\x00\x00\x00\x00\x00\x00\x00\x8c\x08builtins\x94\x8c\x04eval\x94\x93\x94\x8c\x08print(1)\x94\x85\x94R\x94.")
+	// subprocess.Popen, imported but never called
+	importPickle = []byte("csubprocess\nPopen\n.")
+)
+
+// torchArchive writes a PyTorch checkpoint: a ZIP archive with the pickle at
+// archive/data.pkl and tensor storage alongside it
+func torchArchive(t *testing.T, name string, pickle []byte) string {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for entry, content := range map[string][]byte{
+		"archive/data.pkl": pickle,
+		"archive/data/0":   bytes.Repeat([]byte{0x93, 'R'}, 64),
+		"archive/version":  []byte("3\n"),
+	} {
+		w, err := archive.Create(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(content)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPickleVulnerabilities(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name     string
+		path     string
+		severity string // Empty when the file is safe
+		callable string
+	}{
+		{"benign archive", torchArchive(t, "model.pt", benignPickle), "", ""},
+		{"os.system archive", torchArchive(t, "model.pth", systemPickle), "critical", "os.system"},
+		{"eval archive", torchArchive(t, "pytorch_model.bin", evalPickle), "critical", "builtins.eval"},
+		{"plain pickle", filepath.Join(dir, "model.pkl"), "critical", "os.system"},
+		{"imported only", filepath.Join(dir, "model.pickle"), "high", "subprocess.Popen"},
+		{"legacy checkpoint", filepath.Join(dir, "legacy.pt"), "", ""},
+	} {
+		switch filepath.Base(tc.path) {
+		case "model.pkl":
+			os.WriteFile(tc.path, systemPickle, 0o644)
+		case "model.pickle":
+			os.WriteFile(tc.path, importPickle, 0o644)
+		case "legacy.pt":
+			// Several pickles, then raw tensor data
+			legacy := append(append([]byte("\x80\x02\x8a\x0a.\x80\x02M\xe9\x03."), benignPickle...), 0xff, 0x00, 'c')
+			os.WriteFile(tc.path, legacy, 0o644)
+		}
+
+		vulns, err := pickleVulnerabilities(tc.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if tc.severity == "" {
+			if len(vulns) != 0 {
+				t.Errorf("%s: expected no vulnerabilities, got %+v", tc.name, vulns)
+			}
+			continue
+		}
+		if len(vulns) != 1 || vulns[0].Severity != tc.severity || !strings.Contains(vulns[0].Description, tc.callable) {
+			t.Errorf("%s: expected a %s vulnerability naming %s, got %+v", tc.name, tc.severity, tc.callable, vulns)
+		}
+	}
+}
+
+func TestAnalyzeModelContentScansPyTorchArchives(t *testing.T) {
+	as := NewAIMLScanner(setupTestConfig(), nil)
+	model := ModelInfo{Name: "model.pt", Path: torchArchive(t, "model.pt", systemPickle), Framework: "PyTorch"}
+	as.analyzeModelContent(&model)
+
+	findings := as.scanModel(model)
+	if len(findings) == 0 || findings[0].Severity != "critical" || !strings.Contains(findings[0].Description, "os.system") {
+		t.Errorf("expected a critical finding naming os.system, got %+v", findings)
+	}
+}
+
+func TestPickleVulnerabilitiesRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.pt")
+	if err := os.WriteFile(path, []byte("not a pickle"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pickleVulnerabilities(path); err == nil {
+		t.Error("expected an error for a file that is neither a ZIP archive nor a pickle")
+	}
+}
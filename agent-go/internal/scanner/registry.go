@@ -0,0 +1,111 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
+)
+
+// Scanner is a scanner the agent runs on its scan interval, alongside its
+// built-in scanners, sending its results to the API as they are. Custom
+// scanners, such as checks that can't be upstreamed, implement it in their
+// own package and register from an init function, so that an agent built
+// with the package imported runs them:
+//
+//	func init() {
+//		scanner.Register("acme-hardening", func(cfg *config.Config) (scanner.Scanner, error) {
+//			return acme.NewHardeningScanner(cfg), nil
+//		})
+//	}
+//
+// Scan should stop early once ctx is done. A Scanner that walks the
+// filesystem or runs heavy external tools can also implement
+// Heavy() bool, so the resource budget throttles it as it does the
+// software, network, AI/ML and container scanners.
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context) (*models.ScanResult, error)
+}
+
+// Factory creates a registered scanner for the agent's configuration
+type Factory func(cfg *config.Config) (Scanner, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Factory)
+)
+
+func init() {
+	Register(ScannerConfig, func(cfg *config.Config) (Scanner, error) {
+		return NewConfigScanner(cfg), nil
+	})
+}
+
+// Register makes a scanner available to the agent under a name, which must
+// be the name its Scanner reports. Like database/sql's Register, it panics
+// if the name is taken or the factory is nil, as both are programming errors.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" || factory == nil {
+		panic("scanner: Register needs a name and a factory")
+	}
+	if _, taken := registry[name]; taken {
+		panic("scanner: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// Registered returns the names of the registered scanners, sorted
+func Registered() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRegistered creates the registered scanners the configuration enables,
+// in name order. A scanner that can't be created is left out, and why is
+// returned along with the others that could be.
+func NewRegistered(cfg *config.Config) ([]Scanner, error) {
+	var scanners []Scanner
+	var errs []error
+	for _, name := range Registered() {
+		if !cfg.ScannerEnabled(name) {
+			continue
+		}
+		registryMu.Lock()
+		factory := registry[name]
+		registryMu.Unlock()
+
+		s, err := factory(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scanner %s: %w", name, err))
+			continue
+		}
+		if s.Name() != name {
+			errs = append(errs, fmt.Errorf("scanner %s: registered scanner is named %q", name, s.Name()))
+			continue
+		}
+		scanners = append(scanners, s)
+	}
+	return scanners, errors.Join(errs...)
+}
+
+// IsHeavy reports whether a scanner says it walks the filesystem or runs
+// heavy external tools
+func IsHeavy(s Scanner) bool {
+	heavy, ok := s.(interface{ Heavy() bool })
+	return ok && heavy.Heavy()
+}
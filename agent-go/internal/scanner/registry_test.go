@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
+)
+
+type customScanner struct {
+	name  string
+	heavy bool
+}
+
+func (s *customScanner) Name() string { return s.name }
+func (s *customScanner) Heavy() bool  { return s.heavy }
+func (s *customScanner) Scan(ctx context.Context) (*models.ScanResult, error) {
+	return &models.ScanResult{Metadata: map[string]interface{}{"scan_type": s.name}}, nil
+}
+
+// registerForTest registers a scanner for the duration of a test
+func registerForTest(t *testing.T, name string, factory Factory) {
+	t.Helper()
+	Register(name, factory)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, name)
+		registryMu.Unlock()
+	})
+}
+
+func TestRegisteredScannersAreCreatedUnlessDisabled(t *testing.T) {
+	registerForTest(t, "acme-hardening", func(*config.Config) (Scanner, error) {
+		return &customScanner{name: "acme-hardening", heavy: true}, nil
+	})
+	registerForTest(t, "acme-inventory", func(*config.Config) (Scanner, error) {
+		return &customScanner{name: "acme-inventory"}, nil
+	})
+
+	names := Registered()
+	if !slices.Contains(names, ScannerConfig) || !slices.Contains(names, "acme-hardening") || !slices.IsSorted(names) {
+		t.Fatalf("expected the config scanner and custom scanners registered in order, got %v", names)
+	}
+
+	scanners, err := NewRegistered(&config.Config{DisabledScanners: []string{"acme-inventory"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created []string
+	for _, s := range scanners {
+		created = append(created, s.Name())
+	}
+	if !slices.Equal(created, []string{"acme-hardening", ScannerConfig}) {
+		t.Errorf("expected the disabled scanner to be left out, got %v", created)
+	}
+	if !IsHeavy(scanners[0]) || IsHeavy(scanners[1]) {
+		t.Error("expected only the scanner saying so to be heavy")
+	}
+
+	scanners, _ = NewRegistered(&config.Config{EnabledScanners: []string{ScannerConfig}})
+	if len(scanners) != 1 || scanners[0].Name() != ScannerConfig {
+		t.Errorf("expected only the enabled scanner, got %d scanners", len(scanners))
+	}
+}
+
+func TestNewRegisteredReportsScannersThatFail(t *testing.T) {
+	registerForTest(t, "acme-broken", func(*config.Config) (Scanner, error) {
+		return nil, errors.New("license missing")
+	})
+	registerForTest(t, "acme-misnamed", func(*config.Config) (Scanner, error) {
+		return &customScanner{name: "something-else"}, nil
+	})
+
+	scanners, err := NewRegistered(&config.Config{EnabledScanners: []string{"acme-broken", "acme-misnamed", ScannerConfig}})
+	if err == nil {
+		t.Fatal("expected the failing scanners to be reported")
+	}
+	if len(scanners) != 1 || scanners[0].Name() != ScannerConfig {
+		t.Errorf("expected the scanners that could be created to be returned, got %d", len(scanners))
+	}
+}
+
+func TestRegisterRejectsDuplicateNames(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a taken name to panic")
+		}
+	}()
+	Register(ScannerConfig, func(cfg *config.Config) (Scanner, error) { return NewConfigScanner(cfg), nil })
+}
+
+func TestScanScopeRunsCustomScanners(t *testing.T) {
+	scope := &ScanScope{Scanners: []string{ScannerSoftware}}
+	if !scope.Enabled("acme-hardening") {
+		t.Error("expected a scanner the API doesn't know to run whatever the scope")
+	}
+	if scope.Enabled(ScannerConfig) {
+		t.Error("expected the scope to still decide the built-in scanners")
+	}
+}
@@ -0,0 +1,50 @@
+package scanner
+
+import "slices"
+
+// Scanners a scan scope can enable, as named by the API
+const (
+	ScannerSoftware  = "software"
+	ScannerSystem    = "system"
+	ScannerConfig    = "config"
+	ScannerNetwork   = "network"
+	ScannerAIML      = "aiml"
+	ScannerContainer = "container"
+)
+
+// defaultScanners run when the API sets no scope for this agent
+var defaultScanners = []string{ScannerSoftware, ScannerSystem, ScannerConfig, ScannerNetwork}
+
+// ScanScope selects which scanners this agent runs and, for scanners that walk
+// the filesystem, the paths they cover. The API sets it per agent so a fleet
+// can be scanned according to each host's role. A nil scope runs the defaults.
+type ScanScope struct {
+	Scanners []string            `json:"scanners"`
+	Paths    map[string][]string `json:"paths,omitempty"` // Scanner -> root paths it scans
+	Version  int                 `json:"version"`
+	Default  bool                `json:"default"`
+}
+
+// scopedScanners are the scanners the API knows to scope
+var scopedScanners = []string{ScannerSoftware, ScannerSystem, ScannerConfig, ScannerNetwork, ScannerAIML, ScannerContainer}
+
+// Enabled reports whether the scope runs a scanner. Scanners registered
+// beyond the built-in ones are unknown to the API, so they run whatever the
+// scope; the agent's configuration can still disable them.
+func (s *ScanScope) Enabled(scanner string) bool {
+	if !slices.Contains(scopedScanners, scanner) {
+		return true
+	}
+	if s == nil {
+		return slices.Contains(defaultScanners, scanner)
+	}
+	return slices.Contains(s.Scanners, scanner)
+}
+
+// PathsFor returns the root paths a scanner covers, or fallback if the scope sets none
+func (s *ScanScope) PathsFor(scanner string, fallback []string) []string {
+	if s == nil || len(s.Paths[scanner]) == 0 {
+		return fallback
+	}
+	return s.Paths[scanner]
+}
@@ -16,20 +16,20 @@ import (
 	"github.com/google/uuid"
 )
 
-// Scanner handles vulnerability scanning
-type Scanner struct {
+// FileScanner scans the files under the working directory for vulnerabilities and dependencies
+type FileScanner struct {
 	config *config.Config
 }
 
-// NewScanner creates a new scanner instance
-func NewScanner(cfg *config.Config) *Scanner {
-	return &Scanner{
+// NewFileScanner creates a new file scanner
+func NewFileScanner(cfg *config.Config) *FileScanner {
+	return &FileScanner{
 		config: cfg,
 	}
 }
 
 // Scan performs a vulnerability scan
-func (s *Scanner) Scan() (*models.ScanResult, error) {
+func (s *FileScanner) Scan() (*models.ScanResult, error) {
 	startTime := time.Now()
 
 	// Create scan result
@@ -90,7 +90,7 @@ func (s *Scanner) Scan() (*models.ScanResult, error) {
 }
 
 // scanFiles scans the directory for files matching include/exclude patterns
-func (s *Scanner) scanFiles(root string) ([]models.FileInfo, error) {
+func (s *FileScanner) scanFiles(root string) ([]models.FileInfo, error) {
 	var files []models.FileInfo
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -157,7 +157,7 @@ func (s *Scanner) scanFiles(root string) ([]models.FileInfo, error) {
 }
 
 // shouldScanFile determines if a file should be scanned based on patterns
-func (s *Scanner) shouldScanFile(path string) bool {
+func (s *FileScanner) shouldScanFile(path string) bool {
 	// Check exclude patterns first
 	for _, pattern := range s.config.ExcludePatterns {
 		if strings.Contains(path, pattern) {
@@ -176,7 +176,7 @@ func (s *Scanner) shouldScanFile(path string) bool {
 }
 
 // getFileHash calculates SHA256 hash of a file
-func (s *Scanner) getFileHash(path string) (string, error) {
+func (s *FileScanner) getFileHash(path string) (string, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return "", err
@@ -192,7 +192,7 @@ func (s *Scanner) getFileHash(path string) (string, error) {
 }
 
 // detectLanguage detects the programming language of a file
-func (s *Scanner) detectLanguage(path string) string {
+func (s *FileScanner) detectLanguage(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
 	case ".go":
@@ -223,7 +223,7 @@ func (s *Scanner) detectLanguage(path string) string {
 }
 
 // countLines counts the number of lines in a file
-func (s *Scanner) countLines(path string) int {
+func (s *FileScanner) countLines(path string) int {
 	file, err := os.Open(path)
 	if err != nil {
 		return 0
@@ -239,7 +239,7 @@ func (s *Scanner) countLines(path string) int {
 }
 
 // analyzeFiles analyzes files for vulnerabilities and dependencies
-func (s *Scanner) analyzeFiles(files []models.FileInfo) ([]models.Vulnerability, []models.Dependency, error) {
+func (s *FileScanner) analyzeFiles(files []models.FileInfo) ([]models.Vulnerability, []models.Dependency, error) {
 	var vulnerabilities []models.Vulnerability
 	var dependencies []models.Dependency
 
@@ -263,7 +263,7 @@ func (s *Scanner) analyzeFiles(files []models.FileInfo) ([]models.Vulnerability,
 }
 
 // analyzeVulnerabilities analyzes a file for vulnerabilities
-func (s *Scanner) analyzeVulnerabilities(file models.FileInfo) ([]models.Vulnerability, error) {
+func (s *FileScanner) analyzeVulnerabilities(file models.FileInfo) ([]models.Vulnerability, error) {
 	// Agent no longer performs local vulnerability detection
 	// Dependencies are sent to API, which handles enrichment via Python service
 	// This ensures consistent CVE detection across all agents
@@ -272,7 +272,7 @@ func (s *Scanner) analyzeVulnerabilities(file models.FileInfo) ([]models.Vulnera
 }
 
 // analyzeDependencies analyzes a file for dependencies
-func (s *Scanner) analyzeDependencies(file models.FileInfo) ([]models.Dependency, error) {
+func (s *FileScanner) analyzeDependencies(file models.FileInfo) ([]models.Dependency, error) {
 	// Scan actual package managers for real dependencies
 	var dependencies []models.Dependency
 
@@ -298,7 +298,7 @@ func (s *Scanner) analyzeDependencies(file models.FileInfo) ([]models.Dependency
 }
 
 // scanGoMod scans go.mod file for dependencies
-func (s *Scanner) scanGoMod(filePath string) []models.Dependency {
+func (s *FileScanner) scanGoMod(filePath string) []models.Dependency {
 	var dependencies []models.Dependency
 
 	content, err := os.ReadFile(filePath)
@@ -330,7 +330,7 @@ func (s *Scanner) scanGoMod(filePath string) []models.Dependency {
 }
 
 // scanPackageJson scans package.json file for dependencies
-func (s *Scanner) scanPackageJson(filePath string) []models.Dependency {
+func (s *FileScanner) scanPackageJson(filePath string) []models.Dependency {
 	var dependencies []models.Dependency
 
 	content, err := os.ReadFile(filePath)
@@ -363,6 +363,7 @@ func (s *Scanner) scanPackageJson(filePath string) []models.Dependency {
 						Version:   version,
 						Type:      "javascript",
 						Location:  filePath,
+						License:   nodeModuleLicense(filePath, name),
 						CreatedAt: time.Now(),
 					}
 					dependencies = append(dependencies, dep)
@@ -375,7 +376,7 @@ func (s *Scanner) scanPackageJson(filePath string) []models.Dependency {
 }
 
 // scanPythonDeps scans Python requirements files
-func (s *Scanner) scanPythonDeps(filePath string) []models.Dependency {
+func (s *FileScanner) scanPythonDeps(filePath string) []models.Dependency {
 	var dependencies []models.Dependency
 
 	content, err := os.ReadFile(filePath)
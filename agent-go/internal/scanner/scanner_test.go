@@ -1,7 +1,12 @@
 package scanner
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 	"zerotrace/agent/internal/config"
@@ -91,7 +96,7 @@ func TestConfigScanner_Scan(t *testing.T) {
 	cfg := setupTestConfig()
 	scanner := NewConfigScanner(cfg)
 
-	result, err := scanner.Scan()
+	result, err := scanner.Scan(context.Background())
 	if err != nil {
 		t.Fatalf("ConfigScanner.Scan() failed: %v", err)
 	}
@@ -100,3 +105,273 @@ func TestConfigScanner_Scan(t *testing.T) {
 		t.Fatal("ConfigScanner.Scan() returned nil result")
 	}
 }
+
+func TestCompareToBaseline(t *testing.T) {
+	baseline := &ConfigBaseline{
+		HostGroup: "hardened",
+		Version:   2,
+		Settings: map[string]string{
+			"Firewall Status":   "Firewall is enabled",
+			"Screen Lock":       "Screen lock is enabled",
+			"Guest Account":     "Guest account is disabled",
+			"Gatekeeper Status": "Gatekeeper is enabled",
+		},
+	}
+	current := map[string]configSetting{
+		"Firewall Status": {severity: "high", value: "Firewall is enabled"},
+		"Screen Lock":     {severity: "medium", value: "Screen lock delay is 3600 seconds"},
+		// An errored check is not drift; it is reported as unassessed instead
+		"Gatekeeper Status": {severity: "high", value: "Unable to check Gatekeeper status", unassessed: true},
+	}
+
+	drift := compareToBaseline(baseline, current, "darwin")
+	if len(drift) != 2 {
+		t.Fatalf("expected 2 drift findings, got %d", len(drift))
+	}
+	if drift[0].Location != "Guest Account" || drift[0].EnrichmentData["actual"] != "setting not reported" {
+		t.Errorf("unexpected drift for missing setting: %+v", drift[0])
+	}
+	if drift[1].Location != "Screen Lock" || drift[1].Severity != "medium" {
+		t.Errorf("unexpected drift for changed setting: %+v", drift[1])
+	}
+
+	if drift := compareToBaseline(nil, current, "darwin"); drift != nil {
+		t.Errorf("expected no drift without a baseline, got %d findings", len(drift))
+	}
+}
+
+func TestCheckResultRecordsCommand(t *testing.T) {
+	cs := NewConfigScanner(setupTestConfig())
+
+	output, err := cs.command("echo", "1")
+	if err != nil {
+		t.Skip("echo not available")
+	}
+	result := cs.verdict(strings.TrimSpace(string(output)) == "1", string(output), "1", "Setting is enabled")
+	if !result.Passed || result.Errored || result.Command != "echo 1" || result.Observed != "1" || result.Confidence != 1 {
+		t.Errorf("unexpected verdict: %+v", result)
+	}
+	cs.takeEvidence()
+
+	_, err = cs.command("zerotrace-no-such-tool", "--status")
+	result = cs.checkError(err, "Unable to check setting")
+	if result.Passed || !result.Errored || result.Confidence != 0 || result.Command != "zerotrace-no-such-tool --status" {
+		t.Errorf("unexpected error result: %+v", result)
+	}
+	if result.Observed == "" {
+		t.Error("expected the command error to be recorded as observed")
+	}
+}
+
+func TestParseToolVersion(t *testing.T) {
+	cases := map[string]string{
+		"Nmap version 7.94 ( https://nmap.org )":             "7.94",
+		"Docker version 24.0.7, build afdd53b":               "24.0.7",
+		"Client Version: v1.29.2\nKustomize Version: v5.0.4": "1.29.2",
+		"Version: 0.48.3":          "0.48.3",
+		"podman version 4.9.0-rc1": "4.9.0-rc1",
+		"command not found":        "",
+	}
+
+	for output, want := range cases {
+		if got := parseToolVersion(output); got != want {
+			t.Errorf("parseToolVersion(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestCapabilityReportSkipsMissingTools(t *testing.T) {
+	report := newCapabilityReport()
+
+	if report.require("imaginary_scan", "zerotrace-no-such-tool") {
+		t.Fatal("expected a missing tool to be unavailable")
+	}
+	report.available("port_discovery")
+
+	if report.Complete() {
+		t.Error("expected a report with a skipped capability to be incomplete")
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].MissingTool != "zerotrace-no-such-tool" {
+		t.Errorf("unexpected skipped capabilities: %+v", report.Skipped)
+	}
+	if len(report.Available) != 1 || report.Available[0] != "port_discovery" {
+		t.Errorf("unexpected available capabilities: %+v", report.Available)
+	}
+}
+
+func TestConfigScannerCapturesCommandEvidence(t *testing.T) {
+	cs := NewConfigScanner(setupTestConfig())
+	cs.evidence = append(cs.evidence, commandEvidence("ufw", []string{"status"}, []byte("Status: inactive\n"), errors.New("exit status 1")))
+
+	evidence := cs.takeEvidence()
+	if len(evidence) != 1 || len(cs.takeEvidence()) != 0 {
+		t.Fatalf("expected evidence to be taken once, got %d", len(evidence))
+	}
+	data := string(evidence[0].Data)
+	if !strings.HasPrefix(data, "$ ufw status\nStatus: inactive") || !strings.Contains(data, "exit status 1") {
+		t.Errorf("unexpected evidence: %q", data)
+	}
+
+	long := textEvidence("big", strings.Repeat("x", maxEvidenceBytes+10))
+	if len(long.Data) > maxEvidenceBytes+len("\n[truncated]\n") {
+		t.Errorf("expected long output to be truncated, got %d bytes", len(long.Data))
+	}
+}
+
+func TestScanScopeSelectsScannersAndPaths(t *testing.T) {
+	var unset *ScanScope
+	if !unset.Enabled(ScannerSoftware) || unset.Enabled(ScannerAIML) {
+		t.Error("expected an unset scope to run the default scanners")
+	}
+
+	scope := &ScanScope{
+		Scanners: []string{ScannerSoftware, ScannerAIML},
+		Paths:    map[string][]string{ScannerAIML: {"/srv/models"}},
+	}
+	if !scope.Enabled(ScannerAIML) || scope.Enabled(ScannerNetwork) {
+		t.Errorf("unexpected scanners enabled by %v", scope.Scanners)
+	}
+	if paths := scope.PathsFor(ScannerAIML, []string{"/home"}); len(paths) != 1 || paths[0] != "/srv/models" {
+		t.Errorf("expected the scoped AI/ML paths, got %v", paths)
+	}
+	if paths := scope.PathsFor(ScannerContainer, []string{"/home"}); len(paths) != 1 || paths[0] != "/home" {
+		t.Errorf("expected the fallback paths, got %v", paths)
+	}
+}
+
+func TestRunCheckUnknownCheck(t *testing.T) {
+	cs := NewConfigScanner(setupTestConfig())
+	if _, _, found := cs.RunCheck("No Such Check"); found {
+		t.Error("expected an unknown check not to be found")
+	}
+}
+
+func TestRunChecksKeepsOrderAndEvidenceApart(t *testing.T) {
+	cs := NewConfigScanner(setupTestConfig())
+	if _, err := cs.command("echo", "1"); err != nil {
+		t.Skip("echo not available")
+	}
+
+	var checks []securityCheck
+	for i := 0; i < 6; i++ {
+		checks = append(checks, securityCheck{
+			name: fmt.Sprintf("Check %d", i),
+			check: func(cs *ConfigScanner) CheckResult {
+				time.Sleep(time.Duration(6-i) * 10 * time.Millisecond) // Later checks complete first
+				output, err := cs.command("echo", fmt.Sprint(i))
+				if err != nil {
+					return cs.checkError(err, "echo failed")
+				}
+				return cs.verdict(true, string(output), "", "")
+			},
+		})
+	}
+
+	outcomes := cs.runChecks(context.Background(), checks)
+	for i, outcome := range outcomes {
+		want := fmt.Sprint(i)
+		if outcome.result.Observed != want || outcome.result.Command != "echo "+want {
+			t.Errorf("check %d: expected its own result, got %+v", i, outcome.result)
+		}
+		if len(outcome.evidence) != 1 || !strings.HasPrefix(string(outcome.evidence[0].Data), "$ echo "+want+"\n") {
+			t.Errorf("check %d: expected only its own evidence, got %d pieces", i, len(outcome.evidence))
+		}
+	}
+}
+
+func TestTimedOutCommandLeavesCheckUnassessed(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.ConfigCommandTimeout = 50 * time.Millisecond
+	cs := NewConfigScanner(cfg)
+
+	// Like the ARD check, this one takes a failing command to mean the setting is off
+	check := securityCheck{
+		name: "Slow Check",
+		check: func(cs *ConfigScanner) CheckResult {
+			if _, err := cs.command("sleep", "5"); err != nil {
+				return cs.verdict(true, "", "not loaded", "Service is not running")
+			}
+			return cs.verdict(false, "", "not loaded", "Service is running")
+		},
+	}
+
+	start := time.Now()
+	outcome := cs.runCheck(context.Background(), check)
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("expected the command to be stopped at its timeout, took %v", time.Since(start))
+	}
+	if outcome.result.Passed || !outcome.result.Errored || !strings.Contains(outcome.result.Details, "timed out") {
+		t.Errorf("expected a timed-out check to be unable to determine its setting, got %+v", outcome.result)
+	}
+	if outcome.result.Command != "sleep 5" || len(outcome.evidence) != 1 {
+		t.Errorf("expected the timed-out command to be recorded, got %+v with %d evidence", outcome.result, len(outcome.evidence))
+	}
+}
+
+func TestScanDeadlineReportsUnfinishedChecks(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.ConfigCheckWorkers = 1
+	cs := NewConfigScanner(cfg)
+
+	stuck := func(*ConfigScanner) CheckResult {
+		time.Sleep(2 * time.Second)
+		return CheckResult{Passed: true}
+	}
+	checks := []securityCheck{
+		{name: "Quick", check: func(*ConfigScanner) CheckResult { return CheckResult{Passed: true, Confidence: 1} }},
+		{name: "Stuck", check: stuck},
+		{name: "Never Started", check: stuck},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	outcomes := cs.runChecks(ctx, checks)
+	if time.Since(start) > time.Second {
+		t.Fatalf("expected the pool not to be waited on past the deadline, took %v", time.Since(start))
+	}
+
+	if !outcomes[0].result.Passed || outcomes[0].result.Errored {
+		t.Errorf("expected the check finished before the deadline to keep its result, got %+v", outcomes[0].result)
+	}
+	for _, outcome := range outcomes[1:] {
+		if !outcome.result.Errored || !strings.Contains(outcome.result.Details, "deadline") {
+			t.Errorf("expected an unfinished check to be unable to determine its setting, got %+v", outcome.result)
+		}
+	}
+}
+
+func TestContainerScanResultRecordsToolVersions(t *testing.T) {
+	// Stand-ins that only answer version commands, the way the real tools print them
+	bin := t.TempDir()
+	tools := map[string]string{
+		"docker":  "24.0.7",
+		"kubectl": "Client Version: v1.29.2",
+		"trivy":   "Version: 0.48.3",
+	}
+	for tool, output := range tools {
+		script := "#!/bin/sh\ncase \"$*\" in *version*) echo '" + output + "' ;; esac\n"
+		if err := os.WriteFile(filepath.Join(bin, tool), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+	toolVersionCache.Lock()
+	for _, tool := range ContainerScanTools {
+		delete(toolVersionCache.checkedAt, tool)
+	}
+	toolVersionCache.Unlock()
+
+	result, err := NewContainerScanner(setupTestConfig()).ScanResult()
+	if err != nil {
+		t.Fatalf("ScanResult() failed: %v", err)
+	}
+	versions, ok := result.Metadata["tool_versions"].(map[string]string)
+	if !ok {
+		t.Fatalf("tool_versions = %#v, want a map of versions", result.Metadata["tool_versions"])
+	}
+	want := map[string]string{"docker": "24.0.7", "kubectl": "1.29.2", "trivy": "0.48.3"}
+	if fmt.Sprint(versions) != fmt.Sprint(want) {
+		t.Errorf("tool_versions = %v, want %v (tools that aren't installed are left out)", versions, want)
+	}
+}
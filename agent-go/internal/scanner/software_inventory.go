@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"zerotrace/agent/internal/models"
+)
+
+// SoftwareDelta is how the installed software changed since the previous
+// scan, reported in the software ScanResult's "software_delta" metadata
+type SoftwareDelta struct {
+	FullScan  bool   `json:"full_scan"` // Every package was enriched, as on the first scan after start
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	Updated   int    `json:"updated"` // Packages whose version changed
+	Unchanged int    `json:"unchanged"`
+	Hash      string `json:"inventory_hash"`
+}
+
+// softwareInventory is the installed software a scan found, and the
+// dependencies it was enriched into, keyed by package
+type softwareInventory struct {
+	hash         string
+	versions     map[string]string
+	dependencies map[string]models.Dependency
+}
+
+// packageKey identifies an installed package across scans: the same name can
+// be installed through several package managers, or in several places
+func packageKey(app models.InstalledApp) string {
+	return app.Type + "\x00" + app.Name + "\x00" + app.Path
+}
+
+// inventoryHash hashes the set of installed packages and their versions,
+// independent of the order they were listed in
+func inventoryHash(apps []models.InstalledApp) string {
+	entries := make([]string, len(apps))
+	for i, app := range apps {
+		entries[i] = packageKey(app) + "\x00" + app.Version
+	}
+	sort.Strings(entries)
+
+	hash := sha256.New()
+	for _, entry := range entries {
+		hash.Write([]byte(entry))
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// enrichIncrementally converts apps to dependencies, reusing the previous
+// inventory's dependency for every package installed at the same version.
+// With no previous inventory every package is enriched.
+func (s *SoftwareScanner) enrichIncrementally(previous *softwareInventory, apps []models.InstalledApp) ([]models.Dependency, *softwareInventory, SoftwareDelta) {
+	current := &softwareInventory{
+		hash:         inventoryHash(apps),
+		versions:     make(map[string]string, len(apps)),
+		dependencies: make(map[string]models.Dependency, len(apps)),
+	}
+	delta := SoftwareDelta{FullScan: previous == nil, Hash: current.hash}
+
+	dependencies := make([]models.Dependency, 0, len(apps))
+	for _, app := range apps {
+		key := packageKey(app)
+		if _, duplicate := current.versions[key]; duplicate {
+			continue
+		}
+		current.versions[key] = app.Version
+
+		dep, known := models.Dependency{}, false
+		if previous != nil {
+			if version, ok := previous.versions[key]; !ok {
+				delta.Added++
+			} else if version != app.Version {
+				delta.Updated++
+			} else {
+				dep, known = previous.dependencies[key]
+				delta.Unchanged++
+			}
+		}
+		if !known {
+			dep = s.convertAppsToDependencies([]models.InstalledApp{app})[0]
+		}
+		current.dependencies[key] = dep
+		dependencies = append(dependencies, dep)
+	}
+
+	if previous != nil {
+		for key := range previous.versions {
+			if _, ok := current.versions[key]; !ok {
+				delta.Removed++
+			}
+		}
+	}
+	return dependencies, current, delta
+}
@@ -0,0 +1,45 @@
+package scanner
+
+import (
+	"testing"
+
+	"zerotrace/agent/internal/models"
+)
+
+func TestEnrichIncrementallyReportsDelta(t *testing.T) {
+	s := NewSoftwareScanner(setupTestConfig())
+	first := []models.InstalledApp{
+		{Name: "curl", Version: "8.5.0", Type: "apt"},
+		{Name: "openssl", Version: "3.0.2", Type: "apt"},
+		{Name: "vim", Version: "9.0", Type: "apt"},
+	}
+	deps, inventory, delta := s.enrichIncrementally(nil, first)
+	if !delta.FullScan || len(deps) != 3 {
+		t.Fatalf("expected the first scan to enrich all 3 packages, got full=%v deps=%d", delta.FullScan, len(deps))
+	}
+
+	// The previous dependency is reused as is for an unchanged package
+	inventory.dependencies[packageKey(first[0])] = models.Dependency{Name: "curl", Version: "8.5.0", Vendor: "cached"}
+
+	second := []models.InstalledApp{
+		{Name: "openssl", Version: "3.0.13", Type: "apt"},
+		{Name: "curl", Version: "8.5.0", Type: "apt"},
+		{Name: "git", Version: "2.43.0", Type: "apt"},
+	}
+	deps, next, delta := s.enrichIncrementally(inventory, second)
+	if delta.FullScan || delta.Added != 1 || delta.Removed != 1 || delta.Updated != 1 || delta.Unchanged != 1 {
+		t.Fatalf("unexpected delta %+v", delta)
+	}
+	if len(deps) != 3 || deps[1].Vendor != "cached" || deps[0].Version != "3.0.13" {
+		t.Errorf("expected every installed package, reusing curl's dependency, got %+v", deps)
+	}
+	if next.hash == inventory.hash {
+		t.Error("expected the inventory hash to change with the installed packages")
+	}
+
+	// The hash does not depend on the order packages are listed in
+	reordered := []models.InstalledApp{second[2], second[0], second[1]}
+	if _, again, _ := s.enrichIncrementally(next, reordered); again.hash != next.hash {
+		t.Error("expected the same packages listed in another order to hash the same")
+	}
+}
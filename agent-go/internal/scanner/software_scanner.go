@@ -2,11 +2,13 @@ package scanner
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"zerotrace/agent/internal/config"
@@ -17,7 +19,13 @@ import (
 
 // SoftwareScanner handles scanning for installed software applications
 type SoftwareScanner struct {
-	config *config.Config
+	config  *config.Config
+	osquery *OsqueryScanner // Inventories the host in place of the native commands when available
+
+	// The previous scan's inventory, with IncrementalScan. Held in memory
+	// only, so the first scan after the agent starts enriches everything.
+	inventoryMu sync.Mutex
+	inventory   *softwareInventory
 }
 
 // NewSoftwareScanner creates a new software scanner instance
@@ -27,6 +35,12 @@ func NewSoftwareScanner(cfg *config.Config) *SoftwareScanner {
 	}
 }
 
+// SetOsquery makes subsequent scans inventory the host through osquery when
+// it is available, falling back to the native commands otherwise
+func (s *SoftwareScanner) SetOsquery(osquery *OsqueryScanner) {
+	s.osquery = osquery
+}
+
 // Scan performs a software vulnerability scan
 func (s *SoftwareScanner) Scan() (*models.ScanResult, error) {
 	startTime := time.Now()
@@ -47,13 +61,26 @@ func (s *SoftwareScanner) Scan() (*models.ScanResult, error) {
 	// Detect installed software based on OS
 	var installedApps []models.InstalledApp
 	var err error
+	capabilities := newCapabilityReport()
+
+	var osqueryInventory *OsqueryInventory
+	if s.osquery.Available() {
+		osqueryInventory, err = s.osquery.Inventory()
+		if err != nil {
+			log.Printf("osquery inventory failed, falling back to native scanning: %v", err)
+			osqueryInventory = nil
+		}
+	}
 
-	switch runtime.GOOS {
-	case "darwin":
-		installedApps, err = s.scanMacOS()
-	case "linux":
-		installedApps, err = s.scanLinux()
-	case "windows":
+	switch {
+	case osqueryInventory != nil:
+		capabilities.available("osquery")
+		installedApps = osqueryInventory.Apps
+	case runtime.GOOS == "darwin":
+		installedApps, err = s.scanMacOS(capabilities)
+	case runtime.GOOS == "linux":
+		installedApps, err = s.scanLinux(capabilities)
+	case runtime.GOOS == "windows":
 		installedApps, err = s.scanWindows()
 	default:
 		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
@@ -63,20 +90,37 @@ func (s *SoftwareScanner) Scan() (*models.ScanResult, error) {
 		return nil, err
 	}
 
-	// Convert installed apps to dependencies for processing
-	dependencies := s.convertAppsToDependencies(installedApps)
-	result.Dependencies = dependencies
+	// Convert installed apps to dependencies for processing, only those added
+	// or changed since the previous scan when scanning incrementally
+	if s.config.IncrementalScan {
+		s.inventoryMu.Lock()
+		var delta SoftwareDelta
+		result.Dependencies, s.inventory, delta = s.enrichIncrementally(s.inventory, installedApps)
+		s.inventoryMu.Unlock()
+		result.Metadata["software_delta"] = delta
+	} else {
+		result.Dependencies = s.convertAppsToDependencies(installedApps)
+	}
 	result.EndTime = time.Now()
 	result.Metadata["apps_scanned"] = len(installedApps)
 	result.Metadata["scan_duration"] = result.EndTime.Sub(startTime).String()
 	result.Metadata["os"] = runtime.GOOS
 	result.Metadata["arch"] = runtime.GOARCH
+	result.Metadata["capabilities"] = capabilities
+	result.Metadata["inventory_source"] = "native"
+	if osqueryInventory != nil {
+		result.Vulnerabilities = append(result.Vulnerabilities, osqueryInventory.Vulnerabilities...)
+		result.Metadata["inventory_source"] = "osquery"
+		result.Metadata["assets"] = osqueryInventory.Assets
+		result.Metadata["osquery_rows"] = osqueryInventory.Rows
+		result.Metadata["osquery_errors"] = osqueryInventory.Errors
+	}
 
 	return result, nil
 }
 
 // scanMacOS scans for installed applications on macOS
-func (s *SoftwareScanner) scanMacOS() ([]models.InstalledApp, error) {
+func (s *SoftwareScanner) scanMacOS(capabilities *CapabilityReport) ([]models.InstalledApp, error) {
 	var apps []models.InstalledApp
 
 	// Common application directories
@@ -112,39 +156,49 @@ func (s *SoftwareScanner) scanMacOS() ([]models.InstalledApp, error) {
 	}
 
 	// Also check Homebrew packages
-	brewApps, err := s.scanHomebrew()
-	if err == nil {
-		apps = append(apps, brewApps...)
+	if capabilities.require("homebrew_packages", "brew") {
+		brewApps, err := s.scanHomebrew()
+		if err == nil {
+			apps = append(apps, brewApps...)
+		}
 	}
 
 	return apps, nil
 }
 
 // scanLinux scans for installed applications on Linux
-func (s *SoftwareScanner) scanLinux() ([]models.InstalledApp, error) {
+func (s *SoftwareScanner) scanLinux(capabilities *CapabilityReport) ([]models.InstalledApp, error) {
 	var apps []models.InstalledApp
 
 	// Check package managers
 	packageManagers := []struct {
-		name string
-		cmd  string
-		args []string
+		name     string
+		cmd      string
+		args     []string
+		licensed bool // Each line ends with the package's license
 	}{
-		{"apt", "dpkg", []string{"-l"}},
-		{"yum", "rpm", []string{"-qa"}},
-		{"pacman", "pacman", []string{"-Q"}},
-		{"snap", "snap", []string{"list"}},
-		{"flatpak", "flatpak", []string{"list"}},
+		{"apt", "dpkg-query", []string{"-W", "-f=${Package} ${Version}\n"}, false},
+		{"yum", "rpm", []string{"-qa", "--qf", "%{NAME} %{VERSION}-%{RELEASE} %{LICENSE}\n"}, true},
+		{"pacman", "pacman", []string{"-Q"}, false},
+		{"snap", "snap", []string{"list"}, false},
+		{"flatpak", "flatpak", []string{"list"}, false},
 	}
 
+	// A host only has some of these, so only report a gap when there are none
+	found := false
 	for _, pm := range packageManagers {
-		if _, err := exec.LookPath(pm.cmd); err == nil {
-			pmApps, err := s.scanPackageManager(pm.name, pm.cmd, pm.args)
+		if toolInstalled(pm.cmd) {
+			found = true
+			capabilities.available(pm.name + "_packages")
+			pmApps, err := s.scanPackageManager(pm.name, pm.cmd, pm.args, pm.licensed)
 			if err == nil {
 				apps = append(apps, pmApps...)
 			}
 		}
 	}
+	if !found {
+		capabilities.skip("package_inventory", "dpkg/rpm/pacman/snap/flatpak")
+	}
 
 	return apps, nil
 }
@@ -250,7 +304,7 @@ func (s *SoftwareScanner) scanHomebrew() ([]models.InstalledApp, error) {
 }
 
 // scanPackageManager scans a specific package manager
-func (s *SoftwareScanner) scanPackageManager(pmName, cmd string, args []string) ([]models.InstalledApp, error) {
+func (s *SoftwareScanner) scanPackageManager(pmName, cmd string, args []string, licensed bool) ([]models.InstalledApp, error) {
 	var apps []models.InstalledApp
 
 	execCmd := exec.Command(cmd, args...)
@@ -274,6 +328,9 @@ func (s *SoftwareScanner) scanPackageManager(pmName, cmd string, args []string)
 				Version: parts[1],
 				Type:    pmName,
 			}
+			if licensed && len(parts) > 2 {
+				app.License = strings.Join(parts[2:], " ")
+			}
 			apps = append(apps, app)
 		}
 	}
@@ -295,6 +352,7 @@ func (s *SoftwareScanner) convertAppsToDependencies(apps []models.InstalledApp)
 			Size:        app.Size,
 			Vendor:      s.detectVendor(app.Name),
 			Description: fmt.Sprintf("Installed %s application", app.Type),
+			License:     packageLicense(app),
 		}
 		dependencies = append(dependencies, dep)
 	}
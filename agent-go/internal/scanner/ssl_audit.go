@@ -46,11 +46,11 @@ func (ns *NetworkScanner) ComprehensiveSSLAudit(host string, port int) (*SSLAudi
 				Description: fmt.Sprintf("Certificate expired on %s", cert.NotAfter.Format(time.RFC1123)),
 				Remediation: "Renew the SSL/TLS certificate immediately.",
 			})
-		} else if cert.NotAfter.Sub(time.Now()) < 30*24*time.Hour {
+		} else if cert.NotAfter.Sub(time.Now()) < time.Duration(ns.config.TLSCertExpiryDays)*24*time.Hour {
 			audit.Issues = append(audit.Issues, SSLIssue{
 				Severity:    "high",
 				Type:        "expiring-soon-certificate",
-				Description: fmt.Sprintf("Certificate will expire in under 30 days on %s", cert.NotAfter.Format(time.RFC1123)),
+				Description: fmt.Sprintf("Certificate will expire in under %d days on %s", ns.config.TLSCertExpiryDays, cert.NotAfter.Format(time.RFC1123)),
 				Remediation: "Renew the SSL/TLS certificate soon.",
 			})
 		}
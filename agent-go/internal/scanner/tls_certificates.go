@@ -0,0 +1,179 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tlsDialTimeout bounds the handshake used to fetch a service's certificate
+const tlsDialTimeout = 5 * time.Second
+
+// tlsPorts are ports that speak TLS from the first byte, inspected even when
+// service detection did not identify the service
+var tlsPorts = map[int]bool{
+	443: true, 465: true, 636: true, 853: true, 989: true, 990: true, 992: true,
+	993: true, 995: true, 3269: true, 3389: true, 5061: true, 5986: true,
+	6443: true, 8443: true, 9443: true,
+}
+
+// weakSignatureAlgorithms are certificate signature algorithms with practical collision attacks
+var weakSignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.MD2WithRSA:    true,
+	x509.MD5WithRSA:    true,
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+// isTLSService reports whether a discovered port is worth a TLS handshake
+func isTLSService(port int, service string) bool {
+	service = strings.ToLower(service)
+	return tlsPorts[port] || strings.Contains(service, "ssl") || strings.Contains(service, "tls") || strings.Contains(service, "https")
+}
+
+// inspectTLSCertificates fetches the certificate of each TLS service on a host
+// and reports its hygiene problems. hostname is the name the host was
+// discovered as, if any, which its certificates are expected to match.
+func (ns *NetworkScanner) inspectTLSCertificates(host, hostname string, ports []int, services map[int]string) []NetworkFinding {
+	var findings []NetworkFinding
+	for _, port := range ports {
+		if !isTLSService(port, services[port]) {
+			continue
+		}
+
+		serverName := hostname
+		if serverName == "" {
+			serverName = host
+		}
+		dialer := &net.Dialer{Timeout: tlsDialTimeout}
+		conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)), &tls.Config{
+			InsecureSkipVerify: true, // The certificate is inspected rather than trusted
+			ServerName:         serverName,
+		})
+		if err != nil {
+			continue // Not TLS after all, or unreachable
+		}
+		chain := conn.ConnectionState().PeerCertificates
+		conn.Close()
+		if len(chain) == 0 {
+			continue
+		}
+
+		findings = append(findings, certificateFindings(host, port, hostname, chain[0], ns.config.TLSCertExpiryDays, time.Now())...)
+	}
+	return findings
+}
+
+// certificateFindings checks a service's leaf certificate for being
+// self-signed, expired or close to expiry, weakly signed, or issued for
+// another name than hostname, which is skipped when empty
+func certificateFindings(host string, port int, hostname string, cert *x509.Certificate, expiryDays int, now time.Time) []NetworkFinding {
+	var findings []NetworkFinding
+	add := func(check, severity, description, remediation string) {
+		metadata := certificateDetails(cert, now)
+		metadata["check"] = check
+		findings = append(findings, NetworkFinding{
+			ID:           uuid.New(),
+			FindingType:  "tls",
+			Severity:     severity,
+			Host:         host,
+			Port:         port,
+			Protocol:     "tcp",
+			ServiceName:  "tls",
+			Description:  description,
+			Remediation:  remediation,
+			DiscoveredAt: now,
+			Status:       "open",
+			Metadata:     metadata,
+		})
+	}
+	subject := cert.Subject.CommonName
+	if subject == "" {
+		subject = cert.Subject.String()
+	}
+
+	if isSelfSigned(cert) {
+		add("self_signed", "medium",
+			fmt.Sprintf("TLS certificate for %s on %s:%d is self-signed", subject, host, port),
+			"Replace the certificate with one issued by a trusted or internal certificate authority")
+	}
+
+	switch remaining := cert.NotAfter.Sub(now); {
+	case remaining <= 0:
+		add("expired", "high",
+			fmt.Sprintf("TLS certificate for %s on %s:%d expired on %s", subject, host, port, cert.NotAfter.Format(time.RFC1123)),
+			"Renew the certificate immediately")
+	case remaining < time.Duration(expiryDays)*24*time.Hour:
+		add("expiring", "medium",
+			fmt.Sprintf("TLS certificate for %s on %s:%d expires in %d days, on %s", subject, host, port, int(remaining.Hours()/24), cert.NotAfter.Format(time.RFC1123)),
+			"Renew the certificate before it expires")
+	}
+
+	if weakSignatureAlgorithms[cert.SignatureAlgorithm] {
+		add("weak_signature", "high",
+			fmt.Sprintf("TLS certificate for %s on %s:%d is signed with %s", subject, host, port, cert.SignatureAlgorithm),
+			"Reissue the certificate with a SHA-256 or stronger signature")
+	}
+
+	if hostname != "" && cert.VerifyHostname(hostname) != nil {
+		add("hostname_mismatch", "medium",
+			fmt.Sprintf("TLS certificate on %s:%d is issued for %s, not %s", host, port, certificateNames(cert), hostname),
+			"Reissue the certificate with the service's hostname in its subject alternative names")
+	}
+
+	return findings
+}
+
+// isSelfSigned reports whether a certificate is signed by its own key. The
+// signature is checked directly, as self-signed leaf certificates are rarely
+// marked as CAs, and one too weak to verify still counts as self-signed.
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	if len(cert.AuthorityKeyId) > 0 && len(cert.SubjectKeyId) > 0 {
+		return bytes.Equal(cert.AuthorityKeyId, cert.SubjectKeyId)
+	}
+	var insecure x509.InsecureAlgorithmError
+	err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature)
+	return err == nil || errors.As(err, &insecure)
+}
+
+// certificateDetails describes a certificate for a finding's metadata
+func certificateDetails(cert *x509.Certificate, now time.Time) map[string]interface{} {
+	fingerprint := sha256.Sum256(cert.Raw)
+	return map[string]interface{}{
+		"subject":             cert.Subject.String(),
+		"issuer":              cert.Issuer.String(),
+		"serial_number":       cert.SerialNumber.String(),
+		"not_before":          cert.NotBefore,
+		"not_after":           cert.NotAfter,
+		"days_remaining":      int(cert.NotAfter.Sub(now).Hours() / 24),
+		"signature_algorithm": cert.SignatureAlgorithm.String(),
+		"sans":                cert.DNSNames,
+		"fingerprint_sha256":  hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+// certificateNames lists the names a certificate is valid for
+func certificateNames(cert *x509.Certificate) string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 {
+		names = append(names, cert.Subject.CommonName)
+	}
+	return strings.Join(names, ", ")
+}
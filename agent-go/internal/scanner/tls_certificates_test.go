@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCertificateFindings(t *testing.T) {
+	now := time.Now()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "printer.local"},
+		DNSNames:     []string{"printer.local"},
+		NotBefore:    now.Add(-24 * time.Hour),
+		NotAfter:     now.Add(10 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := func(findings []NetworkFinding) map[string]string {
+		found := make(map[string]string)
+		for _, f := range findings {
+			found[f.Metadata["check"].(string)] = f.Severity
+		}
+		return found
+	}
+
+	found := checks(certificateFindings("10.0.0.5", 443, "printer.local", cert, 30, now))
+	if len(found) != 2 || found["self_signed"] == "" || found["expiring"] == "" {
+		t.Errorf("checks = %v, want self_signed and expiring", found)
+	}
+
+	// A shorter threshold, another hostname and no hostname at all
+	found = checks(certificateFindings("10.0.0.5", 443, "nas.local", cert, 7, now))
+	if _, ok := found["expiring"]; ok {
+		t.Errorf("certificate expiring in 10 days flagged with a 7 day threshold")
+	}
+	if found["hostname_mismatch"] != "medium" {
+		t.Errorf("checks = %v, want hostname_mismatch", found)
+	}
+	if _, ok := checks(certificateFindings("10.0.0.5", 443, "", cert, 7, now))["hostname_mismatch"]; ok {
+		t.Errorf("hostname mismatch reported without a hostname")
+	}
+
+	found = checks(certificateFindings("10.0.0.5", 443, "", cert, 30, now.Add(11*24*time.Hour)))
+	if found["expired"] != "high" {
+		t.Errorf("checks = %v, want expired", found)
+	}
+
+	cert.SignatureAlgorithm = x509.SHA1WithRSA
+	findings := certificateFindings("10.0.0.5", 443, "", cert, 7, now)
+	if checks(findings)["weak_signature"] != "high" {
+		t.Errorf("checks = %v, want weak_signature", checks(findings))
+	}
+	if findings[0].Metadata["fingerprint_sha256"] == "" || findings[0].FindingType != "tls" {
+		t.Errorf("finding missing certificate details: %+v", findings[0])
+	}
+}
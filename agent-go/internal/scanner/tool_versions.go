@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// toolVersionCommands maps each external tool to the arguments that print its version
+var toolVersionCommands = map[string][]string{
+	"nmap":    {"--version"},
+	"nuclei":  {"-version"},
+	"docker":  {"version", "--format", "{{.Client.Version}}"},
+	"podman":  {"--version"},
+	"ctr":     {"--version"},
+	"kubectl": {"version", "--client"},
+	"trivy":   {"--version"},
+	"syft":    {"version"},
+	"grype":   {"version"},
+}
+
+// toolLibraries maps tools that are linked into the agent to their Go module path
+var toolLibraries = map[string]string{
+	"naabu":   "github.com/projectdiscovery/naabu/v2",
+	"nmap-go": "github.com/Ullaakut/nmap/v2",
+}
+
+// NetworkScanTools are the tools whose versions affect network scan results
+var NetworkScanTools = []string{"nmap", "nuclei", "naabu", "nmap-go"}
+
+// ContainerScanTools are the tools whose versions affect container scan results
+var ContainerScanTools = []string{"docker", "podman", "ctr", "kubectl", "trivy"}
+
+var versionPattern = regexp.MustCompile(`v?(\d+\.\d+(?:\.\d+)?(?:[-+][0-9A-Za-z.\-]+)?)`)
+
+// toolVersionCacheTTL bounds how long a detected version is reused; tools are
+// rarely upgraded while the agent runs, and exec'ing them every scan is wasteful
+const toolVersionCacheTTL = time.Hour
+
+var toolVersionCache = struct {
+	sync.Mutex
+	versions  map[string]string
+	checkedAt map[string]time.Time
+}{
+	versions:  make(map[string]string),
+	checkedAt: make(map[string]time.Time),
+}
+
+// DetectToolVersions returns the installed version of each requested tool.
+// Tools that are not installed are omitted.
+func DetectToolVersions(tools ...string) map[string]string {
+	versions := make(map[string]string, len(tools))
+	for _, tool := range tools {
+		if version := toolVersion(tool); version != "" {
+			versions[tool] = version
+		}
+	}
+	return versions
+}
+
+// AllToolVersions returns the versions of every tool the agent knows how to use
+func AllToolVersions() map[string]string {
+	tools := make([]string, 0, len(toolVersionCommands)+len(toolLibraries))
+	for tool := range toolVersionCommands {
+		tools = append(tools, tool)
+	}
+	for tool := range toolLibraries {
+		tools = append(tools, tool)
+	}
+	return DetectToolVersions(tools...)
+}
+
+// toolVersion returns a tool's version from the cache, detecting it if stale
+func toolVersion(tool string) string {
+	toolVersionCache.Lock()
+	if checked, ok := toolVersionCache.checkedAt[tool]; ok && time.Since(checked) < toolVersionCacheTTL {
+		version := toolVersionCache.versions[tool]
+		toolVersionCache.Unlock()
+		return version
+	}
+	toolVersionCache.Unlock()
+
+	var version string
+	if module, ok := toolLibraries[tool]; ok {
+		version = linkedModuleVersion(module)
+	} else {
+		version = execToolVersion(tool)
+	}
+
+	toolVersionCache.Lock()
+	toolVersionCache.versions[tool] = version
+	toolVersionCache.checkedAt[tool] = time.Now()
+	toolVersionCache.Unlock()
+
+	return version
+}
+
+// execToolVersion runs a tool's version command and extracts the version number
+func execToolVersion(tool string) string {
+	args, ok := toolVersionCommands[tool]
+	if !ok {
+		return ""
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, tool, args...).CombinedOutput()
+	if err != nil && len(output) == 0 {
+		return ""
+	}
+	return parseToolVersion(string(output))
+}
+
+// parseToolVersion extracts the first version number from a tool's output
+func parseToolVersion(output string) string {
+	match := versionPattern.FindStringSubmatch(strings.TrimSpace(output))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// linkedModuleVersion returns the version of a Go module compiled into the agent
+func linkedModuleVersion(module string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == module {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			return strings.TrimPrefix(dep.Version, "v")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,417 @@
+package scanner
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// protocolProbeTimeout bounds each handshake probe, connect to last byte
+	protocolProbeTimeout = 5 * time.Second
+	// maxCipherProbes bounds the weak cipher suites enumerated per service
+	maxCipherProbes = 8
+)
+
+// Protocol versions as they appear on the wire
+const (
+	versionSSL30 uint16 = 0x0300
+	versionTLS10 uint16 = 0x0301
+	versionTLS11 uint16 = 0x0302
+	versionTLS12 uint16 = 0x0303
+)
+
+// tlsProtocolVersions maps WEAK_TLS_PROTOCOLS names to wire versions
+var tlsProtocolVersions = map[string]uint16{
+	"SSLv3":  versionSSL30,
+	"TLS1.0": versionTLS10,
+	"TLS1.1": versionTLS11,
+	"TLS1.2": versionTLS12,
+}
+
+// cryptoCompliance lists the requirements a weak protocol or cipher on a
+// service breaks, recorded on each finding
+var cryptoCompliance = []string{
+	"PCI-DSS 4.0 Req 4.2.1", // Strong cryptography for cardholder data in transit
+	"PCI-DSS 4.0 Req 2.2.7", // Non-console administrative access encrypted
+	"NIST SP 800-52r2",
+	"HIPAA 164.312(e)(1)",
+}
+
+// tlsCipherSuites names the cipher suites offered by the probes: the
+// suites a weak cipher policy is matched against, and common strong ones so
+// that a protocol probe is answered by any reasonably configured server
+var tlsCipherSuites = map[uint16]string{
+	0x0001: "TLS_RSA_WITH_NULL_MD5",
+	0x0002: "TLS_RSA_WITH_NULL_SHA",
+	0x0003: "TLS_RSA_EXPORT_WITH_RC4_40_MD5",
+	0x0004: "TLS_RSA_WITH_RC4_128_MD5",
+	0x0005: "TLS_RSA_WITH_RC4_128_SHA",
+	0x0006: "TLS_RSA_EXPORT_WITH_RC2_CBC_40_MD5",
+	0x0008: "TLS_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0009: "TLS_RSA_WITH_DES_CBC_SHA",
+	0x000A: "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0014: "TLS_DHE_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0015: "TLS_DHE_RSA_WITH_DES_CBC_SHA",
+	0x0016: "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0018: "TLS_DH_anon_WITH_RC4_128_MD5",
+	0x001A: "TLS_DH_anon_WITH_DES_CBC_SHA",
+	0x001B: "TLS_DH_anon_WITH_3DES_EDE_CBC_SHA",
+	0x002F: "TLS_RSA_WITH_AES_128_CBC_SHA",
+	0x0033: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+	0x0034: "TLS_DH_anon_WITH_AES_128_CBC_SHA",
+	0x0035: "TLS_RSA_WITH_AES_256_CBC_SHA",
+	0x0039: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+	0x003A: "TLS_DH_anon_WITH_AES_256_CBC_SHA",
+	0x003B: "TLS_RSA_WITH_NULL_SHA256",
+	0x009C: "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	0x009D: "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	0x009E: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+	0xC006: "TLS_ECDHE_ECDSA_WITH_NULL_SHA",
+	0xC007: "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	0xC009: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	0xC00A: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	0xC010: "TLS_ECDHE_RSA_WITH_NULL_SHA",
+	0xC011: "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	0xC012: "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0xC013: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	0xC014: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	0xC016: "TLS_ECDH_anon_WITH_RC4_128_SHA",
+	0xC018: "TLS_ECDH_anon_WITH_AES_128_CBC_SHA",
+	0xC02B: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	0xC02C: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	0xC02F: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	0xC030: "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	0xCCA8: "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	0xCCA9: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+}
+
+// errNoServerHello is returned by a handshake probe the server declined
+var errNoServerHello = errors.New("server did not accept the handshake")
+
+// serverHello is what a handshake probe learns from the server's reply
+type serverHello struct {
+	version     uint16
+	cipherSuite uint16
+}
+
+// weakTLSSuites returns the probe cipher suites the policy calls weak: those
+// with a name component, such as RC4 in TLS_RSA_WITH_RC4_128_SHA, in the policy
+func weakTLSSuites(policy []string) []uint16 {
+	var suites []uint16
+	for id, name := range tlsCipherSuites {
+		for _, part := range strings.Split(strings.TrimPrefix(name, "TLS_"), "_") {
+			if containsFold(policy, part) {
+				suites = append(suites, id)
+				break
+			}
+		}
+	}
+	return suites
+}
+
+// probeWeakTLS reports the weak protocol versions and cipher suites a TLS
+// service accepts. Each version is probed with a ClientHello offering only
+// that version. Weak suites are enumerated by offering only them, removing
+// each suite the server picks, so a server that merely prefers strong suites
+// is not reported while one that would fall back to a weak suite is.
+func (ns *NetworkScanner) probeWeakTLS(host string, port int, serverName string) []NetworkFinding {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	var findings []NetworkFinding
+
+	allSuites := make([]uint16, 0, len(tlsCipherSuites))
+	for id := range tlsCipherSuites {
+		allSuites = append(allSuites, id)
+	}
+	for _, protocol := range ns.config.WeakTLSProtocols {
+		version, ok := tlsProtocolVersions[protocol]
+		if !ok {
+			continue
+		}
+		hello, err := probeTLSHandshake(address, serverName, version, allSuites)
+		if err != nil || hello.version != version {
+			continue
+		}
+		findings = append(findings, weakCryptoFinding(host, port, "tls", "weak_protocol", protocol, "high",
+			fmt.Sprintf("TLS service on %s:%d accepts %s", host, port, protocol),
+			fmt.Sprintf("Disable %s on the service and allow only TLS 1.2 and TLS 1.3", protocol)))
+	}
+
+	remaining := weakTLSSuites(ns.config.WeakTLSCiphers)
+	for probes := 0; len(remaining) > 0 && probes < maxCipherProbes; probes++ {
+		hello, err := probeTLSHandshake(address, serverName, versionTLS12, remaining)
+		if err != nil {
+			break
+		}
+		name, offered := tlsCipherSuites[hello.cipherSuite]
+		if !offered {
+			break // Not a suite we offered; the server ignored the offer
+		}
+		findings = append(findings, weakCryptoFinding(host, port, "tls", "weak_cipher", name, "high",
+			fmt.Sprintf("TLS service on %s:%d accepts the weak cipher suite %s", host, port, name),
+			"Remove NULL, export, anonymous, RC4, DES and 3DES cipher suites from the service's configuration"))
+
+		for i, id := range remaining {
+			if id == hello.cipherSuite {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// probeTLSHandshake sends a ClientHello offering one protocol version and the
+// given cipher suites, and reads the server's ServerHello without completing
+// the handshake. Go's TLS stack cannot speak SSLv3 or offer most weak suites,
+// so the hello is built by hand.
+func probeTLSHandshake(address, serverName string, version uint16, suites []uint16) (*serverHello, error) {
+	conn, err := net.DialTimeout("tcp", address, protocolProbeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(protocolProbeTimeout))
+
+	if _, err := conn.Write(clientHello(version, serverName, suites)); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[3:5]))
+	if header[0] != 0x16 || length < 4 || length > 1<<14+2048 {
+		return nil, errNoServerHello // An alert, or not TLS at all
+	}
+	record := make([]byte, length)
+	if _, err := io.ReadFull(conn, record); err != nil {
+		return nil, err
+	}
+	return parseServerHello(record)
+}
+
+// parseServerHello reads the version and cipher suite from a handshake
+// record starting with a ServerHello
+func parseServerHello(record []byte) (*serverHello, error) {
+	// type(1) length(3) version(2) random(32) session_id_length(1)
+	if len(record) < 39 || record[0] != 0x02 {
+		return nil, errNoServerHello
+	}
+	hello := &serverHello{version: binary.BigEndian.Uint16(record[4:6])}
+	offset := 39 + int(record[38])
+	if len(record) < offset+2 {
+		return nil, errNoServerHello
+	}
+	hello.cipherSuite = binary.BigEndian.Uint16(record[offset : offset+2])
+	return hello, nil
+}
+
+// clientHello builds a TLS record holding a ClientHello. SSLv3 hellos carry
+// no extensions; later versions send SNI and what ECDHE suites need.
+func clientHello(version uint16, serverName string, suites []uint16) []byte {
+	body := binary.BigEndian.AppendUint16(nil, version)
+	random := make([]byte, 32)
+	rand.Read(random)
+	body = append(body, random...)
+	body = append(body, 0) // No session to resume
+
+	body = binary.BigEndian.AppendUint16(body, uint16(2*len(suites)))
+	for _, suite := range suites {
+		body = binary.BigEndian.AppendUint16(body, suite)
+	}
+	body = append(body, 1, 0) // Null compression only
+
+	if version > versionSSL30 {
+		var extensions []byte
+		if serverName != "" && net.ParseIP(serverName) == nil {
+			name := []byte(serverName)
+			sni := binary.BigEndian.AppendUint16(nil, uint16(len(name)+3))
+			sni = append(sni, 0) // host_name
+			sni = binary.BigEndian.AppendUint16(sni, uint16(len(name)))
+			extensions = appendExtension(extensions, 0x0000, append(sni, name...))
+		}
+		// supported_groups: x25519, secp256r1, secp384r1
+		extensions = appendExtension(extensions, 0x000A, []byte{0, 6, 0x00, 0x1D, 0x00, 0x17, 0x00, 0x18})
+		// ec_point_formats: uncompressed
+		extensions = appendExtension(extensions, 0x000B, []byte{1, 0})
+		if version >= versionTLS12 {
+			// signature_algorithms: RSA-PSS, RSA and ECDSA with SHA-256/384, and SHA-1 for old servers
+			extensions = appendExtension(extensions, 0x000D, []byte{0, 14,
+				0x08, 0x04, 0x08, 0x05, 0x04, 0x01, 0x05, 0x01, 0x04, 0x03, 0x05, 0x03, 0x02, 0x01})
+		}
+		body = binary.BigEndian.AppendUint16(body, uint16(len(extensions)))
+		body = append(body, extensions...)
+	}
+
+	handshake := []byte{0x01, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+	handshake = append(handshake, body...)
+
+	// The record layer says TLS 1.0 at most, which old servers expect
+	recordVersion := version
+	if recordVersion > versionTLS10 {
+		recordVersion = versionTLS10
+	}
+	record := []byte{0x16}
+	record = binary.BigEndian.AppendUint16(record, recordVersion)
+	record = binary.BigEndian.AppendUint16(record, uint16(len(handshake)))
+	return append(record, handshake...)
+}
+
+func appendExtension(extensions []byte, kind uint16, data []byte) []byte {
+	extensions = binary.BigEndian.AppendUint16(extensions, kind)
+	extensions = binary.BigEndian.AppendUint16(extensions, uint16(len(data)))
+	return append(extensions, data...)
+}
+
+// probeWeakSSH reports an SSH service that speaks protocol version 1 or
+// offers algorithms the policy calls weak. It reads the server's version
+// banner and key exchange offer, which the server sends unprompted, and
+// disconnects before any key exchange.
+func (ns *NetworkScanner) probeWeakSSH(host string, port int) []NetworkFinding {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), protocolProbeTimeout)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(protocolProbeTimeout))
+
+	reader := bufio.NewReader(conn)
+	banner, err := readSSHBanner(reader)
+	if err != nil {
+		return nil
+	}
+
+	var findings []NetworkFinding
+	if !strings.HasPrefix(banner, "SSH-2.0-") {
+		// SSH-1.99 also accepts version 1 clients
+		findings = append(findings, weakCryptoFinding(host, port, "ssh", "weak_protocol", "SSHv1", "high",
+			fmt.Sprintf("SSH service on %s:%d accepts protocol version 1 (%s)", host, port, banner),
+			"Disable SSH protocol version 1 on the service"))
+		if !strings.HasPrefix(banner, "SSH-1.99-") {
+			return findings
+		}
+	}
+
+	if _, err := conn.Write([]byte("SSH-2.0-ZeroTrace_Scanner\r\n")); err != nil {
+		return findings
+	}
+	offered, err := readSSHKexInit(reader)
+	if err != nil {
+		return findings
+	}
+	for _, algorithm := range offered {
+		if containsFold(ns.config.WeakSSHAlgorithms, algorithm) {
+			findings = append(findings, weakCryptoFinding(host, port, "ssh", "weak_cipher", algorithm, "medium",
+				fmt.Sprintf("SSH service on %s:%d offers the weak algorithm %s", host, port, algorithm),
+				fmt.Sprintf("Remove %s from the service's KexAlgorithms, HostKeyAlgorithms, Ciphers or MACs", algorithm)))
+		}
+	}
+	return findings
+}
+
+// readSSHBanner reads the server's identification line, skipping any
+// preamble lines the protocol allows before it
+func readSSHBanner(reader *bufio.Reader) (string, error) {
+	for range 20 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line = strings.TrimRight(line, "\r\n"); strings.HasPrefix(line, "SSH-") {
+			return line, nil
+		}
+	}
+	return "", errors.New("no SSH identification line")
+}
+
+// readSSHKexInit reads the server's SSH_MSG_KEXINIT and returns every
+// algorithm it offers: key exchange, host key, ciphers and MACs
+func readSSHKexInit(reader *bufio.Reader) ([]string, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 2 || length > 35000 {
+		return nil, fmt.Errorf("invalid SSH packet length %d", length)
+	}
+	packet := make([]byte, length-1)
+	if _, err := io.ReadFull(reader, packet); err != nil {
+		return nil, err
+	}
+	payload := packet[:len(packet)-int(header[4])]
+
+	const msgKexInit = 20
+	if len(payload) < 17 || payload[0] != msgKexInit {
+		return nil, errors.New("server did not send a key exchange offer")
+	}
+	rest := payload[17:] // Message type and cookie
+
+	var algorithms []string
+	// Key exchange and host key name-lists, then ciphers and MACs in each direction
+	for range 6 {
+		if len(rest) < 4 {
+			return nil, errors.New("truncated key exchange offer")
+		}
+		n := binary.BigEndian.Uint32(rest[:4])
+		if uint32(len(rest)-4) < n {
+			return nil, errors.New("truncated key exchange offer")
+		}
+		for _, name := range strings.Split(string(rest[4:4+n]), ",") {
+			if name != "" && !containsFold(algorithms, name) {
+				algorithms = append(algorithms, name)
+			}
+		}
+		rest = rest[4+n:]
+	}
+	return algorithms, nil
+}
+
+// weakCryptoFinding builds a weak protocol or cipher finding for a service
+func weakCryptoFinding(host string, port int, service, check, name, severity, description, remediation string) NetworkFinding {
+	return NetworkFinding{
+		ID:           uuid.New(),
+		FindingType:  "weak_crypto",
+		Severity:     severity,
+		Host:         host,
+		Port:         port,
+		Protocol:     "tcp",
+		ServiceName:  service,
+		Description:  description,
+		Remediation:  remediation,
+		DiscoveredAt: time.Now(),
+		Status:       "open",
+		Metadata: map[string]interface{}{
+			"check":      check,
+			"name":       name,
+			"compliance": cryptoCompliance,
+		},
+	}
+}
+
+// inspectWeakCrypto probes the TLS and SSH services on a host for weak
+// protocols and ciphers
+func (ns *NetworkScanner) inspectWeakCrypto(host, hostname string, ports []int, services map[int]string) []NetworkFinding {
+	var findings []NetworkFinding
+	for _, port := range ports {
+		service := strings.ToLower(services[port])
+		switch {
+		case isTLSService(port, service):
+			findings = append(findings, ns.probeWeakTLS(host, port, hostname)...)
+		case port == 22 || strings.Contains(service, "ssh"):
+			findings = append(findings, ns.probeWeakSSH(host, port)...)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,132 @@
+package scanner
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTLSServer serves TLS with the given config on a local port until the test ends
+func startTLSServer(t *testing.T, config *tls.Config) int {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "legacy.local"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Certificates = []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestProbeWeakTLS(t *testing.T) {
+	port := startTLSServer(t, &tls.Config{
+		MinVersion: tls.VersionTLS10,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+		},
+	})
+	ns := &NetworkScanner{config: setupTestConfig()}
+
+	found := make(map[string]bool)
+	for _, f := range ns.probeWeakTLS("127.0.0.1", port, "") {
+		found[f.Metadata["check"].(string)+" "+f.Metadata["name"].(string)] = true
+	}
+	for _, want := range []string{"weak_protocol TLS1.0", "weak_protocol TLS1.1", "weak_cipher TLS_RSA_WITH_3DES_EDE_CBC_SHA"} {
+		if !found[want] {
+			t.Errorf("missing %s in %v", want, found)
+		}
+	}
+	if found["weak_protocol SSLv3"] || len(found) != 3 {
+		t.Errorf("unexpected findings: %v", found)
+	}
+
+	// A service with only strong suites and versions is not reported
+	port = startTLSServer(t, &tls.Config{MinVersion: tls.VersionTLS12})
+	if findings := ns.probeWeakTLS("127.0.0.1", port, ""); len(findings) != 0 {
+		t.Errorf("strong service reported: %+v", findings)
+	}
+}
+
+func TestProbeWeakSSH(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		payload := append([]byte{20}, make([]byte, 16)...)
+		for _, list := range []string{
+			"curve25519-sha256,diffie-hellman-group1-sha1", "ssh-ed25519",
+			"aes128-ctr,3des-cbc", "aes128-ctr,3des-cbc",
+			"hmac-sha2-256", "hmac-sha2-256",
+			"none", "none", "", "",
+		} {
+			payload = binary.BigEndian.AppendUint32(payload, uint32(len(list)))
+			payload = append(payload, list...)
+		}
+		payload = append(payload, 0, 0, 0, 0, 0)
+		padding := 8 - (len(payload)+5)%8 + 8
+		packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+padding+1))
+		packet = append(packet, byte(padding))
+		packet = append(packet, payload...)
+		packet = append(packet, make([]byte, padding)...)
+
+		conn.Write([]byte("SSH-2.0-OpenSSH_5.3\r\n"))
+		conn.Write(packet)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		conn.Read(make([]byte, 64))
+	}()
+
+	ns := &NetworkScanner{config: setupTestConfig()}
+	var names []string
+	for _, f := range ns.probeWeakSSH("127.0.0.1", listener.Addr().(*net.TCPAddr).Port) {
+		names = append(names, f.Metadata["name"].(string))
+	}
+	if strings.Join(names, ",") != "diffie-hellman-group1-sha1,3des-cbc" {
+		t.Errorf("weak algorithms = %v, want diffie-hellman-group1-sha1 and 3des-cbc", names)
+	}
+}
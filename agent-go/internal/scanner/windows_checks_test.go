@@ -0,0 +1,51 @@
+package scanner
+
+import "testing"
+
+func TestParseJSONListAcceptsSingleObject(t *testing.T) {
+	single, err := parseJSONList[firewallProfile]([]byte(`{"Name": "Domain", "Enabled": 1}`))
+	if err != nil || len(single) != 1 || !single[0].enabled() {
+		t.Fatalf("single profile = %+v, %v; want one enabled profile", single, err)
+	}
+
+	profiles, err := parseJSONList[firewallProfile]([]byte(`[{"Name": "Domain", "Enabled": true}, {"Name": "Public", "Enabled": false}]`))
+	if err != nil || len(profiles) != 2 {
+		t.Fatalf("profiles = %+v, %v; want two", profiles, err)
+	}
+	if !profiles[0].enabled() || profiles[1].enabled() {
+		t.Errorf("profiles enabled = %t, %t; want true, false", profiles[0].enabled(), profiles[1].enabled())
+	}
+}
+
+func TestAntivirusProductState(t *testing.T) {
+	cases := []struct {
+		product    antivirusProduct
+		enabled    bool
+		thirdParty bool
+	}{
+		{antivirusProduct{"Windows Defender", 397568}, true, false},  // 0x061100
+		{antivirusProduct{"Windows Defender", 393472}, false, false}, // 0x060100
+		{antivirusProduct{"Sophos Anti-Virus", 266240}, true, true},  // 0x041000
+		{antivirusProduct{"Sophos Anti-Virus", 262144}, false, true}, // 0x040000
+	}
+	for _, tc := range cases {
+		if got := tc.product.enabled(); got != tc.enabled {
+			t.Errorf("%s %#x enabled() = %t, want %t", tc.product.DisplayName, tc.product.ProductState, got, tc.enabled)
+		}
+		if got := tc.product.thirdParty(); got != tc.thirdParty {
+			t.Errorf("%s thirdParty() = %t, want %t", tc.product.DisplayName, got, tc.thirdParty)
+		}
+	}
+}
+
+func TestDefenderPassiveModeIsNotProtecting(t *testing.T) {
+	active := defenderStatus{AMServiceEnabled: true, AntivirusEnabled: true, RealTimeProtectionEnabled: true, AMRunningMode: "Normal"}
+	if !active.protecting() {
+		t.Error("Defender in normal mode with real-time protection is not protecting")
+	}
+	passive := active
+	passive.AMRunningMode = "Passive Mode"
+	if passive.protecting() {
+		t.Error("Defender in passive mode counts as protecting")
+	}
+}
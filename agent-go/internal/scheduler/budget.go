@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"time"
+
+	"zerotrace/agent/internal/config"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// Weight classifies how much load a scanner puts on the host
+type Weight int
+
+const (
+	// Light scanners are cheap (system info, heartbeats) and only count
+	// against the overall concurrency limit
+	Light Weight = iota
+	// Heavy scanners walk large parts of the filesystem or run external
+	// tools; they also share a smaller heavy-scanner limit and are held
+	// back while the host is busy
+	Heavy
+)
+
+// Budget is the agent-wide resource budget that every scanner goroutine
+// runs through. It bounds how many scanners run at once, how many of those
+// may be heavy, and delays heavy scanners while host CPU is above a
+// threshold so the agent does not pile onto an already busy machine.
+type Budget struct {
+	slots        chan struct{}
+	heavySlots   chan struct{}
+	cpuThreshold float64
+	maxWait      time.Duration
+	pollInterval time.Duration
+	cpuPercent   func() (float64, error)
+}
+
+// NewBudget creates a budget from the agent configuration and applies the
+// process-wide CPU share (GOMAXPROCS) if one is configured
+func NewBudget(cfg *config.Config) *Budget {
+	maxConcurrent := cfg.ScanMaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	maxHeavy := cfg.ScanMaxHeavy
+	if maxHeavy <= 0 || maxHeavy > maxConcurrent {
+		maxHeavy = maxConcurrent
+	}
+
+	if cfg.ScanMaxProcs > 0 && cfg.ScanMaxProcs < runtime.NumCPU() {
+		runtime.GOMAXPROCS(cfg.ScanMaxProcs)
+		log.Printf("Scan budget: limiting agent to %d CPUs", cfg.ScanMaxProcs)
+	}
+
+	log.Printf("Scan budget: %d concurrent scanners (%d heavy), CPU threshold %.0f%%",
+		maxConcurrent, maxHeavy, cfg.ScanCPUThreshold)
+
+	return &Budget{
+		slots:        make(chan struct{}, maxConcurrent),
+		heavySlots:   make(chan struct{}, maxHeavy),
+		cpuThreshold: cfg.ScanCPUThreshold,
+		maxWait:      cfg.ScanThrottleMaxWait,
+		pollInterval: 15 * time.Second,
+		cpuPercent:   systemCPUPercent,
+	}
+}
+
+// Run executes fn once the budget allows a scanner of the given weight to
+// start. It returns ctx.Err() without running fn if the context is
+// cancelled while waiting.
+func (b *Budget) Run(ctx context.Context, name string, weight Weight, fn func()) error {
+	start := time.Now()
+
+	if weight == Heavy {
+		select {
+		case b.heavySlots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-b.heavySlots }()
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-b.slots }()
+
+	if weight == Heavy {
+		if err := b.waitForCPU(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	if waited := time.Since(start); waited > time.Second {
+		log.Printf("Scan budget: %s started after waiting %v", name, waited.Round(time.Second))
+	}
+
+	fn()
+	return nil
+}
+
+// waitForCPU blocks while system CPU is above the threshold, up to maxWait
+// so a permanently busy host still gets scanned eventually
+func (b *Budget) waitForCPU(ctx context.Context, name string) error {
+	if b.cpuThreshold <= 0 || b.cpuThreshold >= 100 {
+		return nil
+	}
+
+	deadline := time.Now().Add(b.maxWait)
+	for {
+		usage, err := b.cpuPercent()
+		if err != nil || usage < b.cpuThreshold {
+			return nil
+		}
+		if b.maxWait > 0 && time.Now().After(deadline) {
+			log.Printf("Scan budget: host still busy (%.0f%% CPU), starting %s anyway", usage, name)
+			return nil
+		}
+
+		log.Printf("Scan budget: deferring %s, host CPU at %.0f%%", name, usage)
+		select {
+		case <-time.After(b.pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// systemCPUPercent samples overall host CPU usage
+func systemCPUPercent() (float64, error) {
+	percents, err := cpu.Percent(time.Second, false)
+	if err != nil || len(percents) == 0 {
+		return 0, err
+	}
+	return percents[0], nil
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestBudget(maxConcurrent, maxHeavy int) *Budget {
+	return &Budget{
+		slots:        make(chan struct{}, maxConcurrent),
+		heavySlots:   make(chan struct{}, maxHeavy),
+		pollInterval: time.Millisecond,
+		cpuPercent:   func() (float64, error) { return 0, nil },
+	}
+}
+
+func TestBudgetSerializesHeavyScanners(t *testing.T) {
+	b := newTestBudget(4, 1)
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Run(context.Background(), "heavy", Heavy, func() {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if peak != 1 {
+		t.Fatalf("expected heavy scanners to run one at a time, peak was %d", peak)
+	}
+}
+
+func TestBudgetLightRunsAlongsideHeavy(t *testing.T) {
+	b := newTestBudget(2, 1)
+
+	heavyStarted := make(chan struct{})
+	releaseHeavy := make(chan struct{})
+	go b.Run(context.Background(), "heavy", Heavy, func() {
+		close(heavyStarted)
+		<-releaseHeavy
+	})
+	<-heavyStarted
+	defer close(releaseHeavy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ran := false
+	if err := b.Run(ctx, "light", Light, func() { ran = true }); err != nil {
+		t.Fatalf("light scanner should not wait for heavy scanner: %v", err)
+	}
+	if !ran {
+		t.Fatal("light scanner did not run")
+	}
+}
+
+func TestBudgetDefersHeavyWhileCPUBusy(t *testing.T) {
+	b := newTestBudget(1, 1)
+	b.cpuThreshold = 50
+
+	var samples int32
+	b.cpuPercent = func() (float64, error) {
+		if atomic.AddInt32(&samples, 1) < 3 {
+			return 95, nil
+		}
+		return 10, nil
+	}
+
+	if err := b.Run(context.Background(), "heavy", Heavy, func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if samples < 3 {
+		t.Fatalf("expected scanner to wait for CPU to drop, sampled %d times", samples)
+	}
+}
+
+func TestBudgetCancelledWhileWaiting(t *testing.T) {
+	b := newTestBudget(1, 1)
+	b.slots <- struct{}{} // Budget exhausted
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	ran := false
+	if err := b.Run(ctx, "light", Light, func() { ran = true }); err == nil {
+		t.Fatal("expected context error")
+	}
+	if ran {
+		t.Fatal("scanner should not run after cancellation")
+	}
+}
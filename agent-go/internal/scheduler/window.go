@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// windowRecheck is the longest a Window waits before looking at the clock
+// again, so a host waking from sleep doesn't sleep through its window
+const windowRecheck = 5 * time.Minute
+
+// windowSearchLimit bounds how far ahead Next looks for an allowed minute
+const windowSearchLimit = 5 * 366 * 24 * time.Hour
+
+// Window restricts when periodic scans may start, as a five-field cron
+// expression (minute, hour, day of month, month, day of week) matching the
+// minutes scans are allowed in. "* 1-5 * * 1-5" allows scans from 01:00 to
+// 05:59 on weekdays. Fields take *, lists, ranges and steps; day of week
+// runs from 0 (Sunday) to 7 (Sunday again). As in cron, a day matches
+// either day field when both are restricted. Times are in the host's local
+// time zone. A nil Window allows scans at any time.
+type Window struct {
+	expr     string
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	anyDay   bool // Day of month is *
+	anyWeek  bool // Day of week is *
+}
+
+// windowField is one cron field's name and range of values
+type windowField struct {
+	name     string
+	min, max int
+}
+
+var windowFields = []windowField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseWindow parses a cron expression into a Window; an empty expression
+// returns a nil Window, allowing scans at any time
+func ParseWindow(expr string) (*Window, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(windowFields) {
+		return nil, fmt.Errorf("schedule %q must have 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(fields))
+	}
+
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseWindowField(field, windowFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday is both 0 and 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	w := &Window{
+		expr:     expr,
+		minutes:  sets[0],
+		hours:    sets[1],
+		days:     sets[2],
+		months:   sets[3],
+		weekdays: sets[4],
+		anyDay:   fields[2] == "*",
+		anyWeek:  fields[4] == "*",
+	}
+	if w.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q never allows a scan", expr)
+	}
+	return w, nil
+}
+
+// parseWindowField parses one comma-separated cron field into a bit set of
+// the values it matches
+func parseWindowField(field string, spec windowField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", spec.name, part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		low, high := spec.min, spec.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s field %q", spec.name, part)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s field %q", spec.name, part)
+				}
+			} else if step > 1 {
+				high = spec.max // "5/15" runs from 5 to the end of the range
+			}
+		}
+		if low < spec.min || high > spec.max || low > high {
+			return 0, fmt.Errorf("%s field %q must be within %d-%d", spec.name, part, spec.min, spec.max)
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// String returns the window's cron expression
+func (w *Window) String() string {
+	if w == nil {
+		return "always"
+	}
+	return w.expr
+}
+
+// Allows reports whether a scan may start at t
+func (w *Window) Allows(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	return w.minutes&(1<<t.Minute()) != 0 &&
+		w.hours&(1<<t.Hour()) != 0 &&
+		w.months&(1<<int(t.Month())) != 0 &&
+		w.allowsDay(t)
+}
+
+func (w *Window) allowsDay(t time.Time) bool {
+	day := w.days&(1<<t.Day()) != 0
+	weekday := w.weekdays&(1<<int(t.Weekday())) != 0
+	if w.anyDay || w.anyWeek {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Next returns the earliest time at or after t that a scan may start, or the
+// zero time if the window never opens again
+func (w *Window) Next(t time.Time) time.Time {
+	if w == nil || w.Allows(t) {
+		return t
+	}
+
+	// Skip ahead a month, day or hour at a time while those don't match
+	limit := t.Add(windowSearchLimit)
+	next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+	for next.Before(limit) {
+		switch {
+		case w.months&(1<<int(next.Month())) == 0:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+		case !w.allowsDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case w.hours&(1<<next.Hour()) == 0:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case w.minutes&(1<<next.Minute()) == 0:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// Wait blocks until the window allows a scan, logging when the scan is
+// skipped until the window next opens. It returns false if ctx is done first.
+func (w *Window) Wait(ctx context.Context, scan string) bool {
+	logged := false
+	for {
+		now := time.Now()
+		next := w.Next(now)
+		if next.Equal(now) {
+			return ctx.Err() == nil
+		}
+		if !logged {
+			log.Printf("Skipping %s outside its window %q; next run at %s", scan, w, next.Format(time.RFC3339))
+			logged = true
+		}
+
+		wait := windowRecheck
+		if d := next.Sub(now); !next.IsZero() && d < wait {
+			wait = d
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
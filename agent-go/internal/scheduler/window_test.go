@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWindowNext(t *testing.T) {
+	// Weeknights from 01:00 to 05:59, plus every 15 minutes on the 1st
+	w, err := ParseWindow("*/15 1-5 1 * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		want time.Time
+	}{
+		{"inside the window", at(15, 2, 30), at(15, 2, 30)},
+		{"between steps", at(15, 2, 31), at(15, 2, 45)},
+		{"after hours", at(15, 9, 0), at(16, 1, 0)},
+		{"Friday night to Monday", at(16, 6, 0), at(19, 1, 0)},
+		{"start of the window", at(1, 0, 59), at(1, 1, 0)},
+		{"a Sunday that is the 1st", at(30, 23, 59), at(1, 1, 0).AddDate(0, 1, 0)},
+	}
+	for _, tt := range tests {
+		if got := w.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s: Next(%s) = %s, want %s", tt.name, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	if w, err := ParseWindow(""); w != nil || err != nil {
+		t.Fatalf("ParseWindow(\"\") = %v, %v, want no window", w, err)
+	}
+	var always *Window
+	now := time.Now()
+	if !always.Allows(now) || !always.Next(now).Equal(now) {
+		t.Error("a nil window should allow scans at any time")
+	}
+
+	// Sunday is both 0 and 7
+	w, err := ParseWindow("0 3 * * 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sunday := time.Date(2026, time.October, 18, 3, 0, 0, 0, time.UTC); !w.Allows(sunday) {
+		t.Errorf("%q should allow %s", w, sunday)
+	}
+
+	for _, expr := range []string{
+		"* * * *",     // Too few fields
+		"60 * * * *",  // Minute out of range
+		"* 5-1 * * *", // Backwards range
+		"*/0 * * * *", // Zero step
+		"* * x * *",   // Not a number
+		"0 0 30 2 *",  // February 30th
+	} {
+		if _, err := ParseWindow(expr); err == nil {
+			t.Errorf("ParseWindow(%q) should fail", expr)
+		}
+	}
+}
+
+func TestWindowWaitStopsWithContext(t *testing.T) {
+	// A window that is closed for the next minute at least
+	next := time.Now().Add(2 * time.Minute)
+	w, err := ParseWindow(next.Format("4 15 2 1 *"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if w.Wait(ctx, "test scan") {
+		t.Error("Wait should give up when its context is done")
+	}
+}
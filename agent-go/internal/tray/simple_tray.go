@@ -1,30 +1,38 @@
 package tray
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os/exec"
 	"runtime"
 	"time"
 
+	"zerotrace/agent/internal/ipc"
 	"zerotrace/agent/internal/monitor"
 
 	"fyne.io/systray"
 )
 
+// scanNowTimeout is the longest Scan Now waits for the scan to finish
+const scanNowTimeout = time.Hour
+
 // SimpleTrayManager provides a minimal tray interface for MDM deployment
 type SimpleTrayManager struct {
-	monitor  *monitor.Monitor
-	quitChan chan bool
-	platform PlatformOperations
+	monitor     *monitor.Monitor
+	quitChan    chan bool
+	platform    PlatformOperations
+	agentSocket string // Control socket Scan Now is sent through
 }
 
-// NewSimpleTrayManager creates a new simple tray manager
-func NewSimpleTrayManager() *SimpleTrayManager {
+// NewSimpleTrayManager creates a new simple tray manager that triggers scans
+// through the agent's control socket at agentSocket
+func NewSimpleTrayManager(agentSocket string) *SimpleTrayManager {
 	return &SimpleTrayManager{
-		quitChan: make(chan bool, 1),
-		monitor:  monitor.NewMonitor(),
-		platform: GetPlatformOperations(),
+		quitChan:    make(chan bool, 1),
+		monitor:     monitor.NewMonitor(),
+		platform:    GetPlatformOperations(),
+		agentSocket: agentSocket,
 	}
 }
 
@@ -72,6 +80,7 @@ func (stm *SimpleTrayManager) OnReady() {
 	// Create minimal menu
 	mStatus := systray.AddMenuItem(" Status: Checking...", "Agent status")
 	mCPU := systray.AddMenuItem(" CPU: --", "CPU usage")
+	mScanNow := systray.AddMenuItem(" Scan Now", "Run a scan now")
 	systray.AddSeparator()
 	mQuit := systray.AddMenuItem(" Quit", "Quit agent")
 
@@ -86,6 +95,8 @@ func (stm *SimpleTrayManager) OnReady() {
 				stm.showStatus()
 			case <-mCPU.ClickedCh:
 				stm.showCPUInfo()
+			case <-mScanNow.ClickedCh:
+				go stm.scanNow(mScanNow)
 			case <-mQuit.ClickedCh:
 				stm.quitAgent()
 			case <-stm.quitChan:
@@ -189,6 +200,21 @@ func (stm *SimpleTrayManager) showCPUInfo() {
 	stm.showNotification("ZeroTrace Agent", "CPU Usage", info)
 }
 
+// scanNow asks the agent to scan, and shows how many findings it sent
+func (stm *SimpleTrayManager) scanNow(item *systray.MenuItem) {
+	item.Disable()
+	defer item.Enable()
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanNowTimeout)
+	defer cancel()
+	findings, err := ipc.ScanNow(ctx, stm.agentSocket)
+	if err != nil {
+		stm.showNotification("ZeroTrace Agent", "Scan failed", err.Error())
+		return
+	}
+	stm.showNotification("ZeroTrace Agent", "Scan complete", fmt.Sprintf("%d findings", findings))
+}
+
 // quitAgent quits the agent
 func (stm *SimpleTrayManager) quitAgent() {
 	stm.quitChan <- true
@@ -27,7 +27,12 @@ type TrayManager struct {
 }
 
 // NewTrayManager creates a new tray manager
-func NewTrayManager(cfg *config.Config) *TrayManager {
+func NewTrayManager(cfg *config.Config) (*TrayManager, error) {
+	comm, err := communicator.NewCommunicator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &TrayManager{
 		statusChan:   make(chan string, 1),
 		cpuChan:      make(chan float64, 1),
@@ -35,9 +40,9 @@ func NewTrayManager(cfg *config.Config) *TrayManager {
 		quitChan:     make(chan bool, 1),
 		monitor:      monitor.NewMonitor(),
 		platform:     GetPlatformOperations(),
-		communicator: communicator.NewCommunicator(cfg),
+		communicator: comm,
 		apiConnected: false,
-	}
+	}, nil
 }
 
 // Start initializes and runs the tray icon
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package updater
+
+import (
+	"os"
+	"syscall"
+)
+
+// Relaunch replaces the agent process with the installed release, keeping
+// its process ID so service managers see no restart
+func (u *Updater) Relaunch() error {
+	return syscall.Exec(u.executable, os.Args, os.Environ())
+}
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package updater
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Relaunch starts the installed release and exits, as Windows can't replace
+// a running process's image
+func (u *Updater) Relaunch() error {
+	cmd := exec.Command(u.executable, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,272 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/agent/internal/config"
+	"zerotrace/agent/internal/models"
+)
+
+// ErrNotNewer is returned for a release that isn't newer than the running agent
+var ErrNotNewer = errors.New("release is not newer than the running agent")
+
+// maxBinarySize is the largest agent binary downloaded
+const maxBinarySize = 512 << 20
+
+// SelfCheckFlag makes the agent check that it can run and exit; an installed
+// release must pass it before the agent restarts into it
+const SelfCheckFlag = "-self-check"
+
+// Platform is the platform the running agent was built for, which releases
+// are published for, as GOOS/GOARCH
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// Updater installs agent releases in place of the running binary. A release
+// is only installed when its signed manifest verifies against the pinned
+// public key, and it is rolled back unless the new binary passes its
+// self-check.
+type Updater struct {
+	version       string
+	publicKey     ed25519.PublicKey
+	executable    string // Binary releases replace
+	healthTimeout time.Duration
+	client        *http.Client
+	selfCheck     func(ctx context.Context, path string) error // Runs the installed binary's self-check
+
+	mu     sync.Mutex
+	failed map[string]bool // Versions whose binary failed, not tried again until restart
+}
+
+// New creates an updater for the running agent, which is at version
+func New(cfg *config.Config, version string) (*Updater, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.UpdatePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("update public key is not a base64 ed25519 public key")
+	}
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the agent binary: %w", err)
+	}
+
+	return &Updater{
+		version:       version,
+		publicKey:     ed25519.PublicKey(key),
+		executable:    executable,
+		healthTimeout: cfg.UpdateHealthTimeout,
+		client:        &http.Client{Timeout: 10 * time.Minute},
+		selfCheck:     runSelfCheck,
+		failed:        make(map[string]bool),
+	}, nil
+}
+
+// Install downloads a release, verifies it and swaps it in for the agent
+// binary. Once it returns nil the agent should shut down and Relaunch. A
+// release failing verification or its self-check leaves the binary as it was.
+func (u *Updater) Install(ctx context.Context, release models.AgentUpdate) error {
+	if release.Platform != Platform() {
+		return fmt.Errorf("release is for %s, not %s", release.Platform, Platform())
+	}
+	if compareVersions(release.Version, u.version) <= 0 {
+		return ErrNotNewer
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.failed[release.Version] {
+		return fmt.Errorf("release %s failed before and is not retried", release.Version)
+	}
+
+	binary, err := u.download(ctx, release.URL)
+	if err != nil {
+		return err
+	}
+	if err := u.verify(binary, release); err != nil {
+		u.failed[release.Version] = true
+		return err
+	}
+	if err := u.swap(binary); err != nil {
+		return err
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, u.healthTimeout)
+	defer cancel()
+	if err := u.selfCheck(checkCtx, u.executable); err != nil {
+		u.failed[release.Version] = true
+		if rollbackErr := u.rollback(); rollbackErr != nil {
+			return fmt.Errorf("release %s failed its self-check (%v) and could not be rolled back: %w", release.Version, err, rollbackErr)
+		}
+		return fmt.Errorf("release %s failed its self-check and was rolled back: %w", release.Version, err)
+	}
+	return nil
+}
+
+// download fetches a release's binary
+func (u *Updater) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %w", err)
+	}
+	req.Header.Set("User-Agent", "ZeroTrace-Agent/1.0")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release download returned status %d", resp.StatusCode)
+	}
+	binary, err := io.ReadAll(io.LimitReader(resp.Body, maxBinarySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download release: %w", err)
+	}
+	if len(binary) > maxBinarySize {
+		return nil, fmt.Errorf("release is larger than %d bytes", maxBinarySize)
+	}
+	return binary, nil
+}
+
+// verify checks a binary against the release's digest, and the release's
+// manifest signature against the pinned public key. The version and platform
+// Install checked are those of the manifest, so an older signed binary can't
+// be offered under a newer version.
+func (u *Updater) verify(binary []byte, release models.AgentUpdate) error {
+	signature, err := base64.StdEncoding.DecodeString(release.Signature)
+	if err != nil || !ed25519.Verify(u.publicKey, releaseManifest(release), signature) {
+		return errors.New("release manifest is not signed with the pinned update key")
+	}
+	digest := sha256.Sum256(binary)
+	if !strings.EqualFold(hex.EncodeToString(digest[:]), release.SHA256) {
+		return errors.New("release binary does not match its SHA-256 digest")
+	}
+	return nil
+}
+
+// releaseManifest is what a release is signed over: its version, platform
+// and binary digest, one per line. Signing the binary alone would let a
+// compromised API offer an old, vulnerable release as a newer version.
+func releaseManifest(release models.AgentUpdate) []byte {
+	return []byte("zerotrace-agent-release\n" +
+		"version=" + release.Version + "\n" +
+		"platform=" + release.Platform + "\n" +
+		"sha256=" + strings.ToLower(release.SHA256) + "\n")
+}
+
+// swap writes the new binary next to the agent's and renames it into place,
+// keeping the current binary as .old for rollback. Renames within a directory
+// are atomic, so the agent binary is whole at every point.
+func (u *Updater) swap(binary []byte) error {
+	mode := os.FileMode(0o755)
+	if info, err := os.Stat(u.executable); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	staged := u.executable + ".new"
+	file, err := os.OpenFile(staged, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to stage release: %w", err)
+	}
+	_, err = io.Copy(file, bytes.NewReader(binary))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(staged)
+		return fmt.Errorf("failed to stage release: %w", err)
+	}
+
+	previous := u.executable + ".old"
+	if err := os.Remove(previous); err != nil && !os.IsNotExist(err) {
+		os.Remove(staged)
+		return fmt.Errorf("failed to remove the previous rollback binary: %w", err)
+	}
+	if err := os.Rename(u.executable, previous); err != nil {
+		os.Remove(staged)
+		return fmt.Errorf("failed to keep the current binary for rollback: %w", err)
+	}
+	if err := os.Rename(staged, u.executable); err != nil {
+		os.Rename(previous, u.executable)
+		os.Remove(staged)
+		return fmt.Errorf("failed to install release: %w", err)
+	}
+	return nil
+}
+
+// rollback puts the binary swap replaced back
+func (u *Updater) rollback() error {
+	return os.Rename(u.executable+".old", u.executable)
+}
+
+// runSelfCheck runs a binary's self-check with the agent's own flags, so it
+// checks the same configuration
+func runSelfCheck(ctx context.Context, path string) error {
+	args := append(append([]string{}, os.Args[1:]...), SelfCheckFlag)
+	output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("self-check did not finish in time: %w", ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// compareVersions compares dotted version numbers, ignoring a leading v and
+// any pre-release or build suffix
+func compareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+	return parts
+}
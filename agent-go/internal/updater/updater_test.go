@@ -0,0 +1,172 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerotrace/agent/internal/models"
+)
+
+// testUpdater returns an updater for an agent binary in a temp directory,
+// serving release binaries from a test server
+func testUpdater(t *testing.T, publicKey ed25519.PublicKey, binary []byte, selfCheck func(context.Context, string) error) (*Updater, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	}))
+	t.Cleanup(server.Close)
+
+	executable := filepath.Join(t.TempDir(), "zerotrace-agent")
+	if err := os.WriteFile(executable, []byte("current"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return &Updater{
+		version:       "1.0.0",
+		publicKey:     publicKey,
+		executable:    executable,
+		healthTimeout: time.Second,
+		client:        server.Client(),
+		selfCheck:     selfCheck,
+		failed:        make(map[string]bool),
+	}, server.URL
+}
+
+// signedRelease describes version 1.1.0 of a binary, signed with key
+func signedRelease(key ed25519.PrivateKey, url string, binary []byte) models.AgentUpdate {
+	return signedVersion(key, url, "1.1.0", binary)
+}
+
+func signedVersion(key ed25519.PrivateKey, url, version string, binary []byte) models.AgentUpdate {
+	digest := sha256.Sum256(binary)
+	release := models.AgentUpdate{
+		Version:  version,
+		Platform: Platform(),
+		URL:      url,
+		SHA256:   hex.EncodeToString(digest[:]),
+	}
+	release.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, releaseManifest(release)))
+	return release
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestInstall(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	binary := []byte("release 1.1.0")
+	checked := ""
+	u, url := testUpdater(t, publicKey, binary, func(ctx context.Context, path string) error {
+		checked = readFile(t, path)
+		return nil
+	})
+
+	if err := u.Install(context.Background(), signedRelease(privateKey, url, binary)); err != nil {
+		t.Fatalf("Install() = %v", err)
+	}
+	if got := readFile(t, u.executable); got != string(binary) {
+		t.Errorf("agent binary = %q, want the release", got)
+	}
+	if checked != string(binary) {
+		t.Errorf("self-check ran on %q, want the release", checked)
+	}
+	if got := readFile(t, u.executable+".old"); got != "current" {
+		t.Errorf("rollback binary = %q, want the previous agent", got)
+	}
+}
+
+func TestInstallRollsBackFailedSelfCheck(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	binary := []byte("broken release")
+	checks := 0
+	u, url := testUpdater(t, publicKey, binary, func(ctx context.Context, path string) error {
+		checks++
+		return errors.New("cannot reach API")
+	})
+	release := signedRelease(privateKey, url, binary)
+
+	if err := u.Install(context.Background(), release); err == nil {
+		t.Fatal("a release failing its self-check should not install")
+	}
+	if got := readFile(t, u.executable); got != "current" {
+		t.Errorf("agent binary = %q, want it rolled back", got)
+	}
+	if err := u.Install(context.Background(), release); err == nil || checks != 1 {
+		t.Errorf("a failed release should not be retried, got %v after %d checks", err, checks)
+	}
+}
+
+func TestInstallRejectsUnverifiedReleases(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	binary := []byte("release 1.1.0")
+	u, url := testUpdater(t, publicKey, binary, func(ctx context.Context, path string) error {
+		t.Error("an unverified release should never be run")
+		return nil
+	})
+
+	signed := func(change func(*models.AgentUpdate)) models.AgentUpdate {
+		release := signedRelease(privateKey, url, binary)
+		change(&release)
+		return release
+	}
+	// A signed older release, served as if it were a newer one
+	downgrade := signedVersion(privateKey, url, "0.9.0", binary)
+	downgrade.Version = "1.1.0"
+	otherPlatform := signedRelease(privateKey, url, binary)
+	otherPlatform.Platform = "plan9/386"
+	otherPlatform.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, releaseManifest(otherPlatform)))
+	otherPlatform.Platform = Platform()
+	// A signature of the binary itself, as releases were once signed
+	binarySignature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, binary))
+
+	for name, release := range map[string]models.AgentUpdate{
+		"other key":         signedRelease(otherKey, url, binary),
+		"other binary":      signedRelease(privateKey, url, []byte("other")),
+		"wrong digest":      signed(func(r *models.AgentUpdate) { r.SHA256 = "00" }),
+		"signed binary":     signed(func(r *models.AgentUpdate) { r.Signature = binarySignature }),
+		"relabeled version": downgrade,
+		"relabeled OS":      otherPlatform,
+		"older version":     signedVersion(privateKey, url, "0.9.0", binary),
+		"other OS":          signed(func(r *models.AgentUpdate) { r.Platform = "plan9/386" }),
+	} {
+		if err := u.Install(context.Background(), release); err == nil {
+			t.Errorf("%s: Install() should fail", name)
+		}
+		u.failed = make(map[string]bool)
+	}
+	if got := readFile(t, u.executable); got != "current" {
+		t.Errorf("agent binary = %q, want it untouched", got)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.0", 1},
+		{"v1.2", "1.2.0", 0},
+		{"1.2.0-rc1", "1.2.0", 0},
+		{"1.2.3", "1.3", -1},
+	} {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
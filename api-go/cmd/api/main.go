@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,25 +15,44 @@ import (
 	"time"
 
 	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/events"
 	"zerotrace/api/internal/handlers"
+	"zerotrace/api/internal/logging"
 	"zerotrace/api/internal/middleware"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/monitoring"
+	"zerotrace/api/internal/queue"
 	"zerotrace/api/internal/repository"
 	"zerotrace/api/internal/services"
+	"zerotrace/api/internal/storage"
 	analytics "zerotrace/api/internal/services/analytics"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	envFile := flag.String("env-file", ".env", "Env file to load variables from; the environment takes precedence")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration with secrets redacted, validate it and exit")
+	flag.Parse()
+
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	if err := config.LoadFile(*envFile); err != nil {
+		log.Printf("No %s file found, using system environment variables", *envFile)
 	}
 
 	// Load configuration
 	cfg := config.Load()
 
+	if *printConfig {
+		cfg.PrintSettings(os.Stdout)
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "\nConfiguration is invalid:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("Configuration validation failed: %v", err)
@@ -40,6 +63,19 @@ func main() {
 		log.Fatalf("Environment validation failed: %v", err)
 	}
 
+	// Log through slog from here on, including the standard log package, so
+	// every line is structured
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat))
+
+	// CAs agent client certificates must chain to; nil leaves mutual TLS off
+	var agentCAs *x509.CertPool
+	if cfg.AgentMTLS {
+		var err error
+		if agentCAs, err = middleware.LoadClientCAs(cfg.AgentCACertPath); err != nil {
+			log.Fatalf("Failed to load agent CA certificates: %v", err)
+		}
+	}
+
 	// Set Gin mode
 	if cfg.Debug {
 		gin.SetMode(gin.DebugMode)
@@ -62,67 +98,209 @@ func main() {
 	// Initialize repositories
 	scanRepo := repository.NewScanRepository(db.DB)
 
-	// Initialize config auditor repositories
-	configFileRepo := repository.NewConfigFileRepository(db.DB)
+	// Initialize config auditor repositories, with uploaded file content kept
+	// in the database unless a blob store is configured
+	var configFileStore storage.BlobStore
+	if cfg.ConfigFileStore != "db" {
+		configFileStore, err = storage.NewStore(cfg.ConfigFileStore, cfg.ConfigAuditorStoragePath, storage.S3Config{
+			Endpoint:        cfg.ConfigFileS3Endpoint,
+			Region:          cfg.ConfigFileS3Region,
+			Bucket:          cfg.ConfigFileS3Bucket,
+			AccessKeyID:     cfg.ConfigFileS3AccessKeyID,
+			SecretAccessKey: cfg.ConfigFileS3SecretKey,
+			UsePathStyle:    cfg.ConfigFileS3PathStyle,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize config file storage: %v", err)
+		}
+	}
+	configFileRepo := repository.NewConfigFileRepository(db.DB, cfg.ConfigFileStore, configFileStore)
+
+	// Chunks of chunked config uploads are never kept in the database; they
+	// go to the config file store, or to files when there is none
+	configUploadStore := configFileStore
+	if configUploadStore == nil {
+		configUploadStore, err = storage.NewFileStore(cfg.ConfigAuditorStoragePath)
+		if err != nil {
+			log.Fatalf("Failed to initialize config upload storage: %v", err)
+		}
+	}
 	configFindingRepo := repository.NewConfigFindingRepository(db.DB)
 	configStandardRepo := repository.NewConfigStandardRepository(db.DB)
 	configAnalysisRepo := repository.NewConfigAnalysisRepository(db.DB)
+	dataExportRepo := repository.NewDataExportRepository(db.DB)
 
 	// Initialize services
-	scanService := services.NewScanService(cfg, scanRepo)
-	agentService := services.NewAgentService(db.DB)
+	scanProgressBus := services.NewScanProgressBus()
+	scanService := services.NewScanService(cfg, scanRepo, scanProgressBus)
+	agentService := services.NewAgentService(db.DB, cfg)
 	enrollmentService := services.NewEnrollmentService(cfg, db)
-	vulnerabilityV2Service := services.NewVulnerabilityV2Service()
 	organizationProfileService := services.NewOrganizationProfileService(db.DB)
-	analyticsService := analytics.NewAnalyticsService(db.DB)
-	enrichmentService := services.NewEnrichmentService(cfg.EnrichmentServiceURL)
+	analyticsService := analytics.NewAnalyticsService(db.DB, cfg.HeatmapCacheTTL)
+	threatIntelService := services.NewThreatIntelService(db.DB, cfg)
+	enrichmentService := services.NewEnrichmentService(cfg, threatIntelService)
 	aiService := services.NewAIService(cfg.AIServiceURL)
 
 	// Initialize config auditor services
 	configParserService := services.NewConfigParserService(configFileRepo)
-	configAnalyzerService := services.NewConfigAnalyzerService(configFileRepo, configFindingRepo, configStandardRepo, configAnalysisRepo)
+	configRuleService, err := services.NewConfigRuleService(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load config rules: %v", err)
+	}
+	configAnalyzerService := services.NewConfigAnalyzerService(configFileRepo, configFindingRepo, configStandardRepo, configAnalysisRepo, configRuleService)
 	configJobService := services.NewConfigJobService(configFileRepo, configParserService, configAnalyzerService, cfg)
 	configFileService := services.NewConfigFileService(cfg, configFileRepo, configParserService, configAnalyzerService, configJobService)
 	configFindingService := services.NewConfigFindingService(configFindingRepo)
 	configAnalysisService := services.NewConfigAnalysisService(configAnalysisRepo, configFileRepo)
 
-	// Get underlying sql.DB for AttackPathService
+	// Scheduled export to customer-owned object storage
+	dataExportService := services.NewDataExportService(dataExportRepo, cfg)
+	findingStateService := services.NewFindingStateService(db.DB, cfg)
+	agentCommandService := services.NewAgentCommandService(db.DB, cfg)
+	collectionService := services.NewCollectionService(db.DB, agentService, agentCommandService, cfg)
+	configBaselineService := services.NewConfigBaselineService(db.DB, agentService)
+	containerAllowlistService := services.NewContainerAllowlistService(db.DB, agentService)
+	licensePolicyService := services.NewLicensePolicyService(db.DB, agentService)
+	graphQLService := services.NewGraphQLService(agentService, findingStateService, cfg)
+	scanScopeService := services.NewScanScopeService(db.DB, agentService)
+	scanImportService := services.NewScanImportService(db.DB, agentService)
+	networkAssetService := services.NewNetworkAssetService(db.DB, cfg)
+	complianceSLAService := services.NewComplianceSLAService(db.DB)
+	hostComparisonService := services.NewHostComparisonService(db.DB, agentService)
+	hostRiskService := services.NewHostRiskService(db.DB, agentService)
+	suppressionService := services.NewSuppressionService(db.DB, agentService, findingStateService, hostRiskService)
+	vulnerabilityV2Service := services.NewVulnerabilityV2Service(suppressionService, scanProgressBus)
+	networkTopologyService := services.NewNetworkTopologyService(agentService, networkAssetService, hostRiskService)
+	resultIngestionService := services.NewResultIngestionService(db.DB, agentService, findingStateService, hostRiskService)
+	resultIngestionService.OnIngest(analyticsService.InvalidateHeatmaps)
+	resultBatchService := services.NewResultBatchService(db.DB, cfg)
+	configUploadService := services.NewConfigUploadService(db.DB, configFileService, configUploadStore, cfg)
+	findingRetentionService := services.NewFindingRetentionService(db.DB, cfg, complianceSLAService)
+	evidenceService, err := services.NewEvidenceService(db.DB, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize evidence storage: %v", err)
+	}
+	findingVerificationService := services.NewFindingVerificationService(db.DB, agentCommandService, findingStateService, hostRiskService, evidenceService, enrichmentService)
+	exportJobService, err := services.NewExportJobService(db.DB, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize export storage: %v", err)
+	}
+	exportQuota := middleware.NewExportQuota(cfg)
+	backfillJobService := services.NewBackfillJobService(db.DB, cfg, enrichmentService, agentService, findingStateService, hostRiskService)
+	webhookService := services.NewWebhookService(db.DB, cfg)
+	apiKeyService := services.NewAPIKeyService(db.DB)
+	updateService := services.NewUpdateService(db.DB)
+	statusCtx, stopStatusRoutine := context.WithCancel(context.Background())
+	agentService.StartStatusRoutine(statusCtx)
+	dataExportService.Start()
+	resultBatchService.Start()
+	configUploadService.Start()
+	configRuleService.Start()
+	findingRetentionService.Start()
+	exportJobService.Start()
+	backfillJobService.Start()
+	threatIntelService.Start()
+	webhookService.Start()
+	suppressionService.Start()
+
+	attackPathService := services.NewAttackPathService(db.DB, agentService, networkAssetService)
+
+	// Get underlying sql.DB for connection pool metrics
 	sqlDB, err := db.DB.DB()
 	if err != nil {
 		log.Fatalf("Failed to get underlying sql.DB: %v", err)
 	}
-	attackPathService := services.NewAttackPathService(sqlDB)
+
+	// Fair scheduler shared by ingestion and background processing
+	processingScheduler := queue.NewFairScheduler(cfg.ProcessingMaxConcurrency, cfg.OrgMaxConcurrency, cfg.OrgConcurrencyOverrides, cfg.OrgSchedulingWeights)
+
+	// Prometheus metrics, recorded ahead of recovery so requests that panic
+	// count as the 500s they become
+	metrics := monitoring.NewMetrics()
+	metrics.RegisterDB(sqlDB)
+	metrics.RegisterScheduler(processingScheduler)
+	metrics.RegisterAgents(agentService)
 
 	// Setup router
+	// Rate limit buckets and ETags are shared through Redis when several API
+	// instances run, and held in-process otherwise
+	var bucketStore middleware.BucketStore = middleware.NewMemoryBucketStore()
+	var etagCache middleware.ETagCache
+	if cfg.RedisURL != "" {
+		redisOptions, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		sharedRedis := redis.NewClient(redisOptions)
+		defer sharedRedis.Close()
+		bucketStore = middleware.NewRedisBucketStore(sharedRedis)
+		etagCache = middleware.NewRedisETagCache(sharedRedis, cfg.ETagCacheTTL)
+	}
+
 	router := gin.New()
-	router.Use(gin.Logger(), gin.Recovery())
+	router.Use(metrics.Middleware())
 
-	// Setup middleware (order matters - correlation ID should be first)
+	// Setup middleware (order matters - correlation ID should be first, and the
+	// access log ahead of everything that may reject or panic)
 	router.Use(middleware.CorrelationID())
+	router.Use(middleware.RequestLogger())
+	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(middleware.CompressionMiddleware()) // Add compression
-	router.Use(middleware.ETagMiddleware())        // Add ETag support
-	router.Use(middleware.InputValidationMiddleware())
-	router.Use(middleware.RateLimitMiddleware(cfg))
-	router.Use(middleware.RequestLogger())
+	router.Use(middleware.ETagMiddleware(etagCache)) // Add ETag support
+	router.Use(middleware.InputValidationMiddleware(int64(cfg.MaxRequestBodySize)))
 
-	// Setup routes
-	setupRoutes(router, db, scanService, agentService, enrollmentService, vulnerabilityV2Service, organizationProfileService, analyticsService, enrichmentService, aiService, configFileService, configFindingService, configAnalysisService, attackPathService)
+	// Setup routes, rate limited per client and route group
+	rateLimiter := middleware.NewRateLimiter(cfg, bucketStore)
+	// Service accounts authenticate with API keys, users with Clerk session
+	// tokens, verified locally with Clerk's keys cached
+	auth := middleware.APIKeyOrClerkAuth(apiKeyService, middleware.ClerkAuth(cfg))
+	setupRoutes(router, db, scanService, agentService, enrollmentService, vulnerabilityV2Service, organizationProfileService, analyticsService, enrichmentService, aiService, configFileService, configUploadService, configFindingService, configAnalysisService, attackPathService, processingScheduler, dataExportService, findingStateService, agentCommandService, configBaselineService, containerAllowlistService, suppressionService, scanScopeService, networkAssetService, complianceSLAService, hostComparisonService, hostRiskService, resultIngestionService, resultBatchService, evidenceService, findingVerificationService, networkTopologyService, exportJobService, backfillJobService, collectionService, threatIntelService, webhookService, apiKeyService, updateService, scanImportService, licensePolicyService, graphQLService, rateLimiter, exportQuota, agentCAs, auth, int64(cfg.MaxResultPayloadSize))
 
 	// Create server
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Port),
 		Handler: router,
 	}
+	if agentCAs != nil {
+		// Dashboard clients present no certificate; agent routes require one
+		server.TLSConfig = &tls.Config{
+			ClientAuth: tls.VerifyClientCertIfGiven,
+			ClientCAs:  agentCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
 
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Starting ZeroTrace API server on port %d", cfg.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if cfg.TLSCertPath != "" {
+			err = server.ListenAndServeTLS(cfg.TLSCertPath, cfg.TLSKeyPath)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
 
+	// Serve /metrics unauthenticated on its own address, localhost by default,
+	// so it is not exposed alongside the API
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsServer = &http.Server{
+			Addr:    cfg.MetricsAddr,
+			Handler: metrics.Handler(),
+		}
+		go func() {
+			log.Printf("Serving Prometheus metrics on %s/metrics", cfg.MetricsAddr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -130,12 +308,26 @@ func main() {
 	log.Println("Shutting down server...")
 
 	// Graceful shutdown - stop background workers first
+	stopStatusRoutine()
 	configJobService.Stop()
+	dataExportService.Stop()
+	resultBatchService.Stop()
+	configUploadService.Stop()
+	configRuleService.Stop()
+	findingRetentionService.Stop()
+	exportJobService.Stop()
+	backfillJobService.Stop()
+	threatIntelService.Stop()
+	webhookService.Stop()
+	suppressionService.Stop()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if metricsServer != nil {
+		metricsServer.Shutdown(ctx)
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
@@ -143,45 +335,68 @@ func main() {
 	log.Println("Server exited")
 }
 
-func setupRoutes(router *gin.Engine, db *repository.Database, scanService *services.ScanService, agentService *services.AgentService, enrollmentService *services.EnrollmentService, vulnerabilityV2Service *services.VulnerabilityV2Service, organizationProfileService *services.OrganizationProfileService, analyticsService *analytics.AnalyticsService, enrichmentService *services.EnrichmentService, aiService *services.AIService, configFileService *services.ConfigFileService, configFindingService *services.ConfigFindingService, configAnalysisService *services.ConfigAnalysisService, attackPathService *services.AttackPathService) {
+func setupRoutes(router *gin.Engine, db *repository.Database, scanService *services.ScanService, agentService *services.AgentService, enrollmentService *services.EnrollmentService, vulnerabilityV2Service *services.VulnerabilityV2Service, organizationProfileService *services.OrganizationProfileService, analyticsService *analytics.AnalyticsService, enrichmentService *services.EnrichmentService, aiService *services.AIService, configFileService *services.ConfigFileService, configUploadService *services.ConfigUploadService, configFindingService *services.ConfigFindingService, configAnalysisService *services.ConfigAnalysisService, attackPathService *services.AttackPathService, processingScheduler *queue.FairScheduler, dataExportService *services.DataExportService, findingStateService *services.FindingStateService, agentCommandService *services.AgentCommandService, configBaselineService *services.ConfigBaselineService, containerAllowlistService *services.ContainerAllowlistService, suppressionService *services.SuppressionService, scanScopeService *services.ScanScopeService, networkAssetService *services.NetworkAssetService, complianceSLAService *services.ComplianceSLAService, hostComparisonService *services.HostComparisonService, hostRiskService *services.HostRiskService, resultIngestionService *services.ResultIngestionService, resultBatchService *services.ResultBatchService, evidenceService *services.EvidenceService, findingVerificationService *services.FindingVerificationService, networkTopologyService *services.NetworkTopologyService, exportJobService *services.ExportJobService, backfillJobService *services.BackfillJobService, collectionService *services.CollectionService, threatIntelService *services.ThreatIntelService, webhookService *services.WebhookService, apiKeyService *services.APIKeyService, updateService *services.UpdateService, scanImportService *services.ScanImportService, licensePolicyService *services.LicensePolicyService, graphQLService *services.GraphQLService, rateLimiter *middleware.RateLimiter, exportQuota *middleware.ExportQuota, agentCAs *x509.CertPool, auth gin.HandlerFunc, maxResultPayloadSize int64) {
 	// Root route
 	// router.GET("/", handlers.Root)
 
 	// Health check
 	router.GET("/health", handlers.HealthCheck(db))
 
+	// Agents check in often, while analytics are expensive to compute, so
+	// they have their own rate limits
+	rateLimit := middleware.RateLimitMiddleware(rateLimiter, middleware.RateLimitGroupDefault)
+	agentRateLimit := middleware.RateLimitMiddleware(rateLimiter, middleware.RateLimitGroupAgents)
+	analyticsRateLimit := middleware.RateLimitMiddleware(rateLimiter, middleware.RateLimitGroupAnalytics)
+
 	// Agent routes (public - no auth required)
-	agents := router.Group("/api/agents")
+	// Uploads are capped so one pathological scan cannot exhaust memory;
+	// agents split larger results into several submissions
+	resultPayloadLimit := middleware.MaxPayloadSize(maxResultPayloadSize)
+	// With agent mutual TLS, the routes agents call require their client certificate
+	agentCert := middleware.AgentClientCert(agentCAs)
+	agents := router.Group("/api/agents", agentRateLimit)
 	{
-		agents.POST("/register", handlers.RegisterAgent(agentService))
-		agents.POST("/heartbeat", handlers.AgentHeartbeat(agentService))
-		agents.POST("/results", handlers.AgentResults(agentService, enrichmentService))
-		agents.POST("/status", handlers.AgentStatus(agentService))
-		agents.POST("/system-info", handlers.UpdateSystemInfo(agentService))
-		agents.POST("/network-scan-results", handlers.NetworkScanResults(agentService))
+		agents.POST("/register", agentCert, handlers.RegisterAgent(agentService))
+		agents.POST("/heartbeat", agentCert, handlers.AgentHeartbeat(agentService, agentCommandService, updateService))
+		agents.POST("/commands/:id/status", agentCert, handlers.UpdateCommandStatus(agentCommandService, findingVerificationService))
+		agents.GET("/config-baseline", agentCert, handlers.GetAgentConfigBaseline(configBaselineService))
+		agents.GET("/container-allowlist", agentCert, handlers.GetAgentContainerAllowlist(containerAllowlistService))
+		agents.GET("/scan-scope", agentCert, handlers.GetAgentScanScope(scanScopeService))
+		agents.GET("/update", agentCert, handlers.GetAgentUpdate(updateService))
+		agents.POST("/results", agentCert, resultPayloadLimit, handlers.AgentResults(agentService, enrichmentService, processingScheduler, resultIngestionService, resultBatchService, evidenceService, webhookService, licensePolicyService))
+		agents.POST("/results/batch", agentCert, resultPayloadLimit, handlers.AgentResultsBatch(agentService, enrichmentService, processingScheduler, resultIngestionService, evidenceService, webhookService, licensePolicyService))
+		agents.POST("/status", agentCert, handlers.AgentStatus(agentService))
+		agents.POST("/system-info", agentCert, resultPayloadLimit, handlers.UpdateSystemInfo(agentService))
+		agents.POST("/network-scan-results", agentCert, resultPayloadLimit, handlers.NetworkScanResults(agentService, networkAssetService, evidenceService))
 		agents.GET("/", handlers.GetAgents(agentService))
 		agents.GET("/:id", handlers.GetAgent(agentService))
 		agents.GET("/online", handlers.GetOnlineAgents(agentService))
 		agents.GET("/stats", handlers.GetAgentStats(agentService))
 		agents.GET("/stats/public", handlers.GetPublicAgentStats(agentService))
+		agents.GET("/tool-versions", auth, handlers.GetToolVersionDrift(agentService))
+		agents.GET("/missing-tools", auth, handlers.GetMissingTools(agentService))
+		agents.GET("/flapping-findings", auth, handlers.GetFlappingFindings(findingStateService))
 		agents.GET("/processing-status", handlers.GetProcessingStatus(agentService))
+		agents.GET("/processing-status/organizations", auth, handlers.GetOrgProcessingMetrics(processingScheduler))
 	}
 
 	// Public dashboard routes (no auth required)
-	dashboard := router.Group("/api/dashboard")
+	dashboard := router.Group("/api/dashboard", rateLimit)
 	{
 		dashboard.GET("/overview", handlers.GetPublicDashboardOverview(agentService))
 	}
 
-	// Public vulnerabilities route (no auth required)
-	vulnerabilities := router.Group("/api/vulnerabilities")
+	// Public vulnerabilities route (no auth required). A single vulnerability
+	// names the host and change that introduced it, so it authenticates.
+	vulnerabilities := router.Group("/api/vulnerabilities", rateLimit)
 	{
 		vulnerabilities.GET("/", handlers.GetPublicVulnerabilities(agentService))
+		vulnerabilities.GET("/:id", auth, middleware.RequirePermission(models.APIKeyPermissionReadFindings), handlers.GetVulnerability(agentService))
 	}
 
 	// Organization profile routes (public for now)
 	organizationProfileHandler := handlers.NewOrganizationProfileHandler(organizationProfileService)
-	organizations := router.Group("/api/organizations")
+	organizations := router.Group("/api/organizations", rateLimit)
 	{
 		organizations.POST("/profile", organizationProfileHandler.CreateOrganizationProfile)
 		organizations.GET("/:id/profile", organizationProfileHandler.GetOrganizationProfile)
@@ -192,7 +407,7 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 	}
 
 	// Technology stack analysis routes (merged into organization profile)
-	techStack := router.Group("/api/tech-stack")
+	techStack := router.Group("/api/tech-stack", rateLimit)
 	{
 		techStack.GET("/organizations/:id/analyze", organizationProfileHandler.AnalyzeTechStack)
 		techStack.GET("/organizations/:id/recommendations", organizationProfileHandler.GetTechStackRecommendations)
@@ -200,7 +415,7 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 
 	// AI-powered analysis routes (public for now)
 	aiAnalysisHandler := handlers.NewAIAnalysisHandler(aiService)
-	aiAnalysis := router.Group("/api/ai-analysis")
+	aiAnalysis := router.Group("/api/ai-analysis", analyticsRateLimit)
 	{
 		aiAnalysis.GET("/vulnerabilities/:id/comprehensive", aiAnalysisHandler.AnalyzeVulnerabilityComprehensive)
 		aiAnalysis.GET("/vulnerabilities/trends", aiAnalysisHandler.AnalyzeVulnerabilityTrends)
@@ -214,10 +429,10 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
 
 	// Dashboard history route
-	router.GET("/api/dashboard/history", analyticsHandler.GetDashboardHistory)
+	router.GET("/api/dashboard/history", analyticsRateLimit, analyticsHandler.GetDashboardHistory)
 
 	// Risk heatmap routes (public for now)
-	heatmaps := router.Group("/api/heatmaps")
+	heatmaps := router.Group("/api/heatmaps", analyticsRateLimit)
 	{
 		heatmaps.GET("/organizations/:id", analyticsHandler.GenerateRiskHeatmap)
 		heatmaps.GET("/organizations/:id/hotspots", analyticsHandler.GetHeatmapHotspots)
@@ -227,17 +442,18 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 	}
 
 	// Security maturity score routes (public for now)
-	maturity := router.Group("/api/maturity")
+	maturity := router.Group("/api/maturity", analyticsRateLimit)
 	{
 		maturity.GET("/organizations/:id/score", analyticsHandler.CalculateMaturityScore)
 		maturity.GET("/organizations/:id/benchmark", analyticsHandler.GetMaturityBenchmark)
 		maturity.GET("/organizations/:id/roadmap", analyticsHandler.GetImprovementRoadmap)
 		maturity.GET("/organizations/:id/trends", analyticsHandler.GetMaturityTrends)
+		maturity.GET("/organizations/:id/history", analyticsHandler.GetMaturityHistory)
 		maturity.GET("/organizations/:id/dimensions", analyticsHandler.GetDimensionScores)
 	}
 
 	// Compliance reporting routes (public for now)
-	compliance := router.Group("/api/compliance")
+	compliance := router.Group("/api/compliance", analyticsRateLimit)
 	{
 		compliance.GET("/organizations/:id/report", analyticsHandler.GenerateComplianceReport)
 		compliance.GET("/organizations/:id/score", analyticsHandler.GetComplianceScore)
@@ -248,17 +464,41 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 	}
 
 	// API v2 routes (public - no auth required for now)
-	v2 := router.Group("/api/v2")
+	// Exports are expensive, so each organization may only start a few per window
+	exportQuotaLimit := middleware.ExportQuotaMiddleware(exportQuota)
+	v2 := router.Group("/api/v2", rateLimit)
 	{
+		// Routes that act on an organization's credentials or send its data
+		// elsewhere require a signed-in member of that organization
+		orgMember := middleware.RequireOrganization("id")
+		agentMember := middleware.RequireAgentOrganization(agentService, "id")
+		userOnly := middleware.RequireUser()
+
 		// Vulnerability v2 routes
 		vulnerabilityV2Handler := handlers.NewVulnerabilityV2Handler(vulnerabilityV2Service, agentService)
+		exportJobHandler := handlers.NewExportJobHandler(exportJobService, vulnerabilityV2Handler)
 		v2Vulns := v2.Group("/vulnerabilities")
 		{
 			v2Vulns.GET("/", vulnerabilityV2Handler.GetVulnerabilitiesV2)
 			v2Vulns.GET("/stats", vulnerabilityV2Handler.GetVulnerabilityStats)
-			v2Vulns.GET("/export", vulnerabilityV2Handler.ExportVulnerabilities)
+			// Exports authenticate, so they count against the caller's
+			// organization and callbacks can't be sent on anyone's behalf
+			readFindings := middleware.RequirePermission(models.APIKeyPermissionReadFindings)
+			v2Vulns.GET("/export", auth, readFindings, exportQuotaLimit, vulnerabilityV2Handler.ExportVulnerabilities)
+			v2Vulns.POST("/export/async", auth, readFindings, exportQuotaLimit, exportJobHandler.CreateVulnerabilityExport)
 		}
 
+		// Async export status and download. The download link carries its own token.
+		v2.GET("/exports/:id", auth, exportJobHandler.GetExport)
+		v2.GET("/exports/:id/download", exportJobHandler.DownloadExport)
+
+		// Published schemas of the events sent to integrators
+		v2.GET("/events/types", handlers.ListEventTypes(events.Default()))
+		v2.GET("/events/types/:type/versions/:version/schema", handlers.GetEventSchema(events.Default()))
+
+		// Database storage saved by compressing scan results
+		v2.GET("/storage/compression", handlers.GetCompressionStats())
+
 		// Compliance routes
 		v2Compliance := v2.Group("/compliance")
 		{
@@ -271,6 +511,9 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 			v2Scans.POST("/network", vulnerabilityV2Handler.InitiateNetworkScan)
 			v2Scans.GET("/:scan_id/status", vulnerabilityV2Handler.GetScanStatus)
 			v2Scans.GET("/:scan_id/results", vulnerabilityV2Handler.GetScanResults)
+			// Live progress is only streamed to the scan's own organization,
+			// so unlike the rest of v2 this authenticates
+			v2Scans.GET("/:scan_id/events", auth, middleware.RequirePermission(models.APIKeyPermissionReadScans), handlers.StreamScanEvents(scanService))
 		}
 
 		// Attack Path routes
@@ -282,39 +525,209 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 			v2AttackPaths.POST("/generate", attackPathHandler.GenerateAttackPaths)
 		}
 
-		// Config Auditor routes (public for now)
+		// Config Auditor routes, scoped to the caller's organization
 		configFileHandler := handlers.NewConfigFileHandler(configFileService)
+		configUploadHandler := handlers.NewConfigUploadHandler(configUploadService)
 		configFindingHandler := handlers.NewConfigFindingHandler(configFindingService)
 		configAnalysisHandler := handlers.NewConfigAnalysisHandler(configAnalysisService)
 
-		v2ConfigFiles := v2.Group("/config-files")
+		v2ConfigFiles := v2.Group("/config-files", auth)
 		{
 			v2ConfigFiles.POST("/upload", configFileHandler.UploadConfigFile)
 			v2ConfigFiles.GET("/", configFileHandler.ListConfigFiles)
+			v2ConfigFiles.GET("/usage", configFileHandler.GetStorageUsage)
 			v2ConfigFiles.GET("/:id", configFileHandler.GetConfigFile)
 			v2ConfigFiles.GET("/:id/content", configFileHandler.GetConfigFileContent)
 			v2ConfigFiles.DELETE("/:id", configFileHandler.DeleteConfigFile)
 			v2ConfigFiles.POST("/:id/analyze", configFileHandler.TriggerAnalysis)
+
+			// Chunked uploads, resumable after a dropped connection
+			v2ConfigFiles.POST("/uploads", configUploadHandler.InitUpload)
+			v2ConfigFiles.GET("/uploads/:upload_id", configUploadHandler.GetUpload)
+			v2ConfigFiles.PUT("/uploads/:upload_id/chunks", configUploadHandler.UploadChunk)
+			v2ConfigFiles.POST("/uploads/:upload_id/complete", configUploadHandler.CompleteUpload)
+			v2ConfigFiles.DELETE("/uploads/:upload_id", configUploadHandler.AbortUpload)
 		}
 
-		v2ConfigFindings := v2.Group("/config-findings")
+		v2ConfigFindings := v2.Group("/config-findings", auth)
 		{
 			v2ConfigFindings.GET("/", configFindingHandler.ListConfigFindings)
+			v2ConfigFindings.GET("/export", configFindingHandler.ExportConfigFindings)
 			v2ConfigFindings.GET("/:id", configFindingHandler.GetConfigFinding)
 			v2ConfigFindings.PATCH("/:id/status", configFindingHandler.UpdateFindingStatus)
 			v2ConfigFindings.GET("/stats", configFindingHandler.GetFindingStats)
 		}
 
-		v2ConfigAnalysis := v2.Group("/config-files/:id")
+		v2ConfigAnalysis := v2.Group("/config-files/:id", auth)
 		{
 			v2ConfigAnalysis.GET("/analysis", configAnalysisHandler.GetAnalysisResults)
 			v2ConfigAnalysis.GET("/compliance", configAnalysisHandler.GetComplianceScores)
 			v2ConfigAnalysis.GET("/analysis/status", configAnalysisHandler.GetAnalysisStatus)
 		}
+
+		// Scheduled data export routes
+		dataExportHandler := handlers.NewDataExportHandler(dataExportService)
+		v2Exports := v2.Group("/organizations/:id/exports", auth, orgMember, userOnly)
+		{
+			v2Exports.GET("/config", dataExportHandler.GetExportConfig)
+			v2Exports.PUT("/config", dataExportHandler.UpdateExportConfig)
+			v2Exports.POST("/run", exportQuotaLimit, dataExportHandler.TriggerExport)
+			v2Exports.GET("/runs", dataExportHandler.ListExportRuns)
+		}
+
+		// Fleet-wide re-scan routes, which queue commands on the organization's agents
+		rescanHandler := handlers.NewRescanHandler(agentService, agentCommandService)
+		v2Rescan := v2.Group("/organizations/:id/rescan", auth, orgMember)
+		{
+			v2Rescan.POST("", rescanHandler.TriggerRescan)
+			v2Rescan.GET("/:batch_id", rescanHandler.GetRescanBatch)
+		}
+
+		// Agentless SSH/WinRM collection runs, bounded per organization
+		collectionHandler := handlers.NewCollectionHandler(collectionService)
+		v2Collections := v2.Group("/organizations/:id", auth, orgMember)
+		{
+			v2Collections.GET("/collection-settings", collectionHandler.GetSettings)
+			v2Collections.PUT("/collection-settings", collectionHandler.UpdateSettings)
+			v2Collections.POST("/collections", collectionHandler.StartCollection)
+			v2Collections.GET("/collections/:run_id", collectionHandler.GetCollection)
+		}
+
+		// Organization threat intelligence feeds, tagging and prioritizing findings
+		threatIntelHandler := handlers.NewThreatIntelHandler(threatIntelService)
+		v2ThreatIntel := v2.Group("/organizations/:id/threat-intel/feeds", auth, orgMember, userOnly)
+		{
+			v2ThreatIntel.GET("", threatIntelHandler.ListFeeds)
+			v2ThreatIntel.POST("", threatIntelHandler.CreateFeed)
+			v2ThreatIntel.PUT("/:feed_id", threatIntelHandler.UpdateFeed)
+			v2ThreatIntel.DELETE("/:feed_id", threatIntelHandler.DeleteFeed)
+			v2ThreatIntel.POST("/:feed_id/refresh", threatIntelHandler.RefreshFeed)
+		}
+
+		// Host group configuration baseline routes. Only the organization's
+		// members approve baselines, recorded as their updated_by.
+		configBaselineHandler := handlers.NewConfigBaselineHandler(configBaselineService)
+		v2Baselines := v2.Group("/organizations/:id/config-baselines")
+		{
+			v2Baselines.GET("", configBaselineHandler.ListBaselines)
+			v2Baselines.GET("/:group", configBaselineHandler.GetBaseline)
+			v2Baselines.PUT("/:group", auth, orgMember, configBaselineHandler.UpdateBaseline)
+			v2Baselines.POST("/:group/capture", auth, orgMember, configBaselineHandler.CaptureBaseline)
+		}
+
+		// Expected container findings, allowlisted per organization by its
+		// users, who are audited as the actors of their changes
+		containerAllowlistHandler := handlers.NewContainerAllowlistHandler(containerAllowlistService)
+		v2Allowlist := v2.Group("/organizations/:id/container-allowlist", auth, orgMember, userOnly)
+		{
+			v2Allowlist.GET("", containerAllowlistHandler.ListRules)
+			v2Allowlist.POST("", containerAllowlistHandler.CreateRule)
+			v2Allowlist.GET("/audit", containerAllowlistHandler.GetAuditLog)
+			v2Allowlist.PUT("/:rule_id", containerAllowlistHandler.UpdateRule)
+			v2Allowlist.DELETE("/:rule_id", containerAllowlistHandler.DeleteRule)
+		}
+
+		// Dependency license policies, and how each organization complies with them
+		licensePolicyHandler := handlers.NewLicensePolicyHandler(licensePolicyService)
+		v2Licenses := v2.Group("/organizations/:id/license-policies", auth, orgMember, userOnly)
+		{
+			v2Licenses.GET("", licensePolicyHandler.ListPolicies)
+			v2Licenses.POST("", licensePolicyHandler.CreatePolicy)
+			v2Licenses.GET("/summary", licensePolicyHandler.GetSummary)
+			v2Licenses.PUT("/:policy_id", licensePolicyHandler.UpdatePolicy)
+			v2Licenses.DELETE("/:policy_id", licensePolicyHandler.DeletePolicy)
+		}
+
+		// Accepted risks and false positives, suppressed per organization by
+		// rule and owned by the user who added it
+		suppressionHandler := handlers.NewSuppressionHandler(suppressionService)
+		v2Suppressions := v2.Group("/organizations/:id/suppressions", auth, orgMember, userOnly)
+		{
+			v2Suppressions.GET("", suppressionHandler.ListRules)
+			v2Suppressions.POST("", suppressionHandler.CreateRule)
+			v2Suppressions.DELETE("/:rule_id", suppressionHandler.DeleteRule)
+		}
+
+		// Network assets, deduplicated across agents with overlapping scans
+		v2.GET("/organizations/:id/network-assets", auth, orgMember, handlers.GetNetworkAssets(networkAssetService))
+
+		// Network topology graphs, exported to and imported from graph tools
+		v2.GET("/organizations/:id/topology", auth, orgMember, handlers.ExportNetworkTopology(networkTopologyService))
+		v2.POST("/topology/analyze", auth, handlers.AnalyzeNetworkTopology(networkTopologyService))
+
+		// Posture comparison between two hosts
+		v2.GET("/compare", auth, handlers.CompareHosts(hostComparisonService))
+
+		// Consolidated host risk, ranked per organization, and the criticality
+		// it is scaled by, set by the host's organization
+		v2.GET("/organizations/:id/host-risk", auth, orgMember, handlers.GetHostRiskRanking(hostRiskService))
+		v2.PUT("/agents/:id/criticality", auth, agentMember, handlers.SetAssetCriticality(hostRiskService))
+
+		// Agent tags, single and bulk by filter, and groups of agents selected
+		// by tag, all within the caller's organization
+		v2.GET("/agents/:id/tags", auth, agentMember, handlers.GetAgentTags(agentService))
+		v2.PATCH("/agents/:id/tags", auth, agentMember, handlers.UpdateAgentTags(agentService))
+		v2.POST("/agents/tags", auth, handlers.BulkTagAgents(agentService))
+		v2AgentGroups := v2.Group("/organizations/:id/agent-groups", auth, orgMember)
+		{
+			v2AgentGroups.GET("", handlers.ListAgentGroups(agentService))
+			v2AgentGroups.POST("", handlers.CreateAgentGroup(agentService))
+			v2AgentGroups.GET("/:group_id", handlers.GetAgentGroup(agentService))
+			v2AgentGroups.DELETE("/:group_id", handlers.DeleteAgentGroup(agentService))
+		}
+
+		// Agent releases, which agents update to on heartbeat. Every
+		// organization's agents install them, so only admins publish them.
+		agentReleaseHandler := handlers.NewAgentReleaseHandler(updateService)
+		adminOnly := middleware.RequireAdmin()
+		v2.GET("/agent-releases", agentReleaseHandler.ListReleases)
+		v2.POST("/agent-releases", auth, userOnly, adminOnly, agentReleaseHandler.PublishRelease)
+		v2.DELETE("/agent-releases/:release_id", auth, userOnly, adminOnly, agentReleaseHandler.DeleteRelease)
+
+		// Which scanners each agent runs, and on which paths. Only the
+		// agent's own organization changes them.
+		v2.GET("/agents/:id/scan-scope", handlers.GetScanScope(scanScopeService))
+		v2.PUT("/agents/:id/scan-scope", auth, agentMember, handlers.UpdateScanScope(scanScopeService))
+		v2.DELETE("/agents/:id/scan-scope", auth, agentMember, handlers.DeleteScanScope(scanScopeService))
+
+		// On-demand re-checks of a single finding by the agent that reported
+		// it, which command the caller's own agents
+		v2.POST("/findings/:id/verify", auth, handlers.VerifyFinding(findingVerificationService))
+		v2.GET("/findings/:id/verifications", auth, handlers.ListFindingVerifications(findingVerificationService))
+		v2.GET("/findings/:id/verifications/:verification_id", auth, handlers.GetFindingVerification(findingVerificationService))
+
+		// Manual overrides of the derived priority of the caller's own findings
+		v2.PUT("/findings/:id/priority", auth, handlers.SetFindingPriority(findingStateService))
+		v2.DELETE("/findings/:id/priority", auth, handlers.ClearFindingPriority(findingStateService))
+		// Suppressions change what alerts and may be notified at a callback
+		// URL, so they authenticate and act on the caller's own findings
+		v2.PUT("/findings/:id/suppression", auth, handlers.SuppressFinding(findingStateService))
+		v2.DELETE("/findings/:id/suppression", auth, handlers.UnsuppressFinding(findingStateService))
+
+		// Third-party scanners' reports, imported as the asset's findings. Unlike
+		// the rest of v2 this needs to know the organization, so it authenticates.
+		v2.POST("/import", auth, middleware.RequirePermission(models.APIKeyPermissionImportFindings), resultPayloadLimit, handlers.ImportScanReport(scanImportService, agentService, enrichmentService, processingScheduler, resultIngestionService, evidenceService, webhookService, licensePolicyService))
+
+		// Re-enrichment and re-ingestion backfills over stored findings, which
+		// only signed-in users start and stop
+		v2.POST("/jobs/backfill", auth, userOnly, handlers.CreateBackfillJob(backfillJobService))
+		v2.GET("/jobs/backfill", handlers.ListBackfillJobs(backfillJobService))
+		v2.GET("/jobs/backfill/:id", handlers.GetBackfillJob(backfillJobService))
+		v2.POST("/jobs/backfill/:id/cancel", auth, userOnly, handlers.CancelBackfillJob(backfillJobService))
+		v2.POST("/jobs/backfill/:id/resume", auth, userOnly, handlers.ResumeBackfillJob(backfillJobService))
+
+		// Remediation SLAs per compliance framework
+		complianceSLAHandler := handlers.NewComplianceSLAHandler(complianceSLAService)
+		v2SLA := v2.Group("/organizations/:id/compliance-sla/:framework")
+		{
+			v2SLA.GET("", complianceSLAHandler.GetDashboard)
+			v2SLA.GET("/policy", complianceSLAHandler.GetPolicy)
+			v2SLA.PUT("/policy", auth, orgMember, userOnly, complianceSLAHandler.UpdatePolicy)
+		}
 	}
 
 	// Enrollment routes (public - no auth required)
-	enrollment := router.Group("/api/enrollment")
+	enrollment := router.Group("/api/enrollment", rateLimit)
 	{
 		enrollment.POST("/enroll", handlers.EnrollAgent(enrollmentService))
 	}
@@ -325,22 +738,48 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 		// Note: Authentication is now handled by Clerk
 		// No custom auth routes needed - users authenticate via Clerk frontend
 
-		// Protected routes
+		// Protected routes, for Clerk users and API keys. API keys may only
+		// call routes requiring a permission they were granted.
 		protected := v1.Group("")
-		protected.Use(middleware.ClerkAuth())
+		protected.Use(auth, rateLimit)
 		{
+			readScans := middleware.RequirePermission(models.APIKeyPermissionReadScans)
+			writeScans := middleware.RequirePermission(models.APIKeyPermissionCreateScans)
+			readFindings := middleware.RequirePermission(models.APIKeyPermissionReadFindings)
+			userOnly := middleware.RequireUser()
+
 			// Scan routes
 			scans := protected.Group("/scans")
 			{
-				scans.GET("/", handlers.GetScans(scanService))
-				scans.POST("/", handlers.CreateScan(scanService))
-				scans.GET("/:id", handlers.GetScan(scanService))
-				scans.PUT("/:id", handlers.UpdateScan(scanService))
-				scans.DELETE("/:id", handlers.DeleteScan(scanService))
+				scans.GET("/", readScans, handlers.GetScans(scanService))
+				scans.POST("/", writeScans, handlers.CreateScan(scanService))
+				scans.GET("/:id", readScans, handlers.GetScan(scanService))
+				scans.GET("/:id/results", readScans, handlers.GetScanResults(scanService))
+				scans.GET("/:id/export", readScans, handlers.ExportScan(scanService))
+				scans.PUT("/:id", writeScans, handlers.UpdateScan(scanService))
+				scans.DELETE("/:id", writeScans, handlers.DeleteScan(scanService))
+			}
+
+			// Changes in an agent's vulnerabilities between two of its scans
+			protected.GET("/agents/:id/scans/diff", readScans, handlers.DiffAgentScans(scanService))
+
+			// Dashboard queries over agents, vulnerabilities, findings and
+			// analytics in one round trip
+			protected.GET("/graphql", readFindings, handlers.GraphQL(graphQLService))
+			protected.POST("/graphql", readFindings, handlers.GraphQL(graphQLService))
+
+			// API keys for service accounts, managed by users
+			apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+			apiKeys := protected.Group("/api-keys", userOnly)
+			{
+				apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+				apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+				apiKeys.POST("/:id/rotate", apiKeyHandler.RotateAPIKey)
+				apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
 			}
 
 			// Company routes
-			companies := protected.Group("/companies")
+			companies := protected.Group("/companies", userOnly)
 			{
 				companies.GET("/:id", handlers.GetCompany)
 				companies.PUT("/:id", handlers.UpdateCompany)
@@ -355,14 +794,31 @@ func setupRoutes(router *gin.Engine, db *repository.Database, scanService *servi
 			// }
 
 			// Dashboard routes
-			dashboard := protected.Group("/dashboard")
+			dashboard := protected.Group("/dashboard", userOnly)
 			{
 				dashboard.GET("/overview", handlers.GetDashboardOverview)
 				dashboard.GET("/trends", handlers.GetVulnerabilityTrends)
 			}
 
+			// Raw evidence attached to findings by scanners
+			evidenceHandler := handlers.NewEvidenceHandler(evidenceService)
+			protected.GET("/agents/:id/findings/:key/evidence", readFindings, evidenceHandler.ListFindingEvidence)
+			protected.GET("/evidence/:id", readFindings, evidenceHandler.GetEvidence)
+
+			// Webhooks notified of critical and high findings
+			webhookHandler := handlers.NewWebhookHandler(webhookService)
+			webhooks := protected.Group("/webhooks", userOnly)
+			{
+				webhooks.GET("", webhookHandler.ListWebhooks)
+				webhooks.POST("", webhookHandler.CreateWebhook)
+				webhooks.GET("/:id", webhookHandler.GetWebhook)
+				webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+				webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+				webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+			}
+
 			// Enrollment management routes (protected)
-			enrollment := protected.Group("/enrollment")
+			enrollment := protected.Group("/enrollment", userOnly)
 			{
 				enrollment.POST("/tokens", handlers.GenerateEnrollmentToken(enrollmentService))
 				enrollment.DELETE("/tokens/:id", handlers.RevokeEnrollmentToken(enrollmentService))
@@ -1,16 +1,21 @@
 package config
 
 import (
-	"os"
-	"strconv"
 	"time"
 )
 
 type Config struct {
 	// Server configuration
-	Port  int
-	Host  string
-	Debug bool
+	Port        int
+	Host        string
+	Debug       bool
+	MetricsAddr string // Address Prometheus /metrics is served on, apart from the API; empty disables it
+
+	// TLS and agent mutual TLS
+	TLSCertPath     string // Server certificate; the API serves HTTPS when set
+	TLSKeyPath      string
+	AgentMTLS       bool   // Require agents to present a client certificate issued by AgentCACertPath
+	AgentCACertPath string // PEM CA certificates agent client certificates must chain to
 
 	// Database configuration
 	DBHost     string
@@ -25,22 +30,43 @@ type Config struct {
 	RedisPort     int
 	RedisPassword string
 	RedisDB       int
+	RedisURL      string // Shared by API instances for rate limits and ETags, e.g. redis://:password@host:6379/0; in-process when empty
 
 	// JWT configuration (for Clerk)
 	ClerkJWTVerificationKey string
+	ClerkJWKSURL            string        // Clerk JWKS endpoint session tokens are verified against; the verification key is used when empty
+	ClerkJWKSRefresh        time.Duration // How often the JWKS is refetched; unknown key IDs refetch it early
+	ClerkJWTAudience        string        // Audience session tokens must carry; any when empty
+	ClerkTokenFailureTTL    time.Duration // How long a token that failed validation is rejected without being checked again
 	JWTExpiry               time.Duration
 
-	// Rate limiting
-	RateLimitRequests int
-	RateLimitWindow   time.Duration
+	// Rate limiting, per client and route group
+	RateLimitRequests          int           // Requests each client may make per window to most routes
+	RateLimitAgentRequests     int           // Same, for agent endpoints
+	RateLimitAnalyticsRequests int           // Same, for analytics endpoints
+	RateLimitWindow            time.Duration // Window every bucket refills over
+	RateLimitFailOpen          bool          // Allow requests while the shared rate limit store is unavailable, instead of rejecting them
+	ETagCacheTTL               time.Duration // How long a shared ETag answers conditional requests without running their handler
+	HeatmapCacheTTL            time.Duration // How long a computed risk heatmap is reused; 0 disables caching
 
 	// Logging
 	LogLevel  string
 	LogFormat string
 
 	// Enrichment service
-	EnrichmentServiceURL string
-	
+	EnrichmentServiceURL     string
+	EnrichmentMaxConcurrency int           // Most requests in flight to the enrichment service
+	EnrichmentBatchSize      int           // Most software items sent in one enrichment request
+	EPSSURL                  string        // FIRST EPSS API; EPSS lookups are off when empty
+	EPSSBatchSize            int           // Most CVEs looked up in one EPSS request
+	EPSSCacheTTL             time.Duration // How long a CVE's EPSS score, or its absence, is reused
+
+	// Organization threat intelligence feeds
+	ThreatIntelCheckInterval time.Duration // How often feeds due a refresh are fetched
+	ThreatIntelFetchTimeout  time.Duration // Longest one feed fetch may take
+	ThreatIntelMinRefresh    time.Duration // Smallest refresh interval a feed may configure
+	ThreatIntelMaxStaleness  time.Duration // How long a feed that keeps failing still has its cached indicators applied
+
 	// AI service (same as enrichment service for now)
 	AIServiceURL string
 
@@ -51,82 +77,282 @@ type Config struct {
 	ConfigAuditorWorkerCount     int
 	ConfigAuditorQueueBufferSize int
 	ConfigAuditorStoragePath     string
+	ConfigFileStore              string // db, file or s3
+	ConfigFileS3Endpoint         string
+	ConfigFileS3Region           string
+	ConfigFileS3Bucket           string
+	ConfigFileS3AccessKeyID      string
+	ConfigFileS3SecretKey        string
+	ConfigFileS3PathStyle        bool
+	ConfigUploadChunkSize        int           // Size of every chunk of a chunked config upload but its last
+	ConfigUploadTimeout          time.Duration // How long a chunked config upload may go without a chunk before it is dropped
+	ConfigRulesPath              string        // YAML rules file or directory for config analysis; empty for none
+	ConfigRulesReloadInterval    time.Duration // How often the config rules are checked for changes
+
+	// Multi-tenant processing fairness
+	ProcessingMaxConcurrency int            // Total concurrent ingestion/job slots across all orgs
+	OrgMaxConcurrency        int            // Default per-org concurrency cap
+	OrgConcurrencyOverrides  map[string]int // Per-org cap overrides (org_id=cap)
+	OrgSchedulingWeights     map[string]int // Per-org round-robin weights (org_id=weight)
+
+	// Scheduled data export to customer object storage
+	DataExportCheckInterval time.Duration // How often to look for due exports
+	DataExportMinFrequency  time.Duration // Smallest export frequency an org may configure
+
+	// Key credentials stored in the database are encrypted with
+	SecretEncryptionKey string
+
+	// Flapping detection
+	FlappingThreshold int           // Open/resolved toggles within the window before a finding is flapping
+	FlappingWindow    time.Duration // Correlation window for counting toggles
+
+	// Resolved finding retention
+	FindingRetentionDays     map[string]int // Severity -> days a resolved finding is kept; unset severities use the defaults
+	FindingRetentionInterval time.Duration  // How often expired findings are purged
+
+	// Re-enrichment and re-ingestion backfills
+	BackfillBatchSize   int // Rows a backfill job processes and commits at a time
+	BackfillConcurrency int // Most enrichment requests in flight for one re-enrichment job
+
+	// Agent commands
+	AgentCommandTTL time.Duration // How long an agent has to finish a command before it counts as failed
+
+	// Agents not heard from within this are degraded, and offline after three times as long
+	AgentHeartbeatTimeout time.Duration
+
+	// Agentless collection defaults, for organizations without their own settings
+	CollectionMaxConcurrency    int           // Hosts one run collects from at once
+	CollectionHostTimeout       time.Duration // Longest one host's connection and collection may take
+	CollectionAuthFailureBudget int           // Authentication failures after which a run pauses
+
+	// Network asset deduplication
+	NetworkAssetDedup string // identity, ip_mac, ip or none
+
+	// Request size limits
+	MaxRequestBodySize   int // Largest request body accepted on any route, in bytes
+	MaxResultPayloadSize int // Largest single agent result submission, in bytes; bigger scans must be split
+
+	// Split result submissions
+	ResultBatchTimeout   time.Duration // How long a batch may go without a chunk before it is dropped
+	ResultBatchMaxChunks int           // Most chunks one batch may be split into
+
+	// Finding evidence storage
+	EvidenceStore         string // file or s3
+	EvidenceStoragePath   string // Directory for the file store
+	EvidenceS3Endpoint    string
+	EvidenceS3Region      string
+	EvidenceS3Bucket      string
+	EvidenceS3AccessKeyID string
+	EvidenceS3SecretKey   string
+	EvidenceS3PathStyle   bool
+	EvidenceMaxSize       int // Largest evidence blob accepted, in bytes
+	EvidenceMaxPerFinding int // Most evidence blobs kept from one finding in one scan
+
+	// Export quotas and async exports
+	ExportQuotaLimit     int            // Exports each organization may start per quota window
+	ExportQuotaWindow    time.Duration  // Sliding window export quotas are counted over
+	ExportQuotaOverrides map[string]int // Per-organization quota limits
+	ExportStoragePath    string         // Directory async exports are written to
+	ExportRetention      time.Duration  // How long a finished async export stays downloadable
+	ExportPublicURL      string         // Base URL of export download links; relative when empty
+
+	// Outgoing events
+	EventSchemaValidation bool // Check outgoing events against their published schema before sending
+
+	// Webhooks
+	WebhookCheckInterval time.Duration // How often deliveries due a retry are sent again
+	WebhookTimeout       time.Duration // Longest one delivery attempt may take
+	WebhookMaxAttempts   int           // Attempts before a delivery is given up on
+	DashboardURL         string        // Base URL of the web dashboard, for links in chat alerts
+
+	// Database compression of large JSON columns
+	DBCompression          bool // Write scan results and result chunks gzip-compressed
+	DBCompressionThreshold int  // Smallest encoded value compressed, in bytes
+
+	// GraphQL query limits
+	GraphQLMaxDepth      int // Deepest a GraphQL query may nest fields
+	GraphQLMaxComplexity int // Highest complexity score a GraphQL query may have
+
+	settings   []Setting // Every value with its source, for --print-config
+	loadErrors []error   // Values that failed to parse and fell back to their defaults
 }
 
+// Load reads the configuration from the environment, falling back to defaults.
+// Values that fail to parse fall back to their defaults and are reported by Validate.
 func Load() *Config {
-	return &Config{
+	l := &loader{}
+	enrichmentURL := l.String("ENRICHMENT_SERVICE_URL", "http://localhost:8000", "Enrichment service base URL")
+
+	cfg := &Config{
 		// Server
-		Port:  getEnvAsInt("API_PORT", 8080),
-		Host:  getEnv("API_HOST", "0.0.0.0"),
-		Debug: getEnvAsBool("API_MODE", "debug"),
+		Port:        l.Int("API_PORT", 8080, "Port the API listens on"),
+		Host:        l.String("API_HOST", "0.0.0.0", "Address the API binds to"),
+		Debug:       l.Bool("API_MODE", "debug", "debug or release; release requires a Clerk key"),
+		MetricsAddr: l.String("METRICS_ADDR", "127.0.0.1:9090", "Address Prometheus /metrics is served on, apart from the API; empty disables it"),
+
+		// TLS and agent mutual TLS
+		TLSCertPath:     l.String("TLS_CERT_PATH", "", "Server certificate; the API serves HTTPS when set"),
+		TLSKeyPath:      l.String("TLS_KEY_PATH", "", "Private key of TLS_CERT_PATH"),
+		AgentMTLS:       l.Bool("AGENT_MTLS_ENABLED", "false", "Require agents to present a client certificate whose CN is their agent ID"),
+		AgentCACertPath: l.String("AGENT_CA_CERT_PATH", "", "PEM CA certificates agent client certificates must chain to"),
 
 		// Database
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnvAsInt("DB_PORT", 5432),
-		DBName:     getEnv("DB_NAME", "zerotrace"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", ""),
-		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
+		DBHost:     l.String("DB_HOST", "localhost", "PostgreSQL host"),
+		DBPort:     l.Int("DB_PORT", 5432, "PostgreSQL port"),
+		DBName:     l.String("DB_NAME", "zerotrace", "PostgreSQL database name"),
+		DBUser:     l.String("DB_USER", "postgres", "PostgreSQL user"),
+		DBPassword: l.Secret("DB_PASSWORD", "", "PostgreSQL password"),
+		DBSSLMode:  l.String("DB_SSL_MODE", "disable", "PostgreSQL sslmode"),
 
 		// Redis
-		RedisHost:     getEnv("REDIS_HOST", "localhost"),
-		RedisPort:     getEnvAsInt("REDIS_PORT", 6379),
-		RedisPassword: getEnv("REDIS_PASSWORD", ""),
-		RedisDB:       getEnvAsInt("REDIS_DB", 0),
+		RedisHost:     l.String("REDIS_HOST", "localhost", "Redis host"),
+		RedisPort:     l.Int("REDIS_PORT", 6379, "Redis port"),
+		RedisPassword: l.Secret("REDIS_PASSWORD", "", "Redis password"),
+		RedisDB:       l.Int("REDIS_DB", 0, "Redis database number"),
+		RedisURL:      l.Secret("REDIS_URL", "", "Redis shared by API instances for rate limits and ETags, e.g. redis://:password@host:6379/0; in-process when empty"),
 
 		// JWT (for Clerk) - no default in production
-		ClerkJWTVerificationKey: getEnv("CLERK_JWT_VERIFICATION_KEY", ""),
-		JWTExpiry:               getEnvAsDuration("JWT_EXPIRY", "24h"),
+		ClerkJWTVerificationKey: l.Secret("CLERK_JWT_VERIFICATION_KEY", "", "Clerk JWT verification key; release mode requires it or CLERK_JWKS_URL"),
+		ClerkJWKSURL:            l.String("CLERK_JWKS_URL", "", "Clerk JWKS endpoint, e.g. https://<frontend-api>/.well-known/jwks.json; tokens are verified against its keys instead of CLERK_JWT_VERIFICATION_KEY"),
+		ClerkJWKSRefresh:        l.Duration("CLERK_JWKS_REFRESH_INTERVAL", "1h", "How often Clerk's signing keys are refetched; a token signed by an unknown key refetches them early"),
+		ClerkJWTAudience:        l.String("CLERK_JWT_AUDIENCE", "", "Audience Clerk session tokens must carry; any when empty"),
+		ClerkTokenFailureTTL:    l.Duration("CLERK_TOKEN_FAILURE_TTL", "30s", "How long a token that failed validation is rejected without being checked again; 0 disables"),
+		JWTExpiry:               l.Duration("JWT_EXPIRY", "24h", "JWT lifetime"),
 
-		// Rate limiting
-		RateLimitRequests: getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
-		RateLimitWindow:   getEnvAsDuration("RATE_LIMIT_WINDOW", "1m"),
+		// Rate limiting, per client and route group
+		RateLimitRequests:          l.Int("RATE_LIMIT_REQUESTS", 100, "Requests each client may make per rate limit window"),
+		RateLimitAgentRequests:     l.Int("RATE_LIMIT_AGENT_REQUESTS", 1000, "Requests each client may make to agent endpoints per rate limit window"),
+		RateLimitAnalyticsRequests: l.Int("RATE_LIMIT_ANALYTICS_REQUESTS", 30, "Requests each client may make to analytics endpoints per rate limit window"),
+		RateLimitWindow:            l.Duration("RATE_LIMIT_WINDOW", "1m", "Rate limit window"),
+		RateLimitFailOpen:          l.Bool("RATE_LIMIT_FAIL_OPEN", "false", "Allow requests while Redis is unavailable instead of rejecting them with 503"),
+		ETagCacheTTL:               l.Duration("ETAG_CACHE_TTL", "30s", "How long a response ETag shared through Redis answers conditional requests"),
+		HeatmapCacheTTL:            l.Duration("HEATMAP_CACHE_TTL", "5m", "How long a computed risk heatmap is reused until new scan results arrive for its organization; 0 disables"),
 
 		// Logging
-		LogLevel:  getEnv("LOG_LEVEL", "info"),
-		LogFormat: getEnv("LOG_FORMAT", "json"),
+		LogLevel:  l.String("LOG_LEVEL", "info", "debug, info, warn or error"),
+		LogFormat: l.String("LOG_FORMAT", "json", "json or text"),
 
 		// Enrichment service
-		EnrichmentServiceURL: getEnv("ENRICHMENT_SERVICE_URL", "http://localhost:8000"),
-		
+		EnrichmentServiceURL:     enrichmentURL,
+		EnrichmentMaxConcurrency: l.Int("ENRICHMENT_MAX_CONCURRENCY", 4, "Most requests in flight to the enrichment service"),
+		EnrichmentBatchSize:      l.Int("ENRICHMENT_BATCH_SIZE", 100, "Most software items sent in one enrichment request"),
+		EPSSURL:                  l.String("EPSS_API_URL", "https://api.first.org/data/v1/epss", "FIRST EPSS API findings' exploit probability is looked up from; empty disables EPSS"),
+		EPSSBatchSize:            l.Int("EPSS_BATCH_SIZE", 100, "Most CVEs looked up in one EPSS request"),
+		EPSSCacheTTL:             l.Duration("EPSS_CACHE_TTL", "24h", "How long a CVE's EPSS score is reused before it is looked up again"),
+
+		// Organization threat intelligence feeds
+		ThreatIntelCheckInterval: l.Duration("THREAT_INTEL_CHECK_INTERVAL", "1m", "How often threat intel feeds due a refresh are fetched"),
+		ThreatIntelFetchTimeout:  l.Duration("THREAT_INTEL_FETCH_TIMEOUT", "60s", "Longest one threat intel feed fetch may take"),
+		ThreatIntelMinRefresh:    l.Duration("THREAT_INTEL_MIN_REFRESH", "15m", "Smallest refresh interval a threat intel feed may configure"),
+		ThreatIntelMaxStaleness:  l.Duration("THREAT_INTEL_MAX_STALENESS", "168h", "How long a failing feed's cached indicators are still applied"),
+
 		// AI service (defaults to enrichment service URL)
-		AIServiceURL: getEnv("AI_SERVICE_URL", getEnv("ENRICHMENT_SERVICE_URL", "http://localhost:8000")),
+		AIServiceURL: l.String("AI_SERVICE_URL", enrichmentURL, "AI service base URL; defaults to the enrichment service"),
 
 		// Config Auditor configuration
-		ConfigAuditorMaxFileSize:     getEnvAsInt("CONFIG_AUDITOR_MAX_FILE_SIZE", 10*1024*1024), // 10MB
-		ConfigAuditorDefaultPageSize:  getEnvAsInt("CONFIG_AUDITOR_DEFAULT_PAGE_SIZE", 20),
-		ConfigAuditorMaxPageSize:      getEnvAsInt("CONFIG_AUDITOR_MAX_PAGE_SIZE", 100),
-		ConfigAuditorWorkerCount:      getEnvAsInt("CONFIG_AUDITOR_WORKER_COUNT", 3),
-		ConfigAuditorQueueBufferSize:  getEnvAsInt("CONFIG_AUDITOR_QUEUE_BUFFER_SIZE", 100),
-		ConfigAuditorStoragePath:      getEnv("CONFIG_AUDITOR_STORAGE_PATH", "configs"),
-	}
-}
+		ConfigAuditorMaxFileSize:     l.Int("CONFIG_AUDITOR_MAX_FILE_SIZE", 10*1024*1024, "Largest config file accepted, in bytes"),
+		ConfigAuditorDefaultPageSize: l.Int("CONFIG_AUDITOR_DEFAULT_PAGE_SIZE", 20, "Default page size for config auditor listings"),
+		ConfigAuditorMaxPageSize:     l.Int("CONFIG_AUDITOR_MAX_PAGE_SIZE", 100, "Largest page size for config auditor listings"),
+		ConfigAuditorWorkerCount:     l.Int("CONFIG_AUDITOR_WORKER_COUNT", 3, "Config analysis workers"),
+		ConfigAuditorQueueBufferSize: l.Int("CONFIG_AUDITOR_QUEUE_BUFFER_SIZE", 100, "Config analysis queue size"),
+		ConfigAuditorStoragePath:     l.String("CONFIG_AUDITOR_STORAGE_PATH", "configs", "Directory uploaded config files are stored in when CONFIG_FILE_STORE=file, and chunked uploads when it is db"),
+		ConfigFileStore:              l.String("CONFIG_FILE_STORE", "db", "Where uploaded config file content is kept: db, file or s3"),
+		ConfigFileS3Endpoint:         l.String("CONFIG_FILE_S3_ENDPOINT", "", "S3-compatible endpoint for config files; defaults to AWS"),
+		ConfigFileS3Region:           l.String("CONFIG_FILE_S3_REGION", "us-east-1", "Region of the config file bucket"),
+		ConfigFileS3Bucket:           l.String("CONFIG_FILE_S3_BUCKET", "", "Bucket config files are stored in when CONFIG_FILE_STORE=s3"),
+		ConfigFileS3AccessKeyID:      l.String("CONFIG_FILE_S3_ACCESS_KEY_ID", "", "Access key for the config file bucket"),
+		ConfigFileS3SecretKey:        l.Secret("CONFIG_FILE_S3_SECRET_ACCESS_KEY", "", "Secret key for the config file bucket"),
+		ConfigFileS3PathStyle:        l.Bool("CONFIG_FILE_S3_PATH_STYLE", "false", "Use path-style bucket URLs (most non-AWS S3 implementations)"),
+		ConfigUploadChunkSize:        l.Int("CONFIG_UPLOAD_CHUNK_SIZE", 1024*1024, "Size of the chunks config files are uploaded in when chunked, in bytes"),
+		ConfigUploadTimeout:          l.Duration("CONFIG_UPLOAD_TIMEOUT", "24h", "How long a chunked config upload may go without a chunk before it is dropped"),
+		ConfigRulesPath:              l.String("CONFIG_RULES_PATH", "", "YAML file, or directory of YAML files, of custom config analysis rules"),
+		ConfigRulesReloadInterval:    l.Duration("CONFIG_RULES_RELOAD_INTERVAL", "30s", "How often the config rules are checked for changes and reloaded"),
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+		// Multi-tenant processing fairness
+		ProcessingMaxConcurrency: l.Int("PROCESSING_MAX_CONCURRENCY", 20, "Concurrent processing slots across all organizations"),
+		OrgMaxConcurrency:        l.Int("ORG_MAX_CONCURRENCY", 4, "Default processing slots per organization"),
+		OrgConcurrencyOverrides:  l.IntMap("ORG_CONCURRENCY_OVERRIDES", "Per-organization slot caps (org_id=cap,...)"),
+		OrgSchedulingWeights:     l.IntMap("ORG_SCHEDULING_WEIGHTS", "Per-organization scheduling weights (org_id=weight,...)"),
 
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
-}
+		// Scheduled data export
+		DataExportCheckInterval: l.Duration("DATA_EXPORT_CHECK_INTERVAL", "1m", "How often to look for due exports"),
+		DataExportMinFrequency:  l.Duration("DATA_EXPORT_MIN_FREQUENCY", "1h", "Smallest export frequency an organization may configure"),
 
-func getEnvAsBool(key, defaultValue string) bool {
-	value := getEnv(key, defaultValue)
-	return value == "true" || value == "debug"
-}
+		// Encryption of stored credentials
+		SecretEncryptionKey: l.Secret("SECRET_ENCRYPTION_KEY", "", "32-byte key, hex or base64, stored credentials such as export bucket secrets are encrypted with"),
+
+		// Flapping detection
+		FlappingThreshold: l.Int("FLAPPING_THRESHOLD", 3, "Open/resolved toggles within the window before a finding is flapping"),
+		FlappingWindow:    l.Duration("FLAPPING_WINDOW", "24h", "Correlation window for flapping detection"),
+
+		// Resolved finding retention
+		FindingRetentionDays:     l.IntMap("FINDING_RETENTION_DAYS", "Days resolved findings are kept per severity (critical=730,info=30,...)"),
+		FindingRetentionInterval: l.Duration("FINDING_RETENTION_INTERVAL", "24h", "How often resolved findings past their retention are purged"),
+
+		// Re-enrichment and re-ingestion backfills
+		BackfillBatchSize:   l.Int("BACKFILL_BATCH_SIZE", 500, "Default rows a backfill job processes and commits at a time"),
+		BackfillConcurrency: l.Int("BACKFILL_CONCURRENCY", 2, "Default most enrichment requests in flight for one re-enrichment job"),
 
-func getEnvAsDuration(key, defaultValue string) time.Duration {
-	value := getEnv(key, defaultValue)
-	if duration, err := time.ParseDuration(value); err == nil {
-		return duration
+		// Agent commands
+		AgentCommandTTL: l.Duration("AGENT_COMMAND_TTL", "2h", "How long an agent has to finish a command"),
+
+		AgentHeartbeatTimeout: l.Duration("AGENT_HEARTBEAT_TIMEOUT", "90s", "How long an agent may go without a heartbeat before it is degraded"),
+
+		// Agentless collection defaults
+		CollectionMaxConcurrency:    l.Int("COLLECTION_MAX_CONCURRENCY", 10, "Default hosts an agentless collection run collects from at once"),
+		CollectionHostTimeout:       l.Duration("COLLECTION_HOST_TIMEOUT", "30s", "Default longest one host's collection may take"),
+		CollectionAuthFailureBudget: l.Int("COLLECTION_AUTH_FAILURE_BUDGET", 5, "Default authentication failures after which a collection run pauses"),
+
+		// Network asset deduplication
+		NetworkAssetDedup: l.String("NETWORK_ASSET_DEDUP", "identity", "identity, ip_mac, ip or none"),
+
+		// Request size limits
+		MaxRequestBodySize:   l.Int("MAX_REQUEST_BODY_SIZE", 10*1024*1024, "Largest request body accepted, in bytes"),
+		MaxResultPayloadSize: l.Int("MAX_RESULT_PAYLOAD_SIZE", 5*1024*1024, "Largest agent result submission, in bytes"),
+
+		// Split result submissions
+		ResultBatchTimeout:   l.Duration("RESULT_BATCH_TIMEOUT", "30m", "How long a split result batch may go without a chunk before it is dropped"),
+		ResultBatchMaxChunks: l.Int("RESULT_BATCH_MAX_CHUNKS", 1000, "Most chunks one result batch may be split into"),
+
+		// Finding evidence storage
+		EvidenceStore:         l.String("EVIDENCE_STORE", "file", "Where finding evidence is kept: file or s3"),
+		EvidenceStoragePath:   l.String("EVIDENCE_STORAGE_PATH", "evidence", "Directory finding evidence is stored in when EVIDENCE_STORE=file"),
+		EvidenceS3Endpoint:    l.String("EVIDENCE_S3_ENDPOINT", "", "S3-compatible endpoint for evidence; defaults to AWS"),
+		EvidenceS3Region:      l.String("EVIDENCE_S3_REGION", "us-east-1", "Region of the evidence bucket"),
+		EvidenceS3Bucket:      l.String("EVIDENCE_S3_BUCKET", "", "Bucket finding evidence is stored in when EVIDENCE_STORE=s3"),
+		EvidenceS3AccessKeyID: l.String("EVIDENCE_S3_ACCESS_KEY_ID", "", "Access key for the evidence bucket"),
+		EvidenceS3SecretKey:   l.Secret("EVIDENCE_S3_SECRET_ACCESS_KEY", "", "Secret key for the evidence bucket"),
+		EvidenceS3PathStyle:   l.Bool("EVIDENCE_S3_PATH_STYLE", "false", "Use path-style bucket URLs (most non-AWS S3 implementations)"),
+		EvidenceMaxSize:       l.Int("EVIDENCE_MAX_SIZE", 256*1024, "Largest evidence blob accepted, in bytes"),
+		EvidenceMaxPerFinding: l.Int("EVIDENCE_MAX_PER_FINDING", 5, "Most evidence blobs kept from one finding per scan"),
+
+		// Export quotas and async exports
+		ExportQuotaLimit:     l.Int("EXPORT_QUOTA_LIMIT", 10, "Exports each organization may start per quota window"),
+		ExportQuotaWindow:    l.Duration("EXPORT_QUOTA_WINDOW", "1h", "Sliding window export quotas are counted over"),
+		ExportQuotaOverrides: l.IntMap("EXPORT_QUOTA_OVERRIDES", "Per-organization export quota limits (org_id=limit,...)"),
+		ExportStoragePath:    l.String("EXPORT_STORAGE_PATH", "exports", "Directory async exports are written to"),
+		ExportRetention:      l.Duration("EXPORT_RETENTION", "24h", "How long a finished async export stays downloadable"),
+		ExportPublicURL:      l.String("EXPORT_PUBLIC_URL", "", "Base URL of export download links, e.g. https://api.example.com; relative when empty"),
+
+		// Outgoing events
+		EventSchemaValidation: l.Bool("EVENT_SCHEMA_VALIDATION", "true", "Check outgoing events against their published schema and drop those that do not match"),
+
+		// Webhooks
+		WebhookCheckInterval: l.Duration("WEBHOOK_CHECK_INTERVAL", "30s", "How often webhook deliveries due a retry are sent again"),
+		WebhookTimeout:       l.Duration("WEBHOOK_TIMEOUT", "10s", "Longest one webhook delivery attempt may take"),
+		WebhookMaxAttempts:   l.Int("WEBHOOK_MAX_ATTEMPTS", 6, "Attempts before a webhook delivery is given up on"),
+		DashboardURL:         l.String("DASHBOARD_URL", "", "Base URL of the web dashboard, e.g. https://app.example.com, linked from Slack and Teams alerts"),
+
+		// Database compression of large JSON columns
+		DBCompression:          l.Bool("DB_COMPRESSION", "false", "Store large scan result columns gzip-compressed"),
+		DBCompressionThreshold: l.Int("DB_COMPRESSION_THRESHOLD", 1024, "Smallest encoded JSON value compressed, in bytes"),
+
+		// GraphQL query limits
+		GraphQLMaxDepth:      l.Int("GRAPHQL_MAX_DEPTH", 8, "Deepest a GraphQL query may nest fields"),
+		GraphQLMaxComplexity: l.Int("GRAPHQL_MAX_COMPLEXITY", 100000, "Highest complexity score a GraphQL query may have; list fields count once per item they may return"),
 	}
-	return 24 * time.Hour // Default to 24 hours
+
+	cfg.settings = l.settings
+	cfg.loadErrors = l.errs
+	return cfg
 }
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadReportsSourcesAndValidationErrors(t *testing.T) {
+	t.Setenv("API_MODE", "release")
+	t.Setenv("API_PORT", "not-a-port")
+	t.Setenv("REDIS_PASSWORD", "hunter2")
+	t.Setenv("ORG_MAX_CONCURRENCY", "50")
+
+	cfg := Load()
+
+	settings := make(map[string]Setting)
+	for _, s := range cfg.Settings() {
+		settings[s.Key] = s
+	}
+	if got := settings["API_PORT"]; got.Source != SourceDefault || got.Value != "8080" {
+		t.Errorf("unparseable API_PORT should fall back to the default, got %+v", got)
+	}
+	if got := settings["REDIS_PASSWORD"]; got.Source != SourceEnv || got.Value != "********" {
+		t.Errorf("REDIS_PASSWORD should be redacted and come from env, got %+v", got)
+	}
+	if got := settings["DB_HOST"]; got.Source != SourceDefault {
+		t.Errorf("DB_HOST should come from defaults, got %+v", got)
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"API_PORT", "ORG_MAX_CONCURRENCY", "CLERK_JWT_VERIFICATION_KEY or CLERK_JWKS_URL is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("validation error should mention %s, got: %v", want, err)
+		}
+	}
+}
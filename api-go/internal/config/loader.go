@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Source says where a configuration value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+)
+
+// Setting describes one configuration value as it was resolved
+type Setting struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"` // Redacted for secrets
+	Source      Source `json:"source"`
+	Description string `json:"description"`
+	Secret      bool   `json:"secret,omitempty"`
+}
+
+// fileKeys holds the variables that LoadFile read from an env file, so
+// settings can report them as coming from the file rather than the environment
+var fileKeys = map[string]bool{}
+
+// LoadFile loads variables from an env file into the environment without
+// overriding variables that are already set
+func LoadFile(path string) error {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		fileKeys[key] = true
+	}
+	return nil
+}
+
+// loader reads typed values from the environment, recording each value's
+// source and any value that failed to parse
+type loader struct {
+	settings []Setting
+	errs     []error
+}
+
+// lookup returns a variable's raw value and source
+func (l *loader) lookup(key string) (string, Source) {
+	value := os.Getenv(key)
+	if value == "" {
+		return "", SourceDefault
+	}
+	if fileKeys[key] {
+		return value, SourceFile
+	}
+	return value, SourceEnv
+}
+
+func (l *loader) record(key string, value interface{}, source Source, description string, secret bool) {
+	display := fmt.Sprint(value)
+	if secret && display != "" {
+		display = "********"
+	}
+	l.settings = append(l.settings, Setting{
+		Key:         key,
+		Value:       display,
+		Source:      source,
+		Description: description,
+		Secret:      secret,
+	})
+}
+
+func (l *loader) invalid(key, raw, kind string) {
+	l.errs = append(l.errs, fmt.Errorf("%s: %q is not a valid %s", key, raw, kind))
+}
+
+func (l *loader) String(key, defaultValue, description string) string {
+	raw, source := l.lookup(key)
+	if source == SourceDefault {
+		raw = defaultValue
+	}
+	l.record(key, raw, source, description, false)
+	return raw
+}
+
+// Secret is like String but the value is redacted when printed
+func (l *loader) Secret(key, defaultValue, description string) string {
+	raw, source := l.lookup(key)
+	if source == SourceDefault {
+		raw = defaultValue
+	}
+	l.record(key, raw, source, description, true)
+	return raw
+}
+
+func (l *loader) Int(key string, defaultValue int, description string) int {
+	raw, source := l.lookup(key)
+	value := defaultValue
+	if source != SourceDefault {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			l.invalid(key, raw, "integer")
+			source = SourceDefault
+		} else {
+			value = parsed
+		}
+	}
+	l.record(key, value, source, description, false)
+	return value
+}
+
+func (l *loader) Duration(key, defaultValue, description string) time.Duration {
+	raw, source := l.lookup(key)
+	value, _ := time.ParseDuration(defaultValue)
+	if source != SourceDefault {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			l.invalid(key, raw, "duration")
+			source = SourceDefault
+		} else {
+			value = parsed
+		}
+	}
+	l.record(key, value, source, description, false)
+	return value
+}
+
+// Bool treats "true" and "debug" as true, matching API_MODE's historical values
+func (l *loader) Bool(key, defaultValue, description string) bool {
+	raw, source := l.lookup(key)
+	if source == SourceDefault {
+		raw = defaultValue
+	}
+	value := raw == "true" || raw == "debug"
+	l.record(key, raw, source, description, false)
+	return value
+}
+
+// IntMap parses a comma-separated list of key=value pairs, e.g. "org-a=8,org-b=2"
+func (l *loader) IntMap(key, description string) map[string]int {
+	raw, source := l.lookup(key)
+	result := make(map[string]int)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, found := strings.Cut(pair, "=")
+		intValue, err := strconv.Atoi(strings.TrimSpace(v))
+		if !found || strings.TrimSpace(k) == "" || err != nil {
+			l.invalid(key, pair, "key=integer pair")
+			continue
+		}
+		result[strings.TrimSpace(k)] = intValue
+	}
+	l.record(key, raw, source, description, false)
+	return result
+}
+
+// Settings returns every configuration value with its source, in load order
+func (c *Config) Settings() []Setting {
+	return c.settings
+}
+
+// PrintSettings writes the effective configuration as a table, secrets redacted
+func (c *Config) PrintSettings(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE\tDESCRIPTION")
+	for _, s := range c.settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Key, s.Value, s.Source, s.Description)
+	}
+	return tw.Flush()
+}
+
+// sortedKeys returns a map's keys in order, for stable error messages
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,39 +1,226 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+
+	"zerotrace/api/internal/secrets"
+
+	"github.com/redis/go-redis/v9"
 )
 
-// Validate checks that required configuration is present
+// Validate checks every configuration value and reports all problems at once
 func (c *Config) Validate() error {
-	// In production (non-debug mode), require Clerk JWT key
-	if !c.Debug {
-		if c.ClerkJWTVerificationKey == "" {
-			return fmt.Errorf("CLERK_JWT_VERIFICATION_KEY is required in production mode")
-		}
-		if c.ClerkJWTVerificationKey == "dev-clerk-key-change-in-production" || c.ClerkJWTVerificationKey == "development-key" {
-			return fmt.Errorf("CLERK_JWT_VERIFICATION_KEY must not use development default in production")
+	errs := append([]error(nil), c.loadErrors...)
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
 		}
 	}
 
-	// Validate database configuration
-	if c.DBHost == "" {
-		return fmt.Errorf("DB_HOST is required")
+	// In production (non-debug mode), require Clerk JWT key or JWKS
+	if !c.Debug {
+		check(c.ClerkJWTVerificationKey != "" || c.ClerkJWKSURL != "", "CLERK_JWT_VERIFICATION_KEY or CLERK_JWKS_URL is required in production mode")
+		check(c.ClerkJWTVerificationKey != "dev-clerk-key-change-in-production" && c.ClerkJWTVerificationKey != "development-key",
+			"CLERK_JWT_VERIFICATION_KEY must not use development default in production")
+	}
+	if c.ClerkJWKSURL != "" {
+		u, err := url.Parse(c.ClerkJWKSURL)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "", "CLERK_JWKS_URL must be an http(s) URL, got %q", c.ClerkJWKSURL)
+		check(c.ClerkJWKSRefresh > 0, "CLERK_JWKS_REFRESH_INTERVAL must be positive")
 	}
-	if c.DBName == "" {
-		return fmt.Errorf("DB_NAME is required")
+	check(c.ClerkTokenFailureTTL >= 0, "CLERK_TOKEN_FAILURE_TTL must not be negative")
+
+	// Server
+	check(validPort(c.Port), "API_PORT must be between 1 and 65535, got %d", c.Port)
+	if c.MetricsAddr != "" {
+		_, port, err := net.SplitHostPort(c.MetricsAddr)
+		portNum, _ := strconv.Atoi(port)
+		check(err == nil && validPort(portNum), "METRICS_ADDR must be a host:port, got %q", c.MetricsAddr)
 	}
-	if c.DBUser == "" {
-		return fmt.Errorf("DB_USER is required")
+	check((c.TLSCertPath == "") == (c.TLSKeyPath == ""), "TLS_CERT_PATH and TLS_KEY_PATH must be set together")
+	if c.AgentMTLS {
+		// Client certificates are only presented over TLS the API terminates
+		check(c.TLSCertPath != "", "TLS_CERT_PATH and TLS_KEY_PATH are required when AGENT_MTLS_ENABLED is set")
+		check(c.AgentCACertPath != "", "AGENT_CA_CERT_PATH is required when AGENT_MTLS_ENABLED is set")
 	}
 
+	// Database
+	check(c.DBHost != "", "DB_HOST is required")
+	check(c.DBName != "", "DB_NAME is required")
+	check(c.DBUser != "", "DB_USER is required")
+	check(validPort(c.DBPort), "DB_PORT must be between 1 and 65535, got %d", c.DBPort)
+	check(oneOf(c.DBSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		"DB_SSL_MODE must be one of disable, allow, prefer, require, verify-ca or verify-full, got %q", c.DBSSLMode)
+
+	// Redis
+	check(validPort(c.RedisPort), "REDIS_PORT must be between 1 and 65535, got %d", c.RedisPort)
+	check(c.RedisDB >= 0 && c.RedisDB <= 15, "REDIS_DB must be between 0 and 15, got %d", c.RedisDB)
+
 	// Validate enrichment service URL
-	if c.EnrichmentServiceURL == "" {
-		return fmt.Errorf("ENRICHMENT_SERVICE_URL is required")
+	check(c.EnrichmentServiceURL != "", "ENRICHMENT_SERVICE_URL is required")
+	check(c.EnrichmentMaxConcurrency > 0, "ENRICHMENT_MAX_CONCURRENCY must be positive, got %d", c.EnrichmentMaxConcurrency)
+	check(c.EnrichmentBatchSize > 0, "ENRICHMENT_BATCH_SIZE must be positive, got %d", c.EnrichmentBatchSize)
+	if c.EPSSURL != "" {
+		u, err := url.Parse(c.EPSSURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"EPSS_API_URL must be an absolute http or https URL, got %q", c.EPSSURL)
 	}
+	check(c.EPSSBatchSize > 0, "EPSS_BATCH_SIZE must be positive, got %d", c.EPSSBatchSize)
+	check(c.EPSSCacheTTL > 0, "EPSS_CACHE_TTL must be positive")
+	check(c.ThreatIntelCheckInterval > 0, "THREAT_INTEL_CHECK_INTERVAL must be positive")
+	check(c.ThreatIntelFetchTimeout > 0, "THREAT_INTEL_FETCH_TIMEOUT must be positive")
+	check(c.ThreatIntelMinRefresh > 0, "THREAT_INTEL_MIN_REFRESH must be positive")
+	check(c.ThreatIntelMaxStaleness > 0, "THREAT_INTEL_MAX_STALENESS must be positive")
 
-	return nil
+	check(c.JWTExpiry > 0, "JWT_EXPIRY must be positive")
+	check(c.RateLimitRequests > 0, "RATE_LIMIT_REQUESTS must be positive, got %d", c.RateLimitRequests)
+	check(c.RateLimitAgentRequests > 0, "RATE_LIMIT_AGENT_REQUESTS must be positive, got %d", c.RateLimitAgentRequests)
+	check(c.RateLimitAnalyticsRequests > 0, "RATE_LIMIT_ANALYTICS_REQUESTS must be positive, got %d", c.RateLimitAnalyticsRequests)
+	check(c.RateLimitWindow > 0, "RATE_LIMIT_WINDOW must be positive")
+	if c.RedisURL != "" {
+		_, err := redis.ParseURL(c.RedisURL)
+		check(err == nil, "REDIS_URL must be a redis:// or rediss:// URL: %v", err)
+	}
+	check(c.ETagCacheTTL > 0, "ETAG_CACHE_TTL must be positive")
+	check(c.HeatmapCacheTTL >= 0, "HEATMAP_CACHE_TTL must not be negative")
+
+	check(oneOf(c.LogLevel, "debug", "info", "warn", "error"), "LOG_LEVEL must be one of debug, info, warn or error, got %q", c.LogLevel)
+	check(oneOf(c.LogFormat, "json", "text"), "LOG_FORMAT must be json or text, got %q", c.LogFormat)
+
+	// Config Auditor
+	check(c.ConfigAuditorMaxFileSize > 0, "CONFIG_AUDITOR_MAX_FILE_SIZE must be positive, got %d", c.ConfigAuditorMaxFileSize)
+	check(c.ConfigAuditorDefaultPageSize > 0 && c.ConfigAuditorDefaultPageSize <= c.ConfigAuditorMaxPageSize,
+		"CONFIG_AUDITOR_DEFAULT_PAGE_SIZE must be between 1 and CONFIG_AUDITOR_MAX_PAGE_SIZE (%d), got %d", c.ConfigAuditorMaxPageSize, c.ConfigAuditorDefaultPageSize)
+	check(c.ConfigAuditorWorkerCount > 0, "CONFIG_AUDITOR_WORKER_COUNT must be positive, got %d", c.ConfigAuditorWorkerCount)
+	check(c.ConfigAuditorQueueBufferSize >= 0, "CONFIG_AUDITOR_QUEUE_BUFFER_SIZE must not be negative, got %d", c.ConfigAuditorQueueBufferSize)
+	check(c.ConfigUploadChunkSize > 0, "CONFIG_UPLOAD_CHUNK_SIZE must be positive, got %d", c.ConfigUploadChunkSize)
+	check(c.ConfigUploadTimeout > 0, "CONFIG_UPLOAD_TIMEOUT must be positive")
+	check(c.ConfigRulesReloadInterval > 0, "CONFIG_RULES_RELOAD_INTERVAL must be positive")
+	check(oneOf(c.ConfigFileStore, "db", "file", "s3"), "CONFIG_FILE_STORE must be db, file or s3, got %q", c.ConfigFileStore)
+	if c.ConfigFileStore != "s3" {
+		check(c.ConfigAuditorStoragePath != "", "CONFIG_AUDITOR_STORAGE_PATH is required unless CONFIG_FILE_STORE=s3")
+	}
+	if c.ConfigFileStore == "s3" {
+		check(c.ConfigFileS3Bucket != "", "CONFIG_FILE_S3_BUCKET is required when CONFIG_FILE_STORE=s3")
+		check(c.ConfigFileS3AccessKeyID != "" && c.ConfigFileS3SecretKey != "",
+			"CONFIG_FILE_S3_ACCESS_KEY_ID and CONFIG_FILE_S3_SECRET_ACCESS_KEY are required when CONFIG_FILE_STORE=s3")
+	}
+
+	// Multi-tenant processing fairness
+	check(c.ProcessingMaxConcurrency > 0, "PROCESSING_MAX_CONCURRENCY must be positive, got %d", c.ProcessingMaxConcurrency)
+	check(c.OrgMaxConcurrency > 0 && c.OrgMaxConcurrency <= c.ProcessingMaxConcurrency,
+		"ORG_MAX_CONCURRENCY must be between 1 and PROCESSING_MAX_CONCURRENCY (%d), got %d", c.ProcessingMaxConcurrency, c.OrgMaxConcurrency)
+	for _, org := range sortedKeys(c.OrgConcurrencyOverrides) {
+		check(c.OrgConcurrencyOverrides[org] > 0, "ORG_CONCURRENCY_OVERRIDES: cap for %s must be positive", org)
+	}
+	for _, org := range sortedKeys(c.OrgSchedulingWeights) {
+		check(c.OrgSchedulingWeights[org] > 0, "ORG_SCHEDULING_WEIGHTS: weight for %s must be positive", org)
+	}
+
+	// Scheduled data export
+	check(c.DataExportCheckInterval > 0, "DATA_EXPORT_CHECK_INTERVAL must be positive")
+	check(c.DataExportMinFrequency >= c.DataExportCheckInterval,
+		"DATA_EXPORT_MIN_FREQUENCY must not be shorter than DATA_EXPORT_CHECK_INTERVAL")
+	if c.SecretEncryptionKey != "" {
+		_, err := secrets.NewCipher(c.SecretEncryptionKey)
+		check(err == nil, "SECRET_ENCRYPTION_KEY must be 32 bytes, hex or base64 encoded")
+	}
+
+	// Flapping detection
+	check(c.FlappingThreshold > 0, "FLAPPING_THRESHOLD must be positive, got %d", c.FlappingThreshold)
+	check(c.FlappingWindow > 0, "FLAPPING_WINDOW must be positive")
+
+	// Resolved finding retention
+	for _, severity := range sortedKeys(c.FindingRetentionDays) {
+		check(c.FindingRetentionDays[severity] > 0, "FINDING_RETENTION_DAYS: days for %s must be positive", severity)
+	}
+	check(c.FindingRetentionInterval > 0, "FINDING_RETENTION_INTERVAL must be positive")
+
+	// Backfills
+	check(c.BackfillBatchSize > 0, "BACKFILL_BATCH_SIZE must be positive, got %d", c.BackfillBatchSize)
+	check(c.BackfillConcurrency > 0, "BACKFILL_CONCURRENCY must be positive, got %d", c.BackfillConcurrency)
+
+	check(c.AgentCommandTTL > 0, "AGENT_COMMAND_TTL must be positive")
+
+	// Agentless collection
+	check(c.CollectionMaxConcurrency > 0, "COLLECTION_MAX_CONCURRENCY must be positive, got %d", c.CollectionMaxConcurrency)
+	check(c.CollectionHostTimeout > 0, "COLLECTION_HOST_TIMEOUT must be positive")
+	check(c.CollectionAuthFailureBudget > 0, "COLLECTION_AUTH_FAILURE_BUDGET must be positive, got %d", c.CollectionAuthFailureBudget)
+
+	check(oneOf(c.NetworkAssetDedup, "identity", "ip_mac", "ip", "none"), "NETWORK_ASSET_DEDUP must be identity, ip_mac, ip or none, got %q", c.NetworkAssetDedup)
+
+	// Request size limits
+	check(c.MaxRequestBodySize > 0, "MAX_REQUEST_BODY_SIZE must be positive, got %d", c.MaxRequestBodySize)
+	check(c.MaxResultPayloadSize > 0 && c.MaxResultPayloadSize <= c.MaxRequestBodySize,
+		"MAX_RESULT_PAYLOAD_SIZE must be between 1 and MAX_REQUEST_BODY_SIZE (%d), got %d", c.MaxRequestBodySize, c.MaxResultPayloadSize)
+
+	// Split result submissions
+	check(c.ResultBatchTimeout > 0, "RESULT_BATCH_TIMEOUT must be positive")
+	check(c.ResultBatchMaxChunks > 0, "RESULT_BATCH_MAX_CHUNKS must be positive, got %d", c.ResultBatchMaxChunks)
+
+	// Finding evidence storage
+	check(oneOf(c.EvidenceStore, "file", "s3"), "EVIDENCE_STORE must be file or s3, got %q", c.EvidenceStore)
+	if c.EvidenceStore == "file" {
+		check(c.EvidenceStoragePath != "", "EVIDENCE_STORAGE_PATH is required when EVIDENCE_STORE=file")
+	}
+	if c.EvidenceStore == "s3" {
+		check(c.EvidenceS3Bucket != "", "EVIDENCE_S3_BUCKET is required when EVIDENCE_STORE=s3")
+		check(c.EvidenceS3AccessKeyID != "" && c.EvidenceS3SecretKey != "",
+			"EVIDENCE_S3_ACCESS_KEY_ID and EVIDENCE_S3_SECRET_ACCESS_KEY are required when EVIDENCE_STORE=s3")
+	}
+	check(c.EvidenceMaxSize > 0 && c.EvidenceMaxSize <= c.MaxResultPayloadSize,
+		"EVIDENCE_MAX_SIZE must be between 1 and MAX_RESULT_PAYLOAD_SIZE (%d), got %d", c.MaxResultPayloadSize, c.EvidenceMaxSize)
+	check(c.EvidenceMaxPerFinding > 0, "EVIDENCE_MAX_PER_FINDING must be positive, got %d", c.EvidenceMaxPerFinding)
+
+	// Export quotas and async exports
+	check(c.ExportQuotaLimit > 0, "EXPORT_QUOTA_LIMIT must be positive, got %d", c.ExportQuotaLimit)
+	check(c.ExportQuotaWindow > 0, "EXPORT_QUOTA_WINDOW must be positive")
+	for _, org := range sortedKeys(c.ExportQuotaOverrides) {
+		check(c.ExportQuotaOverrides[org] > 0, "EXPORT_QUOTA_OVERRIDES: limit for %s must be positive", org)
+	}
+	check(c.ExportStoragePath != "", "EXPORT_STORAGE_PATH is required")
+	check(c.ExportRetention > 0, "EXPORT_RETENTION must be positive")
+	if c.ExportPublicURL != "" {
+		u, err := url.Parse(c.ExportPublicURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"EXPORT_PUBLIC_URL must be an absolute http or https URL, got %q", c.ExportPublicURL)
+	}
+
+	// Webhooks
+	check(c.WebhookCheckInterval > 0, "WEBHOOK_CHECK_INTERVAL must be positive")
+	check(c.WebhookTimeout > 0, "WEBHOOK_TIMEOUT must be positive")
+	check(c.WebhookMaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS must be positive, got %d", c.WebhookMaxAttempts)
+	if c.DashboardURL != "" {
+		u, err := url.Parse(c.DashboardURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"DASHBOARD_URL must be an absolute http or https URL, got %q", c.DashboardURL)
+	}
+
+	check(c.DBCompressionThreshold >= 0, "DB_COMPRESSION_THRESHOLD must not be negative, got %d", c.DBCompressionThreshold)
+
+	// GraphQL query limits
+	check(c.GraphQLMaxDepth > 0, "GRAPHQL_MAX_DEPTH must be positive, got %d", c.GraphQLMaxDepth)
+	check(c.GraphQLMaxComplexity > 0, "GRAPHQL_MAX_COMPLEXITY must be positive, got %d", c.GraphQLMaxComplexity)
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
 }
 
 // ValidateEnvironment checks environment variables at startup
@@ -46,8 +233,10 @@ func ValidateEnvironment() error {
 
 	isDebug := os.Getenv("API_MODE") == "debug" || os.Getenv("DEBUG") == "true"
 	if !isDebug {
-		// In production, also require Clerk key
-		requiredVars = append(requiredVars, "CLERK_JWT_VERIFICATION_KEY")
+		// In production, also require a Clerk key or JWKS
+		if os.Getenv("CLERK_JWKS_URL") == "" {
+			requiredVars = append(requiredVars, "CLERK_JWT_VERIFICATION_KEY")
+		}
 	}
 
 	var missing []string
@@ -63,4 +252,3 @@ func ValidateEnvironment() error {
 
 	return nil
 }
-
@@ -0,0 +1,231 @@
+package configrules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Target describes the config file a set of rules is evaluated against
+type Target struct {
+	ConfigFormat string
+	Manufacturer string
+	DeviceType   string
+}
+
+// Violation is a rule a config tree doesn't satisfy
+type Violation struct {
+	Rule *Rule
+
+	// Values are the values at the rule's path that break it, empty when the
+	// path itself is missing or present against the rule
+	Values []string
+}
+
+// Evaluate checks a parsed config tree against the rules that apply to the
+// target, in rule order
+func (s *Set) Evaluate(tree map[string]any, target Target) []Violation {
+	if s == nil {
+		return nil
+	}
+	var violations []Violation
+	for _, rule := range s.Rules {
+		if !rule.Matches(target) {
+			continue
+		}
+		if violation, ok := rule.Evaluate(tree); ok {
+			violations = append(violations, violation)
+		}
+	}
+	return violations
+}
+
+// Matches reports whether a rule applies to a config file
+func (r *Rule) Matches(target Target) bool {
+	return matchesAny(r.AppliesTo.ConfigFormats, target.ConfigFormat) &&
+		matchesAny(r.AppliesTo.Manufacturers, target.Manufacturer) &&
+		matchesAny(r.AppliesTo.DeviceTypes, target.DeviceType)
+}
+
+func matchesAny(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, item := range allowed {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate checks one rule against a parsed config tree
+func (r *Rule) Evaluate(tree map[string]any) (Violation, bool) {
+	values := resolve(tree, r.segments)
+	switch r.Operator {
+	case OpExists:
+		return Violation{Rule: r}, len(values) == 0
+	case OpAbsent:
+		return Violation{Rule: r}, len(values) > 0
+	}
+	if len(values) == 0 {
+		return Violation{Rule: r}, r.Required
+	}
+
+	// contains looks into a list or a string as a whole; every other
+	// operator checks the items of a list one by one
+	if r.Operator != OpContains && r.Operator != OpNotContains {
+		values = flatten(values)
+	}
+	negated := strings.HasPrefix(r.Operator, "not_")
+	var offending []string
+	for _, value := range values {
+		if r.holds(value) == negated {
+			offending = append(offending, describe(value))
+		}
+	}
+	return Violation{Rule: r, Values: offending}, len(offending) > 0
+}
+
+// holds reports whether a value satisfies the operator, ignoring negation
+func (r *Rule) holds(value any) bool {
+	switch r.Operator {
+	case OpEquals, OpNotEquals:
+		return equal(value, r.Value)
+	case OpContains, OpNotContains:
+		if items, ok := value.([]any); ok {
+			for _, item := range items {
+				if equal(item, r.Value) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, token := range tokens(describe(value)) {
+			if token == describe(r.Value) {
+				return true
+			}
+		}
+		return false
+	case OpMatches, OpNotMatches:
+		return r.pattern.MatchString(describe(value))
+	case OpIn, OpNotIn:
+		for _, item := range r.Value.([]any) {
+			if equal(value, item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	actual, ok := number(value)
+	if !ok {
+		return false
+	}
+	expected, _ := number(r.Value)
+	switch r.Operator {
+	case OpGreater:
+		return actual > expected
+	case OpGreaterEq:
+		return actual >= expected
+	case OpLess:
+		return actual < expected
+	default:
+		return actual <= expected
+	}
+}
+
+// resolve returns the values at a dotted path. "*" stands for every key of
+// a map, and a list on the way is walked item by item.
+func resolve(node any, segments []string) []any {
+	if len(segments) == 0 {
+		if node == nil {
+			return nil
+		}
+		return []any{node}
+	}
+	switch n := node.(type) {
+	case map[string]any:
+		if segments[0] == "*" {
+			keys := make([]string, 0, len(n))
+			for key := range n {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			var values []any
+			for _, key := range keys {
+				values = append(values, resolve(n[key], segments[1:])...)
+			}
+			return values
+		}
+		child, ok := lookup(n, segments[0])
+		if !ok {
+			return nil
+		}
+		return resolve(child, segments[1:])
+	case []any:
+		var values []any
+		for _, item := range n {
+			values = append(values, resolve(item, segments)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// lookup finds a key exactly, or else ignoring case, since config keywords
+// are often case-insensitive
+func lookup(node map[string]any, key string) (any, bool) {
+	if value, ok := node[key]; ok {
+		return value, true
+	}
+	for k, value := range node {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func flatten(values []any) []any {
+	var flat []any
+	for _, value := range values {
+		if items, ok := value.([]any); ok {
+			flat = append(flat, flatten(items)...)
+			continue
+		}
+		flat = append(flat, value)
+	}
+	return flat
+}
+
+// equal compares numbers by value and anything else by its text
+func equal(a, b any) bool {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return x == y
+		}
+	}
+	return describe(a) == describe(b)
+}
+
+// tokens splits a config value on whitespace, commas and semicolons
+func tokens(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+func describe(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = describe(item)
+		}
+		return strings.Join(parts, " ")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,319 @@
+// Package configrules loads customer-defined checks of parsed configuration
+// files from YAML and evaluates them against parsed config trees. A rule
+// names a path into the tree, an operator and a value, and states what must
+// hold; a config file where it doesn't violates the rule:
+//
+//	rules:
+//	  - id: nginx-no-tlsv1
+//	    title: nginx accepts TLS 1.0
+//	    severity: high
+//	    category: encryption
+//	    remediation: Set ssl_protocols to TLSv1.2 TLSv1.3
+//	    applies_to:
+//	      config_formats: [nginx]
+//	    path: http.server.ssl_protocols
+//	    operator: not_contains
+//	    value: TLSv1
+package configrules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Operators a rule may assert with
+const (
+	OpExists      = "exists"       // The path is present
+	OpAbsent      = "absent"       // The path is not present
+	OpEquals      = "equals"       // Every value equals Value
+	OpNotEquals   = "not_equals"   // No value equals Value
+	OpContains    = "contains"     // Every value, a list or a space- or comma-separated string, includes Value
+	OpNotContains = "not_contains" // No value includes Value
+	OpMatches     = "matches"      // Every value matches the regular expression Value
+	OpNotMatches  = "not_matches"  // No value matches the regular expression Value
+	OpIn          = "in"           // Every value is one of the list Value
+	OpNotIn       = "not_in"       // No value is one of the list Value
+	OpGreater     = "gt"           // Every value is a number greater than Value
+	OpGreaterEq   = "gte"
+	OpLess        = "lt"
+	OpLessEq      = "lte"
+)
+
+var operators = []string{
+	OpExists, OpAbsent, OpEquals, OpNotEquals, OpContains, OpNotContains, OpMatches, OpNotMatches,
+	OpIn, OpNotIn, OpGreater, OpGreaterEq, OpLess, OpLessEq,
+}
+
+// Severities a rule may have
+var severities = []string{"critical", "high", "medium", "low", "info"}
+
+// maxPatternLength bounds the regular expressions of matches rules
+const maxPatternLength = 1000
+
+var ruleIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// ruleFields are the keys a rule may have, so a misspelt one is reported
+// rather than silently ignored
+var ruleFields = []string{
+	"id", "title", "description", "severity", "category", "remediation", "applies_to", "path", "operator", "value", "required",
+}
+
+// Rule is one check of a parsed config tree
+type Rule struct {
+	ID          string    `yaml:"id"`
+	Title       string    `yaml:"title"`
+	Description string    `yaml:"description"`
+	Severity    string    `yaml:"severity"`
+	Category    string    `yaml:"category"` // Defaults to "custom"
+	Remediation string    `yaml:"remediation"`
+	AppliesTo   AppliesTo `yaml:"applies_to"`
+	Path        string    `yaml:"path"`
+	Operator    string    `yaml:"operator"`
+	Value       any       `yaml:"value"`
+
+	// Required makes a path that isn't present a violation. Otherwise a
+	// rule comparing values only applies to files where its path is present.
+	Required bool `yaml:"required"`
+
+	// Where the rule was defined, for errors and findings
+	Source string `yaml:"-"`
+	Line   int    `yaml:"-"`
+
+	pattern  *regexp.Regexp
+	segments []string
+}
+
+// AppliesTo limits a rule to config files of the given formats,
+// manufacturers or device types, matched case-insensitively. Empty lists
+// don't limit.
+type AppliesTo struct {
+	ConfigFormats []string `yaml:"config_formats"`
+	Manufacturers []string `yaml:"manufacturers"`
+	DeviceTypes   []string `yaml:"device_types"`
+}
+
+// UnmarshalYAML records where a rule is defined and rejects unknown keys
+func (r *Rule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: a rule must be a mapping", node.Line)
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !contains(ruleFields, key.Value) {
+			return fmt.Errorf("line %d: unknown rule field %q", key.Line, key.Value)
+		}
+	}
+
+	type plain Rule
+	if err := node.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	r.Line = node.Line
+	return nil
+}
+
+// Set is a validated set of rules
+type Set struct {
+	Rules []*Rule
+}
+
+// Parse reads and validates the rules of one YAML document. source names it
+// in errors.
+func Parse(source string, data []byte) (*Set, error) {
+	var doc struct {
+		Rules []*Rule `yaml:"rules"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// An empty file is a set of no rules
+	if err := decoder.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	var errs []error
+	for i, rule := range doc.Rules {
+		if rule == nil {
+			errs = append(errs, fmt.Errorf("%s: rule %d is empty", source, i+1))
+			continue
+		}
+		rule.Source = source
+		if err := rule.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: rule %q: %w", source, rule.Line, rule.ID, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &Set{Rules: doc.Rules}, nil
+}
+
+// Load reads the rules of a YAML file, or of every .yaml and .yml file in a
+// directory, in name order. Rule IDs must be unique across files.
+func Load(path string) (*Set, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = ruleFiles(path); err != nil {
+			return nil, err
+		}
+	}
+
+	set := &Set{}
+	var errs []error
+	defined := make(map[string]*Rule)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fileSet, err := Parse(file, data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, rule := range fileSet.Rules {
+			if previous, ok := defined[rule.ID]; ok {
+				errs = append(errs, fmt.Errorf("%s:%d: rule %q is already defined at %s:%d", rule.Source, rule.Line, rule.ID, previous.Source, previous.Line))
+				continue
+			}
+			defined[rule.ID] = rule
+			set.Rules = append(set.Rules, rule)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return set, nil
+}
+
+// ruleFiles lists the YAML files of a directory, in name order
+func ruleFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.Type().IsRegular() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (r *Rule) validate() error {
+	if !ruleIDPattern.MatchString(r.ID) {
+		return errors.New("id is required, and must be lowercase letters, digits, '.', '_' and '-'")
+	}
+	if strings.TrimSpace(r.Title) == "" {
+		return errors.New("title is required")
+	}
+	r.Severity = strings.ToLower(r.Severity)
+	if !contains(severities, r.Severity) {
+		return fmt.Errorf("severity must be one of %s, got %q", strings.Join(severities, ", "), r.Severity)
+	}
+	if r.Category == "" {
+		r.Category = "custom"
+	}
+	if r.Description == "" {
+		r.Description = r.Title
+	}
+
+	r.segments = strings.Split(r.Path, ".")
+	for _, segment := range r.segments {
+		if segment == "" {
+			return fmt.Errorf("path must be keys separated by '.', got %q", r.Path)
+		}
+	}
+
+	if !contains(operators, r.Operator) {
+		return fmt.Errorf("operator must be one of %s, got %q", strings.Join(operators, ", "), r.Operator)
+	}
+	switch r.Operator {
+	case OpExists, OpAbsent:
+		if r.Value != nil {
+			return fmt.Errorf("%s takes no value", r.Operator)
+		}
+	case OpMatches, OpNotMatches:
+		pattern, ok := r.Value.(string)
+		if !ok || pattern == "" {
+			return fmt.Errorf("%s needs a regular expression value", r.Operator)
+		}
+		if len(pattern) > maxPatternLength {
+			return fmt.Errorf("regular expression is over %d characters", maxPatternLength)
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+		r.pattern = compiled
+	case OpIn, OpNotIn:
+		items, ok := r.Value.([]any)
+		if !ok || len(items) == 0 {
+			return fmt.Errorf("%s needs a list value", r.Operator)
+		}
+		for _, item := range items {
+			if !isScalar(item) {
+				return fmt.Errorf("%s needs a list of scalar values", r.Operator)
+			}
+		}
+	case OpGreater, OpGreaterEq, OpLess, OpLessEq:
+		if _, ok := number(r.Value); !ok {
+			return fmt.Errorf("%s needs a numeric value", r.Operator)
+		}
+	default:
+		if r.Value == nil || !isScalar(r.Value) {
+			return fmt.Errorf("%s needs a scalar value", r.Operator)
+		}
+	}
+	return nil
+}
+
+func isScalar(value any) bool {
+	switch value.(type) {
+	case string, int, int64, float64, bool:
+		return true
+	}
+	return false
+}
+
+// number reads a value as a number, including a numeric string
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
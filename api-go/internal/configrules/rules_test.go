@@ -0,0 +1,137 @@
+package configrules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+rules:
+  - id: nginx-no-tlsv1
+    title: nginx accepts TLS 1.0
+    severity: high
+    remediation: Set ssl_protocols to TLSv1.2 TLSv1.3
+    applies_to:
+      config_formats: [nginx]
+    path: http.server.ssl_protocols
+    operator: not_contains
+    value: TLSv1
+  - id: sshd-root-login
+    title: sshd permits root login
+    severity: critical
+    applies_to:
+      config_formats: [sshd]
+    path: PermitRootLogin
+    operator: in
+    value: ["no", prohibit-password]
+    required: true
+  - id: sshd-max-auth-tries
+    title: sshd allows too many authentication attempts
+    severity: medium
+    path: MaxAuthTries
+    operator: lte
+    value: 4
+  - id: no-telnet
+    title: Telnet is enabled
+    severity: high
+    path: services.telnet
+    operator: absent
+  - id: snmp-community
+    title: SNMP uses a default community
+    severity: high
+    path: snmp.*.community
+    operator: not_matches
+    value: (?i)^(public|private)$
+`
+
+func TestParseAndEvaluate(t *testing.T) {
+	set, err := Parse("rules.yaml", []byte(testRules))
+	require.NoError(t, err)
+	require.Len(t, set.Rules, 5)
+	assert.Equal(t, "custom", set.Rules[0].Category)
+	assert.Equal(t, 3, set.Rules[0].Line)
+
+	nginx := map[string]any{
+		"http": map[string]any{
+			"server": []any{
+				map[string]any{"ssl_protocols": "TLSv1.2 TLSv1.3"},
+				map[string]any{"ssl_protocols": "TLSv1 TLSv1.2"},
+			},
+		},
+	}
+	violations := set.Evaluate(nginx, Target{ConfigFormat: "NGINX"})
+	require.Len(t, violations, 1, "only the nginx rule fails, and only for one server")
+	assert.Equal(t, "nginx-no-tlsv1", violations[0].Rule.ID)
+	assert.Equal(t, []string{"TLSv1 TLSv1.2"}, violations[0].Values)
+
+	sshd := map[string]any{
+		"permitrootlogin": "yes",
+		"MaxAuthTries":    float64(6),
+		"services":        map[string]any{"telnet": true},
+		"snmp": map[string]any{
+			"v2": map[string]any{"community": "Public"},
+			"v3": map[string]any{"community": "s3cret"},
+		},
+	}
+	violations = set.Evaluate(sshd, Target{ConfigFormat: "sshd"})
+	var ids []string
+	for _, violation := range violations {
+		ids = append(ids, violation.Rule.ID)
+	}
+	assert.Equal(t, []string{"sshd-root-login", "sshd-max-auth-tries", "no-telnet", "snmp-community"}, ids)
+	assert.Equal(t, []string{"Public"}, violations[3].Values)
+
+	// A rule comparing values only applies where its path is present, unless it's required
+	violations = set.Evaluate(map[string]any{}, Target{ConfigFormat: "sshd"})
+	require.Len(t, violations, 1)
+	assert.Equal(t, "sshd-root-login", violations[0].Rule.ID)
+}
+
+func TestParseReportsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name, rules, err string
+	}{
+		{"unknown field", "rules:\n  - id: a\n    titel: A\n", `line 3: unknown rule field "titel"`},
+		{"unknown top-level key", "rule: []\n", "field rule not found"},
+		{"missing id", "rules:\n  - title: A\n    severity: low\n    path: a\n    operator: exists\n", "rules.yaml:2: rule \"\": id is required"},
+		{"bad severity", "rules:\n  - id: a\n    title: A\n    severity: urgent\n    path: a\n    operator: exists\n", `rules.yaml:2: rule "a": severity must be one of`},
+		{"bad path", "rules:\n  - id: a\n    title: A\n    severity: low\n    path: a..b\n    operator: exists\n", "path must be keys separated by '.'"},
+		{"bad operator", "rules:\n  - id: a\n    title: A\n    severity: low\n    path: a\n    operator: like\n", "operator must be one of"},
+		{"bad regex", "rules:\n  - id: a\n    title: A\n    severity: low\n    path: a\n    operator: matches\n    value: '('\n", "invalid regular expression"},
+		{"non-numeric comparison", "rules:\n  - id: a\n    title: A\n    severity: low\n    path: a\n    operator: gt\n    value: many\n", "gt needs a numeric value"},
+		{"in without a list", "rules:\n  - id: a\n    title: A\n    severity: low\n    path: a\n    operator: in\n    value: b\n", "in needs a list value"},
+		{"exists with a value", "rules:\n  - id: a\n    title: A\n    severity: low\n    path: a\n    operator: exists\n    value: b\n", "exists takes no value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("rules.yaml", []byte(tt.rules))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	set, err := Parse("empty.yaml", nil)
+	require.NoError(t, err)
+	assert.Empty(t, set.Rules)
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	rule := "rules:\n  - id: a\n    title: A\n    severity: low\n    path: a\n    operator: exists\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(rule), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not rules"), 0o600))
+
+	set, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, set.Rules, 1)
+	assert.Equal(t, filepath.Join(dir, "a.yaml"), set.Rules[0].Source)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yml"), []byte(rule), 0o600))
+	_, err = Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `rule "a" is already defined at `+filepath.Join(dir, "a.yaml")+":2")
+}
@@ -28,6 +28,7 @@ const (
 	StatusParsing  = "parsing"
 	StatusParsed   = "parsed"
 	StatusFailed   = "failed"
+	StatusUnparsed = "unparsed" // Stored, but in a format no parser handles
 	StatusPartial  = "partial"
 	StatusAnalyzing = "analyzing"
 	StatusCompleted = "completed"
@@ -48,6 +49,17 @@ const (
 	SeverityInfo     = "info"
 )
 
+// Config formats, detected from a config file's name and content
+const (
+	ConfigFormatSSHD      = "sshd"
+	ConfigFormatNginx     = "nginx"
+	ConfigFormatApache    = "apache"
+	ConfigFormatTerraform = "terraform"
+	ConfigFormatXML       = "xml"
+	ConfigFormatJSON      = "json"
+	ConfigFormatText      = "text"
+)
+
 // Risk score thresholds
 const (
 	RiskThresholdCritical = 0.8
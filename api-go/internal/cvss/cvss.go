@@ -0,0 +1,257 @@
+// Package cvss parses CVSS v3.1 and v4.0 vector strings and scores them,
+// including the environmental score a vulnerability gets from an
+// organization's confidentiality, integrity and availability requirements.
+//
+// v3.1 vectors are scored with the formulas of the v3.1 specification. v4.0
+// scores come from FIRST's lookup of every macro vector, which isn't
+// reproduced here: v4.0 vectors are validated but not scored, and callers
+// fall back to the score the vector was published with.
+package cvss
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrInvalidVector is returned for a vector string that isn't a valid CVSS
+// v3.1 or v4.0 vector
+var ErrInvalidVector = errors.New("invalid CVSS vector")
+
+// Versions of CVSS vectors Parse accepts
+const (
+	Version31 = "3.1"
+	Version40 = "4.0"
+)
+
+// Security requirement levels, the values of the CR, IR and AR metrics
+const (
+	RequirementLow    = "L"
+	RequirementMedium = "M"
+	RequirementHigh   = "H"
+)
+
+// Requirements are an organization's confidentiality, integrity and
+// availability requirements (CR, IR and AR), each RequirementLow,
+// RequirementMedium or RequirementHigh. An empty requirement keeps the one
+// the vector sets, if any.
+type Requirements struct {
+	Confidentiality string
+	Integrity       string
+	Availability    string
+}
+
+// Vector is a parsed CVSS vector
+type Vector struct {
+	Version string
+	Metrics map[string]string // Metric abbreviation -> value, e.g. "AV" -> "N"
+}
+
+// metricSpec is the values a version allows for each metric, and the
+// metrics a vector of it must set
+type metricSpec struct {
+	values   map[string]string // Metric -> its allowed values, one letter each
+	required []string
+}
+
+var specs = map[string]metricSpec{
+	Version31: {
+		values: map[string]string{
+			"AV": "NALP", "AC": "LH", "PR": "NLH", "UI": "NR", "S": "UC",
+			"C": "HLN", "I": "HLN", "A": "HLN",
+			"E": "XUPFH", "RL": "XOTWU", "RC": "XURC",
+			"CR": "XLMH", "IR": "XLMH", "AR": "XLMH",
+			"MAV": "XNALP", "MAC": "XLH", "MPR": "XNLH", "MUI": "XNR", "MS": "XUC",
+			"MC": "XHLN", "MI": "XHLN", "MA": "XHLN",
+		},
+		required: []string{"AV", "AC", "PR", "UI", "S", "C", "I", "A"},
+	},
+	Version40: {
+		values: map[string]string{
+			"AV": "NALP", "AC": "LH", "AT": "NP", "PR": "NLH", "UI": "NPA",
+			"VC": "HLN", "VI": "HLN", "VA": "HLN", "SC": "HLN", "SI": "HLN", "SA": "HLN",
+			"E":  "XAPU",
+			"CR": "XHML", "IR": "XHML", "AR": "XHML",
+			"MAV": "XNALP", "MAC": "XLH", "MAT": "XNP", "MPR": "XNLH", "MUI": "XNPA",
+			"MVC": "XHLN", "MVI": "XHLN", "MVA": "XHLN",
+			"MSC": "XHLN", "MSI": "XSHLN", "MSA": "XSHLN",
+			"S": "XNP", "AU": "XNY", "R": "XAUI", "V": "XDC", "RE": "XLMH", "U": "X",
+		},
+		required: []string{"AV", "AC", "AT", "PR", "UI", "VC", "VI", "VA", "SC", "SI", "SA"},
+	},
+}
+
+// Parse parses a CVSS v3.1 or v4.0 vector string, such as
+// "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H". Every metric must be one
+// the version defines, set once to a value it allows, and every base metric
+// must be set.
+func Parse(vector string) (*Vector, error) {
+	parts := strings.Split(strings.TrimSpace(vector), "/")
+	version, ok := strings.CutPrefix(parts[0], "CVSS:")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing CVSS version prefix", ErrInvalidVector)
+	}
+	spec, ok := specs[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidVector, version)
+	}
+
+	v := &Vector{Version: version, Metrics: make(map[string]string, len(parts)-1)}
+	for _, part := range parts[1:] {
+		metric, value, ok := strings.Cut(part, ":")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: malformed metric %q", ErrInvalidVector, part)
+		}
+		allowed, known := spec.values[metric]
+		if !known {
+			return nil, fmt.Errorf("%w: unknown metric %q", ErrInvalidVector, metric)
+		}
+		if len(value) != 1 || !strings.Contains(allowed, value) {
+			return nil, fmt.Errorf("%w: invalid value %q for %s", ErrInvalidVector, value, metric)
+		}
+		if _, dup := v.Metrics[metric]; dup {
+			return nil, fmt.Errorf("%w: %s set twice", ErrInvalidVector, metric)
+		}
+		v.Metrics[metric] = value
+	}
+	for _, metric := range spec.required {
+		if _, ok := v.Metrics[metric]; !ok {
+			return nil, fmt.Errorf("%w: missing base metric %s", ErrInvalidVector, metric)
+		}
+	}
+	return v, nil
+}
+
+// BaseScore computes the vector's base score. ok is false for v4.0 vectors,
+// which aren't scored.
+func (v *Vector) BaseScore() (score float64, ok bool) {
+	if v.Version != Version31 {
+		return 0, false
+	}
+
+	changed := v.Metrics["S"] == "C"
+	iss := 1 - (1-impactWeight[v.Metrics["C"]])*(1-impactWeight[v.Metrics["I"]])*(1-impactWeight[v.Metrics["A"]])
+	var impact float64
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	} else {
+		impact = 6.42 * iss
+	}
+	exploitability := 8.22 * attackVectorWeight[v.Metrics["AV"]] * attackComplexityWeight[v.Metrics["AC"]] *
+		privilegesWeight(v.Metrics["PR"], changed) * userInteractionWeight[v.Metrics["UI"]]
+
+	if impact <= 0 {
+		return 0, true
+	}
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// EnvironmentalScore computes the vector's environmental score with the
+// organization's requirements in place of the ones the vector sets. Its
+// modified base metrics and temporal metrics are taken into account. ok is
+// false for v4.0 vectors, which aren't scored.
+func (v *Vector) EnvironmentalScore(req Requirements) (score float64, ok bool) {
+	if v.Version != Version31 {
+		return 0, false
+	}
+
+	cr := requirementWeight[v.requirement("CR", req.Confidentiality)]
+	ir := requirementWeight[v.requirement("IR", req.Integrity)]
+	ar := requirementWeight[v.requirement("AR", req.Availability)]
+
+	changed := v.modified("S") == "C"
+	miss := math.Min(1-
+		(1-cr*impactWeight[v.modified("C")])*
+			(1-ir*impactWeight[v.modified("I")])*
+			(1-ar*impactWeight[v.modified("A")]), 0.915)
+	var impact float64
+	if changed {
+		impact = 7.52*(miss-0.029) - 3.25*math.Pow(miss*0.9731-0.02, 13)
+	} else {
+		impact = 6.42 * miss
+	}
+	exploitability := 8.22 * attackVectorWeight[v.modified("AV")] * attackComplexityWeight[v.modified("AC")] *
+		privilegesWeight(v.modified("PR"), changed) * userInteractionWeight[v.modified("UI")]
+
+	if impact <= 0 {
+		return 0, true
+	}
+	temporal := exploitMaturityWeight[v.optional("E")] * remediationLevelWeight[v.optional("RL")] * reportConfidenceWeight[v.optional("RC")]
+	if changed {
+		return roundUp(roundUp(math.Min(1.08*(impact+exploitability), 10)) * temporal), true
+	}
+	return roundUp(roundUp(math.Min(impact+exploitability, 10)) * temporal), true
+}
+
+// modified returns the value of a base metric's modified metric, or of the
+// base metric when it isn't modified
+func (v *Vector) modified(metric string) string {
+	if value := v.Metrics["M"+metric]; value != "" && value != "X" {
+		return value
+	}
+	return v.Metrics[metric]
+}
+
+// optional returns the value of a metric vectors may leave out, X when not
+// defined
+func (v *Vector) optional(metric string) string {
+	if value := v.Metrics[metric]; value != "" {
+		return value
+	}
+	return "X"
+}
+
+// requirement returns the organization's requirement if it has a valid one,
+// otherwise the vector's
+func (v *Vector) requirement(metric, organization string) string {
+	switch organization {
+	case RequirementLow, RequirementMedium, RequirementHigh:
+		return organization
+	}
+	return v.optional(metric)
+}
+
+// roundUp rounds up to one decimal as the v3.1 specification does, avoiding
+// floating point errors such as 4.000001 rounding up to 4.1
+func roundUp(value float64) float64 {
+	i := int(math.Round(value * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
+
+// privilegesWeight weighs the privileges required, which count for more
+// when the scope changes
+func privilegesWeight(value string, scopeChanged bool) float64 {
+	switch value {
+	case "N":
+		return 0.85
+	case "L":
+		if scopeChanged {
+			return 0.68
+		}
+		return 0.62
+	case "H":
+		if scopeChanged {
+			return 0.5
+		}
+		return 0.27
+	}
+	return 0
+}
+
+var (
+	attackVectorWeight     = map[string]float64{"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2}
+	attackComplexityWeight = map[string]float64{"L": 0.77, "H": 0.44}
+	userInteractionWeight  = map[string]float64{"N": 0.85, "R": 0.62}
+	impactWeight           = map[string]float64{"H": 0.56, "L": 0.22, "N": 0}
+	exploitMaturityWeight  = map[string]float64{"X": 1, "H": 1, "F": 0.97, "P": 0.94, "U": 0.91}
+	remediationLevelWeight = map[string]float64{"X": 1, "U": 1, "W": 0.97, "T": 0.96, "O": 0.95}
+	reportConfidenceWeight = map[string]float64{"X": 1, "C": 1, "R": 0.96, "U": 0.92}
+	requirementWeight      = map[string]float64{"X": 1, "H": 1.5, "M": 1, "L": 0.5}
+)
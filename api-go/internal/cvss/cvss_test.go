@@ -0,0 +1,102 @@
+package cvss
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:3.1/AV:L/AC:H/PR:L/UI:R/S:C/C:L/I:N/A:N/E:P/RL:O/RC:C/CR:H/MAV:N",
+		"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N",
+		"CVSS:4.0/AV:N/AC:L/AT:P/PR:L/UI:A/VC:L/VI:L/VA:N/SC:N/SI:N/SA:N/E:A/CR:H/MSI:S",
+	}
+	for _, vector := range valid {
+		if _, err := Parse(vector); err != nil {
+			t.Errorf("Parse(%q) = %v", vector, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", // No version
+		"CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",                    // Unsupported version
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H",                        // Missing A
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:Q",                    // Invalid value
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/AV:L",               // Set twice
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/AT:N",               // v4.0 metric
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/",                   // Trailing slash
+		"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H",                // Missing subsequent system metrics
+		"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:R/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N", // v3.1 value
+	}
+	for _, vector := range invalid {
+		if _, err := Parse(vector); !errors.Is(err, ErrInvalidVector) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidVector", vector, err)
+		}
+	}
+}
+
+func TestBaseScore(t *testing.T) {
+	tests := []struct {
+		vector string
+		want   float64
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", 10.0},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1},
+		{"CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", 7.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:L/I:N/A:N", 5.3},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0},
+	}
+	for _, tt := range tests {
+		v, err := Parse(tt.vector)
+		if err != nil {
+			t.Fatalf("Parse(%q) = %v", tt.vector, err)
+		}
+		if got, ok := v.BaseScore(); !ok || got != tt.want {
+			t.Errorf("BaseScore(%q) = %v, %v, want %v", tt.vector, got, ok, tt.want)
+		}
+	}
+
+	v, _ := Parse("CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N")
+	if _, ok := v.BaseScore(); ok {
+		t.Error("v4.0 vectors should not be scored")
+	}
+}
+
+func TestEnvironmentalScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		vector string
+		req    Requirements
+		want   float64
+	}{
+		{"no requirements is the base score", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", Requirements{}, 9.8},
+		{"low requirements lower it", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", Requirements{"L", "L", "L"}, 8.0},
+		{"high confidentiality raises it", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:L/I:N/A:N", Requirements{Confidentiality: "H"}, 6.1},
+		{"the vector's requirement counts when the organization has none", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:L/I:N/A:N/CR:H", Requirements{}, 6.1},
+		{"the organization's requirement wins", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:L/I:N/A:N/CR:H", Requirements{Confidentiality: "M"}, 5.3},
+		{"temporal metrics lower it", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/E:U/RL:O/RC:U", Requirements{}, 7.8},
+		{"modified metrics replace the base ones", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/MAV:P/MC:N/MI:N/MA:N", Requirements{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Parse(tt.vector)
+			if err != nil {
+				t.Fatalf("Parse(%q) = %v", tt.vector, err)
+			}
+			if got, ok := v.EnvironmentalScore(tt.req); !ok || got != tt.want {
+				t.Errorf("EnvironmentalScore() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundUp(t *testing.T) {
+	for in, want := range map[float64]float64{4.0: 4.0, 4.000001: 4.0, 4.02: 4.1, 9.97: 10.0} {
+		if got := roundUp(in); got != want {
+			t.Errorf("roundUp(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
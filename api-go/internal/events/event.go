@@ -0,0 +1,155 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event headers set on every event delivered over HTTP
+const (
+	TypeHeader          = "X-ZeroTrace-Event"
+	SchemaVersionHeader = "X-ZeroTrace-Schema-Version"
+)
+
+// Event is the envelope every event is delivered in
+type Event struct {
+	ID            uuid.UUID   `json:"id"`
+	Type          string      `json:"type"`
+	SchemaVersion string      `json:"schema_version"`
+	OccurredAt    time.Time   `json:"occurred_at"`
+	Data          interface{} `json:"data"`
+}
+
+// New wraps data in an event of the latest schema version of its type
+func (r *Registry) New(eventType string, data interface{}) (*Event, error) {
+	version, ok := r.Latest(eventType)
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+	return &Event{
+		ID:            uuid.New(),
+		Type:          eventType,
+		SchemaVersion: version,
+		OccurredAt:    time.Now().UTC(),
+		Data:          data,
+	}, nil
+}
+
+// Validate checks an event against the schema of its type and version
+func (r *Registry) Validate(event *Event) error {
+	schema, ok := r.Schema(event.Type, event.SchemaVersion)
+	if !ok {
+		return fmt.Errorf("no schema for %s v%s", event.Type, event.SchemaVersion)
+	}
+
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	var document interface{}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+	return validate(schema, document, "event")
+}
+
+// validate checks a decoded JSON document against the subset of JSON Schema
+// the published schemas use: type, const, enum, format, properties, required,
+// additionalProperties and items
+func validate(schema map[string]interface{}, value interface{}, at string) error {
+	if expected, ok := schema["const"]; ok && value != expected {
+		return fmt.Errorf("%s must be %v, got %v", at, expected, value)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		return fmt.Errorf("%s must be one of %v, got %v", at, enum, value)
+	}
+
+	if typeName, ok := schema["type"].(string); ok && !hasType(value, typeName) {
+		return fmt.Errorf("%s must be of type %s, got %T", at, typeName, value)
+	}
+
+	if format, ok := schema["format"].(string); ok {
+		if text, isString := value.(string); isString {
+			if err := checkFormat(format, text); err != nil {
+				return fmt.Errorf("%s: %w", at, err)
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, present := v[fmt.Sprint(name)]; !present {
+				return fmt.Errorf("%s.%v is required", at, name)
+			}
+		}
+		for name, field := range v {
+			property, known := properties[name].(map[string]interface{})
+			if !known {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s.%s is not in the schema", at, name)
+				}
+				continue
+			}
+			if err := validate(property, field, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validate(items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasType(value interface{}, typeName string) bool {
+	switch typeName {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return false
+	}
+}
+
+func checkFormat(format, text string) error {
+	switch format {
+	case "uuid":
+		if _, err := uuid.Parse(text); err != nil {
+			return fmt.Errorf("%q is not a UUID", text)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+			return fmt.Errorf("%q is not an RFC 3339 date-time", text)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+// Package events defines the events ZeroTrace sends to integrators, such as
+// webhook callbacks, and publishes a versioned JSON schema for each of them.
+//
+// Every event carries its type and schema version. Versions are MAJOR.MINOR:
+// within a major version schemas only evolve in backward-compatible ways, so
+// integrators built against 1.0 keep working with 1.3. The rules are checked
+// when the registry loads:
+//
+//   - fields are never removed, renamed or retyped
+//   - new fields are optional, and required fields stay required
+//   - enum values are only ever added
+//
+// Anything else is a new major version, published alongside the old one.
+// Consumers must ignore fields they do not know.
+package events
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Event types
+const (
+	ExportFinished     = "export.finished"
+	FindingReopened    = "finding.reopened"
+	VulnerabilityFound = "vulnerability.found"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// SchemaVersion describes one published version of an event type
+type SchemaVersion struct {
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	SchemaURL   string `json:"schema_url"`
+}
+
+// EventType lists the published versions of an event type, oldest first
+type EventType struct {
+	Type     string          `json:"type"`
+	Latest   string          `json:"latest"`
+	Versions []SchemaVersion `json:"versions"`
+}
+
+// Registry holds the published event schemas
+type Registry struct {
+	schemas map[string]map[string]map[string]interface{} // Type -> version -> full event schema
+	types   []EventType
+}
+
+var (
+	defaultRegistry *Registry
+	defaultErr      error
+	defaultOnce     sync.Once
+)
+
+// Default returns the registry of the schemas built into ZeroTrace. It panics
+// if they break the evolution rules, which the package tests catch first.
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		defaultRegistry, defaultErr = load()
+	})
+	if defaultErr != nil {
+		panic(defaultErr)
+	}
+	return defaultRegistry
+}
+
+// load reads the embedded schemas, named <type>.v<major>.<minor>.json, and
+// checks that each minor version is compatible with the one before it
+func load() (*Registry, error) {
+	files, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+
+	dataSchemas := make(map[string]map[string]map[string]interface{})
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".json")
+		i := strings.LastIndex(name, ".v")
+		if i < 0 {
+			return nil, fmt.Errorf("schema file %s is not named <type>.v<version>.json", file.Name())
+		}
+		eventType, version := name[:i], name[i+2:]
+		if _, _, ok := parseVersion(version); !ok {
+			return nil, fmt.Errorf("schema file %s has invalid version %q", file.Name(), version)
+		}
+
+		raw, err := schemaFiles.ReadFile(path.Join("schemas", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var schema map[string]interface{}
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil, fmt.Errorf("schema file %s: %w", file.Name(), err)
+		}
+		if dataSchemas[eventType] == nil {
+			dataSchemas[eventType] = make(map[string]map[string]interface{})
+		}
+		dataSchemas[eventType][version] = schema
+	}
+
+	r := &Registry{schemas: make(map[string]map[string]map[string]interface{})}
+	for eventType, versions := range dataSchemas {
+		ordered := make([]string, 0, len(versions))
+		for version := range versions {
+			ordered = append(ordered, version)
+		}
+		sort.Slice(ordered, func(i, j int) bool { return versionLess(ordered[i], ordered[j]) })
+
+		published := EventType{Type: eventType, Latest: ordered[len(ordered)-1]}
+		r.schemas[eventType] = make(map[string]map[string]interface{})
+		for i, version := range ordered {
+			if i > 0 {
+				previous := ordered[i-1]
+				if sameMajor(previous, version) {
+					if err := checkCompatible(versions[previous], versions[version], "data"); err != nil {
+						return nil, fmt.Errorf("%s %s is not backward compatible with %s: %w", eventType, version, previous, err)
+					}
+				}
+			}
+			r.schemas[eventType][version] = eventSchema(eventType, version, versions[version])
+			description, _ := versions[version]["description"].(string)
+			published.Versions = append(published.Versions, SchemaVersion{
+				Version:     version,
+				Description: description,
+				SchemaURL:   fmt.Sprintf("/api/v2/events/types/%s/versions/%s/schema", eventType, version),
+			})
+		}
+		r.types = append(r.types, published)
+	}
+	sort.Slice(r.types, func(i, j int) bool { return r.types[i].Type < r.types[j].Type })
+
+	return r, nil
+}
+
+// Types lists every event type and its published versions
+func (r *Registry) Types() []EventType {
+	return r.types
+}
+
+// Schema returns the JSON schema of a version of an event type
+func (r *Registry) Schema(eventType, version string) (map[string]interface{}, bool) {
+	schema, ok := r.schemas[eventType][version]
+	return schema, ok
+}
+
+// Latest returns the newest schema version of an event type
+func (r *Registry) Latest(eventType string) (string, bool) {
+	for _, t := range r.types {
+		if t.Type == eventType {
+			return t.Latest, true
+		}
+	}
+	return "", false
+}
+
+// eventSchema wraps an event type's data schema in the event envelope
+func eventSchema(eventType, version string, data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                fmt.Sprintf("%s v%s", eventType, version),
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"id":             map[string]interface{}{"type": "string", "format": "uuid"},
+			"type":           map[string]interface{}{"const": eventType},
+			"schema_version": map[string]interface{}{"const": version},
+			"occurred_at":    map[string]interface{}{"type": "string", "format": "date-time"},
+			"data":           withoutAdditionalProperties(data),
+		},
+		"required": []interface{}{"id", "type", "schema_version", "occurred_at", "data"},
+	}
+}
+
+// withoutAdditionalProperties closes every object in a schema, so events
+// cannot carry fields their schema does not document
+func withoutAdditionalProperties(schema map[string]interface{}) map[string]interface{} {
+	closed := make(map[string]interface{}, len(schema)+1)
+	for k, v := range schema {
+		closed[k] = v
+	}
+	if closed["type"] == "object" {
+		if _, set := closed["additionalProperties"]; !set {
+			closed["additionalProperties"] = false
+		}
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		closedProperties := make(map[string]interface{}, len(properties))
+		for name, property := range properties {
+			if p, ok := property.(map[string]interface{}); ok {
+				closedProperties[name] = withoutAdditionalProperties(p)
+			} else {
+				closedProperties[name] = property
+			}
+		}
+		closed["properties"] = closedProperties
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		closed["items"] = withoutAdditionalProperties(items)
+	}
+	return closed
+}
+
+// parseVersion splits a MAJOR.MINOR version
+func parseVersion(version string) (int, int, bool) {
+	majorText, minorText, found := strings.Cut(version, ".")
+	if !found {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(majorText)
+	if err != nil || major < 1 {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(minorText)
+	if err != nil || minor < 0 {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+func versionLess(a, b string) bool {
+	aMajor, aMinor, _ := parseVersion(a)
+	bMajor, bMinor, _ := parseVersion(b)
+	if aMajor != bMajor {
+		return aMajor < bMajor
+	}
+	return aMinor < bMinor
+}
+
+func sameMajor(a, b string) bool {
+	aMajor, _, _ := parseVersion(a)
+	bMajor, _, _ := parseVersion(b)
+	return aMajor == bMajor
+}
+
+// checkCompatible reports how a newer schema breaks consumers of an older one
+func checkCompatible(older, newer map[string]interface{}, at string) error {
+	if older["type"] != newer["type"] {
+		return fmt.Errorf("%s changed type from %v to %v", at, older["type"], newer["type"])
+	}
+
+	if olderEnum, ok := older["enum"].([]interface{}); ok {
+		newerEnum, _ := newer["enum"].([]interface{})
+		for _, value := range olderEnum {
+			if !containsValue(newerEnum, value) {
+				return fmt.Errorf("%s dropped enum value %v", at, value)
+			}
+		}
+	}
+
+	olderProperties, _ := older["properties"].(map[string]interface{})
+	newerProperties, _ := newer["properties"].(map[string]interface{})
+	for name, olderProperty := range olderProperties {
+		newerProperty, ok := newerProperties[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.%s was removed", at, name)
+		}
+		if p, ok := olderProperty.(map[string]interface{}); ok {
+			if err := checkCompatible(p, newerProperty, at+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	olderRequired, _ := older["required"].([]interface{})
+	newerRequired, _ := newer["required"].([]interface{})
+	for _, name := range newerRequired {
+		if !containsValue(olderRequired, name) {
+			return fmt.Errorf("%s.%v became required", at, name)
+		}
+	}
+	for _, name := range olderRequired {
+		if !containsValue(newerRequired, name) {
+			return fmt.Errorf("%s.%v became optional", at, name)
+		}
+	}
+
+	olderItems, _ := older["items"].(map[string]interface{})
+	newerItems, _ := newer["items"].(map[string]interface{})
+	if olderItems != nil {
+		if newerItems == nil {
+			return fmt.Errorf("%s items schema was removed", at)
+		}
+		return checkCompatible(olderItems, newerItems, at+"[]")
+	}
+	return nil
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
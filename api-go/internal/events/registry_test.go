@@ -0,0 +1,92 @@
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDefaultRegistryLoadsPublishedSchemas(t *testing.T) {
+	registry, err := load()
+	if err != nil {
+		t.Fatalf("published schemas break the evolution rules: %v", err)
+	}
+	if version, ok := registry.Latest(ExportFinished); !ok || version != "1.0" {
+		t.Errorf("expected %s at version 1.0, got %q", ExportFinished, version)
+	}
+}
+
+func TestCheckCompatibleRejectsBreakingChanges(t *testing.T) {
+	older := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":     map[string]interface{}{"type": "string"},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"completed", "failed"}},
+		},
+		"required": []interface{}{"id"},
+	}
+	withProperties := func(properties map[string]interface{}, required ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+	}
+
+	cases := map[string]struct {
+		newer map[string]interface{}
+		err   string
+	}{
+		"optional field added": {withProperties(map[string]interface{}{
+			"id":     map[string]interface{}{"type": "string"},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"completed", "failed", "cancelled"}},
+			"size":   map[string]interface{}{"type": "integer"},
+		}, "id"), ""},
+		"field removed": {withProperties(map[string]interface{}{
+			"id": map[string]interface{}{"type": "string"},
+		}, "id"), "data.status was removed"},
+		"field retyped": {withProperties(map[string]interface{}{
+			"id":     map[string]interface{}{"type": "integer"},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"completed", "failed"}},
+		}, "id"), "data.id changed type"},
+		"field became required": {withProperties(map[string]interface{}{
+			"id":     map[string]interface{}{"type": "string"},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"completed", "failed"}},
+		}, "id", "status"), "data.status became required"},
+		"enum value dropped": {withProperties(map[string]interface{}{
+			"id":     map[string]interface{}{"type": "string"},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"completed"}},
+		}, "id"), "dropped enum value failed"},
+	}
+
+	for name, tc := range cases {
+		err := checkCompatible(older, tc.newer, "data")
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s: expected compatible, got %v", name, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: expected error containing %q, got %v", name, tc.err, err)
+		}
+	}
+}
+
+func TestValidateRejectsUndocumentedFields(t *testing.T) {
+	registry := Default()
+
+	event, err := registry.New(ExportFinished, map[string]interface{}{
+		"export_id": uuid.New().String(),
+		"status":    "completed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Validate(event); err != nil {
+		t.Errorf("expected a valid event, got %v", err)
+	}
+
+	event.Data = map[string]interface{}{
+		"export_id": uuid.New().String(),
+		"status":    "completed",
+		"bucket":    "internal",
+	}
+	if err := registry.Validate(event); err == nil || !strings.Contains(err.Error(), "event.data.bucket is not in the schema") {
+		t.Errorf("expected undocumented field to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// DepthLimit rejects operations nesting fields deeper than the limit before
+// they run. gqlgen limits complexity but not depth, and a deep query can be
+// cheap by complexity while still fanning out across every relation.
+type DepthLimit int
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = DepthLimit(0)
+
+// ExtensionName names the extension in gqlgen's extension list
+func (DepthLimit) ExtensionName() string { return "DepthLimit" }
+
+// Validate accepts any schema
+func (DepthLimit) Validate(graphql.ExecutableSchema) error { return nil }
+
+// MutateOperationContext checks the operation's depth once it is validated
+func (l DepthLimit) MutateOperationContext(_ context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	depth := selectionDepth(opCtx.Operation.SelectionSet)
+	if depth <= int(l) {
+		return nil
+	}
+	err := gqlerror.Errorf("operation has a depth of %d, which exceeds the limit of %d", depth, l)
+	errcode.Set(err, "DEPTH_LIMIT_EXCEEDED")
+	return err
+}
+
+// selectionDepth is how deep selections nest fields, counting fragments'
+// fields at the depth they are spread at. Validation has already rejected
+// fragment cycles.
+func selectionDepth(selections ast.SelectionSet) int {
+	depth := 0
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			depth = max(depth, 1+selectionDepth(s.SelectionSet))
+		case *ast.InlineFragment:
+			depth = max(depth, selectionDepth(s.SelectionSet))
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				depth = max(depth, selectionDepth(s.Definition.SelectionSet))
+			}
+		}
+	}
+	return depth
+}
@@ -0,0 +1,39 @@
+// Package graph is the GraphQL executor for the dashboard endpoint, generated
+// by gqlgen from schema.graphqls. Its resolvers are in services.GraphQLService.
+package graph
+
+//go:generate go run github.com/99designs/gqlgen@v0.17.70 generate
+
+import (
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Vulnerability is a vulnerability with the agent that reported it
+type Vulnerability struct {
+	models.Vulnerability
+	Agent *models.Agent `json:"agent"`
+}
+
+// Enrichment is what enrichment recorded about a vulnerability, decoded from
+// its enrichment data
+type Enrichment struct {
+	Source          string                    `json:"source"`
+	PublishedDate   string                    `json:"published_date"`
+	LastModified    string                    `json:"last_modified"`
+	SoftwareName    string                    `json:"software_name"`
+	SoftwareVersion string                    `json:"software_version"`
+	CPEIdentifier   string                    `json:"cpe_identifier"`
+	CPEConfidence   float64                   `json:"cpe_confidence"`
+	FixVersion      string                    `json:"fix_version"`
+	ThreatIntel     []models.ThreatIntelMatch `json:"threat_intel"`
+}
+
+// Analytics is a snapshot of an organization's agents, which its analytics
+// fields are resolved from
+type Analytics struct {
+	OrganizationID uuid.UUID
+	Agents         []*models.Agent
+	Stats          map[string]interface{} // From AgentService.GetAgentStats
+}
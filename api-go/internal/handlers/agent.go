@@ -2,25 +2,56 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/queue"
 	"zerotrace/api/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// GetAgents retrieves all agents for a company
+// Limits on the page size of GetAgents
+const (
+	defaultAgentsLimit = 50
+	maxAgentsLimit     = 500
+)
+
+// GetAgents retrieves a page of agents, oldest first. limit sets the page
+// size, and cursor is the next_cursor of the previous page. organization_id,
+// status, tag (key=value or a bare key, repeatable) and group (an ID, or a
+// name within organization_id) narrow the agents listed.
 func GetAgents(agentService *services.AgentService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// For public endpoint, get all agents without company filter
-		agents := agentService.GetAllAgents()
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAgentsLimit)))
+		if err != nil || limit <= 0 || limit > maxAgentsLimit {
+			BadRequest(c, "INVALID_QUERY", fmt.Sprintf("limit must be between 1 and %d", maxAgentsLimit), nil)
+			return
+		}
+		filter, ok := agentFilterFromQuery(c)
+		if !ok {
+			return
+		}
+
+		// For public endpoint, agents of every company unless filtered
+		agents, nextCursor, err := agentService.ListAgents(filter, limit, c.Query("cursor"))
+		if errors.Is(err, services.ErrInvalidAgentCursor) {
+			BadRequest(c, "INVALID_CURSOR", "Invalid cursor", nil)
+			return
+		}
+		if err != nil {
+			respondAgentTagError(c, err, "AGENTS_FETCH_FAILED", "Failed to fetch agents")
+			return
+		}
 
-		SuccessResponse(c, http.StatusOK, agents, "Agents retrieved successfully")
+		SuccessPageResponse(c, http.StatusOK, agents, nextCursor, "Agents retrieved successfully")
 	}
 }
 
@@ -67,8 +98,40 @@ func GetAgentStats(agentService *services.AgentService) gin.HandlerFunc {
 	}
 }
 
-// AgentHeartbeat handles agent heartbeat updates
-func AgentHeartbeat(agentService *services.AgentService) gin.HandlerFunc {
+// GetToolVersionDrift reports scanner tool versions across the caller's
+// organization's agents and which of them are outdated
+func GetToolVersionDrift(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		report := agentService.GetToolVersionDrift(organizationID)
+
+		SuccessResponse(c, http.StatusOK, report, "Tool version drift retrieved successfully")
+	}
+}
+
+// GetMissingTools reports which of the caller's organization's agents lack
+// each optional scanner tool
+func GetMissingTools(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		report := agentService.GetMissingTools(organizationID)
+
+		SuccessResponse(c, http.StatusOK, report, "Missing scanner tools retrieved successfully")
+	}
+}
+
+// AgentHeartbeat handles agent heartbeat updates and hands the agent any
+// queued commands, and the release to update to when the agent reports its
+// platform and an older version in its metadata
+func AgentHeartbeat(agentService *services.AgentService, commandService *services.AgentCommandService, updateService *services.UpdateService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Temporary struct to bind the request payload with string IDs
 		var req struct {
@@ -86,6 +149,9 @@ func AgentHeartbeat(agentService *services.AgentService) gin.HandlerFunc {
 			BadRequest(c, "INVALID_HEARTBEAT", "Invalid heartbeat data", err.Error())
 			return
 		}
+		if !certifiedAgent(c, req.AgentID) {
+			return
+		}
 
 		// Parse string IDs into UUIDs
 		agentUUID, err := uuid.Parse(req.AgentID)
@@ -133,14 +199,78 @@ func AgentHeartbeat(agentService *services.AgentService) gin.HandlerFunc {
 			return
 		}
 
+		// A failure here only delays commands until the next heartbeat
+		commands, err := commandService.PendingCommands(agentUUID)
+		if err != nil {
+			log.Printf("[Heartbeat Handler] Failed to load pending commands for agent %s: %v", agentUUID, err)
+		}
+
+		// Likewise for updates, which wait for the next heartbeat
+		platform, _ := req.Metadata["platform"].(string)
+		version, _ := req.Metadata["version"].(string)
+		update, err := updateService.UpdateFor(platform, version)
+		if err != nil {
+			log.Printf("[Heartbeat Handler] Failed to check for updates for agent %s: %v", agentUUID, err)
+		}
+
 		c.JSON(http.StatusOK, models.APIResponse{
-			Success:   true,
+			Success: true,
+			Data: gin.H{
+				"commands": commands,
+				"update":   update,
+			},
 			Message:   "Heartbeat updated successfully",
 			Timestamp: time.Now(),
 		})
 	}
 }
 
+// UpdateCommandStatus handles an agent reporting progress on a command
+func UpdateCommandStatus(commandService *services.AgentCommandService, verificationService *services.FindingVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		commandID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_COMMAND_ID", "Invalid command ID format", err.Error())
+			return
+		}
+
+		var req models.AgentCommandStatusUpdate
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid command status", err.Error())
+			return
+		}
+		if !certifiedAgent(c, req.AgentID) {
+			return
+		}
+
+		agentUUID, err := uuid.Parse(req.AgentID)
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID format", err.Error())
+			return
+		}
+
+		cmd, err := commandService.UpdateCommandStatus(agentUUID, commandID, &req)
+		if err != nil {
+			if errors.Is(err, services.ErrCommandNotFound) {
+				NotFound(c, "COMMAND_NOT_FOUND", "Command not found")
+				return
+			}
+			BadRequest(c, "INVALID_STATUS_UPDATE", "Failed to update command status", err.Error())
+			return
+		}
+
+		// A finished re-check settles its finding's verification. If recording
+		// it fails, the verification picks the outcome up when next read.
+		if cmd.Type == models.AgentCommandVerifyFinding && (cmd.Status == models.AgentCommandCompleted || cmd.Status == models.AgentCommandFailed) {
+			if err := verificationService.Complete(c.Request.Context(), cmd); err != nil {
+				log.Printf("[UpdateCommandStatus] Failed to record verification of command %s: %v", cmd.ID, err)
+			}
+		}
+
+		SuccessResponse(c, http.StatusOK, cmd, "Command status updated successfully")
+	}
+}
+
 // RegisterAgent handles agent registration
 func RegisterAgent(agentService *services.AgentService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -160,6 +290,9 @@ func RegisterAgent(agentService *services.AgentService) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
 			return
 		}
+		if !certifiedAgent(c, req.ID) {
+			return
+		}
 
 		// Parse string IDs into UUIDs
 		agentUUID, err := uuid.Parse(req.ID)
@@ -206,12 +339,24 @@ func RegisterAgent(agentService *services.AgentService) gin.HandlerFunc {
 }
 
 // AgentResults handles scan results from agents
-func AgentResults(agentService *services.AgentService, enrichmentService *services.EnrichmentService) gin.HandlerFunc {
+// Scans too large for one submission are sent as chunks carrying a "batch"
+// (batch_id, sequence, total). Chunks are stored until the batch is complete,
+// and the chunk that completes it processes the whole batch as one scan.
+func AgentResults(agentService *services.AgentService, enrichmentService *services.EnrichmentService, scheduler *queue.FairScheduler, ingestion *services.ResultIngestionService, resultBatches *services.ResultBatchService, evidence *services.EvidenceService, webhooks *services.WebhookService, licenses *services.LicensePolicyService) gin.HandlerFunc {
+	pipeline := resultPipeline{agentService, enrichmentService, ingestion, evidence, webhooks, licenses}
 	return func(c *gin.Context) {
 		log.Printf("[AgentResults] *** REQUEST RECEIVED *** from %s", c.ClientIP())
 
 		// Log raw request body for debugging
-		bodyBytes, _ := c.GetRawData()
+		bodyBytes, err := c.GetRawData()
+		if err != nil {
+			if PayloadTooLarge(c, err) {
+				log.Printf("[AgentResults] Rejected oversized submission from %s", c.ClientIP())
+				return
+			}
+			BadRequest(c, "INVALID_REQUEST", "Failed to read request body", err.Error())
+			return
+		}
 		log.Printf("[AgentResults] Received request from agent, body length: %d bytes", len(bodyBytes))
 
 		// Restore body for binding
@@ -221,6 +366,7 @@ func AgentResults(agentService *services.AgentService, enrichmentService *servic
 			AgentID  string                   `json:"agent_id" binding:"required"`
 			Results  []models.AgentScanResult `json:"results"`
 			Metadata map[string]interface{}   `json:"metadata"`
+			Batch    *models.ResultChunkInfo  `json:"batch"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -234,36 +380,76 @@ func AgentResults(agentService *services.AgentService, enrichmentService *servic
 			return
 		}
 
+		if !certifiedAgent(c, req.AgentID) {
+			return
+		}
 		log.Printf("[AgentResults] Successfully parsed request for agent %s with %d results", req.AgentID, len(req.Results))
 
-		// Extract dependencies from scan results for enrichment
-		var allDependencies []models.Dependency
-		for _, result := range req.Results {
-			allDependencies = append(allDependencies, result.Dependencies...)
-		}
+		// Store chunks of a split submission until the whole batch has arrived
+		var batchStatus *models.ResultBatchStatus
+		if req.Batch != nil {
+			agentUUID, err := uuid.Parse(req.AgentID)
+			if err != nil {
+				BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID format", err.Error())
+				return
+			}
+			var organizationID uuid.UUID
+			if agent, exists := agentService.GetAgent(agentUUID); exists {
+				organizationID = agent.OrganizationID
+			}
 
-		// Enrich dependencies with CVE data
-		if len(allDependencies) > 0 {
-			log.Printf("[AgentResults] Enriching %d dependencies with CVE data", len(allDependencies))
-			enrichedVulns, err := enrichmentService.EnrichDependencies(allDependencies)
+			status, assembled, err := resultBatches.AddChunk(agentUUID, organizationID, *req.Batch, req.Results, req.Metadata)
 			if err != nil {
-				log.Printf("[AgentResults] Enrichment failed: %v", err)
-				// Continue without enrichment rather than failing
-			} else {
-				log.Printf("[AgentResults] Found %d vulnerabilities from enrichment", len(enrichedVulns))
-				// Store enriched vulnerabilities in metadata
-				if req.Metadata == nil {
-					req.Metadata = make(map[string]interface{})
+				respondChunkError(c, err)
+				return
+			}
+			if assembled == nil {
+				message := "Result chunk received"
+				if status.Duplicate {
+					message = "Result chunk already received"
 				}
-				req.Metadata["enriched_vulnerabilities"] = enrichedVulns
-				req.Metadata["enrichment_timestamp"] = time.Now()
+				SuccessResponse(c, http.StatusAccepted, status, message)
+				return
+			}
+
+			log.Printf("[AgentResults] Batch %s complete (%d chunks), processing %d results", status.BatchID, status.Total, len(assembled.Results))
+			batchStatus = status
+			req.Results = assembled.Results
+			req.Metadata = assembled.Metadata
+		}
+		// A batch that fails to process is reopened so the agent's retry processes it again
+		releaseBatch := func() {
+			if batchStatus == nil {
+				return
+			}
+			if err := resultBatches.ReleaseBatch(batchStatus.BatchID); err != nil {
+				log.Printf("[AgentResults] Failed to reopen batch %s: %v", batchStatus.BatchID, err)
 			}
 		}
 
-		// Update agent with results (including enriched vulnerabilities)
-		err := agentService.UpdateAgentResults(req.AgentID, req.Results, req.Metadata)
+		// Wait for this organization's turn so one large tenant cannot starve the rest
+		orgID := ""
+		if agentUUID, err := uuid.Parse(req.AgentID); err == nil {
+			if agent, exists := agentService.GetAgent(agentUUID); exists {
+				orgID = agent.OrganizationID.String()
+			}
+		}
+		release, err := scheduler.Acquire(c.Request.Context(), orgID)
 		if err != nil {
-			log.Printf("[AgentResults] Failed to update agent results: %v", err)
+			releaseBatch()
+			log.Printf("[AgentResults] Gave up waiting for a processing slot for org %s: %v", orgID, err)
+			c.JSON(http.StatusServiceUnavailable, models.APIResponse{
+				Success:   false,
+				Message:   "Processing capacity unavailable, retry later",
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		defer release()
+
+		if err := pipeline.process(c.Request.Context(), req.AgentID, req.Results, req.Metadata); err != nil {
+			releaseBatch()
+			log.Printf("[AgentResults] Failed to record agent results, rolled back: %v", err)
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success:   false,
 				Message:   "Failed to update agent results: " + err.Error(),
@@ -272,11 +458,109 @@ func AgentResults(agentService *services.AgentService, enrichmentService *servic
 			return
 		}
 
-		c.JSON(http.StatusOK, models.APIResponse{
+		response := models.APIResponse{
 			Success:   true,
 			Message:   "Scan results received successfully",
 			Timestamp: time.Now(),
-		})
+		}
+		if batchStatus != nil {
+			response.Data = batchStatus
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// respondChunkError maps result batch errors to HTTP responses
+func respondChunkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidChunk):
+		BadRequest(c, "INVALID_CHUNK", "Invalid result chunk", err.Error())
+	case errors.Is(err, services.ErrChunkConflict):
+		ErrorResponse(c, http.StatusConflict, "CHUNK_CONFLICT", "Result chunk conflicts with its batch", err.Error())
+	default:
+		InternalServerError(c, "CHUNK_STORE_FAILED", "Failed to store result chunk", err)
+	}
+}
+
+// resultScope returns the scan type that produced a result, used to decide
+// which previously open findings a missing finding can resolve
+func resultScope(result models.AgentScanResult) string {
+	if scanType, ok := result.Metadata["scan_type"].(string); ok && scanType != "" {
+		return scanType
+	}
+	return "software"
+}
+
+// resultScopes groups a submission's findings by the scan type of the result
+// that reported them, merging the finding deltas of the results of each
+// scope. Each result's scope is listed even without findings, so findings
+// its scan no longer reports are resolved. Findings enriched from
+// dependencies go to the scope of the result that reported the package.
+func resultScopes(results []models.AgentScanResult, enriched []models.Vulnerability) []services.ScopeFindings {
+	var scopes []services.ScopeFindings
+	index := make(map[string]int)
+	scopeFor := func(scope string) *services.ScopeFindings {
+		i, ok := index[scope]
+		if !ok {
+			i = len(scopes)
+			index[scope] = i
+			scopes = append(scopes, services.ScopeFindings{Scope: scope})
+		}
+		return &scopes[i]
+	}
+
+	packageScopes := make(map[string]string)
+	dependencyScope := ""
+	for _, result := range results {
+		scope := scopeFor(resultScope(result))
+		scope.Findings = append(scope.Findings, result.Vulnerabilities...)
+		if result.Delta != nil {
+			if scope.Delta == nil {
+				scope.Delta = &models.FindingDelta{}
+			}
+			scope.Delta.Resolved = append(scope.Delta.Resolved, result.Delta.Resolved...)
+			scope.Delta.Unchanged = append(scope.Delta.Unchanged, result.Delta.Unchanged...)
+		}
+		for _, dep := range result.Dependencies {
+			if _, seen := packageScopes[dep.Name]; !seen {
+				packageScopes[dep.Name] = scope.Scope
+			}
+			if dependencyScope == "" {
+				dependencyScope = scope.Scope
+			}
+		}
+	}
+
+	for _, finding := range enriched {
+		name, ok := packageScopes[finding.PackageName]
+		if !ok {
+			name = dependencyScope
+		}
+		if name == "" {
+			name = "software"
+		}
+		scope := scopeFor(name)
+		scope.Findings = append(scope.Findings, finding)
+	}
+	return scopes
+}
+
+// GetFlappingFindings lists the caller's organization's findings that keep
+// toggling between open and resolved
+func GetFlappingFindings(findingStates *services.FindingStateService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		findings, err := findingStates.GetFlappingFindings(organizationID)
+		if err != nil {
+			InternalServerError(c, "FLAPPING_QUERY_FAILED", "Failed to retrieve flapping findings", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, findings, "Flapping findings retrieved successfully")
 	}
 }
 
@@ -297,6 +581,9 @@ func AgentStatus(agentService *services.AgentService) gin.HandlerFunc {
 			})
 			return
 		}
+		if !certifiedAgent(c, req.AgentID) {
+			return
+		}
 
 		// Update agent status
 		agentService.UpdateAgentStatus(req.AgentID, req.Status, req.Metadata)
@@ -460,6 +747,9 @@ func UpdateSystemInfo(agentService *services.AgentService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req SystemInfoRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
+			if PayloadTooLarge(c, err) {
+				return
+			}
 			c.JSON(http.StatusBadRequest, models.APIResponse{
 				Success:   false,
 				Message:   "Invalid request payload",
@@ -467,6 +757,9 @@ func UpdateSystemInfo(agentService *services.AgentService) gin.HandlerFunc {
 			})
 			return
 		}
+		if !certifiedAgent(c, req.AgentID) {
+			return
+		}
 
 		// Update agent with system information
 		err := agentService.UpdateAgentSystemInfo(req.AgentID, req.SystemInfo)
@@ -489,7 +782,7 @@ func UpdateSystemInfo(agentService *services.AgentService) gin.HandlerFunc {
 }
 
 // NetworkScanResults handles network scan results from agents
-func NetworkScanResults(agentService *services.AgentService) gin.HandlerFunc {
+func NetworkScanResults(agentService *services.AgentService, networkAssetService *services.NetworkAssetService, evidence *services.EvidenceService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Printf("[NetworkScanResults] Request received from %s", c.ClientIP())
 
@@ -499,6 +792,9 @@ func NetworkScanResults(agentService *services.AgentService) gin.HandlerFunc {
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
+			if PayloadTooLarge(c, err) {
+				return
+			}
 			log.Printf("[NetworkScanResults] JSON binding error: %v", err)
 			c.JSON(http.StatusBadRequest, models.APIResponse{
 				Success:   false,
@@ -508,8 +804,17 @@ func NetworkScanResults(agentService *services.AgentService) gin.HandlerFunc {
 			return
 		}
 
+		if !certifiedAgent(c, req.AgentID) {
+			return
+		}
 		log.Printf("[NetworkScanResults] Successfully parsed network scan results for agent %s", req.AgentID)
 
+		agentUUID, _ := uuid.Parse(req.AgentID)
+		agent, agentExists := agentService.GetAgent(agentUUID)
+		if agentExists {
+			evidence.AttachNetworkEvidence(c.Request.Context(), agent.ID, agent.OrganizationID, req.ScanResult)
+		}
+
 		// Store network scan results in agent metadata
 		// In a full implementation, this would be stored in a separate table
 		err := agentService.UpdateAgentMetadata(req.AgentID, map[string]interface{}{
@@ -526,6 +831,13 @@ func NetworkScanResults(agentService *services.AgentService) gin.HandlerFunc {
 			return
 		}
 
+		// Merge discovered hosts with those other agents have already reported
+		if agentExists {
+			if err := networkAssetService.IngestScan(agent.ID, agent.OrganizationID, req.ScanResult, time.Now()); err != nil {
+				log.Printf("[NetworkScanResults] Failed to update network assets: %v", err)
+			}
+		}
+
 		c.JSON(http.StatusOK, models.APIResponse{
 			Success:   true,
 			Message:   "Network scan results received successfully",
@@ -533,3 +845,20 @@ func NetworkScanResults(agentService *services.AgentService) gin.HandlerFunc {
 		})
 	}
 }
+
+// certifiedAgent reports whether agentID, as an agent's request names itself,
+// is the agent its client certificate was issued to, responding 403 when it
+// isn't. Without mutual TLS no agent is certified and every ID is accepted.
+func certifiedAgent(c *gin.Context, agentID string) bool {
+	certified := c.GetString("agent_id")
+	if certified == "" {
+		return true
+	}
+	a, errA := uuid.Parse(certified)
+	b, errB := uuid.Parse(agentID)
+	if (errA == nil && errB == nil && a == b) || certified == agentID {
+		return true
+	}
+	ErrorResponse(c, http.StatusForbidden, "AGENT_ID_MISMATCH", "Agent ID does not match the client certificate", nil)
+	return false
+}
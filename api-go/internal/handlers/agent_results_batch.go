@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"time"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/queue"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// resultPipeline enriches, tags and records agent scan result submissions
+type resultPipeline struct {
+	agentService      *services.AgentService
+	enrichmentService *services.EnrichmentService
+	ingestion         *services.ResultIngestionService
+	evidence          *services.EvidenceService
+	webhooks          *services.WebhookService
+	licenses          *services.LicensePolicyService
+}
+
+// process enriches results' dependencies with CVE data, scores their findings'
+// exploit probability, stores their evidence, adds findings for dependencies
+// whose license violates the organization's license policies, tags their
+// findings with the organization's threat intel and records them as one
+// submission. Once recorded, critical and high findings that opened or
+// reopened are sent to the organization's webhooks, unless they are flapping
+// or suppressed. On error nothing was recorded.
+func (p resultPipeline) process(ctx context.Context, agentID string, results []models.AgentScanResult, metadata map[string]interface{}) error {
+	// Extract dependencies from scan results for enrichment
+	var allDependencies []models.Dependency
+	for _, result := range results {
+		allDependencies = append(allDependencies, result.Dependencies...)
+	}
+
+	// Enrich dependencies with CVE data
+	var enrichedVulns []models.Vulnerability
+	enrichmentComplete := true
+	if len(allDependencies) > 0 {
+		log.Printf("[AgentResults] Enriching %d dependencies with CVE data", len(allDependencies))
+		var err error
+		enrichedVulns, err = p.enrichmentService.EnrichDependencies(allDependencies)
+		if err != nil {
+			log.Printf("[AgentResults] Enrichment failed: %v", err)
+			enrichmentComplete = false
+			// Continue without enrichment rather than failing
+		} else {
+			log.Printf("[AgentResults] Found %d vulnerabilities from enrichment", len(enrichedVulns))
+			// Store enriched vulnerabilities in metadata
+			if metadata == nil {
+				metadata = make(map[string]interface{})
+			}
+			metadata["enriched_vulnerabilities"] = enrichedVulns
+			metadata["enrichment_timestamp"] = time.Now()
+		}
+	}
+
+	// Look up the exploit probability of every finding's CVE at once
+	findingSets := [][]models.Vulnerability{enrichedVulns}
+	for i := range results {
+		findingSets = append(findingSets, results[i].Vulnerabilities)
+	}
+	p.enrichmentService.ApplyEPSS(ctx, findingSets...)
+
+	// Move raw evidence attached to findings into object storage, and tag
+	// findings with the organization's own threat intel
+	var agent *models.Agent
+	if agentUUID, err := uuid.Parse(agentID); err == nil {
+		if found, exists := p.agentService.GetAgent(agentUUID); exists {
+			agent = found
+			p.evidence.AttachResultEvidence(ctx, agent.ID, agent.OrganizationID, results)
+			p.addLicenseFindings(agent.OrganizationID, results)
+			p.enrichmentService.ApplyThreatIntel(agent.OrganizationID, enrichedVulns)
+			for i := range results {
+				p.enrichmentService.ApplyThreatIntel(agent.OrganizationID, results[i].Vulnerabilities)
+			}
+		}
+	}
+
+	// Record the results (including enriched vulnerabilities), finding
+	// open/resolved transitions and host risk in one transaction. A failed
+	// enrichment gives an incomplete picture, which would wrongly resolve
+	// findings, so transitions are only tracked when it succeeded.
+	submission := &services.ResultIngestion{
+		AgentID:       agentID,
+		Results:       results,
+		Metadata:      metadata,
+		TrackFindings: len(results) > 0 && enrichmentComplete,
+	}
+	if submission.TrackFindings {
+		submission.Scopes = resultScopes(results, enrichedVulns)
+	}
+	transitions, err := p.ingestion.Ingest(submission)
+	if err != nil {
+		return err
+	}
+	if agent != nil {
+		findings := enrichedVulns
+		for _, result := range results {
+			findings = append(findings, result.Vulnerabilities...)
+		}
+		p.webhooks.NotifyFindings(agent, transitions, findings)
+	}
+	for _, t := range transitions {
+		if t.BecameFlapping {
+			log.Printf("[AgentResults] Finding %s on agent %s is flapping (%d toggles), suppressing further alerts", t.State.FindingKey, agentID, len(t.State.Transitions))
+		}
+	}
+	return nil
+}
+
+// addLicenseFindings adds a finding to each result for every dependency it
+// reports whose license violates the organization's license policies
+func (p resultPipeline) addLicenseFindings(organizationID uuid.UUID, results []models.AgentScanResult) {
+	if p.licenses == nil {
+		return
+	}
+	for i := range results {
+		findings, err := p.licenses.Findings(organizationID, results[i].Dependencies)
+		if err != nil {
+			log.Printf("[AgentResults] License policy check failed: %v", err)
+			return
+		}
+		results[i].Vulnerabilities = append(results[i].Vulnerabilities, findings...)
+	}
+}
+
+// AgentResultsBatch handles several scan results from an agent in one request,
+// such as a scan cycle's software and configuration results. Each result is
+// processed as its own submission, all or nothing, so one that fails does not
+// hold back the rest. The response reports each result's outcome in request
+// order, with 207 Multi-Status if any failed; the agent resends those alone.
+func AgentResultsBatch(agentService *services.AgentService, enrichmentService *services.EnrichmentService, scheduler *queue.FairScheduler, ingestion *services.ResultIngestionService, evidence *services.EvidenceService, webhooks *services.WebhookService, licenses *services.LicensePolicyService) gin.HandlerFunc {
+	pipeline := resultPipeline{agentService, enrichmentService, ingestion, evidence, webhooks, licenses}
+	return func(c *gin.Context) {
+		var req struct {
+			AgentID  string                   `json:"agent_id" binding:"required"`
+			Results  []models.AgentScanResult `json:"results" binding:"required,min=1"`
+			Metadata map[string]interface{}   `json:"metadata"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			if PayloadTooLarge(c, err) {
+				log.Printf("[AgentResults] Rejected oversized batch from %s", c.ClientIP())
+				return
+			}
+			BadRequest(c, "INVALID_REQUEST", "Invalid request body", err.Error())
+			return
+		}
+		if !certifiedAgent(c, req.AgentID) {
+			return
+		}
+		agentUUID, err := uuid.Parse(req.AgentID)
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID format", err.Error())
+			return
+		}
+		log.Printf("[AgentResults] Processing batch of %d results from agent %s", len(req.Results), req.AgentID)
+
+		// Wait for this organization's turn once for the whole batch
+		orgID := ""
+		if agent, exists := agentService.GetAgent(agentUUID); exists {
+			orgID = agent.OrganizationID.String()
+		}
+		release, err := scheduler.Acquire(c.Request.Context(), orgID)
+		if err != nil {
+			log.Printf("[AgentResults] Gave up waiting for a processing slot for org %s: %v", orgID, err)
+			ErrorResponse(c, http.StatusServiceUnavailable, "PROCESSING_UNAVAILABLE", "Processing capacity unavailable, retry later", err.Error())
+			return
+		}
+		defer release()
+
+		statuses := make([]models.ResultBatchItemStatus, len(req.Results))
+		failed := 0
+		for i, result := range req.Results {
+			statuses[i] = models.ResultBatchItemStatus{Index: i, ResultID: result.ID, Status: models.ResultItemProcessed}
+			// Each result is recorded with its own copy of the batch metadata
+			if err := pipeline.process(c.Request.Context(), req.AgentID, []models.AgentScanResult{result}, maps.Clone(req.Metadata)); err != nil {
+				log.Printf("[AgentResults] Failed to record result %d (%s) of batch from agent %s, rolled back: %v", i, result.ID, req.AgentID, err)
+				statuses[i].Status = models.ResultItemFailed
+				statuses[i].Error = err.Error()
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			c.JSON(http.StatusMultiStatus, models.APIResponse{
+				Success:   false,
+				Message:   fmt.Sprintf("%d of %d results failed to process", failed, len(req.Results)),
+				Data:      statuses,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		SuccessResponse(c, http.StatusOK, statuses, "Scan results received successfully")
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// agentFilterFromQuery reads the organization_id, status, tag and group query
+// parameters into an agent filter, responding with 400 if they are invalid
+func agentFilterFromQuery(c *gin.Context) (models.AgentFilter, bool) {
+	var filter models.AgentFilter
+	if raw := c.Query("organization_id"); raw != "" {
+		organizationID, err := uuid.Parse(raw)
+		if err != nil {
+			BadRequest(c, "INVALID_ORGANIZATION_ID", "Invalid organization ID", err.Error())
+			return filter, false
+		}
+		filter.OrganizationID = &organizationID
+	}
+
+	tags, err := services.ParseAgentTagFilter(c.QueryArray("tag"))
+	if err != nil {
+		BadRequest(c, "INVALID_TAG", "Invalid tag filter", err.Error())
+		return filter, false
+	}
+	filter.Tags = tags
+	filter.Status = c.Query("status")
+	filter.Group = c.Query("group")
+	return filter, true
+}
+
+// respondAgentTagError responds to the errors tag and group operations share,
+// and with a 500 for anything else
+func respondAgentTagError(c *gin.Context, err error, code, message string) {
+	switch {
+	case errors.Is(err, services.ErrAgentNotFound):
+		NotFound(c, "AGENT_NOT_FOUND", "Agent not found")
+	case errors.Is(err, services.ErrAgentGroupNotFound):
+		NotFound(c, "AGENT_GROUP_NOT_FOUND", "Agent group not found")
+	case errors.Is(err, services.ErrAgentGroupExists):
+		ErrorResponse(c, http.StatusConflict, "AGENT_GROUP_EXISTS", "An agent group with that name already exists", nil)
+	case errors.Is(err, services.ErrInvalidAgentTag):
+		BadRequest(c, "INVALID_TAG", "Invalid tags", err.Error())
+	case errors.Is(err, services.ErrEmptyAgentFilter):
+		BadRequest(c, "EMPTY_FILTER", "Filter must select by organization, agent, tag or group", nil)
+	default:
+		InternalServerError(c, code, message, err)
+	}
+}
+
+// GetAgentTags returns an agent's tags
+func GetAgentTags(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID", err.Error())
+			return
+		}
+
+		tags, err := agentService.AgentTags(agentID)
+		if err != nil {
+			respondAgentTagError(c, err, "GET_FAILED", "Failed to retrieve agent tags")
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, tags, "Agent tags retrieved successfully")
+	}
+}
+
+// UpdateAgentTags sets and removes tags on an agent
+func UpdateAgentTags(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID", err.Error())
+			return
+		}
+
+		var req models.UpdateAgentTagsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+
+		tags, err := agentService.UpdateAgentTags(agentID, req.Set, req.Remove)
+		if err != nil {
+			respondAgentTagError(c, err, "UPDATE_FAILED", "Failed to update agent tags")
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, tags, "Agent tags updated successfully")
+	}
+}
+
+// BulkTagAgents sets and removes tags on every one of the caller's
+// organization's agents a filter selects
+func BulkTagAgents(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		var req models.BulkAgentTagsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+
+		ids, err := agentService.TagAgents(organizationID, req.Filter, req.Set, req.Remove)
+		if err != nil {
+			respondAgentTagError(c, err, "UPDATE_FAILED", "Failed to update agent tags")
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, gin.H{"agent_ids": ids, "count": len(ids)}, "Agent tags updated successfully")
+	}
+}
+
+// CreateAgentGroup creates an organization's agent group
+func CreateAgentGroup(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+			return
+		}
+
+		var req models.CreateAgentGroupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+
+		group, err := agentService.CreateAgentGroup(organizationID, &req)
+		if err != nil {
+			respondAgentTagError(c, err, "CREATE_FAILED", "Failed to create agent group")
+			return
+		}
+
+		SuccessResponse(c, http.StatusCreated, group, "Agent group created successfully")
+	}
+}
+
+// ListAgentGroups lists an organization's agent groups
+func ListAgentGroups(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+			return
+		}
+
+		groups, err := agentService.ListAgentGroups(organizationID)
+		if err != nil {
+			InternalServerError(c, "LIST_FAILED", "Failed to list agent groups", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, groups, "Agent groups retrieved successfully")
+	}
+}
+
+// GetAgentGroup returns an organization's agent group with its agents
+func GetAgentGroup(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, groupID, ok := agentGroupParams(c)
+		if !ok {
+			return
+		}
+
+		group, agents, err := agentService.GetAgentGroup(organizationID, groupID)
+		if err != nil {
+			respondAgentTagError(c, err, "GET_FAILED", "Failed to retrieve agent group")
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, gin.H{"group": group, "agents": agents}, "Agent group retrieved successfully")
+	}
+}
+
+// DeleteAgentGroup deletes an organization's agent group
+func DeleteAgentGroup(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, groupID, ok := agentGroupParams(c)
+		if !ok {
+			return
+		}
+
+		if err := agentService.DeleteAgentGroup(organizationID, groupID); err != nil {
+			respondAgentTagError(c, err, "DELETE_FAILED", "Failed to delete agent group")
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, nil, "Agent group deleted successfully")
+	}
+}
+
+func agentGroupParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	groupID, err := uuid.Parse(c.Param("group_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_GROUP_ID", "Invalid agent group ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	return organizationID, groupID, true
+}
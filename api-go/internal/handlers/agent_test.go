@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAgentsRejectsBadRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/agents/", GetAgents(&services.AgentService{}))
+
+	for _, query := range []string{
+		"limit=0", "limit=501", "limit=ten", "cursor=%25%25", "cursor=bm90LWEtY3Vyc29y",
+		"organization_id=acme", "tag=-env", "tag=env=prod&tag=env=dev", "status=asleep", "group=production",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/agents/?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	for _, query := range []string{"", "tag=env=production&tag=os&status=online"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/agents/?"+query, nil))
+		assert.Equal(t, http.StatusOK, w.Code, query)
+		assert.NotContains(t, w.Body.String(), "next_cursor")
+	}
+}
+
+func TestBulkTagAgentsRequiresFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("company_id", uuid.NewString())
+		c.Next()
+	})
+	agentService := &services.AgentService{}
+	router.POST("/api/v2/agents/tags", BulkTagAgents(agentService))
+	router.PATCH("/api/v2/agents/:id/tags", UpdateAgentTags(agentService))
+
+	for body, code := range map[string]string{
+		`{"set": {"env": "production"}}`:                             "EMPTY_FILTER",
+		`{"filter": {"tags": {"os": "linux"}}, "set": {"env": ""}}`:  "INVALID_TAG",
+		`{"filter": {"tags": {"os": "linux"}}}`:                      "INVALID_TAG",
+		`{"filter": {"tags": {"os linux": ""}}, "remove": ["team"]}`: "INVALID_TAG",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v2/agents/tags", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), code, body)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/v2/agents/"+uuid.NewString()+"/tags", strings.NewReader(`{"set": {"env": "production"}}`)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAgentStatusRequiresCertifiedAgent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	agentID := uuid.New()
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// Stands in for AgentClientCert, which sets the agent its certificate names
+		if certified := c.GetHeader("X-Certified-Agent"); certified != "" {
+			c.Set("agent_id", certified)
+		}
+		c.Next()
+	})
+	router.POST("/api/agents/status", AgentStatus(&services.AgentService{}))
+
+	for _, tc := range []struct {
+		name, certified, agentID string
+		status                   int
+	}{
+		{"certified agent", agentID.String(), agentID.String(), http.StatusOK},
+		{"UUIDs compared case-insensitively", agentID.String(), strings.ToUpper(agentID.String()), http.StatusOK},
+		{"another agent", agentID.String(), uuid.NewString(), http.StatusForbidden},
+		{"mutual TLS disabled", "", uuid.NewString(), http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/agents/status", strings.NewReader(`{"agent_id": "`+tc.agentID+`", "status": "online"}`))
+		if tc.certified != "" {
+			req.Header.Set("X-Certified-Agent", tc.certified)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Code, tc.name)
+	}
+}
+
+func TestResultScopes(t *testing.T) {
+	result := func(scanType string, vulns ...models.Vulnerability) models.AgentScanResult {
+		r := models.AgentScanResult{Vulnerabilities: vulns}
+		if scanType != "" {
+			r.Metadata = map[string]interface{}{"scan_type": scanType}
+		}
+		return r
+	}
+	sshRoot := models.Vulnerability{Title: "SSH permits root login"}
+	telnet := models.Vulnerability{Title: "Telnet open"}
+	openssl := models.Vulnerability{CVEID: "CVE-2025-1111", PackageName: "openssl"}
+	leftPad := models.Vulnerability{CVEID: "CVE-2025-2222", PackageName: "left-pad"}
+
+	software := result("", openssl)
+	software.Dependencies = []models.Dependency{{Name: "openssl"}}
+	system := result("system", sshRoot)
+	system.Delta = &models.FindingDelta{Resolved: []string{"a"}}
+	systemAgain := result("system")
+	systemAgain.Delta = &models.FindingDelta{Unchanged: []string{"b"}}
+	network := result("network")
+
+	// The first result no longer decides the scope of the whole submission
+	scopes := resultScopes([]models.AgentScanResult{system, software, network, systemAgain}, []models.Vulnerability{leftPad, openssl})
+	require.Len(t, scopes, 3)
+
+	assert.Equal(t, "system", scopes[0].Scope)
+	assert.Equal(t, []models.Vulnerability{sshRoot}, scopes[0].Findings)
+	assert.Equal(t, &models.FindingDelta{Resolved: []string{"a"}, Unchanged: []string{"b"}}, scopes[0].Delta)
+
+	assert.Equal(t, "software", scopes[1].Scope)
+	assert.Equal(t, []models.Vulnerability{openssl, leftPad, openssl}, scopes[1].Findings, "enriched findings go to the scope that reported their package")
+	assert.Nil(t, scopes[1].Delta)
+
+	assert.Equal(t, "network", scopes[2].Scope, "a scope without findings is listed so its findings resolve")
+	assert.Empty(t, scopes[2].Findings)
+
+	// Enriched findings with no dependency result fall back to software
+	scopes = resultScopes([]models.AgentScanResult{result("network", telnet)}, []models.Vulnerability{leftPad})
+	require.Len(t, scopes, 2)
+	assert.Equal(t, "software", scopes[1].Scope)
+	assert.Equal(t, []models.Vulnerability{leftPad}, scopes[1].Findings)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AgentReleaseHandler handles publishing the agent releases agents update to
+type AgentReleaseHandler struct {
+	updateService *services.UpdateService
+}
+
+// NewAgentReleaseHandler creates a new agent release handler
+func NewAgentReleaseHandler(updateService *services.UpdateService) *AgentReleaseHandler {
+	return &AgentReleaseHandler{
+		updateService: updateService,
+	}
+}
+
+// ListReleases lists the published agent releases, of one platform with
+// ?platform=linux/amd64
+func (h *AgentReleaseHandler) ListReleases(c *gin.Context) {
+	releases, err := h.updateService.ListReleases(c.Query("platform"))
+	if err != nil {
+		InternalServerError(c, "LIST_FAILED", "Failed to list agent releases", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, releases, "Agent releases retrieved successfully")
+}
+
+// PublishRelease publishes an agent release for agents of its platform to
+// update to, recording the admin who published it
+func (h *AgentReleaseHandler) PublishRelease(c *gin.Context) {
+	var req models.AgentReleaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.Actor = c.GetString("user_id")
+
+	release, err := h.updateService.PublishRelease(&req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAgentRelease):
+			BadRequest(c, "INVALID_RELEASE", "Invalid agent release", err.Error())
+		case errors.Is(err, services.ErrAgentReleaseExists):
+			ErrorResponse(c, http.StatusConflict, "RELEASE_EXISTS", "The platform already has a release of that version", nil)
+		default:
+			InternalServerError(c, "PUBLISH_FAILED", "Failed to publish agent release", err)
+		}
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, release, "Agent release published successfully")
+}
+
+// DeleteRelease withdraws an agent release, so that no further agent updates to it
+func (h *AgentReleaseHandler) DeleteRelease(c *gin.Context) {
+	releaseID, err := uuid.Parse(c.Param("release_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_RELEASE_ID", "Invalid agent release ID", err.Error())
+		return
+	}
+
+	if err := h.updateService.DeleteRelease(releaseID); err != nil {
+		if errors.Is(err, services.ErrAgentReleaseNotFound) {
+			NotFound(c, "RELEASE_NOT_FOUND", "Agent release not found")
+			return
+		}
+		InternalServerError(c, "DELETE_FAILED", "Failed to delete agent release", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, nil, "Agent release deleted successfully")
+}
+
+// GetAgentUpdate returns the release an agent should update to, given its
+// ?platform= and ?version=, or null when it runs the latest one
+func GetAgentUpdate(updateService *services.UpdateService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		platform, version := c.Query("platform"), c.Query("version")
+		if platform == "" || version == "" {
+			BadRequest(c, "INVALID_REQUEST", "platform and version are required", nil)
+			return
+		}
+
+		release, err := updateService.UpdateFor(platform, version)
+		if err != nil {
+			InternalServerError(c, "UPDATE_CHECK_FAILED", "Failed to check for agent updates", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, release, "Agent update checked successfully")
+	}
+}
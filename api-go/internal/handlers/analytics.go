@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	analytics "zerotrace/api/internal/services/analytics"
 
@@ -53,19 +59,8 @@ func (h *AnalyticsHandler) GetDashboardHistory(c *gin.Context) {
 
 // GenerateRiskHeatmap generates a risk heatmap for an organization
 func (h *AnalyticsHandler) GenerateRiskHeatmap(c *gin.Context) {
-	organizationIDStr := c.Param("id")
-	organizationID, err := uuid.Parse(organizationIDStr)
-	if err != nil {
-		BadRequest(c, "INVALID_UUID", "Invalid organization ID format", err.Error())
-		return
-	}
-
-	heatmapType := c.DefaultQuery("type", "comprehensive")
-	timeRange := c.DefaultQuery("range", "30d")
-
-	heatmapData, err := h.analyticsService.GenerateRiskHeatmap(organizationID, heatmapType, timeRange)
-	if err != nil {
-		InternalServerError(c, "HEATMAP_GENERATION_FAILED", "Failed to generate heatmap", err)
+	heatmapData, ok := h.riskHeatmap(c)
+	if !ok {
 		return
 	}
 
@@ -74,86 +69,130 @@ func (h *AnalyticsHandler) GenerateRiskHeatmap(c *gin.Context) {
 
 // GetHeatmapHotspots returns hotspots from heatmap
 func (h *AnalyticsHandler) GetHeatmapHotspots(c *gin.Context) {
-	organizationIDStr := c.Param("id")
-	organizationID, err := uuid.Parse(organizationIDStr)
-	if err != nil {
-		BadRequest(c, "INVALID_UUID", "Invalid organization ID format", err.Error())
+	heatmapData, ok := h.riskHeatmap(c)
+	if !ok {
 		return
 	}
 
-	heatmapType := c.DefaultQuery("type", "comprehensive")
-	timeRange := c.DefaultQuery("range", "30d")
+	SuccessResponse(c, http.StatusOK, gin.H{"hotspots": heatmapData.Hotspots}, "Hotspots retrieved successfully")
+}
 
-	heatmapData, err := h.analyticsService.GenerateRiskHeatmap(organizationID, heatmapType, timeRange)
-	if err != nil {
-		InternalServerError(c, "HEATMAP_GENERATION_FAILED", "Failed to generate heatmap", err)
+// GetRiskDistribution returns risk distribution from heatmap
+func (h *AnalyticsHandler) GetRiskDistribution(c *gin.Context) {
+	heatmapData, ok := h.riskHeatmap(c)
+	if !ok {
 		return
 	}
 
-	SuccessResponse(c, http.StatusOK, gin.H{"hotspots": heatmapData.Hotspots}, "Hotspots retrieved successfully")
+	SuccessResponse(c, http.StatusOK, gin.H{"risk_distribution": heatmapData.RiskDistribution}, "Risk distribution retrieved successfully")
 }
 
-// GetRiskDistribution returns risk distribution from heatmap
-func (h *AnalyticsHandler) GetRiskDistribution(c *gin.Context) {
-	organizationIDStr := c.Param("id")
-	organizationID, err := uuid.Parse(organizationIDStr)
-	if err != nil {
-		BadRequest(c, "INVALID_UUID", "Invalid organization ID format", err.Error())
+// GetHeatmapTrends returns trends from heatmap
+func (h *AnalyticsHandler) GetHeatmapTrends(c *gin.Context) {
+	heatmapData, ok := h.riskHeatmap(c)
+	if !ok {
 		return
 	}
 
-	heatmapType := c.DefaultQuery("type", "comprehensive")
-	timeRange := c.DefaultQuery("range", "30d")
+	SuccessResponse(c, http.StatusOK, gin.H{"trends": heatmapData.Trends}, "Trends retrieved successfully")
+}
 
-	heatmapData, err := h.analyticsService.GenerateRiskHeatmap(organizationID, heatmapType, timeRange)
-	if err != nil {
-		InternalServerError(c, "HEATMAP_GENERATION_FAILED", "Failed to generate heatmap", err)
+// GetHeatmapRecommendations returns recommendations from heatmap
+func (h *AnalyticsHandler) GetHeatmapRecommendations(c *gin.Context) {
+	heatmapData, ok := h.riskHeatmap(c)
+	if !ok {
 		return
 	}
 
-	SuccessResponse(c, http.StatusOK, gin.H{"risk_distribution": heatmapData.RiskDistribution}, "Risk distribution retrieved successfully")
+	SuccessResponse(c, http.StatusOK, gin.H{"recommendations": heatmapData.Recommendations}, "Recommendations retrieved successfully")
 }
 
-// GetHeatmapTrends returns trends from heatmap
-func (h *AnalyticsHandler) GetHeatmapTrends(c *gin.Context) {
-	organizationIDStr := c.Param("id")
-	organizationID, err := uuid.Parse(organizationIDStr)
+// riskHeatmap generates the heatmap a request asks for, setting X-Cache to
+// HIT when it was cached and MISS when it was computed. It responds with the
+// error and returns false when the request is invalid or generation fails.
+func (h *AnalyticsHandler) riskHeatmap(c *gin.Context) (*analytics.HeatmapData, bool) {
+	organizationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		BadRequest(c, "INVALID_UUID", "Invalid organization ID format", err.Error())
-		return
+		return nil, false
+	}
+	window, err := parseHeatmapWindow(c)
+	if err != nil {
+		BadRequest(c, "INVALID_PARAM", "Invalid heatmap window", err.Error())
+		return nil, false
 	}
 
 	heatmapType := c.DefaultQuery("type", "comprehensive")
-	timeRange := c.DefaultQuery("range", "30d")
-
-	heatmapData, err := h.analyticsService.GenerateRiskHeatmap(organizationID, heatmapType, timeRange)
+	heatmapData, cached, err := h.analyticsService.GenerateRiskHeatmap(organizationID, heatmapType, window)
 	if err != nil {
 		InternalServerError(c, "HEATMAP_GENERATION_FAILED", "Failed to generate heatmap", err)
-		return
+		return nil, false
 	}
 
-	SuccessResponse(c, http.StatusOK, gin.H{"trends": heatmapData.Trends}, "Trends retrieved successfully")
+	if cached {
+		c.Header("X-Cache", "HIT")
+	} else {
+		c.Header("X-Cache", "MISS")
+	}
+	return heatmapData, true
 }
 
-// GetHeatmapRecommendations returns recommendations from heatmap
-func (h *AnalyticsHandler) GetHeatmapRecommendations(c *gin.Context) {
-	organizationIDStr := c.Param("id")
-	organizationID, err := uuid.Parse(organizationIDStr)
-	if err != nil {
-		BadRequest(c, "INVALID_UUID", "Invalid organization ID format", err.Error())
-		return
+// parseHeatmapWindow reads a heatmap's window from the from and to query
+// parameters, RFC 3339 times or dates, and its trend granularity. Without
+// from, the window is the range before to, such as 30d or 12w, or all time
+// for range=all.
+func parseHeatmapWindow(c *gin.Context) (analytics.HeatmapWindow, error) {
+	window := analytics.HeatmapWindow{Granularity: c.DefaultQuery("granularity", "week")}
+	if !slices.Contains(analytics.HeatmapGranularities, window.Granularity) {
+		return window, fmt.Errorf("granularity must be one of %s", strings.Join(analytics.HeatmapGranularities, ", "))
+	}
+
+	var err error
+	if to := c.Query("to"); to != "" {
+		if window.To, err = parseHeatmapTime(to, true); err != nil {
+			return window, fmt.Errorf("to: %w", err)
+		}
+	}
+	if from := c.Query("from"); from != "" {
+		if window.From, err = parseHeatmapTime(from, false); err != nil {
+			return window, fmt.Errorf("from: %w", err)
+		}
+		if !window.To.IsZero() && !window.From.Before(window.To) {
+			return window, fmt.Errorf("from must be before to")
+		}
+		return window, nil
 	}
 
-	heatmapType := c.DefaultQuery("type", "comprehensive")
 	timeRange := c.DefaultQuery("range", "30d")
+	if timeRange == "all" {
+		return window, nil
+	}
+	count, unit := strings.TrimRight(timeRange, "dw"), strings.TrimLeft(timeRange, "0123456789")
+	days, err := strconv.Atoi(count)
+	if err != nil || days <= 0 || (unit != "d" && unit != "w") {
+		return window, fmt.Errorf("range must be a number of days or weeks, such as 30d or 12w, or all")
+	}
+	if unit == "w" {
+		days *= 7
+	}
+	window.Since = time.Duration(days) * 24 * time.Hour
+	return window, nil
+}
 
-	heatmapData, err := h.analyticsService.GenerateRiskHeatmap(organizationID, heatmapType, timeRange)
+// parseHeatmapTime parses an RFC 3339 time or a date. A date ending a window
+// includes the whole day.
+func parseHeatmapTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
 	if err != nil {
-		InternalServerError(c, "HEATMAP_GENERATION_FAILED", "Failed to generate heatmap", err)
-		return
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a YYYY-MM-DD date", value)
 	}
-
-	SuccessResponse(c, http.StatusOK, gin.H{"recommendations": heatmapData.Recommendations}, "Recommendations retrieved successfully")
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
 }
 
 // Maturity endpoints
@@ -221,13 +260,37 @@ func (h *AnalyticsHandler) GetMaturityTrends(c *gin.Context) {
 		return
 	}
 
-	score, err := h.analyticsService.CalculateMaturityScore(organizationID)
+	trends, err := h.analyticsService.GetMaturityTrends(organizationID)
 	if err != nil {
-		InternalServerError(c, "MATURITY_CALCULATION_FAILED", "Failed to calculate maturity score", err)
+		InternalServerError(c, "MATURITY_TRENDS_FAILED", "Failed to analyze maturity trends", err)
 		return
 	}
 
-	SuccessResponse(c, http.StatusOK, gin.H{"trends": score.Trends}, "Trends retrieved successfully")
+	SuccessResponse(c, http.StatusOK, gin.H{"trends": trends}, "Trends retrieved successfully")
+}
+
+// GetMaturityHistory returns an organization's latest maturity assessments,
+// oldest first
+func (h *AnalyticsHandler) GetMaturityHistory(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_UUID", "Invalid organization ID format", err.Error())
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		BadRequest(c, "INVALID_LIMIT", "limit must be an integer from 1 to 1000", nil)
+		return
+	}
+
+	history, err := h.analyticsService.GetMaturityHistory(organizationID, limit)
+	if err != nil {
+		InternalServerError(c, "MATURITY_HISTORY_FAILED", "Failed to retrieve maturity history", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, gin.H{"history": history}, "Maturity history retrieved successfully")
 }
 
 // GetDimensionScores returns dimension scores
@@ -262,16 +325,48 @@ func (h *AnalyticsHandler) GenerateComplianceReport(c *gin.Context) {
 	framework := c.DefaultQuery("framework", "SOC2")
 	reportType := c.DefaultQuery("type", "full")
 	reportPeriod := c.DefaultQuery("period", "quarterly")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "pdf" {
+		BadRequest(c, "INVALID_FORMAT", "Format must be json or pdf", format)
+		return
+	}
 
-	report, err := h.analyticsService.GenerateComplianceReport(organizationID, framework, reportType, reportPeriod)
+	report, err := h.analyticsService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, reportType, reportPeriod)
 	if err != nil {
 		InternalServerError(c, "COMPLIANCE_REPORT_GENERATION_FAILED", "Failed to generate compliance report", err)
 		return
 	}
 
+	if format == "pdf" {
+		var body bytes.Buffer
+		if err := analytics.WriteCompliancePDF(&body, report); err != nil {
+			InternalServerError(c, "ENCODE_FAILED", "Failed to render compliance report", err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.pdf", complianceReportFilename(report)))
+		c.Data(http.StatusOK, "application/pdf", body.Bytes())
+		return
+	}
+
 	SuccessResponse(c, http.StatusOK, report, "Compliance report generated successfully")
 }
 
+// complianceReportFilename names a compliance report download after its
+// framework, period and generation date, such as
+// compliance-nist-csf-quarterly-2026-10-01
+func complianceReportFilename(report *analytics.ComplianceReport) string {
+	name := fmt.Sprintf("compliance-%s-%s-%s", report.Framework, report.ReportPeriod, report.GeneratedAt.UTC().Format("2006-01-02"))
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, name), "-")
+}
+
 // GetComplianceScore returns compliance score
 func (h *AnalyticsHandler) GetComplianceScore(c *gin.Context) {
 	organizationIDStr := c.Param("id")
@@ -285,7 +380,7 @@ func (h *AnalyticsHandler) GetComplianceScore(c *gin.Context) {
 	reportType := c.DefaultQuery("type", "full")
 	reportPeriod := c.DefaultQuery("period", "quarterly")
 
-	report, err := h.analyticsService.GenerateComplianceReport(organizationID, framework, reportType, reportPeriod)
+	report, err := h.analyticsService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, reportType, reportPeriod)
 	if err != nil {
 		InternalServerError(c, "COMPLIANCE_REPORT_GENERATION_FAILED", "Failed to generate compliance report", err)
 		return
@@ -307,7 +402,7 @@ func (h *AnalyticsHandler) GetComplianceFindings(c *gin.Context) {
 	reportType := c.DefaultQuery("type", "full")
 	reportPeriod := c.DefaultQuery("period", "quarterly")
 
-	report, err := h.analyticsService.GenerateComplianceReport(organizationID, framework, reportType, reportPeriod)
+	report, err := h.analyticsService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, reportType, reportPeriod)
 	if err != nil {
 		InternalServerError(c, "COMPLIANCE_REPORT_GENERATION_FAILED", "Failed to generate compliance report", err)
 		return
@@ -329,7 +424,7 @@ func (h *AnalyticsHandler) GetComplianceRecommendations(c *gin.Context) {
 	reportType := c.DefaultQuery("type", "full")
 	reportPeriod := c.DefaultQuery("period", "quarterly")
 
-	report, err := h.analyticsService.GenerateComplianceReport(organizationID, framework, reportType, reportPeriod)
+	report, err := h.analyticsService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, reportType, reportPeriod)
 	if err != nil {
 		InternalServerError(c, "COMPLIANCE_REPORT_GENERATION_FAILED", "Failed to generate compliance report", err)
 		return
@@ -351,7 +446,7 @@ func (h *AnalyticsHandler) GetComplianceEvidence(c *gin.Context) {
 	reportType := c.DefaultQuery("type", "full")
 	reportPeriod := c.DefaultQuery("period", "quarterly")
 
-	report, err := h.analyticsService.GenerateComplianceReport(organizationID, framework, reportType, reportPeriod)
+	report, err := h.analyticsService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, reportType, reportPeriod)
 	if err != nil {
 		InternalServerError(c, "COMPLIANCE_REPORT_GENERATION_FAILED", "Failed to generate compliance report", err)
 		return
@@ -373,7 +468,7 @@ func (h *AnalyticsHandler) GetExecutiveSummary(c *gin.Context) {
 	reportType := c.DefaultQuery("type", "full")
 	reportPeriod := c.DefaultQuery("period", "quarterly")
 
-	report, err := h.analyticsService.GenerateComplianceReport(organizationID, framework, reportType, reportPeriod)
+	report, err := h.analyticsService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, reportType, reportPeriod)
 	if err != nil {
 		InternalServerError(c, "COMPLIANCE_REPORT_GENERATION_FAILED", "Failed to generate compliance report", err)
 		return
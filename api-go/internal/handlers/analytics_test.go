@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	analytics "zerotrace/api/internal/services/analytics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeatmapWindow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parse := func(query string) (analytics.HeatmapWindow, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		return parseHeatmapWindow(c)
+	}
+
+	window, err := parse("")
+	require.NoError(t, err)
+	assert.Equal(t, analytics.HeatmapWindow{Since: 30 * 24 * time.Hour, Granularity: "week"}, window)
+
+	window, err = parse("range=12w&granularity=month")
+	require.NoError(t, err)
+	assert.Equal(t, analytics.HeatmapWindow{Since: 84 * 24 * time.Hour, Granularity: "month"}, window)
+
+	window, err = parse("range=all")
+	require.NoError(t, err)
+	assert.Zero(t, window.Since)
+
+	// A date ending the window includes the whole day
+	window, err = parse("from=2026-01-01T12:00:00Z&to=2026-01-31&granularity=day")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), window.From)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), window.To)
+}
+
+func TestHeatmapRejectsBadWindows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewAnalyticsHandler(analytics.NewAnalyticsService(nil, time.Minute))
+	router := gin.New()
+	router.GET("/organizations/:id", h.GenerateRiskHeatmap)
+
+	org := uuid.NewString()
+	for _, query := range []string{
+		"granularity=hour",
+		"range=30",
+		"range=",
+		"range=-5d",
+		"from=yesterday",
+		"from=2026-02-01&to=2026-01-01",
+		"to=2026-13-01",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/organizations/"+org+"?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Empty(t, w.Header().Get("X-Cache"), query)
+	}
+}
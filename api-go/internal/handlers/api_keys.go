@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler handles the API keys service accounts of an organization
+// authenticate with
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// ListAPIKeys lists the caller's organization's API keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	organizationID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(organizationID)
+	if err != nil {
+		InternalServerError(c, "LIST_FAILED", "Failed to list API keys", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, keys, "API keys retrieved successfully")
+}
+
+// CreateAPIKey issues an API key to the caller's organization. The key is
+// in the response and can't be retrieved again.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	organizationID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+
+	key, err := h.apiKeyService.CreateAPIKey(organizationID, apiKeyCreator(c), &req)
+	if err != nil {
+		respondAPIKeyError(c, err, "CREATE_FAILED", "Failed to create API key")
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, key, "API key created; store it now, it won't be shown again")
+}
+
+// RotateAPIKey replaces an API key with a new one, keeping the old one
+// working for the requested grace period
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	organizationID, keyID, ok := parseAPIKeyIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.RotateAPIKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+	}
+
+	key, err := h.apiKeyService.RotateAPIKey(organizationID, keyID, apiKeyCreator(c), &req)
+	if err != nil {
+		respondAPIKeyError(c, err, "ROTATE_FAILED", "Failed to rotate API key")
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, key, "API key rotated; store the new key now, it won't be shown again")
+}
+
+// RevokeAPIKey stops an API key from authenticating
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	organizationID, keyID, ok := parseAPIKeyIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(organizationID, keyID); err != nil {
+		respondAPIKeyError(c, err, "REVOKE_FAILED", "Failed to revoke API key")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, nil, "API key revoked")
+}
+
+// apiKeyCreator is who is issuing a key, for its record
+func apiKeyCreator(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprint(userID)
+	}
+	return ""
+}
+
+// parseAPIKeyIDs reads the caller's organization and the key ID path parameter
+func parseAPIKeyIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	organizationID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid API key ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	return organizationID, keyID, true
+}
+
+// respondAPIKeyError responds to API key management errors, and with a 500 for anything else
+func respondAPIKeyError(c *gin.Context, err error, code, message string) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		NotFound(c, "API_KEY_NOT_FOUND", "API key not found")
+	case errors.Is(err, services.ErrInvalidAPIKeyPermission):
+		BadRequest(c, "INVALID_PERMISSION", "Invalid permissions", err.Error())
+	case errors.Is(err, services.ErrAPIKeyRevoked), errors.Is(err, services.ErrAPIKeyExpired), errors.Is(err, services.ErrAPIKeyRotated):
+		ErrorResponse(c, http.StatusConflict, "API_KEY_UNUSABLE", err.Error(), nil)
+	default:
+		InternalServerError(c, code, message, err)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyHandlerRejectsBadRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewAPIKeyHandler(services.NewAPIKeyService(nil))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if org := c.GetHeader("X-Test-Org"); org != "" {
+			c.Set("company_id", org)
+		}
+	})
+	router.POST("/api-keys", h.CreateAPIKey)
+	router.POST("/api-keys/:id/rotate", h.RotateAPIKey)
+	router.DELETE("/api-keys/:id", h.RevokeAPIKey)
+
+	org := uuid.NewString()
+	for _, tc := range []struct {
+		method, path, org, body string
+	}{
+		{http.MethodPost, "/api-keys", "", `{"name": "ci", "permissions": ["scans:read"]}`},
+		{http.MethodPost, "/api-keys", org, `{"permissions": ["scans:read"]}`},
+		{http.MethodPost, "/api-keys", org, `{"name": "ci", "permissions": []}`},
+		{http.MethodPost, "/api-keys", org, `{"name": "ci", "permissions": ["agents:write"]}`},
+		{http.MethodPost, "/api-keys", org, `{"name": "ci", "permissions": ["scans:read"], "expires_in_days": 1000}`},
+		{http.MethodPost, "/api-keys/not-a-uuid/rotate", org, ""},
+		{http.MethodPost, "/api-keys/" + uuid.NewString() + "/rotate", org, `{"grace_period_minutes": -5}`},
+		{http.MethodDelete, "/api-keys/not-a-uuid", org, ""},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.org != "" {
+			req.Header.Set("X-Test-Org", tc.org)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.method+" "+tc.path+" "+tc.body)
+	}
+}
@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+
 	"zerotrace/api/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+// defaultAttackPathOrganization is the organization attack paths are for
+// when the request names none
+const defaultAttackPathOrganization = "00000000-0000-0000-0000-000000000001"
+
 type AttackPathHandler struct {
 	attackPathService *services.AttackPathService
 }
@@ -19,18 +27,14 @@ func NewAttackPathHandler(service *services.AttackPathService) *AttackPathHandle
 
 // GetAttackPaths retrieves all attack paths for an organization
 func (h *AttackPathHandler) GetAttackPaths(c *gin.Context) {
-	organizationID := c.Query("organization_id")
-	if organizationID == "" {
-		// Use default organization for now
-		organizationID = "00000000-0000-0000-0000-000000000001"
+	organizationID, maxDepth, ok := attackPathQuery(c)
+	if !ok {
+		return
 	}
 
-	paths, err := h.attackPathService.GetAttackPaths(organizationID)
+	paths, err := h.attackPathService.GenerateAttackPaths(organizationID, maxDepth)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to retrieve attack paths",
-			"message": err.Error(),
-		})
+		InternalServerError(c, "ATTACK_PATHS_FAILED", "Failed to retrieve attack paths", err)
 		return
 	}
 
@@ -43,18 +47,18 @@ func (h *AttackPathHandler) GetAttackPaths(c *gin.Context) {
 
 // GetAttackPath retrieves a specific attack path
 func (h *AttackPathHandler) GetAttackPath(c *gin.Context) {
-	pathID := c.Param("path_id")
-	if pathID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Path ID is required"})
+	organizationID, maxDepth, ok := attackPathQuery(c)
+	if !ok {
 		return
 	}
 
-	path, err := h.attackPathService.GetAttackPath(pathID)
+	path, err := h.attackPathService.GetAttackPath(organizationID, c.Param("path_id"), maxDepth)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Attack path not found",
-			"message": err.Error(),
-		})
+		InternalServerError(c, "ATTACK_PATHS_FAILED", "Failed to retrieve attack path", err)
+		return
+	}
+	if path == nil {
+		NotFound(c, "ATTACK_PATH_NOT_FOUND", "Attack path not found")
 		return
 	}
 
@@ -66,17 +70,14 @@ func (h *AttackPathHandler) GetAttackPath(c *gin.Context) {
 
 // GenerateAttackPaths generates attack paths from current vulnerabilities and network data
 func (h *AttackPathHandler) GenerateAttackPaths(c *gin.Context) {
-	organizationID := c.Query("organization_id")
-	if organizationID == "" {
-		organizationID = "00000000-0000-0000-0000-000000000001"
+	organizationID, maxDepth, ok := attackPathQuery(c)
+	if !ok {
+		return
 	}
 
-	paths, err := h.attackPathService.GenerateAttackPaths(organizationID)
+	paths, err := h.attackPathService.GenerateAttackPaths(organizationID, maxDepth)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to generate attack paths",
-			"message": err.Error(),
-		})
+		InternalServerError(c, "ATTACK_PATHS_FAILED", "Failed to generate attack paths", err)
 		return
 	}
 
@@ -88,3 +89,22 @@ func (h *AttackPathHandler) GenerateAttackPaths(c *gin.Context) {
 	})
 }
 
+// attackPathQuery reads the organization_id and max_depth query parameters,
+// responding with an error when either is invalid
+func attackPathQuery(c *gin.Context) (uuid.UUID, int, bool) {
+	organizationID, err := uuid.Parse(c.DefaultQuery("organization_id", defaultAttackPathOrganization))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return uuid.Nil, 0, false
+	}
+
+	maxDepth := services.DefaultAttackPathDepth
+	if raw := c.Query("max_depth"); raw != "" {
+		maxDepth, err = strconv.Atoi(raw)
+		if err != nil || maxDepth < 1 || maxDepth > services.MaxAttackPathDepth {
+			BadRequest(c, "INVALID_MAX_DEPTH", fmt.Sprintf("max_depth must be between 1 and %d", services.MaxAttackPathDepth), raw)
+			return uuid.Nil, 0, false
+		}
+	}
+	return organizationID, maxDepth, true
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateBackfillJob starts a re-enrichment or re-ingestion backfill, requested
+// by the caller. Poll the returned job for its progress.
+func CreateBackfillJob(backfillJobs *services.BackfillJobService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateBackfillJobRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+		req.RequestedBy = c.GetString("user_id")
+
+		job, err := backfillJobs.Submit(&req)
+		if err != nil {
+			InternalServerError(c, "BACKFILL_FAILED", "Failed to start backfill", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusAccepted, job, "Backfill started")
+	}
+}
+
+// ListBackfillJobs returns the most recent backfill jobs, newest first
+func ListBackfillJobs(backfillJobs *services.BackfillJobService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobs, err := backfillJobs.List()
+		if err != nil {
+			InternalServerError(c, "LIST_FAILED", "Failed to list backfill jobs", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, jobs, "Backfill jobs retrieved successfully")
+	}
+}
+
+// GetBackfillJob returns a backfill job with its progress and ETA
+func GetBackfillJob(backfillJobs *services.BackfillJobService) gin.HandlerFunc {
+	return backfillJobAction(backfillJobs.Get, "Backfill job retrieved successfully")
+}
+
+// CancelBackfillJob stops a backfill after its current batch
+func CancelBackfillJob(backfillJobs *services.BackfillJobService) gin.HandlerFunc {
+	return backfillJobAction(backfillJobs.Cancel, "Backfill cancelling")
+}
+
+// ResumeBackfillJob restarts a failed or cancelled backfill after its last
+// committed batch
+func ResumeBackfillJob(backfillJobs *services.BackfillJobService) gin.HandlerFunc {
+	return backfillJobAction(backfillJobs.Resume, "Backfill resumed")
+}
+
+// backfillJobAction runs an action on the backfill job named in the path and
+// responds with the job
+func backfillJobAction(action func(uuid.UUID) (*models.BackfillJob, error), message string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_JOB_ID", "Invalid backfill job ID", err.Error())
+			return
+		}
+
+		job, err := action(jobID)
+		switch {
+		case errors.Is(err, services.ErrBackfillJobNotFound):
+			NotFound(c, "JOB_NOT_FOUND", "Backfill job not found")
+		case errors.Is(err, services.ErrBackfillJobFinished), errors.Is(err, services.ErrBackfillJobNotResumable):
+			ErrorResponse(c, http.StatusConflict, "JOB_STATE_CONFLICT", err.Error(), nil)
+		case err != nil:
+			InternalServerError(c, "BACKFILL_FAILED", "Failed to update backfill job", err)
+		default:
+			SuccessResponse(c, http.StatusOK, job, message)
+		}
+	}
+}
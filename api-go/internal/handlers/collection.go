@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CollectionHandler handles agentless SSH/WinRM collection runs and their settings
+type CollectionHandler struct {
+	collectionService *services.CollectionService
+}
+
+// NewCollectionHandler creates a new collection handler
+func NewCollectionHandler(collectionService *services.CollectionService) *CollectionHandler {
+	return &CollectionHandler{
+		collectionService: collectionService,
+	}
+}
+
+// GetSettings returns an organization's collection concurrency, timeout and auth failure budget
+func (h *CollectionHandler) GetSettings(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	settings, err := h.collectionService.Settings(organizationID)
+	if err != nil {
+		InternalServerError(c, "GET_FAILED", "Failed to retrieve collection settings", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, settings, "Collection settings retrieved successfully")
+}
+
+// UpdateSettings replaces an organization's collection settings
+func (h *CollectionHandler) UpdateSettings(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.UpdateCollectionSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+
+	settings, err := h.collectionService.UpdateSettings(organizationID, &req)
+	if err != nil {
+		InternalServerError(c, "UPDATE_FAILED", "Failed to update collection settings", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, settings, "Collection settings updated successfully")
+}
+
+// StartCollection queues a collection run on one of the organization's agents.
+// Poll the returned run for its progress.
+func (h *CollectionHandler) StartCollection(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.StartCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+
+	run, err := h.collectionService.Start(organizationID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrAgentNotFound) {
+			NotFound(c, "AGENT_NOT_FOUND", "Agent not found")
+			return
+		}
+		BadRequest(c, "COLLECTION_FAILED", "Failed to start collection", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusAccepted, run, "Collection queued")
+}
+
+// GetCollection returns a collection run with its progress and per-host results
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+	runID, err := uuid.Parse(c.Param("run_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_RUN_ID", "Invalid collection run ID", err.Error())
+		return
+	}
+
+	run, err := h.collectionService.Get(organizationID, runID)
+	if err != nil {
+		if errors.Is(err, services.ErrCollectionRunNotFound) {
+			NotFound(c, "RUN_NOT_FOUND", "Collection run not found")
+			return
+		}
+		InternalServerError(c, "GET_FAILED", "Failed to retrieve collection run", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, run, "Collection run retrieved successfully")
+}
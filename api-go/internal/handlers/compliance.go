@@ -43,7 +43,7 @@ func (h *ComplianceHandler) GenerateComplianceReport(c *gin.Context) {
 	reportPeriod := c.DefaultQuery("period", "quarterly")
 
 	// Generate compliance report
-	report, err := h.complianceService.GenerateComplianceReport(organizationID, framework, reportType, reportPeriod)
+	report, err := h.complianceService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, reportType, reportPeriod)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -81,7 +81,7 @@ func (h *ComplianceHandler) GetComplianceScore(c *gin.Context) {
 	framework := c.DefaultQuery("framework", "SOC2")
 
 	// Generate compliance report to get score
-	report, err := h.complianceService.GenerateComplianceReport(organizationID, framework, "summary", "current")
+	report, err := h.complianceService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, "summary", "current")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -126,7 +126,7 @@ func (h *ComplianceHandler) GetComplianceFindings(c *gin.Context) {
 	framework := c.DefaultQuery("framework", "SOC2")
 
 	// Generate compliance report to get findings
-	report, err := h.complianceService.GenerateComplianceReport(organizationID, framework, "findings", "current")
+	report, err := h.complianceService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, "findings", "current")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -172,7 +172,7 @@ func (h *ComplianceHandler) GetComplianceRecommendations(c *gin.Context) {
 	framework := c.DefaultQuery("framework", "SOC2")
 
 	// Generate compliance report to get recommendations
-	report, err := h.complianceService.GenerateComplianceReport(organizationID, framework, "recommendations", "current")
+	report, err := h.complianceService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, "recommendations", "current")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -217,7 +217,7 @@ func (h *ComplianceHandler) GetComplianceEvidence(c *gin.Context) {
 	framework := c.DefaultQuery("framework", "SOC2")
 
 	// Generate compliance report to get evidence
-	report, err := h.complianceService.GenerateComplianceReport(organizationID, framework, "evidence", "current")
+	report, err := h.complianceService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, "evidence", "current")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -262,7 +262,7 @@ func (h *ComplianceHandler) GetExecutiveSummary(c *gin.Context) {
 	framework := c.DefaultQuery("framework", "SOC2")
 
 	// Generate compliance report to get executive summary
-	report, err := h.complianceService.GenerateComplianceReport(organizationID, framework, "executive", "current")
+	report, err := h.complianceService.GenerateComplianceReport(c.Request.Context(), organizationID, framework, "executive", "current")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
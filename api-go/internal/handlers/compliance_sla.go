@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ComplianceSLAHandler handles per-framework remediation SLA endpoints
+type ComplianceSLAHandler struct {
+	slaService *services.ComplianceSLAService
+}
+
+// NewComplianceSLAHandler creates a new compliance SLA handler
+func NewComplianceSLAHandler(slaService *services.ComplianceSLAService) *ComplianceSLAHandler {
+	return &ComplianceSLAHandler{
+		slaService: slaService,
+	}
+}
+
+// GetDashboard shows which open findings map to a framework's controls and
+// which controls are at risk because of overdue findings
+func (h *ComplianceSLAHandler) GetDashboard(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	dashboard, err := h.slaService.GetDashboard(organizationID, c.Param("framework"))
+	if err != nil {
+		respondSLAError(c, err, "Failed to build compliance SLA dashboard")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, dashboard, "Compliance SLA dashboard retrieved successfully")
+}
+
+// GetPolicy returns the remediation timelines in effect for a framework
+func (h *ComplianceSLAHandler) GetPolicy(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	policy, err := h.slaService.GetPolicy(organizationID, c.Param("framework"))
+	if err != nil {
+		respondSLAError(c, err, "Failed to retrieve compliance SLA policy")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, policy, "Compliance SLA policy retrieved successfully")
+}
+
+// UpdatePolicy overrides a framework's remediation timelines and severity weights
+func (h *ComplianceSLAHandler) UpdatePolicy(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.UpdateComplianceSLAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.UpdatedBy = c.GetString("user_id")
+
+	policy, err := h.slaService.UpdatePolicy(organizationID, c.Param("framework"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownFramework) {
+			NotFound(c, "UNKNOWN_FRAMEWORK", "Unknown compliance framework")
+			return
+		}
+		BadRequest(c, "UPDATE_FAILED", "Failed to update compliance SLA policy", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, policy, "Compliance SLA policy updated successfully")
+}
+
+// respondSLAError maps an unknown framework to 404 and everything else to 500
+func respondSLAError(c *gin.Context, err error, message string) {
+	if errors.Is(err, services.ErrUnknownFramework) {
+		NotFound(c, "UNKNOWN_FRAMEWORK", "Unknown compliance framework")
+		return
+	}
+	InternalServerError(c, "SLA_FAILED", message, err)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ConfigBaselineHandler handles host group configuration baseline endpoints
+type ConfigBaselineHandler struct {
+	baselineService *services.ConfigBaselineService
+}
+
+// NewConfigBaselineHandler creates a new config baseline handler
+func NewConfigBaselineHandler(baselineService *services.ConfigBaselineService) *ConfigBaselineHandler {
+	return &ConfigBaselineHandler{
+		baselineService: baselineService,
+	}
+}
+
+// ListBaselines lists an organization's host group baselines
+func (h *ConfigBaselineHandler) ListBaselines(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	baselines, err := h.baselineService.ListBaselines(organizationID)
+	if err != nil {
+		InternalServerError(c, "LIST_FAILED", "Failed to list config baselines", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, baselines, "Config baselines retrieved successfully")
+}
+
+// GetBaseline returns a host group's baseline
+func (h *ConfigBaselineHandler) GetBaseline(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	baseline, err := h.baselineService.GetBaseline(organizationID, c.Param("group"))
+	if err != nil {
+		respondBaselineError(c, err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, baseline, "Config baseline retrieved successfully")
+}
+
+// UpdateBaseline replaces a host group's approved settings
+func (h *ConfigBaselineHandler) UpdateBaseline(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.UpdateConfigBaselineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.UpdatedBy = c.GetString("user_id")
+
+	baseline, err := h.baselineService.UpdateBaseline(organizationID, c.Param("group"), &req)
+	if err != nil {
+		BadRequest(c, "UPDATE_FAILED", "Failed to update config baseline", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, baseline, "Config baseline updated successfully")
+}
+
+// CaptureBaseline approves a known-good agent's current configuration as the host group's baseline
+func (h *ConfigBaselineHandler) CaptureBaseline(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.CaptureConfigBaselineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.UpdatedBy = c.GetString("user_id")
+
+	baseline, err := h.baselineService.CaptureBaseline(organizationID, c.Param("group"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAgentNotFound):
+			NotFound(c, "AGENT_NOT_FOUND", "Agent not found in this organization")
+		case errors.Is(err, services.ErrNoConfigScan):
+			ErrorResponse(c, http.StatusConflict, "NO_CONFIG_SCAN", "Agent has not reported a configuration scan yet", nil)
+		default:
+			BadRequest(c, "CAPTURE_FAILED", "Failed to capture config baseline", err.Error())
+		}
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, baseline, "Config baseline captured successfully")
+}
+
+// GetAgentConfigBaseline returns the baseline an agent's configuration scans are compared against
+func GetAgentConfigBaseline(baselineService *services.ConfigBaselineService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Query("agent_id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID format", err.Error())
+			return
+		}
+		if !certifiedAgent(c, c.Query("agent_id")) {
+			return
+		}
+
+		hostGroup := c.DefaultQuery("host_group", "default")
+		baseline, err := baselineService.GetBaselineForAgent(agentID, hostGroup)
+		if err != nil {
+			if errors.Is(err, services.ErrAgentNotFound) {
+				NotFound(c, "AGENT_NOT_FOUND", "Agent not found")
+				return
+			}
+			respondBaselineError(c, err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, baseline, "Config baseline retrieved successfully")
+	}
+}
+
+// respondBaselineError maps a missing baseline to 404 and everything else to 500
+func respondBaselineError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		NotFound(c, "BASELINE_NOT_FOUND", "No baseline for this host group")
+		return
+	}
+	InternalServerError(c, "GET_FAILED", "Failed to retrieve config baseline", err)
+}
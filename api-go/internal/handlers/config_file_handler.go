@@ -57,8 +57,8 @@ func (h *ConfigFileHandler) UploadConfigFile(c *gin.Context) {
 	}
 
 	// Sanitize filename to prevent path traversal attacks
-	sanitizedFilename := filepath.Base(file.Filename)
-	if sanitizedFilename == "." || sanitizedFilename == "/" || strings.Contains(sanitizedFilename, "..") {
+	sanitizedFilename, ok := sanitizeConfigFilename(file.Filename)
+	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filename"})
 		return
 	}
@@ -115,6 +115,16 @@ func (h *ConfigFileHandler) UploadConfigFile(c *gin.Context) {
 	})
 }
 
+// sanitizeConfigFilename strips the directories from an uploaded file's
+// name, and rejects names that could still escape its storage directory
+func sanitizeConfigFilename(filename string) (string, bool) {
+	sanitized := filepath.Base(filename)
+	if sanitized == "." || sanitized == "/" || strings.Contains(sanitized, "..") {
+		return "", false
+	}
+	return sanitized, true
+}
+
 // GetConfigFile retrieves a config file by ID
 func (h *ConfigFileHandler) GetConfigFile(c *gin.Context) {
 	companyID, ok := getCompanyIDOrError(c)
@@ -203,17 +213,34 @@ func (h *ConfigFileHandler) GetConfigFileContent(c *gin.Context) {
 		return
 	}
 
-	content, err := h.configFileService.GetConfigFileContent(id, companyID)
+	content, size, err := h.configFileService.GetConfigFileContent(c.Request.Context(), id, companyID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	defer content.Close()
 
 	// Set headers for file download (sanitize filename in header)
 	safeFilename := filepath.Base(configFile.Filename)
-	c.Header("Content-Disposition", "attachment; filename="+safeFilename)
-	c.Header("Content-Type", "application/octet-stream")
-	c.Data(http.StatusOK, "application/octet-stream", content)
+	c.DataFromReader(http.StatusOK, size, "application/octet-stream", content, map[string]string{
+		"Content-Disposition": "attachment; filename=" + safeFilename,
+	})
+}
+
+// GetStorageUsage reports how much config file content the company stores
+func (h *ConfigFileHandler) GetStorageUsage(c *gin.Context) {
+	companyID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.configFileService.GetStorageUsage(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
 }
 
 // DeleteConfigFile deletes a config file
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ChunkChecksumHeader carries the hex SHA-256 of a config upload chunk
+const ChunkChecksumHeader = "X-Chunk-SHA256"
+
+// ConfigUploadHandler handles chunked, resumable config file uploads
+type ConfigUploadHandler struct {
+	uploadService *services.ConfigUploadService
+}
+
+// NewConfigUploadHandler creates a new config upload handler
+func NewConfigUploadHandler(uploadService *services.ConfigUploadService) *ConfigUploadHandler {
+	return &ConfigUploadHandler{uploadService: uploadService}
+}
+
+// InitUpload starts a chunked upload of a config file, given its size,
+// SHA-256 and device details, and returns the upload's ID and chunk size
+func (h *ConfigUploadHandler) InitUpload(c *gin.Context) {
+	companyID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return
+	}
+	var uploadedBy *uuid.UUID
+	if userIDStr, exists := c.Get("user_id"); exists {
+		if userID, err := uuid.Parse(userIDStr.(string)); err == nil {
+			uploadedBy = &userID
+		}
+	}
+
+	var req models.InitConfigUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	filename, ok := sanitizeConfigFilename(req.Filename)
+	if !ok {
+		BadRequest(c, "INVALID_FILENAME", "Invalid filename", nil)
+		return
+	}
+
+	status, err := h.uploadService.InitUpload(companyID, uploadedBy, filename, req)
+	if err != nil {
+		respondConfigUploadError(c, err)
+		return
+	}
+	SuccessResponse(c, http.StatusCreated, status, "Config upload started")
+}
+
+// GetUpload returns an upload's progress. A client resuming an upload sends
+// the chunks at the offsets it lists as missing.
+func (h *ConfigUploadHandler) GetUpload(c *gin.Context) {
+	companyID, uploadID, ok := configUploadParams(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.uploadService.GetUpload(companyID, uploadID)
+	if err != nil {
+		respondConfigUploadError(c, err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, status, "")
+}
+
+// UploadChunk stores the chunk at the offset query parameter, the raw
+// request body, checked against the SHA-256 in ChunkChecksumHeader
+func (h *ConfigUploadHandler) UploadChunk(c *gin.Context) {
+	companyID, uploadID, ok := configUploadParams(c)
+	if !ok {
+		return
+	}
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil {
+		BadRequest(c, "INVALID_OFFSET", "offset must be the byte offset of the chunk", nil)
+		return
+	}
+	checksum := c.GetHeader(ChunkChecksumHeader)
+	if checksum == "" {
+		BadRequest(c, "MISSING_CHECKSUM", ChunkChecksumHeader+" header is required", nil)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, h.uploadService.ChunkSize()))
+	if err != nil {
+		if PayloadTooLarge(c, err) {
+			return
+		}
+		BadRequest(c, "INVALID_CHUNK", "Failed to read chunk", err.Error())
+		return
+	}
+
+	status, duplicate, err := h.uploadService.UploadChunk(companyID, uploadID, offset, checksum, data)
+	if err != nil {
+		respondConfigUploadError(c, err)
+		return
+	}
+	message := "Chunk received"
+	if duplicate {
+		message = "Chunk already received"
+	}
+	SuccessResponse(c, http.StatusOK, status, message)
+}
+
+// CompleteUpload assembles an upload whose chunks have all arrived into a
+// config file, once it matches the upload's SHA-256
+func (h *ConfigUploadHandler) CompleteUpload(c *gin.Context) {
+	companyID, uploadID, ok := configUploadParams(c)
+	if !ok {
+		return
+	}
+
+	configFile, err := h.uploadService.CompleteUpload(companyID, uploadID)
+	if err != nil {
+		respondConfigUploadError(c, err)
+		return
+	}
+	configFile.FileContent = nil
+	SuccessResponse(c, http.StatusCreated, configFile, "Config file uploaded successfully")
+}
+
+// AbortUpload drops an upload and the chunks it received
+func (h *ConfigUploadHandler) AbortUpload(c *gin.Context) {
+	companyID, uploadID, ok := configUploadParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.uploadService.AbortUpload(companyID, uploadID); err != nil {
+		respondConfigUploadError(c, err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, nil, "Config upload aborted")
+}
+
+// configUploadParams reads the caller's company and the upload ID
+func configUploadParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	companyID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	uploadID, err := uuid.Parse(c.Param("upload_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_UPLOAD_ID", "Invalid upload ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	return companyID, uploadID, true
+}
+
+// respondConfigUploadError maps config upload errors to HTTP responses
+func respondConfigUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrConfigUploadNotFound):
+		NotFound(c, "UPLOAD_NOT_FOUND", "Config upload not found")
+	case errors.Is(err, services.ErrInvalidConfigUpload):
+		BadRequest(c, "INVALID_UPLOAD", "Invalid config upload", err.Error())
+	case errors.Is(err, services.ErrInvalidUploadChunk):
+		BadRequest(c, "INVALID_CHUNK", "Invalid upload chunk", err.Error())
+	case errors.Is(err, services.ErrUploadChunkChecksum):
+		ErrorResponse(c, http.StatusUnprocessableEntity, "CHUNK_CHECKSUM_MISMATCH", "Chunk does not match its checksum; send it again", err.Error())
+	case errors.Is(err, services.ErrUploadChunkConflict):
+		ErrorResponse(c, http.StatusConflict, "CHUNK_CONFLICT", "Chunk conflicts with one already received", err.Error())
+	case errors.Is(err, services.ErrConfigUploadIncomplete):
+		ErrorResponse(c, http.StatusConflict, "UPLOAD_INCOMPLETE", "Config upload is missing chunks", err.Error())
+	case errors.Is(err, services.ErrConfigUploadChecksum):
+		ErrorResponse(c, http.StatusUnprocessableEntity, "UPLOAD_CHECKSUM_MISMATCH", "Uploaded file does not match its checksum", err.Error())
+	default:
+		InternalServerError(c, "CONFIG_UPLOAD_FAILED", "Failed to upload config file", err)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigUploadRejectsBadRequestsBeforeStoringThem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// No database: every request here must be turned away before one is needed
+	uploads := services.NewConfigUploadService(nil, nil, nil, &config.Config{ConfigUploadChunkSize: 4, ConfigAuditorMaxFileSize: 16})
+	h := NewConfigUploadHandler(uploads)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("company_id", "6f1c1b7e-3c0a-4c43-9d43-1f2a1e7f9a10") })
+	configFiles := router.Group("/api/v2/config-files")
+	configFiles.GET("/:id", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+	configFiles.POST("/uploads", h.InitUpload)
+	configFiles.PUT("/uploads/:upload_id/chunks", h.UploadChunk)
+
+	sum := sha256.Sum256([]byte("host"))
+	checksum := hex.EncodeToString(sum[:])
+	uploadID := "0b8e6a5e-58e4-4c1e-9d53-6b3f1f0e2a11"
+	tests := []struct {
+		name, method, path, body, checksum string
+		status                             int
+		code                               string
+	}{
+		{"missing device details", http.MethodPost, "/api/v2/config-files/uploads", `{"filename": "fw.conf", "size": 8, "checksum": "` + checksum + `"}`, "", http.StatusBadRequest, "INVALID_REQUEST"},
+		{"path in filename", http.MethodPost, "/api/v2/config-files/uploads", `{"filename": "..", "size": 8, "checksum": "` + checksum + `", "device_type": "firewall", "manufacturer": "cisco", "config_type": "running_config"}`, "", http.StatusBadRequest, "INVALID_FILENAME"},
+		{"over the size cap", http.MethodPost, "/api/v2/config-files/uploads", `{"filename": "fw.conf", "size": 17, "checksum": "` + checksum + `", "device_type": "firewall", "manufacturer": "cisco", "config_type": "running_config"}`, "", http.StatusBadRequest, "INVALID_UPLOAD"},
+		{"bad checksum", http.MethodPost, "/api/v2/config-files/uploads", `{"filename": "fw.conf", "size": 8, "checksum": "abc", "device_type": "firewall", "manufacturer": "cisco", "config_type": "running_config"}`, "", http.StatusBadRequest, "INVALID_UPLOAD"},
+		{"bad upload ID", http.MethodPut, "/api/v2/config-files/uploads/latest/chunks?offset=0", "host", checksum, http.StatusBadRequest, "INVALID_UPLOAD_ID"},
+		{"no offset", http.MethodPut, "/api/v2/config-files/uploads/" + uploadID + "/chunks", "host", checksum, http.StatusBadRequest, "INVALID_OFFSET"},
+		{"no chunk checksum", http.MethodPut, "/api/v2/config-files/uploads/" + uploadID + "/chunks?offset=0", "host", "", http.StatusBadRequest, "MISSING_CHECKSUM"},
+		{"corrupted chunk", http.MethodPut, "/api/v2/config-files/uploads/" + uploadID + "/chunks?offset=0", "hosT", checksum, http.StatusUnprocessableEntity, "CHUNK_CHECKSUM_MISMATCH"},
+		{"chunk over the chunk size", http.MethodPut, "/api/v2/config-files/uploads/" + uploadID + "/chunks?offset=0", "hostname", checksum, http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			if tt.checksum != "" {
+				req.Header.Set(ChunkChecksumHeader, tt.checksum)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.code != "" {
+				assert.True(t, strings.Contains(w.Body.String(), `"code":"`+tt.code+`"`), w.Body.String())
+			}
+		})
+	}
+
+	// Uploads don't shadow config files
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/config-files/"+uploadID, nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ContainerAllowlistHandler handles the per-organization allowlist of expected container findings
+type ContainerAllowlistHandler struct {
+	allowlistService *services.ContainerAllowlistService
+}
+
+// NewContainerAllowlistHandler creates a new container allowlist handler
+func NewContainerAllowlistHandler(allowlistService *services.ContainerAllowlistService) *ContainerAllowlistHandler {
+	return &ContainerAllowlistHandler{
+		allowlistService: allowlistService,
+	}
+}
+
+// ListRules lists an organization's allowlist rules
+func (h *ContainerAllowlistHandler) ListRules(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	rules, err := h.allowlistService.ListRules(organizationID)
+	if err != nil {
+		InternalServerError(c, "LIST_FAILED", "Failed to list container allowlist", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, rules, "Container allowlist retrieved successfully")
+}
+
+// CreateRule adds a rule to an organization's allowlist. Changes to the
+// allowlist are audited with the user who made them.
+func (h *ContainerAllowlistHandler) CreateRule(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.ContainerAllowlistRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.Actor = c.GetString("user_id")
+
+	rule, err := h.allowlistService.CreateRule(organizationID, &req)
+	if err != nil {
+		BadRequest(c, "CREATE_FAILED", "Failed to create allowlist rule", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, rule, "Allowlist rule created successfully")
+}
+
+// UpdateRule replaces an allowlist rule
+func (h *ContainerAllowlistHandler) UpdateRule(c *gin.Context) {
+	organizationID, ruleID, ok := parseAllowlistRuleParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.ContainerAllowlistRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.Actor = c.GetString("user_id")
+
+	rule, err := h.allowlistService.UpdateRule(organizationID, ruleID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrAllowlistRuleNotFound) {
+			NotFound(c, "RULE_NOT_FOUND", "Allowlist rule not found")
+			return
+		}
+		BadRequest(c, "UPDATE_FAILED", "Failed to update allowlist rule", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, rule, "Allowlist rule updated successfully")
+}
+
+// DeleteRule removes a rule from an organization's allowlist
+func (h *ContainerAllowlistHandler) DeleteRule(c *gin.Context) {
+	organizationID, ruleID, ok := parseAllowlistRuleParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.allowlistService.DeleteRule(organizationID, ruleID, c.GetString("user_id")); err != nil {
+		if errors.Is(err, services.ErrAllowlistRuleNotFound) {
+			NotFound(c, "RULE_NOT_FOUND", "Allowlist rule not found")
+			return
+		}
+		InternalServerError(c, "DELETE_FAILED", "Failed to delete allowlist rule", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, nil, "Allowlist rule deleted successfully")
+}
+
+// GetAuditLog lists the changes made to an organization's allowlist
+func (h *ContainerAllowlistHandler) GetAuditLog(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		BadRequest(c, "INVALID_LIMIT", "limit must be a positive integer", nil)
+		return
+	}
+
+	entries, err := h.allowlistService.GetAuditLog(organizationID, limit)
+	if err != nil {
+		InternalServerError(c, "GET_FAILED", "Failed to retrieve allowlist audit log", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, entries, "Allowlist audit log retrieved successfully")
+}
+
+// GetAgentContainerAllowlist returns the allowlist an agent's container scans apply
+func GetAgentContainerAllowlist(allowlistService *services.ContainerAllowlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Query("agent_id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID format", err.Error())
+			return
+		}
+		if !certifiedAgent(c, c.Query("agent_id")) {
+			return
+		}
+
+		rules, err := allowlistService.ListRulesForAgent(agentID)
+		if err != nil {
+			if errors.Is(err, services.ErrAgentNotFound) {
+				NotFound(c, "AGENT_NOT_FOUND", "Agent not found")
+				return
+			}
+			InternalServerError(c, "GET_FAILED", "Failed to retrieve container allowlist", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, rules, "Container allowlist retrieved successfully")
+	}
+}
+
+func parseAllowlistRuleParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_RULE_ID", "Invalid allowlist rule ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	return organizationID, ruleID, true
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DataExportHandler handles scheduled data export API endpoints
+type DataExportHandler struct {
+	exportService *services.DataExportService
+}
+
+// NewDataExportHandler creates a new data export handler
+func NewDataExportHandler(exportService *services.DataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		exportService: exportService,
+	}
+}
+
+// GetExportConfig retrieves an organization's export configuration
+func (h *DataExportHandler) GetExportConfig(c *gin.Context) {
+	organizationID, ok := parseExportOrgID(c)
+	if !ok {
+		return
+	}
+
+	cfg, err := h.exportService.GetConfig(organizationID)
+	if err != nil {
+		respondExportError(c, err, "GET_FAILED", "Failed to retrieve export configuration")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    cfg,
+	})
+}
+
+// UpdateExportConfig creates or replaces an organization's export configuration
+func (h *DataExportHandler) UpdateExportConfig(c *gin.Context) {
+	organizationID, ok := parseExportOrgID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDataExportConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request payload",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	cfg, err := h.exportService.UpdateConfig(organizationID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error: &models.APIError{
+				Code:    "UPDATE_FAILED",
+				Message: "Failed to update export configuration",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    cfg,
+		Message: "Export configuration updated successfully",
+	})
+}
+
+// TriggerExport starts an export immediately
+func (h *DataExportHandler) TriggerExport(c *gin.Context) {
+	organizationID, ok := parseExportOrgID(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.exportService.TriggerExport(organizationID)
+	if err != nil {
+		if errors.Is(err, services.ErrExportInProgress) {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error: &models.APIError{
+					Code:    "EXPORT_IN_PROGRESS",
+					Message: err.Error(),
+				},
+			})
+			return
+		}
+		respondExportError(c, err, "TRIGGER_FAILED", "Failed to start export")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.APIResponse{
+		Success: true,
+		Data:    run,
+		Message: "Export started",
+	})
+}
+
+// ListExportRuns lists recent export runs
+func (h *DataExportHandler) ListExportRuns(c *gin.Context) {
+	organizationID, ok := parseExportOrgID(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	runs, err := h.exportService.ListRuns(organizationID, limit)
+	if err != nil {
+		respondExportError(c, err, "LIST_FAILED", "Failed to list export runs")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    runs,
+	})
+}
+
+// parseExportOrgID parses the organization ID path parameter
+func parseExportOrgID(c *gin.Context) (uuid.UUID, bool) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error: &models.APIError{
+				Code:    "INVALID_ID",
+				Message: "Invalid organization ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return organizationID, true
+}
+
+// respondExportError maps missing configurations to 404 and everything else to 500
+func respondExportError(c *gin.Context, err error, code, message string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Error: &models.APIError{
+				Code:    "NOT_CONFIGURED",
+				Message: "No export configuration for this organization",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success: false,
+		Error: &models.APIError{
+			Code:    code,
+			Message: message,
+			Details: err.Error(),
+		},
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -57,19 +58,38 @@ func InternalServerError(c *gin.Context, code, message string, err error) {
 	details := map[string]interface{}{}
 	if err != nil {
 		details["error"] = err.Error()
+		c.Error(err) // Reported by the request's access log
 	}
 	ErrorResponse(c, http.StatusInternalServerError, code, message, details)
 }
 
+// PayloadTooLarge creates a 413 response if err came from reading a request
+// body past its size limit, and reports whether it did
+func PayloadTooLarge(c *gin.Context, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+	middleware.AbortPayloadTooLarge(c, maxBytesErr.Limit)
+	return true
+}
+
 // SuccessResponse creates a standardized success response
 func SuccessResponse(c *gin.Context, statusCode int, data interface{}, message string) {
+	SuccessPageResponse(c, statusCode, data, "", message)
+}
+
+// SuccessPageResponse creates a standardized success response for a page of a
+// cursor-paginated list, with the cursor of the next page if there is one
+func SuccessPageResponse(c *gin.Context, statusCode int, data interface{}, nextCursor, message string) {
 	correlationID := middleware.GetCorrelationID(c)
-	
+
 	response := models.APIResponse{
-		Success:   true,
-		Data:      data,
-		Message:   message,
-		Timestamp: time.Now(),
+		Success:    true,
+		Data:       data,
+		Message:    message,
+		NextCursor: nextCursor,
+		Timestamp:  time.Now(),
 	}
 
 	// Add correlation ID to response if available
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/middleware"
+	"zerotrace/api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const limit = 1024
+	read := 0
+	router := gin.New()
+	router.POST("/results", middleware.MaxPayloadSize(limit), func(c *gin.Context) {
+		read++
+		body, err := io.ReadAll(c.Request.Body)
+		if PayloadTooLarge(c, err) {
+			return
+		}
+		require.NoError(t, err)
+		c.String(http.StatusOK, "%d", len(body))
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	assertTooLarge := func(resp *http.Response) {
+		t.Helper()
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		assert.Equal(t, "1024", resp.Header.Get(middleware.MaxPayloadHeader))
+
+		var response models.APIResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		require.NotNil(t, response.Error)
+		assert.Equal(t, "REQUEST_TOO_LARGE", response.Error.Code)
+		details, ok := response.Error.Details.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, float64(limit), details["max_bytes"])
+	}
+
+	// A declared Content-Length over the limit is rejected before the body is read
+	resp, err := http.Post(server.URL+"/results", "application/json", strings.NewReader(strings.Repeat("x", limit+1)))
+	require.NoError(t, err)
+	assertTooLarge(resp)
+	assert.Equal(t, 0, read, "the handler never runs")
+
+	// A chunked body has no Content-Length; it is cut off once past the limit
+	resp, err = http.Post(server.URL+"/results", "application/json", io.MultiReader(strings.NewReader(strings.Repeat("x", limit)), strings.NewReader("x")))
+	require.NoError(t, err)
+	assertTooLarge(resp)
+	assert.Equal(t, 1, read)
+
+	// Bodies within the limit get through, chunked or not, still told the limit
+	resp, err = http.Post(server.URL+"/results", "application/json", io.MultiReader(strings.NewReader(strings.Repeat("x", limit))))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1024", string(body))
+	assert.Equal(t, "1024", resp.Header.Get(middleware.MaxPayloadHeader))
+}
+
+func TestPayloadTooLargeIgnoresOtherErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	assert.False(t, PayloadTooLarge(c, nil))
+	assert.False(t, PayloadTooLarge(c, errors.New("unexpected EOF")))
+	assert.False(t, c.IsAborted())
+}
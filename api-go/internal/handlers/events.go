@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"zerotrace/api/internal/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListEventTypes lists the event types ZeroTrace sends and their published schema versions
+func ListEventTypes(registry *events.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		SuccessResponse(c, http.StatusOK, registry.Types(), "Event types retrieved successfully")
+	}
+}
+
+// GetEventSchema returns the JSON schema of one version of an event type
+func GetEventSchema(registry *events.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schema, ok := registry.Schema(c.Param("type"), c.Param("version"))
+		if !ok {
+			NotFound(c, "SCHEMA_NOT_FOUND", "No schema for this event type and version")
+			return
+		}
+
+		encoded, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			InternalServerError(c, "SCHEMA_ENCODE_FAILED", "Failed to encode schema", err)
+			return
+		}
+		c.Data(http.StatusOK, "application/schema+json", encoded)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EvidenceHandler serves the raw evidence scanners attached to findings
+type EvidenceHandler struct {
+	evidenceService *services.EvidenceService
+}
+
+// NewEvidenceHandler creates a new evidence handler
+func NewEvidenceHandler(evidenceService *services.EvidenceService) *EvidenceHandler {
+	return &EvidenceHandler{
+		evidenceService: evidenceService,
+	}
+}
+
+// ListFindingEvidence lists the evidence stored for one finding on an agent
+func (h *EvidenceHandler) ListFindingEvidence(c *gin.Context) {
+	agentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID", err.Error())
+		return
+	}
+	// Callers whose token carries an organization only see its evidence
+	callerOrganization, _ := getCompanyIDFromContext(c)
+
+	evidence, err := h.evidenceService.ListEvidence(callerOrganization, agentID, c.Param("key"))
+	if err != nil {
+		InternalServerError(c, "EVIDENCE_QUERY_FAILED", "Failed to retrieve evidence", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, evidence, "Evidence retrieved successfully")
+}
+
+// GetEvidence downloads one evidence blob with its original content type
+func (h *EvidenceHandler) GetEvidence(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid evidence ID", err.Error())
+		return
+	}
+	callerOrganization, _ := getCompanyIDFromContext(c)
+
+	evidence, data, err := h.evidenceService.GetEvidence(c.Request.Context(), callerOrganization, id)
+	if errors.Is(err, services.ErrEvidenceNotFound) {
+		NotFound(c, "EVIDENCE_NOT_FOUND", "Evidence not found")
+		return
+	}
+	if err != nil {
+		InternalServerError(c, "EVIDENCE_READ_FAILED", "Failed to read evidence", err)
+		return
+	}
+
+	// Evidence is scanner output, never something for the browser to render
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"evidence-%s\"", evidence.ID))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("X-Evidence-SHA256", evidence.SHA256)
+	c.Data(http.StatusOK, evidence.ContentType, data)
+}
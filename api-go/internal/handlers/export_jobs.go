@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"zerotrace/api/internal/middleware"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+	"zerotrace/api/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ExportJobHandler handles async export endpoints
+type ExportJobHandler struct {
+	jobService           *services.ExportJobService
+	vulnerabilityHandler *VulnerabilityV2Handler
+}
+
+// NewExportJobHandler creates a new export job handler
+func NewExportJobHandler(jobService *services.ExportJobService, vulnerabilityHandler *VulnerabilityV2Handler) *ExportJobHandler {
+	return &ExportJobHandler{
+		jobService:           jobService,
+		vulnerabilityHandler: vulnerabilityHandler,
+	}
+}
+
+// CreateVulnerabilityExport starts a vulnerability export in the background.
+// It takes the same filters and formats as the synchronous export, except
+// PDF, and an optional callback_url notified once the export is ready.
+func (h *ExportJobHandler) CreateVulnerabilityExport(c *gin.Context) {
+	var req types.VulnerabilityV2Request
+	if err := c.ShouldBindQuery(&req); err != nil {
+		BadRequest(c, "INVALID_FILTERS", "Invalid export filters", err.Error())
+		return
+	}
+	format := strings.ToLower(req.Export)
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "sarif" {
+		BadRequest(c, "UNSUPPORTED_FORMAT", "Async exports support json, csv and sarif", format)
+		return
+	}
+
+	var body models.CreateExportJobRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+	}
+
+	render := func(_ context.Context) ([]byte, string, error) {
+		vulnerabilities, _, err := h.vulnerabilityHandler.vulnerabilityService.GetVulnerabilitiesV2(req)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load vulnerabilities: %w", err)
+		}
+		return h.vulnerabilityHandler.renderExport(format, convertVulnerabilitiesToModels(vulnerabilities))
+	}
+
+	job, err := h.jobService.Submit(c.GetString(middleware.ExportQuotaKey), "vulnerabilities", format,
+		h.vulnerabilityHandler.getAppliedFilters(req), body.CallbackURL, render)
+	if errors.Is(err, services.ErrInvalidCallbackURL) {
+		BadRequest(c, "INVALID_CALLBACK_URL", err.Error(), body.CallbackURL)
+		return
+	}
+	if err != nil {
+		InternalServerError(c, "EXPORT_FAILED", "Failed to start export", err)
+		return
+	}
+
+	c.Header("Location", "/api/v2/exports/"+job.ID.String())
+	SuccessResponse(c, http.StatusAccepted, job, "Export started")
+}
+
+// GetExport returns an async export's status, with its download URL once
+// complete. Only the organization that started the export can see it.
+func (h *ExportJobHandler) GetExport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid export ID", err.Error())
+		return
+	}
+
+	job, err := h.jobService.GetJob(id)
+	if err == nil && job.QuotaKey != c.GetString("company_id") {
+		err = services.ErrExportJobNotFound
+	}
+	if errors.Is(err, services.ErrExportJobNotFound) {
+		NotFound(c, "EXPORT_NOT_FOUND", "Export not found or expired")
+		return
+	}
+	if err != nil {
+		InternalServerError(c, "EXPORT_QUERY_FAILED", "Failed to retrieve export", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, job, "Export retrieved successfully")
+}
+
+// DownloadExport downloads a completed async export. The token comes from the export's download URL.
+func (h *ExportJobHandler) DownloadExport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid export ID", err.Error())
+		return
+	}
+
+	job, data, err := h.jobService.Download(c.Request.Context(), id, c.Query("token"))
+	switch {
+	case errors.Is(err, services.ErrExportJobNotFound):
+		NotFound(c, "EXPORT_NOT_FOUND", "Export not found or expired")
+		return
+	case errors.Is(err, services.ErrExportNotReady):
+		ErrorResponse(c, http.StatusConflict, "EXPORT_NOT_READY", "Export is not ready for download", nil)
+		return
+	case err != nil:
+		InternalServerError(c, "EXPORT_READ_FAILED", "Failed to read export", err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s.%s", job.Kind, job.ID, job.Format))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, job.ContentType, data)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// findingExportFormats are the formats config findings and scans export as
+var findingExportFormats = []string{"json", "csv", "sarif"}
+
+var configFindingCSVColumns = []string{"ID", "Rule", "Title", "Severity", "Category", "Config File", "Lines", "Status", "Remediation", "Found At"}
+
+var scanCSVColumns = []string{"CVE ID", "Title", "Severity", "CVSS Score", "Package", "Version", "Location", "Priority", "Status"}
+
+// findingExportFormat reads the format to export in, given as format or
+// export, defaulting to JSON. An unknown format is rejected.
+func findingExportFormat(c *gin.Context) (string, bool) {
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		format = strings.ToLower(c.Query("export"))
+	}
+	if format == "" {
+		return "json", true
+	}
+	if !slices.Contains(findingExportFormats, format) {
+		BadRequest(c, "UNSUPPORTED_FORMAT", "format must be one of "+strings.Join(findingExportFormats, ", "), format)
+		return "", false
+	}
+	return format, true
+}
+
+// sendExport sends a rendered export as a download named after its format
+func sendExport(c *gin.Context, name, format string, data []byte, err error) {
+	if err != nil {
+		InternalServerError(c, "EXPORT_FAILED", "Failed to render export", err)
+		return
+	}
+	contentType := "application/json"
+	if format == "csv" {
+		contentType = "text/csv; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", name, format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ExportConfigFindings exports the config findings matching the list filters
+// as JSON, CSV or SARIF 2.1.0, such as for GitHub code scanning
+func (h *ConfigFindingHandler) ExportConfigFindings(c *gin.Context) {
+	companyID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return
+	}
+	format, ok := findingExportFormat(c)
+	if !ok {
+		return
+	}
+
+	var req models.ListConfigFindingsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		BadRequest(c, "INVALID_QUERY", "Invalid filters", err.Error())
+		return
+	}
+	if configFileID := c.Query("config_file_id"); configFileID != "" {
+		id, err := uuid.Parse(configFileID)
+		if err != nil {
+			BadRequest(c, "INVALID_CONFIG_FILE_ID", "Invalid config file ID", err.Error())
+			return
+		}
+		req.ConfigFileID = &id
+	}
+
+	findings, err := h.configFindingService.ExportConfigFindings(companyID, req)
+	if err != nil {
+		InternalServerError(c, "EXPORT_FAILED", "Failed to fetch config findings", err)
+		return
+	}
+
+	var data []byte
+	switch format {
+	case "sarif":
+		data, err = json.Marshal(services.ConfigFindingsSARIF(findings))
+	case "csv":
+		data, err = configFindingsCSV(findings)
+	default:
+		if findings == nil {
+			findings = []models.ConfigFinding{}
+		}
+		data, err = json.Marshal(findings)
+	}
+	sendExport(c, "config-findings-"+time.Now().UTC().Format("2006-01-02"), format, data, err)
+}
+
+// configFindingsCSV renders config findings as CSV
+func configFindingsCSV(findings []models.ConfigFinding) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(configFindingCSVColumns) // Errors are kept for Error
+	for _, finding := range findings {
+		rule := finding.FindingType
+		if finding.Standard != nil && finding.Standard.RequirementID != "" {
+			rule = finding.Standard.RequirementID
+		}
+		if finding.CVEID != "" {
+			rule = finding.CVEID
+		}
+		var lines []int
+		_ = json.Unmarshal(finding.LineNumbers, &lines) // A malformed list only loses the lines
+		lineCells := make([]string, len(lines))
+		for i, line := range lines {
+			lineCells[i] = strconv.Itoa(line)
+		}
+		writer.Write([]string{
+			finding.ID.String(),
+			csvCell(rule),
+			csvCell(finding.Title),
+			finding.Severity,
+			csvCell(finding.Category),
+			csvCell(finding.ConfigFile.Filename),
+			strings.Join(lineCells, " "),
+			finding.Status,
+			csvCell(finding.Remediation),
+			finding.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// ExportScan exports all of a scan's vulnerabilities as JSON, CSV or SARIF
+// 2.1.0, such as for GitHub code scanning
+func ExportScan(scanService *services.ScanService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scanID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_SCAN_ID", "Invalid scan ID", err.Error())
+			return
+		}
+		format, ok := findingExportFormat(c)
+		if !ok {
+			return
+		}
+		companyID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		scan, vulnerabilities, err := scanService.ExportScan(c.Request.Context(), scanID, companyID)
+		if err != nil {
+			if errors.Is(err, services.ErrScanNotFound) {
+				NotFound(c, "SCAN_NOT_FOUND", "Scan not found")
+				return
+			}
+			if errors.Is(err, services.ErrScanForbidden) {
+				Forbidden(c, "SCAN_FORBIDDEN", "Scan belongs to another organization")
+				return
+			}
+			InternalServerError(c, "EXPORT_FAILED", "Failed to fetch scan results", err)
+			return
+		}
+
+		var data []byte
+		switch format {
+		case "sarif":
+			data, err = json.Marshal(services.ScanSARIF(scan, vulnerabilities))
+		case "csv":
+			data, err = scanCSV(vulnerabilities)
+		default:
+			if vulnerabilities == nil {
+				vulnerabilities = []models.Vulnerability{}
+			}
+			data, err = json.Marshal(gin.H{"scan": scan, "vulnerabilities": vulnerabilities})
+		}
+		sendExport(c, "scan-"+scanID.String(), format, data, err)
+	}
+}
+
+// scanCSV renders a scan's vulnerabilities as CSV
+func scanCSV(vulnerabilities []models.Vulnerability) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(scanCSVColumns) // Errors are kept for Error
+	for _, vuln := range vulnerabilities {
+		score := ""
+		if vuln.CVSSScore != nil {
+			score = strconv.FormatFloat(*vuln.CVSSScore, 'f', 1, 64)
+		}
+		writer.Write([]string{
+			vuln.CVEID,
+			csvCell(vuln.Title),
+			strings.ToLower(string(vuln.Severity)),
+			score,
+			csvCell(vuln.PackageName),
+			csvCell(vuln.PackageVersion),
+			csvCell(vuln.Location),
+			vuln.Priority,
+			vuln.Status,
+		})
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
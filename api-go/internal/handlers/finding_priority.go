@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SetFindingPriority overrides the derived priority of one of the caller's
+// organization's findings, recording the caller. The override holds across
+// scans until it is cleared.
+func SetFindingPriority(findingStates *services.FindingStateService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		findingID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_FINDING_ID", "Invalid finding ID", err.Error())
+			return
+		}
+
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		var req models.SetFindingPriorityRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+
+		finding, err := findingStates.SetPriorityOverride(organizationID, findingID, req.Priority, req.Reason, c.GetString("user_id"))
+		respondFindingPriority(c, finding, err, "Finding priority overridden")
+	}
+}
+
+// ClearFindingPriority returns a finding to its derived priority
+func ClearFindingPriority(findingStates *services.FindingStateService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		findingID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_FINDING_ID", "Invalid finding ID", err.Error())
+			return
+		}
+
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		finding, err := findingStates.ClearPriorityOverride(organizationID, findingID)
+		respondFindingPriority(c, finding, err, "Finding priority override cleared")
+	}
+}
+
+// respondFindingPriority responds with a finding after a priority change
+func respondFindingPriority(c *gin.Context, finding *models.FindingState, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrFindingNotFound):
+		NotFound(c, "FINDING_NOT_FOUND", "Finding not found")
+	case errors.Is(err, services.ErrInvalidPriority):
+		BadRequest(c, "INVALID_PRIORITY", "Priority must be urgent, high, medium or low", err.Error())
+	case err != nil:
+		InternalServerError(c, "PRIORITY_UPDATE_FAILED", "Failed to update finding priority", err)
+	default:
+		SuccessResponse(c, http.StatusOK, finding, message)
+	}
+}
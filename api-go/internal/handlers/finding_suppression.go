@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SuppressFinding suppresses a finding until a fix for it is published. The
+// re-enrichment backfill reopens it once one is, notifying the owner at the
+// callback URL if given.
+func SuppressFinding(findingStates *services.FindingStateService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		findingID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_FINDING_ID", "Invalid finding ID", err.Error())
+			return
+		}
+
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		var req models.SuppressFindingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+
+		finding, err := findingStates.Suppress(organizationID, findingID, &req)
+		respondFindingSuppression(c, finding, err, "Finding suppressed")
+	}
+}
+
+// UnsuppressFinding lifts a finding's suppression
+func UnsuppressFinding(findingStates *services.FindingStateService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		findingID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_FINDING_ID", "Invalid finding ID", err.Error())
+			return
+		}
+
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		finding, err := findingStates.Unsuppress(organizationID, findingID)
+		respondFindingSuppression(c, finding, err, "Finding suppression lifted")
+	}
+}
+
+// respondFindingSuppression responds with a finding after a suppression change
+func respondFindingSuppression(c *gin.Context, finding *models.FindingState, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrFindingNotFound):
+		NotFound(c, "FINDING_NOT_FOUND", "Finding not found")
+	case errors.Is(err, services.ErrInvalidSuppressionMode):
+		BadRequest(c, "INVALID_SUPPRESSION_MODE", "Suppression mode must be until_fix", err.Error())
+	case errors.Is(err, services.ErrInvalidCallbackURL):
+		BadRequest(c, "INVALID_CALLBACK_URL", err.Error(), nil)
+	case err != nil:
+		InternalServerError(c, "SUPPRESSION_UPDATE_FAILED", "Failed to update finding suppression", err)
+	default:
+		SuccessResponse(c, http.StatusOK, finding, message)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// VerifyFinding asks the agent that reported one of the caller's
+// organization's findings to re-run just the check behind it. The outcome arrives once the agent has picked the command
+// up on its next heartbeat; poll the returned verification for it.
+func VerifyFinding(verificationService *services.FindingVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		findingID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_FINDING_ID", "Invalid finding ID", err.Error())
+			return
+		}
+
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		req := models.VerifyFindingRequest{RequestedBy: c.GetString("user_id")}
+		verification, err := verificationService.Verify(c.Request.Context(), organizationID, findingID, &req)
+		if errors.Is(err, services.ErrFindingNotFound) {
+			NotFound(c, "FINDING_NOT_FOUND", "Finding not found")
+			return
+		}
+		if err != nil {
+			InternalServerError(c, "VERIFY_FAILED", "Failed to queue verification", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusAccepted, verification, "Verification queued")
+	}
+}
+
+// ListFindingVerifications returns the verifications of one of the caller's
+// organization's findings, newest first
+func ListFindingVerifications(verificationService *services.FindingVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		findingID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_FINDING_ID", "Invalid finding ID", err.Error())
+			return
+		}
+
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		verifications, err := verificationService.List(c.Request.Context(), organizationID, findingID)
+		if err != nil {
+			InternalServerError(c, "LIST_FAILED", "Failed to list verifications", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, verifications, "Verifications retrieved successfully")
+	}
+}
+
+// GetFindingVerification returns a verification's outcome with the re-check's
+// result and evidence
+func GetFindingVerification(verificationService *services.FindingVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		findingID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_FINDING_ID", "Invalid finding ID", err.Error())
+			return
+		}
+		verificationID, err := uuid.Parse(c.Param("verification_id"))
+		if err != nil {
+			BadRequest(c, "INVALID_VERIFICATION_ID", "Invalid verification ID", err.Error())
+			return
+		}
+
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		verification, err := verificationService.Get(c.Request.Context(), organizationID, findingID, verificationID)
+		if errors.Is(err, services.ErrVerificationNotFound) {
+			NotFound(c, "VERIFICATION_NOT_FOUND", "Verification not found")
+			return
+		}
+		if err != nil {
+			InternalServerError(c, "GET_FAILED", "Failed to retrieve verification", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, verification, "Verification retrieved successfully")
+	}
+}
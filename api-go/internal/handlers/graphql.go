@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"zerotrace/api/internal/services"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gin-gonic/gin"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// GraphQL answers a GraphQL query, posted as JSON or sent as GET parameters,
+// over the data of the caller's organization. Responses use GraphQL's own
+// data and errors shape rather than APIResponse, so GraphQL clients can read
+// them; a request that fails before it runs is a 400.
+func GraphQL(graphQLService *services.GraphQLService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		var params graphql.RawParams
+		if c.Request.Method == http.MethodGet {
+			params.Query = c.Query("query")
+			params.OperationName = c.Query("operationName")
+			if variables := c.Query("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+					c.JSON(http.StatusBadRequest, &graphql.Response{Errors: gqlerror.List{{Message: "Variables are invalid JSON."}}})
+					return
+				}
+			}
+		} else if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, &graphql.Response{Errors: gqlerror.List{{Message: "Body is not a GraphQL request: " + err.Error()}}})
+			return
+		}
+		if params.Query == "" {
+			c.JSON(http.StatusBadRequest, &graphql.Response{Errors: gqlerror.List{{Message: "Must provide query string."}}})
+			return
+		}
+
+		resp := graphQLService.Execute(c.Request.Context(), organizationID, &params)
+		status := http.StatusOK
+		if resp.Data == nil {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, resp)
+	}
+}
@@ -79,3 +79,24 @@ func HealthCheck(db *repository.Database) gin.HandlerFunc {
 		})
 	}
 }
+
+// GetCompressionStats reports the database storage saved by compressing large
+// JSON columns, per column and in total, since the API started
+func GetCompressionStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		columns := repository.GetCompressionStats()
+		total := repository.CompressionStats{Column: "total"}
+		for _, column := range columns {
+			total.Values += column.Values
+			total.Compressed += column.Compressed
+			total.RawBytes += column.RawBytes
+			total.StoredBytes += column.StoredBytes
+		}
+		total.SavedBytes = total.RawBytes - total.StoredBytes
+		if total.RawBytes > 0 {
+			total.Ratio = float64(total.StoredBytes) / float64(total.RawBytes)
+		}
+
+		SuccessResponse(c, http.StatusOK, gin.H{"columns": columns, "total": total}, "Compression statistics retrieved successfully")
+	}
+}
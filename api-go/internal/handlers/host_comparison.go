@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CompareHosts compares two hosts' open findings and configuration check
+// states. Query parameters a and b are the agent IDs; scope optionally
+// restricts the findings to one scan type. Both hosts must be in the caller's
+// organization.
+func CompareHosts(comparisonService *services.HostComparisonService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+		agentA, err := uuid.Parse(c.Query("a"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Query parameter a must be an agent ID", err.Error())
+			return
+		}
+		agentB, err := uuid.Parse(c.Query("b"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Query parameter b must be an agent ID", err.Error())
+			return
+		}
+		if agentA == agentB {
+			BadRequest(c, "SAME_AGENT", "Cannot compare an agent with itself", "")
+			return
+		}
+
+		comparison, err := comparisonService.Compare(organizationID, agentA, agentB, c.Query("scope"))
+		switch {
+		case errors.Is(err, services.ErrAgentNotFound):
+			NotFound(c, "AGENT_NOT_FOUND", err.Error())
+			return
+		case err != nil:
+			InternalServerError(c, "COMPARE_FAILED", "Failed to compare hosts", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, comparison, "Hosts compared successfully")
+	}
+}
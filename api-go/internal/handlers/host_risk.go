@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetHostRiskRanking ranks an organization's hosts by their consolidated risk score, riskiest first
+func GetHostRiskRanking(hostRiskService *services.HostRiskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+			return
+		}
+
+		ranking, err := hostRiskService.Rank(organizationID)
+		if err != nil {
+			InternalServerError(c, "RANK_FAILED", "Failed to rank hosts by risk", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, ranking, "Host risk ranking retrieved successfully")
+	}
+}
+
+// SetAssetCriticality sets how critical a host is to the business and returns its rescored risk
+func SetAssetCriticality(hostRiskService *services.HostRiskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID", err.Error())
+			return
+		}
+
+		var req models.SetAssetCriticalityRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "criticality must be one of low, medium, high or critical", err.Error())
+			return
+		}
+
+		risk, err := hostRiskService.SetCriticality(agentID, req.Criticality)
+		if errors.Is(err, services.ErrAgentNotFound) {
+			NotFound(c, "AGENT_NOT_FOUND", err.Error())
+			return
+		}
+		if err != nil {
+			InternalServerError(c, "SET_CRITICALITY_FAILED", "Failed to set asset criticality", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, risk, "Asset criticality updated successfully")
+	}
+}
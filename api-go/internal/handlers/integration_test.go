@@ -8,9 +8,11 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"zerotrace/api/internal/config"
 	"zerotrace/api/internal/middleware"
 	"zerotrace/api/internal/models"
 	"zerotrace/api/internal/services"
+	"zerotrace/api/internal/testdb"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,14 +23,15 @@ import (
 func TestAgentEndpointsIntegration(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	agentService := services.NewAgentService(nil) // Mock DB
+	db := testdb.Open(t, &models.Agent{}, &models.AgentTag{}, &models.AgentStatusEvent{}, &models.AgentCommand{}, &models.AgentRelease{})
+	agentService := services.NewAgentService(db, &config.Config{})
 	router := gin.New()
 	router.Use(middleware.CorrelationID())
 
 	// Setup routes
 	router.GET("/api/agents", GetAgents(agentService))
 	router.GET("/api/agents/:id", GetAgent(agentService))
-	router.POST("/api/agents/heartbeat", AgentHeartbeat(agentService))
+	router.POST("/api/agents/heartbeat", AgentHeartbeat(agentService, services.NewAgentCommandService(db, &config.Config{}), services.NewUpdateService(db)))
 
 	// Test GetAgents
 	t.Run("GetAgents", func(t *testing.T) {
@@ -98,14 +101,13 @@ func TestErrorHandlingIntegration(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	router := gin.New()
-	router.Use(CorrelationID())
+	router.Use(middleware.CorrelationID())
+	router.GET("/test", func(c *gin.Context) {
+		SuccessResponse(c, http.StatusOK, nil, "test")
+	})
 
 	// Test correlation ID is added
 	t.Run("CorrelationID_Added", func(t *testing.T) {
-		router.GET("/test", func(c *gin.Context) {
-			SuccessResponse(c, http.StatusOK, nil, "test")
-		})
-
 		req := httptest.NewRequest("GET", "/test", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
@@ -116,10 +118,6 @@ func TestErrorHandlingIntegration(t *testing.T) {
 
 	// Test correlation ID from header is preserved
 	t.Run("CorrelationID_Preserved", func(t *testing.T) {
-		router.GET("/test", func(c *gin.Context) {
-			SuccessResponse(c, http.StatusOK, nil, "test")
-		})
-
 		req := httptest.NewRequest("GET", "/test", nil)
 		req.Header.Set("X-Correlation-ID", "test-correlation-id")
 		w := httptest.NewRecorder()
@@ -135,14 +133,14 @@ func TestStandardizedErrorResponses(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	router := gin.New()
-	router.Use(CorrelationID())
+	router.Use(middleware.CorrelationID())
 
 	t.Run("BadRequest", func(t *testing.T) {
-		router.GET("/test", func(c *gin.Context) {
+		router.GET("/bad-request", func(c *gin.Context) {
 			BadRequest(c, "TEST_ERROR", "Test error message", "test details")
 		})
 
-		req := httptest.NewRequest("GET", "/test", nil)
+		req := httptest.NewRequest("GET", "/bad-request", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -158,11 +156,11 @@ func TestStandardizedErrorResponses(t *testing.T) {
 	})
 
 	t.Run("NotFound", func(t *testing.T) {
-		router.GET("/test", func(c *gin.Context) {
+		router.GET("/not-found", func(c *gin.Context) {
 			NotFound(c, "NOT_FOUND", "Resource not found")
 		})
 
-		req := httptest.NewRequest("GET", "/test", nil)
+		req := httptest.NewRequest("GET", "/not-found", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -170,12 +168,12 @@ func TestStandardizedErrorResponses(t *testing.T) {
 	})
 
 	t.Run("InternalServerError", func(t *testing.T) {
-		router.GET("/test", func(c *gin.Context) {
+		router.GET("/internal-error", func(c *gin.Context) {
 			testErr := fmt.Errorf("test error")
 			InternalServerError(c, "INTERNAL_ERROR", "Internal server error", testErr)
 		})
 
-		req := httptest.NewRequest("GET", "/test", nil)
+		req := httptest.NewRequest("GET", "/internal-error", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// LicensePolicyHandler handles the per-organization dependency license policies
+type LicensePolicyHandler struct {
+	licenseService *services.LicensePolicyService
+}
+
+// NewLicensePolicyHandler creates a new license policy handler
+func NewLicensePolicyHandler(licenseService *services.LicensePolicyService) *LicensePolicyHandler {
+	return &LicensePolicyHandler{
+		licenseService: licenseService,
+	}
+}
+
+// ListPolicies lists an organization's license policies
+func (h *LicensePolicyHandler) ListPolicies(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	policies, err := h.licenseService.ListPolicies(organizationID)
+	if err != nil {
+		InternalServerError(c, "LIST_FAILED", "Failed to list license policies", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, policies, "License policies retrieved successfully")
+}
+
+// CreatePolicy adds a license policy to an organization, created by the caller
+func (h *LicensePolicyHandler) CreatePolicy(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.LicensePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.Actor = c.GetString("user_id")
+
+	policy, err := h.licenseService.CreatePolicy(organizationID, &req)
+	if err != nil {
+		BadRequest(c, "CREATE_FAILED", "Failed to create license policy", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, policy, "License policy created successfully")
+}
+
+// UpdatePolicy replaces a license policy
+func (h *LicensePolicyHandler) UpdatePolicy(c *gin.Context) {
+	organizationID, policyID, ok := parseLicensePolicyParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.LicensePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.Actor = c.GetString("user_id")
+
+	policy, err := h.licenseService.UpdatePolicy(organizationID, policyID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrLicensePolicyNotFound) {
+			NotFound(c, "POLICY_NOT_FOUND", "License policy not found")
+			return
+		}
+		BadRequest(c, "UPDATE_FAILED", "Failed to update license policy", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, policy, "License policy updated successfully")
+}
+
+// DeletePolicy removes a license policy from an organization
+func (h *LicensePolicyHandler) DeletePolicy(c *gin.Context) {
+	organizationID, policyID, ok := parseLicensePolicyParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.licenseService.DeletePolicy(organizationID, policyID); err != nil {
+		if errors.Is(err, services.ErrLicensePolicyNotFound) {
+			NotFound(c, "POLICY_NOT_FOUND", "License policy not found")
+			return
+		}
+		InternalServerError(c, "DELETE_FAILED", "Failed to delete license policy", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, nil, "License policy deleted successfully")
+}
+
+// GetSummary reports how an organization's dependencies comply with its license policies
+func (h *LicensePolicyHandler) GetSummary(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	summary, err := h.licenseService.Summary(organizationID)
+	if err != nil {
+		InternalServerError(c, "GET_FAILED", "Failed to summarize license compliance", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, summary, "License compliance summary retrieved successfully")
+}
+
+func parseLicensePolicyParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	policyID, err := uuid.Parse(c.Param("policy_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_POLICY_ID", "Invalid license policy ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	return organizationID, policyID, true
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetNetworkAssets lists an organization's network hosts, each merged across
+// every agent that observed it
+func GetNetworkAssets(networkAssetService *services.NetworkAssetService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+			return
+		}
+
+		hosts, err := networkAssetService.ListAssets(organizationID)
+		if err != nil {
+			InternalServerError(c, "LIST_FAILED", "Failed to list network assets", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, hosts, "Network assets retrieved successfully")
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// topologyContentTypes are the content types topology graphs are served as
+var topologyContentTypes = map[string]string{
+	services.TopologyFormatJSON:    "application/json",
+	services.TopologyFormatGraphML: "application/graphml+xml",
+}
+
+// ExportNetworkTopology exports an organization's analyzed network topology
+// as GraphML or JSON Graph Format, for graph tools such as Gephi or Neo4j
+func ExportNetworkTopology(topologyService *services.NetworkTopologyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+			return
+		}
+		format := c.DefaultQuery("format", services.TopologyFormatJSON)
+		if _, ok := topologyContentTypes[format]; !ok {
+			BadRequest(c, "INVALID_FORMAT", "Format must be json or graphml", nil)
+			return
+		}
+		decay, ok := topologyRiskDecay(c)
+		if !ok {
+			return
+		}
+
+		graph, err := topologyService.Build(organizationID, decay)
+		if err != nil {
+			InternalServerError(c, "TOPOLOGY_FAILED", "Failed to build network topology", err)
+			return
+		}
+		writeTopology(c, graph, format, "topology-"+organizationID.String())
+	}
+}
+
+// AnalyzeNetworkTopology analyzes a topology graph built outside ZeroTrace,
+// without any agent data, and returns it with the analysis results. The graph
+// is GraphML when the format query parameter or the content type says so, and
+// JSON Graph Format otherwise. The result is in the same format unless the
+// output query parameter asks for the other. Only callers in an organization
+// may analyze graphs.
+func AnalyzeNetworkTopology(topologyService *services.NetworkTopologyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := getCompanyIDOrError(c); !ok {
+			return
+		}
+
+		format := c.Query("format")
+		if format == "" {
+			format = services.TopologyFormatJSON
+			if strings.Contains(c.ContentType(), "xml") {
+				format = services.TopologyFormatGraphML
+			}
+		}
+		output := c.DefaultQuery("output", format)
+		for _, f := range []string{format, output} {
+			if _, ok := topologyContentTypes[f]; !ok {
+				BadRequest(c, "INVALID_FORMAT", "Format must be json or graphml", nil)
+				return
+			}
+		}
+		decay, ok := topologyRiskDecay(c)
+		if !ok {
+			return
+		}
+
+		graph, err := services.DecodeTopology(c.Request.Body, format)
+		if err == nil {
+			err = topologyService.Analyze(graph, decay)
+		}
+		if errors.Is(err, services.ErrInvalidTopology) {
+			BadRequest(c, "INVALID_TOPOLOGY", "Invalid topology graph", err.Error())
+			return
+		}
+		if err != nil {
+			InternalServerError(c, "ANALYSIS_FAILED", "Failed to analyze topology graph", err)
+			return
+		}
+		writeTopology(c, graph, output, "topology-analysis")
+	}
+}
+
+// topologyRiskDecay reads the optional decay query parameter, responding with
+// an error when it is invalid
+func topologyRiskDecay(c *gin.Context) (float64, bool) {
+	raw := c.Query("decay")
+	if raw == "" {
+		return services.DefaultTopologyRiskDecay, true
+	}
+	decay, err := strconv.ParseFloat(raw, 64)
+	if err != nil || decay < 0 || decay >= 1 {
+		BadRequest(c, "INVALID_DECAY", "Decay must be a number at least 0 and below 1", raw)
+		return 0, false
+	}
+	return decay, true
+}
+
+// writeTopology responds with a topology graph as a download
+func writeTopology(c *gin.Context, graph *models.TopologyGraph, format, filename string) {
+	var body bytes.Buffer
+	if err := services.EncodeTopology(&body, graph, format); err != nil {
+		InternalServerError(c, "ENCODE_FAILED", "Failed to encode topology graph", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", filename, format))
+	c.Data(http.StatusOK, topologyContentTypes[format], body.Bytes())
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/queue"
 	"zerotrace/api/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -70,6 +71,28 @@ func GetProcessingStatus(agentService *services.AgentService) gin.HandlerFunc {
 	}
 }
 
+// GetOrgProcessingMetrics returns the scheduler's ingestion and job processing
+// metrics, with those of the caller's organization only
+func GetOrgProcessingMetrics(scheduler *queue.FairScheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		metrics := scheduler.Metrics()
+		own := make([]queue.OrgProcessingMetrics, 0, 1)
+		for _, org := range metrics.Organizations {
+			if org.OrganizationID == c.GetString("company_id") {
+				own = append(own, org)
+			}
+		}
+		metrics.Organizations = own
+
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success:   true,
+			Data:      metrics,
+			Message:   "Organization processing metrics retrieved successfully",
+			Timestamp: time.Now(),
+		})
+	}
+}
+
 // Helper functions to extract processing information from agent metadata
 func getProcessingStatus(agent *models.Agent) string {
 	if status, ok := agent.Metadata["processing_status"].(string); ok {
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RescanHandler handles fleet-wide re-scan requests
+type RescanHandler struct {
+	agentService   *services.AgentService
+	commandService *services.AgentCommandService
+}
+
+// NewRescanHandler creates a new rescan handler
+func NewRescanHandler(agentService *services.AgentService, commandService *services.AgentCommandService) *RescanHandler {
+	return &RescanHandler{
+		agentService:   agentService,
+		commandService: commandService,
+	}
+}
+
+// TriggerRescan queues a scan_now command for every online agent in an
+// organization, or those with the requested tags or in the requested group
+func (h *RescanHandler) TriggerRescan(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.RescanRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+	}
+
+	agents, err := h.agentService.FilterAgents(models.AgentFilter{
+		OrganizationID: &organizationID,
+		Status:         models.AgentStatusOnline,
+		Tags:           req.Tags,
+		Group:          req.Group,
+	})
+	if err != nil {
+		respondAgentTagError(c, err, "RESCAN_FAILED", "Failed to select agents to re-scan")
+		return
+	}
+	batch, err := h.commandService.CreateRescanBatch(organizationID, agents, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrNoOnlineAgents) {
+			ErrorResponse(c, http.StatusConflict, "NO_ONLINE_AGENTS", "No online agents to re-scan", err.Error())
+			return
+		}
+		BadRequest(c, "RESCAN_FAILED", "Failed to start re-scan", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusAccepted, batch, "Re-scan queued")
+}
+
+// GetRescanBatch returns the progress of a re-scan batch
+func (h *RescanHandler) GetRescanBatch(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+	batchID, err := uuid.Parse(c.Param("batch_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_BATCH_ID", "Invalid batch ID", err.Error())
+		return
+	}
+
+	batch, err := h.commandService.GetRescanBatch(organizationID, batchID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "BATCH_NOT_FOUND", "Re-scan batch not found")
+			return
+		}
+		InternalServerError(c, "GET_FAILED", "Failed to retrieve re-scan batch", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, batch, "Re-scan progress retrieved successfully")
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRescan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testdb.Open(t, &models.Agent{}, &models.AgentTag{}, &models.AgentStatusEvent{}, &models.AgentCommand{}, &models.RescanBatch{})
+	agentService := services.NewAgentService(db, &config.Config{})
+	commandService := services.NewAgentCommandService(db, &config.Config{})
+	h := NewRescanHandler(agentService, commandService)
+
+	router := gin.New()
+	router.POST("/api/v2/organizations/:id/rescan", h.TriggerRescan)
+	router.GET("/api/v2/organizations/:id/rescan/:batch_id", h.GetRescanBatch)
+	router.POST("/api/agents/commands/:id/status", UpdateCommandStatus(commandService, nil))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		var response models.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.NotNil(t, response.Error)
+		return response.Error.Code
+	}
+
+	orgID := uuid.New()
+	batchPath := "/api/v2/organizations/" + orgID.String() + "/rescan/"
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{http.MethodPost, "/api/v2/organizations/acme/rescan", "", http.StatusBadRequest, "INVALID_ID"},
+		{http.MethodPost, "/api/v2/organizations/" + orgID.String() + "/rescan", "{", http.StatusBadRequest, "INVALID_REQUEST"},
+		{http.MethodPost, "/api/v2/organizations/" + orgID.String() + "/rescan", "", http.StatusConflict, "NO_ONLINE_AGENTS"},
+		{http.MethodGet, "/api/v2/organizations/acme/rescan/" + uuid.NewString(), "", http.StatusBadRequest, "INVALID_ID"},
+		{http.MethodGet, batchPath + "latest", "", http.StatusBadRequest, "INVALID_BATCH_ID"},
+		{http.MethodGet, batchPath + uuid.NewString(), "", http.StatusNotFound, "BATCH_NOT_FOUND"},
+	} {
+		w := serve(tc.method, tc.path, tc.body)
+		assert.Equal(t, tc.status, w.Code, tc.path)
+		assert.Equal(t, tc.code, errorCode(w), tc.path)
+	}
+
+	agentID := uuid.New()
+	require.NoError(t, agentService.UpdateAgentHeartbeat(models.AgentHeartbeat{AgentID: agentID, OrganizationID: orgID}))
+
+	w := serve(http.MethodPost, "/api/v2/organizations/"+orgID.String()+"/rescan", `{"scan_types": ["software"]}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var queued struct {
+		Data models.RescanBatch `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queued))
+	assert.Equal(t, 1, queued.Data.AgentCount)
+
+	// Another organization can't read the batch
+	w = serve(http.MethodGet, "/api/v2/organizations/"+uuid.NewString()+"/rescan/"+queued.Data.ID.String(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	commands, err := commandService.PendingCommands(agentID)
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	w = serve(http.MethodPost, "/api/agents/commands/"+commands[0].ID.String()+"/status", `{"agent_id": "`+agentID.String()+`", "status": "completed"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = serve(http.MethodGet, batchPath+queued.Data.ID.String(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var progress struct {
+		Data models.RescanBatch `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &progress))
+	assert.Equal(t, 1, progress.Data.Progress.Completed)
+	assert.True(t, progress.Data.Progress.Done)
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"zerotrace/api/internal/models"
@@ -21,7 +24,7 @@ func GetScans(scanService *services.ScanService) gin.HandlerFunc {
 		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-		scans, err := scanService.GetScans(companyUUID, page, limit)
+		scans, err := scanService.GetScans(c.Request.Context(), companyUUID, page, limit)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -64,7 +67,7 @@ func CreateScan(scanService *services.ScanService) gin.HandlerFunc {
 		companyID, _ := c.Get("company_id")
 		companyUUID, _ := uuid.Parse(companyID.(string))
 
-		scan, err := scanService.CreateScan(req, companyUUID)
+		scan, err := scanService.CreateScan(c.Request.Context(), req, companyUUID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -106,16 +109,16 @@ func GetScan(scanService *services.ScanService) gin.HandlerFunc {
 		companyID, _ := c.Get("company_id")
 		companyUUID, _ := uuid.Parse(companyID.(string))
 
-		scan, err := scanService.GetScan(scanID, companyUUID)
+		scan, err := scanService.GetScan(c.Request.Context(), scanID, companyUUID)
 		if err != nil {
-			c.JSON(http.StatusNotFound, models.APIResponse{
-				Success: false,
-				Error: &models.APIError{
-					Code:    "SCAN_NOT_FOUND",
-					Message: "Scan not found",
-				},
-				Timestamp: time.Now(),
-			})
+			switch {
+			case errors.Is(err, services.ErrScanNotFound):
+				NotFound(c, "SCAN_NOT_FOUND", "Scan not found")
+			case errors.Is(err, services.ErrScanForbidden):
+				Forbidden(c, "SCAN_FORBIDDEN", "Scan belongs to another organization")
+			default:
+				InternalServerError(c, "SCAN_FETCH_FAILED", "Failed to fetch scan", err)
+			}
 			return
 		}
 
@@ -128,6 +131,116 @@ func GetScan(scanService *services.ScanService) gin.HandlerFunc {
 	}
 }
 
+// Limits on the page size of GetScanResults
+const (
+	defaultScanResultsLimit = 50
+	maxScanResultsLimit     = 500
+)
+
+// GetScanResults retrieves a page of the vulnerabilities a scan found. limit
+// and offset page through them, and severity filters them to a comma-separated
+// list of severities, such as critical,high.
+func GetScanResults(scanService *services.ScanService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scanID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_SCAN_ID", "Invalid scan ID", err.Error())
+			return
+		}
+		severities, limit, offset, err := parseScanResultsQuery(c)
+		if err != nil {
+			BadRequest(c, "INVALID_QUERY", err.Error(), nil)
+			return
+		}
+
+		companyID, _ := c.Get("company_id")
+		companyUUID, _ := uuid.Parse(companyID.(string))
+
+		results, err := scanService.GetScanResults(c.Request.Context(), scanID, companyUUID, severities, limit, offset)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrScanNotFound):
+				NotFound(c, "SCAN_NOT_FOUND", "Scan not found")
+			case errors.Is(err, services.ErrScanForbidden):
+				Forbidden(c, "SCAN_FORBIDDEN", "Scan belongs to another organization")
+			default:
+				InternalServerError(c, "SCAN_RESULTS_FETCH_FAILED", "Failed to fetch scan results", err)
+			}
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, results, "Scan results retrieved successfully")
+	}
+}
+
+// DiffAgentScans compares two scans of an agent, given by the from and to
+// query parameters: the vulnerabilities the to scan introduced, resolved and
+// still has, with their counts by severity
+func DiffAgentScans(scanService *services.ScanService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID", err.Error())
+			return
+		}
+		fromScanID, err := uuid.Parse(c.Query("from"))
+		if err != nil {
+			BadRequest(c, "INVALID_SCAN_ID", "from must be a scan ID", err.Error())
+			return
+		}
+		toScanID, err := uuid.Parse(c.Query("to"))
+		if err != nil {
+			BadRequest(c, "INVALID_SCAN_ID", "to must be a scan ID", err.Error())
+			return
+		}
+
+		companyID, _ := c.Get("company_id")
+		companyUUID, _ := uuid.Parse(companyID.(string))
+
+		diff, err := scanService.DiffAgentScans(c.Request.Context(), companyUUID, agentID, fromScanID, toScanID)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrScanNotFound):
+				NotFound(c, "SCAN_NOT_FOUND", "Scan not found")
+			case errors.Is(err, services.ErrScanForbidden):
+				Forbidden(c, "SCAN_FORBIDDEN", "Scan belongs to another organization")
+			case errors.Is(err, services.ErrScanAgentMismatch):
+				BadRequest(c, "SCAN_AGENT_MISMATCH", "Both scans must belong to the agent", nil)
+			default:
+				InternalServerError(c, "SCAN_DIFF_FAILED", "Failed to diff scans", err)
+			}
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, diff, "Scan diff retrieved successfully")
+	}
+}
+
+// parseScanResultsQuery reads GetScanResults' severity, limit and offset query parameters
+func parseScanResultsQuery(c *gin.Context) ([]models.SeverityLevel, int, int, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultScanResultsLimit)))
+	if err != nil || limit <= 0 || limit > maxScanResultsLimit {
+		return nil, 0, 0, fmt.Errorf("limit must be between 1 and %d", maxScanResultsLimit)
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return nil, 0, 0, errors.New("offset must be a non-negative integer")
+	}
+
+	var severities []models.SeverityLevel
+	for _, value := range strings.Split(c.Query("severity"), ",") {
+		severity := models.SeverityLevel(strings.ToUpper(strings.TrimSpace(value)))
+		switch severity {
+		case "":
+		case models.SeverityCritical, models.SeverityHigh, models.SeverityMedium, models.SeverityLow, models.SeverityInfo:
+			severities = append(severities, severity)
+		default:
+			return nil, 0, 0, fmt.Errorf("unknown severity %q", value)
+		}
+	}
+	return severities, limit, offset, nil
+}
+
 // UpdateScan updates a scan
 func UpdateScan(scanService *services.ScanService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -161,7 +274,7 @@ func UpdateScan(scanService *services.ScanService) gin.HandlerFunc {
 		companyID, _ := c.Get("company_id")
 		companyUUID, _ := uuid.Parse(companyID.(string))
 
-		scan, err := scanService.UpdateScan(scanID, companyUUID, updates)
+		scan, err := scanService.UpdateScan(c.Request.Context(), scanID, companyUUID, updates)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -203,7 +316,7 @@ func DeleteScan(scanService *services.ScanService) gin.HandlerFunc {
 		companyID, _ := c.Get("company_id")
 		companyUUID, _ := uuid.Parse(companyID.(string))
 
-		err = scanService.DeleteScan(scanID, companyUUID)
+		err = scanService.DeleteScan(c.Request.Context(), scanID, companyUUID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// scanEventsKeepAlive is how often an idle scan event stream sends a comment,
+// so proxies don't close it while a scan is slow to report
+const scanEventsKeepAlive = 15 * time.Second
+
+// StreamScanEvents streams a scan of the caller's organization as server-sent
+// "progress" events: its current status, progress and finding count, then
+// each change to them. The stream ends after the event of the scan
+// completing, failing or being cancelled, or when the client disconnects.
+func StreamScanEvents(scanService *services.ScanService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+		scanID, err := uuid.Parse(c.Param("scan_id"))
+		if err != nil {
+			BadRequest(c, "INVALID_SCAN_ID", "Invalid scan ID", err.Error())
+			return
+		}
+
+		current, events, unsubscribe, err := scanService.SubscribeProgress(c.Request.Context(), scanID, companyID)
+		if err != nil {
+			if errors.Is(err, services.ErrScanNotFound) {
+				NotFound(c, "SCAN_NOT_FOUND", "Scan not found")
+				return
+			}
+			if errors.Is(err, services.ErrScanForbidden) {
+				Forbidden(c, "SCAN_FORBIDDEN", "Scan belongs to another organization")
+				return
+			}
+			InternalServerError(c, "SCAN_EVENTS_FAILED", "Failed to subscribe to scan events", err)
+			return
+		}
+		defer unsubscribe()
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no") // Stop nginx holding events back
+		c.SSEvent("progress", current)
+		c.Writer.Flush()
+		if current.Done() {
+			return
+		}
+
+		keepAlive := time.NewTicker(scanEventsKeepAlive)
+		defer keepAlive.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case event, open := <-events:
+				if !open {
+					return false
+				}
+				c.SSEvent("progress", event)
+				return !event.Done()
+			case <-keepAlive.C:
+				_, err := io.WriteString(w, ": keep-alive\n\n")
+				return err == nil
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
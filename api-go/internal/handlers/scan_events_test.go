@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamScanEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	orgID, scanID := uuid.New(), uuid.New()
+	bus := services.NewScanProgressBus()
+	bus.Publish(services.ScanProgressEvent{ScanID: scanID, OrganizationID: orgID, Status: "running"})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("company_id", c.GetHeader("X-Org")) })
+	router.GET("/api/v2/scans/:scan_id/events", StreamScanEvents(services.NewScanService(&config.Config{}, nil, bus)))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(org uuid.UUID, scan string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v2/scans/"+scan+"/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Org", org.String())
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := get(orgID, scanID.String())
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The scan's current state comes first, then each change until it's done
+	events := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		var event strings.Builder
+		for {
+			line, err := events.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return event.String()
+			}
+			event.WriteString(line)
+		}
+	}
+	assert.Contains(t, readEvent(), `"status":"running","progress":0,"findings":0`)
+
+	bus.Publish(services.ScanProgressEvent{ScanID: scanID, OrganizationID: orgID, Status: "running", Progress: 60, Findings: 2})
+	event := readEvent()
+	assert.True(t, strings.HasPrefix(event, "event:progress\n"))
+	assert.Contains(t, event, `"progress":60,"findings":2`)
+
+	bus.Publish(services.ScanProgressEvent{ScanID: scanID, OrganizationID: orgID, Status: "completed", Progress: 100, Findings: 5})
+	assert.Contains(t, readEvent(), `"status":"completed"`)
+	rest, err := io.ReadAll(events)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+
+	// Bad IDs are rejected before anything is streamed
+	resp = get(orgID, "not-a-uuid")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
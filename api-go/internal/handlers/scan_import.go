@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/queue"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ImportScanReport imports a third-party scanner's report, Trivy JSON or
+// SARIF, into the asset it scanned: the agent with the hostname, or an import
+// source such as a CI pipeline, which defaults to the artifact the report
+// names. Its findings are enriched and recorded as an agent's scan result
+// would be, marked with where they were imported from.
+func ImportScanReport(imports *services.ScanImportService, agentService *services.AgentService, enrichmentService *services.EnrichmentService, scheduler *queue.FairScheduler, ingestion *services.ResultIngestionService, evidence *services.EvidenceService, webhooks *services.WebhookService, licenses *services.LicensePolicyService) gin.HandlerFunc {
+	pipeline := resultPipeline{agentService, enrichmentService, ingestion, evidence, webhooks, licenses}
+	return func(c *gin.Context) {
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		format := strings.ToLower(c.Query("format"))
+		if format != "" && !slices.Contains(models.ImportFormats, format) {
+			BadRequest(c, "UNSUPPORTED_FORMAT", "format must be one of "+strings.Join(models.ImportFormats, ", "), format)
+			return
+		}
+		body, err := c.GetRawData()
+		if err != nil {
+			if PayloadTooLarge(c, err) {
+				return
+			}
+			BadRequest(c, "INVALID_REQUEST", "Failed to read request body", err.Error())
+			return
+		}
+		report, err := services.ParseScanReport(body, format)
+		if err != nil {
+			BadRequest(c, "UNSUPPORTED_REPORT", "Scan report could not be imported", err.Error())
+			return
+		}
+
+		hostname := strings.TrimSpace(c.Query("hostname"))
+		sourceID := strings.TrimSpace(c.Query("source"))
+		if hostname == "" && sourceID == "" {
+			sourceID = report.Artifact
+		}
+		if hostname == "" && sourceID == "" {
+			BadRequest(c, "ASSET_REQUIRED", "Name the scanned asset with the hostname or source query parameter", nil)
+			return
+		}
+		agent, err := imports.ResolveAsset(organizationID, hostname, sourceID)
+		if err != nil {
+			if errors.Is(err, services.ErrImportAssetNotFound) {
+				ErrorResponse(c, http.StatusNotFound, "ASSET_NOT_FOUND", "No agent has that hostname; pass source to import into an import source instead", hostname)
+				return
+			}
+			InternalServerError(c, "IMPORT_FAILED", "Failed to resolve the scanned asset", err)
+			return
+		}
+
+		now := time.Now()
+		record := &models.ScanImport{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			AgentID:        agent.ID,
+			SourceID:       sourceID,
+			Hostname:       hostname,
+			Format:         report.Format,
+			Scanner:        report.Scanner,
+			Artifact:       report.Artifact,
+			Findings:       len(report.Findings),
+			ImportedBy:     c.GetString("user_id"),
+			CreatedAt:      now,
+		}
+		result := imports.ImportResult(record, report, now)
+
+		// Wait for this organization's turn, as agents' results do
+		release, err := scheduler.Acquire(c.Request.Context(), organizationID.String())
+		if err != nil {
+			log.Printf("[ImportScanReport] Gave up waiting for a processing slot for org %s: %v", organizationID, err)
+			ErrorResponse(c, http.StatusServiceUnavailable, "CAPACITY_UNAVAILABLE", "Processing capacity unavailable, retry later", nil)
+			return
+		}
+		defer release()
+
+		if err := pipeline.process(c.Request.Context(), agent.ID.String(), []models.AgentScanResult{result}, map[string]interface{}{"import_id": record.ID.String()}); err != nil {
+			InternalServerError(c, "IMPORT_FAILED", "Failed to record imported findings", err)
+			return
+		}
+		if err := imports.Record(record); err != nil {
+			// The findings are recorded, only the import's own record is missing
+			log.Printf("[ImportScanReport] %v", err)
+		}
+
+		SuccessResponse(c, http.StatusCreated, record, "Scan report imported")
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportScanReportRejectsBadReports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/import", func(c *gin.Context) {
+		c.Set("company_id", uuid.NewString())
+	}, ImportScanReport(nil, nil, nil, nil, nil, nil, nil, nil))
+
+	for name, tc := range map[string]struct {
+		query, body, code string
+	}{
+		"unknown format":   {"?format=cyclonedx", `{}`, "UNSUPPORTED_FORMAT"},
+		"unknown schema":   {"?hostname=web-01", `{"matches": []}`, "UNSUPPORTED_REPORT"},
+		"old Trivy schema": {"?format=trivy&hostname=web-01", `{"SchemaVersion": 1, "Results": []}`, "UNSUPPORTED_REPORT"},
+		"no asset to name": {"", `{"version": "2.1.0", "runs": []}`, "ASSET_REQUIRED"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import"+tc.query, strings.NewReader(tc.body)))
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tc.code, body.Error.Code)
+		})
+	}
+}
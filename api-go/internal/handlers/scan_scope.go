@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetScanScope returns the scanners an agent runs and the paths they cover
+func GetScanScope(scanScopeService *services.ScanScopeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID", err.Error())
+			return
+		}
+		respondScanScope(c, scanScopeService, agentID)
+	}
+}
+
+// UpdateScanScope replaces an agent's scan scope
+func UpdateScanScope(scanScopeService *services.ScanScopeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID", err.Error())
+			return
+		}
+
+		var req models.UpdateScanScopeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+			return
+		}
+		req.UpdatedBy = c.GetString("user_id")
+
+		scope, err := scanScopeService.UpdateScope(agentID, &req)
+		if errors.Is(err, services.ErrAgentNotFound) {
+			NotFound(c, "AGENT_NOT_FOUND", "Agent not found")
+			return
+		}
+		if err != nil {
+			BadRequest(c, "UPDATE_FAILED", "Failed to update scan scope", err.Error())
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, scope, "Scan scope updated successfully")
+	}
+}
+
+// DeleteScanScope returns an agent to the default scan scope
+func DeleteScanScope(scanScopeService *services.ScanScopeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID", err.Error())
+			return
+		}
+
+		if err := scanScopeService.DeleteScope(agentID); err != nil {
+			if errors.Is(err, services.ErrAgentNotFound) {
+				NotFound(c, "AGENT_NOT_FOUND", "Agent not found")
+				return
+			}
+			InternalServerError(c, "DELETE_FAILED", "Failed to delete scan scope", err)
+			return
+		}
+
+		SuccessResponse(c, http.StatusOK, nil, "Scan scope reset to the default")
+	}
+}
+
+// GetAgentScanScope returns the scan scope an agent fetches before scanning
+func GetAgentScanScope(scanScopeService *services.ScanScopeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Query("agent_id"))
+		if err != nil {
+			BadRequest(c, "INVALID_AGENT_ID", "Invalid agent ID format", err.Error())
+			return
+		}
+		if !certifiedAgent(c, c.Query("agent_id")) {
+			return
+		}
+		respondScanScope(c, scanScopeService, agentID)
+	}
+}
+
+func respondScanScope(c *gin.Context, scanScopeService *services.ScanScopeService, agentID uuid.UUID) {
+	scope, err := scanScopeService.GetScope(agentID)
+	if errors.Is(err, services.ErrAgentNotFound) {
+		NotFound(c, "AGENT_NOT_FOUND", "Agent not found")
+		return
+	}
+	if err != nil {
+		InternalServerError(c, "GET_FAILED", "Failed to retrieve scan scope", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, scope, "Scan scope retrieved successfully")
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/repository"
+	"zerotrace/api/internal/services"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScanResultsQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	parse := func(query string) ([]models.SeverityLevel, int, int, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/scans/x/results?"+query, nil)
+		return parseScanResultsQuery(c)
+	}
+
+	severities, limit, offset, err := parse("")
+	assert.NoError(t, err)
+	assert.Empty(t, severities)
+	assert.Equal(t, defaultScanResultsLimit, limit)
+	assert.Equal(t, 0, offset)
+
+	severities, limit, offset, err = parse("severity=critical,%20High&limit=20&offset=40")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.SeverityLevel{models.SeverityCritical, models.SeverityHigh}, severities)
+	assert.Equal(t, 20, limit)
+	assert.Equal(t, 40, offset)
+
+	for _, query := range []string{"severity=urgent", "limit=0", "limit=501", "limit=ten", "offset=-1"} {
+		_, _, _, err := parse(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestGetScanResultsRejectsBadRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/v1/scans/:id/results", GetScanResults(nil))
+
+	for _, path := range []string{
+		"/api/v1/scans/not-a-uuid/results",
+		"/api/v1/scans/6f1c1b7e-3c0a-4c43-9d43-1f2a1e7f9a10/results?severity=urgent",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestGetScanResults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t, &models.Scan{}, &models.Vulnerability{})
+	orgA, orgB := uuid.New(), uuid.New()
+	now := time.Now()
+	scan := models.Scan{ID: uuid.New(), CompanyID: orgA, Repository: "app", Status: models.ScanStatusCompleted, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, db.Create(&scan).Error)
+	for _, severity := range []models.SeverityLevel{"critical", "high", "high", "low"} {
+		vuln := models.Vulnerability{ID: uuid.NewString(), ScanID: scan.ID, CompanyID: orgA, Severity: severity, Title: "CVE-2025-0001", CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, db.Omit("References", "AffectedVersions", "PatchedVersions", "EnrichmentData").Create(&vuln).Error)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("company_id", c.GetHeader("X-Test-Org"))
+		c.Next()
+	})
+	router.GET("/api/v1/scans/:id/results", GetScanResults(services.NewScanService(&config.Config{}, repository.NewScanRepository(db), nil)))
+	get := func(org uuid.UUID, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Test-Org", org.String())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(orgA, "/api/v1/scans/"+uuid.NewString()+"/results")
+	assert.Equal(t, http.StatusNotFound, w.Code, "unknown scan")
+	assert.Contains(t, w.Body.String(), "SCAN_NOT_FOUND")
+
+	w = get(orgB, "/api/v1/scans/"+scan.ID.String()+"/results")
+	assert.Equal(t, http.StatusForbidden, w.Code, "another organization's scan")
+	assert.Contains(t, w.Body.String(), "SCAN_FORBIDDEN")
+
+	w = get(orgA, "/api/v1/scans/"+scan.ID.String()+"/results?severity=high&limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data models.ScanResults `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data.Vulnerabilities, 1)
+	assert.Equal(t, int64(2), body.Data.Total, "vulnerabilities matching the filter")
+	assert.Equal(t, map[models.SeverityLevel]int64{
+		models.SeverityCritical: 1, models.SeverityHigh: 2, models.SeverityMedium: 0, models.SeverityLow: 1, models.SeverityInfo: 0,
+	}, body.Data.SeverityCounts, "counts cover every severity, unfiltered")
+}
+
+func TestDiffAgentScansRejectsBadRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/v1/agents/:id/scans/diff", DiffAgentScans(nil))
+
+	id := "6f1c1b7e-3c0a-4c43-9d43-1f2a1e7f9a10"
+	for _, path := range []string{
+		"/api/v1/agents/not-a-uuid/scans/diff?from=" + id + "&to=" + id,
+		"/api/v1/agents/" + id + "/scans/diff?to=" + id,
+		"/api/v1/agents/" + id + "/scans/diff?from=" + id + "&to=latest",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestExportsRejectUnknownFormats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("company_id", "6f1c1b7e-3c0a-4c43-9d43-1f2a1e7f9a10") })
+	router.GET("/api/v1/scans/:id/export", ExportScan(nil))
+	router.GET("/api/v2/config-findings/export", NewConfigFindingHandler(nil).ExportConfigFindings)
+
+	for _, path := range []string{
+		"/api/v1/scans/not-a-uuid/export?format=sarif",
+		"/api/v1/scans/6f1c1b7e-3c0a-4c43-9d43-1f2a1e7f9a10/export?format=pdf",
+		"/api/v2/config-findings/export?format=xml",
+		"/api/v2/config-findings/export?format=sarif&config_file_id=latest",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
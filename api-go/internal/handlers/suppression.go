@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SuppressionHandler handles the per-organization rules suppressing accepted
+// risks and false positives
+type SuppressionHandler struct {
+	suppressionService *services.SuppressionService
+}
+
+// NewSuppressionHandler creates a new suppression handler
+func NewSuppressionHandler(suppressionService *services.SuppressionService) *SuppressionHandler {
+	return &SuppressionHandler{
+		suppressionService: suppressionService,
+	}
+}
+
+// ListRules lists an organization's active suppression rules, and its expired
+// ones too with include_expired=true
+func (h *SuppressionHandler) ListRules(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	rules, err := h.suppressionService.ListRules(organizationID, c.Query("include_expired") == "true")
+	if err != nil {
+		InternalServerError(c, "LIST_FAILED", "Failed to list suppression rules", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, rules, "Suppression rules retrieved successfully")
+}
+
+// CreateRule adds a suppression rule to an organization, suppressing the
+// findings it matches, owned by the user adding it
+func (h *SuppressionHandler) CreateRule(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.SuppressionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+	req.Owner = c.GetString("user_id")
+
+	rule, err := h.suppressionService.CreateRule(organizationID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSuppressionRule) {
+			BadRequest(c, "INVALID_RULE", "Invalid suppression rule", err.Error())
+			return
+		}
+		InternalServerError(c, "CREATE_FAILED", "Failed to create suppression rule", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, rule, "Suppression rule created successfully")
+}
+
+// DeleteRule removes a suppression rule, reopening the findings it suppressed
+func (h *SuppressionHandler) DeleteRule(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_RULE_ID", "Invalid suppression rule ID", err.Error())
+		return
+	}
+
+	if err := h.suppressionService.DeleteRule(organizationID, ruleID); err != nil {
+		if errors.Is(err, services.ErrSuppressionRuleNotFound) {
+			NotFound(c, "RULE_NOT_FOUND", "Suppression rule not found")
+			return
+		}
+		InternalServerError(c, "DELETE_FAILED", "Failed to delete suppression rule", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, nil, "Suppression rule deleted successfully")
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppressionHandlerRejectsBadRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewSuppressionHandler(services.NewSuppressionService(nil, nil, nil, nil))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "user_alice")
+		c.Next()
+	})
+	router.POST("/organizations/:id/suppressions", h.CreateRule)
+	router.DELETE("/organizations/:id/suppressions/:rule_id", h.DeleteRule)
+
+	org := uuid.NewString()
+	for _, tc := range []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/organizations/not-a-uuid/suppressions", `{"cve_id": "CVE-2025-1234", "reason": "False positive"}`},
+		{http.MethodPost, "/organizations/" + org + "/suppressions", `{"cve_id": "CVE-2025-1234"}`},
+		{http.MethodPost, "/organizations/" + org + "/suppressions", `{"reason": "False positive"}`},
+		{http.MethodPost, "/organizations/" + org + "/suppressions", `{"package_version": "1.0.0", "reason": "False positive"}`},
+		{http.MethodPost, "/organizations/" + org + "/suppressions", `{"cve_id": "CVE-2025-1234", "reason": "False positive", "owner": "alice", "expires_at": "2020-01-01T00:00:00Z"}`},
+		{http.MethodDelete, "/organizations/" + org + "/suppressions/not-a-uuid", ""},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.method+" "+tc.path+" "+tc.body)
+	}
+}
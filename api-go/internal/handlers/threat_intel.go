@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ThreatIntelHandler handles an organization's threat intelligence feeds
+type ThreatIntelHandler struct {
+	threatIntelService *services.ThreatIntelService
+}
+
+// NewThreatIntelHandler creates a new threat intel handler
+func NewThreatIntelHandler(threatIntelService *services.ThreatIntelService) *ThreatIntelHandler {
+	return &ThreatIntelHandler{
+		threatIntelService: threatIntelService,
+	}
+}
+
+// ListFeeds lists an organization's feeds with their fetch state
+func (h *ThreatIntelHandler) ListFeeds(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	feeds, err := h.threatIntelService.ListFeeds(organizationID)
+	if err != nil {
+		InternalServerError(c, "LIST_FAILED", "Failed to list threat intel feeds", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, feeds, "Threat intel feeds retrieved successfully")
+}
+
+// CreateFeed adds a feed to an organization
+func (h *ThreatIntelHandler) CreateFeed(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return
+	}
+
+	var req models.ThreatIntelFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+
+	feed, err := h.threatIntelService.CreateFeed(organizationID, &req)
+	if err != nil {
+		BadRequest(c, "CREATE_FAILED", "Failed to create threat intel feed", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, feed, "Threat intel feed created")
+}
+
+// UpdateFeed replaces a feed's settings
+func (h *ThreatIntelHandler) UpdateFeed(c *gin.Context) {
+	organizationID, feedID, ok := parseFeedIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.ThreatIntelFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+
+	feed, err := h.threatIntelService.UpdateFeed(organizationID, feedID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrThreatIntelFeedNotFound) {
+			NotFound(c, "FEED_NOT_FOUND", "Threat intel feed not found")
+			return
+		}
+		BadRequest(c, "UPDATE_FAILED", "Failed to update threat intel feed", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, feed, "Threat intel feed updated")
+}
+
+// DeleteFeed removes a feed and the intel it provided
+func (h *ThreatIntelHandler) DeleteFeed(c *gin.Context) {
+	organizationID, feedID, ok := parseFeedIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.threatIntelService.DeleteFeed(organizationID, feedID); err != nil {
+		if errors.Is(err, services.ErrThreatIntelFeedNotFound) {
+			NotFound(c, "FEED_NOT_FOUND", "Threat intel feed not found")
+			return
+		}
+		InternalServerError(c, "DELETE_FAILED", "Failed to delete threat intel feed", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, nil, "Threat intel feed deleted")
+}
+
+// RefreshFeed fetches a feed now. A failed fetch is reported on the feed,
+// whose cached indicators stay in use.
+func (h *ThreatIntelHandler) RefreshFeed(c *gin.Context) {
+	organizationID, feedID, ok := parseFeedIDs(c)
+	if !ok {
+		return
+	}
+
+	feed, err := h.threatIntelService.RefreshFeed(c.Request.Context(), organizationID, feedID)
+	switch {
+	case errors.Is(err, services.ErrThreatIntelFeedNotFound):
+		NotFound(c, "FEED_NOT_FOUND", "Threat intel feed not found")
+	case errors.Is(err, services.ErrThreatIntelFeedBusy):
+		ErrorResponse(c, http.StatusConflict, "REFRESH_RUNNING", err.Error(), nil)
+	case err != nil:
+		InternalServerError(c, "REFRESH_FAILED", "Failed to refresh threat intel feed", err)
+	default:
+		SuccessResponse(c, http.StatusOK, feed, "Threat intel feed refreshed")
+	}
+}
+
+// parseFeedIDs parses the organization and feed IDs in the path
+func parseFeedIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid organization ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	feedID, err := uuid.Parse(c.Param("feed_id"))
+	if err != nil {
+		BadRequest(c, "INVALID_FEED_ID", "Invalid feed ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	return organizationID, feedID, true
+}
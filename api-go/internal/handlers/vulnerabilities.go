@@ -48,6 +48,7 @@ func GetPublicVulnerabilities(agentService *services.AgentService) gin.HandlerFu
 								"agent_id":        agent.ID,
 								"agent_name":      agent.Name,
 								"agent_hostname":  agent.Hostname,
+								"introduced_by":   vuln.IntroducedBy,
 							}
 							vulnerabilities = append(vulnerabilities, vulnMap)
 						}
@@ -84,3 +85,39 @@ func GetPublicVulnerabilities(agentService *services.AgentService) gin.HandlerFu
 		c.JSON(http.StatusOK, response)
 	}
 }
+
+// GetVulnerability retrieves a single vulnerability reported by one of the
+// caller's organization's agents, with its attribution
+func GetVulnerability(agentService *services.AgentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, ok := getCompanyIDOrError(c)
+		if !ok {
+			return
+		}
+
+		vuln, agent, found := agentService.GetAgentVulnerability(organizationID, c.Param("id"))
+		if !found {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error: &models.APIError{
+					Code:    "NOT_FOUND",
+					Message: "Vulnerability not found",
+				},
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Data: gin.H{
+				"vulnerability":  vuln,
+				"introduced_by":  vuln.IntroducedBy,
+				"agent_id":       agent.ID,
+				"agent_name":     agent.Name,
+				"agent_hostname": agent.Hostname,
+			},
+			Timestamp: time.Now(),
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVulnerabilityIsScopedToOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testdb.Open(t, &models.Agent{}, &models.AgentTag{}, &models.AgentStatusEvent{})
+	agentService := services.NewAgentService(db, &config.Config{})
+	orgA, orgB := uuid.New(), uuid.New()
+	_, err := agentService.RegisterAgent(models.Agent{
+		ID:             uuid.New(),
+		OrganizationID: orgA,
+		Hostname:       "web-1",
+		Metadata: map[string]interface{}{"vulnerabilities": []models.Vulnerability{
+			{ID: "vuln-1", CVEID: "CVE-2025-1111", IntroducedBy: &models.Attribution{ChangeType: models.ChangePackageInstalled}},
+		}},
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/api/vulnerabilities/:id", func(c *gin.Context) {
+		if org := c.GetHeader("X-Test-Org"); org != "" {
+			c.Set("company_id", org)
+		}
+	}, GetVulnerability(agentService))
+	get := func(id, org string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/vulnerabilities/"+id, nil)
+		req.Header.Set("X-Test-Org", org)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("vuln-1", orgA.String())
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"change_type":"package_installed"`)
+	assert.Contains(t, w.Body.String(), `"agent_hostname":"web-1"`)
+
+	assert.Equal(t, http.StatusNotFound, get("vuln-1", orgB.String()).Code, "another organization's vulnerabilities are not found")
+	assert.Equal(t, http.StatusNotFound, get("vuln-2", orgA.String()).Code)
+	assert.Equal(t, http.StatusBadRequest, get("vuln-1", "").Code)
+}
@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +17,7 @@ import (
 	"zerotrace/api/internal/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // VulnerabilityV2Handler handles API v2 vulnerability endpoints
@@ -146,7 +153,8 @@ func (h *VulnerabilityV2Handler) GetComplianceStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// ExportVulnerabilities exports vulnerabilities in various formats
+// ExportVulnerabilities exports vulnerabilities in various formats, read from
+// the export or format query parameter. It takes the list endpoint's filters.
 func (h *VulnerabilityV2Handler) ExportVulnerabilities(c *gin.Context) {
 	var req types.VulnerabilityV2Request
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -155,10 +163,19 @@ func (h *VulnerabilityV2Handler) ExportVulnerabilities(c *gin.Context) {
 	}
 
 	// Set default export format
+	if req.Export == "" {
+		req.Export = c.Query("format")
+	}
 	if req.Export == "" {
 		req.Export = "json"
 	}
 
+	// CSV is written as vulnerabilities are read rather than loaded first
+	if strings.EqualFold(req.Export, "csv") {
+		h.exportCSV(c, req)
+		return
+	}
+
 	// Get vulnerabilities
 	vulnerabilities, _, err := h.vulnerabilityService.GetVulnerabilitiesV2(req)
 	if err != nil {
@@ -173,8 +190,6 @@ func (h *VulnerabilityV2Handler) ExportVulnerabilities(c *gin.Context) {
 	switch strings.ToLower(req.Export) {
 	case "json":
 		h.exportJSON(c, vulnModels)
-	case "csv":
-		h.exportCSV(c, vulnModels)
 	case "pdf":
 		h.exportPDF(c, vulnModels)
 	case "sarif":
@@ -211,8 +226,20 @@ func (h *VulnerabilityV2Handler) InitiateNetworkScan(c *gin.Context) {
 		req.Concurrency = 10
 	}
 
+	// The scan belongs to the organization of the agent running it
+	agentID, err := uuid.Parse(req.AgentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent_id"})
+		return
+	}
+	agent, exists := h.agentService.GetAgent(agentID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+
 	// Initiate scan
-	scanID, err := h.vulnerabilityService.InitiateNetworkScan(req)
+	scanID, err := h.vulnerabilityService.InitiateNetworkScan(agent.OrganizationID, agentID, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -320,30 +347,122 @@ func (h *VulnerabilityV2Handler) exportJSON(c *gin.Context, vulnerabilities []mo
 	c.JSON(http.StatusOK, vulnerabilities)
 }
 
-// exportCSV exports vulnerabilities as CSV
-func (h *VulnerabilityV2Handler) exportCSV(c *gin.Context, vulnerabilities []models.VulnerabilityV2) {
-	c.Header("Content-Type", "text/csv")
-	c.Header("Content-Disposition", "attachment; filename=vulnerabilities.csv")
-
-	// CSV header
-	csv := "ID,Title,Severity,Category,Status,Discovered,Agent,Risk Score,Description\n"
+// vulnerabilityCSVColumns are the columns of CSV exports
+var vulnerabilityCSVColumns = []string{"CVE ID", "Title", "Severity", "CVSS Score", "Affected Package", "Agent Hostname", "First Seen", "Status"}
+
+// csvFlushRows is how many rows a CSV export writes between flushes
+const csvFlushRows = 500
+
+// exportCSV streams the vulnerabilities matching the request as CSV. Rows are
+// flushed to the client as they are written, so an export of any size needs
+// only a buffer's worth of memory.
+func (h *VulnerabilityV2Handler) exportCSV(c *gin.Context, req types.VulnerabilityV2Request) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=vulnerabilities-%s.csv", time.Now().UTC().Format("2006-01-02")))
+	c.Status(http.StatusOK)
+
+	writer := h.newVulnerabilityCSVWriter(c.Writer)
+	rows := 0
+	err := h.vulnerabilityService.ForEachVulnerabilityV2(req, func(vuln models.VulnerabilityV2) error {
+		if err := writer.WriteVulnerability(vuln); err != nil {
+			return err
+		}
+		if rows++; rows%csvFlushRows == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+			return writer.Error()
+		}
+		return nil
+	})
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		// The response has started, so all that's left is to cut it short
+		log.Printf("[ExportVulnerabilities] CSV export stopped after %d rows: %v", rows, err)
+	}
+}
 
-	// CSV data
+// vulnerabilitiesCSV renders vulnerabilities as CSV
+func (h *VulnerabilityV2Handler) vulnerabilitiesCSV(vulnerabilities []models.VulnerabilityV2) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := h.newVulnerabilityCSVWriter(&buf)
 	for _, vuln := range vulnerabilities {
-		csv += fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%.2f,%s\n",
-			vuln.ID,
-			vuln.Title,
-			vuln.Severity,
-			vuln.Category,
-			vuln.Status,
-			vuln.DiscoveredAt.Format("2006-01-02 15:04:05"),
-			vuln.AgentID,
-			vuln.RiskScore,
-			vuln.Description,
-		)
-	}
-
-	c.String(http.StatusOK, csv)
+		if err := writer.WriteVulnerability(vuln); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// vulnerabilityCSVWriter writes vulnerabilities as CSV rows, looking up each
+// agent's hostname once
+type vulnerabilityCSVWriter struct {
+	*csv.Writer
+	agentService *services.AgentService
+	hostnames    map[string]string
+}
+
+// newVulnerabilityCSVWriter returns a writer that has written the header row
+func (h *VulnerabilityV2Handler) newVulnerabilityCSVWriter(w io.Writer) *vulnerabilityCSVWriter {
+	writer := &vulnerabilityCSVWriter{
+		Writer:       csv.NewWriter(w),
+		agentService: h.agentService,
+		hostnames:    make(map[string]string),
+	}
+	writer.Write(vulnerabilityCSVColumns) // Errors are kept for Error
+	return writer
+}
+
+// WriteVulnerability writes a vulnerability's row. The CVE, CVSS score and
+// package come from the cve_id, cvss_score and package_name details scanners
+// attach to findings, and are left empty for findings without them.
+func (w *vulnerabilityCSVWriter) WriteVulnerability(vuln models.VulnerabilityV2) error {
+	cvss := vuln.Detail("cvss_score")
+	if score, err := strconv.ParseFloat(cvss, 64); err == nil {
+		cvss = strconv.FormatFloat(score, 'f', 1, 64)
+	}
+	firstSeen := ""
+	if !vuln.DiscoveredAt.IsZero() {
+		firstSeen = vuln.DiscoveredAt.UTC().Format(time.RFC3339)
+	}
+	return w.Write([]string{
+		csvCell(vuln.Detail("cve_id")),
+		csvCell(vuln.Title),
+		vuln.Severity,
+		cvss,
+		csvCell(vuln.Detail("package_name")),
+		csvCell(w.hostname(vuln.AgentID)),
+		firstSeen,
+		vuln.Status,
+	})
+}
+
+// hostname returns the hostname of the agent that reported a vulnerability,
+// or "" for agents that aren't registered
+func (w *vulnerabilityCSVWriter) hostname(agentID string) string {
+	if hostname, ok := w.hostnames[agentID]; ok {
+		return hostname
+	}
+	hostname := ""
+	if id, err := uuid.Parse(agentID); err == nil && w.agentService != nil {
+		if agent, exists := w.agentService.GetAgent(id); exists {
+			hostname = agent.Hostname
+		}
+	}
+	w.hostnames[agentID] = hostname
+	return hostname
+}
+
+// csvCell guards a value against being run as a formula when the export is
+// opened in a spreadsheet
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
 }
 
 // exportPDF exports vulnerabilities as PDF
@@ -358,9 +477,13 @@ func (h *VulnerabilityV2Handler) exportPDF(c *gin.Context, vulnerabilities []mod
 func (h *VulnerabilityV2Handler) exportSARIF(c *gin.Context, vulnerabilities []models.VulnerabilityV2) {
 	c.Header("Content-Type", "application/json")
 	c.Header("Content-Disposition", "attachment; filename=vulnerabilities.sarif")
+	c.JSON(http.StatusOK, h.sarifDocument(vulnerabilities))
+}
 
+// sarifDocument wraps vulnerabilities in a SARIF log
+func (h *VulnerabilityV2Handler) sarifDocument(vulnerabilities []models.VulnerabilityV2) map[string]interface{} {
 	// SARIF format
-	sarif := map[string]interface{}{
+	return map[string]interface{}{
 		"$schema": "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
 		"version": "2.1.0",
 		"runs": []map[string]interface{}{
@@ -375,8 +498,24 @@ func (h *VulnerabilityV2Handler) exportSARIF(c *gin.Context, vulnerabilities []m
 			},
 		},
 	}
+}
 
-	c.JSON(http.StatusOK, sarif)
+// renderExport renders vulnerabilities in an export format for async exports,
+// returning the content and its content type. PDF is not supported.
+func (h *VulnerabilityV2Handler) renderExport(format string, vulnerabilities []models.VulnerabilityV2) ([]byte, string, error) {
+	switch format {
+	case "json":
+		data, err := json.Marshal(vulnerabilities)
+		return data, "application/json", err
+	case "csv":
+		data, err := h.vulnerabilitiesCSV(vulnerabilities)
+		return data, "text/csv", err
+	case "sarif":
+		data, err := json.Marshal(h.sarifDocument(vulnerabilities))
+		return data, "application/json", err
+	default:
+		return nil, "", fmt.Errorf("unsupported export format %q", format)
+	}
 }
 
 // convertToSARIFResults converts vulnerabilities to SARIF results
@@ -400,10 +539,14 @@ func (h *VulnerabilityV2Handler) convertToSARIFResults(vulnerabilities []models.
 				},
 			},
 			"properties": map[string]interface{}{
-				"category":   vuln.Category,
-				"risk_score": vuln.RiskScore,
-				"status":     vuln.Status,
-				"discovered": vuln.DiscoveredAt,
+				"category":            vuln.Category,
+				"risk_score":          vuln.RiskScore,
+				"cvss_score":          vuln.CVSSScore,
+				"environmental_score": vuln.EnvironmentalScore,
+				"epss_score":          vuln.EPSSScore,
+				"epss_percentile":     vuln.EPSSPercentile,
+				"status":              vuln.Status,
+				"discovered":          vuln.DiscoveredAt,
 			},
 		}
 		results = append(results, result)
@@ -505,6 +648,8 @@ func convertVulnerabilitiesToModels(vulnerabilities []types.VulnerabilityV2Data)
 			Tags:                 vuln.Tags,
 			Metadata:             vuln.Metadata,
 			EnrichmentData:       vuln.EnrichmentData,
+			Suppressed:           vuln.Suppressed,
+			SuppressedBy:         vuln.SuppressedBy,
 			CreatedAt:            vuln.CreatedAt,
 			UpdatedAt:            vuln.UpdatedAt,
 		})
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnerabilitiesCSV(t *testing.T) {
+	h := NewVulnerabilityV2Handler(services.NewVulnerabilityV2Service(nil, nil), nil)
+	data, err := h.vulnerabilitiesCSV([]models.VulnerabilityV2{
+		{
+			AgentID:        "not-registered",
+			Title:          "OpenSSL, heap overflow",
+			Severity:       "critical",
+			Status:         "open",
+			DiscoveredAt:   time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC),
+			Metadata:       map[string]interface{}{"cve_id": "CVE-2024-0727", "package_name": "openssl"},
+			EnrichmentData: map[string]interface{}{"cvss_score": 9.81},
+		},
+		{Title: "=HYPERLINK(\"http://evil\")", Severity: "low", Status: "resolved"},
+	})
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		vulnerabilityCSVColumns,
+		{"CVE-2024-0727", "OpenSSL, heap overflow", "critical", "9.8", "openssl", "", "2026-09-01T12:00:00Z", "open"},
+		{"", "'=HYPERLINK(\"http://evil\")", "low", "", "", "", "", "resolved"},
+	}, rows)
+}
+
+func TestExportVulnerabilitiesCSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/export", NewVulnerabilityV2Handler(services.NewVulnerabilityV2Service(nil, nil), nil).ExportVulnerabilities)
+
+	for _, query := range []string{"format=csv", "export=CSV&severity=high&status=open"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export?"+query, nil))
+		assert.Equal(t, http.StatusOK, w.Code, query)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"), query)
+		assert.Regexp(t, `^attachment; filename=vulnerabilities-\d{4}-\d{2}-\d{2}\.csv$`, w.Header().Get("Content-Disposition"), query)
+		assert.Equal(t, strings.Join(vulnerabilityCSVColumns, ",")+"\n", w.Body.String(), query)
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookHandler handles the webhooks an organization's critical and high
+// findings are sent to
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// ListWebhooks lists the caller's organization's webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	organizationID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(organizationID)
+	if err != nil {
+		InternalServerError(c, "LIST_FAILED", "Failed to list webhooks", err)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, webhooks, "Webhooks retrieved successfully")
+}
+
+// GetWebhook returns one webhook
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	organizationID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(organizationID, webhookID)
+	if err != nil {
+		respondWebhookError(c, err, "GET_FAILED", "Failed to get webhook")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, webhook, "Webhook retrieved successfully")
+}
+
+// CreateWebhook adds a webhook to the caller's organization
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	organizationID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(organizationID, &req)
+	if err != nil {
+		BadRequest(c, "CREATE_FAILED", "Failed to create webhook", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, webhook, "Webhook created")
+}
+
+// UpdateWebhook replaces a webhook's settings
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	organizationID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "INVALID_REQUEST", "Invalid request payload", err.Error())
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(organizationID, webhookID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			NotFound(c, "WEBHOOK_NOT_FOUND", "Webhook not found")
+			return
+		}
+		BadRequest(c, "UPDATE_FAILED", "Failed to update webhook", err.Error())
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, webhook, "Webhook updated")
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	organizationID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(organizationID, webhookID); err != nil {
+		respondWebhookError(c, err, "DELETE_FAILED", "Failed to delete webhook")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, nil, "Webhook deleted")
+}
+
+// ListDeliveries returns a webhook's delivery log, newest first
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	organizationID, webhookID, ok := parseWebhookIDs(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		BadRequest(c, "INVALID_LIMIT", "limit must be between 1 and 500", nil)
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(organizationID, webhookID, limit)
+	if err != nil {
+		respondWebhookError(c, err, "LIST_FAILED", "Failed to list webhook deliveries")
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, deliveries, "Webhook deliveries retrieved successfully")
+}
+
+// parseWebhookIDs reads the caller's organization and the webhook ID in the path
+func parseWebhookIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	organizationID, ok := getCompanyIDOrError(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "INVALID_ID", "Invalid webhook ID", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+	return organizationID, webhookID, true
+}
+
+func respondWebhookError(c *gin.Context, err error, code, message string) {
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		NotFound(c, "WEBHOOK_NOT_FOUND", "Webhook not found")
+		return
+	}
+	InternalServerError(c, code, message, err)
+}
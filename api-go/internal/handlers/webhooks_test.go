@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookHandlerRejectsBadRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewWebhookHandler(&services.WebhookService{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if org := c.GetHeader("X-Test-Org"); org != "" {
+			c.Set("company_id", org)
+		}
+	})
+	router.POST("/webhooks", h.CreateWebhook)
+	router.PUT("/webhooks/:id", h.UpdateWebhook)
+	router.GET("/webhooks/:id/deliveries", h.ListDeliveries)
+
+	org := uuid.NewString()
+	for _, tc := range []struct {
+		method, path, org, body string
+	}{
+		{http.MethodPost, "/webhooks", "", `{"name": "pager", "url": "https://hooks.example.com"}`},
+		{http.MethodPost, "/webhooks", org, `{"url": "https://hooks.example.com"}`},
+		{http.MethodPost, "/webhooks", org, `{"name": "pager", "url": "not a url"}`},
+		{http.MethodPost, "/webhooks", org, `{"name": "pager", "url": "https://hooks.example.com", "min_severity": "low"}`},
+		{http.MethodPost, "/webhooks", org, `{"name": "pager", "url": "https://hooks.example.com", "format": "pagerduty"}`},
+		{http.MethodPut, "/webhooks/not-a-uuid", org, `{"name": "pager", "url": "https://hooks.example.com"}`},
+		{http.MethodGet, "/webhooks/" + uuid.NewString() + "/deliveries?limit=0", org, ""},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.org != "" {
+			req.Header.Set("X-Test-Org", tc.org)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.method+" "+tc.path+" "+tc.body)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"zerotrace/api/internal/queue"
+
 	"github.com/hibiken/asynq"
 )
 
@@ -32,14 +34,16 @@ type JobPayload struct {
 
 // JobManager manages background jobs using asynq
 type JobManager struct {
-	client *asynq.Client
-	server *asynq.Server
-	mux    *asynq.ServeMux
+	client    *asynq.Client
+	server    *asynq.Server
+	mux       *asynq.ServeMux
+	scheduler *queue.FairScheduler
 }
 
 // NewJobManager creates a new job manager
-// Uses Valkey (Redis-compatible) for job queue storage
-func NewJobManager(redisOpt *asynq.RedisClientOpt) *JobManager {
+// Uses Valkey (Redis-compatible) for job queue storage. When a scheduler is
+// provided, jobs are admitted per company so no tenant can hold every worker.
+func NewJobManager(redisOpt *asynq.RedisClientOpt, scheduler *queue.FairScheduler) *JobManager {
 	client := asynq.NewClient(redisOpt)
 	
 	server := asynq.NewServer(
@@ -60,9 +64,10 @@ func NewJobManager(redisOpt *asynq.RedisClientOpt) *JobManager {
 	mux := asynq.NewServeMux()
 	
 	return &JobManager{
-		client: client,
-		server: server,
-		mux:    mux,
+		client:    client,
+		server:    server,
+		mux:       mux,
+		scheduler: scheduler,
 	}
 }
 
@@ -90,7 +95,30 @@ func (jm *JobManager) EnqueueJob(
 
 // RegisterHandler registers a job handler
 func (jm *JobManager) RegisterHandler(jobType JobType, handler asynq.Handler) {
-	jm.mux.HandleFunc(string(jobType), handler)
+	jm.mux.Handle(string(jobType), jm.fairHandler(handler))
+}
+
+// fairHandler wraps a handler so each job waits for a slot from the fair
+// scheduler for its company before running
+func (jm *JobManager) fairHandler(handler asynq.Handler) asynq.Handler {
+	if jm.scheduler == nil {
+		return handler
+	}
+
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var payload JobPayload
+		if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+
+		release, err := jm.scheduler.Acquire(ctx, payload.CompanyID)
+		if err != nil {
+			return fmt.Errorf("failed to acquire processing slot for company %s: %w", payload.CompanyID, err)
+		}
+		defer release()
+
+		return handler.ProcessTask(ctx, t)
+	})
 }
 
 // Start starts the job server
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AgentLookup finds the agent a route acts on
+type AgentLookup interface {
+	GetAgent(agentID uuid.UUID) (*models.Agent, bool)
+}
+
+// APIKeyAuthenticator looks up the API key a request presents
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// APIKeyOrClerkAuth authenticates requests bearing an API key (Bearer zt_...)
+// with keys, and every other request with clerkAuth. API key requests carry
+// their organization in company_id, like Clerk's, and the key's ID and
+// permissions in api_key_id and permissions.
+func APIKeyOrClerkAuth(keys APIKeyAuthenticator, clerkAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !strings.HasPrefix(token, models.APIKeyPrefix) {
+			clerkAuth(c)
+			return
+		}
+
+		key, err := keys.AuthenticateAPIKey(c.Request.Context(), token)
+		switch {
+		case errors.Is(err, services.ErrAPIKeyExpired):
+			rejectToken(c, http.StatusUnauthorized, "API_KEY_EXPIRED", "API key has expired")
+			return
+		case errors.Is(err, services.ErrAPIKeyRevoked):
+			rejectToken(c, http.StatusUnauthorized, "API_KEY_REVOKED", "API key has been revoked")
+			return
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			rejectToken(c, http.StatusUnauthorized, "INVALID_API_KEY", "Invalid API key")
+			return
+		case err != nil:
+			slog.Error("Failed to authenticate API key", "error", err)
+			rejectToken(c, http.StatusServiceUnavailable, "AUTH_UNAVAILABLE", "API keys can't be checked right now, try again later")
+			return
+		}
+
+		c.Set("user_id", "api_key:"+key.ID.String())
+		c.Set("company_id", key.OrganizationID.String())
+		c.Set("api_key_id", key.ID.String())
+		c.Set("permissions", key.Permissions)
+		c.Next()
+	}
+}
+
+// RequirePermission lets API keys through only if they were granted
+// permission. Users authenticated by Clerk are let through.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKey := c.Get("api_key_id"); !isKey {
+			c.Next()
+			return
+		}
+		for _, p := range c.GetStringSlice("permissions") {
+			if p == permission {
+				c.Next()
+				return
+			}
+		}
+		rejectToken(c, http.StatusForbidden, "PERMISSION_DENIED", "API key lacks the "+permission+" permission")
+	}
+}
+
+// RequireUser rejects API keys, for routes only users authenticated by Clerk may call
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKey := c.Get("api_key_id"); isKey {
+			rejectToken(c, http.StatusForbidden, "API_KEY_NOT_ALLOWED", "This endpoint can't be called with an API key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin lets through only users whose organization role is admin, as
+// Clerk names it with or without its org: prefix, for routes that act on
+// every organization. API keys have no role, so they are rejected.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role != "admin" && role != "org:admin" {
+			rejectToken(c, http.StatusForbidden, "ADMIN_REQUIRED", "This endpoint requires an admin")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireOrganization lets callers through only if the organization in the
+// route's param is the one they authenticated as, for routes scoped to one
+// organization
+func RequireOrganization(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyID, exists := c.Get("company_id")
+		if !exists || !sameOrganization(fmt.Sprint(companyID), c.Param(param)) {
+			rejectToken(c, http.StatusForbidden, "ORGANIZATION_FORBIDDEN", "Not a member of this organization")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAgentOrganization lets callers through only if the agent in the
+// route's param belongs to the organization they authenticated as, for routes
+// that act on one agent. Other organizations' agents are reported as not
+// found, like agents that don't exist.
+func RequireAgentOrganization(agents AgentLookup, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agentID, err := uuid.Parse(c.Param(param))
+		if err != nil {
+			rejectToken(c, http.StatusBadRequest, "INVALID_AGENT_ID", "Invalid agent ID")
+			return
+		}
+		agent, exists := agents.GetAgent(agentID)
+		if !exists || !sameOrganization(c.GetString("company_id"), agent.OrganizationID.String()) {
+			rejectToken(c, http.StatusNotFound, "AGENT_NOT_FOUND", "Agent not found")
+			return
+		}
+		c.Next()
+	}
+}
+
+// sameOrganization compares organization IDs, as UUIDs when both are
+func sameOrganization(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	ua, errA := uuid.Parse(a)
+	ub, errB := uuid.Parse(b)
+	if errA == nil && errB == nil {
+		return ua == ub
+	}
+	return a == b
+}
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPIKeys map[string]*models.APIKey
+
+func (f fakeAPIKeys) AuthenticateAPIKey(_ context.Context, key string) (*models.APIKey, error) {
+	switch key {
+	case "zt_expired":
+		return nil, services.ErrAPIKeyExpired
+	case "zt_revoked":
+		return nil, services.ErrAPIKeyRevoked
+	}
+	if k, ok := f[key]; ok {
+		return k, nil
+	}
+	return nil, services.ErrInvalidAPIKey
+}
+
+func TestAPIKeyOrClerkAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	orgID := uuid.New()
+	keys := fakeAPIKeys{
+		"zt_reader": {ID: uuid.New(), OrganizationID: orgID, Permissions: []string{models.APIKeyPermissionReadScans}, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	clerk := func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer session" {
+			rejectToken(c, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired token")
+			return
+		}
+		c.Set("company_id", "org_clerk")
+		c.Next()
+	}
+
+	router := gin.New()
+	router.Use(APIKeyOrClerkAuth(keys, clerk))
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"company_id": c.GetString("company_id")}) }
+	router.GET("/scans", RequirePermission(models.APIKeyPermissionReadScans), ok)
+	router.POST("/scans", RequirePermission(models.APIKeyPermissionCreateScans), ok)
+	router.GET("/webhooks", RequireUser(), ok)
+
+	for _, tc := range []struct {
+		method, path, token string
+		status              int
+		code, companyID     string
+	}{
+		{http.MethodGet, "/scans", "zt_reader", http.StatusOK, "", orgID.String()},
+		{http.MethodPost, "/scans", "zt_reader", http.StatusForbidden, "PERMISSION_DENIED", ""},
+		{http.MethodGet, "/webhooks", "zt_reader", http.StatusForbidden, "API_KEY_NOT_ALLOWED", ""},
+		{http.MethodGet, "/scans", "zt_unknown", http.StatusUnauthorized, "INVALID_API_KEY", ""},
+		{http.MethodGet, "/scans", "zt_expired", http.StatusUnauthorized, "API_KEY_EXPIRED", ""},
+		{http.MethodGet, "/scans", "zt_revoked", http.StatusUnauthorized, "API_KEY_REVOKED", ""},
+
+		// Anything else is a Clerk session token, which every route accepts
+		{http.MethodPost, "/scans", "session", http.StatusOK, "", "org_clerk"},
+		{http.MethodGet, "/webhooks", "session", http.StatusOK, "", "org_clerk"},
+		{http.MethodGet, "/scans", "forged", http.StatusUnauthorized, "INVALID_TOKEN", ""},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		name := tc.method + " " + tc.path + " " + tc.token
+		require.Equal(t, tc.status, w.Code, name)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		if tc.code != "" {
+			assert.Equal(t, tc.code, body["error"].(map[string]interface{})["code"], name)
+		} else {
+			assert.Equal(t, tc.companyID, body["company_id"], name)
+		}
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if role := c.GetHeader("X-Role"); role != "" {
+			c.Set("role", role)
+		}
+		c.Next()
+	})
+	router.POST("/agent-releases", RequireAdmin(), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	for _, tc := range []struct {
+		role   string
+		status int
+	}{
+		{"admin", http.StatusCreated},
+		{"org:admin", http.StatusCreated},
+		{"org:member", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/agent-releases", nil)
+		req.Header.Set("X-Role", tc.role)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Code, "role %q", tc.role)
+	}
+}
+
+func TestRequireOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	orgID := uuid.New()
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if companyID := c.GetHeader("X-Company"); companyID != "" {
+			c.Set("company_id", companyID)
+		}
+		c.Next()
+	})
+	router.GET("/organizations/:id/exports", RequireOrganization("id"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name, companyID, orgParam string
+		status                    int
+	}{
+		{"own organization", orgID.String(), orgID.String(), http.StatusOK},
+		{"UUIDs compared case-insensitively", orgID.String(), strings.ToUpper(orgID.String()), http.StatusOK},
+		{"other organization", uuid.NewString(), orgID.String(), http.StatusForbidden},
+		{"no organization", "", orgID.String(), http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/organizations/"+tc.orgParam+"/exports", nil)
+		if tc.companyID != "" {
+			req.Header.Set("X-Company", tc.companyID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Code, tc.name)
+	}
+}
+
+type fakeAgents map[uuid.UUID]*models.Agent
+
+func (f fakeAgents) GetAgent(agentID uuid.UUID) (*models.Agent, bool) {
+	agent, ok := f[agentID]
+	return agent, ok
+}
+
+func TestRequireAgentOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	orgID := uuid.New()
+	agent := &models.Agent{ID: uuid.New(), OrganizationID: orgID}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if companyID := c.GetHeader("X-Company"); companyID != "" {
+			c.Set("company_id", companyID)
+		}
+		c.Next()
+	})
+	router.PUT("/agents/:id/scan-scope", RequireAgentOrganization(fakeAgents{agent.ID: agent}, "id"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name, companyID, agentParam string
+		status                      int
+	}{
+		{"own agent", orgID.String(), agent.ID.String(), http.StatusOK},
+		{"another organization's agent", uuid.NewString(), agent.ID.String(), http.StatusNotFound},
+		{"no organization", "", agent.ID.String(), http.StatusNotFound},
+		{"unknown agent", orgID.String(), uuid.NewString(), http.StatusNotFound},
+		{"invalid agent ID", orgID.String(), "not-a-uuid", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/agents/"+tc.agentParam+"/scan-scope", nil)
+		if tc.companyID != "" {
+			req.Header.Set("X-Company", tc.companyID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Code, tc.name)
+	}
+}
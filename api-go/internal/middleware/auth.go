@@ -1,47 +1,53 @@
 package middleware
 
 import (
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// ClerkAuth middleware validates Clerk JWT tokens
-func ClerkAuth() gin.HandlerFunc {
-	// Get Clerk JWT verification key from environment
-	clerkSecret := os.Getenv("CLERK_JWT_VERIFICATION_KEY")
+// maxTokenFailures caps the tokens remembered as failing validation
+const maxTokenFailures = 10000
+
+// tokenLeeway is the clock skew allowed when checking a token's times
+const tokenLeeway = 5 * time.Second
+
+// ClerkAuth middleware validates Clerk JWT tokens. Tokens are verified
+// locally, against the keys of Clerk's JWKS when CLERK_JWKS_URL is set and
+// against CLERK_JWT_VERIFICATION_KEY otherwise.
+func ClerkAuth(cfg *config.Config) gin.HandlerFunc {
+	// Get Clerk JWT verification key from config
+	clerkSecret := cfg.ClerkJWTVerificationKey
 	isDebug := os.Getenv("API_MODE") == "debug" || os.Getenv("DEBUG") == "true"
 
-	if clerkSecret == "" {
+	if clerkSecret == "" && cfg.ClerkJWKSURL == "" {
 		if isDebug {
 			// Only allow development key in debug mode
 			clerkSecret = "development-key"
 		} else {
 			// In production, fail if no key is provided
-			// In production, fail if no key is provided
-			// Use log.Fatal inside init/setup instead of panic
-			// Note: We avoid importing "log" and use fmt.Println + os.Exit to match the style or add import.
-			// Ideally we should import "log". Let's update imports too.
-			fmt.Printf("FATAL: CLERK_JWT_VERIFICATION_KEY environment variable is required in production\n")
+			fmt.Printf("FATAL: CLERK_JWT_VERIFICATION_KEY or CLERK_JWKS_URL is required in production\n")
 			os.Exit(1)
 		}
 	}
 
+	verifier := newTokenVerifier(cfg, clerkSecret)
+
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"error": gin.H{
-					"code":    "NO_TOKEN",
-					"message": "No authorization token provided",
-				},
-			})
-			c.Abort()
+			rejectToken(c, http.StatusUnauthorized, "NO_TOKEN", "No authorization token provided")
 			return
 		}
 
@@ -60,51 +66,194 @@ func ClerkAuth() gin.HandlerFunc {
 		}
 
 		// Validate Clerk JWT token
-		claims, err := validateClerkToken(token, clerkSecret)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"error": gin.H{
-					"code":    "INVALID_TOKEN",
-					"message": "Invalid or expired token",
-				},
-			})
-			c.Abort()
+		claims, failure := verifier.verify(c.Request.Context(), token)
+		if failure != nil {
+			rejectToken(c, failure.status, failure.code, failure.message)
 			return
 		}
 
 		// Set user context from Clerk claims
-		if sub, exists := claims["sub"]; exists {
-			c.Set("user_id", sub)
-		}
-		if orgID, exists := claims["org_id"]; exists {
-			c.Set("company_id", orgID)
+		setClerkClaims(c, claims)
+		c.Next()
+	}
+}
+
+// rejectToken aborts a request whose token can't be accepted
+func rejectToken(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	})
+	c.Abort()
+}
+
+// setClerkClaims puts the user, organization and role of a token's claims
+// into the context. Clerk's v2 session tokens nest the organization under "o".
+func setClerkClaims(c *gin.Context, claims jwt.MapClaims) {
+	if sub, exists := claims["sub"]; exists {
+		c.Set("user_id", sub)
+	}
+	orgID, hasOrg := claims["org_id"]
+	orgRole, hasRole := claims["org_role"]
+	if org, ok := claims["o"].(map[string]interface{}); ok {
+		if !hasOrg {
+			orgID, hasOrg = org["id"]
 		}
-		if orgRole, exists := claims["org_role"]; exists {
-			c.Set("role", orgRole)
+		if !hasRole {
+			orgRole, hasRole = org["rol"]
 		}
-
-		c.Next()
+	}
+	if hasOrg {
+		c.Set("company_id", orgID)
+	}
+	if hasRole {
+		c.Set("role", orgRole)
 	}
 }
 
-// validateClerkToken validates a Clerk JWT token
-func validateClerkToken(tokenString, secret string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Verify the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// tokenFailure is why a token was rejected
+type tokenFailure struct {
+	status  int
+	code    string
+	message string
+	until   time.Time // When a remembered failure is forgotten
+}
+
+// tokenVerifier validates Clerk session tokens, remembering tokens that fail
+// for a short while so retrying them is cheap
+type tokenVerifier struct {
+	parser  *jwt.Parser
+	keyFunc func(ctx context.Context) jwt.Keyfunc // Looks keys up on behalf of a request
+
+	failureTTL time.Duration
+	mu         sync.Mutex
+	failures   map[[sha256.Size]byte]tokenFailure
+	now        func() time.Time
+}
+
+// newTokenVerifier creates a verifier for the JWKS or shared key cfg names
+func newTokenVerifier(cfg *config.Config, secret string) *tokenVerifier {
+	options := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithLeeway(tokenLeeway)}
+	if cfg.ClerkJWTAudience != "" {
+		options = append(options, jwt.WithAudience(cfg.ClerkJWTAudience))
+	}
+
+	v := &tokenVerifier{
+		failureTTL: cfg.ClerkTokenFailureTTL,
+		failures:   make(map[[sha256.Size]byte]tokenFailure),
+		now:        time.Now,
+	}
+	if cfg.ClerkJWKSURL != "" {
+		jwks := NewJWKSClient(cfg.ClerkJWKSURL, cfg.ClerkJWKSRefresh)
+		v.parser = jwt.NewParser(append(options, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}))...)
+		v.keyFunc = func(ctx context.Context) jwt.Keyfunc {
+			return func(token *jwt.Token) (interface{}, error) {
+				kid, _ := token.Header["kid"].(string)
+				if kid == "" {
+					return nil, ErrUnknownSigningKey
+				}
+				return jwks.Key(ctx, kid)
+			}
 		}
-		return []byte(secret), nil
-	})
+		return v
+	}
+
+	v.parser = jwt.NewParser(append(options, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))...)
+	v.keyFunc = func(context.Context) jwt.Keyfunc {
+		return func(*jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		}
+	}
+	return v
+}
 
-	if err != nil {
-		return nil, err
+// verify validates a token's signature, expiry and audience, returning its claims
+func (v *tokenVerifier) verify(ctx context.Context, tokenString string) (jwt.MapClaims, *tokenFailure) {
+	hash := sha256.Sum256([]byte(tokenString))
+	if failure := v.remembered(hash); failure != nil {
+		return nil, failure
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(tokenString, claims, v.keyFunc(ctx))
+	if err == nil {
 		return claims, nil
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	failure := classifyTokenError(err)
+	if failure.status == http.StatusUnauthorized {
+		v.remember(hash, *failure)
+	}
+	return nil, failure
+}
+
+// classifyTokenError explains why a token failed validation
+func classifyTokenError(err error) *tokenFailure {
+	failure := func(code, message string) *tokenFailure {
+		return &tokenFailure{status: http.StatusUnauthorized, code: code, message: message}
+	}
+	switch {
+	case errors.Is(err, ErrJWKSUnavailable):
+		return &tokenFailure{status: http.StatusServiceUnavailable, code: "AUTH_UNAVAILABLE", message: "Token signing keys are unavailable, try again later"}
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return failure("MALFORMED_TOKEN", "Token is malformed")
+	case errors.Is(err, ErrUnknownSigningKey):
+		return failure("INVALID_SIGNATURE", "Token is signed by an unknown key")
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return failure("INVALID_SIGNATURE", "Token signature is invalid")
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return failure("TOKEN_EXPIRED", "Token has expired")
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return failure("TOKEN_NOT_YET_VALID", "Token is not valid yet")
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return failure("INVALID_AUDIENCE", "Token was issued for another audience")
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return failure("INVALID_TOKEN", "Token is missing a required claim")
+	default:
+		return failure("INVALID_TOKEN", "Invalid or expired token")
+	}
+}
+
+// remembered returns a token's recent failure, if it has one
+func (v *tokenVerifier) remembered(hash [sha256.Size]byte) *tokenFailure {
+	if v.failureTTL <= 0 {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	failure, ok := v.failures[hash]
+	if !ok {
+		return nil
+	}
+	if !v.now().Before(failure.until) {
+		delete(v.failures, hash)
+		return nil
+	}
+	return &failure
+}
+
+// remember records a token's failure for the failure TTL, dropping expired
+// failures, or every failure, when too many are remembered
+func (v *tokenVerifier) remember(hash [sha256.Size]byte, failure tokenFailure) {
+	if v.failureTTL <= 0 {
+		return
+	}
+	now := v.now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.failures) >= maxTokenFailures {
+		for h, f := range v.failures {
+			if !now.Before(f.until) {
+				delete(v.failures, h)
+			}
+		}
+		if len(v.failures) >= maxTokenFailures {
+			v.failures = make(map[[sha256.Size]byte]tokenFailure)
+		}
+	}
+	failure.until = now.Add(v.failureTTL)
+	v.failures[hash] = failure
 }
@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksServer serves a JWKS whose keys and availability tests change
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	down    bool
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys map[string]*rsa.PublicKey) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.down {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		set := struct {
+			Keys []jwk `json:"keys"`
+		}{}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(keys map[string]*rsa.PublicKey, down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys, s.down = keys, down
+}
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestJWKSClientRefreshesAndRotates(t *testing.T) {
+	oldKey, newKey := rsaKey(t), rsaKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PublicKey{"old": &oldKey.PublicKey})
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	client := NewJWKSClient(server.URL, time.Hour)
+	client.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Keys are fetched once and then served from the cache
+	key, err := client.Key(ctx, "old")
+	require.NoError(t, err)
+	assert.Equal(t, oldKey.PublicKey.N, key.N)
+	_, err = client.Key(ctx, "old")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, server.fetches.Load())
+
+	// An unknown key ID refetches the keys, at most once per jwksMinRefetch
+	server.set(map[string]*rsa.PublicKey{"old": &oldKey.PublicKey, "new": &newKey.PublicKey}, false)
+	_, err = client.Key(ctx, "new")
+	assert.ErrorIs(t, err, ErrUnknownSigningKey)
+	assert.EqualValues(t, 1, server.fetches.Load())
+	now = now.Add(jwksMinRefetch)
+	key, err = client.Key(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, newKey.PublicKey.N, key.N)
+	assert.EqualValues(t, 2, server.fetches.Load())
+
+	// Keys are refetched on schedule, and kept while the endpoint is down
+	server.set(nil, true)
+	now = now.Add(time.Hour)
+	_, err = client.Key(ctx, "old")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, server.fetches.Load())
+	_, err = client.Key(ctx, "old")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, server.fetches.Load(), "a failing endpoint isn't retried on every request")
+
+	// Until keys have been fetched once there are none to verify with
+	empty := NewJWKSClient(server.URL, time.Hour)
+	_, err = empty.Key(ctx, "old")
+	assert.ErrorIs(t, err, ErrJWKSUnavailable)
+}
+
+func TestClerkAuthWithJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, otherKey := rsaKey(t), rsaKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PublicKey{"ins_1": &key.PublicKey})
+
+	router := gin.New()
+	router.Use(ClerkAuth(&config.Config{
+		ClerkJWKSURL:         server.URL,
+		ClerkJWKSRefresh:     time.Hour,
+		ClerkJWTAudience:     "zerotrace",
+		ClerkTokenFailureTTL: time.Minute,
+	}))
+	router.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id"), "company_id": c.GetString("company_id"), "role": c.GetString("role")})
+	})
+
+	request := func(token string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": "user_1",
+			"aud": "zerotrace",
+			"exp": time.Now().Add(time.Minute).Unix(),
+			"o":   map[string]interface{}{"id": "org_1", "rol": "admin"},
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	status, body := request(signToken(t, jwt.SigningMethodRS256, "ins_1", key, claims(nil)))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"user_id": "user_1", "company_id": "org_1", "role": "admin"}, body)
+
+	for name, tc := range map[string]struct {
+		token string
+		code  string
+	}{
+		"expired":          {signToken(t, jwt.SigningMethodRS256, "ins_1", key, claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})), "TOKEN_EXPIRED"},
+		"wrong audience":   {signToken(t, jwt.SigningMethodRS256, "ins_1", key, claims(jwt.MapClaims{"aud": "other-app"})), "INVALID_AUDIENCE"},
+		"forged signature": {signToken(t, jwt.SigningMethodRS256, "ins_1", otherKey, claims(nil)), "INVALID_SIGNATURE"},
+		"unknown key":      {signToken(t, jwt.SigningMethodRS256, "ins_2", otherKey, claims(nil)), "INVALID_SIGNATURE"},
+		"shared secret":    {signToken(t, jwt.SigningMethodHS256, "ins_1", []byte("guess"), claims(nil)), "INVALID_SIGNATURE"},
+		"no expiry":        {signToken(t, jwt.SigningMethodRS256, "ins_1", key, jwt.MapClaims{"sub": "user_1", "aud": "zerotrace"}), "INVALID_TOKEN"},
+		"malformed":        {"not-a-jwt", "MALFORMED_TOKEN"},
+	} {
+		status, body := request(tc.token)
+		assert.Equal(t, http.StatusUnauthorized, status, name)
+		assert.Equal(t, tc.code, body["error"].(map[string]interface{})["code"], name)
+	}
+}
+
+func TestTokenVerifierRemembersFailures(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	v := newTokenVerifier(&config.Config{ClerkTokenFailureTTL: 30 * time.Second}, "secret")
+	v.now = func() time.Time { return now }
+	var lookups int
+	keyFunc := v.keyFunc
+	v.keyFunc = func(ctx context.Context) jwt.Keyfunc {
+		return func(token *jwt.Token) (interface{}, error) {
+			lookups++
+			return keyFunc(ctx)(token)
+		}
+	}
+
+	forged := signToken(t, jwt.SigningMethodHS256, "", []byte("guess"), jwt.MapClaims{"sub": "user_1", "exp": time.Now().Add(time.Hour).Unix()})
+	for i := 0; i < 3; i++ {
+		_, failure := v.verify(context.Background(), forged)
+		require.NotNil(t, failure)
+		assert.Equal(t, "INVALID_SIGNATURE", failure.code)
+	}
+	assert.Equal(t, 1, lookups, "a failing token is rejected without being checked again")
+
+	now = now.Add(30 * time.Second)
+	_, failure := v.verify(context.Background(), forged)
+	require.NotNil(t, failure)
+	assert.Equal(t, 2, lookups, "failures are forgotten after the TTL")
+
+	valid := signToken(t, jwt.SigningMethodHS256, "", []byte("secret"), jwt.MapClaims{"sub": "user_1", "exp": time.Now().Add(time.Hour).Unix()})
+	claims, failure := v.verify(context.Background(), valid)
+	require.Nil(t, failure)
+	assert.Equal(t, "user_1", claims["sub"])
+}
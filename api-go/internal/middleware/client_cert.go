@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AgentIDHeader names the agent a request comes from
+const AgentIDHeader = "X-Agent-ID"
+
+// LoadClientCAs reads the PEM CA certificates agent client certificates must chain to
+func LoadClientCAs(path string) (*x509.CertPool, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent CA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("no PEM certificates in %s", path)
+	}
+	return pool, nil
+}
+
+// AgentClientCert requires agents to authenticate with a TLS client
+// certificate that chains to cas and whose common name is the agent ID they
+// send in X-Agent-ID. The verified agent ID is set as "agent_id", which
+// handlers check the agent ID in the request against. With nil cas, mutual
+// TLS is disabled and every request passes.
+func AgentClientCert(cas *x509.CertPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cas == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+			abortClientCert(c, http.StatusUnauthorized, "CLIENT_CERT_REQUIRED", "A client certificate is required")
+			return
+		}
+		peer := c.Request.TLS.PeerCertificates
+		intermediates := x509.NewCertPool()
+		for _, cert := range peer[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := peer[0].Verify(x509.VerifyOptions{
+			Roots:         cas,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			abortClientCert(c, http.StatusUnauthorized, "CLIENT_CERT_INVALID", "Client certificate is not issued by the agent CA")
+			return
+		}
+
+		agentID := c.GetHeader(AgentIDHeader)
+		if agentID == "" || peer[0].Subject.CommonName != agentID {
+			abortClientCert(c, http.StatusForbidden, "CLIENT_CERT_MISMATCH", "Client certificate was not issued to this agent")
+			return
+		}
+
+		c.Set("agent_id", agentID)
+		c.Next()
+	}
+}
+
+// abortClientCert rejects a request whose client certificate does not identify its agent
+func abortClientCert(c *gin.Context, status int, code, message string) {
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Error: &models.APIError{
+			Code:    code,
+			Message: message,
+		},
+		Timestamp: time.Now(),
+	})
+	c.Abort()
+}
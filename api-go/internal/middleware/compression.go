@@ -66,6 +66,12 @@ func shouldSkipCompression(r *http.Request) bool {
 		strings.Contains(contentType, "application/gzip") {
 		return true
 	}
-	return false
+	// Events would sit in the gzip writer's buffer rather than being sent
+	return acceptsEventStream(r)
+}
+
+// acceptsEventStream reports whether a request is for server-sent events
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
 }
 
@@ -1,6 +1,10 @@
 package middleware
 
 import (
+	"log/slog"
+
+	"zerotrace/api/internal/logging"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -12,7 +16,8 @@ const (
 	CorrelationIDKey = "correlation_id"
 )
 
-// CorrelationID middleware adds a correlation ID to each request
+// CorrelationID middleware adds a correlation ID to each request, and a logger
+// carrying it to the request context
 func CorrelationID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get correlation ID from header or generate a new one
@@ -27,6 +32,11 @@ func CorrelationID() gin.HandlerFunc {
 		// Set in response header
 		c.Header(CorrelationIDHeader, correlationID)
 
+		// Give handlers, and the services they pass the request context to, a
+		// logger that tags every line with the correlation ID and route
+		logger := slog.Default().With(CorrelationIDKey, correlationID, "route", routeOf(c))
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+
 		c.Next()
 	}
 }
@@ -41,3 +51,12 @@ func GetCorrelationID(c *gin.Context) string {
 	return ""
 }
 
+
+// routeOf returns the route template a request matched, such as
+// /api/agents/:id, or "unmatched"
+func routeOf(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
@@ -1,19 +1,39 @@
 package middleware
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"net/http"
 
+	"zerotrace/api/internal/logging"
+
 	"github.com/gin-gonic/gin"
 )
 
-// ETagMiddleware adds ETag support for HTTP caching
-func ETagMiddleware() gin.HandlerFunc {
+// ETagCache holds the ETags of recent responses. Shared between API
+// instances, it lets any of them answer a conditional request whose ETag is
+// still current with 304 without running its handler.
+type ETagCache interface {
+	// Get returns the ETag last stored for key, if it is still trusted
+	Get(ctx context.Context, key string) (string, bool, error)
+	// Set stores the ETag of key's latest response
+	Set(ctx context.Context, key, etag string) error
+}
+
+// ETagMiddleware adds ETag support for HTTP caching. ETags are derived from
+// the response body, so every instance computes the same one. With a cache,
+// a conditional request matching the cached ETag of its URL is answered
+// before its handler runs; a response may then be stale for up to the
+// cache's TTL. Cache errors are logged and the request is handled as if
+// there were no cache.
+func ETagMiddleware(cache ETagCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Only apply to GET and HEAD requests
-		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		// Only apply to GET and HEAD requests, and not to event streams,
+		// which would be held back until they end
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead || acceptsEventStream(c.Request) {
 			c.Next()
 			return
 		}
@@ -21,6 +41,22 @@ func ETagMiddleware() gin.HandlerFunc {
 		// Get If-None-Match header
 		ifNoneMatch := c.GetHeader("If-None-Match")
 
+		// Responses differ by client, so cached ETags are per client
+		var cacheKey string
+		if cache != nil {
+			cacheKey = etagCacheKey(c)
+			if ifNoneMatch != "" {
+				cached, ok, err := cache.Get(c.Request.Context(), cacheKey)
+				if err != nil {
+					logging.FromContext(c.Request.Context()).Warn("ETag cache unavailable", slog.String("error", err.Error()))
+				} else if ok && cached == ifNoneMatch {
+					c.Header("ETag", cached)
+					c.AbortWithStatus(http.StatusNotModified)
+					return
+				}
+			}
+		}
+
 		// Create response recorder to capture body
 		recorder := &responseRecorder{
 			ResponseWriter: c.Writer,
@@ -35,6 +71,11 @@ func ETagMiddleware() gin.HandlerFunc {
 
 		// Set ETag header
 		c.Header("ETag", etag)
+		if cache != nil && recorder.Status() == http.StatusOK {
+			if err := cache.Set(c.Request.Context(), cacheKey, etag); err != nil {
+				logging.FromContext(c.Request.Context()).Warn("Failed to cache ETag", slog.String("error", err.Error()))
+			}
+		}
 
 		// Check if client has matching ETag
 		if ifNoneMatch != "" && ifNoneMatch == etag {
@@ -74,3 +115,8 @@ func generateETag(content []byte) string {
 	return fmt.Sprintf(`"%s"`, hashStr[:16]) // Use first 16 chars
 }
 
+// etagCacheKey identifies a response in the ETag cache by its URL and client
+func etagCacheKey(c *gin.Context) string {
+	hash := sha256.Sum256([]byte(c.Request.URL.RequestURI() + "\x00" + rateLimitClient(c)))
+	return hex.EncodeToString(hash[:])
+}
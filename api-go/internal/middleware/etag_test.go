@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapETagCache struct {
+	etags map[string]string
+	err   error
+}
+
+func (m *mapETagCache) Get(_ context.Context, key string) (string, bool, error) {
+	etag, ok := m.etags[key]
+	return etag, ok, m.err
+}
+
+func (m *mapETagCache) Set(_ context.Context, key, etag string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.etags[key] = etag
+	return nil
+}
+
+func TestETagMiddlewareSharesETags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cache := &mapETagCache{etags: make(map[string]string)}
+	calls := 0
+	newInstance := func() *gin.Engine {
+		router := gin.New()
+		router.Use(ETagMiddleware(cache))
+		router.GET("/items", func(c *gin.Context) {
+			calls++
+			c.String(http.StatusOK, "items")
+		})
+		return router
+	}
+	get := func(router *gin.Engine, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(newInstance(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, 1, calls)
+
+	// Another instance answers a matching conditional request without running the handler
+	w = get(newInstance(), etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, 1, calls)
+
+	// A stale ETag runs the handler
+	w = get(newInstance(), `"stale"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "items", w.Body.String())
+	assert.Equal(t, 2, calls)
+
+	// An unavailable cache is skipped
+	cache.err = errors.New("cache unavailable")
+	w = get(newInstance(), etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRedisStoresReportUnavailableRedis(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+
+	_, err := NewRedisBucketStore(client).Take(context.Background(), "a", RateLimit{Requests: 1, Window: time.Minute}, time.Now())
+	assert.Error(t, err)
+	_, _, err = NewRedisETagCache(client, time.Minute).Get(context.Background(), "a")
+	assert.Error(t, err)
+}
+
+func TestEventStreamsAreNotBufferedOrCompressed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	router := gin.New()
+	router.Use(CompressionMiddleware(), ETagMiddleware(nil))
+	router.GET("/events", func(c *gin.Context) {
+		c.SSEvent("progress", "started")
+		c.Writer.Flush()
+		// Sent as the handler runs, not held back until it returns
+		assert.True(t, w.Flushed)
+		assert.Equal(t, "event:progress\ndata:started\n\n", w.Body.String())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Export quota response headers
+const (
+	ExportQuotaLimitHeader     = "X-Export-Quota-Limit"
+	ExportQuotaRemainingHeader = "X-Export-Quota-Remaining"
+)
+
+// ExportQuotaKey is the context key holding whose quota an export counted against
+const ExportQuotaKey = "export_quota_key"
+
+// ExportQuota caps how many exports each organization may start within a
+// sliding window. Exports are expensive, so they are limited separately from,
+// and far more tightly than, the general request rate limit.
+type ExportQuota struct {
+	limit     int
+	window    time.Duration
+	overrides map[string]int
+
+	mu     sync.Mutex
+	starts map[string][]time.Time // Quota key -> export start times within the window, oldest first
+}
+
+// NewExportQuota creates an export quota from the configuration
+func NewExportQuota(cfg *config.Config) *ExportQuota {
+	limit := cfg.ExportQuotaLimit
+	if limit <= 0 {
+		limit = 10
+	}
+	window := cfg.ExportQuotaWindow
+	if window <= 0 {
+		window = time.Hour
+	}
+
+	return &ExportQuota{
+		limit:     limit,
+		window:    window,
+		overrides: cfg.ExportQuotaOverrides,
+		starts:    make(map[string][]time.Time),
+	}
+}
+
+// Take records an export start for key if its quota allows it. It returns the
+// key's limit and the exports left in the window, and when the quota is used
+// up, how long until the oldest export leaves the window.
+func (q *ExportQuota) Take(key string, now time.Time) (limit, remaining int, retryAfter time.Duration, ok bool) {
+	limit = q.limit
+	if override, exists := q.overrides[key]; exists {
+		limit = override
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Drop starts that have left the window, and every key that has none left
+	cutoff := now.Add(-q.window)
+	for k, starts := range q.starts {
+		i := 0
+		for i < len(starts) && !starts[i].After(cutoff) {
+			i++
+		}
+		if i == len(starts) {
+			delete(q.starts, k)
+		} else if i > 0 {
+			q.starts[k] = starts[i:]
+		}
+	}
+
+	starts := q.starts[key]
+	if len(starts) >= limit {
+		return limit, 0, starts[0].Add(q.window).Sub(now), false
+	}
+	q.starts[key] = append(starts, now)
+	return limit, limit - len(starts) - 1, 0, true
+}
+
+// ExportQuotaMiddleware rejects exports over the organization's quota with
+// 429. The organization is the route's :id parameter or the authenticated
+// user's company; anonymous callers are limited per client IP.
+func ExportQuotaMiddleware(quota *ExportQuota) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := exportQuotaKey(c)
+		limit, remaining, retryAfter, ok := quota.Take(key, time.Now())
+		c.Header(ExportQuotaLimitHeader, strconv.Itoa(limit))
+		c.Header(ExportQuotaRemainingHeader, strconv.Itoa(remaining))
+
+		if !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.JSON(http.StatusTooManyRequests, models.APIResponse{
+				Success: false,
+				Error: &models.APIError{
+					Code:    "EXPORT_QUOTA_EXCEEDED",
+					Message: fmt.Sprintf("Export quota exceeded. Maximum %d exports per %v", limit, quota.window),
+					Details: map[string]interface{}{
+						"limit":               limit,
+						"window":              quota.window.String(),
+						"retry_after_seconds": seconds,
+					},
+				},
+				Timestamp: time.Now(),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(ExportQuotaKey, key)
+		c.Next()
+	}
+}
+
+// exportQuotaKey identifies whose quota an export counts against. Organization
+// IDs are used as-is so EXPORT_QUOTA_OVERRIDES can name them.
+func exportQuotaKey(c *gin.Context) string {
+	if id, err := uuid.Parse(c.Param("id")); err == nil {
+		return id.String()
+	}
+	if companyID, exists := c.Get("company_id"); exists {
+		return fmt.Sprint(companyID)
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportQuotaTake(t *testing.T) {
+	q := NewExportQuota(&config.Config{ExportQuotaLimit: 2, ExportQuotaWindow: time.Hour, ExportQuotaOverrides: map[string]int{"big-org": 3}})
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	limit, remaining, _, ok := q.Take("org-a", start)
+	assert.True(t, ok)
+	assert.Equal(t, 2, limit)
+	assert.Equal(t, 1, remaining)
+
+	_, remaining, _, ok = q.Take("org-a", start.Add(10*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 0, remaining)
+
+	_, remaining, retryAfter, ok := q.Take("org-a", start.Add(20*time.Minute))
+	assert.False(t, ok)
+	assert.Equal(t, 0, remaining)
+	assert.Equal(t, 40*time.Minute, retryAfter, "retry once the oldest export leaves the window")
+
+	// Other organizations have their own quota, and overrides their own limit
+	_, _, _, ok = q.Take("org-b", start.Add(20*time.Minute))
+	assert.True(t, ok)
+	for i := 0; i < 3; i++ {
+		limit, _, _, ok = q.Take("big-org", start)
+		assert.True(t, ok)
+		assert.Equal(t, 3, limit)
+	}
+	_, _, _, ok = q.Take("big-org", start)
+	assert.False(t, ok)
+
+	// The window slides: the first export leaving it frees one slot
+	_, remaining, _, ok = q.Take("org-a", start.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, 0, remaining)
+	_, _, _, ok = q.Take("org-a", start.Add(time.Hour+time.Minute))
+	assert.False(t, ok)
+
+	// Keys whose exports have all left the window are forgotten
+	q.Take("org-a", start.Add(5*time.Hour))
+	assert.Len(t, q.starts, 1)
+}
+
+func TestExportQuotaMiddlewareKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	quota := NewExportQuota(&config.Config{ExportQuotaLimit: 1, ExportQuotaWindow: time.Hour})
+	router := gin.New()
+	asCompany := func(company string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			if company != "" {
+				c.Set("company_id", company)
+			}
+		}
+	}
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.GetString(ExportQuotaKey)) }
+	router.GET("/org-a/export", asCompany("org-a"), ExportQuotaMiddleware(quota), ok)
+	router.GET("/org-b/export", asCompany("org-b"), ExportQuotaMiddleware(quota), ok)
+	router.GET("/anonymous/export", asCompany(""), ExportQuotaMiddleware(quota), ok)
+	router.POST("/organizations/:id/run", asCompany("someone-else"), ExportQuotaMiddleware(quota), ok)
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := get(http.MethodGet, "/org-a/export")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "org-a", w.Body.String(), "routes without :id count against the caller's organization")
+	assert.Equal(t, "1", w.Header().Get(ExportQuotaLimitHeader))
+	assert.Equal(t, "0", w.Header().Get(ExportQuotaRemainingHeader))
+
+	w = get(http.MethodGet, "/org-a/export")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "EXPORT_QUOTA_EXCEEDED")
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, get(http.MethodGet, "/org-b/export").Code)
+
+	w = get(http.MethodGet, "/anonymous/export")
+	assert.Equal(t, "ip:192.0.2.1", w.Body.String())
+
+	w = get(http.MethodPost, "/organizations/6F9619FF-8B86-D011-B42D-00C04FC964FF/run")
+	assert.Equal(t, "6f9619ff-8b86-d011-b42d-00c04fc964ff", w.Body.String(), "the route's organization is normalized")
+}
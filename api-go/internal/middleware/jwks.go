@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwksMinRefetch is the least time between two fetches of a JWKS, so tokens
+// naming made-up key IDs can't make the API hammer the endpoint
+const jwksMinRefetch = 30 * time.Second
+
+var (
+	// ErrUnknownSigningKey is returned for a key ID the JWKS doesn't hold, even after refetching it
+	ErrUnknownSigningKey = errors.New("token is signed by an unknown key")
+	// ErrJWKSUnavailable is returned while no signing keys could be fetched yet
+	ErrJWKSUnavailable = errors.New("signing keys are unavailable")
+)
+
+// JWKSClient caches the RSA signing keys a JSON Web Key Set endpoint
+// publishes. Keys older than the refresh interval are refetched, and so are
+// keys when a token names a key ID the set doesn't hold yet, which is how a
+// rotated-in key is picked up. Keys keep being served while the endpoint is
+// unreachable.
+type JWKSClient struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	now     func() time.Time
+
+	fetchMu sync.Mutex // Held while fetching, so concurrent misses fetch once
+	mu      sync.RWMutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time // When keys were last fetched successfully
+	tried   time.Time // When keys were last fetched, successfully or not
+}
+
+// NewJWKSClient creates a client for the JWKS at url, refetched every refresh
+func NewJWKSClient(url string, refresh time.Duration) *JWKSClient {
+	return &JWKSClient{
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+	}
+}
+
+// Key returns the signing key with the given key ID
+func (j *JWKSClient) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	key, fresh := j.lookup(kid)
+	if key != nil && fresh {
+		return key, nil
+	}
+
+	j.fetchMu.Lock()
+	defer j.fetchMu.Unlock()
+
+	// Another request may have fetched the keys while this one waited
+	if key, fresh = j.lookup(kid); key != nil && fresh {
+		return key, nil
+	}
+	if j.now().Sub(j.tried) >= jwksMinRefetch {
+		if err := j.fetch(ctx); err != nil {
+			slog.Warn("Failed to fetch JWKS", "url", j.url, "error", err)
+		}
+	}
+
+	if key, _ = j.lookup(kid); key != nil {
+		return key, nil
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if j.keys == nil {
+		return nil, ErrJWKSUnavailable
+	}
+	return nil, ErrUnknownSigningKey
+}
+
+// lookup returns the cached key with the given ID, and whether the cache is
+// younger than the refresh interval
+func (j *JWKSClient) lookup(kid string) (*rsa.PublicKey, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.keys[kid], j.keys != nil && j.now().Sub(j.fetched) < j.refresh
+}
+
+// jwk is one key of a JWKS; only RSA signing keys are used
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// fetch replaces the cached keys with the endpoint's. The caller must hold fetchMu.
+func (j *JWKSClient) fetch(ctx context.Context) error {
+	j.mu.Lock()
+	j.tried = j.now()
+	j.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("invalid JWKS: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.rsaKey()
+		if err != nil {
+			return fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS holds no RSA signing keys")
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetched = j.tried
+	j.mu.Unlock()
+	return nil
+}
+
+// rsaKey decodes an RSA key's base64url modulus and exponent
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("out of range")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
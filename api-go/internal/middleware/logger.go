@@ -2,35 +2,58 @@ package middleware
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
 	"time"
 
+	"zerotrace/api/internal/logging"
+
 	"github.com/gin-gonic/gin"
 )
 
-// RequestLogger middleware logs HTTP requests with correlation ID
+// RequestLogger middleware writes one structured access log line per request,
+// through the request's logger so it carries the correlation ID and route.
+// Server errors log at error level and client errors at warn.
 func RequestLogger() gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		// Get correlation ID from context if available
-		correlationID := "unknown"
-		if param.Keys != nil {
-			if id, exists := param.Keys[CorrelationIDKey]; exists {
-				if str, ok := id.(string); ok {
-					correlationID = str
-				}
-			}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
 		}
+		logging.FromContext(c.Request.Context()).LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
 
-		return fmt.Sprintf("[%s] %s - [%s] \"%s %s %s %d %s \"%s\" %s\"\n",
-			correlationID,
-			param.ClientIP,
-			param.TimeStamp.Format(time.RFC1123),
-			param.Method,
-			param.Path,
-			param.Request.Proto,
-			param.StatusCode,
-			param.Latency,
-			param.Request.UserAgent(),
-			param.ErrorMessage,
+// Recovery middleware turns a panic in a handler into a 500, logging it with
+// the request's correlation ID rather than to stderr
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("Recovered from panic",
+			"panic", fmt.Sprint(recovered),
+			"stack", string(debug.Stack()),
 		)
+		c.AbortWithStatus(http.StatusInternalServerError)
 	})
 }
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(logging.New(&buf, "info", "json"))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	router := gin.New()
+	router.Use(CorrelationID(), RequestLogger(), Recovery())
+	router.GET("/api/scans/:id", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("Scan fetched")
+		c.Error(errors.New("database unavailable"))
+		c.Status(http.StatusInternalServerError)
+	})
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/scans/42", nil)
+	req.Header.Set(CorrelationIDHeader, "corr-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 2)
+
+	// Service lines and the access log both carry the correlation ID and route
+	assert.Equal(t, "Scan fetched", lines[0]["msg"])
+	for _, line := range lines {
+		assert.Equal(t, "corr-1", line["correlation_id"])
+		assert.Equal(t, "/api/scans/:id", line["route"])
+	}
+	access := lines[1]
+	assert.Equal(t, "ERROR", access["level"])
+	assert.Equal(t, "/api/scans/42", access["path"])
+	assert.Equal(t, 500.0, access["status"])
+	assert.Contains(t, access, "latency_ms")
+	assert.Contains(t, access["error"], "database unavailable")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	lines = decodeLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "boom", lines[0]["panic"])
+	assert.NotEmpty(t, lines[0]["correlation_id"])
+	assert.Equal(t, lines[0]["correlation_id"], lines[1]["correlation_id"])
+}
+
+// decodeLines parses and drains the JSON log lines written to buf
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal([]byte(raw), &line), raw)
+		lines = append(lines, line)
+	}
+	buf.Reset()
+	return lines
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxPayloadHeader tells a client how large a body the route accepts, so an
+// agent that was rejected knows how small to split its submission
+const MaxPayloadHeader = "X-Max-Payload-Bytes"
+
+// MaxPayloadSize limits a route's request body to limit bytes. Requests that
+// declare a larger Content-Length are rejected with 413 before the body is
+// read; chunked or mislabelled bodies are cut off once they pass the limit, and
+// the handler reading them gets an *http.MaxBytesError.
+func MaxPayloadSize(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(MaxPayloadHeader, strconv.FormatInt(limit, 10))
+
+		if c.Request.ContentLength > limit {
+			AbortPayloadTooLarge(c, limit)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+		c.Next()
+	}
+}
+
+// AbortPayloadTooLarge responds 413 for a body over limit bytes, with guidance
+// for agents to split large scan results into several submissions
+func AbortPayloadTooLarge(c *gin.Context, limit int64) {
+	c.Header(MaxPayloadHeader, strconv.FormatInt(limit, 10))
+	c.JSON(http.StatusRequestEntityTooLarge, models.APIResponse{
+		Success: false,
+		Error: &models.APIError{
+			Code:    "REQUEST_TOO_LARGE",
+			Message: fmt.Sprintf("Request body exceeds maximum size of %d bytes", limit),
+			Details: map[string]interface{}{
+				"max_bytes": limit,
+				"hint":      "Split large scan results into several smaller submissions that share a batch_id",
+			},
+		},
+		Timestamp: time.Now(),
+	})
+	c.Abort()
+}
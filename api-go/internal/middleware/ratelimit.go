@@ -1,118 +1,201 @@
 package middleware
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/logging"
 	"zerotrace/api/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RateLimiter implements token bucket rate limiting
-type RateLimiter struct {
-	requests   int
-	window     time.Duration
-	buckets    map[string]*bucket
-	mu         sync.RWMutex
-	cleanupTicker *time.Ticker
+// Rate limit response headers
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+)
+
+// Route groups with their own rate limits
+const (
+	RateLimitGroupDefault   = "default"
+	RateLimitGroupAgents    = "agents"    // Agent check-ins and result uploads, which are frequent
+	RateLimitGroupAnalytics = "analytics" // Heatmaps, maturity, compliance and AI analysis, which are expensive
+)
+
+// RateLimit is a token bucket: it holds up to Requests tokens and refills
+// at Requests per Window, so a client may burst to Requests and then sustain
+// that rate
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
 }
 
-type bucket struct {
-	tokens     int
-	lastRefill time.Time
-	mu         sync.Mutex
+// BucketResult is the outcome of taking a token from a bucket
+type BucketResult struct {
+	Allowed    bool
+	Remaining  int           // Whole tokens left after this request
+	RetryAfter time.Duration // Until a token is available, when not allowed
 }
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(requests int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		requests: requests,
-		window:   window,
-		buckets:  make(map[string]*bucket),
-	}
+// BucketStore holds the token buckets of every client. The in-memory store
+// suits a single API instance; several instances share their limits through
+// a store backed by Redis or similar.
+type BucketStore interface {
+	// Take removes a token from key's bucket, creating it full under limit
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (BucketResult, error)
+}
 
-	// Start cleanup goroutine to remove old buckets
-	rl.cleanupTicker = time.NewTicker(5 * time.Minute)
-	go rl.cleanup()
+// MemoryBucketStore keeps token buckets in memory
+type MemoryBucketStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
 
-	return rl
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration
 }
 
-// Allow checks if a request is allowed for the given key
-func (rl *RateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	b, exists := rl.buckets[key]
+// NewMemoryBucketStore creates an empty in-memory bucket store
+func NewMemoryBucketStore() *MemoryBucketStore {
+	return &MemoryBucketStore{buckets: make(map[string]*tokenBucket)}
+}
+
+// Take removes a token from key's bucket after refilling it for the time
+// since it was last used
+func (s *MemoryBucketStore) Take(_ context.Context, key string, limit RateLimit, now time.Time) (BucketResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	rate := float64(limit.Requests) / limit.Window.Seconds() // Tokens per second
+	b, exists := s.buckets[key]
 	if !exists {
-		b = &bucket{
-			tokens:     rl.requests,
-			lastRefill: time.Now(),
-		}
-		rl.buckets[key] = b
+		b = &tokenBucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = b
 	}
-	rl.mu.Unlock()
-
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	// Refill tokens based on elapsed time
-	now := time.Now()
-	elapsed := now.Sub(b.lastRefill)
-	tokensToAdd := int(elapsed / (rl.window / time.Duration(rl.requests)))
-	
-	if tokensToAdd > 0 {
-		b.tokens = min(b.tokens+tokensToAdd, rl.requests)
-		b.lastRefill = now
+	b.window = limit.Window
+	b.tokens = math.Min(b.tokens+now.Sub(b.updated).Seconds()*rate, float64(limit.Requests))
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return BucketResult{RetryAfter: wait}, nil
 	}
+	b.tokens--
+	return BucketResult{Allowed: true, Remaining: int(b.tokens)}, nil
+}
 
-	// Check if we have tokens available
-	if b.tokens > 0 {
-		b.tokens--
-		return true
+// sweep drops buckets idle long enough to have refilled, which are the same
+// as no bucket, at most once a minute
+func (s *MemoryBucketStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.window {
+			delete(s.buckets, key)
+		}
 	}
+}
 
-	return false
+// RateLimiter limits each client's request rate per route group
+type RateLimiter struct {
+	store    BucketStore
+	limits   map[string]RateLimit
+	failOpen bool // Let requests through, rather than reject them, while the store is unavailable
 }
 
-// cleanup removes old buckets to prevent memory leaks
-func (rl *RateLimiter) cleanup() {
-	for range rl.cleanupTicker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for key, b := range rl.buckets {
-			b.mu.Lock()
-			// Remove buckets that haven't been used in 1 hour
-			if now.Sub(b.lastRefill) > time.Hour {
-				delete(rl.buckets, key)
-			}
-			b.mu.Unlock()
+// NewRateLimiter creates a rate limiter with the configured per-group limits
+func NewRateLimiter(cfg *config.Config, store BucketStore) *RateLimiter {
+	window := cfg.RateLimitWindow
+	if window <= 0 {
+		window = time.Minute
+	}
+	limit := func(requests, fallback int) RateLimit {
+		if requests <= 0 {
+			requests = fallback
 		}
-		rl.mu.Unlock()
+		return RateLimit{Requests: requests, Window: window}
+	}
+
+	return &RateLimiter{
+		store:    store,
+		failOpen: cfg.RateLimitFailOpen,
+		limits: map[string]RateLimit{
+			RateLimitGroupDefault:   limit(cfg.RateLimitRequests, 100),
+			RateLimitGroupAgents:    limit(cfg.RateLimitAgentRequests, 1000),
+			RateLimitGroupAnalytics: limit(cfg.RateLimitAnalyticsRequests, 30),
+		},
 	}
 }
 
-// RateLimitMiddleware creates a rate limiting middleware
-func RateLimitMiddleware(cfg *config.Config) gin.HandlerFunc {
-	limiter := NewRateLimiter(cfg.RateLimitRequests, cfg.RateLimitWindow)
+// RateLimitMiddleware limits each client of a route group to the group's
+// rate, rejecting requests over it with 429. Clients are the authenticated
+// organization, else the API key the request carries, else its IP address,
+// so it must run after any authentication on the group. While the bucket
+// store is unavailable requests are rejected with 503, or let through when
+// the limiter fails open.
+func RateLimitMiddleware(limiter *RateLimiter, group string) gin.HandlerFunc {
+	limit, ok := limiter.limits[group]
+	if !ok {
+		limit = limiter.limits[RateLimitGroupDefault]
+	}
 
 	return func(c *gin.Context) {
-		// Get client identifier (IP address or user ID)
-		clientID := c.ClientIP()
-		if userID, exists := c.Get("user_id"); exists {
-			clientID = fmt.Sprintf("user:%s", userID)
+		key := group + ":" + rateLimitClient(c)
+		result, err := limiter.store.Take(c.Request.Context(), key, limit, time.Now())
+		if err != nil {
+			logger := logging.FromContext(c.Request.Context())
+			if limiter.failOpen {
+				logger.Warn("Rate limit store unavailable, allowing request", slog.String("error", err.Error()))
+				c.Next()
+				return
+			}
+			logger.Error("Rate limit store unavailable, rejecting request", slog.String("error", err.Error()))
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, models.APIResponse{
+				Success: false,
+				Error: &models.APIError{
+					Code:    "RATE_LIMIT_UNAVAILABLE",
+					Message: "Rate limiting is temporarily unavailable, retry later",
+				},
+				Timestamp: time.Now(),
+			})
+			c.Abort()
+			return
 		}
 
-		// Check rate limit
-		if !limiter.Allow(clientID) {
-			// Use standardized error response format
+		c.Header(RateLimitLimitHeader, strconv.Itoa(limit.Requests))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			seconds := int(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
 			c.JSON(http.StatusTooManyRequests, models.APIResponse{
 				Success: false,
 				Error: &models.APIError{
 					Code:    "RATE_LIMIT_EXCEEDED",
-					Message: fmt.Sprintf("Rate limit exceeded. Maximum %d requests per %v", cfg.RateLimitRequests, cfg.RateLimitWindow),
+					Message: fmt.Sprintf("Rate limit exceeded. Maximum %d requests per %v", limit.Requests, limit.Window),
+					Details: map[string]interface{}{
+						"limit":               limit.Requests,
+						"window":              limit.Window.String(),
+						"retry_after_seconds": seconds,
+					},
 				},
 				Timestamp: time.Now(),
 			})
@@ -124,10 +207,15 @@ func RateLimitMiddleware(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// rateLimitClient identifies whose bucket a request takes from. API keys are
+// hashed so they are never held in the store.
+func rateLimitClient(c *gin.Context) string {
+	if companyID, exists := c.Get("company_id"); exists {
+		return "org:" + fmt.Sprint(companyID)
 	}
-	return b
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+	return "ip:" + c.ClientIP()
 }
-
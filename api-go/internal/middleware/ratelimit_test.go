@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBucketStore(t *testing.T) {
+	store := NewMemoryBucketStore()
+	limit := RateLimit{Requests: 2, Window: time.Minute}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	// A new client may burst to the limit
+	for remaining := 1; remaining >= 0; remaining-- {
+		result, err := store.Take(context.Background(), "a", limit, now)
+		require.NoError(t, err)
+		assert.Equal(t, BucketResult{Allowed: true, Remaining: remaining}, result)
+	}
+	result, err := store.Take(context.Background(), "a", limit, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 30*time.Second, result.RetryAfter)
+
+	// Other clients have their own buckets
+	result, _ = store.Take(context.Background(), "b", limit, now)
+	assert.True(t, result.Allowed)
+
+	// Tokens refill at the limit's rate
+	result, _ = store.Take(context.Background(), "a", limit, now.Add(20*time.Second))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 10*time.Second, result.RetryAfter)
+	result, _ = store.Take(context.Background(), "a", limit, now.Add(30*time.Second))
+	assert.Equal(t, BucketResult{Allowed: true, Remaining: 0}, result)
+
+	// Idle buckets are full again, and are dropped
+	result, _ = store.Take(context.Background(), "c", limit, now.Add(time.Hour))
+	assert.True(t, result.Allowed)
+	assert.NotContains(t, store.buckets, "a")
+}
+
+type failingBucketStore struct{}
+
+func (failingBucketStore) Take(context.Context, string, RateLimit, time.Time) (BucketResult, error) {
+	return BucketResult{}, errors.New("store unavailable")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(&config.Config{RateLimitRequests: 1, RateLimitAgentRequests: 2, RateLimitWindow: time.Minute}, NewMemoryBucketStore())
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if org := c.GetHeader("X-Test-Org"); org != "" {
+			c.Set("company_id", org)
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/default", RateLimitMiddleware(limiter, RateLimitGroupDefault), ok)
+	router.GET("/agents", RateLimitMiddleware(limiter, RateLimitGroupAgents), ok)
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/default", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+
+	w = get("/default", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"code":"RATE_LIMIT_EXCEEDED"`)
+
+	// Route groups, organizations and API keys are limited separately
+	w = get("/agents", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, http.StatusOK, get("/default", map[string]string{"X-Test-Org": "org-a"}).Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("/default", map[string]string{"X-Test-Org": "org-a"}).Code)
+	assert.Equal(t, http.StatusOK, get("/default", map[string]string{"Authorization": "Bearer key-1"}).Code)
+	assert.Equal(t, http.StatusOK, get("/default", map[string]string{"Authorization": "Bearer key-2"}).Code)
+
+	// A failing store rejects requests unless the limiter fails open
+	router = gin.New()
+	router.GET("/closed", RateLimitMiddleware(NewRateLimiter(&config.Config{}, failingBucketStore{}), RateLimitGroupDefault), ok)
+	router.GET("/open", RateLimitMiddleware(NewRateLimiter(&config.Config{RateLimitFailOpen: true}, failingBucketStore{}), RateLimitGroupDefault), ok)
+	w = get("/closed", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, get("/open", nil).Code)
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeTokenScript refills and takes from a token bucket held in a Redis hash
+// in one step, so API instances sharing the bucket never both spend its last
+// token. It returns whether the token was taken and the tokens left, as a
+// string since Lua numbers are truncated to integers on the way out.
+var takeTokenScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or capacity
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisBucketStore keeps token buckets in Redis, so every API instance
+// draws from the same buckets. Buckets expire once idle long enough to have
+// refilled.
+type RedisBucketStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisBucketStore creates a bucket store on a Redis client
+func NewRedisBucketStore(client *redis.Client) *RedisBucketStore {
+	return &RedisBucketStore{client: client, prefix: "zerotrace:ratelimit:"}
+}
+
+// Take removes a token from key's bucket after refilling it for the time
+// since it was last used
+func (s *RedisBucketStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (BucketResult, error) {
+	rate := float64(limit.Requests) / float64(limit.Window.Milliseconds()) // Tokens per millisecond
+	values, err := takeTokenScript.Run(ctx, s.client, []string{s.prefix + key},
+		limit.Requests, strconv.FormatFloat(rate, 'g', -1, 64), now.UnixMilli(), limit.Window.Milliseconds()).Slice()
+	if err != nil {
+		return BucketResult{}, err
+	}
+	if len(values) != 2 {
+		return BucketResult{}, errors.New("unexpected rate limit script result")
+	}
+	allowed, _ := values[0].(int64)
+	text, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return BucketResult{}, err
+	}
+
+	if allowed != 1 {
+		wait := time.Duration((1 - tokens) / rate * float64(time.Millisecond))
+		return BucketResult{RetryAfter: wait}, nil
+	}
+	return BucketResult{Allowed: true, Remaining: int(tokens)}, nil
+}
+
+// RedisETagCache shares response ETags between API instances through Redis
+type RedisETagCache struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisETagCache creates an ETag cache on a Redis client, whose entries
+// are trusted for ttl
+func NewRedisETagCache(client *redis.Client, ttl time.Duration) *RedisETagCache {
+	return &RedisETagCache{client: client, prefix: "zerotrace:etag:", ttl: ttl}
+}
+
+// Get returns the ETag last stored for key, if it has not expired
+func (c *RedisETagCache) Get(ctx context.Context, key string) (string, bool, error) {
+	etag, err := c.client.Get(ctx, c.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return etag, true, nil
+}
+
+// Set stores the ETag of key's latest response
+func (c *RedisETagCache) Set(ctx context.Context, key, etag string) error {
+	return c.client.Set(ctx, c.prefix+key, etag, c.ttl).Err()
+}
@@ -11,8 +11,9 @@ import (
 	"github.com/google/uuid"
 )
 
-// InputValidationMiddleware validates and sanitizes input
-func InputValidationMiddleware() gin.HandlerFunc {
+// InputValidationMiddleware validates and sanitizes input, rejecting request
+// bodies larger than maxBodySize bytes
+func InputValidationMiddleware(maxBodySize int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Validate UUID parameters
 		if id := c.Param("id"); id != "" {
@@ -51,16 +52,8 @@ func InputValidationMiddleware() gin.HandlerFunc {
 		}
 
 		// Limit request body size (handled by Gin's MaxMultipartMemory, but we can add additional checks)
-		if c.Request.ContentLength > 10*1024*1024 { // 10MB limit
-			c.JSON(http.StatusBadRequest, models.APIResponse{
-				Success: false,
-				Error: &models.APIError{
-					Code:    "REQUEST_TOO_LARGE",
-					Message: "Request body exceeds maximum size of 10MB",
-				},
-				Timestamp: time.Now(),
-			})
-			c.Abort()
+		if c.Request.ContentLength > maxBodySize {
+			AbortPayloadTooLarge(c, maxBodySize)
 			return
 		}
 
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Agent command types
+const (
+	AgentCommandScanNow       = "scan_now"
+	AgentCommandVerifyFinding = "verify_finding" // Re-run the check behind one finding
+	AgentCommandCollect       = "collect"        // Collect from other hosts over SSH or WinRM, see CollectionRun
+)
+
+// Agent command statuses
+const (
+	AgentCommandPending   = "pending"   // Queued, waiting for the agent's next heartbeat
+	AgentCommandDelivered = "delivered" // Returned to the agent in a heartbeat response
+	AgentCommandAcked     = "acked"     // Agent accepted the command and started work
+	AgentCommandCompleted = "completed"
+	AgentCommandFailed    = "failed"
+)
+
+// Rescan scan types. Network scans are the agentless scans an agent runs
+// against other hosts on its network.
+const (
+	RescanTypeSoftware = "software"
+	RescanTypeSystem   = "system"
+	RescanTypeNetwork  = "network"
+)
+
+// AgentCommand is an instruction queued for an agent. Commands are delivered
+// in heartbeat responses and the agent reports progress back as it works.
+type AgentCommand struct {
+	ID             uuid.UUID              `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgentID        uuid.UUID              `json:"agent_id" gorm:"type:uuid;not null;index"`
+	OrganizationID uuid.UUID              `json:"organization_id" gorm:"type:uuid;not null;index"`
+	BatchID        *uuid.UUID             `json:"batch_id,omitempty" gorm:"type:uuid;index"`
+	Type           string                 `json:"type" gorm:"size:50;not null"`
+	Payload        map[string]interface{} `json:"payload,omitempty" gorm:"type:jsonb;serializer:json"`
+	Status         string                 `json:"status" gorm:"size:20;not null;index"`
+	Error          string                 `json:"error,omitempty" gorm:"type:text"`
+	Result         map[string]interface{} `json:"result,omitempty" gorm:"type:jsonb;serializer:json"` // What the agent reported, by command type; collect commands report progress while acked
+	ExpiresAt      time.Time              `json:"expires_at"`
+	DeliveredAt    *time.Time             `json:"delivered_at,omitempty"`
+	AckedAt        *time.Time             `json:"acked_at,omitempty"`
+	CompletedAt    *time.Time             `json:"completed_at,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// RescanBatch groups the scan_now commands sent to an organization's agents by one bulk re-scan
+type RescanBatch struct {
+	ID             uuid.UUID           `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID           `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Reason         string              `json:"reason,omitempty" gorm:"type:text"`
+	ScanTypes      []string            `json:"scan_types" gorm:"type:jsonb;serializer:json"`
+	AgentCount     int                 `json:"agent_count"`
+	Progress       RescanBatchProgress `json:"progress" gorm:"-"`
+	CreatedAt      time.Time           `json:"created_at"`
+}
+
+// RescanBatchProgress summarizes the commands in a rescan batch
+type RescanBatchProgress struct {
+	Total        int                `json:"total"`
+	Pending      int                `json:"pending"` // Queued or delivered but not yet acknowledged
+	Acked        int                `json:"acked"`   // Acknowledged and still scanning
+	Completed    int                `json:"completed"`
+	Failed       int                `json:"failed"`
+	Percent      float64            `json:"percent"`
+	Done         bool               `json:"done"`
+	FailedAgents []RescanAgentError `json:"failed_agents,omitempty"`
+}
+
+// RescanAgentError records why an agent's re-scan failed
+type RescanAgentError struct {
+	AgentID uuid.UUID `json:"agent_id"`
+	Error   string    `json:"error"`
+}
+
+// RescanRequest is the body of a bulk re-scan request
+type RescanRequest struct {
+	Reason    string            `json:"reason"`
+	ScanTypes []string          `json:"scan_types"` // Defaults to software, system and network
+	Tags      map[string]string `json:"tags"`       // Only agents with these tags; an empty value matches any value
+	Group     string            `json:"group"`      // Only agents in this group, by ID or name
+}
+
+// AgentCommandStatusUpdate is sent by an agent as it works through a command
+type AgentCommandStatusUpdate struct {
+	AgentID string                 `json:"agent_id" binding:"required"`
+	Status  string                 `json:"status" binding:"required"`
+	Error   string                 `json:"error,omitempty"`
+	Result  map[string]interface{} `json:"result,omitempty"` // Kept when the command completes, or as progress while acked
+}
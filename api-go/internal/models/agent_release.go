@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AgentRelease is an agent binary published for agents of one platform to
+// update to. Its manifest, naming the version, platform and binary digest, is
+// signed with the release signing key, whose public key agents pin; the API
+// only passes the signature on, and agents refuse releases it doesn't verify.
+// Signing the version with the binary keeps an old release from being offered
+// as a newer one.
+type AgentRelease struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Version   string    `json:"version" gorm:"size:50;not null;uniqueIndex:idx_agent_release_platform_version"`
+	Platform  string    `json:"platform" gorm:"size:50;not null;uniqueIndex:idx_agent_release_platform_version"` // GOOS/GOARCH, such as linux/amd64
+	URL       string    `json:"url" gorm:"type:text;not null"`                                                   // Where agents download the binary
+	SHA256    string    `json:"sha256" gorm:"size:64;not null"`                                                  // Hex digest of the binary
+	Signature string    `json:"signature" gorm:"type:text;not null"`                                             // Base64 ed25519 signature of Manifest
+	Manifest  string    `json:"manifest" gorm:"-"`                                                               // What Signature signs, see ReleaseManifest
+	Notes     string    `json:"notes,omitempty" gorm:"type:text"`
+	CreatedBy string    `json:"created_by,omitempty" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReleaseManifest is what an agent release is signed over: its version,
+// platform and lower-case hex SHA-256, one per line. Agents rebuild it from
+// the release they are offered to check its signature.
+func ReleaseManifest(version, platform, sha256 string) string {
+	return "zerotrace-agent-release\n" +
+		"version=" + version + "\n" +
+		"platform=" + platform + "\n" +
+		"sha256=" + sha256 + "\n"
+}
+
+// AgentReleaseRequest publishes an agent release
+type AgentReleaseRequest struct {
+	Version   string `json:"version" binding:"required,max=50"`
+	Platform  string `json:"platform" binding:"required,max=50"`
+	URL       string `json:"url" binding:"required"`
+	SHA256    string `json:"sha256" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+	Notes     string `json:"notes"`
+	Actor     string `json:"-"` // The user publishing it, set from the caller
+}
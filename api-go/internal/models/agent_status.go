@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Agent statuses, derived from how long ago an agent's last heartbeat was
+const (
+	AgentStatusOnline   = "online"   // Heard from within the heartbeat timeout
+	AgentStatusDegraded = "degraded" // Missed heartbeats, but not yet for long
+	AgentStatusOffline  = "offline"
+)
+
+// AgentStatusEvent records an agent moving from one status to another, for
+// alerting to subscribe to
+type AgentStatusEvent struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgentID        uuid.UUID `json:"agent_id" gorm:"type:uuid;not null;index"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	From           string    `json:"from,omitempty" gorm:"size:20"` // Empty for an agent that had no status yet
+	To             string    `json:"to" gorm:"size:20;not null"`
+	LastSeen       time.Time `json:"last_seen"` // The agent's last heartbeat when the status changed
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+}
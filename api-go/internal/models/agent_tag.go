@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AgentTag is one key=value tag on an agent, such as env=production. An agent
+// has at most one value per key.
+type AgentTag struct {
+	AgentID   uuid.UUID `json:"agent_id" gorm:"type:uuid;primaryKey"`
+	Key       string    `json:"key" gorm:"size:63;primaryKey;index:idx_agent_tag_key_value"`
+	Value     string    `json:"value" gorm:"size:255;not null;index:idx_agent_tag_key_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AgentGroup is a named set of an organization's agents, those carrying every
+// tag in its selector, so agents join and leave it as they are tagged
+type AgentGroup struct {
+	ID             uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey"`
+	OrganizationID uuid.UUID         `json:"organization_id" gorm:"type:uuid;not null;uniqueIndex:idx_agent_group_org_name"`
+	Name           string            `json:"name" gorm:"size:100;not null;uniqueIndex:idx_agent_group_org_name"`
+	Description    string            `json:"description,omitempty" gorm:"size:500"`
+	Selector       map[string]string `json:"selector" gorm:"type:jsonb;serializer:json"` // Tag key -> value; an empty value matches any value
+	CreatedBy      string            `json:"created_by,omitempty" gorm:"size:255"`
+	AgentCount     int               `json:"agent_count" gorm:"-"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// AgentFilter selects agents by organization, status, tags and group; every
+// criterion given must match
+type AgentFilter struct {
+	OrganizationID *uuid.UUID        `json:"organization_id,omitempty"`
+	AgentIDs       []uuid.UUID       `json:"agent_ids,omitempty"`
+	Status         string            `json:"status,omitempty"` // online, degraded or offline
+	Tags           map[string]string `json:"tags,omitempty"`   // Tag key -> value; an empty value matches any value
+	Group          string            `json:"group,omitempty"`  // Group ID, or name within OrganizationID
+}
+
+// Empty reports whether the filter selects every agent
+func (f *AgentFilter) Empty() bool {
+	return f.OrganizationID == nil && len(f.AgentIDs) == 0 && f.Status == "" && len(f.Tags) == 0 && f.Group == ""
+}
+
+// UpdateAgentTagsRequest sets and removes tags on one agent
+type UpdateAgentTagsRequest struct {
+	Set    map[string]string `json:"set"`
+	Remove []string          `json:"remove"`
+}
+
+// BulkAgentTagsRequest sets and removes tags on every agent a filter selects
+type BulkAgentTagsRequest struct {
+	Filter AgentFilter       `json:"filter"`
+	Set    map[string]string `json:"set"`
+	Remove []string          `json:"remove"`
+}
+
+// CreateAgentGroupRequest creates an agent group
+type CreateAgentGroupRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Selector    map[string]string `json:"selector" binding:"required"`
+	CreatedBy   string            `json:"created_by"`
+}
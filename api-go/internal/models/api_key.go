@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key, telling them apart from Clerk session tokens
+const APIKeyPrefix = "zt_"
+
+// API key permissions
+const (
+	APIKeyPermissionReadScans      = "scans:read"      // List and read scans and their results
+	APIKeyPermissionCreateScans    = "scans:write"     // Create, update and delete scans
+	APIKeyPermissionReadFindings   = "findings:read"   // Read findings and their evidence
+	APIKeyPermissionImportFindings = "findings:import" // Import third-party scanners' reports
+)
+
+// APIKeyPermissions are every permission an API key may be granted
+var APIKeyPermissions = []string{APIKeyPermissionReadScans, APIKeyPermissionCreateScans, APIKeyPermissionReadFindings, APIKeyPermissionImportFindings}
+
+// APIKey lets a service account, such as a CI pipeline, call the API on
+// behalf of an organization with a fixed set of permissions. Only the key's
+// SHA-256 hash is stored; the key itself is shown once, when it is issued.
+type APIKey struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	OrganizationID uuid.UUID  `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Name           string     `json:"name" gorm:"size:255;not null"`
+	Prefix         string     `json:"prefix" gorm:"size:20;not null"` // The key's first characters, to recognize it by
+	KeyHash        string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Permissions    []string   `json:"permissions" gorm:"type:jsonb;serializer:json"`
+	CreatedBy      string     `json:"created_by,omitempty" gorm:"size:255"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy     *uuid.UUID `json:"replaced_by,omitempty" gorm:"type:uuid"` // The key this one was rotated to
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// HasPermission reports whether the key was granted a permission
+func (k *APIKey) HasPermission(permission string) bool {
+	for _, p := range k.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IssuedAPIKey is a newly issued API key with its secret, which is never shown again
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyRequest issues an API key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=255"`
+	Permissions   []string `json:"permissions" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=730"` // Defaults to 90
+}
+
+// RotateAPIKeyRequest replaces an API key with a new one
+type RotateAPIKeyRequest struct {
+	GracePeriodMinutes int `json:"grace_period_minutes" binding:"omitempty,min=0,max=10080"` // How long the old key keeps working; it stops at once by default
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Backfill job kinds
+const (
+	// BackfillReenrich looks up every reported package again and refreshes
+	// the severity and exploitability of the findings tracked for it
+	BackfillReenrich = "reenrich"
+	// BackfillReingest derives every tracked finding's priority again and
+	// rescores the hosts whose findings changed
+	BackfillReingest = "reingest"
+)
+
+// Backfill job statuses
+const (
+	BackfillJobPending   = "pending"
+	BackfillJobRunning   = "running"
+	BackfillJobCompleted = "completed"
+	BackfillJobFailed    = "failed"
+	BackfillJobCancelled = "cancelled"
+)
+
+// BackfillJob reprocesses stored rows in the background, a batch at a time in
+// key order. Each batch is committed together with the job's cursor, so a job
+// interrupted by a restart resumes after its last committed batch.
+type BackfillJob struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	Kind        string    `json:"kind" gorm:"size:20;not null"`
+	Status      string    `json:"status" gorm:"size:20;not null;index"`
+	BatchSize   int       `json:"batch_size"`
+	Concurrency int       `json:"concurrency"`        // Most enrichment requests in flight, for re-enrichment
+	Cursor      uuid.UUID `json:"-" gorm:"type:uuid"` // Key of the last row processed
+	Total       int64     `json:"total"`              // Reported packages, or tracked findings, when the job started
+	Processed   int64     `json:"processed"`          // Of Total, those processed so far
+	Updated     int64     `json:"updated"`            // Findings that changed
+	Batches     int       `json:"batches"`            // Batches committed
+	Error       string    `json:"error,omitempty" gorm:"type:text"`
+	RequestedBy string    `json:"requested_by,omitempty" gorm:"size:255"`
+
+	// Where the current run started from, to estimate its rate after a resume
+	RunStartedAt   *time.Time `json:"-"`
+	RunStartedFrom int64      `json:"-"`
+
+	// Progress and ETA, computed when the job is read
+	Progress float64    `json:"progress" gorm:"-"` // Share of rows processed, 0 to 1
+	ETA      *time.Time `json:"eta,omitempty" gorm:"-"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// CreateBackfillJobRequest starts a backfill. Batch size and concurrency
+// default to the configured ones.
+type CreateBackfillJobRequest struct {
+	Kind        string `json:"kind" binding:"required,oneof=reenrich reingest"`
+	BatchSize   int    `json:"batch_size" binding:"omitempty,min=1,max=10000"`
+	Concurrency int    `json:"concurrency" binding:"omitempty,min=1,max=32"`
+	RequestedBy string `json:"-"` // The user starting it, set from the caller
+}
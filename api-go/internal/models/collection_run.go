@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Agentless collection protocols. An agent connects to other hosts on its
+// network over one of these with credentials it holds, and collects from them.
+const (
+	CollectionProtocolSSH   = "ssh"
+	CollectionProtocolWinRM = "winrm"
+)
+
+// Collection run statuses
+const (
+	CollectionRunPending   = "pending" // Waiting for the collecting agent's next heartbeat
+	CollectionRunRunning   = "running"
+	CollectionRunPaused    = "paused" // Stopped early because the auth failure budget ran out
+	CollectionRunCompleted = "completed"
+	CollectionRunFailed    = "failed"
+)
+
+// Per-host collection outcomes
+const (
+	CollectionHostSucceeded  = "succeeded"
+	CollectionHostFailed     = "failed"      // Unreachable, timed out or failed to collect
+	CollectionHostAuthFailed = "auth_failed" // Credentials rejected; counts towards the failure budget
+	CollectionHostSkipped    = "skipped"     // Not attempted because the run paused
+)
+
+// CollectionSettings bounds an organization's agentless collection runs, so
+// large runs neither overwhelm the network nor lock accounts out
+type CollectionSettings struct {
+	OrganizationID     uuid.UUID `json:"organization_id" gorm:"type:uuid;primary_key"`
+	MaxConcurrency     int       `json:"max_concurrency"`      // Hosts collected from at once
+	HostTimeoutSeconds int       `json:"host_timeout_seconds"` // Longest one host's connection and collection may take
+	AuthFailureBudget  int       `json:"auth_failure_budget"`  // Authentication failures after which a run pauses
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// UpdateCollectionSettingsRequest sets an organization's collection settings
+type UpdateCollectionSettingsRequest struct {
+	MaxConcurrency     int `json:"max_concurrency" binding:"required,min=1,max=256"`
+	HostTimeoutSeconds int `json:"host_timeout_seconds" binding:"required,min=1,max=600"`
+	AuthFailureBudget  int `json:"auth_failure_budget" binding:"required,min=1,max=1000"`
+}
+
+// CollectionRun is one agentless collection from a list of hosts. It is
+// delivered to the collecting agent as a collect command, whose progress
+// reports carry the per-host results.
+type CollectionRun struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	AgentID        uuid.UUID `json:"agent_id" gorm:"type:uuid;not null"` // Agent collecting
+	CommandID      uuid.UUID `json:"command_id" gorm:"type:uuid;not null"`
+	Protocol       string    `json:"protocol" gorm:"size:20;not null"`
+	Targets        []string  `json:"targets" gorm:"type:jsonb;serializer:json"`
+	RequestedBy    string    `json:"requested_by,omitempty" gorm:"size:255"`
+
+	// Organization settings the run was started with
+	MaxConcurrency     int `json:"max_concurrency"`
+	HostTimeoutSeconds int `json:"host_timeout_seconds"`
+	AuthFailureBudget  int `json:"auth_failure_budget"`
+
+	// State, from the collect command, when the run is read
+	Status      string                 `json:"status" gorm:"-"`
+	Error       string                 `json:"error,omitempty" gorm:"-"`
+	Progress    CollectionRunProgress  `json:"progress" gorm:"-"`
+	Hosts       []CollectionHostResult `json:"hosts,omitempty" gorm:"-"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty" gorm:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CollectionHostResult is the outcome of collecting from one host
+type CollectionHostResult struct {
+	Host       string                 `json:"host"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMS int64                  `json:"duration_ms"`
+	Data       map[string]interface{} `json:"data,omitempty"` // What was collected, by protocol
+}
+
+// CollectionRunProgress summarizes a run's hosts
+type CollectionRunProgress struct {
+	Total        int     `json:"total"`
+	Succeeded    int     `json:"succeeded"`
+	Failed       int     `json:"failed"`
+	AuthFailed   int     `json:"auth_failed"`
+	Skipped      int     `json:"skipped"`
+	Percent      float64 `json:"percent"` // Hosts finished, succeeded or not
+	AuthFailures int     `json:"auth_failures"`
+	Done         bool    `json:"done"`
+}
+
+// CollectionReport is what a collect command reports, as it progresses and on completion
+type CollectionReport struct {
+	Hosts        []CollectionHostResult `json:"hosts"`
+	AuthFailures int                    `json:"auth_failures"`
+	Paused       bool                   `json:"paused"` // The failure budget ran out; remaining hosts were skipped
+}
+
+// StartCollectionRequest starts an agentless collection run from one of the
+// organization's agents
+type StartCollectionRequest struct {
+	AgentID     uuid.UUID `json:"agent_id" binding:"required"`
+	Protocol    string    `json:"protocol" binding:"required,oneof=ssh winrm"`
+	Targets     []string  `json:"targets" binding:"required,min=1,max=10000,dive,required,max=255"`
+	RequestedBy string    `json:"requested_by"`
+}
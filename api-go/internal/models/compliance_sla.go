@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SLA statuses of an open finding or a control
+const (
+	SLAStatusOnTrack = "on_track"
+	SLAStatusDueSoon = "due_soon"
+	SLAStatusOverdue = "overdue"
+	SLAStatusAtRisk  = "at_risk" // Control with overdue findings, likely to fail its next audit
+)
+
+// ComplianceSLAPolicy overrides a framework's default remediation timelines
+// and severity weights for one organization
+type ComplianceSLAPolicy struct {
+	ID              uuid.UUID          `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID  uuid.UUID          `json:"organization_id" gorm:"type:uuid;not null;uniqueIndex:idx_compliance_sla_org_framework"`
+	Framework       string             `json:"framework" gorm:"size:50;not null;uniqueIndex:idx_compliance_sla_org_framework"`
+	RemediationDays map[string]int     `json:"remediation_days" gorm:"type:jsonb;serializer:json"` // Severity -> days to remediate
+	SeverityWeights map[string]float64 `json:"severity_weights" gorm:"type:jsonb;serializer:json"` // Severity -> weight in a control's risk score
+	DueSoonDays     int                `json:"due_soon_days"`                                      // Findings this close to their deadline are due soon
+	UpdatedBy       string             `json:"updated_by,omitempty" gorm:"size:255"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// UpdateComplianceSLAPolicyRequest configures a framework's timelines for an organization.
+// Severities left out keep the framework default.
+type UpdateComplianceSLAPolicyRequest struct {
+	RemediationDays map[string]int     `json:"remediation_days"`
+	SeverityWeights map[string]float64 `json:"severity_weights"`
+	DueSoonDays     int                `json:"due_soon_days"`
+	UpdatedBy       string             `json:"-"` // The user making the change, set from the caller
+}
+
+// ComplianceSLADashboard shows, for one framework, which open findings map to
+// which controls and whether they are within the framework's remediation timelines
+type ComplianceSLADashboard struct {
+	OrganizationID uuid.UUID            `json:"organization_id"`
+	Framework      string               `json:"framework"`
+	Policy         ComplianceSLAPolicy  `json:"policy"`
+	Summary        ComplianceSLASummary `json:"summary"`
+	AtRisk         []ControlSLAStatus   `json:"compliance_at_risk"` // Controls with overdue findings, highest risk first
+	Controls       []ControlSLAStatus   `json:"controls"`
+	GeneratedAt    time.Time            `json:"generated_at"`
+}
+
+// ComplianceSLASummary totals a framework dashboard
+type ComplianceSLASummary struct {
+	OpenFindings     int     `json:"open_findings"`
+	OverdueFindings  int     `json:"overdue_findings"`
+	DueSoonFindings  int     `json:"due_soon_findings"`
+	ControlsAtRisk   int     `json:"controls_at_risk"`
+	ControlsTotal    int     `json:"controls_total"`
+	SLACompliancePct float64 `json:"sla_compliance_pct"` // Share of open findings not overdue
+}
+
+// ControlSLAStatus is the remediation status of the findings mapped to one control
+type ControlSLAStatus struct {
+	ControlID       string             `json:"control_id"`
+	ControlName     string             `json:"control_name"`
+	Status          string             `json:"status"`
+	RiskScore       float64            `json:"risk_score"` // Severity-weighted overdue findings, plus half weight for due soon
+	OpenFindings    int                `json:"open_findings"`
+	OverdueFindings int                `json:"overdue_findings"`
+	DueSoonFindings int                `json:"due_soon_findings"`
+	Findings        []FindingSLAStatus `json:"findings"`
+}
+
+// FindingSLAStatus is one open finding's position against its remediation deadline
+type FindingSLAStatus struct {
+	FindingID     uuid.UUID `json:"finding_id"`
+	AgentID       uuid.UUID `json:"agent_id"`
+	Title         string    `json:"title"`
+	CVEID         string    `json:"cve_id,omitempty"`
+	Severity      string    `json:"severity"`
+	Scope         string    `json:"scope"`
+	FirstSeen     time.Time `json:"first_seen"`
+	DueAt         time.Time `json:"due_at"`
+	DaysRemaining int       `json:"days_remaining"` // Negative once overdue
+	Status        string    `json:"status"`
+}
@@ -19,7 +19,9 @@ type ConfigFile struct {
 	FileSize  int64  `json:"file_size" gorm:"not null"`
 	FileHash  string `json:"file_hash" gorm:"not null;size:64;index"`
 	MimeType  string `json:"mime_type,omitempty" gorm:"size:100"`
-	FileContent []byte `json:"-" gorm:"type:bytea"` // Store file content in PostgreSQL
+	FileContent []byte `json:"-" gorm:"type:bytea"` // Content when StorageBackend is db
+	StorageBackend string `json:"storage_backend" gorm:"size:20;not null;default:'db'"` // db, file or s3
+	StorageKey     string `json:"-" gorm:"size:255"`                                   // Object key in a file or s3 backend
 
 	// Device information
 	DeviceType      string `json:"device_type" gorm:"not null;size:50"`
@@ -268,16 +270,16 @@ func (c *ConfigAnalysisResult) GetComplianceScore(framework string) float64 {
 
 // UploadConfigFileRequest represents a request to upload a config file
 type UploadConfigFileRequest struct {
-	DeviceType      string `form:"device_type" binding:"required"`
-	Manufacturer    string `form:"manufacturer" binding:"required"`
-	Model           string `form:"model"`
-	FirmwareVersion string `form:"firmware_version"`
-	DeviceName      string `form:"device_name"`
-	DeviceLocation  string `form:"device_location"`
-	ConfigType      string `form:"config_type" binding:"required"`
-	ConfigFormat    string `form:"config_format"`
-	Tags            []string `form:"tags"`
-	Notes           string `form:"notes"`
+	DeviceType      string `form:"device_type" json:"device_type" binding:"required"`
+	Manufacturer    string `form:"manufacturer" json:"manufacturer" binding:"required"`
+	Model           string `form:"model" json:"model"`
+	FirmwareVersion string `form:"firmware_version" json:"firmware_version"`
+	DeviceName      string `form:"device_name" json:"device_name"`
+	DeviceLocation  string `form:"device_location" json:"device_location"`
+	ConfigType      string `form:"config_type" json:"config_type" binding:"required"`
+	ConfigFormat    string `form:"config_format" json:"config_format"`
+	Tags            []string `form:"tags" json:"tags"`
+	Notes           string `form:"notes" json:"notes"`
 }
 
 // ListConfigFilesRequest represents filters for listing config files
@@ -311,3 +313,11 @@ type UpdateFindingStatusRequest struct {
 	Notes      string     `json:"notes,omitempty"`
 }
 
+
+// ConfigFileStorageUsage is the config file content a company keeps, in total and per storage backend
+type ConfigFileStorageUsage struct {
+	CompanyID      uuid.UUID        `json:"company_id"`
+	Files          int64            `json:"files"`
+	Bytes          int64            `json:"bytes"`
+	BytesByBackend map[string]int64 `json:"bytes_by_backend"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConfigBaseline is the approved configuration for a group of hosts. Agents in
+// the group report any configuration check whose state differs from the
+// baseline as drift, even when the new state still passes the check.
+type ConfigBaseline struct {
+	ID             uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID         `json:"organization_id" gorm:"type:uuid;not null;uniqueIndex:idx_config_baseline_org_group"`
+	HostGroup      string            `json:"host_group" gorm:"size:100;not null;uniqueIndex:idx_config_baseline_org_group"`
+	Settings       map[string]string `json:"settings" gorm:"type:jsonb;serializer:json"` // Check name -> approved state
+	Version        int               `json:"version" gorm:"not null;default:1"`
+	SourceAgentID  *uuid.UUID        `json:"source_agent_id,omitempty" gorm:"type:uuid"` // Host the baseline was captured from
+	UpdatedBy      string            `json:"updated_by,omitempty" gorm:"size:255"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// UpdateConfigBaselineRequest replaces a host group's approved settings
+type UpdateConfigBaselineRequest struct {
+	Settings  map[string]string `json:"settings" binding:"required"`
+	UpdatedBy string            `json:"-"` // The user making the change, set from the caller
+}
+
+// CaptureConfigBaselineRequest captures a host group's baseline from the latest configuration scan of a known-good agent
+type CaptureConfigBaselineRequest struct {
+	AgentID   string `json:"agent_id" binding:"required"`
+	UpdatedBy string `json:"-"` // The user making the change, set from the caller
+}
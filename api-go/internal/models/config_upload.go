@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Config upload statuses
+const (
+	ConfigUploadReceiving = "receiving" // Waiting for more chunks, or to be completed
+	ConfigUploadComplete  = "complete"  // Assembled and stored as a config file
+)
+
+// ConfigUpload is a config file being uploaded in chunks, so an upload over
+// a flaky link can resume where it stopped rather than start over. The file's
+// size, checksum and device details are given up front; chunks are then sent
+// at offsets that are multiples of ChunkSize. Uploads that go without a chunk
+// until they expire are dropped with their chunks.
+type ConfigUpload struct {
+	ID             uuid.UUID               `json:"upload_id" gorm:"type:uuid;primary_key"`
+	CompanyID      uuid.UUID               `json:"-" gorm:"type:uuid;not null;index"`
+	UploadedBy     *uuid.UUID              `json:"-" gorm:"type:uuid"`
+	Filename       string                  `json:"filename" gorm:"size:255;not null"`
+	Size           int64                   `json:"size" gorm:"not null"`
+	ChunkSize      int64                   `json:"chunk_size" gorm:"not null"`
+	TotalChunks    int                     `json:"total_chunks" gorm:"not null"`
+	ReceivedChunks int                     `json:"received_chunks"`
+	Checksum       string                  `json:"checksum" gorm:"size:64;not null"` // SHA-256 of the whole file
+	Request        UploadConfigFileRequest `json:"-" gorm:"type:jsonb;serializer:json"`
+	Status         string                  `json:"status" gorm:"size:20;not null;index"`
+	ConfigFileID   *uuid.UUID              `json:"config_file_id,omitempty" gorm:"type:uuid"`
+	ExpiresAt      time.Time               `json:"expires_at" gorm:"index"` // Pushed back by every chunk that arrives
+	CreatedAt      time.Time               `json:"created_at"`
+	UpdatedAt      time.Time               `json:"updated_at"`
+}
+
+// ConfigUploadChunk records one chunk of a config upload, keyed by upload and
+// sequence (its offset over the upload's chunk size), so a chunk the client
+// is unsure arrived can safely be sent again. The chunk's content is kept in
+// a blob store, not the database.
+type ConfigUploadChunk struct {
+	UploadID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Sequence  int       `gorm:"primaryKey;autoIncrement:false"`
+	Checksum  string    `gorm:"size:64;not null"` // SHA-256 of Data
+	Size      int64     `gorm:"not null"`
+	Data      []byte    `gorm:"-"` // Read from the blob store when the upload is assembled
+	CreatedAt time.Time
+}
+
+// InitConfigUploadRequest starts a chunked config upload. The device details
+// are those of a single-request upload.
+type InitConfigUploadRequest struct {
+	UploadConfigFileRequest
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required"`
+	Checksum string `json:"checksum" binding:"required"` // Hex SHA-256 of the whole file
+}
+
+// ConfigUploadStatus is a config upload's progress. A client resuming an
+// upload sends the chunks at the offsets in Missing.
+type ConfigUploadStatus struct {
+	*ConfigUpload
+	Missing []int64 `json:"missing"` // Offsets of the chunks not yet received
+}
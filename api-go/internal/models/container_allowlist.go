@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Container allowlist audit actions
+const (
+	ContainerAllowlistCreated = "created"
+	ContainerAllowlistUpdated = "updated"
+	ContainerAllowlistDeleted = "deleted"
+)
+
+// ContainerAllowlistRule marks a container finding as expected for an
+// organization, such as a web server exposing 443 or an intended secret
+// mount. A finding matches when every matcher the rule sets matches it; image
+// and container name are glob patterns. Agents keep matched findings but mark
+// them suppressed by the rule.
+type ContainerAllowlistRule struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Image          string    `json:"image,omitempty" gorm:"size:255"`          // e.g. nginx:*
+	ContainerName  string    `json:"container_name,omitempty" gorm:"size:255"` // e.g. web-*
+	FindingType    string    `json:"finding_type,omitempty" gorm:"size:50"`    // image, runtime, network, storage, config
+	Title          string    `json:"title,omitempty" gorm:"size:255"`          // Finding title, e.g. Exposed Container Ports
+	Reason         string    `json:"reason" gorm:"type:text;not null"`
+	CreatedBy      string    `json:"created_by,omitempty" gorm:"size:255"`
+	UpdatedBy      string    `json:"updated_by,omitempty" gorm:"size:255"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ContainerAllowlistAudit records one change to an organization's container
+// allowlist, with the rule as it stood after the change (before, for deletions)
+type ContainerAllowlistAudit struct {
+	ID             uuid.UUID              `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID              `json:"organization_id" gorm:"type:uuid;not null;index"`
+	RuleID         uuid.UUID              `json:"rule_id" gorm:"type:uuid;not null;index"`
+	Action         string                 `json:"action" gorm:"size:20;not null"`
+	Actor          string                 `json:"actor,omitempty" gorm:"size:255"`
+	Rule           ContainerAllowlistRule `json:"rule" gorm:"type:jsonb;serializer:json"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// ContainerAllowlistRuleRequest creates or replaces an allowlist rule
+type ContainerAllowlistRuleRequest struct {
+	Image         string `json:"image"`
+	ContainerName string `json:"container_name"`
+	FindingType   string `json:"finding_type"`
+	Title         string `json:"title"`
+	Reason        string `json:"reason" binding:"required"`
+	Actor         string `json:"-"` // The user making the change, set from the caller
+}
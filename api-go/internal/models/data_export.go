@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Data export statuses
+const (
+	DataExportStatusRunning   = "running"
+	DataExportStatusCompleted = "completed"
+	DataExportStatusFailed    = "failed"
+)
+
+// Data export modes
+const (
+	DataExportModeFull        = "full"
+	DataExportModeIncremental = "incremental"
+)
+
+// DataExportSchemaVersion is written to every manifest so consumers can detect layout changes
+const DataExportSchemaVersion = "1.0"
+
+// DataExportConfig holds an organization's scheduled export to its own S3-compatible bucket
+type DataExportConfig struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Enabled        bool      `json:"enabled" gorm:"default:false"`
+
+	// Schedule
+	Frequency   string `json:"frequency" gorm:"size:50;not null"` // Go duration, e.g. "24h"
+	Incremental bool   `json:"incremental" gorm:"default:false"`  // Only export records changed since the last successful export
+
+	// Destination
+	Endpoint        string `json:"endpoint,omitempty" gorm:"size:500"`
+	Region          string `json:"region,omitempty" gorm:"size:100"`
+	Bucket          string `json:"bucket" gorm:"size:255;not null"`
+	PathPrefix      string `json:"path_prefix,omitempty" gorm:"size:500"`
+	UsePathStyle    bool   `json:"use_path_style" gorm:"default:false"`
+	AccessKeyID     string `json:"access_key_id" gorm:"size:255;not null"`
+	SecretAccessKey string `json:"-" gorm:"size:500;not null"`
+
+	// State
+	LastExportAt     *time.Time `json:"last_export_at,omitempty"`
+	LastSuccessAt    *time.Time `json:"last_success_at,omitempty"`
+	LastExportStatus string     `json:"last_export_status,omitempty" gorm:"size:50"`
+	LastExportError  string     `json:"last_export_error,omitempty" gorm:"type:text"`
+	NextExportAt     *time.Time `json:"next_export_at,omitempty" gorm:"index"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DataExportRun records a single export attempt
+type DataExportRun struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID      `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Mode           string         `json:"mode" gorm:"size:20;not null"`
+	Trigger        string         `json:"trigger" gorm:"size:20;not null"` // scheduled or manual
+	Status         string         `json:"status" gorm:"size:20;not null;index"`
+	Since          *time.Time     `json:"since,omitempty"`
+	Until          time.Time      `json:"until"`
+	ObjectPrefix   string         `json:"object_prefix" gorm:"size:1000"`
+	ManifestKey    string         `json:"manifest_key,omitempty" gorm:"size:1000"`
+	RecordCounts   map[string]int `json:"record_counts,omitempty" gorm:"type:jsonb;serializer:json"`
+	BytesWritten   int64          `json:"bytes_written"`
+	Error          string         `json:"error,omitempty" gorm:"type:text"`
+	StartedAt      time.Time      `json:"started_at"`
+	CompletedAt    *time.Time     `json:"completed_at,omitempty"`
+}
+
+// DataExportManifest is written as manifest.json alongside each export's data files
+type DataExportManifest struct {
+	SchemaVersion  string                   `json:"schema_version"`
+	ExportID       uuid.UUID                `json:"export_id"`
+	OrganizationID uuid.UUID                `json:"organization_id"`
+	Mode           string                   `json:"mode"`
+	Since          *time.Time               `json:"since,omitempty"`
+	Until          time.Time                `json:"until"`
+	GeneratedAt    time.Time                `json:"generated_at"`
+	Files          []DataExportManifestFile `json:"files"`
+}
+
+// DataExportManifestFile describes one data file in an export
+type DataExportManifestFile struct {
+	Name        string `json:"name"`
+	Key         string `json:"key"`
+	RecordType  string `json:"record_type"`
+	RecordCount int    `json:"record_count"`
+	SizeBytes   int64  `json:"size_bytes"`
+	SHA256      string `json:"sha256"`
+}
+
+// UpdateDataExportConfigRequest creates or replaces an organization's export configuration
+type UpdateDataExportConfigRequest struct {
+	Enabled         bool   `json:"enabled"`
+	Frequency       string `json:"frequency" binding:"required"`
+	Incremental     bool   `json:"incremental"`
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket" binding:"required"`
+	PathPrefix      string `json:"path_prefix"`
+	UsePathStyle    bool   `json:"use_path_style"`
+	AccessKeyID     string `json:"access_key_id" binding:"required"`
+	SecretAccessKey string `json:"secret_access_key"` // Optional on update; keeps the stored secret when empty
+}
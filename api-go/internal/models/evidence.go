@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EvidenceUpload is raw evidence a scanner attached to a finding, such as the
+// command output that triggered a configuration finding. It is moved to
+// object storage on ingestion and replaced by a reference.
+type EvidenceUpload struct {
+	Label       string `json:"label"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"` // Base64 in JSON
+}
+
+// FindingEvidence references an evidence blob held in object storage. The
+// same blob attached to the same finding by later scans is stored once.
+type FindingEvidence struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	AgentID        uuid.UUID `json:"agent_id" gorm:"type:uuid;not null;index:idx_finding_evidence_finding"`
+	FindingKey     string    `json:"finding_key" gorm:"size:64;not null;index:idx_finding_evidence_finding"` // See FindingKey
+	Label          string    `json:"label" gorm:"size:255"`
+	ContentType    string    `json:"content_type" gorm:"size:100;not null"`
+	Size           int       `json:"size"`
+	SHA256         string    `json:"sha256" gorm:"size:64;not null"`
+	ObjectKey      string    `json:"-" gorm:"size:500;not null"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Export job statuses
+const (
+	ExportJobPending   = "pending"
+	ExportJobRunning   = "running"
+	ExportJobCompleted = "completed"
+	ExportJobFailed    = "failed"
+)
+
+// ExportJob is an export generated in the background, for datasets too large
+// to build within a request. Once complete it can be downloaded through its
+// download URL until it expires.
+type ExportJob struct {
+	ID            uuid.UUID              `json:"id" gorm:"type:uuid;primary_key"`
+	QuotaKey      string                 `json:"-" gorm:"size:255;index"` // Organization (or client) the export counted against
+	Kind          string                 `json:"kind" gorm:"size:50;not null"`
+	Format        string                 `json:"format" gorm:"size:20;not null"`
+	Filters       map[string]interface{} `json:"filters,omitempty" gorm:"type:jsonb;serializer:json"`
+	Status        string                 `json:"status" gorm:"size:20;not null;index"`
+	ContentType   string                 `json:"content_type,omitempty" gorm:"size:100"`
+	Size          int                    `json:"size,omitempty"`
+	SHA256        string                 `json:"sha256,omitempty" gorm:"size:64"`
+	Error         string                 `json:"error,omitempty" gorm:"type:text"`
+	DownloadToken string                 `json:"-" gorm:"size:64"`
+	DownloadURL   string                 `json:"download_url,omitempty" gorm:"-"` // Set once the export is complete
+	CallbackURL   string                 `json:"callback_url,omitempty" gorm:"size:1000"`
+	ObjectKey     string                 `json:"-" gorm:"size:500"`
+	CreatedAt     time.Time              `json:"created_at"`
+	CompletedAt   *time.Time             `json:"completed_at,omitempty"`
+	ExpiresAt     *time.Time             `json:"expires_at,omitempty" gorm:"index"` // When the finished export is deleted
+}
+
+// CreateExportJobRequest optionally asks to be notified when an async export is ready
+type CreateExportJobRequest struct {
+	CallbackURL string `json:"callback_url"`
+}
+
+// ExportJobNotification is posted to an export's callback URL once it finishes
+type ExportJobNotification struct {
+	ExportID    uuid.UUID  `json:"export_id"`
+	Status      string     `json:"status"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Finding lifecycle statuses
+const (
+	FindingStatusOpen     = "open"
+	FindingStatusResolved = "resolved"
+)
+
+// Finding priorities, from most to least pressing. A finding's priority is
+// derived from its severity, exploitability and its host's asset criticality
+// unless it is overridden.
+const (
+	FindingPriorityUrgent = "urgent"
+	FindingPriorityHigh   = "high"
+	FindingPriorityMedium = "medium"
+	FindingPriorityLow    = "low"
+)
+
+// Finding suppression modes
+const (
+	// FindingSuppressedUntilFix suppresses a finding no fix exists for yet
+	// until re-enrichment finds a fixed version published, then reopens it
+	FindingSuppressedUntilFix = "until_fix"
+	// FindingSuppressedByRule suppresses a finding matched by one of its
+	// organization's suppression rules while the rule is active
+	FindingSuppressedByRule = "rule"
+)
+
+// FindingState tracks one finding on one agent across scans so that
+// open/resolved transitions, and findings that keep toggling between them,
+// can be detected
+type FindingState struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgentID        uuid.UUID `json:"agent_id" gorm:"type:uuid;not null;uniqueIndex:idx_finding_state_agent_key"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	FindingKey     string    `json:"finding_key" gorm:"size:64;not null;uniqueIndex:idx_finding_state_agent_key"`
+	Scope          string    `json:"scope" gorm:"size:50;not null"` // Scan type that reports this finding
+
+	// Identifying details, kept for display
+	Title          string `json:"title" gorm:"size:500"`
+	CVEID          string `json:"cve_id,omitempty" gorm:"size:50"`
+	PackageName    string `json:"package_name,omitempty" gorm:"size:255"`
+	PackageVersion string `json:"package_version,omitempty" gorm:"size:100"` // As of the latest scan
+	Severity       string `json:"severity" gorm:"size:20"`
+
+	// Exploitability, from enrichment and threat intel, as of the latest scan
+	CVSSScore      float64            `json:"cvss_score,omitempty"`                                     // 0 when unscored
+	EPSS           float64            `json:"epss,omitempty"`                                           // Probability of exploitation in the next 30 days
+	KnownExploited bool               `json:"known_exploited"`                                          // Listed in CISA KEV, a public exploit exists, or the organization's threat intel reports it
+	ThreatIntel    []ThreatIntelMatch `json:"threat_intel,omitempty" gorm:"type:jsonb;serializer:json"` // What the organization's threat intel feeds report about the CVE
+
+	// Priority is the override when set, and DerivedPriority otherwise
+	Priority               string     `json:"priority" gorm:"size:20;index"`
+	DerivedPriority        string     `json:"derived_priority" gorm:"size:20"` // As of the latest scan
+	PriorityOverride       string     `json:"priority_override,omitempty" gorm:"size:20"`
+	PriorityOverrideReason string     `json:"priority_override_reason,omitempty" gorm:"size:500"`
+	PriorityOverriddenAt   *time.Time `json:"priority_overridden_at,omitempty"`
+	PriorityOverriddenBy   string     `json:"priority_overridden_by,omitempty" gorm:"size:255"`
+
+	// Lifecycle
+	Status           string      `json:"status" gorm:"size:20;not null"`
+	Flapping         bool        `json:"flapping" gorm:"default:false;index"`
+	FlappingSince    *time.Time  `json:"flapping_since,omitempty"`
+	TransitionCount  int         `json:"transition_count"`                                     // All-time open/resolved toggles
+	Transitions      []time.Time `json:"recent_transitions" gorm:"type:jsonb;serializer:json"` // Toggles within the correlation window
+	FirstSeen        time.Time   `json:"first_seen"`
+	LastSeen         time.Time   `json:"last_seen"`
+	LastTransitionAt time.Time   `json:"last_transition_at"`
+
+	// Suppression, see SuppressFindingRequest and SuppressionRule. A
+	// suppressed finding is still tracked but raises no alerts and does not
+	// count towards its host's risk.
+	Suppressed             bool       `json:"suppressed" gorm:"default:false;index"`
+	SuppressionMode        string     `json:"suppression_mode,omitempty" gorm:"size:20"`
+	SuppressionOwner       string     `json:"suppression_owner,omitempty" gorm:"size:255"`
+	SuppressionReason      string     `json:"suppression_reason,omitempty" gorm:"size:500"`
+	SuppressionCallbackURL string     `json:"suppression_callback_url,omitempty" gorm:"size:2048"` // Notified when the finding reopens
+	SuppressedAt           *time.Time `json:"suppressed_at,omitempty"`
+	SuppressionRuleID      *uuid.UUID `json:"suppression_rule_id,omitempty" gorm:"type:uuid;index"` // The matching SuppressionRule, in rule mode
+	SuppressionExpiresAt   *time.Time `json:"suppression_expires_at,omitempty"`                     // When the rule expires and the finding reopens
+
+	// Set when a suppression ended on its own: a fixed version was published
+	// (FixVersion is set then) or its suppression rule expired
+	FixVersion string     `json:"fix_version,omitempty" gorm:"size:100"`
+	ReopenedAt *time.Time `json:"reopened_at,omitempty"`
+	ReopenNote string     `json:"reopen_note,omitempty" gorm:"size:500"`
+
+	// Latest on-demand verification, see FindingVerification
+	Verification string     `json:"verification,omitempty" gorm:"size:20"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FindingTransition is a single change in a finding's lifecycle
+type FindingTransition struct {
+	State          *FindingState `json:"state"`
+	From           string        `json:"from,omitempty"` // Empty for a newly seen finding
+	To             string        `json:"to"`
+	BecameFlapping bool          `json:"became_flapping"`
+	// Alert is false for toggles of a finding that is already flapping, so
+	// notifications fire once when flapping starts instead of on every toggle
+	Alert bool `json:"alert"`
+}
+
+// SetFindingPriorityRequest overrides a finding's derived priority
+type SetFindingPriorityRequest struct {
+	Priority string `json:"priority" binding:"required,oneof=urgent high medium low"`
+	Reason   string `json:"reason" binding:"max=500"`
+}
+
+// SuppressFindingRequest suppresses a finding until a fix for it is published.
+// The owner is notified at the callback URL, if given, when it reopens.
+type SuppressFindingRequest struct {
+	Mode        string `json:"mode" binding:"required,oneof=until_fix"`
+	Owner       string `json:"owner" binding:"required,max=255"`
+	Reason      string `json:"reason" binding:"max=500"`
+	CallbackURL string `json:"callback_url" binding:"omitempty,url,max=2048"`
+}
+
+// FindingReopenedNotification is posted to a suppression's callback URL when
+// the finding reopens
+type FindingReopenedNotification struct {
+	FindingID   uuid.UUID `json:"finding_id"`
+	AgentID     uuid.UUID `json:"agent_id"`
+	CVEID       string    `json:"cve_id,omitempty"`
+	PackageName string    `json:"package_name,omitempty"`
+	Owner       string    `json:"owner"`
+	FixVersion  string    `json:"fix_version"`
+	Note        string    `json:"note"`
+	ReopenedAt  time.Time `json:"reopened_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Finding verification statuses
+const (
+	VerificationPending      = "pending"      // Waiting for the agent to re-run the check
+	VerificationConfirmed    = "confirmed"    // The check still reports the finding
+	VerificationResolved     = "resolved"     // The check no longer reports the finding
+	VerificationUnverifiable = "unverifiable" // The check could not be re-run or could not tell
+)
+
+// FindingVerification is one on-demand re-check of a finding: the agent that
+// reported it re-runs just the check behind it and reports whether it holds
+type FindingVerification struct {
+	ID             uuid.UUID              `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	FindingID      uuid.UUID              `json:"finding_id" gorm:"type:uuid;not null;index"` // FindingState ID
+	AgentID        uuid.UUID              `json:"agent_id" gorm:"type:uuid;not null"`
+	OrganizationID uuid.UUID              `json:"organization_id" gorm:"type:uuid;not null;index"`
+	CommandID      uuid.UUID              `json:"command_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Status         string                 `json:"status" gorm:"size:20;not null;index"`
+	Details        string                 `json:"details,omitempty" gorm:"type:text"`
+	CheckResult    map[string]interface{} `json:"check_result,omitempty" gorm:"type:jsonb;serializer:json"` // The re-run check's structured result
+	EvidenceIDs    []uuid.UUID            `json:"evidence_ids,omitempty" gorm:"type:jsonb;serializer:json"`
+	Evidence       []FindingEvidence      `json:"evidence,omitempty" gorm:"-"`
+	RequestedBy    string                 `json:"requested_by,omitempty" gorm:"size:255"`
+	CompletedAt    *time.Time             `json:"completed_at,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// VerifyFindingRequest is a verification request
+type VerifyFindingRequest struct {
+	RequestedBy string `json:"-"` // The user asking, set from the caller
+}
+
+// FindingVerificationReport is what an agent reports, as its verify_finding
+// command's result, after re-running a finding's check
+type FindingVerificationReport struct {
+	Status      string                 `json:"status"` // confirmed, resolved or unverifiable
+	Details     string                 `json:"details"`
+	CheckResult map[string]interface{} `json:"check_result,omitempty"`
+	Evidence    []EvidenceUpload       `json:"evidence,omitempty"`
+}
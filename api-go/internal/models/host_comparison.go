@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// HostComparison compares the security posture of two hosts: the open
+// findings only one of them has, those both have, and the configuration
+// checks whose states differ
+type HostComparison struct {
+	A                 ComparedHost              `json:"a"`
+	B                 ComparedHost              `json:"b"`
+	OnlyA             []FindingState            `json:"only_a"`
+	OnlyB             []FindingState            `json:"only_b"`
+	Common            []FindingState            `json:"common"` // As reported by host A
+	ConfigDifferences []ConfigSettingDifference `json:"config_differences"`
+}
+
+// ComparedHost identifies one side of a host comparison
+type ComparedHost struct {
+	AgentID       uuid.UUID `json:"agent_id"`
+	Name          string    `json:"name"`
+	Hostname      string    `json:"hostname"`
+	HostGroup     string    `json:"host_group,omitempty"`
+	OpenFindings  int       `json:"open_findings"`
+	ConfigScanned bool      `json:"config_scanned"` // False when the host has not reported a configuration scan
+}
+
+// ConfigSettingDifference is a configuration check whose state differs
+// between two hosts. A nil value means the host did not report the check.
+type ConfigSettingDifference struct {
+	Setting string  `json:"setting"`
+	ValueA  *string `json:"value_a"`
+	ValueB  *string `json:"value_b"`
+}
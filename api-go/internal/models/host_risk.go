@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Asset criticality levels, set per host to reflect how much it matters to the business
+const (
+	AssetCriticalityLow      = "low"
+	AssetCriticalityMedium   = "medium"
+	AssetCriticalityHigh     = "high"
+	AssetCriticalityCritical = "critical"
+)
+
+// HostRisk is a host's consolidated risk score, from 0 to 100. It combines
+// the host's open findings, weighted by severity, exploit likelihood (EPSS)
+// and known exploitation (KEV), with the host's exposure, and scales the
+// result by the host's asset criticality.
+type HostRisk struct {
+	AgentID        uuid.UUID `json:"agent_id" gorm:"type:uuid;primaryKey"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Name           string    `json:"name"`
+	Hostname       string    `json:"hostname"`
+	Score          float64   `json:"score" gorm:"index"`
+	Rank           int       `json:"rank" gorm:"-"` // Position within the organization, 1 is the riskiest
+
+	// Score components, each from 0 to 100 before criticality is applied
+	FindingScore  float64 `json:"finding_score"`
+	ExposureScore float64 `json:"exposure_score"`
+	Criticality   string  `json:"criticality" gorm:"size:20"`
+
+	// Inputs
+	OpenFindings   int            `json:"open_findings"`
+	BySeverity     map[string]int `json:"by_severity" gorm:"type:jsonb;serializer:json"`
+	KnownExploited int            `json:"known_exploited"` // Open findings known to be exploited in the wild
+	InternetFacing bool           `json:"internet_facing"`
+	OpenServices   int            `json:"open_services"`
+
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// SetAssetCriticalityRequest sets a host's asset criticality
+type SetAssetCriticalityRequest struct {
+	Criticality string `json:"criticality" binding:"required,oneof=low medium high critical"`
+}
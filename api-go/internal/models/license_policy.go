@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LicensePolicy decides which dependency licenses an organization accepts,
+// such as denying GPL-* in proprietary products. License patterns are SPDX
+// identifiers, matched case-insensitively, and may end in * to match a
+// family. A dependency violates the policy when its license expression
+// can't be satisfied without a denied license or, if the policy allows
+// only some licenses, with the allowed licenses alone.
+type LicensePolicy struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Name           string    `json:"name" gorm:"size:255;not null"`
+	Description    string    `json:"description,omitempty" gorm:"type:text"`
+	Allowed        []string  `json:"allowed" gorm:"type:jsonb;serializer:json"`       // Empty allows any license not denied
+	Denied         []string  `json:"denied" gorm:"type:jsonb;serializer:json"`        // e.g. GPL-*, AGPL-3.0-only
+	FlagUnknown    bool      `json:"flag_unknown"`                                    // Dependencies without a detected license violate the policy
+	Severity       string    `json:"severity" gorm:"size:20;not null;default:medium"` // Of the findings the policy raises
+	Enabled        bool      `json:"enabled" gorm:"not null;default:true"`
+	CreatedBy      string    `json:"created_by,omitempty" gorm:"size:255"`
+	UpdatedBy      string    `json:"updated_by,omitempty" gorm:"size:255"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// LicensePolicyRequest creates or replaces a license policy
+type LicensePolicyRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Allowed     []string `json:"allowed"`
+	Denied      []string `json:"denied"`
+	FlagUnknown bool     `json:"flag_unknown"`
+	Severity    string   `json:"severity"` // Defaults to medium
+	Enabled     *bool    `json:"enabled"`  // Defaults to true
+	Actor       string   `json:"-"`        // The user making the change, set from the caller
+}
+
+// LicenseViolation is a dependency whose license violates a license policy
+type LicenseViolation struct {
+	PackageName    string    `json:"package_name"`
+	PackageVersion string    `json:"package_version,omitempty"`
+	PackageType    string    `json:"package_type,omitempty"`
+	License        string    `json:"license"` // Empty when no license was detected
+	PolicyID       uuid.UUID `json:"policy_id"`
+	PolicyName     string    `json:"policy_name"`
+	Severity       string    `json:"severity"`
+	Reason         string    `json:"reason"`
+	Agents         int       `json:"agents,omitempty"` // Agents the dependency is installed on, in a compliance summary
+}
+
+// LicenseComplianceSummary is how an organization's dependencies, across its
+// agents, comply with its enabled license policies
+type LicenseComplianceSummary struct {
+	OrganizationID uuid.UUID          `json:"organization_id"`
+	Policies       int                `json:"policies"`
+	Dependencies   int                `json:"dependencies"` // Distinct by name, version and type
+	Unlicensed     int                `json:"unlicensed"`   // Dependencies without a detected license
+	Compliant      int                `json:"compliant"`
+	ByLicense      map[string]int     `json:"by_license"`
+	Violations     []LicenseViolation `json:"violations"`
+	GeneratedAt    time.Time          `json:"generated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaturityAssessment is one computed security maturity score of an
+// organization, kept so its maturity can be plotted and trended over time
+type MaturityAssessment struct {
+	ID              uuid.UUID          `json:"id" gorm:"type:uuid;primaryKey"`
+	OrganizationID  uuid.UUID          `json:"organization_id" gorm:"type:uuid;not null;index:idx_maturity_assessments_org_time"`
+	OverallScore    float64            `json:"overall_score"`
+	MaturityLevel   string             `json:"maturity_level" gorm:"size:20"`
+	DimensionScores map[string]float64 `json:"dimension_scores" gorm:"type:jsonb;serializer:json"` // Dimension -> score
+	AssessedAt      time.Time          `json:"assessed_at" gorm:"not null;index:idx_maturity_assessments_org_time"`
+}
@@ -97,9 +97,9 @@ type Scan struct {
 	Progress       int            `json:"progress" db:"progress"`
 	StartTime      *time.Time     `json:"start_time,omitempty" db:"start_time"`
 	EndTime        *time.Time     `json:"end_time,omitempty" db:"end_time"`
-	Options        map[string]any `json:"options" db:"options" gorm:"type:jsonb"`
-	Results        map[string]any `json:"results" db:"results" gorm:"type:jsonb"`
-	Metadata       map[string]any `json:"metadata" db:"metadata" gorm:"type:jsonb"`
+	Options        map[string]any `json:"options" db:"options" gorm:"type:bytea;serializer:gzipjson"` // See repository.CompressedJSONSerializer
+	Results        map[string]any `json:"results" db:"results" gorm:"type:bytea;serializer:gzipjson"`
+	Metadata       map[string]any `json:"metadata" db:"metadata" gorm:"type:bytea;serializer:gzipjson"`
 	Notes          string         `json:"notes,omitempty" db:"notes"`
 	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
@@ -118,33 +118,61 @@ const (
 
 // Vulnerability represents a vulnerability
 type Vulnerability struct {
-	ID               string         `json:"id" db:"id"`
-	ScanID           uuid.UUID      `json:"scan_id" db:"scan_id"`
-	CompanyID        uuid.UUID      `json:"company_id" db:"company_id"`
-	OrganizationID   uuid.UUID      `json:"organization_id" db:"organization_id"`
-	Type             string         `json:"type" db:"type"`
-	Severity         SeverityLevel  `json:"severity" db:"severity"`
-	Title            string         `json:"title" db:"title"`
-	Description      string         `json:"description" db:"description"`
-	CVEID            string         `json:"cve_id,omitempty" db:"cve_id"`
-	CVSSScore        *float64       `json:"cvss_score,omitempty" db:"cvss_score"`
-	CVSSVector       string         `json:"cvss_vector,omitempty" db:"cvss_vector"`
-	PackageName      string         `json:"package_name,omitempty" db:"package_name"`
-	PackageVersion   string         `json:"package_version,omitempty" db:"package_version"`
-	Location         string         `json:"location,omitempty" db:"location"`
-	Remediation      string         `json:"remediation,omitempty" db:"remediation"`
-	References       []string       `json:"references" db:"references" gorm:"type:jsonb"`
-	AffectedVersions []string       `json:"affected_versions" db:"affected_versions" gorm:"type:jsonb"`
-	PatchedVersions  []string       `json:"patched_versions" db:"patched_versions" gorm:"type:jsonb"`
-	ExploitAvailable bool           `json:"exploit_available" db:"exploit_available"`
-	ExploitCount     int            `json:"exploit_count" db:"exploit_count"`
-	Status           string         `json:"status" db:"status"`
-	Priority         string         `json:"priority" db:"priority"`
-	Notes            string         `json:"notes,omitempty" db:"notes"`
-	EnrichmentData   map[string]any `json:"enrichment_data" db:"enrichment_data" gorm:"type:jsonb"`
-	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at" db:"updated_at"`
-}
+	ID                 string           `json:"id" db:"id"`
+	ScanID             uuid.UUID        `json:"scan_id" db:"scan_id"`
+	CompanyID          uuid.UUID        `json:"company_id" db:"company_id"`
+	OrganizationID     uuid.UUID        `json:"organization_id" db:"organization_id"`
+	Type               string           `json:"type" db:"type"`
+	Severity           SeverityLevel    `json:"severity" db:"severity"`
+	Title              string           `json:"title" db:"title"`
+	Description        string           `json:"description" db:"description"`
+	CVEID              string           `json:"cve_id,omitempty" db:"cve_id"`
+	CVSSScore          *float64         `json:"cvss_score,omitempty" db:"cvss_score"`
+	CVSSVector         string           `json:"cvss_vector,omitempty" db:"cvss_vector"`
+	EnvironmentalScore *float64         `json:"environmental_score,omitempty" db:"environmental_score"` // CVSS score under the organization's security requirements; the base score when the vector can't be scored
+	EPSSScore          *float64         `json:"epss_score" db:"epss_score"`                             // Probability of exploitation in the next 30 days; null when FIRST has no data
+	EPSSPercentile     *float64         `json:"epss_percentile" db:"epss_percentile"`                   // Share of CVEs scored at or below it
+	PackageName        string           `json:"package_name,omitempty" db:"package_name"`
+	PackageVersion     string           `json:"package_version,omitempty" db:"package_version"`
+	Location           string           `json:"location,omitempty" db:"location"`
+	Remediation        string           `json:"remediation,omitempty" db:"remediation"`
+	References         []string         `json:"references" db:"references" gorm:"type:jsonb"`
+	AffectedVersions   []string         `json:"affected_versions" db:"affected_versions" gorm:"type:jsonb"`
+	PatchedVersions    []string         `json:"patched_versions" db:"patched_versions" gorm:"type:jsonb"`
+	ExploitAvailable   bool             `json:"exploit_available" db:"exploit_available"`
+	ExploitCount       int              `json:"exploit_count" db:"exploit_count"`
+	Status             string           `json:"status" db:"status"`
+	Priority           string           `json:"priority" db:"priority"`
+	Notes              string           `json:"notes,omitempty" db:"notes"`
+	EnrichmentData     map[string]any   `json:"enrichment_data" db:"enrichment_data" gorm:"type:jsonb"`
+	IntroducedBy       *Attribution     `json:"introduced_by,omitempty" db:"introduced_by" gorm:"type:jsonb;serializer:json"`
+	ImportedFrom       *ImportSource    `json:"imported_from,omitempty" db:"imported_from" gorm:"type:jsonb;serializer:json"` // Set on findings imported from a third-party scanner's report
+	Evidence           []EvidenceUpload `json:"evidence,omitempty" db:"-" gorm:"-"`                                         // Raw evidence from the scanner, moved to object storage on ingestion
+	EvidenceIDs        []uuid.UUID      `json:"evidence_ids,omitempty" db:"evidence_ids" gorm:"type:jsonb;serializer:json"` // Stored evidence, see GET /api/v1/evidence/:id
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// Attribution records the scan and change that first introduced a vulnerability
+type Attribution struct {
+	ScanID          uuid.UUID  `json:"scan_id"`
+	AgentID         uuid.UUID  `json:"agent_id"`
+	ChangeType      ChangeType `json:"change_type"`
+	PackageName     string     `json:"package_name,omitempty"`
+	PreviousVersion string     `json:"previous_version,omitempty"`
+	CurrentVersion  string     `json:"current_version,omitempty"`
+	DetectedAt      time.Time  `json:"detected_at"`
+}
+
+// ChangeType describes the kind of change a vulnerability is attributed to
+type ChangeType string
+
+const (
+	ChangePackageInstalled  ChangeType = "package_installed"
+	ChangeVersionUpgraded   ChangeType = "version_upgraded"
+	ChangeVersionDowngraded ChangeType = "version_downgraded"
+	ChangeNewHost           ChangeType = "new_host"
+)
 
 // SeverityLevel represents vulnerability severity
 type SeverityLevel string
@@ -163,7 +191,7 @@ type Agent struct {
 	CompanyID      uuid.UUID `json:"company_id" db:"company_id"`
 	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
 	Name           string    `json:"name" db:"name"`
-	Status         string    `json:"status" db:"status"`
+	Status         string    `json:"status" db:"status"` // online, degraded or offline, see AgentStatusOnline
 	Version        string    `json:"version" db:"version"`
 	LastSeen       time.Time `json:"last_seen" db:"last_seen"`
 	CPUUsage       float64   `json:"cpu_usage" db:"cpu_usage"`
@@ -192,6 +220,9 @@ type Agent struct {
 	RiskScore      float64 `json:"risk_score" db:"risk_score"`
 	Tags           string  `json:"tags" db:"tags"` // JSON array as string
 
+	// When Status last changed, such as when the agent went offline
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty" db:"status_changed_at"`
+
 	Metadata  map[string]any `json:"metadata" db:"metadata" gorm:"type:jsonb"`
 	CreatedAt time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
@@ -297,6 +328,36 @@ type PaginationResponse struct {
 	HasPrev    bool  `json:"has_prev"`
 }
 
+// ScanResults is a page of the vulnerabilities a scan found
+type ScanResults struct {
+	ScanID          uuid.UUID               `json:"scan_id"`
+	Vulnerabilities []Vulnerability         `json:"vulnerabilities"`
+	Total           int64                   `json:"total"` // Vulnerabilities matching the severity filter
+	Limit           int                     `json:"limit"`
+	Offset          int                     `json:"offset"`
+	SeverityCounts  map[SeverityLevel]int64 `json:"severity_counts"` // All of the scan's vulnerabilities, unfiltered
+}
+
+// ScanDiff compares two scans of the same agent: the vulnerabilities the
+// later scan introduced, those it no longer finds, and those both found,
+// matched by finding key
+type ScanDiff struct {
+	AgentID    uuid.UUID      `json:"agent_id"`
+	FromScanID uuid.UUID      `json:"from_scan_id"`
+	ToScanID   uuid.UUID      `json:"to_scan_id"`
+	Introduced ScanDiffBucket `json:"introduced"`
+	Resolved   ScanDiffBucket `json:"resolved"`   // As found by the earlier scan
+	Persisting ScanDiffBucket `json:"persisting"` // As found by the later scan
+}
+
+// ScanDiffBucket is one side of a scan diff, with its vulnerabilities'
+// counts by severity
+type ScanDiffBucket struct {
+	Vulnerabilities []Vulnerability         `json:"vulnerabilities"`
+	Total           int                     `json:"total"`
+	SeverityCounts  map[SeverityLevel]int64 `json:"severity_counts"`
+}
+
 // Asset represents a scanned asset
 type Asset struct {
 	ID        uuid.UUID              `json:"id"`
@@ -336,6 +397,15 @@ type AgentScanResult struct {
 	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
 	Dependencies    []Dependency    `json:"dependencies"`
 	Metadata        map[string]any  `json:"metadata"`
+	Delta           *FindingDelta   `json:"delta,omitempty"` // Set when Vulnerabilities are only the findings changed since the agent last sent its scope
+}
+
+// FindingDelta refers, by finding key, to the findings of a scan that the
+// agent sent before: those it no longer finds and those it finds unchanged.
+// The result's vulnerabilities are only its new and changed findings.
+type FindingDelta struct {
+	Resolved  []string `json:"resolved"`
+	Unchanged []string `json:"unchanged"`
 }
 
 // Dependency represents a software dependency
@@ -344,15 +414,17 @@ type Dependency struct {
 	Version     string `json:"version"`
 	Type        string `json:"type"`
 	Description string `json:"description,omitempty"`
+	License     string `json:"license,omitempty"` // SPDX expression, when the package metadata declares one
 }
 
 // APIResponse represents standard API response
 type APIResponse struct {
-	Success   bool      `json:"success"`
-	Data      any       `json:"data,omitempty"`
-	Message   string    `json:"message,omitempty"`
-	Error     *APIError `json:"error,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
+	Success    bool      `json:"success"`
+	Data       any       `json:"data,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	Error      *APIError `json:"error,omitempty"`
+	NextCursor string    `json:"next_cursor,omitempty"` // Set by cursor-paginated lists with more pages
+	Timestamp  time.Time `json:"timestamp"`
 }
 
 // APIError represents API error
@@ -440,20 +512,41 @@ type Software struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// NetworkHost represents a host discovered during network scanning
+// NetworkHost represents a host discovered during network scanning. When
+// several agents scan overlapping subnets, their observations of the same
+// host are merged into one record and AgentID is the primary observer.
 type NetworkHost struct {
-	ID         uuid.UUID      `json:"id" db:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	AgentID    uuid.UUID      `json:"agent_id" db:"agent_id"`
-	IPAddress  string         `json:"ip_address" db:"ip_address"`
-	Hostname   string         `json:"hostname" db:"hostname"`
-	MACAddress string         `json:"mac_address" db:"mac_address"`
-	OS         string         `json:"os" db:"os"`
-	Status     string         `json:"status" db:"status"`
-	OpenPorts  []int          `json:"open_ports" db:"open_ports" gorm:"type:jsonb"`
-	Metadata   map[string]any `json:"metadata" db:"metadata" gorm:"type:jsonb"`
-	LastSeen   time.Time      `json:"last_seen" db:"last_seen"`
-	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID             `json:"id" db:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID             `json:"organization_id" db:"organization_id" gorm:"type:uuid;index"`
+	AgentID        uuid.UUID             `json:"agent_id" db:"agent_id"`                      // Primary observer
+	IdentityKey    string                `json:"identity_key" db:"identity_key" gorm:"index"` // mac:, hostname: or ip: followed by the identifying value
+	IPAddress      string                `json:"ip_address" db:"ip_address" gorm:"index"`
+	Hostname       string                `json:"hostname" db:"hostname" gorm:"index"`
+	MACAddress     string                `json:"mac_address" db:"mac_address" gorm:"index"`
+	OS             string                `json:"os" db:"os"`
+	Status         string                `json:"status" db:"status"`
+	OpenPorts      []int                 `json:"open_ports" db:"open_ports" gorm:"type:jsonb;serializer:json"`
+	Observers      []NetworkHostObserver `json:"observers" db:"observers" gorm:"type:jsonb;serializer:json"`
+	ObserverCount  int                   `json:"observer_count" db:"observer_count"` // Agents that have observed this host
+	Sources        []uuid.UUID           `json:"sources" db:"sources" gorm:"type:jsonb;serializer:json"` // Agents reporting this host, primary observer first
+	Metadata       map[string]any        `json:"metadata" db:"metadata" gorm:"type:jsonb;serializer:json"`
+	LastSeen       time.Time             `json:"last_seen" db:"last_seen"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// NetworkHostObserver is one agent's latest view of a network host
+type NetworkHostObserver struct {
+	AgentID    uuid.UUID `json:"agent_id"`
+	IPAddress  string    `json:"ip_address"`
+	Hostname   string    `json:"hostname,omitempty"`
+	MACAddress string    `json:"mac_address,omitempty"`
+	OS         string    `json:"os,omitempty"`
+	OpenPorts  []int     `json:"open_ports"`
+	Subnet     string    `json:"subnet,omitempty"`     // Scanned subnet the host was found in
+	MatchedBy  string    `json:"matched_by,omitempty"` // How the observation was matched to the host: mac, hostname or ip; empty for the first
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
 }
 
 // DashboardSnapshot represents a historical snapshot of dashboard metrics
@@ -471,3 +564,53 @@ type DashboardSnapshot struct {
 	ComplianceScore      float64   `json:"compliance_score" db:"compliance_score"`
 	CreatedAt            time.Time `json:"created_at" db:"created_at"`
 }
+
+// ToolVersionDriftReport summarizes external scanner tool versions across agents
+type ToolVersionDriftReport struct {
+	OrganizationID  uuid.UUID            `json:"organization_id,omitempty"`
+	AgentsReporting int                  `json:"agents_reporting"`
+	DriftingTools   int                  `json:"drifting_tools"`
+	Tools           []ToolVersionSummary `json:"tools"`
+	GeneratedAt     time.Time            `json:"generated_at"`
+}
+
+// ToolVersionSummary describes the versions of one tool in use across agents
+type ToolVersionSummary struct {
+	Tool           string              `json:"tool"`
+	LatestVersion  string              `json:"latest_version"`
+	Versions       map[string]int      `json:"versions"` // version -> agent count
+	AgentCount     int                 `json:"agent_count"`
+	Consistent     bool                `json:"consistent"`
+	OutdatedAgents []OutdatedToolAgent `json:"outdated_agents"`
+}
+
+// OutdatedToolAgent is an agent running an older tool version than the fleet's newest
+type OutdatedToolAgent struct {
+	AgentID  uuid.UUID `json:"agent_id"`
+	Name     string    `json:"name"`
+	Hostname string    `json:"hostname"`
+	Version  string    `json:"version"`
+}
+
+// MissingToolsReport lists, per optional scanner tool, the agents that don't
+// have it installed and so skip the scans that need it
+type MissingToolsReport struct {
+	OrganizationID  uuid.UUID            `json:"organization_id,omitempty"`
+	AgentsReporting int                  `json:"agents_reporting"`
+	Tools           []MissingToolSummary `json:"tools"`
+	GeneratedAt     time.Time            `json:"generated_at"`
+}
+
+// MissingToolSummary describes the agents missing one tool
+type MissingToolSummary struct {
+	Tool         string             `json:"tool"`
+	MissingCount int                `json:"missing_count"`
+	Agents       []MissingToolAgent `json:"agents"`
+}
+
+// MissingToolAgent is an agent that lacks a tool
+type MissingToolAgent struct {
+	AgentID  uuid.UUID `json:"agent_id"`
+	Name     string    `json:"name"`
+	Hostname string    `json:"hostname"`
+}
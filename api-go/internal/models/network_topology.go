@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Topology node types
+const (
+	TopologyNodeAgent       = "agent"        // A host running an agent
+	TopologyNodeNetworkHost = "network_host" // A host agents found on the network
+)
+
+// TopologyGraph is a directed network graph: an edge from one node to
+// another means the first can reach the second. It is what topology export
+// produces and import accepts, and carries the results of analyzing it.
+type TopologyGraph struct {
+	ID            string         `json:"id,omitempty"`
+	Label         string         `json:"label,omitempty"`
+	Nodes         []TopologyNode `json:"nodes"`
+	Edges         []TopologyEdge `json:"edges"`
+	CriticalPaths []TopologyPath `json:"critical_paths"`
+	RiskDecay     float64        `json:"risk_decay"` // Share of a node's risk passed on per hop by the analysis
+	AnalyzedAt    time.Time      `json:"analyzed_at"`
+}
+
+// TopologyNode is a host in a topology graph. RiskScore is from 0 to 100, as
+// host risk scores are.
+type TopologyNode struct {
+	ID          string  `json:"id"`
+	Label       string  `json:"label,omitempty"`
+	Type        string  `json:"type,omitempty"` // agent, network_host, or anything an imported graph uses
+	IPAddress   string  `json:"ip_address,omitempty"`
+	OS          string  `json:"os,omitempty"`
+	RiskScore   float64 `json:"risk_score"`
+	Criticality string  `json:"criticality,omitempty"` // Asset criticality: low, medium, high or critical
+
+	// Set by analysis: the node's risk adjusted for the nodes that can reach it
+	AdjustedRiskScore float64 `json:"adjusted_risk_score"`
+	InheritedRisk     float64 `json:"inherited_risk,omitempty"`   // Risk reaching the node, before weighting by criticality
+	RiskSource        string  `json:"risk_source,omitempty"`      // Node the inherited risk originates from
+	RiskSourceHops    int     `json:"risk_source_hops,omitempty"` // Hops from RiskSource
+}
+
+// TopologyEdge is a directed connection between two nodes. Weight is the cost
+// of the hop for path analysis, 1 unless set.
+type TopologyEdge struct {
+	Source   string  `json:"source"`
+	Target   string  `json:"target"`
+	Weight   float64 `json:"weight"`
+	Critical bool    `json:"critical,omitempty"` // Set by analysis when a critical path runs over the edge
+}
+
+// TopologyPath is the cheapest path from a high-risk node to a high-value one
+type TopologyPath struct {
+	Source    string   `json:"source"`
+	Target    string   `json:"target"`
+	Nodes     []string `json:"nodes"`
+	Distance  float64  `json:"distance"`   // Sum of the edge weights
+	RiskScore float64  `json:"risk_score"` // Mean risk of the nodes on the path
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Result batch statuses
+const (
+	ResultBatchReceiving = "receiving" // Waiting for more chunks
+	ResultBatchComplete  = "complete"  // Every chunk arrived and the batch was handed off for processing
+)
+
+// ResultBatch tracks a scan too large for one submission, which the agent
+// sends as numbered chunks under a batch ID it chooses. Batches that stop
+// receiving chunks are dropped once they expire.
+type ResultBatch struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	AgentID        uuid.UUID  `json:"agent_id" gorm:"type:uuid;not null;index"`
+	OrganizationID uuid.UUID  `json:"organization_id" gorm:"type:uuid;index"`
+	TotalChunks    int        `json:"total_chunks" gorm:"not null"`
+	ReceivedChunks int        `json:"received_chunks"`
+	Status         string     `json:"status" gorm:"size:20;not null;index"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"index"` // Pushed back by every chunk that arrives
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ResultChunk is one part of a result batch. Chunks are keyed by batch and
+// sequence, so an agent can safely resend a chunk it is unsure was received.
+type ResultChunk struct {
+	BatchID   uuid.UUID              `json:"batch_id" gorm:"type:uuid;primaryKey"`
+	Sequence  int                    `json:"sequence" gorm:"primaryKey;autoIncrement:false"`
+	Checksum  string                 `json:"checksum" gorm:"size:64;not null"`              // SHA-256 of the chunk's results and metadata
+	Results   []AgentScanResult      `json:"results" gorm:"type:bytea;serializer:gzipjson"` // See repository.CompressedJSONSerializer
+	Metadata  map[string]interface{} `json:"metadata" gorm:"type:bytea;serializer:gzipjson"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// ResultChunkInfo places a result submission within a batch. Sequence counts
+// from 0 to Total-1.
+type ResultChunkInfo struct {
+	BatchID  string `json:"batch_id" binding:"required"`
+	Sequence int    `json:"sequence"`
+	Total    int    `json:"total" binding:"required"`
+}
+
+// ResultBatchStatus is returned to the agent for every chunk it submits
+type ResultBatchStatus struct {
+	BatchID   uuid.UUID `json:"batch_id"`
+	Received  int       `json:"received"`
+	Total     int       `json:"total"`
+	Complete  bool      `json:"complete"`
+	Duplicate bool      `json:"duplicate"` // The chunk had already been received; nothing changed
+}
+
+// Outcomes of a result submitted in a batch of several scan results
+const (
+	ResultItemProcessed = "processed"
+	ResultItemFailed    = "failed"
+)
+
+// ResultBatchItemStatus reports what became of one result in a batch of
+// several scan results, at its Index in the request
+type ResultBatchItemStatus struct {
+	Index    int       `json:"index"`
+	ResultID uuid.UUID `json:"result_id"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Formats third-party scan reports are imported from
+const (
+	ImportFormatTrivy = "trivy" // Trivy's JSON report, schema version 2
+	ImportFormatSARIF = "sarif" // SARIF 2.1.0, as written by Grype, Trivy and most code scanners
+)
+
+// ImportFormats are the formats third-party scan reports are imported from
+var ImportFormats = []string{ImportFormatTrivy, ImportFormatSARIF}
+
+// ImportSource marks a finding imported from a third-party scanner's report,
+// rather than found by one of the agent's own scanners
+type ImportSource struct {
+	ImportID uuid.UUID `json:"import_id"`
+	SourceID string    `json:"source_id,omitempty"` // Import source the asset was named by, when not by hostname
+	Format   string    `json:"format"`
+	Scanner  string    `json:"scanner"`            // Tool that wrote the report, such as Trivy or Grype
+	Artifact string    `json:"artifact,omitempty"` // What it scanned, such as an image or a path
+}
+
+// ScanImport records a third-party scan report imported into an asset. The
+// asset is an agent found by hostname, or one registered for an import
+// source, such as a CI pipeline, that no agent runs on.
+type ScanImport struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	AgentID        uuid.UUID `json:"agent_id" gorm:"type:uuid;not null;index"`
+	SourceID       string    `json:"source_id,omitempty" gorm:"size:255"`
+	Hostname       string    `json:"hostname,omitempty" gorm:"size:255"`
+	Format         string    `json:"format" gorm:"size:20;not null"`
+	Scanner        string    `json:"scanner" gorm:"size:100"`
+	Artifact       string    `json:"artifact,omitempty"`
+	Findings       int       `json:"findings"`
+	ImportedBy     string    `json:"imported_by,omitempty" gorm:"size:255"` // User or API key
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scanners an agent's scan scope can enable
+const (
+	ScannerSoftware  = "software"
+	ScannerSystem    = "system"
+	ScannerConfig    = "config"
+	ScannerNetwork   = "network"
+	ScannerAIML      = "aiml"
+	ScannerContainer = "container"
+)
+
+// ScanScopeScanners lists every scanner a scan scope can name
+var ScanScopeScanners = []string{ScannerSoftware, ScannerSystem, ScannerConfig, ScannerNetwork, ScannerAIML, ScannerContainer}
+
+// DefaultScanScopeScanners run on agents without a scan scope of their own
+var DefaultScanScopeScanners = []string{ScannerSoftware, ScannerSystem, ScannerConfig, ScannerNetwork}
+
+// AgentScanScope selects which scanners an agent runs and, for scanners that
+// walk the filesystem, the paths they cover, so a fleet can be scanned
+// according to each host's role: a GPU box AI/ML-scanned, a database server not
+type AgentScanScope struct {
+	AgentID        uuid.UUID           `json:"agent_id" gorm:"type:uuid;primaryKey"`
+	OrganizationID uuid.UUID           `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Scanners       []string            `json:"scanners" gorm:"type:jsonb;serializer:json"`
+	Paths          map[string][]string `json:"paths,omitempty" gorm:"type:jsonb;serializer:json"` // Scanner -> root paths it scans
+	Version        int                 `json:"version" gorm:"not null;default:1"`
+	Default        bool                `json:"default" gorm:"-"` // No scope is set; the agent runs the default scanners
+	UpdatedBy      string              `json:"updated_by,omitempty" gorm:"size:255"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// UpdateScanScopeRequest replaces an agent's scan scope
+type UpdateScanScopeRequest struct {
+	Scanners  []string            `json:"scanners" binding:"required"`
+	Paths     map[string][]string `json:"paths"`
+	UpdatedBy string              `json:"-"` // The user setting it, set from the caller
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SuppressionRule suppresses an organization's findings that are accepted
+// risks or false positives. A finding matches when every matcher the rule
+// sets matches it: its CVE, its package (and version, when the rule names
+// one), its finding key, or the agent (asset) it is on. Matched findings are
+// still tracked but excluded from default lists and counts, and reopen once
+// the rule expires or is deleted.
+type SuppressionRule struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID  `json:"organization_id" gorm:"type:uuid;not null;index"`
+	CVEID          string     `json:"cve_id,omitempty" gorm:"size:50"`
+	PackageName    string     `json:"package_name,omitempty" gorm:"size:255"`
+	PackageVersion string     `json:"package_version,omitempty" gorm:"size:100"` // Only with a package; empty matches every version
+	FindingKey     string     `json:"finding_key,omitempty" gorm:"size:64"`
+	AgentID        *uuid.UUID `json:"agent_id,omitempty" gorm:"type:uuid"`
+	Reason         string     `json:"reason" gorm:"type:text;not null"`
+	Owner          string     `json:"owner" gorm:"size:255;not null"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" gorm:"index"` // Never, when nil
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the rule suppresses findings at the given time
+func (r *SuppressionRule) Active(at time.Time) bool {
+	return r.ExpiresAt == nil || at.Before(*r.ExpiresAt)
+}
+
+// SuppressionRuleRequest creates a suppression rule
+type SuppressionRuleRequest struct {
+	CVEID          string     `json:"cve_id" binding:"max=50"`
+	PackageName    string     `json:"package_name" binding:"max=255"`
+	PackageVersion string     `json:"package_version" binding:"max=100"`
+	FindingKey     string     `json:"finding_key" binding:"max=64"`
+	AgentID        *uuid.UUID `json:"agent_id"`
+	Reason         string     `json:"reason" binding:"required"`
+	Owner          string     `json:"-"` // The user adding it, set from the caller
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
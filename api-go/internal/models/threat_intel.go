@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Threat intelligence feed kinds
+const (
+	ThreatIntelFeedSTIX = "stix" // STIX 2.1 objects from a TAXII 2.1 collection
+	ThreatIntelFeedMISP = "misp" // Vulnerability attributes from a MISP instance
+	ThreatIntelFeedJSON = "json" // A list of CVEs with context, in ZeroTrace's own format
+)
+
+// ThreatIntelFeed is an organization's own or commercial threat intelligence
+// source. Its CVE indicators are fetched on a schedule and cached, and tag
+// the organization's findings for those CVEs, raising their priority.
+type ThreatIntelFeed struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	OrganizationID  uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Name            string    `json:"name" gorm:"size:255;not null"`
+	Kind            string    `json:"kind" gorm:"size:20;not null"`
+	URL             string    `json:"url" gorm:"size:1000;not null"`        // TAXII API root, MISP base URL or JSON document
+	Collection      string    `json:"collection,omitempty" gorm:"size:255"` // TAXII collection ID, for stix feeds
+	Enabled         bool      `json:"enabled" gorm:"default:true"`
+	RefreshInterval string    `json:"refresh_interval" gorm:"size:50;not null"` // Go duration, e.g. "1h"
+
+	// Credentials: a username and password for basic authentication, or an
+	// API key sent as MISP's Authorization header or as a bearer token
+	Username string `json:"username,omitempty" gorm:"size:255"`
+	Password string `json:"-" gorm:"size:500"`
+	APIKey   string `json:"-" gorm:"size:500"`
+
+	// State
+	LastFetchedAt  *time.Time `json:"last_fetched_at,omitempty"`
+	LastSuccessAt  *time.Time `json:"last_success_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+	Failures       int        `json:"failures"` // Consecutive failed fetches; retries back off while the feed is down
+	IndicatorCount int        `json:"indicator_count"`
+	NextFetchAt    *time.Time `json:"next_fetch_at,omitempty" gorm:"index"`
+	Stale          bool       `json:"stale" gorm:"-"` // The last fetch failed; cached indicators are still applied
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ThreatIntelFeedRequest creates or replaces a threat intelligence feed
+type ThreatIntelFeedRequest struct {
+	Name            string `json:"name" binding:"required,max=255"`
+	Kind            string `json:"kind" binding:"required,oneof=stix misp json"`
+	URL             string `json:"url" binding:"required,url"`
+	Collection      string `json:"collection"`
+	Enabled         bool   `json:"enabled"`
+	RefreshInterval string `json:"refresh_interval"` // Defaults to 1h
+	Username        string `json:"username"`
+	Password        string `json:"password"` // Optional on update; keeps the stored secret when empty
+	APIKey          string `json:"api_key"`  // Same
+}
+
+// ThreatIntelIndicator is one CVE a feed reported, as last fetched
+type ThreatIntelIndicator struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	FeedID         uuid.UUID `json:"feed_id" gorm:"type:uuid;not null;index"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	CVEID          string    `json:"cve_id" gorm:"size:50;not null;index"`
+	Context        string    `json:"context" gorm:"type:text"` // What the feed says, e.g. the campaign exploiting the CVE
+	Confidence     int       `json:"confidence"`               // 0-100 as the feed reports it; 0 when it does not
+	FetchedAt      time.Time `json:"fetched_at"`
+}
+
+// ThreatIntelMatch tags a finding with what one of its organization's feeds
+// reports about its CVE
+type ThreatIntelMatch struct {
+	Feed       string `json:"feed"`
+	Context    string `json:"context"`
+	Confidence int    `json:"confidence,omitempty"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -16,6 +17,10 @@ type VulnerabilityV2 struct {
 	DiscoveredAt         time.Time              `json:"discovered_at" db:"discovered_at"`
 	LastSeen             time.Time              `json:"last_seen" db:"last_seen"`
 	RiskScore            float64                `json:"risk_score" db:"risk_score"`
+	CVSSScore            *float64               `json:"cvss_score,omitempty" db:"cvss_score"`
+	EnvironmentalScore   *float64               `json:"environmental_score,omitempty" db:"environmental_score"` // CVSS score under the organization's security requirements
+	EPSSScore            *float64               `json:"epss_score" db:"epss_score"`                             // nil without EPSS data
+	EPSSPercentile       *float64               `json:"epss_percentile" db:"epss_percentile"`
 	ExploitComplexity    string                 `json:"exploit_complexity" db:"exploit_complexity"`
 	AttackVector         string                 `json:"attack_vector" db:"attack_vector"`
 	ComplianceFrameworks []string               `json:"compliance_frameworks" db:"compliance_frameworks"`
@@ -24,10 +29,24 @@ type VulnerabilityV2 struct {
 	Tags                 []string               `json:"tags" db:"tags"`
 	Metadata             map[string]interface{} `json:"metadata" db:"metadata"`
 	EnrichmentData       map[string]interface{} `json:"enrichment_data" db:"enrichment_data"`
+	Suppressed           bool                   `json:"suppressed" db:"suppressed"`
+	SuppressedBy         string                 `json:"suppressed_by,omitempty" db:"suppressed_by"` // Suppression rule, or container allowlist rule
 	CreatedAt            time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt            time.Time              `json:"updated_at" db:"updated_at"`
 }
 
+// Detail returns a value from the vulnerability's metadata, or its
+// enrichment data when the metadata doesn't have it, such as the cve_id and
+// package_name details scanners attach to findings
+func (v *VulnerabilityV2) Detail(key string) string {
+	for _, details := range []map[string]interface{}{v.Metadata, v.EnrichmentData} {
+		if value, ok := details[key]; ok && value != nil {
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
 // NetworkFinding represents a network security finding
 type NetworkFinding struct {
 	ID             string                 `json:"id" db:"id"`
@@ -203,6 +222,7 @@ type ContainerFinding struct {
 	Remediation  string                 `json:"remediation" db:"remediation"`
 	DiscoveredAt time.Time              `json:"discovered_at" db:"discovered_at"`
 	Status       string                 `json:"status" db:"status"`
+	SuppressedBy string                 `json:"suppressed_by,omitempty" db:"suppressed_by"` // Allowlist rule that marked the finding expected
 	Metadata     map[string]interface{} `json:"metadata" db:"metadata"`
 	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time              `json:"updated_at" db:"updated_at"`
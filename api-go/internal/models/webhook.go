@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"   // Not yet delivered; retried at NextAttemptAt
+	WebhookDeliveryDelivered = "delivered" // The receiver answered with a 2xx status
+	WebhookDeliveryFailed    = "failed"    // Gave up after the last attempt
+)
+
+// Webhook formats: what is posted to a webhook's URL
+const (
+	WebhookFormatJSON  = "json"  // The vulnerability.found event
+	WebhookFormatSlack = "slack" // A Slack Block Kit message, for an incoming webhook
+	WebhookFormatTeams = "teams" // A Microsoft Teams MessageCard, for an incoming webhook
+)
+
+// Webhook is a customer-supplied URL an organization's critical and high
+// findings are posted to, as vulnerability.found events or chat messages
+type Webhook struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Name           string    `json:"name" gorm:"size:255;not null"`
+	URL            string    `json:"url" gorm:"size:2048;not null"`
+	Format         string    `json:"format" gorm:"size:20;not null;default:json"`
+	MinSeverity    string    `json:"min_severity" gorm:"size:20;not null"` // critical, or high for critical and high findings
+	Enabled        bool      `json:"enabled" gorm:"default:true"`
+	Secret         string    `json:"-" gorm:"size:500"` // Signs each body with HMAC-SHA256 when set
+	HasSecret      bool      `json:"has_secret" gorm:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// WebhookRequest creates or replaces a webhook
+type WebhookRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	URL         string `json:"url" binding:"required,url,max=2048"`
+	Format      string `json:"format" binding:"omitempty,oneof=json slack teams"`    // Defaults to json
+	MinSeverity string `json:"min_severity" binding:"omitempty,oneof=critical high"` // Defaults to high
+	Enabled     bool   `json:"enabled"`
+	Secret      string `json:"secret" binding:"max=500"` // Optional on update; keeps the stored secret when empty
+}
+
+// WebhookDelivery is one event sent, or being sent, to a webhook. The
+// deliveries of a webhook are its delivery log.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	WebhookID      uuid.UUID  `json:"webhook_id" gorm:"type:uuid;not null;index"`
+	OrganizationID uuid.UUID  `json:"organization_id" gorm:"type:uuid;not null;index"`
+	EventID        uuid.UUID  `json:"event_id" gorm:"type:uuid;not null"`
+	EventType      string     `json:"event_type" gorm:"size:100;not null"`
+	SchemaVersion  string     `json:"schema_version" gorm:"size:20;not null"`
+	Payload        string     `json:"-" gorm:"type:text;not null"` // The event body, signed and sent as is on every attempt
+	Status         string     `json:"status" gorm:"size:20;not null;index"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"` // HTTP status of the last attempt
+	Error          string     `json:"error,omitempty" gorm:"type:text"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" gorm:"index"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// VulnerabilityFoundNotification is posted to an organization's webhooks
+// when a scan reports critical or high findings
+type VulnerabilityFoundNotification struct {
+	AgentID  uuid.UUID                 `json:"agent_id"`
+	Hostname string                    `json:"hostname,omitempty"`
+	Findings []VulnerabilityFoundEntry `json:"findings"`
+	FoundAt  time.Time                 `json:"found_at"`
+}
+
+// VulnerabilityFoundEntry is one finding in a vulnerability.found event
+type VulnerabilityFoundEntry struct {
+	ID             string   `json:"id,omitempty"`
+	Title          string   `json:"title"`
+	Severity       string   `json:"severity"`
+	CVEID          string   `json:"cve_id,omitempty"`
+	CVSSScore      *float64 `json:"cvss_score,omitempty"`
+	PackageName    string   `json:"package_name,omitempty"`
+	PackageVersion string   `json:"package_version,omitempty"`
+	Location       string   `json:"location,omitempty"`
+}
@@ -0,0 +1,141 @@
+package monitoring
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/queue"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners probing random
+// paths don't add a series each
+const unmatchedRoute = "unmatched"
+
+// AgentStatusCounter reports how many agents are in each status
+type AgentStatusCounter interface {
+	AgentStatusCounts() map[string]int
+}
+
+// Metrics is the API server's Prometheus registry. Request metrics are
+// recorded by Middleware; everything else is read when /metrics is scraped.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+// NewMetrics creates a registry with request metrics and the Go runtime and
+// process collectors
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "zerotrace",
+			Subsystem: "api",
+			Name:      "requests_total",
+			Help:      "API requests by method, route template and status code",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "zerotrace",
+			Subsystem: "api",
+			Name:      "request_duration_seconds",
+			Help:      "API request latency by method and route template",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Middleware records the count and latency of every request, labelled with
+// the route template (/api/agents/:id) rather than the path, so IDs don't
+// create a series per resource
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		m.requestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDB exports the database connection pool's stats
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, "zerotrace"))
+}
+
+// RegisterScheduler exports how many agent results are waiting for a
+// processing slot and how many hold one
+func (m *Metrics) RegisterScheduler(scheduler *queue.FairScheduler) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "zerotrace",
+			Name:      "scan_queue_depth",
+			Help:      "Agent results waiting for a processing slot",
+		}, func() float64 {
+			queued := 0
+			for _, org := range scheduler.Metrics().Organizations {
+				queued += org.Queued
+			}
+			return float64(queued)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "zerotrace",
+			Name:      "scan_queue_in_flight",
+			Help:      "Agent results being processed",
+		}, func() float64 {
+			return float64(scheduler.Metrics().Active)
+		}),
+	)
+}
+
+// RegisterAgents exports how many agents are online, degraded and offline
+func (m *Metrics) RegisterAgents(agents AgentStatusCounter) {
+	m.registry.MustRegister(&agentCollector{
+		agents: agents,
+		desc:   prometheus.NewDesc("zerotrace_agents", "Agents by status, derived from heartbeat age", []string{"status"}, nil),
+	})
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// agentCollector reads agent statuses at scrape time, reporting every status
+// so a status no agent is in reads 0 rather than disappearing
+type agentCollector struct {
+	agents AgentStatusCounter
+	desc   *prometheus.Desc
+}
+
+func (c *agentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *agentCollector) Collect(ch chan<- prometheus.Metric) {
+	counts := c.agents.AgentStatusCounts()
+	for _, status := range []string{models.AgentStatusOnline, models.AgentStatusDegraded, models.AgentStatusOffline} {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(counts[status]), status)
+	}
+}
@@ -0,0 +1,50 @@
+package monitoring
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/queue"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type agentCounts map[string]int
+
+func (c agentCounts) AgentStatusCounts() map[string]int { return c }
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewMetrics()
+	m.RegisterScheduler(queue.NewFairScheduler(4, 2, nil, nil))
+	m.RegisterAgents(agentCounts{models.AgentStatusOnline: 3, models.AgentStatusOffline: 1})
+
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/api/agents/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	for _, path := range []string{"/api/agents/1", "/api/agents/2", "/wp-login.php"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+
+	// Requests are counted per route template, not per path
+	assert.Contains(t, body, `zerotrace_api_requests_total{method="GET",route="/api/agents/:id",status="204"} 2`)
+	assert.Contains(t, body, `zerotrace_api_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `zerotrace_api_request_duration_seconds_count{method="GET",route="/api/agents/:id"} 2`)
+	assert.NotContains(t, body, "/api/agents/1")
+
+	assert.Contains(t, body, "zerotrace_scan_queue_depth 0")
+	assert.Contains(t, body, "zerotrace_scan_queue_in_flight 0")
+	assert.Contains(t, body, `zerotrace_agents{status="online"} 3`)
+	assert.Contains(t, body, `zerotrace_agents{status="degraded"} 0`)
+	assert.Contains(t, body, `zerotrace_agents{status="offline"} 1`)
+}
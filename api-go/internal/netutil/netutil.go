@@ -0,0 +1,29 @@
+// Package netutil checks addresses that callers supply, such as callback URLs
+// and bucket endpoints, so the API can't be pointed at its own network
+package netutil
+
+import (
+	"net"
+	"strings"
+)
+
+// IsPublicIP reports whether ip is reachable on the internet rather than
+// only from the API's own network
+func IsPublicIP(ip net.IP) bool {
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// IsPublicHost reports whether a URL's host doesn't name a loopback, private
+// or link-local host. Hostnames other than localhost aren't resolved, so
+// connections to them still need checking with IsPublicIP.
+func IsPublicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return IsPublicIP(ip)
+	}
+	return true
+}
@@ -0,0 +1,289 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// unassignedOrg is the scheduling bucket used for work without an organization
+const unassignedOrg = "unassigned"
+
+// FairScheduler hands out processing slots across organizations so that a
+// tenant with thousands of agents cannot starve a tenant with ten. Every
+// organization gets its own FIFO of waiters and a concurrency cap; free slots
+// are granted by walking the organizations round-robin, letting each take up
+// to its weight in slots before moving on to the next.
+type FairScheduler struct {
+	mu         sync.Mutex
+	maxActive  int
+	active     int
+	defaultCap int
+	caps       map[string]int
+	weights    map[string]int
+	orgs       map[string]*orgState
+	ring       []string
+	next       int
+}
+
+// orgState tracks the waiters and counters for a single organization
+type orgState struct {
+	waiters  []*slotWaiter
+	inFlight int
+	credit   int // grants left for this org in the current round
+
+	completed       int64
+	cancelled       int64
+	totalWait       time.Duration
+	maxWait         time.Duration
+	totalProcessing time.Duration
+	lastActivity    time.Time
+}
+
+// slotWaiter is a single pending Acquire call
+type slotWaiter struct {
+	ready    chan struct{}
+	granted  bool
+	enqueued time.Time
+}
+
+// OrgProcessingMetrics is a point-in-time view of one organization's processing
+type OrgProcessingMetrics struct {
+	OrganizationID  string    `json:"organization_id"`
+	InFlight        int       `json:"in_flight"`
+	Queued          int       `json:"queued"`
+	ConcurrencyCap  int       `json:"concurrency_cap"`
+	Weight          int       `json:"weight"`
+	Completed       int64     `json:"completed"`
+	Cancelled       int64     `json:"cancelled"`
+	AvgWaitMs       float64   `json:"avg_wait_ms"`
+	MaxWaitMs       float64   `json:"max_wait_ms"`
+	AvgProcessingMs float64   `json:"avg_processing_ms"`
+	LastActivity    time.Time `json:"last_activity"`
+}
+
+// SchedulerMetrics is a point-in-time view of the whole scheduler
+type SchedulerMetrics struct {
+	Active        int                    `json:"active"`
+	MaxActive     int                    `json:"max_active"`
+	DefaultOrgCap int                    `json:"default_org_cap"`
+	Organizations []OrgProcessingMetrics `json:"organizations"`
+}
+
+// NewFairScheduler creates a new fair scheduler.
+// maxActive bounds the total number of slots across all organizations and
+// defaultCap bounds a single organization; zero or less means unbounded.
+// caps and weights override the cap and round-robin weight per organization.
+func NewFairScheduler(maxActive, defaultCap int, caps, weights map[string]int) *FairScheduler {
+	if caps == nil {
+		caps = make(map[string]int)
+	}
+	if weights == nil {
+		weights = make(map[string]int)
+	}
+
+	return &FairScheduler{
+		maxActive:  maxActive,
+		defaultCap: defaultCap,
+		caps:       caps,
+		weights:    weights,
+		orgs:       make(map[string]*orgState),
+	}
+}
+
+// Acquire blocks until a processing slot is available for the organization
+// or the context is cancelled. The returned release function must be called
+// once the work is finished.
+func (s *FairScheduler) Acquire(ctx context.Context, orgID string) (func(), error) {
+	if orgID == "" {
+		orgID = unassignedOrg
+	}
+
+	w := &slotWaiter{
+		ready:    make(chan struct{}),
+		enqueued: time.Now(),
+	}
+
+	s.mu.Lock()
+	st := s.orgLocked(orgID)
+	st.waiters = append(st.waiters, w)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		start := time.Now()
+		var once sync.Once
+		return func() {
+			once.Do(func() { s.release(orgID, time.Since(start), true) })
+		}, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		granted := w.granted
+		if !granted {
+			s.removeWaiterLocked(st, w)
+		}
+		st.cancelled++
+		s.mu.Unlock()
+
+		// The slot was handed over while we were giving up; return it
+		if granted {
+			s.release(orgID, 0, false)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Metrics returns a snapshot of the scheduler and every organization it has seen
+func (s *FairScheduler) Metrics() SchedulerMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics := SchedulerMetrics{
+		Active:        s.active,
+		MaxActive:     s.maxActive,
+		DefaultOrgCap: s.defaultCap,
+		Organizations: make([]OrgProcessingMetrics, 0, len(s.orgs)),
+	}
+
+	for orgID, st := range s.orgs {
+		m := OrgProcessingMetrics{
+			OrganizationID: orgID,
+			InFlight:       st.inFlight,
+			Queued:         len(st.waiters),
+			ConcurrencyCap: s.capFor(orgID),
+			Weight:         s.weightFor(orgID),
+			Completed:      st.completed,
+			Cancelled:      st.cancelled,
+			MaxWaitMs:      float64(st.maxWait.Microseconds()) / 1000,
+			LastActivity:   st.lastActivity,
+		}
+		if granted := st.completed + int64(st.inFlight); granted > 0 {
+			m.AvgWaitMs = float64(st.totalWait.Microseconds()) / 1000 / float64(granted)
+		}
+		if st.completed > 0 {
+			m.AvgProcessingMs = float64(st.totalProcessing.Microseconds()) / 1000 / float64(st.completed)
+		}
+		metrics.Organizations = append(metrics.Organizations, m)
+	}
+
+	sort.Slice(metrics.Organizations, func(i, j int) bool {
+		return metrics.Organizations[i].OrganizationID < metrics.Organizations[j].OrganizationID
+	})
+
+	return metrics
+}
+
+// release returns a slot and hands it to the next eligible waiter
+func (s *FairScheduler) release(orgID string, processing time.Duration, completed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.orgLocked(orgID)
+	st.inFlight--
+	s.active--
+	st.lastActivity = time.Now()
+	if completed {
+		st.completed++
+		st.totalProcessing += processing
+	}
+
+	s.dispatchLocked()
+}
+
+// dispatchLocked grants free slots to waiting organizations in round-robin order
+func (s *FairScheduler) dispatchLocked() {
+	for s.maxActive <= 0 || s.active < s.maxActive {
+		orgID := s.pickLocked()
+		if orgID == "" {
+			return
+		}
+
+		st := s.orgs[orgID]
+		w := st.waiters[0]
+		st.waiters = st.waiters[1:]
+		st.inFlight++
+		s.active++
+
+		wait := time.Since(w.enqueued)
+		st.totalWait += wait
+		if wait > st.maxWait {
+			st.maxWait = wait
+		}
+		st.lastActivity = time.Now()
+
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// pickLocked returns the next organization that should receive a slot, or ""
+// if no organization with waiters is below its cap
+func (s *FairScheduler) pickLocked() string {
+	n := len(s.ring)
+	for i := 0; i < n; i++ {
+		idx := (s.next + i) % n
+		orgID := s.ring[idx]
+		st := s.orgs[orgID]
+
+		if len(st.waiters) == 0 || !s.belowCap(orgID, st) {
+			st.credit = 0
+			continue
+		}
+
+		if st.credit <= 0 {
+			st.credit = s.weightFor(orgID)
+		}
+		st.credit--
+
+		// Stay on this org until its weight is used up for the round
+		if st.credit == 0 {
+			s.next = (idx + 1) % n
+		} else {
+			s.next = idx
+		}
+		return orgID
+	}
+	return ""
+}
+
+// orgLocked returns the state for an organization, creating it if needed
+func (s *FairScheduler) orgLocked(orgID string) *orgState {
+	st, exists := s.orgs[orgID]
+	if !exists {
+		st = &orgState{}
+		s.orgs[orgID] = st
+		s.ring = append(s.ring, orgID)
+	}
+	return st
+}
+
+// removeWaiterLocked drops a waiter that gave up before being granted a slot
+func (s *FairScheduler) removeWaiterLocked(st *orgState, w *slotWaiter) {
+	for i, candidate := range st.waiters {
+		if candidate == w {
+			st.waiters = append(st.waiters[:i], st.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *FairScheduler) belowCap(orgID string, st *orgState) bool {
+	limit := s.capFor(orgID)
+	return limit <= 0 || st.inFlight < limit
+}
+
+func (s *FairScheduler) capFor(orgID string) int {
+	if limit, ok := s.caps[orgID]; ok {
+		return limit
+	}
+	return s.defaultCap
+}
+
+func (s *FairScheduler) weightFor(orgID string) int {
+	if weight, ok := s.weights[orgID]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
@@ -0,0 +1,112 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFairScheduler_PerOrgCap(t *testing.T) {
+	s := NewFairScheduler(0, 2, nil, nil)
+	ctx := context.Background()
+
+	r1, err := s.Acquire(ctx, "big-org")
+	require.NoError(t, err)
+	r2, err := s.Acquire(ctx, "big-org")
+	require.NoError(t, err)
+
+	// Third slot for the same org must wait for a release
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(timeoutCtx, "big-org")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Another org is unaffected by big-org's cap
+	r3, err := s.Acquire(ctx, "small-org")
+	require.NoError(t, err)
+
+	r1()
+	r2()
+	r3()
+
+	metrics := s.Metrics()
+	assert.Equal(t, 0, metrics.Active)
+	require.Len(t, metrics.Organizations, 2)
+	assert.Equal(t, "big-org", metrics.Organizations[0].OrganizationID)
+	assert.Equal(t, int64(2), metrics.Organizations[0].Completed)
+	assert.Equal(t, int64(1), metrics.Organizations[0].Cancelled)
+	assert.Equal(t, int64(1), metrics.Organizations[1].Completed)
+}
+
+func TestFairScheduler_RoundRobinAcrossOrgs(t *testing.T) {
+	s := NewFairScheduler(1, 0, nil, nil)
+	ctx := context.Background()
+
+	// Occupy the only global slot, then queue a burst from one org
+	// followed by a single request from another
+	hold, err := s.Acquire(ctx, "big-org")
+	require.NoError(t, err)
+
+	order := make(chan string, 4)
+	start := func(orgID string) {
+		go func() {
+			release, err := s.Acquire(ctx, orgID)
+			if err != nil {
+				return
+			}
+			order <- orgID
+			release()
+		}()
+	}
+
+	for i := 0; i < 3; i++ {
+		start("big-org")
+		waitForQueued(t, s, "big-org", i+1)
+	}
+	start("small-org")
+	waitForQueued(t, s, "small-org", 1)
+
+	hold()
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, <-order)
+	}
+
+	// small-org must not wait behind big-org's whole backlog
+	assert.Contains(t, got[:2], "small-org")
+}
+
+func TestFairScheduler_WeightsAndOverrides(t *testing.T) {
+	s := NewFairScheduler(0, 1, map[string]int{"vip": 3}, map[string]int{"vip": 2})
+
+	metrics := s.Metrics()
+	assert.Empty(t, metrics.Organizations)
+
+	release, err := s.Acquire(context.Background(), "vip")
+	require.NoError(t, err)
+	defer release()
+
+	metrics = s.Metrics()
+	require.Len(t, metrics.Organizations, 1)
+	assert.Equal(t, 3, metrics.Organizations[0].ConcurrencyCap)
+	assert.Equal(t, 2, metrics.Organizations[0].Weight)
+	assert.Equal(t, 1, metrics.Organizations[0].InFlight)
+}
+
+func waitForQueued(t *testing.T, s *FairScheduler, orgID string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		for _, org := range s.Metrics().Organizations {
+			if org.OrganizationID == orgID && org.Queued >= want {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued requests from %s", want, orgID)
+}
@@ -52,6 +52,7 @@ type QueueProcessor struct {
 	cancel    context.CancelFunc
 	wg        sync.WaitGroup
 	metrics   *QueueMetrics
+	scheduler *FairScheduler
 }
 
 // QueueMetrics tracks processing metrics
@@ -64,7 +65,9 @@ type QueueMetrics struct {
 }
 
 // NewQueueProcessor creates a new queue processor
-func NewQueueProcessor(redis *redis.Client) *QueueProcessor {
+// Company batches are admitted through the scheduler so a single company's
+// backlog cannot monopolize enrichment and storage.
+func NewQueueProcessor(redis *redis.Client, scheduler *FairScheduler) *QueueProcessor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &QueueProcessor{
@@ -74,6 +77,7 @@ func NewQueueProcessor(redis *redis.Client) *QueueProcessor {
 		ctx:       ctx,
 		cancel:    cancel,
 		metrics:   &QueueMetrics{},
+		scheduler: scheduler,
 	}
 }
 
@@ -235,13 +239,28 @@ func (qp *QueueProcessor) processBatch(apps []AppData) {
 	// Group apps by company for efficient processing
 	companyGroups := qp.groupAppsByCompany(apps)
 
-	// Process each company's apps
+	// Process each company's apps, taking turns through the fair scheduler
+	var wg sync.WaitGroup
 	for companyID, companyApps := range companyGroups {
-		go qp.processCompanyApps(companyID, companyApps)
+		wg.Add(1)
+		go func(companyID string, companyApps []AppData) {
+			defer wg.Done()
+
+			if qp.scheduler != nil {
+				release, err := qp.scheduler.Acquire(qp.ctx, companyID)
+				if err != nil {
+					log.Printf("Skipping %d apps for company %s: %v", len(companyApps), companyID, err)
+					return
+				}
+				defer release()
+			}
+
+			qp.processCompanyApps(companyID, companyApps)
+		}(companyID, companyApps)
 	}
 
 	// Wait for all companies to complete
-	// In a real implementation, you'd use a WaitGroup here
+	wg.Wait()
 
 	duration := time.Since(start)
 	log.Printf("Processed batch of %d apps in %v", len(apps), duration)
@@ -364,6 +383,13 @@ func (qp *QueueProcessor) reportMetrics() {
 
 	log.Printf("Queue Metrics - Processed: %d total, %d today, Errors: %d, Queue Size: %d",
 		metrics.processedTotal, metrics.processedToday, metrics.errorsTotal, metrics.queueSize)
+
+	if qp.scheduler != nil {
+		for _, org := range qp.scheduler.Metrics().Organizations {
+			log.Printf("Queue Metrics - Company %s: %d in flight, %d queued, %d completed, avg wait %.1fms",
+				org.OrganizationID, org.InFlight, org.Queued, org.Completed, org.AvgWaitMs)
+		}
+	}
 }
 
 // cleanupRoutine performs periodic cleanup
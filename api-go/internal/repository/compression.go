@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// CompressedJSONSerializer is the GORM serializer for large JSON columns that
+// may be stored gzip-compressed. Such columns are bytea:
+//
+//	Results map[string]any `gorm:"type:bytea;serializer:gzipjson"`
+//
+// Values are compressed on write when compression is enabled and the encoded
+// JSON reaches the threshold, and decompressed on read whatever the setting,
+// so it can be turned on or off without rewriting existing rows.
+const CompressedJSONSerializer = "gzipjson"
+
+// gzipMagic starts every gzip stream. JSON text never starts with it, so
+// plain and compressed values can share a column.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// CompressionStats reports the storage saved on one compressed column since the API started
+type CompressionStats struct {
+	Column      string  `json:"column"`       // table.column
+	Values      int64   `json:"values"`       // Values written
+	Compressed  int64   `json:"compressed"`   // Values written compressed
+	RawBytes    int64   `json:"raw_bytes"`    // Size of the values as JSON
+	StoredBytes int64   `json:"stored_bytes"` // Size of the values as written
+	SavedBytes  int64   `json:"saved_bytes"`
+	Ratio       float64 `json:"ratio"` // StoredBytes / RawBytes
+}
+
+type gzipJSONSerializer struct {
+	mu        sync.Mutex
+	enabled   bool
+	threshold int
+	stats     map[string]*CompressionStats
+}
+
+var compression = &gzipJSONSerializer{stats: make(map[string]*CompressionStats)}
+
+func init() {
+	schema.RegisterSerializer(CompressedJSONSerializer, compression)
+}
+
+// ConfigureCompression sets whether compressed JSON columns are written
+// compressed, and the smallest encoded value, in bytes, that is
+func ConfigureCompression(enabled bool, threshold int) {
+	compression.mu.Lock()
+	defer compression.mu.Unlock()
+	compression.enabled = enabled
+	compression.threshold = threshold
+}
+
+// GetCompressionStats reports the storage saved on each compressed column, by column
+func GetCompressionStats() []CompressionStats {
+	compression.mu.Lock()
+	defer compression.mu.Unlock()
+
+	stats := make([]CompressionStats, 0, len(compression.stats))
+	for _, s := range compression.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Column < stats[j].Column })
+	return stats
+}
+
+// Scan implements schema.SerializerInterface
+func (s *gzipJSONSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var data []byte
+		switch v := dbValue.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			return fmt.Errorf("unsupported value %T for compressed column %s", dbValue, field.DBName)
+		}
+
+		if bytes.HasPrefix(data, gzipMagic) {
+			reader, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return fmt.Errorf("failed to decompress %s: %w", field.DBName, err)
+			}
+			data, err = io.ReadAll(reader)
+			if err != nil {
+				return fmt.Errorf("failed to decompress %s: %w", field.DBName, err)
+			}
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, fieldValue.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value implements schema.SerializerInterface
+func (s *gzipJSONSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	data, err := json.Marshal(fieldValue)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	enabled, threshold := s.enabled, s.threshold
+	s.mu.Unlock()
+
+	stored := data
+	if enabled && len(data) >= threshold {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		// Small or already dense values can grow; keep those as they are
+		if buf.Len() < len(data) {
+			stored = buf.Bytes()
+		}
+	}
+
+	s.record(field.Schema.Table+"."+field.DBName, len(data), len(stored))
+	return stored, nil
+}
+
+func (s *gzipJSONSerializer) record(column string, raw, stored int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.stats[column]
+	if !ok {
+		stats = &CompressionStats{Column: column}
+		s.stats[column] = stats
+	}
+	stats.Values++
+	if stored < raw {
+		stats.Compressed++
+	}
+	stats.RawBytes += int64(raw)
+	stats.StoredBytes += int64(stored)
+	stats.SavedBytes = stats.RawBytes - stats.StoredBytes
+	if stats.RawBytes > 0 {
+		stats.Ratio = float64(stats.StoredBytes) / float64(stats.RawBytes)
+	}
+}
+
+// migrateCompressedColumns converts JSON columns that have become compressed
+// columns from jsonb to bytea in place, keeping their rows. AutoMigrate
+// cannot, as Postgres has no implicit cast between the two.
+func migrateCompressedColumns(db *gorm.DB, models ...interface{}) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if !db.Migrator().HasTable(stmt.Schema.Table) {
+			continue
+		}
+		columnTypes, err := db.Migrator().ColumnTypes(model)
+		if err != nil {
+			return err
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if field.TagSettings["SERIALIZER"] != CompressedJSONSerializer {
+				continue
+			}
+			for _, column := range columnTypes {
+				if column.Name() != field.DBName || column.DatabaseTypeName() != "JSONB" {
+					continue
+				}
+				err := db.Exec(fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE bytea USING convert_to(%q::text, 'UTF8')`,
+					stmt.Schema.Table, field.DBName, field.DBName)).Error
+				if err != nil {
+					return fmt.Errorf("failed to convert %s.%s to bytea: %w", stmt.Schema.Table, field.DBName, err)
+				}
+			}
+		}
+	}
+	return nil
+}
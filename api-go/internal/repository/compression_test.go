@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func scanResultsField(t *testing.T) *schema.Field {
+	t.Helper()
+	s, err := schema.Parse(&models.Scan{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	return s.LookUpField("results")
+}
+
+func TestCompressedJSONRoundTrip(t *testing.T) {
+	defer ConfigureCompression(false, 0)
+	field := scanResultsField(t)
+	large := map[string]any{"findings": strings.Repeat("CVE-2024-0001 openssl ", 200)}
+	small := map[string]any{"findings": "none"}
+
+	ConfigureCompression(true, 256)
+
+	stored, err := compression.Value(context.Background(), field, reflect.Value{}, large)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(stored.([]byte), gzipMagic), "large value should be compressed")
+
+	plain, err := compression.Value(context.Background(), field, reflect.Value{}, small)
+	require.NoError(t, err)
+	assert.Equal(t, `{"findings":"none"}`, string(plain.([]byte)), "value below the threshold should be stored as JSON")
+
+	// Both read back the same, as do rows written before compression was enabled
+	for value, want := range map[string]map[string]any{"compressed": large, "plain": small} {
+		dbValue := stored
+		if value == "plain" {
+			dbValue = plain
+		}
+		var scan models.Scan
+		require.NoError(t, compression.Scan(context.Background(), field, reflect.ValueOf(&scan).Elem(), dbValue))
+		assert.Equal(t, want, scan.Results, value)
+	}
+
+	var total CompressionStats
+	for _, s := range GetCompressionStats() {
+		if s.Column == "scans.results" {
+			total = s
+		}
+	}
+	assert.Equal(t, int64(2), total.Values)
+	assert.Equal(t, int64(1), total.Compressed)
+	assert.Greater(t, total.SavedBytes, int64(0))
+}
@@ -1,48 +1,143 @@
 package repository
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
 	"time"
 
 	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/storage"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// ConfigFileRepository handles config file database operations
+// configFileInDB is the storage backend of config files whose content is kept in the database
+const configFileInDB = "db"
+
+// ConfigFileRepository handles config file database operations. Config file
+// content is kept either in the database or in a blob store; each file
+// records where, so changing the backend leaves existing files readable.
+// Every method is scoped to one organization.
 type ConfigFileRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	backend string
+	store   storage.BlobStore
 }
 
-// NewConfigFileRepository creates a new config file repository
-func NewConfigFileRepository(db *gorm.DB) *ConfigFileRepository {
-	return &ConfigFileRepository{db: db}
+// NewConfigFileRepository creates a new config file repository. New uploads
+// keep their content in store under the given backend name, or in the
+// database when store is nil.
+func NewConfigFileRepository(db *gorm.DB, backend string, store storage.BlobStore) *ConfigFileRepository {
+	if store == nil {
+		backend = configFileInDB
+	}
+	return &ConfigFileRepository{db: db, backend: backend, store: store}
 }
 
-// Create creates a new config file
-func (r *ConfigFileRepository) Create(configFile *models.ConfigFile) error {
+// Create creates a new config file for an organization, writing its content
+// to the configured backend
+func (r *ConfigFileRepository) Create(orgID uuid.UUID, configFile *models.ConfigFile) error {
+	if err := claimForOrg(orgID, &configFile.CompanyID); err != nil {
+		return err
+	}
 	configFile.ID = uuid.New()
 	configFile.CreatedAt = time.Now()
 	configFile.UpdatedAt = time.Now()
-	return r.db.Create(configFile).Error
+	configFile.StorageBackend = r.backend
+	if r.store == nil {
+		return r.db.Create(configFile).Error
+	}
+
+	key := path.Join(configFile.CompanyID.String(), configFile.FileHash)
+	if err := r.store.PutObject(context.Background(), key, configFile.FileContent, configFile.MimeType); err != nil {
+		return fmt.Errorf("failed to store config file content: %w", err)
+	}
+	configFile.StorageKey = key
+
+	content := configFile.FileContent
+	configFile.FileContent = nil
+	err := r.db.Create(configFile).Error
+	configFile.FileContent = content
+	if err != nil {
+		if delErr := r.store.DeleteObject(context.Background(), key); delErr != nil {
+			log.Printf("Failed to remove content of unsaved config file %s: %v", key, delErr)
+		}
+		return err
+	}
+	return nil
 }
 
-// GetByID retrieves a config file by ID
-func (r *ConfigFileRepository) GetByID(id uuid.UUID) (*models.ConfigFile, error) {
+// LoadContent fills in a config file's content when it is kept outside the database
+func (r *ConfigFileRepository) LoadContent(orgID uuid.UUID, configFile *models.ConfigFile) error {
+	if err := checkOrg(orgID, configFile.CompanyID); err != nil {
+		return err
+	}
+	if len(configFile.FileContent) > 0 || !r.inStore(configFile) {
+		return nil
+	}
+	if r.store == nil {
+		return fmt.Errorf("config file %s is stored in %s, which is not configured", configFile.ID, configFile.StorageBackend)
+	}
+	content, err := r.store.GetObject(context.Background(), configFile.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to read config file content: %w", err)
+	}
+	configFile.FileContent = content
+	return nil
+}
+
+// OpenContent streams a config file's content from wherever it is kept,
+// returning it and its size. The caller must close it.
+func (r *ConfigFileRepository) OpenContent(ctx context.Context, orgID uuid.UUID, configFile *models.ConfigFile) (io.ReadCloser, int64, error) {
+	if err := checkOrg(orgID, configFile.CompanyID); err != nil {
+		return nil, 0, err
+	}
+	if !r.inStore(configFile) {
+		return io.NopCloser(bytes.NewReader(configFile.FileContent)), int64(len(configFile.FileContent)), nil
+	}
+	if r.store == nil {
+		return nil, 0, fmt.Errorf("config file %s is stored in %s, which is not configured", configFile.ID, configFile.StorageBackend)
+	}
+	return r.store.OpenObject(ctx, configFile.StorageKey)
+}
+
+// DeleteContent removes a config file's content from the blob store, if it is kept there
+func (r *ConfigFileRepository) DeleteContent(orgID uuid.UUID, configFile *models.ConfigFile) error {
+	if err := checkOrg(orgID, configFile.CompanyID); err != nil {
+		return err
+	}
+	if !r.inStore(configFile) || r.store == nil {
+		return nil
+	}
+	return r.store.DeleteObject(context.Background(), configFile.StorageKey)
+}
+
+// inStore reports whether a config file's content is kept outside the database
+func (r *ConfigFileRepository) inStore(configFile *models.ConfigFile) bool {
+	return configFile.StorageBackend != "" && configFile.StorageBackend != configFileInDB
+}
+
+// GetByID retrieves an organization's config file by ID
+func (r *ConfigFileRepository) GetByID(orgID, id uuid.UUID) (*models.ConfigFile, error) {
 	var configFile models.ConfigFile
-	err := r.db.Where("id = ?", id).First(&configFile).Error
+	err := r.db.Scopes(ForOrg(orgID)).Where("id = ?", id).First(&configFile).Error
 	if err != nil {
 		return nil, err
 	}
 	return &configFile, nil
 }
 
-// GetByCompanyID retrieves config files by company ID with pagination and filters
-func (r *ConfigFileRepository) GetByCompanyID(companyID uuid.UUID, page, limit int, filters map[string]interface{}) ([]models.ConfigFile, int64, error) {
+// GetByCompanyID retrieves an organization's config files with pagination and filters
+func (r *ConfigFileRepository) GetByCompanyID(orgID uuid.UUID, page, limit int, filters map[string]interface{}) ([]models.ConfigFile, int64, error) {
 	var configFiles []models.ConfigFile
 	var total int64
 
-	query := r.db.Model(&models.ConfigFile{}).Where("company_id = ?", companyID)
+	query := r.db.Model(&models.ConfigFile{}).Scopes(ForOrg(orgID))
 
 	// Apply filters
 	if manufacturer, ok := filters["manufacturer"].(string); ok && manufacturer != "" {
@@ -80,28 +175,29 @@ func (r *ConfigFileRepository) GetByCompanyID(companyID uuid.UUID, page, limit i
 	return configFiles, total, err
 }
 
-// GetByHash retrieves a config file by hash and company ID (for deduplication)
-func (r *ConfigFileRepository) GetByHash(hash string, companyID uuid.UUID) (*models.ConfigFile, error) {
+// GetByHash retrieves an organization's config file by hash (for deduplication)
+func (r *ConfigFileRepository) GetByHash(orgID uuid.UUID, hash string) (*models.ConfigFile, error) {
 	var configFile models.ConfigFile
-	err := r.db.Where("file_hash = ? AND company_id = ?", hash, companyID).First(&configFile).Error
+	err := r.db.Scopes(ForOrg(orgID)).Where("file_hash = ?", hash).First(&configFile).Error
 	if err != nil {
 		return nil, err
 	}
 	return &configFile, nil
 }
 
-// UpdateStatus updates config file status
-func (r *ConfigFileRepository) UpdateStatus(id uuid.UUID, status string) error {
-	return r.db.Model(&models.ConfigFile{}).
+// UpdateStatus updates an organization's config file status
+func (r *ConfigFileRepository) UpdateStatus(orgID, id uuid.UUID, status string) error {
+	return notFoundIfNone(r.db.Model(&models.ConfigFile{}).
+		Scopes(ForOrg(orgID)).
 		Where("id = ?", id).
 		Updates(map[string]interface{}{
 			"analysis_status": status,
 			"updated_at":      time.Now(),
-		}).Error
+		}))
 }
 
 // UpdateParsingStatus updates parsing status and parsed data
-func (r *ConfigFileRepository) UpdateParsingStatus(id uuid.UUID, status string, parsedData interface{}, parsingError string) error {
+func (r *ConfigFileRepository) UpdateParsingStatus(orgID, id uuid.UUID, status string, parsedData interface{}, parsingError string) error {
 	updates := map[string]interface{}{
 		"parsing_status": status,
 		"updated_at":     time.Now(),
@@ -112,13 +208,25 @@ func (r *ConfigFileRepository) UpdateParsingStatus(id uuid.UUID, status string,
 	if parsingError != "" {
 		updates["parsing_error"] = parsingError
 	}
-	return r.db.Model(&models.ConfigFile{}).
+	return notFoundIfNone(r.db.Model(&models.ConfigFile{}).
+		Scopes(ForOrg(orgID)).
+		Where("id = ?", id).
+		Updates(updates))
+}
+
+// UpdateConfigFormat records the format detected for a config file
+func (r *ConfigFileRepository) UpdateConfigFormat(orgID, id uuid.UUID, format string) error {
+	return notFoundIfNone(r.db.Model(&models.ConfigFile{}).
+		Scopes(ForOrg(orgID)).
 		Where("id = ?", id).
-		Updates(updates).Error
+		Updates(map[string]interface{}{
+			"config_format": format,
+			"updated_at":    time.Now(),
+		}))
 }
 
 // UpdateAnalysisStatus updates analysis status
-func (r *ConfigFileRepository) UpdateAnalysisStatus(id uuid.UUID, status string) error {
+func (r *ConfigFileRepository) UpdateAnalysisStatus(orgID, id uuid.UUID, status string) error {
 	updates := map[string]interface{}{
 		"analysis_status": status,
 		"updated_at":      time.Now(),
@@ -130,18 +238,47 @@ func (r *ConfigFileRepository) UpdateAnalysisStatus(id uuid.UUID, status string)
 		now := time.Now()
 		updates["analysis_completed_at"] = now
 	}
-	return r.db.Model(&models.ConfigFile{}).
+	return notFoundIfNone(r.db.Model(&models.ConfigFile{}).
+		Scopes(ForOrg(orgID)).
 		Where("id = ?", id).
-		Updates(updates).Error
+		Updates(updates))
+}
+
+// Delete deletes an organization's config file
+func (r *ConfigFileRepository) Delete(orgID, id uuid.UUID) error {
+	return notFoundIfNone(r.db.Scopes(ForOrg(orgID)).Where("id = ?", id).Delete(&models.ConfigFile{}))
 }
 
-// Delete deletes a config file
-func (r *ConfigFileRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.ConfigFile{}, id).Error
+// GetStorageUsage totals the config file content an organization keeps, per storage backend
+func (r *ConfigFileRepository) GetStorageUsage(orgID uuid.UUID) (*models.ConfigFileStorageUsage, error) {
+	var rows []struct {
+		StorageBackend string
+		Files          int64
+		Bytes          int64
+	}
+	err := r.db.Model(&models.ConfigFile{}).
+		Select("storage_backend, COUNT(*) AS files, COALESCE(SUM(file_size), 0) AS bytes").
+		Scopes(ForOrg(orgID)).
+		Group("storage_backend").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.ConfigFileStorageUsage{
+		CompanyID:      orgID,
+		BytesByBackend: make(map[string]int64, len(rows)),
+	}
+	for _, row := range rows {
+		usage.Files += row.Files
+		usage.Bytes += row.Bytes
+		usage.BytesByBackend[row.StorageBackend] += row.Bytes
+	}
+	return usage, nil
 }
 
-// GetStats retrieves config file statistics for a company
-func (r *ConfigFileRepository) GetStats(companyID uuid.UUID) (map[string]interface{}, error) {
+// GetStats retrieves config file statistics for an organization
+func (r *ConfigFileRepository) GetStats(orgID uuid.UUID) (map[string]interface{}, error) {
 	var stats struct {
 		TotalConfigs      int64 `json:"total_configs"`
 		ParsedConfigs     int64 `json:"parsed_configs"`
@@ -151,31 +288,32 @@ func (r *ConfigFileRepository) GetStats(companyID uuid.UUID) (map[string]interfa
 	}
 
 	// Get total configs
-	err := r.db.Model(&models.ConfigFile{}).Where("company_id = ?", companyID).Count(&stats.TotalConfigs).Error
+	configFiles := func() *gorm.DB { return r.db.Model(&models.ConfigFile{}).Scopes(ForOrg(orgID)) }
+	err := configFiles().Count(&stats.TotalConfigs).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get parsed configs
-	err = r.db.Model(&models.ConfigFile{}).Where("company_id = ? AND parsing_status = ?", companyID, "parsed").Count(&stats.ParsedConfigs).Error
+	err = configFiles().Where("parsing_status = ?", "parsed").Count(&stats.ParsedConfigs).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get analyzed configs
-	err = r.db.Model(&models.ConfigFile{}).Where("company_id = ? AND analysis_status = ?", companyID, "completed").Count(&stats.AnalyzedConfigs).Error
+	err = configFiles().Where("analysis_status = ?", "completed").Count(&stats.AnalyzedConfigs).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get failed configs
-	err = r.db.Model(&models.ConfigFile{}).Where("company_id = ? AND analysis_status = ?", companyID, "failed").Count(&stats.FailedConfigs).Error
+	err = configFiles().Where("analysis_status = ?", "failed").Count(&stats.FailedConfigs).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get pending analysis
-	err = r.db.Model(&models.ConfigFile{}).Where("company_id = ? AND analysis_status = ?", companyID, "pending").Count(&stats.PendingAnalysis).Error
+	err = configFiles().Where("analysis_status = ?", "pending").Count(&stats.PendingAnalysis).Error
 	if err != nil {
 		return nil, err
 	}
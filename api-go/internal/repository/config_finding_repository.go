@@ -9,7 +9,8 @@ import (
 	"gorm.io/gorm"
 )
 
-// ConfigFindingRepository handles config finding database operations
+// ConfigFindingRepository handles config finding database operations. Every
+// method is scoped to one organization.
 type ConfigFindingRepository struct {
 	db *gorm.DB
 }
@@ -19,22 +20,28 @@ func NewConfigFindingRepository(db *gorm.DB) *ConfigFindingRepository {
 	return &ConfigFindingRepository{db: db}
 }
 
-// Create creates a new config finding
-func (r *ConfigFindingRepository) Create(finding *models.ConfigFinding) error {
+// Create creates a new config finding for an organization
+func (r *ConfigFindingRepository) Create(orgID uuid.UUID, finding *models.ConfigFinding) error {
+	if err := claimForOrg(orgID, &finding.CompanyID); err != nil {
+		return err
+	}
 	finding.ID = uuid.New()
 	finding.CreatedAt = time.Now()
 	finding.UpdatedAt = time.Now()
 	return r.db.Create(finding).Error
 }
 
-// CreateBatch creates multiple config findings in a single transaction
-func (r *ConfigFindingRepository) CreateBatch(findings []models.ConfigFinding) error {
+// CreateBatch creates multiple config findings for an organization in a single transaction
+func (r *ConfigFindingRepository) CreateBatch(orgID uuid.UUID, findings []models.ConfigFinding) error {
 	if len(findings) == 0 {
 		return nil
 	}
 
 	now := time.Now()
 	for i := range findings {
+		if err := claimForOrg(orgID, &findings[i].CompanyID); err != nil {
+			return err
+		}
 		findings[i].ID = uuid.New()
 		findings[i].CreatedAt = now
 		findings[i].UpdatedAt = now
@@ -43,11 +50,11 @@ func (r *ConfigFindingRepository) CreateBatch(findings []models.ConfigFinding) e
 	return r.db.CreateInBatches(findings, 100).Error
 }
 
-// GetByConfigFileID retrieves findings by config file ID with filters
-func (r *ConfigFindingRepository) GetByConfigFileID(configFileID uuid.UUID, filters map[string]interface{}) ([]models.ConfigFinding, error) {
+// GetByConfigFileID retrieves an organization's findings by config file ID with filters
+func (r *ConfigFindingRepository) GetByConfigFileID(orgID, configFileID uuid.UUID, filters map[string]interface{}) ([]models.ConfigFinding, error) {
 	var findings []models.ConfigFinding
 
-	query := r.db.Where("config_file_id = ?", configFileID)
+	query := r.db.Scopes(ForOrg(orgID)).Where("config_file_id = ?", configFileID)
 
 	// Apply filters
 	if severity, ok := filters["severity"].(string); ok && severity != "" {
@@ -76,29 +83,12 @@ func (r *ConfigFindingRepository) GetByConfigFileID(configFileID uuid.UUID, filt
 	return findings, err
 }
 
-// GetByCompanyID retrieves findings by company ID with pagination and filters
-func (r *ConfigFindingRepository) GetByCompanyID(companyID uuid.UUID, page, limit int, filters map[string]interface{}) ([]models.ConfigFinding, int64, error) {
+// GetByCompanyID retrieves an organization's findings with pagination and filters
+func (r *ConfigFindingRepository) GetByCompanyID(orgID uuid.UUID, page, limit int, filters map[string]interface{}) ([]models.ConfigFinding, int64, error) {
 	var findings []models.ConfigFinding
 	var total int64
 
-	query := r.db.Model(&models.ConfigFinding{}).Where("company_id = ?", companyID)
-
-	// Apply filters
-	if configFileID, ok := filters["config_file_id"].(*uuid.UUID); ok && configFileID != nil {
-		query = query.Where("config_file_id = ?", *configFileID)
-	}
-	if severity, ok := filters["severity"].(string); ok && severity != "" {
-		query = query.Where("severity = ?", severity)
-	}
-	if category, ok := filters["category"].(string); ok && category != "" {
-		query = query.Where("category = ?", category)
-	}
-	if status, ok := filters["status"].(string); ok && status != "" {
-		query = query.Where("status = ?", status)
-	}
-	if findingType, ok := filters["finding_type"].(string); ok && findingType != "" {
-		query = query.Where("finding_type = ?", findingType)
-	}
+	query := filterConfigFindings(r.db.Model(&models.ConfigFinding{}).Scopes(ForOrg(orgID)), filters)
 
 	// Get total count
 	err := query.Count(&total).Error
@@ -125,18 +115,54 @@ func (r *ConfigFindingRepository) GetByCompanyID(companyID uuid.UUID, page, limi
 	return findings, total, err
 }
 
-// GetByID retrieves a finding by ID
-func (r *ConfigFindingRepository) GetByID(id uuid.UUID) (*models.ConfigFinding, error) {
+// ListForExport retrieves all of an organization's findings matching the
+// filters, most severe first, with the standard each violates and the name of
+// the config file each is in
+func (r *ConfigFindingRepository) ListForExport(orgID uuid.UUID, filters map[string]interface{}) ([]models.ConfigFinding, error) {
+	var findings []models.ConfigFinding
+	err := filterConfigFindings(r.db.Scopes(ForOrg(orgID)), filters).
+		Preload("Standard").
+		Preload("ConfigFile", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "filename") // Not the file's content
+		}).
+		Order("CASE LOWER(severity) WHEN 'critical' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 ELSE 4 END").
+		Order("created_at").
+		Find(&findings).Error
+	return findings, err
+}
+
+// filterConfigFindings applies list filters to a config finding query
+func filterConfigFindings(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	if configFileID, ok := filters["config_file_id"].(*uuid.UUID); ok && configFileID != nil {
+		query = query.Where("config_file_id = ?", *configFileID)
+	}
+	if severity, ok := filters["severity"].(string); ok && severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+	if category, ok := filters["category"].(string); ok && category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if findingType, ok := filters["finding_type"].(string); ok && findingType != "" {
+		query = query.Where("finding_type = ?", findingType)
+	}
+	return query
+}
+
+// GetByID retrieves an organization's finding by ID
+func (r *ConfigFindingRepository) GetByID(orgID, id uuid.UUID) (*models.ConfigFinding, error) {
 	var finding models.ConfigFinding
-	err := r.db.Where("id = ?", id).First(&finding).Error
+	err := r.db.Scopes(ForOrg(orgID)).Where("id = ?", id).First(&finding).Error
 	if err != nil {
 		return nil, err
 	}
 	return &finding, nil
 }
 
-// UpdateStatus updates finding status
-func (r *ConfigFindingRepository) UpdateStatus(id uuid.UUID, status string, resolvedBy *uuid.UUID) error {
+// UpdateStatus updates an organization's finding status
+func (r *ConfigFindingRepository) UpdateStatus(orgID, id uuid.UUID, status string, resolvedBy *uuid.UUID) error {
 	updates := map[string]interface{}{
 		"status":     status,
 		"updated_at": time.Now(),
@@ -148,13 +174,14 @@ func (r *ConfigFindingRepository) UpdateStatus(id uuid.UUID, status string, reso
 			updates["resolved_by"] = resolvedBy
 		}
 	}
-	return r.db.Model(&models.ConfigFinding{}).
+	return notFoundIfNone(r.db.Model(&models.ConfigFinding{}).
+		Scopes(ForOrg(orgID)).
 		Where("id = ?", id).
-		Updates(updates).Error
+		Updates(updates))
 }
 
-// GetStatsByConfigFile retrieves finding statistics for a config file
-func (r *ConfigFindingRepository) GetStatsByConfigFile(configFileID uuid.UUID) (map[string]int, error) {
+// GetStatsByConfigFile retrieves finding statistics for an organization's config file
+func (r *ConfigFindingRepository) GetStatsByConfigFile(orgID, configFileID uuid.UUID) (map[string]int, error) {
 	var stats struct {
 		Total     int64 `json:"total"`
 		Critical  int64 `json:"critical"`
@@ -166,40 +193,44 @@ func (r *ConfigFindingRepository) GetStatsByConfigFile(configFileID uuid.UUID) (
 		Resolved  int64 `json:"resolved"`
 	}
 
+	base := func() *gorm.DB {
+		return r.db.Model(&models.ConfigFinding{}).Scopes(ForOrg(orgID)).Where("config_file_id = ?", configFileID)
+	}
+
 	// Get total
-	err := r.db.Model(&models.ConfigFinding{}).Where("config_file_id = ?", configFileID).Count(&stats.Total).Error
+	err := base().Count(&stats.Total).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get by severity
-	err = r.db.Model(&models.ConfigFinding{}).Where("config_file_id = ? AND severity = ?", configFileID, "critical").Count(&stats.Critical).Error
+	err = base().Where("severity = ?", "critical").Count(&stats.Critical).Error
 	if err != nil {
 		return nil, err
 	}
-	err = r.db.Model(&models.ConfigFinding{}).Where("config_file_id = ? AND severity = ?", configFileID, "high").Count(&stats.High).Error
+	err = base().Where("severity = ?", "high").Count(&stats.High).Error
 	if err != nil {
 		return nil, err
 	}
-	err = r.db.Model(&models.ConfigFinding{}).Where("config_file_id = ? AND severity = ?", configFileID, "medium").Count(&stats.Medium).Error
+	err = base().Where("severity = ?", "medium").Count(&stats.Medium).Error
 	if err != nil {
 		return nil, err
 	}
-	err = r.db.Model(&models.ConfigFinding{}).Where("config_file_id = ? AND severity = ?", configFileID, "low").Count(&stats.Low).Error
+	err = base().Where("severity = ?", "low").Count(&stats.Low).Error
 	if err != nil {
 		return nil, err
 	}
-	err = r.db.Model(&models.ConfigFinding{}).Where("config_file_id = ? AND severity = ?", configFileID, "info").Count(&stats.Info).Error
+	err = base().Where("severity = ?", "info").Count(&stats.Info).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get by status
-	err = r.db.Model(&models.ConfigFinding{}).Where("config_file_id = ? AND status = ?", configFileID, "open").Count(&stats.Open).Error
+	err = base().Where("status = ?", "open").Count(&stats.Open).Error
 	if err != nil {
 		return nil, err
 	}
-	err = r.db.Model(&models.ConfigFinding{}).Where("config_file_id = ? AND status = ?", configFileID, "resolved").Count(&stats.Resolved).Error
+	err = base().Where("status = ?", "resolved").Count(&stats.Resolved).Error
 	if err != nil {
 		return nil, err
 	}
@@ -216,8 +247,8 @@ func (r *ConfigFindingRepository) GetStatsByConfigFile(configFileID uuid.UUID) (
 	}, nil
 }
 
-// DeleteByConfigFileID deletes all findings for a config file
-func (r *ConfigFindingRepository) DeleteByConfigFileID(configFileID uuid.UUID) error {
-	return r.db.Where("config_file_id = ?", configFileID).Delete(&models.ConfigFinding{}).Error
+// DeleteByConfigFileID deletes all findings for an organization's config file
+func (r *ConfigFindingRepository) DeleteByConfigFileID(orgID, configFileID uuid.UUID) error {
+	return r.db.Scopes(ForOrg(orgID)).Where("config_file_id = ?", configFileID).Delete(&models.ConfigFinding{}).Error
 }
 
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DataExportRepository handles data export configuration and run history
+type DataExportRepository struct {
+	db *gorm.DB
+}
+
+// NewDataExportRepository creates a new data export repository
+func NewDataExportRepository(db *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+// GetConfig retrieves the export configuration for an organization
+func (r *DataExportRepository) GetConfig(organizationID uuid.UUID) (*models.DataExportConfig, error) {
+	var cfg models.DataExportConfig
+	err := r.db.Where("organization_id = ?", organizationID).First(&cfg).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// SaveConfig creates or updates an export configuration
+func (r *DataExportRepository) SaveConfig(cfg *models.DataExportConfig) error {
+	now := time.Now()
+	if cfg.ID == uuid.Nil {
+		cfg.ID = uuid.New()
+		cfg.CreatedAt = now
+	}
+	cfg.UpdatedAt = now
+	return r.db.Save(cfg).Error
+}
+
+// GetDueConfigs retrieves enabled configurations whose next export time has passed
+func (r *DataExportRepository) GetDueConfigs(now time.Time) ([]models.DataExportConfig, error) {
+	var configs []models.DataExportConfig
+	err := r.db.Where("enabled = ? AND (next_export_at IS NULL OR next_export_at <= ?)", true, now).
+		Find(&configs).Error
+	return configs, err
+}
+
+// CreateRun records the start of an export run
+func (r *DataExportRepository) CreateRun(run *models.DataExportRun) error {
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+	return r.db.Create(run).Error
+}
+
+// UpdateRun saves the final state of an export run
+func (r *DataExportRepository) UpdateRun(run *models.DataExportRun) error {
+	return r.db.Save(run).Error
+}
+
+// ListRuns retrieves the most recent export runs for an organization
+func (r *DataExportRepository) ListRuns(organizationID uuid.UUID, limit int) ([]models.DataExportRun, error) {
+	var runs []models.DataExportRun
+	err := r.db.Where("organization_id = ?", organizationID).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+// FindVulnerabilities retrieves an organization's findings, optionally only those changed since a time
+func (r *DataExportRepository) FindVulnerabilities(organizationID uuid.UUID, since *time.Time, until time.Time) ([]models.Vulnerability, error) {
+	var vulns []models.Vulnerability
+	query := r.db.Where("organization_id = ? AND updated_at <= ?", organizationID, until)
+	if since != nil {
+		query = query.Where("updated_at > ?", *since)
+	}
+	err := query.Order("updated_at ASC").Find(&vulns).Error
+	return vulns, err
+}
+
+// FindAgents retrieves an organization's agents, optionally only those changed since a time
+func (r *DataExportRepository) FindAgents(organizationID uuid.UUID, since *time.Time, until time.Time) ([]models.Agent, error) {
+	var agents []models.Agent
+	query := r.db.Where("organization_id = ? AND updated_at <= ?", organizationID, until)
+	if since != nil {
+		query = query.Where("updated_at > ?", *since)
+	}
+	err := query.Order("updated_at ASC").Find(&agents).Error
+	return agents, err
+}
+
+// FindNetworkHosts retrieves network hosts discovered by an organization's agents
+func (r *DataExportRepository) FindNetworkHosts(organizationID uuid.UUID, since *time.Time, until time.Time) ([]models.NetworkHost, error) {
+	var hosts []models.NetworkHost
+	query := r.db.Where("agent_id IN (?)", r.db.Model(&models.Agent{}).Select("id").Where("organization_id = ?", organizationID)).
+		Where("updated_at <= ?", until)
+	if since != nil {
+		query = query.Where("updated_at > ?", *since)
+	}
+	err := query.Order("updated_at ASC").Find(&hosts).Error
+	return hosts, err
+}
+
+// FindSoftware retrieves software inventory reported by an organization's agents
+func (r *DataExportRepository) FindSoftware(organizationID uuid.UUID, since *time.Time, until time.Time) ([]models.Software, error) {
+	var software []models.Software
+	query := r.db.Where("agent_id IN (?)", r.db.Model(&models.Agent{}).Select("id").Where("organization_id = ?", organizationID)).
+		Where("updated_at <= ?", until)
+	if since != nil {
+		query = query.Where("updated_at > ?", *since)
+	}
+	err := query.Order("updated_at ASC").Find(&software).Error
+	return software, err
+}
+
+// FindDashboardSnapshots retrieves an organization's historical report snapshots
+func (r *DataExportRepository) FindDashboardSnapshots(organizationID uuid.UUID, since *time.Time, until time.Time) ([]models.DashboardSnapshot, error) {
+	var snapshots []models.DashboardSnapshot
+	query := r.db.Where("organization_id = ? AND created_at <= ?", organizationID, until)
+	if since != nil {
+		query = query.Where("created_at > ?", *since)
+	}
+	err := query.Order("created_at ASC").Find(&snapshots).Error
+	return snapshots, err
+}
@@ -38,6 +38,8 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		gormLogger = logger.Default.LogMode(logger.Info)
 	}
 
+	ConfigureCompression(cfg.DBCompression, cfg.DBCompressionThreshold)
+
 	// Connect to database
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: gormLogger,
@@ -134,6 +136,10 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 func (d *Database) AutoMigrate() error {
 	log.Println("Running database migrations...")
 
+	if err := migrateCompressedColumns(d.DB, &models.Scan{}, &models.ResultChunk{}); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
 	err := d.DB.AutoMigrate(
 		&models.User{},
 		&models.Company{},
@@ -141,11 +147,45 @@ func (d *Database) AutoMigrate() error {
 		&models.Scan{},
 		&models.Vulnerability{},
 		&models.Agent{},
+		&models.AgentStatusEvent{},
+		&models.AgentTag{},
+		&models.AgentGroup{},
 		&models.Software{},
 		&models.NetworkHost{},
 		&models.EnrollmentToken{},
 		&models.AgentCredential{},
 		&models.DashboardSnapshot{},
+		&models.DataExportConfig{},
+		&models.DataExportRun{},
+		&models.FindingState{},
+		&models.AgentCommand{},
+		&models.RescanBatch{},
+		&models.ConfigBaseline{},
+		&models.ContainerAllowlistRule{},
+		&models.ContainerAllowlistAudit{},
+		&models.AgentScanScope{},
+		&models.FindingVerification{},
+		&models.ComplianceSLAPolicy{},
+		&models.ResultBatch{},
+		&models.ResultChunk{},
+		&models.FindingEvidence{},
+		&models.ExportJob{},
+		&models.HostRisk{},
+		&models.BackfillJob{},
+		&models.CollectionSettings{},
+		&models.CollectionRun{},
+		&models.ThreatIntelFeed{},
+		&models.ThreatIntelIndicator{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.APIKey{},
+		&models.SuppressionRule{},
+		&models.AgentRelease{},
+		&models.MaturityAssessment{},
+		&models.ScanImport{},
+		&models.LicensePolicy{},
+		&models.ConfigUpload{},
+		&models.ConfigUploadChunk{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
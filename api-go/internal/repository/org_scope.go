@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrMissingOrganization is returned by tenant queries run without an organization
+	ErrMissingOrganization = errors.New("query is not scoped to an organization")
+	// ErrOrganizationMismatch is returned when a record is written under an
+	// organization other than the one it belongs to
+	ErrOrganizationMismatch = errors.New("record belongs to another organization")
+)
+
+// ForOrg scopes a query to the rows of one organization. Tenant tables record
+// the organization a row belongs to in company_id. A query scoped to uuid.Nil
+// fails with ErrMissingOrganization instead of running unscoped.
+func ForOrg(orgID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if orgID == uuid.Nil {
+			db.AddError(ErrMissingOrganization)
+			return db
+		}
+		return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "company_id"}, Value: orgID})
+	}
+}
+
+// claimForOrg assigns a new record to orgID, refusing records that already
+// belong to another organization
+func claimForOrg(orgID uuid.UUID, companyID *uuid.UUID) error {
+	switch {
+	case orgID == uuid.Nil:
+		return ErrMissingOrganization
+	case *companyID == uuid.Nil:
+		*companyID = orgID
+	case *companyID != orgID:
+		return ErrOrganizationMismatch
+	}
+	return nil
+}
+
+// checkOrg returns gorm.ErrRecordNotFound for a loaded record of another
+// organization, so it can't be told apart from one that doesn't exist
+func checkOrg(orgID, companyID uuid.UUID) error {
+	if orgID == uuid.Nil {
+		return ErrMissingOrganization
+	}
+	if companyID != orgID {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// notFoundIfNone turns an update or delete that matched no rows into
+// gorm.ErrRecordNotFound
+func notFoundIfNone(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// orgFixture is one organization's rows, seeded next to another
+// organization's so tests can check they never leak across
+type orgFixture struct {
+	ID         uuid.UUID
+	Scan       models.Scan
+	ConfigFile models.ConfigFile
+	Finding    models.ConfigFinding
+}
+
+// seedOrgs creates a scan with a vulnerability, a config file and a config
+// finding for each of two organizations, with the same content apart from
+// their IDs
+func seedOrgs(t *testing.T, db *gorm.DB) (a, b orgFixture) {
+	t.Helper()
+	seed := func() orgFixture {
+		org := orgFixture{ID: uuid.New()}
+		now := time.Now()
+		org.Scan = models.Scan{ID: uuid.New(), CompanyID: org.ID, Repository: "app", Status: models.ScanStatusCompleted, CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, db.Create(&org.Scan).Error)
+		vuln := models.Vulnerability{ID: uuid.NewString(), ScanID: org.Scan.ID, CompanyID: org.ID, Severity: "high", Title: "CVE-2025-0001", CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, db.Omit("References", "AffectedVersions", "PatchedVersions", "EnrichmentData").Create(&vuln).Error)
+
+		org.ConfigFile = models.ConfigFile{
+			ID: uuid.New(), CompanyID: org.ID, Filename: "edge.conf", FilePath: "edge.conf", FileSize: 15, FileHash: "abc123",
+			FileContent: []byte("hostname edge-1"), StorageBackend: "db", DeviceType: "router", Manufacturer: "cisco", ConfigType: "running",
+			ParsingStatus: "parsed", AnalysisStatus: "pending", CreatedAt: now, UpdatedAt: now,
+		}
+		require.NoError(t, db.Omit("Company", "Uploader", "Findings", "AnalysisResult").Create(&org.ConfigFile).Error)
+		org.Finding = models.ConfigFinding{
+			ID: uuid.New(), ConfigFileID: org.ConfigFile.ID, CompanyID: org.ID, FindingType: "weak_password", Severity: "high",
+			Category: "authentication", Title: "Weak enable secret", Description: "The enable secret is weak", Status: "open", CreatedAt: now, UpdatedAt: now,
+		}
+		require.NoError(t, db.Omit("ConfigFile", "Company", "Standard").Create(&org.Finding).Error)
+		return org
+	}
+	return seed(), seed()
+}
+
+func openOrgScopeDB(t *testing.T) *gorm.DB {
+	return testdb.Open(t, &models.Scan{}, &models.Vulnerability{}, &models.ConfigFile{}, &models.ConfigFinding{})
+}
+
+func TestForOrg(t *testing.T) {
+	db := openOrgScopeDB(t)
+	orgA, orgB := seedOrgs(t, db)
+
+	var scans []models.Scan
+	require.NoError(t, db.Scopes(ForOrg(orgA.ID)).Find(&scans).Error)
+	require.Len(t, scans, 1)
+	assert.Equal(t, orgA.Scan.ID, scans[0].ID)
+
+	err := db.Scopes(ForOrg(uuid.Nil)).Find(&scans).Error
+	assert.ErrorIs(t, err, ErrMissingOrganization)
+	var count int64
+	assert.ErrorIs(t, db.Scopes(ForOrg(uuid.Nil)).Where("id = ?", orgB.Scan.ID).Delete(&models.Scan{}).Error, ErrMissingOrganization)
+	require.NoError(t, db.Model(&models.Scan{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count, "unscoped queries never run")
+}
+
+func TestScanRepositoryIsScopedToOrganization(t *testing.T) {
+	db := openOrgScopeDB(t)
+	repo := NewScanRepository(db)
+	orgA, orgB := seedOrgs(t, db)
+
+	// Reads through org A only ever return org A's rows
+	scan, err := repo.GetByID(orgA.ID, orgA.Scan.ID)
+	require.NoError(t, err)
+	assert.Equal(t, orgA.ID, scan.CompanyID)
+	_, err = repo.GetByID(orgA.ID, orgB.Scan.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	scans, total, err := repo.GetByCompanyID(orgA.ID, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, scans, 1)
+	assert.Equal(t, orgA.Scan.ID, scans[0].ID)
+
+	scans, err = repo.GetByStatus(orgA.ID, models.ScanStatusCompleted)
+	require.NoError(t, err)
+	require.Len(t, scans, 1)
+	assert.Equal(t, orgA.Scan.ID, scans[0].ID)
+
+	vulns, total, err := repo.GetVulnerabilities(orgA.ID, orgA.Scan.ID, []models.SeverityLevel{models.SeverityHigh}, 20, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, vulns, 1)
+	assert.Equal(t, orgA.Scan.ID, vulns[0].ScanID)
+	vulns, total, err = repo.GetVulnerabilities(orgA.ID, orgB.Scan.ID, nil, 20, 0)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, vulns)
+	vulns, err = repo.ListVulnerabilities(orgA.ID, orgB.Scan.ID)
+	require.NoError(t, err)
+	assert.Empty(t, vulns)
+	counts, err := repo.CountVulnerabilitiesBySeverity(orgA.ID, orgB.Scan.ID)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+
+	stats, err := repo.GetStats(orgA.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats["total_scans"])
+	assert.Equal(t, int64(1), stats["completed_scans"])
+
+	// Writes through org A never reach org B's scan
+	theirs := orgB.Scan
+	assert.ErrorIs(t, repo.Update(orgA.ID, &theirs), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.UpdateStatus(orgA.ID, orgB.Scan.ID, models.ScanStatusFailed), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.UpdateProgress(orgA.ID, orgB.Scan.ID, 50), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Delete(orgA.ID, orgB.Scan.ID), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Create(orgA.ID, &models.Scan{CompanyID: orgB.ID}), ErrOrganizationMismatch)
+	scan, err = repo.GetByID(orgB.ID, orgB.Scan.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScanStatusCompleted, scan.Status)
+	assert.Zero(t, scan.Progress)
+
+	require.NoError(t, repo.UpdateStatus(orgA.ID, orgA.Scan.ID, models.ScanStatusFailed))
+	require.NoError(t, repo.Delete(orgA.ID, orgA.Scan.ID))
+	created := &models.Scan{}
+	require.NoError(t, repo.Create(orgA.ID, created))
+	assert.Equal(t, orgA.ID, created.CompanyID)
+
+	// Org B still sees exactly its own scan
+	scans, total, err = repo.GetByCompanyID(orgB.ID, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, scans, 1)
+	assert.Equal(t, orgB.Scan.ID, scans[0].ID)
+
+	// Nothing runs without an organization
+	_, err = repo.GetByID(uuid.Nil, orgB.Scan.ID)
+	assert.ErrorIs(t, err, ErrMissingOrganization)
+	_, _, err = repo.GetVulnerabilities(uuid.Nil, orgB.Scan.ID, nil, 20, 0)
+	assert.ErrorIs(t, err, ErrMissingOrganization)
+	assert.ErrorIs(t, repo.Delete(uuid.Nil, orgB.Scan.ID), ErrMissingOrganization)
+	assert.ErrorIs(t, repo.Create(uuid.Nil, &models.Scan{}), ErrMissingOrganization)
+}
+
+func TestConfigFileRepositoryIsScopedToOrganization(t *testing.T) {
+	db := openOrgScopeDB(t)
+	repo := NewConfigFileRepository(db, "", nil)
+	orgA, orgB := seedOrgs(t, db)
+
+	configFile, err := repo.GetByID(orgA.ID, orgA.ConfigFile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, orgA.ID, configFile.CompanyID)
+	_, err = repo.GetByID(orgA.ID, orgB.ConfigFile.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Both organizations uploaded the same file; each finds only its own
+	configFile, err = repo.GetByHash(orgA.ID, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, orgA.ConfigFile.ID, configFile.ID)
+
+	configFiles, total, err := repo.GetByCompanyID(orgA.ID, 1, 20, map[string]interface{}{"manufacturer": "cisco"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, configFiles, 1)
+	assert.Equal(t, orgA.ConfigFile.ID, configFiles[0].ID)
+
+	usage, err := repo.GetStorageUsage(orgA.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Files)
+	assert.Equal(t, int64(15), usage.Bytes)
+	stats, err := repo.GetStats(orgA.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats["total_configs"])
+
+	// Org B's config files and their content stay out of reach of org A
+	assert.ErrorIs(t, repo.UpdateStatus(orgA.ID, orgB.ConfigFile.ID, "completed"), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.UpdateParsingStatus(orgA.ID, orgB.ConfigFile.ID, "failed", nil, "bad config"), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.UpdateConfigFormat(orgA.ID, orgB.ConfigFile.ID, "ios"), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.UpdateAnalysisStatus(orgA.ID, orgB.ConfigFile.ID, "analyzing"), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Delete(orgA.ID, orgB.ConfigFile.ID), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Create(orgA.ID, &models.ConfigFile{CompanyID: orgB.ID}), ErrOrganizationMismatch)
+	theirs := &models.ConfigFile{ID: orgB.ConfigFile.ID, CompanyID: orgB.ID, StorageBackend: "s3", StorageKey: orgB.ID.String() + "/abc123"}
+	assert.ErrorIs(t, repo.LoadContent(orgA.ID, theirs), gorm.ErrRecordNotFound)
+	_, _, err = repo.OpenContent(context.Background(), orgA.ID, theirs)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.DeleteContent(orgA.ID, theirs), gorm.ErrRecordNotFound)
+
+	configFile, err = repo.GetByID(orgB.ID, orgB.ConfigFile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "parsed", configFile.ParsingStatus)
+	assert.Equal(t, "pending", configFile.AnalysisStatus)
+	assert.Empty(t, configFile.ConfigFormat)
+
+	require.NoError(t, repo.UpdateAnalysisStatus(orgA.ID, orgA.ConfigFile.ID, "analyzing"))
+	created := &models.ConfigFile{FileHash: "def456", FileContent: []byte("hostname edge-2")}
+	require.NoError(t, repo.Create(orgA.ID, created))
+	assert.Equal(t, orgA.ID, created.CompanyID)
+	_, err = repo.GetByHash(orgB.ID, "def456")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = repo.GetByHash(uuid.Nil, "abc123")
+	assert.ErrorIs(t, err, ErrMissingOrganization)
+	_, err = repo.GetStorageUsage(uuid.Nil)
+	assert.ErrorIs(t, err, ErrMissingOrganization)
+	assert.ErrorIs(t, repo.UpdateAnalysisStatus(uuid.Nil, orgB.ConfigFile.ID, "failed"), ErrMissingOrganization)
+}
+
+func TestConfigFindingRepositoryIsScopedToOrganization(t *testing.T) {
+	db := openOrgScopeDB(t)
+	repo := NewConfigFindingRepository(db)
+	orgA, orgB := seedOrgs(t, db)
+
+	finding, err := repo.GetByID(orgA.ID, orgA.Finding.ID)
+	require.NoError(t, err)
+	assert.Equal(t, orgA.ID, finding.CompanyID)
+	_, err = repo.GetByID(orgA.ID, orgB.Finding.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	findings, err := repo.GetByConfigFileID(orgA.ID, orgA.ConfigFile.ID, map[string]interface{}{"severity": "high"})
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, orgA.Finding.ID, findings[0].ID)
+	findings, err = repo.GetByConfigFileID(orgA.ID, orgB.ConfigFile.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+	findings, total, err := repo.GetByCompanyID(orgA.ID, 1, 20, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, findings, 1)
+	assert.Equal(t, orgA.Finding.ID, findings[0].ID)
+	theirFile := orgB.ConfigFile.ID
+	_, total, err = repo.GetByCompanyID(orgA.ID, 1, 20, map[string]interface{}{"config_file_id": &theirFile})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	stats, err := repo.GetStatsByConfigFile(orgA.ID, orgB.ConfigFile.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stats["total"])
+
+	// Writes through org A never reach org B's findings
+	assert.ErrorIs(t, repo.UpdateStatus(orgA.ID, orgB.Finding.ID, "resolved", nil), gorm.ErrRecordNotFound)
+	require.NoError(t, repo.DeleteByConfigFileID(orgA.ID, orgB.ConfigFile.ID))
+	err = repo.CreateBatch(orgA.ID, []models.ConfigFinding{{ConfigFileID: orgA.ConfigFile.ID}, {ConfigFileID: orgA.ConfigFile.ID, CompanyID: orgB.ID}})
+	assert.ErrorIs(t, err, ErrOrganizationMismatch, "a batch holding one of org B's findings is refused whole")
+	assert.ErrorIs(t, repo.Create(orgA.ID, &models.ConfigFinding{CompanyID: orgB.ID}), ErrOrganizationMismatch)
+
+	finding, err = repo.GetByID(orgB.ID, orgB.Finding.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "open", finding.Status)
+	findings, total, err = repo.GetByCompanyID(orgB.ID, 1, 20, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, findings, 1)
+	assert.Equal(t, orgB.Finding.ID, findings[0].ID)
+
+	batch := []models.ConfigFinding{{ConfigFileID: orgA.ConfigFile.ID, Title: "a", Description: "a"}, {ConfigFileID: orgA.ConfigFile.ID, CompanyID: orgA.ID, Title: "b", Description: "b"}}
+	require.NoError(t, repo.CreateBatch(orgA.ID, batch))
+	for _, finding := range batch {
+		assert.Equal(t, orgA.ID, finding.CompanyID)
+	}
+	require.NoError(t, repo.DeleteByConfigFileID(orgA.ID, orgA.ConfigFile.ID))
+	_, total, err = repo.GetByCompanyID(orgB.ID, 1, 20, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total, "deleting org A's findings leaves org B's")
+
+	_, err = repo.GetByConfigFileID(uuid.Nil, orgB.ConfigFile.ID, nil)
+	assert.ErrorIs(t, err, ErrMissingOrganization)
+	_, err = repo.GetStatsByConfigFile(uuid.Nil, orgB.ConfigFile.ID)
+	assert.ErrorIs(t, err, ErrMissingOrganization)
+	assert.ErrorIs(t, repo.DeleteByConfigFileID(uuid.Nil, orgB.ConfigFile.ID), ErrMissingOrganization)
+}
+
+func TestCheckOrgHidesOtherOrganizations(t *testing.T) {
+	orgA, orgB := uuid.New(), uuid.New()
+	assert.NoError(t, checkOrg(orgA, orgA))
+	assert.ErrorIs(t, checkOrg(orgA, orgB), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, checkOrg(uuid.Nil, orgB), ErrMissingOrganization)
+}
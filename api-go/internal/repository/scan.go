@@ -9,7 +9,9 @@ import (
 	"gorm.io/gorm"
 )
 
-// ScanRepository handles scan database operations
+// ScanRepository handles scan database operations. Every method but Exists
+// is scoped to one organization, and scans of other organizations are never
+// read or written.
 type ScanRepository struct {
 	db *gorm.DB
 }
@@ -19,39 +21,51 @@ func NewScanRepository(db *gorm.DB) *ScanRepository {
 	return &ScanRepository{db: db}
 }
 
-// Create creates a new scan
-func (r *ScanRepository) Create(scan *models.Scan) error {
+// Create creates a new scan for an organization
+func (r *ScanRepository) Create(orgID uuid.UUID, scan *models.Scan) error {
+	if err := claimForOrg(orgID, &scan.CompanyID); err != nil {
+		return err
+	}
 	scan.ID = uuid.New()
 	scan.CreatedAt = time.Now()
 	scan.UpdatedAt = time.Now()
 	return r.db.Create(scan).Error
 }
 
-// GetByID retrieves a scan by ID
-func (r *ScanRepository) GetByID(id uuid.UUID) (*models.Scan, error) {
+// GetByID retrieves an organization's scan by ID
+func (r *ScanRepository) GetByID(orgID, id uuid.UUID) (*models.Scan, error) {
 	var scan models.Scan
-	err := r.db.Where("id = ?", id).First(&scan).Error
+	err := r.db.Scopes(ForOrg(orgID)).Where("id = ?", id).First(&scan).Error
 	if err != nil {
 		return nil, err
 	}
 	return &scan, nil
 }
 
-// GetByCompanyID retrieves scans by company ID with pagination
-func (r *ScanRepository) GetByCompanyID(companyID uuid.UUID, page, limit int) ([]models.Scan, int64, error) {
+// Exists reports whether a scan exists in any organization, so one of another
+// organization can be told apart from one that doesn't exist. Nothing of the
+// scan is read.
+func (r *ScanRepository) Exists(id uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Scan{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// GetByCompanyID retrieves an organization's scans with pagination
+func (r *ScanRepository) GetByCompanyID(orgID uuid.UUID, page, limit int) ([]models.Scan, int64, error) {
 	var scans []models.Scan
 	var total int64
 
 	// Get total count
-	err := r.db.Model(&models.Scan{}).Where("company_id = ?", companyID).Count(&total).Error
+	err := r.db.Model(&models.Scan{}).Scopes(ForOrg(orgID)).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Get paginated results
+	// Get paginated results. Scans have no vulnerabilities relation; those
+	// are read a page at a time with GetVulnerabilities.
 	offset := (page - 1) * limit
-	err = r.db.Where("company_id = ?", companyID).
-		Preload("Vulnerabilities").
+	err = r.db.Scopes(ForOrg(orgID)).
 		Offset(offset).
 		Limit(limit).
 		Order("created_at DESC").
@@ -60,48 +74,120 @@ func (r *ScanRepository) GetByCompanyID(companyID uuid.UUID, page, limit int) ([
 	return scans, total, err
 }
 
-// GetByStatus retrieves scans by status
-func (r *ScanRepository) GetByStatus(companyID uuid.UUID, status models.ScanStatus) ([]models.Scan, error) {
+// GetVulnerabilities retrieves a page of an organization's scan's
+// vulnerabilities, most severe first, and how many match. No severities means
+// every severity. Agents report severities in lower case, so they are
+// compared upper-cased.
+func (r *ScanRepository) GetVulnerabilities(orgID, scanID uuid.UUID, severities []models.SeverityLevel, limit, offset int) ([]models.Vulnerability, int64, error) {
+	query := r.scanVulnerabilities(orgID, scanID)
+	if len(severities) > 0 {
+		query = query.Where("UPPER(severity) IN ?", severities)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var vulnerabilities []models.Vulnerability
+	err := query.
+		Order("CASE UPPER(severity) WHEN 'CRITICAL' THEN 0 WHEN 'HIGH' THEN 1 WHEN 'MEDIUM' THEN 2 WHEN 'LOW' THEN 3 ELSE 4 END").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&vulnerabilities).Error
+	return vulnerabilities, total, err
+}
+
+// ListVulnerabilities retrieves all of an organization's scan's vulnerabilities
+func (r *ScanRepository) ListVulnerabilities(orgID, scanID uuid.UUID) ([]models.Vulnerability, error) {
+	var vulnerabilities []models.Vulnerability
+	err := r.scanVulnerabilities(orgID, scanID).Order("created_at").Find(&vulnerabilities).Error
+	return vulnerabilities, err
+}
+
+// CountVulnerabilitiesBySeverity counts an organization's scan's
+// vulnerabilities by upper-cased severity
+func (r *ScanRepository) CountVulnerabilitiesBySeverity(orgID, scanID uuid.UUID) (map[models.SeverityLevel]int64, error) {
+	var rows []struct {
+		Severity models.SeverityLevel
+		Count    int64
+	}
+	err := r.scanVulnerabilities(orgID, scanID).
+		Select("UPPER(severity) AS severity, COUNT(*) AS count").
+		Group("UPPER(severity)").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[models.SeverityLevel]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Severity] = row.Count
+	}
+	return counts, nil
+}
+
+// GetByStatus retrieves an organization's scans by status
+func (r *ScanRepository) GetByStatus(orgID uuid.UUID, status models.ScanStatus) ([]models.Scan, error) {
 	var scans []models.Scan
-	err := r.db.Where("company_id = ? AND status = ?", companyID, status).
+	err := r.db.Scopes(ForOrg(orgID)).Where("status = ?", status).
 		Order("created_at DESC").
 		Find(&scans).Error
 	return scans, err
 }
 
-// Update updates a scan
-func (r *ScanRepository) Update(scan *models.Scan) error {
+// Update updates an organization's scan
+func (r *ScanRepository) Update(orgID uuid.UUID, scan *models.Scan) error {
+	if err := checkOrg(orgID, scan.CompanyID); err != nil {
+		return err
+	}
 	scan.UpdatedAt = time.Now()
-	return r.db.Save(scan).Error
+	return notFoundIfNone(r.db.Scopes(ForOrg(orgID)).Select("*").Omit("created_at").Updates(scan))
 }
 
-// UpdateStatus updates scan status
-func (r *ScanRepository) UpdateStatus(id uuid.UUID, status models.ScanStatus) error {
-	return r.db.Model(&models.Scan{}).
+// UpdateStatus updates an organization's scan status
+func (r *ScanRepository) UpdateStatus(orgID, id uuid.UUID, status models.ScanStatus) error {
+	return notFoundIfNone(r.db.Model(&models.Scan{}).
+		Scopes(ForOrg(orgID)).
 		Where("id = ?", id).
 		Updates(map[string]interface{}{
 			"status":     status,
 			"updated_at": time.Now(),
-		}).Error
+		}))
 }
 
-// UpdateProgress updates scan progress
-func (r *ScanRepository) UpdateProgress(id uuid.UUID, progress int) error {
-	return r.db.Model(&models.Scan{}).
+// UpdateProgress updates an organization's scan progress
+func (r *ScanRepository) UpdateProgress(orgID, id uuid.UUID, progress int) error {
+	return notFoundIfNone(r.db.Model(&models.Scan{}).
+		Scopes(ForOrg(orgID)).
 		Where("id = ?", id).
 		Updates(map[string]interface{}{
 			"progress":   progress,
 			"updated_at": time.Now(),
-		}).Error
+		}))
 }
 
-// Delete deletes a scan
-func (r *ScanRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.Scan{}, id).Error
+// Delete deletes an organization's scan
+func (r *ScanRepository) Delete(orgID, id uuid.UUID) error {
+	return notFoundIfNone(r.db.Scopes(ForOrg(orgID)).Where("id = ?", id).Delete(&models.Scan{}))
+}
+
+// scanVulnerabilities selects the vulnerabilities of a scan, if the scan
+// belongs to the organization
+func (r *ScanRepository) scanVulnerabilities(orgID, scanID uuid.UUID) *gorm.DB {
+	query := r.db.Model(&models.Vulnerability{})
+	if orgID == uuid.Nil {
+		// Scopes apply when a query runs, so the subquery can't fail this one
+		query.AddError(ErrMissingOrganization)
+		return query
+	}
+	orgScans := r.db.Model(&models.Scan{}).Scopes(ForOrg(orgID)).Select("id")
+	return query.Where("scan_id = ? AND scan_id IN (?)", scanID, orgScans)
 }
 
-// GetStats retrieves scan statistics for a company
-func (r *ScanRepository) GetStats(companyID uuid.UUID) (map[string]interface{}, error) {
+// GetStats retrieves scan statistics for an organization
+func (r *ScanRepository) GetStats(orgID uuid.UUID) (map[string]interface{}, error) {
 	var stats struct {
 		TotalScans     int64 `json:"total_scans"`
 		CompletedScans int64 `json:"completed_scans"`
@@ -110,25 +196,26 @@ func (r *ScanRepository) GetStats(companyID uuid.UUID) (map[string]interface{},
 	}
 
 	// Get total scans
-	err := r.db.Model(&models.Scan{}).Where("company_id = ?", companyID).Count(&stats.TotalScans).Error
+	scans := func() *gorm.DB { return r.db.Model(&models.Scan{}).Scopes(ForOrg(orgID)) }
+	err := scans().Count(&stats.TotalScans).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get completed scans
-	err = r.db.Model(&models.Scan{}).Where("company_id = ? AND status = ?", companyID, models.ScanStatusCompleted).Count(&stats.CompletedScans).Error
+	err = scans().Where("status = ?", models.ScanStatusCompleted).Count(&stats.CompletedScans).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get active scans
-	err = r.db.Model(&models.Scan{}).Where("company_id = ? AND status = ?", companyID, models.ScanStatusScanning).Count(&stats.ActiveScans).Error
+	err = scans().Where("status = ?", models.ScanStatusScanning).Count(&stats.ActiveScans).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get failed scans
-	err = r.db.Model(&models.Scan{}).Where("company_id = ? AND status = ?", companyID, models.ScanStatusFailed).Count(&stats.FailedScans).Error
+	err = scans().Where("status = ?", models.ScanStatusFailed).Count(&stats.FailedScans).Error
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix marks values sealed by a Cipher, so values stored before
+// encryption was introduced can still be read
+const encryptedPrefix = "enc:v1:"
+
+// ErrNoKey is returned when a secret must be encrypted but no key is configured
+var ErrNoKey = errors.New("SECRET_ENCRYPTION_KEY is not configured")
+
+// Cipher encrypts credentials stored in the database with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher from a 32-byte key, hex or base64 encoded. An
+// empty key gives a nil cipher, whose Encrypt fails with ErrNoKey.
+func NewCipher(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, nil
+	}
+	raw, err := decodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret encryption key: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt seals plaintext under a fresh nonce
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if c == nil {
+		return "", ErrNoKey
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt. Values without the encrypted
+// prefix were stored in plaintext and are returned unchanged.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	encoded, sealed := strings.CutPrefix(value, encryptedPrefix)
+	if !sealed {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrNoKey
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted secret: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return "", errors.New("malformed encrypted secret")
+	}
+	plaintext, err := c.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether a stored value was sealed by a Cipher
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// decodeKey accepts a 32-byte key as 64 hex characters or as base64
+func decodeKey(key string) ([]byte, error) {
+	if raw, err := hex.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	if raw, err := base64.StdEncoding.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	return nil, errors.New("secret encryption key must be 32 bytes, hex or base64 encoded")
+}
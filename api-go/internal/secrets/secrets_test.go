@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestCipherRoundTrip(t *testing.T) {
+	c, err := NewCipher(testKey)
+	require.NoError(t, err)
+
+	sealed, err := c.Encrypt("wJalrXUtnFEMI/K7MDENG")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.NotContains(t, sealed, "wJalrXUtnFEMI")
+
+	again, err := c.Encrypt("wJalrXUtnFEMI/K7MDENG")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "each encryption uses a fresh nonce")
+
+	opened, err := c.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "wJalrXUtnFEMI/K7MDENG", opened)
+}
+
+func TestCipherReadsPlaintext(t *testing.T) {
+	c, err := NewCipher(testKey)
+	require.NoError(t, err)
+
+	opened, err := c.Decrypt("stored-before-encryption")
+	require.NoError(t, err)
+	assert.Equal(t, "stored-before-encryption", opened)
+}
+
+func TestCipherRejectsTampering(t *testing.T) {
+	c, err := NewCipher(testKey)
+	require.NoError(t, err)
+	sealed, err := c.Encrypt("secret")
+	require.NoError(t, err)
+
+	other, err := NewCipher(strings.Repeat("ff", 32))
+	require.NoError(t, err)
+	_, err = other.Decrypt(sealed)
+	assert.Error(t, err, "a different key must not open the secret")
+
+	_, err = c.Decrypt(sealed[:len(sealed)-4] + "AAAA")
+	assert.Error(t, err)
+}
+
+func TestNewCipher(t *testing.T) {
+	c, err := NewCipher("")
+	require.NoError(t, err)
+	assert.Nil(t, c)
+	_, err = c.Encrypt("secret")
+	assert.ErrorIs(t, err, ErrNoKey)
+
+	_, err = NewCipher("too-short")
+	assert.Error(t, err)
+
+	_, err = NewCipher("AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=")
+	assert.NoError(t, err, "base64 keys are accepted")
+}
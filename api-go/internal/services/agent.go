@@ -1,27 +1,48 @@
 package services
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"zerotrace/api/internal/config"
 	"zerotrace/api/internal/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// ErrInvalidAgentCursor is returned for agent list cursors ListAgents did not issue
+var ErrInvalidAgentCursor = errors.New("invalid agent cursor")
+
+// agentOfflineTimeouts is how many heartbeat timeouts a degraded agent has
+// before it is offline
+const agentOfflineTimeouts = 3
+
 // AgentService manages agent registration and heartbeats
 type AgentService struct {
-	agents map[uuid.UUID]*models.Agent
-	mutex  sync.RWMutex
-	db     *gorm.DB
+	agents           map[uuid.UUID]*models.Agent
+	tags             map[uuid.UUID]map[string]string // Agent -> tag key -> value, mirroring agent_tags
+	mutex            sync.RWMutex
+	db               *gorm.DB
+	heartbeatTimeout time.Duration
 }
 
 // NewAgentService creates a new agent service
-func NewAgentService(db *gorm.DB) *AgentService {
+func NewAgentService(db *gorm.DB, cfg *config.Config) *AgentService {
+	heartbeatTimeout := cfg.AgentHeartbeatTimeout
+	if heartbeatTimeout <= 0 {
+		heartbeatTimeout = 90 * time.Second
+	}
+
 	// Restore agents from DB on startup
 	agents := make(map[uuid.UUID]*models.Agent)
 	var loadedAgents []models.Agent
@@ -35,10 +56,17 @@ func NewAgentService(db *gorm.DB) *AgentService {
 	} else {
 		log.Printf("[NewAgentService] Failed to load agents from DB: %v", err)
 	}
+	tags, err := loadAgentTags(db)
+	if err != nil {
+		log.Printf("[NewAgentService] Failed to load agent tags from DB: %v", err)
+		tags = make(map[uuid.UUID]map[string]string)
+	}
 
 	return &AgentService{
-		agents: agents,
-		db:     db,
+		agents:           agents,
+		tags:             tags,
+		db:               db,
+		heartbeatTimeout: heartbeatTimeout,
 	}
 }
 
@@ -53,12 +81,17 @@ func (as *AgentService) RegisterAgent(agent models.Agent) (*models.Agent, error)
 	}
 
 	agent.LastSeen = time.Now()
+	if existing, exists := as.agents[agent.ID]; exists {
+		agent.Status, agent.StatusChangedAt = existing.Status, existing.StatusChangedAt
+	}
+	event := setAgentStatus(&agent, models.AgentStatusOnline, agent.LastSeen)
 	as.agents[agent.ID] = &agent
 
 	// Persist to DB
 	if err := as.db.Save(&agent).Error; err != nil {
 		log.Printf("Failed to persist registered agent %s: %v", agent.ID, err)
 	}
+	as.recordStatusEvent(event)
 
 	log.Printf("Agent registered or updated: %s", agent.ID)
 	return &agent, nil
@@ -76,7 +109,6 @@ func (as *AgentService) UpdateAgentHeartbeat(heartbeat models.AgentHeartbeat) er
 			ID:             heartbeat.AgentID,
 			OrganizationID: heartbeat.OrganizationID,
 			Name:           heartbeat.AgentName,
-			Status:         "active",
 			LastSeen:       time.Now(),
 			CreatedAt:      time.Now(),
 			UpdatedAt:      time.Now(),
@@ -88,7 +120,7 @@ func (as *AgentService) UpdateAgentHeartbeat(heartbeat models.AgentHeartbeat) er
 	agent.LastSeen = time.Now()
 	agent.CPUUsage = heartbeat.CPUUsage
 	agent.MemoryUsage = heartbeat.MemoryUsage
-	agent.Status = heartbeat.Status
+	event := setAgentStatus(agent, models.AgentStatusOnline, agent.LastSeen)
 
 	// Log metadata before merge
 	log.Printf("[UpdateAgentHeartbeat] Metadata BEFORE merge: %v", getMetadataKeys(agent.Metadata))
@@ -111,10 +143,111 @@ func (as *AgentService) UpdateAgentHeartbeat(heartbeat models.AgentHeartbeat) er
 	if err := as.db.Save(agent).Error; err != nil {
 		log.Printf("Failed to persist agent heartbeat %s: %v", agent.ID, err)
 	}
+	as.recordStatusEvent(event)
 
 	return nil
 }
 
+// HeartbeatTimeout returns how long an agent may go without a heartbeat
+// before it is degraded. It is offline after agentOfflineTimeouts times as long.
+func (as *AgentService) HeartbeatTimeout() time.Duration {
+	return as.heartbeatTimeout
+}
+
+// statusAt returns the status of an agent last seen at lastSeen, as of now
+func (as *AgentService) statusAt(lastSeen, now time.Time) string {
+	switch age := now.Sub(lastSeen); {
+	case age < as.heartbeatTimeout:
+		return models.AgentStatusOnline
+	case age < agentOfflineTimeouts*as.heartbeatTimeout:
+		return models.AgentStatusDegraded
+	default:
+		return models.AgentStatusOffline
+	}
+}
+
+// setAgentStatus moves an agent to a status, returning the event to record
+// for the change, or nil if the agent already had the status
+func setAgentStatus(agent *models.Agent, status string, now time.Time) *models.AgentStatusEvent {
+	if agent.Status == status {
+		return nil
+	}
+	event := &models.AgentStatusEvent{
+		AgentID:        agent.ID,
+		OrganizationID: agent.OrganizationID,
+		From:           agent.Status,
+		To:             status,
+		LastSeen:       agent.LastSeen,
+		CreatedAt:      now,
+	}
+	agent.Status = status
+	agent.StatusChangedAt = &now
+	return event
+}
+
+// recordStatusEvent stores an agent status change, if there was one
+func (as *AgentService) recordStatusEvent(event *models.AgentStatusEvent) {
+	if event == nil {
+		return
+	}
+	if err := as.db.Create(event).Error; err != nil {
+		log.Printf("Failed to record status change of agent %s to %s: %v", event.AgentID, event.To, err)
+	}
+}
+
+// RefreshAgentStatuses moves every agent to the status the age of its last
+// heartbeat puts it in, persisting each change and recording an event for it.
+// Changes are persisted under the lock, like heartbeats, so a heartbeat can't
+// be overwritten by the stale status it replaced.
+func (as *AgentService) RefreshAgentStatuses() {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	for _, event := range as.refreshStatuses(time.Now()) {
+		err := as.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Agent{}).Where("id = ?", event.AgentID).
+				Updates(map[string]interface{}{"status": event.To, "status_changed_at": event.CreatedAt}).Error; err != nil {
+				return err
+			}
+			return tx.Create(event).Error
+		})
+		if err != nil {
+			log.Printf("Failed to record status change of agent %s to %s: %v", event.AgentID, event.To, err)
+		}
+	}
+}
+
+// refreshStatuses updates the agents' statuses as of now, returning an event
+// for each that changed. The caller holds the write lock.
+func (as *AgentService) refreshStatuses(now time.Time) []*models.AgentStatusEvent {
+	var events []*models.AgentStatusEvent
+	for _, agent := range as.agents {
+		if event := setAgentStatus(agent, as.statusAt(agent.LastSeen, now), now); event != nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// StartStatusRoutine refreshes agent statuses a few times per heartbeat
+// timeout, so an agent is marked degraded or offline soon after it stops
+// sending heartbeats, until ctx is cancelled
+func (as *AgentService) StartStatusRoutine(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(as.heartbeatTimeout / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				as.RefreshAgentStatuses()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
 // GetAgent gets an agent by ID
 func (as *AgentService) GetAgent(agentID uuid.UUID) (*models.Agent, bool) {
 	as.mutex.RLock()
@@ -154,22 +287,118 @@ func (as *AgentService) GetAllAgents() []*models.Agent {
 	return agents
 }
 
+// ListAgents returns up to limit of the agents the filter selects, ordered by
+// creation time and then ID, starting after the agent cursor points at, or at
+// the first agent for an empty cursor. It also returns the cursor of the next
+// page, which is empty on the last page. Unlike an offset, a cursor's page
+// doesn't shift when agents register while a client pages through.
+func (as *AgentService) ListAgents(filter models.AgentFilter, limit int, cursor string) ([]*models.Agent, string, error) {
+	var after *models.Agent
+	if cursor != "" {
+		createdAt, id, err := decodeAgentCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &models.Agent{ID: id, CreatedAt: createdAt}
+	}
+
+	agents, err := as.FilterAgents(filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if agents == nil {
+		agents = []*models.Agent{}
+	}
+
+	sort.Slice(agents, func(i, j int) bool { return agentBefore(agents[i], agents[j]) })
+	start := 0
+	if after != nil {
+		start = sort.Search(len(agents), func(i int) bool { return agentBefore(after, agents[i]) })
+	}
+	end := min(start+limit, len(agents))
+
+	page := agents[start:end]
+	next := ""
+	if end < len(agents) && len(page) > 0 {
+		next = encodeAgentCursor(page[len(page)-1])
+	}
+	return page, next, nil
+}
+
+// agentBefore orders agents by creation time and then ID
+func agentBefore(a, b *models.Agent) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+// encodeAgentCursor returns an opaque cursor pointing at an agent
+func encodeAgentCursor(agent *models.Agent) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(agent.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + agent.ID.String()))
+}
+
+// decodeAgentCursor returns the creation time and ID an agent cursor points at
+func decodeAgentCursor(cursor string) (time.Time, uuid.UUID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("%w: %v", ErrInvalidAgentCursor, err)
+	}
+	rawTime, rawID, ok := strings.Cut(string(data), ",")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidAgentCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, rawTime)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("%w: %v", ErrInvalidAgentCursor, err)
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("%w: %v", ErrInvalidAgentCursor, err)
+	}
+	return createdAt, id, nil
+}
+
 // GetOnlineAgents gets online agents for an organization
 func (as *AgentService) GetOnlineAgents(organizationID uuid.UUID) []*models.Agent {
 	as.mutex.RLock()
 	defer as.mutex.RUnlock()
 
 	var agents []*models.Agent
-	offlineThreshold := time.Now().Add(-5 * time.Minute) // Consider offline after 5 minutes
+	now := time.Now()
 
 	for _, agent := range as.agents {
-		if agent.OrganizationID == organizationID && agent.LastSeen.After(offlineThreshold) {
+		if agent.OrganizationID == organizationID && as.statusAt(agent.LastSeen, now) == models.AgentStatusOnline {
 			agents = append(agents, agent)
 		}
 	}
 	return agents
 }
 
+// GetAgentVulnerability finds a vulnerability reported by any of an
+// organization's agents by its ID
+func (as *AgentService) GetAgentVulnerability(organizationID uuid.UUID, vulnID string) (*models.Vulnerability, *models.Agent, bool) {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	for _, agent := range as.agents {
+		if agent.OrganizationID != organizationID {
+			continue
+		}
+		vulns, ok := agent.Metadata["vulnerabilities"].([]models.Vulnerability)
+		if !ok {
+			continue
+		}
+		for i := range vulns {
+			if vulns[i].ID == vulnID {
+				vuln := vulns[i]
+				return &vuln, agent, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
 // RemoveAgent removes an agent
 func (as *AgentService) RemoveAgent(agentID uuid.UUID) {
 	as.mutex.Lock()
@@ -198,14 +427,19 @@ func (as *AgentService) GetAgentStats(organizationID uuid.UUID) map[string]inter
 	defer as.mutex.RUnlock()
 
 	stats := map[string]interface{}{
-		"total_agents":   0,
-		"online_agents":  0,
-		"offline_agents": 0,
-		"total_cpu":      0.0,
-		"total_memory":   0.0,
+		"total_agents":    0,
+		"online_agents":   0,
+		"degraded_agents": 0,
+		"offline_agents":  0,
+		"total_cpu":       0.0,
+		"total_memory":    0.0,
+		// Thresholds on the time since an agent's last heartbeat
+		"heartbeat_timeout_seconds": as.heartbeatTimeout.Seconds(),
+		"degraded_after_seconds":    as.heartbeatTimeout.Seconds(),
+		"offline_after_seconds":     (agentOfflineTimeouts * as.heartbeatTimeout).Seconds(),
 	}
 
-	offlineThreshold := time.Now().Add(-5 * time.Minute)
+	now := time.Now()
 
 	for _, agent := range as.agents {
 		if agent.OrganizationID == organizationID {
@@ -213,11 +447,8 @@ func (as *AgentService) GetAgentStats(organizationID uuid.UUID) map[string]inter
 			stats["total_cpu"] = stats["total_cpu"].(float64) + agent.CPUUsage
 			stats["total_memory"] = stats["total_memory"].(float64) + agent.MemoryUsage
 
-			if agent.LastSeen.After(offlineThreshold) {
-				stats["online_agents"] = stats["online_agents"].(int) + 1
-			} else {
-				stats["offline_agents"] = stats["offline_agents"].(int) + 1
-			}
+			key := as.statusAt(agent.LastSeen, now) + "_agents"
+			stats[key] = stats[key].(int) + 1
 		}
 	}
 
@@ -241,12 +472,13 @@ func (as *AgentService) GetPublicAgentStats() map[string]interface{} {
 	stats := map[string]interface{}{
 		"total":     0,
 		"online":    0,
+		"degraded":  0,
 		"offline":   0,
 		"avgCpu":    0.0,
 		"avgMemory": 0.0,
 	}
 
-	offlineThreshold := time.Now().Add(-5 * time.Minute)
+	now := time.Now()
 	totalCpu := 0.0
 	totalMemory := 0.0
 
@@ -255,11 +487,8 @@ func (as *AgentService) GetPublicAgentStats() map[string]interface{} {
 		totalCpu += agent.CPUUsage
 		totalMemory += agent.MemoryUsage
 
-		if agent.LastSeen.After(offlineThreshold) {
-			stats["online"] = stats["online"].(int) + 1
-		} else {
-			stats["offline"] = stats["offline"].(int) + 1
-		}
+		status := as.statusAt(agent.LastSeen, now)
+		stats[status] = stats[status].(int) + 1
 	}
 
 	// Calculate averages
@@ -271,6 +500,19 @@ func (as *AgentService) GetPublicAgentStats() map[string]interface{} {
 	return stats
 }
 
+// AgentStatusCounts returns how many agents are in each status
+func (as *AgentService) AgentStatusCounts() map[string]int {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	now := time.Now()
+	for _, agent := range as.agents {
+		counts[as.statusAt(agent.LastSeen, now)]++
+	}
+	return counts
+}
+
 // StartCleanupRoutine starts the cleanup routine
 func (as *AgentService) StartCleanupRoutine() {
 	go func() {
@@ -283,24 +525,27 @@ func (as *AgentService) StartCleanupRoutine() {
 	}()
 }
 
-// UpdateAgentResults updates agent with scan results
-func (as *AgentService) UpdateAgentResults(agentID string, results []models.AgentScanResult, metadata map[string]interface{}) error {
-	as.mutex.Lock()
-	defer as.mutex.Unlock()
+// stageAgentResults applies scan results to a copy of an agent, leaving the
+// agent itself untouched until the results are committed with applyAgentResults
+func (as *AgentService) stageAgentResults(agentID string, results []models.AgentScanResult, metadata map[string]interface{}) (*models.Agent, error) {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
 
 	log.Printf("[UpdateAgentResults] Received agent ID: '%s' (length: %d)", agentID, len(agentID))
 
 	agentUUID, err := uuid.Parse(agentID)
 	if err != nil {
 		log.Printf("[UpdateAgentResults] Invalid agent ID format: %s, error: %v", agentID, err)
-		return fmt.Errorf("invalid agent ID format: %w", err)
+		return nil, fmt.Errorf("invalid agent ID format: %w", err)
 	}
 
-	agent, exists := as.agents[agentUUID]
+	cached, exists := as.agents[agentUUID]
 	if !exists {
 		log.Printf("[UpdateAgentResults] Agent not found: %s", agentID)
-		return fmt.Errorf("agent not found: %s", agentID)
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
 	}
+	agent := *cached
+	agent.Metadata = maps.Clone(cached.Metadata)
 
 	// Update agent with scan results
 	agent.LastSeen = time.Now()
@@ -340,14 +585,28 @@ func (as *AgentService) UpdateAgentResults(agentID string, results []models.Agen
 		var newDependencies []models.Dependency
 		var newVulnerabilities []models.Vulnerability
 
+		// Attribute newly seen vulnerabilities to the dependency change that introduced them
+		seenVulnerabilities := existingVulnerabilities
+		for i := range results {
+			attributeVulnerabilities(agentUUID, &results[i], existingDependencies, seenVulnerabilities)
+			seenVulnerabilities = append(seenVulnerabilities[:len(seenVulnerabilities):len(seenVulnerabilities)], results[i].Vulnerabilities...)
+		}
+
+		var resolved []string
 		for _, result := range results {
 			// Count dependencies as assets (agent sends Dependencies)
 			totalAssets += len(result.Dependencies)
 			newDependencies = append(newDependencies, result.Dependencies...)
+			newVulnerabilities = append(newVulnerabilities, result.Vulnerabilities...)
 
-			for _, vuln := range result.Vulnerabilities {
+			// Findings a delta didn't resend still count towards the scan
+			found := result.Vulnerabilities
+			if result.Delta != nil {
+				found = append(vulnerabilitiesByKey(existingVulnerabilities, result.Delta.Unchanged), found...)
+				resolved = append(resolved, result.Delta.Resolved...)
+			}
+			for _, vuln := range found {
 				totalVulns++
-				newVulnerabilities = append(newVulnerabilities, vuln)
 				switch vuln.Severity {
 				case "critical":
 					criticalVulns++
@@ -361,9 +620,10 @@ func (as *AgentService) UpdateAgentResults(agentID string, results []models.Agen
 			}
 		}
 
-		// Merge with existing data
+		// Merge with existing data, replacing findings reported again rather
+		// than duplicating them
 		allDependencies := append(existingDependencies, newDependencies...)
-		allVulnerabilities := append(existingVulnerabilities, newVulnerabilities...)
+		allVulnerabilities := mergeVulnerabilities(existingVulnerabilities, newVulnerabilities, resolved)
 
 		// Store actual data arrays
 		log.Printf("[UpdateAgentResults] Storing %d dependencies and %d vulnerabilities in metadata (existing: %d deps, %d vulns)", len(allDependencies), len(allVulnerabilities), len(existingDependencies), len(existingVulnerabilities))
@@ -371,30 +631,6 @@ func (as *AgentService) UpdateAgentResults(agentID string, results []models.Agen
 		agent.Metadata["vulnerabilities"] = allVulnerabilities
 		log.Printf("[UpdateAgentResults] Dependencies stored successfully")
 
-		// PERSISTENCE: Save Software/Dependencies to Database
-		if len(allDependencies) > 0 {
-			go func(agentID uuid.UUID, deps []models.Dependency) {
-				for _, dep := range deps {
-					software := models.Software{
-						AgentID:   agentID,
-						Name:      dep.Name,
-						Version:   dep.Version,
-						Type:      dep.Type,
-						Status:    "active",
-						Vendor:    dep.Description, // Using description as vendor for now
-						CreatedAt: time.Now(),
-						UpdatedAt: time.Now(),
-					}
-					// Upsert software based on AgentID + Name + Version
-					if err := as.db.Where("agent_id = ? AND name = ? AND version = ?", agentID, dep.Name, dep.Version).
-						FirstOrCreate(&software).Error; err != nil {
-						log.Printf("Failed to persist software %s: %v", dep.Name, err)
-					}
-				}
-				log.Printf("Persisted %d software items for agent %s", len(deps), agentID)
-			}(agentUUID, allDependencies)
-		}
-
 		// Store counts in metadata
 		agent.Metadata["total_vulnerabilities"] = totalVulns
 		agent.Metadata["critical_vulnerabilities"] = criticalVulns
@@ -404,6 +640,16 @@ func (as *AgentService) UpdateAgentResults(agentID string, results []models.Agen
 		agent.Metadata["total_assets"] = totalAssets
 		agent.Metadata["last_scan_time"] = time.Now().Format(time.RFC3339)
 
+		// Keep the latest configuration check states so they can be captured as
+		// a baseline, and the checks that could not assess their setting
+		for _, result := range results {
+			if settings, ok := result.Metadata["config_settings"]; ok {
+				agent.Metadata["config_settings"] = settings
+				agent.Metadata["host_group"] = result.Metadata["host_group"]
+				agent.Metadata["unassessed_checks"] = result.Metadata["unassessed_checks"]
+			}
+		}
+
 		// Start async enrichment if we have dependencies
 		if len(allDependencies) > 0 {
 			log.Printf("[UpdateAgentResults] Starting async enrichment for agent %s with %d applications", agentID, len(allDependencies))
@@ -424,14 +670,101 @@ func (as *AgentService) UpdateAgentResults(agentID string, results []models.Agen
 
 	log.Printf("[UpdateAgentResults] Final metadata keys: %v", getMetadataKeys(agent.Metadata))
 
-	// Persist to DB
-	if err := as.db.Save(agent).Error; err != nil {
-		log.Printf("Failed to persist agent results %s: %v", agent.ID, err)
+	return &agent, nil
+}
+
+// mergeVulnerabilities returns existing with the findings keyed in resolved
+// removed and each of reported in place of the existing finding with its key
+func mergeVulnerabilities(existing, reported []models.Vulnerability, resolved []string) []models.Vulnerability {
+	drop := make(map[string]bool, len(resolved))
+	for _, key := range resolved {
+		drop[key] = true
+	}
+	index := make(map[string]int, len(existing)+len(reported))
+	merged := make([]models.Vulnerability, 0, len(existing)+len(reported))
+	for _, set := range [][]models.Vulnerability{existing, reported} {
+		for _, vuln := range set {
+			key := FindingKey(&vuln)
+			if drop[key] {
+				continue
+			}
+			if i, ok := index[key]; ok {
+				merged[i] = vuln
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, vuln)
+		}
+	}
+	return merged
+}
+
+// vulnerabilitiesByKey returns the findings of vulns with the given keys
+func vulnerabilitiesByKey(vulns []models.Vulnerability, keys []string) []models.Vulnerability {
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+	var found []models.Vulnerability
+	for i := range vulns {
+		if key := FindingKey(&vulns[i]); wanted[key] {
+			wanted[key] = false
+			found = append(found, vulns[i])
+		}
 	}
+	return found
+}
 
+// saveAgentResults writes an agent staged by stageAgentResults, and the
+// software its results reported, through tx
+func (as *AgentService) saveAgentResults(tx *gorm.DB, agent *models.Agent, results []models.AgentScanResult) error {
+	err := tx.Model(agent).Select("last_seen", "updated_at", "risk_score", "metadata").Updates(agent).Error
+	if err != nil {
+		return fmt.Errorf("failed to persist agent results: %w", err)
+	}
+
+	// Upsert software based on AgentID + Name + Version
+	persisted := 0
+	for _, result := range results {
+		for _, dep := range result.Dependencies {
+			software := models.Software{
+				AgentID:   agent.ID,
+				Name:      dep.Name,
+				Version:   dep.Version,
+				Type:      dep.Type,
+				Status:    "active",
+				Vendor:    dep.Description, // Using description as vendor for now
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+			if err := tx.Where("agent_id = ? AND name = ? AND version = ?", agent.ID, dep.Name, dep.Version).
+				FirstOrCreate(&software).Error; err != nil {
+				return fmt.Errorf("failed to persist software %s: %w", dep.Name, err)
+			}
+			persisted++
+		}
+	}
+	if persisted > 0 {
+		log.Printf("Persisted %d software items for agent %s", persisted, agent.ID)
+	}
 	return nil
 }
 
+// applyAgentResults makes a committed agent staged by stageAgentResults current
+func (as *AgentService) applyAgentResults(staged *models.Agent) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	agent, exists := as.agents[staged.ID]
+	if !exists {
+		return
+	}
+	agent.LastSeen = staged.LastSeen
+	agent.UpdatedAt = staged.UpdatedAt
+	agent.RiskScore = staged.RiskScore
+	agent.Metadata = staged.Metadata
+}
+
 // Helper function to get metadata keys for debugging
 func getMetadataKeys(metadata map[string]interface{}) []string {
 	keys := make([]string, 0, len(metadata))
@@ -664,56 +997,6 @@ func (as *AgentService) UpdateAgentMetadata(agentID string, metadata map[string]
 	agent.LastSeen = time.Now()
 	agent.UpdatedAt = time.Now()
 
-	// PERSISTENCE: Save NetworkHost to Database
-	if networkResults, ok := metadata["network_scan_result"].(map[string]interface{}); ok {
-		go func(agentID uuid.UUID, results map[string]interface{}) {
-			// Iterate over found hosts (assuming standard Nmap/Naabu structure)
-			// This depends on the exact JSON structure of scan_result
-			// For now, checks if there's a "hosts" array or similar
-
-			// Example structure handling (adjust based on actual agent output)
-			if hosts, ok := results["hosts"].([]interface{}); ok {
-				for _, h := range hosts {
-					if hostMap, ok := h.(map[string]interface{}); ok {
-						ip, _ := hostMap["ip"].(string)
-						if ip == "" {
-							continue
-						}
-
-						hostname, _ := hostMap["hostname"].(string)
-						// ... map other fields
-
-						networkHost := models.NetworkHost{
-							AgentID:   agentID,
-							IPAddress: ip,
-							Hostname:  hostname,
-							Status:    "active",
-							LastSeen:  time.Now(),
-							CreatedAt: time.Now(),
-							UpdatedAt: time.Now(),
-						}
-
-						if ports, ok := hostMap["ports"].([]interface{}); ok {
-							var openPorts []int
-							for _, p := range ports {
-								if pNum, ok := p.(float64); ok {
-									openPorts = append(openPorts, int(pNum))
-								}
-							}
-							networkHost.OpenPorts = openPorts
-						}
-
-						// Upsert
-						if err := as.db.Where("agent_id = ? AND ip_address = ?", agentID, ip).
-							FirstOrCreate(&networkHost).Error; err != nil {
-							log.Printf("Failed to persist network host %s: %v", ip, err)
-						}
-					}
-				}
-			}
-		}(agentUUID, networkResults)
-	}
-
 	log.Printf("[UpdateAgentMetadata] Updated metadata for agent %s", agentID)
 
 	// Persist to DB (Update the agent record itself with new metadata)
@@ -723,3 +1006,32 @@ func (as *AgentService) UpdateAgentMetadata(agentID string, metadata map[string]
 
 	return nil
 }
+
+// SetAgentRiskScore records an agent's consolidated host risk score
+func (as *AgentService) SetAgentRiskScore(agentID uuid.UUID, score float64) error {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	agent, exists := as.agents[agentID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	agent.RiskScore = score
+	return as.db.Model(agent).Update("risk_score", score).Error
+}
+
+// SetAgentMetadataValue sets one metadata key of an agent, leaving the rest of the agent as it is
+func (as *AgentService) SetAgentMetadataValue(agentID uuid.UUID, key string, value interface{}) error {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	agent, exists := as.agents[agentID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	if agent.Metadata == nil {
+		agent.Metadata = make(map[string]interface{})
+	}
+	agent.Metadata[key] = value
+	return as.db.Save(agent).Error
+}
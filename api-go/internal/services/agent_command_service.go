@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoOnlineAgents is returned when a re-scan is requested for an organization with no online agents
+	ErrNoOnlineAgents = errors.New("organization has no online agents")
+	// ErrCommandNotFound is returned when a command does not exist or belongs to another agent
+	ErrCommandNotFound = errors.New("command not found")
+)
+
+// defaultRescanTypes are the scans a bulk re-scan runs when none are specified
+var defaultRescanTypes = []string{models.RescanTypeSoftware, models.RescanTypeSystem, models.RescanTypeNetwork}
+
+// AgentCommandService queues commands for agents, hands them out on heartbeat
+// and tracks their progress
+type AgentCommandService struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewAgentCommandService creates a new agent command service
+func NewAgentCommandService(db *gorm.DB, cfg *config.Config) *AgentCommandService {
+	ttl := cfg.AgentCommandTTL
+	if ttl <= 0 {
+		ttl = 2 * time.Hour
+	}
+
+	return &AgentCommandService{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+// CreateRescanBatch queues a scan_now command for every given agent under one batch
+func (s *AgentCommandService) CreateRescanBatch(organizationID uuid.UUID, agents []*models.Agent, req *models.RescanRequest) (*models.RescanBatch, error) {
+	if len(agents) == 0 {
+		return nil, ErrNoOnlineAgents
+	}
+
+	scanTypes := req.ScanTypes
+	if len(scanTypes) == 0 {
+		scanTypes = defaultRescanTypes
+	}
+	for _, scanType := range scanTypes {
+		switch scanType {
+		case models.RescanTypeSoftware, models.RescanTypeSystem, models.RescanTypeNetwork:
+		default:
+			return nil, fmt.Errorf("unsupported scan type %q", scanType)
+		}
+	}
+
+	now := time.Now()
+	batch := &models.RescanBatch{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		Reason:         req.Reason,
+		ScanTypes:      scanTypes,
+		AgentCount:     len(agents),
+		CreatedAt:      now,
+	}
+
+	commands := make([]models.AgentCommand, 0, len(agents))
+	for _, agent := range agents {
+		cmd := s.newCommand(agent.ID, organizationID, models.AgentCommandScanNow, map[string]interface{}{
+			"scan_types": scanTypes,
+			"reason":     req.Reason,
+		}, now)
+		cmd.BatchID = &batch.ID
+		commands = append(commands, cmd)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		return tx.Create(&commands).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue rescan: %w", err)
+	}
+
+	batch.Progress = models.RescanBatchProgress{Total: len(commands), Pending: len(commands)}
+	return batch, nil
+}
+
+// GetRescanBatch returns a rescan batch with its current progress
+func (s *AgentCommandService) GetRescanBatch(organizationID, batchID uuid.UUID) (*models.RescanBatch, error) {
+	var batch models.RescanBatch
+	if err := s.db.Where("id = ? AND organization_id = ?", batchID, organizationID).First(&batch).Error; err != nil {
+		return nil, err
+	}
+
+	s.expireCommands()
+
+	var commands []models.AgentCommand
+	if err := s.db.Where("batch_id = ?", batchID).Find(&commands).Error; err != nil {
+		return nil, err
+	}
+
+	progress := models.RescanBatchProgress{Total: len(commands)}
+	for _, cmd := range commands {
+		switch cmd.Status {
+		case models.AgentCommandPending, models.AgentCommandDelivered:
+			progress.Pending++
+		case models.AgentCommandAcked:
+			progress.Acked++
+		case models.AgentCommandCompleted:
+			progress.Completed++
+		case models.AgentCommandFailed:
+			progress.Failed++
+			progress.FailedAgents = append(progress.FailedAgents, models.RescanAgentError{
+				AgentID: cmd.AgentID,
+				Error:   cmd.Error,
+			})
+		}
+	}
+	if progress.Total > 0 {
+		progress.Percent = float64(progress.Completed+progress.Failed) / float64(progress.Total) * 100
+	}
+	progress.Done = progress.Pending == 0 && progress.Acked == 0
+
+	batch.Progress = progress
+	return &batch, nil
+}
+
+// newCommand builds a pending command, to be created by the caller
+func (s *AgentCommandService) newCommand(agentID, organizationID uuid.UUID, commandType string, payload map[string]interface{}, now time.Time) models.AgentCommand {
+	return models.AgentCommand{
+		ID:             uuid.New(),
+		AgentID:        agentID,
+		OrganizationID: organizationID,
+		Type:           commandType,
+		Payload:        payload,
+		Status:         models.AgentCommandPending,
+		ExpiresAt:      now.Add(s.ttl),
+	}
+}
+
+// GetCommand returns a command, failing it first if it has expired
+func (s *AgentCommandService) GetCommand(commandID uuid.UUID) (*models.AgentCommand, error) {
+	s.expireCommands()
+
+	var cmd models.AgentCommand
+	if err := s.db.Where("id = ?", commandID).First(&cmd).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommandNotFound
+		}
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// PendingCommands returns an agent's queued commands and marks them delivered
+func (s *AgentCommandService) PendingCommands(agentID uuid.UUID) ([]models.AgentCommand, error) {
+	s.expireCommands()
+
+	var commands []models.AgentCommand
+	if err := s.db.Where("agent_id = ? AND status = ?", agentID, models.AgentCommandPending).
+		Order("created_at ASC").Find(&commands).Error; err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 {
+		return commands, nil
+	}
+
+	now := time.Now()
+	ids := make([]uuid.UUID, len(commands))
+	for i := range commands {
+		ids[i] = commands[i].ID
+		commands[i].Status = models.AgentCommandDelivered
+		commands[i].DeliveredAt = &now
+	}
+	if err := s.db.Model(&models.AgentCommand{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":       models.AgentCommandDelivered,
+		"delivered_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return commands, nil
+}
+
+// UpdateCommandStatus records an agent's progress on one of its commands
+func (s *AgentCommandService) UpdateCommandStatus(agentID, commandID uuid.UUID, update *models.AgentCommandStatusUpdate) (*models.AgentCommand, error) {
+	var cmd models.AgentCommand
+	if err := s.db.Where("id = ? AND agent_id = ?", commandID, agentID).First(&cmd).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommandNotFound
+		}
+		return nil, err
+	}
+
+	if cmd.Status == models.AgentCommandCompleted || cmd.Status == models.AgentCommandFailed {
+		return nil, fmt.Errorf("command is already %s", cmd.Status)
+	}
+
+	now := time.Now()
+	switch update.Status {
+	case models.AgentCommandAcked:
+		// Long-running commands report progress by acknowledging again with a partial result
+		if cmd.AckedAt == nil {
+			cmd.AckedAt = &now
+		}
+		if update.Result != nil {
+			cmd.Result = update.Result
+		}
+	case models.AgentCommandCompleted, models.AgentCommandFailed:
+		if cmd.AckedAt == nil {
+			cmd.AckedAt = &now
+		}
+		cmd.CompletedAt = &now
+		cmd.Error = update.Error
+		cmd.Result = update.Result
+	default:
+		return nil, fmt.Errorf("invalid command status %q", update.Status)
+	}
+	cmd.Status = update.Status
+
+	if err := s.db.Save(&cmd).Error; err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// expireCommands fails commands the agent did not finish before they expired,
+// so that batches of offline or crashed agents still complete
+func (s *AgentCommandService) expireCommands() {
+	s.db.Model(&models.AgentCommand{}).
+		Where("status IN ? AND expires_at < ?", []string{models.AgentCommandPending, models.AgentCommandDelivered, models.AgentCommandAcked}, time.Now()).
+		Updates(map[string]interface{}{
+			"status": models.AgentCommandFailed,
+			"error":  "timed out waiting for agent",
+		})
+}
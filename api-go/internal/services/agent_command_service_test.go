@@ -0,0 +1,136 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCommandService(t *testing.T) *AgentCommandService {
+	db := testdb.Open(t, &models.AgentCommand{}, &models.RescanBatch{})
+	return NewAgentCommandService(db, &config.Config{})
+}
+
+func TestCreateRescanBatch(t *testing.T) {
+	s := newTestCommandService(t)
+	orgID := uuid.New()
+	agents := []*models.Agent{{ID: uuid.New()}, {ID: uuid.New()}}
+
+	_, err := s.CreateRescanBatch(orgID, nil, &models.RescanRequest{})
+	assert.ErrorIs(t, err, ErrNoOnlineAgents)
+
+	_, err = s.CreateRescanBatch(orgID, agents, &models.RescanRequest{ScanTypes: []string{"memory"}})
+	assert.ErrorContains(t, err, "unsupported scan type")
+
+	batch, err := s.CreateRescanBatch(orgID, agents, &models.RescanRequest{Reason: "patch tuesday"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultRescanTypes, batch.ScanTypes)
+	assert.Equal(t, models.RescanBatchProgress{Total: 2, Pending: 2}, batch.Progress)
+
+	for _, agent := range agents {
+		commands, err := s.PendingCommands(agent.ID)
+		require.NoError(t, err)
+		require.Len(t, commands, 1)
+		assert.Equal(t, models.AgentCommandScanNow, commands[0].Type)
+		assert.Equal(t, models.AgentCommandDelivered, commands[0].Status)
+		assert.Equal(t, &batch.ID, commands[0].BatchID)
+		assert.Equal(t, "patch tuesday", commands[0].Payload["reason"])
+
+		again, err := s.PendingCommands(agent.ID)
+		require.NoError(t, err)
+		assert.Empty(t, again, "commands are delivered once")
+	}
+
+	_, err = s.GetRescanBatch(uuid.New(), batch.ID)
+	assert.Error(t, err, "batches are scoped to their organization")
+}
+
+func TestUpdateCommandStatus(t *testing.T) {
+	s := newTestCommandService(t)
+	orgID := uuid.New()
+	agentID := uuid.New()
+	batch, err := s.CreateRescanBatch(orgID, []*models.Agent{{ID: agentID}}, &models.RescanRequest{})
+	require.NoError(t, err)
+	commands, err := s.PendingCommands(agentID)
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	commandID := commands[0].ID
+
+	_, err = s.UpdateCommandStatus(uuid.New(), commandID, &models.AgentCommandStatusUpdate{Status: models.AgentCommandAcked})
+	assert.ErrorIs(t, err, ErrCommandNotFound, "agents can only update their own commands")
+
+	_, err = s.UpdateCommandStatus(agentID, commandID, &models.AgentCommandStatusUpdate{Status: "paused"})
+	assert.ErrorContains(t, err, "invalid command status")
+
+	cmd, err := s.UpdateCommandStatus(agentID, commandID, &models.AgentCommandStatusUpdate{Status: models.AgentCommandAcked})
+	require.NoError(t, err)
+	require.NotNil(t, cmd.AckedAt)
+	ackedAt := *cmd.AckedAt
+
+	got, err := s.GetRescanBatch(orgID, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Progress.Acked)
+	assert.False(t, got.Progress.Done)
+
+	cmd, err = s.UpdateCommandStatus(agentID, commandID, &models.AgentCommandStatusUpdate{
+		Status: models.AgentCommandCompleted,
+		Result: map[string]interface{}{"findings": float64(3)},
+	})
+	require.NoError(t, err)
+	assert.True(t, cmd.AckedAt.Equal(ackedAt), "completing keeps the first ack time")
+	assert.NotNil(t, cmd.CompletedAt)
+
+	_, err = s.UpdateCommandStatus(agentID, commandID, &models.AgentCommandStatusUpdate{Status: models.AgentCommandFailed})
+	assert.ErrorContains(t, err, "already completed")
+
+	stored, err := s.GetCommand(commandID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AgentCommandCompleted, stored.Status)
+	assert.Equal(t, float64(3), stored.Result["findings"])
+
+	got, err = s.GetRescanBatch(orgID, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Progress.Completed)
+	assert.Equal(t, float64(100), got.Progress.Percent)
+	assert.True(t, got.Progress.Done)
+
+	_, err = s.GetCommand(uuid.New())
+	assert.ErrorIs(t, err, ErrCommandNotFound)
+}
+
+func TestRescanBatchExpiry(t *testing.T) {
+	s := newTestCommandService(t)
+	orgID := uuid.New()
+	online, offline := uuid.New(), uuid.New()
+	batch, err := s.CreateRescanBatch(orgID, []*models.Agent{{ID: online}, {ID: offline}}, &models.RescanRequest{})
+	require.NoError(t, err)
+
+	commands, err := s.PendingCommands(online)
+	require.NoError(t, err)
+	_, err = s.UpdateCommandStatus(online, commands[0].ID, &models.AgentCommandStatusUpdate{Status: models.AgentCommandCompleted})
+	require.NoError(t, err)
+
+	// The offline agent never picks its command up before it expires
+	require.NoError(t, s.db.Model(&models.AgentCommand{}).Where("agent_id = ?", offline).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	got, err := s.GetRescanBatch(orgID, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Progress.Completed)
+	assert.Equal(t, 1, got.Progress.Failed)
+	assert.True(t, got.Progress.Done)
+	require.Len(t, got.Progress.FailedAgents, 1)
+	assert.Equal(t, offline, got.Progress.FailedAgents[0].AgentID)
+	assert.Equal(t, "timed out waiting for agent", got.Progress.FailedAgents[0].Error)
+
+	pending, err := s.PendingCommands(offline)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "expired commands are not delivered")
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidAgentTag is returned for malformed tag keys, values and agent filters
+	ErrInvalidAgentTag = errors.New("invalid agent tag")
+	// ErrAgentGroupNotFound is returned for unknown agent groups
+	ErrAgentGroupNotFound = errors.New("agent group not found")
+	// ErrAgentGroupExists is returned when an organization already has a group of that name
+	ErrAgentGroupExists = errors.New("agent group already exists")
+	// ErrEmptyAgentFilter is returned when a bulk change would apply to every agent
+	ErrEmptyAgentFilter = errors.New("agent filter must select by organization, agent, tag or group")
+)
+
+// agentTagKey matches tag keys such as env, team.owner or k8s.io/cluster
+var agentTagKey = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,61}[A-Za-z0-9])?$`)
+
+// maxAgentTagValue is the longest tag value, in bytes
+const maxAgentTagValue = 255
+
+// agentTagBatchSize is how many tag rows a bulk change inserts per statement
+const agentTagBatchSize = 500
+
+// loadAgentTags reads every agent's tags, keyed by agent
+func loadAgentTags(db *gorm.DB) (map[uuid.UUID]map[string]string, error) {
+	var rows []models.AgentTag
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	tags := make(map[uuid.UUID]map[string]string)
+	for _, row := range rows {
+		if tags[row.AgentID] == nil {
+			tags[row.AgentID] = make(map[string]string)
+		}
+		tags[row.AgentID][row.Key] = row.Value
+	}
+	return tags, nil
+}
+
+// validateAgentTags checks the keys and values of a tag change. A key can't
+// be both set and removed.
+func validateAgentTags(set map[string]string, remove []string) error {
+	if len(set) == 0 && len(remove) == 0 {
+		return fmt.Errorf("%w: no tags to set or remove", ErrInvalidAgentTag)
+	}
+	for key, value := range set {
+		if err := validateAgentTag(key, value, false); err != nil {
+			return err
+		}
+	}
+	for _, key := range remove {
+		if err := validateAgentTag(key, "", true); err != nil {
+			return err
+		}
+		if _, ok := set[key]; ok {
+			return fmt.Errorf("%w: tag %q is both set and removed", ErrInvalidAgentTag, key)
+		}
+	}
+	return nil
+}
+
+// validateAgentTag checks one tag; anyValue allows an empty value, as
+// selectors and filters use to match any value
+func validateAgentTag(key, value string, anyValue bool) error {
+	if !agentTagKey.MatchString(key) {
+		return fmt.Errorf("%w: key %q must be 1-63 letters, digits, '.', '_', '-' or '/', starting and ending with a letter or digit", ErrInvalidAgentTag, key)
+	}
+	if value == "" && !anyValue {
+		return fmt.Errorf("%w: tag %q needs a value", ErrInvalidAgentTag, key)
+	}
+	if len(value) > maxAgentTagValue || strings.TrimSpace(value) != value {
+		return fmt.Errorf("%w: value of tag %q must be at most %d bytes without surrounding spaces", ErrInvalidAgentTag, key, maxAgentTagValue)
+	}
+	return nil
+}
+
+// ParseAgentTagFilter parses tag= query values, each key=value or a bare key
+// matching any value, into a tag selector
+func ParseAgentTagFilter(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	selector := make(map[string]string, len(values))
+	for _, raw := range values {
+		key, value, _ := strings.Cut(raw, "=")
+		if err := validateAgentTag(key, value, true); err != nil {
+			return nil, err
+		}
+		if existing, ok := selector[key]; ok && existing != value {
+			return nil, fmt.Errorf("%w: tag %q is filtered on twice", ErrInvalidAgentTag, key)
+		}
+		selector[key] = value
+	}
+	return selector, nil
+}
+
+// matchesAgentTags reports whether tags has every key in selector, with the
+// selector's value unless that is empty
+func matchesAgentTags(tags, selector map[string]string) bool {
+	for key, want := range selector {
+		value, ok := tags[key]
+		if !ok || (want != "" && value != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// AgentTags returns a copy of an agent's tags
+func (as *AgentService) AgentTags(agentID uuid.UUID) (map[string]string, error) {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	if _, exists := as.agents[agentID]; !exists {
+		return nil, ErrAgentNotFound
+	}
+	tags := maps.Clone(as.tags[agentID])
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return tags, nil
+}
+
+// UpdateAgentTags sets and removes tags on one agent, returning its tags
+func (as *AgentService) UpdateAgentTags(agentID uuid.UUID, set map[string]string, remove []string) (map[string]string, error) {
+	if err := validateAgentTags(set, remove); err != nil {
+		return nil, err
+	}
+	if _, exists := as.GetAgent(agentID); !exists {
+		return nil, ErrAgentNotFound
+	}
+	if err := as.tagAgents([]uuid.UUID{agentID}, set, remove); err != nil {
+		return nil, err
+	}
+	return as.AgentTags(agentID)
+}
+
+// TagAgents sets and removes tags on every one of an organization's agents
+// the filter selects, returning the IDs of the agents changed. The filter
+// must select by something, so a missing filter can't retag the whole
+// organization, and a filter on another organization selects no agents.
+func (as *AgentService) TagAgents(organizationID uuid.UUID, filter models.AgentFilter, set map[string]string, remove []string) ([]uuid.UUID, error) {
+	if filter.Empty() {
+		return nil, ErrEmptyAgentFilter
+	}
+	if err := validateAgentTags(set, remove); err != nil {
+		return nil, err
+	}
+	if filter.OrganizationID != nil && *filter.OrganizationID != organizationID {
+		return []uuid.UUID{}, nil
+	}
+	filter.OrganizationID = &organizationID
+	agents, err := as.FilterAgents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(agents))
+	for _, agent := range agents {
+		ids = append(ids, agent.ID)
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	if err := as.tagAgents(ids, set, remove); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// tagAgents writes a tag change for the given agents in one transaction, then
+// applies it to the in-memory tags
+func (as *AgentService) tagAgents(ids []uuid.UUID, set map[string]string, remove []string) error {
+	if err := as.db.Transaction(func(tx *gorm.DB) error {
+		return saveAgentTags(tx, ids, set, remove)
+	}); err != nil {
+		return fmt.Errorf("failed to save agent tags: %w", err)
+	}
+	as.applyAgentTags(ids, set, remove)
+	return nil
+}
+
+// saveAgentTags upserts the set tags and deletes the removed ones
+func saveAgentTags(tx *gorm.DB, ids []uuid.UUID, set map[string]string, remove []string) error {
+	if len(remove) > 0 {
+		if err := tx.Where("agent_id IN ? AND key IN ?", ids, remove).Delete(&models.AgentTag{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	rows := make([]models.AgentTag, 0, len(ids)*len(set))
+	for _, id := range ids {
+		for key, value := range set {
+			rows = append(rows, models.AgentTag{AgentID: id, Key: key, Value: value})
+		}
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "agent_id"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).CreateInBatches(rows, agentTagBatchSize).Error
+}
+
+// applyAgentTags applies a saved tag change to the in-memory tags
+func (as *AgentService) applyAgentTags(ids []uuid.UUID, set map[string]string, remove []string) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+	if as.tags == nil {
+		as.tags = make(map[uuid.UUID]map[string]string)
+	}
+	for _, id := range ids {
+		tags := as.tags[id]
+		if tags == nil {
+			tags = make(map[string]string, len(set))
+			as.tags[id] = tags
+		}
+		maps.Copy(tags, set)
+		for _, key := range remove {
+			delete(tags, key)
+		}
+	}
+}
+
+// FilterAgents returns the agents a filter selects, in no particular order
+func (as *AgentService) FilterAgents(filter models.AgentFilter) ([]*models.Agent, error) {
+	match, err := as.agentMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+	var agents []*models.Agent
+	for _, agent := range as.agents {
+		if match(agent) {
+			agents = append(agents, agent)
+		}
+	}
+	return agents, nil
+}
+
+// agentMatcher resolves a filter's group into its selector and returns a
+// function reporting whether an agent matches. The function reads the
+// in-memory tags, so callers must hold the agent mutex.
+func (as *AgentService) agentMatcher(filter models.AgentFilter) (func(*models.Agent) bool, error) {
+	for key, value := range filter.Tags {
+		if err := validateAgentTag(key, value, true); err != nil {
+			return nil, err
+		}
+	}
+	switch filter.Status {
+	case "", models.AgentStatusOnline, models.AgentStatusDegraded, models.AgentStatusOffline:
+	default:
+		return nil, fmt.Errorf("%w: status must be %s, %s or %s", ErrInvalidAgentTag, models.AgentStatusOnline, models.AgentStatusDegraded, models.AgentStatusOffline)
+	}
+	selector := maps.Clone(filter.Tags)
+	organizationID := filter.OrganizationID
+	if filter.Group != "" {
+		group, err := as.findAgentGroup(filter.Group, filter.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		if selector == nil {
+			selector = make(map[string]string, len(group.Selector))
+		}
+		for key, value := range group.Selector {
+			// Both must match, so a tag filtered on with two values matches nothing
+			if existing, ok := selector[key]; ok && existing != "" && value != "" && existing != value {
+				return func(*models.Agent) bool { return false }, nil
+			}
+			if selector[key] == "" {
+				selector[key] = value
+			}
+		}
+		organizationID = &group.OrganizationID
+	}
+
+	now := time.Now()
+	return func(agent *models.Agent) bool {
+		if organizationID != nil && agent.OrganizationID != *organizationID {
+			return false
+		}
+		if filter.Status != "" && as.statusAt(agent.LastSeen, now) != filter.Status {
+			return false
+		}
+		if len(filter.AgentIDs) > 0 && !slices.Contains(filter.AgentIDs, agent.ID) {
+			return false
+		}
+		return matchesAgentTags(as.tags[agent.ID], selector)
+	}, nil
+}
+
+// findAgentGroup looks a group up by ID, or by name within an organization
+func (as *AgentService) findAgentGroup(ref string, organizationID *uuid.UUID) (*models.AgentGroup, error) {
+	id, err := uuid.Parse(ref)
+	if err != nil && organizationID == nil {
+		return nil, fmt.Errorf("%w: group names are looked up within an organization", ErrInvalidAgentTag)
+	}
+	query := as.db.Model(&models.AgentGroup{})
+	if err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("name = ?", ref)
+	}
+	if organizationID != nil {
+		query = query.Where("organization_id = ?", *organizationID)
+	}
+
+	var group models.AgentGroup
+	if err := query.First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAgentGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to load agent group: %w", err)
+	}
+	return &group, nil
+}
+
+// CreateAgentGroup creates an organization's agent group
+func (as *AgentService) CreateAgentGroup(organizationID uuid.UUID, req *models.CreateAgentGroupRequest) (*models.AgentGroup, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: group name is required", ErrInvalidAgentTag)
+	}
+	if len(req.Selector) == 0 {
+		return nil, fmt.Errorf("%w: group selector needs at least one tag", ErrInvalidAgentTag)
+	}
+	for key, value := range req.Selector {
+		if err := validateAgentTag(key, value, true); err != nil {
+			return nil, err
+		}
+	}
+
+	var existing int64
+	if err := as.db.Model(&models.AgentGroup{}).Where("organization_id = ? AND name = ?", organizationID, name).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check agent group: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrAgentGroupExists
+	}
+
+	group := &models.AgentGroup{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		Name:           name,
+		Description:    req.Description,
+		Selector:       req.Selector,
+		CreatedBy:      req.CreatedBy,
+	}
+	if err := as.db.Create(group).Error; err != nil {
+		return nil, fmt.Errorf("failed to create agent group: %w", err)
+	}
+	group.AgentCount = as.countGroupAgents(group)
+	return group, nil
+}
+
+// ListAgentGroups lists an organization's agent groups by name, with how
+// many agents each currently has
+func (as *AgentService) ListAgentGroups(organizationID uuid.UUID) ([]models.AgentGroup, error) {
+	var groups []models.AgentGroup
+	if err := as.db.Where("organization_id = ?", organizationID).Order("name ASC").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to list agent groups: %w", err)
+	}
+	for i := range groups {
+		groups[i].AgentCount = as.countGroupAgents(&groups[i])
+	}
+	return groups, nil
+}
+
+// GetAgentGroup returns an organization's agent group and its agents
+func (as *AgentService) GetAgentGroup(organizationID, groupID uuid.UUID) (*models.AgentGroup, []*models.Agent, error) {
+	group, err := as.findAgentGroup(groupID.String(), &organizationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	agents := as.groupAgents(group)
+	group.AgentCount = len(agents)
+	return group, agents, nil
+}
+
+// DeleteAgentGroup deletes an organization's agent group; its agents keep their tags
+func (as *AgentService) DeleteAgentGroup(organizationID, groupID uuid.UUID) error {
+	result := as.db.Where("id = ? AND organization_id = ?", groupID, organizationID).Delete(&models.AgentGroup{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete agent group: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAgentGroupNotFound
+	}
+	return nil
+}
+
+// groupAgents returns the agents a loaded group currently selects
+func (as *AgentService) groupAgents(group *models.AgentGroup) []*models.Agent {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	agents := []*models.Agent{}
+	for _, agent := range as.agents {
+		if agent.OrganizationID == group.OrganizationID && matchesAgentTags(as.tags[agent.ID], group.Selector) {
+			agents = append(agents, agent)
+		}
+	}
+	return agents
+}
+
+func (as *AgentService) countGroupAgents(group *models.AgentGroup) int {
+	return len(as.groupAgents(group))
+}
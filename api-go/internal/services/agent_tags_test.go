@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAgentTagFilter(t *testing.T) {
+	selector, err := ParseAgentTagFilter([]string{"env=production", "os", "team=data=platform"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "production", "os": "", "team": "data=platform"}, selector)
+
+	for _, values := range [][]string{
+		{"=production"},
+		{"-env=production"},
+		{"env=production", "env=staging"},
+		{"env= production"},
+	} {
+		_, err := ParseAgentTagFilter(values)
+		assert.ErrorIs(t, err, ErrInvalidAgentTag, values)
+	}
+}
+
+func TestTagAndFilterAgents(t *testing.T) {
+	now := time.Now()
+	orgID, otherOrgID := uuid.New(), uuid.New()
+	as := &AgentService{agents: make(map[uuid.UUID]*models.Agent), heartbeatTimeout: 90 * time.Second}
+	agent := func(name string, organizationID uuid.UUID, lastSeen time.Time) uuid.UUID {
+		id := uuid.New()
+		as.agents[id] = &models.Agent{ID: id, Name: name, OrganizationID: organizationID, LastSeen: lastSeen}
+		return id
+	}
+	web, db, laptop := agent("web", orgID, now), agent("db", orgID, now.Add(-time.Hour)), agent("laptop", orgID, now)
+	other := agent("other", otherOrgID, now)
+
+	names := func(filter models.AgentFilter) []string {
+		agents, err := as.FilterAgents(filter)
+		require.NoError(t, err)
+		var names []string
+		for _, a := range agents {
+			names = append(names, a.Name)
+		}
+		return names
+	}
+
+	// Bulk tagging needs a filter, and valid tags
+	_, err := as.TagAgents(orgID, models.AgentFilter{}, map[string]string{"env": "production"}, nil)
+	assert.ErrorIs(t, err, ErrEmptyAgentFilter)
+	_, err = as.TagAgents(orgID, models.AgentFilter{OrganizationID: &orgID}, map[string]string{"env": ""}, nil)
+	assert.ErrorIs(t, err, ErrInvalidAgentTag)
+	_, err = as.TagAgents(orgID, models.AgentFilter{OrganizationID: &orgID}, map[string]string{"env": "prod"}, []string{"env"})
+	assert.ErrorIs(t, err, ErrInvalidAgentTag)
+	ids, err := as.TagAgents(orgID, models.AgentFilter{OrganizationID: &otherOrgID}, map[string]string{"env": "production"}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, ids, "another organization's agents are never tagged")
+
+	require.NoError(t, saveAgentTags(dryRunDB(t), []uuid.UUID{web, db, other}, map[string]string{"env": "production", "os": "linux"}, []string{"team"}))
+	as.applyAgentTags([]uuid.UUID{web, db, other}, map[string]string{"env": "production", "os": "linux"}, nil)
+	as.applyAgentTags([]uuid.UUID{laptop}, map[string]string{"env": "staging", "os": "darwin"}, nil)
+
+	assert.ElementsMatch(t, []string{"web", "db"}, names(models.AgentFilter{OrganizationID: &orgID, Tags: map[string]string{"env": "production", "os": "linux"}}))
+	assert.ElementsMatch(t, []string{"web", "db", "laptop"}, names(models.AgentFilter{OrganizationID: &orgID, Tags: map[string]string{"os": ""}}))
+	assert.ElementsMatch(t, []string{"web"}, names(models.AgentFilter{OrganizationID: &orgID, Status: models.AgentStatusOnline, Tags: map[string]string{"env": "production"}}))
+
+	// Setting a key replaces its value; removing it drops the tag
+	production, err := as.FilterAgents(models.AgentFilter{OrganizationID: &orgID, Tags: map[string]string{"env": "production"}})
+	require.NoError(t, err)
+	require.Len(t, production, 2)
+	as.applyAgentTags([]uuid.UUID{production[0].ID, production[1].ID}, map[string]string{"env": "retired"}, []string{"os"})
+	tags, err := as.AgentTags(db)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "retired"}, tags)
+	otherTags, err := as.AgentTags(other)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "production", "os": "linux"}, otherTags, "other organizations are untouched")
+
+	_, err = as.UpdateAgentTags(uuid.New(), map[string]string{"env": "production"}, nil)
+	assert.ErrorIs(t, err, ErrAgentNotFound)
+	_, err = as.FilterAgents(models.AgentFilter{Status: "asleep"})
+	assert.ErrorIs(t, err, ErrInvalidAgentTag)
+	_, err = as.FilterAgents(models.AgentFilter{Group: "production linux"})
+	assert.ErrorIs(t, err, ErrInvalidAgentTag, "group names need an organization")
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAgents(t *testing.T) {
+	as := &AgentService{agents: make(map[uuid.UUID]*models.Agent)}
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	var want []uuid.UUID
+	for i := 0; i < 5; i++ {
+		// Pairs of agents created at the same time are ordered by ID
+		id := uuid.MustParse("00000000-0000-0000-0000-00000000000" + string(rune('1'+i)))
+		as.agents[id] = &models.Agent{ID: id, CreatedAt: base.Add(time.Duration(i/2) * time.Second)}
+		want = append(want, id)
+	}
+
+	var got []uuid.UUID
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		page, next, err := as.ListAgents(models.AgentFilter{}, 2, cursor)
+		require.NoError(t, err)
+		for _, agent := range page {
+			got = append(got, agent.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+
+		// An agent registered mid-way sorts after every existing one
+		if pages == 0 {
+			late := uuid.New()
+			as.agents[late] = &models.Agent{ID: late, CreatedAt: base.Add(time.Hour)}
+			want = append(want, late)
+		}
+	}
+	assert.Equal(t, want, got)
+
+	for _, cursor := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("no separator")),
+		base64.RawURLEncoding.EncodeToString([]byte("yesterday," + uuid.NewString())),
+		base64.RawURLEncoding.EncodeToString([]byte(base.Format(time.RFC3339Nano) + ",not-a-uuid")),
+	} {
+		_, _, err := as.ListAgents(models.AgentFilter{}, 2, cursor)
+		assert.ErrorIs(t, err, ErrInvalidAgentCursor, cursor)
+	}
+}
+
+func TestAgentStatuses(t *testing.T) {
+	as := &AgentService{agents: make(map[uuid.UUID]*models.Agent), heartbeatTimeout: 90 * time.Second}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, models.AgentStatusOnline, as.statusAt(now.Add(-89*time.Second), now))
+	assert.Equal(t, models.AgentStatusDegraded, as.statusAt(now.Add(-90*time.Second), now))
+	assert.Equal(t, models.AgentStatusDegraded, as.statusAt(now.Add(-269*time.Second), now))
+	assert.Equal(t, models.AgentStatusOffline, as.statusAt(now.Add(-270*time.Second), now))
+
+	agent := &models.Agent{ID: uuid.New(), Status: models.AgentStatusOnline, LastSeen: now}
+	as.agents[agent.ID] = agent
+	assert.Empty(t, as.refreshStatuses(now.Add(time.Minute)))
+
+	// Missed heartbeats degrade the agent, then take it offline, with an event each time
+	events := as.refreshStatuses(now.Add(2 * time.Minute))
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AgentStatusOnline, events[0].From)
+	assert.Equal(t, models.AgentStatusDegraded, events[0].To)
+	assert.Equal(t, now, events[0].LastSeen)
+
+	offlineAt := now.Add(5 * time.Minute)
+	events = as.refreshStatuses(offlineAt)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AgentStatusOffline, agent.Status)
+	assert.Equal(t, offlineAt, *agent.StatusChangedAt)
+	assert.Empty(t, as.refreshStatuses(offlineAt.Add(time.Hour)))
+
+	// A heartbeat brings it straight back online
+	event := setAgentStatus(agent, models.AgentStatusOnline, offlineAt.Add(2*time.Hour))
+	require.NotNil(t, event)
+	assert.Equal(t, models.AgentStatusOffline, event.From)
+	assert.Nil(t, setAgentStatus(agent, models.AgentStatusOnline, offlineAt.Add(3*time.Hour)))
+
+	stats := as.GetPublicAgentStats()
+	assert.Equal(t, 1, stats["offline"]) // Its last heartbeat is still hours old
+	assert.Equal(t, map[string]int{models.AgentStatusOffline: 1}, as.AgentStatusCounts())
+	assert.Equal(t, 270.0, as.GetAgentStats(uuid.Nil)["offline_after_seconds"])
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+)
+
+// maxAlertFindings is the most findings listed in one chat message; the
+// rest are counted
+const maxAlertFindings = 10
+
+// Severity colors of chat messages
+var alertColors = map[string]string{
+	"critical": "#D32F2F",
+	"high":     "#F57C00",
+}
+
+// alertAdapter renders a vulnerability.found notification as the payload a
+// chat integration's incoming webhook expects. Adapters are registered by
+// webhook format in alertAdapters; supporting a new integration means adding
+// one.
+type alertAdapter func(alert *findingAlert) interface{}
+
+var alertAdapters = map[string]alertAdapter{
+	models.WebhookFormatSlack: slackAlert,
+	models.WebhookFormatTeams: teamsAlert,
+}
+
+// AlertService formats critical and high finding notifications as native
+// Slack and Microsoft Teams messages. It only builds payloads; WebhookService
+// delivers them like any other webhook body.
+type AlertService struct {
+	dashboardURL string
+}
+
+// NewAlertService creates a new alert service
+func NewAlertService(cfg *config.Config) *AlertService {
+	return &AlertService{
+		dashboardURL: strings.TrimRight(cfg.DashboardURL, "/"),
+	}
+}
+
+// Payload renders a notification in a chat webhook format, slack or teams
+func (s *AlertService) Payload(format string, notification *models.VulnerabilityFoundNotification) ([]byte, error) {
+	adapter, ok := alertAdapters[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported alert format %q", format)
+	}
+	return json.Marshal(adapter(s.alert(notification)))
+}
+
+// findingAlert is what every chat message says about a notification
+type findingAlert struct {
+	Title    string
+	Host     string
+	Color    string // Of the most severe finding
+	Findings []models.VulnerabilityFoundEntry
+	More     int    // Findings left out of the message
+	HostURL  string // Deep link to the host in the dashboard; empty without a dashboard URL
+}
+
+// alert summarizes a notification, most severe findings first
+func (s *AlertService) alert(notification *models.VulnerabilityFoundNotification) *findingAlert {
+	host := notification.Hostname
+	if host == "" {
+		host = notification.AgentID.String()
+	}
+
+	var critical, high []models.VulnerabilityFoundEntry
+	for _, finding := range notification.Findings {
+		if finding.Severity == "critical" {
+			critical = append(critical, finding)
+		} else {
+			high = append(high, finding)
+		}
+	}
+	findings := append(critical, high...)
+
+	alert := &findingAlert{
+		Title:    fmt.Sprintf("%s on %s", findingCounts(len(critical), len(high)), host),
+		Host:     host,
+		Color:    alertColors["high"],
+		Findings: findings,
+	}
+	if len(critical) > 0 {
+		alert.Color = alertColors["critical"]
+	}
+	if len(findings) > maxAlertFindings {
+		alert.Findings = findings[:maxAlertFindings]
+		alert.More = len(findings) - maxAlertFindings
+	}
+	if s.dashboardURL != "" {
+		alert.HostURL = fmt.Sprintf("%s/agents/%s", s.dashboardURL, notification.AgentID)
+	}
+	return alert
+}
+
+// slackAlert renders an alert as a Slack Block Kit message, in an
+// attachment so it is edged in the severity color
+func slackAlert(alert *findingAlert) interface{} {
+	blocks := []map[string]interface{}{
+		{"type": "header", "text": map[string]interface{}{"type": "plain_text", "text": alert.Title}},
+	}
+	for _, finding := range alert.Findings {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": slackFindingText(finding)},
+		})
+	}
+	if alert.More > 0 {
+		blocks = append(blocks, map[string]interface{}{
+			"type":     "context",
+			"elements": []map[string]interface{}{{"type": "mrkdwn", "text": fmt.Sprintf("and %d more", alert.More)}},
+		})
+	}
+	if alert.HostURL != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "actions",
+			"elements": []map[string]interface{}{{
+				"type": "button",
+				"text": map[string]interface{}{"type": "plain_text", "text": "View in ZeroTrace"},
+				"url":  alert.HostURL,
+			}},
+		})
+	}
+
+	return map[string]interface{}{
+		"text": alert.Title, // Shown in notifications
+		"attachments": []map[string]interface{}{
+			{"color": alert.Color, "blocks": blocks},
+		},
+	}
+}
+
+func slackFindingText(finding models.VulnerabilityFoundEntry) string {
+	title := slackEscape(finding.Title)
+	if finding.CVEID != "" {
+		title = fmt.Sprintf("<%s|%s> %s", cveURL(finding.CVEID), slackEscape(finding.CVEID), title)
+	}
+	text := fmt.Sprintf("*%s* %s", strings.ToUpper(finding.Severity), title)
+	if details := findingDetails(finding); details != "" {
+		text += "\n" + slackEscape(details)
+	}
+	return text
+}
+
+// slackEscape escapes the characters Slack treats as markup
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// teamsAlert renders an alert as a Microsoft Teams MessageCard, themed in
+// the severity color
+func teamsAlert(alert *findingAlert) interface{} {
+	var sections []map[string]interface{}
+	for _, finding := range alert.Findings {
+		title := finding.Title
+		if finding.CVEID != "" {
+			title = fmt.Sprintf("[%s](%s) %s", finding.CVEID, cveURL(finding.CVEID), title)
+		}
+		section := map[string]interface{}{
+			"activityTitle": fmt.Sprintf("**%s** %s", strings.ToUpper(finding.Severity), title),
+		}
+		if details := findingDetails(finding); details != "" {
+			section["text"] = details
+		}
+		sections = append(sections, section)
+	}
+	if alert.More > 0 {
+		sections = append(sections, map[string]interface{}{"text": fmt.Sprintf("and %d more", alert.More)})
+	}
+
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    alert.Title,
+		"title":      alert.Title,
+		"themeColor": strings.TrimPrefix(alert.Color, "#"),
+		"sections":   sections,
+	}
+	if alert.HostURL != "" {
+		card["potentialAction"] = []map[string]interface{}{{
+			"@type":   "OpenUri",
+			"name":    "View in ZeroTrace",
+			"targets": []map[string]string{{"os": "default", "uri": alert.HostURL}},
+		}}
+	}
+	return card
+}
+
+// findingCounts describes how many critical and high findings there are,
+// e.g. "2 critical and 1 high findings"
+func findingCounts(critical, high int) string {
+	var parts []string
+	if critical > 0 {
+		parts = append(parts, fmt.Sprintf("%d critical", critical))
+	}
+	if high > 0 {
+		parts = append(parts, fmt.Sprintf("%d high", high))
+	}
+	noun := "findings"
+	if critical+high == 1 {
+		noun = "finding"
+	}
+	return strings.Join(parts, " and ") + " " + noun
+}
+
+// findingDetails lists a finding's package, CVSS score and location
+func findingDetails(finding models.VulnerabilityFoundEntry) string {
+	var details []string
+	if finding.PackageName != "" {
+		details = append(details, strings.TrimSpace(finding.PackageName+" "+finding.PackageVersion))
+	}
+	if finding.CVSSScore != nil {
+		details = append(details, fmt.Sprintf("CVSS %.1f", *finding.CVSSScore))
+	}
+	if finding.Location != "" {
+		details = append(details, finding.Location)
+	}
+	return strings.Join(details, " · ")
+}
+
+// cveURL links to a CVE's NVD entry
+func cveURL(cveID string) string {
+	return "https://nvd.nist.gov/vuln/detail/" + strings.ToUpper(cveID)
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertPayloads(t *testing.T) {
+	s := NewAlertService(&config.Config{DashboardURL: "https://app.example.com/"})
+	agentID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	score := 9.8
+	notification := &models.VulnerabilityFoundNotification{
+		AgentID:  agentID,
+		Hostname: "web-1",
+		Findings: []models.VulnerabilityFoundEntry{
+			{Title: "Weak <cipher> suite", Severity: "high"},
+			{Title: "Heap overflow", Severity: "critical", CVEID: "cve-2024-0727", CVSSScore: &score, PackageName: "openssl", PackageVersion: "3.0.1"},
+		},
+	}
+
+	data, err := s.Payload(models.WebhookFormatSlack, notification)
+	require.NoError(t, err)
+	var slack struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Color  string `json:"color"`
+			Blocks []struct {
+				Type     string                `json:"type"`
+				Text     struct{ Text string } `json:"text"`
+				Elements []struct {
+					URL string `json:"url"`
+				} `json:"elements"`
+			} `json:"blocks"`
+		} `json:"attachments"`
+	}
+	require.NoError(t, json.Unmarshal(data, &slack))
+	assert.Equal(t, "1 critical and 1 high findings on web-1", slack.Text)
+	require.Len(t, slack.Attachments, 1)
+	assert.Equal(t, "#D32F2F", slack.Attachments[0].Color)
+	blocks := slack.Attachments[0].Blocks
+	require.Len(t, blocks, 4)
+	assert.Equal(t, "header", blocks[0].Type)
+	// The critical finding is listed first, linked to its CVE
+	assert.Equal(t, "*CRITICAL* <https://nvd.nist.gov/vuln/detail/CVE-2024-0727|cve-2024-0727> Heap overflow\nopenssl 3.0.1 · CVSS 9.8", blocks[1].Text.Text)
+	assert.Equal(t, "*HIGH* Weak &lt;cipher&gt; suite", blocks[2].Text.Text)
+	assert.Equal(t, "https://app.example.com/agents/"+agentID.String(), blocks[3].Elements[0].URL)
+
+	data, err = s.Payload(models.WebhookFormatTeams, notification)
+	require.NoError(t, err)
+	var teams struct {
+		Type       string `json:"@type"`
+		Title      string `json:"title"`
+		ThemeColor string `json:"themeColor"`
+		Sections   []struct {
+			ActivityTitle string `json:"activityTitle"`
+			Text          string `json:"text"`
+		} `json:"sections"`
+		PotentialAction []struct {
+			Targets []struct{ URI string } `json:"targets"`
+		} `json:"potentialAction"`
+	}
+	require.NoError(t, json.Unmarshal(data, &teams))
+	assert.Equal(t, "MessageCard", teams.Type)
+	assert.Equal(t, "D32F2F", teams.ThemeColor)
+	require.Len(t, teams.Sections, 2)
+	assert.Equal(t, "**CRITICAL** [cve-2024-0727](https://nvd.nist.gov/vuln/detail/CVE-2024-0727) Heap overflow", teams.Sections[0].ActivityTitle)
+	assert.Equal(t, "openssl 3.0.1 · CVSS 9.8", teams.Sections[0].Text)
+	assert.Equal(t, "https://app.example.com/agents/"+agentID.String(), teams.PotentialAction[0].Targets[0].URI)
+
+	_, err = s.Payload("pagerduty", notification)
+	assert.Error(t, err)
+}
+
+func TestAlertSummary(t *testing.T) {
+	notification := &models.VulnerabilityFoundNotification{AgentID: uuid.New()}
+	for i := 0; i < maxAlertFindings+2; i++ {
+		notification.Findings = append(notification.Findings, models.VulnerabilityFoundEntry{Title: fmt.Sprint(i), Severity: "high"})
+	}
+
+	// Without a dashboard URL there is no deep link, and the agent stands in for a missing hostname
+	alert := NewAlertService(&config.Config{}).alert(notification)
+	assert.Equal(t, "12 high findings on "+notification.AgentID.String(), alert.Title)
+	assert.Equal(t, "#F57C00", alert.Color)
+	assert.Len(t, alert.Findings, maxAlertFindings)
+	assert.Equal(t, 2, alert.More)
+	assert.Empty(t, alert.HostURL)
+	assert.NotContains(t, fmt.Sprint(slackAlert(alert)), "actions")
+	assert.NotContains(t, teamsAlert(alert), "potentialAction")
+
+	assert.Equal(t, "1 critical finding", findingCounts(1, 0))
+}
@@ -11,12 +11,18 @@ import (
 // AnalyticsService provides unified analytics capabilities
 // Consolidates heatmap, maturity, and compliance services
 type AnalyticsService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	heatmaps *heatmapCache // Nil when heatmaps aren't cached
 }
 
-// NewAnalyticsService creates a new unified analytics service
-func NewAnalyticsService(db *gorm.DB) *AnalyticsService {
-	return &AnalyticsService{db: db}
+// NewAnalyticsService creates a new unified analytics service, caching
+// computed heatmaps for heatmapCacheTTL; 0 disables the cache
+func NewAnalyticsService(db *gorm.DB, heatmapCacheTTL time.Duration) *AnalyticsService {
+	s := &AnalyticsService{db: db}
+	if heatmapCacheTTL > 0 {
+		s.heatmaps = newHeatmapCache(heatmapCacheTTL)
+	}
+	return s
 }
 
 // GetVulnerabilitiesForOrganization retrieves vulnerabilities for analytics
@@ -30,6 +36,21 @@ func (s *AnalyticsService) GetVulnerabilitiesForOrganization(organizationID uuid
 	return vulnerabilities, err
 }
 
+// GetVulnerabilitiesInWindow retrieves the vulnerabilities found from up to
+// to for analytics; a zero from is all time
+func (s *AnalyticsService) GetVulnerabilitiesInWindow(organizationID uuid.UUID, from, to time.Time) ([]models.Vulnerability, error) {
+	var vulnerabilities []models.Vulnerability
+
+	query := s.db.Where("organization_id = ? AND created_at < ?", organizationID, to)
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	err := query.Order("severity DESC, created_at DESC").
+		Find(&vulnerabilities).Error
+
+	return vulnerabilities, err
+}
+
 // GetScanHistory retrieves scan history for analytics
 func (s *AnalyticsService) GetScanHistory(organizationID uuid.UUID, limit int) ([]models.Scan, error) {
 	var scans []models.Scan
@@ -1,9 +1,11 @@
 package analytics
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"zerotrace/api/internal/logging"
 	"zerotrace/api/internal/models"
 
 	"github.com/google/uuid"
@@ -104,7 +106,9 @@ type ExecutiveSummary struct {
 }
 
 // GenerateComplianceReport generates a comprehensive compliance report
-func (s *AnalyticsService) GenerateComplianceReport(organizationID uuid.UUID, framework string, reportType string, reportPeriod string) (*ComplianceReport, error) {
+func (s *AnalyticsService) GenerateComplianceReport(ctx context.Context, organizationID uuid.UUID, framework string, reportType string, reportPeriod string) (*ComplianceReport, error) {
+	start := time.Now()
+
 	// Get vulnerability data
 	vulnerabilities, err := s.GetVulnerabilitiesForOrganization(organizationID)
 	if err != nil {
@@ -138,6 +142,14 @@ func (s *AnalyticsService) GenerateComplianceReport(organizationID uuid.UUID, fr
 	// Generate executive summary
 	executiveSummary := s.generateExecutiveSummary(controlScores, findings, overallScore)
 
+	logging.FromContext(ctx).Info("Compliance report generated",
+		"organization_id", organizationID,
+		"framework", framework,
+		"report_type", reportType,
+		"overall_score", overallScore,
+		"findings", len(findings),
+		"latency_ms", float64(time.Since(start).Microseconds())/1000,
+	)
 	return &ComplianceReport{
 		ReportID:         fmt.Sprintf("compliance_%s_%s_%d", framework, organizationID.String(), time.Now().Unix()),
 		OrganizationID:   organizationID,
@@ -0,0 +1,286 @@
+package analytics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Layout of compliance report PDFs, in millimetres on A4 portrait
+const (
+	pdfMargin     = 15.0
+	pdfLineHeight = 5.0
+	pdfRowHeight  = 7.0
+)
+
+// pdfControlColumns are the control scores table's headings and widths
+var pdfControlColumns = []struct {
+	Heading string
+	Width   float64
+}{
+	{"Control", 24},
+	{"Name", 68},
+	{"Category", 34},
+	{"Score", 18},
+	{"Status", 36},
+}
+
+// WriteCompliancePDF writes a compliance report as a PDF: its executive
+// summary, a table of control scores, the findings and the recommendations
+func WriteCompliancePDF(w io.Writer, report *ComplianceReport) error {
+	return newCompliancePDF(report).Output(w)
+}
+
+// compliancePDF lays out a compliance report on an fpdf document
+type compliancePDF struct {
+	*fpdf.Fpdf
+	tr func(string) string // Converts UTF-8 to the core fonts' encoding
+}
+
+// newCompliancePDF lays out a compliance report. Every page's footer carries
+// the time the report was generated, its confidence score and the page number.
+func newCompliancePDF(report *ComplianceReport) *compliancePDF {
+	pdf := &compliancePDF{Fpdf: fpdf.New("P", "mm", "A4", "")}
+	pdf.tr = pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin+5)
+	pdf.SetTitle(fmt.Sprintf("%s Compliance Report", report.Framework), true)
+	pdf.SetCreator("ZeroTrace", true)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(0, pdfLineHeight, pdf.tr(fmt.Sprintf("Generated %s | Confidence %.0f%%",
+			report.GeneratedAt.UTC().Format("2006-01-02 15:04 MST"), report.ConfidenceScore*100)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, pdfLineHeight, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	pdf.AddPage()
+	pdf.title(report)
+	pdf.executiveSummary(report)
+	pdf.controlScores(report.ControlScores)
+	pdf.findings(report.Findings)
+	pdf.recommendations(report.Recommendations)
+	return pdf
+}
+
+func (pdf *compliancePDF) title(report *ComplianceReport) {
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, pdf.tr(fmt.Sprintf("%s Compliance Report", report.Framework)), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, pdfLineHeight, pdf.tr(fmt.Sprintf("Organization %s | %s %s report",
+		report.OrganizationID, report.ReportPeriod, report.ReportType)), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+}
+
+func (pdf *compliancePDF) heading(text string) {
+	// Keep a heading on the same page as the first lines under it
+	pdf.ensureSpace(4 * pdfRowHeight)
+	pdf.Ln(2)
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.CellFormat(0, 8, pdf.tr(text), "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+}
+
+// ensureSpace starts a new page unless height millimetres fit on this one
+func (pdf *compliancePDF) ensureSpace(height float64) bool {
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	if _, autoBottom := pdf.GetAutoPageBreak(); autoBottom > bottom {
+		bottom = autoBottom
+	}
+	if pdf.GetY()+height <= pageHeight-bottom {
+		return false
+	}
+	pdf.AddPage()
+	return true
+}
+
+// paragraph writes wrapped text, with an optional bold label before it
+func (pdf *compliancePDF) paragraph(label, text string) {
+	if text == "" {
+		return
+	}
+	if label != "" {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.Write(pdfLineHeight, pdf.tr(label+": "))
+	}
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.Write(pdfLineHeight, pdf.tr(text))
+	pdf.Ln(pdfLineHeight)
+}
+
+// textHeight returns the height text takes when wrapped to width
+func (pdf *compliancePDF) textHeight(text string, width float64) float64 {
+	return float64(len(pdf.SplitLines([]byte(pdf.tr(text)), width))) * pdfLineHeight
+}
+
+// fit shortens text with an ellipsis to fit in a table cell
+func (pdf *compliancePDF) fit(text string, width float64) string {
+	text = pdf.tr(text)
+	width -= 2 * pdf.GetCellMargin()
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 && pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
+
+func (pdf *compliancePDF) executiveSummary(report *ComplianceReport) {
+	summary := report.ExecutiveSummary
+	pdf.heading("Executive Summary")
+	pdf.paragraph("Overall score", fmt.Sprintf("%.1f (%s)", report.OverallScore, humanize(report.ComplianceLevel)))
+	pdf.paragraph("Status", humanize(summary.OverallStatus))
+	pdf.paragraph("Findings", fmt.Sprintf("%d total, %d critical, %d high", len(report.Findings), summary.CriticalFindings, summary.HighFindings))
+	pdf.paragraph("Trend", humanize(summary.ComplianceTrend))
+	pdf.paragraph("Risk assessment", humanize(summary.RiskAssessment))
+	pdf.paragraph("Next assessment", report.NextAssessment.UTC().Format("2006-01-02"))
+	if len(summary.StrategicInitiatives) > 0 {
+		pdf.paragraph("Strategic initiatives", strings.Join(summary.StrategicInitiatives, "; "))
+	}
+	if len(summary.BudgetRecommendations) > 0 {
+		pdf.paragraph("Budget", strings.Join(summary.BudgetRecommendations, "; "))
+	}
+}
+
+// controlScores writes the controls sorted by ID as a table, repeating the
+// table header on every page it spans
+func (pdf *compliancePDF) controlScores(controls map[string]ControlScore) {
+	pdf.heading("Control Scores")
+	if len(controls) == 0 {
+		pdf.paragraph("", "No controls were assessed.")
+		return
+	}
+
+	ids := make([]string, 0, len(controls))
+	for id := range controls {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for _, column := range pdfControlColumns {
+			pdf.CellFormat(column.Width, pdfRowHeight, column.Heading, "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+	header()
+	for _, id := range ids {
+		control := controls[id]
+		if pdf.ensureSpace(pdfRowHeight) {
+			header()
+		}
+		cells := []string{
+			control.ControlID,
+			control.ControlName,
+			control.Category,
+			fmt.Sprintf("%.1f", control.Score),
+			humanize(control.Status),
+		}
+		for i, column := range pdfControlColumns {
+			align := "L"
+			if column.Heading == "Score" {
+				align = "R"
+			}
+			pdf.CellFormat(column.Width, pdfRowHeight, pdf.fit(cells[i], column.Width), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+// findings writes each finding as a block, moving a block that doesn't fit
+// on the current page to the next rather than splitting it
+func (pdf *compliancePDF) findings(findings []ComplianceFinding) {
+	pdf.heading("Findings")
+	if len(findings) == 0 {
+		pdf.paragraph("", "No findings.")
+		return
+	}
+
+	width, _ := pdf.GetPageSize()
+	width -= 2 * pdfMargin
+	for i, finding := range findings {
+		details := findingDetails(finding)
+		height := pdfRowHeight + pdf.textHeight(finding.Description, width)
+		for _, detail := range details {
+			height += pdf.textHeight(detail[0]+": "+detail[1], width)
+		}
+		pdf.ensureSpace(height)
+
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(0, pdfRowHeight, pdf.tr(fmt.Sprintf("%d. [%s] %s (%s)",
+			i+1, strings.ToUpper(finding.Severity), finding.Title, finding.ControlID)), "", "L", false)
+		pdf.SetFont("Helvetica", "", 10)
+		if finding.Description != "" {
+			pdf.MultiCell(0, pdfLineHeight, pdf.tr(finding.Description), "", "L", false)
+		}
+		for _, detail := range details {
+			pdf.paragraph(detail[0], detail[1])
+		}
+		pdf.Ln(3)
+	}
+}
+
+// findingDetails returns a finding's labelled details, leaving out empty ones
+func findingDetails(finding ComplianceFinding) [][2]string {
+	var details [][2]string
+	add := func(label, value string) {
+		if value != "" {
+			details = append(details, [2]string{label, value})
+		}
+	}
+	add("Impact", humanize(finding.Impact))
+	add("Root cause", finding.RootCause)
+	add("Remediation", finding.RemediationPlan)
+	if len(finding.Recommendations) > 0 {
+		add("Recommendations", strings.Join(finding.Recommendations, "; "))
+	}
+	due := ""
+	if !finding.DueDate.IsZero() {
+		due = ", due " + finding.DueDate.UTC().Format("2006-01-02")
+	}
+	add("Status", humanize(finding.Status)+due)
+	add("Owner", humanize(finding.Owner))
+	return details
+}
+
+func (pdf *compliancePDF) recommendations(recommendations []ComplianceRecommendation) {
+	pdf.heading("Recommendations")
+	if len(recommendations) == 0 {
+		pdf.paragraph("", "No recommendations.")
+		return
+	}
+
+	width, _ := pdf.GetPageSize()
+	width -= 2 * pdfMargin
+	for i, recommendation := range recommendations {
+		pdf.ensureSpace(2*pdfRowHeight + pdf.textHeight(recommendation.Description, width))
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(0, pdfRowHeight, pdf.tr(fmt.Sprintf("%d. %s (%s priority)",
+			i+1, recommendation.Title, recommendation.Priority)), "", "L", false)
+		pdf.SetFont("Helvetica", "", 10)
+		if recommendation.Description != "" {
+			pdf.MultiCell(0, pdfLineHeight, pdf.tr(recommendation.Description), "", "L", false)
+		}
+		pdf.paragraph("Effort", fmt.Sprintf("%s effort, %s impact, %s", recommendation.Effort, recommendation.Impact, recommendation.Timeline))
+		if len(recommendation.SuccessMetrics) > 0 {
+			pdf.paragraph("Success metrics", strings.Join(recommendation.SuccessMetrics, "; "))
+		}
+		pdf.Ln(3)
+	}
+}
+
+// humanize turns identifiers such as partially_compliant into words
+func humanize(value string) string {
+	return strings.ReplaceAll(value, "_", " ")
+}
@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCompliancePDF(t *testing.T) {
+	report := &ComplianceReport{
+		Framework:       "SOC2",
+		ReportType:      "full",
+		ReportPeriod:    "quarterly",
+		OverallScore:    72.5,
+		ComplianceLevel: "mostly_compliant",
+		ControlScores: map[string]ControlScore{
+			"CC6.1": {ControlID: "CC6.1", ControlName: "Logical and Physical Access Security", Score: 75, Status: "partially_compliant"},
+			"CC7.1": {ControlID: "CC7.1", ControlName: "System Operations Monitoring – a name far too long to fit in its table column", Score: 90, Status: "compliant"},
+		},
+		Recommendations: []ComplianceRecommendation{{Title: "Improve Access Controls", Priority: "high"}},
+		GeneratedAt:     time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC),
+		ConfidenceScore: 0.85,
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteCompliancePDF(&out, report))
+	assert.True(t, bytes.HasPrefix(out.Bytes(), []byte("%PDF-")))
+	assert.Equal(t, 1, newCompliancePDF(report).PageNo())
+
+	// Long findings lists run onto further pages, each with the footer
+	for i := 0; i < 60; i++ {
+		report.Findings = append(report.Findings, ComplianceFinding{
+			ControlID:       "CC6.1",
+			Severity:        "high",
+			Title:           fmt.Sprintf("Finding %d", i+1),
+			Description:     strings.Repeat("An access control gap that needs a long explanation. ", 5),
+			RemediationPlan: "Implement missing controls",
+			Status:          "open",
+		})
+	}
+	pdf := newCompliancePDF(report)
+	pages := pdf.PageNo()
+	assert.Greater(t, pages, 3)
+
+	pdf.SetCompression(false)
+	out.Reset()
+	require.NoError(t, pdf.Output(&out))
+	assert.Equal(t, pages, strings.Count(out.String(), "Generated 2026-10-01 09:30 UTC | Confidence 85%"))
+	assert.Contains(t, out.String(), fmt.Sprintf("Page %d of %d", pages, pages))
+	assert.Contains(t, out.String(), "Finding 60")
+}
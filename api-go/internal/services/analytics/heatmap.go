@@ -21,10 +21,43 @@ type HeatmapData struct {
 	RiskDistribution RiskDistribution   `json:"risk_distribution"`
 	Trends           []Trend            `json:"trends"`
 	Recommendations  []string           `json:"recommendations"`
+	From             *time.Time         `json:"from,omitempty"` // Unset when the heatmap covers all time
+	To               time.Time          `json:"to"`
+	Granularity      string             `json:"granularity"`
 	GeneratedAt      time.Time          `json:"generated_at"`
 	ConfidenceScore  float64            `json:"confidence_score"`
 }
 
+// HeatmapWindow is the period a heatmap covers, by when vulnerabilities were
+// found, and how its trends are bucketed. A zero To is now; a zero From is
+// Since before To, or all time when Since is zero too.
+type HeatmapWindow struct {
+	From        time.Time
+	To          time.Time
+	Since       time.Duration
+	Granularity string // day, week or month
+}
+
+// HeatmapGranularities are the periods heatmap trends can be bucketed by
+var HeatmapGranularities = []string{"day", "week", "month"}
+
+// resolve returns the window's bounds as of now, from being zero when the
+// window is unbounded
+func (w HeatmapWindow) resolve(now time.Time) (from, to time.Time) {
+	to = w.To
+	if to.IsZero() {
+		to = now
+	}
+	from = w.From
+	if from.IsZero() && w.Since > 0 {
+		from = to.Add(-w.Since)
+	}
+	return from, to
+}
+
+// severityOrder lists severities most severe first
+var severityOrder = []string{"critical", "high", "medium", "low"}
+
 // HeatmapDimension represents a dimension in the heatmap
 type HeatmapDimension struct {
 	Name        string   `json:"name"`
@@ -78,12 +111,28 @@ type Trend struct {
 	Description string  `json:"description"`
 }
 
-// GenerateRiskHeatmap generates a comprehensive risk heatmap for an organization
-func (s *AnalyticsService) GenerateRiskHeatmap(organizationID uuid.UUID, heatmapType string, timeRange string) (*HeatmapData, error) {
+// GenerateRiskHeatmap generates a comprehensive risk heatmap for an
+// organization over a window, reporting whether it came from the cache
+func (s *AnalyticsService) GenerateRiskHeatmap(organizationID uuid.UUID, heatmapType string, window HeatmapWindow) (*HeatmapData, bool, error) {
+	now := time.Now()
+	key := heatmapCacheKey(organizationID, heatmapType, window)
+	var generation uint64
+	if s.heatmaps != nil {
+		var cached *HeatmapData
+		if cached, generation = s.heatmaps.get(key, organizationID, now); cached != nil {
+			return cached, true, nil
+		}
+	}
+
 	// Get vulnerability data
-	vulnerabilities, err := s.GetVulnerabilitiesForOrganization(organizationID)
+	from, to := window.resolve(now)
+	vulnerabilities, err := s.GetVulnerabilitiesInWindow(organizationID, from, to)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get vulnerabilities: %w", err)
+		return nil, false, fmt.Errorf("failed to get vulnerabilities: %w", err)
+	}
+	granularity := window.Granularity
+	if granularity == "" {
+		granularity = "week"
 	}
 
 	// Calculate risk distribution
@@ -95,7 +144,7 @@ func (s *AnalyticsService) GenerateRiskHeatmap(organizationID uuid.UUID, heatmap
 
 	switch heatmapType {
 	case "severity_trend":
-		dataPoints, dimensions = s.generateSeverityTrendHeatmap(vulnerabilities, timeRange)
+		dataPoints, dimensions = s.generateSeverityTrendHeatmap(vulnerabilities, from, to, granularity)
 	case "compliance_risk":
 		dataPoints, dimensions = s.generateComplianceRiskHeatmap(vulnerabilities)
 	case "technology":
@@ -108,12 +157,12 @@ func (s *AnalyticsService) GenerateRiskHeatmap(organizationID uuid.UUID, heatmap
 	hotspots := s.identifyHotspots(vulnerabilities, dataPoints)
 
 	// Calculate trends
-	trends := s.calculateTrends(vulnerabilities, timeRange)
+	trends := s.calculateTrends(vulnerabilities, from, to, granularity)
 
 	// Generate recommendations
 	recommendations := s.generateHeatmapRecommendations(hotspots, riskDist)
 
-	return &HeatmapData{
+	data := &HeatmapData{
 		OrganizationID:   organizationID,
 		HeatmapType:      heatmapType,
 		Dimensions:       dimensions,
@@ -122,9 +171,18 @@ func (s *AnalyticsService) GenerateRiskHeatmap(organizationID uuid.UUID, heatmap
 		RiskDistribution: riskDist,
 		Trends:           trends,
 		Recommendations:  recommendations,
-		GeneratedAt:      time.Now(),
+		To:               to,
+		Granularity:      granularity,
+		GeneratedAt:      now,
 		ConfidenceScore:  0.85,
-	}, nil
+	}
+	if !from.IsZero() {
+		data.From = &from
+	}
+	if s.heatmaps != nil {
+		s.heatmaps.put(key, organizationID, generation, data, now)
+	}
+	return data, false, nil
 }
 
 // Helper methods for heatmap generation
@@ -152,40 +210,55 @@ func (s *AnalyticsService) calculateRiskDistribution(vulnerabilities []models.Vu
 	return dist
 }
 
-func (s *AnalyticsService) generateSeverityTrendHeatmap(vulnerabilities []models.Vulnerability, timeRange string) ([]HeatmapDataPoint, []HeatmapDimension) {
-	// Implementation for severity trend heatmap
+func (s *AnalyticsService) generateSeverityTrendHeatmap(vulnerabilities []models.Vulnerability, from, to time.Time, granularity string) ([]HeatmapDataPoint, []HeatmapDimension) {
+	// An unbounded window starts at the oldest vulnerability
+	if from.IsZero() {
+		for _, vuln := range vulnerabilities {
+			if from.IsZero() || vuln.CreatedAt.Before(from) {
+				from = vuln.CreatedAt
+			}
+		}
+	}
+	periods := timePeriods(from, to, granularity)
+
 	dataPoints := []HeatmapDataPoint{}
 	dimensions := []HeatmapDimension{
-		{Name: "Severity", Type: "severity", Categories: []string{"critical", "high", "medium", "low"}},
-		{Name: "Time", Type: "trend", Categories: []string{"week1", "week2", "week3", "week4"}},
+		{Name: "Severity", Type: "severity", Categories: severityOrder},
+		{Name: "Time", Type: "trend", Categories: periods},
 	}
-	
+
 	// Group by severity and time period
 	severityCounts := make(map[string]map[string]int)
 	for _, vuln := range vulnerabilities {
 		severity := string(vuln.Severity)
-		period := s.getTimePeriod(vuln.CreatedAt, timeRange)
-		
+		period := periodLabel(periodStart(vuln.CreatedAt, granularity), granularity)
+
 		if severityCounts[severity] == nil {
 			severityCounts[severity] = make(map[string]int)
 		}
 		severityCounts[severity][period]++
 	}
-	
-	// Create data points
-	for severity, periods := range severityCounts {
-		for period, count := range periods {
-			dataPoints = append(dataPoints, HeatmapDataPoint{
-				X:         severity,
-				Y:         period,
-				Value:     float64(count),
-				Count:     count,
-				RiskLevel: severity,
-				Trend:     "stable",
-			})
+
+	// Create data points in severity and time order, each trending against
+	// the period before it
+	for _, severity := range severityOrder {
+		previous := 0
+		for _, period := range periods {
+			count := severityCounts[severity][period]
+			if count > 0 {
+				dataPoints = append(dataPoints, HeatmapDataPoint{
+					X:         severity,
+					Y:         period,
+					Value:     float64(count),
+					Count:     count,
+					RiskLevel: severity,
+					Trend:     trendDirection(previous, count, "increasing", "decreasing", "stable"),
+				})
+			}
+			previous = count
 		}
 	}
-	
+
 	return dataPoints, dimensions
 }
 
@@ -237,9 +310,58 @@ func (s *AnalyticsService) identifyHotspots(vulnerabilities []models.Vulnerabili
 	return hotspots
 }
 
-func (s *AnalyticsService) calculateTrends(vulnerabilities []models.Vulnerability, timeRange string) []Trend {
-	// Calculate trends over time
-	return []Trend{}
+// calculateTrends compares each severity's count in the window's last period
+// with the period before it
+func (s *AnalyticsService) calculateTrends(vulnerabilities []models.Vulnerability, from, to time.Time, granularity string) []Trend {
+	trends := []Trend{}
+	last := periodStart(to.Add(-time.Nanosecond), granularity)
+	previous := periodStart(last.Add(-time.Nanosecond), granularity)
+	if !from.IsZero() && previous.Before(periodStart(from, granularity)) {
+		return trends
+	}
+
+	current := make(map[string]int)
+	earlier := make(map[string]int)
+	for _, vuln := range vulnerabilities {
+		switch periodStart(vuln.CreatedAt, granularity) {
+		case last:
+			current[string(vuln.Severity)]++
+		case previous:
+			earlier[string(vuln.Severity)]++
+		}
+	}
+
+	for _, severity := range severityOrder {
+		before, now := earlier[severity], current[severity]
+		if before == 0 && now == 0 {
+			continue
+		}
+		magnitude := 100.0
+		if before > 0 {
+			magnitude = math.Abs(float64(now-before)) / float64(before) * 100
+		}
+		direction := trendDirection(before, now, "up", "down", "stable")
+		trends = append(trends, Trend{
+			Dimension:   severity,
+			Direction:   direction,
+			Magnitude:   math.Round(magnitude*10) / 10,
+			Confidence:  0.85,
+			Description: fmt.Sprintf("%d %s vulnerabilities this %s, %d the %s before", now, severity, granularity, before, granularity),
+		})
+	}
+	return trends
+}
+
+// trendDirection names how a count changed from one period to the next
+func trendDirection(before, after int, up, down, stable string) string {
+	switch {
+	case after > before:
+		return up
+	case after < before:
+		return down
+	default:
+		return stable
+	}
 }
 
 func (s *AnalyticsService) generateHeatmapRecommendations(hotspots []Hotspot, riskDist RiskDistribution) []string {
@@ -270,9 +392,51 @@ func (s *AnalyticsService) calculateRiskScore(severity string, count int) float6
 	return weight * math.Log(float64(count+1))
 }
 
-func (s *AnalyticsService) getTimePeriod(t time.Time, timeRange string) string {
-	// Simple implementation - returns week number
-	_, week := t.ISOWeek()
-	return fmt.Sprintf("week%d", week%4+1)
+// periodStart returns the start of the UTC day, ISO week or month holding t
+func periodStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case "day":
+		return day
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		// Weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+}
+
+// periodLabel names the period starting at start
+func periodLabel(start time.Time, granularity string) string {
+	switch granularity {
+	case "day":
+		return start.Format("2006-01-02")
+	case "month":
+		return start.Format("2006-01")
+	default:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+}
+
+// timePeriods lists the periods from up to to overlaps, oldest first
+func timePeriods(from, to time.Time, granularity string) []string {
+	periods := []string{}
+	if from.IsZero() {
+		return periods
+	}
+	for start := periodStart(from, granularity); start.Before(to); {
+		periods = append(periods, periodLabel(start, granularity))
+		switch granularity {
+		case "day":
+			start = start.AddDate(0, 0, 1)
+		case "month":
+			start = start.AddDate(0, 1, 0)
+		default:
+			start = start.AddDate(0, 0, 7)
+		}
+	}
+	return periods
 }
 
@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// heatmapCache holds computed heatmaps for a short while, as each one reads
+// every vulnerability in its window. An organization's heatmaps are dropped
+// when new scan results are recorded for it.
+type heatmapCache struct {
+	ttl time.Duration
+
+	mu          sync.Mutex
+	entries     map[string]heatmapCacheEntry
+	generations map[uuid.UUID]uint64 // Bumped by each invalidation of the organization
+	lastSweep   time.Time
+}
+
+type heatmapCacheEntry struct {
+	organizationID uuid.UUID
+	data           *HeatmapData
+	expiresAt      time.Time
+}
+
+func newHeatmapCache(ttl time.Duration) *heatmapCache {
+	return &heatmapCache{
+		ttl:         ttl,
+		entries:     make(map[string]heatmapCacheEntry),
+		generations: make(map[uuid.UUID]uint64),
+	}
+}
+
+// heatmapCacheKey identifies a heatmap by what was asked for rather than the
+// window it resolved to, so heatmaps up to now are reused until they expire
+func heatmapCacheKey(organizationID uuid.UUID, heatmapType string, window HeatmapWindow) string {
+	bound := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s", organizationID, heatmapType, bound(window.From), bound(window.To), window.Since, window.Granularity)
+}
+
+// get returns a cached heatmap, and the organization's generation to store a
+// heatmap computed on a miss under
+func (c *heatmapCache) get(key string, organizationID uuid.UUID, now time.Time) (*HeatmapData, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && now.Before(entry.expiresAt) {
+		return entry.data, 0
+	}
+	return nil, c.generations[organizationID]
+}
+
+// put caches a heatmap unless the organization was invalidated since it was
+// computed, which would cache results from before the new scan
+func (c *heatmapCache) put(key string, organizationID uuid.UUID, generation uint64, data *HeatmapData, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[organizationID] != generation {
+		return
+	}
+	c.sweep(now)
+	c.entries[key] = heatmapCacheEntry{organizationID: organizationID, data: data, expiresAt: now.Add(c.ttl)}
+}
+
+// invalidate drops an organization's cached heatmaps
+func (c *heatmapCache) invalidate(organizationID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[organizationID]++
+	for key, entry := range c.entries {
+		if entry.organizationID == organizationID {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// sweep drops expired heatmaps at most once per TTL
+func (c *heatmapCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// InvalidateHeatmaps drops an organization's cached heatmaps, so the next
+// request computes them from its latest scan results
+func (s *AnalyticsService) InvalidateHeatmaps(organizationID uuid.UUID) {
+	if s.heatmaps != nil {
+		s.heatmaps.invalidate(organizationID)
+	}
+}
@@ -0,0 +1,84 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimePeriods(t *testing.T) {
+	from := time.Date(2026, 1, 29, 15, 0, 0, 0, time.UTC) // A Thursday
+	to := time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []string{"2026-01-29", "2026-01-30", "2026-01-31", "2026-02-01", "2026-02-02"}, timePeriods(from, to, "day"))
+	assert.Equal(t, []string{"2026-W05", "2026-W06"}, timePeriods(from, to, "week"))
+	assert.Equal(t, []string{"2026-01", "2026-02"}, timePeriods(from, to, "month"))
+	assert.Empty(t, timePeriods(time.Time{}, to, "week"))
+	assert.Equal(t, time.Date(2026, 1, 26, 0, 0, 0, 0, time.UTC), periodStart(from, "week"))
+}
+
+func TestSeverityTrends(t *testing.T) {
+	s := &AnalyticsService{}
+	to := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	found := func(severity models.SeverityLevel, month time.Month) models.Vulnerability {
+		return models.Vulnerability{Severity: severity, CreatedAt: time.Date(2026, month, 10, 0, 0, 0, 0, time.UTC)}
+	}
+	vulns := []models.Vulnerability{
+		found("critical", time.January),
+		found("critical", time.February), found("critical", time.February),
+		found("low", time.January), found("low", time.January),
+	}
+
+	trends := s.calculateTrends(vulns, time.Time{}, to, "month")
+	assert.Len(t, trends, 2)
+	assert.Equal(t, Trend{Dimension: "critical", Direction: "up", Magnitude: 100, Confidence: 0.85,
+		Description: "2 critical vulnerabilities this month, 1 the month before"}, trends[0])
+	assert.Equal(t, "low", trends[1].Dimension)
+	assert.Equal(t, "down", trends[1].Direction)
+
+	// A window holding one period has nothing to compare it with
+	assert.Empty(t, s.calculateTrends(vulns, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), to, "month"))
+
+	points, dimensions := s.generateSeverityTrendHeatmap(vulns, time.Time{}, to, "month")
+	assert.Equal(t, []string{"2026-01", "2026-02"}, dimensions[1].Categories)
+	assert.Equal(t, []HeatmapDataPoint{
+		{X: "critical", Y: "2026-01", Value: 1, Count: 1, RiskLevel: "critical", Trend: "increasing"},
+		{X: "critical", Y: "2026-02", Value: 2, Count: 2, RiskLevel: "critical", Trend: "increasing"},
+		{X: "low", Y: "2026-01", Value: 2, Count: 2, RiskLevel: "low", Trend: "increasing"},
+	}, points)
+}
+
+func TestHeatmapCache(t *testing.T) {
+	cache := newHeatmapCache(time.Minute)
+	org, other := uuid.New(), uuid.New()
+	now := time.Now()
+	window := HeatmapWindow{Since: 30 * 24 * time.Hour, Granularity: "week"}
+	key := heatmapCacheKey(org, "comprehensive", window)
+	otherKey := heatmapCacheKey(other, "comprehensive", window)
+	assert.NotEqual(t, key, heatmapCacheKey(org, "comprehensive", HeatmapWindow{Since: window.Since, Granularity: "day"}))
+
+	data, generation := cache.get(key, org, now)
+	assert.Nil(t, data)
+	cache.put(key, org, generation, &HeatmapData{HeatmapType: "comprehensive"}, now)
+	cache.put(otherKey, other, 0, &HeatmapData{}, now)
+	data, _ = cache.get(key, org, now.Add(30*time.Second))
+	assert.NotNil(t, data)
+	data, _ = cache.get(key, org, now.Add(time.Minute))
+	assert.Nil(t, data, "expired heatmaps are recomputed")
+
+	// Ingestion drops the organization's heatmaps, and any computed before it
+	// are not cached once they finish
+	_, generation = cache.get(key, org, now)
+	cache.invalidate(org)
+	data, _ = cache.get(key, org, now)
+	assert.Nil(t, data)
+	cache.put(key, org, generation, &HeatmapData{}, now)
+	data, _ = cache.get(key, org, now)
+	assert.Nil(t, data)
+	data, _ = cache.get(otherKey, other, now)
+	assert.NotNil(t, data, "other organizations' heatmaps are kept")
+}
@@ -124,10 +124,7 @@ func (s *AnalyticsService) CalculateMaturityScore(organizationID uuid.UUID) (*Ma
 	// Generate improvement roadmap
 	improvementRoadmap := s.generateImprovementRoadmap(dimensionScores)
 
-	// Analyze trends
-	trends := s.analyzeMaturityTrends(organizationID)
-
-	return &MaturityScore{
+	score := &MaturityScore{
 		OrganizationID:     organizationID,
 		ScoreID:            fmt.Sprintf("maturity_%s_%d", organizationID.String(), time.Now().Unix()),
 		OverallScore:       overallScore,
@@ -136,11 +133,19 @@ func (s *AnalyticsService) CalculateMaturityScore(organizationID uuid.UUID) (*Ma
 		IndustryBenchmark:  industryBenchmark,
 		PeerComparison:     peerComparison,
 		ImprovementRoadmap: improvementRoadmap,
-		Trends:             trends,
 		GeneratedAt:        time.Now(),
 		NextAssessment:     time.Now().Add(30 * 24 * time.Hour),
 		ConfidenceScore:    0.85,
-	}, nil
+	}
+
+	// Record the score, then analyze trends over the history including it
+	if err := s.recordMaturityAssessment(score); err != nil {
+		return nil, fmt.Errorf("failed to record maturity assessment: %w", err)
+	}
+	if score.Trends, err = s.GetMaturityTrends(organizationID); err != nil {
+		return nil, err
+	}
+	return score, nil
 }
 
 // Helper methods
@@ -210,16 +215,3 @@ func (s *AnalyticsService) generateImprovementRoadmap(dimensions map[string]Dime
 	
 	return roadmap
 }
-
-func (s *AnalyticsService) analyzeMaturityTrends(organizationID uuid.UUID) []MaturityTrend {
-	return []MaturityTrend{
-		{
-			Dimension:   "overall",
-			Direction:   "improving",
-			Magnitude:   5.0,
-			Confidence:  0.8,
-			Description: "Overall maturity is improving",
-		},
-	}
-}
-
@@ -0,0 +1,147 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// maturityTrendWindow is how many of the latest assessments trends are fitted to
+const maturityTrendWindow = 12
+
+// minTrendAssessments is the fewest assessments a trend is fitted to
+const minTrendAssessments = 3
+
+// stableMaturitySlope is the smallest change in score per 30 days counted as
+// improving or declining
+const stableMaturitySlope = 1.0
+
+// recordMaturityAssessment keeps a computed maturity score in the history
+func (s *AnalyticsService) recordMaturityAssessment(score *MaturityScore) error {
+	dimensions := make(map[string]float64, len(score.DimensionScores))
+	for name, dimension := range score.DimensionScores {
+		dimensions[name] = dimension.Score
+	}
+	return s.db.Create(&models.MaturityAssessment{
+		ID:              uuid.New(),
+		OrganizationID:  score.OrganizationID,
+		OverallScore:    score.OverallScore,
+		MaturityLevel:   score.MaturityLevel,
+		DimensionScores: dimensions,
+		AssessedAt:      score.GeneratedAt,
+	}).Error
+}
+
+// GetMaturityHistory returns an organization's latest maturity assessments,
+// oldest first
+func (s *AnalyticsService) GetMaturityHistory(organizationID uuid.UUID, limit int) ([]models.MaturityAssessment, error) {
+	var history []models.MaturityAssessment
+
+	err := s.db.Where("organization_id = ?", organizationID).
+		Order("assessed_at DESC").
+		Limit(limit).
+		Find(&history).Error
+	slices.Reverse(history)
+
+	return history, err
+}
+
+// GetMaturityTrends analyzes the trends of an organization's latest
+// maturity assessments
+func (s *AnalyticsService) GetMaturityTrends(organizationID uuid.UUID) ([]MaturityTrend, error) {
+	history, err := s.GetMaturityHistory(organizationID, maturityTrendWindow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maturity history: %w", err)
+	}
+	return analyzeMaturityTrends(history), nil
+}
+
+// analyzeMaturityTrends fits a line to the overall score and each dimension's
+// score over assessments, oldest first. A trend's magnitude is its slope in
+// score points per 30 days, and its confidence how much of the scores'
+// variance the line explains, or leaves unexplained for a stable trend.
+func analyzeMaturityTrends(history []models.MaturityAssessment) []MaturityTrend {
+	if len(history) < minTrendAssessments {
+		return []MaturityTrend{{
+			Dimension:   "overall",
+			Direction:   "stable",
+			Description: fmt.Sprintf("%d of the %d assessments needed to identify a trend", len(history), minTrendAssessments),
+		}}
+	}
+
+	series := map[string][][2]float64{}
+	start := history[0].AssessedAt
+	for _, assessment := range history {
+		days := assessment.AssessedAt.Sub(start).Hours() / 24
+		series["overall"] = append(series["overall"], [2]float64{days, assessment.OverallScore})
+		for name, score := range assessment.DimensionScores {
+			series[name] = append(series[name], [2]float64{days, score})
+		}
+	}
+
+	// Overall first, then dimensions by name
+	names := make([]string, 0, len(series))
+	for name := range series {
+		if name != "overall" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{"overall"}, names...)
+
+	trends := []MaturityTrend{}
+	for _, name := range names {
+		points := series[name]
+		if len(points) < minTrendAssessments {
+			continue // A dimension added recently
+		}
+		slope, fit := fitMaturityTrend(points)
+		monthly := slope * 30
+
+		trend := MaturityTrend{Dimension: name, Magnitude: math.Round(math.Abs(monthly)*100) / 100}
+		switch {
+		case monthly >= stableMaturitySlope:
+			trend.Direction, trend.Confidence = "improving", fit
+		case monthly <= -stableMaturitySlope:
+			trend.Direction, trend.Confidence = "declining", fit
+		default:
+			trend.Direction, trend.Confidence = "stable", 1-fit
+		}
+		trend.Confidence = math.Round(trend.Confidence*100) / 100
+		trend.Description = fmt.Sprintf("%s maturity is %s by %.1f points a month over the last %d assessments", name, trend.Direction, trend.Magnitude, len(points))
+		if trend.Direction == "stable" {
+			trend.Description = fmt.Sprintf("%s maturity is stable over the last %d assessments", name, len(points))
+		}
+		trends = append(trends, trend)
+	}
+	return trends
+}
+
+// fitMaturityTrend fits a least squares line to (day, score) points,
+// returning its slope per day and its coefficient of determination. Scores
+// that don't vary leave no variance to explain, so fit a flat line with 0.
+func fitMaturityTrend(points [][2]float64) (slope, fit float64) {
+	n := float64(len(points))
+	var meanX, meanY float64
+	for _, p := range points {
+		meanX += p[0] / n
+		meanY += p[1] / n
+	}
+	var sxx, sxy, syy float64
+	for _, p := range points {
+		dx, dy := p[0]-meanX, p[1]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if syy == 0 || sxx == 0 {
+		return 0, 0
+	}
+	slope = sxy / sxx
+	return slope, sxy * sxy / (sxx * syy)
+}
@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeMaturityTrends(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var history []models.MaturityAssessment
+	for i, overall := range []float64{50, 52, 55, 56, 60} {
+		history = append(history, models.MaturityAssessment{
+			OverallScore: overall,
+			DimensionScores: map[string]float64{
+				"vulnerability_management": 70,                               // Unchanged
+				"patch_management":         80 - float64(i)*3,                // Falling steadily
+				"incident_response":        []float64{40, 60, 40, 60, 40}[i], // Noisy but flat
+			},
+			AssessedAt: start.AddDate(0, 0, 30*i),
+		})
+	}
+
+	trends := analyzeMaturityTrends(history)
+	require.Len(t, trends, 4)
+	byDimension := map[string]MaturityTrend{}
+	for _, trend := range trends {
+		byDimension[trend.Dimension] = trend
+	}
+	assert.Equal(t, "overall", trends[0].Dimension)
+
+	overall := byDimension["overall"]
+	assert.Equal(t, "improving", overall.Direction)
+	assert.InDelta(t, 2.4, overall.Magnitude, 0.01)
+	assert.Greater(t, overall.Confidence, 0.9)
+
+	patch := byDimension["patch_management"]
+	assert.Equal(t, "declining", patch.Direction)
+	assert.InDelta(t, 3, patch.Magnitude, 0.01)
+	assert.Equal(t, 1.0, patch.Confidence)
+
+	assert.Equal(t, MaturityTrend{Dimension: "vulnerability_management", Direction: "stable", Confidence: 1,
+		Description: "vulnerability_management maturity is stable over the last 5 assessments"}, byDimension["vulnerability_management"])
+	assert.Equal(t, "stable", byDimension["incident_response"].Direction)
+
+	// Too few assessments give no trend
+	few := analyzeMaturityTrends(history[:2])
+	require.Len(t, few, 1)
+	assert.Equal(t, "stable", few[0].Direction)
+	assert.Zero(t, few[0].Confidence)
+}
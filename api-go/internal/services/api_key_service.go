@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when an API key does not exist or belongs to another organization
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKey is returned when authenticating with a key that was never issued
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyExpired is returned when authenticating with a key past its expiry
+	ErrAPIKeyExpired = errors.New("API key has expired")
+	// ErrAPIKeyRevoked is returned when authenticating with, or rotating, a revoked key
+	ErrAPIKeyRevoked = errors.New("API key has been revoked")
+	// ErrAPIKeyRotated is returned when rotating a key that was already rotated
+	ErrAPIKeyRotated = errors.New("API key has already been rotated")
+	// ErrInvalidAPIKeyPermission is returned for a permission API keys can't be granted
+	ErrInvalidAPIKeyPermission = errors.New("invalid API key permission")
+)
+
+const (
+	// defaultAPIKeyLifetime is how long a key is valid when no expiry is requested
+	defaultAPIKeyLifetime = 90 * 24 * time.Hour
+	// apiKeyUsageInterval is how stale a key's last use may get before it is
+	// recorded again, so busy keys don't write on every request
+	apiKeyUsageInterval = time.Minute
+	// apiKeySecretBytes is the randomness in a key
+	apiKeySecretBytes = 24
+	// apiKeyPrefixLength is how much of a key is kept to recognize it by
+	apiKeyPrefixLength = len(models.APIKeyPrefix) + 8
+)
+
+// APIKeyService issues, rotates and revokes organizations' API keys, and
+// authenticates the service accounts using them
+type APIKeyService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db, now: time.Now}
+}
+
+// hashAPIKey returns the hex SHA-256 a key is stored and looked up by
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateAPIKeyPermissions checks permissions are ones keys may be granted,
+// returning them sorted and without duplicates
+func validateAPIKeyPermissions(permissions []string) ([]string, error) {
+	seen := make(map[string]bool, len(permissions))
+	var valid []string
+	for _, p := range permissions {
+		known := false
+		for _, allowed := range models.APIKeyPermissions {
+			known = known || p == allowed
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAPIKeyPermission, p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			valid = append(valid, p)
+		}
+	}
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("%w: at least one is required", ErrInvalidAPIKeyPermission)
+	}
+	sort.Strings(valid)
+	return valid, nil
+}
+
+// newAPIKey generates a key for an organization; only its hash is kept on the record
+func newAPIKey(organizationID uuid.UUID, name, createdBy string, permissions []string, expiresAt, now time.Time) (*models.IssuedAPIKey, error) {
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := models.APIKeyPrefix + hex.EncodeToString(secret)
+
+	return &models.IssuedAPIKey{
+		APIKey: models.APIKey{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			Name:           name,
+			Prefix:         key[:apiKeyPrefixLength],
+			KeyHash:        hashAPIKey(key),
+			Permissions:    permissions,
+			CreatedBy:      createdBy,
+			ExpiresAt:      expiresAt,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		},
+		Key: key,
+	}, nil
+}
+
+// checkAPIKeyUsable returns why a key can't be used at now, if it can't
+func checkAPIKeyUsable(key *models.APIKey, now time.Time) error {
+	if key.RevokedAt != nil {
+		return ErrAPIKeyRevoked
+	}
+	if !now.Before(key.ExpiresAt) {
+		return ErrAPIKeyExpired
+	}
+	return nil
+}
+
+// CreateAPIKey issues an API key to an organization. The returned key is the
+// only time its secret is available.
+func (s *APIKeyService) CreateAPIKey(organizationID uuid.UUID, createdBy string, req *models.CreateAPIKeyRequest) (*models.IssuedAPIKey, error) {
+	permissions, err := validateAPIKeyPermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	lifetime := defaultAPIKeyLifetime
+	if req.ExpiresInDays > 0 {
+		lifetime = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	now := s.now()
+	issued, err := newAPIKey(organizationID, req.Name, createdBy, permissions, now.Add(lifetime), now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&issued.APIKey).Error; err != nil {
+		return nil, fmt.Errorf("failed to save API key: %w", err)
+	}
+	return issued, nil
+}
+
+// ListAPIKeys lists an organization's API keys, newest first, without their secrets
+func (s *APIKeyService) ListAPIKeys(organizationID uuid.UUID) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := s.db.Where("organization_id = ?", organizationID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// getAPIKey loads an organization's API key
+func (s *APIKeyService) getAPIKey(tx *gorm.DB, organizationID, keyID uuid.UUID) (*models.APIKey, error) {
+	var key models.APIKey
+	err := tx.Where("id = ? AND organization_id = ?", keyID, organizationID).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RotateAPIKey issues a key with the same name, permissions and lifetime as
+// an organization's key, and retires the old one after the grace period, so
+// a pipeline can switch over without failing requests
+func (s *APIKeyService) RotateAPIKey(organizationID, keyID uuid.UUID, createdBy string, req *models.RotateAPIKeyRequest) (*models.IssuedAPIKey, error) {
+	var issued *models.IssuedAPIKey
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		old, err := s.getAPIKey(tx, organizationID, keyID)
+		if err != nil {
+			return err
+		}
+		now := s.now()
+		if err := checkAPIKeyUsable(old, now); err != nil {
+			return err
+		}
+		if old.ReplacedBy != nil {
+			return ErrAPIKeyRotated
+		}
+
+		issued, err = newAPIKey(organizationID, old.Name, createdBy, old.Permissions, now.Add(old.ExpiresAt.Sub(old.CreatedAt)), now)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&issued.APIKey).Error; err != nil {
+			return fmt.Errorf("failed to save API key: %w", err)
+		}
+
+		retireAt := now.Add(time.Duration(req.GracePeriodMinutes) * time.Minute)
+		if old.ExpiresAt.Before(retireAt) {
+			retireAt = old.ExpiresAt
+		}
+		return tx.Model(old).Updates(map[string]interface{}{
+			"replaced_by": issued.ID,
+			"expires_at":  retireAt,
+			"updated_at":  now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// RevokeAPIKey stops an organization's API key from authenticating
+func (s *APIKeyService) RevokeAPIKey(organizationID, keyID uuid.UUID) error {
+	now := s.now()
+	result := s.db.Model(&models.APIKey{}).
+		Where("id = ? AND organization_id = ? AND revoked_at IS NULL", keyID, organizationID).
+		Updates(map[string]interface{}{"revoked_at": now, "updated_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.getAPIKey(s.db, organizationID, keyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns the API key a request presented, if it is
+// usable, recording that it was used
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	err := s.db.WithContext(ctx).Where("key_hash = ?", hashAPIKey(key)).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if err := checkAPIKeyUsable(&apiKey, now); err != nil {
+		return nil, err
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyUsageInterval {
+		// Recording use is best effort; it must not fail the request
+		if err := s.db.Model(&apiKey).UpdateColumn("last_used_at", now).Error; err != nil {
+			log.Printf("Failed to record use of API key %s: %v", apiKey.ID, err)
+		}
+		apiKey.LastUsedAt = &now
+	}
+	return &apiKey, nil
+}
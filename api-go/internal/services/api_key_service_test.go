@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAPIKeyPermissions(t *testing.T) {
+	permissions, err := validateAPIKeyPermissions([]string{"scans:write", "findings:read", "scans:write"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"findings:read", "scans:write"}, permissions)
+
+	for _, invalid := range [][]string{nil, {}, {"scans:read", "admin"}, {"SCANS:READ"}} {
+		_, err := validateAPIKeyPermissions(invalid)
+		assert.ErrorIs(t, err, ErrInvalidAPIKeyPermission, invalid)
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := NewAPIKeyService(dryRunDB(t))
+	s.now = func() time.Time { return now }
+	orgID := uuid.New()
+
+	issued, err := s.CreateAPIKey(orgID, "user_1", &models.CreateAPIKeyRequest{Name: "ci", Permissions: []string{"scans:read", "scans:write"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Key, models.APIKeyPrefix))
+	assert.Len(t, issued.Key, len(models.APIKeyPrefix)+2*apiKeySecretBytes)
+	assert.Equal(t, issued.Key[:apiKeyPrefixLength], issued.Prefix)
+	assert.Equal(t, hashAPIKey(issued.Key), issued.KeyHash, "only the hash is stored")
+	assert.NotContains(t, issued.KeyHash, issued.Key[len(models.APIKeyPrefix):])
+	assert.Equal(t, orgID, issued.OrganizationID)
+	assert.Equal(t, now.Add(defaultAPIKeyLifetime), issued.ExpiresAt)
+	assert.True(t, issued.HasPermission(models.APIKeyPermissionCreateScans))
+	assert.False(t, issued.HasPermission(models.APIKeyPermissionReadFindings))
+
+	other, err := s.CreateAPIKey(orgID, "user_1", &models.CreateAPIKeyRequest{Name: "nightly", Permissions: []string{"findings:read"}, ExpiresInDays: 7})
+	require.NoError(t, err)
+	assert.NotEqual(t, issued.Key, other.Key)
+	assert.Equal(t, now.Add(7*24*time.Hour), other.ExpiresAt)
+
+	_, err = s.CreateAPIKey(orgID, "user_1", &models.CreateAPIKeyRequest{Name: "ci", Permissions: []string{"agents:write"}})
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyPermission)
+}
+
+func TestCheckAPIKeyUsable(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	revokedAt := now.Add(-time.Minute)
+
+	assert.NoError(t, checkAPIKeyUsable(&models.APIKey{ExpiresAt: now.Add(time.Second)}, now))
+	assert.ErrorIs(t, checkAPIKeyUsable(&models.APIKey{ExpiresAt: now}, now), ErrAPIKeyExpired)
+	assert.ErrorIs(t, checkAPIKeyUsable(&models.APIKey{ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}, now), ErrAPIKeyRevoked)
+}
@@ -1,28 +1,83 @@
 package services
 
 import (
-	"database/sql"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"math"
+	"net"
+	"sort"
 	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultAttackPathDepth is how many hosts an attack path may cross
+	// unless asked otherwise
+	DefaultAttackPathDepth = 5
+
+	// MaxAttackPathDepth bounds the depth that may be asked for, as the
+	// search grows with it
+	MaxAttackPathDepth = 10
+
+	// maxAttackPaths bounds the paths returned, riskiest first
+	maxAttackPaths = 100
 )
 
+// attackPathImpact is the impact of compromising a host, by asset criticality
+var attackPathImpact = map[string]float64{
+	models.AssetCriticalityLow:      0.25,
+	models.AssetCriticalityMedium:   0.5,
+	models.AssetCriticalityHigh:     0.8,
+	models.AssetCriticalityCritical: 1,
+}
+
+// attackPathSeverityCVSS stands in for the CVSS score of findings without one
+var attackPathSeverityCVSS = map[string]float64{
+	"critical": 9,
+	"high":     7.5,
+	"medium":   5,
+	"low":      2.5,
+}
+
+// AttackPathService finds the ways an attacker could move through an
+// organization's network. Its hosts form a directed graph: a host reaches
+// those in its subnet and those it observed on the network, unless they were
+// seen with no open ports. A host can be compromised through its most
+// exploitable open finding. Paths run from internet-facing hosts to high-value
+// ones, those of high or critical asset criticality, choosing the hops most
+// likely to succeed.
 type AttackPathService struct {
-	db *sql.DB
+	db            *gorm.DB
+	agentService  *AgentService
+	networkAssets *NetworkAssetService
 }
 
-func NewAttackPathService(db *sql.DB) *AttackPathService {
-	return &AttackPathService{db: db}
+// NewAttackPathService creates a new attack path service
+func NewAttackPathService(db *gorm.DB, agentService *AgentService, networkAssets *NetworkAssetService) *AttackPathService {
+	return &AttackPathService{
+		db:            db,
+		agentService:  agentService,
+		networkAssets: networkAssets,
+	}
 }
 
+// AttackPath is a way through the network from an internet-facing host to a
+// high-value one, exploiting one finding on each host it crosses
 type AttackPath struct {
-	PathID             string       `json:"path_id"`
+	PathID             string       `json:"path_id"` // Stable for the same hosts and findings
 	Name               string       `json:"name"`
+	Nodes              []string     `json:"nodes"` // Agent IDs of the hosts crossed, in order
 	Steps              []AttackStep `json:"steps"`
-	TotalLikelihood    float64      `json:"total_likelihood"`
-	TotalImpact        float64      `json:"total_impact"`
-	CriticalityScore   float64      `json:"criticality_score"`
+	TotalLikelihood    float64      `json:"total_likelihood"`  // Chance every step succeeds
+	TotalImpact        float64      `json:"total_impact"`      // Impact of compromising the target
+	CriticalityScore   float64      `json:"criticality_score"` // TotalLikelihood times TotalImpact
+	RiskScore          float64      `json:"risk_score"`        // CriticalityScore from 0 to 100
 	MitigationPriority string       `json:"mitigation_priority"`
 	DetectionPoints    []string     `json:"detection_points"`
 	PreventionControls []string     `json:"prevention_controls"`
@@ -42,265 +97,376 @@ type AttackStep struct {
 	DetectionDifficulty string   `json:"detection_difficulty"`
 	StepType            string   `json:"step_type"` // initial_access, lateral_movement, privilege_escalation, data_exfiltration, persistence
 	CVEID               string   `json:"cve_id,omitempty"`
-	VulnerabilityID     string   `json:"vulnerability_id,omitempty"`
-	Proof               string   `json:"proof,omitempty"` // Exploit command or evidence
+	VulnerabilityID     string   `json:"vulnerability_id,omitempty"` // The exploited finding
+	Proof               string   `json:"proof,omitempty"`            // Exploit command or evidence
 	MitigationControls  []string `json:"mitigation_controls,omitempty"`
 }
 
-// GetAttackPaths retrieves all attack paths for an organization
-func (s *AttackPathService) GetAttackPaths(organizationID string) ([]AttackPath, error) {
-	// Query vulnerabilities and network scans to build attack paths
-	query := `
-		SELECT 
-			v.id,
-			v.cve_id,
-			v.severity,
-			v.title,
-			v.description,
-			v.risk_score,
-			v.agent_id,
-			a.hostname,
-			a.os,
-			a.metadata
-		FROM vulnerabilities v
-		LEFT JOIN agents a ON v.agent_id = a.id
-		WHERE v.severity IN ('critical', 'high')
-		ORDER BY v.risk_score DESC
-		LIMIT 100
-	`
-
-	rows, err := s.db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query vulnerabilities: %w", err)
-	}
-	defer rows.Close()
-
-	var vulnerabilities []struct {
-		ID          string
-		CVEID       sql.NullString
-		Severity    string
-		Title       string
-		Description sql.NullString
-		RiskScore   float64
-		AgentID     sql.NullString
-		Hostname    sql.NullString
-		OS          sql.NullString
-		Metadata    sql.NullString
-	}
-
-	for rows.Next() {
-		var v struct {
-			ID          string
-			CVEID       sql.NullString
-			Severity    string
-			Title       string
-			Description sql.NullString
-			RiskScore   float64
-			AgentID     sql.NullString
-			Hostname    sql.NullString
-			OS          sql.NullString
-			Metadata    sql.NullString
-		}
+// attackNode is a host in the attack graph with the finding it is most
+// easily compromised through, if any
+type attackNode struct {
+	agent          *models.Agent
+	internetFacing bool
+	criticality    string
+	finding        *models.FindingState
+	likelihood     float64 // Chance exploiting finding succeeds
+	openPorts      []int   // nil when the host was never seen by a network scan
+	observed       []int   // Nodes that observed the host on the network
+}
 
-		if err := rows.Scan(&v.ID, &v.CVEID, &v.Severity, &v.Title, &v.Description, &v.RiskScore, &v.AgentID, &v.Hostname, &v.OS, &v.Metadata); err != nil {
-			log.Printf("Error scanning vulnerability: %v", err)
-			continue
+// GetAttackPath returns one of an organization's attack paths crossing at
+// most maxDepth hosts, nil when there is no such path
+func (s *AttackPathService) GetAttackPath(organizationID uuid.UUID, pathID string, maxDepth int) (*AttackPath, error) {
+	paths, err := s.GenerateAttackPaths(organizationID, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	for i := range paths {
+		if paths[i].PathID == pathID {
+			return &paths[i], nil
 		}
-
-		vulnerabilities = append(vulnerabilities, v)
 	}
+	return nil, nil
+}
 
-	// Build attack paths from vulnerabilities
-	paths := s.buildAttackPathsFromVulnerabilities(vulnerabilities)
+// GenerateAttackPaths builds an organization's attack graph from its agents,
+// the network hosts they observed and their open findings, and returns the
+// riskiest path from each internet-facing host to each high-value host it
+// can reach crossing at most maxDepth hosts, riskiest first
+func (s *AttackPathService) GenerateAttackPaths(organizationID uuid.UUID, maxDepth int) ([]AttackPath, error) {
+	hosts, err := s.networkAssets.ListAssets(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network hosts: %w", err)
+	}
+	var findings []models.FindingState
+	err = s.db.Where("organization_id = ? AND status = ? AND suppressed = ?", organizationID, models.FindingStatusOpen, false).
+		Find(&findings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open findings: %w", err)
+	}
 
-	return paths, nil
+	nodes := buildAttackGraph(s.agentService.GetAgents(organizationID), hosts, findings)
+	return findAttackPaths(nodes, maxDepth, time.Now()), nil
 }
 
-// buildAttackPathsFromVulnerabilities creates attack paths from vulnerability data
-func (s *AttackPathService) buildAttackPathsFromVulnerabilities(vulns []struct {
-	ID          string
-	CVEID       sql.NullString
-	Severity    string
-	Title       string
-	Description sql.NullString
-	RiskScore   float64
-	AgentID     sql.NullString
-	Hostname    sql.NullString
-	OS          sql.NullString
-	Metadata    sql.NullString
-}) []AttackPath {
-	var paths []AttackPath
-
-	// Group vulnerabilities by agent/host to create paths
-	hostVulns := make(map[string][]struct {
-		ID          string
-		CVEID       sql.NullString
-		Severity    string
-		Title       string
-		Description sql.NullString
-		RiskScore   float64
-		AgentID     sql.NullString
-		Hostname    sql.NullString
-		OS          sql.NullString
-		Metadata    sql.NullString
-	})
+// buildAttackGraph makes a node of each agent, with the open ports and
+// observers of the network hosts at its address and its most exploitable
+// finding. Network hosts without an agent have no findings, so could never
+// be exploited, and are left out.
+func buildAttackGraph(agents []*models.Agent, hosts []models.NetworkHost, findings []models.FindingState) []*attackNode {
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID.String() < agents[j].ID.String() })
+
+	nodes := make([]*attackNode, 0, len(agents))
+	byAgent := make(map[uuid.UUID]int, len(agents))
+	byIP := make(map[string]int, len(agents))
+	for _, agent := range agents {
+		byAgent[agent.ID] = len(nodes)
+		if agent.IPAddress != "" {
+			byIP[agent.IPAddress] = len(nodes)
+		}
+		nodes = append(nodes, &attackNode{
+			agent:          agent,
+			internetFacing: isInternetFacing(agent),
+			criticality:    assetCriticality(agent),
+		})
+	}
 
-	for _, vuln := range vulns {
-		hostname := "Unknown"
-		if vuln.Hostname.Valid {
-			hostname = vuln.Hostname.String
-		} else if vuln.AgentID.Valid {
-			hostname = vuln.AgentID.String[:8]
+	for _, host := range hosts {
+		i, ok := byIP[host.IPAddress]
+		if !ok {
+			continue
+		}
+		node := nodes[i]
+		if node.openPorts == nil {
+			node.openPorts = []int{}
+		}
+		node.openPorts = append(node.openPorts, host.OpenPorts...)
+		for _, observer := range host.Observers {
+			if j, ok := byAgent[observer.AgentID]; ok && j != i {
+				node.observed = append(node.observed, j)
+			}
 		}
-		hostVulns[hostname] = append(hostVulns[hostname], vuln)
 	}
 
-	// Create attack paths
-	pathID := 1
-	for hostname, hostVulns := range hostVulns {
-		if len(hostVulns) == 0 {
+	for i := range findings {
+		finding := &findings[i]
+		j, ok := byAgent[finding.AgentID]
+		if !ok {
 			continue
 		}
+		likelihood := exploitLikelihood(finding)
+		node := nodes[j]
+		if likelihood > node.likelihood || (likelihood == node.likelihood && node.finding != nil && finding.FindingKey < node.finding.FindingKey) {
+			node.finding = finding
+			node.likelihood = likelihood
+		}
+	}
+	return nodes
+}
 
-		// Determine step type based on vulnerability
-		steps := []AttackStep{}
-		for i, vuln := range hostVulns {
-			stepType := "initial_access"
-			if i > 0 {
-				stepType = "lateral_movement"
-			}
-			if i == len(hostVulns)-1 {
-				stepType = "data_exfiltration"
-			}
+// exploitLikelihood is the chance of exploiting a finding: its CVSS score,
+// or one standing in for its severity, out of 10, halved unless it is known
+// to be exploited, then raised by its EPSS probability
+func exploitLikelihood(finding *models.FindingState) float64 {
+	cvss := finding.CVSSScore
+	if cvss <= 0 {
+		cvss = attackPathSeverityCVSS[strings.ToLower(finding.Severity)]
+	}
+	exploitability := 0.5 + 0.5*finding.EPSS
+	if finding.KnownExploited {
+		exploitability = 1
+	}
+	return cvss / 10 * exploitability
+}
 
-			cveID := ""
-			if vuln.CVEID.Valid {
-				cveID = vuln.CVEID.String
-			}
+// attackEdges returns the nodes each node reaches: those in its subnet and
+// those it observed, except nodes seen with no open ports
+func attackEdges(nodes []*attackNode) [][]int {
+	reaches := make([]map[int]bool, len(nodes))
+	for i := range reaches {
+		reaches[i] = make(map[int]bool)
+	}
 
-			// Calculate likelihood and impact from risk score
-			likelihood := vuln.RiskScore / 10.0
-			if likelihood > 1.0 {
-				likelihood = 1.0
-			}
-			impact := 0.9
-			if vuln.Severity == "critical" {
-				impact = 0.9
-			} else if vuln.Severity == "high" {
-				impact = 0.7
-			} else {
-				impact = 0.5
+	bySubnet := make(map[string][]int)
+	for i, node := range nodes {
+		if subnet := attackPathSubnet(node.agent.IPAddress); subnet != "" {
+			bySubnet[subnet] = append(bySubnet[subnet], i)
+		}
+	}
+	for _, members := range bySubnet {
+		for _, from := range members {
+			for _, to := range members {
+				reaches[from][to] = true
 			}
+		}
+	}
+	for to, node := range nodes {
+		for _, from := range node.observed {
+			reaches[from][to] = true
+		}
+	}
 
-			step := AttackStep{
-				StepNumber:          i + 1,
-				Action:              fmt.Sprintf("Exploit %s", vuln.Title),
-				Target:              hostname,
-				TargetHostname:      hostname,
-				Technique:           s.inferTechnique(vuln.Title, vuln.Description),
-				Likelihood:          likelihood,
-				Impact:              impact,
-				DetectionDifficulty: s.getDetectionDifficulty(vuln.Severity),
-				StepType:            stepType,
-				CVEID:               cveID,
-				VulnerabilityID:     vuln.ID,
-				Proof:               s.generateProof(vuln, cveID),
-				MitigationControls:  s.getMitigationControls(vuln.Severity),
+	edges := make([][]int, len(nodes))
+	for from := range nodes {
+		for to := range reaches[from] {
+			if to != from && (nodes[to].openPorts == nil || len(nodes[to].openPorts) > 0) {
+				edges[from] = append(edges[from], to)
 			}
+		}
+		sort.Ints(edges[from])
+	}
+	return edges
+}
 
-			// Extract IP from metadata if available
-			if vuln.Metadata.Valid {
-				var metadata map[string]interface{}
-				if err := json.Unmarshal([]byte(vuln.Metadata.String), &metadata); err == nil {
-					if ip, ok := metadata["ip_address"].(string); ok {
-						step.TargetIP = ip
-					}
-				}
-			}
+// attackPathSubnet returns the /24 of an IPv4 address or the /64 of an IPv6
+// one, empty when the address is not valid
+func attackPathSubnet(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String()
+}
+
+// findAttackPaths searches from every exploitable internet-facing node for
+// the likeliest path to each exploitable high-value node, crossing at most
+// maxDepth nodes. A path's likelihood is the product of its nodes', so with
+// each layer the search keeps, per node, the likeliest way to reach and
+// compromise it within that many hops.
+func findAttackPaths(nodes []*attackNode, maxDepth int, now time.Time) []AttackPath {
+	edges := attackEdges(nodes)
 
-			steps = append(steps, step)
+	var paths []AttackPath
+	for entry, node := range nodes {
+		if !node.internetFacing || node.finding == nil {
+			continue
 		}
 
-		if len(steps) > 0 {
-			// Calculate path metrics
-			totalLikelihood := 1.0
-			for _, step := range steps {
-				totalLikelihood *= step.Likelihood
+		// best[v] is the likeliest chance of compromising v within the hops
+		// searched so far, and parents[d][v] the node v is reached from at
+		// depth d+1, -1 at the entry and when v is no better reached then
+		best := make([]float64, len(nodes))
+		best[entry] = node.likelihood
+		parents := [][]int{filledInts(len(nodes), -1)}
+		frontier := []int{entry}
+		for depth := 1; depth < maxDepth && len(frontier) > 0; depth++ {
+			layer := filledInts(len(nodes), -1)
+			next := make(map[int]bool)
+			updated := append([]float64(nil), best...)
+			for _, from := range frontier {
+				for _, to := range edges[from] {
+					if nodes[to].finding == nil {
+						continue
+					}
+					if likelihood := best[from] * nodes[to].likelihood; likelihood > updated[to] {
+						updated[to] = likelihood
+						layer[to] = from
+						next[to] = true
+					}
+				}
 			}
-			totalImpact := steps[len(steps)-1].Impact // Use last step's impact
-			criticalityScore := totalLikelihood * totalImpact
-
-			mitigationPriority := "medium"
-			if criticalityScore > 0.5 {
-				mitigationPriority = "high"
-			} else if criticalityScore < 0.1 {
-				mitigationPriority = "low"
+			best = updated
+			parents = append(parents, layer)
+			frontier = frontier[:0]
+			for v := range next {
+				frontier = append(frontier, v)
 			}
+			sort.Ints(frontier)
+		}
 
-			path := AttackPath{
-				PathID:             fmt.Sprintf("ap_%03d", pathID),
-				Name:               fmt.Sprintf("Attack Path to %s", hostname),
-				Steps:              steps,
-				TotalLikelihood:    totalLikelihood,
-				TotalImpact:        totalImpact,
-				CriticalityScore:   criticalityScore,
-				MitigationPriority: mitigationPriority,
-				DetectionPoints:    []string{"Network logs", "Application logs", "IDS/IPS"},
-				PreventionControls: []string{"Patch management", "Network segmentation", "Access controls"},
+		for target, likelihood := range best {
+			if likelihood > 0 && attackPathImpact[nodes[target].criticality] >= attackPathImpact[models.AssetCriticalityHigh] {
+				paths = append(paths, newAttackPath(nodes, traceAttackPath(parents, entry, target), now))
 			}
-
-			paths = append(paths, path)
-			pathID++
 		}
 	}
 
+	sort.SliceStable(paths, func(i, j int) bool {
+		if paths[i].CriticalityScore != paths[j].CriticalityScore {
+			return paths[i].CriticalityScore > paths[j].CriticalityScore
+		}
+		return len(paths[i].Nodes) < len(paths[j].Nodes)
+	})
+	if len(paths) > maxAttackPaths {
+		paths = paths[:maxAttackPaths]
+	}
 	return paths
 }
 
-// inferTechnique determines MITRE ATT&CK technique from vulnerability
-func (s *AttackPathService) inferTechnique(title string, description sql.NullString) string {
-	titleLower := title
-	descLower := ""
-	if description.Valid {
-		descLower = description.String
+// traceAttackPath follows parents back from target to entry
+func traceAttackPath(parents [][]int, entry, target int) []int {
+	route := []int{target}
+	current := target
+	for depth := len(parents) - 1; depth > 0 && current != entry; depth-- {
+		if from := parents[depth][current]; from >= 0 {
+			route = append(route, from)
+			current = from
+		}
 	}
-
-	combined := titleLower + " " + descLower
-
-	if containsAttackPath(combined, "sql injection") || containsAttackPath(combined, "sqli") {
-		return "T1059.003 - Command and Scripting Interpreter"
+	for i, j := 0, len(route)-1; i < j; i, j = i+1, j-1 {
+		route[i], route[j] = route[j], route[i]
 	}
-	if containsAttackPath(combined, "xss") || containsAttackPath(combined, "cross-site") {
-		return "T1059.007 - JavaScript"
+	return route
+}
+
+// newAttackPath describes the path through the given nodes
+func newAttackPath(nodes []*attackNode, route []int, now time.Time) AttackPath {
+	path := AttackPath{
+		Nodes:              make([]string, 0, len(route)),
+		Steps:              make([]AttackStep, 0, len(route)),
+		TotalLikelihood:    1,
+		DetectionPoints:    []string{"Network logs", "Application logs", "IDS/IPS"},
+		PreventionControls: []string{"Patch management", "Network segmentation", "Access controls"},
+		CreatedAt:          now.UTC().Format(time.RFC3339),
 	}
-	if containsAttackPath(combined, "rce") || containsAttackPath(combined, "remote code execution") {
-		return "T1059 - Command and Scripting Interpreter"
+
+	id := sha256.New()
+	for i, n := range route {
+		node := nodes[n]
+		finding := node.finding
+		label := hostLabel(node.agent)
+		technique := inferTechnique(finding.Title)
+		stepType := "lateral_movement"
+		if i == 0 {
+			stepType = "initial_access"
+		}
+		exploit := finding.CVEID
+		if exploit == "" {
+			exploit = finding.Title
+		}
+
+		path.Nodes = append(path.Nodes, node.agent.ID.String())
+		path.Steps = append(path.Steps, AttackStep{
+			StepNumber:          i + 1,
+			Action:              fmt.Sprintf("Exploit %s on %s", exploit, label),
+			Target:              label,
+			TargetIP:            node.agent.IPAddress,
+			TargetHostname:      node.agent.Hostname,
+			Technique:           technique,
+			TechniqueID:         strings.SplitN(technique, " ", 2)[0],
+			Likelihood:          roundLikelihood(node.likelihood),
+			Impact:              attackPathImpact[node.criticality],
+			DetectionDifficulty: getDetectionDifficulty(finding.Severity),
+			StepType:            stepType,
+			CVEID:               finding.CVEID,
+			VulnerabilityID:     finding.ID.String(),
+			Proof:               generateProof(finding),
+			MitigationControls:  getMitigationControls(finding.Severity),
+		})
+		path.TotalLikelihood *= node.likelihood
+		fmt.Fprintf(id, "%s/%s;", node.agent.ID, finding.ID)
 	}
-	if containsAttackPath(combined, "privilege") || containsAttackPath(combined, "escalation") {
-		return "T1068 - Exploitation for Privilege Escalation"
+
+	target := nodes[route[len(route)-1]]
+	path.PathID = "ap_" + hex.EncodeToString(id.Sum(nil))[:16]
+	path.Name = fmt.Sprintf("Attack path from %s to %s", path.Steps[0].Target, hostLabel(target.agent))
+	path.TotalImpact = attackPathImpact[target.criticality]
+	path.CriticalityScore = roundLikelihood(path.TotalLikelihood * path.TotalImpact)
+	path.TotalLikelihood = roundLikelihood(path.TotalLikelihood)
+	path.RiskScore = roundScore(path.CriticalityScore * 100)
+	switch {
+	case path.CriticalityScore > 0.5:
+		path.MitigationPriority = "high"
+	case path.CriticalityScore < 0.1:
+		path.MitigationPriority = "low"
+	default:
+		path.MitigationPriority = "medium"
 	}
-	if containsAttackPath(combined, "authentication") || containsAttackPath(combined, "bypass") {
-		return "T1078 - Valid Accounts"
+	return path
+}
+
+// hostLabel names a host by its hostname, else its agent name
+func hostLabel(agent *models.Agent) string {
+	if agent.Hostname != "" {
+		return agent.Hostname
 	}
+	return agent.Name
+}
 
-	return "T1190 - Exploit Public-Facing Application"
+func filledInts(n, value int) []int {
+	values := make([]int, n)
+	for i := range values {
+		values[i] = value
+	}
+	return values
 }
 
-func containsAttackPath(s, substr string) bool {
-	return len(s) >= len(substr) && containsSubstring(strings.ToLower(s), strings.ToLower(substr))
+func roundLikelihood(likelihood float64) float64 {
+	return math.Round(likelihood*10000) / 10000
 }
 
-func containsSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+// inferTechnique determines MITRE ATT&CK technique from vulnerability
+func inferTechnique(title string) string {
+	title = strings.ToLower(title)
+	contains := func(substrings ...string) bool {
+		for _, substring := range substrings {
+			if strings.Contains(title, substring) {
+				return true
+			}
 		}
+		return false
+	}
+
+	switch {
+	case contains("sql injection", "sqli"):
+		return "T1059.003 - Command and Scripting Interpreter"
+	case contains("xss", "cross-site"):
+		return "T1059.007 - JavaScript"
+	case contains("rce", "remote code execution"):
+		return "T1059 - Command and Scripting Interpreter"
+	case contains("privilege", "escalation"):
+		return "T1068 - Exploitation for Privilege Escalation"
+	case contains("authentication", "bypass"):
+		return "T1078 - Valid Accounts"
 	}
-	return false
+	return "T1190 - Exploit Public-Facing Application"
 }
 
-func (s *AttackPathService) getDetectionDifficulty(severity string) string {
-	switch severity {
+func getDetectionDifficulty(severity string) string {
+	switch strings.ToLower(severity) {
 	case "critical":
 		return "low" // Critical vulns are easier to detect
 	case "high":
@@ -310,49 +476,20 @@ func (s *AttackPathService) getDetectionDifficulty(severity string) string {
 	}
 }
 
-func (s *AttackPathService) generateProof(vuln struct {
-	ID          string
-	CVEID       sql.NullString
-	Severity    string
-	Title       string
-	Description sql.NullString
-	RiskScore   float64
-	AgentID     sql.NullString
-	Hostname    sql.NullString
-	OS          sql.NullString
-	Metadata    sql.NullString
-}, cveID string) string {
-	if cveID != "" {
-		return fmt.Sprintf("Exploit available for %s. Check Exploit-DB, Metasploit, or GitHub for proof-of-concept.", cveID)
-	}
-	return fmt.Sprintf("Vulnerability %s detected. Manual verification recommended.", vuln.Title)
+func generateProof(finding *models.FindingState) string {
+	if finding.KnownExploited && finding.CVEID != "" {
+		return fmt.Sprintf("%s is known to be exploited. Check Exploit-DB, Metasploit, or GitHub for proof-of-concept.", finding.CVEID)
+	}
+	if finding.CVEID != "" {
+		return fmt.Sprintf("%s is open on this host. Check Exploit-DB, Metasploit, or GitHub for proof-of-concept.", finding.CVEID)
+	}
+	return fmt.Sprintf("Vulnerability %s detected. Manual verification recommended.", finding.Title)
 }
 
-func (s *AttackPathService) getMitigationControls(severity string) []string {
+func getMitigationControls(severity string) []string {
 	controls := []string{"Apply security patches", "Implement network segmentation"}
-	if severity == "critical" {
+	if strings.EqualFold(severity, "critical") {
 		controls = append(controls, "Immediate remediation required", "Enable additional monitoring")
 	}
 	return controls
 }
-
-// GetAttackPath retrieves a specific attack path
-func (s *AttackPathService) GetAttackPath(pathID string) (*AttackPath, error) {
-	paths, err := s.GetAttackPaths("")
-	if err != nil {
-		return nil, err
-	}
-
-	for _, path := range paths {
-		if path.PathID == pathID {
-			return &path, nil
-		}
-	}
-
-	return nil, fmt.Errorf("attack path not found: %s", pathID)
-}
-
-// GenerateAttackPaths generates new attack paths (same as GetAttackPaths for now)
-func (s *AttackPathService) GenerateAttackPaths(organizationID string) ([]AttackPath, error) {
-	return s.GetAttackPaths(organizationID)
-}
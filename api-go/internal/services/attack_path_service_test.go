@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindAttackPaths(t *testing.T) {
+	host := func(hostname, ip, criticality string) *models.Agent {
+		return &models.Agent{ID: uuid.New(), Hostname: hostname, IPAddress: ip, Metadata: map[string]any{"asset_criticality": criticality}}
+	}
+	web := host("web", "203.0.113.10", models.AssetCriticalityMedium)
+	app := host("app", "10.0.0.5", models.AssetCriticalityMedium)
+	worker := host("worker", "10.0.0.6", models.AssetCriticalityMedium)
+	db := host("db", "10.0.0.7", models.AssetCriticalityCritical)
+	vault := host("vault", "10.0.0.8", models.AssetCriticalityHigh)
+	agents := []*models.Agent{web, app, worker, db, vault}
+
+	// The web server sees the app servers on the network; the vault listens on nothing
+	observedBy := func(ip string, ports []int, observer uuid.UUID) models.NetworkHost {
+		return models.NetworkHost{IPAddress: ip, OpenPorts: ports, Observers: []models.NetworkHostObserver{{AgentID: observer}}}
+	}
+	hosts := []models.NetworkHost{
+		observedBy(app.IPAddress, []int{8080}, web.ID),
+		observedBy(worker.IPAddress, []int{8443}, web.ID),
+		observedBy(vault.IPAddress, []int{}, app.ID),
+	}
+
+	finding := func(agent *models.Agent, cve, severity string, score float64, exploited bool) models.FindingState {
+		return models.FindingState{ID: uuid.New(), AgentID: agent.ID, FindingKey: cve, CVEID: cve, Title: cve, Severity: severity, CVSSScore: score, KnownExploited: exploited}
+	}
+	findings := []models.FindingState{
+		finding(web, "CVE-2021-44228", "critical", 10, true),
+		finding(web, "CVE-2019-0001", "low", 2, false),
+		finding(app, "CVE-2022-0002", "high", 8, false),
+		finding(worker, "CVE-2022-0003", "critical", 9, true),
+		finding(db, "CVE-2022-0004", "high", 7, true),
+		finding(vault, "CVE-2022-0005", "critical", 10, true),
+	}
+
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	paths := findAttackPaths(buildAttackGraph(agents, hosts, findings), 3, now)
+
+	// The likeliest way in to the database runs through the exploited worker, not
+	// the app server; the vault can't be reached
+	require.Len(t, paths, 1)
+	path := paths[0]
+	assert.Equal(t, []string{web.ID.String(), worker.ID.String(), db.ID.String()}, path.Nodes)
+	var cves []string
+	for _, step := range path.Steps {
+		cves = append(cves, step.CVEID)
+	}
+	assert.Equal(t, []string{"CVE-2021-44228", "CVE-2022-0003", "CVE-2022-0004"}, cves)
+	assert.Equal(t, "initial_access", path.Steps[0].StepType)
+	assert.Equal(t, "lateral_movement", path.Steps[2].StepType)
+	assert.Equal(t, 0.63, path.TotalLikelihood)
+	assert.Equal(t, 1.0, path.TotalImpact)
+	assert.Equal(t, 63.0, path.RiskScore)
+	assert.Equal(t, "high", path.MitigationPriority)
+
+	// Path IDs are stable; too shallow a search finds nothing
+	assert.Equal(t, path.PathID, findAttackPaths(buildAttackGraph(agents, hosts, findings), 3, now)[0].PathID)
+	assert.Empty(t, findAttackPaths(buildAttackGraph(agents, hosts, findings), 2, now))
+}
@@ -0,0 +1,586 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/events"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBackfillJobNotFound is returned for an unknown backfill job
+	ErrBackfillJobNotFound = errors.New("backfill job not found")
+	// ErrBackfillJobFinished is returned when cancelling a job that already finished
+	ErrBackfillJobFinished = errors.New("backfill job already finished")
+	// ErrBackfillJobNotResumable is returned when resuming a job that did not fail or was not cancelled
+	ErrBackfillJobNotResumable = errors.New("only failed or cancelled backfill jobs can be resumed")
+)
+
+// backfillJobListLimit bounds the jobs returned by List
+const backfillJobListLimit = 50
+
+// BackfillJobService re-enriches and re-ingests stored findings in the
+// background, for backfills after enrichment data or prioritization changes.
+// Jobs page through their rows with keyset pagination and commit each batch
+// in its own short transaction, together with their cursor, so a job can
+// cover millions of rows without a long transaction, and one interrupted by
+// a restart resumes where it stopped instead of starting over. Re-enrichment
+// also reopens findings suppressed until a fix once one is published, and
+// notifies their owners.
+type BackfillJobService struct {
+	db            *gorm.DB
+	enrichment    *EnrichmentService
+	agentService  *AgentService
+	findingStates *FindingStateService
+	hostRisk      *HostRiskService
+	batchSize     int
+	concurrency   int
+
+	httpClient     *http.Client
+	events         *events.Registry
+	validateEvents bool // Check notifications against their published schema before sending
+
+	mu      sync.Mutex
+	cancels map[uuid.UUID]context.CancelFunc // Running jobs
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewBackfillJobService creates a new backfill job service
+func NewBackfillJobService(db *gorm.DB, cfg *config.Config, enrichment *EnrichmentService, agentService *AgentService, findingStates *FindingStateService, hostRisk *HostRiskService) *BackfillJobService {
+	batchSize := cfg.BackfillBatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	concurrency := cfg.BackfillConcurrency
+	if concurrency <= 0 {
+		concurrency = 2
+	}
+
+	return &BackfillJobService{
+		db:            db,
+		enrichment:    enrichment,
+		agentService:  agentService,
+		findingStates: findingStates,
+		hostRisk:      hostRisk,
+		batchSize:     batchSize,
+		concurrency:   concurrency,
+
+		httpClient:     newCallbackClient(10 * time.Second),
+		events:         events.Default(),
+		validateEvents: cfg.EventSchemaValidation,
+
+		cancels:  make(map[uuid.UUID]context.CancelFunc),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start resumes the jobs a restart interrupted, from their last committed batch
+func (s *BackfillJobService) Start() {
+	var interrupted []models.BackfillJob
+	err := s.db.Where("status IN ?", []string{models.BackfillJobPending, models.BackfillJobRunning}).
+		Order("created_at").Find(&interrupted).Error
+	if err != nil {
+		log.Printf("Failed to find interrupted backfill jobs: %v", err)
+		return
+	}
+	for i := range interrupted {
+		log.Printf("Resuming %s backfill %s after %d of %d rows", interrupted[i].Kind, interrupted[i].ID, interrupted[i].Processed, interrupted[i].Total)
+		s.launch(interrupted[i])
+	}
+}
+
+// Stop stops running jobs after their current batch. They stay running and
+// resume on the next Start.
+func (s *BackfillJobService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+}
+
+// Submit records a backfill job and runs it in the background
+func (s *BackfillJobService) Submit(req *models.CreateBackfillJobRequest) (*models.BackfillJob, error) {
+	job := &models.BackfillJob{
+		ID:          uuid.New(),
+		Kind:        req.Kind,
+		Status:      models.BackfillJobPending,
+		BatchSize:   req.BatchSize,
+		Concurrency: req.Concurrency,
+		RequestedBy: req.RequestedBy,
+	}
+	if job.BatchSize <= 0 {
+		job.BatchSize = s.batchSize
+	}
+	if job.Concurrency <= 0 {
+		job.Concurrency = s.concurrency
+	}
+
+	var err error
+	switch job.Kind {
+	case models.BackfillReenrich:
+		err = s.db.Model(&models.Software{}).Count(&job.Total).Error
+	case models.BackfillReingest:
+		err = s.db.Model(&models.FindingState{}).Count(&job.Total).Error
+	default:
+		return nil, fmt.Errorf("unknown backfill kind %q", job.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows to backfill: %w", err)
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to record backfill job: %w", err)
+	}
+
+	s.launch(*job)
+	return job, nil
+}
+
+// Get returns a backfill job with its progress and estimated completion
+func (s *BackfillJobService) Get(id uuid.UUID) (*models.BackfillJob, error) {
+	var job models.BackfillJob
+	if err := s.db.First(&job, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBackfillJobNotFound
+		}
+		return nil, err
+	}
+	backfillProgress(&job, time.Now())
+	return &job, nil
+}
+
+// List returns the most recent backfill jobs, newest first
+func (s *BackfillJobService) List() ([]models.BackfillJob, error) {
+	var jobs []models.BackfillJob
+	if err := s.db.Order("created_at DESC").Limit(backfillJobListLimit).Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list backfill jobs: %w", err)
+	}
+	now := time.Now()
+	for i := range jobs {
+		backfillProgress(&jobs[i], now)
+	}
+	return jobs, nil
+}
+
+// Cancel stops a job after its current batch. What it committed is kept, and
+// it can be resumed from there.
+func (s *BackfillJobService) Cancel(id uuid.UUID) (*models.BackfillJob, error) {
+	s.mu.Lock()
+	cancel, running := s.cancels[id]
+	s.mu.Unlock()
+	if running {
+		cancel()
+		return s.Get(id)
+	}
+
+	result := s.db.Model(&models.BackfillJob{}).
+		Where("id = ? AND status IN ?", id, []string{models.BackfillJobPending, models.BackfillJobRunning}).
+		Update("status", models.BackfillJobCancelled)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	job, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 && job.Status != models.BackfillJobCancelled {
+		return nil, ErrBackfillJobFinished
+	}
+	return job, nil
+}
+
+// Resume restarts a failed or cancelled job after its last committed batch
+func (s *BackfillJobService) Resume(id uuid.UUID) (*models.BackfillJob, error) {
+	result := s.db.Model(&models.BackfillJob{}).
+		Where("id = ? AND status IN ?", id, []string{models.BackfillJobFailed, models.BackfillJobCancelled}).
+		Updates(map[string]interface{}{"status": models.BackfillJobPending, "error": "", "completed_at": nil})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	job, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBackfillJobNotResumable
+	}
+	s.launch(*job)
+	return job, nil
+}
+
+// launch runs a copy of a job in the background until it finishes, is
+// cancelled or the service stops
+func (s *BackfillJobService) launch(job models.BackfillJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.cancels[job.ID] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.cancels, job.ID)
+			s.mu.Unlock()
+			cancel()
+		}()
+		s.run(ctx, &job)
+	}()
+}
+
+// run processes a job's batches in order, checkpointing after each
+func (s *BackfillJobService) run(ctx context.Context, job *models.BackfillJob) {
+	now := time.Now()
+	job.Status = models.BackfillJobRunning
+	if job.StartedAt == nil {
+		job.StartedAt = &now
+	}
+	job.RunStartedAt = &now
+	job.RunStartedFrom = job.Processed
+	if err := s.db.Save(job).Error; err != nil {
+		log.Printf("Failed to start backfill %s: %v", job.ID, err)
+		return
+	}
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			s.finish(job, models.BackfillJobCancelled, nil)
+			return
+		default:
+		}
+
+		var done bool
+		var err error
+		switch job.Kind {
+		case models.BackfillReenrich:
+			done, err = s.reenrichBatch(job)
+		case models.BackfillReingest:
+			done, err = s.reingestBatch(job)
+		default:
+			err = fmt.Errorf("unknown backfill kind %q", job.Kind)
+		}
+		if err != nil {
+			s.finish(job, models.BackfillJobFailed, err)
+			return
+		}
+		if done {
+			s.finish(job, models.BackfillJobCompleted, nil)
+			return
+		}
+	}
+}
+
+// finish records a job's outcome
+func (s *BackfillJobService) finish(job *models.BackfillJob, status string, err error) {
+	now := time.Now()
+	job.Status = status
+	job.CompletedAt = &now
+	if err != nil {
+		job.Error = err.Error()
+		log.Printf("Backfill %s failed after %d of %d rows: %v", job.ID, job.Processed, job.Total, err)
+	}
+	if err := s.db.Save(job).Error; err != nil {
+		log.Printf("Failed to record outcome of backfill %s: %v", job.ID, err)
+	}
+}
+
+// reingestBatch derives the next batch of findings' priorities again, for
+// their hosts' current asset criticality, and rescores the hosts whose
+// findings changed. It reports whether no findings were left.
+func (s *BackfillJobService) reingestBatch(job *models.BackfillJob) (bool, error) {
+	var findings []models.FindingState
+	if err := s.db.Where("id > ?", job.Cursor).Order("id").Limit(job.BatchSize).Find(&findings).Error; err != nil {
+		return false, fmt.Errorf("failed to load findings: %w", err)
+	}
+	if len(findings) == 0 {
+		return true, nil
+	}
+
+	criticalities := s.criticalities(findings)
+	return false, s.commitBatch(job, findings, findings[len(findings)-1].ID, len(findings), func(state *models.FindingState) bool {
+		derived := DerivePriority(state.Severity, state.EPSS, state.KnownExploited, criticalities[state.AgentID])
+		if derived == state.DerivedPriority && state.Priority == effectivePriority(state) {
+			return false
+		}
+		state.DerivedPriority = derived
+		state.Priority = effectivePriority(state)
+		return true
+	})
+}
+
+// reenrichBatch looks the next batch of reported packages up again and
+// refreshes the severity, exploitability and priority of the findings tracked
+// for their CVEs, reopening those suppressed until a fix that now has one. It
+// reports whether no packages were left.
+func (s *BackfillJobService) reenrichBatch(job *models.BackfillJob) (bool, error) {
+	var software []models.Software
+	if err := s.db.Where("id > ?", job.Cursor).Order("id").Limit(job.BatchSize).Find(&software).Error; err != nil {
+		return false, fmt.Errorf("failed to load software: %w", err)
+	}
+	if len(software) == 0 {
+		return true, nil
+	}
+
+	enriched, err := s.lookup(software, job.Concurrency)
+	if err != nil {
+		return false, err
+	}
+
+	// The tracked findings of the batch's packages, keyed by agent, package and CVE
+	agentIDs := make([]uuid.UUID, 0, len(software))
+	names := make([]string, 0, len(software))
+	for _, sw := range software {
+		agentIDs = append(agentIDs, sw.AgentID)
+		names = append(names, sw.Name)
+	}
+	var findings []models.FindingState
+	err = s.db.Where("agent_id IN ? AND package_name IN ? AND cve_id <> ''", agentIDs, names).Find(&findings).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to load findings: %w", err)
+	}
+	current := make(map[string]*models.Vulnerability)
+	for _, sw := range software {
+		vulns := enriched[softwareKey(sw.Name, sw.Version)]
+		for i := range vulns {
+			current[reenrichKey(sw.AgentID, sw.Name, vulns[i].CVEID)] = &vulns[i]
+		}
+	}
+
+	now := time.Now()
+	var reopened []models.FindingState
+	criticalities := s.criticalities(findings)
+	err = s.commitBatch(job, findings, software[len(software)-1].ID, len(software), func(state *models.FindingState) bool {
+		vuln, ok := current[reenrichKey(state.AgentID, state.PackageName, state.CVEID)]
+		if !ok {
+			return false
+		}
+		changed := false
+		if reopenFixed(state, vuln, now) {
+			reopened = append(reopened, *state)
+			changed = true
+		}
+
+		severity := strings.ToLower(string(vuln.Severity))
+		epss := state.EPSS
+		if score := findingEPSS(vuln); score > 0 {
+			epss = score
+		}
+		exploited := state.KnownExploited || knownExploited(vuln)
+		derived := DerivePriority(severity, epss, exploited, criticalities[state.AgentID])
+		if severity == state.Severity && epss == state.EPSS && exploited == state.KnownExploited && derived == state.DerivedPriority {
+			return changed
+		}
+		state.Severity = severity
+		state.EPSS = epss
+		state.KnownExploited = exploited
+		state.DerivedPriority = derived
+		state.Priority = effectivePriority(state)
+		return true
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for i := range reopened {
+		log.Printf("Backfill %s reopened finding %s: %s", job.ID, reopened[i].ID, reopened[i].ReopenNote)
+		if reopened[i].SuppressionCallbackURL != "" {
+			s.notifyReopened(&reopened[i])
+		}
+	}
+	return false, nil
+}
+
+// commitBatch applies update to a batch's findings and advances the job past
+// the batch in one transaction, then rescores the hosts whose findings changed
+func (s *BackfillJobService) commitBatch(job *models.BackfillJob, findings []models.FindingState, cursor uuid.UUID, processed int, update func(*models.FindingState) bool) error {
+	next := *job
+	var agents map[uuid.UUID]bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var changed int
+		var err error
+		changed, agents, err = s.findingStates.refresh(tx, findings, update)
+		if err != nil {
+			return err
+		}
+		next.Cursor = cursor
+		next.Processed += int64(processed)
+		next.Updated += int64(changed)
+		next.Batches++
+		return tx.Save(&next).Error
+	})
+	if err != nil {
+		// refresh already updated the cached states the rollback discarded
+		for agentID := range agents {
+			s.findingStates.Forget(agentID)
+		}
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+	*job = next
+
+	for agentID := range agents {
+		if _, err := s.hostRisk.Recompute(agentID); err != nil && !errors.Is(err, ErrAgentNotFound) {
+			log.Printf("Backfill %s failed to rescore agent %s: %v", job.ID, agentID, err)
+		}
+	}
+	return nil
+}
+
+// lookup enriches a batch's distinct packages, at most concurrency requests
+// at a time, and returns their vulnerabilities by software key
+func (s *BackfillJobService) lookup(software []models.Software, concurrency int) (map[string][]models.Vulnerability, error) {
+	var packages []models.Dependency
+	seen := make(map[string]bool, len(software))
+	for _, sw := range software {
+		if key := softwareKey(sw.Name, sw.Version); !seen[key] {
+			seen[key] = true
+			packages = append(packages, models.Dependency{Name: sw.Name, Version: sw.Version, Type: sw.Type})
+		}
+	}
+
+	// One request per chunk, as the enrichment service batches no further
+	chunk := s.enrichment.batchSize
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	enriched := make(map[string][]models.Vulnerability, len(packages))
+	for start := 0; start < len(packages); start += chunk {
+		batch := packages[start:min(start+chunk, len(packages))]
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			vulns, err := s.enrichment.EnrichDependencies(batch)
+			if err == nil {
+				s.enrichment.ApplyEPSS(context.Background(), vulns)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("enrichment failed: %w", err)
+				}
+				return
+			}
+			for _, vuln := range vulns {
+				key := softwareKey(vuln.PackageName, vuln.PackageVersion)
+				enriched[key] = append(enriched[key], vuln)
+			}
+		}()
+	}
+	wg.Wait()
+	return enriched, firstErr
+}
+
+// criticalities returns the asset criticality of each finding's host
+func (s *BackfillJobService) criticalities(findings []models.FindingState) map[uuid.UUID]string {
+	criticalities := make(map[uuid.UUID]string)
+	for _, finding := range findings {
+		if _, ok := criticalities[finding.AgentID]; ok {
+			continue
+		}
+		criticalities[finding.AgentID] = models.AssetCriticalityMedium
+		if agent, exists := s.agentService.GetAgent(finding.AgentID); exists {
+			criticalities[finding.AgentID] = assetCriticality(agent)
+		}
+	}
+	return criticalities
+}
+
+// notifyReopened tells the owner of a finding's lifted suppression that it
+// reopened. Failures are logged; the finding stays reopened either way.
+func (s *BackfillJobService) notifyReopened(state *models.FindingState) {
+	notification := models.FindingReopenedNotification{
+		FindingID:   state.ID,
+		AgentID:     state.AgentID,
+		CVEID:       state.CVEID,
+		PackageName: state.PackageName,
+		Owner:       state.SuppressionOwner,
+		FixVersion:  state.FixVersion,
+		Note:        state.ReopenNote,
+		ReopenedAt:  *state.ReopenedAt,
+	}
+
+	event, err := s.events.New(events.FindingReopened, notification)
+	if err != nil {
+		log.Printf("Failed to build notification for reopened finding %s: %v", state.ID, err)
+		return
+	}
+	if s.validateEvents {
+		if err := s.events.Validate(event); err != nil {
+			log.Printf("Dropped notification for reopened finding %s that does not match its schema: %v", state.ID, err)
+			return
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode notification for reopened finding %s: %v", state.ID, err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, state.SuppressionCallbackURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to notify owner of reopened finding %s: %v", state.ID, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(events.TypeHeader, event.Type)
+	req.Header.Set(events.SchemaVersionHeader, event.SchemaVersion)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to notify owner of reopened finding %s: %v", state.ID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Callback for reopened finding %s returned status %d", state.ID, resp.StatusCode)
+	}
+}
+
+// reenrichKey identifies a CVE in a package on a host
+func reenrichKey(agentID uuid.UUID, packageName, cveID string) string {
+	return agentID.String() + "\x00" + packageName + "\x00" + strings.ToUpper(cveID)
+}
+
+// backfillProgress sets a job's progress and, while it runs, its estimated
+// completion from the rate of its current run
+func backfillProgress(job *models.BackfillJob, now time.Time) {
+	job.Progress = 1
+	if job.Total > 0 {
+		job.Progress = math.Min(float64(job.Processed)/float64(job.Total), 1)
+	}
+	job.ETA = nil
+	if job.Status != models.BackfillJobRunning || job.RunStartedAt == nil {
+		return
+	}
+	done := job.Processed - job.RunStartedFrom
+	elapsed := now.Sub(*job.RunStartedAt)
+	if done <= 0 || elapsed <= 0 {
+		return
+	}
+	remaining := max(job.Total-job.Processed, 0)
+	eta := now.Add(time.Duration(float64(remaining) / float64(done) * float64(elapsed)))
+	job.ETA = &eta
+}
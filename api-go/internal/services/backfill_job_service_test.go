@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillProgressEstimatesFromCurrentRun(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	resumed := now.Add(-10 * time.Minute)
+	job := &models.BackfillJob{
+		Status:         models.BackfillJobRunning,
+		Total:          1000,
+		Processed:      600,
+		RunStartedAt:   &resumed,
+		RunStartedFrom: 400, // Resumed after a restart; only 200 rows in this run
+	}
+
+	backfillProgress(job, now)
+	assert.Equal(t, 0.6, job.Progress)
+	require.NotNil(t, job.ETA)
+	assert.Equal(t, now.Add(20*time.Minute), *job.ETA, "400 rows left at 20 rows a minute")
+}
+
+func TestBackfillProgressWithoutEstimate(t *testing.T) {
+	justStarted := time.Now()
+	job := &models.BackfillJob{Status: models.BackfillJobRunning, Total: 10, RunStartedAt: &justStarted}
+	backfillProgress(job, justStarted)
+	assert.Zero(t, job.Progress)
+	assert.Nil(t, job.ETA, "no rate yet")
+
+	empty := &models.BackfillJob{Status: models.BackfillJobCompleted}
+	backfillProgress(empty, time.Now())
+	assert.Equal(t, 1.0, empty.Progress)
+	assert.Nil(t, empty.ETA)
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"zerotrace/api/internal/netutil"
+)
+
+// checkCallbackURL checks that a callback URL supplied by a caller is an
+// absolute http(s) URL that doesn't name a loopback, private or link-local
+// host. Hostnames are resolved when the callback is sent, by a client from
+// newCallbackClient.
+func checkCallbackURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || !netutil.IsPublicHost(parsed.Hostname()) {
+		return ErrInvalidCallbackURL
+	}
+	return nil
+}
+
+// newCallbackClient returns a client for sending callbacks, and other requests,
+// to URLs callers supplied. It refuses to connect to addresses that aren't public, checked
+// after DNS resolution and on every redirect, so a hostname can't be pointed
+// at the API's own network.
+func newCallbackClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !netutil.IsPublicIP(ip) {
+				return fmt.Errorf("address %s is not public", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would make the connection on our behalf, past the check above
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrCollectionRunNotFound is returned when a collection run does not exist or belongs to another organization
+var ErrCollectionRunNotFound = errors.New("collection run not found")
+
+// CollectionService runs agentless SSH/WinRM collection from an organization's
+// agents. Each run is queued as a collect command for one agent, which
+// collects from the run's hosts within the organization's concurrency, timeout
+// and auth failure bounds, and reports per-host results as it goes.
+type CollectionService struct {
+	db           *gorm.DB
+	agentService *AgentService
+	commands     *AgentCommandService
+	defaults     models.CollectionSettings
+}
+
+// NewCollectionService creates a new collection service
+func NewCollectionService(db *gorm.DB, agentService *AgentService, commands *AgentCommandService, cfg *config.Config) *CollectionService {
+	return &CollectionService{
+		db:           db,
+		agentService: agentService,
+		commands:     commands,
+		defaults: models.CollectionSettings{
+			MaxConcurrency:     cfg.CollectionMaxConcurrency,
+			HostTimeoutSeconds: int(cfg.CollectionHostTimeout / time.Second),
+			AuthFailureBudget:  cfg.CollectionAuthFailureBudget,
+		},
+	}
+}
+
+// Settings returns an organization's collection settings, the configured
+// defaults if it has none of its own
+func (s *CollectionService) Settings(organizationID uuid.UUID) (*models.CollectionSettings, error) {
+	var settings models.CollectionSettings
+	err := s.db.Where("organization_id = ?", organizationID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		settings = s.defaults
+		settings.OrganizationID = organizationID
+		return &settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateSettings replaces an organization's collection settings. Runs already
+// started keep the settings they started with.
+func (s *CollectionService) UpdateSettings(organizationID uuid.UUID, req *models.UpdateCollectionSettingsRequest) (*models.CollectionSettings, error) {
+	settings := &models.CollectionSettings{
+		OrganizationID:     organizationID,
+		MaxConcurrency:     req.MaxConcurrency,
+		HostTimeoutSeconds: req.HostTimeoutSeconds,
+		AuthFailureBudget:  req.AuthFailureBudget,
+		UpdatedAt:          time.Now(),
+	}
+	if err := s.db.Save(settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Start queues a collection run on one of the organization's agents. The
+// agent uses the credentials it is configured with; none are sent.
+func (s *CollectionService) Start(organizationID uuid.UUID, req *models.StartCollectionRequest) (*models.CollectionRun, error) {
+	agent, exists := s.agentService.GetAgent(req.AgentID)
+	if !exists || agent.OrganizationID != organizationID {
+		return nil, ErrAgentNotFound
+	}
+
+	targets := uniqueTargets(req.Targets)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets to collect from")
+	}
+
+	settings, err := s.Settings(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	run := &models.CollectionRun{
+		ID:                 uuid.New(),
+		OrganizationID:     organizationID,
+		AgentID:            agent.ID,
+		Protocol:           req.Protocol,
+		Targets:            targets,
+		RequestedBy:        req.RequestedBy,
+		MaxConcurrency:     settings.MaxConcurrency,
+		HostTimeoutSeconds: settings.HostTimeoutSeconds,
+		AuthFailureBudget:  settings.AuthFailureBudget,
+		CreatedAt:          now,
+	}
+	cmd := s.commands.newCommand(agent.ID, organizationID, models.AgentCommandCollect, map[string]interface{}{
+		"run_id":               run.ID,
+		"protocol":             run.Protocol,
+		"targets":              run.Targets,
+		"max_concurrency":      run.MaxConcurrency,
+		"host_timeout_seconds": run.HostTimeoutSeconds,
+		"auth_failure_budget":  run.AuthFailureBudget,
+	}, now)
+	run.CommandID = cmd.ID
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&cmd).Error; err != nil {
+			return err
+		}
+		return tx.Create(run).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue collection: %w", err)
+	}
+
+	collectionState(run, &cmd)
+	return run, nil
+}
+
+// Get returns a collection run with its status, progress and per-host results
+func (s *CollectionService) Get(organizationID, runID uuid.UUID) (*models.CollectionRun, error) {
+	var run models.CollectionRun
+	if err := s.db.Where("id = ? AND organization_id = ?", runID, organizationID).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionRunNotFound
+		}
+		return nil, err
+	}
+
+	cmd, err := s.commands.GetCommand(run.CommandID)
+	if err != nil {
+		return nil, err
+	}
+	collectionState(&run, cmd)
+	return &run, nil
+}
+
+// collectionState derives a run's status, progress and host results from its
+// collect command and what the agent last reported on it
+func collectionState(run *models.CollectionRun, cmd *models.AgentCommand) {
+	var report models.CollectionReport
+	if cmd.Result != nil {
+		// Reported by the agent as JSON; a malformed report leaves no host results
+		if data, err := json.Marshal(cmd.Result); err == nil {
+			_ = json.Unmarshal(data, &report)
+		}
+	}
+
+	switch cmd.Status {
+	case models.AgentCommandPending, models.AgentCommandDelivered:
+		run.Status = models.CollectionRunPending
+	case models.AgentCommandAcked:
+		run.Status = models.CollectionRunRunning
+	case models.AgentCommandCompleted:
+		run.Status = models.CollectionRunCompleted
+		if report.Paused {
+			run.Status = models.CollectionRunPaused
+		}
+	case models.AgentCommandFailed:
+		run.Status = models.CollectionRunFailed
+		run.Error = cmd.Error
+	}
+	run.CompletedAt = cmd.CompletedAt
+	run.Hosts = report.Hosts
+
+	progress := models.CollectionRunProgress{Total: len(run.Targets), AuthFailures: report.AuthFailures}
+	for _, host := range report.Hosts {
+		switch host.Status {
+		case models.CollectionHostSucceeded:
+			progress.Succeeded++
+		case models.CollectionHostFailed:
+			progress.Failed++
+		case models.CollectionHostAuthFailed:
+			progress.AuthFailed++
+		case models.CollectionHostSkipped:
+			progress.Skipped++
+		}
+	}
+	if progress.Total > 0 {
+		finished := progress.Succeeded + progress.Failed + progress.AuthFailed + progress.Skipped
+		progress.Percent = float64(finished) / float64(progress.Total) * 100
+	}
+	progress.Done = cmd.Status == models.AgentCommandCompleted || cmd.Status == models.AgentCommandFailed
+	run.Progress = progress
+}
+
+// uniqueTargets trims the requested hosts and drops blanks and duplicates, keeping their order
+func uniqueTargets(targets []string) []string {
+	seen := make(map[string]bool, len(targets))
+	unique := make([]string, 0, len(targets))
+	for _, target := range targets {
+		target = strings.TrimSpace(target)
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		unique = append(unique, target)
+	}
+	return unique
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionStateFromProgressReport(t *testing.T) {
+	run := &models.CollectionRun{Targets: []string{"a", "b", "c", "d"}}
+	cmd := &models.AgentCommand{
+		Status: models.AgentCommandAcked,
+		Result: map[string]interface{}{
+			"hosts": []interface{}{
+				map[string]interface{}{"host": "a", "status": "succeeded", "duration_ms": 120},
+				map[string]interface{}{"host": "b", "status": "auth_failed", "error": "permission denied"},
+			},
+			"auth_failures": 1,
+		},
+	}
+
+	collectionState(run, cmd)
+	assert.Equal(t, models.CollectionRunRunning, run.Status)
+	assert.Len(t, run.Hosts, 2)
+	assert.Equal(t, "permission denied", run.Hosts[1].Error)
+	assert.Equal(t, models.CollectionRunProgress{Total: 4, Succeeded: 1, AuthFailed: 1, Percent: 50, AuthFailures: 1}, run.Progress)
+}
+
+func TestCollectionStatePausedByFailureBudget(t *testing.T) {
+	run := &models.CollectionRun{Targets: []string{"a", "b", "c"}}
+	cmd := &models.AgentCommand{
+		Status: models.AgentCommandCompleted,
+		Result: map[string]interface{}{
+			"hosts": []interface{}{
+				map[string]interface{}{"host": "a", "status": "auth_failed"},
+				map[string]interface{}{"host": "b", "status": "skipped"},
+				map[string]interface{}{"host": "c", "status": "skipped"},
+			},
+			"auth_failures": 1,
+			"paused":        true,
+		},
+	}
+
+	collectionState(run, cmd)
+	assert.Equal(t, models.CollectionRunPaused, run.Status)
+	assert.True(t, run.Progress.Done)
+	assert.Equal(t, 2, run.Progress.Skipped)
+	assert.Equal(t, 100.0, run.Progress.Percent)
+}
+
+func TestUniqueTargets(t *testing.T) {
+	assert.Equal(t, []string{"10.0.0.1", "host-b"}, uniqueTargets([]string{" 10.0.0.1", "host-b", "", "10.0.0.1 "}))
+}
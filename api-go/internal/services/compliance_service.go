@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"strings"
 	"time"
 
+	"zerotrace/api/internal/logging"
 	"zerotrace/api/internal/models"
 
 	"github.com/google/uuid"
@@ -27,7 +29,7 @@ type ComplianceReport struct {
 	ReportID         string                     `json:"report_id"`
 	OrganizationID   uuid.UUID                  `json:"organization_id"`
 	Framework        string                     `json:"framework"`
-	ReportType       string                     `json:"report_type"` // SOC2, ISO27001, PCI DSS, HIPAA
+	ReportType       string                     `json:"report_type"` // SOC2, ISO27001, PCI DSS, HIPAA, NIST-CSF
 	ReportPeriod     string                     `json:"report_period"`
 	OverallScore     float64                    `json:"overall_score"`
 	ComplianceLevel  string                     `json:"compliance_level"`
@@ -117,7 +119,9 @@ type ExecutiveSummary struct {
 }
 
 // GenerateComplianceReport generates a comprehensive compliance report
-func (s *ComplianceService) GenerateComplianceReport(organizationID uuid.UUID, framework string, reportType string, reportPeriod string) (*ComplianceReport, error) {
+func (s *ComplianceService) GenerateComplianceReport(ctx context.Context, organizationID uuid.UUID, framework string, reportType string, reportPeriod string) (*ComplianceReport, error) {
+	start := time.Now()
+
 	// Get organization profile
 	orgProfile, err := s.getOrganizationProfile(organizationID)
 	if err != nil {
@@ -179,6 +183,14 @@ func (s *ComplianceService) GenerateComplianceReport(organizationID uuid.UUID, f
 		ConfidenceScore:  confidenceScore,
 	}
 
+	logging.FromContext(ctx).Info("Compliance report generated",
+		"organization_id", organizationID,
+		"framework", framework,
+		"report_type", reportType,
+		"overall_score", overallScore,
+		"findings", len(findings),
+		"latency_ms", float64(time.Since(start).Microseconds())/1000,
+	)
 	return report, nil
 }
 
@@ -195,6 +207,8 @@ func (s *ComplianceService) generateFrameworkControls(framework string, vulnerab
 		controls = s.generatePCIDSSControls(vulnerabilities, scanHistory, orgProfile)
 	case "HIPAA":
 		controls = s.generateHIPAAControls(vulnerabilities, scanHistory, orgProfile)
+	case "NIST-CSF", "NIST CSF", "CSF":
+		controls = s.generateNISTCSFControls(vulnerabilities, scanHistory, orgProfile)
 	default:
 		controls = s.generateGenericControls(vulnerabilities, scanHistory, orgProfile)
 	}
@@ -214,7 +228,7 @@ func (s *ComplianceService) generateSOC2Controls(vulnerabilities []models.Vulner
 		Score:           s.calculateAccessControlScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countAccessControlEvidence(scanHistory),
-		LastTested:      time.Now().Add(-7 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
 		Description:     "Controls to protect against unauthorized access to systems and data",
 		RemediationPlan: s.generateAccessControlRemediation(vulnerabilities),
@@ -228,7 +242,7 @@ func (s *ComplianceService) generateSOC2Controls(vulnerabilities []models.Vulner
 		Score:           s.calculateCredentialManagementScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateCredentialManagementScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countCredentialEvidence(scanHistory),
-		LastTested:      time.Now().Add(-14 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateCredentialManagementScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for credential issuance and management",
 		RemediationPlan: s.generateCredentialRemediation(vulnerabilities),
@@ -242,7 +256,7 @@ func (s *ComplianceService) generateSOC2Controls(vulnerabilities []models.Vulner
 		Score:           s.calculatePasswordManagementScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculatePasswordManagementScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countPasswordEvidence(scanHistory),
-		LastTested:      time.Now().Add(-21 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculatePasswordManagementScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for password policy and management",
 		RemediationPlan: s.generatePasswordRemediation(vulnerabilities),
@@ -256,7 +270,7 @@ func (s *ComplianceService) generateSOC2Controls(vulnerabilities []models.Vulner
 		Score:           s.calculateSystemOperationsScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateSystemOperationsScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countSystemOperationsEvidence(scanHistory),
-		LastTested:      time.Now().Add(-10 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateSystemOperationsScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for system operations and monitoring",
 		RemediationPlan: s.generateSystemOperationsRemediation(vulnerabilities),
@@ -270,7 +284,7 @@ func (s *ComplianceService) generateSOC2Controls(vulnerabilities []models.Vulner
 		Score:           s.calculateIncidentResponseScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateIncidentResponseScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countIncidentResponseEvidence(scanHistory),
-		LastTested:      time.Now().Add(-5 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateIncidentResponseScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for incident response and management",
 		RemediationPlan: s.generateIncidentResponseRemediation(vulnerabilities),
@@ -291,7 +305,7 @@ func (s *ComplianceService) generateISO27001Controls(vulnerabilities []models.Vu
 		Score:           s.calculateAccessControlScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countAccessControlEvidence(scanHistory),
-		LastTested:      time.Now().Add(-7 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
 		Description:     "Controls to ensure access to information and information processing facilities",
 		RemediationPlan: s.generateAccessControlRemediation(vulnerabilities),
@@ -305,7 +319,7 @@ func (s *ComplianceService) generateISO27001Controls(vulnerabilities []models.Vu
 		Score:           s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countVulnerabilityEvidence(scanHistory),
-		LastTested:      time.Now().Add(-3 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for vulnerability management and patching",
 		RemediationPlan: s.generateVulnerabilityRemediation(vulnerabilities),
@@ -319,7 +333,7 @@ func (s *ComplianceService) generateISO27001Controls(vulnerabilities []models.Vu
 		Score:           s.calculateNetworkSecurityScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateNetworkSecurityScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countNetworkSecurityEvidence(scanHistory),
-		LastTested:      time.Now().Add(-14 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateNetworkSecurityScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for network security management",
 		RemediationPlan: s.generateNetworkSecurityRemediation(vulnerabilities),
@@ -340,7 +354,7 @@ func (s *ComplianceService) generatePCIDSSControls(vulnerabilities []models.Vuln
 		Score:           s.calculateFirewallScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateFirewallScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countFirewallEvidence(scanHistory),
-		LastTested:      time.Now().Add(-30 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateFirewallScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for firewall configuration and maintenance",
 		RemediationPlan: s.generateFirewallRemediation(vulnerabilities),
@@ -354,7 +368,7 @@ func (s *ComplianceService) generatePCIDSSControls(vulnerabilities []models.Vuln
 		Score:           s.calculateDefaultConfigurationScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateDefaultConfigurationScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countDefaultConfigurationEvidence(scanHistory),
-		LastTested:      time.Now().Add(-45 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateDefaultConfigurationScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for secure system configuration",
 		RemediationPlan: s.generateDefaultConfigurationRemediation(vulnerabilities),
@@ -368,7 +382,7 @@ func (s *ComplianceService) generatePCIDSSControls(vulnerabilities []models.Vuln
 		Score:           s.calculateSecureDevelopmentScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateSecureDevelopmentScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countSecureDevelopmentEvidence(scanHistory),
-		LastTested:      time.Now().Add(-60 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateSecureDevelopmentScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for secure system development",
 		RemediationPlan: s.generateSecureDevelopmentRemediation(vulnerabilities),
@@ -389,7 +403,7 @@ func (s *ComplianceService) generateHIPAAControls(vulnerabilities []models.Vulne
 		Score:           s.calculateSecurityManagementScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateSecurityManagementScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countSecurityManagementEvidence(scanHistory),
-		LastTested:      time.Now().Add(-90 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateSecurityManagementScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for security management processes",
 		RemediationPlan: s.generateSecurityManagementRemediation(vulnerabilities),
@@ -403,7 +417,7 @@ func (s *ComplianceService) generateHIPAAControls(vulnerabilities []models.Vulne
 		Score:           s.calculateAccessControlScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countAccessControlEvidence(scanHistory),
-		LastTested:      time.Now().Add(-30 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for access control and authentication",
 		RemediationPlan: s.generateAccessControlRemediation(vulnerabilities),
@@ -417,7 +431,7 @@ func (s *ComplianceService) generateHIPAAControls(vulnerabilities []models.Vulne
 		Score:           s.calculateAuditControlsScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateAuditControlsScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countAuditControlsEvidence(scanHistory),
-		LastTested:      time.Now().Add(-15 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateAuditControlsScore(vulnerabilities, scanHistory)),
 		Description:     "Controls for audit logging and monitoring",
 		RemediationPlan: s.generateAuditControlsRemediation(vulnerabilities),
@@ -426,6 +440,168 @@ func (s *ComplianceService) generateHIPAAControls(vulnerabilities []models.Vulne
 	return controls
 }
 
+// generateNISTCSFControls generates NIST Cybersecurity Framework 2.0 controls:
+// representative subcategories of each of its six functions
+func (s *ComplianceService) generateNISTCSFControls(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult, orgProfile *models.OrganizationProfile) map[string]ControlScore {
+	controls := make(map[string]ControlScore)
+
+	// GV.OV-03 - Cybersecurity Risk Management Performance Is Evaluated
+	controls["GV.OV-03"] = ControlScore{
+		ControlID:       "GV.OV-03",
+		ControlName:     "Cybersecurity Risk Management Performance Is Evaluated",
+		Category:        "Govern",
+		Score:           s.calculateSecurityManagementScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateSecurityManagementScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countSecurityManagementEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateSecurityManagementScore(vulnerabilities, scanHistory)),
+		Description:     "Oversight of cybersecurity risk management through regular assessment",
+		RemediationPlan: s.generateSecurityManagementRemediation(vulnerabilities),
+	}
+
+	// ID.RA-01 - Vulnerabilities in Assets Are Identified and Recorded
+	controls["ID.RA-01"] = ControlScore{
+		ControlID:       "ID.RA-01",
+		ControlName:     "Vulnerabilities in Assets Are Identified and Recorded",
+		Category:        "Identify",
+		Score:           s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countVulnerabilityEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory)),
+		Description:     "Vulnerabilities in assets are identified, validated and recorded",
+		RemediationPlan: s.generateVulnerabilityRemediation(vulnerabilities),
+	}
+
+	// PR.AA-01 - Identities and Credentials Are Managed
+	controls["PR.AA-01"] = ControlScore{
+		ControlID:       "PR.AA-01",
+		ControlName:     "Identities and Credentials Are Managed",
+		Category:        "Protect",
+		Score:           s.calculateCredentialManagementScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateCredentialManagementScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countCredentialEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateCredentialManagementScore(vulnerabilities, scanHistory)),
+		Description:     "Identities and credentials for users, services and hardware are managed",
+		RemediationPlan: s.generateCredentialRemediation(vulnerabilities),
+	}
+
+	// PR.AA-05 - Access Permissions Are Defined and Enforced
+	controls["PR.AA-05"] = ControlScore{
+		ControlID:       "PR.AA-05",
+		ControlName:     "Access Permissions Are Defined and Enforced",
+		Category:        "Protect",
+		Score:           s.calculateAccessControlScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countAccessControlEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
+		Description:     "Access permissions and authorizations are defined, managed and enforced",
+		RemediationPlan: s.generateAccessControlRemediation(vulnerabilities),
+	}
+
+	// PR.PS-01 - Configuration Management Practices Are Applied
+	controls["PR.PS-01"] = ControlScore{
+		ControlID:       "PR.PS-01",
+		ControlName:     "Configuration Management Practices Are Applied",
+		Category:        "Protect",
+		Score:           s.calculateDefaultConfigurationScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateDefaultConfigurationScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countDefaultConfigurationEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateDefaultConfigurationScore(vulnerabilities, scanHistory)),
+		Description:     "Configuration management practices are established and applied",
+		RemediationPlan: s.generateDefaultConfigurationRemediation(vulnerabilities),
+	}
+
+	// PR.PS-02 - Software Is Maintained Commensurate with Risk
+	controls["PR.PS-02"] = ControlScore{
+		ControlID:       "PR.PS-02",
+		ControlName:     "Software Is Maintained Commensurate with Risk",
+		Category:        "Protect",
+		Score:           s.calculateSystemOperationsScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateSystemOperationsScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countSystemOperationsEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateSystemOperationsScore(vulnerabilities, scanHistory)),
+		Description:     "Software is maintained, replaced and removed commensurate with risk",
+		RemediationPlan: s.generateSystemOperationsRemediation(vulnerabilities),
+	}
+
+	// PR.PS-06 - Secure Software Development Practices Are Integrated
+	controls["PR.PS-06"] = ControlScore{
+		ControlID:       "PR.PS-06",
+		ControlName:     "Secure Software Development Practices Are Integrated",
+		Category:        "Protect",
+		Score:           s.calculateSecureDevelopmentScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateSecureDevelopmentScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countSecureDevelopmentEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateSecureDevelopmentScore(vulnerabilities, scanHistory)),
+		Description:     "Secure software development practices are integrated and monitored",
+		RemediationPlan: s.generateSecureDevelopmentRemediation(vulnerabilities),
+	}
+
+	// PR.IR-01 - Networks Are Protected from Unauthorized Access
+	controls["PR.IR-01"] = ControlScore{
+		ControlID:       "PR.IR-01",
+		ControlName:     "Networks Are Protected from Unauthorized Access",
+		Category:        "Protect",
+		Score:           s.calculateNetworkSecurityScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateNetworkSecurityScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countNetworkSecurityEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateNetworkSecurityScore(vulnerabilities, scanHistory)),
+		Description:     "Networks and environments are protected from unauthorized logical access and usage",
+		RemediationPlan: s.generateNetworkSecurityRemediation(vulnerabilities),
+	}
+
+	// DE.CM-09 - Computing Environments Are Monitored
+	controls["DE.CM-09"] = ControlScore{
+		ControlID:       "DE.CM-09",
+		ControlName:     "Computing Environments Are Monitored",
+		Category:        "Detect",
+		Score:           s.calculateAuditControlsScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateAuditControlsScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countAuditControlsEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateAuditControlsScore(vulnerabilities, scanHistory)),
+		Description:     "Hardware, software and runtime environments are monitored for adverse events",
+		RemediationPlan: s.generateAuditControlsRemediation(vulnerabilities),
+	}
+
+	// RS.MA-01 - Incident Response Plan Is Executed
+	controls["RS.MA-01"] = ControlScore{
+		ControlID:       "RS.MA-01",
+		ControlName:     "Incident Response Plan Is Executed",
+		Category:        "Respond",
+		Score:           s.calculateIncidentResponseScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateIncidentResponseScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countIncidentResponseEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateIncidentResponseScore(vulnerabilities, scanHistory)),
+		Description:     "Incidents are responded to once declared",
+		RemediationPlan: s.generateIncidentResponseRemediation(vulnerabilities),
+	}
+
+	// RC.RP-01 - Recovery Plan Is Executed
+	controls["RC.RP-01"] = ControlScore{
+		ControlID:       "RC.RP-01",
+		ControlName:     "Recovery Plan Is Executed",
+		Category:        "Recover",
+		Score:           s.calculateRecoveryScore(vulnerabilities, scanHistory),
+		Status:          s.determineControlStatus(s.calculateRecoveryScore(vulnerabilities, scanHistory)),
+		EvidenceCount:   s.countRecoveryEvidence(scanHistory),
+		LastTested:      s.lastTested(scanHistory),
+		RiskLevel:       s.determineRiskLevel(s.calculateRecoveryScore(vulnerabilities, scanHistory)),
+		Description:     "Affected assets are restored; scored on critical and high findings left open past the recovery window",
+		RemediationPlan: s.generateRecoveryRemediation(vulnerabilities),
+	}
+
+	return controls
+}
+
 // generateGenericControls generates generic compliance controls
 func (s *ComplianceService) generateGenericControls(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult, orgProfile *models.OrganizationProfile) map[string]ControlScore {
 	controls := make(map[string]ControlScore)
@@ -438,7 +614,7 @@ func (s *ComplianceService) generateGenericControls(vulnerabilities []models.Vul
 		Score:           s.calculateAccessControlScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countAccessControlEvidence(scanHistory),
-		LastTested:      time.Now().Add(-7 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateAccessControlScore(vulnerabilities, scanHistory)),
 		Description:     "Generic access control requirements",
 		RemediationPlan: s.generateAccessControlRemediation(vulnerabilities),
@@ -452,7 +628,7 @@ func (s *ComplianceService) generateGenericControls(vulnerabilities []models.Vul
 		Score:           s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory),
 		Status:          s.determineControlStatus(s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory)),
 		EvidenceCount:   s.countVulnerabilityEvidence(scanHistory),
-		LastTested:      time.Now().Add(-3 * 24 * time.Hour),
+		LastTested:      s.lastTested(scanHistory),
 		RiskLevel:       s.determineRiskLevel(s.calculateVulnerabilityManagementScore(vulnerabilities, scanHistory)),
 		Description:     "Generic vulnerability management requirements",
 		RemediationPlan: s.generateVulnerabilityRemediation(vulnerabilities),
@@ -472,79 +648,150 @@ func (s *ComplianceService) getOrganizationProfile(organizationID uuid.UUID) (*m
 	return &profile, nil
 }
 
+// closedVulnerabilityStatuses are statuses of findings that no longer count against a control
+var closedVulnerabilityStatuses = []string{models.FindingStatusResolved, "patched", "suppressed"}
+
+// organizationScans selects the IDs of an organization's scans: those recorded
+// against it, and those its agents ran
+func (s *ComplianceService) organizationScans(organizationID uuid.UUID) *gorm.DB {
+	agents := s.db.Model(&models.Agent{}).Select("id").Where("organization_id = ?", organizationID)
+	return s.db.Model(&models.Scan{}).Select("id").Where("organization_id = ? OR agent_id IN (?)", organizationID, agents)
+}
+
+// getVulnerabilitiesForOrganization loads an organization's open findings:
+// those recorded against it, and those found by its agents' scans
 func (s *ComplianceService) getVulnerabilitiesForOrganization(organizationID uuid.UUID) ([]models.Vulnerability, error) {
-	// Database integration required - this should query vulnerabilities from database
-	// Returning empty slice until database integration is implemented
-	return []models.Vulnerability{}, nil
+	var vulnerabilities []models.Vulnerability
+	err := s.db.Where("organization_id = ? OR scan_id IN (?)", organizationID, s.organizationScans(organizationID)).
+		Where("status IS NULL OR status NOT IN ?", closedVulnerabilityStatuses).
+		Find(&vulnerabilities).Error
+	return vulnerabilities, err
 }
 
+// getScanHistory loads an organization's completed scans, oldest first
 func (s *ComplianceService) getScanHistory(organizationID uuid.UUID) ([]models.ScanResult, error) {
-	// Database integration required - this should query scan history from database
-	// Returning empty slice until database integration is implemented
-	return []models.ScanResult{}, nil
+	var scans []models.Scan
+	err := s.db.Where("id IN (?) AND status = ?", s.organizationScans(organizationID), models.ScanStatusCompleted).
+		Order("created_at ASC").
+		Find(&scans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]models.ScanResult, len(scans))
+	for i, scan := range scans {
+		history[i] = models.ScanResult{
+			ID:        scan.ID,
+			ScanType:  scan.ScanType,
+			Status:    string(scan.Status),
+			Results:   scan.Results,
+			Metadata:  scan.Metadata,
+			CreatedAt: scan.CreatedAt,
+			UpdatedAt: scan.UpdatedAt,
+		}
+		if scan.AgentID != nil {
+			history[i].AgentID = *scan.AgentID
+		}
+	}
+	return history, nil
 }
 
-// Control scoring methods
-func (s *ComplianceService) calculateAccessControlScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	// Calculate based on actual vulnerability data
-	if len(vulnerabilities) == 0 {
-		return 1.0
+// lastTested is when a control was last tested: the organization's latest
+// completed scan, or the zero time if it has none
+func (s *ComplianceService) lastTested(scanHistory []models.ScanResult) time.Time {
+	if len(scanHistory) == 0 {
+		return time.Time{}
 	}
-	
-	baseScore := 1.0
-	highSeverityVulns := 0
+	return scanHistory[len(scanHistory)-1].CreatedAt
+}
+
+// controlSeverityWeights weigh findings by severity when scoring a control.
+// Informational findings carry no weight.
+var controlSeverityWeights = map[models.SeverityLevel]float64{
+	models.SeverityCritical: 10,
+	models.SeverityHigh:     5,
+	models.SeverityMedium:   2,
+	models.SeverityLow:      0.5,
+}
+
+// severityDistribution counts findings by severity. Agents report severities
+// in lower case, so they are counted upper-cased.
+func severityDistribution(vulnerabilities []models.Vulnerability) map[models.SeverityLevel]int {
+	distribution := make(map[models.SeverityLevel]int)
+	for _, vuln := range vulnerabilities {
+		distribution[models.SeverityLevel(strings.ToUpper(string(vuln.Severity)))]++
+	}
+	return distribution
+}
+
+// severityScore scores a control from 1 (no findings) towards 0 as the
+// severity-weighted count of its findings grows. scale is the weighted count
+// that halves the score: 10 is a single critical finding.
+func severityScore(vulnerabilities []models.Vulnerability, scale float64) float64 {
+	weighted := 0.0
+	for severity, count := range severityDistribution(vulnerabilities) {
+		weighted += controlSeverityWeights[severity] * float64(count)
+	}
+	return 1 / (1 + weighted/scale)
+}
+
+// vulnerabilitiesOfType returns the findings of the given types
+func vulnerabilitiesOfType(vulnerabilities []models.Vulnerability, types ...string) []models.Vulnerability {
+	var matched []models.Vulnerability
 	for _, vuln := range vulnerabilities {
-		if vuln.Severity == models.SeverityHigh || vuln.Severity == models.SeverityCritical {
-			highSeverityVulns++
+		for _, t := range types {
+			if strings.EqualFold(vuln.Type, t) {
+				matched = append(matched, vuln)
+				break
+			}
 		}
 	}
+	return matched
+}
 
-	if highSeverityVulns > 0 {
-		baseScore -= float64(highSeverityVulns) * 0.15
+// vulnerabilitiesMentioning returns the findings whose title or description
+// mentions one of the keywords
+func vulnerabilitiesMentioning(vulnerabilities []models.Vulnerability, keywords ...string) []models.Vulnerability {
+	var matched []models.Vulnerability
+	for _, vuln := range vulnerabilities {
+		text := strings.ToLower(vuln.Title + " " + vuln.Description)
+		for _, keyword := range keywords {
+			if strings.Contains(text, keyword) {
+				matched = append(matched, vuln)
+				break
+			}
+		}
 	}
-	
-	// Adjust based on total vulnerabilities
-	baseScore -= float64(len(vulnerabilities)) * 0.05
+	return matched
+}
 
-	return math.Max(baseScore, 0.0)
+// Finding types each control is scored on, as the agent's scanners report them
+var (
+	accessControlTypes     = []string{"auth", "account_security", "config", "configuration"}
+	systemOperationsTypes  = []string{"cve", "os_patch", "operating_system"}
+	networkSecurityTypes   = []string{"network", "protocol", "wireless", "device"}
+	defaultConfigTypes     = []string{"config", "configuration", "postgresql"}
+	secureDevelopmentTypes = []string{"dependency", "code", "iac", "container", "model"}
+	credentialKeywords     = []string{"credential", "secret", "token", "api key", "private key"}
+	passwordKeywords       = []string{"password", "passwd"}
+	firewallKeywords       = []string{"firewall", "port", "exposed"}
+)
+
+// Control scoring methods
+func (s *ComplianceService) calculateAccessControlScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
+	return severityScore(vulnerabilitiesOfType(vulnerabilities, accessControlTypes...), 20)
 }
 
 func (s *ComplianceService) calculateCredentialManagementScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	// Calculate based on actual vulnerability data
-	if len(vulnerabilities) == 0 {
-		return 1.0
-	}
-	baseScore := 1.0 - (float64(len(vulnerabilities)) * 0.1)
-	if baseScore < 0 {
-		baseScore = 0
-	}
-	return baseScore
+	return severityScore(vulnerabilitiesMentioning(vulnerabilities, credentialKeywords...), 10)
 }
 
 func (s *ComplianceService) calculatePasswordManagementScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	// Calculate based on actual vulnerability data
-	// Database integration required for full implementation
-	if len(vulnerabilities) == 0 {
-		return 1.0 // No vulnerabilities = perfect score
-	}
-	// Base score decreases with number of vulnerabilities
-	baseScore := 1.0 - (float64(len(vulnerabilities)) * 0.1)
-	if baseScore < 0 {
-		baseScore = 0
-	}
-	return baseScore
+	return severityScore(vulnerabilitiesMentioning(vulnerabilities, passwordKeywords...), 10)
 }
 
 func (s *ComplianceService) calculateSystemOperationsScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	// Calculate based on actual vulnerability data
-	if len(vulnerabilities) == 0 {
-		return 1.0
-	}
-	baseScore := 1.0 - (float64(len(vulnerabilities)) * 0.1)
-	if baseScore < 0 {
-		baseScore = 0
-	}
-	return baseScore
+	return severityScore(vulnerabilitiesOfType(vulnerabilities, systemOperationsTypes...), 40)
 }
 
 func (s *ComplianceService) calculateIncidentResponseScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
@@ -561,64 +808,43 @@ func (s *ComplianceService) calculateIncidentResponseScore(vulnerabilities []mod
 }
 
 func (s *ComplianceService) calculateVulnerabilityManagementScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	// Calculate based on vulnerability count and scan frequency
-	if len(vulnerabilities) == 0 {
-		return 1.0
-	}
+	// Findings can't be managed without scanning for them
 	if len(scanHistory) == 0 {
-		return 0.5
-	}
-	// Score improves with more scans relative to vulnerabilities
-	ratio := float64(len(scanHistory)) / float64(len(vulnerabilities))
-	baseScore := ratio * 0.5
-	if baseScore > 1.0 {
-		baseScore = 1.0
+		return 0.5 * severityScore(vulnerabilities, 40)
 	}
-	return baseScore
+	return severityScore(vulnerabilities, 40)
 }
 
 func (s *ComplianceService) calculateNetworkSecurityScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	if len(vulnerabilities) == 0 {
-		return 1.0
-	}
-	baseScore := 1.0 - (float64(len(vulnerabilities)) * 0.1)
-	if baseScore < 0 {
-		baseScore = 0
-	}
-	return baseScore
+	return severityScore(vulnerabilitiesOfType(vulnerabilities, networkSecurityTypes...), 20)
 }
 
 func (s *ComplianceService) calculateFirewallScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	if len(vulnerabilities) == 0 {
-		return 1.0
-	}
-	baseScore := 1.0 - (float64(len(vulnerabilities)) * 0.08)
-	if baseScore < 0 {
-		baseScore = 0
-	}
-	return baseScore
+	network := vulnerabilitiesOfType(vulnerabilities, networkSecurityTypes...)
+	return severityScore(vulnerabilitiesMentioning(network, firewallKeywords...), 10)
 }
 
 func (s *ComplianceService) calculateDefaultConfigurationScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	if len(vulnerabilities) == 0 {
-		return 1.0
-	}
-	baseScore := 1.0 - (float64(len(vulnerabilities)) * 0.12)
-	if baseScore < 0 {
-		baseScore = 0
-	}
-	return baseScore
+	return severityScore(vulnerabilitiesOfType(vulnerabilities, defaultConfigTypes...), 20)
 }
 
 func (s *ComplianceService) calculateSecureDevelopmentScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
-	if len(vulnerabilities) == 0 {
-		return 1.0
-	}
-	baseScore := 1.0 - (float64(len(vulnerabilities)) * 0.1)
-	if baseScore < 0 {
-		baseScore = 0
+	return severityScore(vulnerabilitiesOfType(vulnerabilities, secureDevelopmentTypes...), 20)
+}
+
+// recoveryWindow is how long a critical or high finding may stay open before
+// it counts against recovery
+const recoveryWindow = 30 * 24 * time.Hour
+
+func (s *ComplianceService) calculateRecoveryScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
+	var overdue []models.Vulnerability
+	for _, vuln := range vulnerabilities {
+		severity := models.SeverityLevel(strings.ToUpper(string(vuln.Severity)))
+		if (severity == models.SeverityCritical || severity == models.SeverityHigh) && time.Since(vuln.CreatedAt) > recoveryWindow {
+			overdue = append(overdue, vuln)
+		}
 	}
-	return baseScore
+	return severityScore(overdue, 10)
 }
 
 func (s *ComplianceService) calculateSecurityManagementScore(vulnerabilities []models.Vulnerability, scanHistory []models.ScanResult) float64 {
@@ -690,6 +916,10 @@ func (s *ComplianceService) countSecurityManagementEvidence(scanHistory []models
 	return len(scanHistory)
 }
 
+func (s *ComplianceService) countRecoveryEvidence(scanHistory []models.ScanResult) int {
+	return len(scanHistory)
+}
+
 func (s *ComplianceService) countAuditControlsEvidence(scanHistory []models.ScanResult) int {
 	return len(scanHistory)
 }
@@ -760,6 +990,10 @@ func (s *ComplianceService) generateSecurityManagementRemediation(vulnerabilitie
 	return "Establish security policies, create security awareness training, and implement security governance"
 }
 
+func (s *ComplianceService) generateRecoveryRemediation(vulnerabilities []models.Vulnerability) string {
+	return "Remediate critical and high findings within 30 days, and test restoration of affected systems"
+}
+
 func (s *ComplianceService) generateAuditControlsRemediation(vulnerabilities []models.Vulnerability) string {
 	return "Implement audit logging, establish log monitoring, and create audit trail procedures"
 }
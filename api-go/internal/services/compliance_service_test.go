@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverityScore(t *testing.T) {
+	assert.Equal(t, 1.0, severityScore(nil, 10))
+
+	// Agents report lower-case severities
+	critical := []models.Vulnerability{{Severity: "critical"}}
+	assert.InDelta(t, 0.5, severityScore(critical, 10), 0.001)
+	assert.Equal(t, map[models.SeverityLevel]int{models.SeverityCritical: 1, models.SeverityLow: 2},
+		severityDistribution(append(critical, models.Vulnerability{Severity: "low"}, models.Vulnerability{Severity: models.SeverityLow})))
+
+	// Informational findings don't lower the score, and many findings never take it to zero
+	assert.Equal(t, 1.0, severityScore([]models.Vulnerability{{Severity: models.SeverityInfo}}, 10))
+	many := make([]models.Vulnerability, 500)
+	for i := range many {
+		many[i].Severity = models.SeverityHigh
+	}
+	assert.Greater(t, severityScore(many, 40), 0.0)
+	assert.Less(t, severityScore(many, 40), severityScore(many[:10], 40))
+}
+
+func TestComplianceControlsScoreRelevantFindings(t *testing.T) {
+	s := &ComplianceService{}
+	vulnerabilities := []models.Vulnerability{
+		{Type: "network", Severity: "high", Title: "Exposed Database Port"},
+		{Type: "config", Severity: "critical", Title: "Password authentication enabled for root"},
+		{Type: "cve", Severity: "medium", Title: "CVE-2024-0727 in openssl"},
+	}
+	history := []models.ScanResult{
+		{CreatedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{CreatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// Only the config finding is about passwords, and nothing mentions credentials
+	assert.InDelta(t, 0.5, s.calculatePasswordManagementScore(vulnerabilities, history), 0.001)
+	assert.Equal(t, 1.0, s.calculateCredentialManagementScore(vulnerabilities, history))
+	// The exposed port counts against network security and the firewall
+	assert.InDelta(t, 0.8, s.calculateNetworkSecurityScore(vulnerabilities, history), 0.001)
+	assert.InDelta(t, 2.0/3, s.calculateFirewallScore(vulnerabilities, history), 0.001)
+	// Every finding counts towards vulnerability management, which is halved without scans
+	scored := s.calculateVulnerabilityManagementScore(vulnerabilities, history)
+	assert.InDelta(t, scored/2, s.calculateVulnerabilityManagementScore(vulnerabilities, nil), 0.001)
+
+	assert.Equal(t, history[1].CreatedAt, s.lastTested(history))
+	assert.True(t, s.lastTested(nil).IsZero())
+}
+
+func TestNISTCSFControls(t *testing.T) {
+	s := &ComplianceService{}
+	stale := models.Vulnerability{Type: "cve", Severity: "critical", CreatedAt: time.Now().Add(-45 * 24 * time.Hour)}
+	fresh := models.Vulnerability{Type: "cve", Severity: "critical", CreatedAt: time.Now()}
+	history := []models.ScanResult{{CreatedAt: time.Now()}}
+
+	for _, framework := range []string{"NIST-CSF", "nist csf", "CSF"} {
+		controls := s.generateFrameworkControls(framework, []models.Vulnerability{stale, fresh}, history, nil)
+		functions := make(map[string]bool)
+		for _, control := range controls {
+			functions[control.Category] = true
+		}
+		assert.Equal(t, map[string]bool{"Govern": true, "Identify": true, "Protect": true, "Detect": true, "Respond": true, "Recover": true}, functions, framework)
+	}
+
+	// Only the critical finding open past the recovery window counts against recovery
+	controls := s.generateFrameworkControls("CSF", []models.Vulnerability{stale, fresh}, history, nil)
+	assert.InDelta(t, 0.5, controls["RC.RP-01"].Score, 0.001)
+	assert.Equal(t, "non_compliant", controls["RC.RP-01"].Status)
+	assert.Equal(t, 1.0, s.calculateRecoveryScore([]models.Vulnerability{fresh}, history))
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownFramework is returned for a compliance framework without a control catalog
+var ErrUnknownFramework = errors.New("unknown compliance framework")
+
+// slaControl is a framework control and the findings that count against it.
+// A finding maps to the control when its scope is listed, or when its title
+// contains one of the keywords.
+type slaControl struct {
+	ID       string
+	Name     string
+	Scopes   []string
+	Keywords []string
+}
+
+// slaFramework holds a framework's controls and default remediation timelines
+type slaFramework struct {
+	Controls        []slaControl
+	RemediationDays map[string]int
+}
+
+var defaultSeverityWeights = map[string]float64{
+	"critical": 10,
+	"high":     5,
+	"medium":   2,
+	"low":      1,
+	"info":     0,
+}
+
+const defaultDueSoonDays = 7
+
+// Finding scopes are the scan_type agents report their results under
+var (
+	allScopes     = []string{"software", "system", "network", "configuration", "vulnerability_scan", "authenticated"}
+	patchScopes   = []string{"software", "system", "vulnerability_scan"}
+	configScopes  = []string{"configuration"}
+	networkScopes = []string{"network", "authenticated"}
+
+	authKeywords   = []string{"password", "authentication", "account", "login", "ssh", "sudo", "privilege", "mfa"}
+	cryptoKeywords = []string{"tls", "ssl", "cipher", "certificate", "encryption", "plaintext"}
+)
+
+// slaFrameworks maps each supported framework to its controls and default timelines
+var slaFrameworks = map[string]slaFramework{
+	"SOC2": {
+		RemediationDays: map[string]int{"critical": 15, "high": 30, "medium": 90, "low": 180},
+		Controls: []slaControl{
+			{ID: "CC6.1", Name: "Logical Access Security", Keywords: authKeywords},
+			{ID: "CC6.6", Name: "Boundary Protection", Scopes: networkScopes},
+			{ID: "CC6.7", Name: "Data Transmission Protection", Keywords: cryptoKeywords},
+			{ID: "CC7.1", Name: "Vulnerability Detection and Monitoring", Scopes: allScopes},
+			{ID: "CC8.1", Name: "Change and Configuration Management", Scopes: configScopes},
+		},
+	},
+	"ISO27001": {
+		RemediationDays: map[string]int{"critical": 30, "high": 60, "medium": 90, "low": 180},
+		Controls: []slaControl{
+			{ID: "A.8.5", Name: "Secure Authentication", Keywords: authKeywords},
+			{ID: "A.8.8", Name: "Management of Technical Vulnerabilities", Scopes: allScopes},
+			{ID: "A.8.9", Name: "Configuration Management", Scopes: configScopes},
+			{ID: "A.8.20", Name: "Network Security", Scopes: networkScopes},
+			{ID: "A.8.24", Name: "Use of Cryptography", Keywords: cryptoKeywords},
+		},
+	},
+	"PCI DSS": {
+		RemediationDays: map[string]int{"critical": 30, "high": 30, "medium": 90, "low": 180},
+		Controls: []slaControl{
+			{ID: "1.2", Name: "Network Security Controls Configured", Scopes: networkScopes},
+			{ID: "2.2", Name: "System Components Configured Securely", Scopes: configScopes},
+			{ID: "4.2", Name: "Strong Cryptography in Transit", Keywords: cryptoKeywords},
+			{ID: "6.3.3", Name: "Security Patches Installed", Scopes: patchScopes},
+			{ID: "8.3", Name: "Strong Authentication", Keywords: authKeywords},
+			{ID: "11.3", Name: "Vulnerabilities Identified and Addressed", Scopes: allScopes},
+		},
+	},
+	"HIPAA": {
+		RemediationDays: map[string]int{"critical": 30, "high": 60, "medium": 90, "low": 365},
+		Controls: []slaControl{
+			{ID: "164.308(a)(1)(ii)(B)", Name: "Risk Management", Scopes: allScopes},
+			{ID: "164.312(a)(1)", Name: "Access Control", Keywords: authKeywords},
+			{ID: "164.312(d)", Name: "Person or Entity Authentication", Keywords: []string{"authentication", "mfa", "password"}},
+			{ID: "164.312(e)(1)", Name: "Transmission Security", Scopes: networkScopes, Keywords: cryptoKeywords},
+		},
+	},
+}
+
+// NormalizeFramework maps a framework name such as "pci-dss" or "iso_27001"
+// to its canonical name
+func NormalizeFramework(name string) (string, bool) {
+	compact := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToUpper(name))
+	for framework := range slaFrameworks {
+		if strings.ReplaceAll(framework, " ", "") == compact {
+			return framework, true
+		}
+	}
+	return "", false
+}
+
+// ComplianceSLAService ties open findings to framework controls and their remediation timelines
+type ComplianceSLAService struct {
+	db *gorm.DB
+}
+
+// NewComplianceSLAService creates a new compliance SLA service
+func NewComplianceSLAService(db *gorm.DB) *ComplianceSLAService {
+	return &ComplianceSLAService{db: db}
+}
+
+// GetPolicy returns an organization's effective policy for a framework: the
+// framework defaults with any organization overrides applied
+func (s *ComplianceSLAService) GetPolicy(organizationID uuid.UUID, framework string) (*models.ComplianceSLAPolicy, error) {
+	framework, ok := NormalizeFramework(framework)
+	if !ok {
+		return nil, ErrUnknownFramework
+	}
+
+	var override models.ComplianceSLAPolicy
+	err := s.db.Where("organization_id = ? AND framework = ?", organizationID, framework).First(&override).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	policy := defaultSLAPolicy(organizationID, framework)
+	if err == nil {
+		policy.ID = override.ID
+		policy.UpdatedBy = override.UpdatedBy
+		policy.CreatedAt = override.CreatedAt
+		policy.UpdatedAt = override.UpdatedAt
+		mergeSLAPolicy(policy, override.RemediationDays, override.SeverityWeights, override.DueSoonDays)
+	}
+	return policy, nil
+}
+
+// UpdatePolicy stores an organization's overrides for a framework's timelines
+func (s *ComplianceSLAService) UpdatePolicy(organizationID uuid.UUID, framework string, req *models.UpdateComplianceSLAPolicyRequest) (*models.ComplianceSLAPolicy, error) {
+	framework, ok := NormalizeFramework(framework)
+	if !ok {
+		return nil, ErrUnknownFramework
+	}
+	for severity, days := range req.RemediationDays {
+		if _, known := defaultSeverityWeights[severity]; !known {
+			return nil, fmt.Errorf("unknown severity %q", severity)
+		}
+		if days <= 0 {
+			return nil, fmt.Errorf("remediation days for %s must be positive", severity)
+		}
+	}
+	for severity, weight := range req.SeverityWeights {
+		if _, known := defaultSeverityWeights[severity]; !known {
+			return nil, fmt.Errorf("unknown severity %q", severity)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("weight for %s must not be negative", severity)
+		}
+	}
+	if req.DueSoonDays < 0 {
+		return nil, fmt.Errorf("due_soon_days must not be negative")
+	}
+
+	var policy models.ComplianceSLAPolicy
+	err := s.db.Where("organization_id = ? AND framework = ?", organizationID, framework).First(&policy).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		policy = models.ComplianceSLAPolicy{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			Framework:      framework,
+		}
+	}
+	policy.RemediationDays = req.RemediationDays
+	policy.SeverityWeights = req.SeverityWeights
+	policy.DueSoonDays = req.DueSoonDays
+	policy.UpdatedBy = req.UpdatedBy
+
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save SLA policy: %w", err)
+	}
+	return s.GetPolicy(organizationID, framework)
+}
+
+// GetDashboard maps an organization's open findings to a framework's controls
+// and reports which controls are at risk because of overdue findings
+func (s *ComplianceSLAService) GetDashboard(organizationID uuid.UUID, framework string) (*models.ComplianceSLADashboard, error) {
+	policy, err := s.GetPolicy(organizationID, framework)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []models.FindingState
+	if err := s.db.Where("organization_id = ? AND status = ?", organizationID, models.FindingStatusOpen).
+		Find(&findings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load open findings: %w", err)
+	}
+
+	return buildSLADashboard(policy, findings, time.Now()), nil
+}
+
+// defaultSLAPolicy returns a framework's built-in policy
+func defaultSLAPolicy(organizationID uuid.UUID, framework string) *models.ComplianceSLAPolicy {
+	policy := &models.ComplianceSLAPolicy{
+		OrganizationID:  organizationID,
+		Framework:       framework,
+		RemediationDays: make(map[string]int),
+		SeverityWeights: make(map[string]float64),
+		DueSoonDays:     defaultDueSoonDays,
+	}
+	mergeSLAPolicy(policy, slaFrameworks[framework].RemediationDays, defaultSeverityWeights, 0)
+	return policy
+}
+
+func mergeSLAPolicy(policy *models.ComplianceSLAPolicy, days map[string]int, weights map[string]float64, dueSoonDays int) {
+	for severity, d := range days {
+		policy.RemediationDays[severity] = d
+	}
+	for severity, w := range weights {
+		policy.SeverityWeights[severity] = w
+	}
+	if dueSoonDays > 0 {
+		policy.DueSoonDays = dueSoonDays
+	}
+}
+
+// buildSLADashboard evaluates open findings against a policy at a point in time
+func buildSLADashboard(policy *models.ComplianceSLAPolicy, findings []models.FindingState, now time.Time) *models.ComplianceSLADashboard {
+	catalog := slaFrameworks[policy.Framework]
+	dashboard := &models.ComplianceSLADashboard{
+		OrganizationID: policy.OrganizationID,
+		Framework:      policy.Framework,
+		Policy:         *policy,
+		AtRisk:         []models.ControlSLAStatus{},
+		Controls:       make([]models.ControlSLAStatus, 0, len(catalog.Controls)),
+		GeneratedAt:    now,
+	}
+
+	statuses := make([]models.FindingSLAStatus, 0, len(findings))
+	for _, finding := range findings {
+		status, ok := findingSLAStatus(policy, finding, now)
+		if !ok {
+			continue // Informational findings have no remediation deadline
+		}
+		statuses = append(statuses, status)
+
+		dashboard.Summary.OpenFindings++
+		switch status.Status {
+		case models.SLAStatusOverdue:
+			dashboard.Summary.OverdueFindings++
+		case models.SLAStatusDueSoon:
+			dashboard.Summary.DueSoonFindings++
+		}
+	}
+
+	for _, control := range catalog.Controls {
+		cs := models.ControlSLAStatus{
+			ControlID:   control.ID,
+			ControlName: control.Name,
+			Status:      models.SLAStatusOnTrack,
+			Findings:    []models.FindingSLAStatus{},
+		}
+		for _, status := range statuses {
+			if !control.covers(status.Scope, status.Title) {
+				continue
+			}
+			weight := policy.SeverityWeights[status.Severity]
+			cs.OpenFindings++
+			switch status.Status {
+			case models.SLAStatusOverdue:
+				cs.OverdueFindings++
+				cs.RiskScore += weight
+			case models.SLAStatusDueSoon:
+				cs.DueSoonFindings++
+				cs.RiskScore += weight / 2
+			}
+			cs.Findings = append(cs.Findings, status)
+		}
+
+		switch {
+		case cs.OverdueFindings > 0:
+			cs.Status = models.SLAStatusAtRisk
+		case cs.DueSoonFindings > 0:
+			cs.Status = models.SLAStatusDueSoon
+		}
+		sort.Slice(cs.Findings, func(i, j int) bool {
+			return cs.Findings[i].DueAt.Before(cs.Findings[j].DueAt)
+		})
+
+		dashboard.Controls = append(dashboard.Controls, cs)
+		if cs.Status == models.SLAStatusAtRisk {
+			dashboard.AtRisk = append(dashboard.AtRisk, cs)
+		}
+	}
+
+	sort.SliceStable(dashboard.AtRisk, func(i, j int) bool {
+		return dashboard.AtRisk[i].RiskScore > dashboard.AtRisk[j].RiskScore
+	})
+
+	dashboard.Summary.ControlsTotal = len(dashboard.Controls)
+	dashboard.Summary.ControlsAtRisk = len(dashboard.AtRisk)
+	dashboard.Summary.SLACompliancePct = 100
+	if dashboard.Summary.OpenFindings > 0 {
+		onTime := dashboard.Summary.OpenFindings - dashboard.Summary.OverdueFindings
+		dashboard.Summary.SLACompliancePct = math.Round(float64(onTime)/float64(dashboard.Summary.OpenFindings)*1000) / 10
+	}
+
+	return dashboard
+}
+
+// findingSLAStatus computes a finding's remediation deadline; the second
+// result is false for severities without a timeline
+func findingSLAStatus(policy *models.ComplianceSLAPolicy, finding models.FindingState, now time.Time) (models.FindingSLAStatus, bool) {
+	severity := strings.ToLower(finding.Severity)
+	days, ok := policy.RemediationDays[severity]
+	if !ok {
+		return models.FindingSLAStatus{}, false
+	}
+
+	dueAt := finding.FirstSeen.AddDate(0, 0, days)
+	remaining := dueAt.Sub(now)
+
+	status := models.SLAStatusOnTrack
+	switch {
+	case remaining < 0:
+		status = models.SLAStatusOverdue
+	case remaining <= time.Duration(policy.DueSoonDays)*24*time.Hour:
+		status = models.SLAStatusDueSoon
+	}
+
+	return models.FindingSLAStatus{
+		FindingID:     finding.ID,
+		AgentID:       finding.AgentID,
+		Title:         finding.Title,
+		CVEID:         finding.CVEID,
+		Severity:      severity,
+		Scope:         finding.Scope,
+		FirstSeen:     finding.FirstSeen,
+		DueAt:         dueAt,
+		DaysRemaining: int(math.Floor(remaining.Hours() / 24)),
+		Status:        status,
+	}, true
+}
+
+// covers reports whether a finding counts against the control. Keywords match
+// whole words, so "ssl" matches "SSL certificate expired" but not "openssl".
+func (c slaControl) covers(scope, title string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	if len(c.Keywords) == 0 {
+		return false
+	}
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		for _, keyword := range c.Keywords {
+			if word == keyword {
+				return true
+			}
+		}
+	}
+	return false
+}
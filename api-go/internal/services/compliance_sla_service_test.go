@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildSLADashboardFlagsControlsWithOverdueFindings(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	policy := defaultSLAPolicy(uuid.New(), "PCI DSS")
+
+	findings := []models.FindingState{
+		// Critical patch 45 days old: past PCI DSS's 30 day timeline
+		{ID: uuid.New(), Title: "openssl CVE-2024-0001", Severity: "critical", Scope: "software", FirstSeen: now.AddDate(0, 0, -45)},
+		// Config finding due in 3 days
+		{ID: uuid.New(), Title: "Screen lock disabled", Severity: "medium", Scope: "configuration", FirstSeen: now.AddDate(0, 0, -87)},
+		// Weak TLS, recently found
+		{ID: uuid.New(), Title: "Weak TLS cipher suites enabled", Severity: "high", Scope: "network", FirstSeen: now.AddDate(0, 0, -1)},
+		// Informational findings have no deadline
+		{ID: uuid.New(), Title: "Open port 22", Severity: "info", Scope: "network", FirstSeen: now.AddDate(0, -6, 0)},
+	}
+
+	dashboard := buildSLADashboard(policy, findings, now)
+
+	if dashboard.Summary.OpenFindings != 3 || dashboard.Summary.OverdueFindings != 1 || dashboard.Summary.DueSoonFindings != 1 {
+		t.Fatalf("unexpected summary: %+v", dashboard.Summary)
+	}
+
+	controls := make(map[string]models.ControlSLAStatus)
+	for _, control := range dashboard.Controls {
+		controls[control.ControlID] = control
+	}
+	if got := controls["6.3.3"]; got.Status != models.SLAStatusAtRisk || got.RiskScore != 10 {
+		t.Errorf("expected patch control at risk with score 10, got %+v", got)
+	}
+	if got := controls["2.2"]; got.Status != models.SLAStatusDueSoon {
+		t.Errorf("expected configuration control due soon, got %+v", got)
+	}
+	if got := controls["4.2"]; got.Status != models.SLAStatusOnTrack || got.OpenFindings != 1 {
+		t.Errorf("expected cryptography control on track with the TLS finding, got %+v", got)
+	}
+
+	// 11.3 also carries the due-soon configuration finding, so it ranks first
+	if len(dashboard.AtRisk) != 2 || dashboard.AtRisk[0].ControlID != "11.3" || dashboard.AtRisk[1].ControlID != "6.3.3" {
+		t.Errorf("expected vulnerability management then patch controls at risk, got %+v", dashboard.AtRisk)
+	}
+}
+
+func TestNormalizeFramework(t *testing.T) {
+	for input, want := range map[string]string{"pci-dss": "PCI DSS", "iso_27001": "ISO27001", "soc2": "SOC2", "Hipaa": "HIPAA"} {
+		if got, ok := NormalizeFramework(input); !ok || got != want {
+			t.Errorf("NormalizeFramework(%q) = %q, %v; want %q", input, got, ok, want)
+		}
+	}
+	if _, ok := NormalizeFramework("fedramp"); ok {
+		t.Error("expected an unknown framework to be rejected")
+	}
+}
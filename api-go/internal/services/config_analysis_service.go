@@ -2,7 +2,6 @@ package services
 
 import (
 	"encoding/json"
-	"errors"
 
 	"zerotrace/api/internal/models"
 	"zerotrace/api/internal/repository"
@@ -30,15 +29,10 @@ func NewConfigAnalysisService(
 // GetAnalysisResults retrieves analysis results for a config file
 func (s *ConfigAnalysisService) GetAnalysisResults(configFileID uuid.UUID, companyID uuid.UUID) (*models.ConfigAnalysisResult, error) {
 	// Verify config file belongs to company
-	configFile, err := s.configFileRepo.GetByID(configFileID)
-	if err != nil {
+	if _, err := s.configFileRepo.GetByID(companyID, configFileID); err != nil {
 		return nil, err
 	}
 
-	if configFile.CompanyID != companyID {
-		return nil, errors.New("config file not found for this company")
-	}
-
 	// Get analysis result
 	result, err := s.configAnalysisRepo.GetByConfigFileID(configFileID)
 	if err != nil {
@@ -69,15 +63,11 @@ func (s *ConfigAnalysisService) GetComplianceScores(configFileID uuid.UUID, comp
 
 // GetAnalysisStatus retrieves analysis status for a config file
 func (s *ConfigAnalysisService) GetAnalysisStatus(configFileID uuid.UUID, companyID uuid.UUID) (string, error) {
-	configFile, err := s.configFileRepo.GetByID(configFileID)
+	configFile, err := s.configFileRepo.GetByID(companyID, configFileID)
 	if err != nil {
 		return "", err
 	}
 
-	if configFile.CompanyID != companyID {
-		return "", errors.New("config file not found for this company")
-	}
-
 	return configFile.AnalysisStatus, nil
 }
 
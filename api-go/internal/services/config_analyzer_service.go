@@ -3,9 +3,11 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 
+	"zerotrace/api/internal/configrules"
 	"zerotrace/api/internal/constants"
 	"zerotrace/api/internal/models"
 	"zerotrace/api/internal/repository"
@@ -19,6 +21,7 @@ type ConfigAnalyzerService struct {
 	configFindingRepo   *repository.ConfigFindingRepository
 	configStandardRepo  *repository.ConfigStandardRepository
 	configAnalysisRepo  *repository.ConfigAnalysisRepository
+	rules               *ConfigRuleService
 }
 
 // NewConfigAnalyzerService creates a new config analyzer service
@@ -27,25 +30,30 @@ func NewConfigAnalyzerService(
 	configFindingRepo *repository.ConfigFindingRepository,
 	configStandardRepo *repository.ConfigStandardRepository,
 	configAnalysisRepo *repository.ConfigAnalysisRepository,
+	rules *ConfigRuleService,
 ) *ConfigAnalyzerService {
 	return &ConfigAnalyzerService{
 		configFileRepo:     configFileRepo,
 		configFindingRepo:   configFindingRepo,
 		configStandardRepo:  configStandardRepo,
 		configAnalysisRepo:  configAnalysisRepo,
+		rules:               rules,
 	}
 }
 
-// AnalyzeConfigFile analyzes a configuration file against standards
-func (s *ConfigAnalyzerService) AnalyzeConfigFile(configFileID uuid.UUID) error {
+// AnalyzeConfigFile analyzes a company's configuration file against standards
+func (s *ConfigAnalyzerService) AnalyzeConfigFile(companyID, configFileID uuid.UUID) error {
 	// Get config file
-	configFile, err := s.configFileRepo.GetByID(configFileID)
+	configFile, err := s.configFileRepo.GetByID(companyID, configFileID)
 	if err != nil {
 		return fmt.Errorf("failed to get config file: %w", err)
 	}
+	if err := s.configFileRepo.LoadContent(companyID, configFile); err != nil {
+		return err
+	}
 
 	// Update analysis status
-	err = s.configFileRepo.UpdateAnalysisStatus(configFileID, constants.StatusAnalyzing)
+	err = s.configFileRepo.UpdateAnalysisStatus(companyID, configFileID, constants.StatusAnalyzing)
 	if err != nil {
 		return err
 	}
@@ -71,13 +79,13 @@ func (s *ConfigAnalyzerService) AnalyzeConfigFile(configFileID uuid.UUID) error
 	// Check against standards
 	findings, err := s.CheckAgainstStandards(parsedConfig, standards, configFile)
 	if err != nil {
-		s.configFileRepo.UpdateAnalysisStatus(configFileID, constants.StatusFailed)
+		s.configFileRepo.UpdateAnalysisStatus(companyID, configFileID, constants.StatusFailed)
 		return fmt.Errorf("failed to check standards: %w", err)
 	}
 
 	// Save findings
 	if len(findings) > 0 {
-		err = s.configFindingRepo.CreateBatch(findings)
+		err = s.configFindingRepo.CreateBatch(companyID, findings)
 		if err != nil {
 			return fmt.Errorf("failed to save findings: %w", err)
 		}
@@ -100,7 +108,7 @@ func (s *ConfigAnalyzerService) AnalyzeConfigFile(configFileID uuid.UUID) error
 	}
 
 	// Update analysis status to completed
-	err = s.configFileRepo.UpdateAnalysisStatus(configFileID, constants.StatusCompleted)
+	err = s.configFileRepo.UpdateAnalysisStatus(companyID, configFileID, constants.StatusCompleted)
 	if err != nil {
 		return err
 	}
@@ -147,21 +155,7 @@ func (s *ConfigAnalyzerService) CheckAgainstStandards(
 				Status:             constants.StatusOpen,
 			}
 
-			// Set exploitability and impact based on severity
-			switch standard.DefaultSeverity {
-			case constants.SeverityCritical:
-				finding.Exploitability = "high"
-				finding.Impact = "critical"
-			case constants.SeverityHigh:
-				finding.Exploitability = "medium"
-				finding.Impact = "high"
-			case constants.SeverityMedium:
-				finding.Exploitability = "low"
-				finding.Impact = "medium"
-			default:
-				finding.Exploitability = "low"
-				finding.Impact = "low"
-			}
+			setExploitabilityAndImpact(&finding, standard.DefaultSeverity)
 
 			findings = append(findings, finding)
 		}
@@ -171,9 +165,112 @@ func (s *ConfigAnalyzerService) CheckAgainstStandards(
 	basicFindings := s.performBasicSecurityChecks(parsedConfig, configFile, configContent, configLines)
 	findings = append(findings, basicFindings...)
 
+	// And the custom rules from CONFIG_RULES_PATH
+	findings = append(findings, s.checkCustomRules(parsedConfig, configFile, configLines)...)
+
 	return findings, nil
 }
 
+// setExploitabilityAndImpact sets a finding's exploitability and impact based
+// on its severity
+func setExploitabilityAndImpact(finding *models.ConfigFinding, severity string) {
+	switch severity {
+	case constants.SeverityCritical:
+		finding.Exploitability = "high"
+		finding.Impact = "critical"
+	case constants.SeverityHigh:
+		finding.Exploitability = "medium"
+		finding.Impact = "high"
+	case constants.SeverityMedium:
+		finding.Exploitability = "low"
+		finding.Impact = "medium"
+	default:
+		finding.Exploitability = "low"
+		finding.Impact = "low"
+	}
+}
+
+// checkCustomRules evaluates the custom rules that apply to a config file
+func (s *ConfigAnalyzerService) checkCustomRules(
+	parsedConfig map[string]interface{},
+	configFile *models.ConfigFile,
+	configLines []string,
+) []models.ConfigFinding {
+	target := configrules.Target{
+		ConfigFormat: configFile.ConfigFormat,
+		Manufacturer: configFile.Manufacturer,
+		DeviceType:   configFile.DeviceType,
+	}
+
+	var findings []models.ConfigFinding
+	for _, violation := range s.rules.Rules().Evaluate(parsedConfig, target) {
+		rule := violation.Rule
+		lineNumbers, snippet := ruleLines(rule.Path, configLines)
+		description := rule.Description
+		if len(violation.Values) > 0 {
+			description = fmt.Sprintf("%s (found: %s)", description, strings.Join(violation.Values, "; "))
+		}
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"rule_id":     rule.ID,
+			"rule_source": fmt.Sprintf("%s:%d", filepath.Base(rule.Source), rule.Line),
+			"values":      violation.Values,
+		})
+
+		finding := models.ConfigFinding{
+			ConfigFileID:        configFile.ID,
+			CompanyID:           configFile.CompanyID,
+			FindingType:         "custom_rule",
+			Severity:            rule.Severity,
+			Category:            rule.Category,
+			Title:               rule.Title,
+			Description:         description,
+			AffectedComponent:   rule.Path,
+			ConfigSnippet:       snippet,
+			LineNumbers:         s.intArrayToJSON(lineNumbers),
+			Remediation:         rule.Remediation,
+			RemediationSteps:    s.parseRemediationSteps(rule.Remediation),
+			RemediationPriority: rule.Severity,
+			RiskScore:           s.calculateRiskScore(rule.Severity),
+			Status:              constants.StatusOpen,
+			Metadata:            metadata,
+		}
+		setExploitabilityAndImpact(&finding, rule.Severity)
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// ruleLines finds the lines of a config file that mention the last key of a
+// rule's path, and the first of them as a snippet
+func ruleLines(path string, configLines []string) ([]int, string) {
+	segments := strings.Split(path, ".")
+	key := ""
+	for i := len(segments) - 1; i >= 0 && key == ""; i-- {
+		if segments[i] != "*" {
+			key = strings.ToLower(segments[i])
+		}
+	}
+	if key == "" {
+		return nil, ""
+	}
+
+	var lineNumbers []int
+	snippet := ""
+	for i, line := range configLines {
+		if !strings.Contains(strings.ToLower(line), key) {
+			continue
+		}
+		if snippet == "" {
+			snippet = strings.TrimSpace(line)
+		}
+		lineNumbers = append(lineNumbers, i+1)
+		if len(lineNumbers) == 10 {
+			break
+		}
+	}
+	return lineNumbers, snippet
+}
+
 // checkStandard checks if a standard is violated
 func (s *ConfigAnalyzerService) checkStandard(
 	parsedConfig map[string]interface{},
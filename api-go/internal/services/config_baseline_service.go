@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAgentNotFound is returned when an agent is unknown or belongs to another organization
+	ErrAgentNotFound = errors.New("agent not found")
+	// ErrNoConfigScan is returned when a baseline is captured from an agent that has not reported a configuration scan
+	ErrNoConfigScan = errors.New("agent has not reported a configuration scan")
+)
+
+// ConfigBaselineService manages approved configuration baselines per host group
+type ConfigBaselineService struct {
+	db           *gorm.DB
+	agentService *AgentService
+}
+
+// NewConfigBaselineService creates a new config baseline service
+func NewConfigBaselineService(db *gorm.DB, agentService *AgentService) *ConfigBaselineService {
+	return &ConfigBaselineService{
+		db:           db,
+		agentService: agentService,
+	}
+}
+
+// ListBaselines lists an organization's baselines
+func (s *ConfigBaselineService) ListBaselines(organizationID uuid.UUID) ([]models.ConfigBaseline, error) {
+	var baselines []models.ConfigBaseline
+	err := s.db.Where("organization_id = ?", organizationID).Order("host_group ASC").Find(&baselines).Error
+	return baselines, err
+}
+
+// GetBaseline returns a host group's baseline
+func (s *ConfigBaselineService) GetBaseline(organizationID uuid.UUID, hostGroup string) (*models.ConfigBaseline, error) {
+	var baseline models.ConfigBaseline
+	if err := s.db.Where("organization_id = ? AND host_group = ?", organizationID, hostGroup).First(&baseline).Error; err != nil {
+		return nil, err
+	}
+	return &baseline, nil
+}
+
+// GetBaselineForAgent returns the baseline of a host group in the agent's organization
+func (s *ConfigBaselineService) GetBaselineForAgent(agentID uuid.UUID, hostGroup string) (*models.ConfigBaseline, error) {
+	agent, exists := s.agentService.GetAgent(agentID)
+	if !exists {
+		return nil, ErrAgentNotFound
+	}
+	return s.GetBaseline(agent.OrganizationID, hostGroup)
+}
+
+// UpdateBaseline replaces a host group's approved settings, creating the baseline if needed
+func (s *ConfigBaselineService) UpdateBaseline(organizationID uuid.UUID, hostGroup string, req *models.UpdateConfigBaselineRequest) (*models.ConfigBaseline, error) {
+	if len(req.Settings) == 0 {
+		return nil, fmt.Errorf("baseline must contain at least one setting")
+	}
+	return s.saveBaseline(organizationID, hostGroup, req.Settings, nil, req.UpdatedBy)
+}
+
+// CaptureBaseline approves an agent's most recently reported configuration as its host group's baseline
+func (s *ConfigBaselineService) CaptureBaseline(organizationID uuid.UUID, hostGroup string, req *models.CaptureConfigBaselineRequest) (*models.ConfigBaseline, error) {
+	agentID, err := uuid.Parse(req.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent ID: %w", err)
+	}
+
+	agent, exists := s.agentService.GetAgent(agentID)
+	if !exists || agent.OrganizationID != organizationID {
+		return nil, ErrAgentNotFound
+	}
+
+	settings := configSettingsFromMetadata(agent.Metadata)
+	if len(settings) == 0 {
+		return nil, ErrNoConfigScan
+	}
+
+	return s.saveBaseline(organizationID, hostGroup, settings, &agent.ID, req.UpdatedBy)
+}
+
+// saveBaseline creates or replaces a baseline, bumping its version on every change
+func (s *ConfigBaselineService) saveBaseline(organizationID uuid.UUID, hostGroup string, settings map[string]string, sourceAgentID *uuid.UUID, updatedBy string) (*models.ConfigBaseline, error) {
+	baseline, err := s.GetBaseline(organizationID, hostGroup)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		baseline = &models.ConfigBaseline{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			HostGroup:      hostGroup,
+			Version:        1,
+		}
+	} else {
+		baseline.Version++
+	}
+
+	baseline.Settings = settings
+	baseline.SourceAgentID = sourceAgentID
+	baseline.UpdatedBy = updatedBy
+
+	if err := s.db.Save(baseline).Error; err != nil {
+		return nil, fmt.Errorf("failed to save baseline: %w", err)
+	}
+	return baseline, nil
+}
+
+// configSettingsFromMetadata reads the config_settings map an agent's
+// configuration scan stored in its metadata
+func configSettingsFromMetadata(metadata map[string]interface{}) map[string]string {
+	switch raw := metadata["config_settings"].(type) {
+	case map[string]string:
+		return raw
+	case map[string]interface{}:
+		settings := make(map[string]string, len(raw))
+		for name, v := range raw {
+			if value, ok := v.(string); ok {
+				settings[name] = value
+			}
+		}
+		return settings
+	default:
+		return nil
+	}
+}
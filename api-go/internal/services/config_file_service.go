@@ -1,15 +1,18 @@
 package services
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"mime"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"zerotrace/api/internal/config"
 	"zerotrace/api/internal/constants"
@@ -73,7 +76,7 @@ func (s *ConfigFileService) UploadConfigFile(
 	hashString := hex.EncodeToString(hash[:])
 
 	// Check for duplicate (properly handle database errors)
-	existing, err := s.configFileRepo.GetByHash(hashString, companyID)
+	existing, err := s.configFileRepo.GetByHash(companyID, hashString)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, fmt.Errorf("failed to check for duplicates: %w", err)
 	}
@@ -93,7 +96,7 @@ func (s *ConfigFileService) UploadConfigFile(
 	}
 
 	// Detect config format
-	configFormat := s.detectConfigFormat(fileContent, filename)
+	configFormat := s.configFormat(req.ConfigFormat, fileContent, filename)
 
 	// Validate required fields
 	if req.DeviceType == "" {
@@ -163,7 +166,7 @@ func (s *ConfigFileService) UploadConfigFile(
 	}
 
 	// Save to database
-	err = s.configFileRepo.Create(configFile)
+	err = s.configFileRepo.Create(companyID, configFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save config file: %w", err)
 	}
@@ -172,7 +175,7 @@ func (s *ConfigFileService) UploadConfigFile(
 	// Note: This is a fire-and-forget operation. The job service manages workers
 	// and will be stopped gracefully on application shutdown.
 	go func() {
-		if err := s.jobService.QueueConfigAnalysis(configFile.ID); err != nil {
+		if err := s.jobService.QueueConfigAnalysis(companyID, configFile.ID); err != nil {
 			log.Printf("Failed to queue config analysis for %s: %v", configFile.ID, err)
 		}
 	}()
@@ -182,17 +185,7 @@ func (s *ConfigFileService) UploadConfigFile(
 
 // GetConfigFile retrieves a config file by ID
 func (s *ConfigFileService) GetConfigFile(id uuid.UUID, companyID uuid.UUID) (*models.ConfigFile, error) {
-	configFile, err := s.configFileRepo.GetByID(id)
-	if err != nil {
-		return nil, err
-	}
-
-	// Verify company ID matches
-	if configFile.CompanyID != companyID {
-		return nil, errors.New("config file not found for this company")
-	}
-
-	return configFile, nil
+	return s.configFileRepo.GetByID(companyID, id)
 }
 
 // ListConfigFiles lists config files with filters and pagination
@@ -251,14 +244,20 @@ func (s *ConfigFileService) ListConfigFiles(companyID uuid.UUID, req models.List
 	return response, nil
 }
 
-// GetConfigFileContent retrieves the file content
-func (s *ConfigFileService) GetConfigFileContent(id uuid.UUID, companyID uuid.UUID) ([]byte, error) {
+// GetConfigFileContent streams the file content from its storage backend,
+// returning it and its size. The caller must close it.
+func (s *ConfigFileService) GetConfigFileContent(ctx context.Context, id uuid.UUID, companyID uuid.UUID) (io.ReadCloser, int64, error) {
 	configFile, err := s.GetConfigFile(id, companyID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return configFile.FileContent, nil
+	return s.configFileRepo.OpenContent(ctx, companyID, configFile)
+}
+
+// GetStorageUsage reports the config file content a company keeps, per storage backend
+func (s *ConfigFileService) GetStorageUsage(companyID uuid.UUID) (*models.ConfigFileStorageUsage, error) {
+	return s.configFileRepo.GetStorageUsage(companyID)
 }
 
 // DeleteConfigFile deletes a config file
@@ -270,35 +269,23 @@ func (s *ConfigFileService) DeleteConfigFile(id uuid.UUID, companyID uuid.UUID)
 	}
 
 	// Delete from database (cascade will delete findings and analysis results)
-	return s.configFileRepo.Delete(configFile.ID)
+	if err := s.configFileRepo.Delete(companyID, configFile.ID); err != nil {
+		return err
+	}
+	if err := s.configFileRepo.DeleteContent(companyID, configFile); err != nil {
+		log.Printf("Failed to remove content of deleted config file %s: %v", configFile.ID, err)
+	}
+	return nil
 }
 
-// detectConfigFormat detects the configuration file format
-func (s *ConfigFileService) detectConfigFormat(content []byte, filename string) string {
-	ext := filepath.Ext(filename)
-	switch ext {
-	case ".xml":
-		return "xml"
-	case ".json":
-		return "json"
-	case ".txt", ".cfg", ".conf":
-		return "text"
-	default:
-		// Try to detect from content (with bounds checking)
-		if len(content) == 0 {
-			return "text"
-		}
-		contentStr := string(content[:min(100, len(content))])
-		if len(contentStr) > 0 {
-			if contentStr[0] == '<' {
-				return "xml"
-			}
-			if contentStr[0] == '{' || contentStr[0] == '[' {
-				return "json"
-			}
-		}
-		return "text"
+// configFormat is the format a config file is stored as: the one it was
+// uploaded as when that's one with a parser, or else the one detected
+func (s *ConfigFileService) configFormat(requested string, content []byte, filename string) string {
+	switch format := strings.ToLower(strings.TrimSpace(requested)); format {
+	case constants.ConfigFormatSSHD, constants.ConfigFormatNginx, constants.ConfigFormatApache, constants.ConfigFormatTerraform:
+		return format
 	}
+	return detectConfigFormat(filename, content)
 }
 
 // isValidDeviceType validates device type enum value
@@ -380,6 +367,6 @@ func (s *ConfigFileService) TriggerAnalysis(id uuid.UUID, companyID uuid.UUID) e
 		return err
 	}
 
-	return s.jobService.QueueConfigAnalysis(configFile.ID)
+	return s.jobService.QueueConfigAnalysis(companyID, configFile.ID)
 }
 
@@ -1,8 +1,6 @@
 package services
 
 import (
-	"errors"
-
 	"zerotrace/api/internal/models"
 	"zerotrace/api/internal/repository"
 
@@ -70,19 +68,21 @@ func (s *ConfigFindingService) ListConfigFindings(companyID uuid.UUID, req model
 	return response, nil
 }
 
+// ExportConfigFindings retrieves all findings matching the list filters, for
+// export, with their standards and config file names
+func (s *ConfigFindingService) ExportConfigFindings(companyID uuid.UUID, req models.ListConfigFindingsRequest) ([]models.ConfigFinding, error) {
+	return s.configFindingRepo.ListForExport(companyID, map[string]interface{}{
+		"config_file_id": req.ConfigFileID,
+		"severity":       req.Severity,
+		"category":       req.Category,
+		"status":         req.Status,
+		"finding_type":   req.FindingType,
+	})
+}
+
 // GetConfigFinding retrieves a finding by ID
 func (s *ConfigFindingService) GetConfigFinding(id uuid.UUID, companyID uuid.UUID) (*models.ConfigFinding, error) {
-	finding, err := s.configFindingRepo.GetByID(id)
-	if err != nil {
-		return nil, err
-	}
-
-	// Verify company ID matches
-	if finding.CompanyID != companyID {
-		return nil, errors.New("finding not found for this company")
-	}
-
-	return finding, nil
+	return s.configFindingRepo.GetByID(companyID, id)
 }
 
 // UpdateFindingStatus updates finding status
@@ -94,14 +94,12 @@ func (s *ConfigFindingService) UpdateFindingStatus(id uuid.UUID, companyID uuid.
 	}
 
 	// Update status
-	return s.configFindingRepo.UpdateStatus(finding.ID, status, resolvedBy)
+	return s.configFindingRepo.UpdateStatus(companyID, finding.ID, status, resolvedBy)
 }
 
 // GetFindingStats retrieves finding statistics for a config file
 func (s *ConfigFindingService) GetFindingStats(configFileID uuid.UUID, companyID uuid.UUID) (map[string]int, error) {
-	// Verify config file belongs to company (would need config file repo for this)
-	// For now, just get stats
-	stats, err := s.configFindingRepo.GetStatsByConfigFile(configFileID)
+	stats, err := s.configFindingRepo.GetStatsByConfigFile(companyID, configFileID)
 	if err != nil {
 		return nil, err
 	}
@@ -11,12 +11,18 @@ import (
 	"github.com/google/uuid"
 )
 
+// configAnalysisJob is a queued analysis of a company's config file
+type configAnalysisJob struct {
+	CompanyID    uuid.UUID
+	ConfigFileID uuid.UUID
+}
+
 // ConfigJobService handles asynchronous config analysis jobs
 type ConfigJobService struct {
 	configFileRepo    *repository.ConfigFileRepository
 	parserService     *ConfigParserService
 	analyzerService   *ConfigAnalyzerService
-	jobQueue          chan uuid.UUID
+	jobQueue          chan configAnalysisJob
 	workerCount       int
 	wg                sync.WaitGroup
 	stopChan          chan struct{}
@@ -43,7 +49,7 @@ func NewConfigJobService(
 		configFileRepo:  configFileRepo,
 		parserService:   parserService,
 		analyzerService: analyzerService,
-		jobQueue:        make(chan uuid.UUID, queueBufferSize),
+		jobQueue:        make(chan configAnalysisJob, queueBufferSize),
 		workerCount:     workerCount,
 		stopChan:        make(chan struct{}),
 	}
@@ -54,10 +60,10 @@ func NewConfigJobService(
 	return service
 }
 
-// QueueConfigAnalysis queues a config file for analysis
-func (s *ConfigJobService) QueueConfigAnalysis(configFileID uuid.UUID) error {
+// QueueConfigAnalysis queues a company's config file for analysis
+func (s *ConfigJobService) QueueConfigAnalysis(companyID, configFileID uuid.UUID) error {
 	select {
-	case s.jobQueue <- configFileID:
+	case s.jobQueue <- configAnalysisJob{CompanyID: companyID, ConfigFileID: configFileID}:
 		log.Printf("Queued config analysis for file: %s", configFileID)
 		return nil
 	default:
@@ -66,10 +72,10 @@ func (s *ConfigJobService) QueueConfigAnalysis(configFileID uuid.UUID) error {
 	}
 }
 
-// ProcessConfigAnalysis processes a config file analysis
-func (s *ConfigJobService) ProcessConfigAnalysis(configFileID uuid.UUID) error {
+// ProcessConfigAnalysis processes a company's config file analysis
+func (s *ConfigJobService) ProcessConfigAnalysis(companyID, configFileID uuid.UUID) error {
 	// Get config file
-	configFile, err := s.configFileRepo.GetByID(configFileID)
+	configFile, err := s.configFileRepo.GetByID(companyID, configFileID)
 	if err != nil {
 		return err
 	}
@@ -83,7 +89,7 @@ func (s *ConfigJobService) ProcessConfigAnalysis(configFileID uuid.UUID) error {
 		}
 
 		// Reload config file to get parsed data
-		configFile, err = s.configFileRepo.GetByID(configFileID)
+		configFile, err = s.configFileRepo.GetByID(companyID, configFileID)
 		if err != nil {
 			return err
 		}
@@ -91,7 +97,7 @@ func (s *ConfigJobService) ProcessConfigAnalysis(configFileID uuid.UUID) error {
 
 	// Step 2: Analyze the config file
 	if configFile.ParsingStatus == "parsed" {
-		err = s.analyzerService.AnalyzeConfigFile(configFileID)
+		err = s.analyzerService.AnalyzeConfigFile(companyID, configFileID)
 		if err != nil {
 			log.Printf("Failed to analyze config file %s: %v", configFileID, err)
 			return err
@@ -117,13 +123,13 @@ func (s *ConfigJobService) worker(id int) {
 
 	for {
 		select {
-		case configFileID := <-s.jobQueue:
-			log.Printf("Worker %d processing config file: %s", id, configFileID)
-			err := s.ProcessConfigAnalysis(configFileID)
+		case job := <-s.jobQueue:
+			log.Printf("Worker %d processing config file: %s", id, job.ConfigFileID)
+			err := s.ProcessConfigAnalysis(job.CompanyID, job.ConfigFileID)
 			if err != nil {
-				log.Printf("Worker %d error processing %s: %v", id, configFileID, err)
+				log.Printf("Worker %d error processing %s: %v", id, job.ConfigFileID, err)
 			} else {
-				log.Printf("Worker %d completed processing: %s", id, configFileID)
+				log.Printf("Worker %d completed processing: %s", id, job.ConfigFileID)
 			}
 
 		case <-s.stopChan:
@@ -140,9 +146,9 @@ func (s *ConfigJobService) Stop() {
 	log.Println("All config analysis workers stopped")
 }
 
-// GetAnalysisStatus gets the analysis status for a config file
-func (s *ConfigJobService) GetAnalysisStatus(configFileID uuid.UUID) (string, error) {
-	configFile, err := s.configFileRepo.GetByID(configFileID)
+// GetAnalysisStatus gets the analysis status for a company's config file
+func (s *ConfigJobService) GetAnalysisStatus(companyID, configFileID uuid.UUID) (string, error) {
+	configFile, err := s.configFileRepo.GetByID(companyID, configFileID)
 	if err != nil {
 		return "", err
 	}
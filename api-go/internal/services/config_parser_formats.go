@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"zerotrace/api/internal/constants"
+)
+
+// The server and infrastructure config formats below are parsed into one
+// shape, a tree of maps the analyzer's rules can walk with dotted paths:
+//
+//   - A directive or attribute is a key, its arguments a string value
+//   - A directive given more than once is a list of its values
+//   - A block or section is a nested map, its arguments under "_args"
+//
+// so "server { listen 443 ssl; }" in nginx.conf becomes
+// {"server": {"listen": "443 ssl"}}.
+
+// blockArgsKey holds the arguments of a block, like the path of a location
+const blockArgsKey = "_args"
+
+// sniffLimit is how much of a file content sniffing reads
+const sniffLimit = 8 * 1024
+
+var (
+	terraformSignature = regexp.MustCompile(`(?m)^\s*(resource|data|provider|variable|output|module|locals|terraform)(\s+"[^"]*")*\s*\{`)
+	nginxSignature     = regexp.MustCompile(`(?m)^\s*((http|events|server|upstream)\s*\{|location\s+[^{;\n]+\{|(worker_processes|server_name|listen|proxy_pass|ssl_protocols|root)\s+[^;{\n]+;)`)
+	apacheSignature    = regexp.MustCompile(`(?mi)^\s*(</?(VirtualHost|Directory|DirectoryMatch|IfModule|Location|Files|FilesMatch)\b|(ServerRoot|DocumentRoot|LoadModule|ServerTokens|ServerSignature)\s+\S)`)
+	sshdSignature      = regexp.MustCompile(`(?mi)^\s*(PermitRootLogin|PasswordAuthentication|PubkeyAuthentication|ChallengeResponseAuthentication|KbdInteractiveAuthentication|AuthorizedKeysFile|PermitEmptyPasswords|UsePAM|X11Forwarding|Subsystem\s+sftp)\s`)
+)
+
+// detectConfigFormat detects a config file's format from its name, or else
+// its content, falling back to xml, json or text
+func detectConfigFormat(filename string, content []byte) string {
+	name := strings.ToLower(filepath.Base(filename))
+	ext := filepath.Ext(name)
+	switch {
+	case strings.HasPrefix(name, "sshd_config"):
+		return constants.ConfigFormatSSHD
+	case name == "nginx.conf" || (strings.HasPrefix(name, "nginx") && ext == ".conf"):
+		return constants.ConfigFormatNginx
+	case name == "httpd.conf" || name == "apache2.conf" || name == "apache.conf" || name == ".htaccess":
+		return constants.ConfigFormatApache
+	case ext == ".tf" || ext == ".tfvars":
+		return constants.ConfigFormatTerraform
+	case ext == ".xml":
+		return constants.ConfigFormatXML
+	case ext == ".json":
+		return constants.ConfigFormatJSON
+	}
+
+	head := content
+	if len(head) > sniffLimit {
+		head = head[:sniffLimit]
+	}
+	switch {
+	case terraformSignature.Match(head):
+		return constants.ConfigFormatTerraform
+	case nginxSignature.Match(head):
+		return constants.ConfigFormatNginx
+	case apacheSignature.Match(head):
+		return constants.ConfigFormatApache
+	case sshdSignature.Match(head):
+		return constants.ConfigFormatSSHD
+	}
+
+	trimmed := bytes.TrimSpace(head)
+	if len(trimmed) > 0 {
+		switch trimmed[0] {
+		case '<':
+			return constants.ConfigFormatXML
+		case '{', '[':
+			return constants.ConfigFormatJSON
+		}
+	}
+	return constants.ConfigFormatText
+}
+
+// isGenericConfigFormat reports whether a format says no more than how a
+// file is encoded, as files uploaded before formats were detected do
+func isGenericConfigFormat(format string) bool {
+	switch format {
+	case "", constants.ConfigFormatXML, constants.ConfigFormatJSON, constants.ConfigFormatText:
+		return true
+	}
+	return false
+}
+
+// addConfigValue sets a key of a parsed config tree, turning a key given
+// more than once into a list of its values
+func addConfigValue(tree map[string]interface{}, key string, value interface{}) {
+	existing, ok := tree[key]
+	if !ok {
+		tree[key] = value
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		tree[key] = append(list, value)
+		return
+	}
+	tree[key] = []interface{}{existing, value}
+}
+
+// foldKey returns the key a tree already has for a case-insensitive name,
+// so "UsePAM" and "usepam" are one directive
+func foldKey(tree map[string]interface{}, name string) string {
+	if _, ok := tree[name]; ok {
+		return name
+	}
+	for key := range tree {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+// unquote strips the quotes around a whole value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] &&
+		strings.IndexByte(value[1:len(value)-1], value[0]) < 0 {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// ParseSSHD parses an OpenSSH sshd_config. Settings in Match blocks are
+// kept apart from the global ones, under "Match".
+func (s *ConfigParserService) ParseSSHD(config []byte) (map[string]interface{}, error) {
+	parsed := map[string]interface{}{}
+	current := parsed
+
+	scanner := bufio.NewScanner(bytes.NewReader(config))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(config)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Keywords are separated from their arguments by whitespace or an optional "="
+		keyword, args := line, ""
+		if i := strings.IndexAny(line, " \t="); i >= 0 {
+			keyword = line[:i]
+			args = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line[i:]), "="))
+		}
+		args = unquote(args)
+
+		if strings.EqualFold(keyword, "Match") {
+			current = map[string]interface{}{blockArgsKey: args}
+			addConfigValue(parsed, foldKey(parsed, "Match"), current)
+			continue
+		}
+		addConfigValue(current, foldKey(current, keyword), args)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// nginxToken is a word, a quoted string or one of ; { }
+type nginxToken struct {
+	text  string
+	punct bool
+	line  int
+}
+
+func tokenizeNginx(config []byte) ([]nginxToken, error) {
+	var tokens []nginxToken
+	content := string(config)
+	line := 1
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case c == ';' || c == '{' || c == '}':
+			tokens = append(tokens, nginxToken{text: string(c), punct: true, line: line})
+			i++
+		case c == '"' || c == '\'':
+			start := line
+			var b strings.Builder
+			i++
+			for ; i < len(content) && content[i] != c; i++ {
+				if content[i] == '\\' && i+1 < len(content) {
+					i++
+				}
+				if content[i] == '\n' {
+					line++
+				}
+				b.WriteByte(content[i])
+			}
+			if i >= len(content) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			i++
+			tokens = append(tokens, nginxToken{text: b.String(), line: start})
+		default:
+			start := i
+			for i < len(content) && !strings.ContainsRune(" \t\r\n;{}\"'", rune(content[i])) {
+				// ${var} is part of a word
+				if content[i] == '$' && i+1 < len(content) && content[i+1] == '{' {
+					if end := strings.IndexByte(content[i:], '}'); end > 0 {
+						i += end
+					}
+				}
+				i++
+			}
+			tokens = append(tokens, nginxToken{text: content[start:i], line: line})
+		}
+	}
+	return tokens, nil
+}
+
+// ParseNginx parses an nginx.conf into its directives and blocks
+func (s *ConfigParserService) ParseNginx(config []byte) (map[string]interface{}, error) {
+	tokens, err := tokenizeNginx(config)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := map[string]interface{}{}
+	stack := []map[string]interface{}{parsed}
+	openedAt := []int{0}
+	var args []nginxToken
+	for _, token := range tokens {
+		if !token.punct {
+			args = append(args, token)
+			continue
+		}
+		current := stack[len(stack)-1]
+		switch token.text {
+		case ";":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected ';'", token.line)
+			}
+			addConfigValue(current, args[0].text, joinNginxArgs(args[1:]))
+		case "{":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("line %d: block without a name", token.line)
+			}
+			block := map[string]interface{}{}
+			if len(args) > 1 {
+				block[blockArgsKey] = joinNginxArgs(args[1:])
+			}
+			addConfigValue(current, args[0].text, block)
+			stack = append(stack, block)
+			openedAt = append(openedAt, token.line)
+		case "}":
+			if len(args) > 0 {
+				return nil, fmt.Errorf("line %d: directive %q is missing its ';'", args[0].line, args[0].text)
+			}
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("line %d: unexpected '}'", token.line)
+			}
+			stack = stack[:len(stack)-1]
+			openedAt = openedAt[:len(openedAt)-1]
+		}
+		args = nil
+	}
+
+	if len(args) > 0 {
+		return nil, fmt.Errorf("line %d: directive %q is missing its ';'", args[0].line, args[0].text)
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("line %d: block is never closed", openedAt[len(openedAt)-1])
+	}
+	return parsed, nil
+}
+
+func joinNginxArgs(args []nginxToken) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.text
+	}
+	return strings.Join(parts, " ")
+}
+
+var apacheSection = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9]*)\s*([^>]*)>$`)
+
+// ParseApache parses an Apache httpd.conf into its directives and sections
+func (s *ConfigParserService) ParseApache(config []byte) (map[string]interface{}, error) {
+	type section struct {
+		name string
+		tree map[string]interface{}
+		line int
+	}
+
+	parsed := map[string]interface{}{}
+	stack := []section{{tree: parsed}}
+
+	lines := strings.Split(string(config), "\n")
+	for i := 0; i < len(lines); i++ {
+		number := i + 1
+		line := strings.TrimSpace(lines[i])
+		// A trailing backslash continues a directive on the next line
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " " + strings.TrimSpace(lines[i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		current := stack[len(stack)-1]
+		if strings.HasPrefix(line, "<") {
+			match := apacheSection.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: malformed section %q", number, line)
+			}
+			if match[1] == "/" {
+				if len(stack) == 1 {
+					return nil, fmt.Errorf("line %d: </%s> without an opening section", number, match[2])
+				}
+				if !strings.EqualFold(match[2], current.name) {
+					return nil, fmt.Errorf("line %d: </%s> closes <%s> opened on line %d", number, match[2], current.name, current.line)
+				}
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			tree := map[string]interface{}{}
+			if args := strings.TrimSpace(match[3]); args != "" {
+				tree[blockArgsKey] = unquote(args)
+			}
+			addConfigValue(current.tree, foldKey(current.tree, match[2]), tree)
+			stack = append(stack, section{name: match[2], tree: tree, line: number})
+			continue
+		}
+
+		fields := strings.Fields(line)
+		args := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+		addConfigValue(current.tree, foldKey(current.tree, fields[0]), unquote(args))
+	}
+
+	if len(stack) > 1 {
+		open := stack[len(stack)-1]
+		return nil, fmt.Errorf("line %d: <%s> is never closed", open.line, open.name)
+	}
+	return parsed, nil
+}
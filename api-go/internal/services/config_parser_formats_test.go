@@ -0,0 +1,225 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/constants"
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectConfigFormat(t *testing.T) {
+	tests := []struct {
+		filename, content, format string
+	}{
+		{"sshd_config", "", constants.ConfigFormatSSHD},
+		{"etc/ssh/sshd_config.bak", "", constants.ConfigFormatSSHD},
+		{"nginx.conf", "", constants.ConfigFormatNginx},
+		{"httpd.conf", "", constants.ConfigFormatApache},
+		{"main.tf", "", constants.ConfigFormatTerraform},
+		{"prod.tfvars", "region = \"eu-west-1\"", constants.ConfigFormatTerraform},
+		{"panorama.xml", "<config/>", constants.ConfigFormatXML},
+		{"export.json", "{}", constants.ConfigFormatJSON},
+
+		// By content, when the name doesn't tell
+		{"default.conf", "server {\n    listen 80;\n}\n", constants.ConfigFormatNginx},
+		{"site.conf", "<VirtualHost *:443>\n  DocumentRoot /var/www\n</VirtualHost>\n", constants.ConfigFormatApache},
+		{"ssh.txt", "Port 22\nPermitRootLogin no\n", constants.ConfigFormatSSHD},
+		{"network.hcl", "resource \"aws_vpc\" \"main\" {\n}\n", constants.ConfigFormatTerraform},
+		{"backup", "<?xml version=\"1.0\"?><config/>", constants.ConfigFormatXML},
+		{"running.cfg", "hostname fw01\ninterface GigabitEthernet0/0\n nameif outside\n", constants.ConfigFormatText},
+		{"juniper.conf", "system {\n    host-name fw01;\n}\n", constants.ConfigFormatText},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.format, detectConfigFormat(tt.filename, []byte(tt.content)), tt.filename)
+	}
+}
+
+func TestParseSSHD(t *testing.T) {
+	parsed, err := (&ConfigParserService{}).ParseSSHD([]byte(`# Global settings
+Port 22
+port=2222
+PermitRootLogin prohibit-password
+Ciphers aes256-gcm@openssh.com,chacha20-poly1305@openssh.com
+Banner "/etc/issue net"
+
+Match User backup
+    PasswordAuthentication yes
+    ForceCommand internal-sftp
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"22", "2222"}, parsed["Port"], "keywords are case-insensitive and may repeat")
+	assert.Equal(t, "prohibit-password", parsed["PermitRootLogin"])
+	assert.Equal(t, "/etc/issue net", parsed["Banner"])
+	assert.NotContains(t, parsed, "PasswordAuthentication", "Match settings aren't global")
+	assert.Equal(t, map[string]interface{}{
+		"_args":                  "User backup",
+		"PasswordAuthentication": "yes",
+		"ForceCommand":           "internal-sftp",
+	}, parsed["Match"])
+}
+
+func TestParseNginx(t *testing.T) {
+	parsed, err := (&ConfigParserService{}).ParseNginx([]byte(`
+worker_processes auto;
+http {
+    server_tokens off; # Hide the version
+    server {
+        listen 443 ssl;
+        listen [::]:443 ssl;
+        ssl_protocols TLSv1 TLSv1.2;
+        location /api {
+            proxy_pass http://backend;
+            add_header X-Frame-Options "SAMEORIGIN";
+        }
+    }
+    server {
+        listen 80;
+        return 301 https://$host${request_uri};
+    }
+}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "auto", parsed["worker_processes"])
+	http := parsed["http"].(map[string]interface{})
+	assert.Equal(t, "off", http["server_tokens"])
+	servers := http["server"].([]interface{})
+	require.Len(t, servers, 2)
+	tls := servers[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{"443 ssl", "[::]:443 ssl"}, tls["listen"])
+	assert.Equal(t, "TLSv1 TLSv1.2", tls["ssl_protocols"])
+	assert.Equal(t, map[string]interface{}{
+		"_args":      "/api",
+		"proxy_pass": "http://backend",
+		"add_header": "X-Frame-Options SAMEORIGIN",
+	}, tls["location"])
+	assert.Equal(t, "301 https://$host${request_uri}", servers[1].(map[string]interface{})["return"])
+
+	for config, err := range map[string]string{
+		"http {\n    server_tokens off;\n":   "line 1: block is never closed",
+		"http {\n    server_tokens off\n}\n": `line 2: directive "server_tokens" is missing its ';'`,
+		"}\n":                                "line 1: unexpected '}'",
+		"add_header X \"unterminated;\n":     "line 1: unterminated string",
+	} {
+		_, parseErr := (&ConfigParserService{}).ParseNginx([]byte(config))
+		require.Error(t, parseErr, config)
+		assert.Equal(t, err, parseErr.Error())
+	}
+}
+
+func TestParseApache(t *testing.T) {
+	parsed, err := (&ConfigParserService{}).ParseApache([]byte(`ServerTokens Prod
+LoadModule ssl_module modules/mod_ssl.so
+LoadModule headers_module modules/mod_headers.so
+<VirtualHost *:443>
+    ServerName example.com
+    SSLProtocol all -SSLv3 \
+        -TLSv1
+    <Directory "/var/www/html">
+        Options Indexes FollowSymLinks
+    </Directory>
+</VirtualHost>
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "Prod", parsed["ServerTokens"])
+	assert.Len(t, parsed["LoadModule"], 2)
+	vhost := parsed["VirtualHost"].(map[string]interface{})
+	assert.Equal(t, "*:443", vhost["_args"])
+	assert.Equal(t, "all -SSLv3 -TLSv1", vhost["SSLProtocol"], "continued lines are one directive")
+	assert.Equal(t, map[string]interface{}{"_args": "/var/www/html", "Options": "Indexes FollowSymLinks"}, vhost["Directory"])
+
+	_, err = (&ConfigParserService{}).ParseApache([]byte("<VirtualHost *:80>\n<Directory />\n</VirtualHost>\n"))
+	require.Error(t, err)
+	assert.Equal(t, "line 3: </VirtualHost> closes <Directory> opened on line 2", err.Error())
+	_, err = (&ConfigParserService{}).ParseApache([]byte("<IfModule ssl_module>\nListen 443\n"))
+	require.Error(t, err)
+	assert.Equal(t, "line 1: <IfModule> is never closed", err.Error())
+}
+
+func TestParseTerraform(t *testing.T) {
+	parsed, err := (&ConfigParserService{}).ParseTerraform([]byte(`
+terraform {
+  required_version = ">= 1.5"
+}
+
+/* Buckets */
+resource "aws_s3_bucket" "logs" {
+  bucket = "acme-${var.env}-logs" // Per environment
+  acl    = "public-read"
+  force_destroy = false
+  tags = {
+    Team  = "platform"
+    "cost-center" = 42
+  }
+}
+
+resource "aws_security_group" "web" {
+  name   = "web"
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    cidr_blocks = ["0.0.0.0/0", var.office_cidr]
+  }
+  ingress { from_port = 443 }
+}
+
+locals {
+  ports  = [for p in var.ports : p if p > 0]
+  offset = -1
+  policy = <<-EOT
+    {
+      "Version": "2012-10-17"
+    }
+  EOT
+}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, ">= 1.5", parsed["terraform"].(map[string]interface{})["required_version"])
+
+	resources := parsed["resource"].(map[string]interface{})
+	bucket := resources["aws_s3_bucket"].(map[string]interface{})["logs"].(map[string]interface{})
+	assert.Equal(t, "acme-${var.env}-logs", bucket["bucket"])
+	assert.Equal(t, "public-read", bucket["acl"])
+	assert.Equal(t, false, bucket["force_destroy"])
+	assert.Equal(t, map[string]interface{}{"Team": "platform", "cost-center": float64(42)}, bucket["tags"])
+
+	group := resources["aws_security_group"].(map[string]interface{})["web"].(map[string]interface{})
+	assert.Equal(t, "aws_vpc.main.id", group["vpc_id"], "references are kept as written")
+	ingress := group["ingress"].([]interface{})
+	require.Len(t, ingress, 2)
+	assert.Equal(t, []interface{}{"0.0.0.0/0", "var.office_cidr"}, ingress[0].(map[string]interface{})["cidr_blocks"])
+	assert.Equal(t, float64(443), ingress[1].(map[string]interface{})["from_port"])
+
+	locals := parsed["locals"].(map[string]interface{})
+	assert.Equal(t, "[for p in var.ports : p if p > 0]", locals["ports"])
+	assert.Equal(t, float64(-1), locals["offset"])
+	assert.Equal(t, "{\n  \"Version\": \"2012-10-17\"\n}\n", locals["policy"])
+
+	tfvars, err := (&ConfigParserService{}).ParseTerraform([]byte("region = \"eu-west-1\"\nazs = 3\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"region": "eu-west-1", "azs": float64(3)}, tfvars)
+
+	for config, err := range map[string]string{
+		"resource \"a\" \"b\" {\n  x = 1\n": "line 1: block is never closed",
+		"x = 1\nx = 2\n":                    `line 2: attribute "x" is already set`,
+		"x = \"open\n":                      "line 1: unterminated string",
+		"locals {\n  ports = [80,\n":        "line 2: unclosed bracket in \"ports\"",
+		"policy = <<EOT\n{}\n":              "line 1: heredoc is never closed with EOT",
+		"resource \"a\" \"b\"\n":            `line 1: expected '=' or '{' after "resource"`,
+	} {
+		_, parseErr := (&ConfigParserService{}).ParseTerraform([]byte(config))
+		require.Error(t, parseErr, config)
+		assert.Equal(t, err, parseErr.Error())
+	}
+}
+
+func TestParserFor(t *testing.T) {
+	s := &ConfigParserService{}
+	assert.NotNil(t, s.parserFor(&models.ConfigFile{ConfigFormat: constants.ConfigFormatNginx, Manufacturer: "f5"}), "the format picks the parser before the manufacturer")
+	assert.NotNil(t, s.parserFor(&models.ConfigFile{ConfigFormat: constants.ConfigFormatText, Manufacturer: "Cisco"}))
+	assert.Nil(t, s.parserFor(&models.ConfigFile{ConfigFormat: constants.ConfigFormatText, Manufacturer: "MikroTik"}), "an unknown format is left unparsed")
+}
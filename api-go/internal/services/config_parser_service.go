@@ -24,49 +24,50 @@ func NewConfigParserService(configFileRepo *repository.ConfigFileRepository) *Co
 	}
 }
 
-// ParseConfigFile parses a configuration file based on manufacturer and device type
+// ParseConfigFile parses a configuration file based on its format, or its
+// manufacturer and device type. A file no parser handles is marked unparsed.
 func (s *ConfigParserService) ParseConfigFile(configFile *models.ConfigFile) error {
 	// Update status to parsing
-	err := s.configFileRepo.UpdateParsingStatus(configFile.ID, constants.StatusParsing, nil, "")
+	err := s.configFileRepo.UpdateParsingStatus(configFile.CompanyID, configFile.ID, constants.StatusParsing, nil, "")
 	if err != nil {
 		return err
 	}
 
-	var parsedData map[string]interface{}
-	var parseErr error
+	if err := s.configFileRepo.LoadContent(configFile.CompanyID, configFile); err != nil {
+		return err
+	}
 
-	// Parse based on manufacturer
-	switch strings.ToLower(configFile.Manufacturer) {
-	case "cisco":
-		if strings.ToLower(configFile.DeviceType) == "firewall" {
-			parsedData, parseErr = s.ParseCiscoASA(configFile.FileContent)
-		} else {
-			parsedData, parseErr = s.ParseCiscoIOS(configFile.FileContent)
+	// Files uploaded before formats were detected say only how they're encoded
+	if isGenericConfigFormat(configFile.ConfigFormat) {
+		if format := detectConfigFormat(configFile.Filename, configFile.FileContent); format != configFile.ConfigFormat {
+			if err := s.configFileRepo.UpdateConfigFormat(configFile.CompanyID, configFile.ID, format); err != nil {
+				return err
+			}
+			configFile.ConfigFormat = format
 		}
-	case "palo alto", "paloalto", "palo alto networks":
-		parsedData, parseErr = s.ParsePaloAlto(configFile.FileContent)
-	case "fortinet", "fortigate":
-		parsedData, parseErr = s.ParseFortinet(configFile.FileContent)
-	case "juniper":
-		parsedData, parseErr = s.ParseJuniper(configFile.FileContent)
-	default:
-		parseErr = fmt.Errorf("unsupported manufacturer: %s", configFile.Manufacturer)
 	}
 
+	parse := s.parserFor(configFile)
+	if parse == nil {
+		// Keep the file, but there's nothing to analyze in it
+		reason := fmt.Sprintf("no parser for %s config from %s", configFile.ConfigFormat, configFile.Manufacturer)
+		return s.configFileRepo.UpdateParsingStatus(configFile.CompanyID, configFile.ID, constants.StatusUnparsed, nil, reason)
+	}
+	parsedData, parseErr := parse(configFile.FileContent)
 	if parseErr != nil {
-		err = s.configFileRepo.UpdateParsingStatus(configFile.ID, constants.StatusFailed, nil, parseErr.Error())
+		err = s.configFileRepo.UpdateParsingStatus(configFile.CompanyID, configFile.ID, constants.StatusFailed, nil, parseErr.Error())
 		return parseErr
 	}
 
 	// Convert to JSONB format
 	parsedJSON, err := json.Marshal(parsedData)
 	if err != nil {
-		err = s.configFileRepo.UpdateParsingStatus(configFile.ID, constants.StatusFailed, nil, err.Error())
+		err = s.configFileRepo.UpdateParsingStatus(configFile.CompanyID, configFile.ID, constants.StatusFailed, nil, err.Error())
 		return err
 	}
 
 	// Update status to parsed
-	err = s.configFileRepo.UpdateParsingStatus(configFile.ID, constants.StatusParsed, parsedJSON, "")
+	err = s.configFileRepo.UpdateParsingStatus(configFile.CompanyID, configFile.ID, constants.StatusParsed, parsedJSON, "")
 	if err != nil {
 		return err
 	}
@@ -74,6 +75,37 @@ func (s *ConfigParserService) ParseConfigFile(configFile *models.ConfigFile) err
 	return nil
 }
 
+// parserFor picks the parser for a config file: by its format for server and
+// infrastructure configs, or else by the manufacturer of the network device
+// it came from. It returns nil when there is none.
+func (s *ConfigParserService) parserFor(configFile *models.ConfigFile) func([]byte) (map[string]interface{}, error) {
+	switch configFile.ConfigFormat {
+	case constants.ConfigFormatSSHD:
+		return s.ParseSSHD
+	case constants.ConfigFormatNginx:
+		return s.ParseNginx
+	case constants.ConfigFormatApache:
+		return s.ParseApache
+	case constants.ConfigFormatTerraform:
+		return s.ParseTerraform
+	}
+
+	switch strings.ToLower(configFile.Manufacturer) {
+	case "cisco":
+		if strings.ToLower(configFile.DeviceType) == "firewall" {
+			return s.ParseCiscoASA
+		}
+		return s.ParseCiscoIOS
+	case "palo alto", "paloalto", "palo alto networks":
+		return s.ParsePaloAlto
+	case "fortinet", "fortigate":
+		return s.ParseFortinet
+	case "juniper":
+		return s.ParseJuniper
+	}
+	return nil
+}
+
 // ParseCiscoASA parses Cisco ASA configuration
 func (s *ConfigParserService) ParseCiscoASA(config []byte) (map[string]interface{}, error) {
 	content := string(config)
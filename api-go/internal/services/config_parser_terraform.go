@@ -0,0 +1,495 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseTerraform parses Terraform .tf and .tfvars files. Blocks nest under
+// their type and labels, so resource "aws_s3_bucket" "logs" { acl = "private" }
+// is resource.aws_s3_bucket.logs.acl. Strings, numbers, bools, lists and
+// objects become values; any other expression, like a reference or a
+// function call, is kept as its source text.
+func (s *ConfigParserService) ParseTerraform(config []byte) (map[string]interface{}, error) {
+	tokens, err := tokenizeHCL(string(config))
+	if err != nil {
+		return nil, err
+	}
+	p := &hclParser{src: string(config), tokens: tokens}
+	return p.parseBody(0)
+}
+
+type hclKind int
+
+const (
+	hclEOF hclKind = iota
+	hclNewline
+	hclIdent
+	hclString
+	hclNumber
+	hclPunct
+)
+
+type hclToken struct {
+	kind       hclKind
+	text       string // Identifier, punctuation, number or string value
+	start, end int    // Offsets in the source
+	line       int
+}
+
+// hclOperators are the punctuation longer than one character
+var hclOperators = []string{"==", "!=", "<=", ">=", "=>", "&&", "||", "..."}
+
+func tokenizeHCL(src string) ([]hclToken, error) {
+	var tokens []hclToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == '\n':
+			tokens = append(tokens, hclToken{kind: hclNewline, start: i, end: i + 1, line: line})
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"':
+			value, end, err := scanHCLString(src, i, line)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, hclToken{kind: hclString, text: value, start: start, end: end, line: line})
+			i = end
+		case strings.HasPrefix(src[i:], "<<"):
+			value, end, lines, err := scanHCLHeredoc(src, i, line)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, hclToken{kind: hclString, text: value, start: start, end: end, line: line})
+			line += lines
+			i = end
+		case c >= '0' && c <= '9':
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, hclToken{kind: hclNumber, text: src[start:i], start: start, end: i, line: line})
+		case isIdentStart(c):
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i]) || src[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, hclToken{kind: hclIdent, text: src[start:i], start: start, end: i, line: line})
+		default:
+			text := string(c)
+			for _, op := range hclOperators {
+				if strings.HasPrefix(src[i:], op) {
+					text = op
+					break
+				}
+			}
+			i += len(text)
+			tokens = append(tokens, hclToken{kind: hclPunct, text: text, start: start, end: i, line: line})
+		}
+	}
+	return append(tokens, hclToken{kind: hclEOF, start: len(src), end: len(src), line: line}), nil
+}
+
+// scanHCLString reads the quoted string at src[start], decoding escapes and
+// keeping ${...} and %{...} templates as they are
+func scanHCLString(src string, start, line int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\n':
+			return "", 0, fmt.Errorf("line %d: unterminated string", line)
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(src[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(src[i])
+			}
+		case (c == '$' || c == '%') && i+1 < len(src) && src[i+1] == '{':
+			end := templateEnd(src, i+2)
+			if end < 0 {
+				return "", 0, fmt.Errorf("line %d: unterminated template in string", line)
+			}
+			b.WriteString(src[i:end])
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("line %d: unterminated string", line)
+}
+
+// templateEnd finds the end of a template whose body starts at src[i],
+// skipping braces in the strings it contains
+func templateEnd(src string, i int) int {
+	depth := 1
+	inString := false
+	for ; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\n' && !inString:
+			return -1
+		case c == '\\' && inString:
+			i++
+		case c == '"':
+			inString = !inString
+		case c == '{' && !inString:
+			depth++
+		case c == '}' && !inString:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// scanHCLHeredoc reads the <<MARKER or <<-MARKER heredoc at src[start],
+// returning its text, where it ends and how many lines it spans
+func scanHCLHeredoc(src string, start, line int) (string, int, int, error) {
+	i := start + 2
+	indented := i < len(src) && src[i] == '-'
+	if indented {
+		i++
+	}
+	markerStart := i
+	for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+		i++
+	}
+	marker := src[markerStart:i]
+	newline := strings.IndexByte(src[i:], '\n')
+	if marker == "" || newline < 0 || strings.TrimSpace(src[i:i+newline]) != "" {
+		return "", 0, 0, fmt.Errorf("line %d: malformed heredoc", line)
+	}
+	i += newline + 1
+
+	var body []string
+	lines := 1
+	for i < len(src) {
+		end := strings.IndexByte(src[i:], '\n')
+		if end < 0 {
+			end = len(src) - i
+		}
+		text := strings.TrimRight(src[i:i+end], "\r")
+		if strings.TrimSpace(text) == marker {
+			return heredocText(body, indented), i + end, lines, nil
+		}
+		body = append(body, text)
+		lines++
+		i += end + 1
+	}
+	return "", 0, 0, fmt.Errorf("line %d: heredoc is never closed with %s", line, marker)
+}
+
+// heredocText joins a heredoc's lines, dropping their common indent for <<-
+func heredocText(lines []string, indented bool) string {
+	if indented {
+		indent := -1
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			n := len(line) - len(strings.TrimLeft(line, " \t"))
+			if indent < 0 || n < indent {
+				indent = n
+			}
+		}
+		for i, line := range lines {
+			if len(line) >= indent && indent > 0 {
+				lines[i] = line[indent:]
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type hclParser struct {
+	src    string
+	tokens []hclToken
+	pos    int
+}
+
+func (p *hclParser) peek() hclToken {
+	return p.tokens[p.pos]
+}
+
+func (p *hclParser) skipNewlines() {
+	for p.peek().kind == hclNewline {
+		p.pos++
+	}
+}
+
+// parseBody reads attributes and blocks up to the end of the file, or the
+// '}' closing the block opened on line openedAt
+func (p *hclParser) parseBody(openedAt int) (map[string]interface{}, error) {
+	inBlock := openedAt > 0
+	body := map[string]interface{}{}
+	for {
+		p.skipNewlines()
+		token := p.peek()
+		switch {
+		case token.kind == hclEOF:
+			if inBlock {
+				return nil, fmt.Errorf("line %d: block is never closed", openedAt)
+			}
+			return body, nil
+		case token.kind == hclPunct && token.text == "}":
+			if !inBlock {
+				return nil, fmt.Errorf("line %d: unexpected '}'", token.line)
+			}
+			p.pos++
+			return body, nil
+		case token.kind != hclIdent:
+			return nil, fmt.Errorf("line %d: expected an attribute or block, got %q", token.line, p.src[token.start:token.end])
+		}
+		p.pos++
+
+		if next := p.peek(); next.kind == hclPunct && next.text == "=" {
+			p.pos++
+			if _, ok := body[token.text]; ok {
+				return nil, fmt.Errorf("line %d: attribute %q is already set", token.line, token.text)
+			}
+			value, err := p.parseAttributeValue(token)
+			if err != nil {
+				return nil, err
+			}
+			body[token.text] = value
+			continue
+		}
+
+		keys := []string{token.text}
+		for p.peek().kind == hclString || p.peek().kind == hclIdent {
+			keys = append(keys, p.peek().text)
+			p.pos++
+		}
+		if next := p.peek(); next.kind != hclPunct || next.text != "{" {
+			return nil, fmt.Errorf("line %d: expected '=' or '{' after %q", token.line, token.text)
+		}
+		p.pos++
+		block, err := p.parseBody(token.line)
+		if err != nil {
+			return nil, err
+		}
+		mergeHCLBlock(body, keys, block)
+	}
+}
+
+// parseAttributeValue reads an attribute's expression, which ends at the
+// end of its line once its brackets are closed
+func (p *hclParser) parseAttributeValue(name hclToken) (interface{}, error) {
+	start := p.pos
+	depth := 0
+	for {
+		token := p.peek()
+		if token.kind == hclEOF {
+			break
+		}
+		if depth == 0 && (token.kind == hclNewline || (token.kind == hclPunct && token.text == "}")) {
+			break
+		}
+		if token.kind == hclPunct {
+			switch token.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+		}
+		p.pos++
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("line %d: unclosed bracket in %q", name.line, name.text)
+	}
+	span := trimHCLNewlines(p.tokens[start:p.pos])
+	if len(span) == 0 {
+		return nil, fmt.Errorf("line %d: %q has no value", name.line, name.text)
+	}
+	return p.value(span), nil
+}
+
+// value converts an expression to a value, or to its source text when it's
+// more than a literal, list or object
+func (p *hclParser) value(span []hclToken) interface{} {
+	span = trimHCLNewlines(span)
+	if len(span) == 0 {
+		return nil
+	}
+	if len(span) == 1 {
+		token := span[0]
+		switch token.kind {
+		case hclString:
+			return token.text
+		case hclNumber:
+			if n, err := strconv.ParseFloat(token.text, 64); err == nil {
+				return n
+			}
+		case hclIdent:
+			switch token.text {
+			case "true":
+				return true
+			case "false":
+				return false
+			case "null":
+				return nil
+			}
+		}
+	}
+	if len(span) == 2 && span[0].kind == hclPunct && span[0].text == "-" && span[1].kind == hclNumber {
+		if n, err := strconv.ParseFloat(span[1].text, 64); err == nil {
+			return -n
+		}
+	}
+
+	// A for expression builds its list or object when Terraform evaluates it
+	if first := span[0]; first.kind == hclPunct && (first.text == "[" || first.text == "{") && closingIndex(span) == len(span)-1 &&
+		!isForExpression(span[1:len(span)-1]) {
+		inner := span[1 : len(span)-1]
+		if first.text == "[" {
+			list := []interface{}{}
+			for _, item := range splitHCL(inner, false) {
+				list = append(list, p.value(item))
+			}
+			return list
+		}
+		if object, ok := p.object(inner); ok {
+			return object
+		}
+	}
+
+	return strings.TrimSpace(p.src[span[0].start:span[len(span)-1].end])
+}
+
+// object converts the items of an object expression, key = value or
+// key: value, separated by commas or newlines
+func (p *hclParser) object(inner []hclToken) (map[string]interface{}, bool) {
+	object := map[string]interface{}{}
+	for _, item := range splitHCL(inner, true) {
+		if len(item) < 3 || (item[0].kind != hclIdent && item[0].kind != hclString) ||
+			item[1].kind != hclPunct || (item[1].text != "=" && item[1].text != ":") {
+			return nil, false
+		}
+		object[item[0].text] = p.value(item[2:])
+	}
+	return object, true
+}
+
+func isForExpression(inner []hclToken) bool {
+	inner = trimHCLNewlines(inner)
+	return len(inner) > 0 && inner[0].kind == hclIdent && inner[0].text == "for"
+}
+
+// closingIndex finds the bracket closing the one a span starts with
+func closingIndex(span []hclToken) int {
+	depth := 0
+	for i, token := range span {
+		if token.kind != hclPunct {
+			continue
+		}
+		switch token.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitHCL splits a list's or object's items on top-level commas, and for
+// objects newlines, dropping empty items
+func splitHCL(span []hclToken, onNewlines bool) [][]hclToken {
+	var items [][]hclToken
+	depth, start := 0, 0
+	for i, token := range span {
+		if token.kind == hclPunct {
+			switch token.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+		}
+		if depth == 0 && ((token.kind == hclPunct && token.text == ",") || (onNewlines && token.kind == hclNewline)) {
+			if item := trimHCLNewlines(span[start:i]); len(item) > 0 {
+				items = append(items, item)
+			}
+			start = i + 1
+		}
+	}
+	if item := trimHCLNewlines(span[start:]); len(item) > 0 {
+		items = append(items, item)
+	}
+	return items
+}
+
+func trimHCLNewlines(span []hclToken) []hclToken {
+	for len(span) > 0 && span[0].kind == hclNewline {
+		span = span[1:]
+	}
+	for len(span) > 0 && span[len(span)-1].kind == hclNewline {
+		span = span[:len(span)-1]
+	}
+	return span
+}
+
+// mergeHCLBlock nests a block under its type and labels. A block repeated
+// under the same keys, like two ingress rules, makes a list.
+func mergeHCLBlock(tree map[string]interface{}, keys []string, block map[string]interface{}) {
+	if len(keys) == 1 {
+		addConfigValue(tree, keys[0], block)
+		return
+	}
+	child, ok := tree[keys[0]].(map[string]interface{})
+	if !ok {
+		if _, exists := tree[keys[0]]; exists {
+			nested := map[string]interface{}{}
+			mergeHCLBlock(nested, keys[1:], block)
+			addConfigValue(tree, keys[0], nested)
+			return
+		}
+		child = map[string]interface{}{}
+		tree[keys[0]] = child
+	}
+	mergeHCLBlock(child, keys[1:], block)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/configrules"
+)
+
+// ConfigRuleService holds the custom config analysis rules loaded from
+// CONFIG_RULES_PATH and reloads them when the files change. A reload that
+// fails validation is logged and the rules already loaded stay in use.
+type ConfigRuleService struct {
+	path     string
+	interval time.Duration
+
+	mu          sync.RWMutex
+	rules       *configrules.Set
+	fingerprint string
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewConfigRuleService creates a new config rule service, loading its rules.
+// Without CONFIG_RULES_PATH there are no custom rules.
+func NewConfigRuleService(cfg *config.Config) (*ConfigRuleService, error) {
+	interval := cfg.ConfigRulesReloadInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	s := &ConfigRuleService{
+		path:     cfg.ConfigRulesPath,
+		interval: interval,
+		stopChan: make(chan struct{}),
+	}
+	if s.path != "" {
+		if _, err := s.Reload(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Start begins watching the rule files for changes in the background
+func (s *ConfigRuleService) Start() {
+	if s.path == "" {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if reloaded, err := s.Reload(); err != nil {
+					log.Printf("Failed to reload config rules, keeping the rules already loaded: %v", err)
+				} else if reloaded {
+					log.Printf("Reloaded %d config rules from %s", len(s.Rules().Rules), s.path)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops watching the rule files
+func (s *ConfigRuleService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+}
+
+// Rules returns the rules in use, nil when there are none
+func (s *ConfigRuleService) Rules() *configrules.Set {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules
+}
+
+// Reload loads the rules again if the files changed since they were last
+// loaded, and reports whether they did
+func (s *ConfigRuleService) Reload() (bool, error) {
+	fingerprint, err := rulesFingerprint(s.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config rules: %w", err)
+	}
+	s.mu.RLock()
+	unchanged := s.rules != nil && fingerprint == s.fingerprint
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	rules, err := configrules.Load(s.path)
+	if err != nil {
+		return false, fmt.Errorf("invalid config rules: %w", err)
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	s.fingerprint = fingerprint
+	s.mu.Unlock()
+	return true, nil
+}
+
+// rulesFingerprint summarizes the names, sizes and modification times of the
+// rule files, so polling them is cheap
+func rulesFingerprint(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano()), nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", entry.Name(), info.Size(), info.ModTime().UnixNano()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "|"), nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sshdRootLoginRule = `
+rules:
+  - id: sshd-root-login
+    title: sshd permits root login
+    severity: critical
+    category: authentication
+    remediation: Set PermitRootLogin no
+    applies_to:
+      config_formats: [sshd]
+    path: PermitRootLogin
+    operator: equals
+    value: "no"
+`
+
+func TestConfigRuleServiceReloadsChangedRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(sshdRootLoginRule), 0o600))
+
+	rules, err := NewConfigRuleService(&config.Config{ConfigRulesPath: path})
+	require.NoError(t, err)
+	require.Len(t, rules.Rules().Rules, 1)
+
+	reloaded, err := rules.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged files aren't loaded again")
+
+	// A broken edit is reported and the rules already loaded stay in use
+	require.NoError(t, os.WriteFile(path, []byte("rules:\n  - id: broken\n"), 0o600))
+	touch(t, path, time.Now().Add(time.Second))
+	_, err = rules.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+":2")
+	assert.Equal(t, "sshd-root-login", rules.Rules().Rules[0].ID)
+
+	require.NoError(t, os.WriteFile(path, []byte(""), 0o600))
+	touch(t, path, time.Now().Add(2*time.Second))
+	reloaded, err = rules.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Empty(t, rules.Rules().Rules)
+
+	_, err = NewConfigRuleService(&config.Config{ConfigRulesPath: filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.Error(t, err, "rules that can't be loaded at startup are fatal")
+}
+
+func TestCheckAgainstStandardsReportsCustomRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(sshdRootLoginRule), 0o600))
+	rules, err := NewConfigRuleService(&config.Config{ConfigRulesPath: path})
+	require.NoError(t, err)
+
+	analyzer := NewConfigAnalyzerService(nil, nil, nil, nil, rules)
+	configFile := &models.ConfigFile{
+		ConfigFormat: "sshd",
+		FileContent:  []byte("Port 22\nPermitRootLogin yes\n"),
+	}
+	findings, err := analyzer.CheckAgainstStandards(map[string]interface{}{"PermitRootLogin": "yes"}, nil, configFile)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+
+	finding := findings[0]
+	assert.Equal(t, "custom_rule", finding.FindingType)
+	assert.Equal(t, "critical", finding.Severity)
+	assert.Equal(t, "authentication", finding.Category)
+	assert.Equal(t, "PermitRootLogin yes", finding.ConfigSnippet)
+	assert.JSONEq(t, `[2]`, string(finding.LineNumbers))
+	assert.Contains(t, finding.Description, "found: yes")
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(finding.Metadata, &metadata))
+	assert.Equal(t, "sshd-root-login", metadata["rule_id"])
+	assert.Equal(t, "rules.yaml:3", metadata["rule_source"])
+
+	// Rules for other formats don't apply, and without rules there are none to check
+	configFile.ConfigFormat = "nginx"
+	findings, _ = analyzer.CheckAgainstStandards(map[string]interface{}{"PermitRootLogin": "yes"}, nil, configFile)
+	assert.Empty(t, findings)
+	findings, _ = NewConfigAnalyzerService(nil, nil, nil, nil, nil).CheckAgainstStandards(map[string]interface{}{"PermitRootLogin": "yes"}, nil, configFile)
+	assert.Empty(t, findings)
+}
+
+// touch moves a file's modification time so a rewrite is seen even on
+// filesystems with coarse timestamps
+func touch(t *testing.T, path string, at time.Time) {
+	t.Helper()
+	require.NoError(t, os.Chtimes(path, at, at))
+}
@@ -0,0 +1,472 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrConfigUploadNotFound is returned for an upload that does not exist,
+	// has expired or belongs to another company
+	ErrConfigUploadNotFound = errors.New("config upload not found")
+	// ErrInvalidConfigUpload is returned when starting an upload whose size or checksum makes no sense
+	ErrInvalidConfigUpload = errors.New("invalid config upload")
+	// ErrInvalidUploadChunk is returned for a chunk at an offset, or of a size, that doesn't fit its upload
+	ErrInvalidUploadChunk = errors.New("invalid upload chunk")
+	// ErrUploadChunkChecksum is returned for a chunk whose content doesn't match its checksum
+	ErrUploadChunkChecksum = errors.New("upload chunk checksum mismatch")
+	// ErrUploadChunkConflict is returned for a chunk at an offset already received with different content
+	ErrUploadChunkConflict = errors.New("upload chunk conflicts with one already received")
+	// ErrConfigUploadIncomplete is returned when completing an upload still missing chunks
+	ErrConfigUploadIncomplete = errors.New("config upload is missing chunks")
+	// ErrConfigUploadChecksum is returned when an upload's assembled chunks don't match its checksum
+	ErrConfigUploadChecksum = errors.New("config upload checksum mismatch")
+)
+
+// configUploadSweepInterval is how often expired uploads are dropped
+const configUploadSweepInterval = 5 * time.Minute
+
+// ConfigUploadService receives config files too large to upload reliably in
+// one request. A client starts an upload with the file's size and SHA-256,
+// sends its chunks in any order, each with its own SHA-256, and completes it
+// once every chunk has arrived; the file is then checked against its checksum
+// and stored as if it had been uploaded whole. Chunks are idempotent, and a
+// client that lost its connection asks which chunks are missing and resumes.
+// Uploads that go a full timeout without a chunk are dropped. Chunks are
+// kept in a blob store until their upload is completed or dropped.
+type ConfigUploadService struct {
+	db        *gorm.DB
+	files     *ConfigFileService
+	store     storage.BlobStore
+	chunkSize int64
+	maxSize   int64
+	timeout   time.Duration
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewConfigUploadService creates a new config upload service keeping chunks in store
+func NewConfigUploadService(db *gorm.DB, files *ConfigFileService, store storage.BlobStore, cfg *config.Config) *ConfigUploadService {
+	chunkSize := int64(cfg.ConfigUploadChunkSize)
+	if chunkSize <= 0 {
+		chunkSize = 1024 * 1024
+	}
+	maxSize := int64(cfg.ConfigAuditorMaxFileSize)
+	if maxSize <= 0 {
+		maxSize = 10 * 1024 * 1024
+	}
+	timeout := cfg.ConfigUploadTimeout
+	if timeout <= 0 {
+		timeout = 24 * time.Hour
+	}
+
+	return &ConfigUploadService{
+		db:        db,
+		files:     files,
+		store:     store,
+		chunkSize: chunkSize,
+		maxSize:   maxSize,
+		timeout:   timeout,
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// ChunkSize is the size of every chunk of an upload but its last
+func (s *ConfigUploadService) ChunkSize() int64 {
+	return s.chunkSize
+}
+
+// Start begins dropping expired uploads in the background
+func (s *ConfigUploadService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(configUploadSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.sweepExpired(time.Now())
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background sweep
+func (s *ConfigUploadService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+}
+
+// InitUpload starts an upload of a config file of the given size and checksum
+func (s *ConfigUploadService) InitUpload(companyID uuid.UUID, uploadedBy *uuid.UUID, filename string, req models.InitConfigUploadRequest) (*models.ConfigUploadStatus, error) {
+	if req.Size < 1 || req.Size > s.maxSize {
+		return nil, fmt.Errorf("%w: size must be between 1 and %d bytes, got %d", ErrInvalidConfigUpload, s.maxSize, req.Size)
+	}
+	checksum, err := parseSHA256(req.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("%w: checksum %v", ErrInvalidConfigUpload, err)
+	}
+
+	now := time.Now()
+	upload := &models.ConfigUpload{
+		ID:          uuid.New(),
+		CompanyID:   companyID,
+		UploadedBy:  uploadedBy,
+		Filename:    filename,
+		Size:        req.Size,
+		ChunkSize:   s.chunkSize,
+		TotalChunks: int((req.Size + s.chunkSize - 1) / s.chunkSize),
+		Checksum:    checksum,
+		Request:     req.UploadConfigFileRequest,
+		Status:      models.ConfigUploadReceiving,
+		ExpiresAt:   now.Add(s.timeout),
+	}
+	if err := s.db.Create(upload).Error; err != nil {
+		return nil, fmt.Errorf("failed to start config upload: %w", err)
+	}
+
+	return uploadStatus(upload, nil), nil
+}
+
+// GetUpload returns an upload's progress, with the offsets of the chunks it
+// is missing
+func (s *ConfigUploadService) GetUpload(companyID, uploadID uuid.UUID) (*models.ConfigUploadStatus, error) {
+	upload, err := s.findUpload(s.db, companyID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	received, err := s.receivedSequences(s.db, upload)
+	if err != nil {
+		return nil, err
+	}
+	return uploadStatus(upload, received), nil
+}
+
+// UploadChunk stores the chunk of an upload at offset, after checking it
+// against its checksum. Resending a chunk already received changes nothing;
+// duplicate reports that it had been.
+func (s *ConfigUploadService) UploadChunk(companyID, uploadID uuid.UUID, offset int64, checksum string, data []byte) (status *models.ConfigUploadStatus, duplicate bool, err error) {
+	want, err := parseSHA256(checksum)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: checksum %v", ErrInvalidUploadChunk, err)
+	}
+	got := sha256Hex(data)
+	if got != want {
+		return nil, false, fmt.Errorf("%w: chunk at offset %d has SHA-256 %s, not %s", ErrUploadChunkChecksum, offset, got, want)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Concurrent chunks of an upload queue on its row lock
+		upload, err := s.findUpload(tx.Clauses(clause.Locking{Strength: "UPDATE"}), companyID, uploadID)
+		if err != nil {
+			return err
+		}
+		if upload.Status == models.ConfigUploadComplete {
+			duplicate = true
+			status = uploadStatus(upload, nil)
+			return nil
+		}
+
+		sequence, err := chunkSequence(upload, offset, int64(len(data)))
+		if err != nil {
+			return err
+		}
+
+		var existing models.ConfigUploadChunk
+		err = tx.Select("checksum").Where("upload_id = ? AND sequence = ?", uploadID, sequence).Take(&existing).Error
+		switch {
+		case err == nil:
+			if existing.Checksum != got {
+				return fmt.Errorf("%w: chunk at offset %d was already received with different content", ErrUploadChunkConflict, offset)
+			}
+			duplicate = true
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			key := chunkKey(uploadID, sequence)
+			if err := s.store.PutObject(context.Background(), key, data, "application/octet-stream"); err != nil {
+				return fmt.Errorf("failed to store upload chunk: %w", err)
+			}
+			chunk := models.ConfigUploadChunk{UploadID: uploadID, Sequence: sequence, Checksum: got, Size: int64(len(data))}
+			if err := tx.Create(&chunk).Error; err != nil {
+				s.deleteChunks(uploadID, []int{sequence})
+				return err
+			}
+			upload.ReceivedChunks++
+		default:
+			return err
+		}
+
+		upload.ExpiresAt = time.Now().Add(s.timeout)
+		if err := tx.Save(upload).Error; err != nil {
+			return err
+		}
+		received, err := s.receivedSequences(tx, upload)
+		if err != nil {
+			return err
+		}
+		status = uploadStatus(upload, received)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return status, duplicate, nil
+}
+
+// CompleteUpload assembles an upload's chunks and, if they match its
+// checksum, stores them as a config file and queues it for analysis like a
+// file uploaded whole. Completing an upload again returns the same file.
+func (s *ConfigUploadService) CompleteUpload(companyID, uploadID uuid.UUID) (*models.ConfigFile, error) {
+	var configFile *models.ConfigFile
+	var assembled []int
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		upload, err := s.findUpload(tx.Clauses(clause.Locking{Strength: "UPDATE"}), companyID, uploadID)
+		if err != nil {
+			return err
+		}
+		if upload.Status == models.ConfigUploadComplete && upload.ConfigFileID != nil {
+			configFile, err = s.files.GetConfigFile(*upload.ConfigFileID, companyID)
+			return err
+		}
+		if upload.ReceivedChunks < upload.TotalChunks {
+			return fmt.Errorf("%w: %d of %d chunks received", ErrConfigUploadIncomplete, upload.ReceivedChunks, upload.TotalChunks)
+		}
+
+		var chunks []models.ConfigUploadChunk
+		if err := tx.Where("upload_id = ?", uploadID).Order("sequence").Find(&chunks).Error; err != nil {
+			return err
+		}
+		if err := s.readChunks(chunks); err != nil {
+			return err
+		}
+		content, err := assembleUpload(upload, chunks)
+		if err != nil {
+			return err
+		}
+
+		configFile, err = s.files.UploadConfigFile(content, upload.Filename, upload.Request, companyID, upload.UploadedBy)
+		if err != nil {
+			return err
+		}
+
+		// The upload is kept until it expires, so completing it again after
+		// a dropped response finds the file rather than an unknown upload
+		upload.Status = models.ConfigUploadComplete
+		upload.ConfigFileID = &configFile.ID
+		if err := tx.Save(upload).Error; err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			assembled = append(assembled, chunk.Sequence)
+		}
+		return tx.Where("upload_id = ?", uploadID).Delete(&models.ConfigUploadChunk{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.deleteChunks(uploadID, assembled)
+	return configFile, nil
+}
+
+// AbortUpload drops an upload and the chunks it received
+func (s *ConfigUploadService) AbortUpload(companyID, uploadID uuid.UUID) error {
+	var received []int
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND company_id = ?", uploadID, companyID).Delete(&models.ConfigUpload{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConfigUploadNotFound
+		}
+		var err error
+		received, err = s.dropChunks(tx, uploadID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.deleteChunks(uploadID, received)
+	return nil
+}
+
+func (s *ConfigUploadService) findUpload(db *gorm.DB, companyID, uploadID uuid.UUID) (*models.ConfigUpload, error) {
+	var upload models.ConfigUpload
+	err := db.Where("id = ? AND company_id = ? AND expires_at >= ?", uploadID, companyID, time.Now()).Take(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrConfigUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (s *ConfigUploadService) receivedSequences(db *gorm.DB, upload *models.ConfigUpload) ([]int, error) {
+	var received []int
+	err := db.Model(&models.ConfigUploadChunk{}).Where("upload_id = ?", upload.ID).Order("sequence").Pluck("sequence", &received).Error
+	return received, err
+}
+
+// dropChunks deletes the rows of an upload's chunks, returning their
+// sequences so their content can be deleted once the transaction commits
+func (s *ConfigUploadService) dropChunks(tx *gorm.DB, uploadID uuid.UUID) ([]int, error) {
+	var sequences []int
+	if err := tx.Model(&models.ConfigUploadChunk{}).Where("upload_id = ?", uploadID).Pluck("sequence", &sequences).Error; err != nil {
+		return nil, err
+	}
+	return sequences, tx.Where("upload_id = ?", uploadID).Delete(&models.ConfigUploadChunk{}).Error
+}
+
+// readChunks fills in the content of chunks from the blob store, checking
+// each against the size it was received with
+func (s *ConfigUploadService) readChunks(chunks []models.ConfigUploadChunk) error {
+	for i := range chunks {
+		data, err := s.store.GetObject(context.Background(), chunkKey(chunks[i].UploadID, chunks[i].Sequence))
+		if err != nil {
+			return fmt.Errorf("failed to read upload chunk %d: %w", chunks[i].Sequence, err)
+		}
+		if int64(len(data)) != chunks[i].Size {
+			return fmt.Errorf("upload chunk %d is %d bytes in storage, not %d", chunks[i].Sequence, len(data), chunks[i].Size)
+		}
+		chunks[i].Data = data
+	}
+	return nil
+}
+
+// deleteChunks removes the content of an upload's chunks from the blob
+// store. Failures are only logged: the chunks' rows are already gone.
+func (s *ConfigUploadService) deleteChunks(uploadID uuid.UUID, sequences []int) {
+	for _, sequence := range sequences {
+		if err := s.store.DeleteObject(context.Background(), chunkKey(uploadID, sequence)); err != nil {
+			log.Printf("Failed to delete chunk %d of config upload %s: %v", sequence, uploadID, err)
+		}
+	}
+}
+
+// chunkKey is the blob store key of an upload's chunk
+func chunkKey(uploadID uuid.UUID, sequence int) string {
+	return "config-uploads/" + uploadID.String() + "/" + strconv.Itoa(sequence)
+}
+
+// sweepExpired drops uploads, complete or not, that have not received a
+// chunk within the timeout, with their chunks
+func (s *ConfigUploadService) sweepExpired(now time.Time) {
+	var expired []models.ConfigUpload
+	if err := s.db.Select("id", "company_id", "filename", "status", "received_chunks", "total_chunks").
+		Where("expires_at < ?", now).Find(&expired).Error; err != nil {
+		log.Printf("Failed to find expired config uploads: %v", err)
+		return
+	}
+
+	for _, upload := range expired {
+		dropped := false
+		var received []int
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// Recheck the expiry in case a chunk arrived since the upload was listed
+			result := tx.Where("id = ? AND expires_at < ?", upload.ID, now).Delete(&models.ConfigUpload{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			dropped = true
+			var err error
+			received, err = s.dropChunks(tx, upload.ID)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to drop expired config upload %s: %v", upload.ID, err)
+			continue
+		}
+		s.deleteChunks(upload.ID, received)
+		if dropped && upload.Status != models.ConfigUploadComplete {
+			log.Printf("Dropped abandoned upload of %s for company %s (%d of %d chunks received)", upload.Filename, upload.CompanyID, upload.ReceivedChunks, upload.TotalChunks)
+		}
+	}
+}
+
+// chunkSequence returns the sequence of the chunk at offset, checking that
+// the offset starts a chunk and the chunk has that chunk's size
+func chunkSequence(upload *models.ConfigUpload, offset, size int64) (int, error) {
+	if offset < 0 || offset >= upload.Size || offset%upload.ChunkSize != 0 {
+		return 0, fmt.Errorf("%w: offset must be a multiple of %d below %d, got %d", ErrInvalidUploadChunk, upload.ChunkSize, upload.Size, offset)
+	}
+	want := upload.ChunkSize
+	if remaining := upload.Size - offset; remaining < want {
+		want = remaining // The last chunk
+	}
+	if size != want {
+		return 0, fmt.Errorf("%w: chunk at offset %d must be %d bytes, got %d", ErrInvalidUploadChunk, offset, want, size)
+	}
+	return int(offset / upload.ChunkSize), nil
+}
+
+// assembleUpload joins an upload's chunks, in sequence order, and checks the
+// file against the upload's size and checksum
+func assembleUpload(upload *models.ConfigUpload, chunks []models.ConfigUploadChunk) ([]byte, error) {
+	var content bytes.Buffer
+	content.Grow(int(upload.Size))
+	for i, chunk := range chunks {
+		if chunk.Sequence != i {
+			return nil, fmt.Errorf("%w: chunk at offset %d is missing", ErrConfigUploadIncomplete, int64(i)*upload.ChunkSize)
+		}
+		content.Write(chunk.Data)
+	}
+	if int64(content.Len()) != upload.Size {
+		return nil, fmt.Errorf("%w: %d of %d bytes received", ErrConfigUploadIncomplete, content.Len(), upload.Size)
+	}
+
+	if got := sha256Hex(content.Bytes()); got != upload.Checksum {
+		return nil, fmt.Errorf("%w: the file has SHA-256 %s, not %s", ErrConfigUploadChecksum, got, upload.Checksum)
+	}
+	return content.Bytes(), nil
+}
+
+// uploadStatus reports an upload's progress given the sequences of the
+// chunks it received; a complete upload is missing nothing
+func uploadStatus(upload *models.ConfigUpload, received []int) *models.ConfigUploadStatus {
+	status := &models.ConfigUploadStatus{ConfigUpload: upload, Missing: []int64{}}
+	if upload.Status == models.ConfigUploadComplete {
+		return status
+	}
+	next := 0
+	for sequence := 0; sequence < upload.TotalChunks; sequence++ {
+		if next < len(received) && received[next] == sequence {
+			next++
+			continue
+		}
+		status.Missing = append(status.Missing, int64(sequence)*upload.ChunkSize)
+	}
+	return status
+}
+
+// parseSHA256 normalizes a hex SHA-256 digest
+func parseSHA256(checksum string) (string, error) {
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+		return "", errors.New("must be a hex SHA-256 digest")
+	}
+	return checksum, nil
+}
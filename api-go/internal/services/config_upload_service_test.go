@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/storage"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkSequence(t *testing.T) {
+	upload := &models.ConfigUpload{Size: 10, ChunkSize: 4, TotalChunks: 3}
+
+	sequence, err := chunkSequence(upload, 4, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sequence)
+	sequence, err = chunkSequence(upload, 8, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sequence, "the last chunk is only what is left of the file")
+
+	for _, bad := range []struct{ offset, size int64 }{
+		{2, 4},  // Not at a chunk boundary
+		{12, 4}, // Past the end
+		{-4, 4},
+		{0, 3}, // Short
+		{8, 4}, // The last chunk must not pad the file
+	} {
+		_, err := chunkSequence(upload, bad.offset, bad.size)
+		assert.ErrorIs(t, err, ErrInvalidUploadChunk, "offset %d size %d", bad.offset, bad.size)
+	}
+}
+
+func TestAssembleUpload(t *testing.T) {
+	upload := &models.ConfigUpload{Size: 10, ChunkSize: 4, TotalChunks: 3, Checksum: sha256Hex([]byte("hostname a"))}
+	chunks := []models.ConfigUploadChunk{
+		{Sequence: 0, Data: []byte("host")},
+		{Sequence: 1, Data: []byte("name")},
+		{Sequence: 2, Data: []byte(" a")},
+	}
+
+	content, err := assembleUpload(upload, chunks)
+	require.NoError(t, err)
+	assert.Equal(t, "hostname a", string(content))
+
+	_, err = assembleUpload(upload, []models.ConfigUploadChunk{chunks[0], chunks[2]})
+	assert.ErrorIs(t, err, ErrConfigUploadIncomplete)
+
+	// Each chunk matched its own checksum, but the file isn't the one announced
+	upload.Checksum = sha256Hex([]byte("hostname b"))
+	_, err = assembleUpload(upload, chunks)
+	assert.ErrorIs(t, err, ErrConfigUploadChecksum)
+}
+
+func TestUploadStatusListsMissingOffsets(t *testing.T) {
+	upload := &models.ConfigUpload{Size: 10, ChunkSize: 4, TotalChunks: 3, Status: models.ConfigUploadReceiving}
+
+	assert.Equal(t, []int64{0, 4, 8}, uploadStatus(upload, nil).Missing)
+	assert.Equal(t, []int64{4}, uploadStatus(upload, []int{0, 2}).Missing)
+	assert.Empty(t, uploadStatus(upload, []int{0, 1, 2}).Missing)
+
+	upload.Status = models.ConfigUploadComplete
+	assert.Empty(t, uploadStatus(upload, nil).Missing)
+}
+
+func TestParseSHA256(t *testing.T) {
+	checksum, err := parseSHA256(" " + "AB" + sha256Hex([]byte("x"))[2:] + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, "ab"+sha256Hex([]byte("x"))[2:], checksum)
+
+	for _, bad := range []string{"", "abc", sha256Hex([]byte("x"))[:62], "zz" + sha256Hex([]byte("x"))[2:]} {
+		_, err := parseSHA256(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestConfigUploadKeepsChunksInBlobStore(t *testing.T) {
+	db := testdb.Open(t, &models.ConfigUpload{}, &models.ConfigUploadChunk{})
+	store, err := storage.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	uploads := NewConfigUploadService(db, nil, store, &config.Config{ConfigUploadChunkSize: 4, ConfigAuditorMaxFileSize: 16, ConfigUploadTimeout: time.Hour})
+	companyID := uuid.New()
+
+	start := func() uuid.UUID {
+		status, err := uploads.InitUpload(companyID, nil, "fw.conf", models.InitConfigUploadRequest{Size: 10, Checksum: sha256Hex([]byte("hostname a"))})
+		require.NoError(t, err)
+		return status.ID
+	}
+	send := func(uploadID uuid.UUID, offset int64, data string) {
+		_, _, err := uploads.UploadChunk(companyID, uploadID, offset, sha256Hex([]byte(data)), []byte(data))
+		require.NoError(t, err)
+	}
+	stored := func(uploadID uuid.UUID, sequence int) bool {
+		_, err := store.GetObject(context.Background(), chunkKey(uploadID, sequence))
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	uploadID := start()
+	send(uploadID, 0, "host")
+	send(uploadID, 8, " a")
+	send(uploadID, 4, "name")
+	status, duplicate, err := uploads.UploadChunk(companyID, uploadID, 4, sha256Hex([]byte("name")), []byte("name"))
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+	assert.Empty(t, status.Missing)
+	_, _, err = uploads.UploadChunk(companyID, uploadID, 4, sha256Hex([]byte("nope")), []byte("nope"))
+	assert.ErrorIs(t, err, ErrUploadChunkConflict)
+
+	// The database only records the chunks; their content is read back from the store
+	var chunks []models.ConfigUploadChunk
+	require.NoError(t, db.Where("upload_id = ?", uploadID).Order("sequence").Find(&chunks).Error)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, int64(2), chunks[2].Size)
+	require.NoError(t, uploads.readChunks(chunks))
+	content, err := assembleUpload(&models.ConfigUpload{Size: 10, ChunkSize: 4, Checksum: sha256Hex([]byte("hostname a"))}, chunks)
+	require.NoError(t, err)
+	assert.Equal(t, "hostname a", string(content))
+
+	// Other companies can't touch the upload
+	assert.ErrorIs(t, uploads.AbortUpload(uuid.New(), uploadID), ErrConfigUploadNotFound)
+	assert.True(t, stored(uploadID, 0))
+
+	require.NoError(t, uploads.AbortUpload(companyID, uploadID))
+	for sequence := 0; sequence < 3; sequence++ {
+		assert.False(t, stored(uploadID, sequence), "aborting drops chunk %d from the store", sequence)
+	}
+
+	// Expired uploads lose their chunks too
+	expiredID := start()
+	send(expiredID, 0, "host")
+	uploads.sweepExpired(time.Now().Add(2 * time.Hour))
+	assert.False(t, stored(expiredID, 0))
+	var remaining int64
+	require.NoError(t, db.Model(&models.ConfigUploadChunk{}).Count(&remaining).Error)
+	assert.Zero(t, remaining)
+}
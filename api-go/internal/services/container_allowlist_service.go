@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrAllowlistRuleNotFound is returned when an allowlist rule is unknown or belongs to another organization
+var ErrAllowlistRuleNotFound = errors.New("allowlist rule not found")
+
+// ContainerAllowlistService manages each organization's allowlist of expected
+// container findings, auditing every change to it
+type ContainerAllowlistService struct {
+	db           *gorm.DB
+	agentService *AgentService
+}
+
+// NewContainerAllowlistService creates a new container allowlist service
+func NewContainerAllowlistService(db *gorm.DB, agentService *AgentService) *ContainerAllowlistService {
+	return &ContainerAllowlistService{
+		db:           db,
+		agentService: agentService,
+	}
+}
+
+// ListRules lists an organization's allowlist rules, oldest first
+func (s *ContainerAllowlistService) ListRules(organizationID uuid.UUID) ([]models.ContainerAllowlistRule, error) {
+	var rules []models.ContainerAllowlistRule
+	err := s.db.Where("organization_id = ?", organizationID).Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+// ListRulesForAgent lists the allowlist rules of the agent's organization
+func (s *ContainerAllowlistService) ListRulesForAgent(agentID uuid.UUID) ([]models.ContainerAllowlistRule, error) {
+	agent, exists := s.agentService.GetAgent(agentID)
+	if !exists {
+		return nil, ErrAgentNotFound
+	}
+	return s.ListRules(agent.OrganizationID)
+}
+
+// CreateRule adds a rule to an organization's allowlist
+func (s *ContainerAllowlistService) CreateRule(organizationID uuid.UUID, req *models.ContainerAllowlistRuleRequest) (*models.ContainerAllowlistRule, error) {
+	if err := validateAllowlistRule(req); err != nil {
+		return nil, err
+	}
+
+	rule := &models.ContainerAllowlistRule{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		CreatedBy:      req.Actor,
+	}
+	applyAllowlistRequest(rule, req)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return fmt.Errorf("failed to create allowlist rule: %w", err)
+		}
+		return recordAllowlistChange(tx, rule, models.ContainerAllowlistCreated, req.Actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces an allowlist rule's matchers and reason
+func (s *ContainerAllowlistService) UpdateRule(organizationID, ruleID uuid.UUID, req *models.ContainerAllowlistRuleRequest) (*models.ContainerAllowlistRule, error) {
+	if err := validateAllowlistRule(req); err != nil {
+		return nil, err
+	}
+
+	var rule models.ContainerAllowlistRule
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findAllowlistRule(tx, organizationID, ruleID, &rule); err != nil {
+			return err
+		}
+		applyAllowlistRequest(&rule, req)
+		if err := tx.Save(&rule).Error; err != nil {
+			return fmt.Errorf("failed to update allowlist rule: %w", err)
+		}
+		return recordAllowlistChange(tx, &rule, models.ContainerAllowlistUpdated, req.Actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteRule removes a rule from an organization's allowlist
+func (s *ContainerAllowlistService) DeleteRule(organizationID, ruleID uuid.UUID, actor string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var rule models.ContainerAllowlistRule
+		if err := findAllowlistRule(tx, organizationID, ruleID, &rule); err != nil {
+			return err
+		}
+		if err := tx.Delete(&rule).Error; err != nil {
+			return fmt.Errorf("failed to delete allowlist rule: %w", err)
+		}
+		return recordAllowlistChange(tx, &rule, models.ContainerAllowlistDeleted, actor)
+	})
+}
+
+// GetAuditLog lists the changes to an organization's allowlist, newest first
+func (s *ContainerAllowlistService) GetAuditLog(organizationID uuid.UUID, limit int) ([]models.ContainerAllowlistAudit, error) {
+	var entries []models.ContainerAllowlistAudit
+	err := s.db.Where("organization_id = ?", organizationID).Order("created_at DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+func findAllowlistRule(tx *gorm.DB, organizationID, ruleID uuid.UUID, rule *models.ContainerAllowlistRule) error {
+	err := tx.Where("id = ? AND organization_id = ?", ruleID, organizationID).First(rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAllowlistRuleNotFound
+	}
+	return err
+}
+
+func recordAllowlistChange(tx *gorm.DB, rule *models.ContainerAllowlistRule, action, actor string) error {
+	entry := &models.ContainerAllowlistAudit{
+		ID:             uuid.New(),
+		OrganizationID: rule.OrganizationID,
+		RuleID:         rule.ID,
+		Action:         action,
+		Actor:          actor,
+		Rule:           *rule,
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to audit allowlist change: %w", err)
+	}
+	return nil
+}
+
+func applyAllowlistRequest(rule *models.ContainerAllowlistRule, req *models.ContainerAllowlistRuleRequest) {
+	rule.Image = strings.TrimSpace(req.Image)
+	rule.ContainerName = strings.TrimSpace(req.ContainerName)
+	rule.FindingType = strings.ToLower(strings.TrimSpace(req.FindingType))
+	rule.Title = strings.TrimSpace(req.Title)
+	rule.Reason = strings.TrimSpace(req.Reason)
+	rule.UpdatedBy = req.Actor
+}
+
+// validateAllowlistRule rejects rules without a reason, rules that would match
+// every finding, and malformed glob patterns
+func validateAllowlistRule(req *models.ContainerAllowlistRuleRequest) error {
+	if strings.TrimSpace(req.Reason) == "" {
+		return fmt.Errorf("allowlist rule must give a reason")
+	}
+	image, name := strings.TrimSpace(req.Image), strings.TrimSpace(req.ContainerName)
+	if image == "" && name == "" && strings.TrimSpace(req.FindingType) == "" && strings.TrimSpace(req.Title) == "" {
+		return fmt.Errorf("allowlist rule must match on image, container name, finding type or title")
+	}
+	for field, pattern := range map[string]string{"image": image, "container_name": name} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid %s pattern %q: %w", field, pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"zerotrace/api/internal/cvss"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// cvssRequirementKeys are the organization profile risk weights holding its
+// confidentiality, integrity and availability requirements
+var cvssRequirementKeys = [3]string{"CR", "IR", "AR"}
+
+// cvssRequirements returns an organization's security requirements from its
+// profile's CR, IR and AR risk weights. Organizations without a profile, or
+// whose profile doesn't set them, have none.
+func cvssRequirements(db *gorm.DB, organizationID uuid.UUID) (cvss.Requirements, error) {
+	var profile models.OrganizationProfile
+	err := db.Where("organization_id = ?", organizationID).First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return cvss.Requirements{}, nil
+	}
+	if err != nil {
+		return cvss.Requirements{}, fmt.Errorf("failed to load organization profile: %w", err)
+	}
+	return requirementsFromRiskWeights(profile.RiskWeights), nil
+}
+
+// requirementsFromRiskWeights reads the CR, IR and AR risk weights, each
+// L/M/H or low/medium/high in any case. Other values are ignored.
+func requirementsFromRiskWeights(weights map[string]any) cvss.Requirements {
+	var levels [3]string
+	for key, value := range weights {
+		for i, metric := range cvssRequirementKeys {
+			if !strings.EqualFold(key, metric) {
+				continue
+			}
+			level, _ := value.(string)
+			switch strings.ToUpper(strings.TrimSpace(level)) {
+			case "L", "LOW":
+				levels[i] = cvss.RequirementLow
+			case "M", "MEDIUM":
+				levels[i] = cvss.RequirementMedium
+			case "H", "HIGH":
+				levels[i] = cvss.RequirementHigh
+			}
+		}
+	}
+	return cvss.Requirements{Confidentiality: levels[0], Integrity: levels[1], Availability: levels[2]}
+}
+
+// scoreCVSS sets the base and environmental scores of findings from their
+// CVSS vectors. Findings without a vector, with a malformed one, or with one
+// that isn't scored (v4.0) keep the base score they were reported with, which
+// is also their environmental score.
+func scoreCVSS(findings []models.Vulnerability, req cvss.Requirements) {
+	for i := range findings {
+		finding := &findings[i]
+		finding.EnvironmentalScore = nil
+		if finding.CVSSScore != nil {
+			score := *finding.CVSSScore
+			finding.EnvironmentalScore = &score
+		}
+
+		if finding.CVSSVector == "" {
+			continue
+		}
+		vector, err := cvss.Parse(finding.CVSSVector)
+		if err != nil {
+			log.Printf("Ignoring the CVSS vector of finding %s: %v", finding.ID, err)
+			continue
+		}
+		if score, ok := vector.BaseScore(); ok {
+			finding.CVSSScore = &score
+			finding.EnvironmentalScore = &score
+		}
+		if score, ok := vector.EnvironmentalScore(req); ok {
+			finding.EnvironmentalScore = &score
+		}
+	}
+}
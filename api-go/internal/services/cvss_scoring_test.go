@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/cvss"
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirementsFromRiskWeights(t *testing.T) {
+	req := requirementsFromRiskWeights(map[string]any{
+		"critical": 1.0,
+		"CR":       "high",
+		"ir":       "L",
+		"AR":       0.5, // Not a level
+	})
+	assert.Equal(t, cvss.Requirements{Confidentiality: cvss.RequirementHigh, Integrity: cvss.RequirementLow}, req)
+	assert.Equal(t, cvss.Requirements{}, requirementsFromRiskWeights(nil))
+}
+
+func TestScoreCVSS(t *testing.T) {
+	reported := 7.5
+	findings := []models.Vulnerability{
+		{ID: "v31", CVSSScore: &reported, CVSSVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+		{ID: "malformed", CVSSScore: &reported, CVSSVector: "CVSS:3.1/AV:N/AC:L"},
+		{ID: "v40", CVSSScore: &reported, CVSSVector: "CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N"},
+		{ID: "no-vector", CVSSScore: &reported},
+		{ID: "unscored"},
+	}
+
+	scoreCVSS(findings, cvss.Requirements{Confidentiality: "L", Integrity: "L", Availability: "L"})
+
+	// The base score is recomputed from the vector
+	assert.Equal(t, 9.8, *findings[0].CVSSScore)
+	assert.Equal(t, 8.0, *findings[0].EnvironmentalScore)
+	for _, finding := range findings[1:4] {
+		assert.Equal(t, 7.5, *finding.CVSSScore, finding.ID)
+		assert.Equal(t, 7.5, *finding.EnvironmentalScore, finding.ID)
+	}
+	assert.Nil(t, findings[4].EnvironmentalScore)
+	assert.Equal(t, 7.5, reported, "the reported score is not overwritten")
+}
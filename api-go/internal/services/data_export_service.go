@@ -0,0 +1,510 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/repository"
+	"zerotrace/api/internal/secrets"
+	"zerotrace/api/internal/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Data export triggers
+const (
+	DataExportTriggerScheduled = "scheduled"
+	DataExportTriggerManual    = "manual"
+)
+
+var (
+	// ErrExportInProgress is returned when an organization already has an export running
+	ErrExportInProgress = errors.New("an export is already running for this organization")
+	// ErrInvalidExportEndpoint is returned for a bucket endpoint on the API's own network
+	ErrInvalidExportEndpoint = errors.New("endpoint must be an absolute http or https URL on a public host")
+)
+
+// DataExportService periodically exports each organization's findings, assets
+// and reports to the organization's own S3-compatible bucket.
+//
+// Every export is written under
+//
+//	<path_prefix>/<organization_id>/<YYYYMMDDTHHMMSSZ>-<mode>/
+//
+// as newline-delimited JSON files plus a manifest.json listing each file's
+// record count and SHA-256, and a manifest.json.sha256 checksum of the
+// manifest itself. The manifest is written last so its presence marks the
+// export as complete. See docs/DATA_EXPORT.md for the full layout.
+type DataExportService struct {
+	repo          *repository.DataExportRepository
+	cipher        *secrets.Cipher // Encrypts bucket secrets at rest
+	newStore      func(storage.S3Config) (storage.ObjectStore, error)
+	checkInterval time.Duration
+	minFrequency  time.Duration
+
+	mu      sync.Mutex
+	running map[uuid.UUID]bool
+
+	ctx      context.Context // Exports run under it, cancelled by Stop
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDataExportService creates a new data export service. Bucket secrets are
+// encrypted with SECRET_ENCRYPTION_KEY; without it, exports can't be configured.
+func NewDataExportService(repo *repository.DataExportRepository, cfg *config.Config) *DataExportService {
+	cipher, err := secrets.NewCipher(cfg.SecretEncryptionKey)
+	if err != nil {
+		log.Printf("Data export secrets can't be encrypted: %v", err)
+	}
+
+	checkInterval := cfg.DataExportCheckInterval
+	if checkInterval <= 0 {
+		checkInterval = time.Minute
+	}
+
+	// Buckets are the organizations' own, so uploads may only reach public addresses
+	httpClient := newCallbackClient(5 * time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DataExportService{
+		repo:   repo,
+		cipher: cipher,
+		newStore: func(c storage.S3Config) (storage.ObjectStore, error) {
+			c.HTTPClient = httpClient
+			return storage.NewS3Store(c)
+		},
+		checkInterval: checkInterval,
+		minFrequency:  cfg.DataExportMinFrequency,
+		running:       make(map[uuid.UUID]bool),
+		ctx:           ctx,
+		cancel:        cancel,
+		stopChan:      make(chan struct{}),
+	}
+}
+
+// Start launches the scheduler loop
+func (s *DataExportService) Start() {
+	s.wg.Add(1)
+	go s.scheduler()
+}
+
+// Stop stops the scheduler, cancels in-flight exports and waits for them to
+// record their outcome
+func (s *DataExportService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+		s.cancel()
+	})
+	s.wg.Wait()
+	log.Println("Data export scheduler stopped")
+}
+
+// GetConfig retrieves an organization's export configuration
+func (s *DataExportService) GetConfig(organizationID uuid.UUID) (*models.DataExportConfig, error) {
+	return s.repo.GetConfig(organizationID)
+}
+
+// UpdateConfig validates and stores an organization's export configuration
+func (s *DataExportService) UpdateConfig(organizationID uuid.UUID, req *models.UpdateDataExportConfigRequest) (*models.DataExportConfig, error) {
+	frequency, err := time.ParseDuration(req.Frequency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid frequency %q: %w", req.Frequency, err)
+	}
+	if s.minFrequency > 0 && frequency < s.minFrequency {
+		return nil, fmt.Errorf("frequency must be at least %s", s.minFrequency)
+	}
+	if req.Endpoint != "" && checkCallbackURL(req.Endpoint) != nil {
+		return nil, ErrInvalidExportEndpoint
+	}
+
+	cfg, err := s.repo.GetConfig(organizationID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		cfg = &models.DataExportConfig{OrganizationID: organizationID}
+	}
+
+	cfg.Enabled = req.Enabled
+	cfg.Frequency = req.Frequency
+	cfg.Incremental = req.Incremental
+	cfg.Endpoint = req.Endpoint
+	cfg.Region = req.Region
+	cfg.Bucket = req.Bucket
+	cfg.PathPrefix = strings.Trim(req.PathPrefix, "/")
+	cfg.UsePathStyle = req.UsePathStyle
+	cfg.AccessKeyID = req.AccessKeyID
+	if req.SecretAccessKey != "" {
+		sealed, err := s.cipher.Encrypt(req.SecretAccessKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt secret access key: %w", err)
+		}
+		cfg.SecretAccessKey = sealed
+	} else if cfg.SecretAccessKey != "" && !secrets.IsEncrypted(cfg.SecretAccessKey) {
+		// Seal secrets stored before encryption was introduced
+		sealed, err := s.cipher.Encrypt(cfg.SecretAccessKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt secret access key: %w", err)
+		}
+		cfg.SecretAccessKey = sealed
+	}
+
+	// Validate the destination before saving so misconfigurations surface immediately
+	s3Config, err := s.s3ConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.newStore(s3Config); err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+
+	if cfg.Enabled && cfg.NextExportAt == nil {
+		next := time.Now()
+		cfg.NextExportAt = &next
+	}
+
+	if err := s.repo.SaveConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ListRuns retrieves recent export runs for an organization
+func (s *DataExportService) ListRuns(organizationID uuid.UUID, limit int) ([]models.DataExportRun, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.repo.ListRuns(organizationID, limit)
+}
+
+// TriggerExport starts an export for an organization immediately
+func (s *DataExportService) TriggerExport(organizationID uuid.UUID) (*models.DataExportRun, error) {
+	cfg, err := s.repo.GetConfig(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.claim(organizationID) {
+		return nil, ErrExportInProgress
+	}
+
+	run := s.newRun(cfg, DataExportTriggerManual)
+	if err := s.repo.CreateRun(run); err != nil {
+		s.unclaim(organizationID)
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.unclaim(organizationID)
+		s.execute(s.ctx, cfg, run)
+	}()
+
+	return run, nil
+}
+
+// scheduler checks for due exports on every tick
+func (s *DataExportService) scheduler() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	log.Printf("Data export scheduler started (interval %s)", s.checkInterval)
+
+	for {
+		select {
+		case <-ticker.C:
+			s.runDueExports()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// runDueExports runs every export whose next scheduled time has passed
+func (s *DataExportService) runDueExports() {
+	configs, err := s.repo.GetDueConfigs(time.Now())
+	if err != nil {
+		log.Printf("Failed to load due data exports: %v", err)
+		return
+	}
+
+	for i := range configs {
+		cfg := &configs[i]
+		if !s.claim(cfg.OrganizationID) {
+			continue
+		}
+
+		run := s.newRun(cfg, DataExportTriggerScheduled)
+		if err := s.repo.CreateRun(run); err != nil {
+			log.Printf("Failed to record data export run for org %s: %v", cfg.OrganizationID, err)
+			s.unclaim(cfg.OrganizationID)
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.unclaim(cfg.OrganizationID)
+			s.execute(s.ctx, cfg, run)
+		}()
+	}
+}
+
+// newRun prepares a run record, choosing incremental mode when a previous export succeeded
+func (s *DataExportService) newRun(cfg *models.DataExportConfig, trigger string) *models.DataExportRun {
+	now := time.Now().UTC()
+	run := &models.DataExportRun{
+		ID:             uuid.New(),
+		OrganizationID: cfg.OrganizationID,
+		Mode:           models.DataExportModeFull,
+		Trigger:        trigger,
+		Status:         models.DataExportStatusRunning,
+		Until:          now,
+		StartedAt:      now,
+	}
+	if cfg.Incremental && cfg.LastSuccessAt != nil {
+		since := cfg.LastSuccessAt.UTC()
+		run.Mode = models.DataExportModeIncremental
+		run.Since = &since
+	}
+
+	run.ObjectPrefix = path.Join(cfg.PathPrefix, cfg.OrganizationID.String(), now.Format("20060102T150405Z")+"-"+run.Mode)
+	return run
+}
+
+// execute performs an export run and records its outcome on both the run and the config
+func (s *DataExportService) execute(ctx context.Context, cfg *models.DataExportConfig, run *models.DataExportRun) {
+	err := s.export(ctx, cfg, run)
+
+	completed := time.Now().UTC()
+	run.CompletedAt = &completed
+	cfg.LastExportAt = &completed
+	if err != nil {
+		run.Status = models.DataExportStatusFailed
+		run.Error = err.Error()
+		cfg.LastExportError = err.Error()
+		log.Printf("Data export %s for org %s failed: %v", run.ID, cfg.OrganizationID, err)
+	} else {
+		run.Status = models.DataExportStatusCompleted
+		cfg.LastExportError = ""
+		// Incremental exports resume from the snapshot time, not the completion time,
+		// so records changed while the export was running are picked up next time
+		until := run.Until
+		cfg.LastSuccessAt = &until
+		log.Printf("Data export %s for org %s completed (%d bytes)", run.ID, cfg.OrganizationID, run.BytesWritten)
+	}
+	cfg.LastExportStatus = run.Status
+
+	if frequency, perr := time.ParseDuration(cfg.Frequency); perr == nil && frequency > 0 {
+		next := completed.Add(frequency)
+		cfg.NextExportAt = &next
+	}
+
+	if err := s.repo.UpdateRun(run); err != nil {
+		log.Printf("Failed to update data export run %s: %v", run.ID, err)
+	}
+	if err := s.repo.SaveConfig(cfg); err != nil {
+		log.Printf("Failed to update data export config for org %s: %v", cfg.OrganizationID, err)
+	}
+}
+
+// export collects the organization's data and uploads data files followed by the manifest
+func (s *DataExportService) export(ctx context.Context, cfg *models.DataExportConfig, run *models.DataExportRun) error {
+	s3Config, err := s.s3ConfigFor(cfg)
+	if err != nil {
+		return err
+	}
+	store, err := s.newStore(s3Config)
+	if err != nil {
+		return fmt.Errorf("invalid destination: %w", err)
+	}
+
+	files, err := s.collect(cfg.OrganizationID, run.Since, run.Until)
+	if err != nil {
+		return fmt.Errorf("failed to collect export data: %w", err)
+	}
+
+	manifest := models.DataExportManifest{
+		SchemaVersion:  models.DataExportSchemaVersion,
+		ExportID:       run.ID,
+		OrganizationID: cfg.OrganizationID,
+		Mode:           run.Mode,
+		Since:          run.Since,
+		Until:          run.Until,
+		Files:          make([]models.DataExportManifestFile, 0, len(files)),
+	}
+	run.RecordCounts = make(map[string]int, len(files))
+
+	for _, f := range files {
+		key := path.Join(run.ObjectPrefix, f.name)
+		if err := store.PutObject(ctx, key, f.body, "application/x-ndjson"); err != nil {
+			return err
+		}
+
+		manifest.Files = append(manifest.Files, models.DataExportManifestFile{
+			Name:        f.name,
+			Key:         key,
+			RecordType:  f.recordType,
+			RecordCount: f.count,
+			SizeBytes:   int64(len(f.body)),
+			SHA256:      sha256Hex(f.body),
+		})
+		run.RecordCounts[f.recordType] += f.count
+		run.BytesWritten += int64(len(f.body))
+	}
+
+	manifest.GeneratedAt = time.Now().UTC()
+	manifestBody, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	manifestKey := path.Join(run.ObjectPrefix, "manifest.json")
+	if err := store.PutObject(ctx, manifestKey, manifestBody, "application/json"); err != nil {
+		return err
+	}
+	checksum := []byte(sha256Hex(manifestBody) + "  manifest.json\n")
+	if err := store.PutObject(ctx, manifestKey+".sha256", checksum, "text/plain"); err != nil {
+		return err
+	}
+
+	run.ManifestKey = manifestKey
+	run.BytesWritten += int64(len(manifestBody) + len(checksum))
+	return nil
+}
+
+// exportFile is one NDJSON data file in an export
+type exportFile struct {
+	name       string
+	recordType string
+	count      int
+	body       []byte
+}
+
+// collect loads every exported record type for an organization and encodes it as NDJSON
+func (s *DataExportService) collect(organizationID uuid.UUID, since *time.Time, until time.Time) ([]exportFile, error) {
+	vulns, err := s.repo.FindVulnerabilities(organizationID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := s.repo.FindAgents(organizationID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := s.repo.FindNetworkHosts(organizationID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	software, err := s.repo.FindSoftware(organizationID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.repo.FindDashboardSnapshots(organizationID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []exportFile
+	add := func(name, recordType string, records []any) error {
+		body, err := encodeNDJSON(records)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		files = append(files, exportFile{name: name, recordType: recordType, count: len(records), body: body})
+		return nil
+	}
+
+	if err := add("findings/vulnerabilities.ndjson", "vulnerability", toAnySlice(vulns)); err != nil {
+		return nil, err
+	}
+	if err := add("assets/agents.ndjson", "agent", toAnySlice(agents)); err != nil {
+		return nil, err
+	}
+	if err := add("assets/network_hosts.ndjson", "network_host", toAnySlice(hosts)); err != nil {
+		return nil, err
+	}
+	if err := add("assets/software.ndjson", "software", toAnySlice(software)); err != nil {
+		return nil, err
+	}
+	if err := add("reports/dashboard_snapshots.ndjson", "dashboard_snapshot", toAnySlice(snapshots)); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// claim marks an organization's export as running; it returns false if one already is
+func (s *DataExportService) claim(organizationID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[organizationID] {
+		return false
+	}
+	s.running[organizationID] = true
+	return true
+}
+
+func (s *DataExportService) unclaim(organizationID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, organizationID)
+}
+
+// s3ConfigFor builds the object store configuration from an export
+// configuration, decrypting its stored secret
+func (s *DataExportService) s3ConfigFor(cfg *models.DataExportConfig) (storage.S3Config, error) {
+	secretAccessKey, err := s.cipher.Decrypt(cfg.SecretAccessKey)
+	if err != nil {
+		return storage.S3Config{}, fmt.Errorf("failed to decrypt secret access key: %w", err)
+	}
+	return storage.S3Config{
+		Endpoint:        cfg.Endpoint,
+		Region:          cfg.Region,
+		Bucket:          cfg.Bucket,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: secretAccessKey,
+		UsePathStyle:    cfg.UsePathStyle,
+	}, nil
+}
+
+// encodeNDJSON writes one JSON document per line
+func encodeNDJSON(records []any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func toAnySlice[T any](items []T) []any {
+	out := make([]any, len(items))
+	for i := range items {
+		out[i] = items[i]
+	}
+	return out
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -7,24 +7,63 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
+	"zerotrace/api/internal/config"
 	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
 )
 
-// EnrichmentService handles communication with the Python enrichment service
+// EnrichmentService handles communication with the Python enrichment service.
+// Software is looked up in batches; a package already being looked up for
+// another scan is not requested again, the scans share the one upstream call.
+// That matters when a fleet reports the same popular package at once. The
+// number of requests in flight to the enrichment service is bounded.
+// Findings are also tagged from their organization's own threat intel feeds.
 type EnrichmentService struct {
 	enrichmentURL string
 	httpClient    *http.Client
+	batchSize     int
+	slots         chan struct{}       // Bounds concurrent requests to the enrichment service
+	threatIntel   *ThreatIntelService // Organization feeds; nil when not used
+	epss          *epssClient         // nil when EPSS lookups are off
+
+	mu       sync.Mutex
+	inflight map[string]*enrichmentCall // Software key -> the lookup fetching it
+}
+
+// enrichmentCall is one software item's pending lookup. done is closed once
+// the batch containing the item has been fetched.
+type enrichmentCall struct {
+	done     chan struct{}
+	enriched *EnrichedSoftware // nil when the service returned nothing for the item
+	err      error
 }
 
-// NewEnrichmentService creates a new enrichment service
-func NewEnrichmentService(enrichmentURL string) *EnrichmentService {
+// NewEnrichmentService creates a new enrichment service. threatIntel may be
+// nil, in which case findings are not tagged from organization feeds.
+func NewEnrichmentService(cfg *config.Config, threatIntel *ThreatIntelService) *EnrichmentService {
+	maxConcurrency := cfg.EnrichmentMaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 4
+	}
+	batchSize := cfg.EnrichmentBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
 	return &EnrichmentService{
-		enrichmentURL: enrichmentURL,
+		enrichmentURL: cfg.EnrichmentServiceURL,
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second, // Enrichment can take time
 		},
+		batchSize:   batchSize,
+		slots:       make(chan struct{}, maxConcurrency),
+		threatIntel: threatIntel,
+		epss:        newEPSSClient(cfg),
+		inflight:    make(map[string]*enrichmentCall),
 	}
 }
 
@@ -55,6 +94,8 @@ type CVEData struct {
 	Published   string  `json:"published_date"`
 	Modified    string  `json:"last_modified"`
 	Source      string  `json:"source"`
+
+	FixedVersions []string `json:"fixed_versions,omitempty"` // Versions the CVE is fixed in, once published
 }
 
 // EnrichmentResponse represents the response from the enrichment service
@@ -73,18 +114,120 @@ func (e *EnrichmentService) EnrichDependencies(dependencies []models.Dependency)
 
 	log.Printf("[Enrichment] Starting enrichment for %d dependencies", len(dependencies))
 
-	// Convert dependencies to enrichment request format
+	// Convert dependencies to enrichment request format, once per distinct package
 	software := make([]SoftwareItem, 0, len(dependencies))
+	seen := make(map[string]bool, len(dependencies))
 	for _, dep := range dependencies {
-		log.Printf("[Enrichment] Processing dependency: %s %s", dep.Name, dep.Version)
-		software = append(software, SoftwareItem{
+		item := SoftwareItem{
 			Name:    dep.Name,
 			Version: dep.Version,
 			Vendor:  "", // Dependency model doesn't have vendor field
 			Type:    dep.Type,
-		})
+		}
+		if key := softwareKey(item.Name, item.Version); !seen[key] {
+			seen[key] = true
+			software = append(software, item)
+		}
+	}
+
+	calls, owned := e.claim(software)
+	if len(owned) > 0 {
+		log.Printf("[Enrichment] Fetching %d software items, %d already being fetched", len(owned), len(software)-len(owned))
+		e.fetchAll(owned, calls)
+	}
+
+	// Convert enriched data to vulnerabilities
+	vulnerabilities := []models.Vulnerability{}
+	enrichedCount := 0
+	for _, call := range calls {
+		<-call.done
+		if call.err != nil {
+			return []models.Vulnerability{}, call.err
+		}
+		if call.enriched == nil {
+			continue
+		}
+		enrichedCount++
+		vulnerabilities = append(vulnerabilities, vulnerabilitiesFromEnrichment(call.enriched)...)
+	}
+
+	log.Printf("[Enrichment] Found %d vulnerabilities across %d software items", len(vulnerabilities), enrichedCount)
+
+	return vulnerabilities, nil
+}
+
+// ApplyThreatIntel tags findings with what the organization's threat intel
+// feeds report about their CVEs. A tagged finding counts as known exploited,
+// raising its priority.
+func (e *EnrichmentService) ApplyThreatIntel(organizationID uuid.UUID, findings []models.Vulnerability) {
+	if e.threatIntel == nil {
+		return
+	}
+	e.threatIntel.Annotate(organizationID, findings)
+}
+
+// claim returns the lookup for each item, in order. Items nobody is fetching
+// yet are registered as in flight and returned as owned; the caller must
+// fetch them.
+func (e *EnrichmentService) claim(software []SoftwareItem) ([]*enrichmentCall, []SoftwareItem) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	calls := make([]*enrichmentCall, len(software))
+	owned := []SoftwareItem{}
+	for i, item := range software {
+		key := softwareKey(item.Name, item.Version)
+		call, ok := e.inflight[key]
+		if !ok {
+			call = &enrichmentCall{done: make(chan struct{})}
+			e.inflight[key] = call
+			owned = append(owned, item)
+		}
+		calls[i] = call
 	}
+	return calls, owned
+}
+
+// fetchAll looks up the owned items in batches, at most maxConcurrency
+// requests at a time across all callers, and completes their lookups
+func (e *EnrichmentService) fetchAll(owned []SoftwareItem, calls []*enrichmentCall) {
+	var wg sync.WaitGroup
+	for start := 0; start < len(owned); start += e.batchSize {
+		batch := owned[start:min(start+e.batchSize, len(owned))]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
+			e.slots <- struct{}{}
+			enriched, err := e.fetch(batch)
+			<-e.slots
+
+			byKey := make(map[string]*EnrichedSoftware, len(enriched))
+			for i := range enriched {
+				byKey[softwareKey(enriched[i].Name, enriched[i].Version)] = &enriched[i]
+			}
+
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			for _, item := range batch {
+				key := softwareKey(item.Name, item.Version)
+				call := e.inflight[key]
+				delete(e.inflight, key)
+				call.enriched = byKey[key]
+				call.err = err
+				close(call.done)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// fetch makes one request to the enrichment service. Only an error status
+// from the service is returned as an error; when the service is unreachable
+// or answers with something unusable, no enrichment is returned so the API
+// can continue.
+func (e *EnrichmentService) fetch(software []SoftwareItem) ([]EnrichedSoftware, error) {
 	// Marshal request to JSON
 	jsonData, err := json.Marshal(software)
 	if err != nil {
@@ -98,70 +241,73 @@ func (e *EnrichmentService) EnrichDependencies(dependencies []models.Dependency)
 	resp, err := e.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		log.Printf("[Enrichment] Failed to connect to enrichment service: %v", err)
-		// Return empty vulnerabilities instead of error to allow API to continue
-		return []models.Vulnerability{}, nil
+		return nil, nil
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		log.Printf("[Enrichment] Enrichment service returned status %d: %s", resp.StatusCode, string(body))
-		return []models.Vulnerability{}, fmt.Errorf("enrichment service returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("enrichment service returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	// Parse response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Printf("[Enrichment] Failed to read enrichment response: %v", err)
-		return []models.Vulnerability{}, nil
+		return nil, nil
 	}
 
 	var enrichmentResp EnrichmentResponse
 	if err := json.Unmarshal(body, &enrichmentResp); err != nil {
 		log.Printf("[Enrichment] Failed to parse enrichment response: %v", err)
-		return []models.Vulnerability{}, nil
+		return nil, nil
 	}
 
 	if !enrichmentResp.Success {
 		log.Printf("[Enrichment] Enrichment service returned error: %s", enrichmentResp.Message)
-		return []models.Vulnerability{}, nil
+		return nil, nil
 	}
 
-	// Convert enriched data to vulnerabilities
-	vulnerabilities := []models.Vulnerability{}
+	return enrichmentResp.Data, nil
+}
 
-	for _, enriched := range enrichmentResp.Data {
-		for _, cve := range enriched.CVEs {
-			vuln := models.Vulnerability{
-				ID:             cve.ID,
-				Type:           "cve",
-				Title:          cve.ID,
-				Description:    cve.Description,
-				Severity:       models.SeverityLevel(cve.Severity),
-				CVEID:          cve.ID,
-				CVSSScore:      &cve.CVSSScore,
-				PackageName:    enriched.Name,
-				PackageVersion: enriched.Version,
-				Status:         "open",
-				Priority:       getPriorityFromCVSS(cve.CVSSScore),
-				EnrichmentData: map[string]interface{}{
-					"published_date":   cve.Published,
-					"last_modified":    cve.Modified,
-					"software_name":    enriched.Name,
-					"software_version": enriched.Version,
-					"source":           cve.Source,
-					"cpe_identifier":   enriched.CPEIdentifier,
-					"cpe_confidence":   enriched.CPEConfidence,
-				},
-				CreatedAt: time.Now(),
-			}
-			vulnerabilities = append(vulnerabilities, vuln)
+// vulnerabilitiesFromEnrichment converts one enriched package's CVEs to vulnerabilities
+func vulnerabilitiesFromEnrichment(enriched *EnrichedSoftware) []models.Vulnerability {
+	vulnerabilities := make([]models.Vulnerability, 0, len(enriched.CVEs))
+	for _, cve := range enriched.CVEs {
+		vuln := models.Vulnerability{
+			ID:              cve.ID,
+			Type:            "cve",
+			Title:           cve.ID,
+			Description:     cve.Description,
+			Severity:        models.SeverityLevel(cve.Severity),
+			CVEID:           cve.ID,
+			CVSSScore:       &cve.CVSSScore,
+			PackageName:     enriched.Name,
+			PackageVersion:  enriched.Version,
+			PatchedVersions: cve.FixedVersions,
+			Status:          "open",
+			Priority:        getPriorityFromCVSS(cve.CVSSScore),
+			EnrichmentData: map[string]interface{}{
+				"published_date":   cve.Published,
+				"last_modified":    cve.Modified,
+				"software_name":    enriched.Name,
+				"software_version": enriched.Version,
+				"source":           cve.Source,
+				"cpe_identifier":   enriched.CPEIdentifier,
+				"cpe_confidence":   enriched.CPEConfidence,
+			},
+			CreatedAt: time.Now(),
 		}
+		vulnerabilities = append(vulnerabilities, vuln)
 	}
+	return vulnerabilities
+}
 
-	log.Printf("[Enrichment] Found %d vulnerabilities across %d software items", len(vulnerabilities), len(enrichmentResp.Data))
-
-	return vulnerabilities, nil
+// softwareKey identifies a package version for deduplicating lookups
+func softwareKey(name, version string) string {
+	return name + "@" + version
 }
 
 // getPriorityFromCVSS converts CVSS score to priority level
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+)
+
+// enrichmentStub answers every item with one CVE, holding each request until release is closed
+func enrichmentStub(t *testing.T, release <-chan struct{}, requests, active, peak *atomic.Int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if n := active.Add(1); n > peak.Load() {
+			peak.Store(n)
+		}
+		defer active.Add(-1)
+		<-release
+
+		var items []SoftwareItem
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			t.Errorf("bad enrichment request: %v", err)
+		}
+		data := make([]EnrichedSoftware, 0, len(items))
+		for _, item := range items {
+			data = append(data, EnrichedSoftware{
+				Name:    item.Name,
+				Version: item.Version,
+				CVEs:    []CVEData{{ID: "CVE-2021-44228", Severity: "critical", CVSSScore: 10}},
+			})
+		}
+		json.NewEncoder(w).Encode(EnrichmentResponse{Success: true, Data: data})
+	}))
+}
+
+func TestEnrichDependenciesSharesConcurrentLookups(t *testing.T) {
+	var requests, active, peak atomic.Int64
+	release := make(chan struct{})
+	server := enrichmentStub(t, release, &requests, &active, &peak)
+	defer server.Close()
+
+	e := NewEnrichmentService(&config.Config{EnrichmentServiceURL: server.URL, EnrichmentMaxConcurrency: 4, EnrichmentBatchSize: 100}, nil)
+	deps := []models.Dependency{{Name: "log4j-core", Version: "2.14.1"}, {Name: "log4j-core", Version: "2.14.1"}}
+
+	var wg sync.WaitGroup
+	results := make([][]models.Vulnerability, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vulns, err := e.EnrichDependencies(deps)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results[i] = vulns
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected concurrent lookups of one package to share 1 upstream request, got %d", n)
+	}
+	for i, vulns := range results {
+		if len(vulns) != 1 || vulns[0].PackageName != "log4j-core" {
+			t.Errorf("caller %d got %+v", i, vulns)
+		}
+	}
+}
+
+func TestEnrichDependenciesBoundsConcurrency(t *testing.T) {
+	var requests, active, peak atomic.Int64
+	release := make(chan struct{})
+	server := enrichmentStub(t, release, &requests, &active, &peak)
+	defer server.Close()
+
+	e := NewEnrichmentService(&config.Config{EnrichmentServiceURL: server.URL, EnrichmentMaxConcurrency: 2, EnrichmentBatchSize: 1}, nil)
+	deps := []models.Dependency{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+
+	done := make(chan []models.Vulnerability)
+	go func() {
+		vulns, _ := e.EnrichDependencies(deps)
+		done <- vulns
+	}()
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	vulns := <-done
+
+	if n := requests.Load(); n != 5 {
+		t.Errorf("expected one request per batch of 1, got %d", n)
+	}
+	if n := peak.Load(); n > 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", n)
+	}
+	if len(vulns) != 5 {
+		t.Errorf("expected a vulnerability per package, got %d", len(vulns))
+	}
+}
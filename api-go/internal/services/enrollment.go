@@ -114,7 +114,7 @@ func (s *EnrollmentService) EnrollAgent(req *models.AgentEnrollmentRequest) (*mo
 		OrganizationID: enrollmentToken.OrganizationID,
 		CompanyID:      uuid.Nil, // This would be looked up from the organization
 		Name:           req.AgentInfo.Hostname,
-		Status:         "active",
+		Status:         models.AgentStatusOnline,
 		Version:        req.AgentInfo.Version,
 		LastSeen:       time.Now(),
 		Hostname:       req.AgentInfo.Hostname,
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+)
+
+// maxEPSSResponse bounds one EPSS API response read into memory
+const maxEPSSResponse = 5 << 20
+
+// epssClient looks CVEs up in FIRST's EPSS API, many per request. Lookups,
+// including those FIRST has no data for, are cached so the API's rate
+// limits are respected when a fleet reports the same CVEs over and over.
+type epssClient struct {
+	url        string
+	httpClient *http.Client
+	batchSize  int
+	ttl        time.Duration
+
+	mu        sync.Mutex
+	cache     map[string]epssEntry // CVE ID -> its last lookup
+	lastSweep time.Time
+}
+
+// epssEntry is what the EPSS API reported for a CVE. Score and Percentile
+// are nil when it has no data for the CVE.
+type epssEntry struct {
+	Score      *float64
+	Percentile *float64
+	fetchedAt  time.Time
+}
+
+// epssResponse is the EPSS API's response; it sends scores as decimal strings
+type epssResponse struct {
+	Data []struct {
+		CVE        string `json:"cve"`
+		EPSS       string `json:"epss"`
+		Percentile string `json:"percentile"`
+	} `json:"data"`
+}
+
+// newEPSSClient returns nil when EPSS_API_URL is empty, turning lookups off
+func newEPSSClient(cfg *config.Config) *epssClient {
+	if cfg.EPSSURL == "" {
+		return nil
+	}
+	batchSize := cfg.EPSSBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	ttl := cfg.EPSSCacheTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	return &epssClient{
+		url:        cfg.EPSSURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		batchSize:  batchSize,
+		ttl:        ttl,
+		cache:      make(map[string]epssEntry),
+	}
+}
+
+// ApplyEPSS sets the EPSS score and percentile of findings with a CVE ID,
+// looking up the findings of every slice together. Findings whose CVE has no
+// EPSS data keep a nil score, which is not a score of zero; those whose
+// lookup failed are left as they were.
+func (e *EnrichmentService) ApplyEPSS(ctx context.Context, findings ...[]models.Vulnerability) {
+	if e.epss == nil {
+		return
+	}
+
+	var cves []string
+	seen := make(map[string]bool)
+	for _, set := range findings {
+		for i := range set {
+			cve := strings.ToUpper(set[i].CVEID)
+			if cvePattern.MatchString(cve) && !seen[cve] {
+				seen[cve] = true
+				cves = append(cves, cve)
+			}
+		}
+	}
+	if len(cves) == 0 {
+		return
+	}
+
+	entries := e.epss.lookup(ctx, cves)
+	for _, set := range findings {
+		for i := range set {
+			if entry, ok := entries[strings.ToUpper(set[i].CVEID)]; ok {
+				set[i].EPSSScore = entry.Score
+				set[i].EPSSPercentile = entry.Percentile
+			}
+		}
+	}
+}
+
+// lookup returns each CVE's EPSS data, from the cache while fresh and
+// otherwise from the API in batches. CVEs whose batch failed are missing
+// from the result and are not cached, so the next lookup retries them.
+func (c *epssClient) lookup(ctx context.Context, cves []string) map[string]epssEntry {
+	now := time.Now()
+	entries := make(map[string]epssEntry, len(cves))
+	var stale []string
+
+	c.mu.Lock()
+	c.sweep(now)
+	for _, cve := range cves {
+		if entry, ok := c.cache[cve]; ok && now.Sub(entry.fetchedAt) < c.ttl {
+			entries[cve] = entry
+		} else {
+			stale = append(stale, cve)
+		}
+	}
+	c.mu.Unlock()
+
+	for start := 0; start < len(stale); start += c.batchSize {
+		batch := stale[start:min(start+c.batchSize, len(stale))]
+		fetched, err := c.fetch(ctx, batch)
+		if err != nil {
+			log.Printf("[Enrichment] EPSS lookup of %d CVEs failed: %v", len(batch), err)
+			continue
+		}
+
+		c.mu.Lock()
+		for _, cve := range batch {
+			entry := fetched[cve] // Empty when the API has no data for the CVE
+			entry.fetchedAt = now
+			c.cache[cve] = entry
+			entries[cve] = entry
+		}
+		c.mu.Unlock()
+	}
+	return entries
+}
+
+// fetch makes one request to the EPSS API for the given CVEs, which must be
+// valid CVE IDs
+func (c *epssClient) fetch(ctx context.Context, cves []string) (map[string]epssEntry, error) {
+	separator := "?"
+	if strings.Contains(c.url, "?") {
+		separator = "&"
+	}
+	endpoint := fmt.Sprintf("%s%scve=%s&limit=%d", c.url, separator, strings.Join(cves, ","), len(cves))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("EPSS API returned status %d", resp.StatusCode)
+	}
+	var body epssResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxEPSSResponse)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse EPSS response: %w", err)
+	}
+
+	fetched := make(map[string]epssEntry, len(body.Data))
+	for _, row := range body.Data {
+		score, err := strconv.ParseFloat(row.EPSS, 64)
+		if err != nil || score < 0 || score > 1 {
+			continue
+		}
+		entry := epssEntry{Score: &score}
+		if percentile, err := strconv.ParseFloat(row.Percentile, 64); err == nil && percentile >= 0 && percentile <= 1 {
+			entry.Percentile = &percentile
+		}
+		fetched[strings.ToUpper(row.CVE)] = entry
+	}
+	return fetched, nil
+}
+
+// sweep drops expired lookups, at most once per TTL
+func (c *epssClient) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+	for cve, entry := range c.cache {
+		if now.Sub(entry.fetchedAt) >= c.ttl {
+			delete(c.cache, cve)
+		}
+	}
+}
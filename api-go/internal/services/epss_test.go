@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEPSS(t *testing.T) {
+	var requests atomic.Int64
+	var failing atomic.Bool
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		queries = append(queries, r.URL.Query().Get("cve"))
+		var rows []string
+		for _, cve := range strings.Split(r.URL.Query().Get("cve"), ",") {
+			if cve != "CVE-2024-0001" { // No EPSS data for it
+				rows = append(rows, fmt.Sprintf(`{"cve": %q, "epss": "0.97", "percentile": "0.999"}`, cve))
+			}
+		}
+		fmt.Fprintf(w, `{"status": "OK", "data": [%s]}`, strings.Join(rows, ","))
+	}))
+	defer server.Close()
+
+	e := NewEnrichmentService(&config.Config{EPSSURL: server.URL, EPSSBatchSize: 2}, nil)
+	enriched := []models.Vulnerability{{CVEID: "CVE-2021-44228"}, {CVEID: "cve-2024-0001"}, {Title: "No CVE"}}
+	reported := []models.Vulnerability{{CVEID: "CVE-2021-44228"}, {CVEID: "CVE-2023-4863"}}
+	e.ApplyEPSS(context.Background(), enriched, reported)
+
+	// Distinct CVEs of every set are looked up together, in batches
+	assert.Equal(t, []string{"CVE-2021-44228,CVE-2024-0001", "CVE-2023-4863"}, queries)
+	require.NotNil(t, enriched[0].EPSSScore)
+	assert.Equal(t, 0.97, *enriched[0].EPSSScore)
+	assert.Equal(t, 0.999, *enriched[0].EPSSPercentile)
+	assert.Equal(t, 0.97, *reported[0].EPSSScore)
+	assert.Equal(t, 0.97, *reported[1].EPSSScore)
+	// CVEs without EPSS data are null, not zero
+	assert.Nil(t, enriched[1].EPSSScore)
+	assert.Nil(t, enriched[1].EPSSPercentile)
+	assert.Nil(t, enriched[2].EPSSScore)
+
+	// Lookups, including those without data, are cached
+	again := []models.Vulnerability{{CVEID: "CVE-2021-44228"}, {CVEID: "CVE-2024-0001"}}
+	e.ApplyEPSS(context.Background(), again)
+	assert.Equal(t, int64(2), requests.Load())
+	assert.Equal(t, 0.97, *again[0].EPSSScore)
+	assert.Nil(t, again[1].EPSSScore)
+
+	// Failed lookups leave findings alone and are retried
+	failing.Store(true)
+	unscored := []models.Vulnerability{{CVEID: "CVE-2022-22965"}}
+	e.ApplyEPSS(context.Background(), unscored)
+	e.ApplyEPSS(context.Background(), unscored)
+	assert.Nil(t, unscored[0].EPSSScore)
+	assert.Equal(t, int64(4), requests.Load())
+}
+
+func TestApplyEPSSDisabled(t *testing.T) {
+	e := NewEnrichmentService(&config.Config{}, nil)
+	findings := []models.Vulnerability{{CVEID: "CVE-2021-44228"}}
+	e.ApplyEPSS(context.Background(), findings)
+	assert.Nil(t, findings[0].EPSSScore)
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEvidenceNotFound is returned when evidence does not exist or belongs to another organization
+	ErrEvidenceNotFound = errors.New("evidence not found")
+	// ErrInvalidEvidence is returned for evidence that is too large or of a type that is not accepted
+	ErrInvalidEvidence = errors.New("invalid evidence")
+)
+
+// evidenceContentTypes are the evidence types scanners may attach. Images are
+// checked against their content, text must be UTF-8 and JSON must parse.
+var evidenceContentTypes = map[string]bool{
+	"text/plain":       true,
+	"application/json": true,
+	"image/png":        true,
+	"image/jpeg":       true,
+}
+
+// EvidenceService moves the raw evidence scanners attach to findings, such as
+// the command output behind a configuration finding or a Nuclei match
+// response, into object storage and serves it back to authenticated users
+type EvidenceService struct {
+	db            *gorm.DB
+	store         storage.ObjectStore
+	maxSize       int
+	maxPerFinding int
+}
+
+// NewEvidenceService creates a new evidence service backed by the configured store
+func NewEvidenceService(db *gorm.DB, cfg *config.Config) (*EvidenceService, error) {
+	store, err := storage.NewStore(cfg.EvidenceStore, cfg.EvidenceStoragePath, storage.S3Config{
+		Endpoint:        cfg.EvidenceS3Endpoint,
+		Region:          cfg.EvidenceS3Region,
+		Bucket:          cfg.EvidenceS3Bucket,
+		AccessKeyID:     cfg.EvidenceS3AccessKeyID,
+		SecretAccessKey: cfg.EvidenceS3SecretKey,
+		UsePathStyle:    cfg.EvidenceS3PathStyle,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open evidence store: %w", err)
+	}
+
+	return &EvidenceService{
+		db:            db,
+		store:         store,
+		maxSize:       cfg.EvidenceMaxSize,
+		maxPerFinding: cfg.EvidenceMaxPerFinding,
+	}, nil
+}
+
+// AttachResultEvidence stores the evidence attached to each vulnerability in
+// the results, replacing it with references. Evidence that fails validation
+// or cannot be stored is dropped; the finding itself is kept.
+func (s *EvidenceService) AttachResultEvidence(ctx context.Context, agentID, organizationID uuid.UUID, results []models.AgentScanResult) {
+	for i := range results {
+		for j := range results[i].Vulnerabilities {
+			vuln := &results[i].Vulnerabilities[j]
+			if len(vuln.Evidence) == 0 {
+				continue
+			}
+			vuln.EvidenceIDs = s.storeAll(ctx, agentID, organizationID, FindingKey(vuln), vuln.Evidence)
+			vuln.Evidence = nil
+		}
+	}
+}
+
+// AttachNetworkEvidence does the same for the findings of a network scan,
+// which arrive as the agent's raw scan_result document
+func (s *EvidenceService) AttachNetworkEvidence(ctx context.Context, agentID, organizationID uuid.UUID, scanResult map[string]interface{}) {
+	findings, _ := scanResult["network_findings"].([]interface{})
+	for _, raw := range findings {
+		finding, ok := raw.(map[string]interface{})
+		if !ok || finding["evidence"] == nil {
+			continue
+		}
+
+		var uploads []models.EvidenceUpload
+		encoded, _ := json.Marshal(finding["evidence"])
+		delete(finding, "evidence")
+		if err := json.Unmarshal(encoded, &uploads); err != nil {
+			log.Printf("Dropped malformed evidence on network finding from agent %s: %v", agentID, err)
+			continue
+		}
+
+		key := networkFindingKey(finding)
+		finding["finding_key"] = key
+		finding["evidence_ids"] = s.storeAll(ctx, agentID, organizationID, key, uploads)
+	}
+}
+
+// ListEvidence returns the evidence stored for one finding on one agent, newest first
+func (s *EvidenceService) ListEvidence(organizationID, agentID uuid.UUID, findingKey string) ([]models.FindingEvidence, error) {
+	query := s.db.Where("agent_id = ? AND finding_key = ?", agentID, findingKey)
+	if organizationID != uuid.Nil {
+		query = query.Where("organization_id = ?", organizationID)
+	}
+
+	evidence := []models.FindingEvidence{}
+	if err := query.Order("created_at DESC").Find(&evidence).Error; err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// GetEvidence returns an evidence record and its content. uuid.Nil as
+// organizationID skips the organization check.
+func (s *EvidenceService) GetEvidence(ctx context.Context, organizationID, id uuid.UUID) (*models.FindingEvidence, []byte, error) {
+	var evidence models.FindingEvidence
+	if err := s.db.First(&evidence, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrEvidenceNotFound
+		}
+		return nil, nil, err
+	}
+	if organizationID != uuid.Nil && evidence.OrganizationID != organizationID {
+		return nil, nil, ErrEvidenceNotFound
+	}
+
+	data, err := s.store.GetObject(ctx, evidence.ObjectKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, nil, ErrEvidenceNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &evidence, data, nil
+}
+
+// storeAll stores up to maxPerFinding uploads for a finding, returning the IDs of those kept
+func (s *EvidenceService) storeAll(ctx context.Context, agentID, organizationID uuid.UUID, findingKey string, uploads []models.EvidenceUpload) []uuid.UUID {
+	if len(uploads) > s.maxPerFinding {
+		log.Printf("Dropped %d evidence blobs over the limit of %d for finding %s on agent %s", len(uploads)-s.maxPerFinding, s.maxPerFinding, findingKey, agentID)
+		uploads = uploads[:s.maxPerFinding]
+	}
+
+	ids := []uuid.UUID{}
+	for _, upload := range uploads {
+		id, err := s.storeOne(ctx, agentID, organizationID, findingKey, upload)
+		if err != nil {
+			log.Printf("Dropped evidence %q for finding %s on agent %s: %v", upload.Label, findingKey, agentID, err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// storeOne validates and stores one evidence blob. A blob already stored for
+// the finding is not stored again.
+func (s *EvidenceService) storeOne(ctx context.Context, agentID, organizationID uuid.UUID, findingKey string, upload models.EvidenceUpload) (uuid.UUID, error) {
+	contentType, err := validateEvidence(upload, s.maxSize)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	sum := sha256.Sum256(upload.Data)
+	checksum := hex.EncodeToString(sum[:])
+
+	var existing models.FindingEvidence
+	err = s.db.Select("id").
+		Where("agent_id = ? AND finding_key = ? AND sha256 = ?", agentID, findingKey, checksum).
+		Take(&existing).Error
+	if err == nil {
+		return existing.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, err
+	}
+
+	evidence := models.FindingEvidence{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		AgentID:        agentID,
+		FindingKey:     findingKey,
+		Label:          upload.Label,
+		ContentType:    contentType,
+		Size:           len(upload.Data),
+		SHA256:         checksum,
+	}
+	evidence.ObjectKey = fmt.Sprintf("evidence/%s/%s/%s", organizationID, agentID, evidence.ID)
+
+	if err := s.store.PutObject(ctx, evidence.ObjectKey, upload.Data, contentType); err != nil {
+		return uuid.Nil, err
+	}
+	if err := s.db.Create(&evidence).Error; err != nil {
+		return uuid.Nil, err
+	}
+	return evidence.ID, nil
+}
+
+// validateEvidence checks an upload's size and that its content matches an
+// accepted type, returning the normalized content type
+func validateEvidence(upload models.EvidenceUpload, maxSize int) (string, error) {
+	if len(upload.Data) == 0 {
+		return "", fmt.Errorf("%w: empty", ErrInvalidEvidence)
+	}
+	if len(upload.Data) > maxSize {
+		return "", fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrInvalidEvidence, len(upload.Data), maxSize)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(upload.ContentType)
+	if err != nil || !evidenceContentTypes[mediaType] {
+		return "", fmt.Errorf("%w: content type %q is not accepted", ErrInvalidEvidence, upload.ContentType)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		if detected := http.DetectContentType(upload.Data); detected != mediaType {
+			return "", fmt.Errorf("%w: declared %s but content is %s", ErrInvalidEvidence, mediaType, detected)
+		}
+	case mediaType == "application/json":
+		if !json.Valid(upload.Data) {
+			return "", fmt.Errorf("%w: content is not valid JSON", ErrInvalidEvidence)
+		}
+	default:
+		if !utf8.Valid(upload.Data) {
+			return "", fmt.Errorf("%w: text is not valid UTF-8", ErrInvalidEvidence)
+		}
+	}
+
+	return mediaType, nil
+}
+
+// networkFindingKey identifies a network finding the way FindingKey does a
+// vulnerability: by what was found where, not by the scan's random ID
+func networkFindingKey(finding map[string]interface{}) string {
+	title := fmt.Sprint(finding["service_name"])
+	if metadata, ok := finding["metadata"].(map[string]interface{}); ok {
+		if templateID, ok := metadata["template_id"].(string); ok && templateID != "" {
+			title = templateID
+		}
+	}
+	return FindingKey(&models.Vulnerability{
+		Type:     "network_" + fmt.Sprint(finding["finding_type"]),
+		Title:    title,
+		Location: fmt.Sprintf("%v:%v", finding["host"], finding["port"]),
+	})
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"zerotrace/api/internal/models"
+)
+
+func TestValidateEvidenceChecksSizeAndContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name    string
+		upload  models.EvidenceUpload
+		want    string
+		invalid bool
+	}{
+		{"command output", models.EvidenceUpload{ContentType: "text/plain; charset=utf-8", Data: []byte("Status: inactive\n")}, "text/plain", false},
+		{"screenshot", models.EvidenceUpload{ContentType: "image/png", Data: png}, "image/png", false},
+		{"text labelled as image", models.EvidenceUpload{ContentType: "image/png", Data: []byte("<script>")}, "", true},
+		{"broken json", models.EvidenceUpload{ContentType: "application/json", Data: []byte("{")}, "", true},
+		{"executable", models.EvidenceUpload{ContentType: "application/x-msdownload", Data: []byte("MZ")}, "", true},
+		{"too large", models.EvidenceUpload{ContentType: "text/plain", Data: make([]byte, 65)}, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := validateEvidence(tt.upload, 64)
+		if tt.invalid {
+			if !errors.Is(err, ErrInvalidEvidence) {
+				t.Errorf("%s: expected ErrInvalidEvidence, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
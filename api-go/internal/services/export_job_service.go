@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/events"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrExportJobNotFound is returned for an unknown or expired export, or a wrong download token
+	ErrExportJobNotFound = errors.New("export not found")
+	// ErrExportNotReady is returned when downloading an export that has not completed
+	ErrExportNotReady = errors.New("export is not ready")
+	// ErrInvalidCallbackURL is returned when a callback URL is not an absolute
+	// http(s) URL or names a host on a private network
+	ErrInvalidCallbackURL = errors.New("callback_url must be an absolute http or https URL on a public host")
+)
+
+// exportJobSweepInterval is how often expired exports are deleted
+const exportJobSweepInterval = 10 * time.Minute
+
+// ExportRenderer produces an export's content and its content type
+type ExportRenderer func(ctx context.Context) ([]byte, string, error)
+
+// ExportJobService generates large exports in the background. The requester
+// polls the export, or is notified at its callback URL, and downloads the
+// result through a link carrying a secret token. Finished exports are deleted
+// once they expire.
+type ExportJobService struct {
+	db         *gorm.DB
+	store      *storage.FileStore
+	retention  time.Duration
+	publicURL  string
+	httpClient *http.Client
+
+	events         *events.Registry
+	validateEvents bool // Check notifications against their published schema before sending
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewExportJobService creates a new export job service storing exports under the configured path
+func NewExportJobService(db *gorm.DB, cfg *config.Config) (*ExportJobService, error) {
+	store, err := storage.NewFileStore(cfg.ExportStoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export store: %w", err)
+	}
+	retention := cfg.ExportRetention
+	if retention <= 0 {
+		retention = 24 * time.Hour
+	}
+
+	return &ExportJobService{
+		db:         db,
+		store:      store,
+		retention:  retention,
+		publicURL:  strings.TrimRight(cfg.ExportPublicURL, "/"),
+		httpClient: newCallbackClient(10 * time.Second),
+
+		events:         events.Default(),
+		validateEvents: cfg.EventSchemaValidation,
+
+		stopChan: make(chan struct{}),
+	}, nil
+}
+
+// Start fails exports interrupted by a restart and begins deleting expired exports
+func (s *ExportJobService) Start() {
+	err := s.db.Model(&models.ExportJob{}).
+		Where("status IN ?", []string{models.ExportJobPending, models.ExportJobRunning}).
+		Updates(map[string]interface{}{"status": models.ExportJobFailed, "error": "interrupted by a restart"}).Error
+	if err != nil {
+		log.Printf("Failed to fail interrupted exports: %v", err)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(exportJobSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.sweepExpired(time.Now())
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sweep and waits for running exports to finish
+func (s *ExportJobService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+}
+
+// Submit records an export and generates it in the background with render
+func (s *ExportJobService) Submit(quotaKey, kind, format string, filters map[string]interface{}, callbackURL string, render ExportRenderer) (*models.ExportJob, error) {
+	if callbackURL != "" {
+		if err := checkCallbackURL(callbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate download token: %w", err)
+	}
+
+	job := &models.ExportJob{
+		ID:            uuid.New(),
+		QuotaKey:      quotaKey,
+		Kind:          kind,
+		Format:        format,
+		Filters:       filters,
+		Status:        models.ExportJobPending,
+		DownloadToken: hex.EncodeToString(token),
+		CallbackURL:   callbackURL,
+	}
+	job.ObjectKey = "exports/" + job.ID.String()
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to record export: %w", err)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(job, render)
+	}()
+
+	return job, nil
+}
+
+// GetJob returns an export's status, with its download URL once complete
+func (s *ExportJobService) GetJob(id uuid.UUID) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := s.db.First(&job, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportJobNotFound
+		}
+		return nil, err
+	}
+	if job.Status == models.ExportJobCompleted {
+		job.DownloadURL = s.downloadURL(&job)
+	}
+	return &job, nil
+}
+
+// Download returns a completed export's content, checking the download token
+func (s *ExportJobService) Download(ctx context.Context, id uuid.UUID, token string) (*models.ExportJob, []byte, error) {
+	job, err := s.GetJob(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(job.DownloadToken)) != 1 {
+		return nil, nil, ErrExportJobNotFound
+	}
+	if job.Status != models.ExportJobCompleted {
+		return nil, nil, ErrExportNotReady
+	}
+
+	data, err := s.store.GetObject(ctx, job.ObjectKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, nil, ErrExportJobNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, data, nil
+}
+
+// run generates and stores an export, then notifies its callback URL
+func (s *ExportJobService) run(job *models.ExportJob, render ExportRenderer) {
+	s.db.Model(job).Update("status", models.ExportJobRunning)
+
+	err := s.generate(job, render)
+
+	completed := time.Now()
+	expires := completed.Add(s.retention)
+	job.CompletedAt = &completed
+	job.ExpiresAt = &expires
+	job.Status = models.ExportJobCompleted
+	if err != nil {
+		job.Status = models.ExportJobFailed
+		job.Error = err.Error()
+		log.Printf("Export %s failed: %v", job.ID, err)
+	}
+	if err := s.db.Save(job).Error; err != nil {
+		log.Printf("Failed to record outcome of export %s: %v", job.ID, err)
+		return
+	}
+
+	if job.CallbackURL != "" {
+		s.notify(job)
+	}
+}
+
+// generate renders an export and writes it to the store
+func (s *ExportJobService) generate(job *models.ExportJob, render ExportRenderer) error {
+	data, contentType, err := render(context.Background())
+	if err != nil {
+		return err
+	}
+	if err := s.store.PutObject(context.Background(), job.ObjectKey, data, contentType); err != nil {
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	job.ContentType = contentType
+	job.Size = len(data)
+	job.SHA256 = hex.EncodeToString(sum[:])
+	return nil
+}
+
+// notify posts an export's outcome to its callback URL as an export.finished
+// event. Failures are logged; the requester can still poll the export.
+func (s *ExportJobService) notify(job *models.ExportJob) {
+	notification := models.ExportJobNotification{
+		ExportID:  job.ID,
+		Status:    job.Status,
+		ExpiresAt: job.ExpiresAt,
+		Error:     job.Error,
+	}
+	if job.Status == models.ExportJobCompleted {
+		notification.DownloadURL = s.downloadURL(job)
+	}
+
+	event, err := s.events.New(events.ExportFinished, notification)
+	if err != nil {
+		log.Printf("Failed to build notification for export %s: %v", job.ID, err)
+		return
+	}
+	if s.validateEvents {
+		if err := s.events.Validate(event); err != nil {
+			log.Printf("Dropped notification for export %s that does not match its schema: %v", job.ID, err)
+			return
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode notification for export %s: %v", job.ID, err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to notify callback for export %s: %v", job.ID, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(events.TypeHeader, event.Type)
+	req.Header.Set(events.SchemaVersionHeader, event.SchemaVersion)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to notify callback for export %s: %v", job.ID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Callback for export %s returned status %d", job.ID, resp.StatusCode)
+	}
+}
+
+// downloadURL builds an export's download link, relative unless a public URL is configured
+func (s *ExportJobService) downloadURL(job *models.ExportJob) string {
+	return fmt.Sprintf("%s/api/v2/exports/%s/download?token=%s", s.publicURL, job.ID, job.DownloadToken)
+}
+
+// sweepExpired deletes exports, and their content, once they expire
+func (s *ExportJobService) sweepExpired(now time.Time) {
+	var expired []models.ExportJob
+	if err := s.db.Where("expires_at < ?", now).Find(&expired).Error; err != nil {
+		log.Printf("Failed to find expired exports: %v", err)
+		return
+	}
+
+	for _, job := range expired {
+		if err := s.store.DeleteObject(context.Background(), job.ObjectKey); err != nil {
+			log.Printf("Failed to delete expired export %s: %v", job.ID, err)
+			continue
+		}
+		if err := s.db.Delete(&models.ExportJob{}, "id = ?", job.ID).Error; err != nil {
+			log.Printf("Failed to delete expired export %s: %v", job.ID, err)
+		}
+	}
+}
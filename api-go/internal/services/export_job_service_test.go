@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/events"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/google/uuid"
+)
+
+func TestExportNotificationsMatchTheirSchema(t *testing.T) {
+	received := make(chan *http.Request, 2)
+	bodies := make(chan []byte, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	registry := events.Default()
+	s := &ExportJobService{
+		publicURL:      "https://api.example.com",
+		httpClient:     server.Client(),
+		events:         registry,
+		validateEvents: true,
+	}
+
+	expires := time.Now().Add(time.Hour)
+	for _, job := range []*models.ExportJob{
+		{ID: uuid.New(), Status: models.ExportJobCompleted, DownloadToken: "token", CallbackURL: server.URL, ExpiresAt: &expires},
+		{ID: uuid.New(), Status: models.ExportJobFailed, Error: "enrichment unavailable", CallbackURL: server.URL, ExpiresAt: &expires},
+	} {
+		s.notify(job)
+
+		r := <-received
+		if r.Header.Get(events.TypeHeader) != events.ExportFinished || r.Header.Get(events.SchemaVersionHeader) == "" {
+			t.Errorf("missing event headers: %v", r.Header)
+		}
+
+		// Validate what went over the wire, not the Go value
+		var event events.Event
+		if err := json.Unmarshal(<-bodies, &event); err != nil {
+			t.Fatalf("notification is not an event: %v", err)
+		}
+		if err := registry.Validate(&event); err != nil {
+			t.Errorf("%s notification does not match its schema: %v", job.Status, err)
+		}
+	}
+}
+
+func TestCheckCallbackURL(t *testing.T) {
+	for raw, valid := range map[string]bool{
+		"https://hooks.example.com/exports":        true,
+		"http://203.0.113.10:8080/done":            true,
+		"ftp://hooks.example.com/exports":          false,
+		"/api/v2/exports":                          false,
+		"http://localhost:8080/":                   false,
+		"http://api.localhost/":                    false,
+		"http://127.0.0.1/":                        false,
+		"http://10.0.0.5/":                         false,
+		"http://192.168.1.1/":                      false,
+		"http://169.254.169.254/latest/meta-data/": false,
+		"http://[::1]/":                            false,
+		"http://[fe80::1]/":                        false,
+		"http://0.0.0.0/":                          false,
+	} {
+		if err := checkCallbackURL(raw); (err == nil) != valid {
+			t.Errorf("checkCallbackURL(%q) = %v, want valid %v", raw, err, valid)
+		}
+	}
+}
+
+func TestCallbackClientRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	defer server.Close()
+
+	// A public hostname may resolve to a private address; the client checks
+	// what it actually connects to
+	resp, err := newCallbackClient(time.Second).Post(server.URL, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("callback client connected to a loopback address")
+	}
+	if called {
+		t.Error("callback reached a loopback server")
+	}
+}
+
+func TestExportJobLifecycle(t *testing.T) {
+	db := testdb.Open(t, &models.ExportJob{})
+	s, err := NewExportJobService(db, &config.Config{ExportStoragePath: t.TempDir(), ExportPublicURL: "https://api.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func(context.Context) ([]byte, string, error) {
+		return []byte("cve_id\nCVE-2025-1111\n"), "text/csv", nil
+	}
+
+	if _, err := s.Submit("org-a", "vulnerabilities", "csv", nil, "http://169.254.169.254/latest", render); !errors.Is(err, ErrInvalidCallbackURL) {
+		t.Fatalf("private callback URL: got %v, want ErrInvalidCallbackURL", err)
+	}
+
+	job, err := s.Submit("org-a", "vulnerabilities", "csv", map[string]interface{}{"severity": "critical"}, "", render)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.wg.Wait()
+
+	done, err := s.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != models.ExportJobCompleted || done.QuotaKey != "org-a" || done.Size != 21 || done.ExpiresAt == nil {
+		t.Fatalf("unexpected completed export: %+v", done)
+	}
+	wantURL := "https://api.example.com/api/v2/exports/" + job.ID.String() + "/download?token=" + job.DownloadToken
+	if done.DownloadURL != wantURL {
+		t.Errorf("download URL = %q, want %q", done.DownloadURL, wantURL)
+	}
+
+	if _, _, err := s.Download(context.Background(), job.ID, "wrong-token"); !errors.Is(err, ErrExportJobNotFound) {
+		t.Errorf("wrong token: got %v, want ErrExportJobNotFound", err)
+	}
+	_, data, err := s.Download(context.Background(), job.ID, job.DownloadToken)
+	if err != nil || string(data) != "cve_id\nCVE-2025-1111\n" {
+		t.Errorf("download = %q, %v", data, err)
+	}
+
+	failed, err := s.Submit("org-a", "vulnerabilities", "csv", nil, "", func(context.Context) ([]byte, string, error) {
+		return nil, "", errors.New("enrichment unavailable")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.wg.Wait()
+	if got, _ := s.GetJob(failed.ID); got == nil || got.Status != models.ExportJobFailed || got.Error != "enrichment unavailable" || got.DownloadURL != "" {
+		t.Errorf("unexpected failed export: %+v", got)
+	}
+	if _, _, err := s.Download(context.Background(), failed.ID, failed.DownloadToken); err == nil {
+		t.Error("a failed export was downloaded")
+	}
+
+	// Expired exports and their content are deleted
+	s.sweepExpired(time.Now().Add(48 * time.Hour))
+	if _, err := s.GetJob(job.ID); !errors.Is(err, ErrExportJobNotFound) {
+		t.Errorf("expired export: got %v, want ErrExportJobNotFound", err)
+	}
+}
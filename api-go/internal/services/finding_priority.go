@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidPriority is returned for a priority that is not a known level
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// findingPriorityThresholds map a finding's risk to its priority. Risk uses
+// the host risk weights: the severity weight, raised by up to double its EPSS
+// probability, doubled when known exploited and scaled by the host's asset
+// criticality. So a critical on a critical host, or an exploited critical on
+// any host, is urgent, while a high unexploited finding is high on all but
+// low criticality hosts.
+var findingPriorityThresholds = []struct {
+	risk     float64
+	priority string
+}{
+	{10, models.FindingPriorityUrgent},
+	{3.5, models.FindingPriorityHigh},
+	{1, models.FindingPriorityMedium},
+}
+
+// DerivePriority returns the priority of a finding of the given severity,
+// EPSS probability and known exploitation on a host of the given asset
+// criticality. Unknown criticalities count as medium.
+func DerivePriority(severity string, epss float64, knownExploited bool, criticality string) string {
+	multiplier, ok := hostRiskCriticalityMultipliers[criticality]
+	if !ok {
+		multiplier = hostRiskCriticalityMultipliers[models.AssetCriticalityMedium]
+	}
+	risk := hostRiskSeverityWeights[strings.ToLower(severity)] * (1 + epss) * multiplier
+	if knownExploited {
+		risk *= 2
+	}
+	for _, threshold := range findingPriorityThresholds {
+		if risk >= threshold.risk {
+			return threshold.priority
+		}
+	}
+	return models.FindingPriorityLow
+}
+
+// Prioritize sets the priority of each finding an agent reported: the
+// override of its tracked finding if there is one, and derived otherwise.
+// Whatever priority the scanner set is replaced.
+func (s *FindingStateService) Prioritize(agentID uuid.UUID, criticality string, findings []models.Vulnerability) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := s.agentStatesLocked(agentID)
+	for i := range findings {
+		finding := &findings[i]
+		if state, ok := states[FindingKey(finding)]; ok && state.PriorityOverride != "" {
+			finding.Priority = state.PriorityOverride
+			continue
+		}
+		finding.Priority = DerivePriority(string(finding.Severity), findingEPSS(finding), knownExploited(finding), criticality)
+	}
+}
+
+// SetPriorityOverride overrides the derived priority of one of an
+// organization's findings until cleared, recording who overrode it
+func (s *FindingStateService) SetPriorityOverride(organizationID, id uuid.UUID, priority, reason, overriddenBy string) (*models.FindingState, error) {
+	switch priority {
+	case models.FindingPriorityUrgent, models.FindingPriorityHigh, models.FindingPriorityMedium, models.FindingPriorityLow:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPriority, priority)
+	}
+
+	now := time.Now()
+	return s.updateOrganizationFinding(organizationID, id, func(state *models.FindingState) {
+		state.PriorityOverride = priority
+		state.PriorityOverrideReason = reason
+		state.PriorityOverriddenAt = &now
+		state.PriorityOverriddenBy = overriddenBy
+		state.Priority = priority
+	})
+}
+
+// ClearPriorityOverride returns one of an organization's findings to its
+// derived priority
+func (s *FindingStateService) ClearPriorityOverride(organizationID, id uuid.UUID) (*models.FindingState, error) {
+	return s.updateOrganizationFinding(organizationID, id, func(state *models.FindingState) {
+		state.PriorityOverride = ""
+		state.PriorityOverrideReason = ""
+		state.PriorityOverriddenAt = nil
+		state.PriorityOverriddenBy = ""
+		state.Priority = state.DerivedPriority
+	})
+}
+
+// updateFinding applies a manual change to a finding, through its cached
+// state so the next scan of its agent sees it
+func (s *FindingStateService) updateFinding(id uuid.UUID, update func(*models.FindingState)) (*models.FindingState, error) {
+	var stored models.FindingState
+	err := s.db.Select("id", "agent_id", "finding_key").Where("id = ?", id).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFindingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.agentStatesLocked(stored.AgentID)[stored.FindingKey]
+	if !ok {
+		return nil, ErrFindingNotFound
+	}
+	update(state)
+	if err := s.db.Save(state).Error; err != nil {
+		// The cached state no longer matches the database
+		delete(s.states, stored.AgentID)
+		return nil, fmt.Errorf("failed to persist finding state %s: %w", state.FindingKey, err)
+	}
+	updated := *state
+	return &updated, nil
+}
+
+// updateOrganizationFinding is updateFinding for one of an organization's
+// findings; another organization's findings are not found
+func (s *FindingStateService) updateOrganizationFinding(organizationID, id uuid.UUID, update func(*models.FindingState)) (*models.FindingState, error) {
+	var count int64
+	if err := s.db.Model(&models.FindingState{}).Where("id = ? AND organization_id = ?", id, organizationID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrFindingNotFound
+	}
+	return s.updateFinding(id, update)
+}
+
+// effectivePriority returns a finding's override if set, and otherwise its
+// derived priority
+func effectivePriority(state *models.FindingState) string {
+	if state.PriorityOverride != "" {
+		return state.PriorityOverride
+	}
+	return state.DerivedPriority
+}
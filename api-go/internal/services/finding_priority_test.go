@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDerivePriority(t *testing.T) {
+	tests := []struct {
+		name           string
+		severity       string
+		epss           float64
+		knownExploited bool
+		criticality    string
+		want           string
+	}{
+		{"critical on a critical host", "critical", 0, false, models.AssetCriticalityCritical, models.FindingPriorityUrgent},
+		{"critical on a medium host", "critical", 0, false, models.AssetCriticalityMedium, models.FindingPriorityHigh},
+		{"exploited critical on a low host", "critical", 0, true, models.AssetCriticalityLow, models.FindingPriorityUrgent},
+		{"high on a medium host", "HIGH", 0, false, models.AssetCriticalityMedium, models.FindingPriorityHigh},
+		{"high on a low host", "high", 0, false, models.AssetCriticalityLow, models.FindingPriorityMedium},
+		{"likely exploited medium on a critical host", "medium", 0.9, false, models.AssetCriticalityCritical, models.FindingPriorityHigh},
+		{"low on an unknown criticality host", "low", 0, false, "", models.FindingPriorityLow},
+		{"exploited low on a critical host", "low", 0, true, models.AssetCriticalityCritical, models.FindingPriorityMedium},
+		{"info", "info", 1, true, models.AssetCriticalityCritical, models.FindingPriorityLow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DerivePriority(tt.severity, tt.epss, tt.knownExploited, tt.criticality))
+		})
+	}
+}
+
+func TestEffectivePriorityPrefersOverride(t *testing.T) {
+	state := &models.FindingState{DerivedPriority: models.FindingPriorityHigh}
+	assert.Equal(t, models.FindingPriorityHigh, effectivePriority(state))
+
+	state.PriorityOverride = models.FindingPriorityLow
+	assert.Equal(t, models.FindingPriorityLow, effectivePriority(state))
+}
+
+func TestPriorityOverrideIsScopedToOrganization(t *testing.T) {
+	db := testdb.Open(t, &models.FindingState{})
+	s := NewFindingStateService(db, &config.Config{})
+	orgA, orgB := uuid.New(), uuid.New()
+	finding := models.FindingState{ID: uuid.New(), AgentID: uuid.New(), OrganizationID: orgA, FindingKey: "k1", Status: models.FindingStatusOpen, Priority: models.FindingPriorityMedium, DerivedPriority: models.FindingPriorityMedium}
+	require.NoError(t, db.Create(&finding).Error)
+
+	_, err := s.SetPriorityOverride(orgB, finding.ID, models.FindingPriorityUrgent, "Exposed to the internet", "user_mallory")
+	assert.ErrorIs(t, err, ErrFindingNotFound, "another organization's findings are not found")
+	_, err = s.ClearPriorityOverride(orgB, finding.ID)
+	assert.ErrorIs(t, err, ErrFindingNotFound)
+
+	overridden, err := s.SetPriorityOverride(orgA, finding.ID, models.FindingPriorityUrgent, "Exposed to the internet", "user_alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.FindingPriorityUrgent, overridden.Priority)
+	assert.Equal(t, "user_alice", overridden.PriorityOverriddenBy)
+
+	cleared, err := s.ClearPriorityOverride(orgA, finding.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FindingPriorityMedium, cleared.Priority)
+	assert.Empty(t, cleared.PriorityOverriddenBy)
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// defaultFindingRetentionDays is how long a resolved finding of each severity
+// is kept. Criticals are kept longest, as they matter most to audits and
+// remediation time analysis.
+var defaultFindingRetentionDays = map[string]int{
+	"critical": 730,
+	"high":     365,
+	"medium":   180,
+	"low":      90,
+	"info":     30,
+}
+
+// findingRetentionDeleteBatch bounds the findings deleted in one statement
+const findingRetentionDeleteBatch = 500
+
+// FindingRetentionReport is the outcome of one retention sweep
+type FindingRetentionReport struct {
+	Purged            map[string]int // Severity -> resolved findings deleted
+	KeptForCompliance int            // Expired findings kept because an open compliance control covers them
+}
+
+// FindingRetentionService purges resolved findings once they are older than
+// their severity's retention window. A resolved finding that maps to an open
+// compliance control, one at risk because findings mapped to it are overdue,
+// is audit history for that control and is kept regardless of age.
+type FindingRetentionService struct {
+	db            *gorm.DB
+	complianceSLA *ComplianceSLAService
+	retention     map[string]time.Duration // Severity -> how long a resolved finding is kept
+	longest       time.Duration            // Retention of severities without a window of their own
+	interval      time.Duration
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFindingRetentionService creates a new finding retention service
+func NewFindingRetentionService(db *gorm.DB, cfg *config.Config, complianceSLA *ComplianceSLAService) *FindingRetentionService {
+	retention := findingRetentionWindows(cfg.FindingRetentionDays)
+	interval := cfg.FindingRetentionInterval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	s := &FindingRetentionService{
+		db:            db,
+		complianceSLA: complianceSLA,
+		retention:     retention,
+		interval:      interval,
+		stopChan:      make(chan struct{}),
+	}
+	for _, window := range retention {
+		s.longest = max(s.longest, window)
+	}
+	return s
+}
+
+// findingRetentionWindows merges configured retention days into the defaults
+func findingRetentionWindows(days map[string]int) map[string]time.Duration {
+	merged := make(map[string]time.Duration, len(defaultFindingRetentionDays))
+	for severity, d := range defaultFindingRetentionDays {
+		merged[severity] = time.Duration(d) * 24 * time.Hour
+	}
+	for severity, d := range days {
+		if d > 0 {
+			merged[strings.ToLower(severity)] = time.Duration(d) * 24 * time.Hour
+		}
+	}
+	return merged
+}
+
+// Start begins purging expired findings in the background
+func (s *FindingRetentionService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				report, err := s.Sweep(time.Now())
+				if err != nil {
+					log.Printf("Finding retention sweep failed: %v", err)
+				} else if len(report.Purged) > 0 || report.KeptForCompliance > 0 {
+					log.Printf("Finding retention purged %v resolved findings, kept %d past retention for open compliance controls", report.Purged, report.KeptForCompliance)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background purge
+func (s *FindingRetentionService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+}
+
+// Sweep purges the resolved findings whose retention ran out by now, along
+// with their verifications
+func (s *FindingRetentionService) Sweep(now time.Time) (*FindingRetentionReport, error) {
+	report := &FindingRetentionReport{Purged: make(map[string]int)}
+
+	shortest := s.longest
+	for _, window := range s.retention {
+		if window < shortest {
+			shortest = window
+		}
+	}
+	var candidates []models.FindingState
+	err := s.db.Select("id", "organization_id", "scope", "title", "severity", "status", "last_transition_at").
+		Where("status = ? AND last_transition_at < ?", models.FindingStatusResolved, now.Add(-shortest)).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load resolved findings: %w", err)
+	}
+
+	byOrganization := make(map[uuid.UUID][]models.FindingState)
+	for _, finding := range candidates {
+		byOrganization[finding.OrganizationID] = append(byOrganization[finding.OrganizationID], finding)
+	}
+
+	for organizationID, findings := range byOrganization {
+		controls, err := s.openControls(organizationID)
+		if err != nil {
+			return report, err
+		}
+		expired, kept := expiredFindings(findings, s.retention, s.longest, controls, now)
+		report.KeptForCompliance += kept
+		if err := s.purge(expired, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// openControls returns the controls, across the compliance frameworks an
+// organization tracks, that are open: at risk of failing their audit because
+// findings mapped to them are overdue. An organization tracks the frameworks
+// in its profile and those it has set a remediation policy for.
+func (s *FindingRetentionService) openControls(organizationID uuid.UUID) ([]slaControl, error) {
+	frameworks := make(map[string]bool)
+
+	var profiles []string
+	err := s.db.Model(&models.OrganizationProfile{}).Where("organization_id = ?", organizationID).
+		Pluck("compliance_frameworks", &profiles).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load compliance frameworks: %w", err)
+	}
+	for _, encoded := range profiles {
+		var names []string
+		if err := json.Unmarshal([]byte(encoded), &names); err != nil {
+			continue
+		}
+		for _, name := range names {
+			if framework, ok := NormalizeFramework(name); ok {
+				frameworks[framework] = true
+			}
+		}
+	}
+
+	var policies []string
+	if err := s.db.Model(&models.ComplianceSLAPolicy{}).Where("organization_id = ?", organizationID).Pluck("framework", &policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to load compliance policies: %w", err)
+	}
+	for _, name := range policies {
+		if framework, ok := NormalizeFramework(name); ok {
+			frameworks[framework] = true
+		}
+	}
+
+	names := make([]string, 0, len(frameworks))
+	for framework := range frameworks {
+		names = append(names, framework)
+	}
+	sort.Strings(names)
+
+	var controls []slaControl
+	for _, framework := range names {
+		dashboard, err := s.complianceSLA.GetDashboard(organizationID, framework)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s controls: %w", framework, err)
+		}
+		controls = append(controls, atRiskControls(dashboard)...)
+	}
+	return controls, nil
+}
+
+// purge deletes expired findings and their verifications. A finding that
+// reopened since it was selected is left alone.
+func (s *FindingRetentionService) purge(expired []models.FindingState, report *FindingRetentionReport) error {
+	for start := 0; start < len(expired); start += findingRetentionDeleteBatch {
+		batch := expired[start:min(start+findingRetentionDeleteBatch, len(expired))]
+		ids := make([]uuid.UUID, len(batch))
+		for i, finding := range batch {
+			ids[i] = finding.ID
+		}
+
+		var deleted []models.FindingState
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Select("id", "severity").Where("id IN ? AND status = ?", ids, models.FindingStatusResolved).Find(&deleted).Error; err != nil {
+				return err
+			}
+			if len(deleted) == 0 {
+				return nil
+			}
+			deletedIDs := make([]uuid.UUID, len(deleted))
+			for i, finding := range deleted {
+				deletedIDs[i] = finding.ID
+			}
+			if err := tx.Where("finding_id IN ?", deletedIDs).Delete(&models.FindingVerification{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", deletedIDs).Delete(&models.FindingState{}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to purge resolved findings: %w", err)
+		}
+		for _, finding := range deleted {
+			report.Purged[strings.ToLower(finding.Severity)]++
+		}
+	}
+	return nil
+}
+
+// atRiskControls returns the controls a compliance dashboard reports at risk
+func atRiskControls(dashboard *models.ComplianceSLADashboard) []slaControl {
+	var controls []slaControl
+	for _, status := range dashboard.AtRisk {
+		for _, control := range slaFrameworks[dashboard.Framework].Controls {
+			if control.ID == status.ControlID {
+				controls = append(controls, control)
+			}
+		}
+	}
+	return controls
+}
+
+// expiredFindings selects the resolved findings whose severity's retention
+// ran out by now, skipping those an open control covers. Severities without
+// a window of their own are kept for longest. It also returns how many
+// expired findings were kept for compliance.
+func expiredFindings(findings []models.FindingState, retention map[string]time.Duration, longest time.Duration, openControls []slaControl, now time.Time) ([]models.FindingState, int) {
+	var expired []models.FindingState
+	kept := 0
+	for _, finding := range findings {
+		if finding.Status != models.FindingStatusResolved {
+			continue
+		}
+		window, ok := retention[strings.ToLower(finding.Severity)]
+		if !ok {
+			window = longest
+		}
+		if now.Sub(finding.LastTransitionAt) <= window {
+			continue
+		}
+
+		covered := false
+		for _, control := range openControls {
+			if control.covers(finding.Scope, finding.Title) {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			kept++
+			continue
+		}
+		expired = append(expired, finding)
+	}
+	return expired, kept
+}
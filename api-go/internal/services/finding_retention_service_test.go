@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiredFindingsHonorsSeverityRetention(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	retention := findingRetentionWindows(map[string]int{"INFO": 10})
+	resolvedDaysAgo := func(severity string, days int) models.FindingState {
+		return models.FindingState{
+			Severity:         severity,
+			Scope:            "software",
+			Title:            severity + " finding",
+			Status:           models.FindingStatusResolved,
+			LastTransitionAt: now.AddDate(0, 0, -days),
+		}
+	}
+
+	findings := []models.FindingState{
+		resolvedDaysAgo("critical", 400), // Within two years
+		resolvedDaysAgo("critical", 800),
+		resolvedDaysAgo("info", 20), // Past the configured 10 days
+		resolvedDaysAgo("low", 20),
+		resolvedDaysAgo("unknown", 400), // Kept as long as the longest window
+		{Severity: "info", Status: models.FindingStatusOpen, LastTransitionAt: now.AddDate(-5, 0, 0)},
+	}
+	expired, kept := expiredFindings(findings, retention, 730*24*time.Hour, nil, now)
+	assert.Equal(t, []models.FindingState{findings[1], findings[2]}, expired)
+	assert.Zero(t, kept)
+}
+
+func TestExpiredFindingsKeepsFindingsOfOpenControls(t *testing.T) {
+	now := time.Now()
+	overdue := []models.FindingState{{
+		Severity:  "critical",
+		Scope:     "container",
+		Title:     "TLS certificate expired",
+		Status:    models.FindingStatusOpen,
+		FirstSeen: now.AddDate(0, 0, -30),
+	}}
+	controls := atRiskControls(buildSLADashboard(defaultSLAPolicy(uuid.New(), "SOC2"), overdue, now))
+	require.Len(t, controls, 1)
+	assert.Equal(t, "CC6.7", controls[0].ID)
+
+	old := now.AddDate(-3, 0, 0)
+	findings := []models.FindingState{
+		{Severity: "low", Scope: "container", Title: "Weak cipher accepted", Status: models.FindingStatusResolved, LastTransitionAt: old},
+		{Severity: "low", Scope: "software", Title: "Outdated package", Status: models.FindingStatusResolved, LastTransitionAt: old},
+	}
+	expired, kept := expiredFindings(findings, findingRetentionWindows(nil), 730*24*time.Hour, controls, now)
+	assert.Equal(t, []models.FindingState{findings[1]}, expired, "the finding the at-risk control covers is kept")
+	assert.Equal(t, 1, kept)
+}
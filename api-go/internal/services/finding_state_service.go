@@ -0,0 +1,454 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FindingKey returns the deterministic identity of a finding on a host. The
+// same issue in the same package at the same location always maps to the
+// same key, regardless of the random IDs scanners assign each run or the
+// package version it was found in.
+func FindingKey(v *models.Vulnerability) string {
+	id := v.CVEID
+	if id == "" {
+		id = v.Title
+	}
+	parts := []string{v.Type, id, v.PackageName, v.Location}
+	for i := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(parts[i]))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// FindingStateService tracks each finding's open/resolved lifecycle per agent
+// and detects flapping: a finding that toggles more than the configured number
+// of times within the correlation window is marked flapping, and further
+// toggles no longer raise alerts until it settles down for a full window.
+type FindingStateService struct {
+	db        *gorm.DB
+	threshold int
+	window    time.Duration
+
+	mu     sync.Mutex
+	states map[uuid.UUID]map[string]*models.FindingState // agent -> finding key -> state
+}
+
+// NewFindingStateService creates a new finding state service
+func NewFindingStateService(db *gorm.DB, cfg *config.Config) *FindingStateService {
+	threshold := cfg.FlappingThreshold
+	if threshold <= 0 {
+		threshold = 3
+	}
+	window := cfg.FlappingWindow
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+
+	return &FindingStateService{
+		db:        db,
+		threshold: threshold,
+		window:    window,
+		states:    make(map[uuid.UUID]map[string]*models.FindingState),
+	}
+}
+
+// Observe records the complete set of findings a scan of the given scope
+// reported for an agent, writing the changed states through tx. Previously
+// open findings of the same scope that are missing from the set are resolved.
+// Each finding's priority is derived anew for the agent's asset criticality,
+// and the active suppression rules of its organization are applied to it.
+// It returns every transition that occurred. If tx is rolled back, the caller
+// must Forget the agent so its states are reloaded from the database.
+func (s *FindingStateService) Observe(tx *gorm.DB, agentID, organizationID uuid.UUID, scope, criticality string, findings []models.Vulnerability, rules []models.SuppressionRule, at time.Time) ([]models.FindingTransition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := s.agentStatesLocked(agentID)
+	var transitions []models.FindingTransition
+	var changed []*models.FindingState
+	seen := make(map[string]bool, len(findings))
+
+	for i := range findings {
+		key := FindingKey(&findings[i])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		state, transition := s.observeLocked(states, agentID, organizationID, scope, criticality, &findings[i], rules, at)
+		changed = append(changed, state)
+		if transition != nil {
+			transitions = append(transitions, *transition)
+		}
+	}
+
+	for key, state := range states {
+		if seen[key] || state.Scope != scope {
+			continue
+		}
+		if state.Status == models.FindingStatusOpen {
+			changed = append(changed, state)
+			transitions = append(transitions, s.transitionLocked(state, models.FindingStatusResolved, at))
+		} else if state.Flapping {
+			s.settleLocked(state, at)
+			if !state.Flapping {
+				changed = append(changed, state)
+			}
+		}
+	}
+
+	if err := saveFindingStates(tx, changed); err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+// ObserveDelta records how the findings of a scan of the given scope changed
+// since the agent last reported them, like Observe: added are the new and
+// changed findings, and the delta names by key those resolved and those
+// unchanged. Findings the delta doesn't name are left as they are. An
+// unchanged key that isn't known means the agent and the API have drifted
+// apart; it is logged and skipped until the agent's next full report.
+func (s *FindingStateService) ObserveDelta(tx *gorm.DB, agentID, organizationID uuid.UUID, scope, criticality string, added []models.Vulnerability, delta *models.FindingDelta, rules []models.SuppressionRule, at time.Time) ([]models.FindingTransition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := s.agentStatesLocked(agentID)
+	var transitions []models.FindingTransition
+	var changed []*models.FindingState
+
+	for i := range added {
+		state, transition := s.observeLocked(states, agentID, organizationID, scope, criticality, &added[i], rules, at)
+		changed = append(changed, state)
+		if transition != nil {
+			transitions = append(transitions, *transition)
+		}
+	}
+
+	drifted := 0
+	for _, key := range delta.Unchanged {
+		state, exists := states[key]
+		if !exists {
+			drifted++
+			continue
+		}
+		state.LastSeen = at
+		applySuppressionRules(state, rules, at)
+		changed = append(changed, state)
+		if state.Status != models.FindingStatusOpen {
+			transitions = append(transitions, s.transitionLocked(state, models.FindingStatusOpen, at))
+		} else {
+			s.settleLocked(state, at)
+		}
+	}
+	if drifted > 0 {
+		log.Printf("[FindingState] Agent %s reported %d unchanged %s findings that are unknown; they will be recorded on its next full report", agentID, drifted, scope)
+	}
+
+	for _, key := range delta.Resolved {
+		if state, exists := states[key]; exists && state.Status == models.FindingStatusOpen {
+			changed = append(changed, state)
+			transitions = append(transitions, s.transitionLocked(state, models.FindingStatusResolved, at))
+		}
+	}
+
+	if err := saveFindingStates(tx, changed); err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+// observeLocked records that a finding was seen, creating its state if it is
+// new. It returns the state and the transition the sighting caused, if any.
+func (s *FindingStateService) observeLocked(states map[string]*models.FindingState, agentID, organizationID uuid.UUID, scope, criticality string, finding *models.Vulnerability, rules []models.SuppressionRule, at time.Time) (*models.FindingState, *models.FindingTransition) {
+	key := FindingKey(finding)
+	state, exists := states[key]
+	if !exists {
+		state = &models.FindingState{
+			ID:               uuid.New(),
+			AgentID:          agentID,
+			OrganizationID:   organizationID,
+			FindingKey:       key,
+			Scope:            scope,
+			Title:            finding.Title,
+			CVEID:            finding.CVEID,
+			PackageName:      finding.PackageName,
+			PackageVersion:   finding.PackageVersion,
+			Severity:         string(finding.Severity),
+			CVSSScore:        findingCVSS(finding),
+			EPSS:             findingEPSS(finding),
+			KnownExploited:   knownExploited(finding),
+			ThreatIntel:      threatIntelMatches(finding),
+			Status:           models.FindingStatusOpen,
+			FirstSeen:        at,
+			LastSeen:         at,
+			LastTransitionAt: at,
+		}
+		state.DerivedPriority = DerivePriority(state.Severity, state.EPSS, state.KnownExploited, criticality)
+		state.Priority = state.DerivedPriority
+		applySuppressionRules(state, rules, at)
+		states[key] = state
+		return state, &models.FindingTransition{
+			State: state,
+			To:    models.FindingStatusOpen,
+			Alert: !state.Suppressed,
+		}
+	}
+
+	state.LastSeen = at
+	state.PackageVersion = finding.PackageVersion
+	state.Severity = string(finding.Severity)
+	state.CVSSScore = findingCVSS(finding)
+	state.EPSS = findingEPSS(finding)
+	state.KnownExploited = knownExploited(finding)
+	state.ThreatIntel = threatIntelMatches(finding)
+	state.DerivedPriority = DerivePriority(state.Severity, state.EPSS, state.KnownExploited, criticality)
+	state.Priority = effectivePriority(state)
+	applySuppressionRules(state, rules, at)
+	if state.Status != models.FindingStatusOpen {
+		transition := s.transitionLocked(state, models.FindingStatusOpen, at)
+		return state, &transition
+	}
+	s.settleLocked(state, at)
+	return state, nil
+}
+
+// saveFindingStates writes changed finding states through tx
+func saveFindingStates(tx *gorm.DB, changed []*models.FindingState) error {
+	for _, state := range changed {
+		if err := tx.Save(state).Error; err != nil {
+			return fmt.Errorf("failed to persist finding state %s: %w", state.FindingKey, err)
+		}
+	}
+	return nil
+}
+
+// Forget drops an agent's cached finding states, which are reloaded from the
+// database on next use
+func (s *FindingStateService) Forget(agentID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, agentID)
+}
+
+// GetFinding returns a finding's state by its ID
+func (s *FindingStateService) GetFinding(id uuid.UUID) (*models.FindingState, error) {
+	var state models.FindingState
+	if err := s.db.Where("id = ?", id).First(&state).Error; err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// ApplyVerification records the outcome of a finding's on-demand verification
+// through tx. A finding verified resolved is resolved, and one confirmed is
+// reopened if it was resolved since; either counts towards flapping like a
+// scan would. It returns the transition this caused, if any. If tx is rolled
+// back, the caller must Forget the agent.
+func (s *FindingStateService) ApplyVerification(tx *gorm.DB, agentID uuid.UUID, findingKey, outcome string, at time.Time) (*models.FindingTransition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.agentStatesLocked(agentID)[findingKey]
+	if !ok {
+		return nil, fmt.Errorf("finding %s is not tracked for agent %s", findingKey, agentID)
+	}
+	transition := s.verifyLocked(state, outcome, at)
+	if err := tx.Save(state).Error; err != nil {
+		return nil, fmt.Errorf("failed to persist finding state %s: %w", state.FindingKey, err)
+	}
+	return transition, nil
+}
+
+// refresh applies update to stored finding states, writing those it changed
+// through tx. A finding whose agent's states are cached is updated there, so
+// the agent's next scan sees the change. It returns how many findings changed
+// and their agents; if tx is rolled back, the caller must Forget those agents.
+func (s *FindingStateService) refresh(tx *gorm.DB, stored []models.FindingState, update func(*models.FindingState) bool) (int, map[uuid.UUID]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := 0
+	agents := make(map[uuid.UUID]bool)
+	for i := range stored {
+		state := &stored[i]
+		if cached, ok := s.states[state.AgentID][state.FindingKey]; ok {
+			state = cached
+		}
+		if !update(state) {
+			continue
+		}
+		agents[state.AgentID] = true
+		if err := tx.Save(state).Error; err != nil {
+			return changed, agents, fmt.Errorf("failed to persist finding state %s: %w", state.FindingKey, err)
+		}
+		changed++
+	}
+	return changed, agents, nil
+}
+
+// verifyLocked applies a verification outcome to a finding's state
+func (s *FindingStateService) verifyLocked(state *models.FindingState, outcome string, at time.Time) *models.FindingTransition {
+	state.Verification = outcome
+	state.VerifiedAt = &at
+
+	var to string
+	switch {
+	case outcome == models.VerificationResolved && state.Status == models.FindingStatusOpen:
+		to = models.FindingStatusResolved
+	case outcome == models.VerificationConfirmed && state.Status != models.FindingStatusOpen:
+		to = models.FindingStatusOpen
+	default:
+		return nil
+	}
+	transition := s.transitionLocked(state, to, at)
+	return &transition
+}
+
+// findingCVSS returns a finding's CVSS base score, 0 when unscored
+func findingCVSS(v *models.Vulnerability) float64 {
+	if v.CVSSScore == nil || *v.CVSSScore < 0 || *v.CVSSScore > 10 {
+		return 0
+	}
+	return *v.CVSSScore
+}
+
+// findingEPSS returns a finding's EPSS score, falling back to its enrichment
+// data, 0 when unknown
+func findingEPSS(v *models.Vulnerability) float64 {
+	if v.EPSSScore != nil {
+		return *v.EPSSScore
+	}
+	for _, key := range []string{"epss", "epss_score"} {
+		if score, ok := v.EnrichmentData[key].(float64); ok && score >= 0 && score <= 1 {
+			return score
+		}
+	}
+	return 0
+}
+
+// knownExploited reports whether a finding is listed in CISA KEV, has a
+// public exploit, or is reported by its organization's threat intelligence
+func knownExploited(v *models.Vulnerability) bool {
+	if v.ExploitAvailable || len(threatIntelMatches(v)) > 0 {
+		return true
+	}
+	for _, key := range []string{"kev", "cisa_kev"} {
+		if listed, ok := v.EnrichmentData[key].(bool); ok && listed {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFlappingFindings lists findings currently marked flapping.
+// uuid.Nil as organizationID covers every organization.
+func (s *FindingStateService) GetFlappingFindings(organizationID uuid.UUID) ([]models.FindingState, error) {
+	var states []models.FindingState
+	query := s.db.Where("flapping = ?", true)
+	if organizationID != uuid.Nil {
+		query = query.Where("organization_id = ?", organizationID)
+	}
+	err := query.Order("last_transition_at DESC").Find(&states).Error
+	return states, err
+}
+
+// ListFindings lists up to limit of an organization's findings, most recently
+// seen first. uuid.Nil as agentID covers every agent, and an empty status
+// every status.
+func (s *FindingStateService) ListFindings(organizationID, agentID uuid.UUID, status string, limit int) ([]models.FindingState, error) {
+	var states []models.FindingState
+	query := s.db.Where("organization_id = ?", organizationID)
+	if agentID != uuid.Nil {
+		query = query.Where("agent_id = ?", agentID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("last_seen DESC").Limit(limit).Find(&states).Error
+	return states, err
+}
+
+// transitionLocked moves a finding to a new status and updates its flapping state
+func (s *FindingStateService) transitionLocked(state *models.FindingState, to string, at time.Time) models.FindingTransition {
+	transition := models.FindingTransition{
+		State: state,
+		From:  state.Status,
+		To:    to,
+	}
+
+	state.Status = to
+	state.TransitionCount++
+	state.LastTransitionAt = at
+	state.Transitions = append(pruneTransitions(state.Transitions, at, s.window), at)
+
+	switch {
+	case state.Flapping:
+		// Already flapping; suppress per-toggle alerts
+	case len(state.Transitions) > s.threshold:
+		state.Flapping = true
+		state.FlappingSince = &at
+		transition.BecameFlapping = true
+		transition.Alert = true
+	default:
+		transition.Alert = true
+	}
+	if state.Suppressed {
+		transition.Alert = false
+	}
+
+	return transition
+}
+
+// settleLocked clears the flapping flag once a finding has gone a full window without toggling
+func (s *FindingStateService) settleLocked(state *models.FindingState, at time.Time) {
+	state.Transitions = pruneTransitions(state.Transitions, at, s.window)
+	if state.Flapping && len(state.Transitions) == 0 {
+		state.Flapping = false
+		state.FlappingSince = nil
+	}
+}
+
+// agentStatesLocked returns an agent's finding states, loading them from the database on first use
+func (s *FindingStateService) agentStatesLocked(agentID uuid.UUID) map[string]*models.FindingState {
+	if states, ok := s.states[agentID]; ok {
+		return states
+	}
+
+	states := make(map[string]*models.FindingState)
+	var stored []models.FindingState
+	if err := s.db.Where("agent_id = ?", agentID).Find(&stored).Error; err != nil {
+		log.Printf("Failed to load finding states for agent %s: %v", agentID, err)
+	}
+	for i := range stored {
+		states[stored[i].FindingKey] = &stored[i]
+	}
+
+	s.states[agentID] = states
+	return states
+}
+
+// pruneTransitions drops transition times that fall outside the correlation window
+func pruneTransitions(transitions []time.Time, now time.Time, window time.Duration) []time.Time {
+	cutoff := now.Add(-window)
+	kept := transitions[:0]
+	for _, t := range transitions {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
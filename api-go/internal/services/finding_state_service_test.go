@@ -0,0 +1,222 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB builds statements without a database behind it
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	return db
+}
+
+func TestObserveDelta(t *testing.T) {
+	db := dryRunDB(t)
+	agentID, orgID := uuid.New(), uuid.New()
+	s := &FindingStateService{threshold: 3, window: time.Hour, states: map[uuid.UUID]map[string]*models.FindingState{agentID: {}}}
+	finding := func(title, severity string) models.Vulnerability {
+		return models.Vulnerability{Type: "container", Title: title, Severity: models.SeverityLevel(severity)}
+	}
+	privileged, rootUser, latestTag := finding("Privileged container", "high"), finding("Runs as root", "medium"), finding("Uses latest tag", "low")
+	key := func(v models.Vulnerability) string { return FindingKey(&v) }
+
+	start := time.Now()
+	_, err := s.Observe(db, agentID, orgID, "container", "medium", []models.Vulnerability{privileged, rootUser, latestTag}, nil, start)
+	require.NoError(t, err)
+
+	// The agent resends only the changed finding and names the rest by key
+	privileged.Severity = "critical"
+	transitions, err := s.ObserveDelta(db, agentID, orgID, "container", "medium", []models.Vulnerability{privileged}, &models.FindingDelta{
+		Resolved:  []string{key(latestTag)},
+		Unchanged: []string{key(rootUser), "unknown-key"},
+	}, nil, start.Add(time.Minute))
+	require.NoError(t, err)
+
+	states := s.states[agentID]
+	require.Len(t, states, 3, "a delta never creates findings from keys alone")
+	assert.Equal(t, "critical", states[key(privileged)].Severity)
+	assert.Equal(t, models.FindingStatusOpen, states[key(rootUser)].Status)
+	assert.Equal(t, start.Add(time.Minute), states[key(rootUser)].LastSeen)
+	assert.Equal(t, models.FindingStatusResolved, states[key(latestTag)].Status)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, models.FindingStatusResolved, transitions[0].To)
+
+	// Findings the delta doesn't name are left alone, unlike a full report
+	_, err = s.ObserveDelta(db, agentID, orgID, "container", "medium", nil, &models.FindingDelta{}, nil, start.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, models.FindingStatusOpen, states[key(privileged)].Status)
+
+	// A finding reported unchanged after being resolved is open again
+	transitions, err = s.ObserveDelta(db, agentID, orgID, "container", "medium", nil, &models.FindingDelta{Unchanged: []string{key(latestTag)}}, nil, start.Add(3*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, models.FindingStatusOpen, states[key(latestTag)].Status)
+}
+
+func TestObserveAppliesSuppressionRules(t *testing.T) {
+	db := dryRunDB(t)
+	agentID, orgID := uuid.New(), uuid.New()
+	s := &FindingStateService{threshold: 3, window: time.Hour, states: map[uuid.UUID]map[string]*models.FindingState{agentID: {}}}
+	accepted := models.Vulnerability{Type: "dependency", CVEID: "CVE-2025-1111", PackageName: "libfoo", PackageVersion: "1.0.0", Severity: "high"}
+	other := models.Vulnerability{Type: "dependency", CVEID: "CVE-2025-2222", PackageName: "libbar", Severity: "high"}
+	rules := []models.SuppressionRule{{ID: uuid.New(), PackageName: "libfoo", PackageVersion: "1.0.0", Owner: "alice", Reason: "Accepted risk"}}
+
+	start := time.Now()
+	transitions, err := s.Observe(db, agentID, orgID, "dependency", "medium", []models.Vulnerability{accepted, other}, rules, start)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	for _, transition := range transitions {
+		assert.Equal(t, transition.State.CVEID != accepted.CVEID, transition.Alert, "suppressed findings raise no alerts")
+	}
+	state := s.states[agentID][FindingKey(&accepted)]
+	assert.True(t, state.Suppressed)
+
+	// Upgrading out of the suppressed version reopens the finding
+	accepted.PackageVersion = "1.0.1"
+	_, err = s.Observe(db, agentID, orgID, "dependency", "medium", []models.Vulnerability{accepted, other}, rules, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, state.Suppressed)
+	assert.Equal(t, "1.0.1", state.PackageVersion)
+}
+
+func TestObserve(t *testing.T) {
+	db := dryRunDB(t)
+	agentID, orgID := uuid.New(), uuid.New()
+	s := &FindingStateService{threshold: 3, window: time.Hour, states: map[uuid.UUID]map[string]*models.FindingState{agentID: {}}}
+	openssl := models.Vulnerability{Type: "dependency", CVEID: "CVE-2025-1111", PackageName: "openssl", Severity: "high"}
+	zlib := models.Vulnerability{Type: "dependency", CVEID: "CVE-2025-2222", PackageName: "zlib", Severity: "medium"}
+	sshRoot := models.Vulnerability{Type: "config", Title: "SSH permits root login", Severity: "high"}
+	key := func(v models.Vulnerability) string { return FindingKey(&v) }
+
+	start := time.Now()
+	transitions, err := s.Observe(db, agentID, orgID, "software", "medium", []models.Vulnerability{openssl, zlib, openssl}, nil, start)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2, "a finding reported twice is one finding")
+	for _, transition := range transitions {
+		assert.Equal(t, "", transition.From)
+		assert.Equal(t, models.FindingStatusOpen, transition.To)
+		assert.True(t, transition.Alert)
+	}
+	_, err = s.Observe(db, agentID, orgID, "system", "medium", []models.Vulnerability{sshRoot}, nil, start)
+	require.NoError(t, err)
+
+	// Findings still reported cause no transitions
+	transitions, err = s.Observe(db, agentID, orgID, "software", "medium", []models.Vulnerability{openssl, zlib}, nil, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+
+	// Only the reported scope's missing findings are resolved
+	transitions, err = s.Observe(db, agentID, orgID, "software", "medium", []models.Vulnerability{openssl}, nil, start.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, key(zlib), transitions[0].State.FindingKey)
+	assert.Equal(t, models.FindingStatusOpen, transitions[0].From)
+	assert.Equal(t, models.FindingStatusResolved, transitions[0].To)
+	states := s.states[agentID]
+	assert.Equal(t, models.FindingStatusOpen, states[key(sshRoot)].Status)
+
+	// A resolved finding reported again is reopened
+	transitions, err = s.Observe(db, agentID, orgID, "software", "medium", []models.Vulnerability{openssl, zlib}, nil, start.Add(3*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, models.FindingStatusResolved, transitions[0].From)
+	assert.Equal(t, models.FindingStatusOpen, transitions[0].To)
+	assert.True(t, transitions[0].Alert)
+	assert.Equal(t, 2, states[key(zlib)].TransitionCount)
+	assert.Equal(t, start, states[key(zlib)].FirstSeen)
+}
+
+func TestFindingFlapping(t *testing.T) {
+	s := &FindingStateService{threshold: 2, window: time.Hour}
+	start := time.Now()
+	state := &models.FindingState{Status: models.FindingStatusOpen}
+
+	// Toggles alert until there are more than threshold of them in the window
+	statuses := []string{models.FindingStatusResolved, models.FindingStatusOpen, models.FindingStatusResolved, models.FindingStatusOpen}
+	var transitions []models.FindingTransition
+	for i, to := range statuses {
+		transitions = append(transitions, s.transitionLocked(state, to, start.Add(time.Duration(i)*time.Minute)))
+	}
+	assert.True(t, transitions[0].Alert)
+	assert.True(t, transitions[1].Alert)
+	assert.True(t, transitions[2].BecameFlapping)
+	assert.True(t, transitions[2].Alert, "becoming flapping alerts once")
+	assert.False(t, transitions[3].BecameFlapping)
+	assert.False(t, transitions[3].Alert, "further toggles are quiet")
+	assert.True(t, state.Flapping)
+	assert.Equal(t, start.Add(2*time.Minute), *state.FlappingSince)
+	assert.Equal(t, 4, state.TransitionCount)
+
+	// It stays flapping while toggles remain in the window
+	s.settleLocked(state, start.Add(30*time.Minute))
+	assert.True(t, state.Flapping)
+	assert.Len(t, state.Transitions, 4)
+
+	// A full window without toggling settles it
+	s.settleLocked(state, start.Add(3*time.Minute+time.Hour))
+	assert.False(t, state.Flapping)
+	assert.Nil(t, state.FlappingSince)
+	assert.Empty(t, state.Transitions)
+
+	// Old toggles fall out of the window and don't count
+	transition := s.transitionLocked(state, models.FindingStatusResolved, start.Add(3*time.Hour))
+	assert.True(t, transition.Alert)
+	assert.False(t, state.Flapping)
+	assert.Len(t, state.Transitions, 1)
+
+	// Suppressed findings never alert
+	state.Suppressed = true
+	transition = s.transitionLocked(state, models.FindingStatusOpen, start.Add(3*time.Hour+time.Minute))
+	assert.False(t, transition.Alert)
+	assert.Equal(t, models.FindingStatusOpen, state.Status)
+}
+
+func TestObserveSettlesFlappingFindings(t *testing.T) {
+	db := dryRunDB(t)
+	agentID, orgID := uuid.New(), uuid.New()
+	s := &FindingStateService{threshold: 1, window: time.Hour, states: map[uuid.UUID]map[string]*models.FindingState{agentID: {}}}
+	finding := models.Vulnerability{Type: "network", Title: "Telnet open", Severity: "high"}
+	other := models.Vulnerability{Type: "network", Title: "FTP open", Severity: "low"}
+
+	start := time.Now()
+	for i, findings := range [][]models.Vulnerability{{finding}, {other}, {finding, other}, {other}} {
+		_, err := s.Observe(db, agentID, orgID, "network", "medium", findings, nil, start.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+	state := s.states[agentID][FindingKey(&finding)]
+	require.True(t, state.Flapping)
+	require.Equal(t, models.FindingStatusResolved, state.Status)
+
+	// Still missing a window later, the resolved finding settles without a transition
+	transitions, err := s.Observe(db, agentID, orgID, "network", "medium", []models.Vulnerability{other}, nil, start.Add(4*time.Minute+time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+	assert.False(t, state.Flapping)
+}
+
+func TestMergeVulnerabilities(t *testing.T) {
+	v := func(title, severity string) models.Vulnerability {
+		return models.Vulnerability{Type: "aiml", Title: title, Severity: models.SeverityLevel(severity)}
+	}
+	existing := []models.Vulnerability{v("Pickled model", "high"), v("Unlicensed dataset", "low")}
+	resolved := v("Unlicensed dataset", "low")
+
+	merged := mergeVulnerabilities(existing, []models.Vulnerability{v("Pickled model", "critical"), v("PII in dataset", "high")}, []string{FindingKey(&resolved)})
+
+	// Findings reported again replace their earlier copy rather than duplicating it
+	require.Len(t, merged, 2)
+	assert.Equal(t, "Pickled model", merged[0].Title)
+	assert.Equal(t, models.SeverityLevel("critical"), merged[0].Severity)
+	assert.Equal(t, "PII in dataset", merged[1].Title)
+}
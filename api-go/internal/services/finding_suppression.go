@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidSuppressionMode is returned for a suppression mode other than the supported ones
+var ErrInvalidSuppressionMode = errors.New("invalid suppression mode")
+
+// Suppress suppresses one of an organization's findings until the reopen
+// condition of its mode is met. A finding suppressed until a fix is published
+// is reopened by the next re-enrichment backfill that finds a fixed version
+// of its package, and its callback URL, if any, is notified.
+func (s *FindingStateService) Suppress(organizationID, id uuid.UUID, req *models.SuppressFindingRequest) (*models.FindingState, error) {
+	if req.Mode != models.FindingSuppressedUntilFix {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSuppressionMode, req.Mode)
+	}
+	if req.CallbackURL != "" {
+		if err := checkCallbackURL(req.CallbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	return s.updateOrganizationFinding(organizationID, id, func(state *models.FindingState) {
+		state.Suppressed = true
+		state.SuppressionMode = req.Mode
+		state.SuppressionOwner = req.Owner
+		state.SuppressionReason = req.Reason
+		state.SuppressionCallbackURL = req.CallbackURL
+		state.SuppressedAt = &now
+		state.SuppressionRuleID = nil
+		state.SuppressionExpiresAt = nil
+		state.FixVersion = ""
+		state.ReopenedAt = nil
+		state.ReopenNote = ""
+	})
+}
+
+// Unsuppress lifts the suppression of one of an organization's findings by
+// hand. A finding a suppression rule still matches is suppressed again by its
+// next scan.
+func (s *FindingStateService) Unsuppress(organizationID, id uuid.UUID) (*models.FindingState, error) {
+	return s.updateOrganizationFinding(organizationID, id, liftSuppression)
+}
+
+// liftSuppression clears a finding's suppression
+func liftSuppression(state *models.FindingState) {
+	state.Suppressed = false
+	state.SuppressionMode = ""
+	state.SuppressionOwner = ""
+	state.SuppressionReason = ""
+	state.SuppressionCallbackURL = ""
+	state.SuppressedAt = nil
+	state.SuppressionRuleID = nil
+	state.SuppressionExpiresAt = nil
+}
+
+// reopenFixed lifts the suppression of a finding suppressed until a fix is
+// published once vuln, its current enrichment, names a fixed version. It
+// reports whether the finding reopened. The suppression's owner and callback
+// are kept so the owner can be notified.
+func reopenFixed(state *models.FindingState, vuln *models.Vulnerability, at time.Time) bool {
+	if !state.Suppressed || state.SuppressionMode != models.FindingSuppressedUntilFix {
+		return false
+	}
+	fix := fixVersion(vuln)
+	if fix == "" {
+		return false
+	}
+
+	state.Suppressed = false
+	state.SuppressionMode = ""
+	state.FixVersion = fix
+	state.ReopenedAt = &at
+	state.ReopenNote = fmt.Sprintf("Reopened: %s is fixed in %s %s, suppressed until a fix was published", state.CVEID, state.PackageName, fix)
+	return true
+}
+
+// suppressionTarget is what suppression rules are matched against
+type suppressionTarget struct {
+	AgentID        uuid.UUID
+	CVEID          string
+	PackageName    string
+	PackageVersion string
+	FindingKey     string
+}
+
+// findingSuppressionTarget returns what suppression rules match a tracked finding on
+func findingSuppressionTarget(state *models.FindingState) suppressionTarget {
+	return suppressionTarget{
+		AgentID:        state.AgentID,
+		CVEID:          state.CVEID,
+		PackageName:    state.PackageName,
+		PackageVersion: state.PackageVersion,
+		FindingKey:     state.FindingKey,
+	}
+}
+
+// matchSuppressionRule returns the oldest of rules active at the given time
+// that matches target, nil if none does. CVEs and packages are compared
+// case-insensitively.
+func matchSuppressionRule(rules []models.SuppressionRule, target suppressionTarget, at time.Time) *models.SuppressionRule {
+	for i := range rules {
+		rule := &rules[i]
+		switch {
+		case !rule.Active(at):
+		case rule.CVEID != "" && !strings.EqualFold(rule.CVEID, strings.TrimSpace(target.CVEID)):
+		case rule.PackageName != "" && !strings.EqualFold(rule.PackageName, strings.TrimSpace(target.PackageName)):
+		case rule.PackageVersion != "" && rule.PackageVersion != strings.TrimSpace(target.PackageVersion):
+		case rule.FindingKey != "" && rule.FindingKey != target.FindingKey:
+		case rule.AgentID != nil && *rule.AgentID != target.AgentID:
+		default:
+			return rule
+		}
+	}
+	return nil
+}
+
+// applySuppressionRules suppresses a finding one of rules, its
+// organization's, matches at the given time, and reopens a finding
+// suppressed by a rule that has since expired or no longer matches it.
+// Findings suppressed by hand are left as they are. It reports whether the
+// finding changed.
+func applySuppressionRules(state *models.FindingState, rules []models.SuppressionRule, at time.Time) bool {
+	if state.Suppressed && state.SuppressionMode != models.FindingSuppressedByRule {
+		return false
+	}
+
+	rule := matchSuppressionRule(rules, findingSuppressionTarget(state), at)
+	switch {
+	case rule != nil:
+		if state.Suppressed && state.SuppressionRuleID != nil && *state.SuppressionRuleID == rule.ID {
+			return false
+		}
+		liftSuppression(state)
+		state.Suppressed = true
+		state.SuppressionMode = models.FindingSuppressedByRule
+		state.SuppressionOwner = rule.Owner
+		state.SuppressionReason = rule.Reason
+		state.SuppressedAt = &at
+		state.SuppressionRuleID = &rule.ID
+		state.SuppressionExpiresAt = rule.ExpiresAt
+		state.FixVersion = ""
+		state.ReopenedAt = nil
+		state.ReopenNote = ""
+		return true
+	case state.Suppressed:
+		note := "Reopened: its suppression rule no longer applies"
+		if state.SuppressionExpiresAt != nil && !at.Before(*state.SuppressionExpiresAt) {
+			note = fmt.Sprintf("Reopened: its suppression rule expired at %s", state.SuppressionExpiresAt.UTC().Format(time.RFC3339))
+		}
+		liftSuppression(state)
+		state.ReopenedAt = &at
+		state.ReopenNote = note
+		return true
+	}
+	return false
+}
+
+// fixVersion returns the first version a vulnerability is fixed in, empty while no fix is known
+func fixVersion(v *models.Vulnerability) string {
+	for _, version := range v.PatchedVersions {
+		if version = strings.TrimSpace(version); version != "" {
+			return version
+		}
+	}
+	if version, ok := v.EnrichmentData["fix_version"].(string); ok {
+		return strings.TrimSpace(version)
+	}
+	return ""
+}
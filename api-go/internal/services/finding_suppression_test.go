@@ -0,0 +1,124 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReopenFixedWaitsForPublishedFix(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	state := &models.FindingState{
+		CVEID:                  "CVE-2025-1234",
+		PackageName:            "libfoo",
+		Suppressed:             true,
+		SuppressionMode:        models.FindingSuppressedUntilFix,
+		SuppressionOwner:       "alice@example.com",
+		SuppressionCallbackURL: "https://hooks.example.com/owner",
+	}
+
+	assert.False(t, reopenFixed(state, &models.Vulnerability{}, at), "no fix published yet")
+	assert.True(t, state.Suppressed)
+
+	require.True(t, reopenFixed(state, &models.Vulnerability{PatchedVersions: []string{" ", "1.2.4"}}, at))
+	assert.False(t, state.Suppressed)
+	assert.Equal(t, "1.2.4", state.FixVersion)
+	assert.Equal(t, &at, state.ReopenedAt)
+	assert.Contains(t, state.ReopenNote, "libfoo 1.2.4")
+	assert.Equal(t, "alice@example.com", state.SuppressionOwner, "kept to notify the owner")
+
+	assert.False(t, reopenFixed(state, &models.Vulnerability{PatchedVersions: []string{"1.2.5"}}, at), "already reopened")
+}
+
+func TestFixVersionFallsBackToEnrichmentData(t *testing.T) {
+	vuln := &models.Vulnerability{EnrichmentData: map[string]any{"fix_version": "2.0.1"}}
+	assert.Equal(t, "2.0.1", fixVersion(vuln))
+}
+
+func TestMatchSuppressionRule(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	agentID, otherAgent := uuid.New(), uuid.New()
+	expired := at.Add(-time.Hour)
+	target := suppressionTarget{AgentID: agentID, CVEID: "cve-2025-1234", PackageName: "LibFoo", PackageVersion: "1.2.3", FindingKey: "abc123"}
+
+	for _, tc := range []struct {
+		name    string
+		rule    models.SuppressionRule
+		matches bool
+	}{
+		{"cve", models.SuppressionRule{CVEID: "CVE-2025-1234"}, true},
+		{"other cve", models.SuppressionRule{CVEID: "CVE-2025-9999"}, false},
+		{"package, any version", models.SuppressionRule{PackageName: "libfoo"}, true},
+		{"package and version", models.SuppressionRule{PackageName: "libfoo", PackageVersion: "1.2.3"}, true},
+		{"package, other version", models.SuppressionRule{PackageName: "libfoo", PackageVersion: "1.2.4"}, false},
+		{"finding key", models.SuppressionRule{FindingKey: "abc123"}, true},
+		{"asset", models.SuppressionRule{AgentID: &agentID}, true},
+		{"cve on another asset", models.SuppressionRule{CVEID: "CVE-2025-1234", AgentID: &otherAgent}, false},
+		{"expired", models.SuppressionRule{CVEID: "CVE-2025-1234", ExpiresAt: &expired}, false},
+	} {
+		got := matchSuppressionRule([]models.SuppressionRule{tc.rule}, target, at)
+		assert.Equal(t, tc.matches, got != nil, tc.name)
+	}
+}
+
+func TestApplySuppressionRules(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	expires := at.Add(time.Hour)
+	rule := models.SuppressionRule{ID: uuid.New(), CVEID: "CVE-2025-1234", Owner: "alice@example.com", Reason: "Not reachable", ExpiresAt: &expires}
+	state := &models.FindingState{CVEID: "CVE-2025-1234", Status: models.FindingStatusOpen}
+
+	require.True(t, applySuppressionRules(state, []models.SuppressionRule{rule}, at))
+	assert.True(t, state.Suppressed)
+	assert.Equal(t, models.FindingSuppressedByRule, state.SuppressionMode)
+	assert.Equal(t, &rule.ID, state.SuppressionRuleID)
+	assert.Equal(t, "alice@example.com", state.SuppressionOwner)
+	assert.Equal(t, &expires, state.SuppressionExpiresAt)
+	assert.False(t, applySuppressionRules(state, []models.SuppressionRule{rule}, at.Add(time.Minute)), "already suppressed by the rule")
+
+	// Once the rule expires the finding reopens
+	require.True(t, applySuppressionRules(state, []models.SuppressionRule{rule}, expires))
+	assert.False(t, state.Suppressed)
+	assert.Nil(t, state.SuppressionRuleID)
+	assert.Equal(t, &expires, state.ReopenedAt)
+	assert.Contains(t, state.ReopenNote, "expired")
+
+	// Findings suppressed by hand are left to their owner
+	manual := &models.FindingState{CVEID: "CVE-2025-1234", Suppressed: true, SuppressionMode: models.FindingSuppressedUntilFix}
+	assert.False(t, applySuppressionRules(manual, nil, at))
+	assert.False(t, applySuppressionRules(manual, []models.SuppressionRule{rule}, at))
+	assert.Equal(t, models.FindingSuppressedUntilFix, manual.SuppressionMode)
+}
+
+func TestSuppressIsScopedToOrganization(t *testing.T) {
+	db := testdb.Open(t, &models.FindingState{})
+	s := NewFindingStateService(db, &config.Config{})
+	orgA, orgB := uuid.New(), uuid.New()
+	finding := models.FindingState{ID: uuid.New(), AgentID: uuid.New(), OrganizationID: orgA, FindingKey: "k1", CVEID: "CVE-2025-1111", Status: models.FindingStatusOpen}
+	require.NoError(t, db.Create(&finding).Error)
+	req := &models.SuppressFindingRequest{Mode: models.FindingSuppressedUntilFix, Owner: "alice", CallbackURL: "https://hooks.example.com/reopened"}
+
+	_, err := s.Suppress(orgB, finding.ID, req)
+	assert.ErrorIs(t, err, ErrFindingNotFound, "another organization's findings are not found")
+	_, err = s.Unsuppress(orgB, finding.ID)
+	assert.ErrorIs(t, err, ErrFindingNotFound)
+
+	_, err = s.Suppress(orgA, finding.ID, &models.SuppressFindingRequest{Mode: models.FindingSuppressedUntilFix, Owner: "alice", CallbackURL: "http://169.254.169.254/latest"})
+	assert.ErrorIs(t, err, ErrInvalidCallbackURL)
+
+	suppressed, err := s.Suppress(orgA, finding.ID, req)
+	require.NoError(t, err)
+	assert.True(t, suppressed.Suppressed)
+	assert.Equal(t, "https://hooks.example.com/reopened", suppressed.SuppressionCallbackURL)
+
+	lifted, err := s.Unsuppress(orgA, finding.ID)
+	require.NoError(t, err)
+	assert.False(t, lifted.Suppressed)
+	assert.Empty(t, lifted.SuppressionCallbackURL)
+}
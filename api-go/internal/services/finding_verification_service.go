@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrFindingNotFound is returned when a finding does not exist
+	ErrFindingNotFound = errors.New("finding not found")
+	// ErrVerificationNotFound is returned when a verification does not exist or belongs to another finding
+	ErrVerificationNotFound = errors.New("verification not found")
+)
+
+// FindingVerificationService answers "is this finding still true?" without a
+// full rescan: it asks the agent that reported a finding, through a
+// verify_finding command, to re-run just the check behind it, and records the
+// outcome, the check's result and its evidence
+type FindingVerificationService struct {
+	db            *gorm.DB
+	commands      *AgentCommandService
+	findingStates *FindingStateService
+	hostRisk      *HostRiskService
+	evidence      *EvidenceService
+	enrichment    *EnrichmentService
+}
+
+// NewFindingVerificationService creates a new finding verification service
+func NewFindingVerificationService(db *gorm.DB, commands *AgentCommandService, findingStates *FindingStateService, hostRisk *HostRiskService, evidence *EvidenceService, enrichment *EnrichmentService) *FindingVerificationService {
+	return &FindingVerificationService{
+		db:            db,
+		commands:      commands,
+		findingStates: findingStates,
+		hostRisk:      hostRisk,
+		evidence:      evidence,
+		enrichment:    enrichment,
+	}
+}
+
+// Verify queues a re-check of one of an organization's findings on its
+// agent. A verification already waiting on the agent is returned instead of
+// queueing another.
+func (s *FindingVerificationService) Verify(ctx context.Context, organizationID, findingID uuid.UUID, req *models.VerifyFindingRequest) (*models.FindingVerification, error) {
+	finding, err := s.findingStates.GetFinding(findingID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && finding.OrganizationID != organizationID) {
+		return nil, ErrFindingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load finding: %w", err)
+	}
+
+	var pending models.FindingVerification
+	err = s.db.Where("finding_id = ? AND status = ?", findingID, models.VerificationPending).
+		Order("created_at DESC").First(&pending).Error
+	if err == nil {
+		s.refresh(ctx, &pending)
+		if pending.Status == models.VerificationPending {
+			return &pending, nil
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load pending verification: %w", err)
+	}
+
+	now := time.Now()
+	verification := &models.FindingVerification{
+		ID:             uuid.New(),
+		FindingID:      finding.ID,
+		AgentID:        finding.AgentID,
+		OrganizationID: finding.OrganizationID,
+		Status:         models.VerificationPending,
+		RequestedBy:    req.RequestedBy,
+		CreatedAt:      now,
+	}
+	cmd := s.commands.newCommand(finding.AgentID, finding.OrganizationID, models.AgentCommandVerifyFinding, map[string]interface{}{
+		"verification_id": verification.ID,
+		"finding_id":      finding.ID,
+		"finding_key":     finding.FindingKey,
+		"scope":           finding.Scope,
+		"title":           finding.Title,
+		"cve_id":          finding.CVEID,
+		"package_name":    finding.PackageName,
+	}, now)
+	verification.CommandID = cmd.ID
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&cmd).Error; err != nil {
+			return err
+		}
+		return tx.Create(verification).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue verification: %w", err)
+	}
+	return verification, nil
+}
+
+// Complete records the outcome of a verify_finding command the agent has
+// finished or failed. Verifications already recorded are left as they are.
+func (s *FindingVerificationService) Complete(ctx context.Context, cmd *models.AgentCommand) error {
+	var verification models.FindingVerification
+	if err := s.db.Where("command_id = ?", cmd.ID).First(&verification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationNotFound
+		}
+		return err
+	}
+	return s.complete(ctx, cmd, &verification)
+}
+
+// List returns the verifications of one of an organization's findings, newest first
+func (s *FindingVerificationService) List(ctx context.Context, organizationID, findingID uuid.UUID) ([]models.FindingVerification, error) {
+	verifications := []models.FindingVerification{}
+	if err := s.db.Where("finding_id = ? AND organization_id = ?", findingID, organizationID).Order("created_at DESC").Find(&verifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to list verifications: %w", err)
+	}
+	for i := range verifications {
+		s.refresh(ctx, &verifications[i])
+	}
+	return verifications, nil
+}
+
+// Get returns one of the verifications of an organization's finding with the
+// evidence the re-check captured
+func (s *FindingVerificationService) Get(ctx context.Context, organizationID, findingID, verificationID uuid.UUID) (*models.FindingVerification, error) {
+	var verification models.FindingVerification
+	if err := s.db.Where("id = ? AND finding_id = ? AND organization_id = ?", verificationID, findingID, organizationID).First(&verification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, err
+	}
+	s.refresh(ctx, &verification)
+
+	if len(verification.EvidenceIDs) > 0 {
+		if err := s.db.Where("id IN ?", verification.EvidenceIDs).Order("created_at DESC").Find(&verification.Evidence).Error; err != nil {
+			return nil, fmt.Errorf("failed to load verification evidence: %w", err)
+		}
+	}
+	return &verification, nil
+}
+
+// refresh completes a pending verification whose command has finished
+// without the outcome being recorded, such as one that expired because the
+// agent was offline
+func (s *FindingVerificationService) refresh(ctx context.Context, verification *models.FindingVerification) {
+	if verification.Status != models.VerificationPending {
+		return
+	}
+	cmd, err := s.commands.GetCommand(verification.CommandID)
+	if err != nil {
+		log.Printf("Failed to load command %s of verification %s: %v", verification.CommandID, verification.ID, err)
+		return
+	}
+	if cmd.Status != models.AgentCommandCompleted && cmd.Status != models.AgentCommandFailed {
+		return
+	}
+	if err := s.complete(ctx, cmd, verification); err != nil {
+		log.Printf("Failed to complete verification %s: %v", verification.ID, err)
+	}
+}
+
+// complete records a finished command's outcome on its verification and finding
+func (s *FindingVerificationService) complete(ctx context.Context, cmd *models.AgentCommand, verification *models.FindingVerification) error {
+	if verification.Status != models.VerificationPending {
+		return nil
+	}
+	finding, err := s.findingStates.GetFinding(verification.FindingID)
+	if err != nil {
+		return fmt.Errorf("failed to load finding: %w", err)
+	}
+
+	report := s.rematchSoftware(finding, verificationReport(cmd))
+	now := time.Now()
+	verification.Status = report.Status
+	verification.Details = report.Details
+	verification.CheckResult = report.CheckResult
+	verification.CompletedAt = &now
+	if len(report.Evidence) > 0 {
+		verification.EvidenceIDs = s.evidence.storeAll(ctx, finding.AgentID, finding.OrganizationID, finding.FindingKey, report.Evidence)
+	}
+
+	var transition *models.FindingTransition
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(verification).
+			Where("status = ?", models.VerificationPending).
+			Select("status", "details", "check_result", "evidence_ids", "completed_at").
+			Updates(verification)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil // Recorded concurrently
+		}
+		applied, err := s.findingStates.ApplyVerification(tx, finding.AgentID, finding.FindingKey, verification.Status, now)
+		transition = applied
+		return err
+	})
+	if err != nil {
+		s.findingStates.Forget(finding.AgentID)
+		return fmt.Errorf("failed to record verification: %w", err)
+	}
+
+	if transition != nil {
+		log.Printf("Verification %s moved finding %s on agent %s from %s to %s", verification.ID, finding.FindingKey, finding.AgentID, transition.From, transition.To)
+		if _, err := s.hostRisk.Recompute(finding.AgentID); err != nil {
+			log.Printf("Failed to rescore agent %s after verification: %v", finding.AgentID, err)
+		}
+	}
+	return nil
+}
+
+// rematchSoftware settles a software finding whose package the agent found
+// still installed. The agent only re-inventories the package, so whether the
+// installed version is still affected by the finding's CVE is matched here,
+// the same way ingestion matches it.
+func (s *FindingVerificationService) rematchSoftware(finding *models.FindingState, report models.FindingVerificationReport) models.FindingVerificationReport {
+	if finding.Scope != "software" || finding.CVEID == "" || report.Status != models.VerificationConfirmed {
+		return report
+	}
+
+	version, _ := report.CheckResult["observed"].(string)
+	vulnerabilities, err := s.enrichment.EnrichDependencies([]models.Dependency{{Name: finding.PackageName, Version: version}})
+	if err != nil {
+		report.Status = models.VerificationUnverifiable
+		report.Details = fmt.Sprintf("%s, but matching it against %s failed: %v", report.Details, finding.CVEID, err)
+		return report
+	}
+	for _, vulnerability := range vulnerabilities {
+		if strings.EqualFold(vulnerability.CVEID, finding.CVEID) {
+			report.Details = fmt.Sprintf("%s %s is still affected by %s", finding.PackageName, version, finding.CVEID)
+			return report
+		}
+	}
+	report.Status = models.VerificationResolved
+	report.Details = fmt.Sprintf("%s %s is no longer affected by %s", finding.PackageName, version, finding.CVEID)
+	return report
+}
+
+// verificationReport reads the outcome of a finished verify_finding command.
+// A failed command, or a result the agent did not fill in properly, leaves the
+// finding unverifiable.
+func verificationReport(cmd *models.AgentCommand) models.FindingVerificationReport {
+	if cmd.Status == models.AgentCommandFailed {
+		return models.FindingVerificationReport{
+			Status:  models.VerificationUnverifiable,
+			Details: "Agent could not re-run the check: " + cmd.Error,
+		}
+	}
+
+	var report models.FindingVerificationReport
+	encoded, _ := json.Marshal(cmd.Result)
+	if err := json.Unmarshal(encoded, &report); err != nil {
+		return models.FindingVerificationReport{
+			Status:  models.VerificationUnverifiable,
+			Details: fmt.Sprintf("Agent reported a malformed verification result: %v", err),
+		}
+	}
+	switch report.Status {
+	case models.VerificationConfirmed, models.VerificationResolved, models.VerificationUnverifiable:
+	default:
+		return models.FindingVerificationReport{
+			Status:  models.VerificationUnverifiable,
+			Details: fmt.Sprintf("Agent reported an unknown verification status %q", report.Status),
+		}
+	}
+	return report
+}
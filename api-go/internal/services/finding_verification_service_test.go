@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/testdb"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerificationReport(t *testing.T) {
+	failed := verificationReport(&models.AgentCommand{Status: models.AgentCommandFailed, Error: "timed out waiting for agent"})
+	assert.Equal(t, models.VerificationUnverifiable, failed.Status)
+	assert.Contains(t, failed.Details, "timed out")
+
+	completed := verificationReport(&models.AgentCommand{Status: models.AgentCommandCompleted, Result: map[string]interface{}{
+		"status":       "resolved",
+		"details":      "UFW is active",
+		"check_result": map[string]interface{}{"passed": true, "command": "ufw status"},
+		"evidence":     []interface{}{map[string]interface{}{"label": "ufw status", "content_type": "text/plain", "data": "U3RhdHVzOiBhY3RpdmU="}},
+	}})
+	assert.Equal(t, models.VerificationResolved, completed.Status)
+	assert.Equal(t, "ufw status", completed.CheckResult["command"])
+	require.Len(t, completed.Evidence, 1)
+	assert.Equal(t, "Status: active", string(completed.Evidence[0].Data))
+
+	unknown := verificationReport(&models.AgentCommand{Status: models.AgentCommandCompleted, Result: map[string]interface{}{"status": "maybe"}})
+	assert.Equal(t, models.VerificationUnverifiable, unknown.Status)
+}
+
+func TestVerifyMovesFindingLifecycle(t *testing.T) {
+	s := &FindingStateService{threshold: 3, window: time.Hour}
+	now := time.Now()
+	state := &models.FindingState{Status: models.FindingStatusOpen}
+
+	assert.Nil(t, s.verifyLocked(state, models.VerificationConfirmed, now), "confirming an open finding changes nothing")
+	assert.Equal(t, models.VerificationConfirmed, state.Verification)
+
+	transition := s.verifyLocked(state, models.VerificationResolved, now)
+	require.NotNil(t, transition)
+	assert.Equal(t, models.FindingStatusResolved, state.Status)
+	assert.Equal(t, 1, state.TransitionCount)
+
+	assert.Nil(t, s.verifyLocked(state, models.VerificationUnverifiable, now))
+	assert.Equal(t, models.FindingStatusResolved, state.Status)
+	assert.Equal(t, models.VerificationUnverifiable, state.Verification)
+
+	transition = s.verifyLocked(state, models.VerificationConfirmed, now)
+	require.NotNil(t, transition)
+	assert.Equal(t, models.FindingStatusOpen, transition.To)
+}
+
+func TestVerifyIsScopedToOrganization(t *testing.T) {
+	db := testdb.Open(t, &models.FindingState{}, &models.FindingVerification{})
+	s := NewFindingVerificationService(db, nil, NewFindingStateService(db, &config.Config{}), nil, nil, nil)
+	orgA, orgB := uuid.New(), uuid.New()
+	finding := models.FindingState{ID: uuid.New(), AgentID: uuid.New(), OrganizationID: orgA, FindingKey: "k1", Status: models.FindingStatusOpen}
+	require.NoError(t, db.Create(&finding).Error)
+	verification := models.FindingVerification{ID: uuid.New(), FindingID: finding.ID, AgentID: finding.AgentID, OrganizationID: orgA, CommandID: uuid.New(), Status: models.VerificationConfirmed}
+	require.NoError(t, db.Create(&verification).Error)
+
+	_, err := s.Verify(context.Background(), orgB, finding.ID, &models.VerifyFindingRequest{})
+	assert.ErrorIs(t, err, ErrFindingNotFound, "another organization's findings are not found")
+	_, err = s.Get(context.Background(), orgB, finding.ID, verification.ID)
+	assert.ErrorIs(t, err, ErrVerificationNotFound)
+	others, err := s.List(context.Background(), orgB, finding.ID)
+	require.NoError(t, err)
+	assert.Empty(t, others)
+
+	own, err := s.List(context.Background(), orgA, finding.ID)
+	require.NoError(t, err)
+	require.Len(t, own, 1)
+	assert.Equal(t, verification.ID, own[0].ID)
+}
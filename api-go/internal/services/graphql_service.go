@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/graph"
+	"zerotrace/api/internal/models"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/google/uuid"
+)
+
+// graphQLMaxListLimit caps the limit argument of list fields, so a query's
+// complexity stays meaningful
+const graphQLMaxListLimit = 1000
+
+// graphQLSeverities are the severities counted in analytics, most severe first
+var graphQLSeverities = []string{"critical", "high", "medium", "low", "info"}
+
+// GraphQLService answers GraphQL queries over an organization's agents,
+// vulnerabilities, findings and analytics, so a dashboard can fetch what it
+// shows in one round trip. Every query is scoped to one organization. The
+// schema is in graph/schema.graphqls, and the service resolves its fields.
+type GraphQLService struct {
+	agents   *AgentService
+	findings *FindingStateService
+	executor *executor.Executor
+}
+
+// NewGraphQLService creates a new GraphQL service
+func NewGraphQLService(agents *AgentService, findings *FindingStateService, cfg *config.Config) *GraphQLService {
+	s := &GraphQLService{
+		agents:   agents,
+		findings: findings,
+	}
+	s.executor = executor.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  s,
+		Complexity: graphQLComplexity(),
+	}))
+	s.executor.Use(graph.DepthLimit(cfg.GraphQLMaxDepth))
+	s.executor.Use(extension.FixedComplexityLimit(cfg.GraphQLMaxComplexity))
+	return s
+}
+
+// graphQLOrganizationKey holds the organization a query is scoped to in its context
+type graphQLOrganizationKey struct{}
+
+// Execute runs a query against the data of one organization. Queries that
+// fail to parse or validate, or exceed the limits, return errors without data.
+func (s *GraphQLService) Execute(ctx context.Context, organizationID uuid.UUID, params *graphql.RawParams) *graphql.Response {
+	ctx = graphql.StartOperationTrace(context.WithValue(ctx, graphQLOrganizationKey{}, organizationID))
+	opCtx, errs := s.executor.CreateOperationContext(ctx, params)
+	if errs != nil {
+		return s.executor.DispatchError(graphql.WithOperationContext(ctx, opCtx), errs)
+	}
+	responses, ctx := s.executor.DispatchOperation(ctx, opCtx)
+	return responses(ctx)
+}
+
+func graphQLOrganization(ctx context.Context) uuid.UUID {
+	organizationID, _ := ctx.Value(graphQLOrganizationKey{}).(uuid.UUID)
+	return organizationID
+}
+
+// graphQLComplexity scores list fields once per item they may return
+func graphQLComplexity() graph.ComplexityRoot {
+	var c graph.ComplexityRoot
+	c.Query.Agents = func(childComplexity int, _ *string, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Query.Vulnerabilities = func(childComplexity int, _, _ *string, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Query.Findings = func(childComplexity int, _ *string, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Agent.Vulnerabilities = func(childComplexity int, _, _ *string, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Agent.Findings = func(childComplexity int, _ *string, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Analytics.RiskiestAgents = func(childComplexity int, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	return c
+}
+
+// Query resolves the root fields
+func (s *GraphQLService) Query() graph.QueryResolver { return graphQLQuery{s} }
+
+// Agent resolves an agent's vulnerabilities and findings
+func (s *GraphQLService) Agent() graph.AgentResolver { return graphQLAgent{s} }
+
+// Vulnerability resolves a vulnerability's severity, enrichment and attribution
+func (s *GraphQLService) Vulnerability() graph.VulnerabilityResolver { return graphQLVulnerability{} }
+
+// Finding resolves the agent a finding is on
+func (s *GraphQLService) Finding() graph.FindingResolver { return graphQLFinding{s} }
+
+// Analytics resolves an organization's analytics
+func (s *GraphQLService) Analytics() graph.AnalyticsResolver { return graphQLAnalytics{s} }
+
+type graphQLQuery struct{ s *GraphQLService }
+
+func (r graphQLQuery) Agents(ctx context.Context, status *string, limit int) ([]*models.Agent, error) {
+	agents := []*models.Agent{}
+	for _, a := range r.s.organizationAgents(ctx) {
+		if status == nil || *status == "" || a.Status == *status {
+			agents = append(agents, a)
+		}
+	}
+	return agents[:min(len(agents), listLimit(limit))], nil
+}
+
+func (r graphQLQuery) Agent(ctx context.Context, id string) (*models.Agent, error) {
+	agentID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent ID: %w", err)
+	}
+	return r.s.organizationAgent(ctx, agentID), nil
+}
+
+func (r graphQLQuery) Vulnerabilities(ctx context.Context, severity, status *string, limit int) ([]*graph.Vulnerability, error) {
+	return filterVulnerabilities(r.s.organizationAgents(ctx), severity, status, limit), nil
+}
+
+func (r graphQLQuery) Findings(ctx context.Context, status *string, limit int) ([]*models.FindingState, error) {
+	return r.s.listFindings(ctx, uuid.Nil, status, limit)
+}
+
+func (r graphQLQuery) Analytics(ctx context.Context) (*graph.Analytics, error) {
+	organizationID := graphQLOrganization(ctx)
+	return &graph.Analytics{
+		OrganizationID: organizationID,
+		Agents:         r.s.organizationAgents(ctx),
+		Stats:          r.s.agents.GetAgentStats(organizationID),
+	}, nil
+}
+
+type graphQLAgent struct{ s *GraphQLService }
+
+func (r graphQLAgent) Vulnerabilities(_ context.Context, agent *models.Agent, severity, status *string, limit int) ([]*graph.Vulnerability, error) {
+	return filterVulnerabilities([]*models.Agent{agent}, severity, status, limit), nil
+}
+
+func (r graphQLAgent) VulnerabilityCount(_ context.Context, agent *models.Agent) (int, error) {
+	return len(agentVulnerabilities(agent)), nil
+}
+
+func (r graphQLAgent) Findings(ctx context.Context, agent *models.Agent, status *string, limit int) ([]*models.FindingState, error) {
+	return r.s.listFindings(ctx, agent.ID, status, limit)
+}
+
+type graphQLVulnerability struct{}
+
+func (graphQLVulnerability) Severity(_ context.Context, vuln *graph.Vulnerability) (string, error) {
+	return string(vuln.Severity), nil
+}
+
+func (graphQLVulnerability) IntroducedBy(_ context.Context, vuln *graph.Vulnerability) (any, error) {
+	if vuln.IntroducedBy == nil {
+		return nil, nil
+	}
+	return vuln.IntroducedBy, nil
+}
+
+func (graphQLVulnerability) Enrichment(_ context.Context, vuln *graph.Vulnerability) (*graph.Enrichment, error) {
+	if len(vuln.EnrichmentData) == 0 {
+		return nil, nil
+	}
+	// Enrichment data is free-form once stored, so it is decoded by its JSON names
+	raw, err := json.Marshal(vuln.EnrichmentData)
+	if err != nil {
+		return nil, err
+	}
+	var enrichment graph.Enrichment
+	if err := json.Unmarshal(raw, &enrichment); err != nil {
+		return nil, fmt.Errorf("invalid enrichment data: %w", err)
+	}
+	return &enrichment, nil
+}
+
+type graphQLFinding struct{ s *GraphQLService }
+
+func (r graphQLFinding) Agent(ctx context.Context, finding *models.FindingState) (*models.Agent, error) {
+	return r.s.organizationAgent(ctx, finding.AgentID), nil
+}
+
+type graphQLAnalytics struct{ s *GraphQLService }
+
+func (graphQLAnalytics) TotalAgents(_ context.Context, a *graph.Analytics) (int, error) {
+	return a.Stats["total_agents"].(int), nil
+}
+
+func (graphQLAnalytics) OnlineAgents(_ context.Context, a *graph.Analytics) (int, error) {
+	return a.Stats["online_agents"].(int), nil
+}
+
+func (graphQLAnalytics) DegradedAgents(_ context.Context, a *graph.Analytics) (int, error) {
+	return a.Stats["degraded_agents"].(int), nil
+}
+
+func (graphQLAnalytics) OfflineAgents(_ context.Context, a *graph.Analytics) (int, error) {
+	return a.Stats["offline_agents"].(int), nil
+}
+
+func (graphQLAnalytics) AverageRiskScore(_ context.Context, a *graph.Analytics) (float64, error) {
+	if len(a.Agents) == 0 {
+		return 0, nil
+	}
+	total := 0.0
+	for _, agent := range a.Agents {
+		total += agent.RiskScore
+	}
+	return total / float64(len(a.Agents)), nil
+}
+
+func (graphQLAnalytics) VulnerabilityCount(_ context.Context, a *graph.Analytics) (int, error) {
+	count := 0
+	for _, agent := range a.Agents {
+		count += len(agentVulnerabilities(agent))
+	}
+	return count, nil
+}
+
+func (graphQLAnalytics) VulnerabilitiesBySeverity(_ context.Context, a *graph.Analytics) ([]*graph.SeverityCount, error) {
+	return countBySeverity(a.Agents), nil
+}
+
+func (r graphQLAnalytics) FlappingFindings(_ context.Context, a *graph.Analytics) (int, error) {
+	flapping, err := r.s.findings.GetFlappingFindings(a.OrganizationID)
+	return len(flapping), err
+}
+
+func (graphQLAnalytics) RiskiestAgents(_ context.Context, a *graph.Analytics, limit int) ([]*models.Agent, error) {
+	agents := append([]*models.Agent(nil), a.Agents...)
+	sort.SliceStable(agents, func(i, j int) bool { return agents[i].RiskScore > agents[j].RiskScore })
+	return agents[:min(len(agents), listLimit(limit))], nil
+}
+
+// organizationAgents returns the agents of the organization a query is
+// scoped to, by name
+func (s *GraphQLService) organizationAgents(ctx context.Context) []*models.Agent {
+	agents := s.agents.GetAgents(graphQLOrganization(ctx))
+	sort.Slice(agents, func(i, j int) bool {
+		if agents[i].Name != agents[j].Name {
+			return agents[i].Name < agents[j].Name
+		}
+		return agents[i].ID.String() < agents[j].ID.String()
+	})
+	return agents
+}
+
+// organizationAgent returns an agent, or nil unless it belongs to the
+// organization a query is scoped to
+func (s *GraphQLService) organizationAgent(ctx context.Context, agentID uuid.UUID) *models.Agent {
+	agent, exists := s.agents.GetAgent(agentID)
+	if !exists || agent.OrganizationID != graphQLOrganization(ctx) {
+		return nil
+	}
+	return agent
+}
+
+// listFindings returns the tracked findings of the organization a query is
+// scoped to, or of one of its agents
+func (s *GraphQLService) listFindings(ctx context.Context, agentID uuid.UUID, status *string, limit int) ([]*models.FindingState, error) {
+	states, err := s.findings.ListFindings(graphQLOrganization(ctx), agentID, optionalString(status), listLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	findings := make([]*models.FindingState, len(states))
+	for i := range states {
+		findings[i] = &states[i]
+	}
+	return findings, nil
+}
+
+// listLimit caps a list field's limit argument to graphQLMaxListLimit
+func listLimit(limit int) int {
+	return max(0, min(limit, graphQLMaxListLimit))
+}
+
+// listComplexity scores a list field as its selections once per item it may return
+func listComplexity(childComplexity, limit int) int {
+	return 1 + childComplexity*max(1, listLimit(limit))
+}
+
+func optionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// filterVulnerabilities returns the agents' vulnerabilities matching the
+// severity and status, up to the limit
+func filterVulnerabilities(agents []*models.Agent, severity, status *string, limit int) []*graph.Vulnerability {
+	wantSeverity, wantStatus := optionalString(severity), optionalString(status)
+	limit = listLimit(limit)
+
+	vulnerabilities := []*graph.Vulnerability{}
+	for _, a := range agents {
+		for _, vuln := range agentVulnerabilities(a) {
+			if len(vulnerabilities) == limit {
+				return vulnerabilities
+			}
+			if wantSeverity != "" && !strings.EqualFold(string(vuln.Severity), wantSeverity) {
+				continue
+			}
+			if wantStatus != "" && vuln.Status != wantStatus {
+				continue
+			}
+			vulnerabilities = append(vulnerabilities, &graph.Vulnerability{Vulnerability: vuln, Agent: a})
+		}
+	}
+	return vulnerabilities
+}
+
+// countBySeverity counts the agents' vulnerabilities of each severity
+func countBySeverity(agents []*models.Agent) []*graph.SeverityCount {
+	counts := make(map[string]int)
+	for _, a := range agents {
+		for _, vuln := range agentVulnerabilities(a) {
+			counts[strings.ToLower(string(vuln.Severity))]++
+		}
+	}
+
+	result := make([]*graph.SeverityCount, 0, len(graphQLSeverities))
+	for _, severity := range graphQLSeverities {
+		result = append(result, &graph.SeverityCount{Severity: severity, Count: counts[severity]})
+	}
+	return result
+}
+
+// agentVulnerabilities returns the vulnerabilities an agent last reported,
+// whether set by its scan results or decoded from stored metadata
+func agentVulnerabilities(agent *models.Agent) []models.Vulnerability {
+	switch vulns := agent.Metadata["vulnerabilities"].(type) {
+	case []models.Vulnerability:
+		return vulns
+	case nil:
+		return nil
+	default:
+		raw, err := json.Marshal(vulns)
+		if err != nil {
+			return nil
+		}
+		var decoded []models.Vulnerability
+		if json.Unmarshal(raw, &decoded) != nil {
+			return nil
+		}
+		return decoded
+	}
+}
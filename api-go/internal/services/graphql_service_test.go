@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLResolvesAgentsVulnerabilitiesAndEnrichment(t *testing.T) {
+	orgID, otherOrgID := uuid.New(), uuid.New()
+	web := &models.Agent{ID: uuid.New(), OrganizationID: orgID, Name: "web01", RiskScore: 7.5, LastSeen: time.Now(), Metadata: map[string]any{
+		"vulnerabilities": []models.Vulnerability{
+			{ID: "v1", Title: "CVE-2024-0001", Severity: "high", Status: "open", EnrichmentData: map[string]any{
+				"source":       "nvd",
+				"threat_intel": []interface{}{map[string]interface{}{"feed": "kev", "context": "ransomware"}},
+			}},
+			{ID: "v2", Title: "Weak cipher", Severity: "low", Status: "open"},
+		},
+	}}
+	db := &models.Agent{ID: uuid.New(), OrganizationID: orgID, Name: "db01", RiskScore: 2, LastSeen: time.Now()}
+	foreign := &models.Agent{ID: uuid.New(), OrganizationID: otherOrgID, Name: "elsewhere"}
+	agents := &AgentService{agents: map[uuid.UUID]*models.Agent{web.ID: web, db.ID: db, foreign.ID: foreign}, heartbeatTimeout: 90 * time.Second}
+
+	s := NewGraphQLService(agents, nil, &config.Config{GraphQLMaxDepth: 6, GraphQLMaxComplexity: 100000})
+	resp := s.Execute(context.Background(), orgID, &graphql.RawParams{
+		Query: `query($id: ID!) {
+			agents { name vulnerabilities(severity: "HIGH") { title enrichment { source threat_intel { feed } } agent { name } } }
+			foreign: agent(id: $id) { name }
+			analytics {
+				total_agents online_agents vulnerability_count
+				vulnerabilities_by_severity { severity count }
+				riskiest_agents(limit: 1) { name }
+			}
+		}`,
+		Variables: map[string]any{"id": foreign.ID.String()},
+	})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{
+		"agents": [
+			{"name": "db01", "vulnerabilities": []},
+			{"name": "web01", "vulnerabilities": [
+				{"title": "CVE-2024-0001", "enrichment": {"source": "nvd", "threat_intel": [{"feed": "kev"}]}, "agent": {"name": "web01"}}
+			]}
+		],
+		"foreign": null,
+		"analytics": {
+			"total_agents": 2, "online_agents": 2, "vulnerability_count": 2,
+			"vulnerabilities_by_severity": [
+				{"severity": "critical", "count": 0}, {"severity": "high", "count": 1}, {"severity": "medium", "count": 0},
+				{"severity": "low", "count": 1}, {"severity": "info", "count": 0}
+			],
+			"riskiest_agents": [{"name": "web01"}]
+		}
+	}`, string(resp.Data))
+}
+
+func TestGraphQLLimitsListComplexity(t *testing.T) {
+	s := NewGraphQLService(&AgentService{agents: map[uuid.UUID]*models.Agent{}}, nil, &config.Config{GraphQLMaxDepth: 6, GraphQLMaxComplexity: 100000})
+
+	// 1000 agents of 1000 vulnerabilities each is far over the limit
+	resp := s.Execute(context.Background(), uuid.New(), &graphql.RawParams{
+		Query: `{ agents(limit: 100000) { vulnerabilities(limit: 1000) { title } } }`,
+	})
+	assert.Nil(t, resp.Data)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "exceeds the limit of 100000")
+}
+
+func TestGraphQLLimitsDepth(t *testing.T) {
+	s := NewGraphQLService(&AgentService{agents: map[uuid.UUID]*models.Agent{}}, nil, &config.Config{GraphQLMaxDepth: 3, GraphQLMaxComplexity: 100000})
+	execute := func(query string) *graphql.Response {
+		return s.Execute(context.Background(), uuid.New(), &graphql.RawParams{Query: query})
+	}
+
+	resp := execute(`{ agents { vulnerabilities { title } } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"agents": []}`, string(resp.Data))
+
+	// Fields spread from fragments count at the depth they are spread at
+	resp = execute(`{ agents { ...withAgent } } fragment withAgent on Agent { vulnerabilities { agent { name } } }`)
+	assert.Nil(t, resp.Data)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "operation has a depth of 4, which exceeds the limit of 3", resp.Errors[0].Message)
+}
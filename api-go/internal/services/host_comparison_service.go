@@ -0,0 +1,149 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HostComparisonService compares the posture of two hosts, for example to
+// explain why one host is vulnerable and another is not, or to check a host
+// against one built from a golden image
+type HostComparisonService struct {
+	db           *gorm.DB
+	agentService *AgentService
+}
+
+// NewHostComparisonService creates a new host comparison service
+func NewHostComparisonService(db *gorm.DB, agentService *AgentService) *HostComparisonService {
+	return &HostComparisonService{
+		db:           db,
+		agentService: agentService,
+	}
+}
+
+// Compare compares the open findings and configuration check states of two
+// of an organization's agents. Findings are matched by finding key, so a
+// comparison covers every scan type that reports findings. A non-empty scope
+// restricts the findings to one scan type. Other organizations' agents are
+// not found.
+func (s *HostComparisonService) Compare(organizationID, agentA, agentB uuid.UUID, scope string) (*models.HostComparison, error) {
+	a, exists := s.agentService.GetAgent(agentA)
+	if !exists || a.OrganizationID != organizationID {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentA)
+	}
+	b, exists := s.agentService.GetAgent(agentB)
+	if !exists || b.OrganizationID != organizationID {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentB)
+	}
+
+	query := s.db.Where("agent_id IN ? AND status = ?", []uuid.UUID{agentA, agentB}, models.FindingStatusOpen)
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	var findings []models.FindingState
+	if err := query.Find(&findings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load open findings: %w", err)
+	}
+
+	var findingsA, findingsB []models.FindingState
+	for _, finding := range findings {
+		if finding.AgentID == agentA {
+			findingsA = append(findingsA, finding)
+		} else {
+			findingsB = append(findingsB, finding)
+		}
+	}
+
+	comparison := compareFindings(findingsA, findingsB)
+	settingsA := configSettingsFromMetadata(a.Metadata)
+	settingsB := configSettingsFromMetadata(b.Metadata)
+	comparison.ConfigDifferences = compareConfigSettings(settingsA, settingsB)
+	comparison.A = comparedHost(a, len(findingsA), settingsA != nil)
+	comparison.B = comparedHost(b, len(findingsB), settingsB != nil)
+	return comparison, nil
+}
+
+// compareFindings splits two hosts' findings into those unique to each and
+// those common to both, each ordered by severity and then title
+func compareFindings(findingsA, findingsB []models.FindingState) *models.HostComparison {
+	inB := make(map[string]bool, len(findingsB))
+	for _, finding := range findingsB {
+		inB[finding.FindingKey] = true
+	}
+	inA := make(map[string]bool, len(findingsA))
+
+	comparison := &models.HostComparison{
+		OnlyA:  []models.FindingState{},
+		OnlyB:  []models.FindingState{},
+		Common: []models.FindingState{},
+	}
+	for _, finding := range findingsA {
+		inA[finding.FindingKey] = true
+		if inB[finding.FindingKey] {
+			comparison.Common = append(comparison.Common, finding)
+		} else {
+			comparison.OnlyA = append(comparison.OnlyA, finding)
+		}
+	}
+	for _, finding := range findingsB {
+		if !inA[finding.FindingKey] {
+			comparison.OnlyB = append(comparison.OnlyB, finding)
+		}
+	}
+
+	for _, list := range [][]models.FindingState{comparison.OnlyA, comparison.OnlyB, comparison.Common} {
+		sortFindingsBySeverity(list)
+	}
+	return comparison
+}
+
+// compareConfigSettings lists the configuration checks whose states differ
+// between two hosts, including checks only one host reported, by name
+func compareConfigSettings(settingsA, settingsB map[string]string) []models.ConfigSettingDifference {
+	differences := []models.ConfigSettingDifference{}
+	for name, valueA := range settingsA {
+		valueB, ok := settingsB[name]
+		if !ok {
+			differences = append(differences, models.ConfigSettingDifference{Setting: name, ValueA: &valueA})
+		} else if valueA != valueB {
+			differences = append(differences, models.ConfigSettingDifference{Setting: name, ValueA: &valueA, ValueB: &valueB})
+		}
+	}
+	for name, valueB := range settingsB {
+		if _, ok := settingsA[name]; !ok {
+			differences = append(differences, models.ConfigSettingDifference{Setting: name, ValueB: &valueB})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool { return differences[i].Setting < differences[j].Setting })
+	return differences
+}
+
+// sortFindingsBySeverity orders findings from critical to low, then by title
+func sortFindingsBySeverity(findings []models.FindingState) {
+	rank := map[string]int{"critical": 4, "high": 3, "medium": 2, "low": 1}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if ri, rj := rank[findings[i].Severity], rank[findings[j].Severity]; ri != rj {
+			return ri > rj
+		}
+		return findings[i].Title < findings[j].Title
+	})
+}
+
+// comparedHost describes one side of a comparison
+func comparedHost(agent *models.Agent, openFindings int, configScanned bool) models.ComparedHost {
+	hostGroup, _ := agent.Metadata["host_group"].(string)
+	return models.ComparedHost{
+		AgentID:       agent.ID,
+		Name:          agent.Name,
+		Hostname:      agent.Hostname,
+		HostGroup:     hostGroup,
+		OpenFindings:  openFindings,
+		ConfigScanned: configScanned,
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestCompareFindingsSplitsUniqueAndCommon(t *testing.T) {
+	findingsA := []models.FindingState{
+		{FindingKey: "log4shell", Title: "CVE-2021-44228", Severity: "critical"},
+		{FindingKey: "ssh-root", Title: "Root login over SSH", Severity: "high"},
+	}
+	findingsB := []models.FindingState{
+		{FindingKey: "log4shell", Title: "CVE-2021-44228", Severity: "critical"},
+		{FindingKey: "tls10", Title: "TLS 1.0 enabled", Severity: "medium"},
+	}
+
+	comparison := compareFindings(findingsA, findingsB)
+
+	if len(comparison.Common) != 1 || comparison.Common[0].FindingKey != "log4shell" {
+		t.Errorf("expected log4shell in common, got %+v", comparison.Common)
+	}
+	if len(comparison.OnlyA) != 1 || comparison.OnlyA[0].FindingKey != "ssh-root" {
+		t.Errorf("expected ssh-root only on A, got %+v", comparison.OnlyA)
+	}
+	if len(comparison.OnlyB) != 1 || comparison.OnlyB[0].FindingKey != "tls10" {
+		t.Errorf("expected tls10 only on B, got %+v", comparison.OnlyB)
+	}
+}
+
+func TestCompareConfigSettingsReportsDifferingAndMissingChecks(t *testing.T) {
+	differences := compareConfigSettings(
+		map[string]string{"firewall": "enabled", "ssh_root_login": "no", "auditd": "running"},
+		map[string]string{"firewall": "enabled", "ssh_root_login": "yes", "selinux": "enforcing"},
+	)
+
+	if len(differences) != 3 {
+		t.Fatalf("expected 3 differences, got %+v", differences)
+	}
+	if d := differences[0]; d.Setting != "auditd" || d.ValueA == nil || d.ValueB != nil {
+		t.Errorf("expected auditd reported only by A, got %+v", d)
+	}
+	if d := differences[1]; d.Setting != "selinux" || d.ValueA != nil || *d.ValueB != "enforcing" {
+		t.Errorf("expected selinux reported only by B, got %+v", d)
+	}
+	if d := differences[2]; d.Setting != "ssh_root_login" || *d.ValueA != "no" || *d.ValueB != "yes" {
+		t.Errorf("expected differing ssh_root_login, got %+v", d)
+	}
+}
+
+func TestCompareRejectsOtherOrganizationsAgents(t *testing.T) {
+	organizationID := uuid.New()
+	own := &models.Agent{ID: uuid.New(), OrganizationID: organizationID}
+	foreign := &models.Agent{ID: uuid.New(), OrganizationID: uuid.New()}
+	agents := &AgentService{agents: map[uuid.UUID]*models.Agent{own.ID: own, foreign.ID: foreign}}
+	comparisons := NewHostComparisonService(nil, agents)
+
+	for _, pair := range [][2]uuid.UUID{{own.ID, foreign.ID}, {foreign.ID, own.ID}, {own.ID, uuid.New()}} {
+		if _, err := comparisons.Compare(organizationID, pair[0], pair[1], ""); !errors.Is(err, ErrAgentNotFound) {
+			t.Errorf("expected ErrAgentNotFound comparing %s with %s, got %v", pair[0], pair[1], err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Host risk weights. A finding contributes its severity weight, raised by up
+// to double its EPSS probability, and doubled again when it is known to be
+// exploited. The sum saturates towards 100 so a host with many low findings
+// cannot outrank one with a few exploited criticals.
+var hostRiskSeverityWeights = map[string]float64{
+	"critical": 10,
+	"high":     5,
+	"medium":   2,
+	"low":      0.5,
+}
+
+const (
+	hostRiskFindingScale    = 25.0 // Weighted findings at which the finding score reaches 63
+	hostRiskFindingShare    = 0.7  // Share of the score from findings; the rest is exposure
+	hostRiskInternetFacing  = 60.0 // Exposure score of an internet-facing host
+	hostRiskPerOpenService  = 4.0  // Exposure score per open service
+	hostRiskMaxOpenServices = 10   // Open services beyond this add no exposure
+)
+
+// hostRiskCriticalityMultipliers scale a host's score by its asset criticality
+var hostRiskCriticalityMultipliers = map[string]float64{
+	models.AssetCriticalityLow:      0.5,
+	models.AssetCriticalityMedium:   0.75,
+	models.AssetCriticalityHigh:     0.9,
+	models.AssetCriticalityCritical: 1,
+}
+
+// HostRiskService scores each host's overall risk and ranks an organization's
+// hosts by it, answering which machines to fix first
+type HostRiskService struct {
+	db           *gorm.DB
+	agentService *AgentService
+}
+
+// NewHostRiskService creates a new host risk service
+func NewHostRiskService(db *gorm.DB, agentService *AgentService) *HostRiskService {
+	return &HostRiskService{
+		db:           db,
+		agentService: agentService,
+	}
+}
+
+// Recompute scores an agent's host from its open findings and exposure and
+// stores the result
+func (s *HostRiskService) Recompute(agentID uuid.UUID) (*models.HostRisk, error) {
+	agent, exists := s.agentService.GetAgent(agentID)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+
+	risk, err := s.recompute(s.db, agent)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.agentService.SetAgentRiskScore(agent.ID, risk.Score); err != nil {
+		return nil, err
+	}
+	return risk, nil
+}
+
+// recompute scores a host as its findings stand in tx, and stores the result
+// there. Ingestion calls it within the transaction recording a submission.
+func (s *HostRiskService) recompute(tx *gorm.DB, agent *models.Agent) (*models.HostRisk, error) {
+	var findings []models.FindingState
+	err := tx.Where("agent_id = ? AND status = ? AND suppressed = ?", agent.ID, models.FindingStatusOpen, false).Find(&findings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open findings: %w", err)
+	}
+
+	openServices := 0
+	if agent.IPAddress != "" {
+		var hosts []models.NetworkHost
+		err := tx.Where("organization_id = ? AND ip_address = ?", agent.OrganizationID, agent.IPAddress).Find(&hosts).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load network exposure: %w", err)
+		}
+		for _, host := range hosts {
+			openServices = max(openServices, len(host.OpenPorts))
+		}
+	}
+
+	risk := scoreHost(findings, isInternetFacing(agent), openServices, assetCriticality(agent))
+	risk.AgentID = agent.ID
+	risk.OrganizationID = agent.OrganizationID
+	risk.Name = agent.Name
+	risk.Hostname = agent.Hostname
+	risk.ComputedAt = time.Now()
+
+	if err := tx.Save(risk).Error; err != nil {
+		return nil, fmt.Errorf("failed to store host risk: %w", err)
+	}
+	return risk, nil
+}
+
+// Rank lists an organization's hosts from riskiest to least risky
+func (s *HostRiskService) Rank(organizationID uuid.UUID) ([]models.HostRisk, error) {
+	var risks []models.HostRisk
+	err := s.db.Where("organization_id = ?", organizationID).
+		Order("score DESC").Order("open_findings DESC").Order("agent_id").
+		Find(&risks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank hosts: %w", err)
+	}
+	for i := range risks {
+		risks[i].Rank = i + 1
+	}
+	return risks, nil
+}
+
+// SetCriticality sets a host's asset criticality and rescores it
+func (s *HostRiskService) SetCriticality(agentID uuid.UUID, criticality string) (*models.HostRisk, error) {
+	if _, ok := hostRiskCriticalityMultipliers[criticality]; !ok {
+		return nil, fmt.Errorf("unknown asset criticality %q", criticality)
+	}
+	if err := s.agentService.SetAgentMetadataValue(agentID, "asset_criticality", criticality); err != nil {
+		return nil, err
+	}
+	return s.Recompute(agentID)
+}
+
+// scoreHost computes a host's risk score from its open findings and exposure
+func scoreHost(findings []models.FindingState, internetFacing bool, openServices int, criticality string) *models.HostRisk {
+	risk := &models.HostRisk{
+		OpenFindings:   len(findings),
+		BySeverity:     make(map[string]int),
+		InternetFacing: internetFacing,
+		OpenServices:   openServices,
+		Criticality:    criticality,
+	}
+
+	weighted := 0.0
+	for _, finding := range findings {
+		severity := strings.ToLower(finding.Severity)
+		risk.BySeverity[severity]++
+
+		weight := hostRiskSeverityWeights[severity] * (1 + finding.EPSS)
+		if finding.KnownExploited {
+			risk.KnownExploited++
+			weight *= 2
+		}
+		weighted += weight
+	}
+	risk.FindingScore = 100 * (1 - math.Exp(-weighted/hostRiskFindingScale))
+
+	if internetFacing {
+		risk.ExposureScore = hostRiskInternetFacing
+	}
+	risk.ExposureScore += hostRiskPerOpenService * float64(min(openServices, hostRiskMaxOpenServices))
+	risk.ExposureScore = math.Min(risk.ExposureScore, 100)
+
+	score := hostRiskFindingShare*risk.FindingScore + (1-hostRiskFindingShare)*risk.ExposureScore
+	risk.Score = roundScore(score * hostRiskCriticalityMultipliers[criticality])
+	risk.FindingScore = roundScore(risk.FindingScore)
+	risk.ExposureScore = roundScore(risk.ExposureScore)
+	return risk
+}
+
+// isInternetFacing reports whether a host is reachable from the internet: as
+// its metadata says if set, otherwise whether its address is public
+func isInternetFacing(agent *models.Agent) bool {
+	if facing, ok := agent.Metadata["internet_facing"].(bool); ok {
+		return facing
+	}
+	ip := net.ParseIP(agent.IPAddress)
+	if ip == nil {
+		return false
+	}
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsUnspecified()
+}
+
+// assetCriticality returns a host's asset criticality, medium unless set
+func assetCriticality(agent *models.Agent) string {
+	if criticality, ok := agent.Metadata["asset_criticality"].(string); ok {
+		if _, known := hostRiskCriticalityMultipliers[criticality]; known {
+			return criticality
+		}
+	}
+	return models.AssetCriticalityMedium
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*10) / 10
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreHostRanksExploitedCriticalsAboveManyLows(t *testing.T) {
+	var lows []models.FindingState
+	for i := 0; i < 20; i++ {
+		lows = append(lows, models.FindingState{Severity: "LOW"})
+	}
+	exploited := []models.FindingState{
+		{Severity: "CRITICAL", EPSS: 0.9, KnownExploited: true},
+		{Severity: "CRITICAL", EPSS: 0.6, KnownExploited: true},
+	}
+
+	manyLows := scoreHost(lows, false, 0, models.AssetCriticalityMedium)
+	fewCriticals := scoreHost(exploited, false, 0, models.AssetCriticalityMedium)
+
+	assert.Greater(t, fewCriticals.Score, manyLows.Score)
+	assert.Equal(t, 2, fewCriticals.KnownExploited)
+	assert.Equal(t, 20, manyLows.BySeverity["low"])
+}
+
+func TestScoreHostExposureAndCriticality(t *testing.T) {
+	findings := []models.FindingState{{Severity: "high"}}
+
+	internal := scoreHost(findings, false, 0, models.AssetCriticalityMedium)
+	exposed := scoreHost(findings, true, 25, models.AssetCriticalityMedium)
+	assert.Equal(t, 0.0, internal.ExposureScore)
+	assert.Equal(t, 100.0, exposed.ExposureScore, "internet-facing with more than 10 services is fully exposed")
+	assert.Greater(t, exposed.Score, internal.Score)
+
+	crownJewel := scoreHost(findings, true, 25, models.AssetCriticalityCritical)
+	lab := scoreHost(findings, true, 25, models.AssetCriticalityLow)
+	assert.Greater(t, crownJewel.Score, exposed.Score)
+	assert.Less(t, lab.Score, exposed.Score)
+	assert.LessOrEqual(t, crownJewel.Score, 100.0)
+
+	assert.Equal(t, 0.0, scoreHost(nil, false, 0, models.AssetCriticalityCritical).Score)
+}
+
+func TestIsInternetFacing(t *testing.T) {
+	assert.True(t, isInternetFacing(&models.Agent{IPAddress: "203.0.113.10"}))
+	assert.False(t, isInternetFacing(&models.Agent{IPAddress: "10.0.0.5"}))
+	assert.False(t, isInternetFacing(&models.Agent{IPAddress: ""}))
+	assert.True(t, isInternetFacing(&models.Agent{IPAddress: "10.0.0.5", Metadata: map[string]any{"internet_facing": true}}))
+}
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrLicensePolicyNotFound is returned when a license policy is unknown or belongs to another organization
+var ErrLicensePolicyNotFound = errors.New("license policy not found")
+
+// LicenseFindingType is the type of the findings license policy violations raise
+const LicenseFindingType = "license"
+
+// LicensePolicyService manages each organization's license policies and
+// checks agents' dependencies against them
+type LicensePolicyService struct {
+	db           *gorm.DB
+	agentService *AgentService
+}
+
+// NewLicensePolicyService creates a new license policy service
+func NewLicensePolicyService(db *gorm.DB, agentService *AgentService) *LicensePolicyService {
+	return &LicensePolicyService{
+		db:           db,
+		agentService: agentService,
+	}
+}
+
+// ListPolicies lists an organization's license policies, oldest first
+func (s *LicensePolicyService) ListPolicies(organizationID uuid.UUID) ([]models.LicensePolicy, error) {
+	var policies []models.LicensePolicy
+	err := s.db.Where("organization_id = ?", organizationID).Order("created_at ASC").Find(&policies).Error
+	return policies, err
+}
+
+// CreatePolicy adds a license policy to an organization
+func (s *LicensePolicyService) CreatePolicy(organizationID uuid.UUID, req *models.LicensePolicyRequest) (*models.LicensePolicy, error) {
+	if err := validateLicensePolicy(req); err != nil {
+		return nil, err
+	}
+
+	policy := &models.LicensePolicy{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		CreatedBy:      req.Actor,
+	}
+	applyLicensePolicyRequest(policy, req)
+	if err := s.db.Create(policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to create license policy: %w", err)
+	}
+	return policy, nil
+}
+
+// UpdatePolicy replaces a license policy
+func (s *LicensePolicyService) UpdatePolicy(organizationID, policyID uuid.UUID, req *models.LicensePolicyRequest) (*models.LicensePolicy, error) {
+	if err := validateLicensePolicy(req); err != nil {
+		return nil, err
+	}
+
+	var policy models.LicensePolicy
+	if err := findLicensePolicy(s.db, organizationID, policyID, &policy); err != nil {
+		return nil, err
+	}
+	applyLicensePolicyRequest(&policy, req)
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to update license policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// DeletePolicy removes a license policy from an organization
+func (s *LicensePolicyService) DeletePolicy(organizationID, policyID uuid.UUID) error {
+	var policy models.LicensePolicy
+	if err := findLicensePolicy(s.db, organizationID, policyID, &policy); err != nil {
+		return err
+	}
+	if err := s.db.Delete(&policy).Error; err != nil {
+		return fmt.Errorf("failed to delete license policy: %w", err)
+	}
+	return nil
+}
+
+// Findings checks dependencies against the organization's enabled license
+// policies, returning a "license" finding for each violation
+func (s *LicensePolicyService) Findings(organizationID uuid.UUID, dependencies []models.Dependency) ([]models.Vulnerability, error) {
+	policies, err := s.enabledPolicies(organizationID)
+	if err != nil || len(policies) == 0 {
+		return nil, err
+	}
+
+	var findings []models.Vulnerability
+	for _, dep := range dependencies {
+		for _, violation := range licenseViolations(policies, dep) {
+			findings = append(findings, licenseFinding(violation))
+		}
+	}
+	return findings, nil
+}
+
+// Summary reports how the dependencies installed across an organization's
+// agents comply with its enabled license policies
+func (s *LicensePolicyService) Summary(organizationID uuid.UUID) (*models.LicenseComplianceSummary, error) {
+	policies, err := s.enabledPolicies(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The same dependency installed on several agents counts once
+	type installed struct {
+		dep    models.Dependency
+		agents int
+	}
+	byKey := make(map[string]*installed)
+	var keys []string
+	for _, agent := range s.agentService.GetAgents(organizationID) {
+		seen := make(map[string]bool)
+		for _, dep := range agentDependencies(agent) {
+			key := strings.Join([]string{dep.Type, dep.Name, dep.Version}, "\x00")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if byKey[key] == nil {
+				byKey[key] = &installed{dep: dep}
+				keys = append(keys, key)
+			}
+			byKey[key].agents++
+		}
+	}
+	sort.Strings(keys)
+
+	summary := &models.LicenseComplianceSummary{
+		OrganizationID: organizationID,
+		Policies:       len(policies),
+		Dependencies:   len(keys),
+		ByLicense:      make(map[string]int),
+		Violations:     []models.LicenseViolation{},
+		GeneratedAt:    time.Now(),
+	}
+	for _, key := range keys {
+		dep := byKey[key]
+		if dep.dep.License == "" {
+			summary.Unlicensed++
+		} else {
+			summary.ByLicense[dep.dep.License]++
+		}
+		violations := licenseViolations(policies, dep.dep)
+		if len(violations) == 0 {
+			summary.Compliant++
+		}
+		for _, violation := range violations {
+			violation.Agents = dep.agents
+			summary.Violations = append(summary.Violations, violation)
+		}
+	}
+	return summary, nil
+}
+
+func (s *LicensePolicyService) enabledPolicies(organizationID uuid.UUID) ([]models.LicensePolicy, error) {
+	var policies []models.LicensePolicy
+	err := s.db.Where("organization_id = ? AND enabled = ?", organizationID, true).Order("created_at ASC").Find(&policies).Error
+	return policies, err
+}
+
+func findLicensePolicy(tx *gorm.DB, organizationID, policyID uuid.UUID, policy *models.LicensePolicy) error {
+	err := tx.Where("id = ? AND organization_id = ?", policyID, organizationID).First(policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrLicensePolicyNotFound
+	}
+	return err
+}
+
+func applyLicensePolicyRequest(policy *models.LicensePolicy, req *models.LicensePolicyRequest) {
+	policy.Name = strings.TrimSpace(req.Name)
+	policy.Description = strings.TrimSpace(req.Description)
+	policy.Allowed = licensePatterns(req.Allowed)
+	policy.Denied = licensePatterns(req.Denied)
+	policy.FlagUnknown = req.FlagUnknown
+	policy.Severity = strings.ToLower(strings.TrimSpace(req.Severity))
+	if policy.Severity == "" {
+		policy.Severity = "medium"
+	}
+	policy.Enabled = req.Enabled == nil || *req.Enabled
+	policy.UpdatedBy = req.Actor
+}
+
+// licensePatterns trims the patterns and drops empty ones
+func licensePatterns(patterns []string) []string {
+	trimmed := []string{}
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			trimmed = append(trimmed, pattern)
+		}
+	}
+	return trimmed
+}
+
+// validateLicensePolicy rejects policies without a name, policies that
+// can't flag anything, and unknown severities
+func validateLicensePolicy(req *models.LicensePolicyRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("license policy must have a name")
+	}
+	if len(licensePatterns(req.Allowed)) == 0 && len(licensePatterns(req.Denied)) == 0 && !req.FlagUnknown {
+		return fmt.Errorf("license policy must allow or deny licenses, or flag unknown ones")
+	}
+	switch strings.ToLower(strings.TrimSpace(req.Severity)) {
+	case "", "critical", "high", "medium", "low", "info":
+		return nil
+	}
+	return fmt.Errorf("invalid severity %q", req.Severity)
+}
+
+// licenseViolations checks a dependency against each policy
+func licenseViolations(policies []models.LicensePolicy, dep models.Dependency) []models.LicenseViolation {
+	var violations []models.LicenseViolation
+	for _, policy := range policies {
+		reason := licenseViolation(policy, dep.License)
+		if reason == "" {
+			continue
+		}
+		violations = append(violations, models.LicenseViolation{
+			PackageName:    dep.Name,
+			PackageVersion: dep.Version,
+			PackageType:    dep.Type,
+			License:        dep.License,
+			PolicyID:       policy.ID,
+			PolicyName:     policy.Name,
+			Severity:       policy.Severity,
+			Reason:         reason,
+		})
+	}
+	return violations
+}
+
+// licenseViolation explains why a license expression violates the policy,
+// or returns "" if it complies. An expression complies when any of its
+// alternatives does, and an alternative when each license it requires is
+// neither denied nor, if the policy allows only some, left out.
+func licenseViolation(policy models.LicensePolicy, expression string) string {
+	if strings.TrimSpace(expression) == "" {
+		if policy.FlagUnknown {
+			return "no license was detected"
+		}
+		return ""
+	}
+
+	var reason string
+	for _, alternative := range licenseAlternatives(expression) {
+		failed := ""
+		for _, license := range alternative {
+			if pattern, denied := matchLicense(policy.Denied, license); denied {
+				failed = fmt.Sprintf("%s is denied (%s)", license, pattern)
+				break
+			}
+			if _, allowed := matchLicense(policy.Allowed, license); len(policy.Allowed) > 0 && !allowed {
+				failed = fmt.Sprintf("%s is not an allowed license", license)
+				break
+			}
+		}
+		if failed == "" {
+			return ""
+		}
+		if reason == "" {
+			reason = failed
+		}
+	}
+	return reason
+}
+
+// matchLicense returns the first pattern matching a license
+func matchLicense(patterns []string, license string) (string, bool) {
+	for _, pattern := range patterns {
+		if prefix, family := strings.CutSuffix(pattern, "*"); family {
+			if len(license) >= len(prefix) && strings.EqualFold(license[:len(prefix)], prefix) {
+				return pattern, true
+			}
+		} else if strings.EqualFold(license, pattern) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// licenseAlternatives expands an SPDX license expression into the sets of
+// licenses that each satisfy it: "MIT OR (GPL-2.0-only AND BSD-3-Clause)"
+// is satisfied by [MIT] or by [GPL-2.0-only BSD-3-Clause]. A WITH
+// exception doesn't change which license applies, so it is dropped.
+func licenseAlternatives(expression string) [][]string {
+	p := &licenseParser{tokens: strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression))}
+	alternatives := p.or()
+	for p.pos < len(p.tokens) { // Unbalanced parentheses: read on
+		p.pos++
+		alternatives = append(alternatives, p.or()...)
+	}
+	return alternatives
+}
+
+type licenseParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *licenseParser) next() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// or parses alternatives separated by OR
+func (p *licenseParser) or() [][]string {
+	alternatives := p.and()
+	for strings.EqualFold(p.next(), "OR") {
+		p.pos++
+		alternatives = append(alternatives, p.and()...)
+	}
+	return alternatives
+}
+
+// and parses terms separated by AND, each combination of whose alternatives
+// is an alternative of the whole
+func (p *licenseParser) and() [][]string {
+	alternatives := p.term()
+	for strings.EqualFold(p.next(), "AND") {
+		p.pos++
+		terms := p.term()
+		var combined [][]string
+		for _, left := range alternatives {
+			for _, right := range terms {
+				combined = append(combined, append(append([]string{}, left...), right...))
+			}
+		}
+		alternatives = combined
+	}
+	return alternatives
+}
+
+// term parses a parenthesized expression or a license with its exception
+func (p *licenseParser) term() [][]string {
+	switch token := p.next(); {
+	case token == "":
+		return [][]string{{}}
+	case token == "(":
+		p.pos++
+		alternatives := p.or()
+		if p.next() == ")" {
+			p.pos++
+		}
+		return alternatives
+	default:
+		p.pos++
+		if strings.EqualFold(p.next(), "WITH") {
+			p.pos += 2
+		}
+		return [][]string{{token}}
+	}
+}
+
+// licenseFinding raises a finding for a license policy violation
+func licenseFinding(violation models.LicenseViolation) models.Vulnerability {
+	license := violation.License
+	if license == "" {
+		license = "unknown license"
+	}
+	return models.Vulnerability{
+		ID:             uuid.New().String(),
+		Type:           LicenseFindingType,
+		Severity:       models.SeverityLevel(violation.Severity),
+		Title:          fmt.Sprintf("%s is licensed under %s, which violates %s", violation.PackageName, license, violation.PolicyName),
+		Description:    fmt.Sprintf("%s %s violates the license policy %q: %s.", violation.PackageName, violation.PackageVersion, violation.PolicyName, violation.Reason),
+		PackageName:    violation.PackageName,
+		PackageVersion: violation.PackageVersion,
+		Remediation:    "Replace the dependency with one under an accepted license, or obtain a license exception.",
+		Status:         "open",
+		EnrichmentData: map[string]any{
+			"license":     violation.License,
+			"policy_id":   violation.PolicyID,
+			"policy_name": violation.PolicyName,
+			"reason":      violation.Reason,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+// agentDependencies reads the dependencies stored in an agent's metadata,
+// which are decoded from JSON once the agent has been reloaded
+func agentDependencies(agent *models.Agent) []models.Dependency {
+	switch deps := agent.Metadata["dependencies"].(type) {
+	case []models.Dependency:
+		return deps
+	case nil:
+		return nil
+	default:
+		raw, err := json.Marshal(deps)
+		if err != nil {
+			return nil
+		}
+		var decoded []models.Dependency
+		if json.Unmarshal(raw, &decoded) != nil {
+			return nil
+		}
+		return decoded
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicenseAlternatives(t *testing.T) {
+	assert.Equal(t, [][]string{{"MIT"}}, licenseAlternatives("MIT"))
+	assert.Equal(t, [][]string{{"MIT"}, {"GPL-2.0-only", "BSD-3-Clause"}},
+		licenseAlternatives("MIT OR (GPL-2.0-only AND BSD-3-Clause)"))
+	assert.Equal(t, [][]string{{"GPL-2.0-only", "MIT"}, {"GPL-2.0-only", "ISC"}},
+		licenseAlternatives("GPL-2.0-only WITH Classpath-exception-2.0 AND (MIT or ISC)"))
+}
+
+func TestLicenseViolation(t *testing.T) {
+	noGPL := models.LicensePolicy{Name: "No copyleft", Denied: []string{"GPL-*", "AGPL-3.0-only"}}
+	permissive := models.LicensePolicy{Name: "Permissive only", Allowed: []string{"MIT", "Apache-2.0", "BSD-*"}, FlagUnknown: true}
+
+	for license, want := range map[string]string{
+		"MIT":                             "",
+		"gpl-3.0-or-later":                "gpl-3.0-or-later is denied (GPL-*)",
+		"LGPL-2.1-only":                   "",
+		"GPL-2.0-only OR MIT":             "",
+		"MIT AND GPL-2.0-only":            "GPL-2.0-only is denied (GPL-*)",
+		"GPL-2.0-only WITH GCC-exception": "GPL-2.0-only is denied (GPL-*)",
+		"":                                "",
+	} {
+		assert.Equal(t, want, licenseViolation(noGPL, license), license)
+	}
+
+	for license, want := range map[string]string{
+		"BSD-3-Clause":        "",
+		"(MIT OR Apache-2.0)": "",
+		"MPL-2.0":             "MPL-2.0 is not an allowed license",
+		"MIT AND MPL-2.0":     "MPL-2.0 is not an allowed license",
+		"":                    "no license was detected",
+	} {
+		assert.Equal(t, want, licenseViolation(permissive, license), license)
+	}
+}
+
+func TestLicenseViolationsRaiseFindings(t *testing.T) {
+	policy := models.LicensePolicy{ID: uuid.New(), Name: "No copyleft", Denied: []string{"GPL-*"}, Severity: "high"}
+	violations := licenseViolations([]models.LicensePolicy{policy}, models.Dependency{Name: "readline", Version: "8.2", Type: "apt", License: "GPL-3.0-or-later"})
+	require.Len(t, violations, 1)
+
+	finding := licenseFinding(violations[0])
+	assert.Equal(t, LicenseFindingType, finding.Type)
+	assert.Equal(t, models.SeverityLevel("high"), finding.Severity)
+	assert.Equal(t, "readline", finding.PackageName)
+	assert.Equal(t, "8.2", finding.PackageVersion)
+	assert.Contains(t, finding.Title, "GPL-3.0-or-later")
+	assert.Equal(t, policy.ID, finding.EnrichmentData["policy_id"])
+
+	assert.Empty(t, licenseViolations([]models.LicensePolicy{policy}, models.Dependency{Name: "curl", License: "curl"}))
+}
+
+func TestValidateLicensePolicy(t *testing.T) {
+	require.NoError(t, validateLicensePolicy(&models.LicensePolicyRequest{Name: "No copyleft", Denied: []string{"GPL-*"}}))
+
+	for name, req := range map[string]models.LicensePolicyRequest{
+		"no name":          {Denied: []string{"GPL-*"}},
+		"flags nothing":    {Name: "Empty", Denied: []string{" "}},
+		"unknown severity": {Name: "No copyleft", Denied: []string{"GPL-*"}, Severity: "urgent"},
+	} {
+		assert.Error(t, validateLicensePolicy(&req), name)
+	}
+}
+
+func TestAgentDependenciesDecodesReloadedMetadata(t *testing.T) {
+	agent := &models.Agent{Metadata: map[string]any{
+		"dependencies": []interface{}{map[string]interface{}{"name": "lodash", "version": "4.17.21", "license": "MIT"}},
+	}}
+	deps := agentDependencies(agent)
+	require.Len(t, deps, 1)
+	assert.Equal(t, "MIT", deps[0].License)
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// minPeerCount is the fewest peers a comparison is made with. Fewer would
+// be unrepresentative, and could give away a single peer's score.
+const minPeerCount = 5
+
+// peerSizeBands bound organization sizes, by agent count, peers must share
+var peerSizeBands = []struct {
+	max  int
+	name string
+}{
+	{10, "1-10"},
+	{50, "11-50"},
+	{250, "51-250"},
+	{1000, "251-1000"},
+	{math.MaxInt, "1000+"},
+}
+
+// peerSizeBand names the size band of an organization with a number of agents
+func peerSizeBand(agents int) string {
+	for _, band := range peerSizeBands {
+		if agents <= band.max {
+			return band.name
+		}
+	}
+	return peerSizeBands[len(peerSizeBands)-1].name
+}
+
+// getPeerComparison compares an organization's overall score with its
+// peers', falling back to the industry benchmark when there are too few
+func (s *MaturityService) getPeerComparison(organizationID uuid.UUID, industry string, score float64, benchmark IndustryBenchmark) PeerComparison {
+	band, peerScores, err := s.getPeerScores(organizationID, industry)
+	if err != nil {
+		log.Printf("[Maturity] Comparing organization %s with its industry benchmark, failed to load its peers: %v", organizationID, err)
+	}
+
+	comparison := comparePeers(score, peerScores)
+	comparison.Industry = industry
+	comparison.SizeBand = band
+	if len(peerScores) < minPeerCount {
+		comparison.Distribution = PeerDistribution{}
+		comparison.PeerAverage = benchmark.IndustryAverage
+		comparison.PeerPercentile = benchmark.IndustryPercentile
+		comparison.CompetitivePosition = competitivePosition(benchmark.IndustryPercentile)
+		comparison.Basis = "industry_benchmark"
+		comparison.LowConfidence = true
+	}
+	return comparison
+}
+
+// getPeerScores returns an organization's size band and the overall scores
+// of the other organizations in its industry and band
+func (s *MaturityService) getPeerScores(organizationID uuid.UUID, industry string) (string, []float64, error) {
+	var agents int64
+	if err := s.db.Model(&models.Agent{}).Where("organization_id = ?", organizationID).Count(&agents).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to count agents: %w", err)
+	}
+	band := peerSizeBand(int(agents))
+	if industry == "" {
+		return band, nil, nil
+	}
+
+	var industryPeers []uuid.UUID
+	err := s.db.Model(&models.OrganizationProfile{}).
+		Where("LOWER(industry) = LOWER(?) AND organization_id <> ?", industry, organizationID).
+		Pluck("organization_id", &industryPeers).Error
+	if err != nil || len(industryPeers) == 0 {
+		return band, nil, err
+	}
+
+	var sizes []struct {
+		OrganizationID uuid.UUID
+		Agents         int
+	}
+	err = s.db.Model(&models.Agent{}).
+		Select("organization_id, COUNT(*) AS agents").
+		Where("organization_id IN ?", industryPeers).
+		Group("organization_id").
+		Scan(&sizes).Error
+	if err != nil {
+		return band, nil, fmt.Errorf("failed to count peer agents: %w", err)
+	}
+	agentCounts := make(map[uuid.UUID]int, len(sizes))
+	for _, size := range sizes {
+		agentCounts[size.OrganizationID] = size.Agents
+	}
+
+	var scores []float64
+	for _, peerID := range industryPeers {
+		if peerSizeBand(agentCounts[peerID]) != band {
+			continue
+		}
+		score, err := s.getOverallScore(peerID)
+		if err != nil {
+			return band, nil, fmt.Errorf("failed to score peer: %w", err)
+		}
+		scores = append(scores, score)
+	}
+	return band, scores, nil
+}
+
+// getOverallScore calculates an organization's overall maturity score alone
+func (s *MaturityService) getOverallScore(organizationID uuid.UUID) (float64, error) {
+	orgProfile, err := s.getOrganizationProfile(organizationID)
+	if err != nil {
+		return 0, err
+	}
+	vulnerabilities, err := s.getVulnerabilitiesForOrganization(organizationID)
+	if err != nil {
+		return 0, err
+	}
+	scanHistory, err := s.getScanHistory(organizationID)
+	if err != nil {
+		return 0, err
+	}
+	return s.calculateOverallScore(s.calculateDimensionScores(vulnerabilities, scanHistory, orgProfile)), nil
+}
+
+// comparePeers places a score among peers' scores. Its percentile counts
+// peers scoring lower and half those scoring the same.
+func comparePeers(score float64, peerScores []float64) PeerComparison {
+	comparison := PeerComparison{PeerCount: len(peerScores), Basis: "peers"}
+	if len(peerScores) == 0 {
+		return comparison
+	}
+
+	sorted := append([]float64(nil), peerScores...)
+	sort.Float64s(sorted)
+	var total, below float64
+	for _, peer := range sorted {
+		total += peer
+		switch {
+		case peer < score:
+			below++
+		case peer == score:
+			below += 0.5
+		}
+	}
+
+	comparison.PeerAverage = total / float64(len(sorted))
+	comparison.PeerPercentile = below / float64(len(sorted)) * 100
+	comparison.Distribution = PeerDistribution{
+		Min:    sorted[0],
+		P25:    scorePercentile(sorted, 0.25),
+		Median: scorePercentile(sorted, 0.5),
+		P75:    scorePercentile(sorted, 0.75),
+		Max:    sorted[len(sorted)-1],
+	}
+	comparison.CompetitivePosition = competitivePosition(comparison.PeerPercentile)
+	return comparison
+}
+
+// scorePercentile interpolates the score at a fraction of sorted scores
+func scorePercentile(sorted []float64, fraction float64) float64 {
+	position := fraction * float64(len(sorted)-1)
+	lower := int(position)
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[lower+1]-sorted[lower])*(position-float64(lower))
+}
+
+// competitivePosition names the quartile of a percentile
+func competitivePosition(percentile float64) string {
+	switch {
+	case percentile >= 75:
+		return "Top Quartile"
+	case percentile >= 50:
+		return "Above Average"
+	case percentile >= 25:
+		return "Below Average"
+	default:
+		return "Bottom Quartile"
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparePeers(t *testing.T) {
+	comparison := comparePeers(0.6, []float64{0.8, 0.4, 0.6, 0.5, 0.7})
+	assert.Equal(t, 5, comparison.PeerCount)
+	assert.InDelta(t, 0.6, comparison.PeerAverage, 1e-9)
+	assert.InDelta(t, 50, comparison.PeerPercentile, 1e-9, "two peers below and one level count for half")
+	assert.InDeltaSlice(t,
+		[]float64{0.4, 0.5, 0.6, 0.7, 0.8},
+		[]float64{comparison.Distribution.Min, comparison.Distribution.P25, comparison.Distribution.Median, comparison.Distribution.P75, comparison.Distribution.Max},
+		1e-9)
+	assert.Equal(t, "Above Average", comparison.CompetitivePosition)
+	assert.Equal(t, "peers", comparison.Basis)
+	assert.False(t, comparison.LowConfidence)
+
+	assert.Equal(t, "Top Quartile", comparePeers(0.9, []float64{0.1, 0.2, 0.3, 0.4}).CompetitivePosition)
+	assert.InDelta(t, 0.25, scorePercentile([]float64{0, 1}, 0.25), 1e-9)
+}
+
+func TestPeerSizeBand(t *testing.T) {
+	assert.Equal(t, "1-10", peerSizeBand(0))
+	assert.Equal(t, "11-50", peerSizeBand(11))
+	assert.Equal(t, "251-1000", peerSizeBand(1000))
+	assert.Equal(t, "1000+", peerSizeBand(1001))
+}
+
+func TestPeerComparisonFallsBackToIndustryBenchmark(t *testing.T) {
+	s := NewMaturityService(dryRunDB(t))
+	benchmark := IndustryBenchmark{Industry: "finance", IndustryAverage: 0.75, IndustryPercentile: 20}
+
+	// No peers are found, so the benchmark stands in for them
+	comparison := s.getPeerComparison(uuid.New(), "finance", 0.6, benchmark)
+	assert.Equal(t, "industry_benchmark", comparison.Basis)
+	assert.True(t, comparison.LowConfidence)
+	assert.Equal(t, 0, comparison.PeerCount)
+	assert.Equal(t, 0.75, comparison.PeerAverage)
+	assert.Equal(t, 20.0, comparison.PeerPercentile)
+	assert.Equal(t, "Bottom Quartile", comparison.CompetitivePosition)
+	assert.Equal(t, "1-10", comparison.SizeBand)
+	assert.Equal(t, PeerDistribution{}, comparison.Distribution)
+}
@@ -60,13 +60,30 @@ type IndustryBenchmark struct {
 	CompetitiveGap     float64 `json:"competitive_gap"`
 }
 
-// PeerComparison represents peer organization comparison
+// PeerComparison compares an organization with its peers: organizations in
+// the same industry with a similar number of agents. Peers are only
+// described in aggregate, never named, as they are other tenants.
 type PeerComparison struct {
-	PeerCount           int      `json:"peer_count"`
-	PeerAverage         float64  `json:"peer_average"`
-	PeerPercentile      float64  `json:"peer_percentile"`
-	SimilarOrgs         []string `json:"similar_orgs"`
-	CompetitivePosition string   `json:"competitive_position"`
+	Industry            string           `json:"industry"`
+	SizeBand            string           `json:"size_band"` // Agents, such as 11-50
+	PeerCount           int              `json:"peer_count"`
+	PeerAverage         float64          `json:"peer_average"`
+	PeerPercentile      float64          `json:"peer_percentile"`
+	Distribution        PeerDistribution `json:"distribution"`
+	CompetitivePosition string           `json:"competitive_position"`
+	// Basis is "peers", or "industry_benchmark" when there are too few peers
+	// to compare with, in which case the comparison is low confidence
+	Basis         string `json:"basis"`
+	LowConfidence bool   `json:"low_confidence"`
+}
+
+// PeerDistribution summarizes peers' overall maturity scores
+type PeerDistribution struct {
+	Min    float64 `json:"min"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	Max    float64 `json:"max"`
 }
 
 // ImprovementItem represents an improvement recommendation
@@ -125,7 +142,7 @@ func (s *MaturityService) CalculateMaturityScore(organizationID uuid.UUID) (*Mat
 	industryBenchmark := s.getIndustryBenchmark(orgProfile.Industry, overallScore)
 
 	// Get peer comparison
-	peerComparison := s.getPeerComparison(organizationID, overallScore)
+	peerComparison := s.getPeerComparison(organizationID, orgProfile.Industry, overallScore, industryBenchmark)
 
 	// Generate improvement roadmap
 	improvementRoadmap := s.generateImprovementRoadmap(dimensionScores, orgProfile)
@@ -892,25 +909,6 @@ func (s *MaturityService) getIndustryBenchmark(industry string, score float64) I
 	}
 }
 
-func (s *MaturityService) getPeerComparison(organizationID uuid.UUID, score float64) PeerComparison {
-	// Peer comparison calculation based on score
-	// Database integration required for real peer comparison data
-	peerAverage := 0.65
-	peerPercentile := ((score - peerAverage) / peerAverage) * 100
-	if peerPercentile < 0 {
-		peerPercentile = 0
-	} else if peerPercentile > 100 {
-		peerPercentile = 100
-	}
-	return PeerComparison{
-		PeerCount:           0, // Database integration required
-		PeerAverage:         peerAverage,
-		PeerPercentile:      peerPercentile,
-		SimilarOrgs:         []string{"TechCorp", "SecureInc", "DataSafe"},
-		CompetitivePosition: "Above Average",
-	}
-}
-
 func (s *MaturityService) generateImprovementRoadmap(dimensionScores map[string]DimensionScore, orgProfile *models.OrganizationProfile) []ImprovementItem {
 	var roadmap []ImprovementItem
 
@@ -0,0 +1,474 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Network asset deduplication modes
+const (
+	NetworkAssetDedupIdentity = "identity" // Same MAC, else same hostname, else same IP in the same subnet when corroborated
+	NetworkAssetDedupIPMAC    = "ip_mac"   // Same MAC is the same host; otherwise match by IP
+	NetworkAssetDedupIP       = "ip"       // Same IP is the same host
+	NetworkAssetDedupNone     = "none"     // One record per agent and IP
+)
+
+// How an observation was matched to a known host
+const (
+	hostMatchMAC      = "mac"
+	hostMatchHostname = "hostname"
+	hostMatchIP       = "ip"
+)
+
+// NetworkAssetService maintains an organization's network hosts, merging the
+// observations of agents whose scans cover overlapping subnets
+type NetworkAssetService struct {
+	db   *gorm.DB
+	mode string
+
+	// Serializes merges so concurrent uploads from overlapping agents can't
+	// both create a record for the same host
+	mu sync.Mutex
+}
+
+// NewNetworkAssetService creates a new network asset service
+func NewNetworkAssetService(db *gorm.DB, cfg *config.Config) *NetworkAssetService {
+	mode := cfg.NetworkAssetDedup
+	switch mode {
+	case NetworkAssetDedupIdentity, NetworkAssetDedupIPMAC, NetworkAssetDedupIP, NetworkAssetDedupNone:
+	default:
+		log.Printf("Unknown NETWORK_ASSET_DEDUP mode %q, using %s", mode, NetworkAssetDedupIdentity)
+		mode = NetworkAssetDedupIdentity
+	}
+
+	return &NetworkAssetService{
+		db:   db,
+		mode: mode,
+	}
+}
+
+// IngestScan merges the hosts found by an agent's network scan into the organization's assets
+func (s *NetworkAssetService) IngestScan(agentID, organizationID uuid.UUID, scanResult map[string]interface{}, at time.Time) error {
+	observations := hostObservationsFromScan(agentID, scanResult, at)
+	if len(observations) == 0 {
+		return nil
+	}
+
+	ips := make([]string, 0, len(observations))
+	var macs, hostnames []string
+	for _, obs := range observations {
+		ips = append(ips, obs.IPAddress)
+		if obs.MACAddress != "" {
+			macs = append(macs, obs.MACAddress)
+		}
+		if obs.Hostname != "" && s.mode == NetworkAssetDedupIdentity {
+			hostnames = append(hostnames, normalizeHostname(obs.Hostname))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing []*models.NetworkHost
+	matches := s.db.Where("ip_address IN ?", ips)
+	if len(macs) > 0 {
+		matches = matches.Or("mac_address IN ?", macs)
+	}
+	if len(hostnames) > 0 {
+		matches = matches.Or("LOWER(hostname) IN ?", hostnames)
+	}
+	query := s.db.Where("organization_id = ?", organizationID).Where(matches)
+	if err := query.Find(&existing).Error; err != nil {
+		return fmt.Errorf("failed to load network hosts: %w", err)
+	}
+
+	for _, host := range mergeHostObservations(organizationID, existing, observations, s.mode) {
+		if err := s.db.Save(host).Error; err != nil {
+			log.Printf("Failed to persist network host %s: %v", host.IPAddress, err)
+		}
+	}
+	return nil
+}
+
+// ListAssets lists an organization's deduplicated network hosts
+func (s *NetworkAssetService) ListAssets(organizationID uuid.UUID) ([]models.NetworkHost, error) {
+	var hosts []models.NetworkHost
+	err := s.db.Where("organization_id = ?", organizationID).Order("ip_address ASC").Find(&hosts).Error
+	return hosts, err
+}
+
+// mergeHostObservations folds observations into the matching hosts, creating
+// hosts that have not been seen before. It returns the hosts that changed.
+func mergeHostObservations(organizationID uuid.UUID, hosts []*models.NetworkHost, observations []models.NetworkHostObserver, mode string) []*models.NetworkHost {
+	var changed []*models.NetworkHost
+	seen := make(map[*models.NetworkHost]bool)
+
+	for _, obs := range observations {
+		host, matchedBy := matchNetworkHost(hosts, obs, mode)
+		obs.MatchedBy = matchedBy
+		if host == nil {
+			host = &models.NetworkHost{
+				ID:             uuid.New(),
+				OrganizationID: organizationID,
+				Status:         "active",
+				CreatedAt:      obs.FirstSeen,
+			}
+			hosts = append(hosts, host)
+		}
+
+		upsertObserver(host, obs)
+		refreshNetworkHost(host)
+
+		if !seen[host] {
+			seen[host] = true
+			changed = append(changed, host)
+		}
+	}
+
+	return changed
+}
+
+// matchNetworkHost finds the existing host an observation belongs to, and
+// how it was matched
+func matchNetworkHost(hosts []*models.NetworkHost, obs models.NetworkHostObserver, mode string) (*models.NetworkHost, string) {
+	switch mode {
+	case NetworkAssetDedupNone:
+		for _, host := range hosts {
+			if host.AgentID == obs.AgentID && host.IPAddress == obs.IPAddress {
+				return host, hostMatchIP
+			}
+		}
+	case NetworkAssetDedupIP:
+		for _, host := range hosts {
+			if host.IPAddress == obs.IPAddress {
+				return host, hostMatchIP
+			}
+		}
+	case NetworkAssetDedupIPMAC:
+		// A MAC identifies a host even after DHCP hands it a new IP
+		if obs.MACAddress != "" {
+			for _, host := range hosts {
+				if strings.EqualFold(host.MACAddress, obs.MACAddress) {
+					return host, hostMatchMAC
+				}
+			}
+		}
+		// Same IP with a different known MAC is a different device
+		for _, host := range hosts {
+			if host.IPAddress == obs.IPAddress && (host.MACAddress == "" || obs.MACAddress == "") {
+				return host, hostMatchIP
+			}
+		}
+	default:
+		return matchHostIdentity(hosts, obs)
+	}
+	return nil, ""
+}
+
+// matchHostIdentity matches an observation by the most stable identity it
+// has: its MAC, then its hostname, then its IP within the same subnet. DHCP
+// hands an IP to another device as readily as it keeps it, so an IP alone
+// is weak evidence: it only matches a host the same agent already sees at
+// that IP, or one with the same OS or the same open ports. Hosts whose
+// known MAC or hostname differs from the observation's never match.
+func matchHostIdentity(hosts []*models.NetworkHost, obs models.NetworkHostObserver) (*models.NetworkHost, string) {
+	if obs.MACAddress != "" {
+		for _, host := range hosts {
+			if strings.EqualFold(host.MACAddress, obs.MACAddress) {
+				return host, hostMatchMAC
+			}
+		}
+	}
+
+	conflicts := func(host *models.NetworkHost) bool {
+		return (host.MACAddress != "" && obs.MACAddress != "" && !strings.EqualFold(host.MACAddress, obs.MACAddress)) ||
+			(host.Hostname != "" && obs.Hostname != "" && normalizeHostname(host.Hostname) != normalizeHostname(obs.Hostname))
+	}
+
+	if obs.Hostname != "" {
+		for _, host := range hosts {
+			if normalizeHostname(host.Hostname) == normalizeHostname(obs.Hostname) && !conflicts(host) {
+				return host, hostMatchHostname
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		if conflicts(host) || !observedAt(host, obs.IPAddress, obs.Subnet) {
+			continue
+		}
+		if corroborated(host, obs) {
+			return host, hostMatchIP
+		}
+	}
+	return nil, ""
+}
+
+// observedAt reports whether any observer saw the host at the IP, within
+// the same subnet when both scans report one
+func observedAt(host *models.NetworkHost, ip, subnet string) bool {
+	for _, o := range host.Observers {
+		if o.IPAddress == ip && (o.Subnet == "" || subnet == "" || o.Subnet == subnet) {
+			return true
+		}
+	}
+	return host.IPAddress == ip && len(host.Observers) == 0
+}
+
+// corroborated reports whether an observation matching a host by IP alone
+// agrees with it on more than the IP
+func corroborated(host *models.NetworkHost, obs models.NetworkHostObserver) bool {
+	for _, o := range host.Observers {
+		if o.AgentID == obs.AgentID && o.IPAddress == obs.IPAddress {
+			return true
+		}
+	}
+	if obs.OS != "" && strings.EqualFold(host.OS, obs.OS) {
+		return true
+	}
+	return len(obs.OpenPorts) > 0 && slices.Equal(host.OpenPorts, obs.OpenPorts)
+}
+
+// normalizeHostname compares hostnames case-insensitively, with or without
+// the trailing dot of a fully qualified name
+func normalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+}
+
+// upsertObserver records an agent's latest view of a host
+func upsertObserver(host *models.NetworkHost, obs models.NetworkHostObserver) {
+	for i := range host.Observers {
+		if host.Observers[i].AgentID == obs.AgentID {
+			obs.FirstSeen = host.Observers[i].FirstSeen
+			host.Observers[i] = obs
+			return
+		}
+	}
+	host.Observers = append(host.Observers, obs)
+}
+
+// refreshNetworkHost derives a host's fields from its observers. The
+// primary observer's open ports stand for the host's; its address, name,
+// MAC and OS are each taken from the most confident observer reporting one.
+func refreshNetworkHost(host *models.NetworkHost) {
+	primary := choosePrimaryObserver(host.Observers)
+
+	host.AgentID = primary.AgentID
+	host.OpenPorts = primary.OpenPorts
+	host.IPAddress = confidentAttribute(host.Observers, func(o models.NetworkHostObserver) string { return o.IPAddress })
+	host.Hostname = confidentAttribute(host.Observers, func(o models.NetworkHostObserver) string { return o.Hostname })
+	host.MACAddress = confidentAttribute(host.Observers, func(o models.NetworkHostObserver) string { return o.MACAddress })
+	host.OS = confidentAttribute(host.Observers, func(o models.NetworkHostObserver) string { return o.OS })
+	host.ObserverCount = len(host.Observers)
+	host.Status = "active"
+
+	host.Sources = []uuid.UUID{primary.AgentID}
+	for _, obs := range host.Observers {
+		if obs.AgentID != primary.AgentID {
+			host.Sources = append(host.Sources, obs.AgentID)
+		}
+		if obs.LastSeen.After(host.LastSeen) {
+			host.LastSeen = obs.LastSeen
+		}
+	}
+
+	switch {
+	case host.MACAddress != "":
+		host.IdentityKey = hostMatchMAC + ":" + strings.ToLower(host.MACAddress)
+	case host.Hostname != "":
+		host.IdentityKey = hostMatchHostname + ":" + normalizeHostname(host.Hostname)
+	default:
+		host.IdentityKey = hostMatchIP + ":" + host.IPAddress
+		if primary.Subnet != "" {
+			host.IdentityKey += "@" + primary.Subnet
+		}
+	}
+}
+
+// confidentAttribute returns an attribute as the most confident observer
+// reporting it sees it. An observer that resolved the host's MAC shares its
+// network segment, so its view is trusted over one seen through a router;
+// among equals, the most recent view wins.
+func confidentAttribute(observers []models.NetworkHostObserver, attribute func(models.NetworkHostObserver) string) string {
+	var best *models.NetworkHostObserver
+	for i := range observers {
+		obs := &observers[i]
+		if attribute(*obs) == "" {
+			continue
+		}
+		if best == nil || (obs.MACAddress != "") != (best.MACAddress != "") {
+			if best == nil || obs.MACAddress != "" {
+				best = obs
+			}
+			continue
+		}
+		if obs.LastSeen.After(best.LastSeen) {
+			best = obs
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return attribute(*best)
+}
+
+// choosePrimaryObserver picks the observer with the most complete view of a
+// host: the most open ports, then a known MAC, then the longest-standing observer
+func choosePrimaryObserver(observers []models.NetworkHostObserver) models.NetworkHostObserver {
+	best := observers[0]
+	for _, obs := range observers[1:] {
+		switch {
+		case len(obs.OpenPorts) != len(best.OpenPorts):
+			if len(obs.OpenPorts) > len(best.OpenPorts) {
+				best = obs
+			}
+		case (obs.MACAddress != "") != (best.MACAddress != ""):
+			if obs.MACAddress != "" {
+				best = obs
+			}
+		case !obs.FirstSeen.Equal(best.FirstSeen):
+			if obs.FirstSeen.Before(best.FirstSeen) {
+				best = obs
+			}
+		case obs.AgentID.String() < best.AgentID.String():
+			best = obs
+		}
+	}
+	return best
+}
+
+// hostObservationsFromScan extracts one observation per host from a network
+// scan result, accepting both the agent's network_findings and a plain hosts
+// list. Each host is placed in the scanned subnet it was found in, if the
+// result's scan_scope metadata lists it.
+func hostObservationsFromScan(agentID uuid.UUID, scanResult map[string]interface{}, at time.Time) []models.NetworkHostObserver {
+	subnets := scannedSubnets(scanResult)
+	byIP := make(map[string]*models.NetworkHostObserver)
+	observe := func(ip string) *models.NetworkHostObserver {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return nil
+		}
+		obs, ok := byIP[ip]
+		if !ok {
+			obs = &models.NetworkHostObserver{
+				AgentID:   agentID,
+				IPAddress: ip,
+				OpenPorts: []int{},
+				FirstSeen: at,
+				LastSeen:  at,
+			}
+			for _, subnet := range subnets {
+				if subnet.Contains(parsed) {
+					obs.Subnet = subnet.String()
+					break
+				}
+			}
+			byIP[ip] = obs
+		}
+		return obs
+	}
+	addPort := func(obs *models.NetworkHostObserver, port int) {
+		for _, p := range obs.OpenPorts {
+			if p == port {
+				return
+			}
+		}
+		obs.OpenPorts = append(obs.OpenPorts, port)
+	}
+
+	if findings, ok := scanResult["network_findings"].([]interface{}); ok {
+		for _, f := range findings {
+			finding, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			host, _ := finding["host"].(string)
+			obs := observe(host)
+			if obs == nil {
+				continue
+			}
+			if findingType, _ := finding["finding_type"].(string); findingType == "port" {
+				if port, ok := finding["port"].(float64); ok && port > 0 {
+					addPort(obs, int(port))
+				}
+			}
+			if os, _ := finding["os"].(string); os != "" {
+				obs.OS = os
+			}
+			if metadata, ok := finding["metadata"].(map[string]interface{}); ok {
+				if mac, _ := metadata["mac_address"].(string); mac != "" {
+					obs.MACAddress = strings.ToLower(mac)
+				}
+				if hostname, _ := metadata["hostname"].(string); hostname != "" {
+					obs.Hostname = hostname
+				}
+			}
+		}
+	}
+
+	if hosts, ok := scanResult["hosts"].([]interface{}); ok {
+		for _, h := range hosts {
+			hostMap, ok := h.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			ip, _ := hostMap["ip"].(string)
+			obs := observe(ip)
+			if obs == nil {
+				continue
+			}
+			if hostname, _ := hostMap["hostname"].(string); hostname != "" {
+				obs.Hostname = hostname
+			}
+			if mac, _ := hostMap["mac_address"].(string); mac != "" {
+				obs.MACAddress = strings.ToLower(mac)
+			}
+			if ports, ok := hostMap["ports"].([]interface{}); ok {
+				for _, p := range ports {
+					if port, ok := p.(float64); ok {
+						addPort(obs, int(port))
+					}
+				}
+			}
+		}
+	}
+
+	observations := make([]models.NetworkHostObserver, 0, len(byIP))
+	for _, obs := range byIP {
+		sort.Ints(obs.OpenPorts)
+		observations = append(observations, *obs)
+	}
+	sort.Slice(observations, func(i, j int) bool {
+		return observations[i].IPAddress < observations[j].IPAddress
+	})
+	return observations
+}
+
+// scannedSubnets returns the subnets a network scan result's scope targeted
+func scannedSubnets(scanResult map[string]interface{}) []*net.IPNet {
+	metadata, _ := scanResult["metadata"].(map[string]interface{})
+	scope, _ := metadata["scan_scope"].(map[string]interface{})
+	targets, _ := scope["targets"].([]interface{})
+
+	var subnets []*net.IPNet
+	for _, target := range targets {
+		cidr, _ := target.(string)
+		if _, subnet, err := net.ParseCIDR(cidr); err == nil {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func portFinding(host string, port int, mac string) map[string]interface{} {
+	return map[string]interface{}{
+		"finding_type": "port",
+		"host":         host,
+		"port":         float64(port),
+		"metadata":     map[string]interface{}{"mac_address": mac},
+	}
+}
+
+func TestMergeHostObservations_OverlappingAgents(t *testing.T) {
+	orgID := uuid.New()
+	agentA := uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	agentB := uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Agent A covers 10.0.0.0/24, agent B overlaps on .10 and .20 and sees
+	// more of .20's ports from its side of the firewall
+	scanA := map[string]interface{}{"network_findings": []interface{}{
+		portFinding("10.0.0.5", 22, ""),
+		portFinding("10.0.0.10", 443, "AA:BB:CC:00:00:10"),
+		portFinding("10.0.0.20", 80, ""),
+	}}
+	scanB := map[string]interface{}{"network_findings": []interface{}{
+		portFinding("10.0.0.10", 443, "aa:bb:cc:00:00:10"),
+		portFinding("10.0.0.20", 80, ""),
+		portFinding("10.0.0.20", 8080, ""),
+		portFinding("10.0.1.7", 3389, ""),
+	}}
+
+	hosts := mergeHostObservations(orgID, nil, hostObservationsFromScan(agentA, scanA, t0), NetworkAssetDedupIPMAC)
+	require.Len(t, hosts, 3)
+
+	changed := mergeHostObservations(orgID, hosts, hostObservationsFromScan(agentB, scanB, t0.Add(time.Hour)), NetworkAssetDedupIPMAC)
+	require.Len(t, changed, 3)
+	for _, h := range changed {
+		if !containsHost(hosts, h) {
+			hosts = append(hosts, h)
+		}
+	}
+
+	// Four distinct hosts, not seven
+	require.Len(t, hosts, 4)
+	byIP := make(map[string]*models.NetworkHost)
+	for _, h := range hosts {
+		byIP[h.IPAddress] = h
+	}
+
+	assert.Equal(t, 1, byIP["10.0.0.5"].ObserverCount)
+	assert.Equal(t, 1, byIP["10.0.1.7"].ObserverCount)
+
+	// Matched by MAC regardless of case; the earlier observer stays primary on a tie
+	assert.Equal(t, 2, byIP["10.0.0.10"].ObserverCount)
+	assert.Equal(t, agentA, byIP["10.0.0.10"].AgentID)
+
+	// The observer that sees more ports becomes primary
+	assert.Equal(t, 2, byIP["10.0.0.20"].ObserverCount)
+	assert.Equal(t, agentB, byIP["10.0.0.20"].AgentID)
+	assert.Equal(t, []int{80, 8080}, byIP["10.0.0.20"].OpenPorts)
+	assert.Equal(t, t0.Add(time.Hour), byIP["10.0.0.20"].LastSeen)
+
+	// Rescanning does not add another observer
+	changed = mergeHostObservations(orgID, hosts, hostObservationsFromScan(agentA, scanA, t0.Add(2*time.Hour)), NetworkAssetDedupIPMAC)
+	require.Len(t, changed, 3)
+	assert.Equal(t, 2, byIP["10.0.0.10"].ObserverCount)
+	assert.Equal(t, t0, byIP["10.0.0.10"].Observers[0].FirstSeen)
+}
+
+func TestMergeHostObservations_Modes(t *testing.T) {
+	orgID := uuid.New()
+	agentA, agentB := uuid.New(), uuid.New()
+	now := time.Now()
+
+	// Same IP, different MACs: two devices behind different agents' NAT
+	scanA := map[string]interface{}{"network_findings": []interface{}{portFinding("192.168.1.1", 80, "00:00:00:00:00:01")}}
+	scanB := map[string]interface{}{"network_findings": []interface{}{portFinding("192.168.1.1", 80, "00:00:00:00:00:02")}}
+
+	count := func(mode string) int {
+		hosts := mergeHostObservations(orgID, nil, hostObservationsFromScan(agentA, scanA, now), mode)
+		for _, h := range mergeHostObservations(orgID, hosts, hostObservationsFromScan(agentB, scanB, now), mode) {
+			if !containsHost(hosts, h) {
+				hosts = append(hosts, h)
+			}
+		}
+		return len(hosts)
+	}
+
+	assert.Equal(t, 2, count(NetworkAssetDedupIPMAC))
+	assert.Equal(t, 1, count(NetworkAssetDedupIP))
+	assert.Equal(t, 2, count(NetworkAssetDedupNone))
+}
+
+func containsHost(hosts []*models.NetworkHost, host *models.NetworkHost) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+func hostScan(subnet string, hosts ...map[string]interface{}) map[string]interface{} {
+	list := make([]interface{}, len(hosts))
+	for i, h := range hosts {
+		list[i] = h
+	}
+	return map[string]interface{}{
+		"hosts":    list,
+		"metadata": map[string]interface{}{"scan_scope": map[string]interface{}{"targets": []interface{}{subnet}}},
+	}
+}
+
+func TestMergeHostObservations_Identity(t *testing.T) {
+	orgID := uuid.New()
+	agentA := uuid.MustParse("00000000-0000-0000-0000-00000000000a") // On the hosts' segment, sees MACs
+	agentB := uuid.MustParse("00000000-0000-0000-0000-00000000000b") // Behind a router
+	agentC := uuid.MustParse("00000000-0000-0000-0000-00000000000c") // Another site reusing the address range
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var hosts []*models.NetworkHost
+	merge := func(agentID uuid.UUID, scan map[string]interface{}, at time.Time) {
+		for _, h := range mergeHostObservations(orgID, hosts, hostObservationsFromScan(agentID, scan, at), NetworkAssetDedupIdentity) {
+			if !containsHost(hosts, h) {
+				hosts = append(hosts, h)
+			}
+		}
+	}
+	find := func(ip string) []*models.NetworkHost {
+		var found []*models.NetworkHost
+		for _, h := range hosts {
+			if h.IPAddress == ip {
+				found = append(found, h)
+			}
+		}
+		return found
+	}
+
+	merge(agentA, hostScan("10.0.0.0/24",
+		map[string]interface{}{"ip": "10.0.0.10", "mac_address": "AA:BB:CC:00:00:10", "hostname": "web01", "ports": []interface{}{443.0}},
+		map[string]interface{}{"ip": "10.0.0.20", "ports": []interface{}{22.0}},
+		map[string]interface{}{"ip": "10.0.0.30", "ports": []interface{}{3389.0}},
+	), t0)
+	merge(agentB, hostScan("10.0.0.0/24",
+		map[string]interface{}{"ip": "10.0.0.10", "hostname": "WEB01.", "ports": []interface{}{443.0, 8443.0}},
+		map[string]interface{}{"ip": "10.0.0.20", "ports": []interface{}{80.0}},
+		map[string]interface{}{"ip": "10.0.0.30", "ports": []interface{}{3389.0}},
+	), t0.Add(time.Hour))
+	merge(agentC, hostScan("10.0.0.0/16",
+		map[string]interface{}{"ip": "10.0.0.30", "ports": []interface{}{3389.0}},
+	), t0.Add(time.Hour))
+
+	// Matched by hostname; attributes come from the observer on the segment
+	web := find("10.0.0.10")
+	require.Len(t, web, 1)
+	assert.Equal(t, []uuid.UUID{agentB, agentA}, web[0].Sources)
+	assert.Equal(t, hostMatchHostname, web[0].Observers[1].MatchedBy)
+	assert.Equal(t, "web01", web[0].Hostname)
+	assert.Equal(t, "mac:aa:bb:cc:00:00:10", web[0].IdentityKey)
+
+	// An IP alone is weak: merged only when the open ports corroborate it,
+	// and only within the same subnet
+	assert.Len(t, find("10.0.0.20"), 2)
+	rdp := find("10.0.0.30")
+	require.Len(t, rdp, 2)
+	for _, h := range rdp {
+		if h.ObserverCount == 2 {
+			assert.Equal(t, "ip:10.0.0.30@10.0.0.0/24", h.IdentityKey)
+		} else {
+			assert.Equal(t, []uuid.UUID{agentC}, h.Sources)
+		}
+	}
+
+	// DHCP moves the web server; its MAC keeps it the same host, while the
+	// device now holding its old address is a new one
+	merge(agentA, hostScan("10.0.0.0/24",
+		map[string]interface{}{"ip": "10.0.0.50", "mac_address": "aa:bb:cc:00:00:10", "ports": []interface{}{443.0}},
+		map[string]interface{}{"ip": "10.0.0.10", "mac_address": "aa:bb:cc:00:00:99", "ports": []interface{}{443.0}},
+	), t0.Add(2*time.Hour))
+	moved := find("10.0.0.50")
+	require.Len(t, moved, 1)
+	assert.Same(t, web[0], moved[0])
+	assert.Equal(t, "mac:aa:bb:cc:00:00:10", moved[0].IdentityKey)
+	newcomer := find("10.0.0.10")
+	require.Len(t, newcomer, 1)
+	assert.NotSame(t, web[0], newcomer[0])
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultTopologyRiskDecay is the share of a node's risk passed on to
+	// each node it can reach, per hop
+	DefaultTopologyRiskDecay = 0.7
+
+	// topologyEntryRisk is the risk score from which a node is treated as an
+	// attacker's way in when looking for critical paths
+	topologyEntryRisk = 70
+
+	// maxTopologyNodes bounds the graphs analyzed, as critical paths are
+	// searched from every high-risk node
+	maxTopologyNodes = 10000
+
+	// maxTopologyPaths bounds the critical paths reported, riskiest first
+	maxTopologyPaths = 100
+)
+
+// ErrInvalidTopology is returned for a graph that cannot be analyzed
+var ErrInvalidTopology = errors.New("invalid topology graph")
+
+// NetworkTopologyService builds an organization's network topology graph from
+// its agents, their host risk and the network hosts they observed, and
+// analyzes topology graphs, whether built here or imported from graph tools
+type NetworkTopologyService struct {
+	agentService  *AgentService
+	networkAssets *NetworkAssetService
+	hostRisk      *HostRiskService
+}
+
+// NewNetworkTopologyService creates a new network topology service
+func NewNetworkTopologyService(agentService *AgentService, networkAssets *NetworkAssetService, hostRisk *HostRiskService) *NetworkTopologyService {
+	return &NetworkTopologyService{
+		agentService:  agentService,
+		networkAssets: networkAssets,
+		hostRisk:      hostRisk,
+	}
+}
+
+// Build builds and analyzes an organization's topology. Each agent reaches
+// the network hosts it observed.
+func (s *NetworkTopologyService) Build(organizationID uuid.UUID, decay float64) (*models.TopologyGraph, error) {
+	risks, err := s.hostRisk.Rank(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := s.networkAssets.ListAssets(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network hosts: %w", err)
+	}
+
+	graph := buildTopology(organizationID, s.agentService.GetAgents(organizationID), risks, hosts)
+	if err := s.Analyze(graph, decay); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// buildTopology assembles an organization's topology graph. A network host at
+// an agent's address is the agent's own node.
+func buildTopology(organizationID uuid.UUID, agents []*models.Agent, risks []models.HostRisk, hosts []models.NetworkHost) *models.TopologyGraph {
+	graph := &models.TopologyGraph{
+		ID:    organizationID.String(),
+		Label: "Network topology",
+		Nodes: []models.TopologyNode{},
+		Edges: []models.TopologyEdge{},
+	}
+
+	riskByAgent := make(map[uuid.UUID]models.HostRisk, len(risks))
+	for _, risk := range risks {
+		riskByAgent[risk.AgentID] = risk
+	}
+
+	agentNodes := make(map[string]bool, len(agents))
+	nodeByIP := make(map[string]string)
+	for _, agent := range agents {
+		node := models.TopologyNode{
+			ID:          agent.ID.String(),
+			Label:       agent.Hostname,
+			Type:        models.TopologyNodeAgent,
+			IPAddress:   agent.IPAddress,
+			OS:          agent.OS,
+			Criticality: assetCriticality(agent),
+		}
+		if node.Label == "" {
+			node.Label = agent.Name
+		}
+		if risk, ok := riskByAgent[agent.ID]; ok {
+			node.RiskScore = risk.Score
+		}
+		graph.Nodes = append(graph.Nodes, node)
+		agentNodes[node.ID] = true
+		if agent.IPAddress != "" {
+			nodeByIP[agent.IPAddress] = node.ID
+		}
+	}
+
+	edges := make(map[[2]string]bool)
+	for _, host := range hosts {
+		id, isAgent := nodeByIP[host.IPAddress]
+		if !isAgent {
+			id = host.ID.String()
+			node := models.TopologyNode{
+				ID:          id,
+				Label:       host.Hostname,
+				Type:        models.TopologyNodeNetworkHost,
+				IPAddress:   host.IPAddress,
+				OS:          host.OS,
+				Criticality: models.AssetCriticalityMedium,
+			}
+			if node.Label == "" {
+				node.Label = host.IPAddress
+			}
+			if criticality, ok := host.Metadata["asset_criticality"].(string); ok {
+				if _, known := hostRiskCriticalityMultipliers[criticality]; known {
+					node.Criticality = criticality
+				}
+			}
+			graph.Nodes = append(graph.Nodes, node)
+		}
+
+		for _, observer := range host.Observers {
+			source := observer.AgentID.String()
+			edge := [2]string{source, id}
+			if !agentNodes[source] || source == id || edges[edge] {
+				continue
+			}
+			edges[edge] = true
+			graph.Edges = append(graph.Edges, models.TopologyEdge{Source: source, Target: id, Weight: 1})
+		}
+	}
+
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Source != graph.Edges[j].Source {
+			return graph.Edges[i].Source < graph.Edges[j].Source
+		}
+		return graph.Edges[i].Target < graph.Edges[j].Target
+	})
+	return graph
+}
+
+// topologyIndex is a topology graph's adjacency, by node ID
+type topologyIndex struct {
+	nodes map[string]*models.TopologyNode
+	edges map[string]map[string]float64 // source -> target -> weight
+}
+
+// Analyze analyzes a topology graph in place: it adjusts each node's risk for
+// the nodes that can reach it, passing on decay of it per hop, and finds the
+// critical paths from high-risk nodes to high-value ones. A decay of 0 leaves
+// risk unadjusted. Graphs that are not well formed are rejected with
+// ErrInvalidTopology.
+func (s *NetworkTopologyService) Analyze(graph *models.TopologyGraph, decay float64) error {
+	if decay < 0 || decay >= 1 {
+		return fmt.Errorf("%w: risk decay must be at least 0 and below 1, got %g", ErrInvalidTopology, decay)
+	}
+	index, err := indexTopology(graph)
+	if err != nil {
+		return err
+	}
+
+	propagateTopologyRisk(index, decay)
+
+	graph.CriticalPaths = findTopologyPaths(index)
+	critical := make(map[[2]string]bool)
+	for _, path := range graph.CriticalPaths {
+		for i := 0; i < len(path.Nodes)-1; i++ {
+			critical[[2]string{path.Nodes[i], path.Nodes[i+1]}] = true
+		}
+	}
+	for i := range graph.Edges {
+		graph.Edges[i].Critical = critical[[2]string{graph.Edges[i].Source, graph.Edges[i].Target}]
+	}
+
+	graph.RiskDecay = decay
+	graph.AnalyzedAt = time.Now()
+	return nil
+}
+
+// indexTopology checks a graph is well formed and indexes its adjacency
+func indexTopology(graph *models.TopologyGraph) (*topologyIndex, error) {
+	if len(graph.Nodes) > maxTopologyNodes {
+		return nil, fmt.Errorf("%w: %d nodes, at most %d are analyzed", ErrInvalidTopology, len(graph.Nodes), maxTopologyNodes)
+	}
+
+	index := &topologyIndex{
+		nodes: make(map[string]*models.TopologyNode, len(graph.Nodes)),
+		edges: make(map[string]map[string]float64),
+	}
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		switch {
+		case node.ID == "":
+			return nil, fmt.Errorf("%w: node %d has no id", ErrInvalidTopology, i)
+		case index.nodes[node.ID] != nil:
+			return nil, fmt.Errorf("%w: duplicate node %q", ErrInvalidTopology, node.ID)
+		case !(node.RiskScore >= 0 && node.RiskScore <= 100):
+			return nil, fmt.Errorf("%w: node %q has risk score %g, outside 0 to 100", ErrInvalidTopology, node.ID, node.RiskScore)
+		}
+		if node.Criticality == "" {
+			node.Criticality = models.AssetCriticalityMedium
+		} else if _, known := hostRiskCriticalityMultipliers[node.Criticality]; !known {
+			return nil, fmt.Errorf("%w: node %q has unknown criticality %q", ErrInvalidTopology, node.ID, node.Criticality)
+		}
+		index.nodes[node.ID] = node
+	}
+
+	for _, edge := range graph.Edges {
+		switch {
+		case index.nodes[edge.Source] == nil || index.nodes[edge.Target] == nil:
+			return nil, fmt.Errorf("%w: edge %q -> %q references an unknown node", ErrInvalidTopology, edge.Source, edge.Target)
+		case edge.Weight < 0 || math.IsNaN(edge.Weight) || math.IsInf(edge.Weight, 0):
+			return nil, fmt.Errorf("%w: edge %q -> %q has weight %g", ErrInvalidTopology, edge.Source, edge.Target, edge.Weight)
+		}
+		if index.edges[edge.Source] == nil {
+			index.edges[edge.Source] = make(map[string]float64)
+		}
+		index.edges[edge.Source][edge.Target] = edge.Weight
+	}
+	return index, nil
+}
+
+// propagateTopologyRisk passes risk along the graph's edges. A node exposes
+// everything it reaches: it passes on the higher of its own risk and the risk
+// it inherited, reduced by decay per hop. A node keeps the most risk reaching
+// it over any path, weighted by its criticality the way host risk scores are,
+// so an internal database behind a vulnerable DMZ host is scored by the DMZ
+// host's risk however low its own is.
+//
+// As the risk passed on only decreases along a path, the riskiest path to
+// each node is found the way Dijkstra finds the shortest.
+func propagateTopologyRisk(index *topologyIndex, decay float64) {
+	inherited := make(map[string]topologyPressure)
+	queue := &topologyPressureQueue{}
+	relax := func(from topologyPressure) {
+		for target := range index.edges[from.node] {
+			pressure := from.pressure * decay
+			if best, ok := inherited[target]; pressure > 0 && (!ok || pressure > best.pressure) {
+				next := topologyPressure{node: target, pressure: pressure, source: from.source, hops: from.hops + 1}
+				inherited[target] = next
+				heap.Push(queue, next)
+			}
+		}
+	}
+
+	// Every node starts out exposing its own risk
+	for id, node := range index.nodes {
+		relax(topologyPressure{node: id, pressure: node.RiskScore, source: id})
+	}
+	settled := make(map[string]bool)
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(topologyPressure)
+		if settled[current.node] || current.pressure < inherited[current.node].pressure {
+			continue
+		}
+		settled[current.node] = true
+		if current.pressure > index.nodes[current.node].RiskScore {
+			relax(current) // Passes on inherited risk beyond its own
+		}
+	}
+
+	for id, node := range index.nodes {
+		node.AdjustedRiskScore = node.RiskScore
+		node.InheritedRisk, node.RiskSource, node.RiskSourceHops = 0, "", 0
+		if from, ok := inherited[id]; ok && from.source != id {
+			node.InheritedRisk = roundScore(from.pressure)
+			node.RiskSource = from.source
+			node.RiskSourceHops = from.hops
+			weighted := from.pressure * hostRiskCriticalityMultipliers[node.Criticality]
+			node.AdjustedRiskScore = roundScore(math.Max(node.RiskScore, weighted))
+		}
+	}
+}
+
+// findTopologyPaths finds the cheapest path from each node with a risk score
+// of at least topologyEntryRisk to each high or critical node it reaches
+func findTopologyPaths(index *topologyIndex) []models.TopologyPath {
+	paths := []models.TopologyPath{}
+	for id, node := range index.nodes {
+		if node.RiskScore < topologyEntryRisk {
+			continue
+		}
+		distances, predecessors := topologyShortestPaths(index, id)
+		for target, distance := range distances {
+			criticality := index.nodes[target].Criticality
+			if target == id || (criticality != models.AssetCriticalityHigh && criticality != models.AssetCriticalityCritical) {
+				continue
+			}
+			nodes := []string{target}
+			for at := target; at != id; {
+				at = predecessors[at]
+				nodes = append([]string{at}, nodes...)
+			}
+			risk := 0.0
+			for _, hop := range nodes {
+				risk += index.nodes[hop].RiskScore
+			}
+			paths = append(paths, models.TopologyPath{
+				Source:    id,
+				Target:    target,
+				Nodes:     nodes,
+				Distance:  distance,
+				RiskScore: roundScore(risk / float64(len(nodes))),
+			})
+		}
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].RiskScore != paths[j].RiskScore {
+			return paths[i].RiskScore > paths[j].RiskScore
+		}
+		if paths[i].Distance != paths[j].Distance {
+			return paths[i].Distance < paths[j].Distance
+		}
+		if paths[i].Source != paths[j].Source {
+			return paths[i].Source < paths[j].Source
+		}
+		return paths[i].Target < paths[j].Target
+	})
+	if len(paths) > maxTopologyPaths {
+		paths = paths[:maxTopologyPaths]
+	}
+	return paths
+}
+
+// topologyShortestPaths runs Dijkstra from source, returning each reachable
+// node's distance and predecessor
+func topologyShortestPaths(index *topologyIndex, source string) (map[string]float64, map[string]string) {
+	distances := map[string]float64{source: 0}
+	predecessors := make(map[string]string)
+	queue := &topologyPressureQueue{{node: source}}
+	settled := make(map[string]bool)
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(topologyPressure)
+		if settled[current.node] {
+			continue
+		}
+		settled[current.node] = true
+		distance := -current.pressure // Queued negated, as the queue pops the highest first
+		for target, weight := range index.edges[current.node] {
+			if best, ok := distances[target]; !ok || distance+weight < best {
+				distances[target] = distance + weight
+				predecessors[target] = current.node
+				heap.Push(queue, topologyPressure{node: target, pressure: -(distance + weight)})
+			}
+		}
+	}
+	return distances, predecessors
+}
+
+// topologyPressure is risk reaching a node from source
+type topologyPressure struct {
+	node     string
+	pressure float64
+	source   string
+	hops     int
+}
+
+// topologyPressureQueue is a max-heap of nodes by pressure
+type topologyPressureQueue []topologyPressure
+
+func (q topologyPressureQueue) Len() int           { return len(q) }
+func (q topologyPressureQueue) Less(i, j int) bool { return q[i].pressure > q[j].pressure }
+func (q topologyPressureQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *topologyPressureQueue) Push(x any)        { *q = append(*q, x.(topologyPressure)) }
+func (q *topologyPressureQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dmzTopology is a vulnerable DMZ host that is the only way to an internal
+// database through an application server
+func dmzTopology() *models.TopologyGraph {
+	return &models.TopologyGraph{
+		Nodes: []models.TopologyNode{
+			{ID: "dmz", RiskScore: 90, Criticality: models.AssetCriticalityMedium},
+			{ID: "app", RiskScore: 10, Criticality: models.AssetCriticalityMedium},
+			{ID: "db", RiskScore: 20, Criticality: models.AssetCriticalityCritical},
+			{ID: "isolated", RiskScore: 30, Criticality: models.AssetCriticalityCritical},
+		},
+		Edges: []models.TopologyEdge{
+			{Source: "dmz", Target: "app", Weight: 1},
+			{Source: "app", Target: "db", Weight: 2},
+		},
+	}
+}
+
+func TestAnalyzeTopologyElevatesAssetsBehindVulnerableHosts(t *testing.T) {
+	graph := dmzTopology()
+	require.NoError(t, (&NetworkTopologyService{}).Analyze(graph, 0.5))
+
+	nodes := make(map[string]models.TopologyNode)
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = node
+	}
+	assert.Equal(t, 22.5, nodes["db"].AdjustedRiskScore, "db inherits 90 * 0.5 * 0.5 at full criticality")
+	assert.Equal(t, "dmz", nodes["db"].RiskSource)
+	assert.Equal(t, 2, nodes["db"].RiskSourceHops)
+	assert.Equal(t, 33.8, nodes["app"].AdjustedRiskScore, "app's inherited 45 is weighted by its medium criticality, rounded")
+	assert.Equal(t, 90.0, nodes["dmz"].AdjustedRiskScore)
+	assert.Equal(t, 30.0, nodes["isolated"].AdjustedRiskScore)
+
+	require.Len(t, graph.CriticalPaths, 1)
+	assert.Equal(t, []string{"dmz", "app", "db"}, graph.CriticalPaths[0].Nodes)
+	assert.Equal(t, 3.0, graph.CriticalPaths[0].Distance)
+	assert.True(t, graph.Edges[0].Critical && graph.Edges[1].Critical)
+}
+
+func TestAnalyzeTopologyRejectsMalformedGraphs(t *testing.T) {
+	service := &NetworkTopologyService{}
+
+	dangling := dmzTopology()
+	dangling.Edges = append(dangling.Edges, models.TopologyEdge{Source: "db", Target: "missing"})
+	assert.ErrorIs(t, service.Analyze(dangling, 0.5), ErrInvalidTopology)
+
+	unknownCriticality := dmzTopology()
+	unknownCriticality.Nodes[0].Criticality = "crown-jewel"
+	assert.ErrorIs(t, service.Analyze(unknownCriticality, 0.5), ErrInvalidTopology)
+
+	assert.ErrorIs(t, service.Analyze(dmzTopology(), 1), ErrInvalidTopology)
+}
+
+func TestTopologyFormatsRoundTrip(t *testing.T) {
+	for _, format := range []string{TopologyFormatJSON, TopologyFormatGraphML} {
+		t.Run(format, func(t *testing.T) {
+			var encoded bytes.Buffer
+			require.NoError(t, EncodeTopology(&encoded, dmzTopology(), format))
+
+			decoded, err := DecodeTopology(&encoded, format)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, dmzTopology().Nodes, decoded.Nodes)
+			assert.Equal(t, dmzTopology().Edges, decoded.Edges)
+		})
+	}
+}
+
+func TestDecodeGraphMLFromGraphTools(t *testing.T) {
+	// Numbered keys and an undirected graph, as tools such as Gephi export
+	graphML := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="node" attr.name="risk_score" attr.type="double"/>
+  <key id="d1" for="edge" attr.name="Weight" attr.type="double"/>
+  <graph edgedefault="undirected">
+    <node id="a"><data key="d0">80</data></node>
+    <node id="b"/>
+    <edge source="a" target="b"><data key="d1">2.5</data></edge>
+  </graph>
+</graphml>`
+
+	graph, err := DecodeTopology(strings.NewReader(graphML), TopologyFormatGraphML)
+	require.NoError(t, err)
+	require.Len(t, graph.Nodes, 2)
+	assert.Equal(t, 80.0, graph.Nodes[0].RiskScore)
+	assert.Equal(t, []models.TopologyEdge{
+		{Source: "a", Target: "b", Weight: 2.5},
+		{Source: "b", Target: "a", Weight: 2.5},
+	}, graph.Edges)
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidChunk is returned for a chunk whose position in its batch makes no sense
+	ErrInvalidChunk = errors.New("invalid result chunk")
+	// ErrChunkConflict is returned when a chunk disagrees with what was already received for its batch
+	ErrChunkConflict = errors.New("result chunk conflicts with its batch")
+)
+
+// AssembledResults is a complete batch merged back into one logical scan
+type AssembledResults struct {
+	Results  []models.AgentScanResult
+	Metadata map[string]interface{}
+}
+
+// resultBatchSweepInterval is how often expired batches are dropped
+const resultBatchSweepInterval = time.Minute
+
+// ResultBatchService stores the chunks of scans that agents split across
+// several submissions, and hands each batch back as one logical scan once
+// every chunk has arrived. Chunks are idempotent: resending a chunk that was
+// already received is acknowledged without changing anything. Batches that go
+// a full timeout without receiving a chunk are dropped.
+type ResultBatchService struct {
+	db        *gorm.DB
+	timeout   time.Duration
+	maxChunks int
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewResultBatchService creates a new result batch service
+func NewResultBatchService(db *gorm.DB, cfg *config.Config) *ResultBatchService {
+	timeout := cfg.ResultBatchTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+	maxChunks := cfg.ResultBatchMaxChunks
+	if maxChunks <= 0 {
+		maxChunks = 1000
+	}
+
+	return &ResultBatchService{
+		db:        db,
+		timeout:   timeout,
+		maxChunks: maxChunks,
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Start begins dropping expired batches in the background
+func (s *ResultBatchService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(resultBatchSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.sweepExpired(time.Now())
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background sweep
+func (s *ResultBatchService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+}
+
+// AddChunk stores one chunk of a batch. When it completes the batch, the
+// batch is also returned assembled into one logical scan; otherwise the
+// assembled results are nil.
+func (s *ResultBatchService) AddChunk(agentID, organizationID uuid.UUID, info models.ResultChunkInfo, results []models.AgentScanResult, metadata map[string]interface{}) (*models.ResultBatchStatus, *AssembledResults, error) {
+	batchID, err := uuid.Parse(info.BatchID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: batch_id must be a UUID", ErrInvalidChunk)
+	}
+	if info.Total < 1 || info.Total > s.maxChunks {
+		return nil, nil, fmt.Errorf("%w: total must be between 1 and %d, got %d", ErrInvalidChunk, s.maxChunks, info.Total)
+	}
+	if info.Sequence < 0 || info.Sequence >= info.Total {
+		return nil, nil, fmt.Errorf("%w: sequence must be between 0 and %d, got %d", ErrInvalidChunk, info.Total-1, info.Sequence)
+	}
+
+	checksum, err := chunkChecksum(results, metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		status    *models.ResultBatchStatus
+		assembled *AssembledResults
+	)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// The first chunk to arrive creates the batch; concurrent chunks then
+		// queue on its row lock
+		batch := models.ResultBatch{
+			ID:             batchID,
+			AgentID:        agentID,
+			OrganizationID: organizationID,
+			TotalChunks:    info.Total,
+			Status:         models.ResultBatchReceiving,
+			ExpiresAt:      now.Add(s.timeout),
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&batch).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&batch, "id = ?", batchID).Error; err != nil {
+			return err
+		}
+		if batch.AgentID != agentID {
+			return fmt.Errorf("%w: batch %s belongs to another agent", ErrChunkConflict, batchID)
+		}
+		if batch.TotalChunks != info.Total {
+			return fmt.Errorf("%w: batch %s has %d chunks, not %d", ErrChunkConflict, batchID, batch.TotalChunks, info.Total)
+		}
+
+		status = &models.ResultBatchStatus{BatchID: batchID, Total: batch.TotalChunks}
+		if batch.Status == models.ResultBatchComplete {
+			status.Received = batch.ReceivedChunks
+			status.Complete = true
+			status.Duplicate = true
+			return nil
+		}
+
+		var existing models.ResultChunk
+		err := tx.Select("checksum").Where("batch_id = ? AND sequence = ?", batchID, info.Sequence).Take(&existing).Error
+		switch {
+		case err == nil:
+			if existing.Checksum != checksum {
+				return fmt.Errorf("%w: chunk %d of batch %s was already received with different content", ErrChunkConflict, info.Sequence, batchID)
+			}
+			status.Duplicate = true
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			chunk := models.ResultChunk{
+				BatchID:  batchID,
+				Sequence: info.Sequence,
+				Checksum: checksum,
+				Results:  results,
+				Metadata: metadata,
+			}
+			if err := tx.Create(&chunk).Error; err != nil {
+				return err
+			}
+			batch.ReceivedChunks++
+		default:
+			return err
+		}
+
+		batch.ExpiresAt = now.Add(s.timeout)
+		if batch.ReceivedChunks == batch.TotalChunks {
+			var chunks []models.ResultChunk
+			if err := tx.Where("batch_id = ?", batchID).Order("sequence").Find(&chunks).Error; err != nil {
+				return err
+			}
+			assembled = assembleChunks(batchID, chunks)
+			batch.Status = models.ResultBatchComplete
+			batch.CompletedAt = &now
+		}
+		if err := tx.Save(&batch).Error; err != nil {
+			return err
+		}
+
+		status.Received = batch.ReceivedChunks
+		status.Complete = batch.Status == models.ResultBatchComplete
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return status, assembled, nil
+}
+
+// ReleaseBatch reopens a completed batch whose results could not be
+// processed, so the agent resending any chunk hands it off again
+func (s *ResultBatchService) ReleaseBatch(batchID uuid.UUID) error {
+	return s.db.Model(&models.ResultBatch{}).
+		Where("id = ? AND status = ?", batchID, models.ResultBatchComplete).
+		Updates(map[string]interface{}{
+			"status":       models.ResultBatchReceiving,
+			"completed_at": nil,
+		}).Error
+}
+
+// sweepExpired drops batches, complete or not, that have not received a chunk
+// within the timeout. Complete batches are kept until then so that late
+// resends of their chunks are still recognized as duplicates.
+func (s *ResultBatchService) sweepExpired(now time.Time) {
+	var expired []models.ResultBatch
+	if err := s.db.Select("id", "agent_id", "status", "received_chunks", "total_chunks").
+		Where("expires_at < ?", now).Find(&expired).Error; err != nil {
+		log.Printf("Failed to find expired result batches: %v", err)
+		return
+	}
+
+	for _, batch := range expired {
+		dropped := false
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// Recheck the expiry in case a chunk arrived since the batch was listed
+			result := tx.Where("id = ? AND expires_at < ?", batch.ID, now).Delete(&models.ResultBatch{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			dropped = true
+			return tx.Where("batch_id = ?", batch.ID).Delete(&models.ResultChunk{}).Error
+		})
+		if err != nil {
+			log.Printf("Failed to drop expired result batch %s: %v", batch.ID, err)
+			continue
+		}
+		if !dropped {
+			continue
+		}
+		if batch.Status != models.ResultBatchComplete {
+			log.Printf("Dropped incomplete result batch %s from agent %s (%d of %d chunks received)", batch.ID, batch.AgentID, batch.ReceivedChunks, batch.TotalChunks)
+		}
+	}
+}
+
+// chunkChecksum identifies a chunk's content, so a resend can be told apart
+// from a different chunk reusing the same sequence
+func chunkChecksum(results []models.AgentScanResult, metadata map[string]interface{}) (string, error) {
+	data, err := json.Marshal(struct {
+		Results  []models.AgentScanResult `json:"results"`
+		Metadata map[string]interface{}   `json:"metadata"`
+	}{results, metadata})
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum result chunk: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// assembleChunks merges a batch's chunks, in sequence order, back into the
+// scan they were split from. Parts of the same result (same ID) are joined
+// into one result; metadata from later chunks overrides earlier chunks.
+func assembleChunks(batchID uuid.UUID, chunks []models.ResultChunk) *AssembledResults {
+	results := []models.AgentScanResult{}
+	index := make(map[uuid.UUID]int)
+	metadata := make(map[string]interface{})
+
+	for _, chunk := range chunks {
+		for _, part := range chunk.Results {
+			i, seen := index[part.ID]
+			if !seen {
+				index[part.ID] = len(results)
+				results = append(results, part)
+				continue
+			}
+			merged := &results[i]
+			merged.Dependencies = append(merged.Dependencies, part.Dependencies...)
+			merged.Vulnerabilities = append(merged.Vulnerabilities, part.Vulnerabilities...)
+			if part.EndTime.After(merged.EndTime) {
+				merged.EndTime = part.EndTime
+			}
+			for k, v := range part.Metadata {
+				if merged.Metadata == nil {
+					merged.Metadata = make(map[string]interface{})
+				}
+				merged.Metadata[k] = v
+			}
+		}
+		for k, v := range chunk.Metadata {
+			metadata[k] = v
+		}
+	}
+
+	metadata["batch_id"] = batchID.String()
+	metadata["batch_chunks"] = len(chunks)
+	return &AssembledResults{Results: results, Metadata: metadata}
+}
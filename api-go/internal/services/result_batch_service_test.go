@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestAssembleChunksMergesSplitResults(t *testing.T) {
+	batchID := uuid.New()
+	scanID := uuid.New()
+
+	chunks := []models.ResultChunk{
+		{Sequence: 0, Results: []models.AgentScanResult{{
+			ID:           scanID,
+			Dependencies: []models.Dependency{{Name: "numpy"}, {Name: "torch"}},
+			Metadata:     map[string]any{"scan_type": "software"},
+		}}, Metadata: map[string]interface{}{"status": "running"}},
+		{Sequence: 1, Results: []models.AgentScanResult{{
+			ID:              scanID,
+			Dependencies:    []models.Dependency{{Name: "pandas"}},
+			Vulnerabilities: []models.Vulnerability{{CVEID: "CVE-2024-0001"}},
+		}}, Metadata: map[string]interface{}{"status": "completed"}},
+	}
+
+	assembled := assembleChunks(batchID, chunks)
+
+	if len(assembled.Results) != 1 {
+		t.Fatalf("expected parts of one scan to merge into one result, got %d", len(assembled.Results))
+	}
+	result := assembled.Results[0]
+	if len(result.Dependencies) != 3 || result.Dependencies[2].Name != "pandas" {
+		t.Errorf("expected dependencies in chunk order, got %+v", result.Dependencies)
+	}
+	if len(result.Vulnerabilities) != 1 || result.Metadata["scan_type"] != "software" {
+		t.Errorf("unexpected merged result: %+v", result)
+	}
+	if assembled.Metadata["status"] != "completed" || assembled.Metadata["batch_id"] != batchID.String() || assembled.Metadata["batch_chunks"] != 2 {
+		t.Errorf("unexpected merged metadata: %+v", assembled.Metadata)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ResultIngestion is one scan result submission to record
+type ResultIngestion struct {
+	AgentID  string
+	Results  []models.AgentScanResult
+	Metadata map[string]interface{}
+	// Scopes holds the findings the submission reported, by the scan type
+	// that reported them. They are ignored unless TrackFindings is set; an
+	// incomplete picture would wrongly resolve findings.
+	Scopes        []ScopeFindings
+	TrackFindings bool
+}
+
+// ScopeFindings is what a submission reported for one scope
+type ScopeFindings struct {
+	Scope string
+	// Findings is the complete set of findings reported for Scope, including
+	// enriched ones
+	Findings []models.Vulnerability
+	// Delta, when set, makes Findings only the new and changed findings since
+	// the agent last reported Scope, naming the rest by finding key
+	Delta *models.FindingDelta
+}
+
+// ResultIngestionService records scan result submissions all-or-nothing: the
+// agent's results, its reported software, its finding states and its host
+// risk are written in one transaction, so a submission that fails part way
+// leaves nothing behind and the agent can safely resend it whole.
+type ResultIngestionService struct {
+	db            *gorm.DB
+	agentService  *AgentService
+	findingStates *FindingStateService
+	hostRisk      *HostRiskService
+
+	locks    sync.Map                         // agent ID -> *sync.Mutex, serializing each agent's submissions
+	onIngest []func(organizationID uuid.UUID) // Called after each recorded submission
+}
+
+// NewResultIngestionService creates a new result ingestion service
+func NewResultIngestionService(db *gorm.DB, agentService *AgentService, findingStates *FindingStateService, hostRisk *HostRiskService) *ResultIngestionService {
+	return &ResultIngestionService{
+		db:            db,
+		agentService:  agentService,
+		findingStates: findingStates,
+		hostRisk:      hostRisk,
+	}
+}
+
+// OnIngest registers a function called with the organization of each
+// submission once it is recorded, such as to drop results cached from
+// before it. Functions must be registered before submissions are ingested.
+func (s *ResultIngestionService) OnIngest(fn func(organizationID uuid.UUID)) {
+	s.onIngest = append(s.onIngest, fn)
+}
+
+// Ingest records a submission, returning the finding transitions it caused.
+// On error nothing was recorded.
+func (s *ResultIngestionService) Ingest(in *ResultIngestion) ([]models.FindingTransition, error) {
+	agentID, err := uuid.Parse(in.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent ID format: %w", err)
+	}
+
+	// Each submission is staged on top of the agent's last committed one
+	lock, _ := s.locks.LoadOrStore(agentID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// Priorities are derived here rather than trusted from the scanner, so
+	// they are consistent across scanners and honor overrides. CVSS scores
+	// are recomputed from the findings' vectors, with the environmental score
+	// reflecting the organization's security requirements.
+	if agent, exists := s.agentService.GetAgent(agentID); exists {
+		requirements, err := cvssRequirements(s.db, agent.OrganizationID)
+		if err != nil {
+			log.Printf("Scoring agent %s's findings without its organization's security requirements: %v", agentID, err)
+		}
+		for i := range in.Results {
+			scoreCVSS(in.Results[i].Vulnerabilities, requirements)
+		}
+		if enriched, ok := in.Metadata["enriched_vulnerabilities"].([]models.Vulnerability); ok {
+			scoreCVSS(enriched, requirements)
+		}
+		for i := range in.Scopes {
+			scoreCVSS(in.Scopes[i].Findings, requirements)
+		}
+
+		criticality := assetCriticality(agent)
+		for i := range in.Results {
+			s.findingStates.Prioritize(agentID, criticality, in.Results[i].Vulnerabilities)
+		}
+		if enriched, ok := in.Metadata["enriched_vulnerabilities"].([]models.Vulnerability); ok {
+			s.findingStates.Prioritize(agentID, criticality, enriched)
+		}
+	}
+
+	staged, err := s.agentService.stageAgentResults(in.AgentID, in.Results, in.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var transitions []models.FindingTransition
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if in.TrackFindings {
+			now := time.Now()
+			rules, err := activeSuppressionRules(tx, staged.OrganizationID, now)
+			if err != nil {
+				return fmt.Errorf("failed to load suppression rules: %w", err)
+			}
+			for _, scope := range in.Scopes {
+				var observed []models.FindingTransition
+				if scope.Delta != nil {
+					observed, err = s.findingStates.ObserveDelta(tx, staged.ID, staged.OrganizationID, scope.Scope, assetCriticality(staged), scope.Findings, scope.Delta, rules, now)
+				} else {
+					observed, err = s.findingStates.Observe(tx, staged.ID, staged.OrganizationID, scope.Scope, assetCriticality(staged), scope.Findings, rules, now)
+				}
+				if err != nil {
+					return err
+				}
+				transitions = append(transitions, observed...)
+			}
+			risk, err := s.hostRisk.recompute(tx, staged)
+			if err != nil {
+				return err
+			}
+			staged.RiskScore = risk.Score
+		}
+		return s.agentService.saveAgentResults(tx, staged, in.Results)
+	})
+	if err != nil {
+		// Observe already updated the cached states the rollback discarded
+		s.findingStates.Forget(staged.ID)
+		return nil, err
+	}
+
+	s.agentService.applyAgentResults(staged)
+	for _, fn := range s.onIngest {
+		fn(staged.OrganizationID)
+	}
+	return transitions, nil
+}
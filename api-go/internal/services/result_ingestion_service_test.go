@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStagedResultsLeaveAgentUntilApplied(t *testing.T) {
+	agentID := uuid.New()
+	agent := &models.Agent{ID: agentID, Metadata: map[string]interface{}{"host_group": "web"}}
+	as := &AgentService{agents: map[uuid.UUID]*models.Agent{agentID: agent}}
+
+	results := []models.AgentScanResult{{
+		Dependencies:    []models.Dependency{{Name: "openssl", Version: "3.0.1"}},
+		Vulnerabilities: []models.Vulnerability{{CVEID: "CVE-2024-0001", Severity: "critical"}},
+	}}
+	staged, err := as.stageAgentResults(agentID.String(), results, map[string]interface{}{"scan_id": "s1"})
+	require.NoError(t, err)
+
+	// A submission that is rolled back must not show up on the agent
+	assert.Equal(t, 1, staged.Metadata["critical_vulnerabilities"])
+	assert.NotContains(t, agent.Metadata, "critical_vulnerabilities")
+	assert.NotContains(t, agent.Metadata, "scan_id")
+
+	staged.RiskScore = 42
+	as.applyAgentResults(staged)
+	assert.Equal(t, 1, agent.Metadata["critical_vulnerabilities"])
+	assert.Equal(t, "web", agent.Metadata["host_group"])
+	assert.Equal(t, 42.0, agent.RiskScore)
+
+	_, err = as.stageAgentResults(uuid.NewString(), results, nil)
+	assert.ErrorIs(t, err, ErrAgentNotFound)
+}
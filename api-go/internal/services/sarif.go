@@ -0,0 +1,334 @@
+package services
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"zerotrace/api/internal/models"
+)
+
+// SARIFSchema is the JSON schema of SARIF 2.1.0 logs
+const SARIFSchema = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+// sarifFingerprint names the finding key in exported results' partial
+// fingerprints, so code scanning tracks a finding across uploads
+const sarifFingerprint = "zerotraceFindingKey/v1"
+
+// SARIFLog is a SARIF 2.1.0 log, as much of it as findings are imported from
+// and exported as
+type SARIFLog struct {
+	Schema  string     `json:"$schema,omitempty"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool                     SARIFTool                 `json:"tool"`
+	Results                  []SARIFResult             `json:"results"`
+	VersionControlProvenance []SARIFVersionControlInfo `json:"versionControlProvenance,omitempty"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []SARIFRule `json:"rules,omitempty"`
+}
+
+type SARIFRule struct {
+	ID               string           `json:"id"`
+	Name             string           `json:"name,omitempty"`
+	ShortDescription *SARIFMessage    `json:"shortDescription,omitempty"`
+	FullDescription  *SARIFMessage    `json:"fullDescription,omitempty"`
+	Help             *SARIFMessage    `json:"help,omitempty"`
+	HelpURI          string           `json:"helpUri,omitempty"`
+	Properties       *SARIFProperties `json:"properties,omitempty"`
+}
+
+type SARIFProperties struct {
+	SecuritySeverity string   `json:"security-severity,omitempty"` // CVSS-style score, as GitHub code scanning reads it
+	Tags             []string `json:"tags,omitempty"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           *int              `json:"ruleIndex,omitempty"`
+	Level               string            `json:"level,omitempty"`
+	Kind                string            `json:"kind,omitempty"`
+	Message             SARIFMessage      `json:"message"`
+	Locations           []SARIFLocation   `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation *SARIFPhysicalLocation `json:"physicalLocation,omitempty"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation *SARIFArtifactLocation `json:"artifactLocation,omitempty"`
+	Region           *SARIFRegion           `json:"region,omitempty"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFRegion struct {
+	StartLine int `json:"startLine,omitempty"`
+}
+
+type SARIFVersionControlInfo struct {
+	RepositoryURI string `json:"repositoryUri"`
+	RevisionID    string `json:"revisionId,omitempty"`
+	Branch        string `json:"branch,omitempty"`
+}
+
+// SARIFLevel is a severity's SARIF result level
+func SARIFLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	}
+	return "note"
+}
+
+// sarifSecuritySeverity is the score code scanning ranks a rule by: its CVSS
+// score, or one in the middle of its severity's band
+func sarifSecuritySeverity(severity string, cvss *float64) string {
+	if cvss != nil && *cvss > 0 {
+		return strconv.FormatFloat(*cvss, 'f', 1, 64)
+	}
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "9.5"
+	case "high":
+		return "8.0"
+	case "medium":
+		return "5.5"
+	case "low":
+		return "2.0"
+	}
+	return "0.0"
+}
+
+// sarifRules collects each rule once, in the order first seen, and returns
+// the index of a result's rule
+type sarifRules struct {
+	rules   []SARIFRule
+	indexes map[string]int
+}
+
+func (r *sarifRules) add(rule SARIFRule) *int {
+	if r.indexes == nil {
+		r.indexes = make(map[string]int)
+	}
+	index, ok := r.indexes[rule.ID]
+	if !ok {
+		index = len(r.rules)
+		r.indexes[rule.ID] = index
+		r.rules = append(r.rules, rule)
+	}
+	return &index
+}
+
+func newSARIFLog(rules *sarifRules, results []SARIFResult) *SARIFLog {
+	if results == nil {
+		results = []SARIFResult{}
+	}
+	return &SARIFLog{
+		Schema:  SARIFSchema,
+		Version: "2.1.0",
+		Runs: []SARIFRun{{
+			Tool: SARIFTool{Driver: SARIFDriver{
+				Name:           "ZeroTrace",
+				InformationURI: "https://github.com/adhit-r/ZeroTrace",
+				Rules:          rules.rules,
+			}},
+			Results: results,
+		}},
+	}
+}
+
+// sarifPhysicalLocation locates a result in a file, at a line where known
+func sarifPhysicalLocation(uri string, line int) []SARIFLocation {
+	if uri == "" {
+		return nil
+	}
+	location := &SARIFPhysicalLocation{ArtifactLocation: &SARIFArtifactLocation{URI: uri}}
+	if line > 0 {
+		location.Region = &SARIFRegion{StartLine: line}
+	}
+	return []SARIFLocation{{PhysicalLocation: location}}
+}
+
+// ConfigFindingsSARIF exports config findings as a SARIF log. Each finding's
+// rule is its CVE, or else the requirement of the standard it violates, or
+// else its finding type; it is located in the uploaded config file, at its
+// first line where known. Findings should have their ConfigFile and Standard
+// loaded.
+func ConfigFindingsSARIF(findings []models.ConfigFinding) *SARIFLog {
+	var rules sarifRules
+	results := make([]SARIFResult, 0, len(findings))
+	for _, finding := range findings {
+		ruleID := finding.CVEID
+		if ruleID == "" && finding.Standard != nil && finding.Standard.RequirementID != "" {
+			ruleID = finding.Standard.RequirementID
+		}
+		if ruleID == "" {
+			ruleID = finding.FindingType
+		}
+		rule := SARIFRule{
+			ID:               ruleID,
+			ShortDescription: &SARIFMessage{Text: finding.Title},
+			Properties: &SARIFProperties{
+				SecuritySeverity: sarifSecuritySeverity(finding.Severity, finding.CVSSScore),
+				Tags:             sarifTags("configuration", finding.Category),
+			},
+		}
+		if finding.Remediation != "" {
+			rule.Help = &SARIFMessage{Text: finding.Remediation}
+		}
+
+		var lines []int
+		if len(finding.LineNumbers) > 0 {
+			_ = json.Unmarshal(finding.LineNumbers, &lines) // A malformed list only loses the line
+		}
+		line := 0
+		if len(lines) > 0 {
+			line = slices.Min(lines)
+		}
+		results = append(results, SARIFResult{
+			RuleID:    ruleID,
+			RuleIndex: rules.add(rule),
+			Level:     SARIFLevel(finding.Severity),
+			Message:   SARIFMessage{Text: sarifMessageText(finding.Title, finding.Description)},
+			Locations: sarifPhysicalLocation(finding.ConfigFile.Filename, line),
+			PartialFingerprints: map[string]string{
+				sarifFingerprint: finding.ID.String(),
+			},
+			Properties: map[string]any{
+				"severity":           finding.Severity,
+				"status":             finding.Status,
+				"affected_component": finding.AffectedComponent,
+				"config_file_id":     finding.ConfigFileID,
+			},
+		})
+	}
+	return newSARIFLog(&rules, results)
+}
+
+// ScanSARIF exports a scan's vulnerabilities as a SARIF log, with the
+// repository and commit scanned where the scan has them. Each vulnerability's
+// rule is its CVE, or else its title; it is located at its location, or else
+// in its package.
+func ScanSARIF(scan *models.Scan, vulnerabilities []models.Vulnerability) *SARIFLog {
+	var rules sarifRules
+	results := make([]SARIFResult, 0, len(vulnerabilities))
+	for i := range vulnerabilities {
+		vuln := &vulnerabilities[i]
+		severity := strings.ToLower(string(vuln.Severity))
+		ruleID := vuln.CVEID
+		if ruleID == "" {
+			ruleID = vuln.Title
+		}
+		rule := SARIFRule{
+			ID:               ruleID,
+			ShortDescription: &SARIFMessage{Text: vuln.Title},
+			Properties: &SARIFProperties{
+				SecuritySeverity: sarifSecuritySeverity(severity, vuln.CVSSScore),
+				Tags:             sarifTags(vuln.Type),
+			},
+		}
+		if vuln.Remediation != "" {
+			rule.Help = &SARIFMessage{Text: vuln.Remediation}
+		}
+		if len(vuln.References) > 0 {
+			rule.HelpURI = vuln.References[0]
+		}
+
+		uri, line := splitSARIFLocation(vuln.Location)
+		if uri == "" {
+			uri = vuln.PackageName
+		}
+		properties := map[string]any{
+			"severity": severity,
+			"status":   vuln.Status,
+		}
+		if vuln.PackageName != "" {
+			properties["package"] = vuln.PackageName
+			properties["version"] = vuln.PackageVersion
+		}
+		if vuln.EPSSScore != nil {
+			properties["epss_score"] = *vuln.EPSSScore
+		}
+		if vuln.Priority != "" {
+			properties["priority"] = vuln.Priority
+		}
+		results = append(results, SARIFResult{
+			RuleID:    ruleID,
+			RuleIndex: rules.add(rule),
+			Level:     SARIFLevel(severity),
+			Message:   SARIFMessage{Text: sarifMessageText(vuln.Title, vuln.Description)},
+			Locations: sarifPhysicalLocation(uri, line),
+			PartialFingerprints: map[string]string{
+				sarifFingerprint: FindingKey(vuln),
+			},
+			Properties: properties,
+		})
+	}
+
+	sarif := newSARIFLog(&rules, results)
+	if scan != nil && scan.Repository != "" {
+		sarif.Runs[0].VersionControlProvenance = []SARIFVersionControlInfo{{
+			RepositoryURI: scan.Repository,
+			RevisionID:    scan.Commit,
+			Branch:        scan.Branch,
+		}}
+	}
+	return sarif
+}
+
+// splitSARIFLocation splits a "path:line" location into its path and line
+func splitSARIFLocation(location string) (string, int) {
+	if i := strings.LastIndexByte(location, ':'); i > 0 {
+		if line, err := strconv.Atoi(location[i+1:]); err == nil && line > 0 {
+			return location[:i], line
+		}
+	}
+	return location, 0
+}
+
+// sarifTags tags a rule as a security rule, and with the given tags that are set
+func sarifTags(tags ...string) []string {
+	result := []string{"security"}
+	for _, tag := range tags {
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// sarifMessageText is a result's message, which SARIF requires to be set
+func sarifMessageText(title, description string) string {
+	if description != "" {
+		return description
+	}
+	if title != "" {
+		return title
+	}
+	return "Finding"
+}
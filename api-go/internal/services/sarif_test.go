@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestSARIFLevel(t *testing.T) {
+	for severity, level := range map[string]string{
+		"critical": "error", "HIGH": "error", "medium": "warning", "low": "note", "info": "note", "": "note",
+	} {
+		assert.Equal(t, level, SARIFLevel(severity), severity)
+	}
+}
+
+func TestConfigFindingsSARIF(t *testing.T) {
+	standard := &models.ConfigStandard{RequirementID: "CIS-2.1.3"}
+	findings := []models.ConfigFinding{
+		{
+			ID: uuid.New(), FindingType: "compliance_violation", Severity: "high", Category: "network",
+			Title: "SSH version 1 enabled", Description: "SSH v1 has known weaknesses",
+			LineNumbers: datatypes.JSON(`[14, 12]`), Standard: standard,
+			ConfigFile: models.ConfigFile{Filename: "core-switch.cfg"},
+		},
+		{
+			ID: uuid.New(), FindingType: "default_credentials", Severity: "medium",
+			Title: "Default User Account Detected", ConfigFile: models.ConfigFile{Filename: "edge.cfg"},
+		},
+		{
+			ID: uuid.New(), FindingType: "compliance_violation", Severity: "high",
+			Title: "SSH version 1 enabled", Standard: standard, ConfigFile: models.ConfigFile{Filename: "edge.cfg"},
+		},
+	}
+
+	log := ConfigFindingsSARIF(findings)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Equal(t, "ZeroTrace", run.Tool.Driver.Name)
+	require.Len(t, run.Tool.Driver.Rules, 2, "findings of the same rule share it")
+	assert.Equal(t, "8.0", run.Tool.Driver.Rules[0].Properties.SecuritySeverity)
+	require.Len(t, run.Results, 3)
+
+	result := run.Results[0]
+	assert.Equal(t, "CIS-2.1.3", result.RuleID, "the standard's requirement names the rule")
+	assert.Equal(t, "error", result.Level)
+	assert.Equal(t, "SSH v1 has known weaknesses", result.Message.Text)
+	assert.Equal(t, "core-switch.cfg", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 12, result.Locations[0].PhysicalLocation.Region.StartLine, "the first line")
+
+	assert.Equal(t, "default_credentials", run.Results[1].RuleID)
+	assert.Equal(t, "warning", run.Results[1].Level)
+	assert.Nil(t, run.Results[1].Locations[0].PhysicalLocation.Region)
+	assert.Equal(t, 1, *run.Results[1].RuleIndex)
+	assert.Equal(t, 0, *run.Results[2].RuleIndex)
+}
+
+func TestScanSARIFRoundTrips(t *testing.T) {
+	score := 9.8
+	scan := &models.Scan{Repository: "https://github.com/example/shop", Branch: "main", Commit: "4f2a9c1"}
+	vulnerabilities := []models.Vulnerability{
+		{
+			Type: "dependency", Severity: models.SeverityCritical, Title: "openssl buffer overflow", CVEID: "CVE-2022-3602",
+			CVSSScore: &score, PackageName: "openssl", PackageVersion: "3.0.6", Location: "package-lock.json",
+		},
+		{Type: "code", Severity: "medium", Title: "eval() use", Location: "app/views.py:42"},
+	}
+
+	log := ScanSARIF(scan, vulnerabilities)
+	run := log.Runs[0]
+	require.Len(t, run.VersionControlProvenance, 1)
+	assert.Equal(t, "4f2a9c1", run.VersionControlProvenance[0].RevisionID)
+	assert.Equal(t, "9.8", run.Tool.Driver.Rules[0].Properties.SecuritySeverity)
+	assert.Equal(t, FindingKey(&vulnerabilities[0]), run.Results[0].PartialFingerprints[sarifFingerprint])
+	assert.Equal(t, "app/views.py", run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 42, run.Results[1].Locations[0].PhysicalLocation.Region.StartLine)
+
+	// What ZeroTrace exports, it can import
+	data, err := json.Marshal(log)
+	require.NoError(t, err)
+	report, err := ParseScanReport(data, "")
+	require.NoError(t, err)
+	assert.Equal(t, "ZeroTrace", report.Scanner)
+	require.Len(t, report.Findings, 2)
+	assert.Equal(t, "CVE-2022-3602", report.Findings[0].CVEID)
+	assert.Equal(t, models.SeverityLevel("critical"), report.Findings[0].Severity)
+	assert.Equal(t, "app/views.py:42", report.Findings[1].Location)
+	assert.Equal(t, models.SeverityLevel("medium"), report.Findings[1].Severity)
+}
+
+func TestScanSARIFWithoutFindings(t *testing.T) {
+	data, err := json.Marshal(ScanSARIF(&models.Scan{}, nil))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"results":[]`, "SARIF requires results")
+	assert.NotContains(t, string(data), "versionControlProvenance")
+}
@@ -1,14 +1,28 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/logging"
 	"zerotrace/api/internal/models"
 	"zerotrace/api/internal/repository"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrScanNotFound is returned for a scan that does not exist
+	ErrScanNotFound = errors.New("scan not found")
+	// ErrScanForbidden is returned for a scan that belongs to another company
+	ErrScanForbidden = errors.New("scan belongs to another company")
+	// ErrScanAgentMismatch is returned when diffing scans that aren't both
+	// of the requested agent
+	ErrScanAgentMismatch = errors.New("scans do not belong to the same agent")
 )
 
 // ScanService handles scan operations
@@ -16,20 +30,22 @@ type ScanService struct {
 	config            *config.Config
 	scanRepo          *repository.ScanRepository
 	enrichmentService *EnrichmentService
+	progress          *ScanProgressBus // Told of scans' status and finding counts as they change; optional
 }
 
 // NewScanService creates a new scan service
-func NewScanService(cfg *config.Config, scanRepo *repository.ScanRepository) *ScanService {
-	enrichmentService := NewEnrichmentService(cfg.EnrichmentServiceURL)
+func NewScanService(cfg *config.Config, scanRepo *repository.ScanRepository, progress *ScanProgressBus) *ScanService {
+	enrichmentService := NewEnrichmentService(cfg, nil)
 	return &ScanService{
 		config:            cfg,
 		scanRepo:          scanRepo,
 		enrichmentService: enrichmentService,
+		progress:          progress,
 	}
 }
 
 // CreateScan creates a new scan with transaction management
-func (s *ScanService) CreateScan(req models.CreateScanRequest, companyID uuid.UUID) (*models.Scan, error) {
+func (s *ScanService) CreateScan(ctx context.Context, req models.CreateScanRequest, companyID uuid.UUID) (*models.Scan, error) {
 	scan := &models.Scan{
 		ID:         uuid.New(),
 		CompanyID:  companyID,
@@ -46,38 +62,184 @@ func (s *ScanService) CreateScan(req models.CreateScanRequest, companyID uuid.UU
 	}
 
 	// Save to database using repository (repository handles transactions internally)
-	err := s.scanRepo.Create(scan)
+	err := s.scanRepo.Create(companyID, scan)
 	if err != nil {
 		return nil, err
 	}
 
 	// TODO: Queue scan for processing
 
+	s.publishProgress(ctx, scan)
+	logging.FromContext(ctx).Info("Scan created", "scan_id", scan.ID, "company_id", companyID, "scan_type", scan.ScanType)
 	return scan, nil
 }
 
 // GetScan retrieves a scan by ID
-func (s *ScanService) GetScan(scanID, companyID uuid.UUID) (*models.Scan, error) {
+func (s *ScanService) GetScan(ctx context.Context, scanID, companyID uuid.UUID) (*models.Scan, error) {
 	if scanID == uuid.Nil {
 		return nil, errors.New("invalid scan ID")
 	}
 
 	// Query from database using repository
-	scan, err := s.scanRepo.GetByID(scanID)
+	scan, err := s.scanRepo.GetByID(companyID, scanID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Verify whether the scan is another company's
+		exists, err := s.scanRepo.Exists(scanID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			logging.FromContext(ctx).Warn("Scan requested by another company", "scan_id", scanID, "company_id", companyID)
+			return nil, ErrScanForbidden
+		}
+		return nil, ErrScanNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// Verify company ID matches
-	if scan.CompanyID != companyID {
-		return nil, errors.New("scan not found for this company")
+	return scan, nil
+}
+
+// GetScanResults retrieves a page of a scan's vulnerabilities, filtered to the
+// given severities, with the scan's vulnerability counts by severity
+func (s *ScanService) GetScanResults(ctx context.Context, scanID, companyID uuid.UUID, severities []models.SeverityLevel, limit, offset int) (*models.ScanResults, error) {
+	if _, err := s.GetScan(ctx, scanID, companyID); err != nil {
+		return nil, err
 	}
 
-	return scan, nil
+	vulnerabilities, total, err := s.scanRepo.GetVulnerabilities(companyID, scanID, severities, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.scanRepo.CountVulnerabilitiesBySeverity(companyID, scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	severityCounts := newSeverityCounts()
+	for severity, count := range counts {
+		severityCounts[severity] = count
+	}
+
+	if vulnerabilities == nil {
+		vulnerabilities = []models.Vulnerability{}
+	}
+	return &models.ScanResults{
+		ScanID:          scanID,
+		Vulnerabilities: vulnerabilities,
+		Total:           total,
+		Limit:           limit,
+		Offset:          offset,
+		SeverityCounts:  severityCounts,
+	}, nil
+}
+
+// ExportScan retrieves a scan with all of its vulnerabilities, for export
+func (s *ScanService) ExportScan(ctx context.Context, scanID, companyID uuid.UUID) (*models.Scan, []models.Vulnerability, error) {
+	scan, err := s.GetScan(ctx, scanID, companyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	vulnerabilities, err := s.scanRepo.ListVulnerabilities(companyID, scanID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scan, vulnerabilities, nil
+}
+
+// DiffAgentScans compares two of an agent's scans, matching their
+// vulnerabilities by FindingKey
+func (s *ScanService) DiffAgentScans(ctx context.Context, companyID, agentID, fromScanID, toScanID uuid.UUID) (*models.ScanDiff, error) {
+	var vulnerabilities [2][]models.Vulnerability
+	for i, scanID := range []uuid.UUID{fromScanID, toScanID} {
+		scan, err := s.GetScan(ctx, scanID, companyID)
+		if err != nil {
+			return nil, err
+		}
+		if scan.AgentID == nil || *scan.AgentID != agentID {
+			return nil, ErrScanAgentMismatch
+		}
+		if vulnerabilities[i], err = s.scanRepo.ListVulnerabilities(companyID, scanID); err != nil {
+			return nil, err
+		}
+	}
+
+	diff := diffScanVulnerabilities(vulnerabilities[0], vulnerabilities[1])
+	diff.AgentID = agentID
+	diff.FromScanID = fromScanID
+	diff.ToScanID = toScanID
+	return diff, nil
+}
+
+// diffScanVulnerabilities buckets the vulnerabilities of an earlier and a
+// later scan by whether only the later, only the earlier or both found them.
+// A finding a scan reports more than once counts once.
+func diffScanVulnerabilities(from, to []models.Vulnerability) *models.ScanDiff {
+	fromKeys := make(map[string]bool, len(from))
+	for i := range from {
+		fromKeys[FindingKey(&from[i])] = true
+	}
+	toKeys := make(map[string]bool, len(to))
+	for i := range to {
+		toKeys[FindingKey(&to[i])] = true
+	}
+
+	diff := &models.ScanDiff{
+		Introduced: newScanDiffBucket(),
+		Resolved:   newScanDiffBucket(),
+		Persisting: newScanDiffBucket(),
+	}
+	seen := make(map[string]bool, len(to))
+	for _, v := range to {
+		key := FindingKey(&v)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if fromKeys[key] {
+			addToScanDiffBucket(&diff.Persisting, v)
+		} else {
+			addToScanDiffBucket(&diff.Introduced, v)
+		}
+	}
+	for _, v := range from {
+		key := FindingKey(&v)
+		if toKeys[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		addToScanDiffBucket(&diff.Resolved, v)
+	}
+	return diff
+}
+
+func newScanDiffBucket() models.ScanDiffBucket {
+	return models.ScanDiffBucket{Vulnerabilities: []models.Vulnerability{}, SeverityCounts: newSeverityCounts()}
+}
+
+// addToScanDiffBucket adds a vulnerability to a bucket and its counts.
+// Agents report severities in lower case, so they are counted upper-cased.
+func addToScanDiffBucket(bucket *models.ScanDiffBucket, v models.Vulnerability) {
+	bucket.Vulnerabilities = append(bucket.Vulnerabilities, v)
+	bucket.Total++
+	bucket.SeverityCounts[models.SeverityLevel(strings.ToUpper(string(v.Severity)))]++
+}
+
+// newSeverityCounts reports every severity, so clients don't have to treat
+// missing ones as zero
+func newSeverityCounts() map[models.SeverityLevel]int64 {
+	return map[models.SeverityLevel]int64{
+		models.SeverityCritical: 0,
+		models.SeverityHigh:     0,
+		models.SeverityMedium:   0,
+		models.SeverityLow:      0,
+		models.SeverityInfo:     0,
+	}
 }
 
 // GetScans retrieves scans for a company with pagination
-func (s *ScanService) GetScans(companyID uuid.UUID, page, limit int) (*models.PaginationResponse, error) {
+func (s *ScanService) GetScans(ctx context.Context, companyID uuid.UUID, page, limit int) (*models.PaginationResponse, error) {
 	// Query from database using repository
 	scans, total, err := s.scanRepo.GetByCompanyID(companyID, page, limit)
 	if err != nil {
@@ -105,8 +267,8 @@ func (s *ScanService) GetScans(companyID uuid.UUID, page, limit int) (*models.Pa
 }
 
 // UpdateScan updates a scan with transaction management
-func (s *ScanService) UpdateScan(scanID, companyID uuid.UUID, updates map[string]any) (*models.Scan, error) {
-	scan, err := s.GetScan(scanID, companyID)
+func (s *ScanService) UpdateScan(ctx context.Context, scanID, companyID uuid.UUID, updates map[string]any) (*models.Scan, error) {
+	scan, err := s.GetScan(ctx, scanID, companyID)
 	if err != nil {
 		return nil, err
 	}
@@ -132,16 +294,94 @@ func (s *ScanService) UpdateScan(scanID, companyID uuid.UUID, updates map[string
 	scan.UpdatedAt = time.Now()
 	
 	// Update in database using repository (handles transactions)
-	err = s.scanRepo.Update(scan)
+	err = s.scanRepo.Update(companyID, scan)
 	if err != nil {
 		return nil, err
 	}
 
+	s.publishProgress(ctx, scan)
+	logging.FromContext(ctx).Info("Scan updated", "scan_id", scanID, "status", scan.Status, "progress", scan.Progress)
 	return scan, nil
 }
 
+// SubscribeProgress returns a scan's current progress and the events of its
+// changes from now on, until it is done or unsubscribe is called. The channel
+// is nil when the scan is already done.
+func (s *ScanService) SubscribeProgress(ctx context.Context, scanID, companyID uuid.UUID) (current ScanProgressEvent, events <-chan ScanProgressEvent, unsubscribe func(), err error) {
+	if s.progress == nil {
+		return ScanProgressEvent{}, nil, nil, errors.New("scan progress is not published")
+	}
+
+	// Subscribing first means no change is missed while the scan is read
+	latest, events, unsubscribe := s.progress.Subscribe(companyID, scanID)
+	if latest != nil {
+		return *latest, events, unsubscribe, nil
+	}
+
+	scan, err := s.GetScan(ctx, scanID, companyID)
+	if err != nil {
+		unsubscribe()
+		return ScanProgressEvent{}, nil, nil, err
+	}
+	findings, err := s.countFindings(scan)
+	if err != nil {
+		unsubscribe()
+		return ScanProgressEvent{}, nil, nil, err
+	}
+	current = ScanProgressEvent{
+		ScanID:         scan.ID,
+		OrganizationID: companyID,
+		Status:         string(scan.Status),
+		Progress:       scan.Progress,
+		Findings:       findings,
+		At:             scan.UpdatedAt,
+	}
+	if current.Done() {
+		unsubscribe()
+		return current, nil, func() {}, nil
+	}
+	return current, events, unsubscribe, nil
+}
+
+// publishProgress tells the progress bus of a scan's current state
+func (s *ScanService) publishProgress(ctx context.Context, scan *models.Scan) {
+	if s.progress == nil {
+		return
+	}
+
+	findings, err := s.countFindings(scan)
+	if err != nil {
+		// Still publish the status change, with the last count published
+		logging.FromContext(ctx).Warn("Failed to count scan findings", "scan_id", scan.ID, "error", err)
+		if latest, ok := s.progress.Latest(scan.CompanyID, scan.ID); ok {
+			findings = latest.Findings
+		}
+	}
+	s.progress.Publish(ScanProgressEvent{
+		ScanID:         scan.ID,
+		OrganizationID: scan.CompanyID,
+		Status:         string(scan.Status),
+		Progress:       scan.Progress,
+		Findings:       findings,
+		At:             scan.UpdatedAt,
+	})
+}
+
+// countFindings counts the vulnerabilities a scan has found so far
+func (s *ScanService) countFindings(scan *models.Scan) (int, error) {
+	counts, err := s.scanRepo.CountVulnerabilitiesBySeverity(scan.CompanyID, scan.ID)
+	if err != nil {
+		return 0, err
+	}
+	findings := 0
+	for _, count := range counts {
+		findings += int(count)
+	}
+	return findings, nil
+}
+
 // DeleteScan deletes a scan
-func (s *ScanService) DeleteScan(scanID, companyID uuid.UUID) error {
+func (s *ScanService) DeleteScan(ctx context.Context, scanID, companyID uuid.UUID) error {
 	// TODO: Implement actual database deletion
 	return nil
 }
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"zerotrace/api/internal/models"
+)
+
+// ErrUnsupportedReport is returned for scan reports in no import format, or
+// not valid in the one they were sent as
+var ErrUnsupportedReport = errors.New("unsupported scan report")
+
+// ScanReport is a third-party scanner's report, its findings normalized into
+// vulnerabilities
+type ScanReport struct {
+	Format   string
+	Scanner  string // Tool that wrote the report, such as Trivy or Grype
+	Artifact string // What it scanned, when the report says
+	Findings []models.Vulnerability
+}
+
+// ParseScanReport reads a scan report in one of the import formats, detecting
+// which when format is empty. Only failing checks become findings.
+func ParseScanReport(data []byte, format string) (*ScanReport, error) {
+	if format == "" {
+		format = detectReportFormat(data)
+		if format == "" {
+			return nil, fmt.Errorf("%w: expected a Trivy JSON report (SchemaVersion 2) or a SARIF 2.1.0 log", ErrUnsupportedReport)
+		}
+	}
+	switch format {
+	case models.ImportFormatTrivy:
+		return parseTrivyReport(data)
+	case models.ImportFormatSARIF:
+		return parseSARIFReport(data)
+	}
+	return nil, fmt.Errorf("%w: unknown format %q, expected one of %s", ErrUnsupportedReport, format, strings.Join(models.ImportFormats, ", "))
+}
+
+// detectReportFormat recognizes a report by the fields its format requires
+func detectReportFormat(data []byte) string {
+	var probe struct {
+		Schema        string          `json:"$schema"`
+		Version       string          `json:"version"`
+		Runs          json.RawMessage `json:"runs"`
+		SchemaVersion json.RawMessage `json:"SchemaVersion"`
+		Results       json.RawMessage `json:"Results"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return ""
+	}
+	switch {
+	case probe.Runs != nil && (strings.HasPrefix(probe.Version, "2.") || strings.Contains(strings.ToLower(probe.Schema), "sarif")):
+		return models.ImportFormatSARIF
+	case probe.SchemaVersion != nil && probe.Results != nil:
+		return models.ImportFormatTrivy
+	}
+	return ""
+}
+
+// decodeReport decodes a report, naming the format it was read as on error
+func decodeReport(data []byte, format string, report any) error {
+	if err := json.Unmarshal(data, report); err != nil {
+		return fmt.Errorf("%w: not a valid %s report: %v", ErrUnsupportedReport, format, err)
+	}
+	return nil
+}
+
+// trivyReport is Trivy's JSON report, schema version 2
+type trivyReport struct {
+	SchemaVersion int           `json:"SchemaVersion"`
+	ArtifactName  string        `json:"ArtifactName"`
+	ArtifactType  string        `json:"ArtifactType"`
+	Results       []trivyResult `json:"Results"`
+}
+
+type trivyResult struct {
+	Target            string               `json:"Target"`
+	Class             string               `json:"Class"`
+	Vulnerabilities   []trivyVulnerability `json:"Vulnerabilities"`
+	Misconfigurations []trivyMisconfig     `json:"Misconfigurations"`
+}
+
+type trivyVulnerability struct {
+	VulnerabilityID  string               `json:"VulnerabilityID"`
+	PkgName          string               `json:"PkgName"`
+	InstalledVersion string               `json:"InstalledVersion"`
+	FixedVersion     string               `json:"FixedVersion"`
+	Title            string               `json:"Title"`
+	Description      string               `json:"Description"`
+	Severity         string               `json:"Severity"`
+	PrimaryURL       string               `json:"PrimaryURL"`
+	References       []string             `json:"References"`
+	CVSS             map[string]trivyCVSS `json:"CVSS"` // By source, such as nvd
+}
+
+type trivyCVSS struct {
+	V3Vector string  `json:"V3Vector"`
+	V3Score  float64 `json:"V3Score"`
+}
+
+type trivyMisconfig struct {
+	Type        string   `json:"Type"`
+	ID          string   `json:"ID"`
+	Title       string   `json:"Title"`
+	Description string   `json:"Description"`
+	Message     string   `json:"Message"`
+	Resolution  string   `json:"Resolution"`
+	Severity    string   `json:"Severity"`
+	PrimaryURL  string   `json:"PrimaryURL"`
+	References  []string `json:"References"`
+	Status      string   `json:"Status"`
+}
+
+func parseTrivyReport(data []byte) (*ScanReport, error) {
+	var report trivyReport
+	if err := decodeReport(data, "Trivy", &report); err != nil {
+		return nil, err
+	}
+	if report.SchemaVersion != 2 {
+		return nil, fmt.Errorf("%w: Trivy report schema version %d, only version 2 (trivy --format json, v0.20 and later) is supported", ErrUnsupportedReport, report.SchemaVersion)
+	}
+
+	parsed := &ScanReport{Format: models.ImportFormatTrivy, Scanner: "Trivy", Artifact: report.ArtifactName}
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			finding := models.Vulnerability{
+				Type:           "dependency",
+				Severity:       importSeverity(v.Severity),
+				Title:          v.Title,
+				Description:    v.Description,
+				PackageName:    v.PkgName,
+				PackageVersion: v.InstalledVersion,
+				Location:       result.Target,
+				References:     referenceURLs(v.PrimaryURL, v.References),
+				Status:         "open",
+			}
+			if finding.Title == "" {
+				finding.Title = v.VulnerabilityID
+			}
+			if strings.HasPrefix(strings.ToUpper(v.VulnerabilityID), "CVE-") {
+				finding.CVEID = strings.ToUpper(v.VulnerabilityID)
+			} else {
+				// GHSA and distribution advisories name the finding instead
+				finding.Title = v.VulnerabilityID + ": " + finding.Title
+			}
+			if v.FixedVersion != "" {
+				finding.PatchedVersions = strings.Split(v.FixedVersion, ", ")
+				finding.Remediation = fmt.Sprintf("Upgrade %s to %s", v.PkgName, v.FixedVersion)
+			}
+			if cvss, ok := trivyPreferredCVSS(v.CVSS); ok {
+				score := cvss.V3Score
+				finding.CVSSScore = &score
+				finding.CVSSVector = cvss.V3Vector
+			}
+			parsed.Findings = append(parsed.Findings, finding)
+		}
+		for _, m := range result.Misconfigurations {
+			if m.Status != "" && !strings.EqualFold(m.Status, "FAIL") {
+				continue
+			}
+			description := m.Description
+			if m.Message != "" {
+				description = m.Message
+			}
+			parsed.Findings = append(parsed.Findings, models.Vulnerability{
+				Type:        "config",
+				Severity:    importSeverity(m.Severity),
+				Title:       m.ID + ": " + m.Title,
+				Description: description,
+				Location:    result.Target,
+				Remediation: m.Resolution,
+				References:  referenceURLs(m.PrimaryURL, m.References),
+				Status:      "open",
+			})
+		}
+	}
+	return parsed, nil
+}
+
+// trivyPreferredCVSS picks NVD's CVSS v3 score over a vendor's, and vendors'
+// by name so the same report always gives the same score
+func trivyPreferredCVSS(scores map[string]trivyCVSS) (trivyCVSS, bool) {
+	if cvss, ok := scores["nvd"]; ok && cvss.V3Score > 0 {
+		return cvss, true
+	}
+	for _, source := range slices.Sorted(maps.Keys(scores)) {
+		if cvss := scores[source]; cvss.V3Score > 0 {
+			return cvss, true
+		}
+	}
+	return trivyCVSS{}, false
+}
+
+var (
+	cveMentionPattern = regexp.MustCompile(`(?i)\bCVE-\d{4}-\d{4,}\b`)
+	// Grype and Trivy name the package a vulnerability was found in in the
+	// result's message, such as "Package: openssl, Version: 1.1.1k"
+	sarifPackagePattern = regexp.MustCompile(`(?i)package:\s*([^\s,]+)[\s,]+(?:installed\s+)?version:?\s*([^\s,]+)`)
+)
+
+func parseSARIFReport(data []byte) (*ScanReport, error) {
+	var sarif SARIFLog
+	if err := decodeReport(data, "SARIF", &sarif); err != nil {
+		return nil, err
+	}
+	if sarif.Version != "2.1.0" {
+		return nil, fmt.Errorf("%w: SARIF version %q, only 2.1.0 is supported", ErrUnsupportedReport, sarif.Version)
+	}
+
+	parsed := &ScanReport{Format: models.ImportFormatSARIF}
+	var scanners []string
+	for _, run := range sarif.Runs {
+		if name := run.Tool.Driver.Name; name != "" && !containsFold(scanners, name) {
+			scanners = append(scanners, name)
+		}
+		rules := make(map[string]*SARIFRule, len(run.Tool.Driver.Rules))
+		for i := range run.Tool.Driver.Rules {
+			rules[run.Tool.Driver.Rules[i].ID] = &run.Tool.Driver.Rules[i]
+		}
+		for _, result := range run.Results {
+			// Passing and informational results are not findings
+			if result.Kind != "" && result.Kind != "fail" {
+				continue
+			}
+			if result.Level == "none" {
+				continue
+			}
+			rule := rules[result.RuleID]
+			if rule == nil && result.RuleIndex != nil && *result.RuleIndex >= 0 && *result.RuleIndex < len(run.Tool.Driver.Rules) {
+				rule = &run.Tool.Driver.Rules[*result.RuleIndex]
+			}
+			parsed.Findings = append(parsed.Findings, sarifFinding(result, rule))
+		}
+	}
+	parsed.Scanner = strings.Join(scanners, ", ")
+	return parsed, nil
+}
+
+// sarifFinding normalizes a SARIF result. A result naming a CVE, in its rule
+// or message, is a dependency finding; any other is a code finding.
+func sarifFinding(result SARIFResult, rule *SARIFRule) models.Vulnerability {
+	finding := models.Vulnerability{
+		Type:        "code",
+		Severity:    sarifSeverity(result.Level, rule),
+		Title:       result.RuleID,
+		Description: result.Message.Text,
+		Location:    sarifLocationString(result.Locations),
+		Status:      "open",
+	}
+	if rule != nil {
+		if rule.ShortDescription != nil && rule.ShortDescription.Text != "" {
+			finding.Title = rule.ShortDescription.Text
+		} else if rule.Name != "" {
+			finding.Title = rule.Name
+		}
+		if rule.Help != nil {
+			finding.Remediation = rule.Help.Text
+		}
+		finding.References = referenceURLs(rule.HelpURI, nil)
+	}
+	if cve := cveMentionPattern.FindString(result.RuleID + " " + result.Message.Text); cve != "" {
+		finding.Type = "dependency"
+		finding.CVEID = strings.ToUpper(cve)
+	}
+	if match := sarifPackagePattern.FindStringSubmatch(result.Message.Text); match != nil {
+		finding.Type = "dependency"
+		finding.PackageName, finding.PackageVersion = match[1], match[2]
+	}
+	if finding.Title == "" {
+		finding.Title = finding.CVEID
+	}
+	return finding
+}
+
+// sarifSeverity reads a rule's security-severity score where it has one, as
+// its level is only error, warning or note
+func sarifSeverity(level string, rule *SARIFRule) models.SeverityLevel {
+	if rule != nil && rule.Properties != nil && rule.Properties.SecuritySeverity != "" {
+		if score, err := strconv.ParseFloat(rule.Properties.SecuritySeverity, 64); err == nil {
+			switch {
+			case score >= 9.0:
+				return "critical"
+			case score >= 7.0:
+				return "high"
+			case score >= 4.0:
+				return "medium"
+			case score > 0:
+				return "low"
+			}
+			return "info"
+		}
+	}
+	switch level {
+	case "error":
+		return "high"
+	case "note":
+		return "low"
+	}
+	// SARIF results default to warning
+	return "medium"
+}
+
+// sarifLocationString is the first location's file, and line where given
+func sarifLocationString(locations []SARIFLocation) string {
+	for _, location := range locations {
+		physical := location.PhysicalLocation
+		if physical == nil || physical.ArtifactLocation == nil || physical.ArtifactLocation.URI == "" {
+			continue
+		}
+		if physical.Region != nil && physical.Region.StartLine > 0 {
+			return fmt.Sprintf("%s:%d", physical.ArtifactLocation.URI, physical.Region.StartLine)
+		}
+		return physical.ArtifactLocation.URI
+	}
+	return ""
+}
+
+// importSeverity lowercases a scanner's severity as agents report them,
+// treating severities the scanner could not rate as info
+func importSeverity(severity string) models.SeverityLevel {
+	switch s := strings.ToLower(strings.TrimSpace(severity)); s {
+	case "critical", "high", "medium", "low":
+		return models.SeverityLevel(s)
+	}
+	return "info"
+}
+
+// referenceURLs lists a finding's primary URL first, without duplicates
+func referenceURLs(primary string, references []string) []string {
+	var urls []string
+	for _, url := range append([]string{primary}, references...) {
+		if url != "" && !containsFold(urls, url) {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trivyReportJSON = `{
+  "SchemaVersion": 2,
+  "ArtifactName": "registry.example.com/shop/api:1.4.2",
+  "ArtifactType": "container_image",
+  "Results": [
+    {
+      "Target": "registry.example.com/shop/api:1.4.2 (alpine 3.18.4)",
+      "Class": "os-pkgs",
+      "Vulnerabilities": [
+        {
+          "VulnerabilityID": "CVE-2023-5363",
+          "PkgName": "libcrypto3",
+          "InstalledVersion": "3.1.3-r0",
+          "FixedVersion": "3.1.4-r0",
+          "Severity": "HIGH",
+          "Title": "openssl: Incorrect cipher key and IV length processing",
+          "PrimaryURL": "https://avd.aquasec.com/nvd/cve-2023-5363",
+          "References": ["https://avd.aquasec.com/nvd/cve-2023-5363", "https://www.openssl.org/news/secadv/20231024.txt"],
+          "CVSS": {"nvd": {"V3Vector": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", "V3Score": 9.8}}
+        },
+        {
+          "VulnerabilityID": "GHSA-xpw8-rcwv-8f8p",
+          "PkgName": "express",
+          "InstalledVersion": "4.17.1",
+          "Severity": "UNKNOWN",
+          "Title": "Open redirect"
+        }
+      ]
+    },
+    {
+      "Target": "Dockerfile",
+      "Class": "config",
+      "Misconfigurations": [
+        {"ID": "DS002", "Title": "Image user should not be 'root'", "Message": "Specify at least 1 USER command", "Resolution": "Add 'USER <non root user name>'", "Severity": "HIGH", "Status": "FAIL"},
+        {"ID": "DS001", "Title": "':latest' tag used", "Severity": "MEDIUM", "Status": "PASS"}
+      ]
+    }
+  ]
+}`
+
+const sarifLogJSON = `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "Grype",
+          "rules": [
+            {
+              "id": "CVE-2022-3602-openssl",
+              "shortDescription": {"text": "CVE-2022-3602 high vulnerability for openssl package"},
+              "helpUri": "https://github.com/anchore/grype",
+              "properties": {"security-severity": "7.5"}
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "CVE-2022-3602-openssl",
+          "level": "error",
+          "message": {"text": "The path /usr/lib/libssl.so.3 reports openssl at version 3.0.6 which would result in a vulnerable (apk) package installed. Package: openssl, Version: 3.0.6"},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "usr/lib/libssl.so.3"}}}]
+        },
+        {
+          "ruleId": "unused-check",
+          "kind": "pass",
+          "message": {"text": "Nothing found"}
+        }
+      ]
+    },
+    {
+      "tool": {"driver": {"name": "Semgrep"}},
+      "results": [
+        {
+          "ruleId": "python.lang.security.audit.eval-detected",
+          "level": "warning",
+          "message": {"text": "Detected the use of eval()"},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "app/views.py"}, "region": {"startLine": 42}}}]
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParseTrivyReport(t *testing.T) {
+	report, err := ParseScanReport([]byte(trivyReportJSON), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportFormatTrivy, report.Format)
+	assert.Equal(t, "Trivy", report.Scanner)
+	assert.Equal(t, "registry.example.com/shop/api:1.4.2", report.Artifact)
+	require.Len(t, report.Findings, 3, "passing checks are not findings")
+
+	vuln := report.Findings[0]
+	assert.Equal(t, "dependency", vuln.Type)
+	assert.Equal(t, "CVE-2023-5363", vuln.CVEID)
+	assert.Equal(t, models.SeverityLevel("high"), vuln.Severity)
+	assert.Equal(t, "libcrypto3", vuln.PackageName)
+	assert.Equal(t, "3.1.3-r0", vuln.PackageVersion)
+	assert.Equal(t, []string{"3.1.4-r0"}, vuln.PatchedVersions)
+	require.NotNil(t, vuln.CVSSScore)
+	assert.Equal(t, 9.8, *vuln.CVSSScore)
+	assert.Equal(t, []string{"https://avd.aquasec.com/nvd/cve-2023-5363", "https://www.openssl.org/news/secadv/20231024.txt"}, vuln.References)
+
+	// Advisories without a CVE name the finding, and unrated ones are info
+	assert.Empty(t, report.Findings[1].CVEID)
+	assert.Equal(t, "GHSA-xpw8-rcwv-8f8p: Open redirect", report.Findings[1].Title)
+	assert.Equal(t, models.SeverityLevel("info"), report.Findings[1].Severity)
+
+	misconfig := report.Findings[2]
+	assert.Equal(t, "config", misconfig.Type)
+	assert.Equal(t, "DS002: Image user should not be 'root'", misconfig.Title)
+	assert.Equal(t, "Dockerfile", misconfig.Location)
+	assert.Equal(t, "Specify at least 1 USER command", misconfig.Description)
+}
+
+func TestParseSARIFReport(t *testing.T) {
+	report, err := ParseScanReport([]byte(sarifLogJSON), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportFormatSARIF, report.Format)
+	assert.Equal(t, "Grype, Semgrep", report.Scanner)
+	require.Len(t, report.Findings, 2, "passing results are not findings")
+
+	vuln := report.Findings[0]
+	assert.Equal(t, "dependency", vuln.Type)
+	assert.Equal(t, "CVE-2022-3602", vuln.CVEID)
+	assert.Equal(t, models.SeverityLevel("high"), vuln.Severity, "security-severity outranks the level")
+	assert.Equal(t, "openssl", vuln.PackageName)
+	assert.Equal(t, "3.0.6", vuln.PackageVersion)
+	assert.Equal(t, "usr/lib/libssl.so.3", vuln.Location)
+	assert.Equal(t, "CVE-2022-3602 high vulnerability for openssl package", vuln.Title)
+
+	code := report.Findings[1]
+	assert.Equal(t, "code", code.Type)
+	assert.Equal(t, models.SeverityLevel("medium"), code.Severity)
+	assert.Equal(t, "app/views.py:42", code.Location)
+	assert.Equal(t, "python.lang.security.audit.eval-detected", code.Title)
+}
+
+func TestParseScanReportRejectsUnknownReports(t *testing.T) {
+	for name, tc := range map[string]struct {
+		data, format, mentions string
+	}{
+		"not JSON":             {`<xml/>`, "", "SARIF 2.1.0"},
+		"unrecognized JSON":    {`{"matches": []}`, "", "Trivy JSON report"},
+		"unknown format":       {trivyReportJSON, "cyclonedx", "trivy, sarif"},
+		"old Trivy schema":     {`{"SchemaVersion": 1, "Results": []}`, models.ImportFormatTrivy, "version 2"},
+		"other SARIF version":  {`{"version": "2.0.0", "runs": []}`, models.ImportFormatSARIF, "2.1.0"},
+		"sent as wrong format": {sarifLogJSON, models.ImportFormatTrivy, "schema version 0"},
+		"malformed report":     {`{"version": "2.1.0", "runs": {}}`, models.ImportFormatSARIF, "not a valid SARIF report"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseScanReport([]byte(tc.data), tc.format)
+			require.ErrorIs(t, err, ErrUnsupportedReport)
+			assert.Contains(t, err.Error(), tc.mentions)
+		})
+	}
+}
+
+func TestImportScope(t *testing.T) {
+	image := &ScanReport{Format: models.ImportFormatTrivy, Scanner: "Trivy", Artifact: "shop/api:1.4.2"}
+	other := &ScanReport{Format: models.ImportFormatTrivy, Scanner: "Trivy", Artifact: "shop/web:2.0.0"}
+	assert.Regexp(t, `^import:trivy:[0-9a-f]{12}$`, importScope(image))
+	assert.NotEqual(t, importScope(image), importScope(other), "artifacts are tracked apart")
+	assert.Equal(t, "import:sarif", importScope(&ScanReport{Format: models.ImportFormatSARIF}))
+
+	long := &ScanReport{Scanner: "A Very Long Scanner Name Indeed", Artifact: "x"}
+	assert.LessOrEqual(t, len(importScope(long)), 50, "fits FindingState's scope column")
+}
+
+func TestImportResultMarksFindings(t *testing.T) {
+	report, err := ParseScanReport([]byte(trivyReportJSON), models.ImportFormatTrivy)
+	require.NoError(t, err)
+	record := &models.ScanImport{SourceID: "ci-shop-api"}
+
+	result := (&ScanImportService{}).ImportResult(record, report, time.Now())
+	require.Len(t, result.Vulnerabilities, 3)
+	for _, finding := range result.Vulnerabilities {
+		require.NotNil(t, finding.ImportedFrom)
+		assert.Equal(t, "ci-shop-api", finding.ImportedFrom.SourceID)
+		assert.Equal(t, "Trivy", finding.ImportedFrom.Scanner)
+		assert.Equal(t, "registry.example.com/shop/api:1.4.2", finding.ImportedFrom.Artifact)
+	}
+	assert.Equal(t, importScope(report), result.Metadata["scan_type"])
+}
+
+func TestFindAgentByHostname(t *testing.T) {
+	org, otherOrg := uuid.New(), uuid.New()
+	agent := &models.Agent{ID: uuid.New(), OrganizationID: org, Hostname: "Web-01"}
+	as := &AgentService{agents: map[uuid.UUID]*models.Agent{
+		agent.ID:   agent,
+		uuid.New(): {OrganizationID: otherOrg, Hostname: "db-01"},
+	}}
+
+	assert.Same(t, agent, as.FindAgentByHostname(org, "web-01"))
+	assert.Nil(t, as.FindAgentByHostname(org, "db-01"), "another organization's agent")
+}
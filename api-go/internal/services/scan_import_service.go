@@ -0,0 +1,151 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrImportAssetNotFound is returned when a scan report names a hostname no
+// agent of the organization has, and no import source to record it under
+var ErrImportAssetNotFound = errors.New("no agent with that hostname")
+
+// importSourceMetadataKey marks an agent registered for an import source
+const importSourceMetadataKey = "import_source"
+
+// ScanImportService imports third-party scanners' reports into the agents,
+// or import sources, they scanned
+type ScanImportService struct {
+	db           *gorm.DB
+	agentService *AgentService
+	mu           sync.Mutex // Serializes registering import sources' agents
+}
+
+// NewScanImportService creates a new scan import service
+func NewScanImportService(db *gorm.DB, agentService *AgentService) *ScanImportService {
+	return &ScanImportService{
+		db:           db,
+		agentService: agentService,
+	}
+}
+
+// ResolveAsset finds the agent a report is imported into: the organization's
+// agent with the hostname, or else the agent of the import source, which is
+// registered on its first import
+func (s *ScanImportService) ResolveAsset(organizationID uuid.UUID, hostname, sourceID string) (*models.Agent, error) {
+	if hostname != "" {
+		if agent := s.agentService.FindAgentByHostname(organizationID, hostname); agent != nil {
+			return agent, nil
+		}
+		if sourceID == "" {
+			return nil, fmt.Errorf("%w: %s", ErrImportAssetNotFound, hostname)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if agent := s.agentService.findImportSourceAgent(organizationID, sourceID); agent != nil {
+		return agent, nil
+	}
+	return s.agentService.RegisterAgent(models.Agent{
+		CompanyID:      organizationID,
+		OrganizationID: organizationID,
+		Name:           sourceID,
+		Hostname:       hostname,
+		Platform:       "import",
+		Metadata:       map[string]any{importSourceMetadataKey: sourceID},
+	})
+}
+
+// ImportResult stamps a report's findings with where they were imported from
+// and makes them one scan result of the agent. Its scan type scopes finding
+// tracking to the scanner and artifact, so importing one artifact's report
+// does not resolve another's findings, nor the agent's own.
+func (s *ScanImportService) ImportResult(record *models.ScanImport, report *ScanReport, now time.Time) models.AgentScanResult {
+	source := &models.ImportSource{
+		ImportID: record.ID,
+		SourceID: record.SourceID,
+		Format:   report.Format,
+		Scanner:  report.Scanner,
+		Artifact: report.Artifact,
+	}
+	for i := range report.Findings {
+		report.Findings[i].ImportedFrom = source
+	}
+	return models.AgentScanResult{
+		ID:              record.ID,
+		AgentID:         record.AgentID.String(),
+		CompanyID:       record.OrganizationID.String(),
+		StartTime:       now,
+		EndTime:         now,
+		Status:          "completed",
+		Vulnerabilities: report.Findings,
+		Metadata: map[string]any{
+			"scan_type":     importScope(report),
+			"import_id":     record.ID.String(),
+			"import_format": report.Format,
+			"scanner":       report.Scanner,
+		},
+	}
+}
+
+// Record saves an import once its findings are recorded
+func (s *ScanImportService) Record(record *models.ScanImport) error {
+	if err := s.db.Create(record).Error; err != nil {
+		return fmt.Errorf("failed to record scan import: %w", err)
+	}
+	return nil
+}
+
+// importScope names the finding tracking scope of a scanner's reports on an
+// artifact, short enough for FindingState's scope column
+func importScope(report *ScanReport) string {
+	scanner := strings.ToLower(report.Scanner)
+	if scanner == "" {
+		scanner = report.Format
+	}
+	if len(scanner) > 20 {
+		scanner = scanner[:20]
+	}
+	scope := "import:" + scanner
+	if report.Artifact != "" {
+		sum := sha256.Sum256([]byte(report.Artifact))
+		scope += ":" + hex.EncodeToString(sum[:6])
+	}
+	return scope
+}
+
+// FindAgentByHostname finds an organization's agent by hostname, ignoring case
+func (as *AgentService) FindAgentByHostname(organizationID uuid.UUID, hostname string) *models.Agent {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	for _, agent := range as.agents {
+		if agent.OrganizationID == organizationID && strings.EqualFold(agent.Hostname, hostname) {
+			return agent
+		}
+	}
+	return nil
+}
+
+// findImportSourceAgent finds the agent registered for an organization's import source
+func (as *AgentService) findImportSourceAgent(organizationID uuid.UUID, sourceID string) *models.Agent {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	for _, agent := range as.agents {
+		if agent.OrganizationID == organizationID && agent.Metadata[importSourceMetadataKey] == sourceID {
+			return agent
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// scanProgressBuffer is how many events a slow subscriber may fall behind
+// before older ones are dropped in favour of the latest
+const scanProgressBuffer = 16
+
+// ScanProgressEvent is a scan's status, progress and finding count as of a
+// change to any of them
+type ScanProgressEvent struct {
+	ScanID         uuid.UUID `json:"scan_id"`
+	OrganizationID uuid.UUID `json:"-"`
+	Status         string    `json:"status"`
+	Progress       int       `json:"progress"`
+	Findings       int       `json:"findings"`
+	At             time.Time `json:"at"`
+}
+
+// Done reports whether the scan has stopped, so no more events follow
+func (e ScanProgressEvent) Done() bool {
+	switch models.ScanStatus(e.Status) {
+	case models.ScanStatusCompleted, models.ScanStatusFailed, models.ScanStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ScanProgressBus passes scans' progress events from the services running
+// them to subscribers in the same process, only ever to subscribers of the
+// scan's own organization. It remembers the latest event of each running
+// scan, so a subscriber can start from the scan's current state.
+type ScanProgressBus struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[*scanProgressSubscriber]struct{} // By scan ID
+	latest      map[uuid.UUID]ScanProgressEvent                    // Of running scans, by scan ID
+}
+
+type scanProgressSubscriber struct {
+	organizationID uuid.UUID
+	events         chan ScanProgressEvent
+}
+
+// NewScanProgressBus creates a new scan progress bus
+func NewScanProgressBus() *ScanProgressBus {
+	return &ScanProgressBus{
+		subscribers: make(map[uuid.UUID]map[*scanProgressSubscriber]struct{}),
+		latest:      make(map[uuid.UUID]ScanProgressEvent),
+	}
+}
+
+// Publish sends an event to the scan's subscribers. A subscriber that isn't
+// keeping up loses its oldest unread events rather than holding up the scan.
+// Once the scan is done its subscribers' channels are closed.
+func (b *ScanProgressBus) Publish(event ScanProgressEvent) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[event.ScanID] {
+		if sub.organizationID != event.OrganizationID {
+			continue
+		}
+		for sent := false; !sent; {
+			select {
+			case sub.events <- event:
+				sent = true
+			default:
+				select {
+				case <-sub.events:
+				default:
+				}
+			}
+		}
+	}
+
+	if !event.Done() {
+		b.latest[event.ScanID] = event
+		return
+	}
+	delete(b.latest, event.ScanID)
+	for sub := range b.subscribers[event.ScanID] {
+		if sub.organizationID == event.OrganizationID {
+			close(sub.events)
+			delete(b.subscribers[event.ScanID], sub)
+		}
+	}
+	if len(b.subscribers[event.ScanID]) == 0 {
+		delete(b.subscribers, event.ScanID)
+	}
+}
+
+// Latest returns the latest event of a running scan of the organization
+func (b *ScanProgressBus) Latest(organizationID, scanID uuid.UUID) (ScanProgressEvent, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event, ok := b.latest[scanID]
+	if !ok || event.OrganizationID != organizationID {
+		return ScanProgressEvent{}, false
+	}
+	return event, true
+}
+
+// Subscribe returns the events published from now on for a scan of the
+// organization, with the scan's latest event if it is running. The channel is
+// closed when the scan is done; unsubscribe stops the events otherwise.
+func (b *ScanProgressBus) Subscribe(organizationID, scanID uuid.UUID) (latest *ScanProgressEvent, events <-chan ScanProgressEvent, unsubscribe func()) {
+	sub := &scanProgressSubscriber{
+		organizationID: organizationID,
+		events:         make(chan ScanProgressEvent, scanProgressBuffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[scanID] == nil {
+		b.subscribers[scanID] = make(map[*scanProgressSubscriber]struct{})
+	}
+	b.subscribers[scanID][sub] = struct{}{}
+	if event, ok := b.latest[scanID]; ok && event.OrganizationID == organizationID {
+		latest = &event
+	}
+
+	unsubscribe = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[scanID][sub]; !ok {
+			return // Closed when the scan finished
+		}
+		delete(b.subscribers[scanID], sub)
+		if len(b.subscribers[scanID]) == 0 {
+			delete(b.subscribers, scanID)
+		}
+	}
+	return latest, sub.events, unsubscribe
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanProgressBusScopesEventsToOrganization(t *testing.T) {
+	bus := NewScanProgressBus()
+	orgID, otherOrgID, scanID := uuid.New(), uuid.New(), uuid.New()
+
+	bus.Publish(ScanProgressEvent{ScanID: scanID, OrganizationID: orgID, Status: string(models.ScanStatusScanning), Progress: 10})
+
+	latest, events, unsubscribe := bus.Subscribe(orgID, scanID)
+	defer unsubscribe()
+	require.NotNil(t, latest)
+	assert.Equal(t, 10, latest.Progress)
+
+	// Another organization sees nothing of the scan, even knowing its ID
+	foreignLatest, foreignEvents, foreignUnsubscribe := bus.Subscribe(otherOrgID, scanID)
+	assert.Nil(t, foreignLatest)
+	_, ok := bus.Latest(otherOrgID, scanID)
+	assert.False(t, ok)
+
+	bus.Publish(ScanProgressEvent{ScanID: scanID, OrganizationID: orgID, Status: string(models.ScanStatusScanning), Progress: 50, Findings: 3})
+	event := <-events
+	assert.Equal(t, 50, event.Progress)
+	assert.Equal(t, 3, event.Findings)
+	assert.False(t, event.At.IsZero())
+	assert.Empty(t, foreignEvents)
+
+	// The scan finishing closes its subscribers' channels after the last event
+	bus.Publish(ScanProgressEvent{ScanID: scanID, OrganizationID: orgID, Status: string(models.ScanStatusCompleted), Progress: 100, Findings: 4})
+	event = <-events
+	assert.True(t, event.Done())
+	_, open := <-events
+	assert.False(t, open)
+	_, ok = bus.Latest(orgID, scanID)
+	assert.False(t, ok)
+
+	foreignUnsubscribe()
+	assert.Empty(t, bus.subscribers)
+}
+
+func TestScanProgressBusDropsOldEventsForSlowSubscribers(t *testing.T) {
+	bus := NewScanProgressBus()
+	orgID, scanID := uuid.New(), uuid.New()
+	_, events, unsubscribe := bus.Subscribe(orgID, scanID)
+	defer unsubscribe()
+
+	// Publishing never waits on a subscriber that isn't reading
+	for progress := 1; progress <= scanProgressBuffer*2; progress++ {
+		bus.Publish(ScanProgressEvent{ScanID: scanID, OrganizationID: orgID, Status: "running", Progress: progress})
+	}
+	bus.Publish(ScanProgressEvent{ScanID: scanID, OrganizationID: orgID, Status: string(models.ScanStatusFailed), Progress: 40})
+
+	var received []ScanProgressEvent
+	for event := range events {
+		received = append(received, event)
+	}
+	require.Len(t, received, scanProgressBuffer)
+	assert.Equal(t, scanProgressBuffer+2, received[0].Progress)
+	assert.Equal(t, string(models.ScanStatusFailed), received[len(received)-1].Status)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// windowsAbsPath matches an absolute Windows path such as C:\Users
+var windowsAbsPath = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// ScanScopeService manages which scanners each agent runs, and on which paths
+type ScanScopeService struct {
+	db           *gorm.DB
+	agentService *AgentService
+}
+
+// NewScanScopeService creates a new scan scope service
+func NewScanScopeService(db *gorm.DB, agentService *AgentService) *ScanScopeService {
+	return &ScanScopeService{
+		db:           db,
+		agentService: agentService,
+	}
+}
+
+// GetScope returns an agent's scan scope, or the default scope if none is set
+func (s *ScanScopeService) GetScope(agentID uuid.UUID) (*models.AgentScanScope, error) {
+	agent, exists := s.agentService.GetAgent(agentID)
+	if !exists {
+		return nil, ErrAgentNotFound
+	}
+
+	var scope models.AgentScanScope
+	err := s.db.Where("agent_id = ?", agentID).First(&scope).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.AgentScanScope{
+			AgentID:        agent.ID,
+			OrganizationID: agent.OrganizationID,
+			Scanners:       models.DefaultScanScopeScanners,
+			Default:        true,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scan scope: %w", err)
+	}
+	return &scope, nil
+}
+
+// UpdateScope replaces an agent's scan scope, bumping its version
+func (s *ScanScopeService) UpdateScope(agentID uuid.UUID, req *models.UpdateScanScopeRequest) (*models.AgentScanScope, error) {
+	if err := validateScanScope(req.Scanners, req.Paths); err != nil {
+		return nil, err
+	}
+
+	scope, err := s.GetScope(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if scope.Default {
+		scope.Default = false
+		scope.Version = 1
+	} else {
+		scope.Version++
+	}
+	scope.Scanners = req.Scanners
+	scope.Paths = req.Paths
+	scope.UpdatedBy = req.UpdatedBy
+
+	if err := s.db.Save(scope).Error; err != nil {
+		return nil, fmt.Errorf("failed to save scan scope: %w", err)
+	}
+	return scope, nil
+}
+
+// DeleteScope returns an agent to the default scan scope
+func (s *ScanScopeService) DeleteScope(agentID uuid.UUID) error {
+	if _, exists := s.agentService.GetAgent(agentID); !exists {
+		return ErrAgentNotFound
+	}
+	if err := s.db.Where("agent_id = ?", agentID).Delete(&models.AgentScanScope{}).Error; err != nil {
+		return fmt.Errorf("failed to delete scan scope: %w", err)
+	}
+	return nil
+}
+
+// validateScanScope checks that a scope names only known scanners, and gives
+// absolute paths only for scanners it enables
+func validateScanScope(scanners []string, paths map[string][]string) error {
+	for _, scanner := range scanners {
+		if !slices.Contains(models.ScanScopeScanners, scanner) {
+			return fmt.Errorf("unknown scanner %q, must be one of %s", scanner, strings.Join(models.ScanScopeScanners, ", "))
+		}
+	}
+	for scanner, roots := range paths {
+		if !slices.Contains(scanners, scanner) {
+			return fmt.Errorf("paths given for scanner %q, which the scope does not enable", scanner)
+		}
+		for _, root := range roots {
+			if !strings.HasPrefix(root, "/") && !windowsAbsPath.MatchString(root) {
+				return fmt.Errorf("path %q for scanner %q must be absolute", root, scanner)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateScanScope(t *testing.T) {
+	assert.NoError(t, validateScanScope([]string{"software", "aiml"}, map[string][]string{
+		"aiml": {"/srv/models", `D:\datasets`},
+	}))
+	assert.NoError(t, validateScanScope(nil, nil), "an empty scope disables every scanner")
+
+	assert.ErrorContains(t, validateScanScope([]string{"secrets"}, nil), "unknown scanner")
+	assert.ErrorContains(t, validateScanScope([]string{"software"}, map[string][]string{"aiml": {"/srv"}}), "does not enable")
+	assert.ErrorContains(t, validateScanScope([]string{"aiml"}, map[string][]string{"aiml": {"models"}}), "must be absolute")
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffScanVulnerabilities(t *testing.T) {
+	vuln := func(cve, pkg, location, version string, severity models.SeverityLevel) models.Vulnerability {
+		return models.Vulnerability{Type: "dependency", CVEID: cve, PackageName: pkg, Location: location, PackageVersion: version, Severity: severity}
+	}
+	from := []models.Vulnerability{
+		vuln("CVE-2024-1", "openssl", "/usr/lib", "3.0.1", "critical"),
+		vuln("CVE-2024-2", "zlib", "/usr/lib", "1.2.11", "high"),
+		vuln("CVE-2024-2", "zlib", "/opt/app", "1.2.11", "high"),
+	}
+	to := []models.Vulnerability{
+		vuln("CVE-2024-1", "openssl", "/usr/lib", "3.0.2", "critical"), // Same finding, upgraded but still vulnerable
+		vuln("CVE-2024-2", "zlib", "/opt/app", "1.2.11", "high"),
+		vuln("CVE-2024-3", "curl", "/usr/bin", "8.0.0", "medium"),
+		vuln("CVE-2024-3", "curl", "/usr/bin", "8.0.0", "medium"), // Reported twice
+	}
+
+	diff := diffScanVulnerabilities(from, to)
+
+	assert.Equal(t, 1, diff.Introduced.Total)
+	assert.Equal(t, "CVE-2024-3", diff.Introduced.Vulnerabilities[0].CVEID)
+	assert.Equal(t, int64(1), diff.Introduced.SeverityCounts[models.SeverityMedium])
+
+	assert.Equal(t, 1, diff.Resolved.Total)
+	assert.Equal(t, "/usr/lib", diff.Resolved.Vulnerabilities[0].Location)
+	assert.Equal(t, int64(1), diff.Resolved.SeverityCounts[models.SeverityHigh])
+
+	assert.Equal(t, 2, diff.Persisting.Total)
+	assert.Equal(t, "3.0.2", diff.Persisting.Vulnerabilities[0].PackageVersion, "persisting findings are as the later scan found them")
+	assert.Equal(t, int64(1), diff.Persisting.SeverityCounts[models.SeverityCritical])
+	assert.Equal(t, int64(0), diff.Persisting.SeverityCounts[models.SeverityLow], "every severity is reported")
+
+	empty := diffScanVulnerabilities(nil, nil)
+	assert.NotNil(t, empty.Introduced.Vulnerabilities)
+	assert.Len(t, empty.Resolved.SeverityCounts, 5)
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSuppressionRuleNotFound is returned when a suppression rule is unknown or belongs to another organization
+	ErrSuppressionRuleNotFound = errors.New("suppression rule not found")
+	// ErrInvalidSuppressionRule is returned for a suppression rule that can't be created
+	ErrInvalidSuppressionRule = errors.New("invalid suppression rule")
+)
+
+// suppressionSweepInterval is how often findings whose suppression rule
+// expired are reopened
+const suppressionSweepInterval = time.Minute
+
+// SuppressionService manages each organization's suppression rules and keeps
+// its findings' suppression in step with them: a new rule suppresses the
+// findings it matches, and findings reopen when their rule is deleted or
+// expires. Scans apply the active rules to the findings they report, see
+// FindingStateService.Observe.
+type SuppressionService struct {
+	db            *gorm.DB
+	agentService  *AgentService
+	findingStates *FindingStateService
+	hostRisk      *HostRiskService
+	now           func() time.Time
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSuppressionService creates a new suppression service
+func NewSuppressionService(db *gorm.DB, agentService *AgentService, findingStates *FindingStateService, hostRisk *HostRiskService) *SuppressionService {
+	return &SuppressionService{
+		db:            db,
+		agentService:  agentService,
+		findingStates: findingStates,
+		hostRisk:      hostRisk,
+		now:           time.Now,
+		stopChan:      make(chan struct{}),
+	}
+}
+
+// Start begins reopening the findings of expired rules in the background
+func (s *SuppressionService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(suppressionSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				reopened, err := s.Sweep(s.now())
+				if err != nil {
+					log.Printf("Suppression sweep failed: %v", err)
+				} else if reopened > 0 {
+					log.Printf("Reopened %d findings whose suppression rule expired", reopened)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background sweep
+func (s *SuppressionService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+}
+
+// ListRules lists an organization's suppression rules, oldest first. Expired
+// rules are left out unless includeExpired is set.
+func (s *SuppressionService) ListRules(organizationID uuid.UUID, includeExpired bool) ([]models.SuppressionRule, error) {
+	if includeExpired {
+		var rules []models.SuppressionRule
+		err := s.db.Where("organization_id = ?", organizationID).Order("created_at ASC").Find(&rules).Error
+		return rules, err
+	}
+	return activeSuppressionRules(s.db, organizationID, s.now())
+}
+
+// CreateRule adds a suppression rule to an organization and suppresses the
+// open and resolved findings it matches
+func (s *SuppressionService) CreateRule(organizationID uuid.UUID, req *models.SuppressionRuleRequest) (*models.SuppressionRule, error) {
+	now := s.now()
+	rule, err := newSuppressionRule(organizationID, req, now)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.updateFindings(func(tx *gorm.DB) ([]models.SuppressionRule, []models.FindingState, error) {
+		if err := tx.Create(rule).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to create suppression rule: %w", err)
+		}
+		var candidates []models.FindingState
+		err := suppressionRuleFindings(tx, rule).Where("suppressed = ?", false).Find(&candidates).Error
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load findings to suppress: %w", err)
+		}
+		// A finding's cached state may be newer, so every rule is applied
+		rules, err := activeSuppressionRules(tx, organizationID, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load suppression rules: %w", err)
+		}
+		return rules, candidates, nil
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule removes a suppression rule, reopening the findings it suppressed
+// that no other active rule matches
+func (s *SuppressionService) DeleteRule(organizationID, ruleID uuid.UUID) error {
+	now := s.now()
+	_, err := s.updateFindings(func(tx *gorm.DB) ([]models.SuppressionRule, []models.FindingState, error) {
+		result := tx.Where("id = ? AND organization_id = ?", ruleID, organizationID).Delete(&models.SuppressionRule{})
+		if result.Error != nil {
+			return nil, nil, fmt.Errorf("failed to delete suppression rule: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil, nil, ErrSuppressionRuleNotFound
+		}
+		return ruleSuppressedFindings(tx, organizationID, now, "suppression_rule_id = ?", ruleID)
+	}, now)
+	return err
+}
+
+// Sweep reopens the findings whose suppression rule expired by now, unless
+// another active rule matches them. It returns how many reopened.
+func (s *SuppressionService) Sweep(now time.Time) (int, error) {
+	var organizationIDs []uuid.UUID
+	err := s.db.Model(&models.FindingState{}).
+		Where("suppression_mode = ? AND suppression_expires_at <= ?", models.FindingSuppressedByRule, now).
+		Distinct().Pluck("organization_id", &organizationIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired suppressions: %w", err)
+	}
+
+	reopened := 0
+	for _, organizationID := range organizationIDs {
+		changed, err := s.updateFindings(func(tx *gorm.DB) ([]models.SuppressionRule, []models.FindingState, error) {
+			return ruleSuppressedFindings(tx, organizationID, now, "suppression_expires_at <= ?", now)
+		}, now)
+		reopened += changed
+		if err != nil {
+			return reopened, err
+		}
+	}
+	return reopened, nil
+}
+
+// activeRulesForAgent returns the active suppression rules of an agent's
+// organization, none for agents that aren't registered
+func (s *SuppressionService) activeRulesForAgent(agentID uuid.UUID, at time.Time) ([]models.SuppressionRule, error) {
+	agent, exists := s.agentService.GetAgent(agentID)
+	if !exists {
+		return nil, nil
+	}
+	return activeSuppressionRules(s.db, agent.OrganizationID, at)
+}
+
+// ruleSuppressedFindings loads an organization's rules active at the given
+// time and those of its findings suppressed by a rule that match the condition
+func ruleSuppressedFindings(tx *gorm.DB, organizationID uuid.UUID, at time.Time, condition string, args ...interface{}) ([]models.SuppressionRule, []models.FindingState, error) {
+	var findings []models.FindingState
+	err := tx.Where("organization_id = ? AND suppression_mode = ?", organizationID, models.FindingSuppressedByRule).
+		Where(condition, args...).
+		Find(&findings).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load suppressed findings: %w", err)
+	}
+	rules, err := activeSuppressionRules(tx, organizationID, at)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load suppression rules: %w", err)
+	}
+	return rules, findings, nil
+}
+
+// updateFindings applies the suppression rules load returns to the findings
+// it returns, in one transaction with any rule change load makes, then
+// rescores the hosts whose findings changed. It returns how many findings
+// changed.
+func (s *SuppressionService) updateFindings(load func(tx *gorm.DB) ([]models.SuppressionRule, []models.FindingState, error), at time.Time) (int, error) {
+	var changed int
+	var agents map[uuid.UUID]bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		rules, findings, err := load(tx)
+		if err != nil {
+			return err
+		}
+		changed, agents, err = s.findingStates.refresh(tx, findings, func(state *models.FindingState) bool {
+			return applySuppressionRules(state, rules, at)
+		})
+		return err
+	})
+	if err != nil {
+		// refresh already updated the cached states the rollback discarded
+		for agentID := range agents {
+			s.findingStates.Forget(agentID)
+		}
+		return 0, err
+	}
+
+	for agentID := range agents {
+		if _, err := s.hostRisk.Recompute(agentID); err != nil && !errors.Is(err, ErrAgentNotFound) {
+			log.Printf("Failed to rescore agent %s after a suppression change: %v", agentID, err)
+		}
+	}
+	return changed, nil
+}
+
+// activeSuppressionRules lists an organization's rules active at the given time, oldest first
+func activeSuppressionRules(db *gorm.DB, organizationID uuid.UUID, at time.Time) ([]models.SuppressionRule, error) {
+	var rules []models.SuppressionRule
+	err := db.Where("organization_id = ? AND (expires_at IS NULL OR expires_at > ?)", organizationID, at).
+		Order("created_at ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// suppressionRuleFindings selects the findings of a rule's organization that
+// may match it. Package versions are compared by matchSuppressionRule.
+func suppressionRuleFindings(tx *gorm.DB, rule *models.SuppressionRule) *gorm.DB {
+	query := tx.Where("organization_id = ?", rule.OrganizationID)
+	if rule.CVEID != "" {
+		query = query.Where("UPPER(cve_id) = ?", strings.ToUpper(rule.CVEID))
+	}
+	if rule.PackageName != "" {
+		query = query.Where("LOWER(package_name) = ?", strings.ToLower(rule.PackageName))
+	}
+	if rule.FindingKey != "" {
+		query = query.Where("finding_key = ?", rule.FindingKey)
+	}
+	if rule.AgentID != nil {
+		query = query.Where("agent_id = ?", *rule.AgentID)
+	}
+	return query
+}
+
+// newSuppressionRule validates a rule request: it must match on something,
+// name a package with a version, and expire in the future if it expires
+func newSuppressionRule(organizationID uuid.UUID, req *models.SuppressionRuleRequest, now time.Time) (*models.SuppressionRule, error) {
+	rule := &models.SuppressionRule{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		CVEID:          strings.ToUpper(strings.TrimSpace(req.CVEID)),
+		PackageName:    strings.TrimSpace(req.PackageName),
+		PackageVersion: strings.TrimSpace(req.PackageVersion),
+		FindingKey:     strings.ToLower(strings.TrimSpace(req.FindingKey)),
+		AgentID:        req.AgentID,
+		Reason:         strings.TrimSpace(req.Reason),
+		Owner:          strings.TrimSpace(req.Owner),
+		ExpiresAt:      req.ExpiresAt,
+	}
+
+	switch {
+	case rule.Reason == "":
+		return nil, fmt.Errorf("%w: a reason is required", ErrInvalidSuppressionRule)
+	case rule.Owner == "":
+		return nil, fmt.Errorf("%w: an owner is required", ErrInvalidSuppressionRule)
+	case rule.CVEID == "" && rule.PackageName == "" && rule.FindingKey == "" && rule.AgentID == nil:
+		return nil, fmt.Errorf("%w: must match on a CVE, package, finding key or agent", ErrInvalidSuppressionRule)
+	case rule.PackageVersion != "" && rule.PackageName == "":
+		return nil, fmt.Errorf("%w: a package version needs a package name", ErrInvalidSuppressionRule)
+	case rule.AgentID != nil && *rule.AgentID == uuid.Nil:
+		return nil, fmt.Errorf("%w: invalid agent ID", ErrInvalidSuppressionRule)
+	case rule.ExpiresAt != nil && !rule.ExpiresAt.After(now):
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidSuppressionRule)
+	}
+	return rule, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSuppressionRule(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	orgID := uuid.New()
+	later, earlier := now.Add(24*time.Hour), now.Add(-time.Minute)
+
+	rule, err := newSuppressionRule(orgID, &models.SuppressionRuleRequest{
+		CVEID: " cve-2025-1234 ", FindingKey: "ABC123", Reason: "False positive", Owner: "alice", ExpiresAt: &later,
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, orgID, rule.OrganizationID)
+	assert.Equal(t, "CVE-2025-1234", rule.CVEID)
+	assert.Equal(t, "abc123", rule.FindingKey)
+	assert.True(t, rule.Active(now))
+	assert.False(t, rule.Active(later))
+
+	nilAgent := uuid.Nil
+	for name, req := range map[string]models.SuppressionRuleRequest{
+		"no reason":           {CVEID: "CVE-2025-1234", Owner: "alice"},
+		"no owner":            {CVEID: "CVE-2025-1234", Reason: "False positive"},
+		"matches everything":  {Reason: "False positive", Owner: "alice"},
+		"version, no package": {PackageVersion: "1.0.0", Reason: "False positive", Owner: "alice"},
+		"nil agent":           {AgentID: &nilAgent, Reason: "False positive", Owner: "alice"},
+		"expires in the past": {CVEID: "CVE-2025-1234", Reason: "False positive", Owner: "alice", ExpiresAt: &earlier},
+	} {
+		_, err := newSuppressionRule(orgID, &req, now)
+		assert.ErrorIs(t, err, ErrInvalidSuppressionRule, name)
+	}
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"zerotrace/api/internal/models"
+)
+
+// maxThreatIntelResponse bounds one feed response read into memory
+const maxThreatIntelResponse = 50 << 20
+
+// maxTAXIIPages bounds how many pages of a TAXII collection one fetch follows
+const maxTAXIIPages = 100
+
+var cvePattern = regexp.MustCompile(`(?i)^CVE-\d{4}-\d{4,}$`)
+
+// threatIntelEntry is one CVE a feed reports, with what it says about it
+type threatIntelEntry struct {
+	CVEID      string
+	Context    string
+	Confidence int
+}
+
+// threatIntelAdapter fetches a feed's CVE entries. Adapters are registered
+// by feed kind in threatIntelAdapters; supporting a new feed format means
+// adding one.
+type threatIntelAdapter func(ctx context.Context, client *http.Client, feed *models.ThreatIntelFeed) ([]threatIntelEntry, error)
+
+var threatIntelAdapters = map[string]threatIntelAdapter{
+	models.ThreatIntelFeedSTIX: fetchTAXII,
+	models.ThreatIntelFeedMISP: fetchMISP,
+	models.ThreatIntelFeedJSON: fetchThreatIntelJSON,
+}
+
+// stixObject holds the fields of the STIX 2.1 objects a feed's CVEs are read from
+type stixObject struct {
+	Type               string `json:"type"`
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	Confidence         int    `json:"confidence"`
+	RelationshipType   string `json:"relationship_type"`
+	SourceRef          string `json:"source_ref"`
+	TargetRef          string `json:"target_ref"`
+	ExternalReferences []struct {
+		SourceName string `json:"source_name"`
+		ExternalID string `json:"external_id"`
+	} `json:"external_references"`
+}
+
+// fetchTAXII reads the vulnerability objects of a TAXII 2.1 collection. A
+// vulnerability related to a campaign, intrusion set, threat actor or
+// malware is reported with what targets or exploits it; one without is
+// reported with its own description.
+func fetchTAXII(ctx context.Context, client *http.Client, feed *models.ThreatIntelFeed) ([]threatIntelEntry, error) {
+	endpoint := strings.TrimSuffix(feed.URL, "/")
+	if feed.Collection != "" {
+		endpoint += "/collections/" + url.PathEscape(feed.Collection) + "/objects/"
+	}
+
+	var objects []stixObject
+	next := ""
+	for page := 0; page < maxTAXIIPages; page++ {
+		pageURL := endpoint
+		if next != "" {
+			pageURL += "?next=" + url.QueryEscape(next)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/taxii+json;version=2.1")
+		setThreatIntelAuth(req, feed, "Bearer ")
+
+		var envelope struct {
+			More    bool         `json:"more"`
+			Next    string       `json:"next"`
+			Objects []stixObject `json:"objects"`
+		}
+		if err := doThreatIntelRequest(client, req, &envelope); err != nil {
+			return nil, err
+		}
+		objects = append(objects, envelope.Objects...)
+		if !envelope.More || envelope.Next == "" {
+			break
+		}
+		next = envelope.Next
+	}
+
+	byID := make(map[string]*stixObject, len(objects))
+	for i := range objects {
+		byID[objects[i].ID] = &objects[i]
+	}
+
+	var entries []threatIntelEntry
+	related := make(map[string]bool)
+	for _, rel := range objects {
+		if rel.Type != "relationship" || (rel.RelationshipType != "targets" && rel.RelationshipType != "exploits") {
+			continue
+		}
+		target, source := byID[rel.TargetRef], byID[rel.SourceRef]
+		if target == nil || target.Type != "vulnerability" || source == nil {
+			continue
+		}
+		cve := stixCVE(target)
+		if cve == "" {
+			continue
+		}
+		related[target.ID] = true
+		confidence := rel.Confidence
+		if confidence == 0 {
+			confidence = source.Confidence
+		}
+		entries = append(entries, threatIntelEntry{
+			CVEID:      cve,
+			Context:    fmt.Sprintf("%s %q %s %s", strings.ReplaceAll(source.Type, "-", " "), source.Name, rel.RelationshipType, cve),
+			Confidence: confidence,
+		})
+	}
+	for i := range objects {
+		vuln := &objects[i]
+		if vuln.Type != "vulnerability" || related[vuln.ID] {
+			continue
+		}
+		if cve := stixCVE(vuln); cve != "" {
+			note := vuln.Description
+			if note == "" {
+				note = "Listed in " + feed.Name
+			}
+			entries = append(entries, threatIntelEntry{CVEID: cve, Context: note, Confidence: vuln.Confidence})
+		}
+	}
+	return entries, nil
+}
+
+// stixCVE returns the CVE ID of a STIX vulnerability, empty if it has none
+func stixCVE(vuln *stixObject) string {
+	for _, ref := range vuln.ExternalReferences {
+		if strings.EqualFold(ref.SourceName, "cve") && cvePattern.MatchString(ref.ExternalID) {
+			return strings.ToUpper(ref.ExternalID)
+		}
+	}
+	if cvePattern.MatchString(vuln.Name) {
+		return strings.ToUpper(vuln.Name)
+	}
+	return ""
+}
+
+// fetchMISP searches a MISP instance for vulnerability attributes. Each is
+// reported with the event it belongs to and its comment.
+func fetchMISP(ctx context.Context, client *http.Client, feed *models.ThreatIntelFeed) ([]threatIntelEntry, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"returnFormat": "json",
+		"type":         "vulnerability",
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(feed.URL, "/")+"/attributes/restSearch", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	setThreatIntelAuth(req, feed, "")
+
+	var result struct {
+		Response struct {
+			Attribute []struct {
+				Value   string `json:"value"`
+				Comment string `json:"comment"`
+				Event   struct {
+					Info string `json:"info"`
+				} `json:"Event"`
+			} `json:"Attribute"`
+		} `json:"response"`
+	}
+	if err := doThreatIntelRequest(client, req, &result); err != nil {
+		return nil, err
+	}
+
+	var entries []threatIntelEntry
+	for _, attr := range result.Response.Attribute {
+		if !cvePattern.MatchString(attr.Value) {
+			continue
+		}
+		note := attr.Event.Info
+		if attr.Comment != "" {
+			note = strings.TrimSpace(note + ": " + attr.Comment)
+		}
+		entries = append(entries, threatIntelEntry{CVEID: strings.ToUpper(attr.Value), Context: note})
+	}
+	return entries, nil
+}
+
+// threatIntelJSONEntry is one entry of a custom JSON feed
+type threatIntelJSONEntry struct {
+	CVEID      string `json:"cve_id"`
+	Context    string `json:"context"`
+	Confidence int    `json:"confidence"`
+}
+
+// fetchThreatIntelJSON reads a custom JSON feed: a list of
+// {"cve_id", "context", "confidence"} entries, bare or under "indicators"
+func fetchThreatIntelJSON(ctx context.Context, client *http.Client, feed *models.ThreatIntelFeed) ([]threatIntelEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	setThreatIntelAuth(req, feed, "Bearer ")
+
+	var raw json.RawMessage
+	if err := doThreatIntelRequest(client, req, &raw); err != nil {
+		return nil, err
+	}
+	var list []threatIntelJSONEntry
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var wrapped struct {
+			Indicators []threatIntelJSONEntry `json:"indicators"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid feed document: %w", err)
+		}
+		list = wrapped.Indicators
+	}
+
+	var entries []threatIntelEntry
+	for _, entry := range list {
+		if cvePattern.MatchString(entry.CVEID) {
+			entries = append(entries, threatIntelEntry{CVEID: strings.ToUpper(entry.CVEID), Context: entry.Context, Confidence: entry.Confidence})
+		}
+	}
+	return entries, nil
+}
+
+// setThreatIntelAuth authenticates a feed request with basic authentication
+// when the feed has a username, and otherwise with its API key after the
+// given scheme
+func setThreatIntelAuth(req *http.Request, feed *models.ThreatIntelFeed, scheme string) {
+	switch {
+	case feed.Username != "":
+		req.SetBasicAuth(feed.Username, feed.Password)
+	case feed.APIKey != "":
+		req.Header.Set("Authorization", scheme+feed.APIKey)
+	}
+}
+
+// doThreatIntelRequest sends a feed request and decodes its JSON response
+func doThreatIntelRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxThreatIntelResponse))
+	if err != nil {
+		return fmt.Errorf("failed to read feed response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("feed returned status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid feed response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchTAXIIFollowsPagesAndRelationships(t *testing.T) {
+	pages := map[string]string{
+		"": `{"more": true, "next": "p2", "objects": [
+			{"type": "vulnerability", "id": "vulnerability--1", "name": "CVE-2024-3400", "external_references": [{"source_name": "cve", "external_id": "CVE-2024-3400"}]},
+			{"type": "campaign", "id": "campaign--1", "name": "Operation Lantern", "confidence": 80}
+		]}`,
+		"p2": `{"more": false, "objects": [
+			{"type": "relationship", "id": "relationship--1", "relationship_type": "targets", "source_ref": "campaign--1", "target_ref": "vulnerability--1"},
+			{"type": "vulnerability", "id": "vulnerability--2", "name": "cve-2023-4966", "description": "Watched by the SOC"}
+		]}`,
+	}
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/taxii/collections/intel/objects/", r.URL.Path)
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(pages[r.URL.Query().Get("next")]))
+	}))
+	defer server.Close()
+
+	feed := &models.ThreatIntelFeed{Name: "SOC", URL: server.URL + "/taxii/", Collection: "intel", APIKey: "token"}
+	entries, err := fetchTAXII(context.Background(), server.Client(), feed)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token", auth)
+	assert.Equal(t, []threatIntelEntry{
+		{CVEID: "CVE-2024-3400", Context: `campaign "Operation Lantern" targets CVE-2024-3400`, Confidence: 80},
+		{CVEID: "CVE-2023-4966", Context: "Watched by the SOC"},
+	}, entries)
+}
+
+func TestFetchMISPVulnerabilityAttributes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/attributes/restSearch", r.URL.Path)
+		assert.Equal(t, "misp-key", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"response": {"Attribute": [
+			{"value": "CVE-2024-21887", "comment": "exploited against our VPN", "Event": {"info": "Campaign targeting finance"}},
+			{"value": "not-a-cve", "Event": {"info": "ignored"}}
+		]}}`))
+	}))
+	defer server.Close()
+
+	entries, err := fetchMISP(context.Background(), server.Client(), &models.ThreatIntelFeed{URL: server.URL, APIKey: "misp-key"})
+	require.NoError(t, err)
+	assert.Equal(t, []threatIntelEntry{
+		{CVEID: "CVE-2024-21887", Context: "Campaign targeting finance: exploited against our VPN"},
+	}, entries)
+}
+
+func TestFetchThreatIntelJSONAcceptsBothShapes(t *testing.T) {
+	for _, body := range []string{
+		`[{"cve_id": "CVE-2024-1086", "context": "used in campaign targeting us", "confidence": 90}]`,
+		`{"indicators": [{"cve_id": "CVE-2024-1086", "context": "used in campaign targeting us", "confidence": 90}]}`,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, _ := r.BasicAuth()
+			assert.Equal(t, "intel:secret", user+":"+pass)
+			w.Write([]byte(body))
+		}))
+
+		entries, err := fetchThreatIntelJSON(context.Background(), server.Client(), &models.ThreatIntelFeed{URL: server.URL, Username: "intel", Password: "secret"})
+		server.Close()
+		require.NoError(t, err)
+		assert.Equal(t, []threatIntelEntry{{CVEID: "CVE-2024-1086", Context: "used in campaign targeting us", Confidence: 90}}, entries)
+	}
+}
+
+func TestFetchThreatIntelFeedDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := fetchThreatIntelJSON(context.Background(), server.Client(), &models.ThreatIntelFeed{URL: server.URL})
+	assert.EqualError(t, err, "feed returned status 502")
+}
+
+func TestThreatIntelRetryBacksOff(t *testing.T) {
+	assert.Equal(t, time.Minute, threatIntelRetryDelay(1, time.Hour))
+	assert.Equal(t, 4*time.Minute, threatIntelRetryDelay(3, time.Hour))
+	assert.Equal(t, time.Hour, threatIntelRetryDelay(20, time.Hour), "never later than the refresh interval")
+}
+
+func TestThreatIntelMatchRaisesPriority(t *testing.T) {
+	finding := &models.Vulnerability{CVEID: "CVE-2024-1086", Severity: "medium"}
+	assert.False(t, knownExploited(finding))
+
+	// As decoded from stored results
+	var stored []interface{}
+	require.NoError(t, json.Unmarshal([]byte(`[{"feed": "SOC", "context": "used in campaign targeting us"}]`), &stored))
+	finding.EnrichmentData = map[string]interface{}{"threat_intel": stored}
+
+	assert.True(t, knownExploited(finding))
+	assert.Equal(t, []models.ThreatIntelMatch{{Feed: "SOC", Context: "used in campaign targeting us"}}, threatIntelMatches(finding))
+	assert.NotEqual(t,
+		DerivePriority("medium", 0, false, models.AssetCriticalityHigh),
+		DerivePriority("medium", 0, knownExploited(finding), models.AssetCriticalityHigh))
+}
@@ -0,0 +1,478 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrThreatIntelFeedNotFound is returned when a feed does not exist or belongs to another organization
+	ErrThreatIntelFeedNotFound = errors.New("threat intel feed not found")
+	// ErrThreatIntelFeedBusy is returned when a feed is refreshed while a refresh is already running
+	ErrThreatIntelFeedBusy = errors.New("threat intel feed refresh already running")
+)
+
+// defaultThreatIntelRefresh is a feed's refresh interval when it sets none
+const defaultThreatIntelRefresh = time.Hour
+
+// ThreatIntelService fetches organizations' own threat intelligence feeds on
+// a schedule and tags their findings with what the feeds report. Indicators
+// are cached in the database and replaced only by a successful fetch, so
+// findings keep their tags while a feed is down, until its last success is
+// older than the staleness limit. Failed fetches retry with backoff.
+type ThreatIntelService struct {
+	db            *gorm.DB
+	httpClient    *http.Client
+	checkInterval time.Duration
+	fetchTimeout  time.Duration
+	minRefresh    time.Duration
+	maxStaleness  time.Duration
+
+	mu      sync.Mutex
+	running map[uuid.UUID]bool                                 // Feeds being fetched
+	cache   map[uuid.UUID]map[string][]models.ThreatIntelMatch // Organization -> CVE -> matches, loaded on first use
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewThreatIntelService creates a new threat intel service
+func NewThreatIntelService(db *gorm.DB, cfg *config.Config) *ThreatIntelService {
+	checkInterval := cfg.ThreatIntelCheckInterval
+	if checkInterval <= 0 {
+		checkInterval = time.Minute
+	}
+	fetchTimeout := cfg.ThreatIntelFetchTimeout
+	if fetchTimeout <= 0 {
+		fetchTimeout = time.Minute
+	}
+	maxStaleness := cfg.ThreatIntelMaxStaleness
+	if maxStaleness <= 0 {
+		maxStaleness = 7 * 24 * time.Hour
+	}
+
+	return &ThreatIntelService{
+		db:            db,
+		httpClient:    &http.Client{},
+		checkInterval: checkInterval,
+		fetchTimeout:  fetchTimeout,
+		minRefresh:    cfg.ThreatIntelMinRefresh,
+		maxStaleness:  maxStaleness,
+		running:       make(map[uuid.UUID]bool),
+		cache:         make(map[uuid.UUID]map[string][]models.ThreatIntelMatch),
+		stopChan:      make(chan struct{}),
+	}
+}
+
+// Start launches the refresh loop
+func (s *ThreatIntelService) Start() {
+	s.wg.Add(1)
+	go s.scheduler()
+}
+
+// Stop stops the refresh loop and waits for in-flight fetches to finish
+func (s *ThreatIntelService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+	log.Println("Threat intel feed scheduler stopped")
+}
+
+func (s *ThreatIntelService) scheduler() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.refreshDueFeeds()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// refreshDueFeeds fetches every enabled feed whose next fetch time has passed
+func (s *ThreatIntelService) refreshDueFeeds() {
+	var feeds []models.ThreatIntelFeed
+	if err := s.db.Where("enabled = ? AND (next_fetch_at IS NULL OR next_fetch_at <= ?)", true, time.Now()).Find(&feeds).Error; err != nil {
+		log.Printf("Failed to load due threat intel feeds: %v", err)
+		return
+	}
+
+	for i := range feeds {
+		feed := &feeds[i]
+		if !s.claim(feed.ID) {
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.unclaim(feed.ID)
+			s.refresh(context.Background(), feed)
+		}()
+	}
+}
+
+// ListFeeds lists an organization's feeds
+func (s *ThreatIntelService) ListFeeds(organizationID uuid.UUID) ([]models.ThreatIntelFeed, error) {
+	var feeds []models.ThreatIntelFeed
+	if err := s.db.Where("organization_id = ?", organizationID).Order("name ASC").Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+	for i := range feeds {
+		feeds[i].Stale = feeds[i].LastError != ""
+	}
+	return feeds, nil
+}
+
+// CreateFeed adds a feed to an organization. It is first fetched on the next check.
+func (s *ThreatIntelService) CreateFeed(organizationID uuid.UUID, req *models.ThreatIntelFeedRequest) (*models.ThreatIntelFeed, error) {
+	feed := &models.ThreatIntelFeed{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+	}
+	if err := s.applyFeedRequest(feed, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(feed).Error; err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// UpdateFeed replaces a feed's settings and fetches it again on the next check
+func (s *ThreatIntelService) UpdateFeed(organizationID, feedID uuid.UUID, req *models.ThreatIntelFeedRequest) (*models.ThreatIntelFeed, error) {
+	feed, err := s.getFeed(organizationID, feedID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyFeedRequest(feed, req); err != nil {
+		return nil, err
+	}
+	feed.NextFetchAt = nil
+	if err := s.db.Save(feed).Error; err != nil {
+		return nil, err
+	}
+	s.invalidate(organizationID)
+	return feed, nil
+}
+
+// DeleteFeed removes a feed and its cached indicators
+func (s *ThreatIntelService) DeleteFeed(organizationID, feedID uuid.UUID) error {
+	feed, err := s.getFeed(organizationID, feedID)
+	if err != nil {
+		return err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("feed_id = ?", feed.ID).Delete(&models.ThreatIntelIndicator{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(feed).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate(organizationID)
+	return nil
+}
+
+// RefreshFeed fetches a feed now and returns it with the outcome
+func (s *ThreatIntelService) RefreshFeed(ctx context.Context, organizationID, feedID uuid.UUID) (*models.ThreatIntelFeed, error) {
+	feed, err := s.getFeed(organizationID, feedID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.claim(feed.ID) {
+		return nil, ErrThreatIntelFeedBusy
+	}
+	defer s.unclaim(feed.ID)
+
+	s.refresh(ctx, feed)
+	feed.Stale = feed.LastError != ""
+	return feed, nil
+}
+
+// Annotate tags findings with what the organization's feeds report about
+// their CVEs, as EnrichmentData["threat_intel"]. A tagged finding counts as
+// known exploited when its priority is derived.
+func (s *ThreatIntelService) Annotate(organizationID uuid.UUID, findings []models.Vulnerability) {
+	intel := s.organizationIntel(organizationID)
+	for i := range findings {
+		finding := &findings[i]
+		matches := intel[strings.ToUpper(finding.CVEID)]
+		if finding.CVEID == "" || len(matches) == 0 {
+			delete(finding.EnrichmentData, "threat_intel")
+			continue
+		}
+		if finding.EnrichmentData == nil {
+			finding.EnrichmentData = make(map[string]interface{})
+		}
+		finding.EnrichmentData["threat_intel"] = matches
+	}
+}
+
+// refresh fetches a feed and, on success, replaces its cached indicators. A
+// failed fetch leaves them in place and retries sooner than the feed's
+// refresh interval, backing off while the feed stays down.
+func (s *ThreatIntelService) refresh(ctx context.Context, feed *models.ThreatIntelFeed) {
+	adapter, ok := threatIntelAdapters[feed.Kind]
+	if !ok {
+		s.recordFetchFailure(feed, fmt.Errorf("unsupported feed kind %q", feed.Kind))
+		return
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, s.fetchTimeout)
+	entries, err := adapter(fetchCtx, s.httpClient, feed)
+	cancel()
+	if err != nil {
+		s.recordFetchFailure(feed, err)
+		return
+	}
+
+	now := time.Now()
+	indicators := make([]models.ThreatIntelIndicator, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		key := entry.CVEID + "\x00" + entry.Context
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		indicators = append(indicators, models.ThreatIntelIndicator{
+			ID:             uuid.New(),
+			FeedID:         feed.ID,
+			OrganizationID: feed.OrganizationID,
+			CVEID:          entry.CVEID,
+			Context:        entry.Context,
+			Confidence:     entry.Confidence,
+			FetchedAt:      now,
+		})
+	}
+
+	next := now.Add(feedRefreshInterval(feed))
+	feed.LastFetchedAt = &now
+	feed.LastSuccessAt = &now
+	feed.LastError = ""
+	feed.Failures = 0
+	feed.IndicatorCount = len(indicators)
+	feed.NextFetchAt = &next
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("feed_id = ?", feed.ID).Delete(&models.ThreatIntelIndicator{}).Error; err != nil {
+			return err
+		}
+		if len(indicators) > 0 {
+			if err := tx.CreateInBatches(indicators, 500).Error; err != nil {
+				return err
+			}
+		}
+		return s.saveFetchState(tx, feed)
+	})
+	if err != nil {
+		log.Printf("Failed to store threat intel feed %s: %v", feed.ID, err)
+		return
+	}
+	s.invalidate(feed.OrganizationID)
+	log.Printf("Fetched threat intel feed %q for org %s: %d CVE indicators", feed.Name, feed.OrganizationID, len(indicators))
+}
+
+// recordFetchFailure records a failed fetch and schedules its retry
+func (s *ThreatIntelService) recordFetchFailure(feed *models.ThreatIntelFeed, err error) {
+	now := time.Now()
+	feed.Failures++
+	next := now.Add(threatIntelRetryDelay(feed.Failures, feedRefreshInterval(feed)))
+	feed.LastFetchedAt = &now
+	feed.LastError = err.Error()
+	feed.NextFetchAt = &next
+	log.Printf("Failed to fetch threat intel feed %q for org %s (attempt %d), keeping cached indicators: %v", feed.Name, feed.OrganizationID, feed.Failures, err)
+
+	if err := s.saveFetchState(s.db, feed); err != nil {
+		log.Printf("Failed to record threat intel feed %s failure: %v", feed.ID, err)
+	}
+	// The feed may have just passed the staleness limit
+	s.invalidate(feed.OrganizationID)
+}
+
+// saveFetchState stores a feed's fetch outcome without touching settings
+// changed while it was being fetched
+func (s *ThreatIntelService) saveFetchState(tx *gorm.DB, feed *models.ThreatIntelFeed) error {
+	return tx.Model(&models.ThreatIntelFeed{}).Where("id = ?", feed.ID).Updates(map[string]interface{}{
+		"last_fetched_at": feed.LastFetchedAt,
+		"last_success_at": feed.LastSuccessAt,
+		"last_error":      feed.LastError,
+		"failures":        feed.Failures,
+		"indicator_count": feed.IndicatorCount,
+		"next_fetch_at":   feed.NextFetchAt,
+	}).Error
+}
+
+// organizationIntel returns an organization's indicators by CVE, from the
+// enabled feeds fetched successfully within the staleness limit
+func (s *ThreatIntelService) organizationIntel(organizationID uuid.UUID) map[string][]models.ThreatIntelMatch {
+	s.mu.Lock()
+	intel, ok := s.cache[organizationID]
+	s.mu.Unlock()
+	if ok {
+		return intel
+	}
+
+	var feeds []models.ThreatIntelFeed
+	if err := s.db.Where("organization_id = ? AND enabled = ? AND last_success_at > ?", organizationID, true, time.Now().Add(-s.maxStaleness)).
+		Find(&feeds).Error; err != nil {
+		log.Printf("Failed to load threat intel feeds for org %s: %v", organizationID, err)
+		return nil
+	}
+	intel = make(map[string][]models.ThreatIntelMatch)
+	if len(feeds) > 0 {
+		names := make(map[uuid.UUID]string, len(feeds))
+		ids := make([]uuid.UUID, 0, len(feeds))
+		for _, feed := range feeds {
+			names[feed.ID] = feed.Name
+			ids = append(ids, feed.ID)
+		}
+		var indicators []models.ThreatIntelIndicator
+		if err := s.db.Where("feed_id IN ?", ids).Find(&indicators).Error; err != nil {
+			log.Printf("Failed to load threat intel indicators for org %s: %v", organizationID, err)
+			return nil
+		}
+		for _, indicator := range indicators {
+			intel[indicator.CVEID] = append(intel[indicator.CVEID], models.ThreatIntelMatch{
+				Feed:       names[indicator.FeedID],
+				Context:    indicator.Context,
+				Confidence: indicator.Confidence,
+			})
+		}
+	}
+
+	s.mu.Lock()
+	s.cache[organizationID] = intel
+	s.mu.Unlock()
+	return intel
+}
+
+// invalidate drops an organization's cached indicators, to be reloaded on next use
+func (s *ThreatIntelService) invalidate(organizationID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.cache, organizationID)
+	s.mu.Unlock()
+}
+
+func (s *ThreatIntelService) claim(feedID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[feedID] {
+		return false
+	}
+	s.running[feedID] = true
+	return true
+}
+
+func (s *ThreatIntelService) unclaim(feedID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.running, feedID)
+	s.mu.Unlock()
+}
+
+func (s *ThreatIntelService) getFeed(organizationID, feedID uuid.UUID) (*models.ThreatIntelFeed, error) {
+	var feed models.ThreatIntelFeed
+	if err := s.db.Where("id = ? AND organization_id = ?", feedID, organizationID).First(&feed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrThreatIntelFeedNotFound
+		}
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// applyFeedRequest validates a feed request and copies it onto feed. Empty
+// secrets keep the stored ones.
+func (s *ThreatIntelService) applyFeedRequest(feed *models.ThreatIntelFeed, req *models.ThreatIntelFeedRequest) error {
+	if _, ok := threatIntelAdapters[req.Kind]; !ok {
+		return fmt.Errorf("unsupported feed kind %q", req.Kind)
+	}
+	refresh := req.RefreshInterval
+	if refresh == "" {
+		refresh = defaultThreatIntelRefresh.String()
+	}
+	interval, err := time.ParseDuration(refresh)
+	if err != nil {
+		return fmt.Errorf("invalid refresh interval %q: %w", refresh, err)
+	}
+	if interval < s.minRefresh {
+		return fmt.Errorf("refresh interval must be at least %s", s.minRefresh)
+	}
+
+	feed.Name = req.Name
+	feed.Kind = req.Kind
+	feed.URL = req.URL
+	feed.Collection = req.Collection
+	feed.Enabled = req.Enabled
+	feed.RefreshInterval = refresh
+	feed.Username = req.Username
+	if req.Password != "" {
+		feed.Password = req.Password
+	}
+	if req.APIKey != "" {
+		feed.APIKey = req.APIKey
+	}
+	return nil
+}
+
+// feedRefreshInterval returns how often a feed is fetched
+func feedRefreshInterval(feed *models.ThreatIntelFeed) time.Duration {
+	interval, err := time.ParseDuration(feed.RefreshInterval)
+	if err != nil || interval <= 0 {
+		return defaultThreatIntelRefresh
+	}
+	return interval
+}
+
+// threatIntelRetryDelay is how long after its nth consecutive failure a feed
+// is fetched again: a minute, doubling with each failure, up to its refresh
+// interval
+func threatIntelRetryDelay(failures int, interval time.Duration) time.Duration {
+	delay := time.Minute
+	for i := 1; i < failures && delay < interval; i++ {
+		delay *= 2
+	}
+	if delay > interval {
+		return interval
+	}
+	return delay
+}
+
+// threatIntelMatches returns what a finding was tagged with by its
+// organization's threat intel feeds, as set by Annotate or decoded from
+// stored results
+func threatIntelMatches(v *models.Vulnerability) []models.ThreatIntelMatch {
+	switch matches := v.EnrichmentData["threat_intel"].(type) {
+	case []models.ThreatIntelMatch:
+		return matches
+	case []interface{}:
+		data, err := json.Marshal(matches)
+		if err != nil {
+			return nil
+		}
+		var decoded []models.ThreatIntelMatch
+		if json.Unmarshal(data, &decoded) != nil {
+			return nil
+		}
+		return decoded
+	}
+	return nil
+}
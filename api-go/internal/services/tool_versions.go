@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// GetToolVersionDrift reports which external scanner tool versions agents are
+// running, and which agents are behind the newest version seen in the fleet.
+// Agents report their tool versions in heartbeat metadata under "tool_versions".
+// uuid.Nil as organizationID covers every agent.
+func (as *AgentService) GetToolVersionDrift(organizationID uuid.UUID) models.ToolVersionDriftReport {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	type agentVersion struct {
+		agent   *models.Agent
+		version string
+	}
+	byTool := make(map[string][]agentVersion)
+	reporting := 0
+
+	for _, agent := range as.agents {
+		if organizationID != uuid.Nil && agent.OrganizationID != organizationID {
+			continue
+		}
+		versions := toolVersionsFromMetadata(agent.Metadata)
+		if len(versions) == 0 {
+			continue
+		}
+		reporting++
+		for tool, version := range versions {
+			byTool[tool] = append(byTool[tool], agentVersion{agent: agent, version: version})
+		}
+	}
+
+	report := models.ToolVersionDriftReport{
+		OrganizationID:  organizationID,
+		AgentsReporting: reporting,
+		Tools:           make([]models.ToolVersionSummary, 0, len(byTool)),
+		GeneratedAt:     time.Now(),
+	}
+
+	for tool, entries := range byTool {
+		summary := models.ToolVersionSummary{
+			Tool:           tool,
+			Versions:       make(map[string]int),
+			AgentCount:     len(entries),
+			OutdatedAgents: []models.OutdatedToolAgent{},
+		}
+		for _, entry := range entries {
+			summary.Versions[entry.version]++
+			if summary.LatestVersion == "" || compareVersions(entry.version, summary.LatestVersion) > 0 {
+				summary.LatestVersion = entry.version
+			}
+		}
+		summary.Consistent = len(summary.Versions) == 1
+
+		for _, entry := range entries {
+			if compareVersions(entry.version, summary.LatestVersion) < 0 {
+				summary.OutdatedAgents = append(summary.OutdatedAgents, models.OutdatedToolAgent{
+					AgentID:  entry.agent.ID,
+					Name:     entry.agent.Name,
+					Hostname: entry.agent.Hostname,
+					Version:  entry.version,
+				})
+			}
+		}
+		sort.Slice(summary.OutdatedAgents, func(i, j int) bool {
+			return summary.OutdatedAgents[i].Name < summary.OutdatedAgents[j].Name
+		})
+
+		if !summary.Consistent {
+			report.DriftingTools++
+		}
+		report.Tools = append(report.Tools, summary)
+	}
+
+	sort.Slice(report.Tools, func(i, j int) bool {
+		return report.Tools[i].Tool < report.Tools[j].Tool
+	})
+
+	return report
+}
+
+// toolVersionsFromMetadata reads the tool_versions map from agent metadata,
+// which is map[string]interface{} once it has round-tripped through JSON
+func toolVersionsFromMetadata(metadata map[string]interface{}) map[string]string {
+	switch raw := metadata["tool_versions"].(type) {
+	case map[string]string:
+		return raw
+	case map[string]interface{}:
+		versions := make(map[string]string, len(raw))
+		for tool, v := range raw {
+			if version := fmt.Sprint(v); v != nil && version != "" {
+				versions[tool] = version
+			}
+		}
+		return versions
+	default:
+		return nil
+	}
+}
+
+// GetMissingTools reports which agents lack each optional scanner tool, so an
+// empty scan from an agent that couldn't look isn't mistaken for a clean one.
+// Agents report the tools they lack in heartbeat metadata under "missing_tools".
+// uuid.Nil as organizationID covers every agent.
+func (as *AgentService) GetMissingTools(organizationID uuid.UUID) models.MissingToolsReport {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	byTool := make(map[string][]models.MissingToolAgent)
+	reporting := 0
+
+	for _, agent := range as.agents {
+		if organizationID != uuid.Nil && agent.OrganizationID != organizationID {
+			continue
+		}
+		missing, ok := missingToolsFromMetadata(agent.Metadata)
+		if !ok {
+			continue
+		}
+		reporting++
+		for _, tool := range missing {
+			byTool[tool] = append(byTool[tool], models.MissingToolAgent{
+				AgentID:  agent.ID,
+				Name:     agent.Name,
+				Hostname: agent.Hostname,
+			})
+		}
+	}
+
+	report := models.MissingToolsReport{
+		OrganizationID:  organizationID,
+		AgentsReporting: reporting,
+		Tools:           make([]models.MissingToolSummary, 0, len(byTool)),
+		GeneratedAt:     time.Now(),
+	}
+
+	for tool, agents := range byTool {
+		sort.Slice(agents, func(i, j int) bool {
+			return agents[i].Name < agents[j].Name
+		})
+		report.Tools = append(report.Tools, models.MissingToolSummary{
+			Tool:         tool,
+			MissingCount: len(agents),
+			Agents:       agents,
+		})
+	}
+
+	sort.Slice(report.Tools, func(i, j int) bool {
+		return report.Tools[i].Tool < report.Tools[j].Tool
+	})
+
+	return report
+}
+
+// missingToolsFromMetadata reads the missing_tools list from agent metadata.
+// The second result is false for agents too old to report it, as opposed to
+// agents that report nothing missing.
+func missingToolsFromMetadata(metadata map[string]interface{}) ([]string, bool) {
+	switch raw := metadata["missing_tools"].(type) {
+	case []string:
+		return raw, true
+	case []interface{}:
+		tools := make([]string, 0, len(raw))
+		for _, v := range raw {
+			if tool, ok := v.(string); ok && tool != "" {
+				tools = append(tools, tool)
+			}
+		}
+		return tools, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"zerotrace/api/internal/models"
+)
+
+// Topology graph interchange formats
+const (
+	TopologyFormatJSON    = "json"    // JSON Graph Format, https://jsongraphformat.info
+	TopologyFormatGraphML = "graphml" // GraphML, http://graphml.graphdrawing.org
+)
+
+// topologyGraphType identifies ZeroTrace topology graphs in JSON Graph Format
+const topologyGraphType = "zerotrace.network_topology"
+
+// EncodeTopology writes a topology graph in one of the topology formats
+func EncodeTopology(w io.Writer, graph *models.TopologyGraph, format string) error {
+	switch format {
+	case TopologyFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toJSONGraph(graph))
+	case TopologyFormatGraphML:
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(toGraphML(graph)); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+	return fmt.Errorf("unknown topology format %q", format)
+}
+
+// DecodeTopology reads a topology graph in one of the topology formats.
+// Attributes the graph does not carry are left unset. An undirected graph
+// becomes a directed one with an edge each way.
+func DecodeTopology(r io.Reader, format string) (*models.TopologyGraph, error) {
+	switch format {
+	case TopologyFormatJSON:
+		var document jsonGraphDocument
+		if err := json.NewDecoder(r).Decode(&document); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTopology, err)
+		}
+		return fromJSONGraph(&document.Graph), nil
+	case TopologyFormatGraphML:
+		var document graphMLDocument
+		if err := xml.NewDecoder(r).Decode(&document); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTopology, err)
+		}
+		return fromGraphML(&document)
+	}
+	return nil, fmt.Errorf("unknown topology format %q", format)
+}
+
+// jsonGraphDocument is a single graph in JSON Graph Format
+type jsonGraphDocument struct {
+	Graph jsonGraph `json:"graph"`
+}
+
+type jsonGraph struct {
+	ID       string                   `json:"id,omitempty"`
+	Label    string                   `json:"label,omitempty"`
+	Type     string                   `json:"type,omitempty"`
+	Directed *bool                    `json:"directed,omitempty"` // JSON Graph Format graphs are directed unless set to false
+	Metadata *jsonGraphMetadata       `json:"metadata,omitempty"`
+	Nodes    map[string]jsonGraphNode `json:"nodes"`
+	Edges    []jsonGraphEdge          `json:"edges"`
+}
+
+type jsonGraphMetadata struct {
+	RiskDecay     float64               `json:"risk_decay"`
+	AnalyzedAt    *time.Time            `json:"analyzed_at,omitempty"`
+	CriticalPaths []models.TopologyPath `json:"critical_paths,omitempty"`
+}
+
+type jsonGraphNode struct {
+	Label    string                `json:"label,omitempty"`
+	Metadata jsonGraphNodeMetadata `json:"metadata"`
+}
+
+type jsonGraphNodeMetadata struct {
+	Type              string  `json:"type,omitempty"`
+	IPAddress         string  `json:"ip_address,omitempty"`
+	OS                string  `json:"os,omitempty"`
+	RiskScore         float64 `json:"risk_score"`
+	Criticality       string  `json:"criticality,omitempty"`
+	AdjustedRiskScore float64 `json:"adjusted_risk_score,omitempty"`
+	InheritedRisk     float64 `json:"inherited_risk,omitempty"`
+	RiskSource        string  `json:"risk_source,omitempty"`
+	RiskSourceHops    int     `json:"risk_source_hops,omitempty"`
+}
+
+type jsonGraphEdge struct {
+	Source   string                `json:"source"`
+	Target   string                `json:"target"`
+	Relation string                `json:"relation,omitempty"`
+	Directed *bool                 `json:"directed,omitempty"`
+	Metadata jsonGraphEdgeMetadata `json:"metadata"`
+}
+
+type jsonGraphEdgeMetadata struct {
+	Weight   *float64 `json:"weight,omitempty"` // 1 when left out
+	Critical bool     `json:"critical,omitempty"`
+}
+
+func toJSONGraph(graph *models.TopologyGraph) jsonGraphDocument {
+	directed := true
+	document := jsonGraphDocument{Graph: jsonGraph{
+		ID:       graph.ID,
+		Label:    graph.Label,
+		Type:     topologyGraphType,
+		Directed: &directed,
+		Metadata: &jsonGraphMetadata{RiskDecay: graph.RiskDecay, CriticalPaths: graph.CriticalPaths},
+		Nodes:    make(map[string]jsonGraphNode, len(graph.Nodes)),
+		Edges:    make([]jsonGraphEdge, 0, len(graph.Edges)),
+	}}
+	if !graph.AnalyzedAt.IsZero() {
+		document.Graph.Metadata.AnalyzedAt = &graph.AnalyzedAt
+	}
+
+	for _, node := range graph.Nodes {
+		document.Graph.Nodes[node.ID] = jsonGraphNode{
+			Label: node.Label,
+			Metadata: jsonGraphNodeMetadata{
+				Type:              node.Type,
+				IPAddress:         node.IPAddress,
+				OS:                node.OS,
+				RiskScore:         node.RiskScore,
+				Criticality:       node.Criticality,
+				AdjustedRiskScore: node.AdjustedRiskScore,
+				InheritedRisk:     node.InheritedRisk,
+				RiskSource:        node.RiskSource,
+				RiskSourceHops:    node.RiskSourceHops,
+			},
+		}
+	}
+	for _, edge := range graph.Edges {
+		weight := edge.Weight
+		document.Graph.Edges = append(document.Graph.Edges, jsonGraphEdge{
+			Source:   edge.Source,
+			Target:   edge.Target,
+			Relation: "reaches",
+			Metadata: jsonGraphEdgeMetadata{Weight: &weight, Critical: edge.Critical},
+		})
+	}
+	return document
+}
+
+func fromJSONGraph(document *jsonGraph) *models.TopologyGraph {
+	graph := &models.TopologyGraph{
+		ID:    document.ID,
+		Label: document.Label,
+		Nodes: make([]models.TopologyNode, 0, len(document.Nodes)),
+		Edges: make([]models.TopologyEdge, 0, len(document.Edges)),
+	}
+
+	ids := make([]string, 0, len(document.Nodes))
+	for id := range document.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		node := document.Nodes[id]
+		graph.Nodes = append(graph.Nodes, models.TopologyNode{
+			ID:          id,
+			Label:       node.Label,
+			Type:        node.Metadata.Type,
+			IPAddress:   node.Metadata.IPAddress,
+			OS:          node.Metadata.OS,
+			RiskScore:   node.Metadata.RiskScore,
+			Criticality: node.Metadata.Criticality,
+		})
+	}
+
+	graphDirected := document.Directed == nil || *document.Directed
+	for _, edge := range document.Edges {
+		weight := 1.0
+		if edge.Metadata.Weight != nil {
+			weight = *edge.Metadata.Weight
+		}
+		directed := graphDirected
+		if edge.Directed != nil {
+			directed = *edge.Directed
+		}
+		graph.Edges = appendTopologyEdge(graph.Edges, edge.Source, edge.Target, weight, directed)
+	}
+	return graph
+}
+
+// appendTopologyEdge adds an edge, and its reverse if it is undirected
+func appendTopologyEdge(edges []models.TopologyEdge, source, target string, weight float64, directed bool) []models.TopologyEdge {
+	edges = append(edges, models.TopologyEdge{Source: source, Target: target, Weight: weight})
+	if !directed && source != target {
+		edges = append(edges, models.TopologyEdge{Source: target, Target: source, Weight: weight})
+	}
+	return edges
+}
+
+// graphMLDocument is a GraphML file holding a single graph
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr,omitempty"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr,omitempty"`
+	Type string `xml:"attr.type,attr,omitempty"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr,omitempty"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Data        []graphMLData `xml:"data"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID       string        `xml:"id,attr,omitempty"`
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed string        `xml:"directed,attr,omitempty"`
+	Data     []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// graphMLKeys declares the attributes topology graphs carry in GraphML. Keys
+// are named after their attribute, so exported files read well in tools that
+// show key IDs; graph attributes are prefixed, as key IDs must be unique.
+var graphMLKeys = []graphMLKey{
+	{ID: "graph_label", For: "graph", Name: "label", Type: "string"},
+	{ID: "graph_risk_decay", For: "graph", Name: "risk_decay", Type: "double"},
+	{ID: "graph_analyzed_at", For: "graph", Name: "analyzed_at", Type: "string"},
+	{ID: "label", For: "node", Name: "label", Type: "string"},
+	{ID: "type", For: "node", Name: "type", Type: "string"},
+	{ID: "ip_address", For: "node", Name: "ip_address", Type: "string"},
+	{ID: "os", For: "node", Name: "os", Type: "string"},
+	{ID: "risk_score", For: "node", Name: "risk_score", Type: "double"},
+	{ID: "criticality", For: "node", Name: "criticality", Type: "string"},
+	{ID: "adjusted_risk_score", For: "node", Name: "adjusted_risk_score", Type: "double"},
+	{ID: "inherited_risk", For: "node", Name: "inherited_risk", Type: "double"},
+	{ID: "risk_source", For: "node", Name: "risk_source", Type: "string"},
+	{ID: "risk_source_hops", For: "node", Name: "risk_source_hops", Type: "int"},
+	{ID: "weight", For: "edge", Name: "weight", Type: "double"},
+	{ID: "critical", For: "edge", Name: "critical", Type: "boolean"},
+}
+
+func toGraphML(graph *models.TopologyGraph) graphMLDocument {
+	document := graphMLDocument{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{ID: graph.ID, EdgeDefault: "directed"},
+	}
+	data := func(key, value string) graphMLData { return graphMLData{Key: key, Value: value} }
+	number := func(value float64) string { return strconv.FormatFloat(value, 'f', -1, 64) }
+
+	document.Graph.Data = []graphMLData{data("graph_label", graph.Label), data("graph_risk_decay", number(graph.RiskDecay))}
+	if !graph.AnalyzedAt.IsZero() {
+		document.Graph.Data = append(document.Graph.Data, data("graph_analyzed_at", graph.AnalyzedAt.Format(time.RFC3339)))
+	}
+
+	for _, node := range graph.Nodes {
+		element := graphMLNode{ID: node.ID}
+		for key, value := range map[string]string{
+			"label":       node.Label,
+			"type":        node.Type,
+			"ip_address":  node.IPAddress,
+			"os":          node.OS,
+			"criticality": node.Criticality,
+			"risk_source": node.RiskSource,
+		} {
+			if value != "" {
+				element.Data = append(element.Data, data(key, value))
+			}
+		}
+		element.Data = append(element.Data,
+			data("risk_score", number(node.RiskScore)),
+			data("adjusted_risk_score", number(node.AdjustedRiskScore)),
+			data("inherited_risk", number(node.InheritedRisk)),
+			data("risk_source_hops", strconv.Itoa(node.RiskSourceHops)),
+		)
+		sort.Slice(element.Data, func(i, j int) bool { return element.Data[i].Key < element.Data[j].Key })
+		document.Graph.Nodes = append(document.Graph.Nodes, element)
+	}
+	for i, edge := range graph.Edges {
+		document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: edge.Source,
+			Target: edge.Target,
+			Data:   []graphMLData{data("weight", number(edge.Weight)), data("critical", strconv.FormatBool(edge.Critical))},
+		})
+	}
+	return document
+}
+
+func fromGraphML(document *graphMLDocument) (*models.TopologyGraph, error) {
+	// Keys may have any ID, as tools number them; attributes are matched by name
+	names := make(map[[2]string]string, len(document.Keys))
+	for _, key := range document.Keys {
+		name := key.Name
+		if name == "" {
+			name = key.ID
+		}
+		names[[2]string{key.For, key.ID}] = strings.ToLower(name)
+	}
+	attributes := func(kind string, data []graphMLData) map[string]string {
+		values := make(map[string]string, len(data))
+		for _, d := range data {
+			name, ok := names[[2]string{kind, d.Key}]
+			if !ok {
+				name, ok = names[[2]string{"all", d.Key}]
+			}
+			if !ok {
+				name = d.Key
+			}
+			values[name] = strings.TrimSpace(d.Value)
+		}
+		return values
+	}
+	number := func(element, name, value string) (float64, error) {
+		if value == "" {
+			return 0, nil
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s has %s %q, not a number", ErrInvalidTopology, element, name, value)
+		}
+		return parsed, nil
+	}
+
+	graph := &models.TopologyGraph{
+		ID:    document.Graph.ID,
+		Label: attributes("graph", document.Graph.Data)["label"],
+		Nodes: make([]models.TopologyNode, 0, len(document.Graph.Nodes)),
+		Edges: make([]models.TopologyEdge, 0, len(document.Graph.Edges)),
+	}
+	for _, element := range document.Graph.Nodes {
+		values := attributes("node", element.Data)
+		risk, err := number("node "+strconv.Quote(element.ID), "risk_score", values["risk_score"])
+		if err != nil {
+			return nil, err
+		}
+		graph.Nodes = append(graph.Nodes, models.TopologyNode{
+			ID:          element.ID,
+			Label:       values["label"],
+			Type:        values["type"],
+			IPAddress:   values["ip_address"],
+			OS:          values["os"],
+			RiskScore:   risk,
+			Criticality: strings.ToLower(values["criticality"]),
+		})
+	}
+
+	graphDirected := document.Graph.EdgeDefault != "undirected"
+	for _, element := range document.Graph.Edges {
+		weight := 1.0
+		if value := attributes("edge", element.Data)["weight"]; value != "" {
+			parsed, err := number(fmt.Sprintf("edge %q -> %q", element.Source, element.Target), "weight", value)
+			if err != nil {
+				return nil, err
+			}
+			weight = parsed
+		}
+		directed := graphDirected
+		if element.Directed != "" {
+			directed = element.Directed == "true"
+		}
+		graph.Edges = appendTopologyEdge(graph.Edges, element.Source, element.Target, weight, directed)
+	}
+	return graph, nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAgentReleaseNotFound is returned when an agent release is unknown
+	ErrAgentReleaseNotFound = errors.New("agent release not found")
+	// ErrAgentReleaseExists is returned when a platform already has a release of a version
+	ErrAgentReleaseExists = errors.New("agent release already exists")
+	// ErrInvalidAgentRelease is returned for an agent release that can't be published
+	ErrInvalidAgentRelease = errors.New("invalid agent release")
+)
+
+var (
+	releaseVersionPattern  = regexp.MustCompile(`^v?\d+(\.\d+)*([-+][0-9A-Za-z.-]+)?$`)
+	releasePlatformPattern = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9]+$`)
+)
+
+// UpdateService publishes agent releases and tells agents, on heartbeat,
+// which release of their platform to update to
+type UpdateService struct {
+	db *gorm.DB
+}
+
+// NewUpdateService creates a new update service
+func NewUpdateService(db *gorm.DB) *UpdateService {
+	return &UpdateService{db: db}
+}
+
+// ListReleases lists the published releases, of one platform when platform
+// isn't empty, newest first
+func (s *UpdateService) ListReleases(platform string) ([]models.AgentRelease, error) {
+	query := s.db.Order("created_at DESC")
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	var releases []models.AgentRelease
+	if err := query.Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		releases[i].Manifest = models.ReleaseManifest(releases[i].Version, releases[i].Platform, releases[i].SHA256)
+	}
+	return releases, nil
+}
+
+// PublishRelease publishes a release, which agents of its platform running an
+// older version update to on their next heartbeat
+func (s *UpdateService) PublishRelease(req *models.AgentReleaseRequest) (*models.AgentRelease, error) {
+	release, err := newAgentRelease(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.AgentRelease{}).
+			Where("platform = ? AND version = ?", release.Platform, release.Version).
+			Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check agent releases: %w", err)
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: %s for %s", ErrAgentReleaseExists, release.Version, release.Platform)
+		}
+		if err := tx.Create(release).Error; err != nil {
+			return fmt.Errorf("failed to publish agent release: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+// DeleteRelease withdraws a release. Agents already running it keep it, but
+// no further agent updates to it.
+func (s *UpdateService) DeleteRelease(releaseID uuid.UUID) error {
+	result := s.db.Where("id = ?", releaseID).Delete(&models.AgentRelease{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete agent release: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAgentReleaseNotFound
+	}
+	return nil
+}
+
+// UpdateFor returns the release an agent of a platform running a version
+// should update to, nil when it runs the latest one or its platform has none
+func (s *UpdateService) UpdateFor(platform, version string) (*models.AgentRelease, error) {
+	if platform == "" || version == "" {
+		return nil, nil
+	}
+	releases, err := s.ListReleases(platform)
+	if err != nil {
+		return nil, err
+	}
+	return newerRelease(releases, version), nil
+}
+
+// newerRelease returns the latest of the releases, if it is newer than version
+func newerRelease(releases []models.AgentRelease, version string) *models.AgentRelease {
+	var latest *models.AgentRelease
+	for i := range releases {
+		if latest == nil || compareVersions(releases[i].Version, latest.Version) > 0 {
+			latest = &releases[i]
+		}
+	}
+	if latest == nil || compareVersions(latest.Version, version) <= 0 {
+		return nil
+	}
+	return latest
+}
+
+// newAgentRelease validates a release request. The signature can't be
+// verified here, as only agents hold the public key, but it must at least be
+// an ed25519 signature. It signs the manifest of the release as normalized
+// here, with a lower-case platform and digest.
+func newAgentRelease(req *models.AgentReleaseRequest) (*models.AgentRelease, error) {
+	release := &models.AgentRelease{
+		ID:        uuid.New(),
+		Version:   strings.TrimSpace(req.Version),
+		Platform:  strings.ToLower(strings.TrimSpace(req.Platform)),
+		URL:       strings.TrimSpace(req.URL),
+		SHA256:    strings.ToLower(strings.TrimSpace(req.SHA256)),
+		Signature: strings.TrimSpace(req.Signature),
+		Notes:     strings.TrimSpace(req.Notes),
+		CreatedBy: req.Actor,
+	}
+
+	if !releaseVersionPattern.MatchString(release.Version) {
+		return nil, fmt.Errorf("%w: version %q is not a version number", ErrInvalidAgentRelease, release.Version)
+	}
+	if !releasePlatformPattern.MatchString(release.Platform) {
+		return nil, fmt.Errorf("%w: platform must be GOOS/GOARCH, such as linux/amd64, got %q", ErrInvalidAgentRelease, release.Platform)
+	}
+	if u, err := url.Parse(release.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an http(s) URL", ErrInvalidAgentRelease)
+	}
+	if digest, err := hex.DecodeString(release.SHA256); err != nil || len(digest) != 32 {
+		return nil, fmt.Errorf("%w: sha256 must be a hex SHA-256 digest", ErrInvalidAgentRelease)
+	}
+	if signature, err := base64.StdEncoding.DecodeString(release.Signature); err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: signature must be a base64 ed25519 signature", ErrInvalidAgentRelease)
+	}
+	release.Manifest = models.ReleaseManifest(release.Version, release.Platform, release.SHA256)
+	return release, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAgentRelease(t *testing.T) {
+	valid := models.AgentReleaseRequest{
+		Version:   "1.4.0",
+		Platform:  " Linux/AMD64 ",
+		URL:       "https://releases.example.com/zerotrace-agent-1.4.0-linux-amd64",
+		SHA256:    strings.Repeat("AB", 32),
+		Signature: base64.StdEncoding.EncodeToString(make([]byte, 64)),
+	}
+	release, err := newAgentRelease(&valid)
+	require.NoError(t, err)
+	assert.Equal(t, "linux/amd64", release.Platform)
+	assert.Equal(t, strings.Repeat("ab", 32), release.SHA256)
+	assert.Equal(t, "zerotrace-agent-release\nversion=1.4.0\nplatform=linux/amd64\nsha256="+strings.Repeat("ab", 32)+"\n", release.Manifest,
+		"the manifest is what agents verify the signature against, so it must match theirs byte for byte")
+
+	for name, change := range map[string]func(*models.AgentReleaseRequest){
+		"not a version":     func(r *models.AgentReleaseRequest) { r.Version = "latest" },
+		"no architecture":   func(r *models.AgentReleaseRequest) { r.Platform = "linux" },
+		"not a URL":         func(r *models.AgentReleaseRequest) { r.URL = "file:///tmp/agent" },
+		"short digest":      func(r *models.AgentReleaseRequest) { r.SHA256 = "abcd" },
+		"not base64":        func(r *models.AgentReleaseRequest) { r.Signature = "not a signature!" },
+		"wrong signature":   func(r *models.AgentReleaseRequest) { r.Signature = base64.StdEncoding.EncodeToString(make([]byte, 32)) },
+		"version separator": func(r *models.AgentReleaseRequest) { r.Version = "1..4" },
+	} {
+		req := valid
+		change(&req)
+		_, err := newAgentRelease(&req)
+		assert.ErrorIs(t, err, ErrInvalidAgentRelease, name)
+	}
+}
+
+func TestNewerRelease(t *testing.T) {
+	releases := []models.AgentRelease{{Version: "1.9.0"}, {Version: "1.10.0"}, {Version: "1.2.3"}}
+
+	latest := newerRelease(releases, "1.9.1")
+	require.NotNil(t, latest)
+	assert.Equal(t, "1.10.0", latest.Version)
+
+	assert.Nil(t, newerRelease(releases, "1.10.0"), "agents on the latest release have nothing to update to")
+	assert.Nil(t, newerRelease(releases, "2.0.0"), "agents are never downgraded")
+	assert.Nil(t, newerRelease(nil, "1.0.0"))
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// attributeVulnerabilities sets IntroducedBy on each vulnerability in a scan result.
+//
+// Vulnerabilities already known for the agent keep their original attribution.
+// New ones are attributed by diffing the agent's previous dependency set
+// against the one in this result: a package that was not there before was
+// installed, a package whose version changed was upgraded or downgraded, and
+// an agent with no previous scan data is a new host. When none of these
+// apply (for example a new advisory for an unchanged package) the
+// vulnerability is left unattributed.
+func attributeVulnerabilities(agentID uuid.UUID, result *models.AgentScanResult, previousDeps []models.Dependency, previousVulns []models.Vulnerability) {
+	known := make(map[string]*models.Attribution, len(previousVulns))
+	for i := range previousVulns {
+		known[FindingKey(&previousVulns[i])] = previousVulns[i].IntroducedBy
+	}
+
+	previousVersions := make(map[string]string, len(previousDeps))
+	for _, dep := range previousDeps {
+		previousVersions[dependencyKey(dep.Name, dep.Type)] = dep.Version
+	}
+	currentTypes := make(map[string]string, len(result.Dependencies))
+	for _, dep := range result.Dependencies {
+		currentTypes[dep.Name] = dep.Type
+	}
+
+	newHost := len(previousDeps) == 0 && len(previousVulns) == 0
+	detectedAt := result.EndTime
+	if detectedAt.IsZero() {
+		detectedAt = time.Now()
+	}
+
+	for i := range result.Vulnerabilities {
+		vuln := &result.Vulnerabilities[i]
+
+		if attribution, exists := known[FindingKey(vuln)]; exists {
+			vuln.IntroducedBy = attribution
+			continue
+		}
+
+		attribution := &models.Attribution{
+			ScanID:         result.ID,
+			AgentID:        agentID,
+			PackageName:    vuln.PackageName,
+			CurrentVersion: vuln.PackageVersion,
+			DetectedAt:     detectedAt,
+		}
+
+		previousVersion, hadPackage := previousVersions[dependencyKey(vuln.PackageName, currentTypes[vuln.PackageName])]
+		switch {
+		case newHost:
+			attribution.ChangeType = models.ChangeNewHost
+		case vuln.PackageName == "":
+			continue
+		case !hadPackage:
+			attribution.ChangeType = models.ChangePackageInstalled
+		case previousVersion != vuln.PackageVersion:
+			attribution.PreviousVersion = previousVersion
+			if compareVersions(vuln.PackageVersion, previousVersion) < 0 {
+				attribution.ChangeType = models.ChangeVersionDowngraded
+			} else {
+				attribution.ChangeType = models.ChangeVersionUpgraded
+			}
+		default:
+			// Package unchanged; the vulnerability was not caused by a change on this host
+			continue
+		}
+
+		vuln.IntroducedBy = attribution
+	}
+}
+
+func dependencyKey(name, depType string) string {
+	return depType + "|" + name
+}
+
+// compareVersions compares dotted version strings segment by segment. It
+// returns -1, 0 or 1. Missing trailing segments count as zero, so 1.2 equals
+// 1.2.0, except that a version carrying an extra non-numeric segment is a
+// pre-release of the shorter one: 1.2.0-rc1 comes before 1.2.0.
+func compareVersions(a, b string) int {
+	as := strings.FieldsFunc(strings.TrimPrefix(a, "v"), isVersionSeparator)
+	bs := strings.FieldsFunc(strings.TrimPrefix(b, "v"), isVersionSeparator)
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		if i >= len(as) || i >= len(bs) {
+			extra, sign := bs, -1
+			if i < len(as) {
+				extra, sign = as, 1
+			}
+			n, err := strconv.Atoi(extra[i])
+			switch {
+			case err != nil:
+				return -sign
+			case n != 0:
+				return sign
+			}
+			continue
+		}
+		if c := compareVersionSegments(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareVersionSegments compares two version segments run by run: runs of
+// digits numerically and the rest lexically, so rc2 comes before rc10
+func compareVersionSegments(x, y string) int {
+	for x != "" && y != "" {
+		xr, yr := x[:versionRunLength(x)], y[:versionRunLength(y)]
+		x, y = x[len(xr):], y[len(yr):]
+
+		xn, xerr := strconv.Atoi(xr)
+		yn, yerr := strconv.Atoi(yr)
+		switch {
+		case xerr == nil && yerr == nil:
+			if xn != yn {
+				return cmp.Compare(xn, yn)
+			}
+		case xr != yr:
+			return strings.Compare(xr, yr)
+		}
+	}
+	return cmp.Compare(len(x), len(y))
+}
+
+// versionRunLength returns the length of the run of digits, or of other
+// characters, that s starts with
+func versionRunLength(s string) int {
+	digit := unicode.IsDigit(rune(s[0]))
+	for i := 1; i < len(s); i++ {
+		if unicode.IsDigit(rune(s[i])) != digit {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func isVersionSeparator(r rune) bool {
+	return r == '.' || r == '-' || r == '+' || r == '_'
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"2.0", "1.99.99", 1},
+
+		// Differing segment counts
+		{"1.2", "1.2.0", 0},
+		{"1.2", "1.2.1", -1},
+		{"1.2.0.1", "1.2", 1},
+		{"", "0.0.1", -1},
+
+		// Pre-releases
+		{"1.2.0-rc1", "1.2.0", -1},
+		{"1.2.0-beta", "1.2", -1},
+		{"1.2.0-rc1", "1.1.9", 1},
+		{"1.2.0-alpha", "1.2.0-beta", -1},
+		{"1.2.0-rc.2", "1.2.0-rc.10", -1},
+		{"1.2.0-rc2", "1.2.0-rc10", -1},
+		{"1.2.0-1", "1.2.0-rc1", -1},
+
+		// Non-numeric segments
+		{"1.1.1t", "1.1.1u", -1},
+		{"1.1.1", "1.1.1a", -1},
+		{"2.4.52-1ubuntu4", "2.4.52-1ubuntu10", -1},
+		{"1.0.0_p1", "1.0.0_p1", 0},
+		{"2024.01.a", "2024.01.b", -1},
+	} {
+		assert.Equal(t, tc.want, compareVersions(tc.a, tc.b), "compareVersions(%q, %q)", tc.a, tc.b)
+		assert.Equal(t, -tc.want, compareVersions(tc.b, tc.a), "compareVersions(%q, %q)", tc.b, tc.a)
+	}
+}
+
+func TestAttributeVulnerabilities(t *testing.T) {
+	agentID := uuid.New()
+	scannedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	vuln := func(pkg, version string) models.Vulnerability {
+		return models.Vulnerability{Type: "dependency", CVEID: "CVE-2025-" + pkg, PackageName: pkg, PackageVersion: version}
+	}
+	dep := func(name, version string) models.Dependency {
+		return models.Dependency{Name: name, Version: version, Type: "npm"}
+	}
+	earlier := &models.Attribution{ChangeType: models.ChangePackageInstalled, PackageName: "lodash", CurrentVersion: "4.17.20"}
+	knownVuln := vuln("lodash", "4.17.20")
+	knownVuln.IntroducedBy = earlier
+
+	for _, tc := range []struct {
+		name         string
+		previousDeps []models.Dependency
+		previousVuln []models.Vulnerability
+		currentDeps  []models.Dependency
+		found        models.Vulnerability
+		want         models.ChangeType // Empty when left unattributed
+		wantPrevious string
+	}{
+		{name: "new host", currentDeps: []models.Dependency{dep("express", "4.18.0")}, found: vuln("express", "4.18.0"), want: models.ChangeNewHost},
+		{name: "installed", previousDeps: []models.Dependency{dep("lodash", "4.17.21")}, currentDeps: []models.Dependency{dep("lodash", "4.17.21"), dep("express", "4.18.0")}, found: vuln("express", "4.18.0"), want: models.ChangePackageInstalled},
+		{name: "same name in another ecosystem", previousDeps: []models.Dependency{dep("requests", "2.0.0")}, currentDeps: []models.Dependency{{Name: "requests", Version: "2.0.0", Type: "pip"}}, found: vuln("requests", "2.0.0"), want: models.ChangePackageInstalled},
+		{name: "upgraded", previousDeps: []models.Dependency{dep("express", "4.17.3")}, currentDeps: []models.Dependency{dep("express", "4.18.0")}, found: vuln("express", "4.18.0"), want: models.ChangeVersionUpgraded, wantPrevious: "4.17.3"},
+		{name: "released after pre-release", previousDeps: []models.Dependency{dep("express", "5.0.0-rc1")}, currentDeps: []models.Dependency{dep("express", "5.0.0")}, found: vuln("express", "5.0.0"), want: models.ChangeVersionUpgraded, wantPrevious: "5.0.0-rc1"},
+		{name: "downgraded to pre-release", previousDeps: []models.Dependency{dep("express", "5.0.0")}, currentDeps: []models.Dependency{dep("express", "5.0.0-beta.3")}, found: vuln("express", "5.0.0-beta.3"), want: models.ChangeVersionDowngraded, wantPrevious: "5.0.0"},
+		{name: "downgraded with fewer segments", previousDeps: []models.Dependency{dep("express", "4.18.0.1")}, currentDeps: []models.Dependency{dep("express", "4.18")}, found: vuln("express", "4.18"), want: models.ChangeVersionDowngraded, wantPrevious: "4.18.0.1"},
+		{name: "unchanged package", previousDeps: []models.Dependency{dep("express", "4.18.0")}, currentDeps: []models.Dependency{dep("express", "4.18.0")}, found: vuln("express", "4.18.0")},
+		{name: "no package", previousDeps: []models.Dependency{dep("express", "4.18.0")}, found: models.Vulnerability{Type: "config", Title: "SSH permits root login"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := &models.AgentScanResult{ID: uuid.New(), EndTime: scannedAt, Dependencies: tc.currentDeps, Vulnerabilities: []models.Vulnerability{tc.found}}
+			attributeVulnerabilities(agentID, result, tc.previousDeps, tc.previousVuln)
+
+			got := result.Vulnerabilities[0].IntroducedBy
+			if tc.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tc.want, got.ChangeType)
+			assert.Equal(t, tc.wantPrevious, got.PreviousVersion)
+			assert.Equal(t, tc.found.PackageVersion, got.CurrentVersion)
+			assert.Equal(t, result.ID, got.ScanID)
+			assert.Equal(t, agentID, got.AgentID)
+			assert.Equal(t, scannedAt, got.DetectedAt)
+		})
+	}
+
+	// A vulnerability already known for the agent keeps its attribution
+	result := &models.AgentScanResult{ID: uuid.New(), Dependencies: []models.Dependency{dep("lodash", "4.17.21")}, Vulnerabilities: []models.Vulnerability{vuln("lodash", "4.17.21")}}
+	attributeVulnerabilities(agentID, result, []models.Dependency{dep("lodash", "4.17.20")}, []models.Vulnerability{knownVuln})
+	assert.Same(t, earlier, result.Vulnerabilities[0].IntroducedBy)
+}
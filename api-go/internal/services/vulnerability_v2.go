@@ -29,10 +29,13 @@ type VulnerabilityV2Service struct {
 	privacyFindings   map[string]models.PrivacyFinding
 	web3Findings      map[string]models.Web3Finding
 	scanResults       map[string]models.ScanResult
+
+	suppressions *SuppressionService // Marks vulnerabilities its rules match suppressed; optional
+	progress     *ScanProgressBus    // Told of network scans as they start; optional
 }
 
 // NewVulnerabilityV2Service creates a new vulnerability v2 service
-func NewVulnerabilityV2Service() *VulnerabilityV2Service {
+func NewVulnerabilityV2Service(suppressions *SuppressionService, progress *ScanProgressBus) *VulnerabilityV2Service {
 	return &VulnerabilityV2Service{
 		vulnerabilities:   make(map[string]models.VulnerabilityV2),
 		networkFindings:   make(map[string]models.NetworkFinding),
@@ -47,15 +50,93 @@ func NewVulnerabilityV2Service() *VulnerabilityV2Service {
 		privacyFindings:   make(map[string]models.PrivacyFinding),
 		web3Findings:      make(map[string]models.Web3Finding),
 		scanResults:       make(map[string]models.ScanResult),
+		suppressions:      suppressions,
+		progress:          progress,
 	}
 }
 
 // GetVulnerabilitiesV2 retrieves vulnerabilities with enhanced filtering
 func (vs *VulnerabilityV2Service) GetVulnerabilitiesV2(req types.VulnerabilityV2Request) ([]types.VulnerabilityV2Data, int, error) {
-	var vulnerabilities []models.VulnerabilityV2
+	vulnerabilities, err := vs.matchingVulnerabilitiesV2(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	total := len(vulnerabilities)
+	start := (req.Page - 1) * req.PageSize
+	end := start + req.PageSize
+
+	if start >= len(vulnerabilities) {
+		vulnerabilities = []models.VulnerabilityV2{}
+	} else if end > len(vulnerabilities) {
+		vulnerabilities = vulnerabilities[start:]
+	} else {
+		vulnerabilities = vulnerabilities[start:end]
+	}
+
+	// Convert to types
+	var result []types.VulnerabilityV2Data
+	for _, vuln := range vulnerabilities {
+		result = append(result, types.VulnerabilityV2Data{
+			ID:                   vuln.ID,
+			AgentID:              vuln.AgentID,
+			Title:                vuln.Title,
+			Description:          vuln.Description,
+			Severity:             vuln.Severity,
+			Category:             vuln.Category,
+			Status:               vuln.Status,
+			DiscoveredAt:         vuln.DiscoveredAt,
+			LastSeen:             vuln.LastSeen,
+			RiskScore:            vuln.RiskScore,
+			CVSSScore:            vuln.CVSSScore,
+			EnvironmentalScore:   vuln.EnvironmentalScore,
+			EPSSScore:            vuln.EPSSScore,
+			EPSSPercentile:       vuln.EPSSPercentile,
+			ExploitComplexity:    vuln.ExploitComplexity,
+			AttackVector:         vuln.AttackVector,
+			ComplianceFrameworks: vuln.ComplianceFrameworks,
+			Remediation:          vuln.Remediation,
+			References:           vuln.References,
+			Tags:                 vuln.Tags,
+			Metadata:             vuln.Metadata,
+			EnrichmentData:       vuln.EnrichmentData,
+			Suppressed:           vuln.Suppressed,
+			SuppressedBy:         vuln.SuppressedBy,
+			CreatedAt:            vuln.CreatedAt,
+			UpdatedAt:            vuln.UpdatedAt,
+		})
+	}
 
-	// Collect all vulnerabilities from different sources
-	allVulns := make([]models.VulnerabilityV2, 0)
+	return result, total, nil
+}
+
+// ForEachVulnerabilityV2 calls fn with every vulnerability matching the
+// request's filters in its sort order, ignoring its page, and stops at the
+// first error fn returns. Exports use it to write vulnerabilities out one at a
+// time rather than converting the whole set first.
+func (vs *VulnerabilityV2Service) ForEachVulnerabilityV2(req types.VulnerabilityV2Request, fn func(models.VulnerabilityV2) error) error {
+	vulnerabilities, err := vs.matchingVulnerabilitiesV2(req)
+	if err != nil {
+		return err
+	}
+	for _, vuln := range vulnerabilities {
+		if err := fn(vuln); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchingVulnerabilitiesV2 returns the vulnerabilities from every source that
+// match the request's filters, sorted as it asks
+func (vs *VulnerabilityV2Service) matchingVulnerabilitiesV2(req types.VulnerabilityV2Request) ([]models.VulnerabilityV2, error) {
+	// Collect all vulnerabilities from different sources, starting with
+	// application vulnerabilities, the only ones with CVEs
+	allVulns := make([]models.VulnerabilityV2, 0, len(vs.vulnerabilities))
+	for _, vuln := range vs.vulnerabilities {
+		allVulns = append(allVulns, vuln)
+	}
 
 	// Add network findings
 	for _, finding := range vs.networkFindings {
@@ -240,6 +321,8 @@ func (vs *VulnerabilityV2Service) GetVulnerabilitiesV2(req types.VulnerabilityV2
 			Tags:                 []string{"container", finding.FindingType, finding.ImageName},
 			Metadata:             finding.Metadata,
 			EnrichmentData:       make(map[string]interface{}),
+			Suppressed:           finding.SuppressedBy != "", // Allowlisted findings are expected configurations, not vulnerabilities
+			SuppressedBy:         finding.SuppressedBy,
 			CreatedAt:            finding.CreatedAt,
 			UpdatedAt:            finding.UpdatedAt,
 		}
@@ -354,53 +437,52 @@ func (vs *VulnerabilityV2Service) GetVulnerabilitiesV2(req types.VulnerabilityV2
 		allVulns = append(allVulns, vuln)
 	}
 
+	if err := vs.markSuppressed(allVulns, time.Now()); err != nil {
+		return nil, err
+	}
+
 	// Apply filters
-	vulnerabilities = vs.filterVulnerabilities(allVulns, req)
+	vulnerabilities := vs.filterVulnerabilities(allVulns, req)
 
 	// Sort vulnerabilities
-	vulnerabilities = vs.sortVulnerabilities(vulnerabilities, req.SortBy, req.SortOrder)
-
-	// Apply pagination
-	total := len(vulnerabilities)
-	start := (req.Page - 1) * req.PageSize
-	end := start + req.PageSize
+	return vs.sortVulnerabilities(vulnerabilities, req.SortBy, req.SortOrder), nil
+}
 
-	if start >= len(vulnerabilities) {
-		vulnerabilities = []models.VulnerabilityV2{}
-	} else if end > len(vulnerabilities) {
-		vulnerabilities = vulnerabilities[start:]
-	} else {
-		vulnerabilities = vulnerabilities[start:end]
+// markSuppressed marks the vulnerabilities an active suppression rule of
+// their agent's organization matches suppressed by it
+func (vs *VulnerabilityV2Service) markSuppressed(vulnerabilities []models.VulnerabilityV2, at time.Time) error {
+	if vs.suppressions == nil {
+		return nil
 	}
 
-	// Convert to types
-	var result []types.VulnerabilityV2Data
-	for _, vuln := range vulnerabilities {
-		result = append(result, types.VulnerabilityV2Data{
-			ID:                   vuln.ID,
-			AgentID:              vuln.AgentID,
-			Title:                vuln.Title,
-			Description:          vuln.Description,
-			Severity:             vuln.Severity,
-			Category:             vuln.Category,
-			Status:               vuln.Status,
-			DiscoveredAt:         vuln.DiscoveredAt,
-			LastSeen:             vuln.LastSeen,
-			RiskScore:            vuln.RiskScore,
-			ExploitComplexity:    vuln.ExploitComplexity,
-			AttackVector:         vuln.AttackVector,
-			ComplianceFrameworks: vuln.ComplianceFrameworks,
-			Remediation:          vuln.Remediation,
-			References:           vuln.References,
-			Tags:                 vuln.Tags,
-			Metadata:             vuln.Metadata,
-			EnrichmentData:       vuln.EnrichmentData,
-			CreatedAt:            vuln.CreatedAt,
-			UpdatedAt:            vuln.UpdatedAt,
-		})
-	}
+	rules := make(map[uuid.UUID][]models.SuppressionRule)
+	for i := range vulnerabilities {
+		vuln := &vulnerabilities[i]
+		agentID, err := uuid.Parse(vuln.AgentID)
+		if vuln.Suppressed || err != nil {
+			continue
+		}
+		agentRules, ok := rules[agentID]
+		if !ok {
+			if agentRules, err = vs.suppressions.activeRulesForAgent(agentID, at); err != nil {
+				return fmt.Errorf("failed to load suppression rules: %w", err)
+			}
+			rules[agentID] = agentRules
+		}
 
-	return result, total, nil
+		target := suppressionTarget{
+			AgentID:        agentID,
+			CVEID:          vuln.Detail("cve_id"),
+			PackageName:    vuln.Detail("package_name"),
+			PackageVersion: vuln.Detail("package_version"),
+			FindingKey:     vuln.Detail("finding_key"),
+		}
+		if rule := matchSuppressionRule(agentRules, target, at); rule != nil {
+			vuln.Suppressed = true
+			vuln.SuppressedBy = rule.ID.String()
+		}
+	}
+	return nil
 }
 
 // GetVulnerabilityStats retrieves vulnerability statistics
@@ -506,8 +588,8 @@ func (vs *VulnerabilityV2Service) GetComplianceStatus() (models.ComplianceStatus
 	return status, nil
 }
 
-// InitiateNetworkScan initiates a network scan
-func (vs *VulnerabilityV2Service) InitiateNetworkScan(req struct {
+// InitiateNetworkScan initiates a network scan by an agent of the organization
+func (vs *VulnerabilityV2Service) InitiateNetworkScan(organizationID, agentID uuid.UUID, req struct {
 	AgentID     string   `json:"agent_id"`
 	Targets     []string `json:"targets"`
 	Ports       []int    `json:"ports"`
@@ -515,13 +597,10 @@ func (vs *VulnerabilityV2Service) InitiateNetworkScan(req struct {
 	Timeout     int      `json:"timeout"`
 	Concurrency int      `json:"concurrency"`
 }) (string, error) {
-	// Generate scan ID
-	scanID := fmt.Sprintf("scan_%d", time.Now().Unix())
-
 	// Create scan result
 	scanResult := models.ScanResult{
-		ID:              uuid.MustParse(scanID),
-		AgentID:         uuid.MustParse(req.AgentID),
+		ID:              uuid.New(),
+		AgentID:         agentID,
 		ScanType:        "network",
 		Status:          "running",
 		Results:         make(map[string]interface{}),
@@ -533,8 +612,18 @@ func (vs *VulnerabilityV2Service) InitiateNetworkScan(req struct {
 	}
 
 	// Store scan result
+	scanID := scanResult.ID.String()
 	vs.scanResults[scanID] = scanResult
 
+	if vs.progress != nil {
+		vs.progress.Publish(ScanProgressEvent{
+			ScanID:         scanResult.ID,
+			OrganizationID: organizationID,
+			Status:         scanResult.Status,
+			At:             scanResult.CreatedAt,
+		})
+	}
+
 	return scanID, nil
 }
 
@@ -606,6 +695,10 @@ func (vs *VulnerabilityV2Service) filterVulnerabilities(vulnerabilities []models
 	var filtered []models.VulnerabilityV2
 
 	for _, vuln := range vulnerabilities {
+		if vuln.Suppressed && !req.IncludeSuppressed {
+			continue
+		}
+
 		// Filter by category
 		if req.Category != "" && req.Category != "all" && vuln.Category != req.Category {
 			continue
@@ -720,7 +813,28 @@ func (vs *VulnerabilityV2Service) sortVulnerabilities(vulnerabilities []models.V
 			}
 			return vulnerabilities[i].RiskScore < vulnerabilities[j].RiskScore
 		})
+	case "epss_score":
+		// Vulnerabilities without EPSS data sort last either way
+		sortByOptionalScore(vulnerabilities, sortOrder, func(v *models.VulnerabilityV2) *float64 { return v.EPSSScore })
+	case "environmental_score":
+		// As do unscored vulnerabilities
+		sortByOptionalScore(vulnerabilities, sortOrder, func(v *models.VulnerabilityV2) *float64 { return v.EnvironmentalScore })
 	}
 
 	return vulnerabilities
 }
+
+// sortByOptionalScore sorts vulnerabilities by a score they may not have,
+// those without it last whatever the order
+func sortByOptionalScore(vulnerabilities []models.VulnerabilityV2, sortOrder string, score func(*models.VulnerabilityV2) *float64) {
+	sort.SliceStable(vulnerabilities, func(i, j int) bool {
+		a, b := score(&vulnerabilities[i]), score(&vulnerabilities[j])
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		if sortOrder == "desc" {
+			return *a > *b
+		}
+		return *a < *b
+	})
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"zerotrace/api/internal/models"
+	"zerotrace/api/internal/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEachVulnerabilityV2(t *testing.T) {
+	vs := NewVulnerabilityV2Service(nil, nil)
+	vs.systemVulns["a"] = models.SystemVulnerability{ID: "a", Severity: "high", Status: "open"}
+	vs.systemVulns["b"] = models.SystemVulnerability{ID: "b", Severity: "high", Status: "resolved"}
+	vs.authFindings["c"] = models.AuthFinding{ID: "c", Severity: "critical", Status: "open"}
+	vs.authFindings["d"] = models.AuthFinding{ID: "d", Severity: "low", Status: "open"}
+
+	collect := func(req types.VulnerabilityV2Request) []string {
+		var ids []string
+		assert.NoError(t, vs.ForEachVulnerabilityV2(req, func(vuln models.VulnerabilityV2) error {
+			ids = append(ids, vuln.ID)
+			return nil
+		}))
+		return ids
+	}
+
+	// Every match is visited, in sort order, whatever page is asked for
+	assert.Equal(t, []string{"c", "a", "d"}, collect(types.VulnerabilityV2Request{Status: "open", SortBy: "severity", SortOrder: "desc", Page: 2, PageSize: 1}))
+	assert.Equal(t, []string{"a"}, collect(types.VulnerabilityV2Request{Severity: "high", Status: "open"}))
+
+	stop := errors.New("stop")
+	visited := 0
+	err := vs.ForEachVulnerabilityV2(types.VulnerabilityV2Request{}, func(models.VulnerabilityV2) error {
+		visited++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, visited)
+}
+
+func TestGetVulnerabilitiesV2SortsByEPSS(t *testing.T) {
+	low, high := 0.02, 0.9
+	vs := NewVulnerabilityV2Service(nil, nil)
+	vs.vulnerabilities["a"] = models.VulnerabilityV2{ID: "a", EPSSScore: &low}
+	vs.vulnerabilities["b"] = models.VulnerabilityV2{ID: "b"}
+	vs.vulnerabilities["c"] = models.VulnerabilityV2{ID: "c", EPSSScore: &high}
+
+	ids := func(order string) []string {
+		vulns, _, err := vs.GetVulnerabilitiesV2(types.VulnerabilityV2Request{SortBy: "epss_score", SortOrder: order, Page: 1, PageSize: 10})
+		assert.NoError(t, err)
+		var ids []string
+		for _, vuln := range vulns {
+			ids = append(ids, vuln.ID)
+		}
+		return ids
+	}
+
+	// Vulnerabilities without EPSS data come last either way
+	assert.Equal(t, []string{"c", "a", "b"}, ids("desc"))
+	assert.Equal(t, []string{"a", "c", "b"}, ids("asc"))
+}
+
+func TestGetVulnerabilitiesV2SortsByEnvironmentalScore(t *testing.T) {
+	base, env := 9.8, 8.0
+	vs := NewVulnerabilityV2Service(nil, nil)
+	vs.vulnerabilities["a"] = models.VulnerabilityV2{ID: "a", CVSSScore: &base, EnvironmentalScore: &env}
+	vs.vulnerabilities["b"] = models.VulnerabilityV2{ID: "b"}
+	vs.vulnerabilities["c"] = models.VulnerabilityV2{ID: "c", EnvironmentalScore: &base}
+
+	vulns, _, err := vs.GetVulnerabilitiesV2(types.VulnerabilityV2Request{SortBy: "environmental_score", SortOrder: "desc", Page: 1, PageSize: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, vulns, 3) {
+		assert.Equal(t, []string{"c", "a", "b"}, []string{vulns[0].ID, vulns[1].ID, vulns[2].ID})
+		assert.Equal(t, 8.0, *vulns[1].EnvironmentalScore)
+		assert.Equal(t, 9.8, *vulns[1].CVSSScore)
+	}
+}
+
+func TestGetVulnerabilitiesV2LeavesOutSuppressed(t *testing.T) {
+	vs := NewVulnerabilityV2Service(nil, nil)
+	vs.containerFindings["a"] = models.ContainerFinding{ID: "a", Severity: "high", Status: "open"}
+	vs.containerFindings["b"] = models.ContainerFinding{ID: "b", Severity: "medium", Status: "open", SuppressedBy: "rule-1"}
+
+	vulns, total, err := vs.GetVulnerabilitiesV2(types.VulnerabilityV2Request{Page: 1, PageSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, "a", vulns[0].ID)
+
+	vulns, total, err = vs.GetVulnerabilitiesV2(types.VulnerabilityV2Request{SortBy: "severity", SortOrder: "desc", Page: 1, PageSize: 10, IncludeSuppressed: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.True(t, vulns[1].Suppressed)
+	assert.Equal(t, "rule-1", vulns[1].SuppressedBy)
+}
@@ -0,0 +1,524 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/events"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrWebhookNotFound is returned when a webhook does not exist or belongs to another organization
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Webhook delivery headers, alongside the event headers
+const (
+	WebhookSignatureHeader = "X-ZeroTrace-Signature" // sha256=<hex HMAC-SHA256 of the body>, when the webhook has a secret
+	WebhookDeliveryHeader  = "X-ZeroTrace-Delivery"  // Delivery ID, the same on every retry
+)
+
+// maxWebhookRetryDelay caps the backoff between delivery attempts
+const maxWebhookRetryDelay = time.Hour
+
+// WebhookService posts vulnerability.found events to organizations' webhooks
+// when scans report critical or high findings, or Slack and Teams messages
+// for webhooks in those formats. Every delivery is stored as it is queued,
+// so failed ones are retried with backoff and each webhook keeps a log of
+// what was sent to it.
+type WebhookService struct {
+	db             *gorm.DB
+	alerts         *AlertService
+	events         *events.Registry
+	validateEvents bool
+	httpClient     *http.Client
+	checkInterval  time.Duration
+	maxAttempts    int
+
+	mu      sync.Mutex
+	sending map[uuid.UUID]bool // Deliveries being attempted
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(db *gorm.DB, cfg *config.Config) *WebhookService {
+	checkInterval := cfg.WebhookCheckInterval
+	if checkInterval <= 0 {
+		checkInterval = 30 * time.Second
+	}
+	timeout := cfg.WebhookTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	maxAttempts := cfg.WebhookMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 6
+	}
+
+	return &WebhookService{
+		db:             db,
+		alerts:         NewAlertService(cfg),
+		events:         events.Default(),
+		validateEvents: cfg.EventSchemaValidation,
+		httpClient:     &http.Client{Timeout: timeout},
+		checkInterval:  checkInterval,
+		maxAttempts:    maxAttempts,
+		sending:        make(map[uuid.UUID]bool),
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// Start launches the retry loop
+func (s *WebhookService) Start() {
+	s.wg.Add(1)
+	go s.scheduler()
+}
+
+// Stop stops the retry loop and waits for in-flight deliveries to finish
+func (s *WebhookService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	s.wg.Wait()
+	log.Println("Webhook delivery scheduler stopped")
+}
+
+func (s *WebhookService) scheduler() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.retryDueDeliveries()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// ListWebhooks lists an organization's webhooks
+func (s *WebhookService) ListWebhooks(organizationID uuid.UUID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := s.db.Where("organization_id = ?", organizationID).Order("name ASC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].HasSecret = webhooks[i].Secret != ""
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns one of an organization's webhooks
+func (s *WebhookService) GetWebhook(organizationID, webhookID uuid.UUID) (*models.Webhook, error) {
+	return s.getWebhook(organizationID, webhookID)
+}
+
+// CreateWebhook adds a webhook to an organization
+func (s *WebhookService) CreateWebhook(organizationID uuid.UUID, req *models.WebhookRequest) (*models.Webhook, error) {
+	webhook := &models.Webhook{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+	}
+	if err := applyWebhookRequest(webhook, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook replaces a webhook's settings. Queued deliveries are retried
+// against the new URL and secret.
+func (s *WebhookService) UpdateWebhook(organizationID, webhookID uuid.UUID, req *models.WebhookRequest) (*models.Webhook, error) {
+	webhook, err := s.getWebhook(organizationID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWebhookRequest(webhook, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (s *WebhookService) DeleteWebhook(organizationID, webhookID uuid.UUID) error {
+	webhook, err := s.getWebhook(organizationID, webhookID)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(webhook).Error
+	})
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first
+func (s *WebhookService) ListDeliveries(organizationID, webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.getWebhook(organizationID, webhookID); err != nil {
+		return nil, err
+	}
+	var deliveries []models.WebhookDelivery
+	if err := s.db.Where("webhook_id = ?", webhookID).Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// NotifyFindings queues a vulnerability.found event for each of the agent's
+// organization's enabled webhooks whose minimum severity some of the findings
+// that opened or reopened in transitions meet, and sends them in the
+// background. Findings already known, toggles of flapping findings and
+// suppressed findings raise no alert. findings are those the submission
+// reported, whose details are sent when the transition's finding is among
+// them. Failures are logged; the findings are recorded either way.
+func (s *WebhookService) NotifyFindings(agent *models.Agent, transitions []models.FindingTransition, findings []models.Vulnerability) {
+	findings = alertingFindings(transitions, findings)
+	entries := vulnerabilityFoundEntries(findings, models.SeverityHigh)
+	if len(entries) == 0 {
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := s.db.Where("organization_id = ? AND enabled = ?", agent.OrganizationID, true).Find(&webhooks).Error; err != nil {
+		log.Printf("Failed to load webhooks for org %s: %v", agent.OrganizationID, err)
+		return
+	}
+
+	now := time.Now()
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		matching := vulnerabilityFoundEntries(findings, models.SeverityLevel(strings.ToUpper(webhook.MinSeverity)))
+		if len(matching) == 0 {
+			continue
+		}
+		notification := models.VulnerabilityFoundNotification{
+			AgentID:  agent.ID,
+			Hostname: agent.Hostname,
+			Findings: matching,
+			FoundAt:  now.UTC(),
+		}
+		delivery, err := s.queue(webhook, events.VulnerabilityFound, &notification)
+		if err != nil {
+			log.Printf("Failed to queue webhook %s delivery for agent %s: %v", webhook.ID, agent.ID, err)
+			continue
+		}
+		if !s.claim(delivery.ID) {
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.unclaim(delivery.ID)
+			s.deliver(context.Background(), webhook, delivery)
+		}()
+	}
+}
+
+// queue builds an event and stores its delivery to a webhook, due now. Chat
+// webhooks are sent the notification as a message instead of the event.
+func (s *WebhookService) queue(webhook *models.Webhook, eventType string, data *models.VulnerabilityFoundNotification) (*models.WebhookDelivery, error) {
+	event, err := s.events.New(eventType, data)
+	if err != nil {
+		return nil, err
+	}
+	if s.validateEvents {
+		if err := s.events.Validate(event); err != nil {
+			return nil, fmt.Errorf("event does not match its schema: %w", err)
+		}
+	}
+	var body []byte
+	switch webhook.Format {
+	case "", models.WebhookFormatJSON:
+		body, err = json.Marshal(event)
+	default:
+		body, err = s.alerts.Payload(webhook.Format, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:             uuid.New(),
+		WebhookID:      webhook.ID,
+		OrganizationID: webhook.OrganizationID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		SchemaVersion:  event.SchemaVersion,
+		Payload:        string(body),
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  &now,
+	}
+	if err := s.db.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// retryDueDeliveries attempts every pending delivery whose next attempt is due
+func (s *WebhookService) retryDueDeliveries() {
+	var deliveries []models.WebhookDelivery
+	enabled := s.db.Model(&models.Webhook{}).Select("id").Where("enabled = ?", true)
+	if err := s.db.Where("status = ? AND next_attempt_at <= ? AND webhook_id IN (?)", models.WebhookDeliveryPending, time.Now(), enabled).Order("next_attempt_at ASC").Limit(100).Find(&deliveries).Error; err != nil {
+		log.Printf("Failed to load due webhook deliveries: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		var webhook models.Webhook
+		if err := s.db.Where("id = ?", delivery.WebhookID).First(&webhook).Error; err != nil {
+			log.Printf("Failed to load webhook %s for delivery %s: %v", delivery.WebhookID, delivery.ID, err)
+			continue
+		}
+		if !s.claim(delivery.ID) {
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.unclaim(delivery.ID)
+			s.deliver(context.Background(), &webhook, delivery)
+		}()
+	}
+}
+
+// deliver makes one attempt at a delivery and stores its outcome. A
+// disabled webhook's deliveries wait until it is enabled again.
+func (s *WebhookService) deliver(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	if !webhook.Enabled {
+		return
+	}
+	status, err := s.attempt(ctx, webhook, delivery)
+	s.recordAttempt(delivery, status, err, time.Now())
+	if delivery.Status == models.WebhookDeliveryFailed {
+		log.Printf("Gave up on webhook %s delivery %s after %d attempts: %s", webhook.ID, delivery.ID, delivery.Attempts, delivery.Error)
+	}
+
+	if err := s.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_status": delivery.ResponseStatus,
+		"error":           delivery.Error,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"delivered_at":    delivery.DeliveredAt,
+	}).Error; err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// attempt posts a delivery's payload to its webhook, signed with the
+// webhook's secret, and returns the response status
+func (s *WebhookService) attempt(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(events.TypeHeader, delivery.EventType)
+	req.Header.Set(events.SchemaVersionHeader, delivery.SchemaVersion)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, []byte(delivery.Payload)))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// recordAttempt updates a delivery with the outcome of an attempt: delivered,
+// retried after a backoff, or failed once it has used all its attempts
+func (s *WebhookService) recordAttempt(delivery *models.WebhookDelivery, status int, err error, now time.Time) {
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	if err == nil {
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.Error = ""
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.Error = err.Error()
+	if delivery.Attempts >= s.maxAttempts {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+	next := now.Add(webhookRetryDelay(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+}
+
+func (s *WebhookService) claim(deliveryID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sending[deliveryID] {
+		return false
+	}
+	s.sending[deliveryID] = true
+	return true
+}
+
+func (s *WebhookService) unclaim(deliveryID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.sending, deliveryID)
+	s.mu.Unlock()
+}
+
+func (s *WebhookService) getWebhook(organizationID, webhookID uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := s.db.Where("id = ? AND organization_id = ?", webhookID, organizationID).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	webhook.HasSecret = webhook.Secret != ""
+	return &webhook, nil
+}
+
+// applyWebhookRequest validates a webhook request and copies it onto webhook.
+// An empty secret keeps the stored one.
+func applyWebhookRequest(webhook *models.Webhook, req *models.WebhookRequest) error {
+	if !strings.HasPrefix(req.URL, "https://") && !strings.HasPrefix(req.URL, "http://") {
+		return fmt.Errorf("webhook URL must be http or https, got %q", req.URL)
+	}
+	format := req.Format
+	if format == "" {
+		format = models.WebhookFormatJSON
+	}
+	if _, ok := alertAdapters[format]; !ok && format != models.WebhookFormatJSON {
+		return fmt.Errorf("unsupported webhook format %q", req.Format)
+	}
+	minSeverity := strings.ToLower(req.MinSeverity)
+	if minSeverity == "" {
+		minSeverity = "high"
+	}
+	if minSeverity != "critical" && minSeverity != "high" {
+		return fmt.Errorf("min_severity must be critical or high, got %q", req.MinSeverity)
+	}
+
+	webhook.Name = req.Name
+	webhook.URL = req.URL
+	webhook.Format = format
+	webhook.MinSeverity = minSeverity
+	webhook.Enabled = req.Enabled
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	webhook.HasSecret = webhook.Secret != ""
+	return nil
+}
+
+// SignWebhookPayload returns the signature header value of a body: the hex
+// HMAC-SHA256 of the body keyed with the webhook's secret, prefixed with
+// "sha256=". Receivers recompute it over the raw body to verify a delivery.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay is how long after its nth failed attempt a delivery is
+// retried: 30 seconds, doubling with each failure, up to an hour
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts && delay < maxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxWebhookRetryDelay {
+		return maxWebhookRetryDelay
+	}
+	return delay
+}
+
+// alertingFindings returns the findings whose transitions raise an alert:
+// those that opened or reopened, unless flapping or suppressed. A finding
+// missing from reported, such as one a delta only named by key, is described
+// from its state.
+func alertingFindings(transitions []models.FindingTransition, reported []models.Vulnerability) []models.Vulnerability {
+	byKey := make(map[string]*models.Vulnerability, len(reported))
+	for i := range reported {
+		byKey[FindingKey(&reported[i])] = &reported[i]
+	}
+
+	var alerting []models.Vulnerability
+	for _, t := range transitions {
+		if t.To != models.FindingStatusOpen || !t.Alert || t.State == nil || t.State.Suppressed {
+			continue
+		}
+		if finding, ok := byKey[t.State.FindingKey]; ok {
+			alerting = append(alerting, *finding)
+			continue
+		}
+		finding := models.Vulnerability{
+			ID:             t.State.ID.String(),
+			Title:          t.State.Title,
+			Severity:       models.SeverityLevel(t.State.Severity),
+			CVEID:          t.State.CVEID,
+			PackageName:    t.State.PackageName,
+			PackageVersion: t.State.PackageVersion,
+		}
+		if t.State.CVSSScore > 0 {
+			score := t.State.CVSSScore
+			finding.CVSSScore = &score
+		}
+		alerting = append(alerting, finding)
+	}
+	return alerting
+}
+
+// vulnerabilityFoundEntries returns the findings at or above a minimum
+// severity, critical or high, as vulnerability.found entries
+func vulnerabilityFoundEntries(findings []models.Vulnerability, minSeverity models.SeverityLevel) []models.VulnerabilityFoundEntry {
+	var entries []models.VulnerabilityFoundEntry
+	for _, finding := range findings {
+		severity := models.SeverityLevel(strings.ToUpper(string(finding.Severity)))
+		if severity != models.SeverityCritical && (severity != models.SeverityHigh || minSeverity == models.SeverityCritical) {
+			continue
+		}
+		entries = append(entries, models.VulnerabilityFoundEntry{
+			ID:             finding.ID,
+			Title:          finding.Title,
+			Severity:       strings.ToLower(string(severity)),
+			CVEID:          finding.CVEID,
+			CVSSScore:      finding.CVSSScore,
+			PackageName:    finding.PackageName,
+			PackageVersion: finding.PackageVersion,
+			Location:       finding.Location,
+		})
+	}
+	return entries
+}
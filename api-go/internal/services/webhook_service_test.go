@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zerotrace/api/internal/config"
+	"zerotrace/api/internal/events"
+	"zerotrace/api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveryIsSignedAndRetried(t *testing.T) {
+	var status int
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	s := NewWebhookService(nil, &config.Config{WebhookMaxAttempts: 2, EventSchemaValidation: true})
+	webhook := &models.Webhook{ID: uuid.New(), URL: server.URL, Secret: "s3cret", Enabled: true}
+	score := 9.8
+	findings := vulnerabilityFoundEntries([]models.Vulnerability{
+		{ID: "a", Title: "OpenSSL heap overflow", Severity: "critical", CVEID: "CVE-2024-0727", CVSSScore: &score},
+		{ID: "b", Title: "Weak cipher", Severity: models.SeverityHigh},
+		{ID: "c", Title: "Verbose banner", Severity: "medium"},
+	}, models.SeverityHigh)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "high", findings[1].Severity)
+	assert.Len(t, vulnerabilityFoundEntries([]models.Vulnerability{{Severity: "HIGH"}}, models.SeverityCritical), 0)
+
+	event, err := s.events.New(events.VulnerabilityFound, models.VulnerabilityFoundNotification{AgentID: uuid.New(), Findings: findings, FoundAt: time.Now().UTC()})
+	require.NoError(t, err)
+	require.NoError(t, s.events.Validate(event))
+
+	delivery := &models.WebhookDelivery{ID: uuid.New(), EventType: event.Type, SchemaVersion: event.SchemaVersion, Payload: `{"id":"1"}`, Status: models.WebhookDeliveryPending}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	// A failed attempt is retried after a backoff
+	status = http.StatusBadGateway
+	code, err := s.attempt(context.Background(), webhook, delivery)
+	assert.Error(t, err)
+	s.recordAttempt(delivery, code, err, now)
+	assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusBadGateway, delivery.ResponseStatus)
+	assert.Equal(t, now.Add(30*time.Second), *delivery.NextAttemptAt)
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), header.Get(WebhookSignatureHeader))
+	assert.Equal(t, SignWebhookPayload("s3cret", []byte(delivery.Payload)), header.Get(WebhookSignatureHeader))
+	assert.Equal(t, delivery.ID.String(), header.Get(WebhookDeliveryHeader))
+	assert.Equal(t, events.VulnerabilityFound, header.Get(events.TypeHeader))
+
+	// Its last attempt failing gives up on it
+	s.recordAttempt(delivery, 0, assert.AnError, now)
+	assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	status = http.StatusNoContent
+	delivery = &models.WebhookDelivery{ID: uuid.New(), Payload: `{}`, Status: models.WebhookDeliveryPending}
+	code, err = s.attempt(context.Background(), webhook, delivery)
+	require.NoError(t, err)
+	s.recordAttempt(delivery, code, err, now)
+	assert.Equal(t, models.WebhookDeliveryDelivered, delivery.Status)
+	assert.Equal(t, now, *delivery.DeliveredAt)
+
+	// Without a secret nothing is signed
+	webhook.Secret = ""
+	_, err = s.attempt(context.Background(), webhook, delivery)
+	require.NoError(t, err)
+	assert.Empty(t, header.Get(WebhookSignatureHeader))
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookRetryDelay(1))
+	assert.Equal(t, time.Minute, webhookRetryDelay(2))
+	assert.Equal(t, 8*time.Minute, webhookRetryDelay(5))
+	assert.Equal(t, time.Hour, webhookRetryDelay(20))
+}
+
+func TestApplyWebhookRequest(t *testing.T) {
+	webhook := &models.Webhook{}
+	require.NoError(t, applyWebhookRequest(webhook, &models.WebhookRequest{Name: "pager", URL: "https://hooks.example.com/zt", Secret: "s3cret"}))
+	assert.Equal(t, "high", webhook.MinSeverity)
+	assert.True(t, webhook.HasSecret)
+
+	// An empty secret keeps the stored one
+	require.NoError(t, applyWebhookRequest(webhook, &models.WebhookRequest{Name: "pager", URL: "https://hooks.example.com/zt", MinSeverity: "critical"}))
+	assert.Equal(t, "s3cret", webhook.Secret)
+	assert.Equal(t, "critical", webhook.MinSeverity)
+	assert.Equal(t, models.WebhookFormatJSON, webhook.Format)
+
+	require.NoError(t, applyWebhookRequest(webhook, &models.WebhookRequest{Name: "chat", URL: "https://hooks.slack.com/services/T/B/X", Format: models.WebhookFormatSlack}))
+	assert.Equal(t, models.WebhookFormatSlack, webhook.Format)
+
+	assert.Error(t, applyWebhookRequest(webhook, &models.WebhookRequest{URL: "ftp://hooks.example.com"}))
+	assert.Error(t, applyWebhookRequest(webhook, &models.WebhookRequest{URL: "https://hooks.example.com", Format: "pagerduty"}))
+	assert.Error(t, applyWebhookRequest(webhook, &models.WebhookRequest{URL: "https://hooks.example.com", MinSeverity: "low"}))
+}
+
+func TestAlertingFindings(t *testing.T) {
+	reported := []models.Vulnerability{
+		{Type: "dependency", CVEID: "CVE-2025-0001", PackageName: "openssl", Severity: "critical", Location: "/usr/lib"},
+		{Type: "dependency", CVEID: "CVE-2025-0002", PackageName: "zlib", Severity: "high"},
+		{Type: "dependency", CVEID: "CVE-2025-0003", PackageName: "curl", Severity: "high"},
+	}
+	state := func(v models.Vulnerability) *models.FindingState {
+		return &models.FindingState{ID: uuid.New(), FindingKey: FindingKey(&v), CVEID: v.CVEID, Severity: string(v.Severity)}
+	}
+	suppressed := state(reported[2])
+	suppressed.Suppressed = true
+	unreported := &models.FindingState{ID: uuid.New(), FindingKey: "delta-key", Title: "Reopened by delta", CVEID: "CVE-2025-0009", Severity: "high", CVSSScore: 7.5}
+
+	findings := alertingFindings([]models.FindingTransition{
+		{State: state(reported[0]), To: models.FindingStatusOpen, Alert: true},
+		{State: state(reported[1]), From: models.FindingStatusResolved, To: models.FindingStatusOpen, Alert: false}, // Flapping toggle
+		{State: suppressed, To: models.FindingStatusOpen, Alert: true},
+		{State: state(reported[0]), From: models.FindingStatusOpen, To: models.FindingStatusResolved, Alert: true},
+		{State: unreported, From: models.FindingStatusResolved, To: models.FindingStatusOpen, Alert: true},
+	}, reported)
+
+	require.Len(t, findings, 2)
+	assert.Equal(t, "CVE-2025-0001", findings[0].CVEID)
+	assert.Equal(t, "/usr/lib", findings[0].Location, "reported findings keep their details")
+	assert.Equal(t, "CVE-2025-0009", findings[1].CVEID)
+	assert.Equal(t, unreported.ID.String(), findings[1].ID)
+	require.NotNil(t, findings[1].CVSSScore)
+	assert.Equal(t, 7.5, *findings[1].CVSSScore)
+
+	// Findings already open raise no transition and so no alert
+	assert.Empty(t, alertingFindings(nil, reported))
+}
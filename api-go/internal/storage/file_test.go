@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore_RoundTripStaysUnderRoot(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFileStore(root)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.PutObject(ctx, "../../evidence/org/agent/1", []byte("raw output"), "text/plain"))
+	_, err = os.Stat(filepath.Join(root, "evidence", "org", "agent", "1"))
+	require.NoError(t, err, "key with .. must be written under the root")
+
+	body, err := store.GetObject(ctx, "evidence/org/agent/1")
+	require.NoError(t, err)
+	assert.Equal(t, "raw output", string(body))
+
+	_, err = store.GetObject(ctx, "evidence/missing")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
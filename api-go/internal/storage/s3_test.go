@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Store_PutObjectPathStyle(t *testing.T) {
+	var gotPath, gotAuth, gotHash, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		gotHash = r.Header.Get("x-amz-content-sha256")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		Bucket:          "exports",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+
+		AllowPrivateEndpoint: true, // The test server is on loopback
+	})
+	require.NoError(t, err)
+
+	err = store.PutObject(context.Background(), "org 1/manifest.json", []byte(`{"ok":true}`), "application/json")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/exports/org%201/manifest.json", gotPath)
+	assert.Equal(t, `{"ok":true}`, gotBody)
+	assert.Equal(t, sha256Hex([]byte(`{"ok":true}`)), gotHash)
+	assert.True(t, strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+	assert.Contains(t, gotAuth, "/eu-west-1/s3/aws4_request")
+}
+
+func TestS3Store_PutObjectErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<Error><Code>AccessDenied</Code></Error>"))
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "exports",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+
+		AllowPrivateEndpoint: true, // The test server is on loopback
+	})
+	require.NoError(t, err)
+
+	err = store.PutObject(context.Background(), "a.json", []byte("{}"), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AccessDenied")
+}
+
+func TestNewS3Store_Validation(t *testing.T) {
+	_, err := NewS3Store(S3Config{AccessKeyID: "k", SecretAccessKey: "s"})
+	assert.Error(t, err)
+
+	_, err = NewS3Store(S3Config{Bucket: "b"})
+	assert.Error(t, err)
+
+	_, err = NewS3Store(S3Config{Bucket: "b", AccessKeyID: "k", SecretAccessKey: "s", Endpoint: "ftp://host"})
+	assert.Error(t, err)
+
+	for _, endpoint := range []string{"http://localhost:9000", "http://127.0.0.1:9000", "http://10.0.0.5", "http://169.254.169.254", "http://[::1]"} {
+		_, err = NewS3Store(S3Config{Bucket: "b", AccessKeyID: "k", SecretAccessKey: "s", Endpoint: endpoint})
+		assert.Error(t, err, endpoint)
+	}
+	_, err = NewS3Store(S3Config{Bucket: "b", AccessKeyID: "k", SecretAccessKey: "s", Endpoint: "http://10.0.0.5", AllowPrivateEndpoint: true})
+	assert.NoError(t, err)
+}
+
+func TestS3Store_OpenAndDeleteObject(t *testing.T) {
+	objects := map[string]string{"/configs/org/abc": "hostname fw1\n"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := objects[r.URL.Path]
+		switch {
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = w.Write([]byte(body))
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "configs",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+
+		AllowPrivateEndpoint: true, // The test server is on loopback
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	body, size, err := store.OpenObject(ctx, "org/abc")
+	require.NoError(t, err)
+	content, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "hostname fw1\n", string(content))
+	assert.Equal(t, int64(len(content)), size)
+
+	require.NoError(t, store.DeleteObject(ctx, "org/abc"))
+	require.NoError(t, store.DeleteObject(ctx, "org/abc"), "deleting a missing object is not an error")
+	_, _, err = store.OpenObject(ctx, "org/abc")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrObjectNotFound is returned when reading a key that does not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStore is a minimal blob store abstraction
+type ObjectStore interface {
+	// PutObject writes body under key, replacing any existing object
+	PutObject(ctx context.Context, key string, body []byte, contentType string) error
+	// GetObject reads the object stored under key
+	GetObject(ctx context.Context, key string) ([]byte, error)
+}
+
+// BlobStore is an ObjectStore that can also stream and delete objects, as
+// needed to keep user-uploaded files outside the database
+type BlobStore interface {
+	ObjectStore
+	// OpenObject streams the object stored under key, returning its size.
+	// The caller must close the returned reader.
+	OpenObject(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// DeleteObject removes the object stored under key, if any
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// Store backends
+const (
+	BackendFile = "file"
+	BackendS3   = "s3"
+)
+
+// NewStore opens the store of a configured backend: files under dir, or an
+// S3-compatible bucket, which may be on the operator's own network
+func NewStore(backend, dir string, s3 S3Config) (BlobStore, error) {
+	switch backend {
+	case BackendFile:
+		return NewFileStore(dir)
+	case BackendS3:
+		s3.AllowPrivateEndpoint = true
+		return NewS3Store(s3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
@@ -0,0 +1,62 @@
+// Package testdb opens throwaway databases for tests that need to read back
+// what they wrote
+package testdb
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Open returns an in-memory SQLite database with tables for models, and the
+// models they have relations to, closed when the test ends. Postgres column
+// defaults SQLite can't parse, such as gen_random_uuid(), are dropped, so rows
+// must be created with their IDs set.
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	// Each connection to :memory: is its own database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("failed to parse %T: %v", model, err)
+		}
+		dropFunctionDefaults(stmt.Schema, map[*schema.Schema]bool{})
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("failed to create test tables: %v", err)
+	}
+	return db
+}
+
+// dropFunctionDefaults drops the column defaults that call functions from s
+// and the schemas it has relations to, which AutoMigrate creates tables for
+func dropFunctionDefaults(s *schema.Schema, seen map[*schema.Schema]bool) {
+	if seen[s] {
+		return
+	}
+	seen[s] = true
+	for _, field := range s.Fields {
+		if strings.Contains(field.DefaultValue, "(") {
+			field.DefaultValue = ""
+			field.HasDefaultValue = false
+		}
+	}
+	for _, rel := range s.Relationships.Relations {
+		dropFunctionDefaults(rel.FieldSchema, seen)
+	}
+}
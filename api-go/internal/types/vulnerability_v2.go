@@ -9,7 +9,7 @@ type VulnerabilityV2Request struct {
 	Category   string   `json:"category" form:"category"`     // application, network, configuration, system, auth, database, api, container, ai, iot, privacy, web3
 	Severity   string   `json:"severity" form:"severity"`     // critical, high, medium, low, info
 	Compliance string   `json:"compliance" form:"compliance"` // CIS, PCI-DSS, HIPAA, GDPR, SOC2, ISO27001
-	SortBy     string   `json:"sort_by" form:"sort_by"`       // severity, discovered_date, risk_score
+	SortBy     string   `json:"sort_by" form:"sort_by"`       // severity, discovered_date, risk_score, epss_score, environmental_score
 	SortOrder  string   `json:"sort_order" form:"sort_order"` // asc, desc
 	Page       int      `json:"page" form:"page"`
 	PageSize   int      `json:"page_size" form:"page_size"`
@@ -20,6 +20,9 @@ type VulnerabilityV2Request struct {
 	DateTo     string   `json:"date_to" form:"date_to"`
 	Export     string   `json:"export" form:"export"` // json, csv, pdf, sarif
 	Tags       []string `json:"tags" form:"tags"`
+	// IncludeSuppressed also lists suppressed vulnerabilities, which are left
+	// out of lists and counts by default
+	IncludeSuppressed bool `json:"include_suppressed" form:"include_suppressed"`
 }
 
 // VulnerabilityV2Response represents the response structure for vulnerability v2 endpoints
@@ -48,6 +51,10 @@ type VulnerabilityV2Data struct {
 	DiscoveredAt         time.Time              `json:"discovered_at"`
 	LastSeen             time.Time              `json:"last_seen"`
 	RiskScore            float64                `json:"risk_score"`
+	CVSSScore            *float64               `json:"cvss_score,omitempty"`
+	EnvironmentalScore   *float64               `json:"environmental_score,omitempty"` // CVSS score under the organization's security requirements
+	EPSSScore            *float64               `json:"epss_score"`                    // null without EPSS data
+	EPSSPercentile       *float64               `json:"epss_percentile"`
 	ExploitComplexity    string                 `json:"exploit_complexity"`
 	AttackVector         string                 `json:"attack_vector"`
 	ComplianceFrameworks []string               `json:"compliance_frameworks"`
@@ -56,6 +63,8 @@ type VulnerabilityV2Data struct {
 	Tags                 []string               `json:"tags"`
 	Metadata             map[string]interface{} `json:"metadata"`
 	EnrichmentData       map[string]interface{} `json:"enrichment_data"`
+	Suppressed           bool                   `json:"suppressed"`
+	SuppressedBy         string                 `json:"suppressed_by,omitempty"`
 	CreatedAt            time.Time              `json:"created_at"`
 	UpdatedAt            time.Time              `json:"updated_at"`
 }
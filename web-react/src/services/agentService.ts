@@ -5,7 +5,8 @@ export interface Agent {
   name: string;
   hostname: string;
   os: string;
-  status: 'online' | 'offline' | 'unknown';
+  status: 'online' | 'degraded' | 'offline' | 'unknown';
+  status_changed_at?: string;
   last_seen: string;
   cpu_usage: number;
   memory_usage: number;
@@ -46,6 +47,7 @@ export interface AgentResponse {
   success: boolean;
   data: Agent[];
   message: string;
+  next_cursor?: string;
   timestamp: string;
 }
 
@@ -59,10 +61,17 @@ export interface AgentStatsResponse {
 export const agentService = {
   async getAgents(): Promise<Agent[]> {
     try {
-      console.log('Fetching agents from /api/agents/');
-      const response = await api.get<AgentResponse>('/api/agents/');
-      console.log('Agents response:', response.data);
-      return response.data.data || [];
+      // The agent list is paginated; follow next_cursor until the last page
+      const agents: Agent[] = [];
+      let cursor: string | undefined;
+      do {
+        const response = await api.get<AgentResponse>('/api/agents/', {
+          params: { limit: 500, cursor },
+        });
+        agents.push(...(response.data.data || []));
+        cursor = response.data.next_cursor;
+      } while (cursor);
+      return agents;
     } catch (error) {
       console.error('Failed to fetch agents:', error);
       return [];
@@ -1,4 +1,5 @@
 import { api } from './api';
+import { agentService } from './agentService';
 
 export interface InitiateScanRequest {
   agent_id: string;
@@ -89,8 +90,7 @@ export const networkScanService = {
   async getNetworkScanResults(): Promise<NetworkScanResult[]> {
     try {
       // Fetch agents and extract network scan results from metadata
-      const response = await api.get('/api/agents/');
-      const agents = response.data?.data || [];
+      const agents = await agentService.getAgents();
 
       const scanResults: NetworkScanResult[] = [];
 